@@ -0,0 +1,122 @@
+// Command loadgen replays captured or synthetic chat traffic against the router at a
+// fixed rate and reports latency and errors per credential and model.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/loadgen"
+)
+
+// Environment variables used when the corresponding flag is not set
+const (
+	apiKeyEnv       = "AUTO_AI_ROUTER_API_KEY"
+	routerSecretEnv = "AUTO_AI_ROUTER_INTER_ROUTER_SECRET"
+)
+
+// options selects the traffic source and report format
+type options struct {
+	replayPath   string
+	loop         bool
+	models       string
+	promptTokens int
+	maxTokens    int
+	stream       bool
+	jsonOutput   bool
+}
+
+func main() {
+	cfg := loadgen.Config{}
+	opts := options{}
+	flag.StringVar(&cfg.URL, "url", "http://localhost:8080", "Router base URL")
+	flag.StringVar(&cfg.APIKey, "key", os.Getenv(apiKeyEnv), "API key; the master key enables per-credential reports (env "+apiKeyEnv+")")
+	flag.StringVar(&cfg.RouterSecret, "router-secret", os.Getenv(routerSecretEnv), "Sign requests with server.inter_router_secret instead of a key (env "+routerSecretEnv+")")
+	flag.Float64Var(&cfg.RPS, "rps", 1, "Target requests per second")
+	flag.DurationVar(&cfg.Duration, "duration", time.Minute, "Test duration (0 = until the replay file is exhausted)")
+	flag.IntVar(&cfg.MaxRequests, "requests", 0, "Stop after this many requests (0 = no limit)")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 64, "Maximum in-flight requests")
+	flag.DurationVar(&cfg.Timeout, "timeout", 2*time.Minute, "Per-request timeout")
+	flag.StringVar(&opts.replayPath, "replay", "", "JSONL file with captured requests (router errors log, {path, body} objects or request bodies)")
+	flag.BoolVar(&opts.loop, "loop", false, "Replay the file repeatedly until -duration or -requests is reached")
+	flag.StringVar(&opts.models, "models", "", "Comma-separated models for synthetic traffic (used without -replay)")
+	flag.IntVar(&opts.promptTokens, "prompt-tokens", 100, "Approximate prompt size of synthetic requests")
+	flag.IntVar(&opts.maxTokens, "max-tokens", 64, "max_tokens of synthetic requests (0 = provider default)")
+	flag.BoolVar(&opts.stream, "stream", false, "Send synthetic requests with stream: true")
+	flag.BoolVar(&opts.jsonOutput, "json", false, "Print the report as JSON")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg loadgen.Config, opts options, out io.Writer) error {
+	if cfg.RPS <= 0 {
+		return errors.New("-rps must be greater than 0")
+	}
+	if cfg.APIKey == "" && cfg.RouterSecret == "" {
+		return fmt.Errorf("-key (or %s) or -router-secret is required", apiKeyEnv)
+	}
+	if cfg.Duration <= 0 && cfg.MaxRequests <= 0 && (opts.replayPath == "" || opts.loop) {
+		return errors.New("-duration or -requests is required for synthetic or looped traffic")
+	}
+
+	source, err := newSource(opts)
+	if err != nil {
+		return err
+	}
+
+	report := loadgen.NewRunner(cfg, source).Run(ctx)
+	if opts.jsonOutput {
+		return report.WriteJSON(out)
+	}
+	return report.WriteText(out)
+}
+
+func newSource(opts options) (loadgen.Source, error) {
+	if opts.replayPath == "" {
+		var models []string
+		for _, model := range strings.Split(opts.models, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			return nil, errors.New("-replay or -models is required")
+		}
+		return &loadgen.SyntheticSource{
+			Models:       models,
+			PromptTokens: opts.promptTokens,
+			MaxTokens:    opts.maxTokens,
+			Stream:       opts.stream,
+		}, nil
+	}
+
+	file, err := os.Open(opts.replayPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	requests, err := loadgen.LoadReplay(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", opts.replayPath, err)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests found in %s", opts.replayPath)
+	}
+	return loadgen.NewReplaySource(requests, opts.loop), nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Validation(t *testing.T) {
+	cfg := loadgen.Config{APIKey: "k", RPS: 1, Duration: time.Second}
+
+	assert.ErrorContains(t, run(context.Background(), loadgen.Config{APIKey: "k"}, options{models: "gpt-4o"}, nil), "-rps")
+	assert.ErrorContains(t, run(context.Background(), loadgen.Config{RPS: 1, Duration: time.Second}, options{models: "gpt-4o"}, nil), "-key")
+	assert.ErrorContains(t, run(context.Background(), cfg, options{}, nil), "-replay or -models")
+	assert.ErrorContains(t, run(context.Background(), loadgen.Config{APIKey: "k", RPS: 1}, options{models: "gpt-4o"}, nil), "-duration or -requests")
+	assert.ErrorContains(t, run(context.Background(), cfg, options{replayPath: filepath.Join(t.TempDir(), "missing.jsonl")}, nil), "no such file")
+}
+
+func TestRun_Replay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	replayPath := filepath.Join(t.TempDir(), "traffic.jsonl")
+	require.NoError(t, os.WriteFile(replayPath, []byte(`{"model":"gpt-4o","messages":[]}`+"\n"+`{"model":"gpt-4o-mini","messages":[]}`+"\n"), 0o600))
+
+	var out bytes.Buffer
+	cfg := loadgen.Config{URL: server.URL, APIKey: "k", RPS: 100, Concurrency: 2, Timeout: time.Second}
+	require.NoError(t, run(context.Background(), cfg, options{replayPath: replayPath, jsonOutput: true}, &out))
+	assert.Contains(t, out.String(), `"model": "gpt-4o"`)
+	assert.Contains(t, out.String(), `"model": "gpt-4o-mini"`)
+}
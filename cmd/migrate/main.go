@@ -0,0 +1,101 @@
+// Command migrate checks a LiteLLM database against the schema the router is written for and
+// backfills the spend log rows written by the LiteLLM proxy, so the router can take over as
+// spend log writer without double-counting or inconsistent rows.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/migrate"
+)
+
+// databaseURLEnv is used when -database-url is not set
+const databaseURLEnv = "DATABASE_URL"
+
+// errIncompatible is returned when the schema check finds mismatches
+var errIncompatible = errors.New("database schema is incompatible with the router")
+
+// options select what migrate does
+type options struct {
+	databaseURL string
+	backfill    bool
+	dryRun      bool
+	batchSize   int
+	jsonOutput  bool
+}
+
+// database is the part of a connection pool used by migrate
+type database interface {
+	migrate.Querier
+	migrate.Execer
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.databaseURL, "database-url", os.Getenv(databaseURLEnv), "LiteLLM PostgreSQL URL (env "+databaseURLEnv+")")
+	flag.BoolVar(&opts.backfill, "backfill", false, "Convert spend log rows written by the LiteLLM proxy after a successful schema check")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "With -backfill, only count the rows each step would convert")
+	flag.IntVar(&opts.batchSize, "batch-size", migrate.DefaultBatchSize, "Rows updated per statement")
+	flag.BoolVar(&opts.jsonOutput, "json", false, "Print the result as JSON")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options, out io.Writer) error {
+	if opts.databaseURL == "" {
+		return fmt.Errorf("-database-url (or %s) is required", databaseURLEnv)
+	}
+	if opts.batchSize <= 0 {
+		return errors.New("-batch-size must be greater than 0")
+	}
+
+	pool, err := pgxpool.New(ctx, opts.databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer pool.Close()
+	return migrateDatabase(ctx, pool, opts, out)
+}
+
+// migrateDatabase checks the schema, backfills when requested and the schema is
+// compatible, and writes the result
+func migrateDatabase(ctx context.Context, db database, opts options, out io.Writer) error {
+	report, err := migrate.CheckSchema(ctx, db, litellmdb.Schema)
+	if err != nil {
+		return err
+	}
+	result := &migrate.Result{Schema: report, DryRun: opts.backfill && opts.dryRun}
+
+	var runErr error
+	switch {
+	case !report.Compatible():
+		runErr = errIncompatible
+	case opts.backfill:
+		result.Backfill, runErr = migrate.Backfill(ctx, db, migrate.BackfillOptions{BatchSize: opts.batchSize, DryRun: opts.dryRun})
+	}
+
+	write := result.WriteText
+	if opts.jsonOutput {
+		write = result.WriteJSON
+	}
+	if err := write(out); err != nil {
+		return err
+	}
+	return runErr
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDatabase reports the columns of the embedded LiteLLM schema and counts updates
+type fakeDatabase struct {
+	columns [][]interface{}
+	updates int
+}
+
+// newFakeDatabase returns a database matching the LiteLLM schema
+func newFakeDatabase() *fakeDatabase {
+	db := &fakeDatabase{}
+	tables := migrate.ParseSchema(litellmdb.Schema)
+	for _, table := range migrate.RouterTables {
+		for _, column := range tables[table] {
+			db.columns = append(db.columns, []interface{}{table, column.Name, column.Type, column.Nullable})
+		}
+	}
+	return db
+}
+
+type fakeRows struct {
+	pgx.Rows
+	rows [][]interface{}
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.next-1]
+	*dest[0].(*string), *dest[1].(*string), *dest[2].(*string) = row[0].(string), row[1].(string), row[2].(string)
+	*dest[3].(*bool) = row[3].(bool)
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
+
+type countRow struct{}
+
+func (countRow) Scan(dest ...interface{}) error {
+	*dest[0].(*int64) = 3
+	return nil
+}
+
+func (db *fakeDatabase) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return &fakeRows{rows: db.columns}, nil
+}
+
+func (db *fakeDatabase) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return countRow{}
+}
+
+func (db *fakeDatabase) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	db.updates++
+	return pgconn.NewCommandTag("UPDATE 0"), nil
+}
+
+func TestRun_Validation(t *testing.T) {
+	assert.ErrorContains(t, run(context.Background(), options{batchSize: 1}, nil), "-database-url")
+	assert.ErrorContains(t, run(context.Background(), options{databaseURL: "postgres://localhost/litellm"}, nil), "-batch-size")
+	assert.ErrorContains(t, run(context.Background(), options{databaseURL: "postgres://%zz", batchSize: 1}, nil), "failed to connect")
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	db := newFakeDatabase()
+	var out bytes.Buffer
+	require.NoError(t, migrateDatabase(context.Background(), db, options{batchSize: 10}, &out))
+	assert.Contains(t, out.String(), "schema: compatible")
+	assert.Zero(t, db.updates, "no backfill without -backfill")
+
+	out.Reset()
+	require.NoError(t, migrateDatabase(context.Background(), db, options{backfill: true, dryRun: true, batchSize: 10, jsonOutput: true}, &out))
+	var result migrate.Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.True(t, result.DryRun)
+	require.NotEmpty(t, result.Backfill)
+	assert.Equal(t, int64(3), result.Backfill[0].Rows)
+	assert.Zero(t, db.updates)
+
+	require.NoError(t, migrateDatabase(context.Background(), db, options{backfill: true, batchSize: 10}, &out))
+	assert.Equal(t, len(result.Backfill), db.updates, "one batch per step")
+}
+
+func TestMigrateDatabase_Incompatible(t *testing.T) {
+	db := newFakeDatabase()
+	db.columns = db.columns[1:]
+
+	var out bytes.Buffer
+	err := migrateDatabase(context.Background(), db, options{backfill: true, batchSize: 10}, &out)
+	assert.ErrorIs(t, err, errIncompatible)
+	assert.Contains(t, out.String(), "schema: INCOMPATIBLE")
+	assert.Contains(t, out.String(), "missing_column")
+	assert.Zero(t, db.updates, "an incompatible database is not backfilled")
+}
@@ -4,28 +4,47 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/auth"
 	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/cassette"
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/contextwindow"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/deploy"
 	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/faultinject"
+	"github.com/mixaill76/auto_ai_router/internal/forecast"
 	"github.com/mixaill76/auto_ai_router/internal/health"
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/imagefetch"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
 	"github.com/mixaill76/auto_ai_router/internal/logger"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
 	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/modelupdate"
 	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/proxyhealth"
+	"github.com/mixaill76/auto_ai_router/internal/quota"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
 	"github.com/mixaill76/auto_ai_router/internal/router"
+	"github.com/mixaill76/auto_ai_router/internal/sampling"
+	"github.com/mixaill76/auto_ai_router/internal/scheduler"
+	"github.com/mixaill76/auto_ai_router/internal/spendreport"
+	"github.com/mixaill76/auto_ai_router/internal/spendstore"
 	"github.com/mixaill76/auto_ai_router/internal/startup"
+	"github.com/mixaill76/auto_ai_router/internal/worker"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -36,8 +55,32 @@ var (
 
 func main() {
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	generateKey := flag.Bool("generate-config-key", false, "Print a new key for "+config.ConfigKeyEnv+" and exit")
+	encryptValue := flag.Bool("encrypt-value", false, "Encrypt a value read from stdin with "+config.ConfigKeyEnv+" and exit")
+	backfillSpend := flag.Bool("backfill-spend", false, "Push the local spend log (local_spend_log) into LiteLLM DB and exit")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %s [flags] [generate k8s|compose [generate flags]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	if flag.NArg() > 0 {
+		if err := runGenerateCommand(flag.Args(), *configPath, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *generateKey || *encryptValue {
+		if err := runSecretCommand(*generateKey, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// ==================== Load Configuration ====================
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -47,6 +90,14 @@ func main() {
 
 	log := logger.New(cfg.Server.LoggingLevel)
 
+	if *backfillSpend {
+		if err := runSpendBackfill(cfg, log); err != nil {
+			log.Error("Spend backfill failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	config.PrintConfig(log, cfg)
 
 	log.Info("Starting auto_ai_router",
@@ -58,22 +109,73 @@ func main() {
 
 	logCredentials(log, cfg.Credentials)
 
+	tokenManager := auth.NewVertexTokenManager(log)
+	defer tokenManager.Stop()
+
 	// ==================== Startup Validation ====================
-	startup.ValidateProxyCredentialsAtStartup(cfg, log)
+	if cfg.StartupCheck.Enabled {
+		if err := startup.ValidateCredentialsAtStartup(cfg, tokenManager, log); err != nil {
+			log.Error("CRITICAL: Startup credential check failed",
+				"error", err,
+				"reason", "startup_check.strict is enabled",
+				"action", "Fix the failing credentials or set required=false / startup_check.strict=false",
+			)
+			tokenManager.Stop()
+			os.Exit(1)
+		}
+	} else {
+		startup.ValidateProxyCredentialsAtStartup(cfg, log)
+	}
 
 	// ==================== Initialize Core Components ====================
-	_, rateLimiter, bal := initializeBalancer(cfg, log)
+	f2b, rateLimiter, bal := initializeBalancer(cfg, log)
+	restoreFail2BanState(cfg, log, f2b)
+	proxyHealth := proxyhealth.NewTracker()
+	bal.SetProxyHealthChecker(proxyHealth)
 	modelManager := initializeModelManager(log, cfg, rateLimiter, bal)
-	tokenManager := auth.NewVertexTokenManager(log)
-	defer tokenManager.Stop()
 
-	litellmDBManager := initializeLiteLLMDB(cfg, log)
+	var quotaBoosts *quota.Store
+	if cfg.QuotaBoosts.Enabled {
+		quotaBoosts = quota.NewStore(cfg.QuotaBoosts.MaxDuration)
+	}
+
+	litellmDBManager := initializeLiteLLMDB(cfg, quotaBoosts, log)
 	metrics := monitoring.New(cfg.Monitoring.PrometheusEnabled)
+	spendPusher := monitoring.NewSpendPusher(cfg.Monitoring.SpendPush, log)
+	sloTracker := monitoring.NewSLOTracker(cfg.Monitoring.SLO, log)
+	spendReporter := spendreport.New(cfg.SpendReport, log)
+	spendStore := initializeSpendStore(cfg, litellmDBManager, log)
+
+	// ==================== Usage Forecast ====================
+	var usageEstimator *forecast.Estimator
+	if cfg.UsageForecast.Enabled {
+		quotas := make(map[string]forecast.Quota)
+		for _, cred := range cfg.Credentials {
+			if cred.Quota != nil {
+				quotas[cred.Name] = forecast.Quota{
+					DailyTokens:   cred.Quota.DailyTokens,
+					MonthlyTokens: cred.Quota.MonthlyTokens,
+					DailySpend:    cred.Quota.DailySpend,
+					MonthlySpend:  cred.Quota.MonthlySpend,
+				}
+			}
+		}
+		usageEstimator = forecast.New(forecast.Config{
+			Interval:      cfg.UsageForecast.Interval,
+			Window:        cfg.UsageForecast.Window,
+			WarnThreshold: cfg.UsageForecast.WarnThreshold,
+		}, quotas, log)
+		if usageEstimator == nil {
+			log.Warn("usage_forecast is enabled but no credential has a quota")
+		}
+	}
 
 	// ==================== Initialize Model Pricing ====================
 	priceRegistry := models.NewModelPriceRegistry()
+	priceRegistry.SetMapping(cfg.Server.ModelPriceMapping)
 	if cfg.Server.ModelPricesLink != "" {
 		log.Info("Using model prices from", "link", cfg.Server.ModelPricesLink)
+		restorePriceSnapshot(cfg.Server.ModelPricesCacheFile, priceRegistry, log)
 	} else {
 		log.Debug("Model prices not configured (model_prices_link empty)")
 	}
@@ -87,7 +189,121 @@ func main() {
 		log.Info("LiteLLM DB initial health check passed (marked healthy)")
 	}
 
+	// ==================== Create Fair Scheduler ====================
+	var fairScheduler *scheduler.FairScheduler
+	if cfg.FairScheduler.Enabled {
+		fairScheduler = scheduler.NewFairScheduler(scheduler.Config{
+			MaxInFlight:   cfg.FairScheduler.MaxConcurrent,
+			MaxQueue:      cfg.FairScheduler.MaxQueue,
+			QueueTimeout:  cfg.FairScheduler.QueueTimeout,
+			DefaultWeight: cfg.FairScheduler.DefaultWeight,
+			Weights:       cfg.FairScheduler.Weights,
+		})
+		log.Info("Fair scheduler enabled",
+			"max_concurrent", cfg.FairScheduler.MaxConcurrent,
+			"max_queue", cfg.FairScheduler.MaxQueue)
+	}
+
+	// ==================== Create Image Fetcher ====================
+	var imageFetcher *imagefetch.Fetcher
+	if cfg.ImageInlining.Enabled {
+		imageFetcher = imagefetch.New(imagefetch.Config{
+			MaxBytes:             int64(cfg.ImageInlining.MaxSizeMB) * 1024 * 1024,
+			AllowedMIMETypes:     cfg.ImageInlining.AllowedMIMETypes,
+			Timeout:              cfg.ImageInlining.Timeout,
+			CacheTTL:             cfg.ImageInlining.CacheTTL,
+			CacheSize:            cfg.ImageInlining.CacheSize,
+			AllowPrivateNetworks: cfg.ImageInlining.AllowPrivateNetworks,
+			MaxImages:            cfg.ImageInlining.MaxImages,
+			MaxConcurrent:        cfg.ImageInlining.MaxConcurrentFetches,
+		})
+		log.Info("Image URL inlining enabled",
+			"max_size_mb", cfg.ImageInlining.MaxSizeMB,
+			"cache_ttl", cfg.ImageInlining.CacheTTL.String())
+	}
+
+	// ==================== Create Context Manager ====================
+	var contextManager *contextwindow.Manager
+	if cfg.ContextManagement.Enabled {
+		contextManager = contextwindow.New(contextwindow.Config{
+			Strategy:         cfg.ContextManagement.Strategy,
+			MaxPromptTokens:  cfg.ContextManagement.MaxPromptTokens,
+			KeepRecent:       cfg.ContextManagement.KeepRecent,
+			SummaryModel:     cfg.ContextManagement.SummaryModel,
+			SummaryMaxTokens: cfg.ContextManagement.SummaryMaxTokens,
+		})
+		log.Info("Context management enabled",
+			"strategy", cfg.ContextManagement.Strategy,
+			"max_prompt_tokens", cfg.ContextManagement.MaxPromptTokens)
+	}
+
+	// Normalize provider-specific artifacts in converted responses
+	postProcess := converter.PostProcessOptions{
+		StripPlaceholders:     cfg.PostProcessing.StripPlaceholders,
+		TrimLeadingWhitespace: cfg.PostProcessing.TrimLeadingWhitespace,
+		StripStopSequences:    cfg.PostProcessing.StripStopSequences,
+	}
+
+	// Resolve rolling model aliases to pinned snapshots
+	var modelPins *models.PinRegistry
+	if cfg.ModelPins.Enabled {
+		modelPins = models.NewPinRegistry(cfg.ModelPins, func() []string {
+			available := modelManager.GetAllModels().Data
+			ids := make([]string, 0, len(available))
+			for _, model := range available {
+				ids = append(ids, model.ID)
+			}
+			return ids
+		}, log)
+		log.Info("Model snapshot pinning enabled", "aliases", len(cfg.ModelPins.Aliases))
+	}
+
+	// Reuse resolved upstream addresses instead of querying DNS for every new connection
+	var dnsCache *httputil.DNSCache
+	if cfg.DNSCache.Enabled {
+		dnsCache = httputil.NewDNSCache(cfg.DNSCache.TTL)
+		log.Info("Upstream DNS cache enabled", "ttl", cfg.DNSCache.TTL.String())
+	}
+
+	// ==================== Fault Injection (dev mode) ====================
+	var faultRules map[string]faultinject.Rule
+	if cfg.FaultInjection.Enabled {
+		faultRules = make(map[string]faultinject.Rule, len(cfg.FaultInjection.Rules))
+		for _, rule := range cfg.FaultInjection.Rules {
+			faultRules[rule.Credential] = faultinject.Rule{
+				RateLimitProbability:   rule.RateLimitProbability,
+				ServerErrorProbability: rule.ServerErrorProbability,
+				Latency:                rule.Latency,
+				LatencyProbability:     rule.LatencyProbability,
+			}
+		}
+		log.Warn("Fault injection enabled: upstream failures are simulated", "rules", len(faultRules))
+	}
+
+	// ==================== Cassettes (dev mode) ====================
+	var wrapTransport func(http.RoundTripper) http.RoundTripper
+	switch cfg.Cassette.Mode {
+	case config.CassetteModeRecord:
+		wrapTransport = func(next http.RoundTripper) http.RoundTripper {
+			return cassette.NewRecorder(next, cfg.Cassette.Dir, log)
+		}
+		log.Warn("Recording upstream traffic to cassettes", "dir", cfg.Cassette.Dir)
+	case config.CassetteModeReplay:
+		replayer, err := cassette.LoadReplayer(cfg.Cassette.Dir)
+		if err != nil {
+			log.Error("Failed to load cassettes", "dir", cfg.Cassette.Dir, "error", err)
+			tokenManager.Stop()
+			os.Exit(1)
+		}
+		wrapTransport = func(http.RoundTripper) http.RoundTripper { return replayer }
+		log.Warn("Replaying upstream traffic from cassettes", "dir", cfg.Cassette.Dir)
+	}
+
 	// ==================== Create Proxy ====================
+	// Background workers are supervised: a panicking worker is restarted with backoff and
+	// its status is reported in /health
+	workers := worker.NewSupervisor(log)
+
 	prx := proxy.New(&proxy.Config{
 		Balancer:               bal,
 		Logger:                 log,
@@ -99,6 +315,7 @@ func main() {
 		IdleConnTimeout:        cfg.Server.IdleConnTimeout,
 		Metrics:                metrics,
 		MasterKey:              cfg.Server.MasterKey,
+		MasterKeys:             masterkey.NewRing(cfg.Server.MasterKey, cfg.Server.MasterKeys, cfg.Server.MasterKeyGracePeriod),
 		RateLimiter:            rateLimiter,
 		TokenManager:           tokenManager,
 		ModelManager:           modelManager,
@@ -106,27 +323,111 @@ func main() {
 		Commit:                 Commit,
 		LiteLLMDB:              litellmDBManager,
 		HealthChecker:          healthChecker,
+		ProxyHealth:            proxyHealth,
 		PriceRegistry:          priceRegistry,
 		MaxProviderRetries:     cfg.Server.MaxProviderRetries,
+		SpendPusher:            spendPusher,
+		SLOTracker:             sloTracker,
+		UsageEstimator:         usageEstimator,
+		Workers:                workers,
+		History:                healthhistory.New(),
+		SpendReporter:          spendReporter,
+		SpendStore:             spendStore,
+		QuotaBoosts:            quotaBoosts,
+		InterRouterSecret:      cfg.Server.InterRouterSecret,
+		Scheduler:              fairScheduler,
+		ImageFetcher:           imageFetcher,
+		ContextManager:         contextManager,
+		PostProcess:            postProcess,
+		ConversionCheck:        cfg.ConversionCheck,
+		AttributionHeaders:     cfg.Attribution.Enabled,
+		AttributionKeys:        cfg.Attribution.Keys,
+		PriorityClasses:        cfg.PriorityClasses,
+		Tenants:                cfg.Tenants,
+		StreamCoalescing:       cfg.StreamCoalescing,
+		NonStreaming:           cfg.NonStreaming.Enabled,
+		NonStreamingKeys:       cfg.NonStreaming.Keys,
+		LiteLLMHeaders:         cfg.LiteLLMHeaders.Enabled,
+		RoutingOverrides:       cfg.RoutingOverrides.Enabled,
+		RoutingOverrideKeys:    cfg.RoutingOverrides.Keys,
+		MaxCostPerRequest:      cfg.MaxCostPerRequest,
+		ReasoningRouting:       cfg.ReasoningRouting,
+		ReasoningParams:        cfg.ReasoningParams,
+		ResponseHeaders:        cfg.ResponseHeaders,
+		UpstreamErrors:         cfg.UpstreamErrors,
+		SystemPrompts:          cfg.SystemPrompts,
+		Chargeback:             cfg.Chargeback,
+		ParamNegotiation:       cfg.ParamNegotiation,
+		ChoiceFanOut:           cfg.ChoiceFanOut,
+		ModelPins:              modelPins,
+		RequestSampler:         sampling.New(cfg.Monitoring.RequestSampling),
+		UnsupportedParams:      cfg.Server.UnsupportedParams,
+		DeterministicRouting:   cfg.Server.DeterministicRouting,
+		HideUnavailableModels:  cfg.Server.HideUnavailableModels,
+		SkipZeroCostLogs:       cfg.Server.SkipZeroCostLogs,
+		StreamFirstByteTimeout: cfg.Server.StreamFirstByteTimeout,
+		FallbackTimeout:        cfg.Server.FallbackTimeout,
+		FallbackBodyMultiplier: cfg.Server.FallbackBodyMultiplier,
+		RequestDeadline:        cfg.Server.RequestDeadline,
+		UpstreamCompression:    cfg.Server.UpstreamCompression,
+		BatchStateFile:         cfg.Server.BatchStateFile,
+		ReadOnly:               cfg.Server.ReadOnly,
+		DNSCache:               dnsCache,
+		FaultRules:             faultRules,
+		WrapTransport:          wrapTransport,
 	})
 
 	// ==================== Background Goroutines ====================
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	defer bgCancel()
 
-	var wg sync.WaitGroup
 	var updateMutex sync.Mutex
 
-	startMetricsUpdater(cfg, log, bgCtx, bal, rateLimiter, metrics, &wg, &updateMutex)
-	startProxyStatsUpdater(log, bgCtx, bal, rateLimiter, modelManager, &wg, &updateMutex)
+	startMetricsUpdater(cfg, log, bgCtx, bal, rateLimiter, metrics, workers, &updateMutex)
+	startProxyStatsUpdater(log, bgCtx, bal, rateLimiter, modelManager, proxyHealth, cfg.Server.StaleModelTTL, workers, &updateMutex)
 
 	if litellmDBManager.IsEnabled() {
-		startDBHealthMonitor(log, bgCtx, litellmDBManager, healthChecker, &wg)
+		startDBHealthMonitor(log, bgCtx, litellmDBManager, healthChecker, workers)
+	}
+
+	if spendPusher.IsEnabled() {
+		startSpendPusher(log, bgCtx, spendPusher, cfg.Monitoring.SpendPush, workers)
+	}
+
+	if sloTracker != nil {
+		workers.Go(bgCtx, "slo_tracker", func(ctx context.Context, _ func(error)) {
+			sloTracker.Run(ctx)
+		})
+		log.Info("SLO burn rate alerting enabled",
+			"availability", cfg.Monitoring.SLO.Availability,
+			"latency_threshold", cfg.Monitoring.SLO.LatencyThreshold.String(),
+			"webhook", cfg.Monitoring.SLO.WebhookURL != "",
+		)
+	}
+
+	if spendReporter.IsEnabled() {
+		workers.Go(bgCtx, "spend_reporter", func(ctx context.Context, _ func(error)) {
+			spendReporter.Run(ctx)
+		})
+		log.Info("Daily spend report enabled", "time", cfg.SpendReport.Time+" UTC")
+	}
+
+	startTokenPrewarm(log, bgCtx, tokenManager, cfg.Credentials, workers)
+
+	if cfg.Fail2Ban.StateFile != "" {
+		startFail2BanStateSaver(log, bgCtx, f2b, cfg.Fail2Ban, workers)
+	}
+
+	if usageEstimator != nil {
+		workers.Go(bgCtx, "usage_forecast", func(ctx context.Context, _ func(error)) {
+			usageEstimator.Run(ctx)
+		})
+		log.Info("Usage forecast enabled", "warn_threshold", cfg.UsageForecast.WarnThreshold.String())
 	}
 
 	// Start model price sync loop (only if configured)
 	if cfg.Server.ModelPricesLink != "" {
-		startPriceSyncLoop(cfg.Server.ModelPricesLink, priceRegistry, log, bgCtx, &wg)
+		startPriceSyncLoop(cfg.Server.ModelPricesLink, cfg.Server.ModelPricesCacheFile, priceRegistry, log, bgCtx, workers)
 	}
 
 	// ==================== HTTP Server Setup ====================
@@ -156,6 +457,24 @@ func main() {
 		}
 	}()
 
+	// Admin listener (pprof, goroutine dumps, /debug/state) - only if configured
+	var adminServer *http.Server
+	if cfg.Server.AdminPort > 0 {
+		// No WriteTimeout: CPU profiles and traces stream for the requested duration
+		adminServer = &http.Server{
+			Addr:        fmt.Sprintf(":%d", cfg.Server.AdminPort),
+			Handler:     router.NewAdminHandler(prx, log),
+			ReadTimeout: cfg.Server.ReadTimeout,
+			IdleTimeout: cfg.Server.IdleTimeout,
+		}
+		go func() {
+			log.Info("Admin server starting", "port", cfg.Server.AdminPort)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Admin server failed", "error", err)
+			}
+		}()
+	}
+
 	// ==================== Signal Handling & Graceful Shutdown ====================
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -163,13 +482,31 @@ func main() {
 
 	log.Info("Shutting down server...")
 
-	// Shutdown HTTP server
+	// Shutdown HTTP server. In-flight requests get 30 seconds to finish; the remaining ones
+	// have their upstream calls cancelled and log their spend before the spend loggers stop
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	serverDone := make(chan error, 1)
+	go func() {
+		serverCtx, serverCancel := context.WithTimeout(context.Background(), 45*time.Second)
+		defer serverCancel()
+		serverDone <- server.Shutdown(serverCtx)
+	}()
+	if err := prx.Shutdown(ctx); err != nil {
+		log.Warn("In-flight requests cancelled at shutdown", "error", err)
+	}
+	if err := <-serverDone; err != nil {
 		log.Error("Server forced to shutdown", "error", err)
-		os.Exit(1)
+	}
+
+	if adminServer != nil {
+		// The main server shutdown may have used up ctx
+		adminCtx, adminCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer adminCancel()
+		if err := adminServer.Shutdown(adminCtx); err != nil {
+			log.Error("Admin server forced to shutdown", "error", err)
+		}
 	}
 
 	// Stop background goroutines
@@ -179,7 +516,7 @@ func main() {
 	// Wait for completion
 	doneChan := make(chan struct{})
 	go func() {
-		wg.Wait()
+		workers.Wait()
 		close(doneChan)
 	}()
 
@@ -200,6 +537,19 @@ func main() {
 		}
 	}
 
+	if err := spendStore.Close(); err != nil {
+		log.Error("Local spend log shutdown error", "error", err)
+	}
+
+	// Export the in-memory accounting once the spend logs are flushed
+	if cfg.MetricsExport.Enabled {
+		if err := prx.WriteMetricsExport(cfg.MetricsExport.Path); err != nil {
+			log.Error("Failed to export metrics", "path", cfg.MetricsExport.Path, "error", err)
+		} else {
+			log.Info("Metrics exported", "path", cfg.MetricsExport.Path)
+		}
+	}
+
 	if err := router.CloseErrorLogFiles(); err != nil {
 		log.Error("Failed to close error log files", "error", err)
 	}
@@ -209,6 +559,73 @@ func main() {
 
 // ==================== Helper Functions ====================
 
+// runSecretCommand handles -generate-config-key and -encrypt-value
+func runSecretCommand(generateKey bool, in io.Reader, out io.Writer) error {
+	if generateKey {
+		key, err := config.GenerateConfigKey()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, key)
+		return err
+	}
+
+	key, err := config.ConfigKeyFromEnv()
+	if err != nil {
+		return err
+	}
+	plaintext, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("failed to read value: %w", err)
+	}
+	value := strings.TrimRight(string(plaintext), "\r\n")
+	if value == "" {
+		return fmt.Errorf("empty value on stdin")
+	}
+	encrypted, err := config.EncryptValue(value, key)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, encrypted)
+	return err
+}
+
+// runGenerateCommand handles "generate k8s|compose": it prints deployment manifests for the
+// config at configPath
+func runGenerateCommand(args []string, configPath string, out io.Writer) error {
+	if args[0] != "generate" {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("generate needs a target: %s or %s", deploy.TargetKubernetes, deploy.TargetCompose)
+	}
+
+	fs := flag.NewFlagSet("generate "+args[1], flag.ContinueOnError)
+	opts := deploy.Options{ConfigPath: configPath}
+	fs.StringVar(&opts.Name, "name", deploy.DefaultName, "Resource and service name")
+	fs.StringVar(&opts.Namespace, "namespace", "", "Kubernetes namespace")
+	fs.StringVar(&opts.Image, "image", deploy.DefaultImage, "Container image")
+	fs.IntVar(&opts.Replicas, "replicas", 1, "Kubernetes replicas")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	rawConfig, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	manifests, err := deploy.Generate(args[1], cfg, rawConfig, opts)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, manifests)
+	return err
+}
+
 func logCredentials(log *slog.Logger, credentials []config.CredentialConfig) {
 	log.Info("Loaded credentials", "count", len(credentials))
 	for i, cred := range credentials {
@@ -231,12 +648,91 @@ func initializeBalancer(
 		cfg.Fail2Ban.ErrorCodes, rules)
 
 	rateLimiter := ratelimit.New()
+	if cfg.AdaptiveLimits.Enabled {
+		rateLimiter.EnableAdaptive(ratelimit.AdaptiveConfig{
+			DecreaseFactor:   cfg.AdaptiveLimits.DecreaseFactor,
+			IncreaseStep:     cfg.AdaptiveLimits.IncreaseStep,
+			IncreaseInterval: cfg.AdaptiveLimits.IncreaseInterval,
+			MinFactor:        cfg.AdaptiveLimits.MinFactor,
+			Cooldown:         cfg.AdaptiveLimits.Cooldown,
+		})
+	}
 	bal := balancer.New(cfg.Credentials, f2b, rateLimiter)
 	bal.SetLogger(log)
+	for _, pool := range cfg.CredentialPools {
+		rateLimiter.AddPool(pool.Name, ratelimit.PoolLimits{
+			RPM:           pool.RPM,
+			TPM:           pool.TPM,
+			DailyBudget:   pool.DailyBudget,
+			MonthlyBudget: pool.MonthlyBudget,
+		}, pool.Credentials...)
+	}
 
 	return f2b, rateLimiter, bal
 }
 
+// restoreFail2BanState restores the bans and failure counters saved in fail2ban.state_file,
+// so credentials banned before a restart are not hit by all traffic at once
+func restoreFail2BanState(cfg *config.Config, log *slog.Logger, f2b *fail2ban.Fail2Ban) {
+	if cfg.Fail2Ban.StateFile == "" {
+		return
+	}
+
+	state, err := fail2ban.LoadState(cfg.Fail2Ban.StateFile)
+	if err != nil {
+		log.Warn("Failed to restore fail2ban state, starting without bans",
+			"state_file", cfg.Fail2Ban.StateFile, "error", err)
+		return
+	}
+
+	known := make(map[string]bool, len(cfg.Credentials))
+	for _, cred := range cfg.Credentials {
+		known[cred.Name] = true
+	}
+	bans, failures := f2b.Restore(state, func(credential string) bool { return known[credential] })
+	log.Info("Fail2ban state restored",
+		"state_file", cfg.Fail2Ban.StateFile,
+		"bans", bans,
+		"failure_counters", failures,
+		"saved_at", state.SavedAt,
+	)
+}
+
+// startFail2BanStateSaver periodically writes the fail2ban state to state_file,
+// and once more when the background context is cancelled on shutdown
+func startFail2BanStateSaver(
+	log *slog.Logger,
+	bgCtx context.Context,
+	f2b *fail2ban.Fail2Ban,
+	cfg config.Fail2BanConfig,
+	workers *worker.Supervisor,
+) {
+	save := func() error {
+		err := fail2ban.SaveState(cfg.StateFile, f2b.Snapshot())
+		if err != nil {
+			log.Warn("Failed to save fail2ban state", "state_file", cfg.StateFile, "error", err)
+		}
+		return err
+	}
+
+	workers.Go(bgCtx, "fail2ban_state_saver", func(ctx context.Context, report func(error)) {
+		ticker := time.NewTicker(cfg.StateSaveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				report(save())
+				return
+			case <-ticker.C:
+				report(save())
+			}
+		}
+	})
+
+	log.Info("Fail2ban state saver started", "state_file", cfg.StateFile, "interval", cfg.StateSaveInterval)
+}
+
 func convertFailBanRules(
 	rules []config.ErrorCodeRuleConfig,
 	defaultBanDuration time.Duration,
@@ -285,7 +781,8 @@ func initializeModelManager(
 			if modelManager.HasModel(cred.Name, model.ID) {
 				rpm := modelManager.GetModelRPMForCredential(model.ID, cred.Name)
 				tpm := modelManager.GetModelTPMForCredential(model.ID, cred.Name)
-				rateLimiter.AddModelWithTPM(cred.Name, model.ID, rpm, tpm)
+				burst := modelManager.GetModelRPMBurstForCredential(model.ID, cred.Name)
+				rateLimiter.AddModelWithBurst(cred.Name, model.ID, rpm, tpm, burst)
 				log.Debug("Initialized model rate limiters",
 					"credential", cred.Name,
 					"model", model.ID,
@@ -300,7 +797,7 @@ func initializeModelManager(
 	return modelManager
 }
 
-func initializeLiteLLMDB(cfg *config.Config, log *slog.Logger) litellmdb.Manager {
+func initializeLiteLLMDB(cfg *config.Config, quotaBoosts *quota.Store, log *slog.Logger) litellmdb.Manager {
 	if !cfg.LiteLLMDB.Enabled {
 		log.Info("LiteLLM DB integration disabled - using NoopManager (no security checks)")
 		return litellmdb.NewNoopManager()
@@ -309,17 +806,25 @@ func initializeLiteLLMDB(cfg *config.Config, log *slog.Logger) litellmdb.Manager
 	log.Info("Initializing LiteLLM DB integration...", "is_required", cfg.LiteLLMDB.IsRequired)
 
 	litellmCfg := &litellmdb.Config{
-		DatabaseURL:         cfg.LiteLLMDB.DatabaseURL,
-		MaxConns:            int32(cfg.LiteLLMDB.MaxConns),
-		MinConns:            int32(cfg.LiteLLMDB.MinConns),
-		HealthCheckInterval: cfg.LiteLLMDB.HealthCheckInterval,
-		ConnectTimeout:      cfg.LiteLLMDB.ConnectTimeout,
-		AuthCacheTTL:        cfg.LiteLLMDB.AuthCacheTTL,
-		AuthCacheSize:       cfg.LiteLLMDB.AuthCacheSize,
-		LogQueueSize:        cfg.LiteLLMDB.LogQueueSize,
-		LogBatchSize:        cfg.LiteLLMDB.LogBatchSize,
-		LogFlushInterval:    cfg.LiteLLMDB.LogFlushInterval,
-		Logger:              log,
+		DatabaseURL:            cfg.LiteLLMDB.DatabaseURL,
+		ReplicaURL:             cfg.LiteLLMDB.ReplicaURL,
+		MaxConns:               int32(cfg.LiteLLMDB.MaxConns),
+		MinConns:               int32(cfg.LiteLLMDB.MinConns),
+		HealthCheckInterval:    cfg.LiteLLMDB.HealthCheckInterval,
+		ConnectTimeout:         cfg.LiteLLMDB.ConnectTimeout,
+		HealthFailureThreshold: cfg.LiteLLMDB.HealthFailureThreshold,
+		AuthCacheTTL:           cfg.LiteLLMDB.AuthCacheTTL,
+		AuthCacheSize:          cfg.LiteLLMDB.AuthCacheSize,
+		LogQueueSize:           cfg.LiteLLMDB.LogQueueSize,
+		LogBatchSize:           cfg.LiteLLMDB.LogBatchSize,
+		LogFlushInterval:       cfg.LiteLLMDB.LogFlushInterval,
+		Logger:                 log,
+	}
+	if quotaBoosts != nil {
+		litellmCfg.BudgetBoost = func(info *litellmdb.TokenInfo) litellmdb.BudgetBoost {
+			f := quotaBoosts.Factors(info.KeyAlias, info.Token, info.TeamID)
+			return litellmdb.BudgetBoost{Key: f.KeyBudget - 1, Team: f.TeamBudget - 1}
+		}
 	}
 
 	manager, err := litellmdb.New(litellmCfg)
@@ -344,20 +849,128 @@ func initializeLiteLLMDB(cfg *config.Config, log *slog.Logger) litellmdb.Manager
 	return manager
 }
 
-// loadAndUpdateModelPrices loads model prices and updates the registry
+// initializeSpendStore opens the local spend log; returns nil if it is disabled or not needed
+// because spend logs are written to LiteLLM DB
+func initializeSpendStore(cfg *config.Config, litellmDBManager litellmdb.Manager, log *slog.Logger) *spendstore.Store {
+	if !cfg.LocalSpendLog.Enabled {
+		return nil
+	}
+	if litellmDBManager.IsEnabled() {
+		log.Info("Local spend log not used: spend logs are written to LiteLLM DB",
+			"hint", "run with -backfill-spend to push the local spend log into LiteLLM DB")
+		return nil
+	}
+
+	store, err := spendstore.Open(cfg.LocalSpendLog, log)
+	if err != nil {
+		log.Error("Failed to open local spend log", "error", err, "path", cfg.LocalSpendLog.Path)
+		os.Exit(1)
+	}
+	log.Info("Local spend log enabled", "path", cfg.LocalSpendLog.Path, "retention", cfg.LocalSpendLog.Retention)
+	return store
+}
+
+// runSpendBackfill handles -backfill-spend: pushes the entries of the local spend log not yet
+// pushed into LiteLLM DB. Entries already in LiteLLM DB are skipped, so it can be repeated.
+func runSpendBackfill(cfg *config.Config, log *slog.Logger) error {
+	if !cfg.LocalSpendLog.Enabled {
+		return fmt.Errorf("local_spend_log is disabled")
+	}
+	if !cfg.LiteLLMDB.Enabled {
+		return fmt.Errorf("litellm_db is disabled")
+	}
+
+	store, err := spendstore.Open(cfg.LocalSpendLog, log)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	manager, err := litellmdb.New(&litellmdb.Config{
+		DatabaseURL:      cfg.LiteLLMDB.DatabaseURL,
+		MaxConns:         int32(cfg.LiteLLMDB.MaxConns),
+		MinConns:         int32(cfg.LiteLLMDB.MinConns),
+		ConnectTimeout:   cfg.LiteLLMDB.ConnectTimeout,
+		LogQueueSize:     cfg.LiteLLMDB.LogQueueSize,
+		LogBatchSize:     cfg.LiteLLMDB.LogBatchSize,
+		LogFlushInterval: cfg.LiteLLMDB.LogFlushInterval,
+		Logger:           log,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to LiteLLM DB: %w", err)
+	}
+
+	log.Info("Backfilling local spend log into LiteLLM DB", "path", cfg.LocalSpendLog.Path)
+	var handed uint64
+	logSpend := func(ctx context.Context, entry *litellmdb.SpendLogEntry) error {
+		handed++
+		return manager.LogSpend(ctx, entry)
+	}
+	pushed, err := store.Backfill(context.Background(), logSpend, func(ctx context.Context) error {
+		// Shutdown flushes the queued entries
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		if err := manager.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if stats := manager.SpendLoggerStats(); stats.Written < handed {
+			return fmt.Errorf("only %d of %d spend log entries written (%d errors)", stats.Written, handed, stats.Errors)
+		}
+		return nil
+	})
+	if err != nil {
+		_ = manager.Shutdown(context.Background())
+		return err
+	}
+	log.Info("Spend backfill complete", "entries", pushed)
+	return nil
+}
+
+// restorePriceSnapshot loads the prices saved in server.model_prices_cache_file, so spend is
+// priced while model_prices_link cannot be reached after a restart
+func restorePriceSnapshot(cacheFile string, registry *models.ModelPriceRegistry, log *slog.Logger) {
+	if cacheFile == "" {
+		return
+	}
+	snapshot, err := models.LoadPriceSnapshot(cacheFile)
+	if err != nil {
+		log.Warn("Failed to restore model prices snapshot", "cache_file", cacheFile, "error", err)
+		return
+	}
+	if snapshot == nil {
+		return
+	}
+	registry.Restore(*snapshot)
+	monitoring.ModelPricesSyncAge.Set(registry.SyncStatus().Age(time.Now()).Seconds())
+	log.Info("Model prices restored from snapshot",
+		"cache_file", cacheFile,
+		"count", len(snapshot.Prices),
+		"synced_at", snapshot.SyncedAt,
+	)
+}
+
+// loadAndUpdateModelPrices loads model prices and updates the registry. Successfully loaded
+// prices are saved to cacheFile ("" = not persisted); on failure the current prices are kept.
 func loadAndUpdateModelPrices(
 	link string,
+	cacheFile string,
 	registry *models.ModelPriceRegistry,
 	log *slog.Logger,
 	context string, // "startup" or "update" for logging
 ) error {
+	defer func() {
+		monitoring.ModelPricesSyncAge.Set(registry.SyncStatus().Age(time.Now()).Seconds())
+	}()
+
 	prices, err := models.LoadModelPrices(link)
 	if err != nil {
+		registry.RecordSyncError(err)
 		logMessage := "Failed to load model prices"
 		if context != "" {
 			logMessage += " during " + context
 		}
-		log.Warn(logMessage, "error", err)
+		status := registry.SyncStatus()
+		log.Warn(logMessage, "error", err, "models", status.Models, "last_sync", status.LastSync)
 		return err
 	}
 	registry.Update(prices)
@@ -366,27 +979,87 @@ func loadAndUpdateModelPrices(
 	} else {
 		log.Debug("Model prices updated", "count", len(prices))
 	}
+
+	if cacheFile != "" {
+		if err := models.SavePriceSnapshot(cacheFile, registry.Snapshot()); err != nil {
+			log.Warn("Failed to save model prices snapshot", "cache_file", cacheFile, "error", err)
+		}
+	}
 	return nil
 }
 
+// startSpendPusher starts a background goroutine that periodically pushes spend counters to the Pushgateway
+func startSpendPusher(
+	log *slog.Logger,
+	bgCtx context.Context,
+	spendPusher *monitoring.SpendPusher,
+	pushCfg config.SpendPushConfig,
+	workers *worker.Supervisor,
+) {
+	workers.Go(bgCtx, "spend_pusher", func(ctx context.Context, _ func(error)) {
+		spendPusher.Run(ctx)
+	})
+
+	log.Info("Spend push to Pushgateway enabled",
+		"url", pushCfg.PushgatewayURL,
+		"job", pushCfg.Job,
+		"interval", pushCfg.Interval.String(),
+	)
+}
+
+// startTokenPrewarm fetches OAuth2 tokens for all service account Vertex AI credentials in the
+// background and keeps them refreshed, so first requests do not wait for the token exchange
+func startTokenPrewarm(
+	log *slog.Logger,
+	bgCtx context.Context,
+	tokenManager *auth.VertexTokenManager,
+	credentials []config.CredentialConfig,
+	workers *worker.Supervisor,
+) {
+	var creds []auth.TokenCredential
+	for _, cred := range credentials {
+		// api_key (express mode) credentials do not use OAuth2 tokens
+		if cred.Type == config.ProviderTypeVertexAI && (cred.CredentialsFile != "" || cred.CredentialsJSON != "") {
+			creds = append(creds, auth.TokenCredential{
+				Name:            cred.Name,
+				CredentialsFile: cred.CredentialsFile,
+				CredentialsJSON: cred.CredentialsJSON,
+			})
+		}
+	}
+	if len(creds) == 0 {
+		return
+	}
+
+	workers.Go(bgCtx, "token_refresh", func(ctx context.Context, report func(error)) {
+		start := time.Now()
+		err := tokenManager.Prewarm(ctx, creds)
+		if err != nil {
+			log.Warn("Vertex AI token prewarm incomplete", "credentials", len(creds), "error", err)
+		} else {
+			log.Info("Vertex AI tokens prewarmed", "credentials", len(creds), "duration", time.Since(start).String())
+		}
+		report(err)
+		tokenManager.RunRefreshLoop(ctx, auth.DefaultProactiveRefreshInterval)
+	})
+}
+
 // startPriceSyncLoop starts a background goroutine that periodically syncs model prices
 func startPriceSyncLoop(
 	modelPricesLink string,
+	cacheFile string,
 	registry *models.ModelPriceRegistry,
 	log *slog.Logger,
 	bgCtx context.Context,
-	wg *sync.WaitGroup,
+	workers *worker.Supervisor,
 ) {
 	if modelPricesLink == "" {
 		return
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
+	workers.Go(bgCtx, "price_sync", func(ctx context.Context, report func(error)) {
 		// Load prices immediately on startup
-		_ = loadAndUpdateModelPrices(modelPricesLink, registry, log, "startup")
+		report(loadAndUpdateModelPrices(modelPricesLink, cacheFile, registry, log, "startup"))
 
 		// Periodic update loop (every 5 minutes)
 		ticker := time.NewTicker(5 * time.Minute)
@@ -394,14 +1067,14 @@ func startPriceSyncLoop(
 
 		for {
 			select {
-			case <-bgCtx.Done():
+			case <-ctx.Done():
 				log.Debug("Model prices sync loop stopped")
 				return
 			case <-ticker.C:
-				_ = loadAndUpdateModelPrices(modelPricesLink, registry, log, "update")
+				report(loadAndUpdateModelPrices(modelPricesLink, cacheFile, registry, log, "update"))
 			}
 		}
-	}()
+	})
 
 	log.Debug("Model price sync loop started", "interval", "5 minutes", "link", modelPricesLink)
 }
@@ -413,30 +1086,31 @@ func startMetricsUpdater(
 	bal *balancer.RoundRobin,
 	rateLimiter *ratelimit.RPMLimiter,
 	metrics *monitoring.Metrics,
-	wg *sync.WaitGroup,
+	workers *worker.Supervisor,
 	updateMutex *sync.Mutex,
 ) {
 	if !cfg.Monitoring.PrometheusEnabled {
 		return
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	workers.Go(bgCtx, "metrics_updater", func(ctx context.Context, report func(error)) {
 		ticker := time.NewTicker(10 * time.Second)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-bgCtx.Done():
+			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				updateMutex.Lock()
-				updateMetrics(bal, rateLimiter, metrics)
-				updateMutex.Unlock()
+				func() {
+					updateMutex.Lock()
+					defer updateMutex.Unlock()
+					updateMetrics(bal, rateLimiter, metrics)
+				}()
+				report(nil)
 			}
 		}
-	}()
+	})
 
 	log.Info("Metrics updater started (updates every 10 seconds)")
 }
@@ -475,31 +1149,34 @@ func startProxyStatsUpdater(
 	bal *balancer.RoundRobin,
 	rateLimiter *ratelimit.RPMLimiter,
 	modelManager *models.Manager,
-	wg *sync.WaitGroup,
+	proxyHealth *proxyhealth.Tracker,
+	staleModelTTL time.Duration,
+	workers *worker.Supervisor,
 	updateMutex *sync.Mutex,
 ) {
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
+	workers.Go(bgCtx, "proxy_stats_updater", func(ctx context.Context, report func(error)) {
 		// Update immediately on startup
-		modelupdate.UpdateAllProxyCredentials(bgCtx, bal, rateLimiter, log, modelManager, updateMutex)
+		modelupdate.UpdateAllProxyCredentials(ctx, bal, rateLimiter, log, modelManager, updateMutex, proxyHealth)
+		report(nil)
 
-		// Then update periodically
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
+		// Then update periodically with jitter
+		timer := time.NewTimer(modelupdate.NextUpdateDelay(modelupdate.UpdateInterval))
+		defer timer.Stop()
 
 		for {
 			select {
-			case <-bgCtx.Done():
+			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				modelupdate.UpdateAllProxyCredentials(bgCtx, bal, rateLimiter, log, modelManager, updateMutex)
+			case <-timer.C:
+				modelupdate.UpdateAllProxyCredentials(ctx, bal, rateLimiter, log, modelManager, updateMutex, proxyHealth)
+				modelupdate.PruneStaleModels(bal, rateLimiter, modelManager, updateMutex, staleModelTTL, log)
+				report(nil)
+				timer.Reset(modelupdate.NextUpdateDelay(modelupdate.UpdateInterval))
 			}
 		}
-	}()
+	})
 
-	log.Info("Proxy stats updater started (updates every 30 seconds)")
+	log.Info("Proxy stats updater started", "interval", modelupdate.UpdateInterval, "jitter", "10%", "stale_model_ttl", staleModelTTL)
 }
 
 func startDBHealthMonitor(
@@ -507,7 +1184,7 @@ func startDBHealthMonitor(
 	bgCtx context.Context,
 	dbManager litellmdb.Manager,
 	healthChecker *health.DBHealthChecker,
-	wg *sync.WaitGroup,
+	workers *worker.Supervisor,
 ) {
 	monitorCfg := &health.MonitorConfig{
 		CheckInterval:    30 * time.Second,
@@ -517,11 +1194,9 @@ func startDBHealthMonitor(
 
 	monitor := health.NewMonitor(monitorCfg, healthChecker, dbManager)
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		monitor.Start(bgCtx)
-	}()
+	workers.Go(bgCtx, "db_health_monitor", func(ctx context.Context, _ func(error)) {
+		monitor.Start(ctx)
+	})
 
 	log.Info("LiteLLM DB health monitor started (checks every 30 seconds)")
 }
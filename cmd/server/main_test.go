@@ -1,10 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/modelupdate"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSplitCredentialModel(t *testing.T) {
@@ -42,3 +51,76 @@ func TestSplitCredentialModel(t *testing.T) {
 		})
 	}
 }
+
+func TestRunSecretCommand(t *testing.T) {
+	var keyOut bytes.Buffer
+	require.NoError(t, runSecretCommand(true, nil, &keyOut))
+	key := strings.TrimSpace(keyOut.String())
+	t.Setenv(config.ConfigKeyEnv, key)
+
+	var encOut bytes.Buffer
+	require.NoError(t, runSecretCommand(false, strings.NewReader("sk-secret\n"), &encOut))
+	encrypted := strings.TrimSpace(encOut.String())
+	assert.True(t, config.IsEncryptedValue(encrypted))
+
+	decodedKey, err := config.ConfigKeyFromEnv()
+	require.NoError(t, err)
+	plaintext, err := config.DecryptValue(encrypted, decodedKey)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+
+	assert.Error(t, runSecretCommand(false, strings.NewReader("\n"), &encOut))
+}
+
+func TestRunGenerateCommand(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`server:
+  port: 8080
+  master_key: sk-master
+credentials:
+  - name: openai
+    type: openai
+    api_key: sk-test
+    base_url: https://api.openai.com
+`), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, runGenerateCommand([]string{"generate", "k8s", "-replicas", "2", "-namespace", "ai"}, configPath, &out))
+	assert.Contains(t, out.String(), "kind: Deployment")
+	assert.Contains(t, out.String(), "replicas: 2")
+	assert.Contains(t, out.String(), "namespace: ai")
+
+	out.Reset()
+	require.NoError(t, runGenerateCommand([]string{"generate", "compose"}, configPath, &out))
+	assert.Contains(t, out.String(), `"8080:8080"`)
+
+	assert.ErrorContains(t, runGenerateCommand([]string{"deploy"}, configPath, &out), "unknown command")
+	assert.ErrorContains(t, runGenerateCommand([]string{"generate"}, configPath, &out), "needs a target")
+	assert.ErrorContains(t, runGenerateCommand([]string{"generate", "helm"}, configPath, &out), "unknown target")
+	assert.Error(t, runGenerateCommand([]string{"generate", "k8s"}, filepath.Join(t.TempDir(), "missing.yaml"), &out))
+}
+
+func TestLoadAndUpdateModelPrices_Snapshot(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	link := filepath.Join(dir, "prices.json")
+	cacheFile := filepath.Join(dir, "prices-cache.json")
+	require.NoError(t, os.WriteFile(link, []byte(`{"gpt-4o":{"input_cost_per_token":0.001}}`), 0o644))
+
+	registry := models.NewModelPriceRegistry()
+	require.NoError(t, loadAndUpdateModelPrices(link, cacheFile, registry, log, "startup"))
+	require.FileExists(t, cacheFile)
+
+	// The price source is gone after a restart: the snapshot keeps spend priced
+	require.NoError(t, os.Remove(link))
+	restarted := models.NewModelPriceRegistry()
+	restorePriceSnapshot(cacheFile, restarted, log)
+	assert.Error(t, loadAndUpdateModelPrices(link, cacheFile, restarted, log, "startup"))
+
+	require.NotNil(t, restarted.GetPrice("gpt-4o"))
+	status := restarted.SyncStatus()
+	assert.True(t, status.Restored)
+	assert.Equal(t, registry.LastUpdate().UTC(), status.LastSync.UTC())
+	assert.NotEmpty(t, status.LastError)
+	require.FileExists(t, cacheFile, "a failed sync keeps the snapshot")
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultProactiveRefreshInterval is how often RunRefreshLoop checks registered credentials.
+// It must stay well below the 5 minute refresh window so tokens are renewed before requests see them expire.
+const DefaultProactiveRefreshInterval = time.Minute
+
+// TokenCredential identifies a service account credential whose token is kept warm
+type TokenCredential struct {
+	Name            string
+	CredentialsFile string
+	CredentialsJSON string
+}
+
+// Register adds credentials to the proactive refresh set without fetching tokens.
+// Credentials that obtain a token through GetToken are registered automatically.
+func (tm *VertexTokenManager) Register(creds ...TokenCredential) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for _, cred := range creds {
+		tm.registered[cred.Name] = cred
+	}
+}
+
+// Prewarm registers the credentials and fetches their tokens concurrently, so the first
+// request per credential does not wait for the OAuth2 exchange.
+// Failures are logged and returned joined; they are retried by RunRefreshLoop.
+func (tm *VertexTokenManager) Prewarm(ctx context.Context, creds []TokenCredential) error {
+	tm.Register(creds...)
+	return tm.fetchTokens(ctx, creds, "Failed to prewarm Vertex AI token")
+}
+
+// RunRefreshLoop renews the tokens of all registered credentials before they expire,
+// checking every interval until ctx is cancelled or the manager is stopped.
+// GetToken only contacts Google when a token is missing or within the refresh window.
+func (tm *VertexTokenManager) RunRefreshLoop(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultProactiveRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tm.stopChan:
+			return
+		case <-ticker.C:
+			tm.refreshRegistered(ctx)
+		}
+	}
+}
+
+func (tm *VertexTokenManager) refreshRegistered(ctx context.Context) {
+	tm.mu.RLock()
+	creds := make([]TokenCredential, 0, len(tm.registered))
+	for _, cred := range tm.registered {
+		creds = append(creds, cred)
+	}
+	tm.mu.RUnlock()
+
+	_ = tm.fetchTokens(ctx, creds, "Proactive Vertex AI token refresh failed")
+}
+
+// fetchTokens calls GetToken for each credential in parallel, logging failures with failureMsg
+func (tm *VertexTokenManager) fetchTokens(ctx context.Context, creds []TokenCredential, failureMsg string) error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	for _, cred := range creds {
+		wg.Add(1)
+		go func(cred TokenCredential) {
+			defer wg.Done()
+			if _, err := tm.GetToken(ctx, cred.Name, cred.CredentialsFile, cred.CredentialsJSON); err != nil {
+				tm.logger.Warn(failureMsg, "credential", cred.Name, "error", err)
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", cred.Name, err))
+				mu.Unlock()
+			}
+		}(cred)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/oauth2"
+)
+
+func TestPrewarm_RegistersAndReportsFailures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	tm := NewVertexTokenManager(logger)
+	defer tm.Stop()
+
+	failures := monitoring.VertexTokenRefreshesTotal.WithLabelValues("prewarm-invalid", "failure")
+	before := testutil.ToFloat64(failures)
+
+	err := tm.Prewarm(context.Background(), []TokenCredential{
+		{Name: "prewarm-invalid", CredentialsJSON: "not json"},
+		{Name: "prewarm-missing", CredentialsFile: "/nonexistent/sa.json"},
+	})
+	if err == nil {
+		t.Fatal("expected prewarm error for invalid credentials")
+	}
+	for _, name := range []string{"prewarm-invalid", "prewarm-missing"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error should name credential %s: %v", name, err)
+		}
+	}
+
+	tm.mu.RLock()
+	registered := len(tm.registered)
+	tm.mu.RUnlock()
+	if registered != 2 {
+		t.Errorf("failed credentials must stay registered for retry, got %d", registered)
+	}
+	if got := testutil.ToFloat64(failures) - before; got != 1 {
+		t.Errorf("expected 1 failed refresh recorded, got %v", got)
+	}
+}
+
+func TestRefreshRegistered_RenewsTokensBeforeExpiry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	tm := NewVertexTokenManager(logger)
+	defer tm.Stop()
+
+	nearExpiry := time.Now().UTC().Add(3 * time.Minute)
+	farExpiry := time.Now().UTC().Add(time.Hour)
+	newExpiry := time.Now().UTC().Add(2 * time.Hour)
+
+	expiring := &mockTokenSource{token: &oauth2.Token{AccessToken: "renewed", Expiry: newExpiry}}
+	fresh := &mockTokenSource{token: &oauth2.Token{AccessToken: "unused", Expiry: newExpiry}}
+
+	tm.mu.Lock()
+	tm.tokens["expiring"] = &cachedToken{
+		token:       &oauth2.Token{AccessToken: "old", Expiry: nearExpiry},
+		tokenSource: expiring,
+		expiresAt:   nearExpiry,
+	}
+	tm.tokens["fresh"] = &cachedToken{
+		token:       &oauth2.Token{AccessToken: "current", Expiry: farExpiry},
+		tokenSource: fresh,
+		expiresAt:   farExpiry,
+	}
+	tm.mu.Unlock()
+	tm.Register(TokenCredential{Name: "expiring"}, TokenCredential{Name: "fresh"})
+
+	successes := monitoring.VertexTokenRefreshesTotal.WithLabelValues("expiring", "success")
+	before := testutil.ToFloat64(successes)
+
+	tm.refreshRegistered(context.Background())
+
+	if expiring.callCount != 1 {
+		t.Errorf("expected expiring token to be refreshed once, got %d", expiring.callCount)
+	}
+	if fresh.callCount != 0 {
+		t.Errorf("fresh token should not be refreshed, got %d calls", fresh.callCount)
+	}
+	if expiry, _ := tm.GetTokenExpiry("expiring"); !expiry.Equal(newExpiry) {
+		t.Errorf("expected renewed expiry %v, got %v", newExpiry, expiry)
+	}
+	if got := testutil.ToFloat64(successes) - before; got != 1 {
+		t.Errorf("expected 1 successful refresh recorded, got %v", got)
+	}
+}
+
+func TestRunRefreshLoop_StopsWithContext(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	tm := NewVertexTokenManager(logger)
+	defer tm.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tm.RunRefreshLoop(ctx, time.Millisecond)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refresh loop did not stop after context cancellation")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
@@ -34,7 +35,8 @@ type tokenRefreshResponse struct {
 type VertexTokenManager struct {
 	mu                  sync.RWMutex
 	tokens              map[string]*cachedToken
-	credentials         map[string][]byte // Cache for credentials
+	credentials         map[string][]byte          // Cache for credentials
+	registered          map[string]TokenCredential // Credentials kept warm by RunRefreshLoop
 	logger              *slog.Logger
 	tokenRefresh        time.Duration
 	tokenRefreshTimeout time.Duration // Timeout for token refresh operations
@@ -59,6 +61,7 @@ func NewVertexTokenManager(logger *slog.Logger) *VertexTokenManager {
 	tm := &VertexTokenManager{
 		tokens:              make(map[string]*cachedToken),
 		credentials:         make(map[string][]byte),
+		registered:          make(map[string]TokenCredential),
 		logger:              logger,
 		tokenRefresh:        5 * time.Minute,  // Refresh 5 minutes before expiry
 		tokenRefreshTimeout: 30 * time.Second, // Default timeout for refresh operations
@@ -71,6 +74,32 @@ func NewVertexTokenManager(logger *slog.Logger) *VertexTokenManager {
 	return tm
 }
 
+// TokenManagerStats summarizes token cache and refresh queue for diagnostics
+type TokenManagerStats struct {
+	CachedTokens    int `json:"cached_tokens"`
+	RefreshQueueLen int `json:"refresh_queue_len"`
+	RefreshQueueCap int `json:"refresh_queue_cap"`
+	RefreshInFlight int `json:"refresh_in_flight"`
+}
+
+// Stats returns current token cache size and refresh queue depth
+func (tm *VertexTokenManager) Stats() TokenManagerStats {
+	tm.mu.RLock()
+	cached := len(tm.tokens)
+	tm.mu.RUnlock()
+
+	tm.refreshingMu.Lock()
+	inFlight := len(tm.refreshing)
+	tm.refreshingMu.Unlock()
+
+	return TokenManagerStats{
+		CachedTokens:    cached,
+		RefreshQueueLen: len(tm.refreshRequests),
+		RefreshQueueCap: cap(tm.refreshRequests),
+		RefreshInFlight: inFlight,
+	}
+}
+
 // GetToken returns a valid OAuth2 token for the given credential.
 // It loads credentials from file or JSON string and caches the token.
 //
@@ -90,7 +119,10 @@ func NewVertexTokenManager(logger *slog.Logger) *VertexTokenManager {
 // Response channel buffering: Each response channel has a buffer size of 1, which allows
 // the worker to send responses non-blocking. If a waiter has already given up due to timeout,
 // the response will still be sent but go unread.
-func (tm *VertexTokenManager) GetToken(credentialName, credentialsFile, credentialsJSON string) (string, error) {
+//
+// ctx bounds the wait for the token (at most the token refresh timeout). The refresh itself is
+// shared by the coalesced callers and is not cancelled when one of them gives up.
+func (tm *VertexTokenManager) GetToken(ctx context.Context, credentialName, credentialsFile, credentialsJSON string) (string, error) {
 	if tm.stopped.Load() {
 		return "", fmt.Errorf("token manager is stopped")
 	}
@@ -126,7 +158,8 @@ func (tm *VertexTokenManager) GetToken(credentialName, credentialsFile, credenti
 	// Only allocate channel if we actually need to refresh (avoids allocation pressure)
 	responseChan := make(chan tokenRefreshResponse, 1)
 
-	ctx, cancel := context.WithTimeout(context.Background(), tm.tokenRefreshTimeout)
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, tm.tokenRefreshTimeout)
 	defer cancel()
 
 	tm.refreshingMu.Lock()
@@ -163,7 +196,7 @@ func (tm *VertexTokenManager) GetToken(credentialName, credentialsFile, credenti
 					}
 				}
 			}
-			return "", fmt.Errorf("token refresh timeout")
+			return "", refreshWaitError(parent)
 		}
 	} else {
 		// Coalesce with existing refresh
@@ -181,8 +214,17 @@ func (tm *VertexTokenManager) GetToken(credentialName, credentialsFile, credenti
 		// Coalescing callers that timeout must be removed so processRefreshRequest doesn't
 		// attempt to send response to an abandoned channel.
 		tm.removeWaitingChan(credentialName, responseChan)
-		return "", fmt.Errorf("token refresh timeout")
+		return "", refreshWaitError(parent)
+	}
+}
+
+// refreshWaitError is the error of a token wait that ended before the refresh: the caller's
+// context error if it ended first, otherwise the refresh timeout
+func refreshWaitError(parent context.Context) error {
+	if err := parent.Err(); err != nil {
+		return fmt.Errorf("token refresh cancelled: %w", err)
 	}
+	return fmt.Errorf("token refresh timeout")
 }
 
 // refreshWorker is a background goroutine that handles token refresh requests
@@ -263,6 +305,12 @@ func (tm *VertexTokenManager) processRefreshRequest(req tokenRefreshRequest) {
 		token, err = tm.createNewToken(req.credentialName, req.credentialsFile, req.credentialsJSON)
 	}
 
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	monitoring.VertexTokenRefreshesTotal.WithLabelValues(req.credentialName, result).Inc()
+
 	// Send response to all waiting goroutines
 	tm.refreshingMu.Lock()
 	waitingChans, exists := tm.refreshing[req.credentialName]
@@ -350,13 +398,18 @@ func (tm *VertexTokenManager) createNewToken(credentialName, credentialsFile, cr
 		return "", err
 	}
 
-	// Cache the token
+	// Cache the token and keep it warm
 	tm.mu.Lock()
 	tm.tokens[credentialName] = &cachedToken{
 		token:       token,
 		tokenSource: creds.TokenSource,
 		expiresAt:   token.Expiry,
 	}
+	tm.registered[credentialName] = TokenCredential{
+		Name:            credentialName,
+		CredentialsFile: credentialsFile,
+		CredentialsJSON: credentialsJSON,
+	}
 	tm.mu.Unlock()
 
 	tm.logger.Info("Vertex AI token created",
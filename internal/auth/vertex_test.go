@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
 	"testing"
@@ -65,7 +67,7 @@ func TestGetToken_InvalidJSON(t *testing.T) {
 	tm := NewVertexTokenManager(logger)
 	defer tm.Stop()
 
-	_, err := tm.GetToken("test", "", "invalid-json")
+	_, err := tm.GetToken(context.Background(), "test", "", "invalid-json")
 	if err == nil {
 		t.Error("expected error for invalid JSON, got nil")
 	}
@@ -84,7 +86,7 @@ func TestGetToken_InvalidServiceAccountType(t *testing.T) {
 	}
 	b, _ := json.Marshal(invalidSA)
 
-	_, err := tm.GetToken("test", "", string(b))
+	_, err := tm.GetToken(context.Background(), "test", "", string(b))
 	if err == nil {
 		t.Error("expected error for non-service-account type, got nil")
 	}
@@ -95,7 +97,7 @@ func TestGetToken_NoCredentials(t *testing.T) {
 	tm := NewVertexTokenManager(logger)
 	defer tm.Stop()
 
-	_, err := tm.GetToken("test", "", "")
+	_, err := tm.GetToken(context.Background(), "test", "", "")
 	if err == nil {
 		t.Error("expected error for missing credentials, got nil")
 	}
@@ -109,7 +111,7 @@ func TestGetToken_FileNotFound(t *testing.T) {
 	tm := NewVertexTokenManager(logger)
 	defer tm.Stop()
 
-	_, err := tm.GetToken("test", "/nonexistent/path.json", "")
+	_, err := tm.GetToken(context.Background(), "test", "/nonexistent/path.json", "")
 	if err == nil {
 		t.Error("expected error for non-existent file, got nil")
 	}
@@ -235,7 +237,7 @@ func TestGetToken_CachedTokenReuse(t *testing.T) {
 
 	// Get the same token - should reuse cached token (valid for 1 hour)
 	// No credentials needed if cached token is still valid
-	token, err := tm.GetToken("test", "", "")
+	token, err := tm.GetToken(context.Background(), "test", "", "")
 	if err != nil {
 		t.Errorf("expected no error for cached valid token, got: %v", err)
 	}
@@ -303,7 +305,7 @@ func TestGetToken_TokenRefreshError(t *testing.T) {
 	tm.mu.Unlock()
 
 	// Attempt to get expired token - should trigger refresh and fail
-	_, err := tm.GetToken("test", "", "")
+	_, err := tm.GetToken(context.Background(), "test", "", "")
 	if err == nil {
 		t.Error("expected error when token refresh fails")
 	}
@@ -340,7 +342,7 @@ func TestGetToken_NearExpiry(t *testing.T) {
 	tm.mu.Unlock()
 
 	// Get token - should trigger refresh since token expires in 3 min (within 5 min buffer)
-	token, err := tm.GetToken("test", "", "")
+	token, err := tm.GetToken(context.Background(), "test", "", "")
 	if err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
@@ -379,7 +381,7 @@ func TestGetToken_ConcurrentRefresh(t *testing.T) {
 	results := make(chan string, numGoroutines)
 	for i := 0; i < numGoroutines; i++ {
 		go func() {
-			token, err := tm.GetToken("test", "", "")
+			token, err := tm.GetToken(context.Background(), "test", "", "")
 			if err != nil {
 				t.Errorf("GetToken failed: %v", err)
 			}
@@ -434,7 +436,7 @@ func TestGetToken_RequestCoalescing(t *testing.T) {
 	results := make(chan string, numGoroutines)
 	for i := 0; i < numGoroutines; i++ {
 		go func() {
-			token, err := tm.GetToken("test", "", "")
+			token, err := tm.GetToken(context.Background(), "test", "", "")
 			if err != nil {
 				t.Errorf("GetToken failed: %v", err)
 				results <- ""
@@ -498,7 +500,7 @@ func TestGetToken_TimeoutDuringRefresh(t *testing.T) {
 	tm.mu.Unlock()
 
 	// GetToken should timeout
-	_, err := tm.GetToken("slow", "", "")
+	_, err := tm.GetToken(context.Background(), "slow", "", "")
 	if err == nil {
 		t.Error("expected timeout error, got nil")
 	}
@@ -507,6 +509,36 @@ func TestGetToken_TimeoutDuringRefresh(t *testing.T) {
 	}
 }
 
+func TestGetToken_CallerContextCancelled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	tm := NewVertexTokenManager(logger)
+	defer tm.Stop()
+
+	expiredTime := time.Now().UTC().Add(-1 * time.Hour)
+	tm.mu.Lock()
+	tm.tokens["slow"] = &cachedToken{
+		token: &oauth2.Token{AccessToken: "old-token", Expiry: expiredTime},
+		tokenSource: &slowMockTokenSource{
+			delay: 1 * time.Second,
+			token: &oauth2.Token{AccessToken: "slow-token", Expiry: time.Now().UTC().Add(1 * time.Hour)},
+		},
+		expiresAt: expiredTime,
+	}
+	tm.mu.Unlock()
+
+	// The caller's deadline ends the wait long before the token refresh timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := tm.GetToken(ctx, "slow", "", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context deadline error, got '%v'", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GetToken waited %v after the caller's context ended", elapsed)
+	}
+}
+
 func TestGetToken_ParallelDifferentCredentials(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	tm := NewVertexTokenManager(logger)
@@ -540,7 +572,7 @@ func TestGetToken_ParallelDifferentCredentials(t *testing.T) {
 
 	for _, cred := range credentials {
 		go func(credName string) {
-			token, err := tm.GetToken(credName, "", "")
+			token, err := tm.GetToken(context.Background(), credName, "", "")
 			if err != nil {
 				t.Errorf("GetToken(%s) failed: %v", credName, err)
 				results <- struct{ name, token string }{credName, ""}
@@ -596,7 +628,7 @@ func TestGetToken_CoalescingWithTimeout(t *testing.T) {
 
 	for i := 0; i < numGoroutines; i++ {
 		go func() {
-			_, err := tm.GetToken("test", "", "")
+			_, err := tm.GetToken(context.Background(), "test", "", "")
 			results <- err
 		}()
 	}
@@ -684,7 +716,7 @@ func TestGetToken_CoalescingCallerTimeout(t *testing.T) {
 			if idx > 0 {
 				time.Sleep(5 * time.Millisecond)
 			}
-			_, err := tm.GetToken("test", "", "")
+			_, err := tm.GetToken(context.Background(), "test", "", "")
 			results <- struct {
 				idx int
 				err error
@@ -728,7 +760,7 @@ func TestGetToken_CoalescingCallerTimeout(t *testing.T) {
 	tm.mu.Unlock()
 
 	// This should succeed
-	token, err := tm.GetToken("test", "", "")
+	token, err := tm.GetToken(context.Background(), "test", "", "")
 	if err != nil {
 		t.Errorf("Recovery GetToken failed: %v", err)
 	}
@@ -766,7 +798,7 @@ func TestGetToken_ChannelLeakOnTimeout(t *testing.T) {
 	defer func() { tm.tokenRefreshTimeout = originalTimeout }()
 
 	// Fire a request that will timeout
-	_, err := tm.GetToken("test", "", "")
+	_, err := tm.GetToken(context.Background(), "test", "", "")
 	if err == nil {
 		t.Error("Expected timeout error")
 	}
@@ -820,7 +852,7 @@ func TestGetToken_FirstCallerTimeoutRemovesFromMap(t *testing.T) {
 	defer func() { tm.tokenRefreshTimeout = originalTimeout }()
 
 	// Get token - will timeout
-	_, err := tm.GetToken("test", "", "")
+	_, err := tm.GetToken(context.Background(), "test", "", "")
 	if err == nil {
 		t.Error("Expected timeout")
 	}
@@ -866,7 +898,7 @@ func TestGetToken_MultipleTimeoutsSequential(t *testing.T) {
 	}
 	tm.mu.Unlock()
 
-	_, err := tm.GetToken("test", "", "")
+	_, err := tm.GetToken(context.Background(), "test", "", "")
 	if err == nil {
 		t.Error("Expected first timeout")
 	}
@@ -888,7 +920,7 @@ func TestGetToken_MultipleTimeoutsSequential(t *testing.T) {
 	}
 	tm.mu.Unlock()
 
-	_, err = tm.GetToken("test", "", "")
+	_, err = tm.GetToken(context.Background(), "test", "", "")
 	if err == nil {
 		t.Error("Expected second timeout")
 	}
@@ -908,7 +940,7 @@ func TestGetToken_MultipleTimeoutsSequential(t *testing.T) {
 	}
 	tm.mu.Unlock()
 
-	token, err := tm.GetToken("test", "", "")
+	token, err := tm.GetToken(context.Background(), "test", "", "")
 	if err != nil {
 		t.Errorf("Expected success after timeouts, got error: %v", err)
 	}
@@ -948,7 +980,7 @@ func TestGetToken_ProcessRefreshRequestRobustness(t *testing.T) {
 	// Launch 20 concurrent requests
 	for i := 0; i < 20; i++ {
 		go func() {
-			token, err := tm.GetToken("test", "", "")
+			token, err := tm.GetToken(context.Background(), "test", "", "")
 			results <- struct {
 				token string
 				err   error
@@ -999,7 +1031,7 @@ func TestGetToken_RefreshingMapStateAfterCompletion(t *testing.T) {
 	tm.mu.Unlock()
 
 	// Make request
-	token, err := tm.GetToken("test", "", "")
+	token, err := tm.GetToken(context.Background(), "test", "", "")
 	if err != nil {
 		t.Errorf("Expected success, got error: %v", err)
 	}
@@ -1064,7 +1096,7 @@ func TestGetToken_ConcurrentDifferentCredentialsWithTimeout(t *testing.T) {
 		// Each credential gets 2 concurrent requests
 		for i := 0; i < 2; i++ {
 			go func(name string) {
-				token, err := tm.GetToken(name, "", "")
+				token, err := tm.GetToken(context.Background(), name, "", "")
 				results <- struct {
 					cred  string
 					token string
@@ -2,9 +2,12 @@ package balancer
 
 import (
 	"errors"
+	"hash/fnv"
 	"io"
 	"log/slog"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
@@ -19,6 +22,12 @@ type ModelChecker interface {
 	IsEnabled() bool
 }
 
+// ProxyHealthChecker reports proxy credentials whose downstream router reported itself
+// unhealthy on its /health endpoint (or could not be reached)
+type ProxyHealthChecker interface {
+	IsUnhealthy(proxyName string) bool
+}
+
 var (
 	ErrNoCredentialsAvailable = errors.New("no credentials available")
 	ErrRateLimitExceeded      = errors.New("rate limit exceeded")
@@ -33,6 +42,7 @@ type RoundRobin struct {
 	fail2ban        *fail2ban.Fail2Ban
 	rateLimiter     *ratelimit.RPMLimiter
 	modelChecker    ModelChecker
+	proxyHealth     ProxyHealthChecker
 	logger          *slog.Logger
 }
 
@@ -52,7 +62,7 @@ func New(credentials []config.CredentialConfig, f2b *fail2ban.Fail2Ban, rl *rate
 		if tpm == 0 {
 			tpm = -1
 		}
-		rl.AddCredentialWithTPM(c.Name, c.RPM, tpm)
+		rl.AddCredentialWithBurst(c.Name, c.RPM, tpm, c.RPMBurst)
 		credentialIndex[c.Name] = i
 	}
 
@@ -87,6 +97,19 @@ func (r *RoundRobin) SetModelChecker(mc ModelChecker) {
 	r.modelChecker = mc
 }
 
+// SetProxyHealthChecker sets the downstream health source for proxy credentials:
+// proxies it reports unhealthy are skipped and not counted as available
+func (r *RoundRobin) SetProxyHealthChecker(hc ProxyHealthChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.proxyHealth = hc
+}
+
+// proxyUnhealthy reports whether cred is a proxy with an unhealthy downstream (must be called with lock held)
+func (r *RoundRobin) proxyUnhealthy(cred *config.CredentialConfig) bool {
+	return cred.Type == config.ProviderTypeProxy && r.proxyHealth != nil && r.proxyHealth.IsUnhealthy(cred.Name)
+}
+
 // getCredentialByName finds a credential by name (must be called with lock held)
 func (r *RoundRobin) getCredentialByName(name string) *config.CredentialConfig {
 	idx, ok := r.credentialIndex[name]
@@ -144,20 +167,50 @@ func (r *RoundRobin) NextFallbackProxyForModel(modelID string) (*config.Credenti
 	return r.next(modelID, true, true)
 }
 
+// NextForModelSeeded returns an available credential for the model chosen deterministically
+// from seed: the same seed and candidate set always yield the same credential, so replayed
+// requests hit the same backend. Banned or rate-limited candidates are skipped in a fixed order.
+func (r *RoundRobin) NextForModelSeeded(modelID, seed string) (*config.CredentialConfig, error) {
+	return r.nextExcluding(modelID, false, false, nil, seed, 1)
+}
+
+// NextFallbackForModelSeeded is the fallback counterpart of NextForModelSeeded
+func (r *RoundRobin) NextFallbackForModelSeeded(modelID, seed string) (*config.CredentialConfig, error) {
+	return r.nextExcluding(modelID, true, false, nil, seed, 1)
+}
+
 func (r *RoundRobin) next(modelID string, allowOnlyFallback, allowOnlyProxy bool) (*config.CredentialConfig, error) {
-	return r.nextExcluding(modelID, allowOnlyFallback, allowOnlyProxy, nil)
+	return r.nextExcluding(modelID, allowOnlyFallback, allowOnlyProxy, nil, "", 1)
+}
+
+// SelectOptions narrow credential selection for Select
+type SelectOptions struct {
+	Fallback bool            // Select among fallback credentials instead of regular ones
+	Exclude  map[string]bool // Credentials to skip (e.g. already tried)
+	Seed     string          // Deterministic choice, see NextForModelSeeded
+	Share    float64         // Fraction (0..1] of credential/model RPM and TPM the request may use (0 = full limits)
+}
+
+// Select returns an available credential for the model, honouring opts.
+// A Share below 1 makes credentials count as rate-limited once their usage reaches that
+// fraction of their limits, so lower priority requests are throttled first under contention.
+func (r *RoundRobin) Select(modelID string, opts SelectOptions) (*config.CredentialConfig, error) {
+	return r.nextExcluding(modelID, opts.Fallback, false, opts.Exclude, opts.Seed, opts.Share)
 }
 
 // nextExcluding is the core credential selection logic with optional exclude set.
 // Excluded credentials are skipped entirely and don't count as candidates.
 //
-// The algorithm runs in two phases:
+// The algorithm runs in three phases:
 //  1. Build a candidate list via structural filters (exclude, type/fallback, model availability).
 //     These are time-stable properties — they don't change between requests.
 //  2. Select the next candidate using an independent per-type counter when all candidates
 //     share the same ProviderType. This prevents high-frequency traffic of one provider type
 //     (e.g. OpenAI) from interfering with the round-robin cycling of another (e.g. Vertex AI).
-func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyProxy bool, exclude map[string]bool) (*config.CredentialConfig, error) {
+//     A non-empty seed replaces the counters with a hash of the seed and leaves them untouched.
+//  3. Try candidates in order, skipping banned ones, proxies with an unhealthy downstream and
+//     those whose rate limits (scaled by share) are exhausted.
+func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyProxy bool, exclude map[string]bool, seed string, share float64) (*config.CredentialConfig, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -167,39 +220,8 @@ func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyP
 		cred   *config.CredentialConfig
 	}
 	var candidates []candidateEntry
-
-	for i := range r.credentials {
-		cred := &r.credentials[i]
-
-		if len(exclude) > 0 && exclude[cred.Name] {
-			continue
-		}
-
-		if allowOnlyProxy && cred.Type != config.ProviderTypeProxy {
-			monitoring.CredentialSelectionRejected.WithLabelValues("type_not_allowed").Inc()
-			continue
-		}
-
-		if allowOnlyFallback {
-			if !cred.IsFallback {
-				monitoring.CredentialSelectionRejected.WithLabelValues("fallback_not_available").Inc()
-				continue
-			}
-		} else if cred.IsFallback {
-			monitoring.CredentialSelectionRejected.WithLabelValues("fallback_only").Inc()
-			continue
-		}
-
-		// Check model availability before ban/rate checks.
-		// model_not_available is a structural property, not a temporary issue.
-		if modelID != "" && r.modelChecker != nil && r.modelChecker.IsEnabled() {
-			if !r.modelChecker.HasModel(cred.Name, modelID) {
-				monitoring.CredentialSelectionRejected.WithLabelValues("model_not_available").Inc()
-				continue
-			}
-		}
-
-		candidates = append(candidates, candidateEntry{absIdx: i, cred: cred})
+	for _, i := range r.structuralCandidates(modelID, allowOnlyFallback, allowOnlyProxy, exclude, true) {
+		candidates = append(candidates, candidateEntry{absIdx: i, cred: &r.credentials[i]})
 	}
 
 	if len(candidates) == 0 {
@@ -218,7 +240,11 @@ func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyP
 	}
 
 	startOffset := 0
-	if sameType {
+	if seed != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(seed))
+		startOffset = int(h.Sum64() % uint64(len(candidates)))
+	} else if sameType {
 		typeStart := r.typeCounters[candidateType]
 		for i, c := range candidates {
 			if c.absIdx >= typeStart {
@@ -238,10 +264,22 @@ func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyP
 		// If globalStart is past all candidates, wrap to beginning.
 	}
 
-	// Phase 3: Try candidates in round-robin order, applying ban and rate-limit checks.
+	// Round-robin order starting at startOffset. Provisioned Throughput (dedicated capacity)
+	// credentials go first: PT is prepaid, so on-demand credentials are only used when
+	// all PT candidates are banned or rate-limited (or returned 429 and were excluded on retry).
+	order := make([]int, 0, len(candidates))
+	for _, wantPT := range []bool{true, false} {
+		for i := 0; i < len(candidates); i++ {
+			ci := (startOffset + i) % len(candidates)
+			if candidates[ci].cred.ProvisionedThroughput == wantPT {
+				order = append(order, ci)
+			}
+		}
+	}
+
+	// Phase 3: Try candidates in order, applying ban, downstream health and rate-limit checks.
 	rateLimitHit := false
-	for i := 0; i < len(candidates); i++ {
-		ci := (startOffset + i) % len(candidates)
+	for _, ci := range order {
 		c := candidates[ci]
 
 		if r.fail2ban.IsBanned(c.cred.Name, modelID) {
@@ -249,15 +287,25 @@ func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyP
 			continue
 		}
 
+		if r.proxyUnhealthy(c.cred) {
+			monitoring.CredentialSelectionRejected.WithLabelValues("proxy_unhealthy").Inc()
+			continue
+		}
+
 		// Atomically check all rate limits (credential RPM/TPM + model RPM/TPM)
 		// and record usage only if all checks pass. This prevents TOCTOU races
 		// where separate check+record calls could allow exceeding limits.
-		if !r.rateLimiter.TryAllowAll(c.cred.Name, modelID) {
+		if !r.rateLimiter.TryAllowAllShare(c.cred.Name, modelID, share) {
 			monitoring.CredentialSelectionRejected.WithLabelValues("rate_limit").Inc()
 			rateLimitHit = true
 			continue
 		}
 
+		// Seeded selection leaves round-robin state alone.
+		if seed != "" {
+			return c.cred, nil
+		}
+
 		// Advance the appropriate counter past the selected credential.
 		nextIdx := (c.absIdx + 1) % len(r.credentials)
 		if sameType {
@@ -278,15 +326,144 @@ func (r *RoundRobin) nextExcluding(modelID string, allowOnlyFallback, allowOnlyP
 	return nil, ErrNoCredentialsAvailable
 }
 
+// structuralCandidates returns the indexes of the credentials passing the structural
+// (time-stable) filters: exclude set, type/fallback and model availability. With record set,
+// rejections are counted in the credential selection metrics.
+// Must be called with r.mu locked
+func (r *RoundRobin) structuralCandidates(modelID string, allowOnlyFallback, allowOnlyProxy bool, exclude map[string]bool, record bool) []int {
+	reject := func(reason string) {
+		if record {
+			monitoring.CredentialSelectionRejected.WithLabelValues(reason).Inc()
+		}
+	}
+
+	var candidates []int
+	for i := range r.credentials {
+		cred := &r.credentials[i]
+
+		if len(exclude) > 0 && exclude[cred.Name] {
+			continue
+		}
+
+		if allowOnlyProxy && cred.Type != config.ProviderTypeProxy {
+			reject("type_not_allowed")
+			continue
+		}
+
+		if allowOnlyFallback {
+			if !cred.IsFallback {
+				reject("fallback_not_available")
+				continue
+			}
+		} else if cred.IsFallback {
+			reject("fallback_only")
+			continue
+		}
+
+		// Check model availability before ban/rate checks.
+		// model_not_available is a structural property, not a temporary issue.
+		if modelID != "" && r.modelChecker != nil && r.modelChecker.IsEnabled() {
+			if !r.modelChecker.HasModel(cred.Name, modelID) {
+				reject("model_not_available")
+				continue
+			}
+		}
+
+		candidates = append(candidates, i)
+	}
+	return candidates
+}
+
+// Exhaustion describes why Select found no available credential for a model
+type Exhaustion struct {
+	Credentials    int           // Candidates passing the structural filters (exclude, fallback, model availability)
+	Banned         int           // Candidates banned by fail2ban
+	ProxyUnhealthy int           // Proxy candidates whose downstream router is unhealthy
+	RateLimited    int           // Candidates whose RPM/TPM limits (or priority class share) are used up
+	RetryAfter     time.Duration // Earliest estimated time a candidate becomes available (0 = unknown)
+}
+
+// Merge combines the exhaustion of regular and fallback credentials
+func (e Exhaustion) Merge(other Exhaustion) Exhaustion {
+	retryAfter := e.RetryAfter
+	if retryAfter == 0 || (other.RetryAfter > 0 && other.RetryAfter < retryAfter) {
+		retryAfter = other.RetryAfter
+	}
+	return Exhaustion{
+		Credentials:    e.Credentials + other.Credentials,
+		Banned:         e.Banned + other.Banned,
+		ProxyUnhealthy: e.ProxyUnhealthy + other.ProxyUnhealthy,
+		RateLimited:    e.RateLimited + other.RateLimited,
+		RetryAfter:     retryAfter,
+	}
+}
+
+// Explain reports why the candidates of Select(modelID, opts) are unavailable, with the
+// earliest time a temporarily banned or rate-limited one is expected to accept requests.
+// Candidates that are neither banned nor behind an unhealthy proxy count as rate-limited.
+func (r *RoundRobin) Explain(modelID string, opts SelectOptions) Exhaustion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var e Exhaustion
+	earliest := func(wait time.Duration) {
+		if wait > 0 && (e.RetryAfter == 0 || wait < e.RetryAfter) {
+			e.RetryAfter = wait
+		}
+	}
+	for _, i := range r.structuralCandidates(modelID, opts.Fallback, false, opts.Exclude, false) {
+		cred := &r.credentials[i]
+		e.Credentials++
+		switch {
+		case r.fail2ban.IsBanned(cred.Name, modelID):
+			e.Banned++
+			if remaining, permanent := r.fail2ban.BanRemaining(cred.Name, modelID); !permanent {
+				earliest(remaining)
+			}
+		case r.proxyUnhealthy(cred):
+			e.ProxyUnhealthy++
+		default:
+			e.RateLimited++
+			earliest(r.rateLimiter.RetryAfter(cred.Name, modelID))
+		}
+	}
+	return e
+}
+
 // NextForModelExcluding returns the next available non-fallback credential that supports
 // the specified model, excluding credentials in the exclude set. Used for same-type
 // credential retry on provider errors (429/5xx/auth errors).
 func (r *RoundRobin) NextForModelExcluding(modelID string, exclude map[string]bool) (*config.CredentialConfig, error) {
-	return r.nextExcluding(modelID, false, false, exclude)
+	return r.nextExcluding(modelID, false, false, exclude, "", 1)
 }
 
 func (r *RoundRobin) RecordResponse(credentialName, modelID string, statusCode int) {
 	r.fail2ban.RecordResponse(credentialName, modelID, statusCode)
+	r.recordAdaptiveResponse(credentialName, modelID, statusCode)
+}
+
+// recordAdaptiveResponse feeds upstream status codes into adaptive rate limits (no-op when disabled)
+func (r *RoundRobin) recordAdaptiveResponse(credentialName, modelID string, statusCode int) {
+	var (
+		factor  float64
+		changed bool
+	)
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		factor, changed = r.rateLimiter.RecordRateLimited(credentialName, modelID)
+		if changed {
+			r.mu.RLock()
+			logger := r.logger
+			r.mu.RUnlock()
+			logger.Warn("Lowered adaptive rate limits after 429",
+				"credential", credentialName, "model", modelID, "factor", factor)
+		}
+	case statusCode >= 200 && statusCode < 300:
+		factor, changed = r.rateLimiter.RecordSuccess(credentialName, modelID)
+	}
+	if changed {
+		monitoring.CredentialAdaptiveLimitFactor.WithLabelValues(credentialName).Set(factor)
+	}
 }
 
 func (r *RoundRobin) GetCredentialsSnapshot() []config.CredentialConfig {
@@ -297,13 +474,32 @@ func (r *RoundRobin) GetCredentialsSnapshot() []config.CredentialConfig {
 	return creds
 }
 
+// CursorState is the round-robin position of the balancer
+type CursorState struct {
+	Global int            `json:"global"` // Next credential index for mixed-type candidate lists
+	Types  map[string]int `json:"types"`  // Next credential index per provider type
+}
+
+// Cursor returns a copy of the round-robin counters
+func (r *RoundRobin) Cursor() CursorState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cursor := CursorState{Global: r.current, Types: make(map[string]int, len(r.typeCounters))}
+	for providerType, next := range r.typeCounters {
+		cursor.Types[string(providerType)] = next
+	}
+	return cursor
+}
+
 func (r *RoundRobin) GetAvailableCount() int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	count := 0
-	for _, cred := range r.credentials {
-		if !r.fail2ban.HasAnyBan(cred.Name) {
+	for i := range r.credentials {
+		cred := &r.credentials[i]
+		if !r.fail2ban.HasAnyBan(cred.Name) && !r.proxyUnhealthy(cred) {
 			count++
 		}
 	}
@@ -319,6 +515,11 @@ func (r *RoundRobin) GetBannedPairs() []fail2ban.BanPair {
 	return r.fail2ban.GetBannedPairs()
 }
 
+// Fail2Ban returns the ban tracker of the balancer
+func (r *RoundRobin) Fail2Ban() *fail2ban.Fail2Ban {
+	return r.fail2ban
+}
+
 // validateFallbackConfiguration validates fallback credential configuration
 // Logs count of fallback credentials
 func (r *RoundRobin) validateFallbackConfiguration() {
@@ -1,11 +1,15 @@
 package balancer
 
 import (
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -206,6 +210,31 @@ func TestRecordResponse(t *testing.T) {
 	assert.True(t, f2b.IsBanned("cred1", "gpt-4"))
 }
 
+func TestRecordResponse_AdaptiveLimits(t *testing.T) {
+	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
+	rl := ratelimit.New()
+	rl.EnableAdaptive(ratelimit.AdaptiveConfig{
+		DecreaseFactor:   0.5,
+		IncreaseStep:     0.1,
+		IncreaseInterval: time.Minute,
+		MinFactor:        0.1,
+		Cooldown:         time.Minute,
+	})
+
+	credentials := []config.CredentialConfig{
+		{Name: "cred1", APIKey: "key1", BaseURL: "http://test1.com", RPM: 100},
+	}
+	bal := New(credentials, f2b, rl)
+
+	bal.RecordResponse("cred1", "gpt-4", http.StatusTooManyRequests)
+	assert.Equal(t, 0.5, rl.GetAdaptiveFactor("cred1"))
+	assert.Equal(t, 0.5, testutil.ToFloat64(monitoring.CredentialAdaptiveLimitFactor.WithLabelValues("cred1")))
+
+	// Successes right after a 429 do not raise limits yet
+	bal.RecordResponse("cred1", "gpt-4", http.StatusOK)
+	assert.Equal(t, 0.5, rl.GetAdaptiveFactor("cred1"))
+}
+
 func TestGetCredentialsSnapshot(t *testing.T) {
 	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
 	rl := ratelimit.New()
@@ -419,6 +448,38 @@ func TestNextForModel_CredentialRPMExceeded(t *testing.T) {
 	assert.Equal(t, ErrRateLimitExceeded, err)
 }
 
+func TestSelect_Share(t *testing.T) {
+	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
+	rl := ratelimit.New()
+
+	credentials := []config.CredentialConfig{
+		{Name: "cred1", APIKey: "key1", BaseURL: "http://test1.com", RPM: 4},
+		{Name: "fallback", APIKey: "key2", BaseURL: "http://test2.com", RPM: 4, IsFallback: true},
+	}
+	bal := New(credentials, f2b, rl)
+
+	// A 0.5 share may use 2 of the 4 requests per minute
+	for i := 0; i < 2; i++ {
+		cred, err := bal.Select("", SelectOptions{Share: 0.5})
+		require.NoError(t, err)
+		assert.Equal(t, "cred1", cred.Name)
+	}
+	_, err := bal.Select("", SelectOptions{Share: 0.5})
+	assert.Equal(t, ErrRateLimitExceeded, err)
+
+	// Full-share requests still get the reserved capacity
+	cred, err := bal.Select("", SelectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "cred1", cred.Name)
+
+	cred, err = bal.Select("", SelectOptions{Fallback: true, Share: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", cred.Name)
+
+	_, err = bal.Select("", SelectOptions{Exclude: map[string]bool{"cred1": true}})
+	assert.Equal(t, ErrNoCredentialsAvailable, err)
+}
+
 func TestNextForModel_CredentialTPMExceeded(t *testing.T) {
 	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
 	rl := ratelimit.New()
@@ -1125,6 +1186,40 @@ func TestIsProxyCredential_And_GetProxyCredentials(t *testing.T) {
 	assert.True(t, proxyNames["proxy-2"])
 }
 
+// unhealthyProxies implements ProxyHealthChecker for testing
+type unhealthyProxies map[string]bool
+
+func (u unhealthyProxies) IsUnhealthy(proxyName string) bool { return u[proxyName] }
+
+func TestNextForModel_SkipsUnhealthyProxy(t *testing.T) {
+	f2b := fail2ban.New(3, 0, []int{500})
+	rl := ratelimit.New()
+	creds := []config.CredentialConfig{
+		{Name: "proxy-down", Type: config.ProviderTypeProxy, RPM: 100, BaseURL: "http://localhost:8080"},
+		{Name: "proxy-up", Type: config.ProviderTypeProxy, RPM: 100, BaseURL: "http://localhost:8081"},
+		{Name: "openai-1", Type: config.ProviderTypeOpenAI, RPM: 100},
+	}
+	rr := New(creds, f2b, rl)
+	unhealthy := unhealthyProxies{"proxy-down": true, "openai-1": true}
+	rr.SetProxyHealthChecker(unhealthy)
+
+	for i := 0; i < 6; i++ {
+		cred, err := rr.NextForModel("gpt-4o")
+		require.NoError(t, err)
+		assert.NotEqual(t, "proxy-down", cred.Name)
+	}
+	// Only proxies are checked against the downstream health
+	assert.Equal(t, 2, rr.GetAvailableCount())
+
+	unhealthy["proxy-up"] = true
+	for i := 0; i < 3; i++ {
+		cred, err := rr.NextForModel("gpt-4o")
+		require.NoError(t, err)
+		assert.Equal(t, "openai-1", cred.Name)
+	}
+	assert.Equal(t, 1, rr.GetAvailableCount())
+}
+
 // TestRoundRobin_MixedTypeTrafficIndependence verifies that high-frequency OpenAI
 // traffic does not interfere with Vertex AI credential cycling.
 // This is the real-world bug: with a shared r.current counter, 500 RPM of OpenAI
@@ -1177,3 +1272,140 @@ func TestRoundRobin_MixedTypeTrafficIndependence(t *testing.T) {
 	assert.Equal(t, expectedOrder, vertexResults,
 		"Vertex creds should cycle evenly regardless of interleaved OpenAI traffic")
 }
+
+func TestNextForModel_ProvisionedThroughputFirst(t *testing.T) {
+	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
+	rl := ratelimit.New()
+
+	credentials := []config.CredentialConfig{
+		{Name: "ondemand1", Type: config.ProviderTypeVertexAI, RPM: 100},
+		{Name: "pt", Type: config.ProviderTypeVertexAI, RPM: 2, ProvisionedThroughput: true},
+		{Name: "ondemand2", Type: config.ProviderTypeVertexAI, RPM: 100},
+	}
+
+	bal := New(credentials, f2b, rl)
+
+	// PT credential is always preferred while it has capacity
+	for i := 0; i < 2; i++ {
+		cred, err := bal.NextForModel("gemini-2.5-pro")
+		require.NoError(t, err)
+		assert.Equal(t, "pt", cred.Name)
+	}
+
+	// PT rate limit exhausted: on-demand credentials are used
+	cred, err := bal.NextForModel("gemini-2.5-pro")
+	require.NoError(t, err)
+	assert.NotEqual(t, "pt", cred.Name)
+
+	// PT excluded after a 429 on retry: on-demand credential is selected
+	cred, err = bal.NextForModelExcluding("gemini-2.5-pro", map[string]bool{"pt": true})
+	require.NoError(t, err)
+	assert.Contains(t, []string{"ondemand1", "ondemand2"}, cred.Name)
+}
+
+func TestNextForModelSeeded_Deterministic(t *testing.T) {
+	f2b := fail2ban.New(1, time.Minute, []int{500})
+	rl := ratelimit.New()
+
+	credentials := []config.CredentialConfig{
+		{Name: "cred1", Type: config.ProviderTypeOpenAI, RPM: 100},
+		{Name: "cred2", Type: config.ProviderTypeOpenAI, RPM: 100},
+		{Name: "cred3", Type: config.ProviderTypeVertexAI, RPM: 100},
+		{Name: "fallback", Type: config.ProviderTypeOpenAI, RPM: 100, IsFallback: true},
+	}
+
+	bal := New(credentials, f2b, rl)
+
+	// The same seed always hits the same credential, regardless of round-robin traffic
+	first, err := bal.NextForModelSeeded("gpt-4o", "eval-42")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = bal.NextForModel("gpt-4o")
+		require.NoError(t, err)
+		cred, err := bal.NextForModelSeeded("gpt-4o", "eval-42")
+		require.NoError(t, err)
+		assert.Equal(t, first.Name, cred.Name)
+	}
+
+	// Different seeds spread across credentials
+	seen := make(map[string]bool)
+	for _, seed := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		cred, err := bal.NextForModelSeeded("gpt-4o", seed)
+		require.NoError(t, err)
+		seen[cred.Name] = true
+	}
+	assert.Greater(t, len(seen), 1)
+	assert.False(t, seen["fallback"], "seeded selection must not return fallback credentials")
+
+	// A banned credential is skipped, and the seed maps to the same substitute each time
+	bal.RecordResponse(first.Name, "gpt-4o", http.StatusInternalServerError)
+	substitute, err := bal.NextForModelSeeded("gpt-4o", "eval-42")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Name, substitute.Name)
+	cred, err := bal.NextForModelSeeded("gpt-4o", "eval-42")
+	require.NoError(t, err)
+	assert.Equal(t, substitute.Name, cred.Name)
+
+	cred, err = bal.NextFallbackForModelSeeded("gpt-4o", "eval-42")
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", cred.Name)
+}
+
+func TestRoundRobin_Explain(t *testing.T) {
+	f2b := fail2ban.New(1, time.Minute, []int{500})
+	rl := ratelimit.New()
+
+	credentials := []config.CredentialConfig{
+		{Name: "banned", Type: config.ProviderTypeOpenAI, RPM: 100},
+		{Name: "limited", Type: config.ProviderTypeOpenAI, RPM: 1},
+		{Name: "excluded", Type: config.ProviderTypeOpenAI, RPM: 100},
+		{Name: "fallback", Type: config.ProviderTypeOpenAI, RPM: 100, IsFallback: true},
+	}
+	bal := New(credentials, f2b, rl)
+
+	f2b.RecordResponse("banned", "gpt-4o", 500)
+	assert.True(t, rl.Allow("limited"))
+
+	e := bal.Explain("gpt-4o", SelectOptions{Exclude: map[string]bool{"excluded": true}})
+	assert.Equal(t, 2, e.Credentials)
+	assert.Equal(t, 1, e.Banned)
+	assert.Equal(t, 1, e.RateLimited)
+	assert.Zero(t, e.ProxyUnhealthy)
+	assert.Greater(t, e.RetryAfter, 59*time.Second)
+	assert.LessOrEqual(t, e.RetryAfter, time.Minute)
+
+	fallback := bal.Explain("gpt-4o", SelectOptions{Fallback: true})
+	assert.Equal(t, Exhaustion{Credentials: 1, RateLimited: 1}, fallback, "an available credential counts as rate-limited")
+
+	merged := e.Merge(fallback)
+	assert.Equal(t, 3, merged.Credentials)
+	assert.Equal(t, 2, merged.RateLimited)
+	assert.Equal(t, e.RetryAfter, merged.RetryAfter, "unknown estimates are ignored")
+
+	assert.Equal(t, Exhaustion{}, bal.Explain("gpt-4o", SelectOptions{Exclude: map[string]bool{"banned": true, "limited": true, "excluded": true}}))
+}
+
+func TestRoundRobin_Cursor(t *testing.T) {
+	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
+	rl := ratelimit.New()
+	credentials := []config.CredentialConfig{
+		{Name: "openai1", Type: config.ProviderTypeOpenAI, APIKey: "key1", BaseURL: "http://test1.com", RPM: -1},
+		{Name: "openai2", Type: config.ProviderTypeOpenAI, APIKey: "key2", BaseURL: "http://test2.com", RPM: -1},
+	}
+	bal := New(credentials, f2b, rl)
+
+	cursor := bal.Cursor()
+	assert.Equal(t, 0, cursor.Global)
+	assert.Empty(t, cursor.Types)
+
+	cred, err := bal.NextForModel("")
+	require.NoError(t, err)
+	assert.Equal(t, "openai1", cred.Name)
+
+	cursor = bal.Cursor()
+	assert.Equal(t, map[string]int{"openai": 1}, cursor.Types)
+
+	// The returned map is a copy
+	cursor.Types["openai"] = 0
+	assert.Equal(t, 1, bal.Cursor().Types["openai"])
+}
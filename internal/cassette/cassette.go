@@ -0,0 +1,190 @@
+// Package cassette records upstream request/response pairs into fixture files and replays them,
+// so converter changes can be regression-tested against real provider payload shapes.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Redacted replaces secret header and query parameter values in recorded fixtures
+const Redacted = "REDACTED"
+
+// redactedHeaders are never written to fixture files
+var redactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"Api-Key",
+	"X-Goog-Api-Key",
+	"X-Amz-Security-Token",
+	"Cookie",
+	"Set-Cookie",
+	"X-Router-Signature",
+}
+
+// redactedQueryParams are API keys passed in the URL (e.g. Vertex AI express mode)
+var redactedQueryParams = []string{"key", "api_key"}
+
+// Interaction is one recorded upstream request/response pair
+type Interaction struct {
+	Credential string    `json:"credential"`
+	Provider   string    `json:"provider"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+}
+
+// Request is the recorded upstream request
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body
+}
+
+// Response is the recorded upstream response
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body
+}
+
+// Body holds a payload as JSON when possible (readable diffs), else as text or base64
+type Body struct {
+	JSON   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"body_text,omitempty"`
+	Binary []byte          `json:"body_base64,omitempty"`
+}
+
+func newBody(data []byte) Body {
+	switch {
+	case len(data) == 0:
+		return Body{}
+	case json.Valid(data):
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err == nil {
+			return Body{JSON: indented.Bytes()}
+		}
+		return Body{JSON: data}
+	case utf8.Valid(data):
+		return Body{Text: string(data)}
+	default:
+		return Body{Binary: data}
+	}
+}
+
+// Bytes returns the payload; JSON bodies are compacted
+func (b Body) Bytes() []byte {
+	switch {
+	case len(b.JSON) > 0:
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, b.JSON); err == nil {
+			return compacted.Bytes()
+		}
+		return b.JSON
+	case b.Text != "":
+		return []byte(b.Text)
+	default:
+		return b.Binary
+	}
+}
+
+// Load reads all interactions (*.json) from a fixture directory, in file name order
+func Load(dir string) ([]*Interaction, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	interactions := make([]*Interaction, 0, len(paths))
+	for _, path := range paths {
+		interaction, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, nil
+}
+
+// LoadFile reads a single interaction fixture
+func LoadFile(path string) (*Interaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interaction Interaction
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &interaction, nil
+}
+
+// Save writes an interaction to path as indented JSON
+func Save(path string, interaction *Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	for _, key := range redactedHeaders {
+		if _, ok := headers[http.CanonicalHeaderKey(key)]; ok {
+			headers[http.CanonicalHeaderKey(key)] = Redacted
+		}
+	}
+	return headers
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	query := redacted.Query()
+	changed := false
+	for _, param := range redactedQueryParams {
+		if query.Has(param) {
+			query.Set(param, Redacted)
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// requestKey identifies requests for replay: method, URL path and canonical JSON body.
+// Hosts and query strings are ignored so fixtures replay against any base_url or API key.
+func requestKey(method, rawURL string, body []byte) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	return method + " " + path + "\n" + string(body)
+}
@@ -0,0 +1,120 @@
+package cassette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"text\":\"Hel\"}\n\ndata: {\"text\":\"lo\"}\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"Hello"}],"echo":` + string(body) + `}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doRequest(t *testing.T, transport http.RoundTripper, url, body string) (*http.Response, string) {
+	t.Helper()
+	ctx := httputil.WithCredential(context.Background(), &config.CredentialConfig{Name: "anthropic/main", Type: config.ProviderTypeAnthropic})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-Api-Key", "sk-ant-secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	return resp, string(data)
+}
+
+func TestRecorder_WritesRedactedInteractions(t *testing.T) {
+	upstream := newUpstream(t)
+	dir := t.TempDir() + "/cassettes"
+	recorder := NewRecorder(nil, dir, nil)
+
+	_, body := doRequest(t, recorder, upstream.URL+"/v1/messages?key=secret", `{"model":"claude","max_tokens":10}`)
+	assert.Contains(t, body, `"text":"Hello"`)
+	_, body = doRequest(t, recorder, upstream.URL+"/v1/messages", `{"model":"claude","stream":true}`)
+	assert.Contains(t, body, "lo")
+
+	interactions, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, interactions, 2)
+
+	recorded := interactions[0]
+	assert.Equal(t, "anthropic/main", recorded.Credential)
+	assert.Equal(t, "anthropic", recorded.Provider)
+	assert.Equal(t, http.StatusOK, recorded.Response.Status)
+	assert.Equal(t, Redacted, recorded.Request.Headers["X-Api-Key"])
+	assert.Equal(t, Redacted, recorded.Response.Headers["Set-Cookie"])
+	assert.Contains(t, recorded.Request.URL, "key="+Redacted)
+	assert.JSONEq(t, `{"model":"claude","max_tokens":10}`, string(recorded.Request.Bytes()))
+
+	stream := interactions[1]
+	assert.Equal(t, "data: {\"text\":\"Hel\"}\n\ndata: {\"text\":\"lo\"}\n\n", stream.Response.Text)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, file := range files {
+		data, err := os.ReadFile(dir + "/" + file.Name())
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "secret", file.Name())
+		assert.True(t, strings.HasPrefix(file.Name(), "anthropic_main-"), file.Name())
+	}
+}
+
+func TestReplayer_ServesRecordedInteractions(t *testing.T) {
+	upstream := newUpstream(t)
+	dir := t.TempDir()
+	recorder := NewRecorder(nil, dir, nil)
+	_, recordedBody := doRequest(t, recorder, upstream.URL+"/v1/messages", `{"model":"claude","max_tokens":10}`)
+	_, recordedStream := doRequest(t, recorder, upstream.URL+"/v1/messages", `{"model":"claude","stream":true}`)
+	upstream.Close()
+
+	replayer, err := LoadReplayer(dir)
+	require.NoError(t, err)
+
+	// Host and JSON key order do not matter
+	resp, body := doRequest(t, replayer, "https://api.anthropic.com/v1/messages", `{"max_tokens":10, "model":"claude"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, recordedBody, body)
+
+	resp, body = doRequest(t, replayer, "https://api.anthropic.com/v1/messages", `{"model":"claude","stream":true}`)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, recordedStream, body)
+
+	// Repeated requests reuse the last matching interaction
+	_, body = doRequest(t, replayer, "https://api.anthropic.com/v1/messages", `{"model":"claude","stream":true}`)
+	assert.Equal(t, recordedStream, body)
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", strings.NewReader(`{"model":"other"}`))
+	require.NoError(t, err)
+	_, err = replayer.RoundTrip(req)
+	assert.ErrorContains(t, err, "no recorded interaction")
+}
+
+func TestLoadReplayer_EmptyDir(t *testing.T) {
+	_, err := LoadReplayer(t.TempDir())
+	assert.ErrorContains(t, err, "no cassettes found")
+}
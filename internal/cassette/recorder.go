@@ -0,0 +1,125 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Recorder wraps an http.RoundTripper and writes every upstream request/response pair
+// (with secrets redacted) to a fixture file in dir. Streaming responses are recorded
+// once the body has been fully read and closed, so clients still receive them incrementally.
+type Recorder struct {
+	next   http.RoundTripper
+	dir    string
+	logger *slog.Logger
+	seq    atomic.Int64
+}
+
+// NewRecorder creates a recording Transport; dir is created on the first recording
+func NewRecorder(next http.RoundTripper, dir string, logger *slog.Logger) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Recorder{next: next, dir: dir, logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	interaction := &Interaction{
+		RecordedAt: utils.NowUTC(),
+		Request: Request{
+			Method:  req.Method,
+			URL:     redactURL(req.URL),
+			Headers: redactHeaders(req.Header),
+			Body:    newBody(reqBody),
+		},
+	}
+	if cred := httputil.CredentialFromContext(req.Context()); cred != nil {
+		interaction.Credential = cred.Name
+		interaction.Provider = string(cred.Type)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	interaction.Response.Status = resp.StatusCode
+	interaction.Response.Headers = redactHeaders(resp.Header)
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onClose: func(data []byte) {
+			interaction.Response.Body = newBody(data)
+			r.save(interaction)
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(interaction *Interaction) {
+	name := interaction.Credential
+	if name == "" {
+		name = "upstream"
+	}
+	fileName := fmt.Sprintf("%s-%s-%04d.json",
+		unsafeFileChars.ReplaceAllString(name, "_"),
+		interaction.RecordedAt.Format("20060102T150405"),
+		r.seq.Add(1),
+	)
+	path := filepath.Join(r.dir, fileName)
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		r.logger.Error("Failed to create cassette dir", "dir", r.dir, "error", err)
+		return
+	}
+	if err := Save(path, interaction); err != nil {
+		r.logger.Error("Failed to write cassette", "path", path, "error", err)
+		return
+	}
+	r.logger.Debug("Recorded upstream interaction", "path", path, "status", interaction.Response.Status)
+}
+
+// recordingBody buffers everything read from the response body and hands it to onClose once
+type recordingBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	once    sync.Once
+	onClose func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.onClose(b.buf.Bytes()) })
+	return err
+}
@@ -0,0 +1,86 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Replayer is an http.RoundTripper that serves recorded interactions instead of calling upstream.
+// Requests are matched by method, URL path and JSON body; repeated identical requests are served
+// the matching interactions in recording order, the last one repeating once they run out.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]*Interaction
+	served       map[string]int
+}
+
+// NewReplayer creates a Replayer for the given interactions
+func NewReplayer(interactions []*Interaction) *Replayer {
+	r := &Replayer{
+		interactions: make(map[string][]*Interaction, len(interactions)),
+		served:       make(map[string]int),
+	}
+	for _, interaction := range interactions {
+		key := requestKey(interaction.Request.Method, interaction.Request.URL, interaction.Request.Bytes())
+		r.interactions[key] = append(r.interactions[key], interaction)
+	}
+	return r
+}
+
+// LoadReplayer creates a Replayer for all interactions in a fixture directory
+func LoadReplayer(dir string) (*Replayer, error) {
+	interactions, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("no cassettes found in %s", dir)
+	}
+	return NewReplayer(interactions), nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := requestKey(req.Method, req.URL.String(), body)
+	r.mu.Lock()
+	matches := r.interactions[key]
+	index := min(r.served[key], len(matches)-1)
+	r.served[key]++
+	r.mu.Unlock()
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("cassette: no recorded interaction for %s %s", req.Method, req.URL.Path)
+	}
+	recorded := matches[index].Response
+
+	header := make(http.Header, len(recorded.Headers))
+	for key, value := range recorded.Headers {
+		header.Set(key, value)
+	}
+	data := recorded.Bytes()
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -13,6 +18,83 @@ import (
 const DefaultMaxAttempts = 3
 const DefaultBanDuration time.Duration = 0
 
+// DefaultFail2BanStateSaveInterval is how often fail2ban state is written to state_file
+const DefaultFail2BanStateSaveInterval = 10 * time.Second
+
+const DefaultStartupCheckTimeout = 10 * time.Second
+const DefaultStartupCheckConcurrency = 8
+
+const (
+	DefaultAdaptiveDecreaseFactor   = 0.5
+	DefaultAdaptiveIncreaseStep     = 0.1
+	DefaultAdaptiveIncreaseInterval = 30 * time.Second
+	DefaultAdaptiveMinFactor        = 0.1
+	DefaultAdaptiveCooldown         = 10 * time.Second
+)
+
+const (
+	DefaultFairSchedulerMaxConcurrent = 256
+	DefaultFairSchedulerMaxQueue      = 1024
+	DefaultFairSchedulerQueueTimeout  = 30 * time.Second
+)
+
+const (
+	DefaultImageInliningMaxSizeMB            = 10
+	DefaultImageInliningTimeout              = 10 * time.Second
+	DefaultImageInliningCacheTTL             = 10 * time.Minute
+	DefaultImageInliningCacheSize            = 128
+	DefaultImageInliningMaxImages            = 16
+	DefaultImageInliningMaxConcurrentFetches = 8
+)
+
+// DefaultDNSCacheTTL is how long resolved upstream addresses are reused by dns_cache
+const DefaultDNSCacheTTL = time.Minute
+
+// DefaultAnthropicVersion is the anthropic-version header sent when a credential sets none
+const DefaultAnthropicVersion = "2023-06-01"
+
+// DefaultVertexAPIVersion is the Vertex AI API version used when a credential sets none
+const DefaultVertexAPIVersion = "v1beta1"
+
+// VertexAPIVersions are the accepted api_version and predict_api_version values
+var VertexAPIVersions = []string{"v1", "v1beta1"}
+
+// DefaultMockBaseURL is the placeholder base_url of mock credentials (requests are never sent)
+const DefaultMockBaseURL = "http://mock.invalid"
+
+var DefaultImageInliningMIMETypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Handling of request parameters the selected credential cannot honour (server.unsupported_params)
+const (
+	UnsupportedParamsDrop    = "drop"    // Remove them and report via the X-Router-Dropped-Params header
+	UnsupportedParamsReroute = "reroute" // Prefer a credential that supports them, drop if none is available
+)
+
+const DefaultSpendPushInterval = 15 * time.Second
+const DefaultSpendPushJob = "auto_ai_router"
+const DefaultSpendPushMaxLabelValues = 100
+
+// SLO defaults (monitoring.slo)
+const (
+	DefaultSLOLatencyTarget      = 95.0
+	DefaultSLOEvaluationInterval = 30 * time.Second
+	MaxSLOWindow                 = 30 * 24 * time.Hour
+)
+
+// DefaultSLOAlerts are the multi-window burn rate alerts of the Google SRE workbook:
+// "page" fires when 2% of a 30-day error budget is spent within an hour, "ticket" when
+// 5% is spent within six hours
+var DefaultSLOAlerts = []SLOAlertConfig{
+	{Name: "page", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 14.4},
+	{Name: "ticket", LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, BurnRate: 6},
+}
+
+// DefaultRequestSamplingMaxBodyBytes is the default size limit of sampled request and response bodies
+const DefaultRequestSamplingMaxBodyBytes = 16 * 1024
+
+// MinHMACSecretLength is the minimum length of inter_router_secret and hmac_secret
+const MinHMACSecretLength = 32
+
 var DefaultErrorCodes = []int{429}
 
 // ProviderType represents the type of AI provider
@@ -25,12 +107,13 @@ const (
 	ProviderTypeAnthropic ProviderType = "anthropic"
 	ProviderTypeBedrock   ProviderType = "bedrock"
 	ProviderTypeProxy     ProviderType = "proxy"
+	ProviderTypeMock      ProviderType = "mock" // Served locally with canned responses (offline tests)
 )
 
 // IsValid checks if the provider type is valid
 func (p ProviderType) IsValid() bool {
 	switch p {
-	case ProviderTypeOpenAI, ProviderTypeVertexAI, ProviderTypeGemini, ProviderTypeAnthropic, ProviderTypeBedrock, ProviderTypeProxy:
+	case ProviderTypeOpenAI, ProviderTypeVertexAI, ProviderTypeGemini, ProviderTypeAnthropic, ProviderTypeBedrock, ProviderTypeProxy, ProviderTypeMock:
 		return true
 	}
 	return false
@@ -42,6 +125,7 @@ type ModelRPMConfig struct {
 	Model      string `yaml:"model,omitempty"` // Real model name sent to provider (alias for Name if different)
 	RPM        int    `yaml:"rpm"`
 	TPM        int    `yaml:"tpm"`
+	RPMBurst   int    `yaml:"rpm_burst,omitempty"`  // Token bucket capacity; 0 keeps the sliding window
 	Credential string `yaml:"credential,omitempty"` // If set, model is only available for this credential
 }
 
@@ -53,26 +137,91 @@ type Config struct {
 	Models      []ModelRPMConfig   `yaml:"models,omitempty"`
 	ModelAlias  map[string]string  `yaml:"model_alias,omitempty"`
 	LiteLLMDB   LiteLLMDBConfig    `yaml:"litellm_db,omitempty"`
+
+	StartupCheck   StartupCheckConfig   `yaml:"startup_check,omitempty"`
+	AdaptiveLimits AdaptiveLimitsConfig `yaml:"adaptive_limits,omitempty"`
+	FairScheduler  FairSchedulerConfig  `yaml:"fair_scheduler,omitempty"`
+	ImageInlining  ImageInliningConfig  `yaml:"image_inlining,omitempty"`
+	DNSCache       DNSCacheConfig       `yaml:"dns_cache,omitempty"`
+	FaultInjection FaultInjectionConfig `yaml:"fault_injection,omitempty"`
+	Cassette       CassetteConfig       `yaml:"cassette,omitempty"`
+	UsageForecast  UsageForecastConfig  `yaml:"usage_forecast,omitempty"`
+
+	ContextManagement ContextManagementConfig `yaml:"context_management,omitempty"`
+	PostProcessing    PostProcessingConfig    `yaml:"response_postprocessing,omitempty"`
+	ConversionCheck   ConversionCheckConfig   `yaml:"conversion_validation,omitempty"`
+	Attribution       AttributionConfig       `yaml:"attribution_headers,omitempty"`
+	SpendReport       SpendReportConfig       `yaml:"spend_report,omitempty"`
+	LocalSpendLog     LocalSpendLogConfig     `yaml:"local_spend_log,omitempty"`
+	MetricsExport     MetricsExportConfig     `yaml:"metrics_export,omitempty"`
+	QuotaBoosts       QuotaBoostsConfig       `yaml:"quota_boosts,omitempty"`
+	PriorityClasses   PriorityClassesConfig   `yaml:"priority_classes,omitempty"`
+	StreamCoalescing  StreamCoalescingConfig  `yaml:"stream_coalescing,omitempty"`
+	NonStreaming      NonStreamingConfig      `yaml:"non_streaming,omitempty"`
+	LiteLLMHeaders    LiteLLMHeadersConfig    `yaml:"litellm_headers,omitempty"`
+	RoutingOverrides  RoutingOverridesConfig  `yaml:"routing_overrides,omitempty"`
+	MaxCostPerRequest MaxCostPerRequestConfig `yaml:"max_cost_per_request,omitempty"`
+	ModelPins         ModelPinsConfig         `yaml:"model_pins,omitempty"`
+	ReasoningRouting  ReasoningRoutingConfig  `yaml:"reasoning_routing,omitempty"`
+	ReasoningParams   ReasoningParamsConfig   `yaml:"reasoning_params,omitempty"`
+	ResponseHeaders   ResponseHeadersConfig   `yaml:"response_headers,omitempty"`
+	UpstreamErrors    UpstreamErrorsConfig    `yaml:"upstream_errors,omitempty"`
+	SystemPrompts     SystemPromptsConfig     `yaml:"system_prompts,omitempty"`
+	ParamNegotiation  ParamNegotiationConfig  `yaml:"param_negotiation,omitempty"`
+	Chargeback        ChargebackConfig        `yaml:"chargeback,omitempty"`
+	ChoiceFanOut      ChoiceFanOutConfig      `yaml:"n_fanout,omitempty"`
+
+	Tenants         []TenantConfig         `yaml:"tenants,omitempty"`          // Isolated credential pools, keys and rate limits per tenant
+	CredentialPools []CredentialPoolConfig `yaml:"credential_pools,omitempty"` // Credential groups with shared RPM/TPM and budget limits
+
+	Include        []string `yaml:"include,omitempty"`         // Additional YAML files (glob patterns) with credentials, models and model_alias
+	CredentialsDir string   `yaml:"credentials_dir,omitempty"` // Directory whose *.yaml/*.yml files are merged like include entries
+
+	IncludedFiles []string `yaml:"-"` // Files merged at load time (populated by Load)
 }
 
 type ServerConfig struct {
-	Port                   int           `yaml:"port"`
-	MaxBodySizeMB          int           `yaml:"max_body_size_mb"`
-	ResponseBodyMultiplier int           `yaml:"response_body_multiplier"` // Multiplier for response body size limit relative to max_body_size_mb (default: 10)
-	RequestTimeout         time.Duration `yaml:"request_timeout"`
-	LoggingLevel           string        `yaml:"logging_level"`
-	MasterKey              string        `yaml:"master_key"`
-	DefaultModelsRPM       int           `yaml:"default_models_rpm"`
-	MaxIdleConns           int           `yaml:"max_idle_conns"`
-	MaxIdleConnsPerHost    int           `yaml:"max_idle_conns_per_host"`
-	IdleConnTimeout        time.Duration `yaml:"idle_conn_timeout"`
-	ReadTimeout            time.Duration `yaml:"-"`                           // HTTP server read timeout (equals request_timeout, not configurable via YAML)
-	WriteTimeout           time.Duration `yaml:"write_timeout"`               // HTTP server write timeout (default: 60s)
-	IdleTimeout            time.Duration `yaml:"idle_timeout"`                // HTTP server idle timeout (default: 2*write_timeout)
-	MaxProviderRetries     int           `yaml:"max_provider_retries"`        // Max same-type credential retries on provider errors (default: 2, meaning 3 total attempts)
-	ModelPricesLink        string        `yaml:"model_prices_link,omitempty"` // URL or file path to model prices JSON - supports os.environ/VAR_NAME
+	Port                   int               `yaml:"port"`
+	MaxBodySizeMB          int               `yaml:"max_body_size_mb"`
+	ResponseBodyMultiplier int               `yaml:"response_body_multiplier"` // Multiplier for response body size limit relative to max_body_size_mb (default: 10)
+	RequestTimeout         time.Duration     `yaml:"request_timeout"`
+	LoggingLevel           string            `yaml:"logging_level"`
+	MasterKey              string            `yaml:"master_key"`
+	MasterKeys             []string          `yaml:"master_keys,omitempty"`             // Further accepted master keys (e.g. keys being rotated out) - supports os.environ/VAR_NAME
+	MasterKeyGracePeriod   time.Duration     `yaml:"master_key_grace_period,omitempty"` // Validity of the previous master keys after a rotation via the admin API (default: 1h)
+	DefaultModelsRPM       int               `yaml:"default_models_rpm"`
+	MaxIdleConns           int               `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost    int               `yaml:"max_idle_conns_per_host"`
+	IdleConnTimeout        time.Duration     `yaml:"idle_conn_timeout"`
+	ReadTimeout            time.Duration     `yaml:"-"`                                   // HTTP server read timeout (equals request_timeout, not configurable via YAML)
+	WriteTimeout           time.Duration     `yaml:"write_timeout"`                       // HTTP server write timeout (default: 60s)
+	IdleTimeout            time.Duration     `yaml:"idle_timeout"`                        // HTTP server idle timeout (default: 2*write_timeout)
+	MaxProviderRetries     int               `yaml:"max_provider_retries"`                // Max same-type credential retries on provider errors (default: 2, meaning 3 total attempts)
+	ModelPricesLink        string            `yaml:"model_prices_link,omitempty"`         // URL or file path to model prices JSON - supports os.environ/VAR_NAME
+	ModelPriceMapping      map[string]string `yaml:"model_price_mapping,omitempty"`       // Model name -> model prices entry, for models priced under another name
+	ModelPricesCacheFile   string            `yaml:"model_prices_cache_file,omitempty"`   // File keeping the last synced model prices across restarts ("" = not persisted)
+	SkipZeroCostLogs       bool              `yaml:"skip_zero_cost_logs,omitempty"`       // Do not write spend logs with cost 0 for models that lost their price (default: false)
+	AdminPort              int               `yaml:"admin_port,omitempty"`                // Admin listener for /debug/* diagnostics, gated by master_key (0 = disabled)
+	InterRouterSecret      string            `yaml:"inter_router_secret,omitempty"`       // Shared secret for HMAC-signed requests from parent routers - supports os.environ/VAR_NAME
+	UnsupportedParams      string            `yaml:"unsupported_params,omitempty"`        // Handling of request params a credential cannot honour: "drop" (default) or "reroute"
+	DeterministicRouting   bool              `yaml:"deterministic_routing,omitempty"`     // Select credentials by request body hash instead of round-robin (default: false)
+	ReadOnly               bool              `yaml:"read_only,omitempty"`                 // Serve traffic without LiteLLM DB spend writes and admin mutations (toggle: /admin/read-only)
+	HideUnavailableModels  bool              `yaml:"hide_unavailable_models,omitempty"`   // Leave models whose credentials are all banned out of GET /v1/models (default: false)
+	StaleModelTTL          time.Duration     `yaml:"stale_model_ttl,omitempty"`           // Evict proxy models not reported for this long (default: 30m)
+	StreamFirstByteTimeout time.Duration     `yaml:"stream_first_byte_timeout,omitempty"` // Retry streaming requests on another credential if no response byte arrives in time (0 = disabled)
+	FallbackTimeout        time.Duration     `yaml:"fallback_timeout,omitempty"`          // Timeout of each same-type retry and fallback attempt (default: request_timeout)
+	FallbackBodyMultiplier int               `yaml:"fallback_body_multiplier,omitempty"`  // Response body size limit of retry and fallback attempts relative to max_body_size_mb (default: response_body_multiplier)
+	RequestDeadline        time.Duration     `yaml:"request_deadline,omitempty"`          // Total time of all attempts of a request, retries and fallbacks included (0 = none)
+	UpstreamCompression    bool              `yaml:"upstream_compression,omitempty"`      // Ask upstreams for gzip, deflate, br and zstd responses instead of gzip only (default: false)
+	BatchStateFile         string            `yaml:"batch_state_file,omitempty"`          // File keeping Anthropic batch records (credential, owner, spend logged) across restarts ("" = not persisted)
 }
 
+// DefaultMasterKeyGracePeriod keeps the previous master keys valid after a rotation
+const DefaultMasterKeyGracePeriod = time.Hour
+
+// DefaultStaleModelTTL is how long a model a proxy credential stopped reporting is kept
+const DefaultStaleModelTTL = 30 * time.Minute
+
 // ErrorCodeRuleConfig defines per-error-code ban rules
 type ErrorCodeRuleConfig struct {
 	Code        int    `yaml:"code,omitempty"`
@@ -85,26 +234,48 @@ type Fail2BanConfig struct {
 	BanDuration    time.Duration         `yaml:"ban_duration,omitempty"`
 	ErrorCodes     []int                 `yaml:"error_codes,omitempty"`
 	ErrorCodeRules []ErrorCodeRuleConfig `yaml:"error_code_rules,omitempty"`
+	// StateFile persists bans and failure counters across restarts ("" = not persisted)
+	StateFile string `yaml:"state_file,omitempty"`
+	// StateSaveInterval is how often the state is written to state_file (also on shutdown)
+	StateSaveInterval time.Duration `yaml:"state_save_interval,omitempty"`
 }
 
 // UnmarshalYAML implements custom unmarshaling for ServerConfig with env variable support
 func (s *ServerConfig) UnmarshalYAML(value *yaml.Node) error {
 	// Create a temporary struct with all string fields
 	type tempConfig struct {
-		Port                   string `yaml:"port"`
-		MaxBodySizeMB          string `yaml:"max_body_size_mb"`
-		ResponseBodyMultiplier string `yaml:"response_body_multiplier"`
-		RequestTimeout         string `yaml:"request_timeout"`
-		LoggingLevel           string `yaml:"logging_level"`
-		MasterKey              string `yaml:"master_key"`
-		DefaultModelsRPM       string `yaml:"default_models_rpm"`
-		MaxIdleConns           string `yaml:"max_idle_conns"`
-		MaxIdleConnsPerHost    string `yaml:"max_idle_conns_per_host"`
-		IdleConnTimeout        string `yaml:"idle_conn_timeout"`
-		WriteTimeout           string `yaml:"write_timeout"`
-		IdleTimeout            string `yaml:"idle_timeout"`
-		MaxProviderRetries     string `yaml:"max_provider_retries"`
-		ModelPricesLink        string `yaml:"model_prices_link,omitempty"`
+		Port                   string            `yaml:"port"`
+		MaxBodySizeMB          string            `yaml:"max_body_size_mb"`
+		ResponseBodyMultiplier string            `yaml:"response_body_multiplier"`
+		RequestTimeout         string            `yaml:"request_timeout"`
+		LoggingLevel           string            `yaml:"logging_level"`
+		MasterKey              string            `yaml:"master_key"`
+		MasterKeys             []string          `yaml:"master_keys,omitempty"`
+		MasterKeyGracePeriod   string            `yaml:"master_key_grace_period,omitempty"`
+		DefaultModelsRPM       string            `yaml:"default_models_rpm"`
+		MaxIdleConns           string            `yaml:"max_idle_conns"`
+		MaxIdleConnsPerHost    string            `yaml:"max_idle_conns_per_host"`
+		IdleConnTimeout        string            `yaml:"idle_conn_timeout"`
+		WriteTimeout           string            `yaml:"write_timeout"`
+		IdleTimeout            string            `yaml:"idle_timeout"`
+		MaxProviderRetries     string            `yaml:"max_provider_retries"`
+		ModelPricesLink        string            `yaml:"model_prices_link,omitempty"`
+		ModelPriceMapping      map[string]string `yaml:"model_price_mapping,omitempty"`
+		ModelPricesCacheFile   string            `yaml:"model_prices_cache_file,omitempty"`
+		SkipZeroCostLogs       string            `yaml:"skip_zero_cost_logs,omitempty"`
+		AdminPort              string            `yaml:"admin_port,omitempty"`
+		InterRouterSecret      string            `yaml:"inter_router_secret,omitempty"`
+		UnsupportedParams      string            `yaml:"unsupported_params,omitempty"`
+		DeterministicRouting   string            `yaml:"deterministic_routing,omitempty"`
+		ReadOnly               string            `yaml:"read_only,omitempty"`
+		HideUnavailableModels  string            `yaml:"hide_unavailable_models,omitempty"`
+		StaleModelTTL          string            `yaml:"stale_model_ttl,omitempty"`
+		StreamFirstByteTimeout string            `yaml:"stream_first_byte_timeout,omitempty"`
+		FallbackTimeout        string            `yaml:"fallback_timeout,omitempty"`
+		FallbackBodyMultiplier string            `yaml:"fallback_body_multiplier,omitempty"`
+		RequestDeadline        string            `yaml:"request_deadline,omitempty"`
+		UpstreamCompression    string            `yaml:"upstream_compression,omitempty"`
+		BatchStateFile         string            `yaml:"batch_state_file,omitempty"`
 	}
 
 	var temp tempConfig
@@ -125,6 +296,9 @@ func (s *ServerConfig) UnmarshalYAML(value *yaml.Node) error {
 	if s.ResponseBodyMultiplier, err = parseField(temp.ResponseBodyMultiplier, 10, strconv.Atoi, "response_body_multiplier"); err != nil {
 		return err
 	}
+	if s.FallbackBodyMultiplier, err = parseField(temp.FallbackBodyMultiplier, 0, strconv.Atoi, "fallback_body_multiplier"); err != nil {
+		return err
+	}
 	if s.DefaultModelsRPM, err = parseField(temp.DefaultModelsRPM, -1, strconv.Atoi, "default_models_rpm"); err != nil {
 		return err
 	}
@@ -134,6 +308,9 @@ func (s *ServerConfig) UnmarshalYAML(value *yaml.Node) error {
 	if s.MaxIdleConnsPerHost, err = parseField(temp.MaxIdleConnsPerHost, 20, strconv.Atoi, "max_idle_conns_per_host"); err != nil {
 		return err
 	}
+	if s.AdminPort, err = parseField(temp.AdminPort, 0, strconv.Atoi, "admin_port"); err != nil {
+		return err
+	}
 
 	// Duration fields
 	if s.RequestTimeout, err = parseField(temp.RequestTimeout, 60*time.Second, time.ParseDuration, "request_timeout"); err != nil {
@@ -148,16 +325,57 @@ func (s *ServerConfig) UnmarshalYAML(value *yaml.Node) error {
 	if s.IdleTimeout, err = parseField(temp.IdleTimeout, 2*time.Minute, time.ParseDuration, "idle_timeout"); err != nil {
 		return err
 	}
+	if s.MasterKeyGracePeriod, err = parseField(temp.MasterKeyGracePeriod, DefaultMasterKeyGracePeriod, time.ParseDuration, "master_key_grace_period"); err != nil {
+		return err
+	}
+	if s.StaleModelTTL, err = parseField(temp.StaleModelTTL, DefaultStaleModelTTL, time.ParseDuration, "stale_model_ttl"); err != nil {
+		return err
+	}
+	if s.StreamFirstByteTimeout, err = parseField(temp.StreamFirstByteTimeout, 0, time.ParseDuration, "stream_first_byte_timeout"); err != nil {
+		return err
+	}
+	if s.FallbackTimeout, err = parseField(temp.FallbackTimeout, 0, time.ParseDuration, "fallback_timeout"); err != nil {
+		return err
+	}
+	if s.RequestDeadline, err = parseField(temp.RequestDeadline, 0, time.ParseDuration, "request_deadline"); err != nil {
+		return err
+	}
 
 	// Max provider retries (default: 2 = 3 total attempts)
 	if s.MaxProviderRetries, err = parseField(temp.MaxProviderRetries, 2, strconv.Atoi, "max_provider_retries"); err != nil {
 		return err
 	}
 
+	// Boolean fields
+	if s.DeterministicRouting, err = parseField(temp.DeterministicRouting, false, strconv.ParseBool, "deterministic_routing"); err != nil {
+		return err
+	}
+	if s.ReadOnly, err = parseField(temp.ReadOnly, false, strconv.ParseBool, "read_only"); err != nil {
+		return err
+	}
+	if s.HideUnavailableModels, err = parseField(temp.HideUnavailableModels, false, strconv.ParseBool, "hide_unavailable_models"); err != nil {
+		return err
+	}
+	if s.SkipZeroCostLogs, err = parseField(temp.SkipZeroCostLogs, false, strconv.ParseBool, "skip_zero_cost_logs"); err != nil {
+		return err
+	}
+	if s.UpstreamCompression, err = parseField(temp.UpstreamCompression, false, strconv.ParseBool, "upstream_compression"); err != nil {
+		return err
+	}
+
 	// String fields
 	s.LoggingLevel = resolveEnvString(temp.LoggingLevel)
 	s.MasterKey = resolveEnvString(temp.MasterKey)
+	s.MasterKeys = make([]string, 0, len(temp.MasterKeys))
+	for _, key := range temp.MasterKeys {
+		s.MasterKeys = append(s.MasterKeys, resolveEnvString(key))
+	}
 	s.ModelPricesLink = resolveEnvString(temp.ModelPricesLink)
+	s.ModelPriceMapping = temp.ModelPriceMapping
+	s.ModelPricesCacheFile = resolveEnvString(temp.ModelPricesCacheFile)
+	s.BatchStateFile = resolveEnvString(temp.BatchStateFile)
+	s.InterRouterSecret = resolveEnvString(temp.InterRouterSecret)
+	s.UnsupportedParams = resolveEnvString(temp.UnsupportedParams)
 
 	return nil
 }
@@ -169,32 +387,266 @@ type CredentialConfig struct {
 	BaseURL string       `yaml:"base_url"`
 	RPM     int          `yaml:"rpm"`
 	TPM     int          `yaml:"tpm"`
+	// RPMBurst switches RPM limiting to a token bucket refilled at rpm per minute
+	// that holds up to rpm_burst requests. 0 keeps the sliding window.
+	RPMBurst int `yaml:"rpm_burst,omitempty"`
+	// Region is where the credential serves requests (e.g. eu-west-1), matched by the
+	// X-Router-Require-Region routing override. Vertex AI credentials default to location.
+	Region string `yaml:"region,omitempty"`
 
 	// Vertex AI specific fields
 	ProjectID       string `yaml:"project_id,omitempty"`
 	Location        string `yaml:"location,omitempty"`
 	CredentialsFile string `yaml:"credentials_file,omitempty"`
 	CredentialsJSON string `yaml:"credentials_json,omitempty"`
+	// ProvisionedThroughput marks the credential as dedicated capacity (Provisioned Throughput).
+	// Requests are sent with X-Vertex-AI-LLM-Request-Type: dedicated and such credentials
+	// are preferred over on-demand ones for the same model.
+	ProvisionedThroughput bool `yaml:"provisioned_throughput,omitempty"`
+	// QuotaProject is sent as X-Goog-User-Project (billing/quota project)
+	QuotaProject string `yaml:"quota_project,omitempty"`
+	// APIVersion is the Vertex AI API version of generateContent requests (v1 or v1beta1,
+	// default: v1beta1). Preview-only models may need v1beta1.
+	APIVersion string `yaml:"api_version,omitempty"`
+	// PredictAPIVersion is the API version of predict requests (Imagen, embeddings)
+	// (default: api_version)
+	PredictAPIVersion string `yaml:"predict_api_version,omitempty"`
+
+	// Anthropic specific fields
+	// AnthropicVersion is sent as the anthropic-version header (default: 2023-06-01)
+	AnthropicVersion string `yaml:"anthropic_version,omitempty"`
+	// AnthropicBeta lists beta features sent in the anthropic-beta header
+	// (e.g. prompt-caching-2024-07-31, context-1m-2025-08-07), merged with the client's own
+	AnthropicBeta []string `yaml:"anthropic_beta,omitempty"`
 
 	// Proxy specific fields
 	IsFallback bool `yaml:"is_fallback,omitempty"`
+	// HMACSecret signs requests to the downstream router (its server.inter_router_secret)
+	// instead of sending api_key or the client's Authorization header
+	HMACSecret string `yaml:"hmac_secret,omitempty"`
+	// ProxyModels filters and renames the models fetched from the proxy's /v1/models (nil = all, as is)
+	ProxyModels *ProxyModelsConfig `yaml:"proxy_models,omitempty"`
+	// NormalizeStream re-emits the proxy's Chat Completions streams as uniform OpenAI SSE: plain
+	// data events, a usage chunk if requested but missing, and a final data: [DONE]
+	NormalizeStream bool `yaml:"normalize_stream,omitempty"`
+
+	// Required marks the credential as mandatory for startup_check strict mode
+	Required bool `yaml:"required,omitempty"`
+
+	// UnsupportedParams lists OpenAI request parameters this credential cannot honour
+	// in addition to the provider defaults (e.g. logprobs for Gemini models without logprobs support)
+	UnsupportedParams []string `yaml:"unsupported_params,omitempty"`
+
+	// ModelMap rewrites model names to this credential's own identifiers before the provider
+	// URL and request are built (e.g. gpt-4o -> gpt-4o-2024-11-20, or an Azure deployment name)
+	ModelMap map[string]string `yaml:"model_map,omitempty"`
+
+	// Resolve pins upstream hosts to fixed addresses for this credential: host -> IP or host,
+	// with an optional port (e.g. api.openai.com -> 203.0.113.10 behind geo-DNS)
+	Resolve map[string]string `yaml:"resolve,omitempty"`
+
+	// Mock configures the canned responses of a mock credential (nil = defaults)
+	Mock *MockConfig `yaml:"mock,omitempty"`
+
+	// Quota sets daily/monthly token and spend quotas forecast by usage_forecast (nil = no quota)
+	Quota *QuotaConfig `yaml:"quota,omitempty"`
+}
+
+// QuotaConfig is the usage a credential may consume per UTC day and month (0 = no quota)
+type QuotaConfig struct {
+	DailyTokens   int64   `yaml:"daily_tokens"`
+	MonthlyTokens int64   `yaml:"monthly_tokens"`
+	DailySpend    float64 `yaml:"daily_spend"`   // USD
+	MonthlySpend  float64 `yaml:"monthly_spend"` // USD
+}
+
+// UnmarshalYAML implements custom unmarshaling for QuotaConfig with env variable support
+func (q *QuotaConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		DailyTokens   string `yaml:"daily_tokens"`
+		MonthlyTokens string `yaml:"monthly_tokens"`
+		DailySpend    string `yaml:"daily_spend"`
+		MonthlySpend  string `yaml:"monthly_spend"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	parseInt64 := func(v string) (int64, error) { return strconv.ParseInt(v, 10, 64) }
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+
+	var err error
+	if q.DailyTokens, err = parseField(temp.DailyTokens, 0, parseInt64, "quota.daily_tokens"); err != nil {
+		return err
+	}
+	if q.MonthlyTokens, err = parseField(temp.MonthlyTokens, 0, parseInt64, "quota.monthly_tokens"); err != nil {
+		return err
+	}
+	if q.DailySpend, err = parseField(temp.DailySpend, 0, parseFloat, "quota.daily_spend"); err != nil {
+		return err
+	}
+	if q.MonthlySpend, err = parseField(temp.MonthlySpend, 0, parseFloat, "quota.monthly_spend"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ProxyModelsConfig selects and renames the models a proxy credential exposes, so chained
+// routers serving overlapping model names don't collide. Names are upstream model IDs.
+type ProxyModelsConfig struct {
+	Allow  []string          `yaml:"allow"`  // Upstream models exposed (empty = all)
+	Deny   []string          `yaml:"deny"`   // Upstream models never exposed (wins over allow)
+	Rename map[string]string `yaml:"rename"` // Upstream name -> exposed name
+}
+
+// UnmarshalYAML implements custom unmarshaling for ProxyModelsConfig with env variable support
+func (pm *ProxyModelsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Allow  []string          `yaml:"allow"`
+		Deny   []string          `yaml:"deny"`
+		Rename map[string]string `yaml:"rename"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	for _, model := range temp.Allow {
+		pm.Allow = append(pm.Allow, resolveEnvString(model))
+	}
+	for _, model := range temp.Deny {
+		pm.Deny = append(pm.Deny, resolveEnvString(model))
+	}
+	if len(temp.Rename) > 0 {
+		pm.Rename = make(map[string]string, len(temp.Rename))
+		for upstream, exposed := range temp.Rename {
+			pm.Rename[resolveEnvString(upstream)] = resolveEnvString(exposed)
+		}
+	}
+
+	return nil
+}
+
+// Expose returns the name an upstream model is exposed as, or false if the model is filtered out
+func (pm *ProxyModelsConfig) Expose(upstream string) (string, bool) {
+	if pm == nil {
+		return upstream, true
+	}
+	if slices.Contains(pm.Deny, upstream) || (len(pm.Allow) > 0 && !slices.Contains(pm.Allow, upstream)) {
+		return "", false
+	}
+	if exposed, ok := pm.Rename[upstream]; ok {
+		return exposed, true
+	}
+	return upstream, true
+}
+
+// Upstream returns the upstream name of an exposed model
+func (pm *ProxyModelsConfig) Upstream(exposed string) string {
+	if pm == nil {
+		return exposed
+	}
+	for upstream, name := range pm.Rename {
+		if name == exposed {
+			return upstream
+		}
+	}
+	return exposed
+}
+
+func (pm *ProxyModelsConfig) validate() error {
+	for _, model := range slices.Concat(pm.Allow, pm.Deny) {
+		if model == "" {
+			return fmt.Errorf("proxy_models: model name must not be empty")
+		}
+	}
+	exposedBy := make(map[string]string, len(pm.Rename))
+	for upstream, exposed := range pm.Rename {
+		if upstream == "" || exposed == "" {
+			return fmt.Errorf("proxy_models.rename: model names must not be empty")
+		}
+		if other, ok := exposedBy[exposed]; ok {
+			first, second := min(other, upstream), max(other, upstream)
+			return fmt.Errorf("proxy_models.rename: %q and %q are both exposed as %q", first, second, exposed)
+		}
+		exposedBy[exposed] = upstream
+	}
+	return nil
+}
+
+// MockConfig configures responses served locally by a mock credential
+type MockConfig struct {
+	Response         string        `yaml:"response"`          // Chat completion text (Go template with .Model and .Prompt)
+	Latency          time.Duration `yaml:"latency"`           // Delay before the response is sent
+	PromptTokens     int           `yaml:"prompt_tokens"`     // Reported prompt tokens (0 = estimated from the request)
+	CompletionTokens int           `yaml:"completion_tokens"` // Reported completion tokens (0 = estimated from the response)
+}
+
+// UnmarshalYAML implements custom unmarshaling for MockConfig with env variable support
+func (m *MockConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Response         string `yaml:"response"`
+		Latency          string `yaml:"latency"`
+		PromptTokens     string `yaml:"prompt_tokens"`
+		CompletionTokens string `yaml:"completion_tokens"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	m.Response = resolveEnvString(temp.Response)
+	if m.Latency, err = parseField(temp.Latency, 0, time.ParseDuration, "mock.latency"); err != nil {
+		return err
+	}
+	if m.PromptTokens, err = parseField(temp.PromptTokens, 0, strconv.Atoi, "mock.prompt_tokens"); err != nil {
+		return err
+	}
+	if m.CompletionTokens, err = parseField(temp.CompletionTokens, 0, strconv.Atoi, "mock.completion_tokens"); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // UnmarshalYAML implements custom unmarshaling for CredentialConfig with env variable support
 func (c *CredentialConfig) UnmarshalYAML(value *yaml.Node) error {
 	// Create a temporary struct with all string fields
 	type tempConfig struct {
-		Name            string `yaml:"name"`
-		Type            string `yaml:"type"`
-		APIKey          string `yaml:"api_key"`
-		BaseURL         string `yaml:"base_url"`
-		RPM             string `yaml:"rpm"`
-		TPM             string `yaml:"tpm"`
-		ProjectID       string `yaml:"project_id,omitempty"`
-		Location        string `yaml:"location,omitempty"`
-		CredentialsFile string `yaml:"credentials_file,omitempty"`
-		CredentialsJSON string `yaml:"credentials_json,omitempty"`
-		IsFallback      string `yaml:"is_fallback,omitempty"`
+		Name              string             `yaml:"name"`
+		Type              string             `yaml:"type"`
+		APIKey            string             `yaml:"api_key"`
+		BaseURL           string             `yaml:"base_url"`
+		RPM               string             `yaml:"rpm"`
+		TPM               string             `yaml:"tpm"`
+		RPMBurst          string             `yaml:"rpm_burst,omitempty"`
+		Region            string             `yaml:"region,omitempty"`
+		ProjectID         string             `yaml:"project_id,omitempty"`
+		Location          string             `yaml:"location,omitempty"`
+		CredentialsFile   string             `yaml:"credentials_file,omitempty"`
+		CredentialsJSON   string             `yaml:"credentials_json,omitempty"`
+		ProvisionedTP     string             `yaml:"provisioned_throughput,omitempty"`
+		QuotaProject      string             `yaml:"quota_project,omitempty"`
+		APIVersion        string             `yaml:"api_version,omitempty"`
+		PredictAPIVersion string             `yaml:"predict_api_version,omitempty"`
+		AnthropicVersion  string             `yaml:"anthropic_version,omitempty"`
+		AnthropicBeta     []string           `yaml:"anthropic_beta,omitempty"`
+		IsFallback        string             `yaml:"is_fallback,omitempty"`
+		HMACSecret        string             `yaml:"hmac_secret,omitempty"`
+		ProxyModels       *ProxyModelsConfig `yaml:"proxy_models,omitempty"`
+		NormalizeStream   string             `yaml:"normalize_stream,omitempty"`
+		Required          string             `yaml:"required,omitempty"`
+		UnsupportedParams []string           `yaml:"unsupported_params,omitempty"`
+		ModelMap          map[string]string  `yaml:"model_map,omitempty"`
+		Resolve           map[string]string  `yaml:"resolve,omitempty"`
+		Mock              *MockConfig        `yaml:"mock,omitempty"`
+		Quota             *QuotaConfig       `yaml:"quota,omitempty"`
 	}
 
 	var temp tempConfig
@@ -207,12 +659,39 @@ func (c *CredentialConfig) UnmarshalYAML(value *yaml.Node) error {
 	c.Type = ProviderType(resolveEnvString(temp.Type))
 	c.APIKey = resolveEnvString(temp.APIKey)
 	c.BaseURL = resolveEnvString(temp.BaseURL)
+	c.Region = resolveEnvString(temp.Region)
 
 	// Resolve Vertex AI specific fields
 	c.ProjectID = resolveEnvString(temp.ProjectID)
 	c.Location = resolveEnvString(temp.Location)
 	c.CredentialsFile = resolveEnvString(temp.CredentialsFile)
 	c.CredentialsJSON = resolveEnvString(temp.CredentialsJSON)
+	c.QuotaProject = resolveEnvString(temp.QuotaProject)
+	c.APIVersion = resolveEnvString(temp.APIVersion)
+	c.PredictAPIVersion = resolveEnvString(temp.PredictAPIVersion)
+	c.HMACSecret = resolveEnvString(temp.HMACSecret)
+	c.AnthropicVersion = resolveEnvString(temp.AnthropicVersion)
+	for _, beta := range temp.AnthropicBeta {
+		c.AnthropicBeta = append(c.AnthropicBeta, resolveEnvString(beta))
+	}
+	for _, param := range temp.UnsupportedParams {
+		c.UnsupportedParams = append(c.UnsupportedParams, resolveEnvString(param))
+	}
+	if len(temp.ModelMap) > 0 {
+		c.ModelMap = make(map[string]string, len(temp.ModelMap))
+		for model, providerModel := range temp.ModelMap {
+			c.ModelMap[resolveEnvString(model)] = resolveEnvString(providerModel)
+		}
+	}
+	if len(temp.Resolve) > 0 {
+		c.Resolve = make(map[string]string, len(temp.Resolve))
+		for host, target := range temp.Resolve {
+			c.Resolve[resolveEnvString(host)] = resolveEnvString(target)
+		}
+	}
+	c.ProxyModels = temp.ProxyModels
+	c.Mock = temp.Mock
+	c.Quota = temp.Quota
 
 	// Resolve and parse integer fields
 	var err error
@@ -222,11 +701,23 @@ func (c *CredentialConfig) UnmarshalYAML(value *yaml.Node) error {
 	if c.TPM, err = parseField(temp.TPM, -1, strconv.Atoi, "tpm for credential '"+c.Name+"'"); err != nil {
 		return err
 	}
+	if c.RPMBurst, err = parseField(temp.RPMBurst, 0, strconv.Atoi, "rpm_burst for credential '"+c.Name+"'"); err != nil {
+		return err
+	}
 
 	// Resolve and parse boolean field
 	if c.IsFallback, err = parseField(temp.IsFallback, false, strconv.ParseBool, "is_fallback for credential '"+c.Name+"'"); err != nil {
 		return err
 	}
+	if c.Required, err = parseField(temp.Required, false, strconv.ParseBool, "required for credential '"+c.Name+"'"); err != nil {
+		return err
+	}
+	if c.NormalizeStream, err = parseField(temp.NormalizeStream, false, strconv.ParseBool, "normalize_stream for credential '"+c.Name+"'"); err != nil {
+		return err
+	}
+	if c.ProvisionedThroughput, err = parseField(temp.ProvisionedTP, false, strconv.ParseBool, "provisioned_throughput for credential '"+c.Name+"'"); err != nil {
+		return err
+	}
 
 	// Validate base_url for proxy and other provider types that require it
 	if c.BaseURL != "" {
@@ -238,48 +729,71 @@ func (c *CredentialConfig) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-type MonitoringConfig struct {
-	PrometheusEnabled bool   `yaml:"prometheus_enabled"`
-	HealthCheckPath   string `yaml:"-"` // Fixed to "/health", not configurable via YAML
-	LogErrors         bool   `yaml:"log_errors,omitempty"`
-	ErrorsLogPath     string `yaml:"errors_log_path,omitempty"`
+// ProviderModel returns the model identifier to send to this credential for a request routed
+// as modelID with provider-facing name realModelID (models[].model): the model_map entry of
+// modelID, else of realModelID, else realModelID
+func (c *CredentialConfig) ProviderModel(modelID, realModelID string) string {
+	if providerModel, ok := c.ModelMap[modelID]; ok {
+		return providerModel
+	}
+	if providerModel, ok := c.ModelMap[realModelID]; ok {
+		return providerModel
+	}
+	return realModelID
 }
 
-// LiteLLMDBConfig holds configuration for LiteLLM database integration
-type LiteLLMDBConfig struct {
-	// Enable/disable module
-	Enabled bool `yaml:"enabled"`
-
-	// IsRequired specifies whether LiteLLM DB is mandatory (fail startup on error)
-	// or optional (degrade to NoopManager with warning on error)
-	IsRequired bool `yaml:"is_required"` // default: false
-
-	// Database connection postgresql://[user[:password]@][netloc][:port][/dbname][?param1=value1&...]
-	DatabaseURL string `yaml:"database_url"` // os.environ/LITELLM_DATABASE_URL
-	MaxConns    int    `yaml:"max_conns"`    // default: 10
-	MinConns    int    `yaml:"min_conns"`    // default: 2
+// AnthropicAPIVersion returns the anthropic-version header to send to this credential:
+// anthropic_version, or DefaultAnthropicVersion if it is not set
+func (c *CredentialConfig) AnthropicAPIVersion() string {
+	if c.AnthropicVersion != "" {
+		return c.AnthropicVersion
+	}
+	return DefaultAnthropicVersion
+}
 
-	// Health check
-	HealthCheckInterval time.Duration `yaml:"health_check_interval"` // default: 10s
-	ConnectTimeout      time.Duration `yaml:"connect_timeout"`       // default: 5s
+type MonitoringConfig struct {
+	PrometheusEnabled bool                  `yaml:"prometheus_enabled"`
+	HealthCheckPath   string                `yaml:"-"` // Fixed to "/health", not configurable via YAML
+	LogErrors         bool                  `yaml:"log_errors,omitempty"`
+	ErrorsLogPath     string                `yaml:"errors_log_path,omitempty"`
+	SpendPush         SpendPushConfig       `yaml:"spend_push,omitempty"`
+	RequestSampling   RequestSamplingConfig `yaml:"request_sampling,omitempty"`
+	SLO               SLOConfig             `yaml:"slo,omitempty"`
+}
 
-	// Auth cache
-	AuthCacheTTL  time.Duration `yaml:"auth_cache_ttl"`  // default: 5s
-	AuthCacheSize int           `yaml:"auth_cache_size"` // default: 10000
+// SLOConfig defines availability and latency objectives whose error budget burn rates are
+// computed inside the router, exported as metrics and alerted on via webhook
+type SLOConfig struct {
+	Enabled            bool             `yaml:"enabled"`
+	Availability       float64          `yaml:"availability"`        // Target share of requests without a 5xx response in percent, e.g. 99.9 (0 = not tracked)
+	LatencyThreshold   time.Duration    `yaml:"latency_threshold"`   // Requests slower than this (time to first byte) spend the latency budget (0 = not tracked)
+	LatencyTarget      float64          `yaml:"latency_target"`      // Target share of requests within latency_threshold in percent (default: 95)
+	EvaluationInterval time.Duration    `yaml:"evaluation_interval"` // How often burn rates are computed (default: 30s)
+	WebhookURL         string           `yaml:"webhook_url"`         // POST firing and resolved alerts here (optional)
+	WebhookFormat      string           `yaml:"webhook_format"`      // "json" or "slack" (default: json)
+	Alerts             []SLOAlertConfig `yaml:"alerts"`              // Burn rate alerts (default: DefaultSLOAlerts)
+}
 
-	// Spend logging
-	LogQueueSize     int           `yaml:"log_queue_size"`     // default: 10000
-	LogBatchSize     int           `yaml:"log_batch_size"`     // default: 100
-	LogFlushInterval time.Duration `yaml:"log_flush_interval"` // default: 5s
+// SLOAlertConfig fires when the burn rate exceeds BurnRate over both windows: the long
+// window avoids alerting on short spikes, the short one resolves the alert quickly
+type SLOAlertConfig struct {
+	Name        string        `yaml:"name"`
+	LongWindow  time.Duration `yaml:"long_window"`
+	ShortWindow time.Duration `yaml:"short_window"`
+	BurnRate    float64       `yaml:"burn_rate"`
 }
 
-// UnmarshalYAML implements custom unmarshaling for MonitoringConfig with env variable support
-func (m *MonitoringConfig) UnmarshalYAML(value *yaml.Node) error {
-	// Create a temporary struct with all string fields
+// UnmarshalYAML implements custom unmarshaling for SLOConfig with env variable support
+func (s *SLOConfig) UnmarshalYAML(value *yaml.Node) error {
 	type tempConfig struct {
-		PrometheusEnabled string `yaml:"prometheus_enabled"`
-		LogErrors         string `yaml:"log_errors,omitempty"`
-		ErrorsLogPath     string `yaml:"errors_log_path,omitempty"`
+		Enabled            string           `yaml:"enabled"`
+		Availability       string           `yaml:"availability"`
+		LatencyThreshold   string           `yaml:"latency_threshold"`
+		LatencyTarget      string           `yaml:"latency_target"`
+		EvaluationInterval string           `yaml:"evaluation_interval"`
+		WebhookURL         string           `yaml:"webhook_url"`
+		WebhookFormat      string           `yaml:"webhook_format"`
+		Alerts             []SLOAlertConfig `yaml:"alerts"`
 	}
 
 	var temp tempConfig
@@ -287,37 +801,39 @@ func (m *MonitoringConfig) UnmarshalYAML(value *yaml.Node) error {
 		return err
 	}
 
-	// Resolve and parse boolean fields
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+
 	var err error
-	if m.PrometheusEnabled, err = parseField(temp.PrometheusEnabled, false, strconv.ParseBool, "prometheus_enabled"); err != nil {
+	if s.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "monitoring.slo.enabled"); err != nil {
 		return err
 	}
-	if m.LogErrors, err = parseField(temp.LogErrors, false, strconv.ParseBool, "log_errors"); err != nil {
+	if s.Availability, err = parseField(temp.Availability, 0, parseFloat, "monitoring.slo.availability"); err != nil {
+		return err
+	}
+	if s.LatencyThreshold, err = parseField(temp.LatencyThreshold, 0, time.ParseDuration, "monitoring.slo.latency_threshold"); err != nil {
+		return err
+	}
+	if s.LatencyTarget, err = parseField(temp.LatencyTarget, DefaultSLOLatencyTarget, parseFloat, "monitoring.slo.latency_target"); err != nil {
+		return err
+	}
+	if s.EvaluationInterval, err = parseField(temp.EvaluationInterval, DefaultSLOEvaluationInterval, time.ParseDuration, "monitoring.slo.evaluation_interval"); err != nil {
 		return err
 	}
 
-	// Resolve string fields
-	m.HealthCheckPath = "/health" // Fixed path, not configurable via YAML
-	m.ErrorsLogPath = resolveEnvString(temp.ErrorsLogPath)
+	s.WebhookURL = resolveEnvString(temp.WebhookURL)
+	s.WebhookFormat = resolveEnvString(temp.WebhookFormat)
+	s.Alerts = temp.Alerts
 
 	return nil
 }
 
-// UnmarshalYAML implements custom unmarshaling for LiteLLMDBConfig with env variable support
-func (l *LiteLLMDBConfig) UnmarshalYAML(value *yaml.Node) error {
+// UnmarshalYAML implements custom unmarshaling for SLOAlertConfig with env variable support
+func (a *SLOAlertConfig) UnmarshalYAML(value *yaml.Node) error {
 	type tempConfig struct {
-		Enabled             string `yaml:"enabled"`
-		IsRequired          string `yaml:"is_required"`
-		DatabaseURL         string `yaml:"database_url"`
-		MaxConns            string `yaml:"max_conns"`
-		MinConns            string `yaml:"min_conns"`
-		HealthCheckInterval string `yaml:"health_check_interval"`
-		ConnectTimeout      string `yaml:"connect_timeout"`
-		AuthCacheTTL        string `yaml:"auth_cache_ttl"`
-		AuthCacheSize       string `yaml:"auth_cache_size"`
-		LogQueueSize        string `yaml:"log_queue_size"`
-		LogBatchSize        string `yaml:"log_batch_size"`
-		LogFlushInterval    string `yaml:"log_flush_interval"`
+		Name        string `yaml:"name"`
+		LongWindow  string `yaml:"long_window"`
+		ShortWindow string `yaml:"short_window"`
+		BurnRate    string `yaml:"burn_rate"`
 	}
 
 	var temp tempConfig
@@ -325,320 +841,3045 @@ func (l *LiteLLMDBConfig) UnmarshalYAML(value *yaml.Node) error {
 		return err
 	}
 
-	var err error
-
-	l.DatabaseURL = resolveEnvString(temp.DatabaseURL)
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
 
-	// Boolean fields
-	if l.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "litellm_db.enabled"); err != nil {
+	var err error
+	if a.LongWindow, err = parseField(temp.LongWindow, 0, time.ParseDuration, "monitoring.slo.alerts.long_window"); err != nil {
 		return err
 	}
-	if l.IsRequired, err = parseField(temp.IsRequired, false, strconv.ParseBool, "litellm_db.is_required"); err != nil {
+	if a.ShortWindow, err = parseField(temp.ShortWindow, 0, time.ParseDuration, "monitoring.slo.alerts.short_window"); err != nil {
 		return err
 	}
-
-	// Integer fields (defaults optimized for ~1000 requests/minute)
-	if l.MaxConns, err = parseField(temp.MaxConns, 25, strconv.Atoi, "litellm_db.max_conns"); err != nil {
+	if a.BurnRate, err = parseField(temp.BurnRate, 0, parseFloat, "monitoring.slo.alerts.burn_rate"); err != nil {
 		return err
 	}
-	if l.MinConns, err = parseField(temp.MinConns, 5, strconv.Atoi, "litellm_db.min_conns"); err != nil {
-		return err
+	a.Name = resolveEnvString(temp.Name)
+
+	return nil
+}
+
+// validate checks the objectives and alerts and fills in defaults
+func (s *SLOConfig) validate() error {
+	if !s.Enabled {
+		return nil
 	}
-	if l.AuthCacheSize, err = parseField(temp.AuthCacheSize, 10000, strconv.Atoi, "litellm_db.auth_cache_size"); err != nil {
-		return err
+	if s.LatencyTarget == 0 {
+		s.LatencyTarget = DefaultSLOLatencyTarget
 	}
-	if l.LogQueueSize, err = parseField(temp.LogQueueSize, 5000, strconv.Atoi, "litellm_db.log_queue_size"); err != nil {
-		return err
+	if s.EvaluationInterval == 0 {
+		s.EvaluationInterval = DefaultSLOEvaluationInterval
 	}
-	if l.LogBatchSize, err = parseField(temp.LogBatchSize, 100, strconv.Atoi, "litellm_db.log_batch_size"); err != nil {
-		return err
+	if s.WebhookFormat == "" {
+		s.WebhookFormat = WebhookFormatJSON
 	}
-	// Duration fields
-	if l.HealthCheckInterval, err = parseField(temp.HealthCheckInterval, 10*time.Second, time.ParseDuration, "litellm_db.health_check_interval"); err != nil {
-		return err
+	if len(s.Alerts) == 0 {
+		s.Alerts = append([]SLOAlertConfig(nil), DefaultSLOAlerts...)
 	}
-	if l.ConnectTimeout, err = parseField(temp.ConnectTimeout, 5*time.Second, time.ParseDuration, "litellm_db.connect_timeout"); err != nil {
-		return err
+
+	if s.Availability == 0 && s.LatencyThreshold == 0 {
+		return fmt.Errorf("monitoring.slo requires availability or latency_threshold")
 	}
-	if l.AuthCacheTTL, err = parseField(temp.AuthCacheTTL, 5*time.Second, time.ParseDuration, "litellm_db.auth_cache_ttl"); err != nil {
-		return err
+	if s.Availability < 0 || s.Availability >= 100 {
+		return fmt.Errorf("invalid monitoring.slo.availability: %v (must be between 0 and 100, exclusive)", s.Availability)
 	}
-	if l.LogFlushInterval, err = parseField(temp.LogFlushInterval, 5*time.Second, time.ParseDuration, "litellm_db.log_flush_interval"); err != nil {
-		return err
+	if s.LatencyThreshold < 0 {
+		return fmt.Errorf("invalid monitoring.slo.latency_threshold: %v", s.LatencyThreshold)
+	}
+	if s.LatencyTarget <= 0 || s.LatencyTarget >= 100 {
+		return fmt.Errorf("invalid monitoring.slo.latency_target: %v (must be between 0 and 100, exclusive)", s.LatencyTarget)
+	}
+	if s.EvaluationInterval < 0 {
+		return fmt.Errorf("invalid monitoring.slo.evaluation_interval: %v", s.EvaluationInterval)
+	}
+	if s.WebhookURL != "" {
+		if u, err := url.Parse(s.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid monitoring.slo.webhook_url: %s (must be an http or https URL)", s.WebhookURL)
+		}
+	}
+	if s.WebhookFormat != WebhookFormatJSON && s.WebhookFormat != WebhookFormatSlack {
+		return fmt.Errorf("invalid monitoring.slo.webhook_format: %q (must be %q or %q)", s.WebhookFormat, WebhookFormatJSON, WebhookFormatSlack)
 	}
 
+	names := make(map[string]bool, len(s.Alerts))
+	for i, alert := range s.Alerts {
+		if alert.Name == "" {
+			return fmt.Errorf("monitoring.slo.alerts[%d]: name is required", i)
+		}
+		if names[alert.Name] {
+			return fmt.Errorf("monitoring.slo.alerts[%d]: duplicate name %q", i, alert.Name)
+		}
+		names[alert.Name] = true
+		if alert.ShortWindow < time.Minute || alert.LongWindow <= alert.ShortWindow || alert.LongWindow > MaxSLOWindow {
+			return fmt.Errorf("monitoring.slo.alerts %q: invalid windows %v/%v (need 1m <= short_window < long_window <= %v)",
+				alert.Name, alert.ShortWindow, alert.LongWindow, MaxSLOWindow)
+		}
+		if alert.BurnRate <= 0 {
+			return fmt.Errorf("monitoring.slo.alerts %q: invalid burn_rate %v (must be > 0)", alert.Name, alert.BurnRate)
+		}
+	}
 	return nil
 }
 
-// UnmarshalYAML implements custom unmarshaling for Fail2BanConfig
-func (f *Fail2BanConfig) UnmarshalYAML(value *yaml.Node) error {
-	// Create a temporary struct with string ban_duration
-	type tempConfig struct {
-		MaxAttempts    string                `yaml:"max_attempts,omitempty"`
-		BanDuration    string                `yaml:"ban_duration,omitempty"`
-		ErrorCodes     []int                 `yaml:"error_codes,omitempty"`
-		ErrorCodeRules []ErrorCodeRuleConfig `yaml:"error_code_rules,omitempty"`
+// RequestSamplingConfig configures logging of full (redacted) requests and responses for a
+// percentage of requests and for selected API keys. Both can be changed at runtime via the
+// admin API; the config only sets the startup values.
+type RequestSamplingConfig struct {
+	Percent      float64  `yaml:"percent"`        // Share of all requests logged, 0-100 (default: 0)
+	Keys         []string `yaml:"keys"`           // API keys (sk-...) or their hashes whose requests are always logged
+	LogPath      string   `yaml:"log_path"`       // JSON lines file (default: application log)
+	MaxBodyBytes int      `yaml:"max_body_bytes"` // Logged body size limit per request and response (default: 16384)
+}
+
+// UnmarshalYAML implements custom unmarshaling for RequestSamplingConfig with env variable support
+func (r *RequestSamplingConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Percent      string   `yaml:"percent"`
+		Keys         []string `yaml:"keys"`
+		LogPath      string   `yaml:"log_path"`
+		MaxBodyBytes string   `yaml:"max_body_bytes"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
 	}
+
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+
 	var err error
+	if r.Percent, err = parseField(temp.Percent, 0, parseFloat, "monitoring.request_sampling.percent"); err != nil {
+		return err
+	}
+	if r.MaxBodyBytes, err = parseField(temp.MaxBodyBytes, DefaultRequestSamplingMaxBodyBytes, strconv.Atoi, "monitoring.request_sampling.max_body_bytes"); err != nil {
+		return err
+	}
+
+	r.Keys = make([]string, 0, len(temp.Keys))
+	for _, key := range temp.Keys {
+		r.Keys = append(r.Keys, resolveEnvString(key))
+	}
+	r.LogPath = resolveEnvString(temp.LogPath)
+
+	return nil
+}
+
+// SpendPushConfig configures pushing per-request spend/token counters to a Prometheus Pushgateway.
+// Useful when litellm_db is disabled but cost dashboards are still required.
+type SpendPushConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	PushgatewayURL string        `yaml:"pushgateway_url"`  // e.g. http://pushgateway:9091 - supports os.environ/VAR_NAME
+	Job            string        `yaml:"job"`              // Pushgateway job label (default: auto_ai_router)
+	Instance       string        `yaml:"instance"`         // Pushgateway instance grouping label (default: hostname)
+	Interval       time.Duration `yaml:"interval"`         // Push interval (default: 15s)
+	Username       string        `yaml:"username"`         // Optional basic auth username
+	Password       string        `yaml:"password"`         // Optional basic auth password - supports os.environ/VAR_NAME
+	MaxLabelValues int           `yaml:"max_label_values"` // Distinct team_id / user_id values pushed before the rest is reported as "other" (default: 100)
+}
+
+// UnmarshalYAML implements custom unmarshaling for SpendPushConfig with env variable support
+func (s *SpendPushConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled        string `yaml:"enabled"`
+		PushgatewayURL string `yaml:"pushgateway_url"`
+		Job            string `yaml:"job"`
+		Instance       string `yaml:"instance"`
+		Interval       string `yaml:"interval"`
+		Username       string `yaml:"username"`
+		Password       string `yaml:"password"`
+		MaxLabelValues string `yaml:"max_label_values"`
+	}
+
 	var temp tempConfig
 	if err := value.Decode(&temp); err != nil {
 		return err
 	}
 
-	if f.MaxAttempts, err = parseField(temp.MaxAttempts, DefaultMaxAttempts, strconv.Atoi, "fail2ban.max_attempts"); err != nil {
+	var err error
+	if s.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "monitoring.spend_push.enabled"); err != nil {
+		return err
+	}
+	if s.Interval, err = parseField(temp.Interval, DefaultSpendPushInterval, time.ParseDuration, "monitoring.spend_push.interval"); err != nil {
+		return err
+	}
+	if s.MaxLabelValues, err = parseField(temp.MaxLabelValues, DefaultSpendPushMaxLabelValues, strconv.Atoi, "monitoring.spend_push.max_label_values"); err != nil {
+		return err
+	}
+
+	s.PushgatewayURL = resolveEnvString(temp.PushgatewayURL)
+	s.Job = resolveEnvString(temp.Job)
+	s.Instance = resolveEnvString(temp.Instance)
+	s.Username = resolveEnvString(temp.Username)
+	s.Password = resolveEnvString(temp.Password)
+
+	return nil
+}
+
+// LiteLLMDBConfig holds configuration for LiteLLM database integration
+type LiteLLMDBConfig struct {
+	// Enable/disable module
+	Enabled bool `yaml:"enabled"`
+
+	// IsRequired specifies whether LiteLLM DB is mandatory (fail startup on error)
+	// or optional (degrade to NoopManager with warning on error)
+	IsRequired bool `yaml:"is_required"` // default: false
+
+	// Database connection postgresql://[user[:password]@][netloc][:port][/dbname][?param1=value1&...]
+	DatabaseURL string `yaml:"database_url"` // os.environ/LITELLM_DATABASE_URL
+	MaxConns    int    `yaml:"max_conns"`    // default: 10
+	MinConns    int    `yaml:"min_conns"`    // default: 2
+
+	// Optional read replica for auth lookups; spend writes always use database_url
+	ReplicaURL string `yaml:"replica_url,omitempty"` // os.environ/LITELLM_REPLICA_URL
+
+	// Health check
+	HealthCheckInterval    time.Duration `yaml:"health_check_interval"`    // default: 10s
+	ConnectTimeout         time.Duration `yaml:"connect_timeout"`          // default: 5s
+	HealthFailureThreshold int           `yaml:"health_failure_threshold"` // Consecutive failed checks before unhealthy (default: 3)
+
+	// Auth cache
+	AuthCacheTTL  time.Duration `yaml:"auth_cache_ttl"`  // default: 5s
+	AuthCacheSize int           `yaml:"auth_cache_size"` // default: 10000
+
+	// Spend logging
+	LogQueueSize     int           `yaml:"log_queue_size"`     // default: 10000
+	LogBatchSize     int           `yaml:"log_batch_size"`     // default: 100
+	LogFlushInterval time.Duration `yaml:"log_flush_interval"` // default: 5s
+}
+
+// UnmarshalYAML implements custom unmarshaling for MonitoringConfig with env variable support
+func (m *MonitoringConfig) UnmarshalYAML(value *yaml.Node) error {
+	// Create a temporary struct with all string fields
+	type tempConfig struct {
+		PrometheusEnabled string                 `yaml:"prometheus_enabled"`
+		LogErrors         string                 `yaml:"log_errors,omitempty"`
+		ErrorsLogPath     string                 `yaml:"errors_log_path,omitempty"`
+		SpendPush         SpendPushConfig        `yaml:"spend_push,omitempty"`
+		RequestSampling   *RequestSamplingConfig `yaml:"request_sampling,omitempty"`
+		SLO               SLOConfig              `yaml:"slo,omitempty"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	// Resolve and parse boolean fields
+	var err error
+	if m.PrometheusEnabled, err = parseField(temp.PrometheusEnabled, false, strconv.ParseBool, "prometheus_enabled"); err != nil {
+		return err
+	}
+	if m.LogErrors, err = parseField(temp.LogErrors, false, strconv.ParseBool, "log_errors"); err != nil {
+		return err
+	}
+
+	// Resolve string fields
+	m.HealthCheckPath = "/health" // Fixed path, not configurable via YAML
+	m.ErrorsLogPath = resolveEnvString(temp.ErrorsLogPath)
+	m.SpendPush = temp.SpendPush
+	m.SLO = temp.SLO
+	m.RequestSampling = RequestSamplingConfig{MaxBodyBytes: DefaultRequestSamplingMaxBodyBytes}
+	if temp.RequestSampling != nil {
+		m.RequestSampling = *temp.RequestSampling
+	}
+
+	return nil
+}
+
+// UnmarshalYAML implements custom unmarshaling for LiteLLMDBConfig with env variable support
+func (l *LiteLLMDBConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled             string `yaml:"enabled"`
+		IsRequired          string `yaml:"is_required"`
+		DatabaseURL         string `yaml:"database_url"`
+		ReplicaURL          string `yaml:"replica_url,omitempty"`
+		MaxConns            string `yaml:"max_conns"`
+		MinConns            string `yaml:"min_conns"`
+		HealthCheckInterval string `yaml:"health_check_interval"`
+		ConnectTimeout      string `yaml:"connect_timeout"`
+		HealthFailures      string `yaml:"health_failure_threshold"`
+		AuthCacheTTL        string `yaml:"auth_cache_ttl"`
+		AuthCacheSize       string `yaml:"auth_cache_size"`
+		LogQueueSize        string `yaml:"log_queue_size"`
+		LogBatchSize        string `yaml:"log_batch_size"`
+		LogFlushInterval    string `yaml:"log_flush_interval"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+
+	l.DatabaseURL = resolveEnvString(temp.DatabaseURL)
+	l.ReplicaURL = resolveEnvString(temp.ReplicaURL)
+
+	// Boolean fields
+	if l.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "litellm_db.enabled"); err != nil {
+		return err
+	}
+	if l.IsRequired, err = parseField(temp.IsRequired, false, strconv.ParseBool, "litellm_db.is_required"); err != nil {
+		return err
+	}
+
+	// Integer fields (defaults optimized for ~1000 requests/minute)
+	if l.MaxConns, err = parseField(temp.MaxConns, 25, strconv.Atoi, "litellm_db.max_conns"); err != nil {
+		return err
+	}
+	if l.MinConns, err = parseField(temp.MinConns, 5, strconv.Atoi, "litellm_db.min_conns"); err != nil {
+		return err
+	}
+	if l.AuthCacheSize, err = parseField(temp.AuthCacheSize, 10000, strconv.Atoi, "litellm_db.auth_cache_size"); err != nil {
+		return err
+	}
+	if l.LogQueueSize, err = parseField(temp.LogQueueSize, 5000, strconv.Atoi, "litellm_db.log_queue_size"); err != nil {
+		return err
+	}
+	if l.LogBatchSize, err = parseField(temp.LogBatchSize, 100, strconv.Atoi, "litellm_db.log_batch_size"); err != nil {
+		return err
+	}
+	if l.HealthFailureThreshold, err = parseField(temp.HealthFailures, 3, strconv.Atoi, "litellm_db.health_failure_threshold"); err != nil {
+		return err
+	}
+	// Duration fields
+	if l.HealthCheckInterval, err = parseField(temp.HealthCheckInterval, 10*time.Second, time.ParseDuration, "litellm_db.health_check_interval"); err != nil {
+		return err
+	}
+	if l.ConnectTimeout, err = parseField(temp.ConnectTimeout, 5*time.Second, time.ParseDuration, "litellm_db.connect_timeout"); err != nil {
+		return err
+	}
+	if l.AuthCacheTTL, err = parseField(temp.AuthCacheTTL, 5*time.Second, time.ParseDuration, "litellm_db.auth_cache_ttl"); err != nil {
+		return err
+	}
+	if l.LogFlushInterval, err = parseField(temp.LogFlushInterval, 5*time.Second, time.ParseDuration, "litellm_db.log_flush_interval"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// StartupCheckConfig controls connectivity probing of all credentials at startup
+type StartupCheckConfig struct {
+	Enabled     bool          `yaml:"enabled"`     // Probe every credential at startup (default: false, only proxies are checked)
+	Strict      bool          `yaml:"strict"`      // Refuse to start if a credential marked as required fails (default: false)
+	Timeout     time.Duration `yaml:"timeout"`     // Per-credential probe timeout (default: 10s)
+	Concurrency int           `yaml:"concurrency"` // Maximum number of parallel probes (default: 8)
+}
+
+// UnmarshalYAML implements custom unmarshaling for StartupCheckConfig with env variable support
+func (s *StartupCheckConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled     string `yaml:"enabled"`
+		Strict      string `yaml:"strict"`
+		Timeout     string `yaml:"timeout"`
+		Concurrency string `yaml:"concurrency"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if s.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "startup_check.enabled"); err != nil {
+		return err
+	}
+	if s.Strict, err = parseField(temp.Strict, false, strconv.ParseBool, "startup_check.strict"); err != nil {
+		return err
+	}
+	if s.Timeout, err = parseField(temp.Timeout, DefaultStartupCheckTimeout, time.ParseDuration, "startup_check.timeout"); err != nil {
+		return err
+	}
+	if s.Concurrency, err = parseField(temp.Concurrency, DefaultStartupCheckConcurrency, strconv.Atoi, "startup_check.concurrency"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AdaptiveLimitsConfig enables AIMD tuning of credential/model RPM and TPM limits:
+// limits shrink when a credential returns 429 and grow back while it keeps succeeding
+type AdaptiveLimitsConfig struct {
+	Enabled          bool          `yaml:"enabled"`           // Adjust effective limits from upstream 429s (default: false)
+	DecreaseFactor   float64       `yaml:"decrease_factor"`   // Limit multiplier applied on 429 (default: 0.5)
+	IncreaseStep     float64       `yaml:"increase_step"`     // Fraction of the configured limit restored per increase_interval (default: 0.1)
+	IncreaseInterval time.Duration `yaml:"increase_interval"` // Time without 429s between increases (default: 30s)
+	MinFactor        float64       `yaml:"min_factor"`        // Lowest fraction of the configured limit (default: 0.1)
+	Cooldown         time.Duration `yaml:"cooldown"`          // Minimum time between decreases (default: 10s)
+}
+
+// UnmarshalYAML implements custom unmarshaling for AdaptiveLimitsConfig with env variable support
+func (a *AdaptiveLimitsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled          string `yaml:"enabled"`
+		DecreaseFactor   string `yaml:"decrease_factor"`
+		IncreaseStep     string `yaml:"increase_step"`
+		IncreaseInterval string `yaml:"increase_interval"`
+		MinFactor        string `yaml:"min_factor"`
+		Cooldown         string `yaml:"cooldown"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
 		return err
 	}
 
-	if temp.BanDuration == "permanent" {
-		f.BanDuration = DefaultBanDuration
-	} else {
-		if f.BanDuration, err = parseField(temp.BanDuration, DefaultBanDuration, time.ParseDuration, "ban_duration"); err != nil {
-			return err
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+
+	var err error
+	if a.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "adaptive_limits.enabled"); err != nil {
+		return err
+	}
+	if a.DecreaseFactor, err = parseField(temp.DecreaseFactor, DefaultAdaptiveDecreaseFactor, parseFloat, "adaptive_limits.decrease_factor"); err != nil {
+		return err
+	}
+	if a.IncreaseStep, err = parseField(temp.IncreaseStep, DefaultAdaptiveIncreaseStep, parseFloat, "adaptive_limits.increase_step"); err != nil {
+		return err
+	}
+	if a.IncreaseInterval, err = parseField(temp.IncreaseInterval, DefaultAdaptiveIncreaseInterval, time.ParseDuration, "adaptive_limits.increase_interval"); err != nil {
+		return err
+	}
+	if a.MinFactor, err = parseField(temp.MinFactor, DefaultAdaptiveMinFactor, parseFloat, "adaptive_limits.min_factor"); err != nil {
+		return err
+	}
+	if a.Cooldown, err = parseField(temp.Cooldown, DefaultAdaptiveCooldown, time.ParseDuration, "adaptive_limits.cooldown"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FairSchedulerConfig bounds concurrent requests and shares admission between API keys
+// with weighted round robin, so one noisy key cannot starve the others
+type FairSchedulerConfig struct {
+	Enabled       bool           `yaml:"enabled"`        // Queue requests per key when the router is at capacity (default: false)
+	MaxConcurrent int            `yaml:"max_concurrent"` // Requests processed concurrently across all keys (default: 256)
+	MaxQueue      int            `yaml:"max_queue"`      // Requests waiting for admission across all keys (default: 1024)
+	QueueTimeout  time.Duration  `yaml:"queue_timeout"`  // Maximum wait for admission (default: 30s)
+	DefaultWeight int            `yaml:"default_weight"` // Share of keys without an explicit weight (default: 1)
+	Weights       map[string]int `yaml:"weights"`        // Key alias or team ID -> share of admissions per round
+}
+
+// UnmarshalYAML implements custom unmarshaling for FairSchedulerConfig with env variable support
+func (f *FairSchedulerConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled       string         `yaml:"enabled"`
+		MaxConcurrent string         `yaml:"max_concurrent"`
+		MaxQueue      string         `yaml:"max_queue"`
+		QueueTimeout  string         `yaml:"queue_timeout"`
+		DefaultWeight string         `yaml:"default_weight"`
+		Weights       map[string]int `yaml:"weights"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if f.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "fair_scheduler.enabled"); err != nil {
+		return err
+	}
+	if f.MaxConcurrent, err = parseField(temp.MaxConcurrent, DefaultFairSchedulerMaxConcurrent, strconv.Atoi, "fair_scheduler.max_concurrent"); err != nil {
+		return err
+	}
+	if f.MaxQueue, err = parseField(temp.MaxQueue, DefaultFairSchedulerMaxQueue, strconv.Atoi, "fair_scheduler.max_queue"); err != nil {
+		return err
+	}
+	if f.QueueTimeout, err = parseField(temp.QueueTimeout, DefaultFairSchedulerQueueTimeout, time.ParseDuration, "fair_scheduler.queue_timeout"); err != nil {
+		return err
+	}
+	if f.DefaultWeight, err = parseField(temp.DefaultWeight, 1, strconv.Atoi, "fair_scheduler.default_weight"); err != nil {
+		return err
+	}
+	f.Weights = temp.Weights
+
+	return nil
+}
+
+// ImageInliningConfig makes the router fetch remote image URLs itself and send them
+// to non-OpenAI providers as inline base64 data
+type ImageInliningConfig struct {
+	Enabled              bool          `yaml:"enabled"`                // Inline http(s) image_url content (default: false)
+	MaxSizeMB            int           `yaml:"max_size_mb"`            // Maximum image size (default: 10)
+	AllowedMIMETypes     []string      `yaml:"allowed_mime_types"`     // Accepted image types (default: png, jpeg, gif, webp)
+	Timeout              time.Duration `yaml:"timeout"`                // Per-image fetch timeout (default: 10s)
+	CacheTTL             time.Duration `yaml:"cache_ttl"`              // How long fetched images are reused (default: 10m)
+	CacheSize            int           `yaml:"cache_size"`             // Maximum number of cached images (default: 128)
+	AllowPrivateNetworks bool          `yaml:"allow_private_networks"` // Allow URLs resolving to private/loopback addresses (default: false)
+	MaxImages            int           `yaml:"max_images"`             // Maximum distinct image URLs inlined per request (default: 16)
+	MaxConcurrentFetches int           `yaml:"max_concurrent_fetches"` // Maximum concurrent image downloads (default: 8)
+}
+
+// UnmarshalYAML implements custom unmarshaling for ImageInliningConfig with env variable support
+func (i *ImageInliningConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled              string   `yaml:"enabled"`
+		MaxSizeMB            string   `yaml:"max_size_mb"`
+		AllowedMIMETypes     []string `yaml:"allowed_mime_types"`
+		Timeout              string   `yaml:"timeout"`
+		CacheTTL             string   `yaml:"cache_ttl"`
+		CacheSize            string   `yaml:"cache_size"`
+		AllowPrivateNetworks string   `yaml:"allow_private_networks"`
+		MaxImages            string   `yaml:"max_images"`
+		MaxConcurrentFetches string   `yaml:"max_concurrent_fetches"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if i.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "image_inlining.enabled"); err != nil {
+		return err
+	}
+	if i.MaxSizeMB, err = parseField(temp.MaxSizeMB, DefaultImageInliningMaxSizeMB, strconv.Atoi, "image_inlining.max_size_mb"); err != nil {
+		return err
+	}
+	if i.Timeout, err = parseField(temp.Timeout, DefaultImageInliningTimeout, time.ParseDuration, "image_inlining.timeout"); err != nil {
+		return err
+	}
+	if i.CacheTTL, err = parseField(temp.CacheTTL, DefaultImageInliningCacheTTL, time.ParseDuration, "image_inlining.cache_ttl"); err != nil {
+		return err
+	}
+	if i.CacheSize, err = parseField(temp.CacheSize, DefaultImageInliningCacheSize, strconv.Atoi, "image_inlining.cache_size"); err != nil {
+		return err
+	}
+	if i.AllowPrivateNetworks, err = parseField(temp.AllowPrivateNetworks, false, strconv.ParseBool, "image_inlining.allow_private_networks"); err != nil {
+		return err
+	}
+	if i.MaxImages, err = parseField(temp.MaxImages, DefaultImageInliningMaxImages, strconv.Atoi, "image_inlining.max_images"); err != nil {
+		return err
+	}
+	if i.MaxConcurrentFetches, err = parseField(temp.MaxConcurrentFetches, DefaultImageInliningMaxConcurrentFetches, strconv.Atoi, "image_inlining.max_concurrent_fetches"); err != nil {
+		return err
+	}
+	i.AllowedMIMETypes = temp.AllowedMIMETypes
+
+	return nil
+}
+
+// DNSCacheConfig caches the DNS results of upstream hosts, so new upstream connections do not
+// wait for the resolver. Addresses are reused for ttl regardless of the record TTL.
+type DNSCacheConfig struct {
+	Enabled bool          `yaml:"enabled"` // Cache upstream DNS results (default: false)
+	TTL     time.Duration `yaml:"ttl"`     // How long resolved addresses are reused (default: 1m)
+}
+
+// UnmarshalYAML implements custom unmarshaling for DNSCacheConfig with env variable support
+func (d *DNSCacheConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string `yaml:"enabled"`
+		TTL     string `yaml:"ttl"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if d.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "dns_cache.enabled"); err != nil {
+		return err
+	}
+	if d.TTL, err = parseField(temp.TTL, DefaultDNSCacheTTL, time.ParseDuration, "dns_cache.ttl"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FaultInjectionConfig injects synthetic upstream failures into the proxy transport (dev mode),
+// so fail2ban rules, fallback chains and client retries can be tested without real provider errors
+type FaultInjectionConfig struct {
+	Enabled bool              `yaml:"enabled"` // Inject faults into upstream requests (default: false, never enable in production)
+	Rules   []FaultRuleConfig `yaml:"rules"`   // Per-credential fault probabilities
+}
+
+// FaultRuleConfig sets fault probabilities for one credential ("*" matches all credentials without a rule)
+type FaultRuleConfig struct {
+	Credential             string        `yaml:"credential"`               // Credential name or "*"
+	RateLimitProbability   float64       `yaml:"rate_limit_probability"`   // Probability of a synthetic 429 response
+	ServerErrorProbability float64       `yaml:"server_error_probability"` // Probability of a synthetic 500 response
+	Latency                time.Duration `yaml:"latency"`                  // Delay added before the request is sent
+	LatencyProbability     float64       `yaml:"latency_probability"`      // Probability of adding Latency (default: 1 when latency is set)
+}
+
+// UnmarshalYAML implements custom unmarshaling for FaultInjectionConfig with env variable support
+func (f *FaultInjectionConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string            `yaml:"enabled"`
+		Rules   []FaultRuleConfig `yaml:"rules"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if f.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "fault_injection.enabled"); err != nil {
+		return err
+	}
+	f.Rules = temp.Rules
+
+	return nil
+}
+
+// UnmarshalYAML implements custom unmarshaling for FaultRuleConfig with env variable support
+func (r *FaultRuleConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Credential             string `yaml:"credential"`
+		RateLimitProbability   string `yaml:"rate_limit_probability"`
+		ServerErrorProbability string `yaml:"server_error_probability"`
+		Latency                string `yaml:"latency"`
+		LatencyProbability     string `yaml:"latency_probability"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+
+	var err error
+	r.Credential = resolveEnvString(temp.Credential)
+	if r.RateLimitProbability, err = parseField(temp.RateLimitProbability, 0, parseFloat, "fault_injection.rules.rate_limit_probability"); err != nil {
+		return err
+	}
+	if r.ServerErrorProbability, err = parseField(temp.ServerErrorProbability, 0, parseFloat, "fault_injection.rules.server_error_probability"); err != nil {
+		return err
+	}
+	if r.Latency, err = parseField(temp.Latency, 0, time.ParseDuration, "fault_injection.rules.latency"); err != nil {
+		return err
+	}
+	if r.LatencyProbability, err = parseField(temp.LatencyProbability, 0, parseFloat, "fault_injection.rules.latency_probability"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	DefaultUsageForecastInterval      = time.Minute
+	DefaultUsageForecastWindow        = 15 * time.Minute
+	DefaultUsageForecastWarnThreshold = 2 * time.Hour
+)
+
+// UsageForecastConfig forecasts when credentials exhaust their quota (credentials[].quota)
+// at the current usage rate
+type UsageForecastConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Interval      time.Duration `yaml:"interval"`       // How often metrics and warnings are refreshed (default: 1m)
+	Window        time.Duration `yaml:"window"`         // Rolling window the usage rate is measured over (default: 15m)
+	WarnThreshold time.Duration `yaml:"warn_threshold"` // Warn when a quota runs out within this duration (default: 2h)
+}
+
+// UnmarshalYAML implements custom unmarshaling for UsageForecastConfig with env variable support
+func (u *UsageForecastConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled       string `yaml:"enabled"`
+		Interval      string `yaml:"interval"`
+		Window        string `yaml:"window"`
+		WarnThreshold string `yaml:"warn_threshold"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if u.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "usage_forecast.enabled"); err != nil {
+		return err
+	}
+	if u.Interval, err = parseField(temp.Interval, DefaultUsageForecastInterval, time.ParseDuration, "usage_forecast.interval"); err != nil {
+		return err
+	}
+	if u.Window, err = parseField(temp.Window, DefaultUsageForecastWindow, time.ParseDuration, "usage_forecast.window"); err != nil {
+		return err
+	}
+	if u.WarnThreshold, err = parseField(temp.WarnThreshold, DefaultUsageForecastWarnThreshold, time.ParseDuration, "usage_forecast.warn_threshold"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Context management strategies
+const (
+	ContextStrategyTruncate  = "truncate"  // Drop the oldest non-system messages
+	ContextStrategySummarize = "summarize" // Replace the oldest non-system messages with a summary
+)
+
+const (
+	DefaultContextKeepRecent       = 1
+	DefaultContextSummaryMaxTokens = 512
+)
+
+// ContextManagementConfig keeps multi-turn Chat Completions requests within the model
+// context (model_prices max_input_tokens) or a configured prompt budget
+type ContextManagementConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	Strategy         string `yaml:"strategy"`           // "truncate" or "summarize" (default: truncate)
+	MaxPromptTokens  int    `yaml:"max_prompt_tokens"`  // Prompt budget (0 = model max_input_tokens only)
+	KeepRecent       int    `yaml:"keep_recent"`        // Most recent non-system messages that are never removed (default: 1)
+	SummaryModel     string `yaml:"summary_model"`      // Model used to summarize removed messages (required for summarize)
+	SummaryMaxTokens int    `yaml:"summary_max_tokens"` // max_tokens of summary requests (default: 512)
+}
+
+// UnmarshalYAML implements custom unmarshaling for ContextManagementConfig with env variable support
+func (c *ContextManagementConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled          string `yaml:"enabled"`
+		Strategy         string `yaml:"strategy"`
+		MaxPromptTokens  string `yaml:"max_prompt_tokens"`
+		KeepRecent       string `yaml:"keep_recent"`
+		SummaryModel     string `yaml:"summary_model"`
+		SummaryMaxTokens string `yaml:"summary_max_tokens"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if c.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "context_management.enabled"); err != nil {
+		return err
+	}
+	c.Strategy = resolveEnvString(temp.Strategy)
+	if c.MaxPromptTokens, err = parseField(temp.MaxPromptTokens, 0, strconv.Atoi, "context_management.max_prompt_tokens"); err != nil {
+		return err
+	}
+	if c.KeepRecent, err = parseField(temp.KeepRecent, DefaultContextKeepRecent, strconv.Atoi, "context_management.keep_recent"); err != nil {
+		return err
+	}
+	c.SummaryModel = resolveEnvString(temp.SummaryModel)
+	if c.SummaryMaxTokens, err = parseField(temp.SummaryMaxTokens, DefaultContextSummaryMaxTokens, strconv.Atoi, "context_management.summary_max_tokens"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PostProcessingConfig normalizes provider-specific artifacts in responses converted
+// from Vertex AI, Gemini, Anthropic and Bedrock
+type PostProcessingConfig struct {
+	StripPlaceholders     bool `yaml:"strip_placeholders"`      // Empty content instead of "[No content generated]"-style placeholders
+	TrimLeadingWhitespace bool `yaml:"trim_leading_whitespace"` // Remove whitespace at the start of the assistant content
+	StripStopSequences    bool `yaml:"strip_stop_sequences"`    // Remove a stop sequence echoed at the end of the content
+}
+
+// UnmarshalYAML implements custom unmarshaling for PostProcessingConfig with env variable support
+func (p *PostProcessingConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		StripPlaceholders     string `yaml:"strip_placeholders"`
+		TrimLeadingWhitespace string `yaml:"trim_leading_whitespace"`
+		StripStopSequences    string `yaml:"strip_stop_sequences"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if p.StripPlaceholders, err = parseField(temp.StripPlaceholders, false, strconv.ParseBool, "response_postprocessing.strip_placeholders"); err != nil {
+		return err
+	}
+	if p.TrimLeadingWhitespace, err = parseField(temp.TrimLeadingWhitespace, false, strconv.ParseBool, "response_postprocessing.trim_leading_whitespace"); err != nil {
+		return err
+	}
+	if p.StripStopSequences, err = parseField(temp.StripStopSequences, false, strconv.ParseBool, "response_postprocessing.strip_stop_sequences"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConversionCheckConfig schema-checks request bodies converted for Vertex AI, Gemini,
+// Anthropic and Bedrock before they are sent
+type ConversionCheckConfig struct {
+	Enabled bool `yaml:"enabled"` // Check converted requests and log malformed ones
+	Reject  bool `yaml:"reject"`  // Answer malformed conversions with 500 instead of forwarding them
+}
+
+// UnmarshalYAML implements custom unmarshaling for ConversionCheckConfig with env variable support
+func (c *ConversionCheckConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string `yaml:"enabled"`
+		Reject  string `yaml:"reject"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if c.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "conversion_validation.enabled"); err != nil {
+		return err
+	}
+	if c.Reject, err = parseField(temp.Reject, false, strconv.ParseBool, "conversion_validation.reject"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AttributionConfig returns X-Router-* headers naming the credential, provider and model
+// that served each response to the master key and the listed keys
+type AttributionConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Keys    []string `yaml:"keys"` // Key aliases or team IDs receiving the headers ("*" = all keys)
+}
+
+// UnmarshalYAML implements custom unmarshaling for AttributionConfig with env variable support
+func (a *AttributionConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string   `yaml:"enabled"`
+		Keys    []string `yaml:"keys"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if a.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "attribution_headers.enabled"); err != nil {
+		return err
+	}
+	a.Keys = make([]string, 0, len(temp.Keys))
+	for _, key := range temp.Keys {
+		a.Keys = append(a.Keys, resolveEnvString(key))
+	}
+
+	return nil
+}
+
+// Webhook payload formats of spend reports and SLO alerts
+const (
+	WebhookFormatJSON  = "json"  // The report or alert as JSON
+	WebhookFormatSlack = "slack" // {"text": ...} for Slack incoming webhooks
+)
+
+// Spend report file formats
+const (
+	SpendReportFormatJSON = "json" // File with the report as JSON
+	SpendReportFormatCSV  = "csv"  // File with one row per total/key/team/model entry
+)
+
+const (
+	DefaultSpendReportTime = "00:00"
+	DefaultSpendReportTopN = 10
+)
+
+// SpendReportConfig generates a daily spend report (top keys, teams, models, total spend
+// and error rates) and posts it to a webhook and/or writes it to a directory
+type SpendReportConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Time          string `yaml:"time"`           // UTC time of day the report of the previous 24h is generated, "HH:MM" (default: 00:00)
+	TopN          int    `yaml:"top_n"`          // Entries listed per top keys/teams/models (default: 10)
+	WebhookURL    string `yaml:"webhook_url"`    // POST the report here (optional)
+	WebhookFormat string `yaml:"webhook_format"` // "json" or "slack" (default: json)
+	OutputDir     string `yaml:"output_dir"`     // Write spend-report-YYYY-MM-DD.<format> files here (optional)
+	OutputFormat  string `yaml:"output_format"`  // "json" or "csv" (default: json)
+}
+
+// UnmarshalYAML implements custom unmarshaling for SpendReportConfig with env variable support
+func (s *SpendReportConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled       string `yaml:"enabled"`
+		Time          string `yaml:"time"`
+		TopN          string `yaml:"top_n"`
+		WebhookURL    string `yaml:"webhook_url"`
+		WebhookFormat string `yaml:"webhook_format"`
+		OutputDir     string `yaml:"output_dir"`
+		OutputFormat  string `yaml:"output_format"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if s.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "spend_report.enabled"); err != nil {
+		return err
+	}
+	if s.TopN, err = parseField(temp.TopN, DefaultSpendReportTopN, strconv.Atoi, "spend_report.top_n"); err != nil {
+		return err
+	}
+	s.Time = resolveEnvString(temp.Time)
+	s.WebhookURL = resolveEnvString(temp.WebhookURL)
+	s.WebhookFormat = resolveEnvString(temp.WebhookFormat)
+	s.OutputDir = resolveEnvString(temp.OutputDir)
+	s.OutputFormat = resolveEnvString(temp.OutputFormat)
+
+	return nil
+}
+
+// DefaultLocalSpendLogPath is the SQLite database file used when local_spend_log.path is not set
+const DefaultLocalSpendLogPath = "spend.db"
+
+// LocalSpendLogConfig keeps spend log entries in an embedded SQLite database while LiteLLM DB
+// is disabled, so usage history survives and can be backfilled into Postgres later
+type LocalSpendLogConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Path      string        `yaml:"path"`      // SQLite database file (default: spend.db)
+	Retention time.Duration `yaml:"retention"` // Delete entries older than this (0 = keep forever)
+}
+
+// UnmarshalYAML implements custom unmarshaling for LocalSpendLogConfig with env variable support
+func (l *LocalSpendLogConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled   string `yaml:"enabled"`
+		Path      string `yaml:"path"`
+		Retention string `yaml:"retention"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if l.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "local_spend_log.enabled"); err != nil {
+		return err
+	}
+	if l.Retention, err = parseField(temp.Retention, 0, time.ParseDuration, "local_spend_log.retention"); err != nil {
+		return err
+	}
+	l.Path = resolveEnvString(temp.Path)
+
+	return nil
+}
+
+// DefaultMetricsExportPath is the file the in-memory accounting is exported to by default
+const DefaultMetricsExportPath = "metrics_export.json"
+
+// MetricsExportConfig writes the in-memory usage counters, spend aggregates and limiter
+// snapshots to a JSON file at graceful shutdown, so short-lived instances keep the accounting
+// of their last minutes
+type MetricsExportConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"` // JSON file, replaced at every shutdown (default: metrics_export.json)
+}
+
+// UnmarshalYAML implements custom unmarshaling for MetricsExportConfig with env variable support
+func (m *MetricsExportConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string `yaml:"enabled"`
+		Path    string `yaml:"path"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if m.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "metrics_export.enabled"); err != nil {
+		return err
+	}
+	m.Path = resolveEnvString(temp.Path)
+
+	return nil
+}
+
+// DefaultQuotaBoostMaxDuration is the longest boost accepted when max_duration is not set
+const DefaultQuotaBoostMaxDuration = 7 * 24 * time.Hour
+
+// QuotaBoostsConfig enables the admin API that grants temporary RPM and budget boosts to a
+// key or team. Enabling it also enforces the LiteLLM key and team rpm_limit.
+type QuotaBoostsConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	MaxDuration time.Duration `yaml:"max_duration"` // Longest boost that can be granted (default: 168h)
+}
+
+// UnmarshalYAML implements custom unmarshaling for QuotaBoostsConfig with env variable support
+func (q *QuotaBoostsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled     string `yaml:"enabled"`
+		MaxDuration string `yaml:"max_duration"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if q.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "quota_boosts.enabled"); err != nil {
+		return err
+	}
+	if q.MaxDuration, err = parseField(temp.MaxDuration, DefaultQuotaBoostMaxDuration, time.ParseDuration, "quota_boosts.max_duration"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DefaultPriorityClasses are the class shares used when priority_classes.classes is not set
+var DefaultPriorityClasses = map[string]float64{"gold": 1, "silver": 0.75, "bronze": 0.5}
+
+// PriorityClassesConfig assigns keys and teams to priority classes. Each class may use a share
+// of every credential's RPM/TPM, so lower classes are throttled first under contention.
+type PriorityClassesConfig struct {
+	Enabled      bool               `yaml:"enabled"`
+	Classes      map[string]float64 `yaml:"classes"`       // Class name -> share (0..1] of credential/model RPM and TPM (default: gold 1, silver 0.75, bronze 0.5)
+	DefaultClass string             `yaml:"default_class"` // Class of keys without an assignment ("" = full limits)
+	Assignments  map[string]string  `yaml:"assignments"`   // Key alias or team ID -> class name
+}
+
+// UnmarshalYAML implements custom unmarshaling for PriorityClassesConfig with env variable support
+func (p *PriorityClassesConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled      string             `yaml:"enabled"`
+		Classes      map[string]float64 `yaml:"classes"`
+		DefaultClass string             `yaml:"default_class"`
+		Assignments  map[string]string  `yaml:"assignments"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if p.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "priority_classes.enabled"); err != nil {
+		return err
+	}
+	p.Classes = temp.Classes
+	p.DefaultClass = resolveEnvString(temp.DefaultClass)
+	p.Assignments = temp.Assignments
+
+	return nil
+}
+
+// Stream coalescing defaults
+const (
+	DefaultStreamCoalescingInterval = 25 * time.Millisecond
+	DefaultStreamCoalescingMaxBytes = 4096
+)
+
+// StreamCoalescingConfig merges the content deltas of streamed Chat Completions chunks
+// received within a short window, so clients parse fewer SSE events
+type StreamCoalescingConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`  // Longest time a delta is held before it is flushed (default: 25ms)
+	MaxBytes int           `yaml:"max_bytes"` // Flush earlier once this many bytes are buffered (default: 4096)
+}
+
+// UnmarshalYAML implements custom unmarshaling for StreamCoalescingConfig with env variable support
+func (s *StreamCoalescingConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled  string `yaml:"enabled"`
+		Interval string `yaml:"interval"`
+		MaxBytes string `yaml:"max_bytes"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if s.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "stream_coalescing.enabled"); err != nil {
+		return err
+	}
+	if s.Interval, err = parseField(temp.Interval, DefaultStreamCoalescingInterval, time.ParseDuration, "stream_coalescing.interval"); err != nil {
+		return err
+	}
+	if s.MaxBytes, err = parseField(temp.MaxBytes, DefaultStreamCoalescingMaxBytes, strconv.Atoi, "stream_coalescing.max_bytes"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NonStreamingConfig answers streaming requests of the listed keys with a single JSON response:
+// the router consumes the upstream stream and returns the assembled completion
+type NonStreamingConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Keys    []string `yaml:"keys"` // Key aliases or team IDs whose responses are assembled ("*" = all keys)
+}
+
+// UnmarshalYAML implements custom unmarshaling for NonStreamingConfig with env variable support
+func (n *NonStreamingConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string   `yaml:"enabled"`
+		Keys    []string `yaml:"keys"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if n.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "non_streaming.enabled"); err != nil {
+		return err
+	}
+	n.Keys = make([]string, 0, len(temp.Keys))
+	for _, key := range temp.Keys {
+		n.Keys = append(n.Keys, resolveEnvString(key))
+	}
+
+	return nil
+}
+
+// LiteLLMHeadersConfig returns the x-litellm-* response headers of the LiteLLM proxy
+// (model id, response cost, key spend) for tooling written against LiteLLM
+type LiteLLMHeadersConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// UnmarshalYAML implements custom unmarshaling for LiteLLMHeadersConfig with env variable support
+func (l *LiteLLMHeadersConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string `yaml:"enabled"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if l.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "litellm_headers.enabled"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RoutingOverridesConfig lets the master key and the listed keys steer credential selection
+// per request with X-Router-Prefer-Credential, X-Router-Exclude-Providers and X-Router-Require-Region
+type RoutingOverridesConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Keys    []string `yaml:"keys"` // Key aliases or team IDs allowed to send the headers ("*" = all keys)
+}
+
+// UnmarshalYAML implements custom unmarshaling for RoutingOverridesConfig with env variable support
+func (r *RoutingOverridesConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string   `yaml:"enabled"`
+		Keys    []string `yaml:"keys"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if r.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "routing_overrides.enabled"); err != nil {
+		return err
+	}
+	r.Keys = make([]string, 0, len(temp.Keys))
+	for _, key := range temp.Keys {
+		r.Keys = append(r.Keys, resolveEnvString(key))
+	}
+
+	return nil
+}
+
+// MaxCostPerRequestConfig rejects requests whose worst-case cost (estimated prompt plus
+// max_tokens of output) exceeds the ceiling of their API key
+type MaxCostPerRequestConfig struct {
+	Enabled bool               `yaml:"enabled"`
+	Default float64            `yaml:"default"` // Ceiling in USD of keys without an entry (0 = no ceiling)
+	Keys    map[string]float64 `yaml:"keys"`    // Key alias or team ID -> ceiling in USD (0 = no ceiling)
+}
+
+// UnmarshalYAML implements custom unmarshaling for MaxCostPerRequestConfig with env variable support
+func (m *MaxCostPerRequestConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string             `yaml:"enabled"`
+		Default string             `yaml:"default"`
+		Keys    map[string]float64 `yaml:"keys"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+	if m.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "max_cost_per_request.enabled"); err != nil {
+		return err
+	}
+	if m.Default, err = parseField(temp.Default, 0, parseFloat, "max_cost_per_request.default"); err != nil {
+		return err
+	}
+	m.Keys = temp.Keys
+
+	return nil
+}
+
+// Model pin policies
+const (
+	ModelPinPolicyLatest = "latest" // Follow the newest available snapshot
+	ModelPinPolicyPinned = "pinned" // Pin the newest snapshot once, then move only when bumped
+	ModelPinPolicyManual = "manual" // Use the configured snapshot until bumped
+)
+
+// ModelPinsConfig resolves rolling model aliases (gpt-4o, claude-sonnet-latest) to dated
+// snapshots, so output behavior changes only when operators bump the pin
+type ModelPinsConfig struct {
+	Enabled bool                      `yaml:"enabled"`
+	Aliases map[string]ModelPinConfig `yaml:"aliases"` // Rolling alias -> pin
+}
+
+// ModelPinConfig is the pin of one rolling alias
+type ModelPinConfig struct {
+	Policy   string `yaml:"policy"`   // latest, pinned or manual (default: pinned)
+	Family   string `yaml:"family"`   // Snapshot name prefix (default: the alias without a "-latest" suffix)
+	Snapshot string `yaml:"snapshot"` // Pinned snapshot (required for manual; pinned: default newest at first use)
+}
+
+// UnmarshalYAML implements custom unmarshaling for ModelPinsConfig with env variable support
+func (m *ModelPinsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string                    `yaml:"enabled"`
+		Aliases map[string]ModelPinConfig `yaml:"aliases"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if m.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "model_pins.enabled"); err != nil {
+		return err
+	}
+	m.Aliases = make(map[string]ModelPinConfig, len(temp.Aliases))
+	for alias, pin := range temp.Aliases {
+		pin.Policy = resolveEnvString(pin.Policy)
+		if pin.Policy == "" {
+			pin.Policy = ModelPinPolicyPinned
+		}
+		pin.Family = resolveEnvString(pin.Family)
+		if pin.Family == "" {
+			pin.Family = strings.TrimSuffix(alias, "-latest")
+		}
+		pin.Snapshot = resolveEnvString(pin.Snapshot)
+		m.Aliases[alias] = pin
+	}
+
+	return nil
+}
+
+// ReasoningEfforts are the reasoning_effort values a reasoning group tier may route
+var ReasoningEfforts = []string{"none", "minimal", "low", "medium", "high", "xhigh"}
+
+// ReasoningRoutingConfig exposes model groups: virtual model names whose requests are routed
+// to a model by their reasoning_effort, so clients pick quality tiers without backend names
+type ReasoningRoutingConfig struct {
+	Enabled bool                            `yaml:"enabled"`
+	Groups  map[string]ReasoningGroupConfig `yaml:"groups"` // Group model name -> tiers
+}
+
+// ReasoningGroupConfig maps the reasoning_effort of a model group's requests to models
+type ReasoningGroupConfig struct {
+	Tiers        map[string]string `yaml:"tiers"`         // reasoning_effort -> model
+	Default      string            `yaml:"default"`       // Model of requests without (a tier for) their reasoning_effort
+	NonReasoning []string          `yaml:"non_reasoning"` // Group models that reject reasoning_effort (it is removed)
+}
+
+// ReasoningParamsConfig adapts the requests of reasoning models per model family. A family
+// matches its name and names continuing it after "-" or "." (o4 matches o4-mini); the longest
+// matching family applies.
+type ReasoningParamsConfig struct {
+	Models map[string]ReasoningModelConfig `yaml:"models"` // Model family -> parameter handling
+}
+
+// ReasoningModelConfig is the parameter handling of a reasoning model family. RenameParams and
+// DropParams replace the built-in o1, o3 and gpt-5 rules of the family; ThinkingBudgets replace
+// the built-in budgets of the Gemini 2.5 and Claude thinking that reasoning_effort maps to.
+type ReasoningModelConfig struct {
+	RenameParams    map[string]string `yaml:"rename_params,omitempty"`    // Renamed request params, e.g. max_tokens: max_completion_tokens
+	DropParams      []string          `yaml:"drop_params,omitempty"`      // Removed request params, e.g. temperature, top_p
+	ThinkingBudgets map[string]int    `yaml:"thinking_budgets,omitempty"` // reasoning_effort -> thinking budget tokens (0 = thinking off)
+}
+
+// UnmarshalYAML implements custom unmarshaling for ReasoningParamsConfig: families and
+// reasoning_effort names are matched case-insensitively
+func (r *ReasoningParamsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Models map[string]ReasoningModelConfig `yaml:"models"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	r.Models = make(map[string]ReasoningModelConfig, len(temp.Models))
+	for family, model := range temp.Models {
+		if len(model.ThinkingBudgets) > 0 {
+			budgets := make(map[string]int, len(model.ThinkingBudgets))
+			for effort, budget := range model.ThinkingBudgets {
+				budgets[strings.ToLower(effort)] = budget
+			}
+			model.ThinkingBudgets = budgets
+		}
+		r.Models[strings.ToLower(resolveEnvString(family))] = model
+	}
+	return nil
+}
+
+// UnmarshalYAML implements custom unmarshaling for ReasoningRoutingConfig with env variable support
+func (r *ReasoningRoutingConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string                          `yaml:"enabled"`
+		Groups  map[string]ReasoningGroupConfig `yaml:"groups"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if r.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "reasoning_routing.enabled"); err != nil {
+		return err
+	}
+	r.Groups = make(map[string]ReasoningGroupConfig, len(temp.Groups))
+	for name, group := range temp.Groups {
+		tiers := make(map[string]string, len(group.Tiers))
+		for effort, model := range group.Tiers {
+			tiers[strings.ToLower(effort)] = resolveEnvString(model)
+		}
+		group.Tiers = tiers
+		group.Default = resolveEnvString(group.Default)
+		for i, model := range group.NonReasoning {
+			group.NonReasoning[i] = resolveEnvString(model)
+		}
+		r.Groups[name] = group
+	}
+
+	return nil
+}
+
+// Response header limits
+const (
+	DefaultResponseHeadersMaxCount = 100
+	DefaultResponseHeadersMaxBytes = 32 * 1024
+)
+
+// DefaultStrippedResponseHeaders are upstream response headers never returned to clients:
+// they would set state in the client for the provider's origin rather than the router's
+var DefaultStrippedResponseHeaders = []string{
+	"Set-Cookie", "Set-Cookie2", "Alt-Svc", "Clear-Site-Data", "Strict-Transport-Security", "Refresh",
+}
+
+// ResponseHeadersConfig limits and sanitizes the upstream response headers returned to
+// clients, guarding against misbehaving OpenAI-compatible backends
+type ResponseHeadersConfig struct {
+	MaxCount int      `yaml:"max_count"` // Most upstream headers returned (default: 100, -1 = no limit)
+	MaxBytes int      `yaml:"max_bytes"` // Largest total size of the returned headers (default: 32768, -1 = no limit)
+	Strip    []string `yaml:"strip"`     // Further headers never returned
+	Allow    []string `yaml:"allow"`     // Headers of DefaultStrippedResponseHeaders returned anyway
+}
+
+// UnmarshalYAML implements custom unmarshaling for ResponseHeadersConfig with env variable support
+func (r *ResponseHeadersConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		MaxCount string   `yaml:"max_count"`
+		MaxBytes string   `yaml:"max_bytes"`
+		Strip    []string `yaml:"strip"`
+		Allow    []string `yaml:"allow"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if r.MaxCount, err = parseField(temp.MaxCount, DefaultResponseHeadersMaxCount, strconv.Atoi, "response_headers.max_count"); err != nil {
+		return err
+	}
+	if r.MaxBytes, err = parseField(temp.MaxBytes, DefaultResponseHeadersMaxBytes, strconv.Atoi, "response_headers.max_bytes"); err != nil {
+		return err
+	}
+	r.Strip = make([]string, 0, len(temp.Strip))
+	for _, name := range temp.Strip {
+		r.Strip = append(r.Strip, resolveEnvString(name))
+	}
+	r.Allow = make([]string, 0, len(temp.Allow))
+	for _, name := range temp.Allow {
+		r.Allow = append(r.Allow, resolveEnvString(name))
+	}
+
+	return nil
+}
+
+// DefaultUpstreamErrorsMaxDetailLength is the longest detail of a wrapped upstream error
+const DefaultUpstreamErrorsMaxDetailLength = 512
+
+// UpstreamErrorsConfig wraps upstream 4xx/5xx bodies into the standard error envelope with a
+// truncated detail stripped of hostnames, IP addresses, URLs and stack traces. The full
+// upstream body is still logged.
+type UpstreamErrorsConfig struct {
+	Wrap            bool `yaml:"wrap"`              // Replace upstream error bodies by the error envelope
+	MaxDetailLength int  `yaml:"max_detail_length"` // Longest detail in characters (default: 512)
+}
+
+// UnmarshalYAML implements custom unmarshaling for UpstreamErrorsConfig with env variable support
+func (u *UpstreamErrorsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Wrap            string `yaml:"wrap"`
+		MaxDetailLength string `yaml:"max_detail_length"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if u.Wrap, err = parseField(temp.Wrap, false, strconv.ParseBool, "upstream_errors.wrap"); err != nil {
+		return err
+	}
+	if u.MaxDetailLength, err = parseField(temp.MaxDetailLength, DefaultUpstreamErrorsMaxDetailLength, strconv.Atoi, "upstream_errors.max_detail_length"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// System prompt positions
+const (
+	SystemPromptPrepend = "prepend" // Before the request's first message
+	SystemPromptAppend  = "append"  // After the request's last message
+)
+
+// SystemPromptsConfig adds policy system prompts to Chat Completions requests per key, team
+// and model. A system_prompt in the LiteLLM key metadata, then in the team metadata, takes
+// precedence over the rules.
+type SystemPromptsConfig struct {
+	Enabled bool                 `yaml:"enabled"`
+	Rules   []SystemPromptConfig `yaml:"rules"` // The first matching rule applies
+}
+
+// SystemPromptConfig is the system prompt of the listed keys and models
+type SystemPromptConfig struct {
+	Keys     []string `yaml:"keys"`     // Key aliases or team IDs ("*" = all keys)
+	Models   []string `yaml:"models"`   // Models the prompt is added for (empty = all)
+	Prompt   string   `yaml:"prompt"`   // Template with {{key_alias}}, {{team_id}}, {{team_alias}}, {{user_id}}, {{model}} and {{date}}
+	Position string   `yaml:"position"` // prepend (default) or append
+}
+
+// UnmarshalYAML implements custom unmarshaling for SystemPromptsConfig with env variable support
+func (s *SystemPromptsConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string               `yaml:"enabled"`
+		Rules   []SystemPromptConfig `yaml:"rules"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if s.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "system_prompts.enabled"); err != nil {
+		return err
+	}
+	s.Rules = make([]SystemPromptConfig, 0, len(temp.Rules))
+	for _, rule := range temp.Rules {
+		for i, key := range rule.Keys {
+			rule.Keys[i] = resolveEnvString(key)
+		}
+		rule.Prompt = resolveEnvString(rule.Prompt)
+		rule.Position = resolveEnvString(rule.Position)
+		if rule.Position == "" {
+			rule.Position = SystemPromptPrepend
+		}
+		s.Rules = append(s.Rules, rule)
+	}
+
+	return nil
+}
+
+// ChargebackConfig applies internal chargeback rates (a markup and a per-request fee) on top of
+// the provider cost of requests per key, team and credential. The charged cost is logged in the
+// spend log metadata; spend keeps the provider cost.
+type ChargebackConfig struct {
+	Enabled bool                   `yaml:"enabled"`
+	Rules   []ChargebackRuleConfig `yaml:"rules"` // The first matching rule applies
+}
+
+// ChargebackRuleConfig is the chargeback rate of the listed keys and credentials
+type ChargebackRuleConfig struct {
+	Keys          []string `yaml:"keys"`            // Key aliases or team IDs ("*" = all keys)
+	Credentials   []string `yaml:"credentials"`     // Credentials the rate applies to (empty = all)
+	MarkupPercent float64  `yaml:"markup_percent"`  // Percentage added to the provider cost
+	FeePerRequest float64  `yaml:"fee_per_request"` // Fixed USD fee per successful request
+}
+
+// UnmarshalYAML implements custom unmarshaling for ChargebackConfig with env variable support
+func (c *ChargebackConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string                 `yaml:"enabled"`
+		Rules   []ChargebackRuleConfig `yaml:"rules"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if c.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "chargeback.enabled"); err != nil {
+		return err
+	}
+	c.Rules = make([]ChargebackRuleConfig, 0, len(temp.Rules))
+	for _, rule := range temp.Rules {
+		for i, key := range rule.Keys {
+			rule.Keys[i] = resolveEnvString(key)
+		}
+		for i, cred := range rule.Credentials {
+			rule.Credentials[i] = resolveEnvString(cred)
+		}
+		c.Rules = append(c.Rules, rule)
+	}
+
+	return nil
+}
+
+// DefaultParamNegotiationTTL is how long a learned unsupported parameter is dropped
+const DefaultParamNegotiationTTL = 24 * time.Hour
+
+// ParamNegotiationConfig learns the request parameters a credential rejects for a model with
+// 400 Bad Request and drops them from later requests, like configured unsupported_params
+type ParamNegotiationConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"` // How long a learned parameter is dropped before it is sent again (default: 24h)
+}
+
+// UnmarshalYAML implements custom unmarshaling for ParamNegotiationConfig with env variable support
+func (n *ParamNegotiationConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string `yaml:"enabled"`
+		TTL     string `yaml:"ttl"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if n.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "param_negotiation.enabled"); err != nil {
+		return err
+	}
+	if n.TTL, err = parseField(temp.TTL, DefaultParamNegotiationTTL, time.ParseDuration, "param_negotiation.ttl"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DefaultChoiceFanOutMaxN is the largest n fanned out by default
+const DefaultChoiceFanOutMaxN = 8
+
+// ChoiceFanOutConfig serves chat requests with n > 1 on providers without an n parameter
+// (Anthropic, Bedrock) by sending n upstream requests and merging their choices. Without it
+// such requests are rejected with 422.
+type ChoiceFanOutConfig struct {
+	Enabled bool `yaml:"enabled"`
+	MaxN    int  `yaml:"max_n"` // Largest n fanned out; larger requests are rejected (default: 8)
+}
+
+// UnmarshalYAML implements custom unmarshaling for ChoiceFanOutConfig with env variable support
+func (f *ChoiceFanOutConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Enabled string `yaml:"enabled"`
+		MaxN    string `yaml:"max_n"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	if f.Enabled, err = parseField(temp.Enabled, false, strconv.ParseBool, "n_fanout.enabled"); err != nil {
+		return err
+	}
+	if f.MaxN, err = parseField(temp.MaxN, DefaultChoiceFanOutMaxN, strconv.Atoi, "n_fanout.max_n"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TenantConfig is a namespace with its own credential pool, keys and rate limit, so several
+// teams share one router process without sharing providers. Requests are mapped to a tenant
+// by their master key, LiteLLM key alias or team ID.
+type TenantConfig struct {
+	Name        string   `yaml:"name"`
+	MasterKeys  []string `yaml:"master_keys"` // Keys authenticating as the tenant without LiteLLM DB - supports os.environ/VAR_NAME
+	Keys        []string `yaml:"keys"`        // LiteLLM key aliases of the tenant
+	Teams       []string `yaml:"teams"`       // LiteLLM team IDs of the tenant
+	Credentials []string `yaml:"credentials"` // Names of the credentials only the tenant may use
+	RPM         int      `yaml:"rpm"`         // Requests per minute of the whole tenant (0 = unlimited)
+}
+
+// UnmarshalYAML implements custom unmarshaling for TenantConfig with env variable support
+func (t *TenantConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Name        string   `yaml:"name"`
+		MasterKeys  []string `yaml:"master_keys"`
+		Keys        []string `yaml:"keys"`
+		Teams       []string `yaml:"teams"`
+		Credentials []string `yaml:"credentials"`
+		RPM         string   `yaml:"rpm"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	var err error
+	t.Name = resolveEnvString(temp.Name)
+	if t.RPM, err = parseField(temp.RPM, 0, strconv.Atoi, "tenants.rpm"); err != nil {
+		return err
+	}
+	t.MasterKeys = make([]string, 0, len(temp.MasterKeys))
+	for _, key := range temp.MasterKeys {
+		t.MasterKeys = append(t.MasterKeys, resolveEnvString(key))
+	}
+	t.Keys = temp.Keys
+	t.Teams = temp.Teams
+	t.Credentials = temp.Credentials
+
+	return nil
+}
+
+// CredentialPoolConfig groups credentials that share one provider quota (e.g. all keys of an
+// OpenAI organization). The pool limits apply to the requests of all its credentials together,
+// in addition to each credential's own rpm/tpm.
+type CredentialPoolConfig struct {
+	Name          string   `yaml:"name"`
+	Credentials   []string `yaml:"credentials"`    // Names of the pooled credentials
+	RPM           int      `yaml:"rpm"`            // Requests per minute of the whole pool (0 = unlimited)
+	TPM           int      `yaml:"tpm"`            // Tokens per minute of the whole pool (0 = unlimited)
+	DailyBudget   float64  `yaml:"daily_budget"`   // USD the pool may spend per UTC day (0 = unlimited)
+	MonthlyBudget float64  `yaml:"monthly_budget"` // USD the pool may spend per UTC month (0 = unlimited)
+}
+
+// UnmarshalYAML implements custom unmarshaling for CredentialPoolConfig with env variable support
+func (p *CredentialPoolConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Name          string   `yaml:"name"`
+		Credentials   []string `yaml:"credentials"`
+		RPM           string   `yaml:"rpm"`
+		TPM           string   `yaml:"tpm"`
+		DailyBudget   string   `yaml:"daily_budget"`
+		MonthlyBudget string   `yaml:"monthly_budget"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	parseFloat := func(v string) (float64, error) { return strconv.ParseFloat(v, 64) }
+
+	var err error
+	p.Name = resolveEnvString(temp.Name)
+	p.Credentials = temp.Credentials
+	if p.RPM, err = parseField(temp.RPM, 0, strconv.Atoi, "credential_pools.rpm"); err != nil {
+		return err
+	}
+	if p.TPM, err = parseField(temp.TPM, 0, strconv.Atoi, "credential_pools.tpm"); err != nil {
+		return err
+	}
+	if p.DailyBudget, err = parseField(temp.DailyBudget, 0, parseFloat, "credential_pools.daily_budget"); err != nil {
+		return err
+	}
+	if p.MonthlyBudget, err = parseField(temp.MonthlyBudget, 0, parseFloat, "credential_pools.monthly_budget"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Cassette modes
+const (
+	CassetteModeRecord = "record" // Write upstream request/response pairs to fixture files
+	CassetteModeReplay = "replay" // Serve recorded responses instead of calling upstream
+)
+
+// CassetteConfig records upstream traffic into fixture files or replays it (dev mode),
+// so converter changes can be regression-tested against real provider payloads
+type CassetteConfig struct {
+	Mode string `yaml:"mode"` // "record" or "replay" (default: disabled)
+	Dir  string `yaml:"dir"`  // Fixture directory
+}
+
+// UnmarshalYAML implements custom unmarshaling for CassetteConfig with env variable support
+func (c *CassetteConfig) UnmarshalYAML(value *yaml.Node) error {
+	type tempConfig struct {
+		Mode string `yaml:"mode"`
+		Dir  string `yaml:"dir"`
+	}
+
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	c.Mode = resolveEnvString(temp.Mode)
+	c.Dir = resolveEnvString(temp.Dir)
+
+	return nil
+}
+
+// UnmarshalYAML implements custom unmarshaling for Fail2BanConfig
+func (f *Fail2BanConfig) UnmarshalYAML(value *yaml.Node) error {
+	// Create a temporary struct with string ban_duration
+	type tempConfig struct {
+		MaxAttempts       string                `yaml:"max_attempts,omitempty"`
+		BanDuration       string                `yaml:"ban_duration,omitempty"`
+		ErrorCodes        []int                 `yaml:"error_codes,omitempty"`
+		ErrorCodeRules    []ErrorCodeRuleConfig `yaml:"error_code_rules,omitempty"`
+		StateFile         string                `yaml:"state_file,omitempty"`
+		StateSaveInterval string                `yaml:"state_save_interval,omitempty"`
+	}
+	var err error
+	var temp tempConfig
+	if err := value.Decode(&temp); err != nil {
+		return err
+	}
+
+	if f.MaxAttempts, err = parseField(temp.MaxAttempts, DefaultMaxAttempts, strconv.Atoi, "fail2ban.max_attempts"); err != nil {
+		return err
+	}
+
+	if temp.BanDuration == "permanent" {
+		f.BanDuration = DefaultBanDuration
+	} else {
+		if f.BanDuration, err = parseField(temp.BanDuration, DefaultBanDuration, time.ParseDuration, "ban_duration"); err != nil {
+			return err
+		}
+	}
+	if len(temp.ErrorCodes) == 0 {
+		f.ErrorCodes = DefaultErrorCodes
+	} else {
+		f.ErrorCodes = temp.ErrorCodes
+	}
+
+	f.ErrorCodeRules = temp.ErrorCodeRules
+
+	f.StateFile = resolveEnvString(temp.StateFile)
+	if f.StateSaveInterval, err = parseField(temp.StateSaveInterval, DefaultFail2BanStateSaveInterval, time.ParseDuration, "fail2ban.state_save_interval"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if !hasMappingKey(&root, "fail2ban") {
+		cfg.Fail2Ban = defaultFail2BanConfig()
+	}
+
+	// Merge credentials, models and aliases from included files
+	if err := mergeIncludes(&cfg, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to load included config: %w", err)
+	}
+
+	// Decrypt enc:v1: secrets (api_key, credentials_json, master_key, ...)
+	if err := cfg.decryptSecrets(); err != nil {
+		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+
+	// Resolve env variables in model_alias values
+	if cfg.ModelAlias != nil {
+		resolved := make(map[string]string, len(cfg.ModelAlias))
+		for alias, target := range cfg.ModelAlias {
+			resolved[resolveEnvString(alias)] = resolveEnvString(target)
+		}
+		cfg.ModelAlias = resolved
+	}
+
+	// Normalize credentials
+	cfg.Normalize()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+func defaultFail2BanConfig() Fail2BanConfig {
+	return Fail2BanConfig{
+		MaxAttempts:       DefaultMaxAttempts,
+		BanDuration:       DefaultBanDuration,
+		ErrorCodes:        append([]int(nil), DefaultErrorCodes...),
+		StateSaveInterval: DefaultFail2BanStateSaveInterval,
+	}
+}
+
+func hasMappingKey(node *yaml.Node, key string) bool {
+	if node == nil {
+		return false
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize cleans up configuration values
+func (c *Config) Normalize() {
+	// Remove /v1 suffix from base_url to avoid duplication
+	for i := range c.Credentials {
+		c.Credentials[i].BaseURL = strings.TrimSuffix(c.Credentials[i].BaseURL, "/v1")
+		if c.Credentials[i].Type == ProviderTypeMock && c.Credentials[i].BaseURL == "" {
+			c.Credentials[i].BaseURL = DefaultMockBaseURL
+		}
+		if c.Credentials[i].Type == ProviderTypeAnthropic {
+			c.Credentials[i].AnthropicVersion = c.Credentials[i].AnthropicAPIVersion()
+		}
+		if c.Credentials[i].Type == ProviderTypeVertexAI {
+			if c.Credentials[i].APIVersion == "" {
+				c.Credentials[i].APIVersion = DefaultVertexAPIVersion
+			}
+			if c.Credentials[i].PredictAPIVersion == "" {
+				c.Credentials[i].PredictAPIVersion = c.Credentials[i].APIVersion
+			}
+		}
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		return fmt.Errorf("invalid port: %d", c.Server.Port)
+	}
+
+	switch c.Server.UnsupportedParams {
+	case "":
+		c.Server.UnsupportedParams = UnsupportedParamsDrop
+	case UnsupportedParamsDrop, UnsupportedParamsReroute:
+	default:
+		return fmt.Errorf("invalid server.unsupported_params: %q (must be %q or %q)",
+			c.Server.UnsupportedParams, UnsupportedParamsDrop, UnsupportedParamsReroute)
+	}
+
+	// Admin listener is optional (0 = disabled) and must not share the main port
+	if c.Server.AdminPort < 0 || c.Server.AdminPort > 65535 {
+		return fmt.Errorf("invalid admin_port: %d", c.Server.AdminPort)
+	}
+	if c.Server.AdminPort != 0 && c.Server.AdminPort == c.Server.Port {
+		return fmt.Errorf("admin_port must differ from port: %d", c.Server.AdminPort)
+	}
+
+	if c.Server.MaxBodySizeMB <= 0 {
+		return fmt.Errorf("invalid max_body_size_mb: %d", c.Server.MaxBodySizeMB)
+	}
+
+	if c.Server.ResponseBodyMultiplier <= 0 {
+		c.Server.ResponseBodyMultiplier = 10
+	}
+
+	// -1 means unlimited timeout
+	if c.Server.RequestTimeout < 0 && c.Server.RequestTimeout != -1 {
+		return fmt.Errorf("invalid request_timeout: %v", c.Server.RequestTimeout)
+	}
+
+	// Validate logging level
+	if c.Server.LoggingLevel != "" {
+		validLevels := map[string]bool{"info": true, "debug": true, "error": true}
+		if !validLevels[c.Server.LoggingLevel] {
+			return fmt.Errorf("invalid logging_level: %s (must be info, debug, or error)", c.Server.LoggingLevel)
+		}
+	} else {
+		c.Server.LoggingLevel = "info" // Default to info
+	}
+
+	// Validate master_key
+	if c.Server.MasterKey == "" {
+		return fmt.Errorf("master_key is required")
+	}
+	for _, key := range c.Server.MasterKeys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid master_keys entry: must not be empty")
+		}
+	}
+	if c.Server.MasterKeyGracePeriod < 0 {
+		return fmt.Errorf("invalid master_key_grace_period: %v (must not be negative)", c.Server.MasterKeyGracePeriod)
+	}
+	if c.Server.MasterKeyGracePeriod == 0 {
+		c.Server.MasterKeyGracePeriod = DefaultMasterKeyGracePeriod
+	}
+	if c.Server.StaleModelTTL < 0 {
+		return fmt.Errorf("invalid stale_model_ttl: %v (must not be negative)", c.Server.StaleModelTTL)
+	}
+	if c.Server.StaleModelTTL == 0 {
+		c.Server.StaleModelTTL = DefaultStaleModelTTL
+	}
+	if c.Server.StreamFirstByteTimeout < 0 {
+		return fmt.Errorf("invalid stream_first_byte_timeout: %v (must not be negative)", c.Server.StreamFirstByteTimeout)
+	}
+
+	// Fallback attempts get shorter budgets; request_timeout still bounds every attempt
+	if c.Server.FallbackTimeout < 0 {
+		return fmt.Errorf("invalid fallback_timeout: %v (must not be negative)", c.Server.FallbackTimeout)
+	}
+	if c.Server.RequestTimeout > 0 && c.Server.FallbackTimeout > c.Server.RequestTimeout {
+		return fmt.Errorf("invalid fallback_timeout: %v (must not exceed request_timeout %v)", c.Server.FallbackTimeout, c.Server.RequestTimeout)
+	}
+	if c.Server.FallbackBodyMultiplier < 0 {
+		return fmt.Errorf("invalid fallback_body_multiplier: %d (must not be negative)", c.Server.FallbackBodyMultiplier)
+	}
+	if c.Server.FallbackBodyMultiplier == 0 {
+		c.Server.FallbackBodyMultiplier = c.Server.ResponseBodyMultiplier
+	}
+	if c.Server.RequestDeadline < 0 {
+		return fmt.Errorf("invalid request_deadline: %v (must not be negative)", c.Server.RequestDeadline)
+	}
+
+	// Inter-router secret is optional; short secrets make HMAC signatures guessable
+	if c.Server.InterRouterSecret != "" && len(c.Server.InterRouterSecret) < MinHMACSecretLength {
+		return fmt.Errorf("inter_router_secret must be at least %d characters", MinHMACSecretLength)
+	}
+
+	// Validate and normalize default_models_rpm
+	// -1 means unlimited RPM, 0 is treated as unlimited
+	if c.Server.DefaultModelsRPM == 0 {
+		c.Server.DefaultModelsRPM = -1 // Convert 0 to unlimited (-1)
+	} else if c.Server.DefaultModelsRPM < -1 {
+		return fmt.Errorf("invalid default_models_rpm: %d (must be -1 for unlimited or positive number)", c.Server.DefaultModelsRPM)
+	}
+
+	// ReadTimeout equals RequestTimeout (not configurable via YAML)
+	c.Server.ReadTimeout = c.Server.RequestTimeout
+
+	// Validate MaxProviderRetries
+	if c.Server.MaxProviderRetries < 0 {
+		return fmt.Errorf("invalid max_provider_retries: %d (must be >= 0)", c.Server.MaxProviderRetries)
+	}
+
+	// Validate IdleTimeout against WriteTimeout
+	if c.Server.IdleTimeout == 0 {
+		c.Server.IdleTimeout = c.Server.WriteTimeout * 2
+	}
+
+	if c.Fail2Ban.MaxAttempts <= 0 {
+		return fmt.Errorf("invalid max_attempts: %d", c.Fail2Ban.MaxAttempts)
+	}
+	if c.Fail2Ban.StateFile != "" && c.Fail2Ban.StateSaveInterval <= 0 {
+		return fmt.Errorf("invalid fail2ban.state_save_interval: %s (must be positive)", c.Fail2Ban.StateSaveInterval)
+	}
+
+	if len(c.Credentials) == 0 {
+		return fmt.Errorf("no credentials configured")
+	}
+
+	// Validate spend push settings
+	if c.Monitoring.SpendPush.Enabled {
+		if c.Monitoring.SpendPush.PushgatewayURL == "" {
+			return fmt.Errorf("monitoring.spend_push.pushgateway_url is required when spend_push is enabled")
+		}
+		if u, err := url.Parse(c.Monitoring.SpendPush.PushgatewayURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid monitoring.spend_push.pushgateway_url: %s (must be an http or https URL)", c.Monitoring.SpendPush.PushgatewayURL)
+		}
+		if c.Monitoring.SpendPush.Interval <= 0 {
+			c.Monitoring.SpendPush.Interval = DefaultSpendPushInterval
+		}
+		if c.Monitoring.SpendPush.Job == "" {
+			c.Monitoring.SpendPush.Job = DefaultSpendPushJob
+		}
+		if c.Monitoring.SpendPush.MaxLabelValues <= 0 {
+			return fmt.Errorf("monitoring.spend_push.max_label_values must be positive")
+		}
+	}
+
+	// Validate request sampling settings
+	if sampling := c.Monitoring.RequestSampling; sampling.Percent < 0 || sampling.Percent > 100 {
+		return fmt.Errorf("invalid monitoring.request_sampling.percent: %v (must be between 0 and 100)", sampling.Percent)
+	}
+	if c.Monitoring.RequestSampling.MaxBodyBytes <= 0 {
+		c.Monitoring.RequestSampling.MaxBodyBytes = DefaultRequestSamplingMaxBodyBytes
+	}
+
+	if err := c.Monitoring.SLO.validate(); err != nil {
+		return err
+	}
+
+	// Validate startup check settings (zero values fall back to defaults)
+	if c.StartupCheck.Timeout < 0 {
+		return fmt.Errorf("invalid startup_check.timeout: %v", c.StartupCheck.Timeout)
+	} else if c.StartupCheck.Timeout == 0 {
+		c.StartupCheck.Timeout = DefaultStartupCheckTimeout
+	}
+	if c.StartupCheck.Concurrency < 0 {
+		return fmt.Errorf("invalid startup_check.concurrency: %d (must be > 0)", c.StartupCheck.Concurrency)
+	} else if c.StartupCheck.Concurrency == 0 {
+		c.StartupCheck.Concurrency = DefaultStartupCheckConcurrency
+	}
+
+	// Validate response header limits (zero values fall back to defaults)
+	if err := c.ResponseHeaders.validate(); err != nil {
+		return err
+	}
+
+	// Validate adaptive limits (zero values fall back to defaults)
+	if c.AdaptiveLimits.Enabled {
+		if err := c.AdaptiveLimits.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate fair scheduler (zero values fall back to defaults)
+	if c.FairScheduler.Enabled {
+		if err := c.FairScheduler.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate image inlining (zero values fall back to defaults)
+	if c.ImageInlining.Enabled {
+		if err := c.ImageInlining.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate DNS cache (zero TTL falls back to the default)
+	if c.DNSCache.Enabled {
+		if err := c.DNSCache.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate fault injection rules
+	if c.FaultInjection.Enabled {
+		if err := c.FaultInjection.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate usage forecasting (zero values fall back to defaults)
+	if c.UsageForecast.Enabled {
+		if err := c.UsageForecast.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate context management (zero values fall back to defaults)
+	if c.ContextManagement.Enabled {
+		if err := c.ContextManagement.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate attribution header keys
+	for _, key := range c.Attribution.Keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid attribution_headers.keys entry: must not be empty")
+		}
+	}
+	for _, key := range c.RoutingOverrides.Keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid routing_overrides.keys entry: must not be empty")
+		}
+	}
+
+	// Validate spend report schedule and destinations (zero values fall back to defaults)
+	if c.SpendReport.Enabled {
+		if err := c.SpendReport.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate local spend log (zero values fall back to defaults)
+	if c.LocalSpendLog.Enabled {
+		if c.LocalSpendLog.Path == "" {
+			c.LocalSpendLog.Path = DefaultLocalSpendLogPath
+		}
+		if c.LocalSpendLog.Retention < 0 {
+			return fmt.Errorf("invalid local_spend_log.retention: %v (must not be negative)", c.LocalSpendLog.Retention)
+		}
+	}
+
+	// Validate metrics export (empty path falls back to the default)
+	if c.MetricsExport.Enabled && c.MetricsExport.Path == "" {
+		c.MetricsExport.Path = DefaultMetricsExportPath
+	}
+
+	// Validate quota boosts (granted through the admin listener)
+	if c.QuotaBoosts.Enabled {
+		if c.Server.AdminPort == 0 {
+			return fmt.Errorf("quota_boosts requires server.admin_port")
+		}
+		if err := c.QuotaBoosts.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate priority class shares and assignments
+	if c.PriorityClasses.Enabled {
+		if err := c.PriorityClasses.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate stream coalescing window
+	if c.StreamCoalescing.Enabled {
+		if err := c.StreamCoalescing.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate non-streaming keys
+	if c.NonStreaming.Enabled {
+		if err := c.NonStreaming.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate wrapped upstream errors (zero length falls back to the default)
+	if c.UpstreamErrors.Wrap {
+		if err := c.UpstreamErrors.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate system prompt rules
+	if c.SystemPrompts.Enabled {
+		if err := c.SystemPrompts.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate parameter negotiation (zero TTL falls back to the default)
+	if c.ParamNegotiation.Enabled {
+		if err := c.ParamNegotiation.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate n fan-out (zero max_n falls back to the default)
+	if c.ChoiceFanOut.Enabled {
+		if err := c.ChoiceFanOut.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate per-request cost ceilings
+	if c.MaxCostPerRequest.Enabled {
+		if err := c.MaxCostPerRequest.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate model snapshot pins
+	if c.ModelPins.Enabled {
+		if err := c.ModelPins.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate reasoning routing groups
+	if c.ReasoningRouting.Enabled {
+		if err := c.ReasoningRouting.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate reasoning model parameter handling
+	if err := c.ReasoningParams.validate(); err != nil {
+		return err
+	}
+
+	// Validate cassette recording/replay
+	if c.Cassette.Mode != "" {
+		if err := c.Cassette.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate Fail2Ban error code rules for duplicates
+	seenErrorCodes := make(map[int]bool)
+	for _, rule := range c.Fail2Ban.ErrorCodeRules {
+		if seenErrorCodes[rule.Code] {
+			return fmt.Errorf("fail2ban: duplicate error_code_rules for code %d", rule.Code)
+		}
+		seenErrorCodes[rule.Code] = true
+	}
+
+	for i, cred := range c.Credentials {
+		if cred.Name == "" {
+			return fmt.Errorf("credential %d: name is required", i)
+		}
+
+		// Validate provider type
+		if !cred.Type.IsValid() {
+			return fmt.Errorf("credential %s: invalid type: %s (must be 'openai', 'vertex-ai', 'gemini', 'anthropic', 'bedrock', 'proxy', or 'mock')", cred.Name, cred.Type)
+		}
+
+		if cred.HMACSecret != "" && cred.Type != ProviderTypeProxy {
+			return fmt.Errorf("credential %s: hmac_secret is only supported for proxy type", cred.Name)
+		}
+
+		if q := cred.Quota; q != nil && (q.DailyTokens < 0 || q.MonthlyTokens < 0 || q.DailySpend < 0 || q.MonthlySpend < 0) {
+			return fmt.Errorf("credential %s: quota values must not be negative", cred.Name)
+		}
+
+		// Validate by provider type
+		switch cred.Type {
+		case ProviderTypeProxy:
+			// base_url is required for proxy
+			if cred.BaseURL == "" {
+				return fmt.Errorf("credential %s: base_url is required for proxy type", cred.Name)
+			}
+			// Validate base_url is a valid URL
+			if err := validateBaseURL(cred.Name, cred.BaseURL); err != nil {
+				return err
+			}
+			// api_key is optional for proxy
+			if cred.HMACSecret != "" && len(cred.HMACSecret) < MinHMACSecretLength {
+				return fmt.Errorf("credential %s: hmac_secret must be at least %d characters", cred.Name, MinHMACSecretLength)
+			}
+			if cred.ProxyModels != nil {
+				if err := cred.ProxyModels.validate(); err != nil {
+					return fmt.Errorf("credential %s: %w", cred.Name, err)
+				}
+			}
+
+		case ProviderTypeVertexAI:
+			// For Vertex AI, project_id and location are required
+			if cred.ProjectID == "" {
+				return fmt.Errorf("credential %s: project_id is required for vertex-ai type", cred.Name)
+			}
+			if cred.Location == "" {
+				return fmt.Errorf("credential %s: location is required for vertex-ai type", cred.Name)
+			}
+			// API Key is required for Vertex AI (Express Mode)
+			if cred.APIKey == "" && cred.CredentialsFile == "" && cred.CredentialsJSON == "" {
+				return fmt.Errorf("credential %s: api_key, credentials_file, or credentials_json is required for vertex-ai type", cred.Name)
+			}
+			// Validate credentials_file exists if provided
+			if cred.CredentialsFile != "" {
+				if _, err := os.Stat(cred.CredentialsFile); err != nil {
+					return fmt.Errorf("credential %s: credentials_file does not exist or is not accessible: %w", cred.Name, err)
+				}
+			}
+			// base_url is optional for Vertex AI (will be constructed dynamically)
+			for _, version := range []string{cred.APIVersion, cred.PredictAPIVersion} {
+				if version != "" && !slices.Contains(VertexAPIVersions, version) {
+					return fmt.Errorf("credential %s: invalid Vertex AI API version %q (one of %s)", cred.Name, version, strings.Join(VertexAPIVersions, ", "))
+				}
+			}
+
+		case ProviderTypeMock:
+			// Mock credentials never leave the router: api_key and base_url are optional
+			if cred.Mock != nil && (cred.Mock.Latency < 0 || cred.Mock.PromptTokens < 0 || cred.Mock.CompletionTokens < 0) {
+				return fmt.Errorf("credential %s: mock latency and token counts must not be negative", cred.Name)
+			}
+			if cred.Mock != nil && cred.Mock.Response != "" {
+				if _, err := template.New("mock").Parse(cred.Mock.Response); err != nil {
+					return fmt.Errorf("credential %s: invalid mock response template: %w", cred.Name, err)
+				}
+			}
+
+		case ProviderTypeGemini:
+			// For Gemini (Google AI Studio), api_key and base_url are required
+			if cred.APIKey == "" {
+				return fmt.Errorf("credential %s: api_key is required for gemini type", cred.Name)
+			}
+			if cred.BaseURL == "" {
+				return fmt.Errorf("credential %s: base_url is required for gemini type", cred.Name)
+			}
+			if err := validateBaseURL(cred.Name, cred.BaseURL); err != nil {
+				return err
+			}
+
+		default:
+			// For OpenAI and Anthropic, require APIKey and BaseURL
+			if cred.APIKey == "" {
+				return fmt.Errorf("credential %s: api_key is required", cred.Name)
+			}
+			if cred.BaseURL == "" {
+				return fmt.Errorf("credential %s: base_url is required", cred.Name)
+			}
+			// Validate base_url is a valid URL
+			if err := validateBaseURL(cred.Name, cred.BaseURL); err != nil {
+				return err
+			}
+		}
+
+		if cred.Type != ProviderTypeVertexAI && (cred.ProvisionedThroughput || cred.QuotaProject != "") {
+			return fmt.Errorf("credential %s: provisioned_throughput and quota_project are only supported for vertex-ai type", cred.Name)
+		}
+		if cred.Type != ProviderTypeProxy && cred.ProxyModels != nil {
+			return fmt.Errorf("credential %s: proxy_models is only supported for proxy type", cred.Name)
+		}
+		if cred.Type != ProviderTypeProxy && cred.NormalizeStream {
+			return fmt.Errorf("credential %s: normalize_stream is only supported for proxy type", cred.Name)
+		}
+		if len(cred.ModelMap) > 0 && (cred.Type == ProviderTypeProxy || cred.Type == ProviderTypeMock) {
+			return fmt.Errorf("credential %s: model_map is not supported for %s type (proxy credentials use proxy_models.rename)", cred.Name, cred.Type)
+		}
+		for model, providerModel := range cred.ModelMap {
+			if model == "" || providerModel == "" {
+				return fmt.Errorf("credential %s: model_map: model names must not be empty", cred.Name)
+			}
+		}
+		if len(cred.Resolve) > 0 && cred.Type == ProviderTypeMock {
+			return fmt.Errorf("credential %s: resolve is not supported for mock type", cred.Name)
+		}
+		for host, target := range cred.Resolve {
+			if err := validateResolveEntry(host, target); err != nil {
+				return fmt.Errorf("credential %s: resolve: %w", cred.Name, err)
+			}
+		}
+		if cred.Type != ProviderTypeVertexAI && (cred.APIVersion != "" || cred.PredictAPIVersion != "") {
+			return fmt.Errorf("credential %s: api_version and predict_api_version are only supported for vertex-ai type", cred.Name)
+		}
+		if cred.Type != ProviderTypeAnthropic && (cred.AnthropicVersion != "" || len(cred.AnthropicBeta) > 0) {
+			return fmt.Errorf("credential %s: anthropic_version and anthropic_beta are only supported for anthropic type", cred.Name)
+		}
+		for _, beta := range cred.AnthropicBeta {
+			if strings.TrimSpace(beta) == "" || strings.Contains(beta, ",") {
+				return fmt.Errorf("credential %s: invalid anthropic_beta entry %q (must be a single non-empty feature name)", cred.Name, beta)
+			}
+		}
+
+		// -1 means unlimited RPM
+		if cred.RPM <= 0 && !isUnlimited(cred.RPM) {
+			return fmt.Errorf("credential %s: invalid rpm: %d (must be -1 for unlimited or positive number)", cred.Name, cred.RPM)
+		}
+		// TPM: 0 or -1 means unlimited, positive means limited
+		if cred.TPM < -1 {
+			return fmt.Errorf("credential %s: invalid tpm: %d (must be -1 or 0 for unlimited, or positive number)", cred.Name, cred.TPM)
+		}
+		if err := validateRPMBurst(cred.RPM, cred.RPMBurst); err != nil {
+			return fmt.Errorf("credential %s: %w", cred.Name, err)
+		}
+	}
+
+	for _, model := range c.Models {
+		if err := validateRPMBurst(model.RPM, model.RPMBurst); err != nil {
+			return fmt.Errorf("model %s: %w", model.Name, err)
+		}
+	}
+
+	// Validate tenants (after credentials, whose names they reference)
+	if len(c.Tenants) > 0 {
+		if err := c.validateTenants(); err != nil {
+			return err
+		}
+	}
+
+	// Validate credential pools (after credentials, whose names they reference)
+	if len(c.CredentialPools) > 0 {
+		if err := c.validateCredentialPools(); err != nil {
+			return err
+		}
+	}
+
+	// Validate chargeback rates (after credentials, whose names they reference)
+	if c.Chargeback.Enabled {
+		if err := c.validateChargeback(); err != nil {
+			return err
+		}
+	}
+
+	// Validate LiteLLM DB config
+	if c.LiteLLMDB.Enabled {
+		if c.LiteLLMDB.DatabaseURL == "" {
+			return fmt.Errorf("litellm_db.database_url is required when enabled")
+		}
+		if !strings.HasPrefix(c.LiteLLMDB.DatabaseURL, "postgres://") && !strings.HasPrefix(c.LiteLLMDB.DatabaseURL, "postgresql://") {
+			return fmt.Errorf("litellm_db.database_url must start with postgres:// or postgresql://, got: %s", c.LiteLLMDB.DatabaseURL)
+		}
+		if c.LiteLLMDB.ReplicaURL != "" && !strings.HasPrefix(c.LiteLLMDB.ReplicaURL, "postgres://") && !strings.HasPrefix(c.LiteLLMDB.ReplicaURL, "postgresql://") {
+			return fmt.Errorf("litellm_db.replica_url must start with postgres:// or postgresql://")
+		}
+		if c.LiteLLMDB.HealthFailureThreshold < 1 {
+			return fmt.Errorf("invalid litellm_db.health_failure_threshold: %d (must be >= 1)", c.LiteLLMDB.HealthFailureThreshold)
+		}
+	}
+
+	return nil
+}
+
+// validate fills in defaults for zero values and checks adaptive limit ranges
+func (a *AdaptiveLimitsConfig) validate() error {
+	if a.DecreaseFactor == 0 {
+		a.DecreaseFactor = DefaultAdaptiveDecreaseFactor
+	}
+	if a.IncreaseStep == 0 {
+		a.IncreaseStep = DefaultAdaptiveIncreaseStep
+	}
+	if a.IncreaseInterval == 0 {
+		a.IncreaseInterval = DefaultAdaptiveIncreaseInterval
+	}
+	if a.MinFactor == 0 {
+		a.MinFactor = DefaultAdaptiveMinFactor
+	}
+	if a.Cooldown == 0 {
+		a.Cooldown = DefaultAdaptiveCooldown
+	}
+
+	if a.DecreaseFactor <= 0 || a.DecreaseFactor >= 1 {
+		return fmt.Errorf("invalid adaptive_limits.decrease_factor: %v (must be between 0 and 1)", a.DecreaseFactor)
+	}
+	if a.IncreaseStep < 0 || a.IncreaseStep > 1 {
+		return fmt.Errorf("invalid adaptive_limits.increase_step: %v (must be between 0 and 1)", a.IncreaseStep)
+	}
+	if a.MinFactor < 0 || a.MinFactor > 1 {
+		return fmt.Errorf("invalid adaptive_limits.min_factor: %v (must be between 0 and 1)", a.MinFactor)
+	}
+	if a.IncreaseInterval < 0 {
+		return fmt.Errorf("invalid adaptive_limits.increase_interval: %v", a.IncreaseInterval)
+	}
+	if a.Cooldown < 0 {
+		return fmt.Errorf("invalid adaptive_limits.cooldown: %v", a.Cooldown)
+	}
+	return nil
+}
+
+// validate fills in defaults for zero values and checks fair scheduler settings
+func (f *FairSchedulerConfig) validate() error {
+	if f.MaxConcurrent == 0 {
+		f.MaxConcurrent = DefaultFairSchedulerMaxConcurrent
+	}
+	if f.MaxQueue == 0 {
+		f.MaxQueue = DefaultFairSchedulerMaxQueue
+	}
+	if f.QueueTimeout == 0 {
+		f.QueueTimeout = DefaultFairSchedulerQueueTimeout
+	}
+	if f.DefaultWeight == 0 {
+		f.DefaultWeight = 1
+	}
+
+	if f.MaxConcurrent < 0 {
+		return fmt.Errorf("invalid fair_scheduler.max_concurrent: %d (must be > 0)", f.MaxConcurrent)
+	}
+	if f.MaxQueue < 0 {
+		return fmt.Errorf("invalid fair_scheduler.max_queue: %d (must be > 0)", f.MaxQueue)
+	}
+	if f.QueueTimeout < 0 {
+		return fmt.Errorf("invalid fair_scheduler.queue_timeout: %v", f.QueueTimeout)
+	}
+	if f.DefaultWeight < 0 {
+		return fmt.Errorf("invalid fair_scheduler.default_weight: %d (must be > 0)", f.DefaultWeight)
+	}
+	for name, weight := range f.Weights {
+		if weight <= 0 {
+			return fmt.Errorf("invalid fair_scheduler.weights[%s]: %d (must be > 0)", name, weight)
+		}
+	}
+	return nil
+}
+
+func (d *DNSCacheConfig) validate() error {
+	if d.TTL == 0 {
+		d.TTL = DefaultDNSCacheTTL
+	}
+	if d.TTL < 0 {
+		return fmt.Errorf("invalid dns_cache.ttl: %v", d.TTL)
+	}
+	return nil
+}
+
+// validateResolveEntry checks a credential resolve entry: a host name mapped to an IP or
+// host name with an optional port
+func validateResolveEntry(host, target string) error {
+	if host == "" || strings.ContainsAny(host, ":/") {
+		return fmt.Errorf("invalid host %q (must be a host name without port or scheme)", host)
+	}
+	targetHost := target
+	if h, port, err := net.SplitHostPort(target); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("invalid port in %s target %q", host, target)
+		}
+		targetHost = h
+	} else if strings.Contains(target, ":") && net.ParseIP(target) == nil {
+		return fmt.Errorf("invalid %s target %q (must be an IP or host name with an optional port)", host, target)
+	}
+	if targetHost == "" || strings.Contains(targetHost, "/") {
+		return fmt.Errorf("invalid %s target %q (must be an IP or host name with an optional port)", host, target)
+	}
+	return nil
+}
+
+func (i *ImageInliningConfig) validate() error {
+	if i.MaxSizeMB == 0 {
+		i.MaxSizeMB = DefaultImageInliningMaxSizeMB
+	}
+	if len(i.AllowedMIMETypes) == 0 {
+		i.AllowedMIMETypes = DefaultImageInliningMIMETypes
+	}
+	if i.Timeout == 0 {
+		i.Timeout = DefaultImageInliningTimeout
+	}
+	if i.MaxImages == 0 {
+		i.MaxImages = DefaultImageInliningMaxImages
+	}
+	if i.MaxConcurrentFetches == 0 {
+		i.MaxConcurrentFetches = DefaultImageInliningMaxConcurrentFetches
+	}
+
+	if i.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid image_inlining.max_size_mb: %d (must be > 0)", i.MaxSizeMB)
+	}
+	for _, mimeType := range i.AllowedMIMETypes {
+		if !strings.HasPrefix(strings.ToLower(mimeType), "image/") {
+			return fmt.Errorf("invalid image_inlining.allowed_mime_types entry: %q (must be an image/* type)", mimeType)
+		}
+	}
+	if i.Timeout < 0 {
+		return fmt.Errorf("invalid image_inlining.timeout: %v", i.Timeout)
+	}
+	if i.CacheTTL < 0 {
+		return fmt.Errorf("invalid image_inlining.cache_ttl: %v", i.CacheTTL)
+	}
+	if i.CacheSize < 0 {
+		return fmt.Errorf("invalid image_inlining.cache_size: %d (must be >= 0)", i.CacheSize)
+	}
+	if i.MaxImages < 0 {
+		return fmt.Errorf("invalid image_inlining.max_images: %d (must be > 0)", i.MaxImages)
+	}
+	if i.MaxConcurrentFetches < 0 {
+		return fmt.Errorf("invalid image_inlining.max_concurrent_fetches: %d (must be > 0)", i.MaxConcurrentFetches)
+	}
+	return nil
+}
+
+func (f *FaultInjectionConfig) validate() error {
+	seen := make(map[string]bool, len(f.Rules))
+	for i := range f.Rules {
+		rule := &f.Rules[i]
+		if rule.Credential == "" {
+			return fmt.Errorf("fault_injection.rules[%d]: credential is required (name or \"*\")", i)
+		}
+		if seen[rule.Credential] {
+			return fmt.Errorf("fault_injection.rules: duplicate rule for credential %q", rule.Credential)
+		}
+		seen[rule.Credential] = true
+
+		if rule.Latency > 0 && rule.LatencyProbability == 0 {
+			rule.LatencyProbability = 1
+		}
+		for name, p := range map[string]float64{
+			"rate_limit_probability":   rule.RateLimitProbability,
+			"server_error_probability": rule.ServerErrorProbability,
+			"latency_probability":      rule.LatencyProbability,
+		} {
+			if p < 0 || p > 1 {
+				return fmt.Errorf("invalid fault_injection.rules[%q].%s: %v (must be between 0 and 1)", rule.Credential, name, p)
+			}
+		}
+		if rule.RateLimitProbability+rule.ServerErrorProbability > 1 {
+			return fmt.Errorf("invalid fault_injection.rules[%q]: rate_limit_probability + server_error_probability must not exceed 1", rule.Credential)
+		}
+		if rule.Latency < 0 {
+			return fmt.Errorf("invalid fault_injection.rules[%q].latency: %v", rule.Credential, rule.Latency)
+		}
+	}
+	return nil
+}
+
+func (c *CassetteConfig) validate() error {
+	if c.Mode != CassetteModeRecord && c.Mode != CassetteModeReplay {
+		return fmt.Errorf("invalid cassette.mode: %q (must be %q or %q)", c.Mode, CassetteModeRecord, CassetteModeReplay)
+	}
+	if c.Dir == "" {
+		return fmt.Errorf("cassette.dir is required when cassette.mode is %q", c.Mode)
+	}
+	return nil
+}
+
+func (u *UsageForecastConfig) validate() error {
+	if u.Interval == 0 {
+		u.Interval = DefaultUsageForecastInterval
+	}
+	if u.Window == 0 {
+		u.Window = DefaultUsageForecastWindow
+	}
+	if u.WarnThreshold == 0 {
+		u.WarnThreshold = DefaultUsageForecastWarnThreshold
+	}
+	if u.Interval < 0 {
+		return fmt.Errorf("invalid usage_forecast.interval: %v (must be > 0)", u.Interval)
+	}
+	if u.Window < time.Minute {
+		return fmt.Errorf("invalid usage_forecast.window: %v (must be >= 1m)", u.Window)
+	}
+	if u.WarnThreshold < 0 {
+		return fmt.Errorf("invalid usage_forecast.warn_threshold: %v (must be > 0)", u.WarnThreshold)
+	}
+	return nil
+}
+
+func (c *ContextManagementConfig) validate() error {
+	if c.Strategy == "" {
+		c.Strategy = ContextStrategyTruncate
+	}
+	if c.KeepRecent == 0 {
+		c.KeepRecent = DefaultContextKeepRecent
+	}
+	if c.SummaryMaxTokens == 0 {
+		c.SummaryMaxTokens = DefaultContextSummaryMaxTokens
+	}
+
+	if c.Strategy != ContextStrategyTruncate && c.Strategy != ContextStrategySummarize {
+		return fmt.Errorf("invalid context_management.strategy: %q (must be %q or %q)", c.Strategy, ContextStrategyTruncate, ContextStrategySummarize)
+	}
+	if c.Strategy == ContextStrategySummarize && c.SummaryModel == "" {
+		return fmt.Errorf("context_management.summary_model is required when context_management.strategy is %q", ContextStrategySummarize)
+	}
+	if c.MaxPromptTokens < 0 {
+		return fmt.Errorf("invalid context_management.max_prompt_tokens: %d (must be >= 0)", c.MaxPromptTokens)
+	}
+	if c.KeepRecent < 0 {
+		return fmt.Errorf("invalid context_management.keep_recent: %d (must be > 0)", c.KeepRecent)
+	}
+	if c.SummaryMaxTokens < 0 {
+		return fmt.Errorf("invalid context_management.summary_max_tokens: %d (must be > 0)", c.SummaryMaxTokens)
+	}
+	return nil
+}
+
+func (s *SpendReportConfig) validate() error {
+	if s.Time == "" {
+		s.Time = DefaultSpendReportTime
+	}
+	if s.TopN == 0 {
+		s.TopN = DefaultSpendReportTopN
+	}
+	if s.WebhookFormat == "" {
+		s.WebhookFormat = WebhookFormatJSON
+	}
+	if s.OutputFormat == "" {
+		s.OutputFormat = SpendReportFormatJSON
+	}
+
+	if _, err := time.Parse("15:04", s.Time); err != nil {
+		return fmt.Errorf("invalid spend_report.time: %q (must be HH:MM)", s.Time)
+	}
+	if s.TopN < 0 {
+		return fmt.Errorf("invalid spend_report.top_n: %d (must be > 0)", s.TopN)
+	}
+	if s.WebhookURL == "" && s.OutputDir == "" {
+		return fmt.Errorf("spend_report requires webhook_url or output_dir")
+	}
+	if s.WebhookURL != "" {
+		if u, err := url.Parse(s.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid spend_report.webhook_url: %s (must be an http or https URL)", s.WebhookURL)
+		}
+	}
+	if s.WebhookFormat != WebhookFormatJSON && s.WebhookFormat != WebhookFormatSlack {
+		return fmt.Errorf("invalid spend_report.webhook_format: %q (must be %q or %q)", s.WebhookFormat, WebhookFormatJSON, WebhookFormatSlack)
+	}
+	if s.OutputFormat != SpendReportFormatJSON && s.OutputFormat != SpendReportFormatCSV {
+		return fmt.Errorf("invalid spend_report.output_format: %q (must be %q or %q)", s.OutputFormat, SpendReportFormatJSON, SpendReportFormatCSV)
+	}
+	return nil
+}
+
+func (q *QuotaBoostsConfig) validate() error {
+	if q.MaxDuration == 0 {
+		q.MaxDuration = DefaultQuotaBoostMaxDuration
+	}
+	if q.MaxDuration < 0 {
+		return fmt.Errorf("invalid quota_boosts.max_duration: %v (must be > 0)", q.MaxDuration)
+	}
+	return nil
+}
+
+func (p *PriorityClassesConfig) validate() error {
+	if len(p.Classes) == 0 {
+		p.Classes = make(map[string]float64, len(DefaultPriorityClasses))
+		for name, share := range DefaultPriorityClasses {
+			p.Classes[name] = share
+		}
+	}
+
+	for name, share := range p.Classes {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid priority_classes.classes entry: name must not be empty")
+		}
+		if share <= 0 || share > 1 {
+			return fmt.Errorf("invalid priority_classes.classes[%s]: %v (must be > 0 and <= 1)", name, share)
 		}
 	}
-	if len(temp.ErrorCodes) == 0 {
-		f.ErrorCodes = DefaultErrorCodes
-	} else {
-		f.ErrorCodes = temp.ErrorCodes
+	if _, ok := p.Classes[p.DefaultClass]; p.DefaultClass != "" && !ok {
+		return fmt.Errorf("invalid priority_classes.default_class: unknown class %q", p.DefaultClass)
+	}
+	for name, class := range p.Assignments {
+		if _, ok := p.Classes[class]; !ok {
+			return fmt.Errorf("invalid priority_classes.assignments[%s]: unknown class %q", name, class)
+		}
 	}
-
-	f.ErrorCodeRules = temp.ErrorCodeRules
-
 	return nil
 }
 
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+func (s *StreamCoalescingConfig) validate() error {
+	if s.Interval == 0 {
+		s.Interval = DefaultStreamCoalescingInterval
 	}
-
-	var root yaml.Node
-	if err := yaml.Unmarshal(data, &root); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if s.MaxBytes == 0 {
+		s.MaxBytes = DefaultStreamCoalescingMaxBytes
 	}
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if s.Interval < 0 || s.Interval > time.Second {
+		return fmt.Errorf("invalid stream_coalescing.interval: %v (must be > 0 and <= 1s)", s.Interval)
 	}
-
-	if !hasMappingKey(&root, "fail2ban") {
-		cfg.Fail2Ban = defaultFail2BanConfig()
+	if s.MaxBytes < 0 {
+		return fmt.Errorf("invalid stream_coalescing.max_bytes: %d (must be > 0)", s.MaxBytes)
 	}
+	return nil
+}
 
-	// Resolve env variables in model_alias values
-	if cfg.ModelAlias != nil {
-		resolved := make(map[string]string, len(cfg.ModelAlias))
-		for alias, target := range cfg.ModelAlias {
-			resolved[resolveEnvString(alias)] = resolveEnvString(target)
+func (m *MaxCostPerRequestConfig) validate() error {
+	if m.Default < 0 {
+		return fmt.Errorf("invalid max_cost_per_request.default: %v (must be >= 0)", m.Default)
+	}
+	for name, ceiling := range m.Keys {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid max_cost_per_request.keys entry: name must not be empty")
+		}
+		if ceiling < 0 {
+			return fmt.Errorf("invalid max_cost_per_request.keys[%s]: %v (must be >= 0)", name, ceiling)
 		}
-		cfg.ModelAlias = resolved
 	}
+	return nil
+}
 
-	// Normalize credentials
-	cfg.Normalize()
-
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+func (m *ModelPinsConfig) validate() error {
+	for alias, pin := range m.Aliases {
+		if strings.TrimSpace(alias) == "" {
+			return fmt.Errorf("invalid model_pins.aliases entry: alias must not be empty")
+		}
+		switch pin.Policy {
+		case ModelPinPolicyLatest, ModelPinPolicyPinned:
+		case ModelPinPolicyManual:
+			if pin.Snapshot == "" {
+				return fmt.Errorf("model_pins.aliases[%s].snapshot is required for policy manual", alias)
+			}
+		default:
+			return fmt.Errorf("invalid model_pins.aliases[%s].policy: %q (must be latest, pinned or manual)", alias, pin.Policy)
+		}
+		if pin.Snapshot == alias {
+			return fmt.Errorf("invalid model_pins.aliases[%s].snapshot: must differ from the alias", alias)
+		}
 	}
-
-	return &cfg, nil
+	return nil
 }
 
-func defaultFail2BanConfig() Fail2BanConfig {
-	return Fail2BanConfig{
-		MaxAttempts: DefaultMaxAttempts,
-		BanDuration: DefaultBanDuration,
-		ErrorCodes:  append([]int(nil), DefaultErrorCodes...),
+func (r *ReasoningRoutingConfig) validate() error {
+	for name, group := range r.Groups {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid reasoning_routing.groups entry: name must not be empty")
+		}
+		if len(group.Tiers) == 0 && group.Default == "" {
+			return fmt.Errorf("reasoning_routing.groups[%s] must configure tiers or a default model", name)
+		}
+		for effort, model := range group.Tiers {
+			if !slices.Contains(ReasoningEfforts, effort) {
+				return fmt.Errorf("invalid reasoning_routing.groups[%s].tiers[%s]: unknown reasoning_effort (must be one of %s)",
+					name, effort, strings.Join(ReasoningEfforts, ", "))
+			}
+			if model == "" || model == name {
+				return fmt.Errorf("invalid reasoning_routing.groups[%s].tiers[%s]: model must be set and differ from the group", name, effort)
+			}
+		}
+		if group.Default == name {
+			return fmt.Errorf("invalid reasoning_routing.groups[%s].default: model must differ from the group", name)
+		}
 	}
+	return nil
 }
 
-func hasMappingKey(node *yaml.Node, key string) bool {
-	if node == nil {
-		return false
-	}
-	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
-		node = node.Content[0]
-	}
-	if node.Kind != yaml.MappingNode {
-		return false
-	}
-	for i := 0; i+1 < len(node.Content); i += 2 {
-		if node.Content[i].Value == key {
-			return true
+func (r *ReasoningParamsConfig) validate() error {
+	for family, model := range r.Models {
+		if strings.TrimSpace(family) == "" {
+			return fmt.Errorf("invalid reasoning_params.models entry: family must not be empty")
+		}
+		for from, to := range model.RenameParams {
+			if from == "" || to == "" || from == to {
+				return fmt.Errorf("invalid reasoning_params.models[%s].rename_params[%s]: names must be set and differ", family, from)
+			}
+		}
+		for _, param := range model.DropParams {
+			if param == "" || param == "model" || param == "messages" {
+				return fmt.Errorf("invalid reasoning_params.models[%s].drop_params: %q cannot be dropped", family, param)
+			}
+		}
+		for effort, budget := range model.ThinkingBudgets {
+			if !slices.Contains(ReasoningEfforts, effort) {
+				return fmt.Errorf("invalid reasoning_params.models[%s].thinking_budgets[%s]: unknown reasoning_effort (must be one of %s)",
+					family, effort, strings.Join(ReasoningEfforts, ", "))
+			}
+			if budget < 0 {
+				return fmt.Errorf("invalid reasoning_params.models[%s].thinking_budgets[%s]: %d (must be >= 0)", family, effort, budget)
+			}
 		}
 	}
-	return false
+	return nil
 }
 
-// Normalize cleans up configuration values
-func (c *Config) Normalize() {
-	// Remove /v1 suffix from base_url to avoid duplication
-	for i := range c.Credentials {
-		c.Credentials[i].BaseURL = strings.TrimSuffix(c.Credentials[i].BaseURL, "/v1")
+func (u *UpstreamErrorsConfig) validate() error {
+	if u.MaxDetailLength == 0 {
+		u.MaxDetailLength = DefaultUpstreamErrorsMaxDetailLength
+	}
+	if u.MaxDetailLength < 0 {
+		return fmt.Errorf("invalid upstream_errors.max_detail_length: %d (must be > 0)", u.MaxDetailLength)
 	}
+	return nil
 }
 
-func (c *Config) Validate() error {
-	if c.Server.Port <= 0 || c.Server.Port > 65535 {
-		return fmt.Errorf("invalid port: %d", c.Server.Port)
+func (n *ParamNegotiationConfig) validate() error {
+	if n.TTL == 0 {
+		n.TTL = DefaultParamNegotiationTTL
 	}
-
-	if c.Server.MaxBodySizeMB <= 0 {
-		return fmt.Errorf("invalid max_body_size_mb: %d", c.Server.MaxBodySizeMB)
+	if n.TTL < 0 {
+		return fmt.Errorf("invalid param_negotiation.ttl: %v (must be > 0)", n.TTL)
 	}
+	return nil
+}
 
-	if c.Server.ResponseBodyMultiplier <= 0 {
-		c.Server.ResponseBodyMultiplier = 10
+func (f *ChoiceFanOutConfig) validate() error {
+	if f.MaxN == 0 {
+		f.MaxN = DefaultChoiceFanOutMaxN
 	}
-
-	// -1 means unlimited timeout
-	if c.Server.RequestTimeout < 0 && c.Server.RequestTimeout != -1 {
-		return fmt.Errorf("invalid request_timeout: %v", c.Server.RequestTimeout)
+	if f.MaxN < 2 {
+		return fmt.Errorf("invalid n_fanout.max_n: %d (must be >= 2)", f.MaxN)
 	}
+	return nil
+}
 
-	// Validate logging level
-	if c.Server.LoggingLevel != "" {
-		validLevels := map[string]bool{"info": true, "debug": true, "error": true}
-		if !validLevels[c.Server.LoggingLevel] {
-			return fmt.Errorf("invalid logging_level: %s (must be info, debug, or error)", c.Server.LoggingLevel)
+func (s *SystemPromptsConfig) validate() error {
+	for i, rule := range s.Rules {
+		if len(rule.Keys) == 0 {
+			return fmt.Errorf("system_prompts.rules[%d].keys is required (use \"*\" for all keys)", i)
+		}
+		for _, key := range rule.Keys {
+			if strings.TrimSpace(key) == "" {
+				return fmt.Errorf("invalid system_prompts.rules[%d].keys entry: name must not be empty", i)
+			}
+		}
+		if strings.TrimSpace(rule.Prompt) == "" {
+			return fmt.Errorf("system_prompts.rules[%d].prompt is required", i)
+		}
+		if rule.Position != SystemPromptPrepend && rule.Position != SystemPromptAppend {
+			return fmt.Errorf("invalid system_prompts.rules[%d].position: %q (must be prepend or append)", i, rule.Position)
 		}
-	} else {
-		c.Server.LoggingLevel = "info" // Default to info
 	}
+	return nil
+}
 
-	// Validate master_key
-	if c.Server.MasterKey == "" {
-		return fmt.Errorf("master_key is required")
+func (r *ResponseHeadersConfig) validate() error {
+	if r.MaxCount == 0 {
+		r.MaxCount = DefaultResponseHeadersMaxCount
 	}
-
-	// Validate and normalize default_models_rpm
-	// -1 means unlimited RPM, 0 is treated as unlimited
-	if c.Server.DefaultModelsRPM == 0 {
-		c.Server.DefaultModelsRPM = -1 // Convert 0 to unlimited (-1)
-	} else if c.Server.DefaultModelsRPM < -1 {
-		return fmt.Errorf("invalid default_models_rpm: %d (must be -1 for unlimited or positive number)", c.Server.DefaultModelsRPM)
+	if r.MaxBytes == 0 {
+		r.MaxBytes = DefaultResponseHeadersMaxBytes
 	}
-
-	// ReadTimeout equals RequestTimeout (not configurable via YAML)
-	c.Server.ReadTimeout = c.Server.RequestTimeout
-
-	// Validate MaxProviderRetries
-	if c.Server.MaxProviderRetries < 0 {
-		return fmt.Errorf("invalid max_provider_retries: %d (must be >= 0)", c.Server.MaxProviderRetries)
+	if r.MaxCount < -1 {
+		return fmt.Errorf("invalid response_headers.max_count: %d (must be > 0, or -1 for no limit)", r.MaxCount)
 	}
-
-	// Validate IdleTimeout against WriteTimeout
-	if c.Server.IdleTimeout == 0 {
-		c.Server.IdleTimeout = c.Server.WriteTimeout * 2
+	if r.MaxBytes < -1 {
+		return fmt.Errorf("invalid response_headers.max_bytes: %d (must be > 0, or -1 for no limit)", r.MaxBytes)
 	}
-
-	if c.Fail2Ban.MaxAttempts <= 0 {
-		return fmt.Errorf("invalid max_attempts: %d", c.Fail2Ban.MaxAttempts)
+	for _, name := range append(slices.Clone(r.Strip), r.Allow...) {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid response_headers entry: header name must not be empty")
+		}
 	}
+	return nil
+}
 
-	if len(c.Credentials) == 0 {
-		return fmt.Errorf("no credentials configured")
+// validateTenants checks that tenant names, keys, teams and credentials are unique, so every
+// request and credential belongs to at most one tenant
+func (c *Config) validateTenants() error {
+	credentials := make(map[string]bool, len(c.Credentials))
+	for _, cred := range c.Credentials {
+		credentials[cred.Name] = true
 	}
-
-	// Validate Fail2Ban error code rules for duplicates
-	seenErrorCodes := make(map[int]bool)
-	for _, rule := range c.Fail2Ban.ErrorCodeRules {
-		if seenErrorCodes[rule.Code] {
-			return fmt.Errorf("fail2ban: duplicate error_code_rules for code %d", rule.Code)
-		}
-		seenErrorCodes[rule.Code] = true
+	masterKeys := map[string]string{c.Server.MasterKey: ""}
+	for _, key := range c.Server.MasterKeys {
+		masterKeys[key] = ""
 	}
 
-	for i, cred := range c.Credentials {
-		if cred.Name == "" {
-			return fmt.Errorf("credential %d: name is required", i)
+	names := make(map[string]bool, len(c.Tenants))
+	owners := make(map[string]string) // "<kind>:<value>" -> tenant
+	for i, tenant := range c.Tenants {
+		if strings.TrimSpace(tenant.Name) == "" {
+			return fmt.Errorf("tenant %d: name is required", i)
 		}
-
-		// Validate provider type
-		if !cred.Type.IsValid() {
-			return fmt.Errorf("credential %s: invalid type: %s (must be 'openai', 'vertex-ai', 'gemini', 'anthropic', 'bedrock', or 'proxy')", cred.Name, cred.Type)
+		if names[tenant.Name] {
+			return fmt.Errorf("tenants: duplicate tenant name %q", tenant.Name)
+		}
+		names[tenant.Name] = true
+		if tenant.RPM < 0 {
+			return fmt.Errorf("tenant %s: invalid rpm: %d (must be >= 0)", tenant.Name, tenant.RPM)
+		}
+		if len(tenant.Credentials) == 0 {
+			return fmt.Errorf("tenant %s: credentials must list at least one credential", tenant.Name)
 		}
 
-		// Validate by provider type
-		switch cred.Type {
-		case ProviderTypeProxy:
-			// base_url is required for proxy
-			if cred.BaseURL == "" {
-				return fmt.Errorf("credential %s: base_url is required for proxy type", cred.Name)
-			}
-			// Validate base_url is a valid URL
-			if err := validateBaseURL(cred.Name, cred.BaseURL); err != nil {
-				return err
-			}
-			// api_key is optional for proxy
-
-		case ProviderTypeVertexAI:
-			// For Vertex AI, project_id and location are required
-			if cred.ProjectID == "" {
-				return fmt.Errorf("credential %s: project_id is required for vertex-ai type", cred.Name)
+		for _, key := range tenant.MasterKeys {
+			if strings.TrimSpace(key) == "" {
+				return fmt.Errorf("tenant %s: invalid master_keys entry: must not be empty", tenant.Name)
 			}
-			if cred.Location == "" {
-				return fmt.Errorf("credential %s: location is required for vertex-ai type", cred.Name)
+			if owner, ok := masterKeys[key]; ok {
+				if owner == "" {
+					return fmt.Errorf("tenant %s: master_keys entry must differ from server master keys", tenant.Name)
+				}
+				return fmt.Errorf("tenant %s: master_keys entry is already used by tenant %s", tenant.Name, owner)
 			}
-			// API Key is required for Vertex AI (Express Mode)
-			if cred.APIKey == "" && cred.CredentialsFile == "" && cred.CredentialsJSON == "" {
-				return fmt.Errorf("credential %s: api_key, credentials_file, or credentials_json is required for vertex-ai type", cred.Name)
+			masterKeys[key] = tenant.Name
+		}
+		for _, cred := range tenant.Credentials {
+			if !credentials[cred] {
+				return fmt.Errorf("tenant %s: unknown credential %q", tenant.Name, cred)
 			}
-			// Validate credentials_file exists if provided
-			if cred.CredentialsFile != "" {
-				if _, err := os.Stat(cred.CredentialsFile); err != nil {
-					return fmt.Errorf("credential %s: credentials_file does not exist or is not accessible: %w", cred.Name, err)
+		}
+
+		for _, list := range []struct {
+			kind   string
+			values []string
+		}{{"credentials", tenant.Credentials}, {"keys", tenant.Keys}, {"teams", tenant.Teams}} {
+			for _, value := range list.values {
+				if strings.TrimSpace(value) == "" {
+					return fmt.Errorf("tenant %s: invalid %s entry: must not be empty", tenant.Name, list.kind)
+				}
+				if owner, ok := owners[list.kind+":"+value]; ok && owner != tenant.Name {
+					return fmt.Errorf("tenant %s: %s entry %q is already assigned to tenant %s", tenant.Name, list.kind, value, owner)
 				}
+				owners[list.kind+":"+value] = tenant.Name
 			}
-			// base_url is optional for Vertex AI (will be constructed dynamically)
+		}
+	}
+	return nil
+}
 
-		case ProviderTypeGemini:
-			// For Gemini (Google AI Studio), api_key and base_url are required
-			if cred.APIKey == "" {
-				return fmt.Errorf("credential %s: api_key is required for gemini type", cred.Name)
-			}
-			if cred.BaseURL == "" {
-				return fmt.Errorf("credential %s: base_url is required for gemini type", cred.Name)
+// validateCredentialPools checks that pool names are unique and every pooled credential exists
+// and belongs to one pool only
+func (c *Config) validateCredentialPools() error {
+	credentials := make(map[string]bool, len(c.Credentials))
+	for _, cred := range c.Credentials {
+		credentials[cred.Name] = true
+	}
+
+	names := make(map[string]bool, len(c.CredentialPools))
+	owners := make(map[string]string) // credential -> pool
+	for i, pool := range c.CredentialPools {
+		if strings.TrimSpace(pool.Name) == "" {
+			return fmt.Errorf("credential pool %d: name is required", i)
+		}
+		if names[pool.Name] {
+			return fmt.Errorf("credential_pools: duplicate pool name %q", pool.Name)
+		}
+		names[pool.Name] = true
+		if pool.RPM < 0 {
+			return fmt.Errorf("credential pool %s: invalid rpm: %d (must be >= 0)", pool.Name, pool.RPM)
+		}
+		if pool.TPM < 0 {
+			return fmt.Errorf("credential pool %s: invalid tpm: %d (must be >= 0)", pool.Name, pool.TPM)
+		}
+		if pool.DailyBudget < 0 || pool.MonthlyBudget < 0 {
+			return fmt.Errorf("credential pool %s: budgets must not be negative", pool.Name)
+		}
+		if len(pool.Credentials) == 0 {
+			return fmt.Errorf("credential pool %s: credentials must list at least one credential", pool.Name)
+		}
+		for _, cred := range pool.Credentials {
+			if !credentials[cred] {
+				return fmt.Errorf("credential pool %s: unknown credential %q", pool.Name, cred)
 			}
-			if err := validateBaseURL(cred.Name, cred.BaseURL); err != nil {
-				return err
+			if owner, ok := owners[cred]; ok && owner != pool.Name {
+				return fmt.Errorf("credential pool %s: credential %q is already in pool %s", pool.Name, cred, owner)
 			}
+			owners[cred] = pool.Name
+		}
+	}
+	return nil
+}
 
-		default:
-			// For OpenAI and Anthropic, require APIKey and BaseURL
-			if cred.APIKey == "" {
-				return fmt.Errorf("credential %s: api_key is required", cred.Name)
-			}
-			if cred.BaseURL == "" {
-				return fmt.Errorf("credential %s: base_url is required", cred.Name)
+// validateChargeback checks that every chargeback rule lists keys, references existing
+// credentials and has non-negative rates
+func (c *Config) validateChargeback() error {
+	credentials := make(map[string]bool, len(c.Credentials))
+	for _, cred := range c.Credentials {
+		credentials[cred.Name] = true
+	}
+
+	for i, rule := range c.Chargeback.Rules {
+		if len(rule.Keys) == 0 {
+			return fmt.Errorf("chargeback.rules[%d].keys is required (use \"*\" for all keys)", i)
+		}
+		for _, key := range rule.Keys {
+			if strings.TrimSpace(key) == "" {
+				return fmt.Errorf("invalid chargeback.rules[%d].keys entry: name must not be empty", i)
 			}
-			// Validate base_url is a valid URL
-			if err := validateBaseURL(cred.Name, cred.BaseURL); err != nil {
-				return err
+		}
+		for _, cred := range rule.Credentials {
+			if !credentials[cred] {
+				return fmt.Errorf("chargeback.rules[%d]: unknown credential %q", i, cred)
 			}
 		}
-
-		// -1 means unlimited RPM
-		if cred.RPM <= 0 && !isUnlimited(cred.RPM) {
-			return fmt.Errorf("credential %s: invalid rpm: %d (must be -1 for unlimited or positive number)", cred.Name, cred.RPM)
+		if rule.MarkupPercent < 0 {
+			return fmt.Errorf("invalid chargeback.rules[%d].markup_percent: %v (must be >= 0)", i, rule.MarkupPercent)
 		}
-		// TPM: 0 or -1 means unlimited, positive means limited
-		if cred.TPM < -1 {
-			return fmt.Errorf("credential %s: invalid tpm: %d (must be -1 or 0 for unlimited, or positive number)", cred.Name, cred.TPM)
+		if rule.FeePerRequest < 0 {
+			return fmt.Errorf("invalid chargeback.rules[%d].fee_per_request: %v (must be >= 0)", i, rule.FeePerRequest)
 		}
 	}
+	return nil
+}
 
-	// Validate LiteLLM DB config
-	if c.LiteLLMDB.Enabled {
-		if c.LiteLLMDB.DatabaseURL == "" {
-			return fmt.Errorf("litellm_db.database_url is required when enabled")
-		}
-		if !strings.HasPrefix(c.LiteLLMDB.DatabaseURL, "postgres://") && !strings.HasPrefix(c.LiteLLMDB.DatabaseURL, "postgresql://") {
-			return fmt.Errorf("litellm_db.database_url must start with postgres:// or postgresql://, got: %s", c.LiteLLMDB.DatabaseURL)
+func (n *NonStreamingConfig) validate() error {
+	if len(n.Keys) == 0 {
+		return fmt.Errorf("non_streaming.keys must list at least one key alias or team ID")
+	}
+	for _, key := range n.Keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid non_streaming.keys entry: must not be empty")
 		}
 	}
-
 	return nil
 }
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fragmentConfig is the subset of Config that may be provided by included files
+// and credentials_dir files. Server-wide sections (server, fail2ban, monitoring,
+// litellm_db) can only be defined in the main config file.
+type fragmentConfig struct {
+	Credentials []CredentialConfig `yaml:"credentials,omitempty"`
+	Models      []ModelRPMConfig   `yaml:"models,omitempty"`
+	ModelAlias  map[string]string  `yaml:"model_alias,omitempty"`
+}
+
+// resolveIncludePath resolves env variables in path and makes it relative to baseDir
+func resolveIncludePath(baseDir, path string) string {
+	path = resolveEnvString(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// collectIncludeFiles returns the ordered list of fragment files referenced by the config.
+// Entries in include are expanded as glob patterns (in declaration order, sorted within a pattern),
+// followed by all *.yaml/*.yml files from credentials_dir sorted by file name.
+func collectIncludeFiles(cfg *Config, baseDir string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range cfg.Include {
+		resolved := resolveIncludePath(baseDir, pattern)
+		if resolved == "" {
+			continue
+		}
+		matches, err := filepath.Glob(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: invalid pattern: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			// A literal path without glob characters must exist
+			if !strings.ContainsAny(resolved, "*?[") {
+				return nil, fmt.Errorf("include %s: file does not exist", pattern)
+			}
+			continue
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			add(match)
+		}
+	}
+
+	if cfg.CredentialsDir != "" {
+		dir := resolveIncludePath(baseDir, cfg.CredentialsDir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("credentials_dir %s: %w", cfg.CredentialsDir, err)
+		}
+		// os.ReadDir returns entries sorted by file name
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if ext != ".yaml" && ext != ".yml" {
+				continue
+			}
+			add(filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// loadFragment reads and strictly decodes a single included file.
+// Unknown top-level keys are rejected so that misplaced server-wide settings are not silently ignored.
+func loadFragment(path string) (*fragmentConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included file %s: %w", path, err)
+	}
+
+	var fragment fragmentConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fragment); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse included file %s: %w", path, err)
+	}
+	return &fragment, nil
+}
+
+// mergeIncludes loads all fragment files referenced by include and credentials_dir
+// and merges them into cfg. Credentials and models are appended in file order.
+// Duplicate credential names and conflicting model aliases are reported as errors.
+func mergeIncludes(cfg *Config, baseDir string) error {
+	files, err := collectIncludeFiles(cfg, baseDir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	credentialSource := make(map[string]string, len(cfg.Credentials))
+	for _, cred := range cfg.Credentials {
+		credentialSource[cred.Name] = "main config"
+	}
+
+	for _, file := range files {
+		fragment, err := loadFragment(file)
+		if err != nil {
+			return err
+		}
+
+		for _, cred := range fragment.Credentials {
+			if source, exists := credentialSource[cred.Name]; exists && cred.Name != "" {
+				return fmt.Errorf("credential %s: defined in both %s and %s", cred.Name, source, file)
+			}
+			credentialSource[cred.Name] = file
+			cfg.Credentials = append(cfg.Credentials, cred)
+		}
+
+		cfg.Models = append(cfg.Models, fragment.Models...)
+
+		if len(fragment.ModelAlias) > 0 && cfg.ModelAlias == nil {
+			cfg.ModelAlias = make(map[string]string, len(fragment.ModelAlias))
+		}
+		for alias, target := range fragment.ModelAlias {
+			if existing, exists := cfg.ModelAlias[alias]; exists && existing != target {
+				return fmt.Errorf("model_alias %s: conflicting targets %q and %q (from %s)", alias, existing, target, file)
+			}
+			cfg.ModelAlias[alias] = target
+		}
+	}
+
+	cfg.IncludedFiles = files
+	return nil
+}
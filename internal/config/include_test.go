@@ -0,0 +1,248 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const includeBaseConfig = `
+server:
+  port: 8080
+  max_body_size_mb: 10
+  request_timeout: 30s
+  master_key: "sk-test"
+
+monitoring:
+  prometheus_enabled: false
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoad_Include(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	writeFile(t, configPath, includeBaseConfig+`
+include:
+  - teams/*.yaml
+
+credentials:
+  - name: "main"
+    type: "openai"
+    api_key: "sk-main"
+    base_url: "https://api.openai.com"
+    rpm: 10
+
+model_alias:
+  fast: gpt-4o-mini
+`)
+	writeFile(t, filepath.Join(tmpDir, "teams", "b.yaml"), `
+credentials:
+  - name: "team_b"
+    type: "openai"
+    api_key: "sk-b"
+    base_url: "https://b.example.com/v1"
+    rpm: 20
+`)
+	writeFile(t, filepath.Join(tmpDir, "teams", "a.yaml"), `
+credentials:
+  - name: "team_a"
+    type: "anthropic"
+    api_key: "sk-a"
+    base_url: "https://api.anthropic.com"
+    rpm: 30
+models:
+  - name: "claude-sonnet-4"
+    credential: "team_a"
+    rpm: 5
+model_alias:
+  smart: claude-sonnet-4
+`)
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Credentials, 3)
+	assert.Equal(t, "main", cfg.Credentials[0].Name)
+	assert.Equal(t, "team_a", cfg.Credentials[1].Name)
+	assert.Equal(t, "team_b", cfg.Credentials[2].Name)
+	// Included credentials are normalized like the main ones
+	assert.Equal(t, "https://b.example.com", cfg.Credentials[2].BaseURL)
+
+	require.Len(t, cfg.Models, 1)
+	assert.Equal(t, "team_a", cfg.Models[0].Credential)
+
+	assert.Equal(t, "gpt-4o-mini", cfg.ModelAlias["fast"])
+	assert.Equal(t, "claude-sonnet-4", cfg.ModelAlias["smart"])
+
+	assert.Equal(t, []string{
+		filepath.Join(tmpDir, "teams", "a.yaml"),
+		filepath.Join(tmpDir, "teams", "b.yaml"),
+	}, cfg.IncludedFiles)
+}
+
+func TestLoad_CredentialsDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	// No credentials in the main file: all of them come from credentials.d
+	writeFile(t, configPath, includeBaseConfig+`
+credentials_dir: credentials.d
+`)
+	writeFile(t, filepath.Join(tmpDir, "credentials.d", "10-openai.yaml"), `
+credentials:
+  - name: "openai"
+    type: "openai"
+    api_key: "sk-1"
+    base_url: "https://api.openai.com"
+    rpm: 10
+`)
+	writeFile(t, filepath.Join(tmpDir, "credentials.d", "20-proxy.yml"), `
+credentials:
+  - name: "proxy"
+    type: "proxy"
+    base_url: "http://proxy:8080"
+    rpm: 10
+`)
+	// Ignored: wrong extension, hidden file, subdirectory
+	writeFile(t, filepath.Join(tmpDir, "credentials.d", "README.md"), "not yaml")
+	writeFile(t, filepath.Join(tmpDir, "credentials.d", ".30-hidden.yaml"), "::invalid")
+	writeFile(t, filepath.Join(tmpDir, "credentials.d", "sub", "40.yaml"), "::invalid")
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Credentials, 2)
+	assert.Equal(t, "openai", cfg.Credentials[0].Name)
+	assert.Equal(t, "proxy", cfg.Credentials[1].Name)
+	assert.Len(t, cfg.IncludedFiles, 2)
+}
+
+func TestLoad_CredentialsDir_EnvVariable(t *testing.T) {
+	tmpDir := t.TempDir()
+	credDir := filepath.Join(tmpDir, "secrets")
+	t.Setenv("TEST_CREDENTIALS_DIR", credDir)
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	writeFile(t, configPath, includeBaseConfig+`
+credentials_dir: os.environ/TEST_CREDENTIALS_DIR
+`)
+	writeFile(t, filepath.Join(credDir, "cred.yaml"), `
+credentials:
+  - name: "openai"
+    type: "openai"
+    api_key: "sk-1"
+    base_url: "https://api.openai.com"
+    rpm: 10
+`)
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.Credentials, 1)
+	assert.Equal(t, "openai", cfg.Credentials[0].Name)
+}
+
+func TestLoad_Include_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		main     string
+		files    map[string]string
+		errorMsg string
+	}{
+		{
+			name:     "missing literal file",
+			main:     "include:\n  - missing.yaml\n",
+			errorMsg: "include missing.yaml: file does not exist",
+		},
+		{
+			name:     "missing credentials dir",
+			main:     "credentials_dir: nope.d\n",
+			errorMsg: "credentials_dir nope.d",
+		},
+		{
+			name: "duplicate credential name",
+			main: `
+include: [extra.yaml]
+credentials:
+  - name: "dup"
+    type: "openai"
+    api_key: "sk-1"
+    base_url: "https://api.openai.com"
+    rpm: 10
+`,
+			files: map[string]string{"extra.yaml": `
+credentials:
+  - name: "dup"
+    type: "openai"
+    api_key: "sk-2"
+    base_url: "https://api.openai.com"
+    rpm: 10
+`},
+			errorMsg: "credential dup: defined in both main config and",
+		},
+		{
+			name: "conflicting alias",
+			main: `
+include: [extra.yaml]
+credentials:
+  - name: "main"
+    type: "openai"
+    api_key: "sk-1"
+    base_url: "https://api.openai.com"
+    rpm: 10
+model_alias:
+  fast: gpt-4o-mini
+`,
+			files:    map[string]string{"extra.yaml": "model_alias:\n  fast: gemini-2.5-flash\n"},
+			errorMsg: "model_alias fast: conflicting targets",
+		},
+		{
+			name:     "server section in included file",
+			main:     "include: [extra.yaml]\n",
+			files:    map[string]string{"extra.yaml": "server:\n  port: 9090\n"},
+			errorMsg: "failed to parse included file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			writeFile(t, configPath, includeBaseConfig+tt.main)
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(tmpDir, name), content)
+			}
+
+			_, err := Load(configPath)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestLoad_Include_EmptyFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	writeFile(t, configPath, includeBaseConfig+`
+include: [empty.yaml]
+credentials:
+  - name: "main"
+    type: "openai"
+    api_key: "sk-1"
+    base_url: "https://api.openai.com"
+    rpm: 10
+`)
+	writeFile(t, filepath.Join(tmpDir, "empty.yaml"), "")
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Len(t, cfg.Credentials, 1)
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Encrypted config values have the form "enc:v1:<base64(nonce || AES-256-GCM ciphertext)>".
+// The 32-byte key is supplied base64-encoded via ConfigKeyEnv (e.g. from a Kubernetes secret
+// or a KMS-decrypted env var), so config files can be committed without plaintext provider keys.
+const (
+	EncryptedValuePrefix = "enc:v1:"
+	ConfigKeyEnv         = "AUTO_AI_ROUTER_CONFIG_KEY"
+	configKeySize        = 32
+)
+
+// IsEncryptedValue reports whether value is an encrypted config value
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, EncryptedValuePrefix)
+}
+
+// GenerateConfigKey returns a new random base64-encoded key for ConfigKeyEnv
+func GenerateConfigKey() (string, error) {
+	key := make([]byte, configKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ConfigKeyFromEnv reads and decodes the config encryption key from ConfigKeyEnv
+func ConfigKeyFromEnv() ([]byte, error) {
+	encoded := strings.TrimSpace(os.Getenv(ConfigKeyEnv))
+	if encoded == "" {
+		return nil, fmt.Errorf("%s is not set", ConfigKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ConfigKeyEnv, err)
+	}
+	if len(key) != configKeySize {
+		return nil, fmt.Errorf("invalid %s: key must be %d bytes, got %d", ConfigKeyEnv, configKeySize, len(key))
+	}
+	return key, nil
+}
+
+// EncryptValue encrypts plaintext into an "enc:v1:" config value
+func EncryptValue(plaintext string, key []byte) (string, error) {
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts an "enc:v1:" config value. Non-encrypted values are returned unchanged.
+func DecryptValue(value string, key []byte) (string, error) {
+	if !IsEncryptedValue(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value (wrong %s?)", ConfigKeyEnv)
+	}
+	return string(plaintext), nil
+}
+
+func newConfigCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretField is a config value that may be encrypted
+type secretField struct {
+	path  string
+	value *string
+}
+
+// secretFields lists config values that may hold encrypted secrets
+func (c *Config) secretFields() []secretField {
+	fields := []secretField{
+		{"server.master_key", &c.Server.MasterKey},
+		{"server.inter_router_secret", &c.Server.InterRouterSecret},
+		{"litellm_db.database_url", &c.LiteLLMDB.DatabaseURL},
+		{"litellm_db.replica_url", &c.LiteLLMDB.ReplicaURL},
+		{"monitoring.spend_push.password", &c.Monitoring.SpendPush.Password},
+	}
+	for i := range c.Server.MasterKeys {
+		fields = append(fields, secretField{fmt.Sprintf("server.master_keys[%d]", i), &c.Server.MasterKeys[i]})
+	}
+	for i := range c.Credentials {
+		cred := &c.Credentials[i]
+		fields = append(fields,
+			secretField{"credential " + cred.Name + ": api_key", &cred.APIKey},
+			secretField{"credential " + cred.Name + ": credentials_json", &cred.CredentialsJSON},
+			secretField{"credential " + cred.Name + ": hmac_secret", &cred.HMACSecret},
+		)
+	}
+	for i := range c.Tenants {
+		tenant := &c.Tenants[i]
+		for j := range tenant.MasterKeys {
+			fields = append(fields, secretField{fmt.Sprintf("tenants[%d].master_keys[%d]", i, j), &tenant.MasterKeys[j]})
+		}
+	}
+	return fields
+}
+
+// decryptSecrets decrypts "enc:v1:" values in place.
+// The key is only required when the config actually contains encrypted values.
+func (c *Config) decryptSecrets() error {
+	var key []byte
+	for _, field := range c.secretFields() {
+		if !IsEncryptedValue(*field.value) {
+			continue
+		}
+		if key == nil {
+			var err error
+			if key, err = ConfigKeyFromEnv(); err != nil {
+				return fmt.Errorf("%s is encrypted: %w", field.path, err)
+			}
+		}
+		plaintext, err := DecryptValue(*field.value, key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.path, err)
+		}
+		*field.value = plaintext
+	}
+	return nil
+}
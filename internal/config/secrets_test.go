@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfigKey(t *testing.T) []byte {
+	t.Helper()
+	encoded, err := GenerateConfigKey()
+	require.NoError(t, err)
+	t.Setenv(ConfigKeyEnv, encoded)
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	return key
+}
+
+func TestEncryptDecryptValue(t *testing.T) {
+	key := newTestConfigKey(t)
+
+	encrypted, err := EncryptValue("sk-secret", key)
+	require.NoError(t, err)
+	assert.True(t, IsEncryptedValue(encrypted))
+	assert.NotContains(t, encrypted, "sk-secret")
+
+	// Random nonce: same plaintext encrypts differently
+	again, err := EncryptValue("sk-secret", key)
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	plaintext, err := DecryptValue(encrypted, key)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+
+	// Plain values pass through
+	plaintext, err = DecryptValue("sk-plain", key)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-plain", plaintext)
+}
+
+func TestDecryptValue_Errors(t *testing.T) {
+	key := newTestConfigKey(t)
+	encrypted, err := EncryptValue("sk-secret", key)
+	require.NoError(t, err)
+
+	otherKey := make([]byte, configKeySize)
+	_, err = DecryptValue(encrypted, otherKey)
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	_, err = DecryptValue(EncryptedValuePrefix+"not-base64!", key)
+	assert.ErrorContains(t, err, "invalid encrypted value")
+
+	_, err = DecryptValue(EncryptedValuePrefix+"AAAA", key)
+	assert.ErrorContains(t, err, "too short")
+}
+
+func TestConfigKeyFromEnv(t *testing.T) {
+	t.Setenv(ConfigKeyEnv, "")
+	_, err := ConfigKeyFromEnv()
+	assert.ErrorContains(t, err, "is not set")
+
+	t.Setenv(ConfigKeyEnv, base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = ConfigKeyFromEnv()
+	assert.ErrorContains(t, err, "must be 32 bytes")
+
+	newTestConfigKey(t)
+	key, err := ConfigKeyFromEnv()
+	require.NoError(t, err)
+	assert.Len(t, key, configKeySize)
+}
+
+func TestLoad_EncryptedValues(t *testing.T) {
+	key := newTestConfigKey(t)
+	apiKey, err := EncryptValue("sk-openai-secret", key)
+	require.NoError(t, err)
+	masterKey, err := EncryptValue("sk-master-secret", key)
+	require.NoError(t, err)
+
+	// Encrypted values may also come from environment variables
+	t.Setenv("TEST_ENCRYPTED_MASTER_KEY", masterKey)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+server:
+  port: 8080
+  master_key: os.environ/TEST_ENCRYPTED_MASTER_KEY
+
+credentials:
+  - name: openai
+    type: openai
+    api_key: "` + apiKey + `"
+    base_url: https://api.openai.com
+    rpm: 10
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-master-secret", cfg.Server.MasterKey)
+	assert.Equal(t, "sk-openai-secret", cfg.Credentials[0].APIKey)
+
+	// Without the key, encrypted configs fail to load with the offending field named
+	t.Setenv(ConfigKeyEnv, "")
+	_, err = Load(configPath)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "server.master_key is encrypted"), err.Error())
+}
+
+func TestLoad_EncryptedTenantMasterKeys(t *testing.T) {
+	key := newTestConfigKey(t)
+	tenantKey, err := EncryptValue("sk-tenant-secret", key)
+	require.NoError(t, err)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+server:
+  port: 8080
+  master_key: sk-master
+
+credentials:
+  - name: openai
+    type: openai
+    api_key: sk-openai
+    base_url: https://api.openai.com
+    rpm: 10
+
+tenants:
+  - name: team-a
+    master_keys:
+      - "` + tenantKey + `"
+    credentials: [openai]
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.Tenants, 1)
+	assert.Equal(t, []string{"sk-tenant-secret"}, cfg.Tenants[0].MasterKeys)
+}
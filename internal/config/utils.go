@@ -65,6 +65,17 @@ func isUnlimited(value int) bool {
 	return value == -1
 }
 
+// validateRPMBurst checks that a token bucket burst is only set on a limited RPM
+func validateRPMBurst(rpm, burst int) error {
+	if burst < 0 {
+		return fmt.Errorf("invalid rpm_burst: %d (must be 0 to disable or positive number)", burst)
+	}
+	if burst > 0 && rpm <= 0 {
+		return fmt.Errorf("rpm_burst requires a positive rpm, got rpm: %d", rpm)
+	}
+	return nil
+}
+
 // PrintConfig outputs the configuration in a structured, readable format to the logger
 func PrintConfig(logger *slog.Logger, cfg *Config) {
 	logger.Info("=== Configuration Loaded ===")
@@ -80,12 +91,30 @@ func PrintConfig(logger *slog.Logger, cfg *Config) {
 		"idle_timeout", cfg.Server.IdleTimeout.String(),
 		"logging_level", cfg.Server.LoggingLevel,
 		"master_key", "***REDACTED***",
+		"master_keys", len(cfg.Server.MasterKeys),
+		"master_key_grace_period", cfg.Server.MasterKeyGracePeriod.String(),
 		"default_models_rpm", rpmToString(cfg.Server.DefaultModelsRPM),
 		"max_idle_conns", cfg.Server.MaxIdleConns,
 		"max_idle_conns_per_host", cfg.Server.MaxIdleConnsPerHost,
 		"idle_conn_timeout", cfg.Server.IdleConnTimeout.String(),
 		"model_prices_link", cfg.Server.ModelPricesLink,
+		"model_price_mappings", len(cfg.Server.ModelPriceMapping),
+		"model_prices_cache_file", cfg.Server.ModelPricesCacheFile,
+		"skip_zero_cost_logs", cfg.Server.SkipZeroCostLogs,
 		"max_provider_retries", cfg.Server.MaxProviderRetries,
+		"admin_port", cfg.Server.AdminPort,
+		"inter_router_auth", cfg.Server.InterRouterSecret != "",
+		"unsupported_params", cfg.Server.UnsupportedParams,
+		"deterministic_routing", cfg.Server.DeterministicRouting,
+		"read_only", cfg.Server.ReadOnly,
+		"hide_unavailable_models", cfg.Server.HideUnavailableModels,
+		"stale_model_ttl", cfg.Server.StaleModelTTL.String(),
+		"stream_first_byte_timeout", cfg.Server.StreamFirstByteTimeout.String(),
+		"fallback_timeout", cfg.Server.FallbackTimeout.String(),
+		"fallback_body_multiplier", cfg.Server.FallbackBodyMultiplier,
+		"request_deadline", cfg.Server.RequestDeadline.String(),
+		"upstream_compression", cfg.Server.UpstreamCompression,
+		"batch_state_file", cfg.Server.BatchStateFile,
 	)
 
 	// Monitoring config
@@ -94,7 +123,31 @@ func PrintConfig(logger *slog.Logger, cfg *Config) {
 		"health_check_path", cfg.Monitoring.HealthCheckPath,
 		"log_errors", cfg.Monitoring.LogErrors,
 		"errors_log_path", cfg.Monitoring.ErrorsLogPath,
+		"spend_push_enabled", cfg.Monitoring.SpendPush.Enabled,
+		"request_sampling_percent", cfg.Monitoring.RequestSampling.Percent,
+		"request_sampling_keys", len(cfg.Monitoring.RequestSampling.Keys),
 	)
+	if cfg.Monitoring.SpendPush.Enabled {
+		logger.Info("  spend_push",
+			"pushgateway_url", cfg.Monitoring.SpendPush.PushgatewayURL,
+			"job", cfg.Monitoring.SpendPush.Job,
+			"instance", cfg.Monitoring.SpendPush.Instance,
+			"interval", cfg.Monitoring.SpendPush.Interval.String(),
+		)
+	}
+	if cfg.Monitoring.SLO.Enabled {
+		alerts := make([]string, 0, len(cfg.Monitoring.SLO.Alerts))
+		for _, alert := range cfg.Monitoring.SLO.Alerts {
+			alerts = append(alerts, alert.Name)
+		}
+		logger.Info("  slo",
+			"availability", cfg.Monitoring.SLO.Availability,
+			"latency_threshold", cfg.Monitoring.SLO.LatencyThreshold.String(),
+			"latency_target", cfg.Monitoring.SLO.LatencyTarget,
+			"alerts", alerts,
+			"webhook", cfg.Monitoring.SLO.WebhookURL != "",
+		)
+	}
 
 	// Fail2Ban config
 	logger.Info("fail2ban",
@@ -103,6 +156,280 @@ func PrintConfig(logger *slog.Logger, cfg *Config) {
 		"error_codes_count", len(cfg.Fail2Ban.ErrorCodes),
 		"error_code_rules_count", len(cfg.Fail2Ban.ErrorCodeRules),
 	)
+	if cfg.Fail2Ban.StateFile != "" {
+		logger.Info("fail2ban_state",
+			"state_file", cfg.Fail2Ban.StateFile,
+			"save_interval", cfg.Fail2Ban.StateSaveInterval.String(),
+		)
+	}
+
+	// Startup check config
+	logger.Info("startup_check",
+		"enabled", cfg.StartupCheck.Enabled,
+		"strict", cfg.StartupCheck.Strict,
+		"timeout", cfg.StartupCheck.Timeout.String(),
+		"concurrency", cfg.StartupCheck.Concurrency,
+	)
+
+	// Fair scheduler config
+	if cfg.FairScheduler.Enabled {
+		logger.Info("fair_scheduler",
+			"max_concurrent", cfg.FairScheduler.MaxConcurrent,
+			"max_queue", cfg.FairScheduler.MaxQueue,
+			"queue_timeout", cfg.FairScheduler.QueueTimeout.String(),
+			"default_weight", cfg.FairScheduler.DefaultWeight,
+			"weights_count", len(cfg.FairScheduler.Weights),
+		)
+	}
+
+	// Image inlining config
+	if cfg.ImageInlining.Enabled {
+		logger.Info("image_inlining",
+			"max_size_mb", cfg.ImageInlining.MaxSizeMB,
+			"allowed_mime_types", cfg.ImageInlining.AllowedMIMETypes,
+			"timeout", cfg.ImageInlining.Timeout.String(),
+			"cache_ttl", cfg.ImageInlining.CacheTTL.String(),
+			"cache_size", cfg.ImageInlining.CacheSize,
+			"allow_private_networks", cfg.ImageInlining.AllowPrivateNetworks,
+		)
+	}
+
+	// DNS cache config
+	if cfg.DNSCache.Enabled {
+		logger.Info("dns_cache",
+			"ttl", cfg.DNSCache.TTL.String(),
+		)
+	}
+
+	// Fault injection config
+	if cfg.FaultInjection.Enabled {
+		logger.Warn("fault_injection enabled: upstream failures are simulated, do not use in production",
+			"rules_count", len(cfg.FaultInjection.Rules),
+		)
+	}
+
+	// Usage forecast config
+	if cfg.UsageForecast.Enabled {
+		logger.Info("usage_forecast",
+			"interval", cfg.UsageForecast.Interval.String(),
+			"window", cfg.UsageForecast.Window.String(),
+			"warn_threshold", cfg.UsageForecast.WarnThreshold.String(),
+		)
+	}
+
+	// Context management config
+	if cfg.ContextManagement.Enabled {
+		logger.Info("context_management",
+			"strategy", cfg.ContextManagement.Strategy,
+			"max_prompt_tokens", cfg.ContextManagement.MaxPromptTokens,
+			"keep_recent", cfg.ContextManagement.KeepRecent,
+			"summary_model", cfg.ContextManagement.SummaryModel,
+		)
+	}
+
+	// Conversion validation config
+	if cfg.ConversionCheck.Enabled {
+		logger.Info("conversion_validation", "reject", cfg.ConversionCheck.Reject)
+	}
+
+	// Response post-processing config
+	if pp := cfg.PostProcessing; pp.StripPlaceholders || pp.TrimLeadingWhitespace || pp.StripStopSequences {
+		logger.Info("response_postprocessing",
+			"strip_placeholders", pp.StripPlaceholders,
+			"trim_leading_whitespace", pp.TrimLeadingWhitespace,
+			"strip_stop_sequences", pp.StripStopSequences,
+		)
+	}
+
+	// Attribution headers config
+	if cfg.Attribution.Enabled {
+		logger.Info("attribution_headers",
+			"keys", cfg.Attribution.Keys,
+		)
+	}
+
+	// Spend report config (webhook URL omitted, Slack webhook URLs are secrets)
+	if cfg.SpendReport.Enabled {
+		logger.Info("spend_report",
+			"time", cfg.SpendReport.Time,
+			"top_n", cfg.SpendReport.TopN,
+			"webhook", cfg.SpendReport.WebhookURL != "",
+			"webhook_format", cfg.SpendReport.WebhookFormat,
+			"output_dir", cfg.SpendReport.OutputDir,
+			"output_format", cfg.SpendReport.OutputFormat,
+		)
+	}
+
+	// Local spend log config
+	if cfg.LocalSpendLog.Enabled {
+		logger.Info("local_spend_log",
+			"path", cfg.LocalSpendLog.Path,
+			"retention", cfg.LocalSpendLog.Retention,
+		)
+	}
+
+	// Metrics export config
+	if cfg.MetricsExport.Enabled {
+		logger.Info("metrics_export", "path", cfg.MetricsExport.Path)
+	}
+
+	// Quota boosts config
+	if cfg.QuotaBoosts.Enabled {
+		logger.Info("quota_boosts",
+			"max_duration", cfg.QuotaBoosts.MaxDuration,
+		)
+	}
+
+	// Priority classes config
+	if cfg.PriorityClasses.Enabled {
+		logger.Info("priority_classes",
+			"classes", cfg.PriorityClasses.Classes,
+			"default_class", cfg.PriorityClasses.DefaultClass,
+			"assignments", len(cfg.PriorityClasses.Assignments),
+		)
+	}
+
+	// Credential pools config
+	for _, pool := range cfg.CredentialPools {
+		logger.Info("credential pool",
+			"name", pool.Name,
+			"credentials", pool.Credentials,
+			"rpm", pool.RPM,
+			"tpm", pool.TPM,
+			"daily_budget", pool.DailyBudget,
+			"monthly_budget", pool.MonthlyBudget,
+		)
+	}
+
+	// Tenants config
+	for _, tenant := range cfg.Tenants {
+		logger.Info("tenant",
+			"name", tenant.Name,
+			"credentials", tenant.Credentials,
+			"master_keys", len(tenant.MasterKeys),
+			"keys", len(tenant.Keys),
+			"teams", len(tenant.Teams),
+			"rpm", tenant.RPM,
+		)
+	}
+
+	// Stream coalescing config
+	if cfg.StreamCoalescing.Enabled {
+		logger.Info("stream_coalescing",
+			"interval", cfg.StreamCoalescing.Interval,
+			"max_bytes", cfg.StreamCoalescing.MaxBytes,
+		)
+	}
+
+	// Non-streaming config
+	if cfg.NonStreaming.Enabled {
+		logger.Info("non_streaming",
+			"keys", cfg.NonStreaming.Keys,
+		)
+	}
+
+	// LiteLLM headers config
+	if cfg.LiteLLMHeaders.Enabled {
+		logger.Info("litellm_headers", "enabled", true)
+	}
+
+	if cfg.MaxCostPerRequest.Enabled {
+		logger.Info("max_cost_per_request",
+			"default", cfg.MaxCostPerRequest.Default,
+			"keys", len(cfg.MaxCostPerRequest.Keys),
+		)
+	}
+
+	if cfg.ModelPins.Enabled {
+		for alias, pin := range cfg.ModelPins.Aliases {
+			logger.Info("model_pin",
+				"alias", alias,
+				"policy", pin.Policy,
+				"family", pin.Family,
+				"snapshot", pin.Snapshot,
+			)
+		}
+	}
+
+	if cfg.ReasoningRouting.Enabled {
+		for name, group := range cfg.ReasoningRouting.Groups {
+			logger.Info("reasoning_group",
+				"group", name,
+				"tiers", group.Tiers,
+				"default", group.Default,
+				"non_reasoning", group.NonReasoning,
+			)
+		}
+	}
+
+	for family, model := range cfg.ReasoningParams.Models {
+		logger.Info("reasoning_params",
+			"family", family,
+			"rename_params", model.RenameParams,
+			"drop_params", model.DropParams,
+			"thinking_budgets", model.ThinkingBudgets,
+		)
+	}
+
+	logger.Info("response_headers",
+		"max_count", cfg.ResponseHeaders.MaxCount,
+		"max_bytes", cfg.ResponseHeaders.MaxBytes,
+		"strip", cfg.ResponseHeaders.Strip,
+		"allow", cfg.ResponseHeaders.Allow,
+	)
+
+	if cfg.ParamNegotiation.Enabled {
+		logger.Info("param_negotiation", "ttl", cfg.ParamNegotiation.TTL)
+	}
+
+	if cfg.ChoiceFanOut.Enabled {
+		logger.Info("n_fanout", "max_n", cfg.ChoiceFanOut.MaxN)
+	}
+
+	if cfg.SystemPrompts.Enabled {
+		logger.Info("system_prompts", "rules", len(cfg.SystemPrompts.Rules))
+	}
+
+	if cfg.Chargeback.Enabled {
+		logger.Info("chargeback", "rules", len(cfg.Chargeback.Rules))
+	}
+
+	if cfg.UpstreamErrors.Wrap {
+		logger.Info("upstream_errors", "max_detail_length", cfg.UpstreamErrors.MaxDetailLength)
+	}
+
+	if cfg.RoutingOverrides.Enabled {
+		logger.Info("routing_overrides",
+			"keys", cfg.RoutingOverrides.Keys,
+		)
+	}
+
+	// Cassette config
+	if cfg.Cassette.Mode != "" {
+		logger.Warn("cassette enabled: upstream traffic is recorded or replayed, do not use in production",
+			"mode", cfg.Cassette.Mode,
+			"dir", cfg.Cassette.Dir,
+		)
+	}
+
+	// Adaptive limits config
+	if cfg.AdaptiveLimits.Enabled {
+		logger.Info("adaptive_limits",
+			"decrease_factor", cfg.AdaptiveLimits.DecreaseFactor,
+			"increase_step", cfg.AdaptiveLimits.IncreaseStep,
+			"increase_interval", cfg.AdaptiveLimits.IncreaseInterval.String(),
+			"min_factor", cfg.AdaptiveLimits.MinFactor,
+			"cooldown", cfg.AdaptiveLimits.Cooldown.String(),
+		)
+	}
+
+	// Included files
+	if len(cfg.IncludedFiles) > 0 {
+		logger.Info("include", "files_count", len(cfg.IncludedFiles))
+		for _, file := range cfg.IncludedFiles {
+			logger.Info("  included", "file", file)
+		}
+	}
 
 	// Credentials
 	logger.Info("credentials",
@@ -117,11 +444,51 @@ func PrintConfig(logger *slog.Logger, cfg *Config) {
 			"tpm":         tpmToString(cred.TPM),
 			"is_fallback": cred.IsFallback,
 		}
+		if cred.RPMBurst > 0 {
+			credLog["rpm_burst"] = cred.RPMBurst
+		}
+		if cred.Region != "" {
+			credLog["region"] = cred.Region
+		}
+		if cred.Quota != nil {
+			credLog["quota"] = fmt.Sprintf("daily_tokens=%d monthly_tokens=%d daily_spend=%g monthly_spend=%g",
+				cred.Quota.DailyTokens, cred.Quota.MonthlyTokens, cred.Quota.DailySpend, cred.Quota.MonthlySpend)
+		}
+		if cred.HMACSecret != "" {
+			credLog["hmac_signed"] = true
+		}
+		if cred.ProxyModels != nil {
+			credLog["proxy_models"] = fmt.Sprintf("allow=%d deny=%d rename=%d",
+				len(cred.ProxyModels.Allow), len(cred.ProxyModels.Deny), len(cred.ProxyModels.Rename))
+		}
+		if len(cred.ModelMap) > 0 {
+			credLog["model_map"] = len(cred.ModelMap)
+		}
+		if len(cred.Resolve) > 0 {
+			credLog["resolve"] = cred.Resolve
+		}
+
+		if cred.Type == ProviderTypeAnthropic {
+			credLog["anthropic_version"] = cred.AnthropicVersion
+			if len(cred.AnthropicBeta) > 0 {
+				credLog["anthropic_beta"] = strings.Join(cred.AnthropicBeta, ",")
+			}
+		}
 
 		// Add Vertex AI specific fields if present
 		if cred.Type == ProviderTypeVertexAI {
 			credLog["project_id"] = cred.ProjectID
 			credLog["location"] = cred.Location
+			credLog["api_version"] = cred.APIVersion
+			if cred.PredictAPIVersion != cred.APIVersion {
+				credLog["predict_api_version"] = cred.PredictAPIVersion
+			}
+			if cred.ProvisionedThroughput {
+				credLog["provisioned_throughput"] = true
+			}
+			if cred.QuotaProject != "" {
+				credLog["quota_project"] = cred.QuotaProject
+			}
 		}
 
 		logger.Info(fmt.Sprintf("  [%d] credential", i), convertMapToArgs(credLog)...)
@@ -158,7 +525,9 @@ func PrintConfig(logger *slog.Logger, cfg *Config) {
 			"is_required", cfg.LiteLLMDB.IsRequired,
 			"max_conns", cfg.LiteLLMDB.MaxConns,
 			"min_conns", cfg.LiteLLMDB.MinConns,
+			"replica_url", security.MaskDatabaseURL(cfg.LiteLLMDB.ReplicaURL),
 			"health_check_interval", cfg.LiteLLMDB.HealthCheckInterval.String(),
+			"health_failure_threshold", cfg.LiteLLMDB.HealthFailureThreshold,
 			"connect_timeout", cfg.LiteLLMDB.ConnectTimeout.String(),
 			"auth_cache_ttl", cfg.LiteLLMDB.AuthCacheTTL.String(),
 			"auth_cache_size", cfg.LiteLLMDB.AuthCacheSize,
@@ -0,0 +1,55 @@
+package contextwindow
+
+import "encoding/json"
+
+// estimateTokens estimates the prompt tokens of messages
+func estimateTokens(messages []interface{}) int {
+	total := 0
+	for _, message := range messages {
+		total += messageTokens(message)
+	}
+	return total
+}
+
+// messageTokens estimates the tokens of one message with the 4 characters per token
+// heuristic used for prompt estimates elsewhere in the router. Tool call arguments count
+// towards the message; images and audio are not estimated.
+func messageTokens(message interface{}) int {
+	chars := len(messageText(message))
+	if m, ok := message.(map[string]interface{}); ok {
+		if toolCalls, ok := m["tool_calls"]; ok {
+			if data, err := json.Marshal(toolCalls); err == nil {
+				chars += len(data)
+			}
+		}
+	}
+	return (chars+3)/4 + messageOverheadTokens
+}
+
+// messageText returns the text content of a message (string or text content blocks)
+func messageText(message interface{}) string {
+	m, ok := message.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch content := m["content"].(type) {
+	case string:
+		return content
+	case []interface{}:
+		var text string
+		for _, block := range content {
+			blockMap, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if t, ok := blockMap["text"].(string); ok {
+				if text != "" {
+					text += "\n"
+				}
+				text += t
+			}
+		}
+		return text
+	}
+	return ""
+}
@@ -0,0 +1,232 @@
+package contextwindow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Strategies for fitting a conversation into the prompt budget
+const (
+	StrategyTruncate  = "truncate"  // Drop the oldest non-system messages
+	StrategySummarize = "summarize" // Replace the oldest non-system messages with a summary
+)
+
+// messageOverheadTokens approximates the per-message tokens used by roles and separators
+const messageOverheadTokens = 4
+
+// summaryPrompt instructs the summary model
+const summaryPrompt = "Summarize the following conversation between a user and an assistant. " +
+	"Keep facts, decisions, names, numbers and open questions needed to continue it. " +
+	"Reply with the summary only."
+
+// Config configures a Manager
+type Config struct {
+	Strategy         string // "truncate" or "summarize"
+	MaxPromptTokens  int    // Prompt budget (0 = model context only)
+	KeepRecent       int    // Most recent non-system messages that are never removed
+	SummaryModel     string // Model used to summarize removed messages (summarize strategy)
+	SummaryMaxTokens int    // max_tokens of summary requests
+}
+
+// Summarizer sends an OpenAI Chat Completions request body and returns the response body
+type Summarizer func(ctx context.Context, body []byte) ([]byte, error)
+
+// Result describes how a request was fitted into the prompt budget
+type Result struct {
+	Dropped      int  // Number of messages removed from the conversation
+	Summarized   bool // Removed messages were replaced with a summary
+	TokensBefore int  // Estimated prompt tokens of the original request
+	TokensAfter  int  // Estimated prompt tokens of the forwarded request
+}
+
+// Manager keeps multi-turn Chat Completions requests within the model context
+// by removing or summarizing the oldest conversation turns.
+type Manager struct {
+	cfg Config
+}
+
+// New creates a Manager
+func New(cfg Config) *Manager {
+	if cfg.Strategy == "" {
+		cfg.Strategy = StrategyTruncate
+	}
+	if cfg.KeepRecent < 1 {
+		cfg.KeepRecent = 1
+	}
+	return &Manager{cfg: cfg}
+}
+
+// Budget returns the prompt token budget for a model with the given context size
+// (0 = unknown). Returns 0 when no budget applies.
+func (m *Manager) Budget(modelLimit int) int {
+	budget := m.cfg.MaxPromptTokens
+	if modelLimit > 0 && (budget == 0 || modelLimit < budget) {
+		budget = modelLimit
+	}
+	return budget
+}
+
+// Fit removes the oldest non-system messages of an OpenAI Chat Completions request body
+// until its estimated prompt tokens fit the budget. System and developer messages and the
+// last KeepRecent messages are always kept. With the summarize strategy the removed
+// messages are replaced by a summary from summarize; if that fails they are only removed
+// and the error is returned alongside the truncated body.
+// Returns the body unchanged (and a zero Dropped count) when it already fits.
+func (m *Manager) Fit(ctx context.Context, body []byte, budget int, summarize Summarizer) ([]byte, Result, error) {
+	if budget <= 0 {
+		return body, Result{}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large integers (e.g. seed) intact on re-encoding
+	var req map[string]interface{}
+	if err := decoder.Decode(&req); err != nil {
+		return body, Result{}, nil
+	}
+	messages, ok := req["messages"].([]interface{})
+	if !ok {
+		return body, Result{}, nil
+	}
+
+	result := Result{TokensBefore: estimateTokens(messages)}
+	result.TokensAfter = result.TokensBefore
+	if result.TokensBefore <= budget {
+		return body, result, nil
+	}
+
+	// Indexes of messages that may be removed, oldest first
+	var removable []int
+	for i, message := range messages {
+		if !isSystemMessage(message) {
+			removable = append(removable, i)
+		}
+	}
+	cutoff := max(len(removable)-m.cfg.KeepRecent, 0)
+	// Kept tool results need the assistant message that requested them
+	for cutoff > 0 && role(messages[removable[cutoff]]) == "tool" {
+		cutoff--
+	}
+	removable = removable[:cutoff]
+
+	removed := make(map[int]bool)
+	tokens := result.TokensBefore
+	for _, idx := range removable {
+		// Tool results must not outlive the assistant message that requested them
+		if tokens <= budget && role(messages[idx]) != "tool" {
+			break
+		}
+		removed[idx] = true
+		tokens -= messageTokens(messages[idx])
+	}
+	if len(removed) == 0 {
+		return body, result, nil
+	}
+
+	var dropped []interface{}
+	kept := make([]interface{}, 0, len(messages)-len(removed)+1)
+	for i, message := range messages {
+		if removed[i] {
+			dropped = append(dropped, message)
+			continue
+		}
+		kept = append(kept, message)
+	}
+	result.Dropped = len(dropped)
+
+	var summaryErr error
+	if m.cfg.Strategy == StrategySummarize && summarize != nil {
+		summary, err := m.summarize(ctx, dropped, summarize)
+		if err != nil {
+			summaryErr = fmt.Errorf("failed to summarize %d messages: %w", len(dropped), err)
+		} else {
+			kept = insertAfterSystem(kept, map[string]interface{}{
+				"role":    "system",
+				"content": "Summary of the earlier conversation:\n" + summary,
+			})
+			result.Summarized = true
+		}
+	}
+
+	req["messages"] = kept
+	newBody, err := json.Marshal(req)
+	if err != nil {
+		return body, Result{TokensBefore: result.TokensBefore, TokensAfter: result.TokensBefore}, fmt.Errorf("failed to encode request: %w", err)
+	}
+	result.TokensAfter = estimateTokens(kept)
+	return newBody, result, summaryErr
+}
+
+// summarize asks the summary model to condense messages
+func (m *Manager) summarize(ctx context.Context, messages []interface{}, summarize Summarizer) (string, error) {
+	var transcript strings.Builder
+	for _, message := range messages {
+		text := messageText(message)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n\n", role(message), text)
+	}
+
+	req := map[string]interface{}{
+		"model": m.cfg.SummaryModel,
+		"messages": []map[string]string{
+			{"role": "system", "content": summaryPrompt},
+			{"role": "user", "content": transcript.String()},
+		},
+	}
+	if m.cfg.SummaryMaxTokens > 0 {
+		req["max_tokens"] = m.cfg.SummaryMaxTokens
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	respBody, err := summarize(ctx, body)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("invalid summary response: %w", err)
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", errors.New("empty summary response")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// insertAfterSystem inserts message after the leading system messages
+func insertAfterSystem(messages []interface{}, message interface{}) []interface{} {
+	pos := 0
+	for pos < len(messages) && isSystemMessage(messages[pos]) {
+		pos++
+	}
+	messages = append(messages, nil)
+	copy(messages[pos+1:], messages[pos:])
+	messages[pos] = message
+	return messages
+}
+
+func role(message interface{}) string {
+	if m, ok := message.(map[string]interface{}); ok {
+		r, _ := m["role"].(string)
+		return r
+	}
+	return ""
+}
+
+func isSystemMessage(message interface{}) bool {
+	r := role(message)
+	return r == "system" || r == "developer"
+}
@@ -0,0 +1,205 @@
+package contextwindow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// words returns a string of n 4-character words (~n tokens)
+func words(n int) string {
+	return strings.Repeat("abc ", n)
+}
+
+func conversation() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"role": "system", "content": "You are helpful."},
+		{"role": "user", "content": words(100)},
+		{"role": "assistant", "content": words(100)},
+		{"role": "user", "content": words(100)},
+		{"role": "assistant", "content": words(100)},
+		{"role": "user", "content": "Latest question"},
+	}
+}
+
+func requestBody(t *testing.T, messages []map[string]interface{}) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"model": "gpt-4o", "seed": json.Number("9007199254740993"), "messages": messages})
+	require.NoError(t, err)
+	return body
+}
+
+func decodeMessages(t *testing.T, body []byte) []map[string]interface{} {
+	t.Helper()
+	var req struct {
+		Messages []map[string]interface{} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(body, &req))
+	return req.Messages
+}
+
+func TestBudget(t *testing.T) {
+	assert.Equal(t, 0, New(Config{}).Budget(0))
+	assert.Equal(t, 128000, New(Config{}).Budget(128000))
+	assert.Equal(t, 8000, New(Config{MaxPromptTokens: 8000}).Budget(128000))
+	assert.Equal(t, 4000, New(Config{MaxPromptTokens: 8000}).Budget(4000))
+	assert.Equal(t, 8000, New(Config{MaxPromptTokens: 8000}).Budget(0))
+}
+
+func TestFit_WithinBudget(t *testing.T) {
+	body := requestBody(t, conversation())
+
+	newBody, result, err := New(Config{}).Fit(context.Background(), body, 10000, nil)
+	require.NoError(t, err)
+	assert.Equal(t, body, newBody)
+	assert.Equal(t, 0, result.Dropped)
+
+	newBody, _, err = New(Config{}).Fit(context.Background(), body, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, body, newBody)
+}
+
+func TestFit_Truncate(t *testing.T) {
+	body := requestBody(t, conversation())
+
+	newBody, result, err := New(Config{}).Fit(context.Background(), body, 250, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Dropped)
+	assert.False(t, result.Summarized)
+	assert.Greater(t, result.TokensBefore, 250)
+	assert.LessOrEqual(t, result.TokensAfter, 250)
+	assert.Contains(t, string(newBody), `"seed":9007199254740993`)
+
+	messages := decodeMessages(t, newBody)
+	require.Len(t, messages, 4)
+	assert.Equal(t, "system", messages[0]["role"])
+	assert.Equal(t, "user", messages[1]["role"])
+	assert.Equal(t, "Latest question", messages[3]["content"])
+}
+
+func TestFit_KeepsRecentMessages(t *testing.T) {
+	body := requestBody(t, conversation())
+
+	// Budget below what the kept messages need: only removable messages go
+	newBody, result, err := New(Config{KeepRecent: 2}).Fit(context.Background(), body, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Dropped)
+
+	messages := decodeMessages(t, newBody)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "system", messages[0]["role"])
+	assert.Equal(t, "assistant", messages[1]["role"])
+	assert.Equal(t, "Latest question", messages[2]["content"])
+}
+
+func TestFit_ToolResultsStayWithToolCalls(t *testing.T) {
+	messages := []map[string]interface{}{
+		{"role": "user", "content": words(200)},
+		{"role": "assistant", "content": nil, "tool_calls": []map[string]interface{}{
+			{"id": "call_1", "type": "function", "function": map[string]interface{}{"name": "lookup", "arguments": `{"q":"` + words(50) + `"}`}},
+		}},
+		{"role": "tool", "tool_call_id": "call_1", "content": words(50)},
+		{"role": "user", "content": "Thanks, and now?"},
+	}
+	body := requestBody(t, messages)
+
+	// Dropping the first user message fits the budget; the tool chain is kept intact
+	newBody, result, err := New(Config{}).Fit(context.Background(), body, 150, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Dropped)
+	kept := decodeMessages(t, newBody)
+	require.Len(t, kept, 3)
+	assert.Equal(t, "assistant", kept[0]["role"])
+
+	// Dropping the assistant tool call also drops its tool result
+	newBody, result, err = New(Config{}).Fit(context.Background(), body, 40, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Dropped)
+	kept = decodeMessages(t, newBody)
+	require.Len(t, kept, 1)
+	assert.Equal(t, "user", kept[0]["role"])
+
+	// The last message is a tool result: its assistant tool call is never removed
+	body = requestBody(t, messages[:3])
+	newBody, result, err = New(Config{}).Fit(context.Background(), body, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Dropped)
+	kept = decodeMessages(t, newBody)
+	require.Len(t, kept, 2)
+	assert.Equal(t, "assistant", kept[0]["role"])
+}
+
+func TestFit_Summarize(t *testing.T) {
+	body := requestBody(t, conversation())
+
+	var summaryReq struct {
+		Model     string `json:"model"`
+		MaxTokens int    `json:"max_tokens"`
+		Messages  []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	summarizer := func(ctx context.Context, reqBody []byte) ([]byte, error) {
+		require.NoError(t, json.Unmarshal(reqBody, &summaryReq))
+		return []byte(`{"choices":[{"message":{"role":"assistant","content":" The user asked about abc. "}}]}`), nil
+	}
+
+	m := New(Config{Strategy: StrategySummarize, SummaryModel: "gpt-4o-mini", SummaryMaxTokens: 256})
+	newBody, result, err := m.Fit(context.Background(), body, 250, summarizer)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Dropped)
+	assert.True(t, result.Summarized)
+
+	assert.Equal(t, "gpt-4o-mini", summaryReq.Model)
+	assert.Equal(t, 256, summaryReq.MaxTokens)
+	require.Len(t, summaryReq.Messages, 2)
+	assert.True(t, strings.HasPrefix(summaryReq.Messages[1].Content, "user: abc"))
+	assert.Contains(t, summaryReq.Messages[1].Content, "assistant: abc")
+
+	messages := decodeMessages(t, newBody)
+	require.Len(t, messages, 5)
+	assert.Equal(t, "You are helpful.", messages[0]["content"])
+	assert.Equal(t, "system", messages[1]["role"])
+	assert.Equal(t, "Summary of the earlier conversation:\nThe user asked about abc.", messages[1]["content"])
+	assert.Equal(t, "user", messages[2]["role"])
+}
+
+func TestFit_SummarizeFailureFallsBackToTruncation(t *testing.T) {
+	body := requestBody(t, conversation())
+	summarizer := func(ctx context.Context, reqBody []byte) ([]byte, error) {
+		return nil, errors.New("summary model unavailable")
+	}
+
+	m := New(Config{Strategy: StrategySummarize, SummaryModel: "gpt-4o-mini"})
+	newBody, result, err := m.Fit(context.Background(), body, 250, summarizer)
+	assert.ErrorContains(t, err, "summary model unavailable")
+	assert.Equal(t, 2, result.Dropped)
+	assert.False(t, result.Summarized)
+	assert.Len(t, decodeMessages(t, newBody), 4)
+}
+
+func TestFit_InvalidBody(t *testing.T) {
+	for _, body := range [][]byte{[]byte(`not json`), []byte(`{"input":"hi"}`)} {
+		newBody, result, err := New(Config{}).Fit(context.Background(), body, 1, nil)
+		require.NoError(t, err)
+		assert.Equal(t, body, newBody)
+		assert.Equal(t, 0, result.Dropped)
+	}
+}
+
+func TestMessageTokens(t *testing.T) {
+	assert.Equal(t, messageOverheadTokens+25, messageTokens(map[string]interface{}{"role": "user", "content": words(25)}))
+	assert.Equal(t, messageOverheadTokens+3, messageTokens(map[string]interface{}{
+		"role": "user",
+		"content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "hello"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,AAAA"}},
+			map[string]interface{}{"type": "text", "text": "world"},
+		},
+	}))
+}
@@ -0,0 +1,61 @@
+package anthropic
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+)
+
+// searchResultCitations adds the results of a web_search_tool_result block to citations.
+// Failed searches (web_search_tool_result_error) have no results.
+func searchResultCitations(citations []openai.Citation, block *ContentBlock) []openai.Citation {
+	results, ok := block.Content.([]interface{})
+	if !ok {
+		return citations
+	}
+	for _, rawResult := range results {
+		result, ok := rawResult.(map[string]interface{})
+		if !ok || result["type"] != "web_search_result" {
+			continue
+		}
+		url, _ := result["url"].(string)
+		title, _ := result["title"].(string)
+		citations = addCitation(citations, openai.Citation{URL: url, Title: title})
+	}
+	return citations
+}
+
+// textCitations adds the sources cited by a text block to citations, each with the segment of
+// the answer the block spans: bytes [start, start+len(text)) of the message content.
+func textCitations(citations []openai.Citation, sources []TextCitation, text string, start int) []openai.Citation {
+	for _, source := range sources {
+		title := source.Title
+		if title == "" {
+			title = source.DocumentTitle
+		}
+		citations = addCitation(citations, openai.Citation{
+			URL:   source.URL,
+			Title: title,
+			Text:  source.CitedText,
+			Segments: []openai.CitationSegment{
+				{Text: text, StartIndex: start, EndIndex: start + len(text)},
+			},
+		})
+	}
+	return citations
+}
+
+// addCitation appends citation, merging it into an existing citation of the same source
+// (same URL, or same title for documents without one)
+func addCitation(citations []openai.Citation, citation openai.Citation) []openai.Citation {
+	for i := range citations {
+		existing := &citations[i]
+		if existing.URL != citation.URL || (citation.URL == "" && existing.Title != citation.Title) {
+			continue
+		}
+		if existing.Text == "" {
+			existing.Text = citation.Text
+		}
+		existing.Segments = append(existing.Segments, citation.Segments...)
+		return citations
+	}
+	return append(citations, citation)
+}
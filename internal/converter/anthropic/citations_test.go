@@ -0,0 +1,131 @@
+package anthropic
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const webSearchResponse = `{
+	"id": "msg_1",
+	"type": "message",
+	"role": "assistant",
+	"content": [
+		{"type": "server_tool_use", "id": "srvtoolu_1", "name": "web_search", "input": {"query": "euro 2024 winner"}},
+		{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_1", "content": [
+			{"type": "web_search_result", "url": "https://example.com/euro", "title": "Euro 2024", "encrypted_content": "abc"},
+			{"type": "web_search_result", "url": "https://example.com/final", "title": "The final", "encrypted_content": "def"}
+		]},
+		{"type": "server_tool_use", "id": "srvtoolu_2", "name": "web_search", "input": {"query": "euro 2024 final"}},
+		{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_2", "content": {"type": "web_search_tool_result_error", "error_code": "max_uses_exceeded"}},
+		{"type": "text", "text": "Results: "},
+		{"type": "text", "text": "Spain won Euro 2024.", "citations": [
+			{"type": "web_search_result_location", "url": "https://example.com/euro", "title": "Euro 2024", "cited_text": "Spain beat England 2-1.", "encrypted_index": "x"}
+		]},
+		{"type": "text", "text": " It was their fourth title.", "citations": [
+			{"type": "web_search_result_location", "url": "https://example.com/euro", "title": "Euro 2024", "cited_text": "A record fourth title.", "encrypted_index": "y"},
+			{"type": "char_location", "document_title": "notes.pdf", "cited_text": "Fourth title", "start_char_index": 0, "end_char_index": 12}
+		]}
+	],
+	"stop_reason": "end_turn",
+	"usage": {"input_tokens": 100, "output_tokens": 20, "server_tool_use": {"web_search_requests": 2}}
+}`
+
+func TestAnthropicToOpenAI_WebSearchCitations(t *testing.T) {
+	out, err := AnthropicToOpenAI([]byte(webSearchResponse), "claude-sonnet-4-5")
+	require.NoError(t, err)
+
+	var resp openai.OpenAIResponse
+	require.NoError(t, json.Unmarshal(out, &resp))
+	require.Len(t, resp.Choices, 1)
+	message := resp.Choices[0].Message
+	assert.Equal(t, "Results: Spain won Euro 2024. It was their fourth title.", message.Content)
+	assert.Empty(t, message.ToolCalls, "server tools run on Anthropic's side")
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	assert.Equal(t, []openai.Citation{
+		{URL: "https://example.com/euro", Title: "Euro 2024", Text: "Spain beat England 2-1.", Segments: []openai.CitationSegment{
+			{Text: "Spain won Euro 2024.", StartIndex: 9, EndIndex: 29},
+			{Text: " It was their fourth title.", StartIndex: 29, EndIndex: 56},
+		}},
+		{URL: "https://example.com/final", Title: "The final"},
+		{Title: "notes.pdf", Text: "Fourth title", Segments: []openai.CitationSegment{
+			{Text: " It was their fourth title.", StartIndex: 29, EndIndex: 56},
+		}},
+	}, message.Citations)
+
+	require.NotNil(t, resp.Usage)
+	require.NotNil(t, resp.Usage.ServerToolUse)
+	assert.Equal(t, 2, resp.Usage.ServerToolUse.WebSearchRequests)
+
+	// Answers without sources have no citations or server tool usage
+	out, err = AnthropicToOpenAI([]byte(`{"id":"msg_2","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`), "claude-sonnet-4-5")
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "citations")
+	assert.NotContains(t, string(out), "server_tool_use")
+}
+
+func TestTransformAnthropicStreamToOpenAI_WebSearchCitations(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":100,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\":\"euro 2024\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","url":"https://example.com/euro","title":"Euro 2024"}]}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Results: "}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"content_block_start","index":3,"content_block":{"type":"text","text":"","citations":[]}}`,
+		`{"type":"content_block_delta","index":3,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","url":"https://example.com/euro","title":"Euro 2024","cited_text":"Spain beat England 2-1."}}}`,
+		`{"type":"content_block_delta","index":3,"delta":{"type":"text_delta","text":"Spain won "}}`,
+		`{"type":"content_block_delta","index":3,"delta":{"type":"text_delta","text":"Euro 2024."}}`,
+		`{"type":"content_block_stop","index":3}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20,"server_tool_use":{"web_search_requests":1}}}`,
+		`{"type":"message_stop"}`,
+	}
+	var stream strings.Builder
+	for _, event := range events {
+		stream.WriteString("data: " + event + "\n\n")
+	}
+
+	var output bytes.Buffer
+	require.NoError(t, TransformAnthropicStreamToOpenAI(strings.NewReader(stream.String()), "claude-sonnet-4-5", &output))
+
+	var content string
+	var toolCalls int
+	var citations [][]openai.Citation
+	var usage *openai.OpenAIUsage
+	for _, line := range strings.Split(output.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") || line == "data: [DONE]" {
+			continue
+		}
+		var chunk openai.OpenAIStreamingChunk
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk))
+		delta := chunk.Choices[0].Delta
+		content += delta.Content
+		toolCalls += len(delta.ToolCalls)
+		if len(delta.Citations) > 0 {
+			citations = append(citations, delta.Citations)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	assert.Equal(t, "Results: Spain won Euro 2024.", content)
+	assert.Zero(t, toolCalls, "server tools run on Anthropic's side")
+	assert.Equal(t, [][]openai.Citation{
+		{{URL: "https://example.com/euro", Title: "Euro 2024"}},
+		{{URL: "https://example.com/euro", Title: "Euro 2024", Text: "Spain beat England 2-1.", Segments: []openai.CitationSegment{
+			{Text: "Spain won Euro 2024.", StartIndex: 9, EndIndex: 29},
+		}}},
+	}, citations)
+	require.NotNil(t, usage)
+	require.NotNil(t, usage.ServerToolUse)
+	assert.Equal(t, 1, usage.ServerToolUse.WebSearchRequests)
+}
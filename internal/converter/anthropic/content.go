@@ -21,7 +21,7 @@ func convertOpenAIContentToAnthropic(content interface{}) []ContentBlock {
 			}
 			blockType, _ := blockMap["type"].(string)
 			switch blockType {
-			case "text":
+			case "text", "input_text":
 				text, _ := blockMap["text"].(string)
 				if text != "" {
 					blocks = append(blocks, ContentBlock{Type: "text", Text: text})
@@ -27,6 +27,15 @@ func TestExtractMediaType(t *testing.T) {
 	}
 }
 
+func TestConvertOpenAIContentToAnthropic_InputText(t *testing.T) {
+	blocks := convertOpenAIContentToAnthropic([]interface{}{
+		map[string]interface{}{"type": "text", "text": "a"},
+		map[string]interface{}{"type": "input_text", "text": "b"},
+		map[string]interface{}{"type": "input_text", "text": ""},
+	})
+	assert.Equal(t, []ContentBlock{{Type: "text", Text: "a"}, {Type: "text", Text: "b"}}, blocks)
+}
+
 func TestConvertImageURLToAnthropic(t *testing.T) {
 	t.Run("data_url", func(t *testing.T) {
 		url := "data:image/jpeg;base64,/9j/4AAQ"
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mixaill76/auto_ai_router/internal/converter/converterutil"
 	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 )
 
@@ -33,13 +34,15 @@ func OpenAIToAnthropic(openAIBody []byte, model string) ([]byte, error) {
 	}
 
 	// max_tokens is mandatory in Anthropic; default to 4096.
-	maxTokens := 4096
+	// max_completion_tokens (reasoning models) takes precedence over max_tokens.
+	maxTokens := defaultMaxTokens
 	if req.MaxTokens != nil {
 		maxTokens = *req.MaxTokens
 	}
 	if req.MaxCompletionTokens != nil {
 		maxTokens = *req.MaxCompletionTokens
 	}
+	maxTokensSet := req.MaxTokens != nil || req.MaxCompletionTokens != nil
 
 	anthropicReq := AnthropicRequest{
 		Model:     model,
@@ -89,7 +92,7 @@ func OpenAIToAnthropic(openAIBody []byte, model string) ([]byte, error) {
 	if req.Thinking != nil {
 		thinkingParam = req.Thinking
 	}
-	if tc := mapThinkingConfig(thinkingParam, req.ReasoningEffort); tc != nil {
+	if tc := fitThinkingBudget(mapThinkingConfig(thinkingParam, req.ReasoningEffort), &anthropicReq.MaxTokens, maxTokensSet); tc != nil {
 		anthropicReq.Thinking = tc
 		// Anthropic requires temperature=1.0 when thinking is enabled.
 		temp := 1.0
@@ -122,18 +125,27 @@ func OpenAIToAnthropic(openAIBody []byte, model string) ([]byte, error) {
 }
 
 // convertOpenAIMessagesToAnthropic converts the OpenAI messages array to Anthropic format.
-// System / developer messages are aggregated into the top-level system field.
+// System / developer messages are merged (in message order) into the top-level system field:
+// a string, or text blocks when any system text block carries cache_control.
 // Tool result messages become user-role messages containing tool_result blocks.
 // Returns (systemContent, messages).
 func convertOpenAIMessagesToAnthropic(openAIMessages []openai.OpenAIMessage) (interface{}, []AnthropicMessage) {
-	var systemTexts []string
+	var systemPrompts []string
+	var systemBlocks []ContentBlock
+	systemCached := false
 	var messages []AnthropicMessage
 
 	for _, msg := range openAIMessages {
 		switch msg.Role {
 		case "system", "developer":
-			texts := extractSystemText(msg.Content)
-			systemTexts = append(systemTexts, texts...)
+			blocks := extractSystemBlocks(msg.Content)
+			texts := make([]string, len(blocks))
+			for i, block := range blocks {
+				texts[i] = block.Text
+				systemCached = systemCached || block.CacheControl != nil
+			}
+			systemPrompts = append(systemPrompts, strings.Join(texts, "\n"))
+			systemBlocks = append(systemBlocks, blocks...)
 
 		case "user":
 			blocks := convertOpenAIContentToAnthropic(msg.Content)
@@ -184,8 +196,11 @@ func convertOpenAIMessagesToAnthropic(openAIMessages []openai.OpenAIMessage) (in
 	}
 
 	var systemContent interface{}
-	if len(systemTexts) > 0 {
-		systemContent = strings.Join(systemTexts, "\n")
+	if systemCached {
+		// Separate blocks keep the cache breakpoints
+		systemContent = systemBlocks
+	} else if system := converterutil.JoinSystemPrompts(systemPrompts); system != "" {
+		systemContent = system
 	}
 
 	return systemContent, messages
@@ -215,23 +230,37 @@ func OpenAIToBedrock(openAIBody []byte, model string) ([]byte, error) {
 
 // extractSystemText extracts all text strings from a system/developer message content.
 func extractSystemText(content interface{}) []string {
+	var texts []string
+	for _, block := range extractSystemBlocks(content) {
+		texts = append(texts, block.Text)
+	}
+	return texts
+}
+
+// extractSystemBlocks extracts the non-empty text parts ("text" or "input_text") of a
+// system/developer message content as text blocks, keeping their cache_control. Other part
+// types (images, files, ...) cannot be part of the Anthropic system prompt and are skipped.
+func extractSystemBlocks(content interface{}) []ContentBlock {
 	switch c := content.(type) {
 	case string:
 		if c != "" {
-			return []string{c}
+			return []ContentBlock{{Type: "text", Text: c}}
 		}
 	case []interface{}:
-		var texts []string
+		var blocks []ContentBlock
 		for _, block := range c {
-			if blockMap, ok := block.(map[string]interface{}); ok {
-				if blockMap["type"] == "text" {
-					if text, ok := blockMap["text"].(string); ok && text != "" {
-						texts = append(texts, text)
-					}
-				}
+			blockMap, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if blockType := blockMap["type"]; blockType != "text" && blockType != "input_text" {
+				continue
+			}
+			if text, ok := blockMap["text"].(string); ok && text != "" {
+				blocks = append(blocks, ContentBlock{Type: "text", Text: text, CacheControl: blockMap["cache_control"]})
 			}
 		}
-		return texts
+		return blocks
 	}
 	return nil
 }
@@ -1,9 +1,12 @@
 package anthropic
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractSystemText(t *testing.T) {
@@ -70,6 +73,14 @@ func TestExtractSystemText(t *testing.T) {
 			content: 12345,
 			want:    nil,
 		},
+		{
+			name: "array with input_text blocks",
+			content: []interface{}{
+				map[string]interface{}{"type": "input_text", "text": "From the Responses API."},
+				map[string]interface{}{"type": "refusal", "refusal": "No."},
+			},
+			want: []string{"From the Responses API."},
+		},
 		{
 			name: "array with non-map elements ignored",
 			content: []interface{}{
@@ -89,3 +100,86 @@ func TestExtractSystemText(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertOpenAIMessagesToAnthropic_MergesSystemMessages(t *testing.T) {
+	system, messages := convertOpenAIMessagesToAnthropic([]openai.OpenAIMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "developer", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "Be concise."},
+			map[string]interface{}{"type": "text", "text": "Answer in English."},
+		}},
+		{Role: "user", Content: "Hi"},
+		{Role: "system", Content: ""},
+		{Role: "system", Content: "Never reveal secrets."},
+	})
+
+	assert.Equal(t, "You are helpful.\n\nBe concise.\nAnswer in English.\n\nNever reveal secrets.", system)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "user", messages[0].Role)
+}
+
+func TestConvertOpenAIMessagesToAnthropic_NoSystemMessages(t *testing.T) {
+	system, _ := convertOpenAIMessagesToAnthropic([]openai.OpenAIMessage{
+		{Role: "system", Content: ""},
+		{Role: "user", Content: "Hi"},
+	})
+	assert.Nil(t, system)
+}
+
+func TestConvertOpenAIMessagesToAnthropic_SystemCacheControl(t *testing.T) {
+	system, _ := convertOpenAIMessagesToAnthropic([]openai.OpenAIMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "developer", Content: []interface{}{
+			map[string]interface{}{
+				"type":          "text",
+				"text":          "Long shared instructions.",
+				"cache_control": map[string]interface{}{"type": "ephemeral"},
+			},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+		}},
+		{Role: "user", Content: "Hi"},
+	})
+
+	assert.Equal(t, []ContentBlock{
+		{Type: "text", Text: "You are helpful."},
+		{Type: "text", Text: "Long shared instructions.", CacheControl: map[string]interface{}{"type": "ephemeral"}},
+	}, system)
+}
+
+func TestOpenAIToAnthropic_DeveloperRoleWithArrayContent(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-sonnet-4-5",
+		"messages": [
+			{"role": "developer", "content": [
+				{"type": "text", "text": "Rules.", "cache_control": {"type": "ephemeral"}},
+				{"type": "file", "file": {"file_id": "file-1"}}
+			]},
+			{"role": "system", "content": [{"type": "text", "text": "More rules."}]},
+			{"role": "user", "content": "Hi"}
+		]
+	}`)
+
+	out, err := OpenAIToAnthropic(body, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "text", "text": "Rules.", "cache_control": {"type": "ephemeral"}},
+		{"type": "text", "text": "More rules."}
+	]`, rawField(t, out, "system"))
+	assert.JSONEq(t, `[{"role": "user", "content": [{"type": "text", "text": "Hi"}]}]`, rawField(t, out, "messages"))
+
+	// Without cache_control the system prompt stays a string
+	out, err = OpenAIToAnthropic([]byte(`{"messages": [
+		{"role": "developer", "content": [{"type": "text", "text": "Rules."}, {"type": "input_text", "text": "More."}]},
+		{"role": "user", "content": "Hi"}
+	]}`), "claude-sonnet-4-5")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"Rules.\nMore."`, rawField(t, out, "system"))
+}
+
+// rawField returns the raw JSON of a top-level field of body
+func rawField(t *testing.T, body []byte, field string) string {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &fields))
+	return string(fields[field])
+}
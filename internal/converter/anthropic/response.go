@@ -28,11 +28,18 @@ func AnthropicToOpenAI(anthropicBody []byte, model string) ([]byte, error) {
 	var textContent string
 	var reasoningContent string
 	var toolCalls []openai.OpenAIToolCall
+	var citations []openai.Citation
 
-	for _, block := range anthropicResp.Content {
+	for i := range anthropicResp.Content {
+		block := &anthropicResp.Content[i]
 		switch block.Type {
 		case "text":
+			citations = textCitations(citations, block.Citations, block.Text, len(textContent))
 			textContent += block.Text
+		case "web_search_tool_result":
+			// Results of a server-side web search; server_tool_use blocks are executed by
+			// Anthropic and not returned as tool calls
+			citations = searchResultCitations(citations, block)
 		case "thinking":
 			reasoningContent += block.Thinking
 		case "tool_use":
@@ -65,11 +72,13 @@ func AnthropicToOpenAI(anthropicBody []byte, model string) ([]byte, error) {
 	if len(toolCalls) > 0 {
 		message.ToolCalls = toolCalls
 	}
+	message.Citations = citations
 
 	choice := openai.OpenAIChoice{
-		Index:        0,
-		Message:      message,
-		FinishReason: finishReason,
+		Index:                0,
+		Message:              message,
+		FinishReason:         finishReason,
+		ContentFilterResults: stopReasonContentFilterResults(anthropicResp.StopReason),
 	}
 	openAIResp.Choices = append(openAIResp.Choices, choice)
 
@@ -90,11 +99,22 @@ func mapAnthropicStopReason(reason string) string {
 		return "tool_calls"
 	case "stop_sequence":
 		return "stop"
+	case "refusal":
+		return "content_filter"
 	default:
 		return "stop"
 	}
 }
 
+// stopReasonContentFilterResults returns content_filter_results for responses the
+// model's safety classifiers stopped (stop_reason "refusal"), nil otherwise.
+func stopReasonContentFilterResults(reason string) openai.ContentFilterResults {
+	if reason != "refusal" {
+		return nil
+	}
+	return openai.ContentFilterResults{"refusal": {Filtered: true}}
+}
+
 // convertAnthropicUsageToOpenAI converts Anthropic usage to the OpenAI usage struct.
 func convertAnthropicUsageToOpenAI(usage *AnthropicUsage) *openai.OpenAIUsage {
 	if usage == nil {
@@ -110,5 +130,14 @@ func convertAnthropicUsageToOpenAI(usage *AnthropicUsage) *openai.OpenAIUsage {
 			CachedTokens: usage.CacheReadInputTokens,
 		}
 	}
+	result.ServerToolUse = convertServerToolUse(usage.ServerToolUse)
 	return result
 }
+
+// convertServerToolUse returns the web searches of a response for per-request pricing, nil if none
+func convertServerToolUse(details *ServerToolUsageDetails) *openai.ServerToolUse {
+	if details == nil || details.WebSearchRequests == 0 {
+		return nil
+	}
+	return &openai.ServerToolUse{WebSearchRequests: details.WebSearchRequests}
+}
@@ -17,6 +17,11 @@ type blockState struct {
 	id          string // tool_use id
 	name        string // tool_use name
 	toolCallIdx int
+
+	// text block sources, sent with the block's segment once the block is complete
+	text      strings.Builder
+	textStart int
+	citations []TextCitation
 }
 
 // TransformAnthropicStreamToOpenAI reads an Anthropic SSE stream from anthropicStream and
@@ -25,8 +30,9 @@ type blockState struct {
 // Supported Anthropic event types:
 //
 //	message_start        — captures the message ID and input token usage
-//	content_block_start  — opens a new content block (text / thinking / tool_use)
-//	content_block_delta  — streams incremental text, thinking, or tool JSON
+//	content_block_start  — opens a new content block (text / thinking / tool_use /
+//	                       web_search_tool_result, whose results are sent as citations)
+//	content_block_delta  — streams incremental text, thinking, tool JSON, or text citations
 //	content_block_stop   — closes the current content block
 //	message_delta        — carries stop_reason and output token usage
 //	message_stop         — signals end of stream ([DONE] is written after the loop)
@@ -40,8 +46,9 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 	isFirstChunk := true
 
 	// Per-block state; Anthropic streams one block at a time.
-	var current blockState
+	current := &blockState{}
 	toolCallIdx := 0
+	contentLen := 0 // bytes of text content sent, for citation segment offsets
 
 	// Usage accumulated across message_start / message_delta events.
 	var promptTokens, completionTokens int
@@ -86,11 +93,21 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 			if event.ContentBlock == nil {
 				continue
 			}
-			current = blockState{
+			current = &blockState{
 				blockType:   event.ContentBlock.Type,
 				id:          event.ContentBlock.ID,
 				name:        event.ContentBlock.Name,
 				toolCallIdx: toolCallIdx,
+				textStart:   contentLen,
+				citations:   event.ContentBlock.Citations,
+			}
+			if current.blockType == "web_search_tool_result" {
+				if citations := searchResultCitations(nil, event.ContentBlock); len(citations) > 0 {
+					chunk := buildStreamChunk(chatID, model, timestamp, openai.OpenAIStreamingDelta{Citations: citations}, nil, nil)
+					if err := writeChunk(output, chunk); err != nil {
+						return err
+					}
+				}
 			}
 			// For tool_use blocks: emit the opening chunk with id + name immediately so
 			// that OpenAI clients receive id/name before any argument deltas.
@@ -120,6 +137,8 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 			switch event.Delta.Type {
 			case "text_delta":
 				if event.Delta.Text != "" {
+					current.text.WriteString(event.Delta.Text)
+					contentLen += len(event.Delta.Text)
 					delta := openai.OpenAIStreamingDelta{Content: event.Delta.Text}
 					chunk := buildStreamChunk(chatID, model, timestamp, delta, nil, nil)
 					if err := writeChunk(output, chunk); err != nil {
@@ -127,6 +146,11 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 					}
 				}
 
+			case "citations_delta":
+				if event.Delta.Citation != nil {
+					current.citations = append(current.citations, *event.Delta.Citation)
+				}
+
 			case "thinking_delta":
 				if event.Delta.Thinking != "" {
 					delta := openai.OpenAIStreamingDelta{ReasoningContent: event.Delta.Thinking}
@@ -137,8 +161,8 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 				}
 
 			case "input_json_delta":
-				// Stream partial tool arguments to the client.
-				if event.Delta.PartialJSON != "" {
+				// Stream partial tool arguments to the client (server_tool_use input stays upstream).
+				if event.Delta.PartialJSON != "" && current.blockType == "tool_use" {
 					tc := openai.OpenAIStreamingToolCall{
 						Index: current.toolCallIdx,
 						Function: &openai.OpenAIStreamingToolFunction{
@@ -156,12 +180,20 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 			}
 
 		case "content_block_stop":
+			// Send the sources of a finished text block with the segment it spans.
+			if len(current.citations) > 0 {
+				citations := textCitations(nil, current.citations, current.text.String(), current.textStart)
+				chunk := buildStreamChunk(chatID, model, timestamp, openai.OpenAIStreamingDelta{Citations: citations}, nil, nil)
+				if err := writeChunk(output, chunk); err != nil {
+					return err
+				}
+			}
 			// Increment tool_call index when a tool_use block finishes.
 			if current.blockType == "tool_use" {
 				toolCallIdx++
 			}
 			// Reset current block state.
-			current = blockState{toolCallIdx: toolCallIdx}
+			current = &blockState{toolCallIdx: toolCallIdx}
 
 		case "message_delta":
 			// Carries the stop_reason and final output token count.
@@ -170,15 +202,19 @@ func TransformAnthropicStreamToOpenAI(anthropicStream io.Reader, model string, o
 			}
 			if event.Delta.StopReason != "" {
 				reason := mapAnthropicStopReason(event.Delta.StopReason)
+				var serverToolUse *openai.ServerToolUse
 				if event.Usage != nil {
 					completionTokens = event.Usage.OutputTokens
+					serverToolUse = convertServerToolUse(event.Usage.ServerToolUse)
 				}
 				usage := &openai.OpenAIUsage{
 					PromptTokens:     promptTokens,
 					CompletionTokens: completionTokens,
 					TotalTokens:      promptTokens + completionTokens,
+					ServerToolUse:    serverToolUse,
 				}
 				chunk := buildStreamChunk(chatID, model, timestamp, openai.OpenAIStreamingDelta{}, &reason, usage)
+				chunk.Choices[0].ContentFilterResults = stopReasonContentFilterResults(event.Delta.StopReason)
 				if err := writeChunk(output, chunk); err != nil {
 					return err
 				}
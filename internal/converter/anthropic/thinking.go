@@ -1,5 +1,12 @@
 package anthropic
 
+// defaultMaxTokens is the max_tokens of requests that set neither max_tokens nor
+// max_completion_tokens (mandatory in Anthropic)
+const defaultMaxTokens = 4096
+
+// minThinkingBudget is the smallest budget_tokens Anthropic accepts
+const minThinkingBudget = 1024
+
 // mapThinkingConfig maps OpenAI thinking / reasoning_effort parameters to an Anthropic
 // ThinkingConfig.  Returns nil when thinking should not be included in the request.
 //
@@ -52,9 +59,30 @@ func mapReasoningEffortToBudget(effort string) int {
 		return 15000
 	case "high":
 		return 30000
+	case "xhigh":
+		return 60000
 	case "disable", "none":
 		return 0
 	default:
 		return 0
 	}
 }
+
+// fitThinkingBudget keeps the thinking budget below max_tokens, which covers thinking and
+// output alike. Without a client max_tokens the default output allowance is added to the
+// budget; a client limit caps the budget instead, and thinking is dropped when less than
+// minThinkingBudget tokens would remain for it.
+func fitThinkingBudget(tc *AnthropicThinking, maxTokens *int, maxTokensSet bool) *AnthropicThinking {
+	if tc == nil || tc.BudgetTokens < *maxTokens {
+		return tc
+	}
+	if !maxTokensSet {
+		*maxTokens = tc.BudgetTokens + defaultMaxTokens
+		return tc
+	}
+	if *maxTokens-1 < minThinkingBudget {
+		return nil
+	}
+	tc.BudgetTokens = *maxTokens - 1
+	return tc
+}
@@ -15,6 +15,7 @@ func TestMapReasoningEffortToBudget(t *testing.T) {
 		{"low", 5000},
 		{"medium", 15000},
 		{"high", 30000},
+		{"xhigh", 60000},
 		{"disable", 0},
 		{"none", 0},
 		{"unknown", 0},
@@ -75,3 +76,24 @@ func TestMapThinkingConfig(t *testing.T) {
 		assert.Nil(t, result)
 	})
 }
+
+func TestFitThinkingBudget(t *testing.T) {
+	maxTokens := defaultMaxTokens
+	tc := fitThinkingBudget(&AnthropicThinking{Type: "enabled", BudgetTokens: 30000}, &maxTokens, false)
+	assert.Equal(t, 30000, tc.BudgetTokens)
+	assert.Equal(t, 30000+defaultMaxTokens, maxTokens, "the default output allowance is added to the budget")
+
+	maxTokens = 8000
+	tc = fitThinkingBudget(&AnthropicThinking{Type: "enabled", BudgetTokens: 15000}, &maxTokens, true)
+	assert.Equal(t, 7999, tc.BudgetTokens, "max_completion_tokens caps thinking and output together")
+	assert.Equal(t, 8000, maxTokens)
+
+	maxTokens = 1000
+	assert.Nil(t, fitThinkingBudget(&AnthropicThinking{Type: "enabled", BudgetTokens: 5000}, &maxTokens, true),
+		"no room for the smallest budget")
+
+	maxTokens = 20000
+	tc = fitThinkingBudget(&AnthropicThinking{Type: "enabled", BudgetTokens: 5000}, &maxTokens, true)
+	assert.Equal(t, 5000, tc.BudgetTokens)
+	assert.Nil(t, fitThinkingBudget(nil, &maxTokens, true))
+}
@@ -29,7 +29,8 @@ type ContentBlock struct {
 	Type string `json:"type"`
 
 	// text block
-	Text string `json:"text,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Citations []TextCitation `json:"citations,omitempty"` // sources of the text (responses)
 
 	// image / document block
 	Source *MediaSource `json:"source,omitempty"`
@@ -46,6 +47,19 @@ type ContentBlock struct {
 	// thinking block (in responses)
 	Thinking  string `json:"thinking,omitempty"`
 	Signature string `json:"signature,omitempty"`
+
+	// Prompt caching breakpoint (system text blocks), e.g. {"type": "ephemeral"}
+	CacheControl interface{} `json:"cache_control,omitempty"`
+}
+
+// TextCitation is a source cited by a response text block, e.g. a web_search_result_location
+// of a web search result or a char_location of a document.
+type TextCitation struct {
+	Type          string `json:"type"`
+	URL           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"`
+	DocumentTitle string `json:"document_title,omitempty"`
+	CitedText     string `json:"cited_text,omitempty"`
 }
 
 // MediaSource describes the source of an image or document content block.
@@ -165,6 +179,9 @@ type AnthropicStreamDelta struct {
 	// input_json_delta (tool input streaming)
 	PartialJSON string `json:"partial_json,omitempty"`
 
+	// citations_delta (a source of the current text block)
+	Citation *TextCitation `json:"citation,omitempty"`
+
 	// message_delta
 	StopReason   string `json:"stop_reason,omitempty"`
 	StopSequence string `json:"stop_sequence,omitempty"`
@@ -176,4 +193,6 @@ type AnthropicStreamUsage struct {
 	OutputTokens             int `json:"output_tokens,omitempty"`
 	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+
+	ServerToolUse *ServerToolUsageDetails `json:"server_tool_use,omitempty"`
 }
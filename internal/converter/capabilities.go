@@ -0,0 +1,93 @@
+package converter
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+)
+
+// providerUnsupportedParams lists OpenAI request parameters a provider type cannot honour.
+// Credentials can extend the list with unsupported_params (e.g. Gemini models without logprobs).
+var providerUnsupportedParams = map[config.ProviderType][]string{
+	config.ProviderTypeAnthropic: {"logprobs", "top_logprobs"},
+	config.ProviderTypeBedrock:   {"logprobs", "top_logprobs"},
+}
+
+// RequestedParams returns the top-level parameters set in an OpenAI request body.
+// Parameters set to null, false, 0 or "" are not considered requested.
+func RequestedParams(body []byte) map[string]bool {
+	var data map[string]any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil
+	}
+
+	requested := make(map[string]bool, len(data))
+	for key, val := range data {
+		switch v := val.(type) {
+		case nil:
+			continue
+		case bool:
+			if !v {
+				continue
+			}
+		case float64:
+			if v == 0 {
+				continue
+			}
+		case string:
+			if v == "" {
+				continue
+			}
+		}
+		requested[key] = true
+	}
+	return requested
+}
+
+// UnsupportedParams returns the requested parameters that cred cannot honour, in a stable order.
+// Proxy credentials forward requests unchanged and never report unsupported parameters.
+func UnsupportedParams(cred *config.CredentialConfig, requested map[string]bool) []string {
+	if len(requested) == 0 {
+		return nil
+	}
+
+	var unsupported []string
+	for _, param := range CredentialUnsupportedParams(cred) {
+		if requested[param] {
+			unsupported = append(unsupported, param)
+		}
+	}
+	// stream_options is only valid together with stream
+	if slices.Contains(unsupported, "stream") && requested["stream_options"] && !slices.Contains(unsupported, "stream_options") {
+		unsupported = append(unsupported, "stream_options")
+	}
+	return unsupported
+}
+
+// CredentialUnsupportedParams returns every parameter cred cannot honour (provider type
+// defaults, then the credential's unsupported_params), without duplicates.
+func CredentialUnsupportedParams(cred *config.CredentialConfig) []string {
+	if cred.Type == config.ProviderTypeProxy {
+		return nil
+	}
+
+	var unsupported []string
+	for _, params := range [][]string{providerUnsupportedParams[cred.Type], cred.UnsupportedParams} {
+		for _, param := range params {
+			if !slices.Contains(unsupported, param) {
+				unsupported = append(unsupported, param)
+			}
+		}
+	}
+	return unsupported
+}
+
+// DropParams removes the given top-level parameters from an OpenAI request body.
+func DropParams(body []byte, params []string) []byte {
+	if len(params) == 0 {
+		return body
+	}
+	return openai.UpdateJSONField(body, openai.ModelParamsMapping{KeysToRemove: params})
+}
@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+func TestRequestedParams(t *testing.T) {
+	requested := RequestedParams([]byte(`{"model":"m","logprobs":true,"top_logprobs":0,"seed":null,"stream":false,"user":""}`))
+	want := map[string]bool{"model": true, "logprobs": true}
+	if !reflect.DeepEqual(requested, want) {
+		t.Fatalf("RequestedParams = %v, want %v", requested, want)
+	}
+
+	if got := RequestedParams([]byte(`not json`)); got != nil {
+		t.Fatalf("expected nil for invalid JSON, got %v", got)
+	}
+}
+
+func TestUnsupportedParams(t *testing.T) {
+	requested := map[string]bool{"model": true, "logprobs": true, "top_logprobs": true}
+
+	tests := []struct {
+		name string
+		cred config.CredentialConfig
+		want []string
+	}{
+		{"anthropic defaults", config.CredentialConfig{Type: config.ProviderTypeAnthropic}, []string{"logprobs", "top_logprobs"}},
+		{"bedrock defaults", config.CredentialConfig{Type: config.ProviderTypeBedrock}, []string{"logprobs", "top_logprobs"}},
+		{"vertex supports logprobs", config.CredentialConfig{Type: config.ProviderTypeVertexAI}, nil},
+		{"credential override", config.CredentialConfig{Type: config.ProviderTypeGemini, UnsupportedParams: []string{"logprobs", "seed"}}, []string{"logprobs"}},
+		{"no duplicates", config.CredentialConfig{Type: config.ProviderTypeAnthropic, UnsupportedParams: []string{"logprobs"}}, []string{"logprobs", "top_logprobs"}},
+		{"proxy forwards everything", config.CredentialConfig{Type: config.ProviderTypeProxy, UnsupportedParams: []string{"logprobs"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnsupportedParams(&tt.cred, requested); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("UnsupportedParams = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	streamReq := map[string]bool{"model": true, "stream": true, "stream_options": true}
+	cred := config.CredentialConfig{Type: config.ProviderTypeOpenAI, UnsupportedParams: []string{"stream"}}
+	if got, want := UnsupportedParams(&cred, streamReq), []string{"stream", "stream_options"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("UnsupportedParams = %v, want %v (stream_options follows stream)", got, want)
+	}
+}
+
+func TestCredentialUnsupportedParams(t *testing.T) {
+	cred := config.CredentialConfig{Type: config.ProviderTypeAnthropic, UnsupportedParams: []string{"seed", "logprobs"}}
+	if got, want := CredentialUnsupportedParams(&cred), []string{"logprobs", "top_logprobs", "seed"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CredentialUnsupportedParams = %v, want %v", got, want)
+	}
+	if got := CredentialUnsupportedParams(&config.CredentialConfig{Type: config.ProviderTypeProxy, UnsupportedParams: []string{"seed"}}); got != nil {
+		t.Fatalf("proxy credentials should report nothing, got %v", got)
+	}
+}
+
+func TestDropParams(t *testing.T) {
+	body := []byte(`{"model":"claude-sonnet-4-5","logprobs":true,"top_logprobs":3,"messages":[]}`)
+	got := DropParams(body, []string{"logprobs", "top_logprobs"})
+
+	var data map[string]any
+	if err := json.Unmarshal(got, &data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := data["logprobs"]; ok {
+		t.Fatalf("logprobs not dropped: %s", got)
+	}
+	if _, ok := data["top_logprobs"]; ok {
+		t.Fatalf("top_logprobs not dropped: %s", got)
+	}
+	if data["model"] != "claude-sonnet-4-5" {
+		t.Fatalf("model lost: %s", got)
+	}
+
+	if got := DropParams(body, nil); string(got) != string(body) {
+		t.Fatalf("expected unchanged body, got %s", got)
+	}
+}
@@ -0,0 +1,144 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/cassette"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Regenerate golden files after an intended output change:
+//
+//	go test ./internal/converter -run TestCassetteRegression -update
+var updateGolden = flag.Bool("update", false, "rewrite cassette golden files")
+
+// volatileFields are generated per conversion and replaced before comparing with golden files
+var volatileFields = map[string]bool{"id": true, "created": true}
+
+// TestCassetteRegression converts recorded provider responses (testdata/cassettes, see
+// the cassette config section) to OpenAI format and compares them with golden files.
+func TestCassetteRegression(t *testing.T) {
+	paths, err := filepath.Glob("testdata/cassettes/*.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			interaction, err := cassette.LoadFile(path)
+			require.NoError(t, err)
+
+			providerType := config.ProviderType(interaction.Provider)
+			contentType := interaction.Response.Headers["Content-Type"]
+			mode := RequestMode{
+				IsStreaming: strings.HasPrefix(contentType, "text/event-stream"),
+				ModelID:     cassetteModel(interaction),
+			}
+			conv := New(providerType, mode)
+
+			var got []byte
+			if mode.IsStreaming {
+				var out bytes.Buffer
+				require.NoError(t, conv.StreamTo(bytes.NewReader(interaction.Response.Bytes()), &out))
+				got = normalizeStream(t, out.Bytes())
+			} else {
+				converted, err := conv.ResponseTo(interaction.Response.Bytes())
+				require.NoError(t, err)
+				got = normalizeJSON(t, converted)
+			}
+
+			goldenPath := strings.TrimSuffix(path, ".json") + ".golden"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, got, 0o644))
+			}
+			want, err := os.ReadFile(goldenPath)
+			require.NoError(t, err, "run with -update to create the golden file")
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+// cassetteModel returns the model of a recorded request: the body "model" field,
+// or the Vertex AI / Gemini URL segment (.../models/<model>:generateContent)
+func cassetteModel(interaction *cassette.Interaction) string {
+	var body struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(interaction.Request.Bytes(), &body); err == nil && body.Model != "" {
+		return body.Model
+	}
+	u, err := url.Parse(interaction.Request.URL)
+	if err != nil {
+		return ""
+	}
+	_, model, _ := strings.Cut(u.Path, "/models/")
+	model, _, _ = strings.Cut(model, ":")
+	return model
+}
+
+// normalizeJSON indents a JSON document with volatile fields replaced
+func normalizeJSON(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var v any
+	require.NoError(t, json.Unmarshal(data, &v), string(data))
+	return encodeJSON(t, replaceVolatile(v), "  ")
+}
+
+// normalizeStream renders each SSE data event on one line with volatile fields replaced
+func normalizeStream(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if payload != "[DONE]" {
+			var v any
+			require.NoError(t, json.Unmarshal([]byte(payload), &v), payload)
+			payload = string(encodeJSON(t, replaceVolatile(v), ""))
+		}
+		out.WriteString("data: " + strings.TrimSuffix(payload, "\n") + "\n")
+	}
+	require.NoError(t, scanner.Err())
+	return out.Bytes()
+}
+
+// encodeJSON encodes v without HTML escaping, so placeholders stay readable in golden files
+func encodeJSON(t *testing.T, v any, indent string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	require.NoError(t, encoder.Encode(v))
+	return out.Bytes()
+}
+
+func replaceVolatile(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, field := range val {
+			if volatileFields[key] {
+				val[key] = "<" + key + ">"
+				continue
+			}
+			val[key] = replaceVolatile(field)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = replaceVolatile(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/anthropic"
+)
+
+// singleChoiceProviders return one choice per request and have no n parameter: chat requests
+// with n > 1 are rejected, or fanned out to n requests (RequestMode.ChoiceFanOut)
+var singleChoiceProviders = map[config.ProviderType]bool{
+	config.ProviderTypeAnthropic: true,
+	config.ProviderTypeBedrock:   true,
+}
+
+// ReturnsSingleChoice reports whether providerType returns one choice per chat request
+func ReturnsSingleChoice(providerType config.ProviderType) bool {
+	return singleChoiceProviders[providerType]
+}
+
+// RequestedChoices returns the number of choices (n) an OpenAI chat request asks for (1 if
+// unset or invalid)
+func RequestedChoices(body []byte) int {
+	var req struct {
+		N *int `json:"n"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.N == nil || *req.N < 1 {
+		return 1
+	}
+	return *req.N
+}
+
+// checkChoices rejects n > 1 for providers returning one choice per request, unless it is
+// fanned out
+func (c *ProviderConverter) checkChoices(raw interface{}) error {
+	n, ok := raw.(float64)
+	if !ok || n <= 1 || !singleChoiceProviders[c.providerType] {
+		return nil
+	}
+	if c.mode.ChoiceFanOut == 0 {
+		return requestError(ErrCodeUnsupportedParam, "n", "%s returns one choice per request, n must be 1", c.providerType)
+	}
+	if n > float64(c.mode.ChoiceFanOut) {
+		return requestError(ErrCodeUnsupportedParam, "n", "must be at most %d for %s", c.mode.ChoiceFanOut, c.providerType)
+	}
+	return nil
+}
+
+// isFannedOut reports whether body is the JSON array of the provider responses of a fanned
+// out request
+func isFannedOut(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// fanOutResponseTo converts the provider responses of a fanned out request and merges them
+// into one OpenAI response with a choice per request
+func (c *ProviderConverter) fanOutResponseTo(body []byte) ([]byte, error) {
+	var bodies []json.RawMessage
+	if err := json.Unmarshal(body, &bodies); err != nil {
+		return nil, fmt.Errorf("failed to parse fanned out responses: %w", err)
+	}
+	converted := make([][]byte, 0, len(bodies))
+	for i, providerBody := range bodies {
+		openAIBody, err := c.postProcess(anthropic.AnthropicToOpenAI(providerBody, c.mode.ModelID))
+		if err != nil {
+			return nil, fmt.Errorf("fanned out response %d: %w", i, err)
+		}
+		converted = append(converted, openAIBody)
+	}
+	return MergeChoices(converted)
+}
+
+// MergeChoices merges OpenAI chat responses into the first one: the choices of all responses
+// in order, indexed from 0, and their usage summed (every request was billed for its prompt)
+func MergeChoices(bodies [][]byte) ([]byte, error) {
+	if len(bodies) == 0 {
+		return nil, fmt.Errorf("no responses to merge")
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal(bodies[0], &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse response 0: %w", err)
+	}
+	var choices []interface{}
+	var usage map[string]interface{}
+	for i, body := range bodies {
+		var resp struct {
+			Choices []map[string]interface{} `json:"choices"`
+			Usage   map[string]interface{}   `json:"usage"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response %d: %w", i, err)
+		}
+		for _, choice := range resp.Choices {
+			choice["index"] = len(choices)
+			choices = append(choices, choice)
+		}
+		if resp.Usage != nil {
+			if usage == nil {
+				usage = make(map[string]interface{}, len(resp.Usage))
+			}
+			sumUsage(usage, resp.Usage)
+		}
+	}
+
+	merged["choices"] = choices
+	if usage != nil {
+		merged["usage"] = usage
+	}
+	return json.Marshal(merged)
+}
+
+// sumUsage adds the token counts of usage to total, recursing into detail objects
+func sumUsage(total, usage map[string]interface{}) {
+	for key, value := range usage {
+		switch v := value.(type) {
+		case float64:
+			sum, _ := total[key].(float64)
+			total[key] = sum + v
+		case map[string]interface{}:
+			details, ok := total[key].(map[string]interface{})
+			if !ok {
+				details = make(map[string]interface{}, len(v))
+				total[key] = details
+			}
+			sumUsage(details, v)
+		default:
+			if _, exists := total[key]; !exists {
+				total[key] = value
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestedChoices(t *testing.T) {
+	assert.Equal(t, 3, RequestedChoices([]byte(`{"model":"m","n":3}`)))
+	assert.Equal(t, 1, RequestedChoices([]byte(`{"model":"m"}`)))
+	assert.Equal(t, 1, RequestedChoices([]byte(`{"model":"m","n":0}`)))
+	assert.Equal(t, 1, RequestedChoices([]byte(`not json`)))
+}
+
+func TestRequestFrom_Choices(t *testing.T) {
+	body := []byte(`{"model":"claude","messages":[{"role":"user","content":"hi"}],"n":3}`)
+
+	_, err := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude"}).RequestFrom(context.Background(), body)
+	reqErr := AsRequestError(err)
+	require.NotNil(t, reqErr, "n > 1 is rejected without fan-out")
+	assert.Equal(t, ErrCodeUnsupportedParam, reqErr.Code)
+	assert.Equal(t, "n", reqErr.Param)
+
+	_, err = New(config.ProviderTypeBedrock, RequestMode{ModelID: "claude", ChoiceFanOut: 2}).RequestFrom(context.Background(), body)
+	reqErr = AsRequestError(err)
+	require.NotNil(t, reqErr)
+	assert.Equal(t, "n: must be at most 2 for bedrock", reqErr.Error())
+
+	converted, err := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude", ChoiceFanOut: 4}).RequestFrom(context.Background(), body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(converted), `"n"`)
+
+	_, err = New(config.ProviderTypeVertexAI, RequestMode{ModelID: "gemini-2.5-flash"}).RequestFrom(context.Background(), body)
+	assert.NoError(t, err, "vertex has candidateCount")
+}
+
+func TestResponseTo_FannedOut(t *testing.T) {
+	body := []byte(`[
+		{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"one"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}},
+		{"id":"msg_2","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"two"}],"stop_reason":"max_tokens","usage":{"input_tokens":10,"output_tokens":3}}
+	]`)
+
+	got, err := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude", ChoiceFanOut: 2}).ResponseTo(body)
+	require.NoError(t, err)
+
+	var resp openai.OpenAIResponse
+	require.NoError(t, json.Unmarshal(got, &resp))
+	require.Len(t, resp.Choices, 2)
+	assert.Equal(t, 0, resp.Choices[0].Index)
+	assert.Equal(t, "one", resp.Choices[0].Message.Content)
+	assert.Equal(t, 1, resp.Choices[1].Index)
+	assert.Equal(t, "two", resp.Choices[1].Message.Content)
+	assert.Equal(t, "length", resp.Choices[1].FinishReason)
+
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, 20, resp.Usage.PromptTokens, "every request is billed for its prompt")
+	assert.Equal(t, 5, resp.Usage.CompletionTokens)
+	assert.Equal(t, 25, resp.Usage.TotalTokens)
+
+	_, err = New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude"}).ResponseTo([]byte(`[{"content":`))
+	assert.Error(t, err)
+}
+
+func TestMergeChoices(t *testing.T) {
+	merged, err := MergeChoices([][]byte{
+		[]byte(`{"id":"a","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"x"}}],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3,"completion_tokens_details":{"reasoning_tokens":1}}}`),
+		[]byte(`{"id":"b","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"y"}}],"usage":{"prompt_tokens":1,"completion_tokens":4,"total_tokens":5}}`),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a","object":"chat.completion",
+		"choices":[{"index":0,"message":{"role":"assistant","content":"x"}},{"index":1,"message":{"role":"assistant","content":"y"}}],
+		"usage":{"prompt_tokens":2,"completion_tokens":6,"total_tokens":8,"completion_tokens_details":{"reasoning_tokens":1}}}`, string(merged))
+
+	_, err = MergeChoices(nil)
+	assert.Error(t, err)
+}
@@ -1,9 +1,12 @@
 package converter
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"strings"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
@@ -14,9 +17,18 @@ import (
 // RequestMode holds context parameters for a conversion session.
 type RequestMode struct {
 	IsImageGeneration bool   // true for /images/generations requests
+	IsImageEdit       bool   // true for /images/edits and /images/variations requests (multipart)
 	IsEmbeddings      bool   // true for /embeddings requests
 	IsStreaming       bool   // true for streaming (stream: true) requests
 	ModelID           string // e.g. "gemini-2.0-flash", "claude-opus-4-5"
+
+	ThinkingBudgets map[string]int // reasoning_effort -> thinking budget tokens of converted requests (nil = built-in)
+	ChoiceFanOut    int            // Largest n fanned out for providers returning one choice per request (0 = n > 1 rejected)
+
+	PostProcess   PostProcessOptions // Normalizations applied to converted chat responses
+	StopSequences []string           // Request stop sequences (for PostProcess.StripStopSequences)
+
+	Logger *slog.Logger // Request-scoped logger for stream conversion (nil = slog.Default())
 }
 
 // ProviderConverter performs request/response conversion for a specific provider.
@@ -36,13 +48,24 @@ func New(providerType config.ProviderType, mode RequestMode) *ProviderConverter
 
 // RequestFrom converts an OpenAI-format request body to the provider-specific format.
 // Returns the original body unchanged for OpenAI-compatible providers (passthrough).
-func (c *ProviderConverter) RequestFrom(body []byte) ([]byte, error) {
+// Requests with content the provider cannot take fail with a *RequestError; a request whose
+// ctx already ended is not converted and fails with the cause of ctx.
+func (c *ProviderConverter) RequestFrom(ctx context.Context, body []byte) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("request conversion aborted: %w", context.Cause(ctx))
+	}
 	// Handle embeddings requests
 	if c.mode.IsEmbeddings {
 		switch c.providerType {
 		case config.ProviderTypeVertexAI:
+			if err := checkEmbeddingRequest(body); err != nil {
+				return nil, err
+			}
 			return vertex.OpenAIEmbeddingToVertex(body)
 		case config.ProviderTypeGemini:
+			if err := checkEmbeddingRequest(body); err != nil {
+				return nil, err
+			}
 			return vertex.OpenAIEmbeddingToGemini(body, c.mode.ModelID)
 		case config.ProviderTypeAnthropic:
 			return nil, errors.New("anthropic does not support embeddings")
@@ -53,6 +76,21 @@ func (c *ProviderConverter) RequestFrom(body []byte) ([]byte, error) {
 		}
 	}
 
+	// Image edits and variations are multipart forms, only OpenAI-compatible providers take them
+	if c.mode.IsImageEdit {
+		if !SupportsImageEdits(c.providerType) {
+			return nil, requestError(ErrCodeUnsupportedEndpoint, "", "%s does not support image edits and variations", c.providerType)
+		}
+		return body, nil
+	}
+
+	if !c.mode.IsImageGeneration && !c.IsPassthrough() {
+		if err := c.checkRequest(body); err != nil {
+			return nil, err
+		}
+		body = applyThinkingBudget(body, c.mode.ThinkingBudgets)
+	}
+
 	switch c.providerType {
 	case config.ProviderTypeVertexAI, config.ProviderTypeGemini:
 		return vertex.OpenAIToVertex(body, c.mode.IsImageGeneration, c.mode.ModelID)
@@ -68,13 +106,14 @@ func (c *ProviderConverter) RequestFrom(body []byte) ([]byte, error) {
 		}
 		return anthropic.OpenAIToBedrock(body, c.mode.ModelID)
 	default:
-		// ProviderTypeOpenAI, ProviderTypeProxy, and others: pass through unchanged
+		// ProviderTypeOpenAI, ProviderTypeProxy, ProviderTypeMock, and others: pass through unchanged
 		return body, nil
 	}
 }
 
 // ResponseTo converts a provider-specific response body to OpenAI format.
 // Returns the original body unchanged for OpenAI-compatible providers (passthrough).
+// The response of a fanned out request is the JSON array of its provider responses.
 func (c *ProviderConverter) ResponseTo(body []byte) ([]byte, error) {
 	// Handle embeddings responses
 	if c.mode.IsEmbeddings {
@@ -98,20 +137,42 @@ func (c *ProviderConverter) ResponseTo(body []byte) ([]byte, error) {
 			// Imagen: native image generation endpoint
 			return vertex.VertexImageToOpenAI(body)
 		}
-		return vertex.VertexToOpenAI(body, c.mode.ModelID)
+		return c.postProcess(vertex.VertexToOpenAI(body, c.mode.ModelID))
 	case config.ProviderTypeAnthropic, config.ProviderTypeBedrock:
-		return anthropic.AnthropicToOpenAI(body, c.mode.ModelID)
+		if isFannedOut(body) {
+			return c.fanOutResponseTo(body)
+		}
+		return c.postProcess(anthropic.AnthropicToOpenAI(body, c.mode.ModelID))
 	default:
 		return body, nil
 	}
 }
 
+// postProcess applies the configured normalizations to a converted chat response
+func (c *ProviderConverter) postProcess(body []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return PostProcessResponse(body, c.mode.PostProcess, c.mode.StopSequences), nil
+}
+
 // StreamTo transforms a provider SSE stream into OpenAI-compatible SSE format,
 // writing the result to writer. For passthrough providers, bytes are copied directly.
 func (c *ProviderConverter) StreamTo(reader io.Reader, writer io.Writer) error {
+	if c.mode.PostProcess.TrimLeadingWhitespace && !c.IsPassthrough() {
+		pp := NewStreamPostProcessor(writer, c.mode.PostProcess)
+		if err := c.streamTo(reader, pp); err != nil {
+			return err
+		}
+		return pp.Flush()
+	}
+	return c.streamTo(reader, writer)
+}
+
+func (c *ProviderConverter) streamTo(reader io.Reader, writer io.Writer) error {
 	switch c.providerType {
 	case config.ProviderTypeVertexAI, config.ProviderTypeGemini:
-		return vertex.TransformVertexStreamToOpenAI(reader, c.mode.ModelID, writer)
+		return vertex.TransformVertexStreamToOpenAI(reader, c.mode.ModelID, writer, c.mode.Logger)
 	case config.ProviderTypeAnthropic:
 		return anthropic.TransformAnthropicStreamToOpenAI(reader, c.mode.ModelID, writer)
 	case config.ProviderTypeBedrock:
@@ -170,13 +231,24 @@ func (c *ProviderConverter) BuildURL(cred *config.CredentialConfig) string {
 // Passthrough providers use the OpenAI wire format natively.
 func (c *ProviderConverter) IsPassthrough() bool {
 	switch c.providerType {
-	case config.ProviderTypeOpenAI, config.ProviderTypeProxy:
+	case config.ProviderTypeOpenAI, config.ProviderTypeProxy, config.ProviderTypeMock:
 		return true
 	default:
 		return false
 	}
 }
 
+// SupportsImageEdits reports whether credentials of a provider type take image edit and
+// variation requests: the multipart body is passed through, other providers have no conversion
+func SupportsImageEdits(providerType config.ProviderType) bool {
+	switch providerType {
+	case config.ProviderTypeVertexAI, config.ProviderTypeGemini, config.ProviderTypeAnthropic, config.ProviderTypeBedrock:
+		return false
+	default:
+		return true
+	}
+}
+
 // UsageFromResponse extracts token usage from an OpenAI-format response body.
 // Should be called after ResponseTo() so the body is always in OpenAI format.
 func (c *ProviderConverter) UsageFromResponse(body []byte) *TokenUsage {
@@ -218,6 +290,9 @@ func ExtractTokenUsage(body []byte) *TokenUsage {
 				AudioTokens              int `json:"audio_tokens,omitempty"`
 				ReasoningTokens          int `json:"reasoning_tokens,omitempty"`
 			} `json:"completion_tokens_details,omitempty"`
+			ServerToolUse struct {
+				WebSearchRequests int `json:"web_search_requests,omitempty"`
+			} `json:"server_tool_use,omitempty"`
 			// Responses API / Image generation format (input_tokens/output_tokens)
 			InputTokens        int `json:"input_tokens"`
 			OutputTokens       int `json:"output_tokens"`
@@ -281,5 +356,6 @@ func ExtractTokenUsage(body []byte) *TokenUsage {
 		RejectedPredictionTokens: resp.Usage.CompletionTokensDetails.RejectedPredictionTokens,
 		AudioOutputTokens:        audioOut,
 		ReasoningTokens:          reasoning,
+		WebSearchRequests:        resp.Usage.ServerToolUse.WebSearchRequests,
 	}
 }
@@ -2,8 +2,10 @@ package converter
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 
@@ -17,7 +19,7 @@ import (
 func TestProviderConverter_RequestFrom_Passthrough(t *testing.T) {
 	c := New(config.ProviderTypeOpenAI, RequestMode{})
 	body := []byte(`{"test":true}`)
-	got, err := c.RequestFrom(body)
+	got, err := c.RequestFrom(context.Background(), body)
 	if err != nil {
 		t.Fatalf("RequestFrom error: %v", err)
 	}
@@ -30,7 +32,7 @@ func TestProviderConverter_RequestFrom_Anthropic(t *testing.T) {
 	body := mustJSON(t, minimalOpenAIChatRequest())
 
 	c := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude-test"})
-	got, err := c.RequestFrom(body)
+	got, err := c.RequestFrom(context.Background(), body)
 	if err != nil {
 		t.Fatalf("RequestFrom error: %v", err)
 	}
@@ -44,9 +46,24 @@ func TestProviderConverter_RequestFrom_Anthropic(t *testing.T) {
 	}
 }
 
+func TestProviderConverter_RequestFrom_ContextEnded(t *testing.T) {
+	cause := errors.New("shutting down")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+
+	c := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude-test"})
+	_, err := c.RequestFrom(ctx, []byte(`{"model":"gpt-4","messages":[{"role":"user","content":"hi"}]}`))
+	if !errors.Is(err, cause) {
+		t.Fatalf("expected the context cause, got %v", err)
+	}
+	if AsRequestError(err) != nil {
+		t.Fatalf("an ended context is not a request error: %v", err)
+	}
+}
+
 func TestProviderConverter_RequestFrom_AnthropicImageNotSupported(t *testing.T) {
 	c := New(config.ProviderTypeAnthropic, RequestMode{IsImageGeneration: true})
-	_, err := c.RequestFrom([]byte(`{"model":"gpt-4"}`))
+	_, err := c.RequestFrom(context.Background(), []byte(`{"model":"gpt-4"}`))
 	if err == nil {
 		t.Fatalf("expected error for image generation")
 	}
@@ -55,6 +72,61 @@ func TestProviderConverter_RequestFrom_AnthropicImageNotSupported(t *testing.T)
 	}
 }
 
+func TestProviderConverter_RequestFrom_ImageEdit(t *testing.T) {
+	form := []byte("--b\r\nContent-Disposition: form-data; name=\"model\"\r\n\r\ndall-e-2\r\n--b--\r\n")
+	out, err := New(config.ProviderTypeOpenAI, RequestMode{IsImageEdit: true}).RequestFrom(context.Background(), form)
+	if err != nil || string(out) != string(form) {
+		t.Fatalf("expected passthrough of the multipart body, got %q, %v", out, err)
+	}
+
+	_, err = New(config.ProviderTypeVertexAI, RequestMode{IsImageEdit: true}).RequestFrom(context.Background(), form)
+	reqErr := AsRequestError(err)
+	if reqErr == nil || reqErr.Code != ErrCodeUnsupportedEndpoint {
+		t.Fatalf("expected unsupported_endpoint request error, got %v", err)
+	}
+}
+
+func TestProviderConverter_RequestFrom_ThinkingBudgets(t *testing.T) {
+	budgets := map[string]int{"high": 8000, "low": 0}
+	convert := func(providerType config.ProviderType, model, body string) map[string]interface{} {
+		t.Helper()
+		out, err := New(providerType, RequestMode{ModelID: model, ThinkingBudgets: budgets}).RequestFrom(context.Background(), []byte(body))
+		if err != nil {
+			t.Fatalf("RequestFrom: %v", err)
+		}
+		var req map[string]interface{}
+		if err := json.Unmarshal(out, &req); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return req
+	}
+	const high = `{"model":"m","reasoning_effort":"high","max_completion_tokens":20000,"messages":[{"role":"user","content":"hi"}]}`
+
+	claude := convert(config.ProviderTypeAnthropic, "claude-sonnet-4-5", high)
+	thinking, _ := claude["thinking"].(map[string]interface{})
+	if thinking["budget_tokens"] != float64(8000) || claude["max_tokens"] != float64(20000) {
+		t.Fatalf("expected the configured budget within max_completion_tokens, got %v", claude)
+	}
+
+	gemini := convert(config.ProviderTypeGemini, "gemini-2.5-flash", high)
+	genCfg, _ := gemini["generationConfig"].(map[string]interface{})
+	thinkingCfg, _ := genCfg["thinkingConfig"].(map[string]interface{})
+	if thinkingCfg["thinkingBudget"] != float64(8000) || genCfg["maxOutputTokens"] != float64(20000) {
+		t.Fatalf("expected thinkingBudget 8000, got %v", genCfg)
+	}
+
+	off := convert(config.ProviderTypeAnthropic, "claude-sonnet-4-5", `{"model":"m","reasoning_effort":"low","messages":[{"role":"user","content":"hi"}]}`)
+	if off["thinking"] != nil {
+		t.Fatalf("expected a budget of 0 to turn thinking off, got %v", off["thinking"])
+	}
+
+	explicit := convert(config.ProviderTypeAnthropic, "claude-sonnet-4-5",
+		`{"model":"m","reasoning_effort":"high","thinking":{"type":"enabled","budget_tokens":2000},"messages":[{"role":"user","content":"hi"}]}`)
+	if thinking, _ := explicit["thinking"].(map[string]interface{}); thinking["budget_tokens"] != float64(2000) {
+		t.Fatalf("expected the client's thinking to be kept, got %v", explicit["thinking"])
+	}
+}
+
 func TestProviderConverter_RequestFrom_VertexImageGeneration_Imagen(t *testing.T) {
 	n := 2
 	imgReq := openai.OpenAIImageRequest{
@@ -67,7 +139,7 @@ func TestProviderConverter_RequestFrom_VertexImageGeneration_Imagen(t *testing.T
 	body := mustJSON(t, imgReq)
 
 	c := New(config.ProviderTypeVertexAI, RequestMode{IsImageGeneration: true, ModelID: "imagen-3"})
-	got, err := c.RequestFrom(body)
+	got, err := c.RequestFrom(context.Background(), body)
 	if err != nil {
 		t.Fatalf("RequestFrom error: %v", err)
 	}
@@ -90,7 +162,7 @@ func TestProviderConverter_RequestFrom_VertexImageGeneration_Imagen(t *testing.T
 func TestProviderConverter_RequestFrom_VertexChat(t *testing.T) {
 	body := mustJSON(t, minimalOpenAIChatRequest())
 	c := New(config.ProviderTypeVertexAI, RequestMode{ModelID: "gemini-1.5-flash"})
-	got, err := c.RequestFrom(body)
+	got, err := c.RequestFrom(context.Background(), body)
 	if err != nil {
 		t.Fatalf("RequestFrom error: %v", err)
 	}
@@ -224,6 +296,25 @@ func TestProviderConverter_ResponseTo_Anthropic(t *testing.T) {
 	}
 }
 
+func TestProviderConverter_ResponseTo_AnthropicRefusal(t *testing.T) {
+	body := []byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"I can't"}],"stop_reason":"refusal","usage":{"input_tokens":5,"output_tokens":2}}`)
+
+	c := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude"})
+	got, err := c.ResponseTo(body)
+	if err != nil {
+		t.Fatalf("ResponseTo error: %v", err)
+	}
+
+	resp := mustUnmarshal[openai.OpenAIResponse](t, got)
+	choice := resp.Choices[0]
+	if choice.FinishReason != "content_filter" {
+		t.Fatalf("expected finish_reason content_filter, got %q", choice.FinishReason)
+	}
+	if !choice.ContentFilterResults["refusal"].Filtered {
+		t.Fatalf("expected refusal content filter result, got %v", choice.ContentFilterResults)
+	}
+}
+
 func TestProviderConverter_StreamTo(t *testing.T) {
 	{
 		c := New(config.ProviderTypeOpenAI, RequestMode{})
@@ -355,6 +446,11 @@ func TestExtractTokenUsage(t *testing.T) {
 		t.Fatalf("unexpected image token counts: %+v", usage)
 	}
 
+	searchBody := []byte(`{"usage":{"prompt_tokens":5,"completion_tokens":7,"server_tool_use":{"web_search_requests":3}}}`)
+	if usage = ExtractTokenUsage(searchBody); usage == nil || usage.WebSearchRequests != 3 {
+		t.Fatalf("unexpected web search requests: %+v", usage)
+	}
+
 	zeroBody := []byte(`{"usage":{"prompt_tokens":0,"completion_tokens":0}}`)
 	if got := ExtractTokenUsage(zeroBody); got != nil {
 		t.Fatalf("expected nil for zero usage")
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"strings"
 
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
@@ -16,6 +17,13 @@ func GenerateID() string {
 	return "chatcmpl-" + hex.EncodeToString(bytes)[:20]
 }
 
+// GenerateAudioID generates a unique ID for generated audio output.
+func GenerateAudioID() string {
+	bytes := make([]byte, 16)
+	_, _ = rand.Read(bytes)
+	return "audio_" + hex.EncodeToString(bytes)[:20]
+}
+
 // GetCurrentTimestamp returns the current Unix timestamp (UTC).
 // Used by multiple transformers for response created timestamp.
 func GetCurrentTimestamp() int64 {
@@ -60,6 +68,28 @@ func ExtractTextBlocks(content interface{}) []string {
 	}
 }
 
+// SystemPromptSeparator separates the texts of multiple system/developer messages
+// when they are merged into a single provider system instruction.
+const SystemPromptSeparator = "\n\n"
+
+// ExtractSystemPrompt returns the text of a system/developer message, with multiple
+// text blocks joined by newlines. Returns empty string if the message has no text.
+func ExtractSystemPrompt(content interface{}) string {
+	return strings.Join(ExtractTextBlocks(content), "\n")
+}
+
+// JoinSystemPrompts merges system/developer message texts (in message order) into one
+// system instruction, skipping empty ones.
+func JoinSystemPrompts(prompts []string) string {
+	var nonEmpty []string
+	for _, prompt := range prompts {
+		if prompt != "" {
+			nonEmpty = append(nonEmpty, prompt)
+		}
+	}
+	return strings.Join(nonEmpty, SystemPromptSeparator)
+}
+
 // EncodeBase64 encodes a byte slice to base64 string.
 // Used for preserving binary data like Gemini 3 thoughtSignature in JSON responses.
 func EncodeBase64(data []byte) string {
@@ -76,3 +76,19 @@ func TestDecodeBase64(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractSystemPrompt(t *testing.T) {
+	assert.Equal(t, "Be helpful.", ExtractSystemPrompt("Be helpful."))
+	assert.Equal(t, "First.\nSecond.", ExtractSystemPrompt([]interface{}{
+		map[string]interface{}{"type": "text", "text": "First."},
+		map[string]interface{}{"type": "image_url"},
+		map[string]interface{}{"type": "text", "text": "Second."},
+	}))
+	assert.Equal(t, "", ExtractSystemPrompt(nil))
+}
+
+func TestJoinSystemPrompts(t *testing.T) {
+	assert.Equal(t, "", JoinSystemPrompts(nil))
+	assert.Equal(t, "Only one.", JoinSystemPrompts([]string{"", "Only one.", ""}))
+	assert.Equal(t, "First.\n\nSecond.", JoinSystemPrompts([]string{"First.", "Second."}))
+}
@@ -0,0 +1,131 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+// Run a fuzzer beyond its seed corpus with e.g.
+//
+//	go test ./internal/converter -run '^$' -fuzz '^FuzzRequestFrom$' -fuzztime 60s
+//
+// or all of them with make fuzz.
+//
+// Failing inputs are saved to testdata/fuzz/<Fuzzer> and replayed by go test from then on.
+
+// convertingProviders are the provider types whose converters rewrite request bodies
+var convertingProviders = []config.ProviderType{
+	config.ProviderTypeVertexAI, config.ProviderTypeGemini, config.ProviderTypeAnthropic, config.ProviderTypeBedrock,
+}
+
+var fuzzRequestSeeds = []string{
+	`{"model":"m","messages":[{"role":"user","content":"hi"}]}`,
+	`{"model":"m","messages":[{"role":"system","content":"be brief"},{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]}],"max_tokens":100,"temperature":0.2,"stop":["END"]}`,
+	`{"model":"m","messages":[{"role":"user","content":"weather?"},{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Paris\"}"}}]},{"role":"tool","tool_call_id":"call_1","content":"sunny"}],"tools":[{"type":"function","function":{"name":"weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}}],"tool_choice":"auto"}`,
+	`{"model":"m","messages":[{"role":"developer","content":[{"type":"text","text":"rules"}]},{"role":"user","content":"x"}],"response_format":{"type":"json_schema","json_schema":{"name":"r","schema":{"type":"object"}}},"reasoning_effort":"high","stream":true}`,
+	`{"model":"m","messages":[]}`,
+	`{"messages":[{"role":"assistant","content":""}]}`,
+	`{}`,
+	`[]`,
+}
+
+// FuzzRequestFrom feeds arbitrary OpenAI request bodies to every converter: conversion must
+// not panic and, when it succeeds, produce JSON
+func FuzzRequestFrom(f *testing.F) {
+	for _, seed := range fuzzRequestSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, provider := range convertingProviders {
+			conv := New(provider, RequestMode{ModelID: "model-1"})
+			converted, err := conv.RequestFrom(context.Background(), body)
+			if err != nil {
+				continue
+			}
+			if !json.Valid(converted) {
+				t.Fatalf("%s: conversion produced invalid JSON: %s", provider, converted)
+			}
+			_ = conv.ValidateRequest(converted)
+		}
+	})
+}
+
+// FuzzRequestFrom_Conversation converts well-formed conversations: the result must pass
+// ValidateRequest and keep the text of every message, catching silent content loss
+func FuzzRequestFrom_Conversation(f *testing.F) {
+	f.Add("be brief", "what is the capital of France?", "Paris.", "and of Spain?")
+	f.Add("", "hi", "", "")
+	f.Add("<system> & \"quotes\"", "multi\nline\tuser", "ünïcödé 👋", "  padded  ")
+	f.Fuzz(func(t *testing.T, system, user, assistant, followUp string) {
+		if strings.TrimSpace(user) == "" || !isValidJSONText(system, user, assistant, followUp) {
+			return
+		}
+		messages := []map[string]interface{}{}
+		if system != "" {
+			messages = append(messages, map[string]interface{}{"role": "system", "content": system})
+		}
+		messages = append(messages, map[string]interface{}{"role": "user", "content": user})
+		if assistant != "" && strings.TrimSpace(followUp) != "" {
+			messages = append(messages,
+				map[string]interface{}{"role": "assistant", "content": assistant},
+				map[string]interface{}{"role": "user", "content": followUp})
+		}
+		body, err := json.Marshal(map[string]interface{}{"model": "m", "messages": messages})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, provider := range convertingProviders {
+			conv := New(provider, RequestMode{ModelID: "model-1"})
+			converted, err := conv.RequestFrom(context.Background(), body)
+			if err != nil {
+				t.Fatalf("%s: conversion failed: %v", provider, err)
+			}
+			if err := conv.ValidateRequest(converted); err != nil {
+				t.Fatalf("%s: %v\nbody: %s", provider, err, converted)
+			}
+			for _, message := range messages {
+				text, _ := json.Marshal(message["content"])
+				if !bytes.Contains(converted, bytes.Trim(text, `"`)) {
+					t.Fatalf("%s: %s message text %s lost: %s", provider, message["role"], text, converted)
+				}
+			}
+		}
+	})
+}
+
+// FuzzResponseTo feeds arbitrary provider responses to the response converters: conversion
+// must not panic and, when it succeeds, produce JSON
+func FuzzResponseTo(f *testing.F) {
+	f.Add([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":1}}`))
+	f.Add([]byte(`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"MAX_TOKENS"}]}`))
+	f.Add([]byte(`{"id":"msg_1","content":[{"type":"text","text":"hi"},{"type":"tool_use","id":"t","name":"f","input":{}}],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":1}}`))
+	f.Add([]byte(`{"content":[{"type":"thinking","thinking":"hmm","signature":"s"}],"stop_reason":"max_tokens"}`))
+	f.Add([]byte(`{}`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, provider := range convertingProviders {
+			converted, err := New(provider, RequestMode{ModelID: "model-1"}).ResponseTo(body)
+			if err == nil && !json.Valid(converted) {
+				t.Fatalf("%s: conversion produced invalid JSON: %s", provider, converted)
+			}
+		}
+	})
+}
+
+// isValidJSONText reports whether the strings survive a JSON round trip unchanged (the fuzzer
+// also generates invalid UTF-8, which encoding/json replaces)
+func isValidJSONText(texts ...string) bool {
+	for _, text := range texts {
+		encoded, _ := json.Marshal(text)
+		var decoded string
+		if json.Unmarshal(encoded, &decoded) != nil || decoded != text {
+			return false
+		}
+	}
+	return true
+}
@@ -118,7 +118,7 @@ var gpt5Mapping = ModelParamsMapping{
 }
 
 // modelMappings maps model family prefixes to their parameter transformations.
-// Order matters: longer prefixes are checked first via matchModelFamily.
+// Order matters: longer prefixes are checked first via MatchModelFamily.
 var modelMappings = []struct {
 	prefix  string
 	mapping ModelParamsMapping
@@ -155,10 +155,10 @@ func extractBaseModelName(modelID string) string {
 	return strings.ToLower(modelID)
 }
 
-// matchModelFamily checks if modelID belongs to a given model family.
+// MatchModelFamily checks if modelID belongs to a given model family.
 // Strips provider prefixes and suffixes before matching.
 // Matches: exact name ("o1"), or name followed by "-" or "." ("o1-mini", "gpt-5.1").
-func matchModelFamily(modelID, family string) bool {
+func MatchModelFamily(modelID, family string) bool {
 	base := extractBaseModelName(modelID)
 	if base == family {
 		return true
@@ -170,7 +170,7 @@ func matchModelFamily(modelID, family string) bool {
 // This ensures unsupported parameters are removed and renamed before sending to the provider.
 func ReplaceBodyParam(modelID string, body []byte) []byte {
 	for _, m := range modelMappings {
-		if matchModelFamily(modelID, m.prefix) {
+		if MatchModelFamily(modelID, m.prefix) {
 			return UpdateJSONField(body, m.mapping)
 		}
 	}
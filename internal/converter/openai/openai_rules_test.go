@@ -24,7 +24,7 @@ func makeBody(t *testing.T, fields map[string]any) []byte {
 	return b
 }
 
-// --- matchModelFamily tests ---
+// --- MatchModelFamily tests ---
 
 func TestMatchModelFamily(t *testing.T) {
 	tests := []struct {
@@ -89,9 +89,9 @@ func TestMatchModelFamily(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.modelID+"_in_"+tt.family, func(t *testing.T) {
-			got := matchModelFamily(tt.modelID, tt.family)
+			got := MatchModelFamily(tt.modelID, tt.family)
 			if got != tt.want {
-				t.Errorf("matchModelFamily(%q, %q) = %v, want %v", tt.modelID, tt.family, got, tt.want)
+				t.Errorf("MatchModelFamily(%q, %q) = %v, want %v", tt.modelID, tt.family, got, tt.want)
 			}
 		})
 	}
@@ -84,9 +84,20 @@ type OpenAIResponse struct {
 }
 
 type OpenAIChoice struct {
-	Index        int                   `json:"index"`
-	Message      OpenAIResponseMessage `json:"message"`
-	FinishReason string                `json:"finish_reason"`
+	Index                int                   `json:"index"`
+	Message              OpenAIResponseMessage `json:"message"`
+	FinishReason         string                `json:"finish_reason"`
+	ContentFilterResults ContentFilterResults  `json:"content_filter_results,omitempty"`
+}
+
+// ContentFilterResults reports provider content filter outcomes per category
+// (Azure OpenAI format), set when finish_reason is "content_filter"
+type ContentFilterResults map[string]ContentFilterResult
+
+// ContentFilterResult is the content filter outcome for one category
+type ContentFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"` // safe, low, medium or high
 }
 
 type OpenAIResponseMessage struct {
@@ -96,6 +107,34 @@ type OpenAIResponseMessage struct {
 	Refusal          string           `json:"refusal,omitempty"`
 	ReasoningContent string           `json:"reasoning_content,omitempty"`
 	Images           []ImageData      `json:"images,omitempty"` // custom extension for Gemini image responses
+	Audio            *AudioOutput     `json:"audio,omitempty"`
+	Citations        []Citation       `json:"citations,omitempty"` // custom extension for Vertex grounding and Anthropic web search
+}
+
+// Citation is a source of a grounded answer (custom extension, from Vertex grounding metadata
+// or Anthropic web search results)
+type Citation struct {
+	URL      string            `json:"url,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	Domain   string            `json:"domain,omitempty"`
+	Text     string            `json:"text,omitempty"`     // Retrieved text (Vertex AI Search / RAG sources)
+	Segments []CitationSegment `json:"segments,omitempty"` // Parts of the answer supported by the source
+}
+
+// CitationSegment is a part of the answer supported by a citation.
+// Offsets are in bytes of the answer text.
+type CitationSegment struct {
+	Text       string   `json:"text,omitempty"`
+	StartIndex int      `json:"start_index"`
+	EndIndex   int      `json:"end_index"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// AudioOutput is audio generated by the model (OpenAI "audio" output modality)
+type AudioOutput struct {
+	ID     string `json:"id"`
+	Data   string `json:"data"`             // base64-encoded audio data
+	Format string `json:"format,omitempty"` // e.g., "wav", "mp3", "pcm16" (derived from the provider MIME type)
 }
 
 type OpenAIToolCall struct {
@@ -133,6 +172,12 @@ type OpenAIUsage struct {
 	TotalTokens             int                     `json:"total_tokens"`
 	PromptTokensDetails     *TokenDetails           `json:"prompt_tokens_details,omitempty"`
 	CompletionTokensDetails *CompletionTokenDetails `json:"completion_tokens_details,omitempty"`
+	ServerToolUse           *ServerToolUse          `json:"server_tool_use,omitempty"` // LiteLLM extension, billed per request
+}
+
+// ServerToolUse counts the server-side tool invocations of a response (Anthropic web search)
+type ServerToolUse struct {
+	WebSearchRequests int `json:"web_search_requests"`
 }
 
 // Streaming types
@@ -148,9 +193,10 @@ type OpenAIStreamingChunk struct {
 }
 
 type OpenAIStreamingChoice struct {
-	Index        int                  `json:"index"`
-	Delta        OpenAIStreamingDelta `json:"delta"`
-	FinishReason *string              `json:"finish_reason"`
+	Index                int                  `json:"index"`
+	Delta                OpenAIStreamingDelta `json:"delta"`
+	FinishReason         *string              `json:"finish_reason"`
+	ContentFilterResults ContentFilterResults `json:"content_filter_results,omitempty"`
 }
 
 type OpenAIStreamingDelta struct {
@@ -159,6 +205,8 @@ type OpenAIStreamingDelta struct {
 	ToolCalls        []OpenAIStreamingToolCall `json:"tool_calls,omitempty"`
 	Refusal          string                    `json:"refusal,omitempty"`
 	ReasoningContent string                    `json:"reasoning_content,omitempty"`
+	Audio            *AudioOutput              `json:"audio,omitempty"`
+	Citations        []Citation                `json:"citations,omitempty"` // custom extension for Vertex grounding and Anthropic web search
 }
 
 type OpenAIStreamingToolCall struct {
@@ -0,0 +1,233 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/vertex"
+)
+
+// PostProcessOptions selects normalizations applied to converted responses, so clients
+// see the same output conventions as from OpenAI regardless of the backend.
+type PostProcessOptions struct {
+	StripPlaceholders     bool // Replace "[No content generated]"-style placeholders with empty content
+	TrimLeadingWhitespace bool // Remove whitespace at the start of the assistant content
+	StripStopSequences    bool // Remove a stop sequence the provider echoed at the end of the content
+}
+
+// Enabled reports whether any normalization is selected
+func (o PostProcessOptions) Enabled() bool {
+	return o.StripPlaceholders || o.TrimLeadingWhitespace || o.StripStopSequences
+}
+
+// placeholders are contents converters put into choices without generated content
+var placeholders = map[string]bool{
+	vertex.PlaceholderMaxTokens: true,
+	vertex.PlaceholderNoContent: true,
+}
+
+// StopSequences returns the stop sequences of an OpenAI request body ("stop" string or list)
+func StopSequences(body []byte) []string {
+	var req struct {
+		Stop json.RawMessage `json:"stop"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Stop) == 0 {
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(req.Stop, &single); err == nil {
+		if single == "" {
+			return nil
+		}
+		return []string{single}
+	}
+	var list []string
+	if err := json.Unmarshal(req.Stop, &list); err != nil {
+		return nil
+	}
+	stops := list[:0]
+	for _, stop := range list {
+		if stop != "" {
+			stops = append(stops, stop)
+		}
+	}
+	return stops
+}
+
+// PostProcessResponse normalizes the choices of an OpenAI Chat Completions response body.
+// Returns the body unchanged when nothing was normalized or it cannot be parsed.
+func PostProcessResponse(body []byte, opts PostProcessOptions, stopSequences []string) []byte {
+	if !opts.Enabled() {
+		return body
+	}
+	resp, ok := decodeJSONObject(body)
+	if !ok {
+		return body
+	}
+	choices, _ := resp["choices"].([]interface{})
+
+	changed := false
+	for _, c := range choices {
+		choice, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		message, ok := choice["message"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, ok := message["content"].(string)
+		if !ok {
+			continue
+		}
+
+		normalized := content
+		if opts.StripPlaceholders && placeholders[normalized] {
+			normalized = ""
+		}
+		if opts.TrimLeadingWhitespace {
+			normalized = strings.TrimLeft(normalized, " \t\r\n")
+		}
+		if opts.StripStopSequences && choice["finish_reason"] == "stop" {
+			normalized = trimStopSequence(normalized, stopSequences)
+		}
+		if normalized != content {
+			message["content"] = normalized
+			changed = true
+		}
+	}
+	if !changed {
+		return body
+	}
+
+	newBody, err := json.Marshal(resp)
+	if err != nil {
+		return body
+	}
+	return newBody
+}
+
+// trimStopSequence removes the longest stop sequence content ends with
+func trimStopSequence(content string, stopSequences []string) string {
+	longest := ""
+	for _, stop := range stopSequences {
+		if len(stop) > len(longest) && strings.HasSuffix(content, stop) {
+			longest = stop
+		}
+	}
+	return strings.TrimSuffix(content, longest)
+}
+
+// StreamPostProcessor applies the TrimLeadingWhitespace normalization to an OpenAI
+// Chat Completions SSE stream. Complete events are processed and written together in
+// one Write per input Write, so downstream chunk boundaries are preserved.
+// Stop sequences and placeholders are only normalized in non-streaming responses.
+type StreamPostProcessor struct {
+	w       io.Writer
+	opts    PostProcessOptions
+	pending []byte
+	started map[json.Number]bool // Choice indexes whose content has started
+}
+
+// NewStreamPostProcessor creates a StreamPostProcessor writing to w.
+// Call Flush when the stream ends to write an incomplete trailing event.
+func NewStreamPostProcessor(w io.Writer, opts PostProcessOptions) *StreamPostProcessor {
+	return &StreamPostProcessor{w: w, opts: opts, started: make(map[json.Number]bool)}
+}
+
+// Write implements io.Writer
+func (s *StreamPostProcessor) Write(p []byte) (int, error) {
+	if !s.opts.TrimLeadingWhitespace {
+		return s.w.Write(p)
+	}
+	s.pending = append(s.pending, p...)
+	end := bytes.LastIndex(s.pending, []byte("\n\n"))
+	if end < 0 {
+		return len(p), nil
+	}
+	end += 2
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(s.pending[:end], []byte("\n")) {
+		out.Write(s.processLine(line))
+	}
+	s.pending = append(s.pending[:0], s.pending[end:]...)
+	if _, err := s.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes data of an incomplete trailing event unchanged
+func (s *StreamPostProcessor) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	_, err := s.w.Write(s.pending)
+	s.pending = nil
+	return err
+}
+
+// processLine trims leading whitespace from delta contents until a choice's content starts.
+// Chunks whose only content is the trimmed whitespace are kept with empty content.
+func (s *StreamPostProcessor) processLine(line []byte) []byte {
+	payload, ok := bytes.CutPrefix(line, []byte("data: "))
+	if !ok || bytes.HasPrefix(payload, []byte("[DONE]")) {
+		return line
+	}
+	chunk, ok := decodeJSONObject(payload)
+	if !ok {
+		return line
+	}
+	choices, _ := chunk["choices"].([]interface{})
+
+	changed := false
+	for _, c := range choices {
+		choice, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index, _ := choice["index"].(json.Number)
+		if s.started[index] {
+			continue
+		}
+		delta, ok := choice["delta"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, ok := delta["content"].(string)
+		if !ok || content == "" {
+			continue
+		}
+		trimmed := strings.TrimLeft(content, " \t\r\n")
+		if trimmed != "" {
+			s.started[index] = true
+		}
+		if trimmed != content {
+			delta["content"] = trimmed
+			changed = true
+		}
+	}
+	if !changed {
+		return line
+	}
+
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return line
+	}
+	return append(append([]byte("data: "), data...), '\n')
+}
+
+// decodeJSONObject decodes a JSON object keeping numbers intact on re-encoding
+func decodeJSONObject(data []byte) (map[string]interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, false
+	}
+	return obj, true
+}
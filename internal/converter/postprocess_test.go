@@ -0,0 +1,162 @@
+package converter
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/mixaill76/auto_ai_router/internal/converter/vertex"
+)
+
+func TestStopSequences(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"stop":"END"}`, []string{"END"}},
+		{`{"stop":["###","","END"]}`, []string{"###", "END"}},
+		{`{"stop":""}`, nil},
+		{`{"stop":null}`, nil},
+		{`{"messages":[]}`, nil},
+		{`not json`, nil},
+	}
+	for _, tt := range tests {
+		if got := StopSequences([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("StopSequences(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func chatResponse(t *testing.T, content, finishReason string) []byte {
+	t.Helper()
+	return mustJSON(t, openai.OpenAIResponse{
+		ID:     "chatcmpl-1",
+		Object: "chat.completion",
+		Model:  "test",
+		Choices: []openai.OpenAIChoice{{
+			Message:      openai.OpenAIResponseMessage{Role: "assistant", Content: content},
+			FinishReason: finishReason,
+		}},
+	})
+}
+
+func TestPostProcessResponse(t *testing.T) {
+	all := PostProcessOptions{StripPlaceholders: true, TrimLeadingWhitespace: true, StripStopSequences: true}
+	tests := []struct {
+		name    string
+		content string
+		finish  string
+		opts    PostProcessOptions
+		want    string
+	}{
+		{"placeholder", vertex.PlaceholderNoContent, "stop", all, ""},
+		{"max tokens placeholder", vertex.PlaceholderMaxTokens, "length", all, ""},
+		{"placeholder kept when disabled", vertex.PlaceholderNoContent, "stop", PostProcessOptions{TrimLeadingWhitespace: true}, vertex.PlaceholderNoContent},
+		{"leading whitespace", "\n\n  Hello world ", "stop", all, "Hello world "},
+		{"stop sequence", "Hello\nEND", "stop", all, "Hello\n"},
+		{"longest stop sequence", "Hello###", "stop", all, "Hello"},
+		{"stop sequence only with finish_reason stop", "Hello END", "length", all, "Hello END"},
+		{"unchanged", "Hello", "stop", all, "Hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := chatResponse(t, tt.content, tt.finish)
+			got := PostProcessResponse(body, tt.opts, []string{"END", "#", "###"})
+			resp := mustUnmarshal[openai.OpenAIResponse](t, got)
+			if resp.Choices[0].Message.Content != tt.want {
+				t.Fatalf("content = %q, want %q", resp.Choices[0].Message.Content, tt.want)
+			}
+			if tt.content == tt.want && !bytes.Equal(got, body) {
+				t.Fatalf("unchanged response was re-encoded")
+			}
+		})
+	}
+}
+
+func TestPostProcessResponse_Disabled(t *testing.T) {
+	body := chatResponse(t, " "+vertex.PlaceholderNoContent, "stop")
+	if got := PostProcessResponse(body, PostProcessOptions{}, nil); !bytes.Equal(got, body) {
+		t.Fatalf("disabled post-processing changed the body: %s", got)
+	}
+	invalid := []byte(`not json`)
+	if got := PostProcessResponse(invalid, PostProcessOptions{TrimLeadingWhitespace: true}, nil); !bytes.Equal(got, invalid) {
+		t.Fatalf("invalid body changed: %s", got)
+	}
+}
+
+func TestStreamPostProcessor_TrimLeadingWhitespace(t *testing.T) {
+	var out bytes.Buffer
+	pp := NewStreamPostProcessor(&out, PostProcessOptions{TrimLeadingWhitespace: true})
+
+	events := []string{
+		`data: {"choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"content":"\n"}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"content":"  Hello"}}]}` + "\n\n" +
+			`data: {"choices":[{"index":0,"delta":{"content":" world"}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":9007199254740993}}`,
+		"\n\ndata: [DONE]\n\n",
+	}
+	writes := 0
+	for _, event := range events {
+		before := out.Len()
+		n, err := pp.Write([]byte(event))
+		if err != nil || n != len(event) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+		if out.Len() > before {
+			writes++
+		}
+	}
+	if err := pp.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if writes != 4 {
+		t.Fatalf("expected 4 downstream writes (the split event is buffered), got %d", writes)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		`"content":""}}]}` + "\n\n",
+		`{"choices":[{"delta":{"content":"Hello"},"index":0}]}`,
+		`"content":" world"`,
+		`9007199254740993`,
+		"data: [DONE]\n\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stream missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"content":"\n"`) || strings.Contains(got, `"  Hello"`) {
+		t.Errorf("leading whitespace was not trimmed:\n%s", got)
+	}
+}
+
+func TestProviderConverter_PostProcess(t *testing.T) {
+	anthropicBody := []byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"\n\nHi STOP"}],"stop_reason":"stop_sequence","stop_sequence":"STOP","usage":{"input_tokens":1,"output_tokens":1}}`)
+	c := New(config.ProviderTypeAnthropic, RequestMode{
+		ModelID:       "claude",
+		PostProcess:   PostProcessOptions{TrimLeadingWhitespace: true, StripStopSequences: true},
+		StopSequences: []string{"STOP"},
+	})
+	got, err := c.ResponseTo(anthropicBody)
+	if err != nil {
+		t.Fatalf("ResponseTo error: %v", err)
+	}
+	resp := mustUnmarshal[openai.OpenAIResponse](t, got)
+	if resp.Choices[0].Message.Content != "Hi " {
+		t.Fatalf("content = %q, want %q", resp.Choices[0].Message.Content, "Hi ")
+	}
+
+	stream := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"\\n\\nHi\"}}\n\n"
+	var out bytes.Buffer
+	if err := New(config.ProviderTypeAnthropic, RequestMode{ModelID: "claude", PostProcess: PostProcessOptions{TrimLeadingWhitespace: true}}).
+		StreamTo(strings.NewReader(stream), &out); err != nil {
+		t.Fatalf("StreamTo error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"content":"Hi"`) {
+		t.Fatalf("stream content not trimmed:\n%s", out.String())
+	}
+}
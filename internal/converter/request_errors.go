@@ -0,0 +1,259 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+// Codes of RequestError, returned to clients as the OpenAI error code
+const (
+	ErrCodeInvalidRequest         = "invalid_request"          // The body is not a valid OpenAI request
+	ErrCodeUnsupportedContentType = "unsupported_content_type" // A message content part the provider cannot take
+	ErrCodeInvalidToolSchema      = "invalid_tool_schema"      // A function tool without name or with invalid parameters
+	ErrCodeUnsupportedParam       = "unsupported_param"        // A parameter value the provider cannot express
+	ErrCodeUnsupportedEndpoint    = "unsupported_endpoint"     // An endpoint the provider has no conversion for
+)
+
+// RequestError is a RequestFrom failure caused by the client's request, which the client can
+// fix. Other RequestFrom errors are the router's.
+type RequestError struct {
+	Code    string // ErrCode* constant
+	Param   string // Path of the offending field, e.g. "messages[1].content[0].type" ("" = whole body)
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	if e.Param == "" {
+		return e.Message
+	}
+	return e.Param + ": " + e.Message
+}
+
+// StatusCode returns the HTTP status of the error: 400 for a body that is not a valid request,
+// 422 for a valid request the provider cannot take
+func (e *RequestError) StatusCode() int {
+	if e.Code == ErrCodeInvalidRequest {
+		return http.StatusBadRequest
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// AsRequestError returns the RequestError wrapped by err (nil if err is not caused by the request)
+func AsRequestError(err error) *RequestError {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr
+	}
+	return nil
+}
+
+func requestError(code, param, format string, args ...interface{}) *RequestError {
+	return &RequestError{Code: code, Param: param, Message: fmt.Sprintf(format, args...)}
+}
+
+// providerContentTypes are the message content part types each converting provider takes.
+// Audio and video parts become text placeholders for Anthropic and Bedrock, which also take
+// Responses API "input_text" parts as text.
+var providerContentTypes = map[config.ProviderType][]string{
+	config.ProviderTypeVertexAI:  {"text", "image_url", "input_audio", "video_url", "file"},
+	config.ProviderTypeGemini:    {"text", "image_url", "input_audio", "video_url", "file"},
+	config.ProviderTypeAnthropic: {"text", "input_text", "image_url", "input_audio", "video_url", "file"},
+	config.ProviderTypeBedrock:   {"text", "input_text", "image_url", "input_audio", "video_url", "file"},
+}
+
+// providerToolTypes are the tool types each converting provider takes
+var providerToolTypes = map[config.ProviderType][]string{
+	config.ProviderTypeVertexAI:  {"function", "computer_use", "web_search", "web_search_preview", "google_search_retrieval", "google_maps", "code_execution"},
+	config.ProviderTypeGemini:    {"function", "computer_use", "web_search", "web_search_preview", "google_search_retrieval", "google_maps", "code_execution"},
+	config.ProviderTypeAnthropic: {"function", "computer_use", "text_editor", "bash", "web_search", "web_search_preview"},
+	config.ProviderTypeBedrock:   {"function", "computer_use", "text_editor", "bash", "web_search", "web_search_preview"},
+}
+
+// checkRequest reports the parts of an OpenAI chat request the provider conversion would drop
+// or cannot express, as a RequestError
+func (c *ProviderConverter) checkRequest(body []byte) error {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return requestError(ErrCodeInvalidRequest, "", "body is not a valid JSON object: %v", err)
+	}
+
+	if raw, ok := req["messages"]; ok && raw != nil {
+		messages, ok := raw.([]interface{})
+		if !ok {
+			return requestError(ErrCodeInvalidRequest, "messages", "must be an array")
+		}
+		for i, entry := range messages {
+			if err := c.checkMessage(entry, fmt.Sprintf("messages[%d]", i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if raw, ok := req["tools"]; ok && raw != nil {
+		tools, ok := raw.([]interface{})
+		if !ok {
+			return requestError(ErrCodeInvalidRequest, "tools", "must be an array")
+		}
+		for i, entry := range tools {
+			if err := c.checkTool(entry, fmt.Sprintf("tools[%d]", i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := checkToolChoice(req["tool_choice"]); err != nil {
+		return err
+	}
+	if err := c.checkChoices(req["n"]); err != nil {
+		return err
+	}
+	return checkResponseFormat(req["response_format"])
+}
+
+func (c *ProviderConverter) checkMessage(entry interface{}, path string) error {
+	message, ok := entry.(map[string]interface{})
+	if !ok {
+		return requestError(ErrCodeInvalidRequest, path, "must be an object")
+	}
+	parts, ok := message["content"].([]interface{})
+	if !ok {
+		return nil
+	}
+	role, _ := message["role"].(string)
+	for j, entry := range parts {
+		partPath := fmt.Sprintf("%s.content[%d]", path, j)
+		part, ok := entry.(map[string]interface{})
+		if !ok {
+			return requestError(ErrCodeInvalidRequest, partPath, "must be an object")
+		}
+		partType, _ := part["type"].(string)
+		if partType == "refusal" && role == "assistant" {
+			continue // Earlier refusals carry no content for the provider
+		}
+		if !slices.Contains(providerContentTypes[c.providerType], partType) {
+			return requestError(ErrCodeUnsupportedContentType, partPath+".type",
+				"content type %q is not supported by %s (supported: %v)", partType, c.providerType, providerContentTypes[c.providerType])
+		}
+	}
+	return nil
+}
+
+func (c *ProviderConverter) checkTool(entry interface{}, path string) error {
+	tool, ok := entry.(map[string]interface{})
+	if !ok {
+		return requestError(ErrCodeInvalidToolSchema, path, "must be an object")
+	}
+	toolType, _ := tool["type"].(string)
+	if toolType == "" {
+		return requestError(ErrCodeInvalidToolSchema, path+".type", "is required")
+	}
+	if !slices.Contains(providerToolTypes[c.providerType], toolType) {
+		return requestError(ErrCodeUnsupportedParam, path+".type",
+			"tool type %q is not supported by %s (supported: %v)", toolType, c.providerType, providerToolTypes[c.providerType])
+	}
+	if toolType != "function" {
+		return nil
+	}
+
+	function, ok := tool["function"].(map[string]interface{})
+	if !ok {
+		return requestError(ErrCodeInvalidToolSchema, path+".function", "must be an object")
+	}
+	if name, _ := function["name"].(string); name == "" {
+		return requestError(ErrCodeInvalidToolSchema, path+".function.name", "is required")
+	}
+	raw, ok := function["parameters"]
+	if !ok || raw == nil {
+		return nil
+	}
+	params, ok := raw.(map[string]interface{})
+	if !ok {
+		return requestError(ErrCodeInvalidToolSchema, path+".function.parameters", "must be a JSON Schema object")
+	}
+	if schemaType, ok := params["type"]; ok && schemaType != "object" {
+		return requestError(ErrCodeInvalidToolSchema, path+".function.parameters.type", "is %v, must be \"object\"", schemaType)
+	}
+	if props, ok := params["properties"]; ok && props != nil {
+		if _, ok := props.(map[string]interface{}); !ok {
+			return requestError(ErrCodeInvalidToolSchema, path+".function.parameters.properties", "must be an object")
+		}
+	}
+	return nil
+}
+
+// checkToolChoice accepts the tool_choice values both converters map: none, auto, required
+// and a named function
+func checkToolChoice(raw interface{}) error {
+	switch choice := raw.(type) {
+	case nil:
+		return nil
+	case string:
+		if choice == "none" || choice == "auto" || choice == "required" {
+			return nil
+		}
+		return requestError(ErrCodeUnsupportedParam, "tool_choice", "%q is not supported (supported: none, auto, required or a function)", choice)
+	case map[string]interface{}:
+		function, _ := choice["function"].(map[string]interface{})
+		if name, _ := function["name"].(string); name != "" {
+			return nil
+		}
+		if choiceType, _ := choice["type"].(string); choiceType != "" && choiceType != "function" {
+			return requestError(ErrCodeUnsupportedParam, "tool_choice.type", "%q is not supported (supported: function)", choiceType)
+		}
+		return requestError(ErrCodeInvalidRequest, "tool_choice.function.name", "is required")
+	default:
+		return requestError(ErrCodeInvalidRequest, "tool_choice", "must be a string or an object")
+	}
+}
+
+func checkResponseFormat(raw interface{}) error {
+	if raw == nil {
+		return nil
+	}
+	format, ok := raw.(map[string]interface{})
+	if !ok {
+		return requestError(ErrCodeInvalidRequest, "response_format", "must be an object")
+	}
+	switch formatType, _ := format["type"].(string); formatType {
+	case "text", "json_object":
+		return nil
+	case "json_schema":
+		if schema, ok := format["json_schema"]; ok && schema != nil {
+			if _, ok := schema.(map[string]interface{}); !ok {
+				return requestError(ErrCodeInvalidRequest, "response_format.json_schema", "must be an object")
+			}
+		}
+		return nil
+	default:
+		return requestError(ErrCodeUnsupportedParam, "response_format.type", "%q is not supported (supported: text, json_object, json_schema)", formatType)
+	}
+}
+
+// checkEmbeddingRequest accepts the embedding inputs Vertex AI and Gemini take: a string or an
+// array of strings (token arrays are not supported)
+func checkEmbeddingRequest(body []byte) error {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return requestError(ErrCodeInvalidRequest, "", "body is not a valid JSON object: %v", err)
+	}
+	switch input := req["input"].(type) {
+	case string:
+		return nil
+	case []interface{}:
+		for i, item := range input {
+			if _, ok := item.(string); !ok {
+				return requestError(ErrCodeUnsupportedParam, fmt.Sprintf("input[%d]", i), "must be a string, token arrays are not supported")
+			}
+		}
+		return nil
+	case nil:
+		return requestError(ErrCodeInvalidRequest, "input", "is required")
+	default:
+		return requestError(ErrCodeUnsupportedParam, "input", "must be a string or an array of strings")
+	}
+}
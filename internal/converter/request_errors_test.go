@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestFrom_RequestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider config.ProviderType
+		body     string
+		code     string // "" = converted
+		param    string
+	}{
+		{"valid request", config.ProviderTypeAnthropic,
+			`{"model":"m","messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}],"tools":[{"type":"function","function":{"name":"f","parameters":{"type":"object","properties":{}}}}],"tool_choice":"auto","response_format":{"type":"json_object"}}`,
+			"", ""},
+		{"input text", config.ProviderTypeBedrock,
+			`{"model":"m","messages":[{"role":"developer","content":[{"type":"input_text","text":"s"}]},{"role":"user","content":[{"type":"input_text","text":"hi"}]}]}`,
+			"", ""},
+		{"assistant refusal", config.ProviderTypeVertexAI,
+			`{"model":"m","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"refusal","refusal":"no"}]}]}`,
+			"", ""},
+		{"not JSON", config.ProviderTypeAnthropic, `{"model":`, ErrCodeInvalidRequest, ""},
+		{"messages not an array", config.ProviderTypeGemini, `{"model":"m","messages":"hi"}`, ErrCodeInvalidRequest, "messages"},
+		{"content part not an object", config.ProviderTypeBedrock,
+			`{"model":"m","messages":[{"role":"user","content":["hi"]}]}`, ErrCodeInvalidRequest, "messages[0].content[0]"},
+		{"unsupported content type", config.ProviderTypeVertexAI,
+			`{"model":"m","messages":[{"role":"system","content":"s"},{"role":"user","content":[{"type":"text","text":"a"},{"type":"input_file"}]}]}`,
+			ErrCodeUnsupportedContentType, "messages[1].content[1].type"},
+		{"user refusal", config.ProviderTypeAnthropic,
+			`{"model":"m","messages":[{"role":"user","content":[{"type":"refusal"}]}]}`, ErrCodeUnsupportedContentType, "messages[0].content[0].type"},
+		{"tool without name", config.ProviderTypeAnthropic,
+			`{"model":"m","messages":[],"tools":[{"type":"function","function":{"description":"d"}}]}`, ErrCodeInvalidToolSchema, "tools[0].function.name"},
+		{"tool without type", config.ProviderTypeAnthropic,
+			`{"model":"m","messages":[],"tools":[{"function":{"name":"f"}}]}`, ErrCodeInvalidToolSchema, "tools[0].type"},
+		{"tool parameters not an object", config.ProviderTypeVertexAI,
+			`{"model":"m","messages":[],"tools":[{"type":"function","function":{"name":"f","parameters":"{}"}}]}`, ErrCodeInvalidToolSchema, "tools[0].function.parameters"},
+		{"tool parameters properties", config.ProviderTypeVertexAI,
+			`{"model":"m","messages":[],"tools":[{"type":"function","function":{"name":"f","parameters":{"type":"object","properties":[]}}}]}`,
+			ErrCodeInvalidToolSchema, "tools[0].function.parameters.properties"},
+		{"provider tool type", config.ProviderTypeAnthropic,
+			`{"model":"m","messages":[],"tools":[{"type":"code_execution"}]}`, ErrCodeUnsupportedParam, "tools[0].type"},
+		{"tool choice", config.ProviderTypeGemini, `{"model":"m","messages":[],"tool_choice":{"type":"allowed_tools"}}`, ErrCodeUnsupportedParam, "tool_choice.type"},
+		{"tool choice without name", config.ProviderTypeGemini, `{"model":"m","messages":[],"tool_choice":{"type":"function"}}`, ErrCodeInvalidRequest, "tool_choice.function.name"},
+		{"response format", config.ProviderTypeAnthropic, `{"model":"m","messages":[],"response_format":{"type":"grammar"}}`, ErrCodeUnsupportedParam, "response_format.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.provider, RequestMode{ModelID: "m"}).RequestFrom(context.Background(), []byte(tt.body))
+			if tt.code == "" {
+				require.NoError(t, err)
+				return
+			}
+			reqErr := AsRequestError(err)
+			require.NotNil(t, reqErr, "error %v", err)
+			assert.Equal(t, tt.code, reqErr.Code)
+			assert.Equal(t, tt.param, reqErr.Param)
+		})
+	}
+}
+
+func TestRequestFrom_PassthroughNotChecked(t *testing.T) {
+	body := []byte(`{"model":"m","messages":[{"role":"user","content":[{"type":"input_file"}]}]}`)
+	for _, provider := range []config.ProviderType{config.ProviderTypeOpenAI, config.ProviderTypeProxy} {
+		out, err := New(provider, RequestMode{}).RequestFrom(context.Background(), body)
+		require.NoError(t, err)
+		assert.Equal(t, body, out)
+	}
+}
+
+func TestRequestFrom_EmbeddingInput(t *testing.T) {
+	conv := New(config.ProviderTypeGemini, RequestMode{IsEmbeddings: true, ModelID: "text-embedding-004"})
+
+	_, err := conv.RequestFrom(context.Background(), []byte(`{"input":["a","b"]}`))
+	require.NoError(t, err)
+
+	_, err = conv.RequestFrom(context.Background(), []byte(`{"input":[[1,2,3]]}`))
+	reqErr := AsRequestError(err)
+	require.NotNil(t, reqErr)
+	assert.Equal(t, ErrCodeUnsupportedParam, reqErr.Code)
+	assert.Equal(t, "input[0]", reqErr.Param)
+
+	_, err = conv.RequestFrom(context.Background(), []byte(`{}`))
+	assert.Equal(t, ErrCodeInvalidRequest, AsRequestError(err).Code)
+}
+
+func TestRequestError_StatusAndUnwrap(t *testing.T) {
+	invalid := &RequestError{Code: ErrCodeInvalidRequest, Message: "body is not a valid JSON object"}
+	assert.Equal(t, http.StatusBadRequest, invalid.StatusCode())
+	assert.Equal(t, "body is not a valid JSON object", invalid.Error())
+
+	unsupported := &RequestError{Code: ErrCodeUnsupportedParam, Param: "tool_choice", Message: "is not supported"}
+	assert.Equal(t, http.StatusUnprocessableEntity, unsupported.StatusCode())
+	assert.Equal(t, "tool_choice: is not supported", unsupported.Error())
+
+	assert.Same(t, unsupported, AsRequestError(fmt.Errorf("convert: %w", unsupported)))
+	assert.Nil(t, AsRequestError(errors.New("internal")))
+	assert.Nil(t, AsRequestError(nil))
+}
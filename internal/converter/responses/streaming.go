@@ -20,6 +20,7 @@ const (
 
 // streamAccumulator accumulates data across streaming chunks.
 type streamAccumulator struct {
+	logger     *slog.Logger
 	responseID string
 	model      string
 	createdAt  int64
@@ -99,11 +100,16 @@ type chatStreamChunk struct {
 
 // TransformChatStreamToResponses reads Chat Completions SSE from reader,
 // transforms to Responses API SSE events, and writes to writer.
-func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model string) error {
+// Debug lines go to logger (nil = slog.Default()).
+func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model string, logger *slog.Logger) error {
 	scanner := bufio.NewScanner(reader)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
+	if logger == nil {
+		logger = slog.Default()
+	}
 	acc := &streamAccumulator{
+		logger:     logger,
 		responseID: generateResponseID(),
 		model:      model,
 	}
@@ -115,18 +121,18 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 
 		if !strings.HasPrefix(line, "data: ") {
 			if line != "" {
-				slog.Debug("[responses/streaming] skipping non-data line",
+				logger.Debug("[responses/streaming] skipping non-data line",
 					"line_num", lineCount, "line_prefix", truncate(line, 80), "line_len", len(line))
 			}
 			continue
 		}
 
 		data := strings.TrimPrefix(line, "data: ")
-		slog.Debug("[responses/streaming] received SSE data line",
+		logger.Debug("[responses/streaming] received SSE data line",
 			"line_num", lineCount, "data_prefix", truncate(data, 200))
 
 		if data == "[DONE]" {
-			slog.Debug("[responses/streaming] received [DONE], emitting completion events",
+			logger.Debug("[responses/streaming] received [DONE], emitting completion events",
 				"has_usage", acc.usage != nil, "full_text_len", len(acc.fullText),
 				"tool_calls", len(acc.toolCalls), "header_emitted", acc.headerEmitted,
 				"message_started", acc.messageStarted)
@@ -140,12 +146,12 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 
 		var chunk chatStreamChunk
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-			slog.Debug("[responses/streaming] failed to parse chunk JSON",
+			logger.Debug("[responses/streaming] failed to parse chunk JSON",
 				"error", err, "data_prefix", truncate(data, 200))
 			continue
 		}
 
-		slog.Debug("[responses/streaming] parsed chunk",
+		logger.Debug("[responses/streaming] parsed chunk",
 			"choices", len(chunk.Choices), "has_usage", chunk.Usage != nil,
 			"model", chunk.Model, "id", chunk.ID)
 
@@ -180,7 +186,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 
 		// Handle finish_reason
 		if choice.FinishReason != nil {
-			slog.Debug("[responses/streaming] finish_reason received",
+			logger.Debug("[responses/streaming] finish_reason received",
 				"reason", *choice.FinishReason,
 				"accumulated_text_len", len(acc.fullText),
 				"tool_calls", len(acc.toolCalls))
@@ -190,7 +196,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 
 		// Handle text content delta
 		if choice.Delta.Content != "" {
-			slog.Debug("[responses/streaming] text delta",
+			logger.Debug("[responses/streaming] text delta",
 				"content_len", len(choice.Delta.Content),
 				"header_emitted", acc.headerEmitted,
 				"message_started", acc.messageStarted)
@@ -215,7 +221,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 				"content_index": 0,
 				"delta":         choice.Delta.Content,
 			}
-			if err := writeSSE(writer, "response.output_text.delta", deltaEvent); err != nil {
+			if err := writeSSE(writer, logger, "response.output_text.delta", deltaEvent); err != nil {
 				return err
 			}
 		}
@@ -260,7 +266,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 						"status":    "in_progress",
 					},
 				}
-				if err := writeSSE(writer, "response.output_item.added", itemAddedEvent); err != nil {
+				if err := writeSSE(writer, logger, "response.output_item.added", itemAddedEvent); err != nil {
 					return err
 				}
 			}
@@ -281,7 +287,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 					"output_index": outputIndex,
 					"delta":        tc.Function.Arguments,
 				}
-				if err := writeSSE(writer, "response.function_call_arguments.delta", argDeltaEvent); err != nil {
+				if err := writeSSE(writer, logger, "response.function_call_arguments.delta", argDeltaEvent); err != nil {
 					return err
 				}
 			}
@@ -289,11 +295,11 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 	}
 
 	if err := scanner.Err(); err != nil {
-		slog.Error("[responses/streaming] scanner error", "error", err)
+		logger.Error("[responses/streaming] scanner error", "error", err)
 		return fmt.Errorf("scanner error: %w", err)
 	}
 
-	slog.Debug("[responses/streaming] scanner finished",
+	logger.Debug("[responses/streaming] scanner finished",
 		"lines_read", lineCount, "completed", acc.completed,
 		"header_emitted", acc.headerEmitted, "message_started", acc.messageStarted,
 		"full_text_len", len(acc.fullText), "tool_calls", len(acc.toolCalls),
@@ -302,7 +308,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 	// If the stream ended without [DONE] (e.g., connection dropped),
 	// still emit completion events so the client gets a proper ending.
 	if acc.headerEmitted && !acc.completed {
-		slog.Debug("[responses/streaming] stream ended without [DONE], emitting fallback completion")
+		logger.Debug("[responses/streaming] stream ended without [DONE], emitting fallback completion")
 		if err := emitCompletionEvents(writer, acc); err != nil {
 			return err
 		}
@@ -311,7 +317,7 @@ func TransformChatStreamToResponses(reader io.Reader, writer io.Writer, model st
 	// If no header events were emitted, emit them now along with completion
 	// to ensure the client receives a valid response even if no data was received
 	if !acc.headerEmitted {
-		slog.Warn("[responses/streaming] stream ended without emitting any events, emitting empty response",
+		logger.Warn("[responses/streaming] stream ended without emitting any events, emitting empty response",
 			"lines_read", lineCount, "completed", acc.completed)
 		if err := emitHeaderEvents(writer, acc); err != nil {
 			return err
@@ -342,7 +348,7 @@ func emitHeaderEvents(w io.Writer, acc *streamAccumulator) error {
 		"type":     "response.created",
 		"response": respObj,
 	}
-	if err := writeSSE(w, "response.created", createdEvent); err != nil {
+	if err := writeSSE(w, acc.logger, "response.created", createdEvent); err != nil {
 		return err
 	}
 
@@ -350,7 +356,7 @@ func emitHeaderEvents(w io.Writer, acc *streamAccumulator) error {
 		"type":     "response.in_progress",
 		"response": respObj,
 	}
-	return writeSSE(w, "response.in_progress", inProgressEvent)
+	return writeSSE(w, acc.logger, "response.in_progress", inProgressEvent)
 }
 
 // emitMessageStartEvents emits output_item.added and content_part.added for a message.
@@ -371,7 +377,7 @@ func emitMessageStartEvents(w io.Writer, acc *streamAccumulator) error {
 			"content": []interface{}{},
 		},
 	}
-	if err := writeSSE(w, "response.output_item.added", itemAddedEvent); err != nil {
+	if err := writeSSE(w, acc.logger, "response.output_item.added", itemAddedEvent); err != nil {
 		return err
 	}
 
@@ -385,7 +391,7 @@ func emitMessageStartEvents(w io.Writer, acc *streamAccumulator) error {
 			"annotations": []interface{}{},
 		},
 	}
-	return writeSSE(w, "response.content_part.added", contentPartEvent)
+	return writeSSE(w, acc.logger, "response.content_part.added", contentPartEvent)
 }
 
 // emitCompletionEvents emits all closing events and the final response.completed.
@@ -399,7 +405,7 @@ func emitCompletionEvents(w io.Writer, acc *streamAccumulator) error {
 			"content_index": 0,
 			"text":          acc.fullText,
 		}
-		if err := writeSSE(w, "response.output_text.done", textDoneEvent); err != nil {
+		if err := writeSSE(w, acc.logger, "response.output_text.done", textDoneEvent); err != nil {
 			return err
 		}
 
@@ -414,7 +420,7 @@ func emitCompletionEvents(w io.Writer, acc *streamAccumulator) error {
 				"annotations": []interface{}{},
 			},
 		}
-		if err := writeSSE(w, "response.content_part.done", contentPartDoneEvent); err != nil {
+		if err := writeSSE(w, acc.logger, "response.content_part.done", contentPartDoneEvent); err != nil {
 			return err
 		}
 
@@ -436,7 +442,7 @@ func emitCompletionEvents(w io.Writer, acc *streamAccumulator) error {
 				},
 			},
 		}
-		if err := writeSSE(w, "response.output_item.done", msgDoneEvent); err != nil {
+		if err := writeSSE(w, acc.logger, "response.output_item.done", msgDoneEvent); err != nil {
 			return err
 		}
 	}
@@ -454,7 +460,7 @@ func emitCompletionEvents(w io.Writer, acc *streamAccumulator) error {
 			"output_index": outputIndex,
 			"arguments":    tc.arguments,
 		}
-		if err := writeSSE(w, "response.function_call_arguments.done", argsDoneEvent); err != nil {
+		if err := writeSSE(w, acc.logger, "response.function_call_arguments.done", argsDoneEvent); err != nil {
 			return err
 		}
 
@@ -471,7 +477,7 @@ func emitCompletionEvents(w io.Writer, acc *streamAccumulator) error {
 				"status":    "completed",
 			},
 		}
-		if err := writeSSE(w, "response.output_item.done", fcDoneEvent); err != nil {
+		if err := writeSSE(w, acc.logger, "response.output_item.done", fcDoneEvent); err != nil {
 			return err
 		}
 	}
@@ -482,7 +488,7 @@ func emitCompletionEvents(w io.Writer, acc *streamAccumulator) error {
 		"type":     "response.completed",
 		"response": completedResp,
 	}
-	return writeSSE(w, "response.completed", completedEvent)
+	return writeSSE(w, acc.logger, "response.completed", completedEvent)
 }
 
 // buildInProgressResponse builds the response object for in-progress events.
@@ -572,16 +578,16 @@ func buildCompletedResponse(acc *streamAccumulator) map[string]interface{} {
 }
 
 // writeSSE writes a single SSE event to the writer.
-func writeSSE(w io.Writer, eventType string, data interface{}) error {
+func writeSSE(w io.Writer, logger *slog.Logger, eventType string, data interface{}) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal SSE data: %w", err)
 	}
-	slog.Debug("[responses/streaming] writeSSE",
+	logger.Debug("[responses/streaming] writeSSE",
 		"event", eventType, "data_len", len(jsonData))
 	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, jsonData)
 	if err != nil {
-		slog.Error("[responses/streaming] writeSSE failed",
+		logger.Error("[responses/streaming] writeSSE failed",
 			"event", eventType, "error", err)
 	}
 	return err
@@ -139,7 +139,7 @@ func TestStreamTransform_BasicText(t *testing.T) {
 		"data: [DONE]\n\n"
 
 	var output bytes.Buffer
-	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o")
+	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o", nil)
 	require.NoError(t, err)
 
 	result := output.String()
@@ -162,7 +162,7 @@ func TestStreamTransform_EventSequence(t *testing.T) {
 		"data: [DONE]\n\n"
 
 	var output bytes.Buffer
-	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o")
+	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o", nil)
 	require.NoError(t, err)
 
 	result := output.String()
@@ -201,7 +201,7 @@ func TestStreamTransform_Usage(t *testing.T) {
 		"data: [DONE]\n\n"
 
 	var output bytes.Buffer
-	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o")
+	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o", nil)
 	require.NoError(t, err)
 
 	result := output.String()
@@ -242,7 +242,7 @@ func TestStreamTransform_ConsistentMessageIDs(t *testing.T) {
 		"data: [DONE]\n\n"
 
 	var output bytes.Buffer
-	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o")
+	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o", nil)
 	require.NoError(t, err)
 
 	result := output.String()
@@ -303,7 +303,7 @@ func TestStreamTransform_NoDoneEmitsCompletion(t *testing.T) {
 	// No [DONE] — connection dropped
 
 	var output bytes.Buffer
-	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o")
+	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o", nil)
 	require.NoError(t, err)
 
 	result := output.String()
@@ -335,7 +335,7 @@ func TestStreamTransform_ToolCall(t *testing.T) {
 		"data: [DONE]\n\n"
 
 	var output bytes.Buffer
-	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o")
+	err := TransformChatStreamToResponses(strings.NewReader(input), &output, "gpt-4o", nil)
 	require.NoError(t, err)
 
 	result := output.String()
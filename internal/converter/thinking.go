@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// applyThinkingBudget replaces the reasoning_effort of a request with the thinking budget
+// configured for it (reasoning_params): extra_body.thinking, which the Vertex AI, Gemini,
+// Anthropic and Bedrock conversions prefer over their built-in effort budgets. A budget of 0
+// turns thinking off (reasoning_effort "none"). Thinking set by the client is kept.
+func applyThinkingBudget(body []byte, budgets map[string]int) []byte {
+	if len(budgets) == 0 {
+		return body
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body
+	}
+	effort, _ := req["reasoning_effort"].(string)
+	budget, ok := budgets[strings.ToLower(strings.TrimSpace(effort))]
+	if !ok || req["thinking"] != nil {
+		return body
+	}
+	extraBody, _ := req["extra_body"].(map[string]interface{})
+	if extraBody["thinking"] != nil {
+		return body
+	}
+
+	if budget == 0 {
+		req["reasoning_effort"] = "none"
+	} else {
+		if extraBody == nil {
+			extraBody = make(map[string]interface{})
+		}
+		extraBody["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		req["extra_body"] = extraBody
+	}
+	out, err := json.Marshal(req)
+	if err != nil {
+		return body
+	}
+	return out
+}
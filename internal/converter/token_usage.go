@@ -15,6 +15,7 @@ type TokenUsage struct {
 	RejectedPredictionTokens int
 	ImageCount               int // Number of images to generate (1-10)
 	ImageTokens              int // Token count for image processing
+	WebSearchRequests        int // Server-side web searches (Anthropic), billed per query
 }
 
 // Total returns the sum of prompt and completion tokens.
@@ -36,5 +37,6 @@ type TokenCosts struct {
 	CachedOutputCost float64
 	PredictionCost   float64
 	ImageCost        float64
+	WebSearchCost    float64
 	TotalCost        float64
 }
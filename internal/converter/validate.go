@@ -0,0 +1,226 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+// ErrMalformedConversion is wrapped by the errors of ValidateRequest
+var ErrMalformedConversion = errors.New("malformed converted request")
+
+// vertexPartData are the fields of a Vertex part carrying content; a part without any is
+// rejected by the API (or, worse, silently ignored)
+var vertexPartData = []string{
+	"text", "inlineData", "fileData", "functionCall", "functionResponse", "executableCode", "codeExecutionResult",
+}
+
+// ValidateRequest schema-checks a request body produced by RequestFrom, catching conversions
+// that lost content (empty contents, parts or blocks) or are missing required fields before
+// they reach the provider. Passthrough, embeddings and image generation requests are not checked.
+func (c *ProviderConverter) ValidateRequest(body []byte) error {
+	if c.mode.IsEmbeddings || c.mode.IsImageGeneration {
+		return nil
+	}
+	var req map[string]interface{}
+	switch c.providerType {
+	case config.ProviderTypeVertexAI, config.ProviderTypeGemini:
+		if err := json.Unmarshal(body, &req); err != nil {
+			return malformed("body", "not a JSON object")
+		}
+		return validateVertexRequest(req)
+	case config.ProviderTypeAnthropic, config.ProviderTypeBedrock:
+		if err := json.Unmarshal(body, &req); err != nil {
+			return malformed("body", "not a JSON object")
+		}
+		return validateAnthropicRequest(req, c.providerType == config.ProviderTypeAnthropic)
+	default:
+		return nil
+	}
+}
+
+func malformed(path, problem string) error {
+	return fmt.Errorf("%w: %s %s", ErrMalformedConversion, path, problem)
+}
+
+func validateVertexRequest(req map[string]interface{}) error {
+	contents, ok := req["contents"].([]interface{})
+	if !ok || len(contents) == 0 {
+		return malformed("contents", "is empty")
+	}
+	for i, entry := range contents {
+		path := fmt.Sprintf("contents[%d]", i)
+		content, ok := entry.(map[string]interface{})
+		if !ok {
+			return malformed(path, "is not an object")
+		}
+		if role, _ := content["role"].(string); role != "user" && role != "model" {
+			return malformed(path+".role", fmt.Sprintf("is %q, expected user or model", role))
+		}
+		if err := validateVertexParts(content, path); err != nil {
+			return err
+		}
+	}
+
+	if system, ok := req["systemInstruction"]; ok && system != nil {
+		content, ok := system.(map[string]interface{})
+		if !ok {
+			return malformed("systemInstruction", "is not an object")
+		}
+		if err := validateVertexParts(content, "systemInstruction"); err != nil {
+			return err
+		}
+	}
+
+	tools, _ := req["tools"].([]interface{})
+	for i, entry := range tools {
+		tool, _ := entry.(map[string]interface{})
+		declarations, _ := tool["functionDeclarations"].([]interface{})
+		for j, decl := range declarations {
+			if name, _ := decl.(map[string]interface{})["name"].(string); name == "" {
+				return malformed(fmt.Sprintf("tools[%d].functionDeclarations[%d].name", i, j), "is empty")
+			}
+		}
+	}
+	return nil
+}
+
+func validateVertexParts(content map[string]interface{}, path string) error {
+	parts, ok := content["parts"].([]interface{})
+	if !ok || len(parts) == 0 {
+		return malformed(path+".parts", "is empty")
+	}
+	for j, entry := range parts {
+		partPath := fmt.Sprintf("%s.parts[%d]", path, j)
+		part, ok := entry.(map[string]interface{})
+		if !ok {
+			return malformed(partPath, "is not an object")
+		}
+		data := ""
+		for _, field := range vertexPartData {
+			if value, ok := part[field]; ok && value != nil {
+				data = field
+				break
+			}
+		}
+		switch data {
+		case "":
+			return malformed(partPath, "has no content")
+		case "inlineData":
+			blob, _ := part["inlineData"].(map[string]interface{})
+			if mimeType, _ := blob["mimeType"].(string); mimeType == "" {
+				return malformed(partPath+".inlineData.mimeType", "is empty")
+			}
+			if value, _ := blob["data"].(string); value == "" {
+				return malformed(partPath+".inlineData.data", "is empty")
+			}
+		case "fileData":
+			file, _ := part["fileData"].(map[string]interface{})
+			if uri, _ := file["fileUri"].(string); uri == "" {
+				return malformed(partPath+".fileData.fileUri", "is empty")
+			}
+		case "functionCall", "functionResponse":
+			call, _ := part[data].(map[string]interface{})
+			if name, _ := call["name"].(string); name == "" {
+				return malformed(partPath+"."+data+".name", "is empty")
+			}
+		}
+	}
+	return nil
+}
+
+// validateAnthropicRequest checks a Messages API body; Bedrock bodies carry no model
+func validateAnthropicRequest(req map[string]interface{}, requireModel bool) error {
+	if model, _ := req["model"].(string); requireModel && model == "" {
+		return malformed("model", "is empty")
+	}
+	if maxTokens, _ := req["max_tokens"].(float64); maxTokens < 1 || maxTokens != float64(int64(maxTokens)) {
+		return malformed("max_tokens", "is not a positive integer")
+	}
+	messages, ok := req["messages"].([]interface{})
+	if !ok || len(messages) == 0 {
+		return malformed("messages", "is empty")
+	}
+	for i, entry := range messages {
+		path := fmt.Sprintf("messages[%d]", i)
+		message, ok := entry.(map[string]interface{})
+		if !ok {
+			return malformed(path, "is not an object")
+		}
+		if role, _ := message["role"].(string); role != "user" && role != "assistant" {
+			return malformed(path+".role", fmt.Sprintf("is %q, expected user or assistant", role))
+		}
+		if err := validateAnthropicContent(message["content"], path+".content"); err != nil {
+			return err
+		}
+	}
+
+	if system, ok := req["system"].([]interface{}); ok {
+		if err := validateAnthropicBlocks(system, "system"); err != nil {
+			return err
+		}
+	}
+
+	tools, _ := req["tools"].([]interface{})
+	for i, entry := range tools {
+		if name, _ := entry.(map[string]interface{})["name"].(string); name == "" {
+			return malformed(fmt.Sprintf("tools[%d].name", i), "is empty")
+		}
+	}
+	return nil
+}
+
+func validateAnthropicContent(content interface{}, path string) error {
+	switch content := content.(type) {
+	case string:
+		if content == "" {
+			return malformed(path, "is empty")
+		}
+		return nil
+	case []interface{}:
+		if len(content) == 0 {
+			return malformed(path, "is empty")
+		}
+		return validateAnthropicBlocks(content, path)
+	default:
+		return malformed(path, "is neither a string nor a list of blocks")
+	}
+}
+
+func validateAnthropicBlocks(blocks []interface{}, path string) error {
+	for j, entry := range blocks {
+		blockPath := fmt.Sprintf("%s[%d]", path, j)
+		block, ok := entry.(map[string]interface{})
+		if !ok {
+			return malformed(blockPath, "is not an object")
+		}
+		blockType, _ := block["type"].(string)
+		switch blockType {
+		case "":
+			return malformed(blockPath+".type", "is empty")
+		case "text":
+			// The API rejects empty text blocks
+			if text, _ := block["text"].(string); text == "" {
+				return malformed(blockPath+".text", "is empty")
+			}
+		case "image", "document":
+			if _, ok := block["source"].(map[string]interface{}); !ok {
+				return malformed(blockPath+".source", "is missing")
+			}
+		case "tool_use":
+			if id, _ := block["id"].(string); id == "" {
+				return malformed(blockPath+".id", "is empty")
+			}
+			if name, _ := block["name"].(string); name == "" {
+				return malformed(blockPath+".name", "is empty")
+			}
+		case "tool_result":
+			if id, _ := block["tool_use_id"].(string); id == "" {
+				return malformed(blockPath+".tool_use_id", "is empty")
+			}
+		}
+	}
+	return nil
+}
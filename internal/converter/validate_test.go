@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderConverter_ValidateRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider config.ProviderType
+		body     string
+		problem  string // Expected in the error, "" = valid
+	}{
+		{"vertex valid", config.ProviderTypeVertexAI,
+			`{"contents":[{"role":"user","parts":[{"text":"hi"}]},{"role":"model","parts":[{"functionCall":{"name":"f","args":{}}}]}],"systemInstruction":{"parts":[{"text":"be brief"}]}}`, ""},
+		{"vertex no contents", config.ProviderTypeGemini, `{"contents":[]}`, "contents is empty"},
+		{"vertex empty parts", config.ProviderTypeVertexAI, `{"contents":[{"role":"model"}]}`, "contents[0].parts is empty"},
+		{"vertex empty part", config.ProviderTypeVertexAI, `{"contents":[{"role":"user","parts":[{"thoughtSignature":"c2ln"}]}]}`, "contents[0].parts[0] has no content"},
+		{"vertex bad role", config.ProviderTypeVertexAI, `{"contents":[{"role":"assistant","parts":[{"text":"hi"}]}]}`, `contents[0].role is "assistant"`},
+		{"vertex inline data without mime type", config.ProviderTypeVertexAI,
+			`{"contents":[{"role":"user","parts":[{"inlineData":{"data":"AAAA"}}]}]}`, "inlineData.mimeType is empty"},
+		{"vertex unnamed function response", config.ProviderTypeVertexAI,
+			`{"contents":[{"role":"user","parts":[{"functionResponse":{"response":{}}}]}]}`, "functionResponse.name is empty"},
+		{"vertex unnamed tool", config.ProviderTypeVertexAI,
+			`{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"tools":[{"functionDeclarations":[{"description":"x"}]}]}`, "tools[0].functionDeclarations[0].name is empty"},
+		{"vertex empty system instruction", config.ProviderTypeVertexAI,
+			`{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"systemInstruction":{"parts":[]}}`, "systemInstruction.parts is empty"},
+		{"vertex not json", config.ProviderTypeVertexAI, `[`, "body not a JSON object"},
+
+		{"anthropic valid", config.ProviderTypeAnthropic,
+			`{"model":"claude","max_tokens":10,"system":[{"type":"text","text":"be brief"}],"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"f","input":{}}]},{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":""}]}]}`, ""},
+		{"bedrock valid without model", config.ProviderTypeBedrock,
+			`{"anthropic_version":"bedrock-2023-05-31","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`, ""},
+		{"anthropic without model", config.ProviderTypeAnthropic, `{"max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`, "model is empty"},
+		{"anthropic zero max tokens", config.ProviderTypeAnthropic, `{"model":"claude","max_tokens":0,"messages":[{"role":"user","content":"hi"}]}`, "max_tokens is not a positive integer"},
+		{"anthropic no messages", config.ProviderTypeAnthropic, `{"model":"claude","max_tokens":10,"messages":[]}`, "messages is empty"},
+		{"anthropic empty content", config.ProviderTypeAnthropic, `{"model":"claude","max_tokens":10,"messages":[{"role":"user","content":""}]}`, "messages[0].content is empty"},
+		{"anthropic empty text block", config.ProviderTypeAnthropic,
+			`{"model":"claude","max_tokens":10,"messages":[{"role":"user","content":[{"type":"text","text":""}]}]}`, "messages[0].content[0].text is empty"},
+		{"anthropic image without source", config.ProviderTypeAnthropic,
+			`{"model":"claude","max_tokens":10,"messages":[{"role":"user","content":[{"type":"image"}]}]}`, "messages[0].content[0].source is missing"},
+		{"anthropic tool result without id", config.ProviderTypeBedrock,
+			`{"max_tokens":10,"messages":[{"role":"user","content":[{"type":"tool_result","content":"x"}]}]}`, "tool_use_id is empty"},
+		{"anthropic bad role", config.ProviderTypeAnthropic, `{"model":"claude","max_tokens":10,"messages":[{"role":"system","content":"hi"}]}`, `messages[0].role is "system"`},
+		{"anthropic unnamed tool", config.ProviderTypeAnthropic,
+			`{"model":"claude","max_tokens":10,"messages":[{"role":"user","content":"hi"}],"tools":[{"input_schema":{}}]}`, "tools[0].name is empty"},
+
+		{"passthrough not checked", config.ProviderTypeOpenAI, `not json`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New(tt.provider, RequestMode{}).ValidateRequest([]byte(tt.body))
+			if tt.problem == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrMalformedConversion)
+			assert.Contains(t, err.Error(), tt.problem)
+		})
+	}
+
+	// Embeddings and image generation bodies have other schemas
+	assert.NoError(t, New(config.ProviderTypeVertexAI, RequestMode{IsEmbeddings: true}).ValidateRequest([]byte(`{"instances":[]}`)))
+	assert.NoError(t, New(config.ProviderTypeVertexAI, RequestMode{IsImageGeneration: true}).ValidateRequest([]byte(`{"instances":[]}`)))
+}
+
+func TestProviderConverter_ValidateRequest_ConvertedRequests(t *testing.T) {
+	body := `{"model":"m","messages":[` +
+		`{"role":"system","content":"be brief"},` +
+		`{"role":"user","content":[{"type":"text","text":"what is in the image?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]},` +
+		`{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":\"x\"}"}}]},` +
+		`{"role":"tool","tool_call_id":"call_1","content":"{\"result\":1}"},` +
+		`{"role":"user","content":"thanks"}],` +
+		`"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object"}}}]}`
+
+	for _, provider := range []config.ProviderType{config.ProviderTypeVertexAI, config.ProviderTypeGemini, config.ProviderTypeAnthropic, config.ProviderTypeBedrock} {
+		t.Run(string(provider), func(t *testing.T) {
+			conv := New(provider, RequestMode{ModelID: "model-1"})
+			converted, err := conv.RequestFrom(context.Background(), []byte(body))
+			require.NoError(t, err)
+			assert.NoError(t, conv.ValidateRequest(converted))
+		})
+	}
+}
@@ -244,19 +244,9 @@ func GeminiEmbeddingToOpenAI(body []byte, model string) ([]byte, error) {
 }
 
 // BuildVertexEmbeddingURL constructs the Vertex AI URL for embeddings.
-// Format: https://{location}-aiplatform.googleapis.com/v1beta1/projects/{project}/locations/{location}/publishers/google/models/{model}:predict
+// Format: https://{location}-aiplatform.googleapis.com/{predict_api_version}/projects/{project}/locations/{location}/publishers/google/models/{model}:predict
 func BuildVertexEmbeddingURL(cred *config.CredentialConfig, modelID string) string {
-	if cred.Location == "global" {
-		return fmt.Sprintf(
-			"https://aiplatform.googleapis.com/v1beta1/projects/%s/locations/global/publishers/google/models/%s:predict",
-			cred.ProjectID, modelID,
-		)
-	}
-
-	return fmt.Sprintf(
-		"https://%s-aiplatform.googleapis.com/v1beta1/projects/%s/locations/%s/publishers/google/models/%s:predict",
-		cred.Location, cred.ProjectID, cred.Location, modelID,
-	)
+	return buildVertexPredictURL(cred, modelID)
 }
 
 // BuildGeminiEmbeddingURL constructs the Gemini API URL for embeddings.
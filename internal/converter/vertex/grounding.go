@@ -0,0 +1,112 @@
+package vertex
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"google.golang.org/genai"
+)
+
+// extraBodyTools returns the native Vertex tools of extra_body.tools, e.g.
+// {"googleSearchRetrieval": {...}} or {"retrieval": {"vertexAiSearch": {"datastore": "..."}}}.
+// Entries that are not Vertex tool objects are skipped.
+func extraBodyTools(extraBody map[string]interface{}) []*genai.Tool {
+	rawTools, ok := extraBody["tools"].([]interface{})
+	if !ok {
+		return nil
+	}
+	var tools []*genai.Tool
+	for _, rawTool := range rawTools {
+		if _, ok := rawTool.(map[string]interface{}); !ok {
+			continue
+		}
+		data, err := json.Marshal(rawTool)
+		if err != nil {
+			continue
+		}
+		var tool genai.Tool
+		if err := json.Unmarshal(data, &tool); err != nil {
+			continue
+		}
+		// No known field decoded: not a Vertex tool
+		if encoded, err := json.Marshal(&tool); err != nil || string(encoded) == "{}" {
+			continue
+		}
+		tools = append(tools, &tool)
+	}
+	return tools
+}
+
+// extraBodyRetrievalConfig returns extra_body.tool_config.retrievalConfig (user location and
+// language for grounding), also accepted as retrieval_config
+func extraBodyRetrievalConfig(extraBody map[string]interface{}) *genai.RetrievalConfig {
+	toolConfig, ok := extraBody["tool_config"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	rawConfig, ok := toolConfig["retrievalConfig"]
+	if !ok {
+		rawConfig, ok = toolConfig["retrieval_config"]
+	}
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(rawConfig)
+	if err != nil {
+		return nil
+	}
+	var config genai.RetrievalConfig
+	if err := json.Unmarshal(data, &config); err != nil || (config.LatLng == nil && config.LanguageCode == "") {
+		return nil
+	}
+	return &config
+}
+
+// convertGroundingMetadata converts the grounding chunks of a candidate to citations, with the
+// answer segments each chunk supports. Returns nil for ungrounded candidates.
+func convertGroundingMetadata(meta *genai.GroundingMetadata) []openai.Citation {
+	if meta == nil || len(meta.GroundingChunks) == 0 {
+		return nil
+	}
+
+	citations := make([]openai.Citation, 0, len(meta.GroundingChunks))
+	for _, chunk := range meta.GroundingChunks {
+		var citation openai.Citation
+		switch {
+		case chunk == nil:
+		case chunk.Web != nil:
+			citation = openai.Citation{URL: chunk.Web.URI, Title: chunk.Web.Title, Domain: chunk.Web.Domain}
+		case chunk.RetrievedContext != nil:
+			rc := chunk.RetrievedContext
+			citation = openai.Citation{URL: rc.URI, Title: rc.Title, Text: rc.Text}
+		case chunk.Maps != nil:
+			citation = openai.Citation{URL: chunk.Maps.URI, Title: chunk.Maps.Title, Text: chunk.Maps.Text}
+		}
+		citations = append(citations, citation)
+	}
+
+	for _, support := range meta.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		for i, index := range support.GroundingChunkIndices {
+			if index < 0 || int(index) >= len(citations) {
+				continue
+			}
+			segment := openai.CitationSegment{
+				Text:       support.Segment.Text,
+				StartIndex: int(support.Segment.StartIndex),
+				EndIndex:   int(support.Segment.EndIndex),
+			}
+			// Gemini 2.5+ returns no confidence scores
+			if i < len(support.ConfidenceScores) {
+				// Shortest float32 form, so 0.9 is not returned as 0.8999999761581421
+				confidence, _ := strconv.ParseFloat(strconv.FormatFloat(float64(support.ConfidenceScores[i]), 'g', -1, 32), 64)
+				segment.Confidence = &confidence
+			}
+			citations[index].Segments = append(citations[index].Segments, segment)
+		}
+	}
+	return citations
+}
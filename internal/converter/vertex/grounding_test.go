@@ -0,0 +1,128 @@
+package vertex
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const groundedResponse = `{
+	"candidates": [{
+		"content": {"role": "model", "parts": [{"text": "Spain won Euro 2024. The final was in Berlin."}]},
+		"finishReason": "STOP",
+		"groundingMetadata": {
+			"webSearchQueries": ["euro 2024 winner"],
+			"groundingChunks": [
+				{"web": {"uri": "https://example.com/euro", "title": "example.com", "domain": "example.com"}},
+				{"retrievedContext": {"uri": "gs://docs/final.pdf", "title": "final.pdf", "text": "The final took place in Berlin."}}
+			],
+			"groundingSupports": [
+				{"segment": {"startIndex": 0, "endIndex": 19, "text": "Spain won Euro 2024."}, "groundingChunkIndices": [0], "confidenceScores": [0.9]},
+				{"segment": {"startIndex": 20, "endIndex": 45, "text": "The final was in Berlin."}, "groundingChunkIndices": [0, 1, 7]}
+			]
+		}
+	}]
+}`
+
+func TestVertexToOpenAI_GroundingCitations(t *testing.T) {
+	out, err := VertexToOpenAI([]byte(groundedResponse), "gemini-2.5-flash")
+	require.NoError(t, err)
+
+	var resp openai.OpenAIResponse
+	require.NoError(t, json.Unmarshal(out, &resp))
+	require.Len(t, resp.Choices, 1)
+	confidence := 0.9
+	assert.Equal(t, []openai.Citation{
+		{URL: "https://example.com/euro", Title: "example.com", Domain: "example.com", Segments: []openai.CitationSegment{
+			{Text: "Spain won Euro 2024.", StartIndex: 0, EndIndex: 19, Confidence: &confidence},
+			{Text: "The final was in Berlin.", StartIndex: 20, EndIndex: 45},
+		}},
+		{URL: "gs://docs/final.pdf", Title: "final.pdf", Text: "The final took place in Berlin.", Segments: []openai.CitationSegment{
+			{Text: "The final was in Berlin.", StartIndex: 20, EndIndex: 45},
+		}},
+	}, resp.Choices[0].Message.Citations)
+
+	// Ungrounded answers have no citations field
+	out, err = VertexToOpenAI([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"finishReason":"STOP"}]}`), "gemini-2.5-flash")
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "citations")
+}
+
+func TestTransformVertexStreamToOpenAI_GroundingCitations(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Spain won "}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Euro 2024."}]},"finishReason":"STOP","groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://example.com/euro","title":"example.com"}}],"groundingSupports":[{"segment":{"endIndex":19,"text":"Spain won Euro 2024."},"groundingChunkIndices":[0]}]}}]}`,
+		"",
+	}, "\n")
+
+	var output bytes.Buffer
+	require.NoError(t, TransformVertexStreamToOpenAI(strings.NewReader(stream), "gemini-2.5-flash", &output, nil))
+
+	var chunks []openai.OpenAIStreamingChunk
+	for _, line := range strings.Split(output.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") || line == "data: [DONE]" {
+			continue
+		}
+		var chunk openai.OpenAIStreamingChunk
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk))
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+	assert.Empty(t, chunks[0].Choices[0].Delta.Citations)
+	assert.Equal(t, []openai.Citation{{
+		URL:      "https://example.com/euro",
+		Title:    "example.com",
+		Segments: []openai.CitationSegment{{Text: "Spain won Euro 2024.", StartIndex: 0, EndIndex: 19}},
+	}}, chunks[1].Choices[0].Delta.Citations)
+}
+
+func TestOpenAIToVertex_ExtraBodyGroundingTools(t *testing.T) {
+	body := []byte(`{
+		"model": "gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "Who won Euro 2024?"}],
+		"tools": [{"type": "function", "function": {"name": "lookup", "parameters": {"type": "object"}}}],
+		"tool_choice": "auto",
+		"extra_body": {
+			"tools": [
+				{"googleSearchRetrieval": {"dynamicRetrievalConfig": {"mode": "MODE_DYNAMIC", "dynamicThreshold": 0.3}}},
+				{"retrieval": {"vertexAiSearch": {"datastore": "projects/p/locations/global/collections/default_collection/dataStores/docs"}}},
+				{"unknownTool": {}},
+				"googleSearch"
+			],
+			"tool_config": {"retrievalConfig": {"latLng": {"latitude": 52.52, "longitude": 13.4}, "languageCode": "de"}}
+		}
+	}`)
+
+	out, err := OpenAIToVertex(body, false, "gemini-2.5-flash")
+	require.NoError(t, err)
+
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &req))
+	tools, ok := req["tools"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, tools, 3, "functions, google search retrieval and Vertex AI Search; invalid entries skipped")
+	assert.Contains(t, tools[0], "functionDeclarations")
+	assert.Equal(t, map[string]interface{}{
+		"googleSearchRetrieval": map[string]interface{}{
+			"dynamicRetrievalConfig": map[string]interface{}{"mode": "MODE_DYNAMIC", "dynamicThreshold": 0.3},
+		},
+	}, tools[1])
+	assert.Equal(t, map[string]interface{}{
+		"retrieval": map[string]interface{}{
+			"vertexAiSearch": map[string]interface{}{"datastore": "projects/p/locations/global/collections/default_collection/dataStores/docs"},
+		},
+	}, tools[2])
+
+	toolConfig, ok := req["toolConfig"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"mode": "AUTO"}, toolConfig["functionCallingConfig"])
+	assert.Equal(t, map[string]interface{}{
+		"latLng":       map[string]interface{}{"latitude": 52.52, "longitude": 13.4},
+		"languageCode": "de",
+	}, toolConfig["retrievalConfig"])
+}
@@ -40,21 +40,9 @@ type VertexImagePrediction struct {
 }
 
 // BuildVertexImageURL constructs the Vertex AI URL for image generation
-// Format: https://{location}-aiplatform.googleapis.com/v1beta1/projects/{project}/locations/{location}/publishers/google/models/{model}:predict
+// Format: https://{location}-aiplatform.googleapis.com/{predict_api_version}/projects/{project}/locations/{location}/publishers/google/models/{model}:predict
 func BuildVertexImageURL(cred *config.CredentialConfig, modelID string) string {
-	// For global location (no regional prefix)
-	if cred.Location == "global" {
-		return fmt.Sprintf(
-			"https://aiplatform.googleapis.com/v1beta1/projects/%s/locations/global/publishers/google/models/%s:predict",
-			cred.ProjectID, modelID,
-		)
-	}
-
-	// For regional locations
-	return fmt.Sprintf(
-		"https://%s-aiplatform.googleapis.com/v1beta1/projects/%s/locations/%s/publishers/google/models/%s:predict",
-		cred.Location, cred.ProjectID, cred.Location, modelID,
-	)
+	return buildVertexPredictURL(cred, modelID)
 }
 
 // OpenAIImageToVertex converts OpenAI image request to Vertex AI Imagen format
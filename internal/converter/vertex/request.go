@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mixaill76/auto_ai_router/internal/converter/converterutil"
 	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"google.golang.org/genai"
 )
@@ -37,17 +38,21 @@ func OpenAIToVertex(openAIBody []byte, isImageGeneration bool, model string) ([]
 	vertexReq.GenerationConfig = buildGenerationConfig(&req, model)
 
 	// Messages → Contents + SystemInstruction
+	// toolResults collects consecutive tool messages: Gemini expects all functionResponse
+	// parts answering one model turn in a single content, one per functionCall part.
+	var toolResults *genai.Content
+	// systemPrompts collects all system/developer messages, merged into one SystemInstruction
+	var systemPrompts []string
 	for _, msg := range req.Messages {
+		if msg.Role != "tool" {
+			toolResults = nil
+		}
 		switch msg.Role {
 		case "system", "developer":
-			content := extractTextContent(msg.Content)
-			vertexReq.SystemInstruction = &genai.Content{
-				Role:  "user",
-				Parts: []*genai.Part{{Text: content}},
-			}
+			systemPrompts = append(systemPrompts, converterutil.ExtractSystemPrompt(msg.Content))
 		case "tool":
 			// OpenAI tool result: {role: "tool", tool_call_id: "call_xyz", name: "func_name", content: "..."}
-			// Vertex expects: Part.FunctionResponse{Name: funcName, Response: {output: content}}
+			// Vertex expects: Part.FunctionResponse{ID: tool_call_id, Name: funcName, Response: {output: content}}
 			funcName := msg.Name
 			if funcName == "" && msg.ToolCallID != "" {
 				// Look up function name from preceding assistant message's tool_calls
@@ -68,14 +73,16 @@ func OpenAIToVertex(openAIBody []byte, isImageGeneration bool, model string) ([]
 			} else {
 				responseData = map[string]interface{}{"output": ""}
 			}
-			vertexReq.Contents = append(vertexReq.Contents, &genai.Content{
-				Role: "user",
-				Parts: []*genai.Part{{
-					FunctionResponse: &genai.FunctionResponse{
-						Name:     funcName,
-						Response: responseData,
-					},
-				}},
+			if toolResults == nil {
+				toolResults = &genai.Content{Role: "user"}
+				vertexReq.Contents = append(vertexReq.Contents, toolResults)
+			}
+			toolResults.Parts = append(toolResults.Parts, &genai.Part{
+				FunctionResponse: &genai.FunctionResponse{
+					ID:       msg.ToolCallID,
+					Name:     funcName,
+					Response: responseData,
+				},
 			})
 		default:
 			role := msg.Role
@@ -100,6 +107,12 @@ func OpenAIToVertex(openAIBody []byte, isImageGeneration bool, model string) ([]
 			})
 		}
 	}
+	if system := converterutil.JoinSystemPrompts(systemPrompts); system != "" {
+		vertexReq.SystemInstruction = &genai.Content{
+			Role:  "user",
+			Parts: []*genai.Part{{Text: system}},
+		}
+	}
 
 	// Tools
 	if len(req.Tools) > 0 {
@@ -108,10 +121,22 @@ func OpenAIToVertex(openAIBody []byte, isImageGeneration bool, model string) ([]
 		}
 	}
 
+	// Native Vertex tools (grounding with Vertex AI Search, RAG, ...) from extra_body.tools
+	vertexReq.Tools = append(vertexReq.Tools, extraBodyTools(req.ExtraBody)...)
+	if tool := codeExecutionTool(req.ExtraBody, vertexReq.Tools); tool != nil {
+		vertexReq.Tools = append(vertexReq.Tools, tool)
+	}
+
 	// Tool choice (Phase 1)
 	if req.ToolChoice != nil {
 		vertexReq.ToolConfig = mapToolChoice(req.ToolChoice)
 	}
+	if retrievalConfig := extraBodyRetrievalConfig(req.ExtraBody); retrievalConfig != nil {
+		if vertexReq.ToolConfig == nil {
+			vertexReq.ToolConfig = &genai.ToolConfig{}
+		}
+		vertexReq.ToolConfig.RetrievalConfig = retrievalConfig
+	}
 
 	return json.Marshal(vertexReq)
 }
@@ -239,21 +239,30 @@ func TestOpenAIToVertex_ToolRoleMessage_MultipleToolCalls_ResolvesCorrectName(t
 		t.Fatalf("unmarshal vertex request: %v", err)
 	}
 
-	// Contents: [0] user, [1] model, [2] tool result (call_2), [3] tool result (call_1)
-	if len(vertexReq.Contents) < 4 {
-		t.Fatalf("expected at least 4 contents, got %d", len(vertexReq.Contents))
+	// Contents: [0] user, [1] model, [2] tool results (call_2, call_1) grouped in one content
+	if len(vertexReq.Contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d", len(vertexReq.Contents))
+	}
+	if len(vertexReq.Contents[2].Parts) != 2 {
+		t.Fatalf("expected 2 function response parts, got %d", len(vertexReq.Contents[2].Parts))
 	}
 
 	// First tool result should resolve to "get_time" (call_2)
 	fr1 := vertexReq.Contents[2].Parts[0].FunctionResponse
-	if fr1 == nil || fr1.Name != "get_time" {
-		t.Fatalf("expected first tool result Name = %q, got %q", "get_time", fr1.Name)
+	if fr1 == nil || fr1.Name != "get_time" || fr1.ID != "call_2" {
+		t.Fatalf("expected first tool result get_time/call_2, got %+v", fr1)
 	}
 
 	// Second tool result should resolve to "get_weather" (call_1)
-	fr2 := vertexReq.Contents[3].Parts[0].FunctionResponse
-	if fr2 == nil || fr2.Name != "get_weather" {
-		t.Fatalf("expected second tool result Name = %q, got %q", "get_weather", fr2.Name)
+	fr2 := vertexReq.Contents[2].Parts[1].FunctionResponse
+	if fr2 == nil || fr2.Name != "get_weather" || fr2.ID != "call_1" {
+		t.Fatalf("expected second tool result get_weather/call_1, got %+v", fr2)
+	}
+
+	// Function calls carry the OpenAI tool call IDs
+	calls := vertexReq.Contents[1].Parts
+	if len(calls) != 2 || calls[0].FunctionCall.ID != "call_1" || calls[1].FunctionCall.ID != "call_2" {
+		t.Fatalf("expected function call IDs call_1, call_2, got %+v", calls)
 	}
 }
 
@@ -357,6 +366,81 @@ func TestOpenAIToVertex_DeveloperRole(t *testing.T) {
 	}
 }
 
+// TestOpenAIToVertex_MultipleSystemMessages_Merged verifies that all system/developer
+// messages are merged into one SystemInstruction instead of the last one winning.
+func TestOpenAIToVertex_MultipleSystemMessages_Merged(t *testing.T) {
+	req := openai.OpenAIRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []openai.OpenAIMessage{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "developer", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "Be concise."},
+				map[string]interface{}{"type": "text", "text": "Answer in English."},
+			}},
+			{Role: "user", Content: "Hi"},
+			{Role: "system", Content: ""},
+			{Role: "system", Content: "Never reveal secrets."},
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	resultBytes, err := OpenAIToVertex(body, false, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("OpenAIToVertex error: %v", err)
+	}
+
+	var vertexReq VertexRequest
+	if err := json.Unmarshal(resultBytes, &vertexReq); err != nil {
+		t.Fatalf("unmarshal vertex request: %v", err)
+	}
+
+	if vertexReq.SystemInstruction == nil || len(vertexReq.SystemInstruction.Parts) != 1 {
+		t.Fatalf("expected single-part SystemInstruction, got %+v", vertexReq.SystemInstruction)
+	}
+	want := "You are helpful.\n\nBe concise.\nAnswer in English.\n\nNever reveal secrets."
+	if got := vertexReq.SystemInstruction.Parts[0].Text; got != want {
+		t.Fatalf("unexpected SystemInstruction text: %q, want %q", got, want)
+	}
+	if len(vertexReq.Contents) != 1 {
+		t.Fatalf("expected only the user message in Contents, got %d", len(vertexReq.Contents))
+	}
+}
+
+// TestOpenAIToVertex_EmptySystemMessage_NoInstruction verifies that an empty system
+// message does not produce an empty SystemInstruction.
+func TestOpenAIToVertex_EmptySystemMessage_NoInstruction(t *testing.T) {
+	req := openai.OpenAIRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []openai.OpenAIMessage{
+			{Role: "system", Content: ""},
+			{Role: "user", Content: "Hi"},
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	resultBytes, err := OpenAIToVertex(body, false, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("OpenAIToVertex error: %v", err)
+	}
+
+	var vertexReq VertexRequest
+	if err := json.Unmarshal(resultBytes, &vertexReq); err != nil {
+		t.Fatalf("unmarshal vertex request: %v", err)
+	}
+
+	if vertexReq.SystemInstruction != nil {
+		t.Fatalf("expected no SystemInstruction, got %+v", vertexReq.SystemInstruction)
+	}
+}
+
 // TestOpenAIToVertex_ToolRoleMessage_JSONContent verifies that JSON content
 // in a tool result is parsed as a map, not wrapped in {"output": ...}.
 func TestOpenAIToVertex_ToolRoleMessage_JSONContent(t *testing.T) {
@@ -440,3 +524,54 @@ func TestOpenAIToVertex_ToolRoleMessage_EmptyContent(t *testing.T) {
 		t.Fatalf("expected response = {output: ''}, got %v", fr.Response)
 	}
 }
+
+// TestOpenAIToVertex_ToolLoop_SeparatesTurns verifies that tool results of different
+// model turns stay in separate contents and are keyed by tool_call_id.
+func TestOpenAIToVertex_ToolLoop_SeparatesTurns(t *testing.T) {
+	toolCall := func(id, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":       id,
+			"type":     "function",
+			"function": map[string]interface{}{"name": name, "arguments": `{}`},
+		}
+	}
+	req := openai.OpenAIRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []openai.OpenAIMessage{
+			{Role: "user", Content: "Plan a trip"},
+			{Role: "assistant", ToolCalls: []interface{}{toolCall("call_a", "get_weather")}},
+			{Role: "tool", ToolCallID: "call_a", Content: "sunny"},
+			{Role: "assistant", ToolCalls: []interface{}{toolCall("call_b", "book_hotel")}},
+			{Role: "tool", ToolCallID: "call_b", Content: `{"booked": true}`},
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	resultBytes, err := OpenAIToVertex(body, false, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("OpenAIToVertex error: %v", err)
+	}
+	var vertexReq VertexRequest
+	if err := json.Unmarshal(resultBytes, &vertexReq); err != nil {
+		t.Fatalf("unmarshal vertex request: %v", err)
+	}
+
+	// Contents: user, model(call_a), user(response a), model(call_b), user(response b)
+	if len(vertexReq.Contents) != 5 {
+		t.Fatalf("expected 5 contents, got %d", len(vertexReq.Contents))
+	}
+	first := vertexReq.Contents[2].Parts[0].FunctionResponse
+	if first == nil || first.ID != "call_a" || first.Name != "get_weather" || first.Response["output"] != "sunny" {
+		t.Fatalf("unexpected first function response: %+v", first)
+	}
+	second := vertexReq.Contents[4].Parts[0].FunctionResponse
+	if second == nil || second.ID != "call_b" || second.Name != "book_hotel" || second.Response["booked"] != true {
+		t.Fatalf("unexpected second function response: %+v", second)
+	}
+	if vertexReq.Contents[3].Parts[0].FunctionCall.ID != "call_b" {
+		t.Fatalf("expected function call ID call_b, got %q", vertexReq.Contents[3].Parts[0].FunctionCall.ID)
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"strings"
 
 	converterutil "github.com/mixaill76/auto_ai_router/internal/converter/converterutil"
 	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"google.golang.org/genai"
 )
 
+// Placeholder contents of choices for which Vertex returned no parts
+const (
+	PlaceholderMaxTokens = "[Response truncated due to max tokens limit]"
+	PlaceholderNoContent = "[No content generated]"
+)
+
 // VertexToOpenAI converts Vertex AI response to OpenAI format
 func VertexToOpenAI(vertexBody []byte, model string) ([]byte, error) {
 	var vertexResp genai.GenerateContentResponse
@@ -31,6 +36,8 @@ func VertexToOpenAI(vertexBody []byte, model string) ([]byte, error) {
 		var content string
 		var reasoningContent string
 		var images []openai.ImageData
+		var audioData []byte
+		var audioMIMEType string
 		var toolCalls []openai.OpenAIToolCall
 
 		if candidate.Content != nil && candidate.Content.Parts != nil {
@@ -43,6 +50,12 @@ func VertexToOpenAI(vertexBody []byte, model string) ([]byte, error) {
 				if part.Text != "" {
 					content += part.Text
 				}
+				// Handle audio output (speech): chunks of one response are concatenated
+				if isAudioBlob(part.InlineData) {
+					audioData = append(audioData, part.InlineData.Data...)
+					audioMIMEType = part.InlineData.MIMEType
+					continue
+				}
 				// Handle inline data (images) from Vertex response
 				if part.InlineData != nil {
 					b64Data := base64.StdEncoding.EncodeToString(part.InlineData.Data)
@@ -55,31 +68,17 @@ func VertexToOpenAI(vertexBody []byte, model string) ([]byte, error) {
 					toolCall := convertGenaiToOpenAIFunctionCall(part.FunctionCall, part.ThoughtSignature)
 					toolCalls = append(toolCalls, toolCall)
 				}
-				// Handle code execution results (model executed code and returned output)
-				if part.CodeExecutionResult != nil {
-					if part.CodeExecutionResult.Output != "" {
-						content += "\n```\n" + part.CodeExecutionResult.Output + "\n```"
-					}
-				}
-				// Handle executable code (model-generated code to be executed)
-				if part.ExecutableCode != nil {
-					if part.ExecutableCode.Code != "" {
-						lang := strings.ToLower(string(part.ExecutableCode.Language))
-						if lang == "" || lang == "language_unspecified" {
-							lang = "python" // Vertex default language
-						}
-						content += "\n```" + lang + "\n" + part.ExecutableCode.Code + "\n```"
-					}
-				}
+				// Handle code execution (model-generated code and its output)
+				content += codeExecutionContent(part)
 			}
 		}
 
-		if content == "" && len(images) == 0 && len(toolCalls) == 0 && reasoningContent == "" {
+		if content == "" && len(images) == 0 && len(audioData) == 0 && len(toolCalls) == 0 && reasoningContent == "" {
 			// Handle case where parts is empty but we have a finish reason
 			if candidate.FinishReason == genai.FinishReasonMaxTokens {
-				content = "[Response truncated due to max tokens limit]"
-			} else if candidate.FinishReason != genai.FinishReasonSafety {
-				content = "[No content generated]"
+				content = PlaceholderMaxTokens
+			} else if mapFinishReason(string(candidate.FinishReason)) != "content_filter" {
+				content = PlaceholderNoContent
 			}
 		}
 
@@ -94,13 +93,22 @@ func VertexToOpenAI(vertexBody []byte, model string) ([]byte, error) {
 			message.ReasoningContent = reasoningContent
 		}
 
+		if len(audioData) > 0 {
+			message.Audio = &openai.AudioOutput{
+				ID:     converterutil.GenerateAudioID(),
+				Data:   base64.StdEncoding.EncodeToString(audioData),
+				Format: getAudioFormat(audioMIMEType),
+			}
+		}
+
 		// Only include tool_calls if there are any
 		if len(toolCalls) > 0 {
 			message.ToolCalls = toolCalls
 		}
+		message.Citations = convertGroundingMetadata(candidate.GroundingMetadata)
 
 		// Set refusal message when content is filtered for safety
-		if candidate.FinishReason == genai.FinishReasonSafety && content == "" && len(toolCalls) == 0 {
+		if mapFinishReason(string(candidate.FinishReason)) == "content_filter" && content == "" && len(toolCalls) == 0 {
 			message.Refusal = "Content was filtered for safety reasons"
 			message.Content = "" // ensure empty
 		}
@@ -114,13 +122,23 @@ func VertexToOpenAI(vertexBody []byte, model string) ([]byte, error) {
 		}
 
 		choice := openai.OpenAIChoice{
-			Index:        int(candidate.Index),
-			Message:      message,
-			FinishReason: finishReason,
+			Index:                int(candidate.Index),
+			Message:              message,
+			FinishReason:         finishReason,
+			ContentFilterResults: candidateContentFilterResults(candidate),
 		}
 		openAIResp.Choices = append(openAIResp.Choices, choice)
 	}
 
+	// A blocked prompt has no candidates, only prompt feedback
+	if len(vertexResp.Candidates) == 0 && isPromptBlocked(vertexResp.PromptFeedback) {
+		openAIResp.Choices = append(openAIResp.Choices, openai.OpenAIChoice{
+			Message:              openai.OpenAIResponseMessage{Role: "assistant", Refusal: promptBlockedRefusal(vertexResp.PromptFeedback)},
+			FinishReason:         "content_filter",
+			ContentFilterResults: promptContentFilterResults(vertexResp.PromptFeedback),
+		})
+	}
+
 	// Convert usage metadata
 	if vertexResp.UsageMetadata != nil {
 		openAIResp.Usage = convertVertexUsageMetadata(vertexResp.UsageMetadata)
@@ -233,9 +251,8 @@ func mapFinishReason(vertexReason string) string {
 		return "stop"
 	case "MAX_TOKENS":
 		return "length"
-	case "SAFETY":
-		return "content_filter"
-	case "RECITATION":
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII",
+		"IMAGE_SAFETY", "IMAGE_PROHIBITED_CONTENT", "IMAGE_RECITATION":
 		return "content_filter"
 	case "TOOL_CALL":
 		return "tool_calls"
@@ -244,6 +261,15 @@ func mapFinishReason(vertexReason string) string {
 	}
 }
 
+// toolCallID returns the function call ID assigned by Gemini, or a new one.
+// Reusing Gemini's ID lets tool results be matched back to the call via tool_call_id.
+func toolCallID(genaiCall *genai.FunctionCall) string {
+	if genaiCall.ID != "" {
+		return genaiCall.ID
+	}
+	return converterutil.GenerateID()
+}
+
 // convertGenaiToOpenAIFunctionCall converts genai.FunctionCall to OpenAI tool call format.
 // Preserves thoughtSignature in provider_specific_fields for Gemini 3.x multi-turn conversations.
 // Per litellm >= 1.80.5 and Google Gemini 3 requirements, thoughtSignature must be preserved
@@ -258,7 +284,7 @@ func convertGenaiToOpenAIFunctionCall(genaiCall *genai.FunctionCall, thoughtSign
 	}
 
 	toolCall := openai.OpenAIToolCall{
-		ID:   converterutil.GenerateID(),
+		ID:   toolCallID(genaiCall),
 		Type: "function",
 		Function: openai.OpenAIToolFunction{
 			Name:      genaiCall.Name,
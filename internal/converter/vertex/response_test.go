@@ -127,6 +127,10 @@ func TestMapFinishReason(t *testing.T) {
 		{"MAX_TOKENS", "length"},
 		{"SAFETY", "content_filter"},
 		{"RECITATION", "content_filter"},
+		{"BLOCKLIST", "content_filter"},
+		{"PROHIBITED_CONTENT", "content_filter"},
+		{"SPII", "content_filter"},
+		{"IMAGE_SAFETY", "content_filter"},
 		{"TOOL_CALL", "tool_calls"},
 		{"UNKNOWN_REASON", "stop"},
 		{"", "stop"},
@@ -247,3 +251,79 @@ func TestVertexToOpenAI_FinishReasonNoOverrideWithoutToolCalls(t *testing.T) {
 		t.Fatalf("expected finish_reason = %q, got %q", "stop", openAIResp.Choices[0].FinishReason)
 	}
 }
+
+// TestConvertGenaiToOpenAIFunctionCall_PreservesID verifies that a function call ID
+// assigned by Gemini is reused as tool call ID, so tool results can be matched back.
+func TestConvertGenaiToOpenAIFunctionCall_PreservesID(t *testing.T) {
+	withID := convertGenaiToOpenAIFunctionCall(&genai.FunctionCall{ID: "fc_123", Name: "get_weather"}, nil)
+	if withID.ID != "fc_123" {
+		t.Fatalf("expected tool call ID %q, got %q", "fc_123", withID.ID)
+	}
+
+	withoutID := convertGenaiToOpenAIFunctionCall(&genai.FunctionCall{Name: "get_weather"}, nil)
+	if withoutID.ID == "" {
+		t.Fatal("expected generated tool call ID")
+	}
+}
+
+// TestVertexToOpenAI_AudioOutput verifies that audio inlineData is returned as
+// message.audio (not as an image) and audio tokens are reported in usage.
+func TestVertexToOpenAI_AudioOutput(t *testing.T) {
+	vertexResp := genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content: &genai.Content{
+				Role: "model",
+				Parts: []*genai.Part{
+					{InlineData: &genai.Blob{MIMEType: "audio/L16;codec=pcm;rate=24000", Data: []byte("pcm-")}},
+					{InlineData: &genai.Blob{MIMEType: "audio/L16;codec=pcm;rate=24000", Data: []byte("data")}},
+				},
+			},
+			FinishReason: genai.FinishReasonStop,
+		}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     5,
+			CandidatesTokenCount: 100,
+			TotalTokenCount:      105,
+			CandidatesTokensDetails: []*genai.ModalityTokenCount{
+				{Modality: genai.MediaModalityAudio, TokenCount: 100},
+			},
+		},
+	}
+	body, err := json.Marshal(vertexResp)
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+
+	result, err := VertexToOpenAI(body, "gemini-2.5-flash-preview-tts")
+	if err != nil {
+		t.Fatalf("VertexToOpenAI error: %v", err)
+	}
+
+	var openAIResp openai.OpenAIResponse
+	if err := json.Unmarshal(result, &openAIResp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+
+	message := openAIResp.Choices[0].Message
+	if message.Audio == nil {
+		t.Fatalf("expected message.audio, got nil")
+	}
+	if message.Audio.Data != "cGNtLWRhdGE=" { // base64("pcm-data")
+		t.Fatalf("unexpected audio data: %q", message.Audio.Data)
+	}
+	if message.Audio.Format != "pcm16" {
+		t.Fatalf("expected format pcm16, got %q", message.Audio.Format)
+	}
+	if message.Audio.ID == "" {
+		t.Fatalf("expected audio ID")
+	}
+	if len(message.Images) != 0 {
+		t.Fatalf("audio must not be returned as images, got %d", len(message.Images))
+	}
+	if message.Content != "" {
+		t.Fatalf("expected empty content for audio-only response, got %q", message.Content)
+	}
+	if openAIResp.Usage.CompletionTokensDetails == nil || openAIResp.Usage.CompletionTokensDetails.AudioTokens != 100 {
+		t.Fatalf("expected 100 completion audio tokens, got %+v", openAIResp.Usage.CompletionTokensDetails)
+	}
+}
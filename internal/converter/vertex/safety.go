@@ -0,0 +1,137 @@
+package vertex
+
+import (
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"google.golang.org/genai"
+)
+
+// finishReasonCategories names the content_filter_results category of finish reasons
+// that block output for a reason other than the safety ratings
+var finishReasonCategories = map[genai.FinishReason]string{
+	genai.FinishReasonRecitation:             "protected_material",
+	genai.FinishReasonImageRecitation:        "protected_material",
+	genai.FinishReasonBlocklist:              "blocklist",
+	genai.FinishReasonProhibitedContent:      "prohibited_content",
+	genai.FinishReasonImageProhibitedContent: "prohibited_content",
+	genai.FinishReasonSPII:                   "pii",
+	genai.FinishReasonImageSafety:            "image_safety",
+}
+
+// blockReasonCategories names the content_filter_results category of prompt block reasons
+var blockReasonCategories = map[genai.BlockedReason]string{
+	genai.BlockedReasonBlocklist:         "blocklist",
+	genai.BlockedReasonProhibitedContent: "prohibited_content",
+	genai.BlockedReasonImageSafety:       "image_safety",
+	genai.BlockedReasonModelArmor:        "model_armor",
+	genai.BlockedReasonJailbreak:         "jailbreak",
+	genai.BlockedReasonOther:             "other",
+}
+
+// harmCategories maps Vertex harm categories to content_filter_results categories
+var harmCategories = map[genai.HarmCategory]string{
+	genai.HarmCategoryHateSpeech:            "hate",
+	genai.HarmCategoryHarassment:            "harassment",
+	genai.HarmCategorySexuallyExplicit:      "sexual",
+	genai.HarmCategoryDangerousContent:      "dangerous",
+	genai.HarmCategoryCivicIntegrity:        "civic_integrity",
+	genai.HarmCategoryImageHate:             "hate",
+	genai.HarmCategoryImageHarassment:       "harassment",
+	genai.HarmCategoryImageSexuallyExplicit: "sexual",
+	genai.HarmCategoryImageDangerousContent: "dangerous",
+	genai.HarmCategoryJailbreak:             "jailbreak",
+}
+
+// candidateContentFilterResults builds content_filter_results for a candidate stopped
+// by a content filter. Returns nil for candidates that were not filtered.
+func candidateContentFilterResults(candidate *genai.Candidate) openai.ContentFilterResults {
+	if mapFinishReason(string(candidate.FinishReason)) != "content_filter" {
+		return nil
+	}
+	results := safetyRatingResults(candidate.SafetyRatings)
+	if category, ok := finishReasonCategories[candidate.FinishReason]; ok {
+		results[category] = openai.ContentFilterResult{Filtered: true}
+	}
+	return results
+}
+
+// isPromptBlocked reports whether Vertex AI refused to process the prompt
+func isPromptBlocked(feedback *genai.GenerateContentResponsePromptFeedback) bool {
+	return feedback != nil && feedback.BlockReason != "" && feedback.BlockReason != genai.BlockedReasonUnspecified
+}
+
+// promptBlockedRefusal returns the refusal message for a blocked prompt
+func promptBlockedRefusal(feedback *genai.GenerateContentResponsePromptFeedback) string {
+	if feedback.BlockReasonMessage != "" {
+		return feedback.BlockReasonMessage
+	}
+	return "Prompt was blocked for safety reasons"
+}
+
+// promptContentFilterResults builds content_filter_results for a blocked prompt
+func promptContentFilterResults(feedback *genai.GenerateContentResponsePromptFeedback) openai.ContentFilterResults {
+	results := safetyRatingResults(feedback.SafetyRatings)
+	if category, ok := blockReasonCategories[feedback.BlockReason]; ok {
+		results[category] = openai.ContentFilterResult{Filtered: true}
+	}
+	return results
+}
+
+// safetyRatingResults converts Vertex safety ratings to content_filter_results entries.
+// A category is reported as filtered if any of its ratings blocked the content.
+func safetyRatingResults(ratings []*genai.SafetyRating) openai.ContentFilterResults {
+	results := make(openai.ContentFilterResults, len(ratings))
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		category, ok := harmCategories[rating.Category]
+		if !ok {
+			continue
+		}
+		result := results[category]
+		result.Filtered = result.Filtered || rating.Blocked
+		if severity := ratingSeverity(rating); severityRank(severity) > severityRank(result.Severity) {
+			result.Severity = severity
+		}
+		results[category] = result
+	}
+	return results
+}
+
+// ratingSeverity returns the OpenAI severity level of a rating, preferring the
+// severity reported by Vertex AI over the probability (Gemini API reports only probability)
+func ratingSeverity(rating *genai.SafetyRating) string {
+	level := strings.TrimPrefix(string(rating.Severity), "HARM_SEVERITY_")
+	if rating.Severity == "" || rating.Severity == genai.HarmSeverityUnspecified {
+		level = string(rating.Probability)
+	}
+	switch level {
+	case "NEGLIGIBLE":
+		return "safe"
+	case "LOW":
+		return "low"
+	case "MEDIUM":
+		return "medium"
+	case "HIGH":
+		return "high"
+	default:
+		return ""
+	}
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "safe":
+		return 1
+	case "low":
+		return 2
+	case "medium":
+		return 3
+	case "high":
+		return 4
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,150 @@
+package vertex
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"google.golang.org/genai"
+)
+
+// TestVertexToOpenAI_SafetyContentFilterResults verifies that SAFETY blocks are reported
+// as content_filter with per-category results built from the safety ratings.
+func TestVertexToOpenAI_SafetyContentFilterResults(t *testing.T) {
+	vertexResp := genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{
+				FinishReason: genai.FinishReasonSafety,
+				SafetyRatings: []*genai.SafetyRating{
+					{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh, Severity: genai.HarmSeverityMedium, Blocked: true},
+					{Category: genai.HarmCategoryHateSpeech, Probability: genai.HarmProbabilityNegligible},
+				},
+			},
+		},
+	}
+	vertexBytes, err := json.Marshal(vertexResp)
+	if err != nil {
+		t.Fatalf("marshal vertex response: %v", err)
+	}
+
+	resultBytes, err := VertexToOpenAI(vertexBytes, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("VertexToOpenAI error: %v", err)
+	}
+	var openAIResp openai.OpenAIResponse
+	if err := json.Unmarshal(resultBytes, &openAIResp); err != nil {
+		t.Fatalf("unmarshal OpenAI response: %v", err)
+	}
+
+	choice := openAIResp.Choices[0]
+	if choice.FinishReason != "content_filter" {
+		t.Fatalf("expected finish_reason content_filter, got %q", choice.FinishReason)
+	}
+	if choice.Message.Refusal == "" {
+		t.Errorf("expected refusal message for filtered response")
+	}
+	want := openai.ContentFilterResults{
+		"dangerous": {Filtered: true, Severity: "medium"},
+		"hate":      {Filtered: false, Severity: "safe"},
+	}
+	if len(choice.ContentFilterResults) != len(want) {
+		t.Fatalf("expected %v, got %v", want, choice.ContentFilterResults)
+	}
+	for category, result := range want {
+		if choice.ContentFilterResults[category] != result {
+			t.Errorf("content_filter_results[%s] = %+v, want %+v", category, choice.ContentFilterResults[category], result)
+		}
+	}
+}
+
+// TestVertexToOpenAI_RecitationContentFilterResults verifies that non-safety filter
+// reasons are reported under their own category.
+func TestVertexToOpenAI_RecitationContentFilterResults(t *testing.T) {
+	body := []byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Partial"}]},"finishReason":"RECITATION"}]}`)
+
+	resultBytes, err := VertexToOpenAI(body, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("VertexToOpenAI error: %v", err)
+	}
+	var openAIResp openai.OpenAIResponse
+	if err := json.Unmarshal(resultBytes, &openAIResp); err != nil {
+		t.Fatalf("unmarshal OpenAI response: %v", err)
+	}
+
+	choice := openAIResp.Choices[0]
+	if choice.FinishReason != "content_filter" || choice.Message.Content != "Partial" {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if !choice.ContentFilterResults["protected_material"].Filtered {
+		t.Errorf("expected protected_material to be filtered, got %v", choice.ContentFilterResults)
+	}
+}
+
+// TestVertexToOpenAI_NoContentFilterResultsOnStop verifies that unfiltered responses
+// do not carry content_filter_results.
+func TestVertexToOpenAI_NoContentFilterResultsOnStop(t *testing.T) {
+	body := []byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"finishReason":"STOP","safetyRatings":[{"category":"HARM_CATEGORY_HATE_SPEECH","probability":"NEGLIGIBLE"}]}]}`)
+
+	resultBytes, err := VertexToOpenAI(body, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("VertexToOpenAI error: %v", err)
+	}
+	if strings.Contains(string(resultBytes), "content_filter_results") {
+		t.Fatalf("unexpected content_filter_results in %s", resultBytes)
+	}
+}
+
+// TestVertexToOpenAI_PromptBlocked verifies that a blocked prompt (no candidates)
+// becomes a content_filter choice with a refusal.
+func TestVertexToOpenAI_PromptBlocked(t *testing.T) {
+	body := []byte(`{"promptFeedback":{"blockReason":"PROHIBITED_CONTENT","blockReasonMessage":"Prompt blocked"},"usageMetadata":{"promptTokenCount":7}}`)
+
+	resultBytes, err := VertexToOpenAI(body, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("VertexToOpenAI error: %v", err)
+	}
+	var openAIResp openai.OpenAIResponse
+	if err := json.Unmarshal(resultBytes, &openAIResp); err != nil {
+		t.Fatalf("unmarshal OpenAI response: %v", err)
+	}
+
+	if len(openAIResp.Choices) != 1 {
+		t.Fatalf("expected 1 choice, got %d", len(openAIResp.Choices))
+	}
+	choice := openAIResp.Choices[0]
+	if choice.FinishReason != "content_filter" || choice.Message.Refusal != "Prompt blocked" {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if !choice.ContentFilterResults["prohibited_content"].Filtered {
+		t.Errorf("expected prohibited_content to be filtered, got %v", choice.ContentFilterResults)
+	}
+}
+
+// TestTransformVertexStreamToOpenAI_ContentFilter verifies content filter reporting in streams.
+func TestTransformVertexStreamToOpenAI_ContentFilter(t *testing.T) {
+	input := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Step one"}]}}]}`,
+		`data: {"candidates":[{"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}]}`,
+		"",
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := TransformVertexStreamToOpenAI(strings.NewReader(input), "gemini-2.5-flash", &out, nil); err != nil {
+		t.Fatalf("TransformVertexStreamToOpenAI error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"finish_reason":"content_filter","content_filter_results":{"harassment":{"filtered":true,"severity":"high"}}`) {
+		t.Fatalf("expected content filter chunk, got %s", out.String())
+	}
+
+	out.Reset()
+	blocked := `data: {"promptFeedback":{"blockReason":"SAFETY"}}` + "\n"
+	if err := TransformVertexStreamToOpenAI(strings.NewReader(blocked), "gemini-2.5-flash", &out, nil); err != nil {
+		t.Fatalf("TransformVertexStreamToOpenAI error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"refusal":"Prompt was blocked for safety reasons"`) ||
+		!strings.Contains(out.String(), `"finish_reason":"content_filter"`) {
+		t.Fatalf("expected blocked prompt chunk, got %s", out.String())
+	}
+}
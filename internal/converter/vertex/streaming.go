@@ -2,6 +2,7 @@ package vertex
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,12 +14,17 @@ import (
 	"google.golang.org/genai"
 )
 
-// TransformVertexStreamToOpenAI converts Vertex AI SSE stream to OpenAI SSE format
-func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output io.Writer) error {
+// TransformVertexStreamToOpenAI converts Vertex AI SSE stream to OpenAI SSE format.
+// Debug lines go to logger (nil = slog.Default()).
+func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output io.Writer, logger *slog.Logger) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	scanner := bufio.NewScanner(vertexStream)
 	chatID := converterutil.GenerateID()
 	timestamp := converterutil.GetCurrentTimestamp()
 	isFirstChunk := true
+	audioID := "" // one ID for all audio deltas of the stream, generated on the first audio chunk
 
 	vertexLineCount := 0
 	vertexChunkCount := 0
@@ -34,7 +40,7 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 		// Extract JSON data
 		jsonData := strings.TrimPrefix(line, "data: ")
 		if jsonData == "[DONE]" {
-			slog.Debug("[vertex/streaming] received [DONE]", "lines_read", vertexLineCount, "chunks_processed", vertexChunkCount)
+			logger.Debug("[vertex/streaming] received [DONE]", "lines_read", vertexLineCount, "chunks_processed", vertexChunkCount)
 			// Write final done message
 			_, _ = fmt.Fprintf(output, "data: [DONE]\n\n")
 			break
@@ -43,17 +49,42 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 		// Parse Vertex AI chunk
 		var vertexChunk VertexStreamingChunk
 		if err := json.Unmarshal([]byte(jsonData), &vertexChunk); err != nil {
-			slog.Debug("[vertex/streaming] failed to parse Vertex chunk",
+			logger.Debug("[vertex/streaming] failed to parse Vertex chunk",
 				"error", err, "json_prefix", jsonData[:min(len(jsonData), 200)])
 			continue // Skip malformed chunks
 		}
 
+		// A blocked prompt is reported as a single content_filter chunk
+		if len(vertexChunk.Candidates) == 0 && isPromptBlocked(vertexChunk.PromptFeedback) {
+			vertexChunkCount++
+			finishReason := "content_filter"
+			openAIChunk := openai.OpenAIStreamingChunk{
+				ID:      chatID,
+				Object:  "chat.completion.chunk",
+				Created: timestamp,
+				Model:   model,
+				Choices: []openai.OpenAIStreamingChoice{{
+					Delta:                openai.OpenAIStreamingDelta{Role: "assistant", Refusal: promptBlockedRefusal(vertexChunk.PromptFeedback)},
+					FinishReason:         &finishReason,
+					ContentFilterResults: promptContentFilterResults(vertexChunk.PromptFeedback),
+				}},
+			}
+			if vertexChunk.UsageMetadata != nil {
+				openAIChunk.Usage = convertVertexUsageMetadata(vertexChunk.UsageMetadata)
+			}
+			if chunkJSON, err := json.Marshal(openAIChunk); err == nil {
+				_, _ = fmt.Fprintf(output, "data: %s\n\n", chunkJSON)
+			}
+			isFirstChunk = false
+			continue
+		}
+
 		// Skip chunks with no candidates
 		if len(vertexChunk.Candidates) == 0 {
-			slog.Debug("[vertex/streaming] chunk with no candidates",
+			logger.Debug("[vertex/streaming] chunk with no candidates",
 				"has_usage", vertexChunk.UsageMetadata != nil)
 			// Still emit usage-only chunks (they have no candidates but have usage metadata)
-			if vertexChunk.UsageMetadata != nil {
+			if hasTokenCounts(vertexChunk.UsageMetadata) {
 				openAIChunk := openai.OpenAIStreamingChunk{
 					ID:      chatID,
 					Object:  "chat.completion.chunk",
@@ -64,7 +95,7 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 				}
 				chunkJSON, err := json.Marshal(openAIChunk)
 				if err == nil {
-					slog.Debug("[vertex/streaming] emitting usage-only chunk",
+					logger.Debug("[vertex/streaming] emitting usage-only chunk",
 						"prompt_tokens", vertexChunk.UsageMetadata.PromptTokenCount,
 						"candidates_tokens", vertexChunk.UsageMetadata.CandidatesTokenCount,
 						"total_tokens", vertexChunk.UsageMetadata.TotalTokenCount)
@@ -100,6 +131,8 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 			// Extract content and function calls from parts
 			var content string
 			var reasoningContent string
+			var audioData []byte
+			var audioMIMEType string
 			var toolCalls []openai.OpenAIStreamingToolCall
 			toolCallIdx := 0
 
@@ -113,13 +146,30 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 					if part.Text != "" {
 						content += part.Text
 					}
+					content += codeExecutionContent(part)
 					// Handle function calls
 					if part.FunctionCall != nil {
 						toolCall := convertVertexFunctionCallToStreamingOpenAI(part.FunctionCall, part.ThoughtSignature, toolCallIdx)
 						toolCalls = append(toolCalls, toolCall)
 						toolCallIdx++
 					}
-					// Note: streaming doesn't support images in delta, only text
+					// Handle audio output (speech) as delta.audio chunks
+					if isAudioBlob(part.InlineData) {
+						audioData = append(audioData, part.InlineData.Data...)
+						audioMIMEType = part.InlineData.MIMEType
+					}
+					// Note: streaming doesn't support images in delta, only text and audio
+				}
+			}
+
+			if len(audioData) > 0 {
+				if audioID == "" {
+					audioID = converterutil.GenerateAudioID()
+				}
+				choice.Delta.Audio = &openai.AudioOutput{
+					ID:     audioID,
+					Data:   base64.StdEncoding.EncodeToString(audioData),
+					Format: getAudioFormat(audioMIMEType),
 				}
 			}
 
@@ -130,9 +180,11 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 			if len(toolCalls) > 0 {
 				choice.Delta.ToolCalls = toolCalls
 			}
+			// Grounding metadata comes with the chunks that complete the grounded text
+			choice.Delta.Citations = convertGroundingMetadata(candidate.GroundingMetadata)
 
-			// Handle finish reason
-			if candidate.FinishReason != genai.FinishReasonUnspecified {
+			// Handle finish reason (intermediate chunks have none)
+			if candidate.FinishReason != "" && candidate.FinishReason != genai.FinishReasonUnspecified {
 				finishReason := mapFinishReason(string(candidate.FinishReason))
 				// Vertex returns "STOP" even with function calls (Gemini 3+).
 				// Override for OpenAI compatibility.
@@ -140,14 +192,15 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 					finishReason = "tool_calls"
 				}
 				choice.FinishReason = &finishReason
+				choice.ContentFilterResults = candidateContentFilterResults(candidate)
 			}
 
 			openAIChunk.Choices = append(openAIChunk.Choices, choice)
 		}
 
 		// Convert usage metadata if present
-		if vertexChunk.UsageMetadata != nil {
-			//slog.Error("STREAMING_VERTEX_USAGE_CHUNK",
+		if hasTokenCounts(vertexChunk.UsageMetadata) {
+			//logger.Error("STREAMING_VERTEX_USAGE_CHUNK",
 			//	"prompt_tokens", vertexChunk.UsageMetadata.PromptTokenCount,
 			//	"candidates_tokens", vertexChunk.UsageMetadata.CandidatesTokenCount,
 			//	"total_tokens", vertexChunk.UsageMetadata.TotalTokenCount,
@@ -166,13 +219,19 @@ func TransformVertexStreamToOpenAI(vertexStream io.Reader, model string, output
 		isFirstChunk = false
 	}
 
-	slog.Debug("[vertex/streaming] scan finished",
+	logger.Debug("[vertex/streaming] scan finished",
 		"lines_read", vertexLineCount, "chunks_processed", vertexChunkCount,
 		"scanner_err", scanner.Err())
 
 	return scanner.Err()
 }
 
+// hasTokenCounts reports whether usage metadata carries token counts: intermediate stream
+// chunks may have metadata without them (e.g. only trafficType)
+func hasTokenCounts(meta *genai.GenerateContentResponseUsageMetadata) bool {
+	return meta != nil && (meta.PromptTokenCount > 0 || meta.TotalTokenCount > 0)
+}
+
 // convertVertexFunctionCallToStreamingOpenAI converts Vertex function call to OpenAI streaming tool call format.
 // Preserves thoughtSignature in provider_specific_fields for Gemini 3.x multi-turn streaming.
 func convertVertexFunctionCallToStreamingOpenAI(genaiCall *genai.FunctionCall, thoughtSignature []byte, index int) openai.OpenAIStreamingToolCall {
@@ -186,7 +245,7 @@ func convertVertexFunctionCallToStreamingOpenAI(genaiCall *genai.FunctionCall, t
 
 	toolCall := openai.OpenAIStreamingToolCall{
 		Index: index,
-		ID:    converterutil.GenerateID(),
+		ID:    toolCallID(genaiCall),
 		Type:  "function",
 		Function: &openai.OpenAIStreamingToolFunction{
 			Name:      genaiCall.Name,
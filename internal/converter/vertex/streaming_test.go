@@ -1,8 +1,12 @@
 package vertex
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/genai"
@@ -80,3 +84,38 @@ func TestConvertVertexFunctionCallToStreamingOpenAI(t *testing.T) {
 		assert.Equal(t, 5, result.Index)
 	})
 }
+
+func TestTransformVertexStreamToOpenAI_AudioOutput(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=24000","data":"AAEC"}}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=24000","data":"AwQF"}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":50,"candidatesTokensDetails":[{"modality":"AUDIO","tokenCount":50}]}}`,
+		"",
+	}, "\n")
+
+	var output bytes.Buffer
+	require.NoError(t, TransformVertexStreamToOpenAI(strings.NewReader(stream), "gemini-2.5-flash-preview-tts", &output, nil))
+
+	var chunks []openai.OpenAIStreamingChunk
+	for _, line := range strings.Split(output.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var chunk openai.OpenAIStreamingChunk
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk))
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+
+	first := chunks[0].Choices[0].Delta.Audio
+	second := chunks[1].Choices[0].Delta.Audio
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	assert.Equal(t, "AAEC", first.Data)
+	assert.Equal(t, "AwQF", second.Data)
+	assert.Equal(t, "pcm16", first.Format)
+	assert.Equal(t, first.ID, second.ID, "audio deltas share one ID")
+
+	require.NotNil(t, chunks[1].Usage)
+	require.NotNil(t, chunks[1].Usage.CompletionTokensDetails)
+	assert.Equal(t, 50, chunks[1].Usage.CompletionTokensDetails.AudioTokens)
+}
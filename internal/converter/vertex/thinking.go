@@ -55,7 +55,7 @@ func mapReasoningEffort(effort string, model string) *genai.ThinkingConfig {
 			} else {
 				config.ThinkingLevel = genai.ThinkingLevelHigh
 			}
-		case "high":
+		case "high", "xhigh":
 			config.ThinkingLevel = genai.ThinkingLevelHigh
 		case "disable", "none":
 			config.IncludeThoughts = false
@@ -78,6 +78,8 @@ func mapReasoningEffort(effort string, model string) *genai.ThinkingConfig {
 			budget = 15000
 		case "high":
 			budget = 30000
+		case "xhigh":
+			budget = 32768 // Largest budget of Gemini 2.5 Pro
 		case "disable", "none":
 			config.IncludeThoughts = false
 			zero := int32(0)
@@ -2,6 +2,7 @@ package vertex
 
 import (
 	"encoding/json"
+	"strings"
 
 	converterutil "github.com/mixaill76/auto_ai_router/internal/converter/converterutil"
 	"google.golang.org/genai"
@@ -140,6 +141,48 @@ func convertGoogleSearchRetrieval(toolMap map[string]interface{}) *genai.GoogleS
 	return retrieval
 }
 
+// codeExecutionTool returns the codeExecution tool requested with extra_body.code_execution=true,
+// or nil if it is not requested or already among tools (tools type "code_execution")
+func codeExecutionTool(extraBody map[string]interface{}, tools []*genai.Tool) *genai.Tool {
+	if enabled, _ := extraBody["code_execution"].(bool); !enabled {
+		return nil
+	}
+	for _, tool := range tools {
+		if tool.CodeExecution != nil {
+			return nil
+		}
+	}
+	return &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}}
+}
+
+// codeExecutionContent renders executableCode and codeExecutionResult parts as fenced
+// markdown blocks: the code in its language, the output as plain text. Failed and timed out
+// executions are marked before their output. Returns "" for other parts.
+func codeExecutionContent(part *genai.Part) string {
+	var content string
+	if part.ExecutableCode != nil && part.ExecutableCode.Code != "" {
+		lang := strings.ToLower(string(part.ExecutableCode.Language))
+		if lang == "" || lang == "language_unspecified" {
+			lang = "python" // Vertex default language
+		}
+		content += "\n```" + lang + "\n" + part.ExecutableCode.Code + "\n```\n"
+	}
+	if result := part.CodeExecutionResult; result != nil {
+		switch result.Outcome {
+		case genai.OutcomeFailed:
+			content += "\nCode execution failed:"
+		case genai.OutcomeDeadlineExceeded:
+			content += "\nCode execution timed out:"
+		}
+		if result.Output != "" {
+			content += "\n```\n" + strings.TrimSuffix(result.Output, "\n") + "\n```\n"
+		} else if content != "" {
+			content += "\n"
+		}
+	}
+	return content
+}
+
 // convertToolCallsToGenaiParts converts OpenAI tool_calls to genai.Part with FunctionCall.
 // Restores thoughtSignature from provider_specific_fields for Gemini 3 multi-turn conversations.
 func convertToolCallsToGenaiParts(toolCalls []interface{}) []*genai.Part {
@@ -187,6 +230,7 @@ func convertToolCallsToGenaiParts(toolCalls []interface{}) []*genai.Part {
 
 		part := &genai.Part{
 			FunctionCall: &genai.FunctionCall{
+				ID:   converterutil.GetString(toolCallMap, "id"),
 				Name: funcName,
 				Args: args,
 			},
@@ -1,10 +1,15 @@
 package vertex
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
 )
 
 // TestConvertToolCallsToGenaiParts_NestedFormat verifies conversion of
@@ -379,3 +384,90 @@ func TestConvertGoogleSearchRetrieval(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenAIToVertex_CodeExecutionExtraBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int // codeExecution tools in the request
+	}{
+		{name: "flag enables the tool", body: `{"messages":[{"role":"user","content":"2+2?"}],"extra_body":{"code_execution":true}}`, want: 1},
+		{name: "flag off", body: `{"messages":[{"role":"user","content":"2+2?"}],"extra_body":{"code_execution":false}}`, want: 0},
+		{name: "no duplicate with tools type", body: `{"messages":[{"role":"user","content":"2+2?"}],"tools":[{"type":"code_execution"}],"extra_body":{"code_execution":true}}`, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := OpenAIToVertex([]byte(tt.body), false, "gemini-2.5-flash")
+			require.NoError(t, err)
+			var req VertexRequest
+			require.NoError(t, json.Unmarshal(out, &req))
+			count := 0
+			for _, tool := range req.Tools {
+				if tool.CodeExecution != nil {
+					count++
+				}
+			}
+			assert.Equal(t, tt.want, count)
+		})
+	}
+}
+
+func TestCodeExecutionContent(t *testing.T) {
+	tests := []struct {
+		name string
+		part *genai.Part
+		want string
+	}{
+		{name: "text part", part: &genai.Part{Text: "Hi"}, want: ""},
+		{
+			name: "executable code",
+			part: &genai.Part{ExecutableCode: &genai.ExecutableCode{Language: genai.LanguagePython, Code: "print(2+2)"}},
+			want: "\n```python\nprint(2+2)\n```\n",
+		},
+		{
+			name: "successful result",
+			part: &genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "4\n"}},
+			want: "\n```\n4\n```\n",
+		},
+		{
+			name: "failed result",
+			part: &genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeFailed, Output: "ZeroDivisionError"}},
+			want: "\nCode execution failed:\n```\nZeroDivisionError\n```\n",
+		},
+		{
+			name: "timed out without output",
+			part: &genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeDeadlineExceeded}},
+			want: "\nCode execution timed out:\n",
+		},
+		{name: "successful result without output", part: &genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK}}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, codeExecutionContent(tt.part))
+		})
+	}
+}
+
+func TestTransformVertexStreamToOpenAI_CodeExecution(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Let me compute it."},{"executableCode":{"language":"PYTHON","code":"print(2+2)"}}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"codeExecutionResult":{"outcome":"OUTCOME_OK","output":"4\n"}},{"text":"The answer is 4."}]},"finishReason":"STOP"}]}`,
+		"",
+	}, "\n")
+
+	var output bytes.Buffer
+	require.NoError(t, TransformVertexStreamToOpenAI(strings.NewReader(stream), "gemini-2.5-flash", &output, nil))
+
+	var content string
+	for _, line := range strings.Split(output.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") || line == "data: [DONE]" {
+			continue
+		}
+		var chunk openai.OpenAIStreamingChunk
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk))
+		for _, choice := range chunk.Choices {
+			content += choice.Delta.Content
+		}
+	}
+	assert.Equal(t, "Let me compute it.\n```python\nprint(2+2)\n```\n\n```\n4\n```\nThe answer is 4.", content)
+}
@@ -4,8 +4,9 @@ import "google.golang.org/genai"
 
 // VertexStreamingChunk wraps genai types for streaming response
 type VertexStreamingChunk struct {
-	Candidates    []*genai.Candidate                          `json:"candidates,omitempty"`
-	UsageMetadata *genai.GenerateContentResponseUsageMetadata `json:"usageMetadata,omitempty"`
+	Candidates     []*genai.Candidate                           `json:"candidates,omitempty"`
+	UsageMetadata  *genai.GenerateContentResponseUsageMetadata  `json:"usageMetadata,omitempty"`
+	PromptFeedback *genai.GenerateContentResponsePromptFeedback `json:"promptFeedback,omitempty"`
 }
 
 // VertexRequest represents the Vertex AI API request format
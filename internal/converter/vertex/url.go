@@ -30,27 +30,48 @@ func BuildGeminiURL(cred *config.CredentialConfig, modelID string, streaming boo
 	return fmt.Sprintf("%s/v1beta/models/%s:%s", baseURL, modelID, endpoint)
 }
 
-// BuildVertexURL constructs the Vertex AI URL dynamically
-// Format: https://{location}-aiplatform.googleapis.com/v1beta1/projects/{project}/locations/{location}/publishers/{publisher}/models/{model}:{endpoint}
+// BuildVertexURL constructs the Vertex AI URL dynamically.
+// Streaming and non-streaming requests use the same api_version.
+// Format: https://{location}-aiplatform.googleapis.com/{api_version}/projects/{project}/locations/{location}/publishers/{publisher}/models/{model}:{endpoint}
 func BuildVertexURL(cred *config.CredentialConfig, modelID string, streaming bool) string {
-	publisher := determineVertexPublisher(modelID)
-
 	endpoint := "generateContent"
 	if streaming {
 		endpoint = "streamGenerateContent?alt=sse"
 	}
 
+	return buildVertexModelURL(cred, apiVersion(cred.APIVersion), determineVertexPublisher(modelID), modelID, endpoint)
+}
+
+// buildVertexPredictURL constructs the Vertex AI URL of a Google predict endpoint (Imagen, embeddings)
+func buildVertexPredictURL(cred *config.CredentialConfig, modelID string) string {
+	version := cred.PredictAPIVersion
+	if version == "" {
+		version = cred.APIVersion
+	}
+	return buildVertexModelURL(cred, apiVersion(version), "google", modelID, "predict")
+}
+
+// buildVertexModelURL constructs a Vertex AI publisher model URL
+func buildVertexModelURL(cred *config.CredentialConfig, version, publisher, modelID, endpoint string) string {
 	// For global location (no regional prefix)
 	if cred.Location == "global" {
 		return fmt.Sprintf(
-			"https://aiplatform.googleapis.com/v1beta1/projects/%s/locations/global/publishers/%s/models/%s:%s",
-			cred.ProjectID, publisher, modelID, endpoint,
+			"https://aiplatform.googleapis.com/%s/projects/%s/locations/global/publishers/%s/models/%s:%s",
+			version, cred.ProjectID, publisher, modelID, endpoint,
 		)
 	}
 
 	// For regional locations
 	return fmt.Sprintf(
-		"https://%s-aiplatform.googleapis.com/v1beta1/projects/%s/locations/%s/publishers/%s/models/%s:%s",
-		cred.Location, cred.ProjectID, cred.Location, publisher, modelID, endpoint,
+		"https://%s-aiplatform.googleapis.com/%s/projects/%s/locations/%s/publishers/%s/models/%s:%s",
+		cred.Location, version, cred.ProjectID, cred.Location, publisher, modelID, endpoint,
 	)
 }
+
+// apiVersion returns version, or the default Vertex AI API version when it is not set
+func apiVersion(version string) string {
+	if version == "" {
+		return config.DefaultVertexAPIVersion
+	}
+	return version
+}
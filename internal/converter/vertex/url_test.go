@@ -69,4 +69,33 @@ func TestBuildVertexURL(t *testing.T) {
 		url := BuildVertexURL(cred, "claude-sonnet-4-20250514", false)
 		assert.Contains(t, url, "/publishers/anthropic/")
 	})
+
+	t.Run("api_version", func(t *testing.T) {
+		cred := &config.CredentialConfig{
+			ProjectID:  "my-project",
+			Location:   "us-central1",
+			APIVersion: "v1",
+		}
+		assert.Equal(t, "https://us-central1-aiplatform.googleapis.com/v1/projects/my-project/locations/us-central1/publishers/google/models/gemini-2.5-flash:generateContent",
+			BuildVertexURL(cred, "gemini-2.5-flash", false))
+		assert.Equal(t, "https://us-central1-aiplatform.googleapis.com/v1/projects/my-project/locations/us-central1/publishers/google/models/gemini-2.5-flash:streamGenerateContent?alt=sse",
+			BuildVertexURL(cred, "gemini-2.5-flash", true), "streaming uses the same api_version")
+	})
+}
+
+func TestBuildVertexPredictURL(t *testing.T) {
+	cred := &config.CredentialConfig{
+		ProjectID:  "my-project",
+		Location:   "global",
+		APIVersion: "v1",
+	}
+	assert.Equal(t, "https://aiplatform.googleapis.com/v1/projects/my-project/locations/global/publishers/google/models/imagen-3.0-generate-002:predict",
+		BuildVertexImageURL(cred, "imagen-3.0-generate-002"), "predict falls back to api_version")
+
+	cred.PredictAPIVersion = "v1beta1"
+	assert.Equal(t, "https://aiplatform.googleapis.com/v1beta1/projects/my-project/locations/global/publishers/google/models/imagen-4.0-generate-preview:predict",
+		BuildVertexImageURL(cred, "imagen-4.0-generate-preview"))
+	assert.Equal(t, "https://aiplatform.googleapis.com/v1beta1/projects/my-project/locations/global/publishers/google/models/text-embedding-004:predict",
+		BuildVertexEmbeddingURL(cred, "text-embedding-004"))
+	assert.Contains(t, BuildVertexURL(cred, "gemini-2.5-flash", false), "/v1/projects/", "generateContent keeps api_version")
 }
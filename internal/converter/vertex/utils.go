@@ -155,3 +155,33 @@ func getAudioMimeType(format string) string {
 	// Default to wav if format is not recognized
 	return "audio/wav"
 }
+
+// isAudioBlob reports whether inline data returned by the model is audio
+func isAudioBlob(blob *genai.Blob) bool {
+	return blob != nil && strings.HasPrefix(strings.ToLower(blob.MIMEType), "audio/")
+}
+
+// getAudioFormat maps an audio MIME type to an OpenAI audio format name (inverse of getAudioMimeType).
+// Gemini speech output is raw 16-bit PCM ("audio/L16;codec=pcm;rate=24000").
+func getAudioFormat(mimeType string) string {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	formats := map[string]string{
+		"audio/wav":   "wav",
+		"audio/x-wav": "wav",
+		"audio/wave":  "wav",
+		"audio/mpeg":  "mp3",
+		"audio/mp3":   "mp3",
+		"audio/ogg":   "ogg",
+		"audio/opus":  "opus",
+		"audio/aac":   "aac",
+		"audio/flac":  "flac",
+		"audio/mp4":   "m4a",
+		"audio/l16":   "pcm16",
+		"audio/pcm":   "pcm16",
+	}
+
+	if format, ok := formats[mediaType]; ok {
+		return format
+	}
+	return strings.TrimPrefix(mediaType, "audio/")
+}
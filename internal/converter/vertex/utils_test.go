@@ -72,6 +72,25 @@ func TestGetAudioMimeType(t *testing.T) {
 	}
 }
 
+func TestGetAudioFormat(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{"audio/wav", "wav"},
+		{"audio/mpeg", "mp3"},
+		{"audio/L16;codec=pcm;rate=24000", "pcm16"},
+		{"audio/pcm", "pcm16"},
+		{"audio/ogg", "ogg"},
+		{"audio/x-custom", "x-custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			assert.Equal(t, tt.want, getAudioFormat(tt.mimeType))
+		})
+	}
+}
+
 func TestParseDataURLToPart(t *testing.T) {
 	t.Run("valid_data_url", func(t *testing.T) {
 		rawData := []byte("hello world")
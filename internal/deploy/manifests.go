@@ -0,0 +1,285 @@
+// Package deploy renders deployment manifests (Kubernetes, docker-compose) for a router
+// configuration, with the listener ports, health probes and metrics scraping taken from it.
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+const (
+	// TargetKubernetes renders a config Secret, a Deployment and a Service
+	TargetKubernetes = "k8s"
+	// TargetCompose renders a docker-compose file
+	TargetCompose = "compose"
+
+	// DefaultName is the name of the generated resources and service
+	DefaultName = "auto-ai-router"
+	// DefaultImage is the published router image
+	DefaultImage = "ghcr.io/mixaill76/auto_ai_router:latest"
+
+	// ReadinessPath serves the /health/readiness report (LiteLLM-compatible)
+	ReadinessPath = "/readyz"
+	// LivenessPath answers 200 as long as the process serves HTTP, without further checks
+	LivenessPath = "/livez"
+	// MetricsPath is the Prometheus endpoint on the main port (monitoring.prometheus_enabled)
+	MetricsPath = "/metrics"
+
+	// containerConfigDir is where the config file is mounted in the container
+	containerConfigDir = "/app/config"
+)
+
+// Options customize the generated manifests
+type Options struct {
+	Name       string // Resource and service name (default: DefaultName)
+	Namespace  string // Kubernetes namespace ("" = none set)
+	Image      string // Container image (default: DefaultImage)
+	Replicas   int    // Kubernetes replicas (default: 1)
+	ConfigPath string // Path of the config file on the host, mounted by docker-compose
+}
+
+// envReference matches os.environ/NAME values of the config file
+var envReference = regexp.MustCompile(`os\.environ/([A-Za-z_][A-Za-z0-9_]*)`)
+
+// manifestData is the input of the manifest templates
+type manifestData struct {
+	Options
+	Port          int
+	AdminPort     int // 0 = no admin listener
+	Metrics       bool
+	Config        string   // Raw config file
+	ConfigDir     string   // Config mount directory in the Kubernetes container
+	ConfigFile    string   // Config file name in the container
+	ConfigVolume  string   // docker-compose volume source
+	Env           []string // Environment variables the config reads
+	ReadinessPath string
+	LivenessPath  string
+	MetricsPath   string
+}
+
+// Generate renders the manifests of target for cfg. rawConfig is the unresolved config
+// file, embedded as is, so os.environ/ references and encrypted values stay out of the
+// manifests; the variables they need are passed from a Secret (k8s) or the shell (compose).
+func Generate(target string, cfg *config.Config, rawConfig []byte, opts Options) (string, error) {
+	var tmpl *template.Template
+	switch target {
+	case TargetKubernetes:
+		tmpl = kubernetesTemplate
+	case TargetCompose:
+		tmpl = composeTemplate
+	default:
+		return "", fmt.Errorf("unknown target %q (expected %s or %s)", target, TargetKubernetes, TargetCompose)
+	}
+	if opts.Replicas < 0 {
+		return "", fmt.Errorf("invalid replicas: %d", opts.Replicas)
+	}
+
+	data := newManifestData(cfg, rawConfig, opts)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s manifests: %w", target, err)
+	}
+	return buf.String(), nil
+}
+
+func newManifestData(cfg *config.Config, rawConfig []byte, opts Options) manifestData {
+	if opts.Name == "" {
+		opts.Name = DefaultName
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	if opts.Replicas == 0 {
+		opts.Replicas = 1
+	}
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = "config.yaml"
+	}
+
+	return manifestData{
+		Options:       opts,
+		Port:          cfg.Server.Port,
+		AdminPort:     cfg.Server.AdminPort,
+		Metrics:       cfg.Monitoring.PrometheusEnabled,
+		Config:        strings.TrimRight(string(rawConfig), "\n"),
+		ConfigDir:     containerConfigDir,
+		ConfigFile:    filepath.Base(opts.ConfigPath),
+		ConfigVolume:  composeVolumeSource(opts.ConfigPath),
+		Env:           configEnv(string(rawConfig)),
+		ReadinessPath: ReadinessPath,
+		LivenessPath:  LivenessPath,
+		MetricsPath:   MetricsPath,
+	}
+}
+
+// configEnv returns the sorted environment variables the raw config reads
+func configEnv(rawConfig string) []string {
+	seen := make(map[string]bool)
+	for _, match := range envReference.FindAllStringSubmatch(rawConfig, -1) {
+		seen[match[1]] = true
+	}
+	if strings.Contains(rawConfig, config.EncryptedValuePrefix) {
+		seen[config.ConfigKeyEnv] = true
+	}
+	env := make([]string, 0, len(seen))
+	for name := range seen {
+		env = append(env, name)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// composeVolumeSource makes a relative config path explicit; docker-compose reads a bare
+// name as a named volume
+func composeVolumeSource(path string) string {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, ".") {
+		return path
+	}
+	return "./" + path
+}
+
+// indent prefixes every non-empty line of s with spaces
+func indent(spaces int, s string) string {
+	prefix := strings.Repeat(" ", spaces)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		} else {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var templateFuncs = template.FuncMap{"indent": indent}
+
+var kubernetesTemplate = template.Must(template.New(TargetKubernetes).Funcs(templateFuncs).Parse(`# Generated by auto_ai_router generate k8s
+{{- define "metadata"}}
+  labels:
+    app.kubernetes.io/name: {{.Name}}
+{{- if .Namespace}}
+  namespace: {{.Namespace}}
+{{- end}}
+{{- end}}
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{.Name}}-config
+{{- template "metadata" .}}
+type: Opaque
+stringData:
+  {{.ConfigFile}}: |
+{{indent 4 .Config}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.Name}}
+{{- template "metadata" .}}
+spec:
+  replicas: {{.Replicas}}
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{.Name}}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{.Name}}
+{{- if .Metrics}}
+      annotations:
+        prometheus.io/scrape: "true"
+        prometheus.io/port: "{{.Port}}"
+        prometheus.io/path: {{.MetricsPath}}
+{{- end}}
+    spec:
+      containers:
+        - name: {{.Name}}
+          image: {{.Image}}
+          command: ["./auto_ai_router", "-config", "{{.ConfigDir}}/{{.ConfigFile}}"]
+          ports:
+            - name: http
+              containerPort: {{.Port}}
+{{- if .AdminPort}}
+            - name: admin
+              containerPort: {{.AdminPort}}
+{{- end}}
+{{- if .Env}}
+          env:
+{{- range .Env}}
+            - name: {{.}}
+              valueFrom:
+                secretKeyRef:
+                  name: {{$.Name}}-env
+                  key: {{.}}
+{{- end}}
+{{- end}}
+          readinessProbe:
+            httpGet:
+              path: {{.ReadinessPath}}
+              port: http
+            periodSeconds: 10
+            failureThreshold: 3
+          livenessProbe:
+            httpGet:
+              path: {{.LivenessPath}}
+              port: http
+            initialDelaySeconds: 10
+            periodSeconds: 30
+            failureThreshold: 3
+          volumeMounts:
+            - name: config
+              mountPath: {{.ConfigDir}}
+              readOnly: true
+      volumes:
+        - name: config
+          secret:
+            secretName: {{.Name}}-config
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{.Name}}
+{{- template "metadata" .}}
+spec:
+  selector:
+    app.kubernetes.io/name: {{.Name}}
+  ports:
+    - name: http
+      port: {{.Port}}
+      targetPort: http
+`))
+
+var composeTemplate = template.Must(template.New(TargetCompose).Funcs(templateFuncs).Parse(`# Generated by auto_ai_router generate compose
+services:
+  {{.Name}}:
+    image: {{.Image}}
+    command: ["./auto_ai_router", "-config", "/app/{{.ConfigFile}}"]
+    ports:
+      - "{{.Port}}:{{.Port}}"
+{{- if .AdminPort}}
+      - "127.0.0.1:{{.AdminPort}}:{{.AdminPort}}"
+{{- end}}
+    volumes:
+      - {{.ConfigVolume}}:/app/{{.ConfigFile}}:ro
+{{- if .Env}}
+    environment:
+{{- range .Env}}
+      - {{.}}=${{"{"}}{{.}}{{"}"}}
+{{- end}}
+{{- end}}
+    restart: unless-stopped
+    healthcheck:
+      test: ["CMD", "wget", "--no-verbose", "--tries=1", "--spider", "http://localhost:{{.Port}}{{.ReadinessPath}}"]
+      interval: 30s
+      timeout: 3s
+      retries: 3
+      start_period: 5s
+`))
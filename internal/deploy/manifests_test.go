@@ -0,0 +1,157 @@
+package deploy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testRawConfig = `server:
+  port: 8080
+  admin_port: 6060
+  master_key: os.environ/MASTER_KEY
+
+credentials:
+  - name: openai
+    api_key: os.environ/OPENAI_API_KEY
+    base_url: enc:v1:c2VhbGVk
+`
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Server.Port = 8080
+	cfg.Server.AdminPort = 6060
+	cfg.Monitoring.PrometheusEnabled = true
+	return cfg
+}
+
+// decodeDocuments parses every YAML document of a multi-document manifest
+func decodeDocuments(t *testing.T, manifests string) []map[string]interface{} {
+	t.Helper()
+	decoder := yaml.NewDecoder(bytes.NewBufferString(manifests))
+	var docs []map[string]interface{}
+	for {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs
+		}
+		require.NoError(t, err, manifests)
+		docs = append(docs, doc)
+	}
+}
+
+// lookup walks a decoded document along keys (map keys or list indexes)
+func lookup(t *testing.T, doc interface{}, keys ...interface{}) interface{} {
+	t.Helper()
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			m, ok := doc.(map[string]interface{})
+			require.True(t, ok, "expected a map at %q", k)
+			doc = m[k]
+		case int:
+			list, ok := doc.([]interface{})
+			require.True(t, ok, "expected a list at %d", k)
+			require.Greater(t, len(list), k)
+			doc = list[k]
+		}
+	}
+	return doc
+}
+
+func TestGenerate_Kubernetes(t *testing.T) {
+	manifests, err := Generate(TargetKubernetes, testConfig(), []byte(testRawConfig), Options{
+		Namespace:  "ai",
+		Replicas:   3,
+		ConfigPath: "deploy/router.yaml",
+	})
+	require.NoError(t, err)
+
+	docs := decodeDocuments(t, manifests)
+	require.Len(t, docs, 3)
+	secret, deployment, service := docs[0], docs[1], docs[2]
+
+	assert.Equal(t, "Secret", secret["kind"], "the config may hold plaintext keys")
+	assert.Equal(t, "auto-ai-router-config", lookup(t, secret, "metadata", "name"))
+	assert.Equal(t, "ai", lookup(t, secret, "metadata", "namespace"))
+	assert.Equal(t, testRawConfig, lookup(t, secret, "stringData", "router.yaml"), "config is embedded unresolved")
+
+	assert.Equal(t, "Deployment", deployment["kind"])
+	assert.Equal(t, 3, lookup(t, deployment, "spec", "replicas"))
+	assert.Equal(t, "8080", lookup(t, deployment, "spec", "template", "metadata", "annotations", "prometheus.io/port"))
+	container := lookup(t, deployment, "spec", "template", "spec", "containers", 0)
+	assert.Equal(t, DefaultImage, lookup(t, container, "image"))
+	assert.Equal(t, []interface{}{"./auto_ai_router", "-config", "/app/config/router.yaml"}, lookup(t, container, "command"))
+	assert.Equal(t, 6060, lookup(t, container, "ports", 1, "containerPort"))
+	assert.Equal(t, ReadinessPath, lookup(t, container, "readinessProbe", "httpGet", "path"))
+	assert.Equal(t, LivenessPath, lookup(t, container, "livenessProbe", "httpGet", "path"))
+	assert.Equal(t, "auto-ai-router-config", lookup(t, deployment, "spec", "template", "spec", "volumes", 0, "secret", "secretName"))
+
+	env := lookup(t, container, "env").([]interface{})
+	require.Len(t, env, 3)
+	assert.Equal(t, config.ConfigKeyEnv, lookup(t, env, 0, "name"), "encrypted values need the config key")
+	assert.Equal(t, "MASTER_KEY", lookup(t, env, 1, "name"))
+	assert.Equal(t, "auto-ai-router-env", lookup(t, env, 1, "valueFrom", "secretKeyRef", "name"))
+	assert.Equal(t, "OPENAI_API_KEY", lookup(t, env, 2, "valueFrom", "secretKeyRef", "key"))
+
+	assert.Equal(t, "Service", service["kind"])
+	assert.Equal(t, 8080, lookup(t, service, "spec", "ports", 0, "port"))
+	assert.Len(t, lookup(t, service, "spec", "ports"), 1, "the admin port is not exposed by the service")
+}
+
+func TestGenerate_KubernetesMinimal(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Port = 9000
+	manifests, err := Generate(TargetKubernetes, cfg, []byte("server:\n  port: 9000\n"), Options{Name: "router"})
+	require.NoError(t, err)
+
+	docs := decodeDocuments(t, manifests)
+	require.Len(t, docs, 3)
+	deployment := docs[1]
+	assert.Nil(t, lookup(t, deployment, "metadata", "namespace"))
+	assert.Equal(t, 1, lookup(t, deployment, "spec", "replicas"))
+	assert.Nil(t, lookup(t, deployment, "spec", "template", "metadata", "annotations"), "no scrape annotations without prometheus")
+	container := lookup(t, deployment, "spec", "template", "spec", "containers", 0)
+	assert.Equal(t, "router", lookup(t, container, "name"))
+	assert.Len(t, lookup(t, container, "ports"), 1)
+	assert.Nil(t, lookup(t, container, "env"))
+}
+
+func TestGenerate_Compose(t *testing.T) {
+	manifests, err := Generate(TargetCompose, testConfig(), []byte(testRawConfig), Options{Image: "router:dev"})
+	require.NoError(t, err)
+
+	docs := decodeDocuments(t, manifests)
+	require.Len(t, docs, 1)
+	service := lookup(t, docs[0], "services", DefaultName)
+	assert.Equal(t, "router:dev", lookup(t, service, "image"))
+	assert.Equal(t, []interface{}{"8080:8080", "127.0.0.1:6060:6060"}, lookup(t, service, "ports"))
+	assert.Equal(t, []interface{}{"./config.yaml:/app/config.yaml:ro"}, lookup(t, service, "volumes"))
+	assert.Equal(t, []interface{}{
+		config.ConfigKeyEnv + "=${" + config.ConfigKeyEnv + "}",
+		"MASTER_KEY=${MASTER_KEY}",
+		"OPENAI_API_KEY=${OPENAI_API_KEY}",
+	}, lookup(t, service, "environment"))
+	assert.Contains(t, lookup(t, service, "healthcheck", "test"), "http://localhost:8080/readyz")
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	_, err := Generate("helm", testConfig(), nil, Options{})
+	assert.ErrorContains(t, err, "unknown target")
+
+	_, err = Generate(TargetKubernetes, testConfig(), nil, Options{Replicas: -1})
+	assert.ErrorContains(t, err, "invalid replicas")
+}
+
+func TestComposeVolumeSource(t *testing.T) {
+	assert.Equal(t, "./config.yaml", composeVolumeSource("config.yaml"))
+	assert.Equal(t, "./conf/config.yaml", composeVolumeSource("./conf/config.yaml"))
+	assert.Equal(t, "/etc/router/config.yaml", composeVolumeSource("/etc/router/config.yaml"))
+}
@@ -22,6 +22,7 @@ type banInfo struct {
 	banTime     time.Time
 	banDuration time.Duration // 0 = permanent
 	errorCode   int
+	reason      string // Set for manual bans ("" = banned by errorCode)
 }
 
 // BanPair represents a banned credential+model pair with ban details
@@ -32,6 +33,7 @@ type BanPair struct {
 	ErrorCodeCounts map[int]int
 	BanTime         time.Time
 	BanDuration     time.Duration
+	Reason          string // Set for manual bans
 }
 
 type Fail2Ban struct {
@@ -160,7 +162,16 @@ func (f *Fail2Ban) RecordResponse(credentialName, modelID string, statusCode int
 	}
 }
 
+// IsBanned reports whether the credential+model pair is banned, either on its own or
+// by a manual ban of the whole credential
 func (f *Fail2Ban) IsBanned(credentialName, modelID string) bool {
+	if f.isKeyBanned(credentialName, modelID) {
+		return true
+	}
+	return modelID != "" && f.isKeyBanned(credentialName, "")
+}
+
+func (f *Fail2Ban) isKeyBanned(credentialName, modelID string) bool {
 	key := banKey(credentialName, modelID)
 
 	// First check with read lock
@@ -207,6 +218,30 @@ func (f *Fail2Ban) IsBanned(credentialName, modelID string) bool {
 	return true
 }
 
+// BanRemaining returns how long the credential+model pair stays banned, including a manual
+// ban of the whole credential. permanent is true for bans without expiry; a pair that is not
+// banned returns 0, false.
+func (f *Fail2Ban) BanRemaining(credentialName, modelID string) (remaining time.Duration, permanent bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	keys := []string{banKey(credentialName, modelID)}
+	if modelID != "" {
+		keys = append(keys, banKey(credentialName, ""))
+	}
+	for _, key := range keys {
+		ban, exists := f.banned[key]
+		if !exists {
+			continue
+		}
+		if ban.banDuration == 0 {
+			return 0, true
+		}
+		remaining = max(remaining, ban.banDuration-time.Since(ban.banTime))
+	}
+	return remaining, false
+}
+
 func (f *Fail2Ban) GetFailureCount(credentialName, modelID string) int {
 	key := banKey(credentialName, modelID)
 
@@ -226,7 +261,26 @@ func (f *Fail2Ban) GetFailureCount(credentialName, modelID string) int {
 	return total
 }
 
-func (f *Fail2Ban) Unban(credentialName, modelID string) {
+// Ban manually bans a credential+model pair, or every model of the credential when modelID
+// is empty, for duration (0 = permanent). An existing ban of the pair is replaced.
+func (f *Fail2Ban) Ban(credentialName, modelID string, duration time.Duration, reason string) {
+	if reason == "" {
+		reason = "manual"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.banned[banKey(credentialName, modelID)] = &banInfo{
+		banTime:     utils.NowUTC(),
+		banDuration: duration,
+		reason:      reason,
+	}
+	monitoring.CredentialBanEvents.WithLabelValues(credentialName, modelID, "manual").Inc()
+}
+
+// Unban lifts the ban of a credential+model pair (modelID "" = the credential-wide ban)
+// and resets its failure counters. Returns false if the pair was not banned.
+func (f *Fail2Ban) Unban(credentialName, modelID string) bool {
 	key := banKey(credentialName, modelID)
 
 	f.mu.Lock()
@@ -236,23 +290,56 @@ func (f *Fail2Ban) Unban(credentialName, modelID string) {
 		delete(f.failures, key)
 		// Record unban event only if pair was actually banned
 		monitoring.CredentialUnbanEvents.WithLabelValues(credentialName, modelID).Inc()
+		return true
 	}
+	return false
 }
 
-// UnbanCredential unbans ALL models for a given credential
-func (f *Fail2Ban) UnbanCredential(credentialName string) {
+// UnbanCredential unbans ALL models for a given credential and returns the number of lifted bans
+func (f *Fail2Ban) UnbanCredential(credentialName string) int {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	prefix := credentialName + "|"
+	unbanned := 0
 	for key := range f.banned {
 		if strings.HasPrefix(key, prefix) {
 			_, model := parseBanKey(key)
 			delete(f.banned, key)
 			delete(f.failures, key)
 			monitoring.CredentialUnbanEvents.WithLabelValues(credentialName, model).Inc()
+			unbanned++
+		}
+	}
+	return unbanned
+}
+
+// ResetFailures clears the failure counters of a credential+model pair, or of every model of
+// the credential when modelID is empty, without lifting bans. Returns the number of reset pairs.
+func (f *Fail2Ban) ResetFailures(credentialName, modelID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if modelID != "" {
+		key := banKey(credentialName, modelID)
+		if _, exists := f.failures[key]; !exists {
+			return 0
+		}
+		delete(f.failures, key)
+		delete(f.lastError, key)
+		return 1
+	}
+
+	prefix := credentialName + "|"
+	reset := 0
+	for key := range f.failures {
+		if strings.HasPrefix(key, prefix) {
+			delete(f.failures, key)
+			delete(f.lastError, key)
+			reset++
 		}
 	}
+	return reset
 }
 
 // HasAnyBan returns true if any model on the given credential is currently banned
@@ -311,6 +398,7 @@ func (f *Fail2Ban) GetBannedPairs() []BanPair {
 			ErrorCodeCounts: counts,
 			BanTime:         ban.banTime,
 			BanDuration:     ban.banDuration,
+			Reason:          ban.reason,
 		})
 	}
 	return pairs
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -637,3 +638,65 @@ func TestGetBannedModelsForCredential(t *testing.T) {
 	models2 := f2b.GetBannedModelsForCredential("cred2")
 	assert.Len(t, models2, 0)
 }
+
+func TestBan_Manual(t *testing.T) {
+	f2b := New(3, 0, []int{500})
+
+	f2b.Ban("cred1", "gpt-4", time.Hour, "")
+	assert.True(t, f2b.IsBanned("cred1", "gpt-4"))
+	assert.False(t, f2b.IsBanned("cred1", "gpt-3.5"))
+
+	f2b.Ban("cred2", "", 0, "maintenance")
+	assert.True(t, f2b.IsBanned("cred2", "gpt-4"), "credential-wide ban covers every model")
+	assert.True(t, f2b.HasAnyBan("cred2"))
+
+	pairs := f2b.GetBannedPairs()
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Credential < pairs[j].Credential })
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "manual", pairs[0].Reason)
+	assert.Equal(t, time.Hour, pairs[0].BanDuration)
+	assert.Equal(t, "maintenance", pairs[1].Reason)
+
+	assert.False(t, f2b.Unban("cred2", "gpt-4"), "a single model of a credential-wide ban is not banned on its own")
+	assert.True(t, f2b.Unban("cred2", ""))
+	assert.False(t, f2b.IsBanned("cred2", "gpt-4"))
+	assert.Equal(t, 1, f2b.UnbanCredential("cred1"))
+	assert.Zero(t, f2b.UnbanCredential("cred1"))
+}
+
+func TestResetFailures(t *testing.T) {
+	f2b := New(3, 0, []int{500})
+	f2b.RecordResponse("cred1", "gpt-4", 500)
+	f2b.RecordResponse("cred1", "gpt-3.5", 500)
+	f2b.RecordResponse("cred2", "gpt-4", 500)
+
+	assert.Equal(t, 1, f2b.ResetFailures("cred1", "gpt-4"))
+	assert.Zero(t, f2b.GetFailureCount("cred1", "gpt-4"))
+	assert.Equal(t, 1, f2b.GetFailureCount("cred1", "gpt-3.5"))
+	assert.Zero(t, f2b.ResetFailures("cred1", "gpt-4"))
+
+	assert.Equal(t, 1, f2b.ResetFailures("cred1", ""))
+	assert.Zero(t, f2b.GetFailureCount("cred1", "gpt-3.5"))
+	assert.Equal(t, 1, f2b.GetFailureCount("cred2", "gpt-4"), "other credentials keep their counters")
+}
+
+func TestBanRemaining(t *testing.T) {
+	f2b := New(1, 0, []int{500})
+
+	remaining, permanent := f2b.BanRemaining("cred1", "gpt-4")
+	assert.Zero(t, remaining)
+	assert.False(t, permanent, "not banned")
+
+	f2b.Ban("cred1", "gpt-4", time.Hour, "")
+	remaining, permanent = f2b.BanRemaining("cred1", "gpt-4")
+	assert.False(t, permanent)
+	assert.InDelta(t, time.Hour.Seconds(), remaining.Seconds(), 1)
+
+	f2b.Ban("cred1", "", 2*time.Hour, "maintenance")
+	remaining, _ = f2b.BanRemaining("cred1", "gpt-4")
+	assert.InDelta(t, (2 * time.Hour).Seconds(), remaining.Seconds(), 1, "the longer credential-wide ban applies")
+
+	f2b.RecordResponse("cred2", "gpt-4", 500)
+	_, permanent = f2b.BanRemaining("cred2", "gpt-4")
+	assert.True(t, permanent, "ban duration 0 never expires")
+}
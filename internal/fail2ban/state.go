@@ -0,0 +1,160 @@
+package fail2ban
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// State is the fail2ban state persisted across restarts (fail2ban.state_file)
+type State struct {
+	SavedAt  time.Time      `json:"saved_at"`
+	Bans     []BanState     `json:"bans"`
+	Failures []FailureState `json:"failures"`
+}
+
+// BanState is a persisted ban of a credential+model pair
+type BanState struct {
+	Credential  string        `json:"credential"`
+	Model       string        `json:"model"`
+	ErrorCode   int           `json:"error_code"`
+	BanTime     time.Time     `json:"ban_time"`
+	BanDuration time.Duration `json:"ban_duration"`     // 0 = permanent
+	Reason      string        `json:"reason,omitempty"` // Set for manual bans
+}
+
+// FailureState holds the persisted failure counters of a credential+model pair
+type FailureState struct {
+	Credential string      `json:"credential"`
+	Model      string      `json:"model"`
+	Counts     map[int]int `json:"counts"` // status code -> count
+	LastError  time.Time   `json:"last_error"`
+}
+
+// Snapshot returns the current bans and failure counters. Expired temporary bans are left out.
+func (f *Fail2Ban) Snapshot() State {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	state := State{
+		SavedAt:  utils.NowUTC(),
+		Bans:     make([]BanState, 0, len(f.banned)),
+		Failures: make([]FailureState, 0, len(f.failures)),
+	}
+	for key, ban := range f.banned {
+		if ban.banDuration > 0 && time.Since(ban.banTime) > ban.banDuration {
+			continue
+		}
+		credential, model := parseBanKey(key)
+		state.Bans = append(state.Bans, BanState{
+			Credential:  credential,
+			Model:       model,
+			ErrorCode:   ban.errorCode,
+			BanTime:     ban.banTime,
+			BanDuration: ban.banDuration,
+			Reason:      ban.reason,
+		})
+	}
+	for key, codes := range f.failures {
+		if len(codes) == 0 {
+			continue
+		}
+		credential, model := parseBanKey(key)
+		counts := make(map[int]int, len(codes))
+		for code, count := range codes {
+			counts[code] = count
+		}
+		state.Failures = append(state.Failures, FailureState{
+			Credential: credential,
+			Model:      model,
+			Counts:     counts,
+			LastError:  f.lastError[key],
+		})
+	}
+	return state
+}
+
+// Restore loads bans and failure counters saved by Snapshot, replacing the current state of
+// the same pairs. Temporary bans keep their original ban time, so downtime counts towards
+// ban_duration and bans that expired meanwhile are dropped. Pairs of credentials for which
+// known returns false (removed from the config) are skipped.
+// Returns the number of restored bans and failure counters.
+func (f *Fail2Ban) Restore(state State, known func(credential string) bool) (bans, failures int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, ban := range state.Bans {
+		if !known(ban.Credential) || (ban.BanDuration > 0 && time.Since(ban.BanTime) > ban.BanDuration) {
+			continue
+		}
+		f.banned[banKey(ban.Credential, ban.Model)] = &banInfo{
+			banTime:     ban.BanTime,
+			banDuration: ban.BanDuration,
+			errorCode:   ban.ErrorCode,
+			reason:      ban.Reason,
+		}
+		bans++
+	}
+	for _, failure := range state.Failures {
+		if !known(failure.Credential) || len(failure.Counts) == 0 {
+			continue
+		}
+		key := banKey(failure.Credential, failure.Model)
+		counts := make(map[int]int, len(failure.Counts))
+		for code, count := range failure.Counts {
+			counts[code] = count
+		}
+		f.failures[key] = counts
+		f.lastError[key] = failure.LastError
+		failures++
+	}
+	return bans, failures
+}
+
+// SaveState writes state to path as JSON. The file is replaced atomically, so a crash
+// while saving leaves the previous state intact.
+func SaveState(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fail2ban state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create fail2ban state file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write fail2ban state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write fail2ban state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace fail2ban state file: %w", err)
+	}
+	return nil
+}
+
+// LoadState reads a state written by SaveState. A missing file is an empty state.
+func LoadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read fail2ban state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse fail2ban state file: %w", err)
+	}
+	return state, nil
+}
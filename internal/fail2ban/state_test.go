@@ -0,0 +1,92 @@
+package fail2ban
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	f2b := NewWithRules(2, 0, []int{401, 429, 500}, []ErrorCodeRule{{Code: 429, MaxAttempts: 2, BanDuration: time.Hour}})
+	f2b.RecordResponse("cred1", "gpt-4", 401)
+	f2b.RecordResponse("cred1", "gpt-4", 401)
+	f2b.RecordResponse("cred2", "gpt-4", 429)
+	f2b.RecordResponse("cred2", "gpt-4", 429)
+	f2b.RecordResponse("cred3", "gpt-4", 500)
+	f2b.RecordResponse("removed", "gpt-4", 401)
+	f2b.RecordResponse("removed", "gpt-4", 401)
+
+	state := f2b.Snapshot()
+	assert.Len(t, state.Bans, 3)
+	assert.Len(t, state.Failures, 4)
+
+	restored := NewWithRules(2, 0, []int{401, 429, 500}, nil)
+	bans, failures := restored.Restore(state, func(credential string) bool { return credential != "removed" })
+	assert.Equal(t, 2, bans)
+	assert.Equal(t, 3, failures)
+
+	assert.True(t, restored.IsBanned("cred1", "gpt-4"), "permanent ban is restored")
+	assert.True(t, restored.IsBanned("cred2", "gpt-4"), "temporary ban is restored")
+	assert.False(t, restored.IsBanned("removed", "gpt-4"), "bans of removed credentials are skipped")
+	assert.Equal(t, 1, restored.GetFailureCount("cred3", "gpt-4"))
+
+	// One more 500 bans cred3: the restored counter carries over
+	restored.RecordResponse("cred3", "gpt-4", 500)
+	assert.True(t, restored.IsBanned("cred3", "gpt-4"))
+
+	for _, pair := range restored.GetBannedPairs() {
+		if pair.Credential == "cred2" {
+			assert.Equal(t, time.Hour, pair.BanDuration)
+			assert.Equal(t, 429, pair.ErrorCode)
+		}
+	}
+}
+
+func TestRestore_ExpiredBan(t *testing.T) {
+	state := State{Bans: []BanState{
+		{Credential: "cred1", Model: "gpt-4", ErrorCode: 429, BanTime: time.Now().Add(-2 * time.Hour), BanDuration: time.Hour},
+		{Credential: "cred2", Model: "gpt-4", ErrorCode: 429, BanTime: time.Now().Add(-30 * time.Minute), BanDuration: time.Hour},
+	}}
+
+	f2b := New(3, 0, []int{429})
+	bans, _ := f2b.Restore(state, func(string) bool { return true })
+	assert.Equal(t, 1, bans)
+	assert.False(t, f2b.IsBanned("cred1", "gpt-4"), "ban that expired while the router was down is dropped")
+	assert.True(t, f2b.IsBanned("cred2", "gpt-4"))
+
+	// Downtime counts towards the ban duration
+	for _, pair := range f2b.GetBannedPairs() {
+		assert.WithinDuration(t, time.Now().Add(-30*time.Minute), pair.BanTime, time.Second)
+	}
+}
+
+func TestSaveLoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fail2ban.json")
+
+	state, err := LoadState(path)
+	require.NoError(t, err, "missing file is an empty state")
+	assert.Empty(t, state.Bans)
+
+	f2b := New(1, 0, []int{401})
+	f2b.RecordResponse("cred1", "gpt-4", 401)
+	require.NoError(t, SaveState(path, f2b.Snapshot()))
+	require.NoError(t, SaveState(path, f2b.Snapshot()), "existing file is replaced")
+
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	require.Len(t, state.Bans, 1)
+	assert.Equal(t, "cred1", state.Bans[0].Credential)
+	assert.Equal(t, map[int]int{401: 1}, state.Failures[0].Counts)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	_, err = LoadState(path)
+	assert.ErrorContains(t, err, "failed to parse fail2ban state file")
+}
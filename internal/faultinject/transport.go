@@ -0,0 +1,107 @@
+// Package faultinject simulates upstream failures in the proxy transport (dev mode),
+// so fail2ban rules, fallback chains and client retries can be tested without real provider errors.
+package faultinject
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// MatchAll is the rule key applied to credentials without their own rule
+const MatchAll = "*"
+
+// Rule sets fault probabilities for a credential
+type Rule struct {
+	RateLimitProbability   float64       // Probability of a synthetic 429 response
+	ServerErrorProbability float64       // Probability of a synthetic 500 response
+	Latency                time.Duration // Delay added before the request is sent
+	LatencyProbability     float64       // Probability of adding Latency
+}
+
+// Transport wraps an http.RoundTripper and injects faults into requests tagged with
+// httputil.WithCredential. Untagged requests and credentials without a rule pass through unchanged.
+type Transport struct {
+	next  http.RoundTripper
+	rules map[string]Rule
+	rand  func() float64
+}
+
+// NewTransport creates a fault injecting Transport; rules are keyed by credential name or MatchAll
+func NewTransport(next http.RoundTripper, rules map[string]Rule) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, rules: rules, rand: rand.Float64}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cred := httputil.CredentialFromContext(req.Context())
+	if cred == nil {
+		return t.next.RoundTrip(req)
+	}
+	credentialName := cred.Name
+	rule, ok := t.rules[credentialName]
+	if !ok {
+		rule, ok = t.rules[MatchAll]
+	}
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	if rule.Latency > 0 && t.rand() < rule.LatencyProbability {
+		monitoring.FaultInjectionsTotal.WithLabelValues(credentialName, "latency").Inc()
+		timer := time.NewTimer(rule.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	// One draw decides between 429, 500 and passing through, so the probabilities add up
+	roll := t.rand()
+	switch {
+	case roll < rule.RateLimitProbability:
+		monitoring.FaultInjectionsTotal.WithLabelValues(credentialName, "rate_limit").Inc()
+		return faultResponse(req, http.StatusTooManyRequests, "rate_limit_exceeded"), nil
+	case roll < rule.RateLimitProbability+rule.ServerErrorProbability:
+		monitoring.FaultInjectionsTotal.WithLabelValues(credentialName, "server_error").Inc()
+		return faultResponse(req, http.StatusInternalServerError, "server_error"), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// faultResponse builds an OpenAI-style error response without contacting the upstream
+func faultResponse(req *http.Request, statusCode int, errorType string) *http.Response {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	body := fmt.Sprintf(`{"error":{"message":"Injected fault: %s","type":%q,"code":%d}}`,
+		http.StatusText(statusCode), errorType, statusCode)
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	if statusCode == http.StatusTooManyRequests {
+		header.Set("Retry-After", "1")
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,105 @@
+package faultinject
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUpstream(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func withCredential(name string) context.Context {
+	return httputil.WithCredential(context.Background(), &config.CredentialConfig{Name: name})
+}
+
+func doRequest(t *testing.T, client *http.Client, ctx context.Context, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestTransport_InjectsErrors(t *testing.T) {
+	var calls int32
+	upstream := newUpstream(t, &calls)
+
+	transport := NewTransport(nil, map[string]Rule{
+		"flaky":  {RateLimitProbability: 1},
+		"broken": {ServerErrorProbability: 1},
+	})
+	client := &http.Client{Transport: transport}
+
+	resp := doRequest(t, client, withCredential("flaky"), upstream.URL)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "Injected fault")
+
+	resp = doRequest(t, client, withCredential("broken"), upstream.URL)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// Credentials without a rule and untagged requests pass through
+	resp = doRequest(t, client, withCredential("healthy"), upstream.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, client, context.Background(), upstream.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestTransport_ProbabilitiesShareOneDraw(t *testing.T) {
+	var calls int32
+	upstream := newUpstream(t, &calls)
+
+	transport := NewTransport(nil, map[string]Rule{MatchAll: {RateLimitProbability: 0.2, ServerErrorProbability: 0.3}})
+	client := &http.Client{Transport: transport}
+
+	var statuses []int
+	for _, roll := range []float64{0.1, 0.3, 0.6} {
+		transport.rand = func() float64 { return roll }
+		statuses = append(statuses, doRequest(t, client, withCredential("any"), upstream.URL).StatusCode)
+	}
+	assert.Equal(t, []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK}, statuses)
+}
+
+func TestTransport_Latency(t *testing.T) {
+	var calls int32
+	upstream := newUpstream(t, &calls)
+
+	transport := NewTransport(nil, map[string]Rule{"slow": {Latency: 50 * time.Millisecond, LatencyProbability: 1}})
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	resp := doRequest(t, client, withCredential("slow"), upstream.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// Cancellation interrupts the injected delay
+	transport.rules["slow"] = Rule{Latency: time.Minute, LatencyProbability: 1}
+	ctx, cancel := context.WithTimeout(withCredential("slow"), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upstream.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
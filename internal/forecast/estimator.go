@@ -0,0 +1,275 @@
+// Package forecast estimates when credentials will exhaust their daily/monthly token and
+// spend quotas at the current usage rate, and warns before they do.
+package forecast
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Quota periods and kinds
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+	KindTokens    = "tokens"
+	KindSpend     = "spend"
+)
+
+// Quota is the usage allowed to a credential per UTC day and month (0 = no quota)
+type Quota struct {
+	DailyTokens   int64
+	MonthlyTokens int64
+	DailySpend    float64 // USD
+	MonthlySpend  float64 // USD
+}
+
+// Config configures an Estimator
+type Config struct {
+	Interval      time.Duration // How often forecasts are refreshed (metrics and warnings)
+	Window        time.Duration // Rolling window the current usage rate is measured over
+	WarnThreshold time.Duration // Warn when a quota runs out within this duration
+}
+
+// Forecast is the expected exhaustion of one quota at the current usage rate
+type Forecast struct {
+	Period      string
+	Kind        string
+	Used        float64
+	Limit       float64
+	RatePerHour float64
+	// TimeToExhaustion is nil if the quota does not run out before its period resets
+	TimeToExhaustion *time.Duration
+	Warning          bool
+}
+
+// bucket aggregates usage over one minute of the rate window
+type bucket struct {
+	minute time.Time
+	tokens float64
+	spend  float64
+}
+
+// usage tracks a credential's consumption in the current day/month and recent minutes
+type usage struct {
+	day, month             time.Time
+	dayTokens, monthTokens float64
+	daySpend, monthSpend   float64
+	recent                 []bucket
+	warned                 map[string]bool
+}
+
+// Estimator tracks per-credential usage and forecasts quota exhaustion.
+// Usage is kept in memory and starts from zero when the router restarts.
+// A nil *Estimator is valid and records nothing.
+type Estimator struct {
+	cfg     Config
+	quotas  map[string]Quota
+	logger  *slog.Logger
+	now     func() time.Time
+	started time.Time
+
+	mu    sync.Mutex
+	usage map[string]*usage
+}
+
+// New creates an Estimator for the credentials with quotas.
+// Returns nil if no credential has a quota.
+func New(cfg Config, quotas map[string]Quota, logger *slog.Logger) *Estimator {
+	if len(quotas) == 0 {
+		return nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	e := &Estimator{
+		cfg:    cfg,
+		quotas: quotas,
+		logger: logger,
+		now:    utils.NowUTC,
+		usage:  make(map[string]*usage, len(quotas)),
+	}
+	e.started = e.now()
+	return e
+}
+
+// Record adds the tokens and cost of a completed request
+func (e *Estimator) Record(credential string, tokens int, cost float64) {
+	if e == nil {
+		return
+	}
+	if _, ok := e.quotas[credential]; !ok {
+		return
+	}
+
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	u := e.usageLocked(credential, now)
+	u.dayTokens += float64(tokens)
+	u.monthTokens += float64(tokens)
+	u.daySpend += cost
+	u.monthSpend += cost
+
+	minute := now.Truncate(time.Minute)
+	if n := len(u.recent); n > 0 && u.recent[n-1].minute.Equal(minute) {
+		u.recent[n-1].tokens += float64(tokens)
+		u.recent[n-1].spend += cost
+		return
+	}
+	u.recent = append(u.recent, bucket{minute: minute, tokens: float64(tokens), spend: cost})
+}
+
+// Forecasts returns the forecasts of every quota configured for a credential
+func (e *Estimator) Forecasts(credential string) []Forecast {
+	if e == nil {
+		return nil
+	}
+	quota, ok := e.quotas[credential]
+	if !ok {
+		return nil
+	}
+
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.forecastsLocked(quota, e.usageLocked(credential, now), now)
+}
+
+// Run refreshes quota metrics and logs warnings every Interval until ctx is cancelled
+func (e *Estimator) Run(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.refresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh updates quota metrics and logs a warning when a quota enters the warning threshold
+func (e *Estimator) refresh() {
+	credentials := make([]string, 0, len(e.quotas))
+	for credential := range e.quotas {
+		credentials = append(credentials, credential)
+	}
+	sort.Strings(credentials)
+
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, credential := range credentials {
+		u := e.usageLocked(credential, now)
+		for _, f := range e.forecastsLocked(e.quotas[credential], u, now) {
+			seconds := math.Inf(1)
+			if f.TimeToExhaustion != nil {
+				seconds = f.TimeToExhaustion.Seconds()
+			}
+			monitoring.QuotaExhaustionSeconds.WithLabelValues(credential, f.Period, f.Kind).Set(seconds)
+			monitoring.QuotaUsedRatio.WithLabelValues(credential, f.Period, f.Kind).Set(f.Used / f.Limit)
+
+			key := f.Period + "_" + f.Kind
+			if !f.Warning {
+				u.warned[key] = false
+				continue
+			}
+			if u.warned[key] {
+				continue
+			}
+			u.warned[key] = true
+			monitoring.QuotaWarningsTotal.WithLabelValues(credential, f.Period, f.Kind).Inc()
+			e.logger.Warn("Credential quota will be exhausted soon",
+				"credential", credential,
+				"quota", key,
+				"used", f.Used,
+				"limit", f.Limit,
+				"rate_per_hour", f.RatePerHour,
+				"time_to_exhaustion", f.TimeToExhaustion.Round(time.Second).String(),
+			)
+		}
+	}
+}
+
+// usageLocked returns the usage of a credential, resetting counters of elapsed periods
+// and dropping buckets older than the rate window. Caller must hold e.mu.
+func (e *Estimator) usageLocked(credential string, now time.Time) *usage {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	u, ok := e.usage[credential]
+	if !ok {
+		u = &usage{day: day, month: month, warned: make(map[string]bool)}
+		e.usage[credential] = u
+	}
+	if !u.day.Equal(day) {
+		u.day, u.dayTokens, u.daySpend = day, 0, 0
+	}
+	if !u.month.Equal(month) {
+		u.month, u.monthTokens, u.monthSpend = month, 0, 0
+	}
+
+	cutoff := now.Add(-e.cfg.Window)
+	drop := 0
+	for drop < len(u.recent) && u.recent[drop].minute.Add(time.Minute).Before(cutoff) {
+		drop++
+	}
+	u.recent = u.recent[drop:]
+	return u
+}
+
+// forecastsLocked computes forecasts for the configured quotas. Caller must hold e.mu.
+func (e *Estimator) forecastsLocked(quota Quota, u *usage, now time.Time) []Forecast {
+	// Until a full window has passed since startup, measure the rate over the elapsed time
+	window := min(e.cfg.Window, max(now.Sub(e.started), time.Minute))
+	var recentTokens, recentSpend float64
+	for _, b := range u.recent {
+		recentTokens += b.tokens
+		recentSpend += b.spend
+	}
+	tokensPerHour := recentTokens / window.Hours()
+	spendPerHour := recentSpend / window.Hours()
+
+	dayEnd := u.day.AddDate(0, 0, 1)
+	monthEnd := u.month.AddDate(0, 1, 0)
+
+	var forecasts []Forecast
+	add := func(period, kind string, used, limit, ratePerHour float64, resetAt time.Time) {
+		if limit <= 0 {
+			return
+		}
+		f := Forecast{Period: period, Kind: kind, Used: used, Limit: limit, RatePerHour: ratePerHour}
+		remaining := limit - used
+		switch {
+		case remaining <= 0:
+			tte := time.Duration(0)
+			f.TimeToExhaustion = &tte
+		case ratePerHour > 0:
+			tte := time.Duration(remaining / ratePerHour * float64(time.Hour))
+			if now.Add(tte).Before(resetAt) {
+				f.TimeToExhaustion = &tte
+			}
+		}
+		f.Warning = f.TimeToExhaustion != nil && *f.TimeToExhaustion <= e.cfg.WarnThreshold
+		forecasts = append(forecasts, f)
+	}
+	add(PeriodDaily, KindTokens, u.dayTokens, float64(quota.DailyTokens), tokensPerHour, dayEnd)
+	add(PeriodDaily, KindSpend, u.daySpend, quota.DailySpend, spendPerHour, dayEnd)
+	add(PeriodMonthly, KindTokens, u.monthTokens, float64(quota.MonthlyTokens), tokensPerHour, monthEnd)
+	add(PeriodMonthly, KindSpend, u.monthSpend, quota.MonthlySpend, spendPerHour, monthEnd)
+	return forecasts
+}
@@ -0,0 +1,116 @@
+package forecast
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEstimator(t *testing.T, now *time.Time, quotas map[string]Quota) (*Estimator, *bytes.Buffer) {
+	t.Helper()
+	var logs bytes.Buffer
+	e := New(Config{Interval: time.Minute, Window: 10 * time.Minute, WarnThreshold: 2 * time.Hour},
+		quotas, slog.New(slog.NewTextHandler(&logs, nil)))
+	require.NotNil(t, e)
+	e.now = func() time.Time { return *now }
+	e.started = now.Add(-time.Hour)
+	return e, &logs
+}
+
+func forecastFor(forecasts []Forecast, period, kind string) *Forecast {
+	for i := range forecasts {
+		if forecasts[i].Period == period && forecasts[i].Kind == kind {
+			return &forecasts[i]
+		}
+	}
+	return nil
+}
+
+func TestEstimator_ForecastsExhaustion(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	e, _ := newTestEstimator(t, &now, map[string]Quota{
+		"openai": {DailyTokens: 1_000_000, DailySpend: 100, MonthlyTokens: 1_000_000_000},
+	})
+
+	// 10k tokens and $1 per minute over the 10 minute window: 600k tokens/h, $60/h
+	for i := 0; i < 10; i++ {
+		e.Record("openai", 10_000, 1)
+		now = now.Add(time.Minute)
+	}
+
+	forecasts := e.Forecasts("openai")
+	require.Len(t, forecasts, 3, "quotas set to 0 are not forecast")
+
+	tokens := forecastFor(forecasts, PeriodDaily, KindTokens)
+	require.NotNil(t, tokens)
+	assert.Equal(t, 100_000.0, tokens.Used)
+	assert.InDelta(t, 600_000, tokens.RatePerHour, 1)
+	require.NotNil(t, tokens.TimeToExhaustion)
+	assert.InDelta(t, (90 * time.Minute).Seconds(), tokens.TimeToExhaustion.Seconds(), 1)
+	assert.True(t, tokens.Warning)
+
+	spend := forecastFor(forecasts, PeriodDaily, KindSpend)
+	require.NotNil(t, spend)
+	require.NotNil(t, spend.TimeToExhaustion)
+	assert.InDelta(t, (90 * time.Minute).Seconds(), spend.TimeToExhaustion.Seconds(), 1)
+
+	// Exhaustion after the month resets is not forecast
+	monthly := forecastFor(forecasts, PeriodMonthly, KindTokens)
+	require.NotNil(t, monthly)
+	assert.Nil(t, monthly.TimeToExhaustion)
+	assert.False(t, monthly.Warning)
+
+	// Without recent usage the rate drops to zero
+	now = now.Add(time.Hour)
+	tokens = forecastFor(e.Forecasts("openai"), PeriodDaily, KindTokens)
+	assert.Zero(t, tokens.RatePerHour)
+	assert.Nil(t, tokens.TimeToExhaustion)
+
+	assert.Nil(t, e.Forecasts("unknown"))
+}
+
+func TestEstimator_ResetsPeriods(t *testing.T) {
+	now := time.Date(2025, 6, 30, 23, 59, 0, 0, time.UTC)
+	e, _ := newTestEstimator(t, &now, map[string]Quota{"vertex": {DailyTokens: 100, MonthlyTokens: 1000}})
+
+	e.Record("vertex", 150, 0)
+	daily := forecastFor(e.Forecasts("vertex"), PeriodDaily, KindTokens)
+	require.NotNil(t, daily.TimeToExhaustion)
+	assert.Zero(t, *daily.TimeToExhaustion, "exhausted quotas report zero")
+
+	now = now.Add(2 * time.Minute)
+	forecasts := e.Forecasts("vertex")
+	assert.Zero(t, forecastFor(forecasts, PeriodDaily, KindTokens).Used)
+	assert.Zero(t, forecastFor(forecasts, PeriodMonthly, KindTokens).Used)
+}
+
+func TestEstimator_RefreshWarnsOnce(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	e, logs := newTestEstimator(t, &now, map[string]Quota{"openai": {DailyTokens: 1000}})
+
+	e.Record("openai", 900, 0)
+	e.refresh()
+	e.refresh()
+	assert.Equal(t, 1, strings.Count(logs.String(), "quota will be exhausted soon"))
+	assert.Contains(t, logs.String(), "quota=daily_tokens")
+
+	// A new day clears the warning, the next one is logged again
+	now = now.Add(24 * time.Hour)
+	e.refresh()
+	e.Record("openai", 990, 0)
+	e.refresh()
+	assert.Equal(t, 2, strings.Count(logs.String(), "quota will be exhausted soon"))
+}
+
+func TestEstimator_Nil(t *testing.T) {
+	assert.Nil(t, New(Config{}, nil, nil))
+
+	var e *Estimator
+	e.Record("openai", 10, 1)
+	assert.Nil(t, e.Forecasts("openai"))
+}
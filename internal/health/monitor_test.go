@@ -26,14 +26,15 @@ func (m *MockDBManager) ValidateToken(ctx context.Context, rawToken string) (*mo
 func (m *MockDBManager) ValidateTokenForModel(ctx context.Context, rawToken, model string) (*models.TokenInfo, error) {
 	return nil, nil
 }
-func (m *MockDBManager) LogSpend(entry *models.SpendLogEntry) error { return nil }
-func (m *MockDBManager) IsEnabled() bool                            { return true }
-func (m *MockDBManager) IsHealthy() bool                            { return m.healthy }
-func (m *MockDBManager) AuthCacheStats() models.AuthCacheStats      { return models.AuthCacheStats{} }
-func (m *MockDBManager) SpendLoggerStats() models.SpendLoggerStats  { return models.SpendLoggerStats{} }
-func (m *MockDBManager) ConnectionStats() *pgxpool.Stat             { return nil }
-func (m *MockDBManager) GetPool() *pgxpool.Pool                     { return nil }
-func (m *MockDBManager) Shutdown(ctx context.Context) error         { return nil }
+func (m *MockDBManager) LogSpend(ctx context.Context, entry *models.SpendLogEntry) error { return nil }
+func (m *MockDBManager) IsEnabled() bool                                                 { return true }
+func (m *MockDBManager) IsHealthy() bool                                                 { return m.healthy }
+func (m *MockDBManager) AuthCacheStats() models.AuthCacheStats                           { return models.AuthCacheStats{} }
+func (m *MockDBManager) SpendLoggerStats() models.SpendLoggerStats                       { return models.SpendLoggerStats{} }
+func (m *MockDBManager) ConnectionStats() *pgxpool.Stat                                  { return nil }
+func (m *MockDBManager) GetPool() *pgxpool.Pool                                          { return nil }
+func (m *MockDBManager) SetReadOnly(readOnly bool)                                       {}
+func (m *MockDBManager) Shutdown(ctx context.Context) error                              { return nil }
 
 // Compile-time check
 var _ litellmdb.Manager = (*MockDBManager)(nil)
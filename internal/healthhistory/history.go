@@ -0,0 +1,194 @@
+// Package healthhistory keeps per-credential request, token, error and spend histories at
+// one-minute resolution for the health dashboard. Histories are kept in memory, cover the
+// last MaxWindow and start empty when the router restarts.
+package healthhistory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+const (
+	// Resolution is the duration of one history point
+	Resolution = time.Minute
+	// MaxWindow is the longest history kept
+	MaxWindow = 24 * time.Hour
+	// DefaultWindow is the history returned when no window is requested
+	DefaultWindow = time.Hour
+
+	capacity = int(MaxWindow / Resolution)
+)
+
+// bucket aggregates the requests of one minute
+type bucket struct {
+	minute   int64 // Unix minute the bucket holds (a stale bucket is reused once its slot comes round again)
+	requests float64
+	errors   float64
+	tokens   float64
+	spend    float64
+}
+
+// ring holds the buckets of one credential, indexed by Unix minute modulo capacity
+type ring [capacity]bucket
+
+// Series is the history of one credential, one value per minute, oldest first
+type Series struct {
+	RPM       []float64 `json:"rpm"`        // Requests
+	TPM       []float64 `json:"tpm"`        // Prompt and completion tokens
+	ErrorRate []float64 `json:"error_rate"` // Share of failed requests (0 without requests)
+	Spend     []float64 `json:"spend"`      // USD
+}
+
+// Snapshot is the history of every credential over a window, as served by GET /health/history
+type Snapshot struct {
+	Window      string            `json:"window"`
+	Resolution  string            `json:"resolution"`
+	Start       time.Time         `json:"start"` // Start of the first point
+	Credentials map[string]Series `json:"credentials"`
+}
+
+// Recorder records completed requests into per-credential histories.
+// A nil *Recorder is valid and records nothing.
+type Recorder struct {
+	now func() time.Time
+
+	mu          sync.Mutex
+	credentials map[string]*ring
+}
+
+// New creates an empty Recorder
+func New() *Recorder {
+	return &Recorder{now: utils.NowUTC, credentials: make(map[string]*ring)}
+}
+
+// Record adds a completed request of credential with its tokens and cost
+func (r *Recorder) Record(credential string, tokens int, cost float64, failed bool) {
+	if r == nil {
+		return
+	}
+	minute := r.now().Unix() / int64(Resolution/time.Second)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	history, ok := r.credentials[credential]
+	if !ok {
+		history = new(ring)
+		r.credentials[credential] = history
+	}
+	b := &history[minute%int64(capacity)]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.requests++
+	if failed {
+		b.errors++
+	}
+	b.tokens += float64(tokens)
+	b.spend += cost
+}
+
+// Snapshot returns the histories of all recorded credentials over the last window
+// (rounded to whole minutes, clamped to [Resolution, MaxWindow]); the last point is the
+// current, still filling minute
+func (r *Recorder) Snapshot(window time.Duration) *Snapshot {
+	points := int(window / Resolution)
+	points = max(1, min(points, capacity))
+	snapshot := &Snapshot{
+		Window:      (time.Duration(points) * Resolution).String(),
+		Resolution:  Resolution.String(),
+		Credentials: make(map[string]Series),
+	}
+	if r == nil {
+		snapshot.Start = utils.NowUTC().Truncate(Resolution).Add(-time.Duration(points-1) * Resolution)
+		return snapshot
+	}
+
+	now := r.now()
+	last := now.Unix() / int64(Resolution/time.Second)
+	first := last - int64(points) + 1
+	snapshot.Start = time.Unix(first*int64(Resolution/time.Second), 0).UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for credential, history := range r.credentials {
+		series := Series{
+			RPM:       make([]float64, points),
+			TPM:       make([]float64, points),
+			ErrorRate: make([]float64, points),
+			Spend:     make([]float64, points),
+		}
+		for i := range points {
+			minute := first + int64(i)
+			b := history[minute%int64(capacity)]
+			if b.minute != minute {
+				continue
+			}
+			series.RPM[i] = b.requests
+			series.TPM[i] = b.tokens
+			series.Spend[i] = b.spend
+			if b.requests > 0 {
+				series.ErrorRate[i] = b.errors / b.requests
+			}
+		}
+		snapshot.Credentials[credential] = series
+	}
+	return snapshot
+}
+
+// Totals are the requests, tokens and spend of one credential summed over a window
+type Totals struct {
+	Requests int64   `json:"requests"`
+	Errors   int64   `json:"errors"`
+	Tokens   int64   `json:"tokens"`
+	Spend    float64 `json:"spend"` // USD
+}
+
+// Totals returns the summed histories over the last window (clamped like Snapshot) of the
+// credentials with requests in it; idle credentials are left out
+func (r *Recorder) Totals(window time.Duration) map[string]Totals {
+	totals := make(map[string]Totals)
+	if r == nil {
+		return totals
+	}
+	points := max(1, min(int(window/Resolution), capacity))
+	last := r.now().Unix() / int64(Resolution/time.Second)
+	first := last - int64(points) + 1
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for credential, history := range r.credentials {
+		var total Totals
+		for minute := first; minute <= last; minute++ {
+			b := history[minute%int64(capacity)]
+			if b.minute != minute {
+				continue
+			}
+			total.Requests += int64(b.requests)
+			total.Errors += int64(b.errors)
+			total.Tokens += int64(b.tokens)
+			total.Spend += b.spend
+		}
+		if total.Requests > 0 {
+			totals[credential] = total
+		}
+	}
+	return totals
+}
+
+// ParseWindow parses the window of a history request ("" = DefaultWindow)
+func ParseWindow(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultWindow, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q: %w", value, err)
+	}
+	if window < Resolution || window > MaxWindow {
+		return 0, fmt.Errorf("window must be between %s and %s", Resolution, MaxWindow)
+	}
+	return window, nil
+}
@@ -0,0 +1,114 @@
+package healthhistory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRecorder(now *time.Time) *Recorder {
+	r := New()
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestRecorder_Snapshot(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 30, 0, time.UTC)
+	r := newTestRecorder(&now)
+
+	r.Record("openai", 100, 0.01, false)
+	r.Record("openai", 50, 0.02, true)
+	now = now.Add(2 * time.Minute)
+	r.Record("openai", 10, 0, false)
+	r.Record("vertex", 5, 0.5, true)
+
+	snapshot := r.Snapshot(5 * time.Minute)
+	assert.Equal(t, "5m0s", snapshot.Window)
+	assert.Equal(t, "1m0s", snapshot.Resolution)
+	assert.Equal(t, time.Date(2025, 6, 1, 11, 58, 0, 0, time.UTC), snapshot.Start)
+
+	openai := snapshot.Credentials["openai"]
+	assert.Equal(t, []float64{0, 0, 2, 0, 1}, openai.RPM)
+	assert.Equal(t, []float64{0, 0, 150, 0, 10}, openai.TPM)
+	assert.Equal(t, []float64{0, 0, 0.5, 0, 0}, openai.ErrorRate)
+	assert.InDeltaSlice(t, []float64{0, 0, 0.03, 0, 0}, openai.Spend, 1e-9)
+
+	vertex := snapshot.Credentials["vertex"]
+	assert.Equal(t, []float64{0, 0, 0, 0, 1}, vertex.RPM)
+	assert.Equal(t, []float64{0, 0, 0, 0, 1}, vertex.ErrorRate)
+
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"error_rate":[0,0,0.5,0,0]`)
+}
+
+func TestRecorder_RingWrapsAround(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestRecorder(&now)
+	r.Record("openai", 100, 1, false)
+
+	// The slot of a minute is reused a full window later: the old bucket must not leak into it
+	now = now.Add(MaxWindow)
+	r.Record("openai", 1, 0, false)
+
+	series := r.Snapshot(MaxWindow).Credentials["openai"]
+	require.Len(t, series.RPM, capacity)
+	assert.Equal(t, 1.0, series.RPM[capacity-1])
+	assert.Equal(t, 1.0, series.TPM[capacity-1])
+	var total float64
+	for _, requests := range series.RPM {
+		total += requests
+	}
+	assert.Equal(t, 1.0, total, "the request of a day ago is out of the window")
+
+	// Windows are clamped to the kept history
+	assert.Len(t, r.Snapshot(48 * time.Hour).Credentials["openai"].RPM, capacity)
+	assert.Len(t, r.Snapshot(0).Credentials["openai"].RPM, 1)
+}
+
+func TestRecorder_Totals(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 30, 0, time.UTC)
+	r := newTestRecorder(&now)
+
+	r.Record("vertex", 5, 0.5, true)
+	now = now.Add(10 * time.Minute)
+	r.Record("openai", 100, 0.01, false)
+	r.Record("openai", 50, 0.02, true)
+
+	totals := r.Totals(5 * time.Minute)
+	require.Len(t, totals, 1, "idle credentials are left out")
+	assert.Equal(t, int64(2), totals["openai"].Requests)
+	assert.Equal(t, int64(1), totals["openai"].Errors)
+	assert.Equal(t, int64(150), totals["openai"].Tokens)
+	assert.InDelta(t, 0.03, totals["openai"].Spend, 1e-9)
+
+	assert.Len(t, r.Totals(MaxWindow), 2)
+	var nilRecorder *Recorder
+	assert.Empty(t, nilRecorder.Totals(time.Hour))
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+	r.Record("openai", 1, 1, false)
+	snapshot := r.Snapshot(time.Hour)
+	assert.Empty(t, snapshot.Credentials)
+	assert.Equal(t, "1h0m0s", snapshot.Window)
+}
+
+func TestParseWindow(t *testing.T) {
+	window, err := ParseWindow("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultWindow, window)
+
+	window, err = ParseWindow("24h")
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, window)
+
+	for _, value := range []string{"soon", "30s", "48h"} {
+		_, err := ParseWindow(value)
+		assert.Error(t, err, value)
+	}
+}
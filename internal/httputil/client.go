@@ -12,6 +12,7 @@ import (
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
 const (
@@ -102,6 +103,15 @@ var proxyHTTPClient = NewHTTPClient(&HTTPClientConfig{
 	IdleConnTimeout:     defaultIdleConnTimeout,
 })
 
+// StatusError is returned by FetchFromProxy when the proxy answers with a non-200 status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("proxy returned status %d", e.StatusCode)
+}
+
 // FetchFromProxy makes an HTTP GET request to a proxy credential
 // and returns the response body. Handles timeouts, auth headers, and error logging.
 // Note: caller should provide ctx with timeout if defaultTimeout is insufficient
@@ -142,8 +152,12 @@ func FetchFromProxy(
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Add Authorization header if api_key is set
-	if cred.APIKey != "" {
+	// Sign the request with hmac_secret (like proxied requests), else send the api_key
+	if cred.HMACSecret != "" {
+		if err := SignRequest(req, cred.HMACSecret, nil, utils.NowUTC()); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+	} else if cred.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+cred.APIKey)
 	}
 
@@ -172,7 +186,7 @@ func FetchFromProxy(
 			"status", resp.StatusCode,
 			"response_preview", preview,
 		)
-		return nil, fmt.Errorf("proxy returned status %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Read body with size limit
@@ -63,6 +63,30 @@ func TestFetchFromProxy_NoAPIKey(t *testing.T) {
 	assert.Equal(t, "ok", string(body))
 }
 
+func TestFetchFromProxy_HMACSecret(t *testing.T) {
+	const secret = "inter-router-secret-of-32-characters"
+	verifier := NewRequestVerifier(secret, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.Header.Get("Authorization"), "no key travels with a signed request")
+		assert.NoError(t, verifier.Verify(r, nil, time.Now()))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cred := &config.CredentialConfig{
+		Name:       "test_cred_signed",
+		BaseURL:    server.URL,
+		APIKey:     "test-api-key",
+		HMACSecret: secret,
+	}
+
+	body, err := FetchFromProxy(context.Background(), cred, FederationPath, testhelpers.NewTestLogger())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
+
 func TestFetchFromProxy_BaseURLTrailingSlash(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/test", r.URL.Path)
@@ -0,0 +1,21 @@
+package httputil
+
+import (
+	"context"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+type credentialKey struct{}
+
+// WithCredential tags an upstream request context with the credential it is sent for,
+// so transport wrappers (fault injection, mock provider) can act per credential
+func WithCredential(ctx context.Context, cred *config.CredentialConfig) context.Context {
+	return context.WithValue(ctx, credentialKey{}, cred)
+}
+
+// CredentialFromContext returns the credential set by WithCredential, or nil
+func CredentialFromContext(ctx context.Context) *config.CredentialConfig {
+	cred, _ := ctx.Value(credentialKey{}).(*config.CredentialConfig)
+	return cred
+}
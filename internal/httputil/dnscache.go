@@ -0,0 +1,62 @@
+package httputil
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache caches upstream DNS lookups for a fixed TTL, so requests do not pay the
+// resolver latency (and its spikes) on every new connection
+type DNSCache struct {
+	ttl    time.Duration
+	lookup func(ctx context.Context, host string) ([]string, error)
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewDNSCache creates a DNS cache keeping results of the system resolver for ttl
+func NewDNSCache(ttl time.Duration) *DNSCache {
+	return &DNSCache{
+		ttl:     ttl,
+		lookup:  net.DefaultResolver.LookupHost,
+		now:     time.Now,
+		entries: make(map[string]dnsEntry),
+	}
+}
+
+// LookupHost returns the addresses of host, from the cache while its entry is fresh.
+// When a refresh fails, the expired addresses are returned so that a resolver outage
+// does not take down upstreams that were reachable a moment ago.
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		if ok {
+			return entry.addrs, nil
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
@@ -0,0 +1,49 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSCache_LookupHost(t *testing.T) {
+	now := time.Now()
+	lookups := 0
+	var lookupErr error
+	cache := NewDNSCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	cache.lookup = func(_ context.Context, host string) ([]string, error) {
+		lookups++
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		return []string{"203.0.113.10", "203.0.113.11"}, nil
+	}
+
+	addrs, err := cache.LookupHost(context.Background(), "api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.10", "203.0.113.11"}, addrs)
+
+	_, err = cache.LookupHost(context.Background(), "api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, lookups, "fresh entries are served from the cache")
+
+	now = now.Add(2 * time.Minute)
+	_, err = cache.LookupHost(context.Background(), "api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 2, lookups, "expired entries are resolved again")
+
+	// A failed refresh serves the expired addresses; unknown hosts fail
+	now = now.Add(2 * time.Minute)
+	lookupErr = errors.New("resolver unavailable")
+	addrs, err = cache.LookupHost(context.Background(), "api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.10", "203.0.113.11"}, addrs)
+
+	_, err = cache.LookupHost(context.Background(), "other.example.com")
+	assert.ErrorIs(t, err, lookupErr)
+}
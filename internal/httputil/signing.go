@@ -0,0 +1,135 @@
+package httputil
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Inter-router authentication headers.
+// A parent router signs each request to a proxy credential with a shared secret,
+// so no bare API key travels between routers and captured requests cannot be replayed.
+const (
+	RouterTimestampHeader = "X-Router-Timestamp" // Unix seconds when the request was signed
+	RouterNonceHeader     = "X-Router-Nonce"     // Random per-request value (replay protection)
+	RouterSignatureHeader = "X-Router-Signature" // hex(HMAC-SHA256(secret, canonical request))
+
+	// DefaultRouterSignatureMaxAge is the accepted clock skew between routers;
+	// nonces are remembered for the same window
+	DefaultRouterSignatureMaxAge = 5 * time.Minute
+)
+
+var (
+	ErrSignatureMissing  = errors.New("missing inter-router signature headers")
+	ErrSignatureExpired  = errors.New("inter-router signature timestamp outside allowed window")
+	ErrSignatureInvalid  = errors.New("invalid inter-router signature")
+	ErrSignatureReplayed = errors.New("inter-router signature nonce already used")
+)
+
+// IsSignedRequest reports whether r carries an inter-router signature
+func IsSignedRequest(r *http.Request) bool {
+	return r.Header.Get(RouterSignatureHeader) != ""
+}
+
+// SignRequest sets inter-router signature headers on req for the given body
+func SignRequest(req *http.Request, secret string, body []byte, now time.Time) error {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req.Header.Set(RouterTimestampHeader, timestamp)
+	req.Header.Set(RouterNonceHeader, nonce)
+	req.Header.Set(RouterSignatureHeader, routerSignature(secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	return nil
+}
+
+// routerSignature computes the signature over method, request URI, timestamp, nonce and body digest
+func routerSignature(secret, method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + nonce + "\n"))
+	mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RequestVerifier validates inter-router signatures and rejects replayed nonces
+type RequestVerifier struct {
+	secret string
+	maxAge time.Duration
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time // nonce -> expiry
+	lastSweep time.Time
+}
+
+// NewRequestVerifier creates a verifier for the shared secret.
+// maxAge <= 0 uses DefaultRouterSignatureMaxAge.
+func NewRequestVerifier(secret string, maxAge time.Duration) *RequestVerifier {
+	if maxAge <= 0 {
+		maxAge = DefaultRouterSignatureMaxAge
+	}
+	return &RequestVerifier{
+		secret: secret,
+		maxAge: maxAge,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Verify checks the signature of r against body.
+// A valid nonce is consumed, so the same signed request is accepted only once.
+func (v *RequestVerifier) Verify(r *http.Request, body []byte, now time.Time) error {
+	timestamp := r.Header.Get(RouterTimestampHeader)
+	nonce := r.Header.Get(RouterNonceHeader)
+	signature := r.Header.Get(RouterSignatureHeader)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	signedAt := time.Unix(unix, 0)
+	if now.Sub(signedAt) > v.maxAge || signedAt.Sub(now) > v.maxAge {
+		return ErrSignatureExpired
+	}
+
+	expected := routerSignature(v.secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.sweepLocked(now)
+	if expiry, seen := v.nonces[nonce]; seen && now.Before(expiry) {
+		return ErrSignatureReplayed
+	}
+	// Keep the nonce until its timestamp leaves the allowed window
+	v.nonces[nonce] = signedAt.Add(v.maxAge)
+	return nil
+}
+
+// sweepLocked drops expired nonces at most once per maxAge. Caller must hold v.mu.
+func (v *RequestVerifier) sweepLocked(now time.Time) {
+	if now.Sub(v.lastSweep) < v.maxAge {
+		return
+	}
+	for nonce, expiry := range v.nonces {
+		if !now.Before(expiry) {
+			delete(v.nonces, nonce)
+		}
+	}
+	v.lastSweep = now
+}
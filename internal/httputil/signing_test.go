@@ -0,0 +1,91 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningSecret = "signing-secret-signing-secret-00"
+
+func newTestSignedRequest(t *testing.T, body string, now time.Time) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?x=1", nil)
+	require.NoError(t, SignRequest(req, testSigningSecret, []byte(body), now))
+	return req
+}
+
+func TestSignRequest_Verify(t *testing.T) {
+	now := time.Now()
+	req := newTestSignedRequest(t, `{"model":"gpt-4o"}`, now)
+
+	assert.True(t, IsSignedRequest(req))
+	assert.NotEmpty(t, req.Header.Get(RouterNonceHeader))
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), req.Header.Get(RouterTimestampHeader))
+
+	v := NewRequestVerifier(testSigningSecret, time.Minute)
+	assert.NoError(t, v.Verify(req, []byte(`{"model":"gpt-4o"}`), now))
+}
+
+func TestRequestVerifier_Rejects(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"model":"gpt-4o"}`)
+
+	t.Run("missing headers", func(t *testing.T) {
+		v := NewRequestVerifier(testSigningSecret, time.Minute)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		assert.ErrorIs(t, v.Verify(req, body, now), ErrSignatureMissing)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		v := NewRequestVerifier("another-secret-another-secret-00", time.Minute)
+		req := newTestSignedRequest(t, string(body), now)
+		assert.ErrorIs(t, v.Verify(req, body, now), ErrSignatureInvalid)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		v := NewRequestVerifier(testSigningSecret, time.Minute)
+		req := newTestSignedRequest(t, string(body), now)
+		assert.ErrorIs(t, v.Verify(req, []byte(`{"model":"o3"}`), now), ErrSignatureInvalid)
+	})
+
+	t.Run("tampered path", func(t *testing.T) {
+		v := NewRequestVerifier(testSigningSecret, time.Minute)
+		req := newTestSignedRequest(t, string(body), now)
+		req.URL.Path = "/v1/embeddings"
+		assert.ErrorIs(t, v.Verify(req, body, now), ErrSignatureInvalid)
+	})
+
+	t.Run("expired and future timestamps", func(t *testing.T) {
+		v := NewRequestVerifier(testSigningSecret, time.Minute)
+		old := newTestSignedRequest(t, string(body), now.Add(-2*time.Minute))
+		assert.ErrorIs(t, v.Verify(old, body, now), ErrSignatureExpired)
+		future := newTestSignedRequest(t, string(body), now.Add(2*time.Minute))
+		assert.ErrorIs(t, v.Verify(future, body, now), ErrSignatureExpired)
+	})
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		v := NewRequestVerifier(testSigningSecret, time.Minute)
+		req := newTestSignedRequest(t, string(body), now)
+		require.NoError(t, v.Verify(req, body, now))
+		assert.ErrorIs(t, v.Verify(req, body, now.Add(30*time.Second)), ErrSignatureReplayed)
+	})
+}
+
+func TestRequestVerifier_SweepsExpiredNonces(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{}`)
+	v := NewRequestVerifier(testSigningSecret, time.Minute)
+
+	require.NoError(t, v.Verify(newTestSignedRequest(t, "{}", now), body, now))
+	assert.Len(t, v.nonces, 1)
+
+	later := now.Add(2 * time.Minute)
+	require.NoError(t, v.Verify(newTestSignedRequest(t, "{}", later), body, later))
+	assert.Len(t, v.nonces, 1, "expired nonce is dropped")
+}
@@ -0,0 +1,190 @@
+package httputil
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// Connection states of the auto_ai_router_upstream_connections gauge
+const (
+	connStateActive = "active"
+	connStateIdle   = "idle"
+)
+
+// metricsDial counts the dials of dial per upstream host and returns connections tracked
+// in the connections gauge
+func metricsDial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			monitoring.UpstreamDialsTotal.WithLabelValues(host, "error").Inc()
+			return nil, err
+		}
+		monitoring.UpstreamDialsTotal.WithLabelValues(host, "success").Inc()
+		tracked := &trackedConn{Conn: conn, host: host}
+		tracked.setState(connStateActive)
+		return tracked, nil
+	}
+}
+
+// metricsTransport records connection reuse and DNS, connect, TLS handshake and
+// time-to-first-byte latencies of each request
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tracer := &requestTracer{host: req.URL.Hostname(), connectStart: make(map[string]time.Time)}
+	ctx := httptrace.WithClientTrace(req.Context(), tracer.clientTrace())
+	return t.next.RoundTrip(req.WithContext(ctx))
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *metricsTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// requestTracer observes the phases of one upstream request
+type requestTracer struct {
+	host string
+
+	mu           sync.Mutex
+	conn         *trackedConn
+	dnsStart     time.Time
+	connectStart map[string]time.Time // Dial start per address (dual-stack dials run in parallel)
+	tlsStart     time.Time
+	wroteRequest time.Time
+}
+
+func (rt *requestTracer) observe(phase string, start time.Time) {
+	if !start.IsZero() {
+		monitoring.UpstreamPhaseDuration.WithLabelValues(rt.host, phase).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (rt *requestTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			rt.mu.Lock()
+			rt.dnsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			rt.mu.Lock()
+			defer rt.mu.Unlock()
+			rt.observe("dns", rt.dnsStart)
+		},
+		ConnectStart: func(_, addr string) {
+			rt.mu.Lock()
+			rt.connectStart[addr] = time.Now()
+			rt.mu.Unlock()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			rt.mu.Lock()
+			defer rt.mu.Unlock()
+			if err == nil {
+				rt.observe("connect", rt.connectStart[addr])
+			}
+		},
+		TLSHandshakeStart: func() {
+			rt.mu.Lock()
+			rt.tlsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			rt.mu.Lock()
+			defer rt.mu.Unlock()
+			if err == nil {
+				rt.observe("tls", rt.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			monitoring.UpstreamConnectionsAcquiredTotal.WithLabelValues(rt.host, strconv.FormatBool(info.Reused)).Inc()
+			conn := unwrapTrackedConn(info.Conn)
+			if conn == nil {
+				return
+			}
+			conn.setState(connStateActive)
+			rt.mu.Lock()
+			rt.conn = conn
+			rt.mu.Unlock()
+		},
+		PutIdleConn: func(err error) {
+			rt.mu.Lock()
+			conn := rt.conn
+			rt.mu.Unlock()
+			if err == nil && conn != nil {
+				conn.setState(connStateIdle)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			rt.mu.Lock()
+			rt.wroteRequest = time.Now()
+			rt.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			rt.mu.Lock()
+			defer rt.mu.Unlock()
+			rt.observe("time_to_first_byte", rt.wroteRequest)
+		},
+	}
+}
+
+// trackedConn is an upstream connection counted in the connections gauge
+type trackedConn struct {
+	net.Conn
+	host string
+
+	mu     sync.Mutex
+	state  string
+	closed bool
+}
+
+// setState moves the connection to state in the connections gauge
+func (c *trackedConn) setState(state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.state == state {
+		return
+	}
+	if c.state != "" {
+		monitoring.UpstreamConnections.WithLabelValues(c.host, c.state).Dec()
+	}
+	c.state = state
+	monitoring.UpstreamConnections.WithLabelValues(c.host, state).Inc()
+}
+
+// Close implements net.Conn
+func (c *trackedConn) Close() error {
+	c.mu.Lock()
+	if !c.closed && c.state != "" {
+		monitoring.UpstreamConnections.WithLabelValues(c.host, c.state).Dec()
+	}
+	c.closed = true
+	c.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// unwrapTrackedConn returns the trackedConn under a (TLS) connection, or nil
+func unwrapTrackedConn(conn net.Conn) *trackedConn {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tracked, _ := conn.(*trackedConn)
+	return tracked
+}
@@ -0,0 +1,51 @@
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpstreamTransport_Metrics(t *testing.T) {
+	monitoring.UpstreamConnections.Reset()
+	monitoring.UpstreamDialsTotal.Reset()
+	monitoring.UpstreamConnectionsAcquiredTotal.Reset()
+	monitoring.UpstreamPhaseDuration.Reset()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport := NewHTTPClient(nil).Transport.(*http.Transport)
+	client := &http.Client{Transport: NewUpstreamTransport(transport, UpstreamTransportOptions{Metrics: true})}
+	host := "127.0.0.1"
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.UpstreamDialsTotal.WithLabelValues(host, "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.UpstreamConnectionsAcquiredTotal.WithLabelValues(host, "false")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.UpstreamConnectionsAcquiredTotal.WithLabelValues(host, "true")), "the second request reuses the idle connection")
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.UpstreamConnections.WithLabelValues(host, "idle")))
+	assert.Zero(t, testutil.ToFloat64(monitoring.UpstreamConnections.WithLabelValues(host, "active")))
+	assert.Equal(t, 2, testutil.CollectAndCount(monitoring.UpstreamPhaseDuration), "connect and time_to_first_byte (no DNS or TLS for 127.0.0.1 over HTTP)")
+
+	transport.CloseIdleConnections()
+	assert.Zero(t, testutil.ToFloat64(monitoring.UpstreamConnections.WithLabelValues(host, "idle")))
+
+	// Failed dials are counted per host
+	_, err := client.Get("http://127.0.0.1:1")
+	require.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.UpstreamDialsTotal.WithLabelValues(host, "error")))
+}
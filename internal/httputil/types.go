@@ -1,5 +1,7 @@
 package httputil
 
+import "time"
+
 // ProxyHealthResponse represents the JSON response from /health endpoint
 type ProxyHealthResponse struct {
 	Status               string                           `json:"status"`
@@ -8,18 +10,78 @@ type ProxyHealthResponse struct {
 	TotalCredentials     int                              `json:"total_credentials"`
 	Credentials          map[string]CredentialHealthStats `json:"credentials"`
 	Models               map[string]ModelHealthStats      `json:"models"`
+	Prices               *PriceSyncHealth                 `json:"prices,omitempty"`  // omitted without model_prices_link
+	Workers              map[string]WorkerHealth          `json:"workers,omitempty"` // background workers by name
+}
+
+// Sources of the model prices in use
+const (
+	PriceSourceSync     = "sync"     // Synced from model_prices_link by this process
+	PriceSourceSnapshot = "snapshot" // Restored from model_prices_cache_file
+	PriceSourceNone     = "none"     // Never loaded
+)
+
+// PriceSyncHealth reports the model prices spend is calculated with
+type PriceSyncHealth struct {
+	Source     string     `json:"source"` // sync, snapshot or none
+	Models     int        `json:"models"`
+	LastSync   *time.Time `json:"last_sync,omitempty"`
+	AgeSeconds float64    `json:"age_seconds"`
+	LastError  string     `json:"last_error,omitempty"` // error of the last failed sync
+}
+
+// WorkerHealth reports a supervised background worker (price sync, metrics updater, ...).
+// Restarting workers do not make the router unhealthy.
+type WorkerHealth struct {
+	State     string     `json:"state"` // running, restarting or stopped
+	Restarts  int        `json:"restarts"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"` // error of the last run or panic
+	LastPanic *time.Time `json:"last_panic,omitempty"`
 }
 
 // CredentialHealthStats represents health stats for a single credential
 type CredentialHealthStats struct {
-	Type              string      `json:"type"`
-	IsFallback        bool        `json:"is_fallback"`
-	IsBanned          bool        `json:"is_banned"`
-	CurrentRPM        int         `json:"current_rpm"`
-	CurrentTPM        int         `json:"current_tpm"`
-	LimitRPM          int         `json:"limit_rpm"`
-	LimitTPM          int         `json:"limit_tpm"`
-	BannedErrorCounts map[int]int `json:"banned_error_counts,omitempty"` // aggregated error counts from banned models
+	Type              string            `json:"type"`
+	IsFallback        bool              `json:"is_fallback"`
+	IsBanned          bool              `json:"is_banned"`
+	CurrentRPM        int               `json:"current_rpm"`
+	CurrentTPM        int               `json:"current_tpm"`
+	LimitRPM          int               `json:"limit_rpm"`
+	LimitTPM          int               `json:"limit_tpm"`
+	BannedErrorCounts map[int]int       `json:"banned_error_counts,omitempty"` // aggregated error counts from banned models
+	Quotas            []QuotaForecast   `json:"quotas,omitempty"`              // quota exhaustion forecasts (usage_forecast)
+	Downstream        *DownstreamHealth `json:"downstream,omitempty"`          // last /health report of a proxy credential
+}
+
+// Downstream router health statuses
+const (
+	DownstreamHealthy     = "healthy"
+	DownstreamUnhealthy   = "unhealthy"
+	DownstreamUnreachable = "unreachable"
+)
+
+// DownstreamHealth is the availability a downstream router (proxy credential) reported on its
+// /health endpoint, polled with the proxy model updates
+type DownstreamHealth struct {
+	Status               string    `json:"status"` // healthy, unhealthy or unreachable
+	CredentialsAvailable int       `json:"credentials_available"`
+	TotalCredentials     int       `json:"total_credentials"`
+	ConsecutiveFailures  int       `json:"consecutive_failures,omitempty"`
+	Error                string    `json:"error,omitempty"`
+	CheckedAt            time.Time `json:"checked_at"`
+}
+
+// QuotaForecast is the forecast exhaustion of one credential quota at the current usage rate
+type QuotaForecast struct {
+	Period      string  `json:"period"` // daily or monthly
+	Kind        string  `json:"kind"`   // tokens or spend (USD)
+	Used        float64 `json:"used"`
+	Limit       float64 `json:"limit"`
+	RatePerHour float64 `json:"rate_per_hour"`
+	// SecondsToExhaustion is omitted when the quota does not run out before it resets
+	SecondsToExhaustion *float64 `json:"seconds_to_exhaustion,omitempty"`
+	Warning             bool     `json:"warning"`
 }
 
 // ModelHealthStats represents health stats for a single model
@@ -33,3 +95,27 @@ type ModelHealthStats struct {
 	LimitTPM        int         `json:"limit_tpm"`
 	ErrorCodeCounts map[int]int `json:"error_code_counts,omitempty"` // error code -> count when banned
 }
+
+// FederationPath is the endpoint a router exposes for parent routers using it as a proxy credential
+const FederationPath = "/federation/limits"
+
+// FederationResponse represents the JSON response from the federation endpoint.
+// It reports per-model capacity aggregated over all credentials of the router.
+type FederationResponse struct {
+	Status  string                         `json:"status"`
+	Version string                         `json:"version"`
+	Models  map[string]FederatedModelStats `json:"models"`
+}
+
+// FederatedModelStats represents aggregated capacity for a single model.
+// Limit and remaining values use -1 for unlimited.
+type FederatedModelStats struct {
+	Credentials  int `json:"credentials"` // credentials serving the model
+	Available    int `json:"available"`   // credentials not banned for the model
+	LimitRPM     int `json:"limit_rpm"`
+	LimitTPM     int `json:"limit_tpm"`
+	CurrentRPM   int `json:"current_rpm"`
+	CurrentTPM   int `json:"current_tpm"`
+	RemainingRPM int `json:"remaining_rpm"`
+	RemainingTPM int `json:"remaining_tpm"`
+}
@@ -0,0 +1,152 @@
+package httputil
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDialTimeout   = 30 * time.Second
+	defaultDialKeepAlive = 30 * time.Second
+)
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// UpstreamTransportOptions selects the behaviour added by NewUpstreamTransport
+type UpstreamTransportOptions struct {
+	Metrics  bool      // Record connection pool and phase metrics per upstream host
+	DNSCache *DNSCache // Optional: resolve upstream hosts through the cache
+}
+
+// NewUpstreamTransport wraps the upstream transport t: requests of credentials with
+// resolve entries go through a clone of t dialing the pinned addresses, so pinned
+// connections are never shared with other credentials. With opts.Metrics it records
+// open connections by state, dials, connection reuse and DNS, connect, TLS handshake and
+// time-to-first-byte latencies per upstream host. It replaces t's dialer, so call it
+// before t is first used.
+func NewUpstreamTransport(t *http.Transport, opts UpstreamTransportOptions) http.RoundTripper {
+	netDial := t.DialContext
+	if netDial == nil {
+		netDial = (&net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultDialKeepAlive}).DialContext
+	}
+	newDial := func(pins map[string]string) dialFunc {
+		dial := dialFunc(netDial)
+		if opts.DNSCache != nil || len(pins) > 0 {
+			dial = resolvingDial(dial, opts.DNSCache, pins)
+		}
+		if opts.Metrics {
+			dial = metricsDial(dial)
+		}
+		return dial
+	}
+	if opts.DNSCache != nil || opts.Metrics {
+		t.DialContext = newDial(nil)
+		// A custom dialer disables HTTP/2 unless it is forced
+		t.ForceAttemptHTTP2 = true
+	}
+
+	var next http.RoundTripper = &credentialTransport{
+		base:    t,
+		newDial: newDial,
+		pinned:  make(map[string]*pinnedTransport),
+	}
+	if opts.Metrics {
+		next = &metricsTransport{next: next}
+	}
+	return next
+}
+
+// resolvingDial dials addr after replacing its host with the pinned target (host or host:port)
+// and, with a cache, resolving host names through it. Cached addresses are tried in order.
+func resolvingDial(dial dialFunc, cache *DNSCache, pins map[string]string) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		if target, ok := pins[host]; ok {
+			if pinnedHost, pinnedPort, err := net.SplitHostPort(target); err == nil {
+				host, port = pinnedHost, pinnedPort
+			} else {
+				host = target
+			}
+		}
+		if cache == nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, net.JoinHostPort(host, port))
+		}
+
+		addrs, err := cache.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var firstErr error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// credentialTransport sends requests of credentials with resolve entries through their own
+// clone of base, and all other requests through base
+type credentialTransport struct {
+	base    *http.Transport
+	newDial func(pins map[string]string) dialFunc
+
+	mu     sync.Mutex
+	pinned map[string]*pinnedTransport // By credential name
+}
+
+type pinnedTransport struct {
+	pins      map[string]string
+	transport *http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cred := CredentialFromContext(req.Context())
+	if cred == nil || len(cred.Resolve) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	return t.transportFor(cred.Name, cred.Resolve).RoundTrip(req)
+}
+
+// transportFor returns the transport of a credential, replacing it when its pins changed
+func (t *credentialTransport) transportFor(name string, pins map[string]string) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pinned, ok := t.pinned[name]; ok {
+		if maps.Equal(pinned.pins, pins) {
+			return pinned.transport
+		}
+		pinned.transport.CloseIdleConnections()
+	}
+	transport := t.base.Clone()
+	transport.DialContext = t.newDial(pins)
+	transport.ForceAttemptHTTP2 = true
+	t.pinned[name] = &pinnedTransport{pins: maps.Clone(pins), transport: transport}
+	return transport
+}
+
+// CloseIdleConnections closes the idle connections of base and of every credential transport
+func (t *credentialTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, pinned := range t.pinned {
+		pinned.transport.CloseIdleConnections()
+	}
+}
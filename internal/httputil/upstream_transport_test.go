@@ -0,0 +1,85 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpstreamTransport_Resolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(serverURL.Host)
+	require.NoError(t, err)
+
+	// Host names are only reachable through resolve entries or the cache
+	var lookups []string
+	cache := NewDNSCache(time.Minute)
+	cache.lookup = func(_ context.Context, host string) ([]string, error) {
+		lookups = append(lookups, host)
+		if host == "cached.example.test" {
+			return []string{"127.0.0.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	transport := NewHTTPClient(nil).Transport.(*http.Transport)
+	client := &http.Client{Transport: NewUpstreamTransport(transport, UpstreamTransportOptions{DNSCache: cache})}
+
+	get := func(cred *config.CredentialConfig, rawURL string) (string, error) {
+		req, err := http.NewRequestWithContext(WithCredential(context.Background(), cred), http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	pinned := &config.CredentialConfig{Name: "eu", Resolve: map[string]string{
+		"api.example.test":  "127.0.0.1",
+		"auth.example.test": "127.0.0.1:" + port,
+	}}
+	host, err := get(pinned, "http://api.example.test:"+port+"/v1/models")
+	require.NoError(t, err)
+	assert.Equal(t, "api.example.test:"+port, host, "the Host header keeps the upstream name")
+
+	host, err = get(pinned, "http://auth.example.test/token")
+	require.NoError(t, err)
+	assert.Equal(t, "auth.example.test", host, "a pinned port replaces the URL port")
+
+	host, err = get(&config.CredentialConfig{Name: "us"}, "http://cached.example.test:"+port)
+	require.NoError(t, err)
+	assert.Equal(t, "cached.example.test:"+port, host)
+	_, err = get(&config.CredentialConfig{Name: "us"}, "http://cached.example.test:"+port)
+	require.NoError(t, err)
+
+	// Pins of one credential do not apply to others
+	_, err = get(&config.CredentialConfig{Name: "us"}, "http://api.example.test:"+port)
+	assert.Error(t, err)
+	_, err = get(nil, "http://api.example.test:"+port)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"cached.example.test", "api.example.test", "api.example.test"}, lookups,
+		"pinned hosts skip DNS, cached hosts are resolved once")
+
+	// Changed pins replace the credential's transport
+	pinned = &config.CredentialConfig{Name: "eu", Resolve: map[string]string{"api.example.test": "127.0.0.1:1"}}
+	_, err = get(pinned, "http://api.example.test:"+port)
+	assert.Error(t, err)
+}
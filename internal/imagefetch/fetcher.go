@@ -0,0 +1,263 @@
+package imagefetch
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+const maxRedirects = 3
+
+var ErrPrivateAddress = errors.New("image URL resolves to a private or loopback address")
+
+// Config configures a Fetcher
+type Config struct {
+	MaxBytes             int64         // Maximum image size
+	AllowedMIMETypes     []string      // Accepted image MIME types
+	Timeout              time.Duration // Per-image fetch timeout
+	CacheTTL             time.Duration // How long fetched images are reused (0 = no caching)
+	CacheSize            int           // Maximum number of cached images
+	AllowPrivateNetworks bool          // Allow URLs resolving to private/loopback addresses
+	MaxImages            int           // Maximum distinct image URLs inlined per request (0 = unlimited)
+	MaxConcurrent        int           // Maximum concurrent downloads across requests (0 = unlimited)
+}
+
+// Image is a fetched image
+type Image struct {
+	MIMEType string
+	Data     []byte
+}
+
+// DataURL returns the image as a base64 data URL
+func (i *Image) DataURL() string {
+	return "data:" + i.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// Fetcher downloads remote images so they can be inlined into provider requests
+// that do not accept (or cannot fetch) remote image URLs.
+//
+// Thread-safe via internal mutex.
+type Fetcher struct {
+	client           *http.Client
+	maxBytes         int64
+	allowedMIMETypes map[string]bool
+	timeout          time.Duration
+	cacheTTL         time.Duration
+	cacheSize        int
+	maxImages        int
+	downloads        chan struct{} // Download slots (nil = unlimited)
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	image     *Image
+	expiresAt time.Time
+}
+
+// New creates a Fetcher
+func New(cfg Config) *Fetcher {
+	allowed := make(map[string]bool, len(cfg.AllowedMIMETypes))
+	for _, mimeType := range cfg.AllowedMIMETypes {
+		allowed[strings.ToLower(mimeType)] = true
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = denyPrivateAddresses
+	}
+
+	var downloads chan struct{}
+	if cfg.MaxConcurrent > 0 {
+		downloads = make(chan struct{}, cfg.MaxConcurrent)
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: &http.Transport{
+				// No proxy: the dialer must see the real destination address
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   cfg.Timeout,
+				ResponseHeaderTimeout: cfg.Timeout,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return nil
+			},
+		},
+		maxBytes:         cfg.MaxBytes,
+		allowedMIMETypes: allowed,
+		timeout:          cfg.Timeout,
+		cacheTTL:         cfg.CacheTTL,
+		cacheSize:        cfg.CacheSize,
+		maxImages:        cfg.MaxImages,
+		downloads:        downloads,
+		cache:            make(map[string]cacheEntry),
+	}
+}
+
+// Fetch downloads an http(s) image, validating its size and MIME type.
+// Recently fetched images are served from the cache; downloads wait for a free slot.
+func (f *Fetcher) Fetch(ctx context.Context, url string) (*Image, error) {
+	if image := f.cached(url); image != nil {
+		monitoring.ImageInliningTotal.WithLabelValues("cached").Inc()
+		return image, nil
+	}
+
+	if f.downloads != nil {
+		select {
+		case f.downloads <- struct{}{}:
+			defer func() { <-f.downloads }()
+		case <-ctx.Done():
+			monitoring.ImageInliningTotal.WithLabelValues("error").Inc()
+			return nil, context.Cause(ctx)
+		}
+	}
+
+	image, err := f.download(ctx, url)
+	if err != nil {
+		monitoring.ImageInliningTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	monitoring.ImageInliningTotal.WithLabelValues("fetched").Inc()
+	f.store(url, image)
+	return image, nil
+}
+
+func (f *Fetcher) download(ctx context.Context, url string) (*Image, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image: status %d", resp.StatusCode)
+	}
+	if f.maxBytes > 0 && resp.ContentLength > f.maxBytes {
+		return nil, fmt.Errorf("image too large: %d bytes (max %d)", resp.ContentLength, f.maxBytes)
+	}
+
+	reader := io.Reader(resp.Body)
+	if f.maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, f.maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if f.maxBytes > 0 && int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("image too large: more than %d bytes", f.maxBytes)
+	}
+
+	mimeType := detectMIMEType(resp.Header.Get("Content-Type"), data)
+	if !f.allowedMIMETypes[mimeType] {
+		return nil, fmt.Errorf("image MIME type %q is not allowed", mimeType)
+	}
+
+	return &Image{MIMEType: mimeType, Data: data}, nil
+}
+
+// detectMIMEType uses the Content-Type header, sniffing the data when it is missing or generic
+func detectMIMEType(contentType string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "application/octet-stream" {
+		return strings.ToLower(mediaType)
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return mediaType
+}
+
+func (f *Fetcher) cached(url string) *Image {
+	if f.cacheTTL <= 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := f.cache[url]
+	if !ok {
+		return nil
+	}
+	if utils.NowUTC().After(entry.expiresAt) {
+		delete(f.cache, url)
+		return nil
+	}
+	return entry.image
+}
+
+func (f *Fetcher) store(url string, image *Image) {
+	if f.cacheTTL <= 0 || f.cacheSize <= 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := utils.NowUTC()
+	if _, ok := f.cache[url]; !ok && len(f.cache) >= f.cacheSize {
+		f.evictLocked(now)
+	}
+	f.cache[url] = cacheEntry{image: image, expiresAt: now.Add(f.cacheTTL)}
+}
+
+// evictLocked drops expired entries, or the entry closest to expiry if none expired.
+// Caller must hold f.mu.
+func (f *Fetcher) evictLocked(now time.Time) {
+	var oldestURL string
+	var oldest time.Time
+	for url, entry := range f.cache {
+		if now.After(entry.expiresAt) {
+			delete(f.cache, url)
+			continue
+		}
+		if oldestURL == "" || entry.expiresAt.Before(oldest) {
+			oldestURL, oldest = url, entry.expiresAt
+		}
+	}
+	if len(f.cache) >= f.cacheSize && oldestURL != "" {
+		delete(f.cache, oldestURL)
+	}
+}
+
+// denyPrivateAddresses rejects connections to loopback, private, link-local and
+// unspecified addresses, so clients cannot make the router fetch internal URLs
+func denyPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ErrPrivateAddress
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package imagefetch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough for http.DetectContentType to report image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newTestServer(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(pngHeader)
+		case "/sniffed":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write(pngHeader)
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html></html>"))
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, 2048))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestFetcher(cacheTTL time.Duration) *Fetcher {
+	return New(Config{
+		MaxBytes:             1024,
+		AllowedMIMETypes:     []string{"image/png", "image/jpeg"},
+		Timeout:              5 * time.Second,
+		CacheTTL:             cacheTTL,
+		CacheSize:            2,
+		AllowPrivateNetworks: true, // httptest listens on loopback
+	})
+}
+
+func TestFetch(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	f := newTestFetcher(0)
+
+	image, err := f.Fetch(context.Background(), server.URL+"/cat.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", image.MIMEType)
+	assert.Equal(t, pngHeader, image.Data)
+
+	// Generic content type falls back to sniffing
+	image, err = f.Fetch(context.Background(), server.URL+"/sniffed")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", image.MIMEType)
+}
+
+func TestFetch_Rejected(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	f := newTestFetcher(0)
+
+	_, err := f.Fetch(context.Background(), server.URL+"/page.html")
+	assert.ErrorContains(t, err, "not allowed")
+
+	_, err = f.Fetch(context.Background(), server.URL+"/large.png")
+	assert.ErrorContains(t, err, "too large")
+
+	_, err = f.Fetch(context.Background(), server.URL+"/missing.png")
+	assert.ErrorContains(t, err, "status 404")
+}
+
+func TestFetch_DeniesPrivateNetworks(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	f := New(Config{MaxBytes: 1024, AllowedMIMETypes: []string{"image/png"}, Timeout: time.Second})
+
+	_, err := f.Fetch(context.Background(), server.URL+"/cat.png")
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
+
+func TestFetch_Cache(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	f := newTestFetcher(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := f.Fetch(context.Background(), server.URL+"/cat.png")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+
+	// Cache is bounded by CacheSize
+	_, err := f.Fetch(context.Background(), server.URL+"/sniffed")
+	require.NoError(t, err)
+	_, err = f.Fetch(context.Background(), server.URL+"/cat.png?v=2")
+	require.NoError(t, err)
+	assert.Len(t, f.cache, 2)
+}
+
+func TestInlineImageURLs(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	f := newTestFetcher(time.Minute)
+
+	body := []byte(`{
+		"model": "gemini-2.5-flash",
+		"seed": 9007199254740993,
+		"messages": [
+			{"role": "system", "content": "Describe images."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is this?"},
+				{"type": "image_url", "image_url": {"url": "` + server.URL + `/cat.png", "detail": "high"}},
+				{"type": "image_url", "image_url": {"url": "` + server.URL + `/cat.png"}},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}},
+				{"type": "image_url", "image_url": {"url": "` + server.URL + `/page.html"}}
+			]}
+		]
+	}`)
+
+	newBody, inlined, err := f.InlineImageURLs(context.Background(), body)
+	assert.ErrorContains(t, err, "page.html")
+	assert.Equal(t, 2, inlined)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits), "duplicate URLs are fetched once")
+	assert.Contains(t, string(newBody), `"seed":9007199254740993`)
+
+	var req struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(newBody, &req))
+	var blocks []struct {
+		ImageURL struct {
+			URL    string `json:"url"`
+			Detail string `json:"detail"`
+		} `json:"image_url"`
+	}
+	require.NoError(t, json.Unmarshal(req.Messages[1].Content, &blocks))
+
+	dataURL := (&Image{MIMEType: "image/png", Data: pngHeader}).DataURL()
+	assert.Equal(t, dataURL, blocks[1].ImageURL.URL)
+	assert.Equal(t, "high", blocks[1].ImageURL.Detail)
+	assert.Equal(t, dataURL, blocks[2].ImageURL.URL)
+	assert.Equal(t, "data:image/png;base64,AAAA", blocks[3].ImageURL.URL)
+	assert.Equal(t, server.URL+"/page.html", blocks[4].ImageURL.URL)
+}
+
+func TestInlineImageURLs_MaxImages(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	f := newTestFetcher(0)
+	f.maxImages = 1
+
+	body := []byte(`{"messages":[{"role":"user","content":[
+		{"type":"image_url","image_url":{"url":"` + server.URL + `/cat.png"}},
+		{"type":"image_url","image_url":{"url":"` + server.URL + `/cat.png"}},
+		{"type":"image_url","image_url":{"url":"` + server.URL + `/sniffed"}}
+	]}]}`)
+	newBody, inlined, err := f.InlineImageURLs(context.Background(), body)
+	assert.ErrorContains(t, err, "1 image URLs over the limit of 1 left unchanged")
+	assert.Equal(t, 2, inlined, "duplicates of an inlined URL are not counted against the limit")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	assert.Contains(t, string(newBody), server.URL+"/sniffed")
+}
+
+func TestFetch_MaxConcurrent(t *testing.T) {
+	var inFlight, peak int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(pngHeader)
+	}))
+	defer server.Close()
+
+	f := New(Config{MaxBytes: 1024, AllowedMIMETypes: []string{"image/png"}, Timeout: 5 * time.Second, AllowPrivateNetworks: true, MaxConcurrent: 2})
+	body := []byte(`{"messages":[{"role":"user","content":[
+		{"type":"image_url","image_url":{"url":"` + server.URL + `/1.png"}},
+		{"type":"image_url","image_url":{"url":"` + server.URL + `/2.png"}},
+		{"type":"image_url","image_url":{"url":"` + server.URL + `/3.png"}}
+	]}]}`)
+
+	done := make(chan int)
+	go func() {
+		_, inlined, _ := f.InlineImageURLs(context.Background(), body)
+		done <- inlined
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&inFlight) == 2 }, time.Second, 5*time.Millisecond)
+	close(release)
+	assert.Equal(t, 3, <-done)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+
+	// A request waiting for a download slot gives up with its context
+	f.downloads <- struct{}{}
+	f.downloads <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := f.Fetch(ctx, server.URL+"/4.png")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestInlineImageURLs_NoRemoteImages(t *testing.T) {
+	f := newTestFetcher(0)
+
+	body := []byte(`{"messages":[{"role":"user","content":"Hi"}]}`)
+	newBody, inlined, err := f.InlineImageURLs(context.Background(), body)
+	require.NoError(t, err)
+	assert.Equal(t, 0, inlined)
+	assert.Equal(t, body, newBody)
+
+	invalid := []byte(`not json`)
+	newBody, _, err = f.InlineImageURLs(context.Background(), invalid)
+	require.NoError(t, err)
+	assert.Equal(t, invalid, newBody)
+}
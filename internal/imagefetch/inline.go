@@ -0,0 +1,120 @@
+package imagefetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// InlineImageURLs replaces remote http(s) image_url URLs in an OpenAI Chat Completions
+// request body with base64 data URLs.
+// Returns the (possibly unchanged) body and the number of inlined images. Images that
+// fail to download, and URLs beyond the first MaxImages distinct ones, keep their original
+// URL; their errors are joined into the returned error.
+func (f *Fetcher) InlineImageURLs(ctx context.Context, body []byte) ([]byte, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large integers (e.g. seed) intact on re-encoding
+
+	var req map[string]interface{}
+	if err := decoder.Decode(&req); err != nil {
+		return body, 0, nil
+	}
+
+	imageURLs := collectImageURLs(req)
+	if len(imageURLs) == 0 {
+		return body, 0, nil
+	}
+
+	urls := make(map[string]string, len(imageURLs))
+	var order []string
+	for _, imageURL := range imageURLs {
+		url := imageURL["url"].(string)
+		if _, ok := urls[url]; !ok {
+			urls[url] = ""
+			order = append(order, url)
+		}
+	}
+
+	var errs []error
+	if f.maxImages > 0 && len(order) > f.maxImages {
+		errs = append(errs, fmt.Errorf("%d image URLs over the limit of %d left unchanged", len(order)-f.maxImages, f.maxImages))
+		order = order[:f.maxImages]
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, url := range order {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			image, err := f.Fetch(ctx, url)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", url, err))
+				return
+			}
+			urls[url] = image.DataURL()
+		}(url)
+	}
+	wg.Wait()
+
+	inlined := 0
+	for _, imageURL := range imageURLs {
+		if dataURL := urls[imageURL["url"].(string)]; dataURL != "" {
+			imageURL["url"] = dataURL
+			inlined++
+		}
+	}
+	if inlined == 0 {
+		return body, 0, errors.Join(errs...)
+	}
+
+	newBody, err := json.Marshal(req)
+	if err != nil {
+		return body, 0, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return newBody, inlined, errors.Join(errs...)
+}
+
+// collectImageURLs returns the image_url objects of messages[].content[] blocks with remote URLs
+func collectImageURLs(req map[string]interface{}) []map[string]interface{} {
+	messages, ok := req["messages"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var imageURLs []map[string]interface{}
+	for _, message := range messages {
+		messageMap, ok := message.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		blocks, ok := messageMap["content"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, block := range blocks {
+			blockMap, ok := block.(map[string]interface{})
+			if !ok || blockMap["type"] != "image_url" {
+				continue
+			}
+			imageURL, ok := blockMap["image_url"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if url, ok := imageURL["url"].(string); ok && isRemoteURL(url) {
+				imageURLs = append(imageURLs, imageURL)
+			}
+		}
+	}
+	return imageURLs
+}
+
+func isRemoteURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
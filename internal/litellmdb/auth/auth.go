@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"log/slog"
 	"strings"
 	"time"
@@ -18,9 +19,10 @@ import (
 // Authenticator provides token authentication via LiteLLM database
 // Synchronous (blocking) - token validation must complete before request processing
 type Authenticator struct {
-	pool   *connection.ConnectionPool
-	cache  *Cache
-	logger *slog.Logger
+	pool        *connection.ConnectionPool
+	cache       *Cache
+	logger      *slog.Logger
+	budgetBoost models.BudgetBoostFunc // nil = no budget boosts
 }
 
 // NewAuthenticator creates a new authenticator
@@ -32,6 +34,19 @@ func NewAuthenticator(pool *connection.ConnectionPool, cache *Cache, logger *slo
 	}
 }
 
+// SetBudgetBoost sets the temporary budget boosts applied during validation
+func (a *Authenticator) SetBudgetBoost(fn models.BudgetBoostFunc) {
+	a.budgetBoost = fn
+}
+
+// validate checks a token with its active budget boosts
+func (a *Authenticator) validate(info *models.TokenInfo) error {
+	if a.budgetBoost == nil {
+		return info.Validate("")
+	}
+	return info.ValidateWithBoost("", a.budgetBoost(info))
+}
+
 // ValidateToken validates a token and returns its information
 //
 // Algorithm:
@@ -56,7 +71,7 @@ func (a *Authenticator) ValidateToken(ctx context.Context, rawToken string) (*mo
 			"token_prefix", security.MaskToken(hashedToken),
 		)
 		// Validate even from cache (expires, budget could have changed externally)
-		if err := info.Validate(""); err != nil {
+		if err := a.validate(info); err != nil {
 			return nil, err
 		}
 		return info, nil
@@ -69,7 +84,7 @@ func (a *Authenticator) ValidateToken(ctx context.Context, rawToken string) (*mo
 	}
 
 	// 4. Validate
-	if err := info.Validate(""); err != nil {
+	if err := a.validate(info); err != nil {
 		// Don't cache invalid tokens
 		return nil, err
 	}
@@ -86,6 +101,19 @@ func (a *Authenticator) ValidateToken(ctx context.Context, rawToken string) (*mo
 	return info, nil
 }
 
+// decodeMetadata decodes a JSON metadata column (nil if empty or not a JSON object)
+func (a *Authenticator) decodeMetadata(raw []byte, level string) map[string]interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		a.logger.Warn("Failed to decode metadata", "level", level, "error", err)
+		return nil
+	}
+	return metadata
+}
+
 // ValidateTokenForModel validates a token with model access check
 func (a *Authenticator) ValidateTokenForModel(ctx context.Context, rawToken, model string) (*models.TokenInfo, error) {
 	info, err := a.ValidateToken(ctx, rawToken)
@@ -105,11 +133,12 @@ func (a *Authenticator) ValidateTokenForModel(ctx context.Context, rawToken, mod
 // Single query loads: Token → User → Team → Organization → Memberships
 // All with their budget data (embedded or external via BudgetTable)
 func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string) (*models.TokenInfo, error) {
-	if !a.pool.IsHealthy() {
+	if !a.pool.IsReadHealthy() {
 		return nil, models.ErrConnectionFailed
 	}
 
-	conn, err := a.pool.Acquire(ctx)
+	// Read-only lookup: served by the replica when configured
+	conn, err := a.pool.AcquireRead(ctx)
 	if err != nil {
 		a.logger.Error("Failed to acquire connection for auth",
 			"error", err,
@@ -127,6 +156,7 @@ func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string
 	var expires *time.Time
 	var blocked *bool
 	var tokenModels []string
+	var tokenMetadata []byte
 
 	// ============ User fields ============
 	var userIDCheck, userAlias, userEmail *string
@@ -137,6 +167,8 @@ func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string
 	var teamMaxBudget, teamSpend *float64
 	var teamBlocked *bool
 	var teamTPMLimit, teamRPMLimit *int64
+	var teamModels []string
+	var teamMetadata []byte
 
 	// ============ Organization fields (with external budget) ============
 	var orgIDCheck *string
@@ -169,6 +201,7 @@ func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string
 		&expires,
 		&blocked,
 		&tokenModels,
+		&tokenMetadata,
 
 		// User
 		&userIDCheck,
@@ -186,6 +219,8 @@ func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string
 		&teamBlocked,
 		&teamTPMLimit,
 		&teamRPMLimit,
+		&teamModels,
+		&teamMetadata,
 
 		// Organization
 		&orgIDCheck,
@@ -241,6 +276,7 @@ func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string
 		info.Blocked = *blocked
 	}
 	info.Models = tokenModels
+	info.Metadata = a.decodeMetadata(tokenMetadata, "token")
 
 	info.MaxBudget = tokenMaxBudget
 	info.Expires = expires
@@ -266,6 +302,8 @@ func (a *Authenticator) fetchTokenFromDB(ctx context.Context, hashedToken string
 	info.TeamBlocked = teamBlocked
 	info.TeamTPMLimit = teamTPMLimit
 	info.TeamRPMLimit = teamRPMLimit
+	info.TeamModels = teamModels
+	info.TeamMetadata = a.decodeMetadata(teamMetadata, "team")
 
 	// Set Organization fields (external budget from BudgetTable)
 	info.OrgSpend = orgSpend
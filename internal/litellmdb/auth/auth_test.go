@@ -625,3 +625,24 @@ func TestAuthenticator_CacheHitRate(t *testing.T) {
 	assert.Greater(t, stats.HitRate, 0.0)
 	assert.LessOrEqual(t, stats.HitRate, 100.0)
 }
+
+func TestAuthenticator_ValidateToken_BudgetBoost(t *testing.T) {
+	cache, err := NewCache(100, time.Minute)
+	require.NoError(t, err)
+	maxBudget := 10.0
+	cache.Set(HashToken("sk-boosted"), &models.TokenInfo{KeyAlias: "campaign", Spend: 12, MaxBudget: &maxBudget})
+
+	a := NewAuthenticator(nil, cache, slog.Default())
+	_, err = a.ValidateToken(context.Background(), "sk-boosted")
+	assert.ErrorIs(t, err, models.ErrBudgetExceeded)
+
+	a.SetBudgetBoost(func(info *models.TokenInfo) models.BudgetBoost {
+		if info.KeyAlias == "campaign" {
+			return models.BudgetBoost{Key: 0.5}
+		}
+		return models.BudgetBoost{}
+	})
+	info, err := a.ValidateToken(context.Background(), "sk-boosted")
+	require.NoError(t, err)
+	assert.Equal(t, "campaign", info.KeyAlias)
+}
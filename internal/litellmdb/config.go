@@ -25,6 +25,9 @@ type Config = models.Config
 // TokenInfo type alias for backwards compatibility
 type TokenInfo = models.TokenInfo
 
+// BudgetBoost type alias for backwards compatibility
+type BudgetBoost = models.BudgetBoost
+
 // SpendLogEntry type alias for backwards compatibility
 type SpendLogEntry = models.SpendLogEntry
 
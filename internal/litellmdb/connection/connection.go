@@ -12,18 +12,33 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/queries"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/security"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
-// ConnectionPool manages PostgreSQL connections with auto-reconnect
+// Pool labels used in metrics and logs
+const (
+	poolPrimary = "primary"
+	poolReplica = "replica"
+)
+
+// ConnectionPool manages PostgreSQL connections with auto-reconnect.
+// Writes (spend logs) always use the primary pool; auth lookups use the
+// optional read replica while it is healthy and fall back to the primary.
 type ConnectionPool struct {
 	pool   *pgxpool.Pool
 	config *models.Config
 	logger *slog.Logger
 
 	// Health status
-	healthy atomic.Bool
+	healthy             atomic.Bool
+	consecutiveFailures int // Failed primary checks in a row (health check goroutine only)
+
+	// Optional read replica (nil if replica_url is not configured)
+	replica         *pgxpool.Pool
+	replicaHealthy  atomic.Bool
+	replicaFailures int
 
 	// Lifecycle
 	ctx    context.Context
@@ -55,10 +70,50 @@ func NewConnectionPool(cfg *models.Config) (*ConnectionPool, error) {
 		reconnectDelay: time.Second, // Initial delay 1s
 	}
 
-	// Create pool config
-	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	pool, err := cp.newPgxPool(ctx, cfg.DatabaseURL)
 	if err != nil {
 		cancel()
+		return nil, err
+	}
+	cp.pool = pool
+	cp.healthy.Store(true)
+
+	// Replica is best-effort: auth falls back to the primary if it is unavailable
+	if cfg.ReplicaURL != "" {
+		replica, replicaErr := cp.newPgxPool(ctx, cfg.ReplicaURL)
+		if replicaErr != nil {
+			cp.logger.Warn("LiteLLM DB read replica unavailable, auth lookups will use primary",
+				"replica", security.MaskDatabaseURL(cfg.ReplicaURL),
+				"error", replicaErr,
+			)
+		} else {
+			cp.replica = replica
+			cp.replicaHealthy.Store(true)
+		}
+	}
+	cp.updateMetrics()
+
+	// Start background health check
+	cp.wg.Add(1)
+	go cp.healthCheckLoop()
+
+	cp.logger.Info("LiteLLM DB connection pool initialized",
+		"max_conns", cfg.MaxConns,
+		"min_conns", cfg.MinConns,
+		"database", security.MaskDatabaseURL(cfg.DatabaseURL),
+		"replica", cp.replica != nil,
+	)
+
+	return cp, nil
+}
+
+// newPgxPool creates and pings a pgx pool for databaseURL
+func (cp *ConnectionPool) newPgxPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
+	cfg := cp.config
+
+	// Create pool config
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
 		return nil, fmt.Errorf("litellmdb: invalid database URL: %w", err)
 	}
 
@@ -82,31 +137,16 @@ func NewConnectionPool(cfg *models.Config) (*ConnectionPool, error) {
 
 	pool, err := pgxpool.NewWithConfig(connectCtx, poolConfig)
 	if err != nil {
-		cancel()
 		return nil, fmt.Errorf("litellmdb: failed to connect: %w", err)
 	}
 
 	// Verify connection
 	if err := pool.Ping(connectCtx); err != nil {
 		pool.Close()
-		cancel()
 		return nil, fmt.Errorf("litellmdb: ping failed: %w", err)
 	}
 
-	cp.pool = pool
-	cp.healthy.Store(true)
-
-	// Start background health check
-	cp.wg.Add(1)
-	go cp.healthCheckLoop()
-
-	cp.logger.Info("LiteLLM DB connection pool initialized",
-		"max_conns", cfg.MaxConns,
-		"min_conns", cfg.MinConns,
-		"database", security.MaskDatabaseURL(cfg.DatabaseURL),
-	)
-
-	return cp, nil
+	return pool, nil
 }
 
 // Acquire gets a connection from the pool
@@ -120,6 +160,27 @@ func (cp *ConnectionPool) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
 	return cp.pool.Acquire(ctx)
 }
 
+// AcquireRead gets a connection for read-only queries (auth lookups).
+// Uses the replica while it is healthy, otherwise the primary.
+func (cp *ConnectionPool) AcquireRead(ctx context.Context) (*pgxpool.Conn, error) {
+	if cp.closed.Load() {
+		return nil, models.ErrConnectionFailed
+	}
+	if cp.replica != nil && cp.replicaHealthy.Load() {
+		conn, err := cp.replica.Acquire(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		cp.logger.Warn("Failed to acquire replica connection, falling back to primary", "error", err)
+	}
+	return cp.Acquire(ctx)
+}
+
+// IsReadHealthy returns true if read queries can be served (primary or replica)
+func (cp *ConnectionPool) IsReadHealthy() bool {
+	return cp.healthy.Load() || (cp.replica != nil && cp.replicaHealthy.Load())
+}
+
 // Pool returns the underlying pgxpool.Pool
 func (cp *ConnectionPool) Pool() *pgxpool.Pool {
 	return cp.pool
@@ -138,6 +199,14 @@ func (cp *ConnectionPool) Stats() *pgxpool.Stat {
 	return cp.pool.Stat()
 }
 
+// ReplicaStats returns read replica pool statistics (nil if no replica)
+func (cp *ConnectionPool) ReplicaStats() *pgxpool.Stat {
+	if cp.replica == nil {
+		return nil
+	}
+	return cp.replica.Stat()
+}
+
 // Close closes the connection pool
 func (cp *ConnectionPool) Close() {
 	if !cp.closed.CompareAndSwap(false, true) {
@@ -162,10 +231,13 @@ func (cp *ConnectionPool) Close() {
 		cp.logger.Warn("Health check goroutine did not stop within timeout")
 	}
 
-	// Close pool
+	// Close pools
 	if cp.pool != nil {
 		cp.pool.Close()
 	}
+	if cp.replica != nil {
+		cp.replica.Close()
+	}
 
 	cp.logger.Info("LiteLLM DB connection pool closed")
 }
@@ -187,8 +259,13 @@ func (cp *ConnectionPool) healthCheckLoop() {
 	}
 }
 
-// performHealthCheck executes a health check
+// performHealthCheck executes a health check on the primary and the replica.
+// The primary is marked unhealthy and reconnected only after HealthFailureThreshold
+// consecutive failures, so a short failover does not flip the health status or drop
+// the pooled connections. Only a successful health query marks it healthy again.
 func (cp *ConnectionPool) performHealthCheck() {
+	defer cp.updateMetrics()
+
 	ctx, cancel := context.WithTimeout(cp.ctx, 5*time.Second)
 	defer cancel()
 
@@ -196,24 +273,69 @@ func (cp *ConnectionPool) performHealthCheck() {
 	err := cp.pool.QueryRow(ctx, queries.QueryHealthCheck).Scan(&result)
 
 	if err != nil {
-		wasHealthy := cp.healthy.Swap(false)
-		if wasHealthy {
-			cp.logger.Error("LiteLLM DB health check failed",
+		cp.consecutiveFailures++
+		if cp.consecutiveFailures >= cp.config.HealthFailureThreshold {
+			wasHealthy := cp.healthy.Swap(false)
+			if wasHealthy {
+				cp.logger.Error("LiteLLM DB health check failed",
+					"error", err,
+					"consecutive_failures", cp.consecutiveFailures,
+				)
+			}
+			// Try to reconnect
+			cp.tryReconnect()
+		} else {
+			cp.logger.Warn("LiteLLM DB health check failed, keeping healthy status",
 				"error", err,
+				"consecutive_failures", cp.consecutiveFailures,
+				"threshold", cp.config.HealthFailureThreshold,
 			)
 		}
-		// Try to reconnect
-		cp.tryReconnect()
 	} else {
+		cp.consecutiveFailures = 0
 		wasUnhealthy := !cp.healthy.Swap(true)
 		if wasUnhealthy {
 			cp.logger.Info("LiteLLM DB connection restored")
+			cp.reconnectMu.Lock()
 			cp.reconnectDelay = time.Second // Reset backoff
+			cp.reconnectMu.Unlock()
+		}
+	}
+
+	if cp.replica != nil {
+		cp.checkReplica()
+	}
+}
+
+// checkReplica pings the read replica and updates its health status
+func (cp *ConnectionPool) checkReplica() {
+	ctx, cancel := context.WithTimeout(cp.ctx, 5*time.Second)
+	defer cancel()
+
+	var result int
+	if err := cp.replica.QueryRow(ctx, queries.QueryHealthCheck).Scan(&result); err != nil {
+		cp.replicaFailures++
+		if cp.replicaFailures < cp.config.HealthFailureThreshold {
+			return
+		}
+		if cp.replicaHealthy.Swap(false) {
+			cp.logger.Warn("LiteLLM DB read replica unhealthy, auth lookups use primary", "error", err)
 		}
+		// Drop connections to a failed-over host so new ones resolve the current endpoint
+		cp.replica.Reset()
+		monitoring.LiteLLMDBReconnects.WithLabelValues(poolReplica, "failure").Inc()
+		return
+	}
+
+	cp.replicaFailures = 0
+	if !cp.replicaHealthy.Swap(true) {
+		cp.logger.Info("LiteLLM DB read replica restored")
+		monitoring.LiteLLMDBReconnects.WithLabelValues(poolReplica, "success").Inc()
 	}
 }
 
-// tryReconnect attempts to restore connection with exponential backoff
+// tryReconnect attempts to restore connection with exponential backoff.
+// A successful ping only resets the backoff; health is restored by the next successful health check.
 func (cp *ConnectionPool) tryReconnect() {
 	cp.reconnectMu.Lock()
 	defer cp.reconnectMu.Unlock()
@@ -227,6 +349,10 @@ func (cp *ConnectionPool) tryReconnect() {
 		"delay", cp.reconnectDelay,
 	)
 
+	// After a failover existing connections may still point at the old primary
+	// (now gone or read-only); reset so the pool dials the current endpoint
+	cp.pool.Reset()
+
 	ctx, cancel := context.WithTimeout(cp.ctx, cp.config.ConnectTimeout)
 	defer cancel()
 
@@ -236,17 +362,45 @@ func (cp *ConnectionPool) tryReconnect() {
 	if err != nil {
 		// Increase backoff (max 30s)
 		cp.reconnectDelay = minDuration(cp.reconnectDelay*2, 30*time.Second)
+		monitoring.LiteLLMDBReconnects.WithLabelValues(poolPrimary, "failure").Inc()
 		cp.logger.Error("Reconnection failed",
 			"error", err,
 			"next_delay", cp.reconnectDelay,
 		)
 	} else {
-		cp.healthy.Store(true)
 		cp.reconnectDelay = time.Second
+		monitoring.LiteLLMDBReconnects.WithLabelValues(poolPrimary, "success").Inc()
 		cp.logger.Info("Reconnection successful")
 	}
 }
 
+// updateMetrics publishes pool statistics and health to Prometheus
+func (cp *ConnectionPool) updateMetrics() {
+	setPoolMetrics(poolPrimary, cp.Stats(), cp.healthy.Load())
+	if cp.replica != nil {
+		setPoolMetrics(poolReplica, cp.ReplicaStats(), cp.replicaHealthy.Load())
+	}
+}
+
+func setPoolMetrics(pool string, stat *pgxpool.Stat, healthy bool) {
+	healthValue := 0.0
+	if healthy {
+		healthValue = 1
+	}
+	monitoring.LiteLLMDBHealthy.WithLabelValues(pool).Set(healthValue)
+
+	if stat == nil {
+		return
+	}
+	monitoring.LiteLLMDBPoolConnections.WithLabelValues(pool, "total").Set(float64(stat.TotalConns()))
+	monitoring.LiteLLMDBPoolConnections.WithLabelValues(pool, "idle").Set(float64(stat.IdleConns()))
+	monitoring.LiteLLMDBPoolConnections.WithLabelValues(pool, "acquired").Set(float64(stat.AcquiredConns()))
+	monitoring.LiteLLMDBPoolConnections.WithLabelValues(pool, "constructing").Set(float64(stat.ConstructingConns()))
+	monitoring.LiteLLMDBPoolConnections.WithLabelValues(pool, "max").Set(float64(stat.MaxConns()))
+	monitoring.LiteLLMDBPoolAcquireWaitSeconds.WithLabelValues(pool).Set(stat.AcquireDuration().Seconds())
+	monitoring.LiteLLMDBPoolEmptyAcquire.WithLabelValues(pool).Set(float64(stat.EmptyAcquireCount()))
+}
+
 // minDuration returns the minimum of two durations
 func minDuration(a, b time.Duration) time.Duration {
 	if a < b {
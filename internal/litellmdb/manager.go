@@ -17,8 +17,8 @@ type Manager interface {
 	ValidateToken(ctx context.Context, rawToken string) (*models.TokenInfo, error)
 	ValidateTokenForModel(ctx context.Context, rawToken, model string) (*models.TokenInfo, error)
 
-	// Logging - asynchronous logging
-	LogSpend(entry *models.SpendLogEntry) error
+	// Logging - asynchronous logging; ctx bounds the wait for queue space
+	LogSpend(ctx context.Context, entry *models.SpendLogEntry) error
 
 	// Status
 	IsEnabled() bool
@@ -32,6 +32,9 @@ type Manager interface {
 	// Pool access (for login queries)
 	GetPool() *pgxpool.Pool
 
+	// Read-only mode - pauses background writes (queued spend logs, DLQ recovery, daily aggregation)
+	SetReadOnly(readOnly bool)
+
 	// Lifecycle
 	Shutdown(ctx context.Context) error
 }
@@ -54,7 +57,7 @@ func (n *NoopManager) ValidateTokenForModel(ctx context.Context, rawToken, model
 	return nil, models.ErrModuleDisabled
 }
 
-func (n *NoopManager) LogSpend(entry *models.SpendLogEntry) error {
+func (n *NoopManager) LogSpend(ctx context.Context, entry *models.SpendLogEntry) error {
 	// no-op
 	return nil
 }
@@ -83,6 +86,10 @@ func (n *NoopManager) GetPool() *pgxpool.Pool {
 	return nil
 }
 
+func (n *NoopManager) SetReadOnly(readOnly bool) {
+	// no-op
+}
+
 func (n *NoopManager) Shutdown(ctx context.Context) error {
 	return nil
 }
@@ -128,6 +135,7 @@ func New(cfg *models.Config) (Manager, error) {
 
 	// Create authenticator
 	authenticator := auth.NewAuthenticator(pool, cache, cfg.Logger)
+	authenticator.SetBudgetBoost(cfg.BudgetBoost)
 
 	// Create spend logger
 	logger := spendlog.NewLogger(pool, cfg)
@@ -165,8 +173,8 @@ func (m *DefaultManager) ValidateTokenForModel(ctx context.Context, rawToken, mo
 }
 
 // LogSpend adds an entry to the logging queue
-func (m *DefaultManager) LogSpend(entry *models.SpendLogEntry) error {
-	return m.spendLogger.Log(entry)
+func (m *DefaultManager) LogSpend(ctx context.Context, entry *models.SpendLogEntry) error {
+	return m.spendLogger.Log(ctx, entry)
 }
 
 // IsEnabled returns true (module is enabled)
@@ -199,6 +207,11 @@ func (m *DefaultManager) GetPool() *pgxpool.Pool {
 	return m.pool.Pool()
 }
 
+// SetReadOnly pauses or resumes the spend logger's batch flushes, DLQ recovery and aggregation writes
+func (m *DefaultManager) SetReadOnly(readOnly bool) {
+	m.spendLogger.SetReadOnly(readOnly)
+}
+
 // Shutdown stops all components
 func (m *DefaultManager) Shutdown(ctx context.Context) error {
 	m.logger.Info("Shutting down LiteLLM DB Manager...")
@@ -70,7 +70,7 @@ func TestNoopManager_IsDisabled(t *testing.T) {
 	assert.False(t, noop.IsHealthy())
 
 	// All operations should be no-ops
-	assert.NoError(t, noop.LogSpend(&models.SpendLogEntry{RequestID: "test"}))
+	assert.NoError(t, noop.LogSpend(context.Background(), &models.SpendLogEntry{RequestID: "test"}))
 
 	authStats := noop.AuthCacheStats()
 	assert.Equal(t, 0, authStats.Size)
@@ -138,8 +138,8 @@ func TestNoopManager_AllMethodsSafe(t *testing.T) {
 	_ = noop.AuthCacheStats()
 	_ = noop.SpendLoggerStats()
 	_ = noop.ConnectionStats()
-	_ = noop.LogSpend(nil)
-	_ = noop.LogSpend(&models.SpendLogEntry{})
+	_ = noop.LogSpend(context.Background(), nil)
+	_ = noop.LogSpend(context.Background(), &models.SpendLogEntry{})
 	_ = noop.Shutdown(context.Background())
 
 	_, _ = noop.ValidateToken(context.Background(), "test")
@@ -34,9 +34,9 @@ func TestNoopManager(t *testing.T) {
 
 	t.Run("LogSpend", func(t *testing.T) {
 		// Should not panic
-		err := manager.LogSpend(&models.SpendLogEntry{RequestID: "test"})
+		err := manager.LogSpend(context.Background(), &models.SpendLogEntry{RequestID: "test"})
 		assert.NoError(t, err)
-		err = manager.LogSpend(nil)
+		err = manager.LogSpend(context.Background(), nil)
 		assert.NoError(t, err)
 	})
 
@@ -118,7 +118,7 @@ func TestDefaultManager_Integration(t *testing.T) {
 		}
 
 		// Should not panic
-		err := manager.LogSpend(entry)
+		err := manager.LogSpend(context.Background(), entry)
 		assert.NoError(t, err)
 
 		// Wait for flush
@@ -0,0 +1,116 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultBatchSize is the number of rows updated per statement
+const DefaultBatchSize = 1000
+
+// liteLLMRow matches spend log rows written by the LiteLLM proxy: the router always records
+// latency_ms in the metadata
+const liteLLMRow = `(metadata IS NULL OR (jsonb_typeof(metadata) = 'object' AND NOT metadata ? 'latency_ms'))`
+
+// backfillStep converts the spend log rows matching where by running set on them
+type backfillStep struct {
+	name        string
+	description string
+	where       string
+	set         string
+}
+
+// backfillSteps run in order. The metadata step must run last: it makes rows written by the
+// LiteLLM proxy indistinguishable from the router's own rows.
+var backfillSteps = []backfillStep{
+	{
+		name:        "aggregated",
+		description: "mark LiteLLM rows as aggregated, so their spend is not added to the daily tables again",
+		where:       `(cache_hit IS NULL OR cache_hit = '') AND ` + liteLLMRow,
+		set:         `cache_hit = 'False'`,
+	},
+	{
+		name:        "status",
+		description: "set the status of rows written before LiteLLM recorded it",
+		where:       `(status IS NULL OR status = '')`,
+		set:         `status = CASE WHEN metadata->>'status' = 'failure' THEN 'failure' ELSE 'success' END`,
+	},
+	{
+		name:        "request_tags",
+		description: "set missing request tags to an empty list",
+		where:       `request_tags IS NULL`,
+		set:         `request_tags = '[]'::jsonb`,
+	},
+	{
+		name:        "metadata",
+		description: "add the router's latency_ms, retry_count, fallback_used and cache_hit metadata fields",
+		where:       liteLLMRow,
+		set: `metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+			'latency_ms', GREATEST(0, (EXTRACT(EPOCH FROM ("endTime" - "startTime")) * 1000)::bigint),
+			'retry_count', 0,
+			'fallback_used', false,
+			'cache_hit', COALESCE(cache_hit = 'True', false))`,
+	},
+}
+
+// Execer runs statements; *pgxpool.Pool and *pgx.Conn implement it
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// StepResult is the outcome of one backfill step
+type StepResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Rows        int64  `json:"rows"` // Converted rows, or rows to convert in a dry run
+}
+
+// BackfillOptions control a backfill run
+type BackfillOptions struct {
+	BatchSize int  // Rows updated per statement (default: DefaultBatchSize)
+	DryRun    bool // Only count the rows each step would convert
+}
+
+// Backfill converts the spend log rows written by the LiteLLM proxy to the form the router
+// writes. Rows are updated in batches, so the table stays writable; steps only match rows
+// they have not converted yet, so an interrupted backfill can be run again.
+func Backfill(ctx context.Context, db Execer, opts BackfillOptions) ([]StepResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	results := make([]StepResult, 0, len(backfillSteps))
+	for _, step := range backfillSteps {
+		result := StepResult{Name: step.name, Description: step.description}
+		if opts.DryRun {
+			query := `SELECT count(*) FROM "LiteLLM_SpendLogs" WHERE ` + step.where
+			if err := db.QueryRow(ctx, query).Scan(&result.Rows); err != nil {
+				return results, fmt.Errorf("backfill step %s: %w", step.name, err)
+			}
+			results = append(results, result)
+			continue
+		}
+
+		query := `UPDATE "LiteLLM_SpendLogs" SET ` + step.set + ` WHERE request_id IN (
+			SELECT request_id FROM "LiteLLM_SpendLogs" WHERE ` + step.where + ` LIMIT $1)`
+		for {
+			if err := ctx.Err(); err != nil {
+				return append(results, result), err
+			}
+			tag, err := db.Exec(ctx, query, opts.BatchSize)
+			if err != nil {
+				return append(results, result), fmt.Errorf("backfill step %s: %w", step.name, err)
+			}
+			result.Rows += tag.RowsAffected()
+			if tag.RowsAffected() < int64(opts.BatchSize) {
+				break
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,148 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB answers UPDATE statements from a queue of affected row counts per step
+type fakeDB struct {
+	updates map[string][]int64 // step name -> affected rows of successive batches
+	counts  map[string]int64   // step name -> dry run count
+	execs   []string
+	columns [][]interface{} // information_schema rows returned by Query
+	limits  []interface{}
+	err     error
+}
+
+// stepOf returns the name of the backfill step whose condition is part of sql
+func stepOf(sql string) string {
+	for i := len(backfillSteps) - 1; i >= 0; i-- {
+		if strings.Contains(sql, "SET "+backfillSteps[i].set) || strings.HasSuffix(sql, "WHERE "+backfillSteps[i].where) {
+			return backfillSteps[i].name
+		}
+	}
+	return ""
+}
+
+func (f *fakeDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if f.err != nil {
+		return pgconn.CommandTag{}, f.err
+	}
+	step := stepOf(sql)
+	f.execs = append(f.execs, step)
+	f.limits = append(f.limits, args...)
+	var rows int64
+	if queue := f.updates[step]; len(queue) > 0 {
+		rows, f.updates[step] = queue[0], queue[1:]
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", rows)), nil
+}
+
+type fakeRow struct {
+	count int64
+	err   error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*int64) = r.count
+	return nil
+}
+
+func (f *fakeDB) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	return fakeRow{count: f.counts[stepOf(sql)], err: f.err}
+}
+
+func TestBackfill(t *testing.T) {
+	db := &fakeDB{updates: map[string][]int64{
+		"aggregated": {2, 2, 1},
+		"metadata":   {2, 0},
+	}}
+
+	results, err := Backfill(context.Background(), db, BackfillOptions{BatchSize: 2})
+	require.NoError(t, err)
+	require.Len(t, results, len(backfillSteps))
+	assert.Equal(t, "aggregated", results[0].Name)
+	assert.Equal(t, int64(5), results[0].Rows)
+	assert.Equal(t, int64(0), results[1].Rows)
+	assert.Equal(t, int64(0), results[2].Rows)
+	assert.Equal(t, "metadata", results[3].Name)
+	assert.Equal(t, int64(2), results[3].Rows)
+
+	assert.Equal(t, []string{"aggregated", "aggregated", "aggregated", "status", "request_tags", "metadata", "metadata"}, db.execs,
+		"a step repeats until a batch is not full")
+	assert.Equal(t, 2, db.limits[0])
+}
+
+func TestBackfill_DryRun(t *testing.T) {
+	db := &fakeDB{counts: map[string]int64{"aggregated": 10, "metadata": 12}}
+
+	results, err := Backfill(context.Background(), db, BackfillOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, db.execs, "a dry run does not update")
+	assert.Equal(t, int64(10), results[0].Rows)
+	assert.Equal(t, int64(12), results[3].Rows)
+}
+
+func TestBackfill_Errors(t *testing.T) {
+	db := &fakeDB{err: errors.New("connection reset")}
+	_, err := Backfill(context.Background(), db, BackfillOptions{})
+	assert.ErrorContains(t, err, "backfill step aggregated: connection reset")
+
+	_, err = Backfill(context.Background(), db, BackfillOptions{DryRun: true})
+	assert.ErrorContains(t, err, "connection reset")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Backfill(ctx, &fakeDB{}, BackfillOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBackfillSteps_MetadataLast(t *testing.T) {
+	// Earlier steps tell LiteLLM rows apart by the missing latency_ms, which the metadata step adds
+	assert.Equal(t, "metadata", backfillSteps[len(backfillSteps)-1].name)
+	assert.Contains(t, backfillSteps[0].where, liteLLMRow)
+}
+
+// fakeRows returns information_schema column rows: table, column, type, nullable
+type fakeRows struct {
+	pgx.Rows
+	rows [][]interface{}
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.next-1]
+	*dest[0].(*string) = row[0].(string)
+	*dest[1].(*string) = row[1].(string)
+	*dest[2].(*string) = row[2].(string)
+	*dest[3].(*bool) = row[3].(bool)
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
+
+func (f *fakeDB) Query(_ context.Context, _ string, args ...interface{}) (pgx.Rows, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.limits = append(f.limits, args...)
+	return &fakeRows{rows: f.columns}, nil
+}
@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Result is the outcome of a migrate run
+type Result struct {
+	Schema   Report       `json:"schema"`
+	Backfill []StepResult `json:"backfill,omitempty"`
+	DryRun   bool         `json:"dry_run,omitempty"`
+}
+
+// WriteText writes the result as tables
+func (r *Result) WriteText(w io.Writer) error {
+	verdict := "compatible"
+	if !r.Schema.Compatible() {
+		verdict = "INCOMPATIBLE"
+	}
+	if _, err := fmt.Fprintf(w, "schema: %s  tables: %d  mismatches: %d  extra columns: %d\n",
+		verdict, r.Schema.Tables, len(r.Schema.Mismatches), len(r.Schema.Extra)); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(r.Schema.Mismatches) > 0 || len(r.Schema.Extra) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nTABLE\tCOLUMN\tPROBLEM\tEXPECTED\tACTUAL")
+		for _, m := range append(r.Schema.Mismatches, r.Schema.Extra...) {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Table, m.Column, m.Problem, m.Expected, m.Actual)
+		}
+	}
+	if r.Backfill != nil {
+		rowsHeader := "ROWS"
+		if r.DryRun {
+			rowsHeader = "ROWS TO CONVERT"
+		}
+		_, _ = fmt.Fprintf(tw, "\nSTEP\t%s\tDESCRIPTION\n", rowsHeader)
+		for _, step := range r.Backfill {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", step.Name, step.Rows, step.Description)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the result as JSON
+func (r *Result) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,234 @@
+// Package migrate checks a LiteLLM database against the schema the router is written for and
+// converts spend log rows written by the LiteLLM proxy, so the router can take over as writer.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RouterTables are the LiteLLM tables the router reads or writes
+var RouterTables = []string{
+	"LiteLLM_BudgetTable",
+	"LiteLLM_DailyAgentSpend",
+	"LiteLLM_DailyEndUserSpend",
+	"LiteLLM_DailyOrganizationSpend",
+	"LiteLLM_DailyTagSpend",
+	"LiteLLM_DailyTeamSpend",
+	"LiteLLM_DailyUserSpend",
+	"LiteLLM_OrganizationMembership",
+	"LiteLLM_OrganizationTable",
+	"LiteLLM_SpendLogs",
+	"LiteLLM_TeamMembership",
+	"LiteLLM_TeamTable",
+	"LiteLLM_UserTable",
+	"LiteLLM_VerificationToken",
+}
+
+// Problems reported by Compare
+const (
+	ProblemMissingTable  = "missing_table"
+	ProblemMissingColumn = "missing_column"
+	ProblemType          = "type"
+	ProblemNullable      = "nullable" // The schema requires a value, the database allows NULL
+	ProblemExtraColumn   = "extra_column"
+)
+
+// Column is a table column as reported by information_schema.columns
+type Column struct {
+	Name     string
+	Type     string // data_type, e.g. "text", "jsonb", "ARRAY"
+	Nullable bool
+}
+
+// Mismatch is a difference between the expected schema and the database
+type Mismatch struct {
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Problem  string `json:"problem"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// Report is the result of a schema check
+type Report struct {
+	Tables     int        `json:"tables"`
+	Mismatches []Mismatch `json:"mismatches"`
+	// Extra are columns the expected schema does not know, added by a newer LiteLLM version.
+	// The router does not use them, so they do not make the database incompatible.
+	Extra []Mismatch `json:"extra_columns"`
+}
+
+// Compatible reports whether the router can use the database
+func (r Report) Compatible() bool {
+	return len(r.Mismatches) == 0
+}
+
+// prismaTypes maps Prisma scalar types to PostgreSQL data types
+var prismaTypes = map[string]string{
+	"String":   "text",
+	"Int":      "integer",
+	"BigInt":   "bigint",
+	"Float":    "double precision",
+	"Decimal":  "numeric",
+	"Boolean":  "boolean",
+	"DateTime": "timestamp without time zone",
+	"Json":     "jsonb",
+	"Bytes":    "bytea",
+}
+
+var (
+	blockStart = regexp.MustCompile(`^(model|enum)\s+(\w+)\s*\{`)
+	columnMap  = regexp.MustCompile(`@map\("([^"]+)"\)`)
+)
+
+// ParseSchema returns the columns of every model of a Prisma schema by table name.
+// Relation fields are skipped; enum columns have the type "USER-DEFINED".
+func ParseSchema(prisma string) map[string][]Column {
+	lines := strings.Split(prisma, "\n")
+
+	enums := make(map[string]bool)
+	for _, line := range lines {
+		if m := blockStart.FindStringSubmatch(strings.TrimSpace(line)); m != nil && m[1] == "enum" {
+			enums[m[2]] = true
+		}
+	}
+
+	tables := make(map[string][]Column)
+	var table string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+		if table == "" {
+			if m := blockStart.FindStringSubmatch(line); m != nil && m[1] == "model" {
+				table = m[2]
+				tables[table] = nil
+			}
+			continue
+		}
+		if line == "}" {
+			table = ""
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "@@") {
+			continue
+		}
+
+		prismaType := fields[1]
+		list := strings.HasSuffix(prismaType, "[]")
+		optional := strings.HasSuffix(prismaType, "?")
+		prismaType = strings.TrimSuffix(strings.TrimSuffix(prismaType, "?"), "[]")
+
+		column := Column{Name: fields[0], Nullable: optional || list}
+		if m := columnMap.FindStringSubmatch(line); m != nil {
+			column.Name = m[1]
+		}
+		switch {
+		case enums[prismaType]:
+			column.Type = "USER-DEFINED"
+		case prismaTypes[prismaType] != "":
+			column.Type = prismaTypes[prismaType]
+		default:
+			continue // Relation field
+		}
+		if list {
+			column.Type = "ARRAY"
+		}
+		tables[table] = append(tables[table], column)
+	}
+	return tables
+}
+
+// Compare checks the actual columns of tables (table -> column name -> column) against the
+// expected schema. Scalar lists are not checked for nullability: Prisma creates them nullable.
+func Compare(expected map[string][]Column, actual map[string]map[string]Column, tables []string) Report {
+	report := Report{Tables: len(tables), Mismatches: []Mismatch{}, Extra: []Mismatch{}}
+	for _, table := range tables {
+		columns, ok := actual[table]
+		if !ok {
+			report.Mismatches = append(report.Mismatches, Mismatch{Table: table, Problem: ProblemMissingTable})
+			continue
+		}
+
+		known := make(map[string]bool, len(expected[table]))
+		for _, want := range expected[table] {
+			known[want.Name] = true
+			got, ok := columns[want.Name]
+			switch {
+			case !ok:
+				report.Mismatches = append(report.Mismatches, Mismatch{
+					Table: table, Column: want.Name, Problem: ProblemMissingColumn, Expected: want.Type,
+				})
+			case got.Type != want.Type:
+				report.Mismatches = append(report.Mismatches, Mismatch{
+					Table: table, Column: want.Name, Problem: ProblemType, Expected: want.Type, Actual: got.Type,
+				})
+			case got.Nullable && !want.Nullable && want.Type != "ARRAY":
+				report.Mismatches = append(report.Mismatches, Mismatch{
+					Table: table, Column: want.Name, Problem: ProblemNullable, Expected: "NOT NULL", Actual: "NULL",
+				})
+			}
+		}
+
+		extra := make([]string, 0)
+		for name := range columns {
+			if !known[name] {
+				extra = append(extra, name)
+			}
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			report.Extra = append(report.Extra, Mismatch{
+				Table: table, Column: name, Problem: ProblemExtraColumn, Actual: columns[name].Type,
+			})
+		}
+	}
+	return report
+}
+
+// queryColumns lists the columns of the router tables in the current schema
+const queryColumns = `
+	SELECT table_name, column_name, data_type, is_nullable = 'YES'
+	FROM information_schema.columns
+	WHERE table_schema = current_schema() AND table_name = ANY($1)
+`
+
+// Querier runs queries; *pgxpool.Pool and *pgx.Conn implement it
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// CheckSchema compares the router tables of the database with the Prisma schema
+func CheckSchema(ctx context.Context, db Querier, prisma string) (Report, error) {
+	rows, err := db.Query(ctx, queryColumns, RouterTables)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list columns: %w", err)
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]Column)
+	for rows.Next() {
+		var table string
+		var column Column
+		if err := rows.Scan(&table, &column.Name, &column.Type, &column.Nullable); err != nil {
+			return Report{}, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]Column)
+		}
+		actual[table][column.Name] = column
+	}
+	if err := rows.Err(); err != nil {
+		return Report{}, fmt.Errorf("failed to list columns: %w", err)
+	}
+	return Compare(ParseSchema(prisma), actual, RouterTables), nil
+}
@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+// Comment { with a brace
+model LiteLLM_Test { // trailing comment
+  id         String   @id @default(uuid())
+  spend      Float    @default(0.0)
+  models     String[]
+  tags       Json?    @default("[]")
+  created_at DateTime @default(now()) @map("created")
+  state      JobStatus
+  budget     LiteLLM_Budget? @relation(fields: [id], references: [id])
+  @@index([created_at])
+}
+
+enum JobStatus {
+  ACTIVE
+  INACTIVE
+}
+`
+
+func TestParseSchema(t *testing.T) {
+	tables := ParseSchema(testSchema)
+	require.Len(t, tables, 1)
+	assert.Equal(t, []Column{
+		{Name: "id", Type: "text"},
+		{Name: "spend", Type: "double precision"},
+		{Name: "models", Type: "ARRAY", Nullable: true},
+		{Name: "tags", Type: "jsonb", Nullable: true},
+		{Name: "created", Type: "timestamp without time zone"},
+		{Name: "state", Type: "USER-DEFINED"},
+	}, tables["LiteLLM_Test"])
+}
+
+func TestParseSchema_LiteLLM(t *testing.T) {
+	tables := ParseSchema(litellmdb.Schema)
+	for _, table := range RouterTables {
+		assert.NotEmpty(t, tables[table], table)
+	}
+
+	columns := make(map[string]Column)
+	for _, column := range tables["LiteLLM_SpendLogs"] {
+		columns[column.Name] = column
+	}
+	assert.Equal(t, Column{Name: "metadata", Type: "jsonb", Nullable: true}, columns["metadata"])
+	assert.Equal(t, Column{Name: "startTime", Type: "timestamp without time zone"}, columns["startTime"])
+	assert.Equal(t, Column{Name: "request_id", Type: "text"}, columns["request_id"])
+}
+
+func TestCompare(t *testing.T) {
+	expected := map[string][]Column{
+		"LiteLLM_SpendLogs": {
+			{Name: "request_id", Type: "text"},
+			{Name: "spend", Type: "double precision"},
+			{Name: "metadata", Type: "jsonb", Nullable: true},
+			{Name: "agent_id", Type: "text", Nullable: true},
+			{Name: "models", Type: "ARRAY"},
+		},
+		"LiteLLM_TeamTable": {{Name: "team_id", Type: "text"}},
+	}
+	actual := map[string]map[string]Column{
+		"LiteLLM_SpendLogs": {
+			"request_id": {Name: "request_id", Type: "text"},
+			"spend":      {Name: "spend", Type: "double precision", Nullable: true},
+			"metadata":   {Name: "metadata", Type: "json", Nullable: true},
+			"models":     {Name: "models", Type: "ARRAY", Nullable: true},
+			"new_column": {Name: "new_column", Type: "text", Nullable: true},
+		},
+	}
+
+	report := Compare(expected, actual, []string{"LiteLLM_SpendLogs", "LiteLLM_TeamTable"})
+	assert.False(t, report.Compatible())
+	assert.Equal(t, 2, report.Tables)
+	assert.Equal(t, []Mismatch{
+		{Table: "LiteLLM_SpendLogs", Column: "spend", Problem: ProblemNullable, Expected: "NOT NULL", Actual: "NULL"},
+		{Table: "LiteLLM_SpendLogs", Column: "metadata", Problem: ProblemType, Expected: "jsonb", Actual: "json"},
+		{Table: "LiteLLM_SpendLogs", Column: "agent_id", Problem: ProblemMissingColumn, Expected: "text"},
+		{Table: "LiteLLM_TeamTable", Problem: ProblemMissingTable},
+	}, report.Mismatches)
+	assert.Equal(t, []Mismatch{
+		{Table: "LiteLLM_SpendLogs", Column: "new_column", Problem: ProblemExtraColumn, Actual: "text"},
+	}, report.Extra)
+
+	matching := map[string]map[string]Column{
+		"LiteLLM_TeamTable": {"team_id": {Name: "team_id", Type: "text"}, "extra": {Name: "extra", Type: "text"}},
+	}
+	report = Compare(expected, matching, []string{"LiteLLM_TeamTable"})
+	assert.True(t, report.Compatible(), "extra columns of a newer LiteLLM version are compatible")
+	assert.Len(t, report.Extra, 1)
+}
+
+func TestCheckSchema(t *testing.T) {
+	prisma := "model LiteLLM_SpendLogs {\n  request_id String @id\n  metadata Json?\n}\n"
+	db := &fakeDB{columns: [][]interface{}{
+		{"LiteLLM_SpendLogs", "request_id", "text", false},
+		{"LiteLLM_SpendLogs", "metadata", "json", true},
+	}}
+
+	report, err := CheckSchema(context.Background(), db, prisma)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{RouterTables}, db.limits)
+	assert.Equal(t, len(RouterTables), report.Tables)
+	assert.Contains(t, report.Mismatches, Mismatch{
+		Table: "LiteLLM_SpendLogs", Column: "metadata", Problem: ProblemType, Expected: "jsonb", Actual: "json",
+	})
+	assert.Contains(t, report.Mismatches, Mismatch{Table: "LiteLLM_TeamTable", Problem: ProblemMissingTable})
+
+	_, err = CheckSchema(context.Background(), &fakeDB{err: errors.New("denied")}, prisma)
+	assert.ErrorContains(t, err, "denied")
+}
+
+func TestResult_Write(t *testing.T) {
+	result := &Result{
+		Schema: Report{
+			Tables:     2,
+			Mismatches: []Mismatch{{Table: "LiteLLM_SpendLogs", Column: "metadata", Problem: ProblemType, Expected: "jsonb", Actual: "json"}},
+			Extra:      []Mismatch{{Table: "LiteLLM_SpendLogs", Column: "new_column", Problem: ProblemExtraColumn, Actual: "text"}},
+		},
+		Backfill: []StepResult{{Name: "aggregated", Description: "mark", Rows: 7}},
+		DryRun:   true,
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, result.WriteText(&text))
+	assert.Contains(t, text.String(), "schema: INCOMPATIBLE  tables: 2  mismatches: 1  extra columns: 1")
+	assert.Regexp(t, `LiteLLM_SpendLogs\s+metadata\s+type\s+jsonb\s+json`, text.String())
+	assert.Regexp(t, `STEP\s+ROWS TO CONVERT`, text.String())
+	assert.Regexp(t, `aggregated\s+7\s+mark`, text.String())
+
+	var out bytes.Buffer
+	require.NoError(t, result.WriteJSON(&out))
+	var decoded Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, *result, decoded)
+}
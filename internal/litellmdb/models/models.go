@@ -45,9 +45,13 @@ type Config struct {
 	MaxConns    int32  // Max connections in pool (default: 10)
 	MinConns    int32  // Min connections in pool (default: 2)
 
+	// Optional read replica for auth lookups (spend writes always go to DatabaseURL)
+	ReplicaURL string
+
 	// Health check
-	HealthCheckInterval time.Duration // Health check interval (default: 10s)
-	ConnectTimeout      time.Duration // Connection timeout (default: 5s)
+	HealthCheckInterval    time.Duration // Health check interval (default: 10s)
+	ConnectTimeout         time.Duration // Connection timeout (default: 5s)
+	HealthFailureThreshold int           // Consecutive failed checks before marking unhealthy (default: 3)
 
 	// Auth cache
 	AuthCacheTTL  time.Duration // Token cache TTL (default: 5s)
@@ -58,6 +62,9 @@ type Config struct {
 	LogBatchSize     int           // Batch size for INSERT (default: 100)
 	LogFlushInterval time.Duration // Flush interval (default: 5s)
 
+	// Optional: temporary budget boosts applied when tokens are validated
+	BudgetBoost BudgetBoostFunc
+
 	// Logger
 	Logger *slog.Logger
 }
@@ -65,15 +72,16 @@ type Config struct {
 // DefaultConfig returns configuration with default values
 func DefaultConfig() *Config {
 	return &Config{
-		MaxConns:            10,
-		MinConns:            2,
-		HealthCheckInterval: 10 * time.Second,
-		ConnectTimeout:      5 * time.Second,
-		AuthCacheTTL:        5 * time.Second,
-		AuthCacheSize:       10000,
-		LogQueueSize:        10000,
-		LogBatchSize:        100,
-		LogFlushInterval:    5 * time.Second,
+		MaxConns:               10,
+		MinConns:               2,
+		HealthCheckInterval:    10 * time.Second,
+		ConnectTimeout:         5 * time.Second,
+		HealthFailureThreshold: 3,
+		AuthCacheTTL:           5 * time.Second,
+		AuthCacheSize:          10000,
+		LogQueueSize:           10000,
+		LogBatchSize:           100,
+		LogFlushInterval:       5 * time.Second,
 	}
 }
 
@@ -96,6 +104,9 @@ func (c *Config) ApplyDefaults() {
 	if c.ConnectTimeout == 0 {
 		c.ConnectTimeout = defaults.ConnectTimeout
 	}
+	if c.HealthFailureThreshold <= 0 {
+		c.HealthFailureThreshold = defaults.HealthFailureThreshold
+	}
 	if c.AuthCacheTTL == 0 {
 		c.AuthCacheTTL = defaults.AuthCacheTTL
 	}
@@ -166,6 +177,9 @@ type TokenInfo struct {
 	TeamBlocked   *bool    // Team is blocked
 	TeamTPMLimit  *int64   // Team's TPM limit
 	TeamRPMLimit  *int64   // Team's RPM limit
+	TeamModels    []string // Models allowed to the team (empty = all)
+
+	TeamMetadata map[string]interface{} // Team metadata dict
 
 	// ==================== Organization Level (external budget) ====================
 	OrgSpend     *float64 // Organization's current spend
@@ -197,22 +211,43 @@ func (t *TokenInfo) IsExpired() bool {
 	return utils.NowUTC().After(*t.Expires)
 }
 
+// BudgetBoost is extra budget temporarily granted to a token and its team,
+// as a fraction of the max budget (0.5 = +50%, 0 = none)
+type BudgetBoost struct {
+	Key  float64
+	Team float64
+}
+
+// BudgetBoostFunc returns the budget boosts active for a token
+type BudgetBoostFunc func(info *TokenInfo) BudgetBoost
+
 // IsBudgetExceeded checks if token budget is exceeded (embedded, use >)
 func (t *TokenInfo) IsBudgetExceeded() bool {
+	return t.isBudgetExceeded(0)
+}
+
+func (t *TokenInfo) isBudgetExceeded(boost float64) bool {
 	if t.MaxBudget == nil {
 		return false
 	}
-	return t.Spend > *t.MaxBudget
+	return t.Spend > *t.MaxBudget*(1+boost)
 }
 
-// IsModelAllowed checks if model is in allowed list
+// AllProxyModels in a models list allows every model, as in LiteLLM
+const AllProxyModels = "all-proxy-models"
+
+// IsModelAllowed checks if model is in the allowed lists of the token and of its team
 func (t *TokenInfo) IsModelAllowed(model string) bool {
-	// Empty list = all models allowed
-	if len(t.Models) == 0 {
+	return modelListAllows(t.Models, model) && modelListAllows(t.TeamModels, model)
+}
+
+// modelListAllows checks if model is in list (empty list = all models allowed)
+func modelListAllows(list []string, model string) bool {
+	if len(list) == 0 {
 		return true
 	}
-	for _, m := range t.Models {
-		if m == model {
+	for _, m := range list {
+		if m == model || m == AllProxyModels {
 			return true
 		}
 	}
@@ -231,12 +266,12 @@ func (t *TokenInfo) checkUserBudget() bool {
 	return *t.UserSpend > *t.UserMaxBudget
 }
 
-// checkTeamBudget checks team budget (embedded, use >)
-func (t *TokenInfo) checkTeamBudget() bool {
+// isTeamBudgetExceeded checks team budget raised by boost (embedded, use >)
+func (t *TokenInfo) isTeamBudgetExceeded(boost float64) bool {
 	if t.TeamMaxBudget == nil || t.TeamSpend == nil {
 		return false
 	}
-	return *t.TeamSpend > *t.TeamMaxBudget
+	return *t.TeamSpend > *t.TeamMaxBudget*(1+boost)
 }
 
 // checkTeamMemberBudget checks team member budget (external, use >=)
@@ -274,6 +309,11 @@ func (t *TokenInfo) checkOrganizationMemberBudget() bool {
 // 7. Organization member budget
 // 8. Model allowed
 func (t *TokenInfo) Validate(model string) error {
+	return t.ValidateWithBoost(model, BudgetBoost{})
+}
+
+// ValidateWithBoost is Validate with the token and team budgets raised by boost
+func (t *TokenInfo) ValidateWithBoost(model string, boost BudgetBoost) error {
 	// Check basic validity
 	if t.Blocked {
 		return ErrTokenBlocked
@@ -283,10 +323,10 @@ func (t *TokenInfo) Validate(model string) error {
 	}
 
 	// Check budget hierarchy (embedded first, then external)
-	if t.IsBudgetExceeded() {
+	if t.isBudgetExceeded(boost.Key) {
 		return ErrBudgetExceeded
 	}
-	if t.checkTeamBudget() {
+	if t.isTeamBudgetExceeded(boost.Team) {
 		return ErrBudgetExceeded
 	}
 	if t.checkTeamMemberBudget() {
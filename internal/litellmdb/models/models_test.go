@@ -18,6 +18,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, int32(2), cfg.MinConns)
 	assert.Equal(t, 10*time.Second, cfg.HealthCheckInterval)
 	assert.Equal(t, 5*time.Second, cfg.ConnectTimeout)
+	assert.Equal(t, 3, cfg.HealthFailureThreshold)
 	assert.Equal(t, 5*time.Second, cfg.AuthCacheTTL)
 	assert.Equal(t, 10000, cfg.AuthCacheSize)
 	assert.Equal(t, 10000, cfg.LogQueueSize)
@@ -36,6 +37,7 @@ func TestConfig_ApplyDefaults_AllZero(t *testing.T) {
 	assert.Equal(t, defaults.MinConns, cfg.MinConns)
 	assert.Equal(t, defaults.HealthCheckInterval, cfg.HealthCheckInterval)
 	assert.Equal(t, defaults.ConnectTimeout, cfg.ConnectTimeout)
+	assert.Equal(t, defaults.HealthFailureThreshold, cfg.HealthFailureThreshold)
 	assert.Equal(t, defaults.AuthCacheTTL, cfg.AuthCacheTTL)
 	assert.Equal(t, defaults.AuthCacheSize, cfg.AuthCacheSize)
 	assert.Equal(t, defaults.LogQueueSize, cfg.LogQueueSize)
@@ -201,6 +203,23 @@ func TestTokenInfo_IsModelAllowed_ModelNotInList(t *testing.T) {
 	assert.False(t, token.IsModelAllowed("claude-3"))
 }
 
+func TestTokenInfo_IsModelAllowed_TeamModels(t *testing.T) {
+	token := &TokenInfo{
+		TeamModels: []string{"gpt-4", "claude-3"},
+	}
+	assert.True(t, token.IsModelAllowed("gpt-4"))
+	assert.False(t, token.IsModelAllowed("gemini-pro"))
+
+	// Both the key and the team must allow the model
+	token.Models = []string{"gpt-4", "gemini-pro"}
+	assert.True(t, token.IsModelAllowed("gpt-4"))
+	assert.False(t, token.IsModelAllowed("claude-3"))
+	assert.False(t, token.IsModelAllowed("gemini-pro"))
+
+	token.TeamModels = []string{AllProxyModels}
+	assert.True(t, token.IsModelAllowed("gemini-pro"))
+}
+
 // ==================== Budget Check Helper Tests ====================
 
 func TestTokenInfo_checkUserBudget_PersonalKey(t *testing.T) {
@@ -240,7 +259,7 @@ func TestTokenInfo_checkUserBudget_NilBudget(t *testing.T) {
 	assert.False(t, token.checkUserBudget())
 }
 
-func TestTokenInfo_checkTeamBudget_ExceededEmbedded(t *testing.T) {
+func TestTokenInfo_isTeamBudgetExceeded_ExceededEmbedded(t *testing.T) {
 	teamBudget := 100.0
 	teamSpend := 150.0
 	token := &TokenInfo{
@@ -248,10 +267,10 @@ func TestTokenInfo_checkTeamBudget_ExceededEmbedded(t *testing.T) {
 		TeamSpend:     &teamSpend,
 	}
 
-	assert.True(t, token.checkTeamBudget())
+	assert.True(t, token.isTeamBudgetExceeded(0))
 }
 
-func TestTokenInfo_checkTeamBudget_NotExceeded(t *testing.T) {
+func TestTokenInfo_isTeamBudgetExceeded_NotExceeded(t *testing.T) {
 	teamBudget := 100.0
 	teamSpend := 50.0
 	token := &TokenInfo{
@@ -259,7 +278,7 @@ func TestTokenInfo_checkTeamBudget_NotExceeded(t *testing.T) {
 		TeamSpend:     &teamSpend,
 	}
 
-	assert.False(t, token.checkTeamBudget())
+	assert.False(t, token.isTeamBudgetExceeded(0))
 }
 
 func TestTokenInfo_checkTeamMemberBudget_ExceededExternal(t *testing.T) {
@@ -416,6 +435,21 @@ func TestTokenInfo_Validate_ValidationOrder(t *testing.T) {
 
 // ==================== Integration Tests ====================
 
+func TestTokenInfo_ValidateWithBoost(t *testing.T) {
+	maxBudget, teamMaxBudget, teamSpend := 100.0, 1000.0, 1200.0
+	token := &TokenInfo{Spend: 140, MaxBudget: &maxBudget}
+
+	assert.ErrorIs(t, token.Validate(""), ErrBudgetExceeded)
+	assert.NoError(t, token.ValidateWithBoost("", BudgetBoost{Key: 0.5}))
+	assert.ErrorIs(t, token.ValidateWithBoost("", BudgetBoost{Key: 0.2}), ErrBudgetExceeded)
+	assert.ErrorIs(t, token.ValidateWithBoost("", BudgetBoost{Team: 0.5}), ErrBudgetExceeded, "team boost does not raise the key budget")
+
+	token = &TokenInfo{TeamID: "team1", TeamMaxBudget: &teamMaxBudget, TeamSpend: &teamSpend}
+	assert.ErrorIs(t, token.Validate(""), ErrBudgetExceeded)
+	assert.NoError(t, token.ValidateWithBoost("", BudgetBoost{Team: 0.5}))
+	assert.ErrorIs(t, token.ValidateWithBoost("", BudgetBoost{Key: 0.5}), ErrBudgetExceeded, "key boost does not raise the team budget")
+}
+
 func TestConfig_FullWorkflow(t *testing.T) {
 	// Create config with custom values
 	cfg := &Config{
@@ -291,6 +291,21 @@ const (
 // Number of parameters per SpendLogEntry in batch insert
 const spendLogParamCount = 25
 
+// SpendLogsTable is the spend log table name used by COPY
+const SpendLogsTable = "LiteLLM_SpendLogs"
+
+// SpendLogCopyColumns lists the columns written by COPY, in GetSpendLogParams order
+// followed by messages and response (always NULL, matching the batch INSERT)
+var SpendLogCopyColumns = []string{
+	"request_id", "call_type", "api_key", "spend", "total_tokens",
+	"prompt_tokens", "completion_tokens", "startTime", "endTime",
+	"model", "model_id", "model_group", "custom_llm_provider", "api_base",
+	"user", "metadata", "team_id", "organization_id", "end_user",
+	"requester_ip_address", "status", "session_id",
+	"agent_id", "mcp_namespaced_tool_name", "request_tags",
+	"messages", "response",
+}
+
 // BuildBatchInsertQuery builds a query for batch INSERT
 func BuildBatchInsertQuery(count int) string {
 	if count <= 0 {
@@ -21,6 +21,7 @@ SELECT
   t.expires,
   t.blocked as token_blocked,
   t.models as token_models,
+  t.metadata as token_metadata,
 
   -- ============ User ============
   u.user_id as user_id_check,
@@ -38,6 +39,8 @@ SELECT
   tm.blocked as team_blocked,
   tm.tpm_limit as team_tpm_limit,
   tm.rpm_limit as team_rpm_limit,
+  tm.models as team_models,
+  tm.metadata as team_metadata,
 
   -- ============ Organization ============
   o.organization_id as org_id_check,
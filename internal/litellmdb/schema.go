@@ -0,0 +1,8 @@
+package litellmdb
+
+import _ "embed"
+
+// Schema is the LiteLLM Prisma schema the queries of this package are written against
+//
+//go:embed schema.prisma
+var Schema string
@@ -53,3 +53,21 @@ func GetBatchParams(entries []*models.SpendLogEntry) []interface{} {
 	}
 	return params
 }
+
+// GetCopyRows returns rows for COPY into LiteLLM_SpendLogs (columns: queries.SpendLogCopyColumns)
+func GetCopyRows(entries []*models.SpendLogEntry) [][]interface{} {
+	rows := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, append(GetSpendLogParams(entry), nil, nil)) // messages, response = NULL
+	}
+	return rows
+}
+
+// getRequestIDs returns request IDs of entries in order
+func getRequestIDs(entries []*models.SpendLogEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.RequestID)
+	}
+	return ids
+}
@@ -2,15 +2,19 @@ package spendlog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/connection"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/queries"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
@@ -46,6 +50,7 @@ type Logger struct {
 	wg        sync.WaitGroup
 	shutdown  atomic.Bool // Track if shutdown has been called
 	startOnce sync.Once   // Ensure Start() is called only once
+	readOnly  atomic.Bool // Read-only mode: batch flushes, DLQ recovery and aggregation skip their writes
 
 	// Metrics
 	queued            uint64 // Total queued
@@ -113,12 +118,20 @@ func (sl *Logger) Start() {
 	})
 }
 
+// SetReadOnly pauses (or resumes) all background writes. Queued entries are held in the
+// queue, failed batches stay in the DLQ and unaggregated logs are picked up by the
+// safety-net once read-only mode is switched off; the next flush tick writes them.
+// Shutdown still flushes held entries.
+func (sl *Logger) SetReadOnly(readOnly bool) {
+	sl.readOnly.Store(readOnly)
+}
+
 // Log adds an entry to the queue with backpressure handling
-// BLOCKING: Waits up to 5 seconds for queue space if full
-// Returns ErrQueueFull if timeout reached (entry not queued)
+// BLOCKING: Waits up to 5 seconds for queue space if full, or until ctx is done
+// Returns ErrQueueFull if timeout reached (entry not queued), ctx.Err() if ctx ended first
 // This preserves all spend entries with slight latency impact on API calls
 // If queue has space, returns immediately
-func (sl *Logger) Log(entry *models.SpendLogEntry) error {
+func (sl *Logger) Log(ctx context.Context, entry *models.SpendLogEntry) error {
 	if entry == nil {
 		return nil
 	}
@@ -133,7 +146,7 @@ func (sl *Logger) Log(entry *models.SpendLogEntry) error {
 	}
 
 	// Queue was full, now attempt blocking send with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	select {
@@ -145,10 +158,19 @@ func (sl *Logger) Log(entry *models.SpendLogEntry) error {
 		)
 		return nil
 
-	case <-ctx.Done():
-		// Timeout reached - queue still full after 5 seconds
+	case <-waitCtx.Done():
 		atomic.AddUint64(&sl.dropped, 1)
 		atomic.AddUint64(&sl.queueFullCount, 1)
+		if err := ctx.Err(); err != nil {
+			// Caller gave up (request logging grace expired) before the queue had space
+			sl.logger.Error("[DB] SpendLog entry dropped: queue full, context done",
+				"request_id", entry.RequestID,
+				"queue_len", len(sl.queue),
+				"error", err,
+			)
+			return err
+		}
+		// Timeout reached - queue still full after 5 seconds
 		sl.logger.Error("[DB] SpendLog entry dropped: queue full timeout",
 			"request_id", entry.RequestID,
 			"queue_len", len(sl.queue),
@@ -282,6 +304,13 @@ func (sl *Logger) worker() {
 	defer ticker.Stop()
 
 	for {
+		// Read-only mode: leave entries in the queue (a nil channel never receives) and
+		// hold the current batch until the mode is switched off
+		queue := sl.queue
+		if sl.readOnly.Load() {
+			queue = nil
+		}
+
 		select {
 		case <-sl.stopChan:
 			// Shutdown: write remaining entries
@@ -291,17 +320,19 @@ func (sl *Logger) worker() {
 			}
 			return
 
-		case entry := <-sl.queue:
+		case entry := <-queue:
 			batch = append(batch, entry)
 			// Check batch size
-			if len(batch) >= sl.config.LogBatchSize {
+			if len(batch) >= sl.config.LogBatchSize && !sl.readOnly.Load() {
+				// Under backlog grow the batch so fewer, larger COPYs keep up with the queue
+				sl.fillBatch(&batch, sl.adaptiveBatchSize(len(sl.queue)))
 				sl.flushBatch(batch)
 				batch = batch[:0] // Reset slice, keep capacity
 			}
 
 		case <-ticker.C:
 			// Timer: write accumulated entries
-			if len(batch) > 0 {
+			if len(batch) > 0 && !sl.readOnly.Load() {
 				sl.flushBatch(batch)
 				batch = batch[:0]
 			}
@@ -309,6 +340,42 @@ func (sl *Logger) worker() {
 	}
 }
 
+// maxAdaptiveBatchSize caps adaptive batches so the INSERT fallback stays below
+// PostgreSQL's 65535 bind parameter limit (25 parameters per row)
+const maxAdaptiveBatchSize = 2000
+
+// adaptiveBatchSize returns the flush batch size for the current queue depth:
+// log_batch_size when the queue keeps up, growing with the backlog up to
+// 10x log_batch_size (bounded by maxAdaptiveBatchSize)
+func (sl *Logger) adaptiveBatchSize(queueLen int) int {
+	base := sl.config.LogBatchSize
+	limit := base * 10
+	if limit > maxAdaptiveBatchSize {
+		limit = maxAdaptiveBatchSize
+	}
+	if limit < base {
+		limit = base
+	}
+
+	size := base + queueLen
+	if size > limit {
+		size = limit
+	}
+	return size
+}
+
+// fillBatch appends queued entries to batch without blocking until it reaches size
+func (sl *Logger) fillBatch(batch *[]*models.SpendLogEntry, size int) {
+	for len(*batch) < size {
+		select {
+		case entry := <-sl.queue:
+			*batch = append(*batch, entry)
+		default:
+			return
+		}
+	}
+}
+
 // drainQueue reads all remaining entries from the queue
 func (sl *Logger) drainQueue(batch *[]*models.SpendLogEntry) {
 	for {
@@ -391,7 +458,7 @@ func (sl *Logger) flushBatch(batch []*models.SpendLogEntry) {
 // 1. INSERT batch into SpendLogs
 // 2. Aggregate and UPDATE spend for Token, User, Team, Org, TeamMember, OrgMember
 // All operations executed in a single transaction for atomicity
-func (sl *Logger) flushBatchWithSpendUpdate(batch []*models.SpendLogEntry) error {
+func (sl *Logger) flushBatchWithSpendUpdate(batch []*models.SpendLogEntry) (err error) {
 	// Skip if pool is not initialized (e.g., in tests)
 	if sl.pool == nil {
 		return models.ErrConnectionFailed
@@ -401,6 +468,16 @@ func (sl *Logger) flushBatchWithSpendUpdate(batch []*models.SpendLogEntry) error
 		return models.ErrConnectionFailed
 	}
 
+	start := time.Now()
+	method := insertMethodCopy
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		monitoring.LiteLLMDBSpendFlushDuration.WithLabelValues(method, status).Observe(time.Since(start).Seconds())
+	}()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -421,26 +498,11 @@ func (sl *Logger) flushBatchWithSpendUpdate(batch []*models.SpendLogEntry) error
 		_ = tx.Rollback(ctx)
 	}()
 
-	// 1. INSERT batch into SpendLogs with RETURNING request_id
-	query := queries.BuildBatchInsertQuery(len(batch))
-	params := GetBatchParams(batch)
-	insertRows, err := tx.Query(ctx, query, params...)
-	if err != nil {
-		return fmt.Errorf("batch insert: %w", err)
-	}
-
+	// 1. Write batch into SpendLogs (COPY, falling back to INSERT ... ON CONFLICT)
 	var insertedIDs []string
-	for insertRows.Next() {
-		var id string
-		if err := insertRows.Scan(&id); err != nil {
-			insertRows.Close()
-			return fmt.Errorf("scan returning request_id: %w", err)
-		}
-		insertedIDs = append(insertedIDs, id)
-	}
-	insertRows.Close()
-	if err := insertRows.Err(); err != nil {
-		return fmt.Errorf("iterate returning rows: %w", err)
+	insertedIDs, method, err = sl.insertBatch(ctx, tx, batch)
+	if err != nil {
+		return err
 	}
 
 	// 2. Aggregate and UPDATE spend (only for actually inserted entries)
@@ -472,6 +534,84 @@ func (sl *Logger) flushBatchWithSpendUpdate(batch []*models.SpendLogEntry) error
 	return nil
 }
 
+// Spend log insert methods (flush duration metric label)
+const (
+	insertMethodCopy   = "copy"
+	insertMethodInsert = "insert"
+)
+
+// insertBatch writes batch into LiteLLM_SpendLogs within tx and returns the request IDs
+// actually inserted together with the method used.
+//
+// Fast path: COPY (pgx CopyFrom) inside a savepoint. COPY cannot skip duplicates, so if it
+// fails (e.g. request_id already written by a DLQ retry of a partially committed batch)
+// the savepoint is rolled back and the batch is written with INSERT ... ON CONFLICT DO NOTHING.
+func (sl *Logger) insertBatch(ctx context.Context, tx pgx.Tx, batch []*models.SpendLogEntry) ([]string, string, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, insertMethodCopy, fmt.Errorf("begin savepoint: %w", err)
+	}
+	_, copyErr := savepoint.CopyFrom(ctx,
+		pgx.Identifier{queries.SpendLogsTable},
+		queries.SpendLogCopyColumns,
+		pgx.CopyFromRows(GetCopyRows(batch)),
+	)
+	if copyErr == nil {
+		if copyErr = savepoint.Commit(ctx); copyErr == nil {
+			return getRequestIDs(batch), insertMethodCopy, nil
+		}
+	}
+	if err := savepoint.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return nil, insertMethodCopy, fmt.Errorf("rollback savepoint: %w", err)
+	}
+
+	monitoring.LiteLLMDBSpendCopyFallbacks.Inc()
+	if isUniqueViolation(copyErr) {
+		sl.logger.Debug("[DB] SpendLog COPY hit duplicate request_id, falling back to INSERT",
+			"batch_size", len(batch),
+		)
+	} else {
+		sl.logger.Warn("[DB] SpendLog COPY failed, falling back to INSERT",
+			"batch_size", len(batch),
+			"error", copyErr,
+		)
+	}
+
+	ids, err := insertBatchReturning(ctx, tx, batch)
+	return ids, insertMethodInsert, err
+}
+
+// insertBatchReturning executes batch INSERT ... ON CONFLICT DO NOTHING RETURNING request_id
+func insertBatchReturning(ctx context.Context, tx pgx.Tx, batch []*models.SpendLogEntry) ([]string, error) {
+	query := queries.BuildBatchInsertQuery(len(batch))
+	params := GetBatchParams(batch)
+	insertRows, err := tx.Query(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("batch insert: %w", err)
+	}
+
+	var insertedIDs []string
+	for insertRows.Next() {
+		var id string
+		if err := insertRows.Scan(&id); err != nil {
+			insertRows.Close()
+			return nil, fmt.Errorf("scan returning request_id: %w", err)
+		}
+		insertedIDs = append(insertedIDs, id)
+	}
+	insertRows.Close()
+	if err := insertRows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate returning rows: %w", err)
+	}
+	return insertedIDs, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation (23505)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 // addToDLQ adds a failed batch to the dead letter queue
 // Max queue size: 10 failed batches (~100KB max memory)
 // If DLQ is full, drops the oldest batch and logs error
@@ -556,6 +696,11 @@ func (sl *Logger) flushDLQ() {
 		sl.dlqMu.Unlock()
 		return
 	}
+	if sl.readOnly.Load() {
+		sl.dlqMu.Unlock()
+		sl.logger.Debug("[DB] Read-only mode, SpendLog DLQ recovery skipped", "dlq_size", sl.getDLQSize())
+		return
+	}
 
 	// Alert if DLQ is growing too large
 	if len(sl.dlq) >= 5 {
@@ -722,6 +867,10 @@ func (sl *Logger) aggregateSpendLogs() {
 	if sl.pool == nil {
 		return
 	}
+	if sl.readOnly.Load() {
+		sl.logger.Debug("[DB] Read-only mode, spend aggregation skipped")
+		return
+	}
 
 	if !sl.pool.IsHealthy() {
 		atomic.AddUint64(&sl.aggregationErrors, 1)
@@ -10,6 +10,7 @@ import (
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
 	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockConnectionPool is a mock for testing without real database
@@ -230,6 +231,50 @@ func TestLogger_DLQCleanup(t *testing.T) {
 	_ = logger.Shutdown(context.Background())
 }
 
+func TestLogger_ReadOnlySkipsDLQRecovery(t *testing.T) {
+	cfg := &models.Config{
+		LogQueueSize:     10,
+		LogBatchSize:     5,
+		LogFlushInterval: 1 * time.Second,
+		Logger:           testhelpers.NewTestLogger(),
+	}
+
+	logger := NewLogger(nil, cfg)
+	logger.addToDLQ([]*models.SpendLogEntry{{RequestID: "test-1"}}, nil, 4)
+
+	logger.SetReadOnly(true)
+	logger.flushDLQ()
+	assert.Equal(t, 1, logger.getDLQSize())
+	assert.True(t, logger.lastDLQRecoveryTime.IsZero(), "no recovery attempt while read-only")
+
+	logger.SetReadOnly(false)
+	logger.flushDLQ()
+	assert.Equal(t, 1, logger.getDLQSize(), "the batch is re-added after the failed retry")
+	assert.False(t, logger.lastDLQRecoveryTime.IsZero())
+}
+
+func TestLogger_ReadOnlyHoldsQueuedEntries(t *testing.T) {
+	cfg := &models.Config{
+		LogQueueSize:     10,
+		LogBatchSize:     1,
+		LogFlushInterval: 10 * time.Millisecond,
+		Logger:           testhelpers.NewTestLogger(),
+	}
+
+	logger := NewLogger(nil, cfg)
+	logger.SetReadOnly(true)
+	logger.Start()
+	defer func() { _ = logger.Shutdown(context.Background()) }()
+
+	require.NoError(t, logger.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-1"}))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, logger.queue, 1, "held in the queue while read-only")
+
+	logger.SetReadOnly(false)
+	assert.Eventually(t, func() bool { return len(logger.queue) == 0 }, time.Second, 10*time.Millisecond,
+		"flushed once read-only mode is switched off")
+}
+
 func TestLogger_ConcurrentLogAndShutdown(t *testing.T) {
 	cfg := &models.Config{
 		LogQueueSize:     50,
@@ -254,7 +299,7 @@ func TestLogger_ConcurrentLogAndShutdown(t *testing.T) {
 			case <-stopChan:
 				return
 			default:
-				_ = logger.Log(&models.SpendLogEntry{
+				_ = logger.Log(context.Background(), &models.SpendLogEntry{
 					RequestID: "test-" + time.Now().Format("20060102150405"),
 				})
 				time.Sleep(10 * time.Millisecond)
@@ -307,8 +352,8 @@ func TestLogger_QueueFullError(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	// Fill queue
-	assert.Nil(t, logger.Log(&models.SpendLogEntry{RequestID: "1"}))
-	assert.Nil(t, logger.Log(&models.SpendLogEntry{RequestID: "2"}))
+	assert.Nil(t, logger.Log(context.Background(), &models.SpendLogEntry{RequestID: "1"}))
+	assert.Nil(t, logger.Log(context.Background(), &models.SpendLogEntry{RequestID: "2"}))
 
 	// Next log should timeout and return error
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
@@ -317,7 +362,7 @@ func TestLogger_QueueFullError(t *testing.T) {
 	// Create a blocking send to fill queue
 	go func() {
 		for i := 0; i < 10; i++ {
-			_ = logger.Log(&models.SpendLogEntry{RequestID: "block"})
+			_ = logger.Log(context.Background(), &models.SpendLogEntry{RequestID: "block"})
 		}
 	}()
 
@@ -347,7 +392,7 @@ func TestLogger_StatsMetricsIncrement(t *testing.T) {
 
 	// Log some entries
 	for i := 0; i < 3; i++ {
-		_ = logger.Log(&models.SpendLogEntry{RequestID: "test-entry-" + fmt.Sprint(i)})
+		_ = logger.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-entry-" + fmt.Sprint(i)})
 	}
 
 	stats := logger.Stats()
@@ -369,7 +414,7 @@ func TestLogger_DrainQueueOnShutdown(t *testing.T) {
 
 	// Add entries to the queue
 	for i := 0; i < 5; i++ {
-		_ = logger.Log(&models.SpendLogEntry{RequestID: "drain-test-" + fmt.Sprint(i)})
+		_ = logger.Log(context.Background(), &models.SpendLogEntry{RequestID: "drain-test-" + fmt.Sprint(i)})
 	}
 
 	// Queue should have entries
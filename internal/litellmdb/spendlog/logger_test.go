@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb/queries"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLogger_Log_NonBlocking(t *testing.T) {
@@ -39,7 +41,7 @@ func TestLogger_Log_NonBlocking(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		_ = sl.Log(entry)
+		_ = sl.Log(context.Background(), entry)
 		close(done)
 	}()
 
@@ -78,13 +80,13 @@ func TestLogger_Log_QueueFull(t *testing.T) {
 	_ = cancel // Unused
 
 	// Fill queue
-	_ = sl.Log(&models.SpendLogEntry{RequestID: "test-1"})
-	_ = sl.Log(&models.SpendLogEntry{RequestID: "test-2"})
+	_ = sl.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-1"})
+	_ = sl.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-2"})
 
 	// This will block for 5 seconds and then return ErrQueueFull
 	// since there's no worker consuming entries
 	start := time.Now()
-	err := sl.Log(&models.SpendLogEntry{RequestID: "test-3"})
+	err := sl.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-3"})
 	elapsed := time.Since(start)
 
 	// Should timeout after approximately 5 seconds
@@ -98,6 +100,34 @@ func TestLogger_Log_QueueFull(t *testing.T) {
 	assert.Equal(t, uint64(1), stats.QueueFullCount)
 }
 
+func TestSpendLogger_LogQueueFullContextDone(t *testing.T) {
+	cfg := &models.Config{
+		DatabaseURL:      "postgresql://localhost/test",
+		LogQueueSize:     1,
+		LogBatchSize:     10,
+		LogFlushInterval: time.Hour,
+	}
+	cfg.ApplyDefaults()
+
+	sl := &Logger{
+		config:   cfg,
+		logger:   cfg.Logger,
+		queue:    make(chan *models.SpendLogEntry, cfg.LogQueueSize),
+		stopChan: make(chan struct{}),
+	}
+	require.NoError(t, sl.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-1"}))
+
+	// The caller's deadline ends the wait for queue space before the 5 second timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := sl.Log(ctx, &models.SpendLogEntry{RequestID: "test-2"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, uint64(1), sl.Stats().Dropped)
+}
+
 func TestLogger_Log_NilEntry(t *testing.T) {
 	cfg := &models.Config{
 		DatabaseURL:  "postgresql://localhost/test",
@@ -119,7 +149,7 @@ func TestLogger_Log_NilEntry(t *testing.T) {
 	_ = cancel // Unused
 
 	// Should not panic or queue
-	_ = sl.Log(nil)
+	_ = sl.Log(context.Background(), nil)
 
 	stats := sl.Stats()
 	assert.Equal(t, uint64(0), stats.Queued)
@@ -145,8 +175,8 @@ func TestLogger_Stats(t *testing.T) {
 	_ = ctx    // Unused
 	_ = cancel // Unused
 
-	_ = sl.Log(&models.SpendLogEntry{RequestID: "test-1"})
-	_ = sl.Log(&models.SpendLogEntry{RequestID: "test-2"})
+	_ = sl.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-1"})
+	_ = sl.Log(context.Background(), &models.SpendLogEntry{RequestID: "test-2"})
 
 	stats := sl.Stats()
 	assert.Equal(t, 2, stats.QueueLen)
@@ -246,6 +276,67 @@ func TestGetBatchParams(t *testing.T) {
 	assert.Equal(t, "req-2", params[25]) // Second entry starts at position 25
 }
 
+func TestGetCopyRows(t *testing.T) {
+	entries := []*models.SpendLogEntry{
+		{RequestID: "req-1", Status: "success"},
+		{RequestID: "req-2", Status: "failure"},
+	}
+
+	rows := GetCopyRows(entries)
+
+	assert.Len(t, rows, 2)
+	for _, row := range rows {
+		assert.Len(t, row, len(queries.SpendLogCopyColumns))
+		assert.Nil(t, row[25]) // messages
+		assert.Nil(t, row[26]) // response
+	}
+	assert.Equal(t, "req-1", rows[0][0])
+	assert.Equal(t, "failure", rows[1][20])
+	assert.Equal(t, []string{"req-1", "req-2"}, getRequestIDs(entries))
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	assert.True(t, isUniqueViolation(&pgconn.PgError{Code: "23505"}))
+	assert.True(t, isUniqueViolation(fmt.Errorf("copy: %w", &pgconn.PgError{Code: "23505"})))
+	assert.False(t, isUniqueViolation(&pgconn.PgError{Code: "23502"}))
+	assert.False(t, isUniqueViolation(fmt.Errorf("timeout")))
+	assert.False(t, isUniqueViolation(nil))
+}
+
+func TestLogger_AdaptiveBatchSize(t *testing.T) {
+	sl := &Logger{config: &models.Config{LogBatchSize: 100}}
+
+	assert.Equal(t, 100, sl.adaptiveBatchSize(0))
+	assert.Equal(t, 350, sl.adaptiveBatchSize(250))
+	assert.Equal(t, 1000, sl.adaptiveBatchSize(5000)) // 10x log_batch_size
+
+	sl.config.LogBatchSize = 500
+	assert.Equal(t, maxAdaptiveBatchSize, sl.adaptiveBatchSize(10000))
+
+	sl.config.LogBatchSize = 3000 // above the cap: never shrink below log_batch_size
+	assert.Equal(t, 3000, sl.adaptiveBatchSize(10000))
+}
+
+func TestLogger_FillBatch(t *testing.T) {
+	sl := &Logger{
+		config: &models.Config{LogBatchSize: 2},
+		queue:  make(chan *models.SpendLogEntry, 10),
+	}
+	for i := 0; i < 5; i++ {
+		sl.queue <- &models.SpendLogEntry{RequestID: fmt.Sprintf("req-%d", i)}
+	}
+
+	batch := []*models.SpendLogEntry{{RequestID: "first"}}
+	sl.fillBatch(&batch, 4)
+	assert.Len(t, batch, 4)
+	assert.Len(t, sl.queue, 2)
+
+	// Does not block on an empty queue
+	sl.fillBatch(&batch, 10)
+	assert.Len(t, batch, 6)
+	assert.Empty(t, sl.queue)
+}
+
 // TestLogger_SQLInjectionPrevention validates that SQL injection attacks are prevented
 // through pgx parameterized queries.
 //
@@ -182,6 +182,11 @@ func (sl *Logger) aggregateByIDs(ids []string) {
 	if len(ids) == 0 {
 		return
 	}
+	if sl.readOnly.Load() {
+		// Left unprocessed for the safety-net
+		sl.logger.Debug("[DB] Read-only mode, spend aggregation skipped", "ids_count", len(ids))
+		return
+	}
 
 	aggCtx, aggCancel := context.WithTimeout(context.Background(), 3*time.Minute)
 	defer aggCancel()
@@ -35,9 +35,10 @@ type LoginResult struct {
 const SessionJWTDuration = 24 * time.Hour
 
 // AuthenticateUser validates credentials against admin config and DB users.
-// Admin path: compares with UI_USERNAME/UI_PASSWORD env vars.
+// Admin path: compares with UI_USERNAME/UI_PASSWORD env vars. Without UI_PASSWORD the
+// password is the master key, or any key isMasterKey accepts (nil = masterKey only).
 // DB user path: looks up user by email in LiteLLM_UserTable.
-func AuthenticateUser(ctx context.Context, req LoginRequest, masterKey string, pool *pgxpool.Pool) (*LoginResult, error) {
+func AuthenticateUser(ctx context.Context, req LoginRequest, masterKey string, isMasterKey func(string) bool, pool *pgxpool.Pool) (*LoginResult, error) {
 	if req.Username == "" || req.Password == "" {
 		return nil, ErrInvalidCredentials
 	}
@@ -48,11 +49,12 @@ func AuthenticateUser(ctx context.Context, req LoginRequest, masterKey string, p
 		uiUsername = "admin"
 	}
 	uiPassword := os.Getenv("UI_PASSWORD")
+	passwordOK := uiPassword != "" && constantTimeEqual(req.Password, uiPassword)
 	if uiPassword == "" {
-		uiPassword = masterKey
+		passwordOK = constantTimeEqual(req.Password, masterKey) || (isMasterKey != nil && isMasterKey(req.Password))
 	}
 
-	if constantTimeEqual(req.Username, uiUsername) && constantTimeEqual(req.Password, uiPassword) {
+	if constantTimeEqual(req.Username, uiUsername) && passwordOK {
 		return &LoginResult{
 			UserID:    uiUsername,
 			Key:       masterKey,
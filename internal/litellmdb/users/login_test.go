@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckPassword(t *testing.T) {
@@ -85,7 +86,7 @@ func TestAuthenticateUser_AdminPath(t *testing.T) {
 			Username: "testadmin",
 			Password: "testpass123",
 		}
-		result, err := AuthenticateUser(context.TODO(), req, masterKey, nil)
+		result, err := AuthenticateUser(context.TODO(), req, masterKey, nil, nil)
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, "testadmin", result.UserID)
@@ -99,7 +100,7 @@ func TestAuthenticateUser_AdminPath(t *testing.T) {
 			Password: "wrongpass",
 		}
 		// Without a DB pool, it should fail (no DB path, wrong admin creds)
-		result, err := AuthenticateUser(context.TODO(), req, masterKey, nil)
+		result, err := AuthenticateUser(context.TODO(), req, masterKey, nil, nil)
 		assert.Error(t, err)
 		assert.Nil(t, result)
 	})
@@ -109,12 +110,31 @@ func TestAuthenticateUser_AdminPath(t *testing.T) {
 			Username: "",
 			Password: "",
 		}
-		result, err := AuthenticateUser(context.TODO(), req, masterKey, nil)
+		result, err := AuthenticateUser(context.TODO(), req, masterKey, nil, nil)
 		assert.ErrorIs(t, err, ErrInvalidCredentials)
 		assert.Nil(t, result)
 	})
 }
 
+func TestAuthenticateUser_MasterKeyPassword(t *testing.T) {
+	t.Setenv("UI_USERNAME", "")
+	t.Setenv("UI_PASSWORD", "")
+
+	masterKey := "sk-master-key"
+	isMasterKey := func(password string) bool { return password == masterKey || password == "sk-rotated-key" }
+
+	for _, password := range []string{masterKey, "sk-rotated-key"} {
+		result, err := AuthenticateUser(context.TODO(), LoginRequest{Username: "admin", Password: password}, masterKey, isMasterKey, nil)
+		require.NoError(t, err, password)
+		assert.Equal(t, masterKey, result.Key, "sessions use the primary master key")
+	}
+
+	_, err := AuthenticateUser(context.TODO(), LoginRequest{Username: "admin", Password: "sk-rotated-key"}, masterKey, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = AuthenticateUser(context.TODO(), LoginRequest{Username: "admin", Password: "sk-other"}, masterKey, isMasterKey, nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
 // sha256Hex computes SHA256 hex hash of a string.
 func sha256Hex(s string) string {
 	h := sha256.Sum256([]byte(s))
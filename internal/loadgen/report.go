@@ -0,0 +1,180 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report aggregates results per credential and model.
+//
+// Thread-safe via internal mutex.
+type Report struct {
+	Elapsed time.Duration
+
+	mu      sync.Mutex
+	stats   map[statsKey]*Stats
+	skipped int
+}
+
+type statsKey struct {
+	credential string
+	model      string
+}
+
+// Stats summarizes the requests sent to one credential and model
+type Stats struct {
+	Credential string         `json:"credential"`
+	Model      string         `json:"model"`
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	Statuses   map[string]int `json:"statuses"`
+	P50        time.Duration  `json:"p50_ns"`
+	P90        time.Duration  `json:"p90_ns"`
+	P99        time.Duration  `json:"p99_ns"`
+	Max        time.Duration  `json:"max_ns"`
+
+	latencies []time.Duration
+}
+
+// NewReport creates an empty Report
+func NewReport() *Report {
+	return &Report{stats: make(map[statsKey]*Stats)}
+}
+
+// Add records a result. Transport errors and non-2xx responses count as errors.
+func (r *Report) Add(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := statsKey{credential: result.Credential, model: result.Model}
+	stats, ok := r.stats[key]
+	if !ok {
+		stats = &Stats{Credential: result.Credential, Model: result.Model, Statuses: make(map[string]int)}
+		r.stats[key] = stats
+	}
+
+	stats.Requests++
+	stats.latencies = append(stats.latencies, result.Latency)
+	status := strconv.Itoa(result.StatusCode)
+	if result.Err != nil || result.StatusCode == 0 {
+		status = "error"
+	}
+	stats.Statuses[status]++
+	if result.Err != nil || result.StatusCode < 200 || result.StatusCode >= 300 {
+		stats.Errors++
+	}
+}
+
+// AddSkipped records a tick skipped because Concurrency requests were already in flight
+func (r *Report) AddSkipped() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+// Skipped returns the number of skipped ticks
+func (r *Report) Skipped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.skipped
+}
+
+// Stats returns per credential and model statistics, sorted by credential then model
+func (r *Report) Stats() []Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]Stats, 0, len(r.stats))
+	for _, stats := range r.stats {
+		s := *stats
+		latencies := append([]time.Duration(nil), stats.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		s.P50 = percentile(latencies, 0.50)
+		s.P90 = percentile(latencies, 0.90)
+		s.P99 = percentile(latencies, 0.99)
+		s.Max = percentile(latencies, 1)
+		s.latencies = nil
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Credential != result[j].Credential {
+			return result[i].Credential < result[j].Credential
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p*float64(len(sorted))+0.5) - 1
+	idx = max(0, min(idx, len(sorted)-1))
+	return sorted[idx]
+}
+
+// WriteText writes a human-readable table
+func (r *Report) WriteText(w io.Writer) error {
+	stats := r.Stats()
+
+	total, errors := 0, 0
+	for _, s := range stats {
+		total += s.Requests
+		errors += s.Errors
+	}
+	rps := 0.0
+	if r.Elapsed > 0 {
+		rps = float64(total) / r.Elapsed.Seconds()
+	}
+	if _, err := fmt.Fprintf(w, "requests: %d  errors: %d  skipped: %d  elapsed: %s  achieved rps: %.2f\n\n",
+		total, errors, r.Skipped(), r.Elapsed.Round(time.Millisecond), rps); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CREDENTIAL\tMODEL\tREQUESTS\tERRORS\tP50\tP90\tP99\tMAX\tSTATUSES")
+	for _, s := range stats {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			s.Credential, s.Model, s.Requests, s.Errors,
+			s.P50.Round(time.Millisecond), s.P90.Round(time.Millisecond),
+			s.P99.Round(time.Millisecond), s.Max.Round(time.Millisecond),
+			formatStatuses(s.Statuses))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		ElapsedNS time.Duration `json:"elapsed_ns"`
+		Skipped   int           `json:"skipped"`
+		Stats     []Stats       `json:"stats"`
+	}{r.Elapsed, r.Skipped(), r.Stats()})
+}
+
+func formatStatuses(statuses map[string]int) string {
+	keys := make([]string, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Strings(keys)
+
+	result := ""
+	for i, status := range keys {
+		if i > 0 {
+			result += " "
+		}
+		result += fmt.Sprintf("%s=%d", status, statuses[status])
+	}
+	return result
+}
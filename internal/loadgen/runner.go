@@ -0,0 +1,154 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// credentialHeader is set by the router for master-key requests (proxy.CredentialHeader)
+const credentialHeader = "X-Router-Credential"
+
+// Config configures a Runner
+type Config struct {
+	URL          string        // Router base URL
+	APIKey       string        // Bearer token (master key for per-credential reports)
+	RouterSecret string        // Optional: sign requests as a parent router (server.inter_router_secret)
+	RPS          float64       // Target requests per second
+	Duration     time.Duration // Stop after this long (0 = until the source is exhausted)
+	MaxRequests  int           // Stop after sending this many requests (0 = no limit)
+	Concurrency  int           // Maximum in-flight requests; ticks beyond it are skipped
+	Timeout      time.Duration // Per-request timeout
+}
+
+// Result is the outcome of a single request
+type Result struct {
+	Credential string
+	Model      string
+	StatusCode int // 0 on transport errors
+	Err        error
+	Latency    time.Duration
+}
+
+// Runner sends requests from a Source at a fixed rate
+type Runner struct {
+	cfg    Config
+	client *http.Client
+	source Source
+}
+
+// NewRunner creates a Runner
+func NewRunner(cfg Config, source Source) *Runner {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return &Runner{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		source: source,
+	}
+}
+
+// Run sends requests until the duration elapses, MaxRequests is reached, the source
+// is exhausted or ctx is cancelled, then waits for in-flight requests to finish
+func (r *Runner) Run(ctx context.Context) *Report {
+	if r.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Duration)
+		defer cancel()
+	}
+
+	interval := time.Second
+	if r.cfg.RPS > 0 {
+		interval = time.Duration(float64(time.Second) / r.cfg.RPS)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	report := NewReport()
+	slots := make(chan struct{}, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := utils.NowUTC()
+
+loop:
+	for sent := 0; r.cfg.MaxRequests <= 0 || sent < r.cfg.MaxRequests; {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			report.AddSkipped()
+			continue
+		}
+
+		req, ok := r.source.Next()
+		if !ok {
+			<-slots
+			break loop
+		}
+		sent++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			// In-flight requests are not cut off when the run ends
+			report.Add(r.send(context.WithoutCancel(ctx), req))
+		}()
+	}
+
+	wg.Wait()
+	report.Elapsed = utils.NowUTC().Sub(start)
+	return report
+}
+
+func (r *Runner) send(ctx context.Context, req Request) Result {
+	result := Result{Model: req.Model, Credential: "unknown"}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, strings.TrimSuffix(r.cfg.URL, "/")+req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if r.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+r.cfg.APIKey)
+	}
+	if r.cfg.RouterSecret != "" {
+		if err := httputil.SignRequest(httpReq, r.cfg.RouterSecret, req.Body, utils.NowUTC()); err != nil {
+			result.Err = fmt.Errorf("failed to sign request: %w", err)
+			return result
+		}
+	}
+
+	start := utils.NowUTC()
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		result.Err = err
+		result.Latency = utils.NowUTC().Sub(start)
+		return result
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Latency covers the full response, including streamed chunks
+	_, err = io.Copy(io.Discard, resp.Body)
+	result.Latency = utils.NowUTC().Sub(start)
+	result.StatusCode = resp.StatusCode
+	result.Err = err
+	if credential := resp.Header.Get(credentialHeader); credential != "" {
+		result.Credential = credential
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Run(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		assert.Equal(t, "Bearer master-key", r.Header.Get("Authorization"))
+		if n%2 == 0 {
+			w.Header().Set(credentialHeader, "oai2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set(credentialHeader, "oai1")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	source := NewReplaySource([]Request{{Method: "POST", Path: "/v1/chat/completions", Body: []byte(`{}`), Model: "gpt-4o"}}, true)
+	runner := NewRunner(Config{URL: server.URL, APIKey: "master-key", RPS: 200, MaxRequests: 4, Concurrency: 4, Timeout: 5 * time.Second}, source)
+
+	report := runner.Run(context.Background())
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	stats := report.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, "oai1", stats[0].Credential)
+	assert.Equal(t, 2, stats[0].Requests)
+	assert.Equal(t, 0, stats[0].Errors)
+	assert.Equal(t, "oai2", stats[1].Credential)
+	assert.Equal(t, 2, stats[1].Errors)
+	assert.Equal(t, map[string]int{"429": 2}, stats[1].Statuses)
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.Contains(t, out.String(), "requests: 4  errors: 2")
+	assert.Contains(t, out.String(), "429=2")
+}
+
+func TestRunner_SignsRequests(t *testing.T) {
+	verifier := httputil.NewRequestVerifier("0123456789abcdef0123456789abcdef", httputil.DefaultRouterSignatureMaxAge)
+	var verified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if verifier.Verify(r, []byte(`{"model":"gpt-4o"}`), time.Now()) == nil {
+			atomic.AddInt32(&verified, 1)
+		}
+	}))
+	defer server.Close()
+
+	source := NewReplaySource([]Request{{Method: "POST", Path: "/v1/chat/completions", Body: []byte(`{"model":"gpt-4o"}`), Model: "gpt-4o"}}, false)
+	runner := NewRunner(Config{URL: server.URL, RouterSecret: "0123456789abcdef0123456789abcdef", RPS: 100, Concurrency: 1, Timeout: 5 * time.Second}, source)
+
+	report := runner.Run(context.Background())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&verified))
+	require.Len(t, report.Stats(), 1)
+	assert.Equal(t, "unknown", report.Stats()[0].Credential)
+}
+
+func TestRunner_StopsAtDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	source := &SyntheticSource{Models: []string{"gpt-4o"}}
+	runner := NewRunner(Config{URL: server.URL, APIKey: "k", RPS: 50, Duration: 100 * time.Millisecond, Concurrency: 8, Timeout: time.Second}, source)
+
+	report := runner.Run(context.Background())
+	requests := 0
+	for _, s := range report.Stats() {
+		requests += s.Requests
+	}
+	assert.Greater(t, requests, 0)
+	assert.Less(t, requests, 10)
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.Equal(t, time.Duration(5), percentile(latencies, 0.5))
+	assert.Equal(t, time.Duration(9), percentile(latencies, 0.9))
+	assert.Equal(t, time.Duration(10), percentile(latencies, 1))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.5))
+}
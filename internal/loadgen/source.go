@@ -0,0 +1,172 @@
+package loadgen
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultPath = "/v1/chat/completions"
+
+// maxReplayLineSize bounds a single captured request (request bodies may embed images)
+const maxReplayLineSize = 64 * 1024 * 1024
+
+// Request is a single request sent to the router
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+	Model  string // Model from the body, used for per-model reporting
+}
+
+// Source yields the requests to send. Next is called from a single goroutine.
+type Source interface {
+	// Next returns the next request, or false when the source is exhausted
+	Next() (Request, bool)
+}
+
+// replayEntry covers the supported capture formats:
+//   - router error log entries (monitoring.errors_log_path): {"path", "method", "request": {"body": "..."}}
+//   - captured requests: {"path", "method", "body": {...} or "..."}
+//   - bare OpenAI request bodies: {"model": ..., "messages": [...]}
+type replayEntry struct {
+	Path    string          `json:"path"`
+	Method  string          `json:"method"`
+	Body    json.RawMessage `json:"body"`
+	Request *struct {
+		Body string `json:"body"`
+	} `json:"request"`
+}
+
+// LoadReplay parses captured traffic, one JSON object per line.
+// Blank lines and entries without a request body (e.g. GET requests) are skipped.
+func LoadReplay(r io.Reader) ([]Request, error) {
+	var requests []Request
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		var entry replayEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		req := Request{Method: entry.Method, Path: entry.Path}
+		switch {
+		case entry.Request != nil:
+			req.Body = []byte(entry.Request.Body)
+		case len(entry.Body) > 0:
+			var body string
+			if err := json.Unmarshal(entry.Body, &body); err == nil {
+				req.Body = []byte(body)
+			} else {
+				req.Body = entry.Body
+			}
+		case entry.Path == "":
+			req.Body = []byte(data)
+		}
+		if len(req.Body) == 0 {
+			continue
+		}
+
+		if req.Method == "" {
+			req.Method = http.MethodPost
+		}
+		if req.Path == "" {
+			req.Path = defaultPath
+		}
+		req.Model = modelFromBody(req.Body)
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func modelFromBody(body []byte) string {
+	var data struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil || data.Model == "" {
+		return "unknown"
+	}
+	return data.Model
+}
+
+// ReplaySource replays captured requests in order
+type ReplaySource struct {
+	requests []Request
+	loop     bool
+	next     int
+}
+
+// NewReplaySource creates a ReplaySource; with loop the requests are replayed indefinitely
+func NewReplaySource(requests []Request, loop bool) *ReplaySource {
+	return &ReplaySource{requests: requests, loop: loop}
+}
+
+func (s *ReplaySource) Next() (Request, bool) {
+	if len(s.requests) == 0 || (!s.loop && s.next >= len(s.requests)) {
+		return Request{}, false
+	}
+	req := s.requests[s.next%len(s.requests)]
+	s.next++
+	return req, true
+}
+
+// SyntheticSource generates chat completion requests, cycling through Models
+type SyntheticSource struct {
+	Models       []string
+	PromptTokens int // Approximate prompt size
+	MaxTokens    int // max_tokens for the completion (0 = provider default)
+	Stream       bool
+	next         int
+}
+
+func (s *SyntheticSource) Next() (Request, bool) {
+	if len(s.Models) == 0 {
+		return Request{}, false
+	}
+	model := s.Models[s.next%len(s.Models)]
+	s.next++
+
+	body := map[string]any{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "user", "content": syntheticPrompt(s.next, s.PromptTokens)},
+		},
+	}
+	if s.MaxTokens > 0 {
+		body["max_tokens"] = s.MaxTokens
+	}
+	if s.Stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]bool{"include_usage": true}
+	}
+
+	data, _ := json.Marshal(body)
+	return Request{Method: http.MethodPost, Path: defaultPath, Body: data, Model: model}, true
+}
+
+// syntheticPrompt builds a prompt of roughly tokens tokens; the request number keeps
+// prompts unique so provider-side prompt caches do not skew latencies
+func syntheticPrompt(n, tokens int) string {
+	const words = "the quick brown fox jumps over the lazy dog "
+	var b strings.Builder
+	fmt.Fprintf(&b, "Request %d. Reply with one short sentence about: ", n)
+	for i := 0; i < tokens/len(strings.Fields(words)); i++ {
+		b.WriteString(words)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,81 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReplay(t *testing.T) {
+	input := strings.Join([]string{
+		`{"timestamp":"2026-01-01T00:00:00Z","path":"/v1/chat/completions","method":"POST","status":500,"request":{"headers":{},"body":"{\"model\":\"gpt-4o\",\"messages\":[]}"},"response":{"headers":{},"body":""}}`,
+		``,
+		`{"path":"/v1/embeddings","body":{"model":"text-embedding-3-small","input":"hi"}}`,
+		`{"path":"/v1/chat/completions","body":"{\"model\":\"claude-sonnet-4-5\"}"}`,
+		`{"model":"gemini-2.5-flash","messages":[{"role":"user","content":"Hi"}]}`,
+		`{"path":"/v1/models","method":"GET","request":{"body":""}}`,
+	}, "\n")
+
+	requests, err := LoadReplay(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, requests, 4, "requests without a body are skipped")
+
+	assert.Equal(t, Request{Method: "POST", Path: "/v1/chat/completions", Body: []byte(`{"model":"gpt-4o","messages":[]}`), Model: "gpt-4o"}, requests[0])
+	assert.Equal(t, "/v1/embeddings", requests[1].Path)
+	assert.Equal(t, "text-embedding-3-small", requests[1].Model)
+	assert.Equal(t, `{"model":"claude-sonnet-4-5"}`, string(requests[2].Body))
+	assert.Equal(t, defaultPath, requests[3].Path)
+	assert.Equal(t, "gemini-2.5-flash", requests[3].Model)
+
+	_, err = LoadReplay(strings.NewReader("{\"model\":\"a\"}\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReplaySource(t *testing.T) {
+	requests := []Request{{Model: "a"}, {Model: "b"}}
+
+	source := NewReplaySource(requests, false)
+	var models []string
+	for req, ok := source.Next(); ok; req, ok = source.Next() {
+		models = append(models, req.Model)
+	}
+	assert.Equal(t, []string{"a", "b"}, models)
+
+	source = NewReplaySource(requests, true)
+	for i := 0; i < 5; i++ {
+		req, ok := source.Next()
+		require.True(t, ok)
+		assert.Equal(t, requests[i%2].Model, req.Model)
+	}
+}
+
+func TestSyntheticSource(t *testing.T) {
+	source := &SyntheticSource{Models: []string{"gpt-4o", "gemini-2.5-flash"}, PromptTokens: 50, MaxTokens: 16, Stream: true}
+
+	first, ok := source.Next()
+	require.True(t, ok)
+	second, _ := source.Next()
+	assert.Equal(t, "gpt-4o", first.Model)
+	assert.Equal(t, "gemini-2.5-flash", second.Model)
+
+	var body struct {
+		Model     string `json:"model"`
+		MaxTokens int    `json:"max_tokens"`
+		Stream    bool   `json:"stream"`
+		Messages  []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(first.Body, &body))
+	assert.Equal(t, 16, body.MaxTokens)
+	assert.True(t, body.Stream)
+	require.Len(t, body.Messages, 1)
+	assert.Greater(t, len(strings.Fields(body.Messages[0].Content)), 40)
+	assert.NotEqual(t, string(first.Body), string(second.Body))
+
+	_, ok = (&SyntheticSource{}).Next()
+	assert.False(t, ok)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -20,6 +21,7 @@ func New(level string) *slog.Logger {
 		opts: &slog.HandlerOptions{
 			Level: slogLevel,
 		},
+		out: os.Stdout,
 	}
 	return slog.New(handler)
 }
@@ -61,7 +63,10 @@ const (
 
 // PrettyHandler is a custom slog handler that formats logs nicely with colors
 type PrettyHandler struct {
-	opts *slog.HandlerOptions
+	opts   *slog.HandlerOptions
+	out    io.Writer
+	attrs  []slog.Attr // Attributes added by WithAttrs (keys already prefixed with their groups)
+	prefix string      // Groups opened by WithGroup, as "group." key prefix
 }
 
 // Handle implements the slog.Handler interface
@@ -81,25 +86,47 @@ func (h *PrettyHandler) Handle(ctx context.Context, record slog.Record) error {
 		record.Message,
 	))
 
-	// Add attributes
-	record.Attrs(func(attr slog.Attr) bool {
+	// Add attributes: logger attributes (e.g. request context) first, then the record's
+	for _, attr := range h.attrs {
 		sb.WriteString(fmt.Sprintf(" %s=%v", attr.Key, attr.Value.Any()))
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		sb.WriteString(fmt.Sprintf(" %s%s=%v", h.prefix, attr.Key, attr.Value.Any()))
 		return true
 	})
 
 	sb.WriteString("\n")
-	_, err := fmt.Fprint(os.Stdout, sb.String())
+	out := h.out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err := fmt.Fprint(out, sb.String())
 	return err
 }
 
 // WithAttrs returns a new handler with the given attributes attached
 func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return h
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	clone.attrs = append(clone.attrs, h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
 }
 
 // WithGroup returns a new handler with the given group name
 func (h *PrettyHandler) WithGroup(name string) slog.Handler {
-	return h
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
 }
 
 // Enabled reports whether the handler handles records at the given level
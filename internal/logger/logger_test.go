@@ -34,6 +34,20 @@ func TestNewJSON(t *testing.T) {
 	assert.NotNil(t, logger)
 }
 
+func TestPrettyHandler_WithAttrs(t *testing.T) {
+	var out strings.Builder
+	handler := &PrettyHandler{opts: &slog.HandlerOptions{Level: slog.LevelInfo}, out: &out}
+	base := slog.New(handler)
+
+	base.With("request_id", "req-1").WithGroup("upstream").With("credential", "openai").Info("sent", "status", 200)
+	base.Info("plain")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "» sent request_id=req-1 upstream.credential=openai upstream.status=200")
+	assert.NotContains(t, lines[1], "request_id", "attributes are not shared with the parent logger")
+}
+
 func TestTruncateLongFields_InvalidJSON(t *testing.T) {
 	body := "not valid json"
 	result := TruncateLongFields(body, 100)
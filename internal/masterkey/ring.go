@@ -0,0 +1,239 @@
+// Package masterkey holds the router master keys. Several keys can be valid at once, so a
+// master key can be rotated without downtime: adding a key keeps the previous keys valid
+// for a grace period.
+package masterkey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/security"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Key sources
+const (
+	SourceConfig = "config" // server.master_key or server.master_keys
+	SourceAdmin  = "admin"  // Added via the admin API (lost when the router restarts)
+)
+
+var (
+	ErrEmptyKey     = errors.New("key must not be empty")
+	ErrDuplicateKey = errors.New("key is already a master key")
+	ErrNotFound     = errors.New("master key not found")
+	ErrPrimaryKey   = errors.New("cannot revoke the primary master key: add a new key first")
+)
+
+// Key describes a master key without its secret
+type Key struct {
+	ID        string     `json:"id"`      // Fingerprint (sha256 prefix) naming the key in logs and metrics
+	Preview   string     `json:"preview"` // Masked key
+	Source    string     `json:"source"`  // config or admin
+	Primary   bool       `json:"primary"` // Newest key: signs session JWTs and router-internal requests
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // End of the rotation grace period (nil = no expiry)
+}
+
+type entry struct {
+	secret []byte
+	key    Key
+}
+
+// Ring is the set of valid master keys, newest first. A nil *Ring accepts no key.
+type Ring struct {
+	grace time.Duration
+	now   func() time.Time
+
+	mu   sync.RWMutex
+	keys []*entry
+}
+
+// NewRing creates a ring with the primary key and further accepted keys from the config.
+// grace is the default validity of the previous keys after a rotation.
+func NewRing(primary string, additional []string, grace time.Duration) *Ring {
+	r := &Ring{grace: grace, now: utils.NowUTC}
+	now := r.now()
+	for _, secret := range append([]string{primary}, additional...) {
+		if secret == "" || r.find(secret) != nil {
+			continue
+		}
+		r.keys = append(r.keys, newEntry(secret, SourceConfig, now))
+	}
+	return r
+}
+
+// Fingerprint returns the ID of a master key
+func Fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func newEntry(secret, source string, now time.Time) *entry {
+	return &entry{
+		secret: []byte(secret),
+		key: Key{
+			ID:        Fingerprint(secret),
+			Preview:   security.MaskAPIKey(secret),
+			Source:    source,
+			CreatedAt: now,
+		},
+	}
+}
+
+// Match returns the valid key equal to token. All keys are compared in constant time.
+func (r *Ring) Match(token string) (Key, bool) {
+	if r == nil || token == "" {
+		return Key{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	var (
+		match Key
+		found bool
+	)
+	for i, e := range r.keys {
+		if subtle.ConstantTimeCompare(e.secret, []byte(token)) == 1 && !e.expired(now) {
+			match, found = e.key, true
+			match.Primary = i == 0
+		}
+	}
+	return match, found
+}
+
+// Primary returns the newest valid key ("" for a nil ring)
+func (r *Ring) Primary() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.keys) == 0 {
+		return ""
+	}
+	return string(r.keys[0].secret)
+}
+
+// Secrets returns the valid keys, newest first (session JWTs signed with any of them are accepted)
+func (r *Ring) Secrets() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	secrets := make([]string, 0, len(r.keys))
+	for _, e := range r.keys {
+		if !e.expired(now) {
+			secrets = append(secrets, string(e.secret))
+		}
+	}
+	return secrets
+}
+
+// Add makes secret the primary key. The previous keys stay valid for grace (0 = the ring's
+// default) unless they expire earlier.
+func (r *Ring) Add(secret string, grace time.Duration) (Key, error) {
+	if r == nil {
+		return Key{}, errors.New("master key ring is not configured")
+	}
+	if secret == "" {
+		return Key{}, ErrEmptyKey
+	}
+	if grace <= 0 {
+		grace = r.grace
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.pruneLocked(now)
+	if r.find(secret) != nil {
+		return Key{}, ErrDuplicateKey
+	}
+
+	expiresAt := now.Add(grace)
+	for _, e := range r.keys {
+		if e.key.ExpiresAt == nil || e.key.ExpiresAt.After(expiresAt) {
+			e.key.ExpiresAt = &expiresAt
+		}
+	}
+	e := newEntry(secret, SourceAdmin, now)
+	r.keys = append([]*entry{e}, r.keys...)
+
+	key := e.key
+	key.Primary = true
+	return key, nil
+}
+
+// Revoke invalidates a previous key immediately. The primary key cannot be revoked, so the
+// ring always keeps a valid key.
+func (r *Ring) Revoke(id string) error {
+	if r == nil {
+		return ErrNotFound
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked(r.now())
+
+	for i, e := range r.keys {
+		if e.key.ID != id {
+			continue
+		}
+		if i == 0 {
+			return ErrPrimaryKey
+		}
+		r.keys = append(r.keys[:i], r.keys[i+1:]...)
+		return nil
+	}
+	return ErrNotFound
+}
+
+// List returns the valid keys, newest first
+func (r *Ring) List() []Key {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked(r.now())
+
+	keys := make([]Key, 0, len(r.keys))
+	for i, e := range r.keys {
+		key := e.key
+		key.Primary = i == 0
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// find returns the entry of secret, including expired ones; callers hold mu
+func (r *Ring) find(secret string) *entry {
+	for _, e := range r.keys {
+		if subtle.ConstantTimeCompare(e.secret, []byte(secret)) == 1 {
+			return e
+		}
+	}
+	return nil
+}
+
+// pruneLocked removes expired keys; the primary key has no expiry
+func (r *Ring) pruneLocked(now time.Time) {
+	kept := r.keys[:0]
+	for _, e := range r.keys {
+		if !e.expired(now) {
+			kept = append(kept, e)
+		}
+	}
+	r.keys = kept
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return e.key.ExpiresAt != nil && !e.key.ExpiresAt.After(now)
+}
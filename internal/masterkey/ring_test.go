@@ -0,0 +1,96 @@
+package masterkey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRing(now *time.Time, primary string, additional ...string) *Ring {
+	r := NewRing(primary, additional, time.Hour)
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestRing_ConfigKeys(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	r := newTestRing(&now, "sk-primary", "sk-previous", "sk-primary", "")
+
+	assert.Equal(t, "sk-primary", r.Primary())
+	assert.Equal(t, []string{"sk-primary", "sk-previous"}, r.Secrets())
+
+	key, ok := r.Match("sk-previous")
+	require.True(t, ok)
+	assert.Equal(t, Fingerprint("sk-previous"), key.ID)
+	assert.Equal(t, SourceConfig, key.Source)
+	assert.False(t, key.Primary)
+	assert.Nil(t, key.ExpiresAt, "config keys do not expire")
+
+	_, ok = r.Match("sk-unknown")
+	assert.False(t, ok)
+	_, ok = r.Match("")
+	assert.False(t, ok)
+}
+
+func TestRing_Rotation(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	r := newTestRing(&now, "sk-old")
+
+	key, err := r.Add("sk-new", 0)
+	require.NoError(t, err)
+	assert.True(t, key.Primary)
+	assert.Equal(t, SourceAdmin, key.Source)
+	assert.Equal(t, "sk-new", r.Primary())
+
+	old, ok := r.Match("sk-old")
+	require.True(t, ok, "the previous key stays valid during the grace period")
+	require.NotNil(t, old.ExpiresAt)
+	assert.Equal(t, now.Add(time.Hour), *old.ExpiresAt)
+
+	// A second rotation does not extend the grace period of older keys
+	_, err = r.Add("sk-newer", 2*time.Hour)
+	require.NoError(t, err)
+	old, _ = r.Match("sk-old")
+	assert.Equal(t, now.Add(time.Hour), *old.ExpiresAt)
+	assert.Len(t, r.List(), 3)
+
+	now = now.Add(90 * time.Minute)
+	_, ok = r.Match("sk-old")
+	assert.False(t, ok, "expired after the grace period")
+	_, ok = r.Match("sk-new")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"sk-newer", "sk-new"}, r.Secrets())
+
+	_, err = r.Add("sk-new", 0)
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+	_, err = r.Add("", 0)
+	assert.ErrorIs(t, err, ErrEmptyKey)
+}
+
+func TestRing_Revoke(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	r := newTestRing(&now, "sk-old")
+	_, err := r.Add("sk-new", 0)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, r.Revoke(Fingerprint("sk-new")), ErrPrimaryKey)
+	assert.ErrorIs(t, r.Revoke("unknown"), ErrNotFound)
+	require.NoError(t, r.Revoke(Fingerprint("sk-old")))
+	_, ok := r.Match("sk-old")
+	assert.False(t, ok)
+	assert.Len(t, r.List(), 1)
+}
+
+func TestRing_Nil(t *testing.T) {
+	var r *Ring
+	_, ok := r.Match("sk")
+	assert.False(t, ok)
+	assert.Empty(t, r.Primary())
+	assert.Nil(t, r.Secrets())
+	assert.Nil(t, r.List())
+	assert.ErrorIs(t, r.Revoke("id"), ErrNotFound)
+	_, err := r.Add("sk", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,334 @@
+// Package mockprovider serves canned OpenAI-format responses for mock credentials, so routing,
+// rate limiting and spend logging can be tested end to end without reaching a real provider.
+package mockprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// DefaultResponse is the chat completion text of mock credentials without mock.response
+const DefaultResponse = "This is a mock response from {{.Model}}."
+
+// mockImagePNG is a 1x1 transparent PNG returned for image generation requests
+const mockImagePNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
+
+// embeddingDimensions is the size of mock embedding vectors
+const embeddingDimensions = 8
+
+// Transport answers requests tagged with a mock credential (httputil.WithCredential) locally;
+// all other requests are passed to the wrapped RoundTripper
+type Transport struct {
+	next http.RoundTripper
+	seq  atomic.Int64
+}
+
+// NewTransport creates a mock provider Transport
+func NewTransport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next}
+}
+
+// requestBody is the subset of an OpenAI request the mock reads
+type requestBody struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	N        int    `json:"n"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Prompt string          `json:"prompt"`
+	Input  json.RawMessage `json:"input"`
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cred := httputil.CredentialFromContext(req.Context())
+	if cred == nil || cred.Type != config.ProviderTypeMock {
+		return t.next.RoundTrip(req)
+	}
+
+	var data []byte
+	if req.Body != nil {
+		var err error
+		data, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mock := cred.Mock
+	if mock == nil {
+		mock = &config.MockConfig{}
+	}
+	if mock.Latency > 0 {
+		timer := time.NewTimer(mock.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	var body requestBody
+	if mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil && mediaType == "multipart/form-data" {
+		body = formRequestBody(data, params["boundary"])
+	} else if len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			return response(req, http.StatusBadRequest, "application/json",
+				[]byte(`{"error":{"message":"mock: invalid JSON request body","type":"invalid_request_error"}}`)), nil
+		}
+	}
+
+	id := t.seq.Add(1)
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/images/generations"),
+		strings.HasSuffix(req.URL.Path, "/images/edits"),
+		strings.HasSuffix(req.URL.Path, "/images/variations"):
+		return response(req, http.StatusOK, "application/json", imageResponse(body)), nil
+	case strings.HasSuffix(req.URL.Path, "/embeddings"):
+		return response(req, http.StatusOK, "application/json", embeddingsResponse(body, mock)), nil
+	}
+
+	text, err := renderResponse(mock.Response, body)
+	if err != nil {
+		return response(req, http.StatusInternalServerError, "application/json",
+			[]byte(fmt.Sprintf(`{"error":{"message":%q,"type":"server_error"}}`, "mock: "+err.Error()))), nil
+	}
+	promptTokens := mock.PromptTokens
+	if promptTokens == 0 {
+		promptTokens = estimateTokens(string(data))
+	}
+	completionTokens := mock.CompletionTokens
+	if completionTokens == 0 {
+		completionTokens = estimateTokens(text)
+	}
+
+	completion := chatCompletion{
+		id:               fmt.Sprintf("chatcmpl-mock-%d", id),
+		model:            body.Model,
+		text:             text,
+		promptTokens:     promptTokens,
+		completionTokens: completionTokens,
+	}
+	if body.Stream {
+		return response(req, http.StatusOK, "text/event-stream", completion.stream()), nil
+	}
+	return response(req, http.StatusOK, "application/json", completion.json()), nil
+}
+
+// renderResponse executes the response template with the request model and last user message
+func renderResponse(text string, body requestBody) (string, error) {
+	if text == "" {
+		text = DefaultResponse
+	}
+	tmpl, err := template.New("mock").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid response template: %w", err)
+	}
+
+	prompt := ""
+	for _, message := range body.Messages {
+		if message.Role == "user" {
+			prompt = contentText(message.Content)
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct{ Model, Prompt string }{body.Model, prompt}); err != nil {
+		return "", fmt.Errorf("invalid response template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// contentText returns the text of a string or content-block message
+func contentText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// estimateTokens approximates a token count as four characters per token
+func estimateTokens(text string) int {
+	return max(1, len(text)/4)
+}
+
+func response(req *http.Request, statusCode int, contentType string, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+type chatCompletion struct {
+	id               string
+	model            string
+	text             string
+	promptTokens     int
+	completionTokens int
+}
+
+func (c chatCompletion) usage() map[string]int {
+	return map[string]int{
+		"prompt_tokens":     c.promptTokens,
+		"completion_tokens": c.completionTokens,
+		"total_tokens":      c.promptTokens + c.completionTokens,
+	}
+}
+
+func (c chatCompletion) json() []byte {
+	data, _ := json.Marshal(map[string]any{
+		"id":      c.id,
+		"object":  "chat.completion",
+		"created": utils.NowUTC().Unix(),
+		"model":   c.model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": c.text},
+			"finish_reason": "stop",
+		}},
+		"usage": c.usage(),
+	})
+	return data
+}
+
+// stream renders the completion as SSE chunks: role, one chunk per word, finish reason, usage
+func (c chatCompletion) stream() []byte {
+	created := utils.NowUTC().Unix()
+	var b bytes.Buffer
+	writeChunk := func(choices []map[string]any, usage map[string]int) {
+		chunk := map[string]any{
+			"id":      c.id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   c.model,
+			"choices": choices,
+		}
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		data, _ := json.Marshal(chunk)
+		b.WriteString("data: ")
+		b.Write(data)
+		b.WriteString("\n\n")
+	}
+
+	writeChunk([]map[string]any{{"index": 0, "delta": map[string]string{"role": "assistant", "content": ""}}}, nil)
+	words := strings.SplitAfter(c.text, " ")
+	for _, word := range words {
+		if word != "" {
+			writeChunk([]map[string]any{{"index": 0, "delta": map[string]string{"content": word}}}, nil)
+		}
+	}
+	writeChunk([]map[string]any{{"index": 0, "delta": map[string]string{}, "finish_reason": "stop"}}, nil)
+	writeChunk([]map[string]any{}, c.usage())
+	b.WriteString("data: [DONE]\n\n")
+	return b.Bytes()
+}
+
+// formRequestBody reads the fields of a multipart image edit or variation request
+func formRequestBody(data []byte, boundary string) requestBody {
+	var body requestBody
+	form, err := multipart.NewReader(bytes.NewReader(data), boundary).ReadForm(int64(len(data)))
+	if err != nil {
+		return body
+	}
+	defer func() { _ = form.RemoveAll() }()
+	field := func(name string) string {
+		if values := form.Value[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	body.Model = field("model")
+	body.Prompt = field("prompt")
+	body.N, _ = strconv.Atoi(field("n"))
+	return body
+}
+
+func imageResponse(body requestBody) []byte {
+	n := max(1, body.N)
+	images := make([]map[string]string, n)
+	for i := range images {
+		images[i] = map[string]string{"b64_json": mockImagePNG, "revised_prompt": body.Prompt}
+	}
+	data, _ := json.Marshal(map[string]any{
+		"created": utils.NowUTC().Unix(),
+		"data":    images,
+	})
+	return data
+}
+
+func embeddingsResponse(body requestBody, mock *config.MockConfig) []byte {
+	var inputs []string
+	if err := json.Unmarshal(body.Input, &inputs); err != nil {
+		var input string
+		_ = json.Unmarshal(body.Input, &input)
+		inputs = []string{input}
+	}
+
+	promptTokens := mock.PromptTokens
+	embeddings := make([]map[string]any, len(inputs))
+	for i, input := range inputs {
+		vector := make([]float64, embeddingDimensions)
+		for j := range vector {
+			// Deterministic per input, so similarity checks in tests are stable
+			vector[j] = float64((len(input)+j*31)%100) / 100
+		}
+		embeddings[i] = map[string]any{"object": "embedding", "index": i, "embedding": vector}
+		if mock.PromptTokens == 0 {
+			promptTokens += estimateTokens(input)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"object": "list",
+		"data":   embeddings,
+		"model":  body.Model,
+		"usage":  map[string]int{"prompt_tokens": promptTokens, "total_tokens": promptTokens},
+	})
+	return data
+}
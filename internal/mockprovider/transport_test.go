@@ -0,0 +1,215 @@
+package mockprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingTransport struct {
+	calls int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return response(req, http.StatusOK, "application/json", []byte(`{"upstream":true}`)), nil
+}
+
+func doRequest(t *testing.T, transport http.RoundTripper, ctx context.Context, path, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.DefaultMockBaseURL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp, string(data)
+}
+
+func mockContext(mock *config.MockConfig) context.Context {
+	return httputil.WithCredential(context.Background(), &config.CredentialConfig{
+		Name: "mock",
+		Type: config.ProviderTypeMock,
+		Mock: mock,
+	})
+}
+
+func TestTransport_ChatCompletion(t *testing.T) {
+	next := &countingTransport{}
+	transport := NewTransport(next)
+
+	ctx := mockContext(&config.MockConfig{Response: "{{.Model}} says: {{.Prompt}}", PromptTokens: 11, CompletionTokens: 7})
+	resp, body := doRequest(t, transport, ctx, "/v1/chat/completions",
+		`{"model":"gpt-mock","messages":[{"role":"system","content":"Be brief."},{"role":"user","content":[{"type":"text","text":"Hello"}]}]}`)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var completion struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &completion))
+	assert.Equal(t, "gpt-mock", completion.Model)
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "gpt-mock says: Hello", completion.Choices[0].Message.Content)
+	assert.Equal(t, "stop", completion.Choices[0].FinishReason)
+	assert.Equal(t, 11, completion.Usage.PromptTokens)
+	assert.Equal(t, 7, completion.Usage.CompletionTokens)
+	assert.Equal(t, 18, completion.Usage.TotalTokens)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&next.calls))
+
+	// Without a mock section the default response and estimated token counts are used
+	_, body = doRequest(t, transport, mockContext(nil), "/v1/chat/completions",
+		`{"model":"gpt-mock","messages":[{"role":"user","content":"Hi"}]}`)
+	require.NoError(t, json.Unmarshal([]byte(body), &completion))
+	assert.Equal(t, "This is a mock response from gpt-mock.", completion.Choices[0].Message.Content)
+	assert.Positive(t, completion.Usage.PromptTokens)
+	assert.Positive(t, completion.Usage.CompletionTokens)
+}
+
+func TestTransport_Stream(t *testing.T) {
+	transport := NewTransport(&countingTransport{})
+
+	resp, body := doRequest(t, transport, mockContext(&config.MockConfig{Response: "one two three", CompletionTokens: 3}),
+		"/v1/chat/completions", `{"model":"gpt-mock","stream":true,"messages":[{"role":"user","content":"Hi"}]}`)
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+
+	var text strings.Builder
+	var completionTokens int
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		for _, choice := range chunk.Choices {
+			text.WriteString(choice.Delta.Content)
+		}
+		if chunk.Usage != nil {
+			completionTokens = chunk.Usage.CompletionTokens
+		}
+	}
+	assert.Equal(t, "one two three", text.String())
+	assert.Equal(t, 3, completionTokens)
+}
+
+func TestTransport_ImagesAndEmbeddings(t *testing.T) {
+	transport := NewTransport(&countingTransport{})
+
+	_, body := doRequest(t, transport, mockContext(nil), "/v1/images/generations", `{"model":"image-mock","prompt":"a cat","n":2}`)
+	var images struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &images))
+	require.Len(t, images.Data, 2)
+	assert.Equal(t, mockImagePNG, images.Data[0].B64JSON)
+
+	_, body = doRequest(t, transport, mockContext(nil), "/v1/embeddings", `{"model":"embed-mock","input":["a","bb"]}`)
+	var embeddings struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &embeddings))
+	require.Len(t, embeddings.Data, 2)
+	assert.Len(t, embeddings.Data[0].Embedding, embeddingDimensions)
+	assert.Equal(t, 2, embeddings.Usage.PromptTokens)
+}
+
+func TestTransport_ImageEdits(t *testing.T) {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	require.NoError(t, writer.WriteField("model", "image-mock"))
+	require.NoError(t, writer.WriteField("prompt", "add a hat"))
+	require.NoError(t, writer.WriteField("n", "3"))
+	file, err := writer.CreateFormFile("image", "cat.png")
+	require.NoError(t, err)
+	_, _ = file.Write([]byte("png"))
+	require.NoError(t, writer.Close())
+
+	req, err := http.NewRequestWithContext(mockContext(nil), http.MethodPost, config.DefaultMockBaseURL+"/v1/images/edits", &form)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := NewTransport(&countingTransport{}).RoundTrip(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var images struct {
+		Data []struct {
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&images))
+	require.Len(t, images.Data, 3)
+	assert.Equal(t, "add a hat", images.Data[0].RevisedPrompt)
+}
+
+func TestTransport_PassesThroughOtherCredentials(t *testing.T) {
+	next := &countingTransport{}
+	transport := NewTransport(next)
+
+	openaiCtx := httputil.WithCredential(context.Background(), &config.CredentialConfig{Name: "oai", Type: config.ProviderTypeOpenAI})
+	_, body := doRequest(t, transport, openaiCtx, "/v1/chat/completions", `{}`)
+	assert.Equal(t, `{"upstream":true}`, body)
+	_, body = doRequest(t, transport, context.Background(), "/v1/chat/completions", `{}`)
+	assert.Equal(t, `{"upstream":true}`, body)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&next.calls))
+}
+
+func TestTransport_Latency(t *testing.T) {
+	transport := NewTransport(&countingTransport{})
+	ctx := mockContext(&config.MockConfig{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	doRequest(t, transport, ctx, "/v1/chat/completions", `{"model":"gpt-mock"}`)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// Cancelled requests stop waiting
+	ctx, cancel := context.WithCancel(mockContext(&config.MockConfig{Latency: time.Minute}))
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.DefaultMockBaseURL+"/v1/chat/completions", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,6 +24,9 @@ type ModelPrice struct {
 	InputCostPerTokenAbove200k  float64 `json:"input_cost_per_token_above_200k_tokens,omitempty"`
 	OutputCostPerTokenAbove200k float64 `json:"output_cost_per_token_above_200k_tokens,omitempty"`
 
+	// Tiered pricing: rates of the whole request selected by its prompt tokens (see PriceTier)
+	TieredPricing []PriceTier `json:"tiered_pricing,omitempty"`
+
 	// Audio tokens (can be more specific than regular tokens)
 	InputCostPerAudioToken  float64 `json:"input_cost_per_audio_token,omitempty"`
 	OutputCostPerAudioToken float64 `json:"output_cost_per_audio_token,omitempty"`
@@ -40,38 +45,128 @@ type ModelPrice struct {
 
 	// Vision/Images cost per image (not per token)
 	OutputCostPerImage float64 `json:"output_cost_per_image,omitempty"`
+
+	// Server-side web search cost per query (not per token)
+	SearchContextCostPerQuery SearchQueryCost `json:"search_context_cost_per_query,omitempty"`
+
+	// Context window (prompt tokens the model accepts, 0 = unknown)
+	MaxInputTokens  TokenLimit `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens TokenLimit `json:"max_output_tokens,omitempty"`
+
+	// Capabilities (nil = not listed in the model prices JSON)
+	Mode                    string       `json:"mode,omitempty"` // chat, completion, embedding, image_generation, ...
+	SupportsFunctionCalling *FeatureFlag `json:"supports_function_calling,omitempty"`
+	SupportsVision          *FeatureFlag `json:"supports_vision,omitempty"`
+	SupportsAudioInput      *FeatureFlag `json:"supports_audio_input,omitempty"`
+	SupportsAudioOutput     *FeatureFlag `json:"supports_audio_output,omitempty"`
+	SupportsPDFInput        *FeatureFlag `json:"supports_pdf_input,omitempty"`
+	SupportsResponseSchema  *FeatureFlag `json:"supports_response_schema,omitempty"`
+	SupportsReasoning       *FeatureFlag `json:"supports_reasoning,omitempty"`
+}
+
+// TokenLimit is a token count from the model prices JSON. Non-numeric values
+// (e.g. the descriptive strings of the LiteLLM sample_spec entry) decode as 0.
+type TokenLimit int
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *TokenLimit) UnmarshalJSON(data []byte) error {
+	var n float64
+	if err := json.Unmarshal(data, &n); err != nil {
+		*t = 0
+		return nil
+	}
+	*t = TokenLimit(n)
+	return nil
+}
+
+// SearchQueryCost is the search_context_cost_per_query of the model prices JSON: the cost per
+// web search at the medium search context size, the size Anthropic web search is billed at.
+// Plain numbers are accepted too; other values decode as 0.
+type SearchQueryCost float64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *SearchQueryCost) UnmarshalJSON(data []byte) error {
+	var bySize struct {
+		Medium float64 `json:"search_context_size_medium"`
+	}
+	if err := json.Unmarshal(data, &bySize); err == nil {
+		*c = SearchQueryCost(bySize.Medium)
+		return nil
+	}
+	var n float64
+	if err := json.Unmarshal(data, &n); err != nil {
+		*c = 0
+		return nil
+	}
+	*c = SearchQueryCost(n)
+	return nil
+}
+
+// FeatureFlag is a supports_* capability from the model prices JSON.
+// Non-boolean values decode as false.
+type FeatureFlag bool
+
+// UnmarshalJSON implements json.Unmarshaler
+func (f *FeatureFlag) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err != nil {
+		*f = false
+		return nil
+	}
+	*f = FeatureFlag(b)
+	return nil
 }
 
 // ModelPriceRegistry stores and manages cached model prices
 type ModelPriceRegistry struct {
 	mu         sync.RWMutex
 	prices     map[string]*ModelPrice // key: normalized model name
+	keys       []string               // Price keys, longest first (closest-match candidates)
+	mapping    map[string]string      // Model name -> price key (server.model_price_mapping)
+	resolved   map[string]string      // Model name -> price key it resolved to ("" = unresolved), reset on changes
+	generation uint64                 // Incremented when resolved is reset
 	lastUpdate time.Time
+	restored   bool            // Prices come from a snapshot, not from a sync of this process
+	lastError  string          // Error of the last failed sync ("" after a successful one)
+	lost       map[string]bool // Price keys and model names priced by earlier prices, but not the current ones
 }
 
 // NewModelPriceRegistry creates a new price registry
 func NewModelPriceRegistry() *ModelPriceRegistry {
 	return &ModelPriceRegistry{
-		prices: make(map[string]*ModelPrice),
+		prices:   make(map[string]*ModelPrice),
+		mapping:  make(map[string]string),
+		resolved: make(map[string]string),
+		lost:     make(map[string]bool),
 	}
 }
 
-// GetPrice returns the price for a model, or nil if not found
+// GetPrice returns the price for a model, or nil if not found. Names without an exact
+// entry are resolved like in ResolvePrice.
 func (r *ModelPriceRegistry) GetPrice(modelName string) *ModelPrice {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.prices[modelName]
+	price, _ := r.ResolvePrice(modelName)
+	return price
 }
 
 // Update safely updates the registry with new prices
 func (r *ModelPriceRegistry) Update(prices map[string]*ModelPrice) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.setPrices(prices)
+	r.lastUpdate = utils.NowUTC()
+	r.restored = false
+	r.lastError = ""
+}
+
+// setPrices replaces the prices. Must be called with r.mu held.
+func (r *ModelPriceRegistry) setPrices(prices map[string]*ModelPrice) {
+	r.trackLostPrices(prices)
 	r.prices = make(map[string]*ModelPrice)
 	for k, v := range prices {
 		r.prices[k] = v
 	}
-	r.lastUpdate = utils.NowUTC()
+	r.keys = sortedPriceKeys(r.prices)
+	r.resetResolved()
 }
 
 // LastUpdate returns the time of last successful update
@@ -106,6 +201,7 @@ type ModelsResponse struct {
 type ModelLimits struct {
 	RPM        int
 	TPM        int
+	RPMBurst   int    // Token bucket capacity; 0 means sliding window
 	Credential string // If set, limits apply only to this credential
 }
 
@@ -132,10 +228,11 @@ type Manager struct {
 	modelRealNames     map[string]string        // alias name -> real model name (from models[].model field)
 	defaultModelsRPM   int                      // default RPM for models
 	logger             *slog.Logger
-	credentials        []config.CredentialConfig   // credentials for fetching remote models
-	remoteModelsCache  map[string]remoteModelCache // cache for remote models per credential (credentialName -> cache)
-	cacheExpiration    time.Duration               // how long to cache remote models (default 5 minutes)
-	allModelsCache     allModelsCache              // cached result of GetAllModels (3 second TTL)
+	credentials        []config.CredentialConfig       // credentials for fetching remote models
+	remoteModelsCache  map[string]remoteModelCache     // cache for remote models per credential (credentialName -> cache)
+	cacheExpiration    time.Duration                   // how long to cache remote models (default 5 minutes)
+	allModelsCache     allModelsCache                  // cached result of GetAllModels (3 second TTL)
+	modelsSeen         map[string]map[string]time.Time // credential name -> dynamically added model -> last AddModel
 }
 
 // New creates a new model manager
@@ -152,6 +249,7 @@ func New(logger *slog.Logger, defaultModelsRPM int, staticModels []config.ModelR
 		credentials:        make([]config.CredentialConfig, 0),
 		remoteModelsCache:  make(map[string]remoteModelCache),
 		cacheExpiration:    5 * time.Minute, // Default cache TTL: 5 minutes
+		modelsSeen:         make(map[string]map[string]time.Time),
 	}
 
 	// Load static models from config.yaml
@@ -161,6 +259,7 @@ func New(logger *slog.Logger, defaultModelsRPM int, staticModels []config.ModelR
 			m.modelLimits[staticModel.Name] = append(m.modelLimits[staticModel.Name], ModelLimits{
 				RPM:        staticModel.RPM,
 				TPM:        staticModel.TPM,
+				RPMBurst:   staticModel.RPMBurst,
 				Credential: staticModel.Credential,
 			})
 			// Register real model name mapping if Model field differs from Name
@@ -553,11 +652,22 @@ func (m *Manager) HasModel(credentialName, modelID string) bool {
 	return true
 }
 
-// AddModel adds a model to the credential mapping (used for dynamically loaded models from proxy)
+// AddModel adds a model to the credential mapping (used for dynamically loaded models from proxy).
+// Each call refreshes the model's last seen time for PruneStaleModels; models registered from
+// the static config are never tracked.
 func (m *Manager) AddModel(credentialName, modelID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	seen := m.modelsSeen[credentialName]
+	if _, dynamic := seen[modelID]; dynamic || !m.contains(m.credentialModels[credentialName], modelID) {
+		if seen == nil {
+			seen = make(map[string]time.Time)
+			m.modelsSeen[credentialName] = seen
+		}
+		seen[modelID] = utils.NowUTC()
+	}
+
 	// Add to credentialModels
 	if !m.contains(m.credentialModels[credentialName], modelID) {
 		m.credentialModels[credentialName] = append(m.credentialModels[credentialName], modelID)
@@ -569,6 +679,46 @@ func (m *Manager) AddModel(credentialName, modelID string) {
 	}
 }
 
+// PruneStaleModels removes the dynamically added models of a credential that were last seen
+// before olderThan and returns their IDs
+func (m *Manager) PruneStaleModels(credentialName string, olderThan time.Time) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var removed []string
+	for modelID, seen := range m.modelsSeen[credentialName] {
+		if !seen.Before(olderThan) {
+			continue
+		}
+		delete(m.modelsSeen[credentialName], modelID)
+		m.credentialModels[credentialName] = remove(m.credentialModels[credentialName], modelID)
+		if len(m.credentialModels[credentialName]) == 0 {
+			delete(m.credentialModels, credentialName)
+		}
+		m.modelToCredentials[modelID] = remove(m.modelToCredentials[modelID], credentialName)
+		if len(m.modelToCredentials[modelID]) == 0 {
+			delete(m.modelToCredentials, modelID)
+		}
+		removed = append(removed, modelID)
+	}
+	if len(m.modelsSeen[credentialName]) == 0 {
+		delete(m.modelsSeen, credentialName)
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// remove returns a copy of slice without value, preserving order
+func remove(slice []string, value string) []string {
+	result := make([]string, 0, len(slice))
+	for _, item := range slice {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // contains checks if a string slice contains a value
 func (m *Manager) contains(slice []string, value string) bool {
 	for _, item := range slice {
@@ -579,6 +729,27 @@ func (m *Manager) contains(slice []string, value string) bool {
 	return false
 }
 
+// ManagerStats summarizes model manager cache sizes for diagnostics
+type ManagerStats struct {
+	Credentials        int `json:"credentials"`
+	Models             int `json:"models"`
+	RemoteModelsCaches int `json:"remote_models_caches"`
+	Aliases            int `json:"aliases"`
+}
+
+// Stats returns current model manager cache sizes
+func (m *Manager) Stats() ManagerStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return ManagerStats{
+		Credentials:        len(m.credentialModels),
+		Models:             len(m.modelToCredentials),
+		RemoteModelsCaches: len(m.remoteModelsCache),
+		Aliases:            len(m.modelAliases),
+	}
+}
+
 // IsEnabled returns whether model filtering should be used
 // Returns true if there are models defined in static config
 func (m *Manager) IsEnabled() bool {
@@ -705,6 +876,16 @@ func (m *Manager) GetModelTPMForCredential(modelID, credentialName string) int {
 	return -1
 }
 
+// GetModelRPMBurstForCredential returns the RPM token bucket burst for a specific model and credential.
+// Returns 0 (sliding window) when no burst is configured.
+func (m *Manager) GetModelRPMBurstForCredential(modelID, credentialName string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	burst, _ := findLimit(m.modelLimits[modelID], credentialName, func(ml *ModelLimits) int { return ml.RPMBurst }, func(v int) int { return v })
+	return burst
+}
+
 // GetModelsForCredential returns all models available for a specific credential.
 //
 // Behavior:
@@ -826,6 +1007,9 @@ func (m *Manager) GetRemoteModelsWithError(ctx context.Context, cred *config.Cre
 		return nil, err
 	}
 
+	// Apply proxy_models: only exposed models are cached, under their exposed names
+	modelsResp.Data = exposeProxyModels(cred, modelsResp.Data)
+
 	// Cache the result
 	m.mu.Lock()
 	m.remoteModelsCache[cred.Name] = remoteModelCache{
@@ -842,3 +1026,20 @@ func (m *Manager) GetRemoteModelsWithError(ctx context.Context, cred *config.Cre
 
 	return modelsResp.Data, nil
 }
+
+// exposeProxyModels filters and renames the models fetched from a proxy credential (proxy_models)
+func exposeProxyModels(cred *config.CredentialConfig, remoteModels []Model) []Model {
+	if cred.ProxyModels == nil {
+		return remoteModels
+	}
+	exposed := make([]Model, 0, len(remoteModels))
+	for _, model := range remoteModels {
+		name, ok := cred.ProxyModels.Expose(model.ID)
+		if !ok {
+			continue
+		}
+		model.ID = name
+		exposed = append(exposed, model)
+	}
+	return exposed
+}
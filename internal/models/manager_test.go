@@ -265,6 +265,20 @@ func TestGetModelRPMForCredential_NoStaticModels(t *testing.T) {
 	assert.Equal(t, 75, rpm)
 }
 
+func TestGetModelRPMBurstForCredential(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	staticModels := []config.ModelRPMConfig{
+		{Name: "gpt-4", Credential: "cred1", RPM: 100, RPMBurst: 300},
+		{Name: "gpt-4", RPM: 50},
+	}
+	manager := New(logger, 50, staticModels)
+
+	assert.Equal(t, 300, manager.GetModelRPMBurstForCredential("gpt-4", "cred1"))
+	assert.Equal(t, 0, manager.GetModelRPMBurstForCredential("gpt-4", "cred2"), "global entry has no burst")
+	assert.Equal(t, 0, manager.GetModelRPMBurstForCredential("non-existing", "cred1"))
+}
+
 func TestGetModelTPMForCredential(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
@@ -404,6 +418,33 @@ func TestAddModel(t *testing.T) {
 	assert.Len(t, models, 1, "Should not create duplicate model entry")
 }
 
+func TestPruneStaleModels(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	manager := New(logger, 100, []config.ModelRPMConfig{{Name: "gpt-4o", Credential: "gateway"}})
+	manager.LoadModelsFromConfig([]config.CredentialConfig{{Name: "gateway"}, {Name: "other"}})
+
+	manager.AddModel("gateway", "gpt-4o") // static, never evicted
+	manager.AddModel("gateway", "old-model")
+	manager.AddModel("other", "old-model")
+	manager.modelsSeen["gateway"]["old-model"] = time.Now().Add(-time.Hour)
+	manager.AddModel("gateway", "new-model")
+
+	removed := manager.PruneStaleModels("gateway", time.Now().Add(-time.Minute))
+	assert.Equal(t, []string{"old-model"}, removed)
+	assert.False(t, manager.HasModel("gateway", "old-model"))
+	assert.Equal(t, []string{"other"}, manager.GetCredentialsForModel("old-model"))
+	assert.True(t, manager.HasModel("gateway", "gpt-4o"))
+	assert.True(t, manager.HasModel("gateway", "new-model"))
+
+	assert.Empty(t, manager.PruneStaleModels("gateway", time.Now().Add(-time.Minute)))
+
+	removed = manager.PruneStaleModels("other", time.Now().Add(time.Minute))
+	assert.Equal(t, []string{"old-model"}, removed)
+	assert.Empty(t, manager.GetCredentialsForModel("old-model"))
+	assert.NotContains(t, manager.modelsSeen, "other")
+	assert.Equal(t, 1, manager.Stats().Credentials, "credential without models is dropped")
+}
+
 // TestConcurrentGetAllModels tests concurrent access to GetAllModels
 func TestConcurrentGetAllModels(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// pinRefreshInterval is how long the newest available snapshots are cached
+const pinRefreshInterval = 30 * time.Second
+
+var (
+	// ErrUnknownPin is returned for aliases without a pin
+	ErrUnknownPin = errors.New("model alias is not pinned")
+	// ErrPinFollowsLatest is returned when bumping an alias with policy latest
+	ErrPinFollowsLatest = errors.New("model alias follows the newest snapshot (policy latest)")
+	// ErrNoSnapshot is returned when no snapshot of the alias family is available
+	ErrNoSnapshot = errors.New("no snapshot of the model family is available")
+	// ErrInvalidSnapshot is returned for snapshots outside the alias family
+	ErrInvalidSnapshot = errors.New("snapshot does not belong to the model family")
+	// ErrSnapshotUnavailable is returned for snapshots no credential serves
+	ErrSnapshotUnavailable = errors.New("snapshot is not served by any credential")
+)
+
+// Pin is the current state of a rolling alias
+type Pin struct {
+	Alias    string     `json:"alias"`
+	Policy   string     `json:"policy"`
+	Family   string     `json:"family"`
+	Snapshot string     `json:"snapshot"`            // Model requests for the alias are sent to ("" = passed through)
+	Latest   string     `json:"latest,omitempty"`    // Newest available snapshot
+	Stale    bool       `json:"stale"`               // A newer snapshot than Snapshot is available
+	PinnedAt *time.Time `json:"pinned_at,omitempty"` // When the pin was set at runtime (nil = from config)
+}
+
+type pinState struct {
+	cfg      config.ModelPinConfig
+	snapshot string
+	pinnedAt *time.Time
+}
+
+// PinRegistry resolves rolling model aliases to pinned snapshots (model_pins).
+// Snapshots are the available models named family-<version> or family@<version>,
+// e.g. gpt-4o-2024-08-06 or claude-sonnet-4-5@20250929. Runtime bumps are kept in memory;
+// update the config snapshot to keep them across restarts.
+type PinRegistry struct {
+	mu         sync.Mutex
+	pins       map[string]*pinState
+	listModels func() []string // Model IDs served by the credentials
+	available  map[string]bool // Model IDs served at the last refresh
+	latest     map[string]string
+	refreshed  time.Time
+	logger     *slog.Logger
+}
+
+// NewPinRegistry creates a registry for the configured aliases; listModels returns the
+// model IDs the newest snapshots are picked from
+func NewPinRegistry(cfg config.ModelPinsConfig, listModels func() []string, logger *slog.Logger) *PinRegistry {
+	r := &PinRegistry{
+		pins:       make(map[string]*pinState, len(cfg.Aliases)),
+		listModels: listModels,
+		logger:     logger,
+	}
+	for alias, pin := range cfg.Aliases {
+		r.pins[alias] = &pinState{cfg: pin, snapshot: pin.Snapshot}
+	}
+	return r
+}
+
+// Resolve returns the snapshot requests for modelID are sent to, and false if modelID is not
+// a pinned alias or no snapshot is available yet. Safe on a nil registry.
+func (r *PinRegistry) Resolve(modelID string) (string, bool) {
+	if r == nil {
+		return modelID, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.pins[modelID]
+	if !ok {
+		return modelID, false
+	}
+	r.refreshLocked(false)
+	latest := r.latest[modelID]
+
+	switch state.cfg.Policy {
+	case config.ModelPinPolicyLatest:
+		if latest == "" {
+			return modelID, false
+		}
+		return latest, true
+	case config.ModelPinPolicyPinned:
+		if state.snapshot == "" && latest != "" {
+			state.snapshot = latest
+			now := utils.NowUTC()
+			state.pinnedAt = &now
+			r.logger.Info("Pinned model alias to newest snapshot", "alias", modelID, "snapshot", latest)
+		}
+	}
+	if state.snapshot == "" {
+		return modelID, false
+	}
+	return state.snapshot, true
+}
+
+// List returns the pins sorted by alias
+func (r *PinRegistry) List() []Pin {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshLocked(false)
+
+	pins := make([]Pin, 0, len(r.pins))
+	for alias := range r.pins {
+		pins = append(pins, r.pinLocked(alias))
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Alias < pins[j].Alias })
+	return pins
+}
+
+// Bump pins alias to snapshot, or to the newest available snapshot if snapshot is empty.
+// The snapshot must belong to the alias family and be served by a credential.
+func (r *PinRegistry) Bump(alias, snapshot string) (Pin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.pins[alias]
+	if !ok {
+		return Pin{}, ErrUnknownPin
+	}
+	if state.cfg.Policy == config.ModelPinPolicyLatest {
+		return Pin{}, ErrPinFollowsLatest
+	}
+	r.refreshLocked(true)
+	if snapshot == "" {
+		if snapshot = r.latest[alias]; snapshot == "" {
+			return Pin{}, ErrNoSnapshot
+		}
+	} else if _, ok := snapshotVersion(state.cfg.Family, snapshot); !ok {
+		return Pin{}, ErrInvalidSnapshot
+	} else if !r.available[snapshot] {
+		return Pin{}, ErrSnapshotUnavailable
+	}
+
+	previous := state.snapshot
+	now := utils.NowUTC()
+	state.snapshot = snapshot
+	state.pinnedAt = &now
+	r.logger.Warn("Model pin bumped", "alias", alias, "previous", previous, "snapshot", snapshot)
+	return r.pinLocked(alias), nil
+}
+
+func (r *PinRegistry) pinLocked(alias string) Pin {
+	state := r.pins[alias]
+	pin := Pin{
+		Alias:    alias,
+		Policy:   state.cfg.Policy,
+		Family:   state.cfg.Family,
+		Snapshot: state.snapshot,
+		Latest:   r.latest[alias],
+		PinnedAt: state.pinnedAt,
+	}
+	if pin.Policy == config.ModelPinPolicyLatest {
+		pin.Snapshot = pin.Latest
+	}
+	pin.Stale = pin.Latest != "" && pin.Snapshot != "" && newerSnapshot(state.cfg.Family, pin.Latest, pin.Snapshot)
+	return pin
+}
+
+// refreshLocked recomputes the newest snapshot of each alias family from the available
+// models once pinRefreshInterval has passed (or always with force)
+func (r *PinRegistry) refreshLocked(force bool) {
+	now := utils.NowUTC()
+	if !force && r.latest != nil && now.Sub(r.refreshed) < pinRefreshInterval {
+		return
+	}
+	r.refreshed = now
+
+	var modelIDs []string
+	if r.listModels != nil {
+		modelIDs = r.listModels()
+	}
+	r.available = make(map[string]bool, len(modelIDs))
+	for _, model := range modelIDs {
+		r.available[model] = true
+	}
+	r.latest = make(map[string]string, len(r.pins))
+	for alias, state := range r.pins {
+		family := state.cfg.Family
+		for _, model := range modelIDs {
+			if _, ok := snapshotVersion(family, model); !ok {
+				continue
+			}
+			if current := r.latest[alias]; current == "" || newerSnapshot(family, model, current) {
+				r.latest[alias] = model
+			}
+		}
+
+		stale := 0.0
+		if pin := r.pinLocked(alias); pin.Stale {
+			stale = 1
+		}
+		monitoring.ModelPinStale.WithLabelValues(alias).Set(stale)
+	}
+}
+
+// snapshotVersion returns the version of model if it is a snapshot of family:
+// family, then "-" or "@", then digits separated by "-", "@" or "." (2024-08-06, 4-5@20250929, 001)
+func snapshotVersion(family, model string) (string, bool) {
+	if family == "" || len(model) <= len(family)+1 || !strings.HasPrefix(model, family) {
+		return "", false
+	}
+	if sep := model[len(family)]; sep != '-' && sep != '@' {
+		return "", false
+	}
+	version := model[len(family)+1:]
+	for _, c := range version {
+		if (c < '0' || c > '9') && c != '-' && c != '@' && c != '.' {
+			return "", false
+		}
+	}
+	if version[0] < '0' || version[0] > '9' {
+		return "", false
+	}
+	return version, true
+}
+
+// newerSnapshot reports whether snapshot a of family is newer than b: by trailing release
+// date (YYYYMMDD or YYYY-MM-DD) first, then by the version digits (4-5 is newer than 4)
+func newerSnapshot(family, a, b string) bool {
+	va, _ := snapshotVersion(family, a)
+	vb, _ := snapshotVersion(family, b)
+	da, db := snapshotDate(va), snapshotDate(vb)
+	if da != db {
+		return da > db
+	}
+	na, nb := digitsOnly(va), digitsOnly(vb)
+	if len(na) != len(nb) {
+		return len(na) > len(nb)
+	}
+	return na > nb
+}
+
+// snapshotDate returns the trailing YYYYMMDD date of a version ("" = none)
+func snapshotDate(version string) string {
+	digits := digitsOnly(version)
+	if len(digits) < 8 {
+		return ""
+	}
+	date := digits[len(digits)-8:]
+	// The date must be the version's last component(s): 20250929 or 2024-08-06
+	if strings.HasSuffix(version, date) || strings.HasSuffix(version, date[:4]+"-"+date[4:6]+"-"+date[6:]) {
+		if date[:2] == "19" || date[:2] == "20" {
+			return date
+		}
+	}
+	return ""
+}
+
+func digitsOnly(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
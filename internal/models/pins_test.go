@@ -0,0 +1,114 @@
+package models
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotVersion(t *testing.T) {
+	tests := []struct {
+		family, model string
+		want          string
+		ok            bool
+	}{
+		{"gpt-4o", "gpt-4o-2024-08-06", "2024-08-06", true},
+		{"claude-sonnet", "claude-sonnet-4-5@20250929", "4-5@20250929", true},
+		{"gemini-2.0-flash", "gemini-2.0-flash-001", "001", true},
+		{"gpt-4o", "gpt-4o-mini-2024-07-18", "", false},
+		{"gpt-4o", "gpt-4o", "", false},
+		{"gpt-4o", "gpt-4o-audio-preview", "", false},
+		{"gpt-4", "gpt-4o-2024-08-06", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := snapshotVersion(tt.family, tt.model)
+		assert.Equal(t, tt.ok, ok, tt.model)
+		assert.Equal(t, tt.want, got, tt.model)
+	}
+}
+
+func TestNewerSnapshot(t *testing.T) {
+	assert.True(t, newerSnapshot("gpt-4o", "gpt-4o-2024-11-20", "gpt-4o-2024-08-06"))
+	assert.False(t, newerSnapshot("gpt-4o", "gpt-4o-2024-05-13", "gpt-4o-2024-08-06"))
+	assert.True(t, newerSnapshot("claude-sonnet", "claude-sonnet-4-5-20250929", "claude-sonnet-4-20250514"))
+	assert.True(t, newerSnapshot("claude-sonnet", "claude-sonnet-4-20250514", "claude-sonnet-3-7-20250219"))
+	assert.True(t, newerSnapshot("gemini-2.0-flash", "gemini-2.0-flash-002", "gemini-2.0-flash-001"))
+}
+
+func TestPinRegistry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	available := []string{"gpt-4o", "gpt-4o-2024-05-13", "gpt-4o-2024-08-06", "gpt-4o-mini-2024-07-18",
+		"claude-sonnet-4-20250514", "gemini-2.0-flash-001"}
+	registry := NewPinRegistry(config.ModelPinsConfig{
+		Enabled: true,
+		Aliases: map[string]config.ModelPinConfig{
+			"gpt-4o":               {Policy: config.ModelPinPolicyPinned, Family: "gpt-4o"},
+			"claude-sonnet-latest": {Policy: config.ModelPinPolicyLatest, Family: "claude-sonnet"},
+			"gemini-flash":         {Policy: config.ModelPinPolicyManual, Family: "gemini-2.0-flash", Snapshot: "gemini-2.0-flash-001"},
+		},
+	}, func() []string { return available }, logger)
+
+	resolved, ok := registry.Resolve("gpt-4o")
+	require.True(t, ok)
+	assert.Equal(t, "gpt-4o-2024-08-06", resolved, "pinned to the newest snapshot at first use")
+	resolved, ok = registry.Resolve("claude-sonnet-latest")
+	require.True(t, ok)
+	assert.Equal(t, "claude-sonnet-4-20250514", resolved)
+	resolved, ok = registry.Resolve("gemini-flash")
+	require.True(t, ok)
+	assert.Equal(t, "gemini-2.0-flash-001", resolved)
+	resolved, ok = registry.Resolve("gpt-4o-2024-05-13")
+	assert.False(t, ok)
+	assert.Equal(t, "gpt-4o-2024-05-13", resolved)
+
+	// New snapshots: latest follows them, pinned and manual aliases only report them as stale
+	available = append(available, "gpt-4o-2024-11-20", "claude-sonnet-4-5-20250929", "gemini-2.0-flash-002")
+	registry.refreshed = time.Time{}
+	_, err := registry.Bump("claude-sonnet-latest", "")
+	assert.ErrorIs(t, err, ErrPinFollowsLatest)
+
+	resolved, _ = registry.Resolve("gpt-4o")
+	assert.Equal(t, "gpt-4o-2024-08-06", resolved)
+	resolved, _ = registry.Resolve("claude-sonnet-latest")
+	assert.Equal(t, "claude-sonnet-4-5-20250929", resolved)
+
+	pins := registry.List()
+	require.Len(t, pins, 3)
+	assert.Equal(t, "claude-sonnet-latest", pins[0].Alias)
+	assert.False(t, pins[0].Stale)
+	assert.Equal(t, "gemini-flash", pins[1].Alias)
+	assert.True(t, pins[1].Stale)
+	assert.Nil(t, pins[1].PinnedAt, "pin from config")
+	assert.Equal(t, Pin{Alias: "gpt-4o", Policy: "pinned", Family: "gpt-4o", Snapshot: "gpt-4o-2024-08-06", Latest: "gpt-4o-2024-11-20", Stale: true, PinnedAt: pins[2].PinnedAt}, pins[2])
+	assert.NotNil(t, pins[2].PinnedAt)
+
+	pin, err := registry.Bump("gpt-4o", "")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-2024-11-20", pin.Snapshot)
+	assert.False(t, pin.Stale)
+	resolved, _ = registry.Resolve("gpt-4o")
+	assert.Equal(t, "gpt-4o-2024-11-20", resolved)
+
+	pin, err = registry.Bump("gemini-flash", "gemini-2.0-flash-002")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.0-flash-002", pin.Snapshot)
+
+	_, err = registry.Bump("gemini-flash", "gpt-4o-2024-11-20")
+	assert.ErrorIs(t, err, ErrInvalidSnapshot)
+	_, err = registry.Bump("gemini-flash", "gemini-2.0-flash-003")
+	assert.ErrorIs(t, err, ErrSnapshotUnavailable)
+	resolved, _ = registry.Resolve("gemini-flash")
+	assert.Equal(t, "gemini-2.0-flash-002", resolved, "a rejected bump keeps the pin")
+	_, err = registry.Bump("unknown", "")
+	assert.ErrorIs(t, err, ErrUnknownPin)
+
+	var disabled *PinRegistry
+	resolved, ok = disabled.Resolve("gpt-4o")
+	assert.False(t, ok)
+	assert.Equal(t, "gpt-4o", resolved)
+}
@@ -7,6 +7,53 @@ import (
 // Tiered pricing threshold: tokens above this count are billed at a different rate
 const tokenTiering200kThreshold = 200_000
 
+// PriceTier is a tiered_pricing entry of the model prices JSON: the rates of requests whose
+// prompt exceeds AboveInputTokens (e.g. long-context pricing above 200k input tokens).
+// Rates left at 0 keep the model's base rate.
+type PriceTier struct {
+	AboveInputTokens            int     `json:"above_input_tokens"`
+	InputCostPerToken           float64 `json:"input_cost_per_token,omitempty"`
+	OutputCostPerToken          float64 `json:"output_cost_per_token,omitempty"`
+	InputCostPerCachedToken     float64 `json:"input_cost_per_cached_token,omitempty"`
+	OutputCostPerReasoningToken float64 `json:"output_cost_per_reasoning_token,omitempty"`
+}
+
+// ForPromptTokens returns the price of a request with promptTokens prompt tokens: the model
+// price itself, or a copy with the rates of the highest tier whose threshold the prompt
+// exceeds. A selected tier prices every token of the request and replaces the above-200k
+// rates, which only price the tokens above the threshold.
+func (p *ModelPrice) ForPromptTokens(promptTokens int) *ModelPrice {
+	var tier *PriceTier
+	for i := range p.TieredPricing {
+		t := &p.TieredPricing[i]
+		if promptTokens > t.AboveInputTokens && (tier == nil || t.AboveInputTokens > tier.AboveInputTokens) {
+			tier = t
+		}
+	}
+	if tier == nil {
+		return p
+	}
+
+	priced := *p
+	priced.TieredPricing = nil
+	priced.InputCostPerTokenAbove200k = 0
+	priced.OutputCostPerTokenAbove200k = 0
+	for _, rate := range []struct {
+		tier float64
+		base *float64
+	}{
+		{tier.InputCostPerToken, &priced.InputCostPerToken},
+		{tier.OutputCostPerToken, &priced.OutputCostPerToken},
+		{tier.InputCostPerCachedToken, &priced.InputCostPerCachedToken},
+		{tier.OutputCostPerReasoningToken, &priced.OutputCostPerReasoningToken},
+	} {
+		if rate.tier > 0 {
+			*rate.base = rate.tier
+		}
+	}
+	return &priced
+}
+
 // CalculateTokenCosts computes costs based on token usage and model pricing
 // Returns nil if price is nil (model not found in pricing database)
 //
@@ -24,12 +71,13 @@ func CalculateTokenCosts(usage *converter.TokenUsage, price *ModelPrice) *conver
 	if usage == nil || price == nil {
 		return nil
 	}
+	price = price.ForPromptTokens(usage.PromptTokens)
 
 	costs := &converter.TokenCosts{}
 
 	// Calculate "regular" input tokens by subtracting specialized token types
 	// This handles both Vertex/OpenAI (where they're included) and Anthropic (where they're separate)
-	regularInputTokens := usage.PromptTokens - usage.AudioInputTokens - usage.CachedInputTokens
+	regularInputTokens := usage.PromptTokens - usage.AudioInputTokens - usage.CachedInputTokens - usage.ImageTokens
 	if regularInputTokens < 0 {
 		// Safety: shouldn't happen, but use 0 if somehow negative
 		regularInputTokens = 0
@@ -79,6 +127,13 @@ func CalculateTokenCosts(usage *converter.TokenUsage, price *ModelPrice) *conver
 	}
 	costs.AudioOutputCost = float64(usage.AudioOutputTokens) * audioOutputCost
 
+	// Image input tokens (included in PromptTokens) with fallback to regular tokens
+	imageInputCost := price.InputCostPerImageToken
+	if imageInputCost == 0 {
+		imageInputCost = price.InputCostPerToken
+	}
+	costs.InputCost += float64(usage.ImageTokens) * imageInputCost
+
 	// Cached tokens with fallback
 	cachedInputCost := price.InputCostPerCachedToken
 	if cachedInputCost == 0 {
@@ -121,6 +176,9 @@ func CalculateTokenCosts(usage *converter.TokenUsage, price *ModelPrice) *conver
 		costs.ImageCost = float64(usage.ImageCount) * price.OutputCostPerImageToken
 	}
 
+	// Server-side web searches are billed per query
+	costs.WebSearchCost = float64(usage.WebSearchRequests) * float64(price.SearchContextCostPerQuery)
+
 	// Calculate total
 	costs.TotalCost = costs.InputCost +
 		costs.OutputCost +
@@ -130,7 +188,8 @@ func CalculateTokenCosts(usage *converter.TokenUsage, price *ModelPrice) *conver
 		costs.CachedInputCost +
 		costs.CachedOutputCost +
 		costs.PredictionCost +
-		costs.ImageCost
+		costs.ImageCost +
+		costs.WebSearchCost
 
 	return costs
 }
@@ -1,10 +1,12 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/mixaill76/auto_ai_router/internal/converter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCalculateTokenCosts_RegularTokensOnly(t *testing.T) {
@@ -189,6 +191,53 @@ func TestCalculateTokenCosts_CachedFallbackToRegularPrice(t *testing.T) {
 	assert.Equal(t, 1.0, costs.TotalCost)
 }
 
+func TestCalculateTokenCosts_ImageInputTokens(t *testing.T) {
+	// Image tokens are included in PromptTokens
+	usage := &converter.TokenUsage{
+		PromptTokens: 100,
+		ImageTokens:  40,
+	}
+
+	price := &ModelPrice{
+		InputCostPerToken:      0.01,
+		InputCostPerImageToken: 0.02,
+	}
+
+	costs := CalculateTokenCosts(usage, price)
+
+	assert.NotNil(t, costs)
+
+	// Regular: (100 - 40) * 0.01 = 0.6, images: 40 * 0.02 = 0.8
+	assert.InDelta(t, 1.4, costs.InputCost, 1e-9)
+	assert.InDelta(t, 1.4, costs.TotalCost, 1e-9)
+
+	// Without an image price, image tokens cost the regular price
+	price.InputCostPerImageToken = 0
+	assert.InDelta(t, 1.0, CalculateTokenCosts(usage, price).TotalCost, 1e-9)
+}
+
+func TestCalculateTokenCosts_WebSearchRequests(t *testing.T) {
+	usage := &converter.TokenUsage{
+		PromptTokens:      100,
+		CompletionTokens:  50,
+		WebSearchRequests: 3,
+	}
+
+	price := &ModelPrice{
+		InputCostPerToken:         0.001,
+		OutputCostPerToken:        0.002,
+		SearchContextCostPerQuery: 0.01,
+	}
+
+	costs := CalculateTokenCosts(usage, price)
+
+	assert.NotNil(t, costs)
+
+	// Searches: 3 * 0.01 = 0.03, tokens: 100 * 0.001 + 50 * 0.002 = 0.2
+	assert.InDelta(t, 0.03, costs.WebSearchCost, 1e-9)
+	assert.InDelta(t, 0.23, costs.TotalCost, 1e-9)
+}
+
 func TestCalculateTokenCosts_SafetyNegativeTokens(t *testing.T) {
 	// Edge case: more audio tokens reported than total (shouldn't happen, but be safe)
 	usage := &converter.TokenUsage{
@@ -385,3 +434,71 @@ func TestCalculateTokenCosts_Below200k_NoTiering(t *testing.T) {
 	// Total: 250.0
 	assert.InDelta(t, 250.0, costs.TotalCost, 0.0001)
 }
+
+func TestCalculateTokenCosts_TieredPricing(t *testing.T) {
+	price := &ModelPrice{
+		InputCostPerToken:       0.001,
+		OutputCostPerToken:      0.002,
+		InputCostPerCachedToken: 0.0001,
+		TieredPricing: []PriceTier{
+			{AboveInputTokens: 500_000, InputCostPerToken: 0.004},
+			{AboveInputTokens: 200_000, InputCostPerToken: 0.002, OutputCostPerToken: 0.003, InputCostPerCachedToken: 0.0002},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		prompt     int
+		cached     int
+		completion int
+		wantInput  float64
+		wantOutput float64
+		wantCached float64
+	}{
+		// At the threshold the base rates apply
+		{"base tier", 200_000, 0, 1_000, 200.0, 2.0, 0},
+		// Above 200k every token is billed at the tier rate
+		{"long context tier", 300_000, 100_000, 1_000, 400.0, 3.0, 20.0},
+		// The 500k tier only overrides the input rate
+		{"highest tier", 600_000, 0, 1_000, 2400.0, 2.0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costs := CalculateTokenCosts(&converter.TokenUsage{
+				PromptTokens:      tt.prompt,
+				CachedInputTokens: tt.cached,
+				CompletionTokens:  tt.completion,
+			}, price)
+			require.NotNil(t, costs)
+			assert.InDelta(t, tt.wantInput, costs.InputCost, 1e-6)
+			assert.InDelta(t, tt.wantOutput, costs.OutputCost, 1e-6)
+			assert.InDelta(t, tt.wantCached, costs.CachedInputCost, 1e-6)
+		})
+	}
+
+	// The selected tier is a copy: the registry's price is not modified
+	assert.Equal(t, 0.001, price.InputCostPerToken)
+	assert.Same(t, price, price.ForPromptTokens(100))
+}
+
+func TestCalculateTokenCosts_TieredPricingReplacesAbove200k(t *testing.T) {
+	price := &ModelPrice{
+		InputCostPerToken:          0.001,
+		InputCostPerTokenAbove200k: 0.0005,
+		TieredPricing:              []PriceTier{{AboveInputTokens: 200_000, InputCostPerToken: 0.002}},
+	}
+	costs := CalculateTokenCosts(&converter.TokenUsage{PromptTokens: 300_000}, price)
+	require.NotNil(t, costs)
+	assert.InDelta(t, 600.0, costs.InputCost, 1e-6)
+}
+
+func TestModelPrice_TieredPricingJSON(t *testing.T) {
+	var price ModelPrice
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"input_cost_per_token": 0.00000125,
+		"output_cost_per_token": 0.00001,
+		"tiered_pricing": [{"above_input_tokens": 200000, "input_cost_per_token": 0.0000025, "output_cost_per_token": 0.000015}]
+	}`), &price))
+	require.Len(t, price.TieredPricing, 1)
+	assert.Equal(t, PriceTier{AboveInputTokens: 200000, InputCostPerToken: 0.0000025, OutputCostPerToken: 0.000015}, price.TieredPricing[0])
+}
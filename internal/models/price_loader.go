@@ -161,9 +161,10 @@ func loadFromHTTP(link string) ([]byte, error) {
 //   - "openai/gpt-4-turbo" -> "gpt-4-turbo"
 //   - "anthropic.claude/claude-3-opus" -> "claude-3-opus"
 //   - "vertex/gemini-1.5-pro" -> "gemini-1.5-pro"
+//   - "us.anthropic.claude-3-haiku" -> "claude-3-haiku"
 //   - "claude-sonnet" -> "claude-sonnet"
 //
-// Versions are preserved (gpt-4-turbo stays gpt-4-turbo)
+// Versions are preserved (gpt-4-turbo stays gpt-4-turbo, gpt-4.1 stays gpt-4.1)
 func NormalizeModelName(fullName string) string {
 	// Trim whitespace
 	fullName = strings.TrimSpace(fullName)
@@ -172,14 +173,31 @@ func NormalizeModelName(fullName string) string {
 	parts := strings.Split(fullName, "/")
 	modelName := parts[len(parts)-1]
 
-	// Split by '.' to remove namespace prefixes (e.g., "anthropic.claude" -> take last part)
-	// But keep the model name intact
-	if len(parts) == 1 && strings.Contains(fullName, ".") {
-		// Format like "anthropic.claude/model" was not found, try splitting by dot
-		dotParts := strings.Split(fullName, ".")
-		modelName = dotParts[len(dotParts)-1]
+	// Remove dot-separated namespace prefixes (e.g., "anthropic.claude" -> "claude"); only
+	// all-letter segments are namespaces, so version dots (gpt-4.1) are kept
+	if len(parts) == 1 {
+		for {
+			namespace, rest, found := strings.Cut(modelName, ".")
+			if !found || rest == "" || !isLetters(namespace) {
+				break
+			}
+			modelName = rest
+		}
 	}
 
 	// Convert to lowercase for case-insensitive matching
 	return strings.ToLower(modelName)
 }
+
+// isLetters reports whether s is a non-empty run of ASCII letters
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
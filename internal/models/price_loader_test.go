@@ -1,9 +1,12 @@
 package models
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNormalizeModelName(t *testing.T) {
@@ -58,9 +61,19 @@ func TestNormalizeModelName(t *testing.T) {
 			expected: "",
 		},
 		{
-			name:     "dot splits without slash takes last segment",
+			name:     "version dots without slash are kept",
 			input:    "claude-3.5-sonnet@20241022",
-			expected: "5-sonnet@20241022",
+			expected: "claude-3.5-sonnet@20241022",
+		},
+		{
+			name:     "bedrock region and vendor namespaces",
+			input:    "us.anthropic.claude-3-haiku-20240307-v1:0",
+			expected: "claude-3-haiku-20240307-v1:0",
+		},
+		{
+			name:     "dotted version",
+			input:    "gpt-4.1",
+			expected: "gpt-4.1",
 		},
 		{
 			name:     "slash preserves dots in model name",
@@ -137,3 +150,63 @@ func TestHasPathTraversal(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadModelPrices_MaxInputTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	data := `{
+		"sample_spec": {"max_input_tokens": "max input tokens, if the provider specifies it", "input_cost_per_token": 0},
+		"openai/gpt-4o": {"max_input_tokens": 128000, "input_cost_per_token": 0.0000025},
+		"gpt-4o-mini": {"input_cost_per_token": 0.00000015}
+	}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	prices, err := LoadModelPrices(path)
+	require.NoError(t, err)
+	assert.Equal(t, TokenLimit(0), prices["sample_spec"].MaxInputTokens)
+	assert.Equal(t, TokenLimit(128000), prices["gpt-4o"].MaxInputTokens)
+	assert.Equal(t, TokenLimit(0), prices["gpt-4o-mini"].MaxInputTokens)
+}
+
+func TestLoadModelPrices_SearchContextCost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	data := `{
+		"sample_spec": {"search_context_cost_per_query": {"search_context_size_medium": "cost per query"}},
+		"claude-sonnet-4-5": {"search_context_cost_per_query": {"search_context_size_low": 0.01, "search_context_size_medium": 0.01, "search_context_size_high": 0.01}},
+		"gpt-4o-search-preview": {"search_context_cost_per_query": {"search_context_size_low": 0.03, "search_context_size_medium": 0.035, "search_context_size_high": 0.05}},
+		"custom-search": {"search_context_cost_per_query": 0.02}
+	}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	prices, err := LoadModelPrices(path)
+	require.NoError(t, err)
+	assert.Equal(t, SearchQueryCost(0), prices["sample_spec"].SearchContextCostPerQuery)
+	assert.Equal(t, SearchQueryCost(0.01), prices["claude-sonnet-4-5"].SearchContextCostPerQuery)
+	assert.Equal(t, SearchQueryCost(0.035), prices["gpt-4o-search-preview"].SearchContextCostPerQuery, "medium context size")
+	assert.Equal(t, SearchQueryCost(0.02), prices["custom-search"].SearchContextCostPerQuery)
+}
+
+func TestLoadModelPrices_Capabilities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	data := `{
+		"sample_spec": {"mode": "one of: chat, embedding", "supports_vision": "true or false", "max_output_tokens": "max output tokens"},
+		"gpt-4o": {"mode": "chat", "max_output_tokens": 16384, "supports_function_calling": true, "supports_vision": true, "supports_audio_input": false},
+		"text-embedding-3-small": {"mode": "embedding"}
+	}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	prices, err := LoadModelPrices(path)
+	require.NoError(t, err)
+
+	gpt := prices["gpt-4o"]
+	assert.Equal(t, "chat", gpt.Mode)
+	assert.Equal(t, TokenLimit(16384), gpt.MaxOutputTokens)
+	require.NotNil(t, gpt.SupportsFunctionCalling)
+	assert.True(t, bool(*gpt.SupportsFunctionCalling))
+	require.NotNil(t, gpt.SupportsAudioInput)
+	assert.False(t, bool(*gpt.SupportsAudioInput))
+	assert.Nil(t, gpt.SupportsPDFInput, "unlisted capabilities are unknown")
+
+	require.NotNil(t, prices["sample_spec"].SupportsVision)
+	assert.False(t, bool(*prices["sample_spec"].SupportsVision), "non-boolean values decode as false")
+	assert.Nil(t, prices["text-embedding-3-small"].SupportsVision)
+}
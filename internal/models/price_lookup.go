@@ -0,0 +1,134 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxResolvedPrices bounds the cache of resolved model names (model names come from clients)
+const maxResolvedPrices = 10000
+
+// versionSuffixes are stripped one at a time from a model name without a price of its own:
+// release dates (gpt-4o-2024-11-20, claude-3-5-sonnet@20240620, gpt-4-0613), Bedrock
+// versions (-v1:0), Gemini revisions (-001) and -latest
+var versionSuffixes = []*regexp.Regexp{
+	regexp.MustCompile(`-v\d+(:\d+)?$`),
+	regexp.MustCompile(`[-@]\d{4}-\d{2}-\d{2}$`),
+	regexp.MustCompile(`[-@]\d{8}$`),
+	regexp.MustCompile(`[-@]\d{2}-\d{2}$`),
+	regexp.MustCompile(`[-@]\d{3,4}$`),
+	regexp.MustCompile(`[-@]latest$`),
+}
+
+// SetMapping sets the explicit model name -> price entry mapping (server.model_price_mapping),
+// checked before any other resolution
+func (r *ModelPriceRegistry) SetMapping(mapping map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mapping = make(map[string]string, len(mapping))
+	for model, priceModel := range mapping {
+		r.mapping[strings.ToLower(strings.TrimSpace(model))] = NormalizeModelName(priceModel)
+	}
+	r.resetResolved()
+}
+
+// ResolvePrice returns the price of a model and the price entry it was found under (nil and
+// "" if none). Names without an exact entry are resolved, in order, by model_price_mapping,
+// by the normalized name (provider and vertex_ai/ prefixes and Bedrock namespaces removed),
+// by the normalized name without its date/version suffixes and finally by the longest price
+// entry the name starts with (gpt-4o-mini-custom -> gpt-4o-mini). Resolutions are cached
+// until the next Update.
+func (r *ModelPriceRegistry) ResolvePrice(modelName string) (*ModelPrice, string) {
+	r.mu.RLock()
+	if price, ok := r.prices[modelName]; ok {
+		r.mu.RUnlock()
+		return price, modelName
+	}
+	key, cached := r.resolved[modelName]
+	if !cached {
+		key = r.resolvePriceKey(modelName)
+	}
+	price := r.prices[key]
+	generation := r.generation
+	r.mu.RUnlock()
+
+	if !cached {
+		r.mu.Lock()
+		// Prices or mapping changed since the lookup: the resolution may be stale
+		if r.generation == generation {
+			if len(r.resolved) >= maxResolvedPrices {
+				r.resetResolved()
+			}
+			r.resolved[modelName] = key
+		}
+		r.mu.Unlock()
+	}
+	if price == nil {
+		return nil, ""
+	}
+	return price, key
+}
+
+// resolvePriceKey finds the price entry of a model name without an exact entry ("" = none).
+// Must be called with r.mu held.
+func (r *ModelPriceRegistry) resolvePriceKey(modelName string) string {
+	if target, ok := r.mapping[strings.ToLower(strings.TrimSpace(modelName))]; ok {
+		if _, found := r.prices[target]; found {
+			return target
+		}
+	}
+
+	name := NormalizeModelName(modelName)
+	if name == "" {
+		return ""
+	}
+	for {
+		if _, found := r.prices[name]; found {
+			return name
+		}
+		stripped := stripVersionSuffix(name)
+		if stripped == name {
+			break
+		}
+		name = stripped
+	}
+
+	for _, key := range r.keys {
+		if len(key) < len(name) && strings.HasPrefix(name, key) && (name[len(key)] == '-' || name[len(key)] == '@') {
+			return key
+		}
+	}
+	return ""
+}
+
+// resetResolved clears the cache of resolved model names. Must be called with r.mu locked.
+func (r *ModelPriceRegistry) resetResolved() {
+	r.resolved = make(map[string]string)
+	r.generation++
+}
+
+// stripVersionSuffix removes the first matching suffix of versionSuffixes
+func stripVersionSuffix(name string) string {
+	for _, suffix := range versionSuffixes {
+		if loc := suffix.FindStringIndex(name); loc != nil && loc[0] > 0 {
+			return name[:loc[0]]
+		}
+	}
+	return name
+}
+
+// sortedPriceKeys returns the keys of prices, longest first
+func sortedPriceKeys(prices map[string]*ModelPrice) []string {
+	keys := make([]string, 0, len(prices))
+	for key := range prices {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLookupTestRegistry() *ModelPriceRegistry {
+	registry := NewModelPriceRegistry()
+	registry.Update(map[string]*ModelPrice{
+		"gpt-4o":            {InputCostPerToken: 1},
+		"gpt-4o-mini":       {InputCostPerToken: 2},
+		"claude-3-5-sonnet": {InputCostPerToken: 3},
+		"gemini-1.5-pro":    {InputCostPerToken: 4},
+		"gpt-4":             {InputCostPerToken: 5},
+	})
+	return registry
+}
+
+func TestModelPriceRegistry_ResolvePrice(t *testing.T) {
+	registry := newLookupTestRegistry()
+
+	tests := []struct {
+		model string
+		want  string // Price entry, "" = unresolved
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"gpt-4o-2024-11-20", "gpt-4o"},
+		{"GPT-4o", "gpt-4o"},
+		{"openai/gpt-4o-mini", "gpt-4o-mini"},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini"},
+		{"vertex_ai/gemini-1.5-pro-002", "gemini-1.5-pro"},
+		{"claude-3-5-sonnet@20240620", "claude-3-5-sonnet"},
+		{"us.anthropic.claude-3-5-sonnet-20241022-v2:0", "claude-3-5-sonnet"},
+		{"gpt-4-0613", "gpt-4"},
+		{"gpt-4o-mini-custom", "gpt-4o-mini"},
+		{"gpt-4oxyz", ""},
+		{"llama-3-70b", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			price, key := registry.ResolvePrice(tt.model)
+			assert.Equal(t, tt.want, key)
+			if tt.want == "" {
+				assert.Nil(t, price)
+				assert.Nil(t, registry.GetPrice(tt.model), "cached resolution")
+			} else {
+				require.NotNil(t, price)
+				assert.Same(t, price, registry.GetPrice(tt.model), "cached resolution")
+			}
+		})
+	}
+}
+
+func TestModelPriceRegistry_Mapping(t *testing.T) {
+	registry := newLookupTestRegistry()
+	assert.Nil(t, registry.GetPrice("my-finetune"))
+
+	registry.SetMapping(map[string]string{"My-Finetune": "openai/gpt-4o-mini", "broken": "missing-model"})
+	_, key := registry.ResolvePrice("my-finetune")
+	assert.Equal(t, "gpt-4o-mini", key, "mapping resets cached misses")
+	assert.Nil(t, registry.GetPrice("broken"), "mapping to an unknown entry")
+
+	// Update resets the cache: a resolved model gets its own entry
+	_, key = registry.ResolvePrice("gpt-4o-2024-11-20")
+	assert.Equal(t, "gpt-4o", key)
+	registry.Update(map[string]*ModelPrice{"gpt-4o": {}, "gpt-4o-2024-11-20": {}})
+	_, key = registry.ResolvePrice("gpt-4o-2024-11-20")
+	assert.Equal(t, "gpt-4o-2024-11-20", key)
+}
+
+func TestModelPriceRegistry_ConcurrentLookups(t *testing.T) {
+	registry := newLookupTestRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				assert.NotNil(t, registry.GetPrice(fmt.Sprintf("gpt-4o-mini-%d", j)))
+				assert.Nil(t, registry.GetPrice(fmt.Sprintf("unknown-%d-%d", i, j)))
+				if j%50 == 0 {
+					registry.Update(map[string]*ModelPrice{"gpt-4o-mini": {}})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
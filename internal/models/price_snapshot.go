@@ -0,0 +1,149 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PriceSnapshot is the last synced model prices, kept on disk for restarts while the price
+// source is down
+type PriceSnapshot struct {
+	SyncedAt time.Time              `json:"synced_at"`
+	Prices   map[string]*ModelPrice `json:"prices"` // Normalized model name -> price
+}
+
+// PriceSyncStatus describes the prices the registry currently uses
+type PriceSyncStatus struct {
+	Models    int
+	LastSync  time.Time // Zero if prices were never loaded
+	Restored  bool      // Prices come from a snapshot saved by an earlier process
+	LastError string    // Error of the last failed sync ("" after a successful one)
+}
+
+// Age returns the time since the prices were synced at now (0 if never)
+func (s PriceSyncStatus) Age(now time.Time) time.Duration {
+	if s.LastSync.IsZero() {
+		return 0
+	}
+	return now.Sub(s.LastSync)
+}
+
+// Snapshot returns the current prices and the time they were synced
+func (r *ModelPriceRegistry) Snapshot() PriceSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	prices := make(map[string]*ModelPrice, len(r.prices))
+	for k, v := range r.prices {
+		prices[k] = v
+	}
+	return PriceSnapshot{SyncedAt: r.lastUpdate, Prices: prices}
+}
+
+// Restore loads a snapshot saved by SavePriceSnapshot. The sync time of the snapshot is kept,
+// so the price age reflects when the prices were fetched.
+func (r *ModelPriceRegistry) Restore(snapshot PriceSnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setPrices(snapshot.Prices)
+	r.lastUpdate = snapshot.SyncedAt
+	r.restored = true
+}
+
+// RecordSyncError records a failed price sync; the current prices are kept
+func (r *ModelPriceRegistry) RecordSyncError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastError = err.Error()
+}
+
+// SyncStatus returns the state of the prices
+func (r *ModelPriceRegistry) SyncStatus() PriceSyncStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return PriceSyncStatus{
+		Models:    len(r.prices),
+		LastSync:  r.lastUpdate,
+		Restored:  r.restored,
+		LastError: r.lastError,
+	}
+}
+
+// HadPrice reports whether a model without a price now was priced by earlier prices, i.e. a
+// new price list dropped it
+func (r *ModelPriceRegistry) HadPrice(modelName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lost[modelName] || r.lost[NormalizeModelName(modelName)]
+}
+
+// trackLostPrices records the price keys and resolved model names of the current prices that
+// next no longer prices. Must be called with r.mu held.
+func (r *ModelPriceRegistry) trackLostPrices(next map[string]*ModelPrice) {
+	if len(r.lost) >= maxResolvedPrices {
+		r.lost = make(map[string]bool)
+	}
+	for key := range r.prices {
+		if next[key] == nil {
+			r.lost[key] = true
+		}
+	}
+	for model, key := range r.resolved {
+		if key != "" && next[key] == nil {
+			r.lost[model] = true
+		}
+	}
+	for key := range next {
+		delete(r.lost, key)
+	}
+}
+
+// SavePriceSnapshot writes snapshot to path as JSON. The file is replaced atomically, so a
+// crash while saving leaves the previous snapshot intact.
+func SavePriceSnapshot(path string, snapshot PriceSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode model prices snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create model prices snapshot: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write model prices snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write model prices snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace model prices snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadPriceSnapshot reads a snapshot written by SavePriceSnapshot. A missing file returns
+// (nil, nil).
+func LoadPriceSnapshot(path string) (*PriceSnapshot, error) {
+	data, err := loadFromFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot PriceSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse model prices snapshot: %w", err)
+	}
+	if len(snapshot.Prices) == 0 {
+		return nil, fmt.Errorf("model prices snapshot has no prices")
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceSnapshot_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	registry := NewModelPriceRegistry()
+	registry.Update(map[string]*ModelPrice{"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002}})
+
+	require.NoError(t, SavePriceSnapshot(path, registry.Snapshot()))
+	snapshot, err := LoadPriceSnapshot(path)
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.WithinDuration(t, registry.LastUpdate(), snapshot.SyncedAt, time.Millisecond)
+	require.Contains(t, snapshot.Prices, "gpt-4o")
+	assert.Equal(t, 0.002, snapshot.Prices["gpt-4o"].OutputCostPerToken)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left behind")
+}
+
+func TestPriceSnapshot_LoadMissingOrEmpty(t *testing.T) {
+	dir := t.TempDir()
+	snapshot, err := LoadPriceSnapshot(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(empty, []byte(`{"synced_at":"2026-01-01T00:00:00Z","prices":{}}`), 0o644))
+	_, err = LoadPriceSnapshot(empty)
+	assert.Error(t, err)
+
+	broken := filepath.Join(dir, "broken.json")
+	require.NoError(t, os.WriteFile(broken, []byte(`{`), 0o644))
+	_, err = LoadPriceSnapshot(broken)
+	assert.Error(t, err)
+}
+
+func TestModelPriceRegistry_RestoreAndSyncStatus(t *testing.T) {
+	registry := NewModelPriceRegistry()
+	assert.Zero(t, registry.SyncStatus().Age(time.Now()), "never synced")
+
+	syncedAt := time.Now().Add(-2 * time.Hour)
+	registry.Restore(PriceSnapshot{SyncedAt: syncedAt, Prices: map[string]*ModelPrice{"gpt-4o": {InputCostPerToken: 1}}})
+	registry.RecordSyncError(errors.New("connection refused"))
+
+	status := registry.SyncStatus()
+	assert.Equal(t, 1, status.Models)
+	assert.True(t, status.Restored)
+	assert.Equal(t, syncedAt, status.LastSync)
+	assert.Equal(t, "connection refused", status.LastError)
+	assert.InDelta(t, 2*time.Hour, status.Age(time.Now()), float64(time.Minute))
+	assert.NotNil(t, registry.GetPrice("gpt-4o"))
+
+	registry.Update(map[string]*ModelPrice{"gpt-4o": {InputCostPerToken: 2}})
+	status = registry.SyncStatus()
+	assert.False(t, status.Restored)
+	assert.Empty(t, status.LastError)
+	assert.WithinDuration(t, time.Now(), status.LastSync, time.Minute)
+}
+
+func TestModelPriceRegistry_HadPrice(t *testing.T) {
+	registry := NewModelPriceRegistry()
+	registry.Update(map[string]*ModelPrice{
+		"gpt-4o":      {InputCostPerToken: 1},
+		"gpt-4o-mini": {InputCostPerToken: 2},
+	})
+	require.NotNil(t, registry.GetPrice("gpt-4o-2024-11-20"))
+	assert.False(t, registry.HadPrice("gpt-4o"))
+
+	registry.Update(map[string]*ModelPrice{"gpt-4o-mini": {InputCostPerToken: 2}})
+	assert.True(t, registry.HadPrice("gpt-4o"))
+	assert.True(t, registry.HadPrice("GPT-4o"))
+	assert.True(t, registry.HadPrice("gpt-4o-2024-11-20"), "resolved model names are tracked")
+	assert.False(t, registry.HadPrice("gpt-4o-mini"))
+	assert.False(t, registry.HadPrice("unknown-model"))
+
+	registry.Update(map[string]*ModelPrice{"gpt-4o": {InputCostPerToken: 3}})
+	assert.False(t, registry.HadPrice("gpt-4o"), "a price added back is no longer lost")
+}
@@ -2,16 +2,74 @@ package modelupdate
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"math/rand"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/balancer"
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/proxyhealth"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
+const (
+	// UpdateInterval is the base interval between proxy model updates
+	UpdateInterval = 30 * time.Second
+
+	// updateJitterFraction spreads updates of many routers over ±10% of the interval,
+	// so downstream proxies are not polled by all upstream routers at the same moment
+	updateJitterFraction = 0.1
+)
+
+// proxyFetchTimeout bounds a single proxy fetch, so one slow downstream router
+// cannot delay the sync of the others. Variable for tests.
+var proxyFetchTimeout = 5 * time.Second
+
+// syncState tracks the last successful sync per proxy credential for the staleness metric
+var syncState = struct {
+	mu          sync.Mutex
+	lastSuccess map[string]time.Time
+	firstSeen   map[string]time.Time
+}{
+	lastSuccess: make(map[string]time.Time),
+	firstSeen:   make(map[string]time.Time),
+}
+
+// NextUpdateDelay returns base with random jitter of ±10% applied
+func NextUpdateDelay(base time.Duration) time.Duration {
+	jitter := time.Duration((rand.Float64()*2 - 1) * updateJitterFraction * float64(base))
+	return base + jitter
+}
+
+// recordSyncResult stores a sync attempt and updates the staleness gauge for the credential.
+// A proxy that never synced successfully reports the time since it was first seen.
+func recordSyncResult(credentialName string, success bool, now time.Time) {
+	syncState.mu.Lock()
+	defer syncState.mu.Unlock()
+
+	if _, ok := syncState.firstSeen[credentialName]; !ok {
+		syncState.firstSeen[credentialName] = now
+	}
+	if success {
+		syncState.lastSuccess[credentialName] = now
+	}
+
+	last, ok := syncState.lastSuccess[credentialName]
+	if !ok {
+		last = syncState.firstSeen[credentialName]
+	}
+	monitoring.ProxyModelsSyncStaleness.WithLabelValues(credentialName).Set(now.Sub(last).Seconds())
+}
+
 // UpdateAllProxyCredentials fetches the latest models from all proxy credentials
 // and updates the balancer, rate limiter, and model manager with the results.
 // This function is designed to be called periodically in a background goroutine.
@@ -23,6 +81,10 @@ import (
 //   - log: Logger for operation details
 //   - modelManager: Model manager for storing fetched models
 //   - updateMutex: Synchronizes updates (prevents race conditions with metrics)
+//   - proxyHealth: Receives the /health reports of downstream routers (nil = not polled)
+//
+// Each proxy is fetched in its own goroutine with an individual timeout (proxyFetchTimeout);
+// results are applied as they arrive, holding updateMutex only while applying one proxy's models.
 func UpdateAllProxyCredentials(
 	ctx context.Context,
 	bal *balancer.RoundRobin,
@@ -30,6 +92,7 @@ func UpdateAllProxyCredentials(
 	log *slog.Logger,
 	modelManager *models.Manager,
 	updateMutex *sync.Mutex,
+	proxyHealth *proxyhealth.Tracker,
 ) {
 	// Get all proxy credentials
 	credentials := bal.GetCredentialsSnapshot()
@@ -49,6 +112,9 @@ func UpdateAllProxyCredentials(
 	type proxyResult struct {
 		credential *config.CredentialConfig
 		models     []models.Model
+		federation *httputil.FederationResponse  // nil if the downstream router doesn't expose it
+		health     *httputil.ProxyHealthResponse // nil if /health is not polled, unreachable or not a router
+		report     *httputil.DownstreamHealth    // nil if the proxy doesn't expose router health
 		err        error
 	}
 
@@ -60,12 +126,38 @@ func UpdateAllProxyCredentials(
 		go func(c *config.CredentialConfig) {
 			defer wg.Done()
 
-			// Fetch models from proxy
-			remoteModels, err := modelManager.GetRemoteModelsWithError(ctx, c)
+			// Fetch models from proxy with an individual timeout
+			fetchCtx, cancel := context.WithTimeout(ctx, proxyFetchTimeout)
+			defer cancel()
+			remoteModels, err := modelManager.GetRemoteModelsWithError(fetchCtx, c)
+
+			// Downstream auto_ai_router instances report per-model headroom.
+			// Other proxies (or older versions) don't have the endpoint, which is not an error.
+			var federation *httputil.FederationResponse
+			if err == nil {
+				// Discard fetch errors: a 404 from a non-router proxy would be logged every update
+				var resp httputil.FederationResponse
+				if fedErr := httputil.FetchJSONFromProxy(fetchCtx, c, httputil.FederationPath, slog.New(slog.DiscardHandler), &resp); fedErr == nil {
+					federation = &resp
+				} else {
+					log.Debug("Federation limits not available from proxy", "credential", c.Name, "error", fedErr)
+				}
+			}
+
+			var health *httputil.ProxyHealthResponse
+			var report *httputil.DownstreamHealth
+			if proxyHealth != nil {
+				healthCtx, cancelHealth := context.WithTimeout(ctx, proxyFetchTimeout)
+				health, report = fetchDownstreamHealth(healthCtx, c)
+				cancelHealth()
+			}
 
 			resultsChan <- proxyResult{
 				credential: c,
 				models:     remoteModels,
+				federation: federation,
+				health:     health,
+				report:     report,
 				err:        err,
 			}
 		}(cred)
@@ -82,6 +174,11 @@ func UpdateAllProxyCredentials(
 	failedCount := 0
 
 	for result := range resultsChan {
+		recordSyncResult(result.credential.Name, result.err == nil, utils.NowUTC())
+		if result.report != nil {
+			recordDownstreamHealth(result.credential.Name, *result.report, proxyHealth, log)
+		}
+
 		if result.err != nil {
 			log.Warn("Failed to fetch models from proxy",
 				"credential", result.credential.Name,
@@ -91,24 +188,35 @@ func UpdateAllProxyCredentials(
 			continue
 		}
 
-		// Update rate limiter and model manager with fetched models
-		addedCount := 0
-		updateMutex.Lock()
-		for _, model := range result.models {
-			// Get default RPM/TPM from model manager
-			modelRPM := modelManager.GetModelRPMForCredential(model.ID, result.credential.Name)
-			modelTPM := modelManager.GetModelTPMForCredential(model.ID, result.credential.Name)
+		// Update rate limiter and model manager with fetched models. The lock is released by
+		// defer, so a panic recovered by the worker supervisor does not leave it held.
+		addedCount := func() int {
+			updateMutex.Lock()
+			defer updateMutex.Unlock()
 
-			// AddModelWithTPM handles duplicates internally (overwrites existing)
-			rateLimiter.AddModelWithTPM(result.credential.Name, model.ID, modelRPM, modelTPM)
+			addedCount := 0
+			for _, model := range result.models {
+				// Get default RPM/TPM from model manager
+				modelRPM := modelManager.GetModelRPMForCredential(model.ID, result.credential.Name)
+				modelTPM := modelManager.GetModelTPMForCredential(model.ID, result.credential.Name)
+				modelBurst := modelManager.GetModelRPMBurstForCredential(model.ID, result.credential.Name)
 
-			// Register model in manager so HasModel() returns true for this credential.
-			// Without this the balancer's model checker always rejects proxy credentials
-			// because modelToCredentials is only populated at startup via GetAllModels().
-			modelManager.AddModel(result.credential.Name, model.ID)
-			addedCount++
-		}
-		updateMutex.Unlock()
+				// AddModelWithBurst handles duplicates internally (overwrites existing)
+				rateLimiter.AddModelWithBurst(result.credential.Name, model.ID, modelRPM, modelTPM, modelBurst)
+
+				// Register model in manager so HasModel() returns true for this credential.
+				// Without this the balancer's model checker always rejects proxy credentials
+				// because modelToCredentials is only populated at startup via GetAllModels().
+				modelManager.AddModel(result.credential.Name, model.ID)
+				addedCount++
+			}
+			if result.federation != nil {
+				applyFederationLimits(result.credential, result.federation, rateLimiter, modelManager)
+			} else if result.health != nil {
+				applyHealthLimits(result.credential, result.health, rateLimiter, modelManager)
+			}
+			return addedCount
+		}()
 
 		if addedCount > 0 {
 			log.Info("Updated proxy models",
@@ -134,6 +242,248 @@ func UpdateAllProxyCredentials(
 	}
 }
 
+// PruneStaleModels evicts the models proxy credentials stopped reporting: models not seen for
+// ttl are removed from the model manager and the rate limiter, so they no longer show up in
+// /health. Credentials without a successful sync within ttl are skipped, so an unreachable
+// proxy keeps its models until it answers again. Returns the number of evicted models.
+func PruneStaleModels(
+	bal *balancer.RoundRobin,
+	rateLimiter *ratelimit.RPMLimiter,
+	modelManager *models.Manager,
+	updateMutex *sync.Mutex,
+	ttl time.Duration,
+	log *slog.Logger,
+) int {
+	now := utils.NowUTC()
+	cutoff := now.Add(-ttl)
+
+	syncState.mu.Lock()
+	lastSuccess := make(map[string]time.Time, len(syncState.lastSuccess))
+	for name, last := range syncState.lastSuccess {
+		lastSuccess[name] = last
+	}
+	syncState.mu.Unlock()
+
+	evicted := 0
+	for _, cred := range bal.GetCredentialsSnapshot() {
+		if cred.Type != config.ProviderTypeProxy {
+			continue
+		}
+		if last, ok := lastSuccess[cred.Name]; !ok || last.Before(cutoff) {
+			continue
+		}
+
+		removed := func() []string {
+			updateMutex.Lock()
+			defer updateMutex.Unlock()
+			removed := modelManager.PruneStaleModels(cred.Name, cutoff)
+			for _, modelID := range removed {
+				rateLimiter.RemoveModel(cred.Name, modelID)
+			}
+			return removed
+		}()
+
+		if len(removed) > 0 {
+			monitoring.StaleModelsEvictedTotal.WithLabelValues(cred.Name).Add(float64(len(removed)))
+			log.Info("Evicted stale proxy models",
+				"credential", cred.Name,
+				"models", removed,
+				"ttl", ttl,
+			)
+			evicted += len(removed)
+		}
+	}
+	return evicted
+}
+
+// applyFederationLimits lowers model limits of a proxy credential to the aggregated limits
+// reported by the downstream router, and syncs usage so that remaining headroom matches.
+// The balancer then stops selecting the proxy for a model as soon as the downstream is exhausted.
+// Reported models are filtered and renamed by the credential's proxy_models.
+func applyFederationLimits(
+	cred *config.CredentialConfig,
+	federation *httputil.FederationResponse,
+	rateLimiter *ratelimit.RPMLimiter,
+	modelManager *models.Manager,
+) {
+	credentialName := cred.Name
+	for upstreamID, stats := range federation.Models {
+		modelID, ok := cred.ProxyModels.Expose(upstreamID)
+		if !ok {
+			continue
+		}
+		addImportedLimits(rateLimiter, modelManager, credentialName, modelID, stats.LimitRPM, stats.LimitTPM)
+
+		usedRPM := 0
+		if stats.LimitRPM >= 0 && stats.RemainingRPM >= 0 {
+			usedRPM = stats.LimitRPM - stats.RemainingRPM
+		}
+		usedTPM := 0
+		if stats.LimitTPM >= 0 && stats.RemainingTPM >= 0 {
+			usedTPM = stats.LimitTPM - stats.RemainingTPM
+		}
+		rateLimiter.SetModelCurrentUsage(credentialName, modelID, usedRPM, usedTPM)
+
+		modelManager.AddModel(credentialName, modelID)
+	}
+}
+
+// addImportedLimits sets the model limits of a proxy credential from limits a downstream
+// router reported. Imported limits only ever lower the configured limits of the model, so a
+// downstream cannot lift the caps of its parent; the configured RPM burst is kept unless the
+// downstream RPM is stricter.
+func addImportedLimits(rateLimiter *ratelimit.RPMLimiter, modelManager *models.Manager, credentialName, modelID string, importedRPM, importedTPM int) {
+	localRPM := modelManager.GetModelRPMForCredential(modelID, credentialName)
+	localTPM := modelManager.GetModelTPMForCredential(modelID, credentialName)
+
+	rpm := stricterLimit(localRPM, importedRPM)
+	burst := 0
+	if rpm == localRPM {
+		burst = modelManager.GetModelRPMBurstForCredential(modelID, credentialName)
+	}
+	rateLimiter.AddModelWithBurst(credentialName, modelID, rpm, stricterLimit(localTPM, importedTPM), burst)
+}
+
+// stricterLimit returns the lower of two limits; values <= 0 are unlimited
+func stricterLimit(local, imported int) int {
+	if imported <= 0 {
+		return local
+	}
+	if local <= 0 || imported < local {
+		return imported
+	}
+	return local
+}
+
+// fetchDownstreamHealth polls the /health endpoint of a proxy credential. A 503 or 5xx answer
+// makes the downstream unhealthy and a network error or timeout unreachable. Proxies without
+// router health (4xx, a body that is not a router health report) return a nil report and are
+// never marked unhealthy. health is the parsed report of a healthy downstream.
+func fetchDownstreamHealth(ctx context.Context, cred *config.CredentialConfig) (*httputil.ProxyHealthResponse, *httputil.DownstreamHealth) {
+	report := &httputil.DownstreamHealth{CheckedAt: utils.NowUTC()}
+
+	// Discard fetch errors: they are reported once per status change by recordDownstreamHealth
+	body, err := httputil.FetchFromProxy(ctx, cred, "/health", slog.New(slog.DiscardHandler))
+	if err != nil {
+		var statusErr *httputil.StatusError
+		switch {
+		case errors.As(err, &statusErr) && statusErr.StatusCode < http.StatusInternalServerError:
+			return nil, nil
+		case errors.As(err, &statusErr):
+			report.Status = httputil.DownstreamUnhealthy
+		default:
+			report.Status = httputil.DownstreamUnreachable
+		}
+		report.Error = err.Error()
+		return nil, report
+	}
+
+	var health httputil.ProxyHealthResponse
+	if err := json.Unmarshal(body, &health); err != nil {
+		return nil, nil
+	}
+	if health.Status != httputil.DownstreamHealthy && health.Status != httputil.DownstreamUnhealthy {
+		return nil, nil
+	}
+	report.Status = health.Status
+	report.CredentialsAvailable = health.CredentialsAvailable
+	report.TotalCredentials = health.TotalCredentials
+	return &health, report
+}
+
+// recordDownstreamHealth stores a /health report and logs status changes of the downstream
+func recordDownstreamHealth(credentialName string, report httputil.DownstreamHealth, proxyHealth *proxyhealth.Tracker, log *slog.Logger) {
+	previous, known := proxyHealth.Report(credentialName)
+	proxyHealth.RecordHealth(credentialName, report)
+
+	healthy := report.Status == httputil.DownstreamHealthy
+	if healthy {
+		monitoring.ProxyDownstreamHealthy.WithLabelValues(credentialName).Set(1)
+	} else {
+		monitoring.ProxyDownstreamHealthy.WithLabelValues(credentialName).Set(0)
+	}
+
+	switch {
+	case !healthy && (!known || previous.Status != report.Status):
+		log.Warn("Downstream router is not healthy, proxy credential is skipped",
+			"credential", credentialName,
+			"status", report.Status,
+			"credentials_available", report.CredentialsAvailable,
+			"error", report.Error,
+		)
+	case healthy && known && previous.Status != report.Status:
+		log.Info("Downstream router recovered",
+			"credential", credentialName,
+			"credentials_available", report.CredentialsAvailable,
+		)
+	}
+}
+
+// applyHealthLimits is the fallback of applyFederationLimits for downstream routers without
+// the federation endpoint: model limits and usage are summed over the downstream credentials
+// reported on /health, skipping banned ones
+func applyHealthLimits(
+	cred *config.CredentialConfig,
+	health *httputil.ProxyHealthResponse,
+	rateLimiter *ratelimit.RPMLimiter,
+	modelManager *models.Manager,
+) {
+	credentialName := cred.Name
+	type modelHeadroom struct {
+		available              int
+		limitRPM, limitTPM     int
+		currentRPM, currentTPM int
+		unlimitedRPM           bool
+		unlimitedTPM           bool
+	}
+	headroom := make(map[string]*modelHeadroom)
+	for _, stats := range health.Models {
+		modelID, exposed := cred.ProxyModels.Expose(stats.Model)
+		if !exposed {
+			continue
+		}
+		h, ok := headroom[modelID]
+		if !ok {
+			h = &modelHeadroom{}
+			headroom[modelID] = h
+		}
+		if stats.IsBanned {
+			continue
+		}
+		h.available++
+		if stats.LimitRPM > 0 {
+			h.limitRPM += stats.LimitRPM
+		} else {
+			h.unlimitedRPM = true
+		}
+		if stats.LimitTPM > 0 {
+			h.limitTPM += stats.LimitTPM
+		} else {
+			h.unlimitedTPM = true
+		}
+		h.currentRPM += stats.CurrentRPM
+		h.currentTPM += stats.CurrentTPM
+	}
+
+	for modelID, h := range headroom {
+		limitRPM, limitTPM := h.limitRPM, h.limitTPM
+		if h.unlimitedRPM {
+			limitRPM = -1
+		}
+		if h.unlimitedTPM {
+			limitTPM = -1
+		}
+		if h.available == 0 {
+			// Every downstream credential of the model is banned: no headroom left
+			limitRPM, limitTPM = 1, -1
+			h.currentRPM, h.currentTPM = 1, 0
+		}
+		addImportedLimits(rateLimiter, modelManager, credentialName, modelID, limitRPM, limitTPM)
+		rateLimiter.SetModelCurrentUsage(credentialName, modelID, h.currentRPM, h.currentTPM)
+		modelManager.AddModel(credentialName, modelID)
+	}
+}
+
 // SplitCredentialModel parses a "credential:model" format string.
 // Returns a slice of two strings: [credential, model].
 // If the model name contains colons (e.g., "gpt-4o:turbo"), it splits on the first colon only.
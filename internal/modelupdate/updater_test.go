@@ -1,9 +1,27 @@
 package modelupdate
 
 import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/proxyhealth"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSplitCredentialModel(t *testing.T) {
@@ -56,3 +74,274 @@ func TestSplitCredentialModel(t *testing.T) {
 		})
 	}
 }
+
+func TestNextUpdateDelay(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		delay := NextUpdateDelay(30 * time.Second)
+		assert.GreaterOrEqual(t, delay, 27*time.Second)
+		assert.LessOrEqual(t, delay, 33*time.Second)
+	}
+}
+
+func TestUpdateAllProxyCredentials_SlowProxyDoesNotBlockOthers(t *testing.T) {
+	oldTimeout := proxyFetchTimeout
+	proxyFetchTimeout = 200 * time.Millisecond
+	defer func() { proxyFetchTimeout = oldTimeout }()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","object":"model"}]}`))
+	}))
+	defer fast.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	credentials := []config.CredentialConfig{
+		{Name: "update_fast", Type: config.ProviderTypeProxy, BaseURL: fast.URL, RPM: 100},
+		{Name: "update_slow", Type: config.ProviderTypeProxy, BaseURL: slow.URL, RPM: 100},
+	}
+	rl := ratelimit.New()
+	bal := balancer.New(credentials, fail2ban.New(3, 0, []int{500}), rl)
+	modelManager := models.New(logger, 50, []config.ModelRPMConfig{})
+
+	start := time.Now()
+	UpdateAllProxyCredentials(context.Background(), bal, rl, logger, modelManager, &sync.Mutex{}, nil)
+	assert.Less(t, time.Since(start), 2*time.Second, "slow proxy must be bounded by the per-proxy timeout")
+
+	assert.True(t, modelManager.HasModel("update_fast", "gpt-4o"))
+	assert.Less(t, testutil.ToFloat64(monitoring.ProxyModelsSyncStaleness.WithLabelValues("update_fast")), 1.0)
+}
+
+func TestUpdateAllProxyCredentials_ProxyModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","object":"model"},{"id":"gpt-4o-mini","object":"model"},{"id":"o1","object":"model"}]}`))
+		case httputil.FederationPath:
+			_, _ = w.Write([]byte(`{"status":"healthy","models":{"gpt-4o":{"limit_rpm":10,"limit_tpm":-1,"remaining_rpm":4,"remaining_tpm":-1},"o1":{"limit_rpm":5,"limit_tpm":-1,"remaining_rpm":5,"remaining_tpm":-1}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	credentials := []config.CredentialConfig{{
+		Name: "renamed_proxy", Type: config.ProviderTypeProxy, BaseURL: server.URL, RPM: 100,
+		ProxyModels: &config.ProxyModelsConfig{
+			Allow:  []string{"gpt-4o", "gpt-4o-mini"},
+			Deny:   []string{"gpt-4o-mini"},
+			Rename: map[string]string{"gpt-4o": "east/gpt-4o"},
+		},
+	}}
+	rl := ratelimit.New()
+	bal := balancer.New(credentials, fail2ban.New(3, 0, []int{500}), rl)
+	modelManager := models.New(logger, 50, []config.ModelRPMConfig{})
+
+	UpdateAllProxyCredentials(context.Background(), bal, rl, logger, modelManager, &sync.Mutex{}, nil)
+
+	assert.Equal(t, []string{"renamed_proxy"}, modelManager.GetCredentialsForModel("east/gpt-4o"))
+	assert.Empty(t, modelManager.GetCredentialsForModel("gpt-4o"), "renamed model is only exposed under its new name")
+	assert.Empty(t, modelManager.GetCredentialsForModel("gpt-4o-mini"), "deny wins over allow")
+	assert.Empty(t, modelManager.GetCredentialsForModel("o1"), "models outside allow are not exposed")
+	assert.Equal(t, 10, rl.GetModelLimitRPM("renamed_proxy", "east/gpt-4o"))
+	assert.Equal(t, 6, rl.GetCurrentModelRPM("renamed_proxy", "east/gpt-4o"))
+}
+
+func TestRecordSyncResult_Staleness(t *testing.T) {
+	now := time.Now()
+	recordSyncResult("stale_proxy", true, now)
+	assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.ProxyModelsSyncStaleness.WithLabelValues("stale_proxy")))
+
+	recordSyncResult("stale_proxy", false, now.Add(90*time.Second))
+	assert.Equal(t, 90.0, testutil.ToFloat64(monitoring.ProxyModelsSyncStaleness.WithLabelValues("stale_proxy")))
+
+	// Never synced: staleness counts from first attempt
+	recordSyncResult("never_synced", false, now)
+	recordSyncResult("never_synced", false, now.Add(30*time.Second))
+	assert.Equal(t, 30.0, testutil.ToFloat64(monitoring.ProxyModelsSyncStaleness.WithLabelValues("never_synced")))
+}
+
+func TestApplyFederationLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rl := ratelimit.New()
+	rl.AddCredential("downstream", -1)
+	modelManager := models.New(logger, 50, []config.ModelRPMConfig{})
+
+	applyFederationLimits(&config.CredentialConfig{Name: "downstream"}, &httputil.FederationResponse{
+		Models: map[string]httputil.FederatedModelStats{
+			"gpt-4o":      {LimitRPM: 10, LimitTPM: 1000, RemainingRPM: 0, RemainingTPM: 400},
+			"gpt-4o-mini": {LimitRPM: 500, LimitTPM: -1, RemainingRPM: 500, RemainingTPM: -1},
+			"embed-v1":    {LimitRPM: -1, LimitTPM: -1, RemainingRPM: -1, RemainingTPM: -1},
+		},
+	}, rl, modelManager)
+
+	assert.Equal(t, 10, rl.GetModelLimitRPM("downstream", "gpt-4o"))
+	assert.Equal(t, 10, rl.GetCurrentModelRPM("downstream", "gpt-4o"))
+	assert.Equal(t, 600, rl.GetCurrentModelTPM("downstream", "gpt-4o"))
+	assert.False(t, rl.TryAllowAll("downstream", "gpt-4o"), "exhausted downstream must not be selected")
+
+	assert.Equal(t, 50, rl.GetModelLimitRPM("downstream", "gpt-4o-mini"), "imported limits never raise the local limit")
+	assert.Equal(t, 50, rl.GetModelLimitRPM("downstream", "embed-v1"))
+	assert.Equal(t, -1, rl.GetModelLimitTPM("downstream", "embed-v1"))
+	assert.True(t, rl.TryAllowAll("downstream", "embed-v1"))
+	assert.True(t, modelManager.HasModel("downstream", "embed-v1"))
+}
+
+func TestFetchDownstreamHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantReport string // "" = not a router, no report
+		wantHealth bool
+	}{
+		{name: "healthy router", status: http.StatusOK, body: `{"status":"healthy","credentials_available":2,"total_credentials":3}`, wantReport: httputil.DownstreamHealthy, wantHealth: true},
+		{name: "unhealthy router", status: http.StatusServiceUnavailable, body: `{"status":"unhealthy"}`, wantReport: httputil.DownstreamUnhealthy},
+		{name: "server error", status: http.StatusBadGateway, body: `bad gateway`, wantReport: httputil.DownstreamUnhealthy},
+		{name: "no health endpoint", status: http.StatusNotFound, body: `not found`},
+		{name: "not a router", status: http.StatusOK, body: `{"healthy_endpoints":[]}`},
+		{name: "not json", status: http.StatusOK, body: `OK`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/health", r.URL.Path)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			health, report := fetchDownstreamHealth(context.Background(), &config.CredentialConfig{Name: "health_" + tt.name, BaseURL: server.URL})
+			assert.Equal(t, tt.wantHealth, health != nil)
+			if tt.wantReport == "" {
+				assert.Nil(t, report)
+				return
+			}
+			require.NotNil(t, report)
+			assert.Equal(t, tt.wantReport, report.Status)
+			if tt.wantHealth {
+				assert.Equal(t, 2, report.CredentialsAvailable)
+				assert.Equal(t, 3, report.TotalCredentials)
+			} else {
+				assert.NotEmpty(t, report.Error)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, report := fetchDownstreamHealth(context.Background(), &config.CredentialConfig{Name: "health_closed", BaseURL: server.URL})
+	require.NotNil(t, report)
+	assert.Equal(t, httputil.DownstreamUnreachable, report.Status)
+}
+
+func TestUpdateAllProxyCredentials_DownstreamHealth(t *testing.T) {
+	var healthy atomic.Bool
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/health":
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"status":"unhealthy","credentials_available":0,"total_credentials":1}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"healthy","credentials_available":1,"total_credentials":1}`))
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","object":"model"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer downstream.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	credentials := []config.CredentialConfig{
+		{Name: "downstream_health", Type: config.ProviderTypeProxy, BaseURL: downstream.URL, RPM: 100},
+	}
+	rl := ratelimit.New()
+	bal := balancer.New(credentials, fail2ban.New(3, 0, []int{500}), rl)
+	tracker := proxyhealth.NewTracker()
+	bal.SetProxyHealthChecker(tracker)
+	modelManager := models.New(logger, 50, []config.ModelRPMConfig{})
+
+	UpdateAllProxyCredentials(context.Background(), bal, rl, logger, modelManager, &sync.Mutex{}, tracker)
+	assert.True(t, tracker.IsUnhealthy("downstream_health"))
+	assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.ProxyDownstreamHealthy.WithLabelValues("downstream_health")))
+	_, err := bal.NextForModel("gpt-4o")
+	assert.ErrorIs(t, err, balancer.ErrNoCredentialsAvailable)
+
+	healthy.Store(true)
+	UpdateAllProxyCredentials(context.Background(), bal, rl, logger, modelManager, &sync.Mutex{}, tracker)
+	assert.False(t, tracker.IsUnhealthy("downstream_health"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.ProxyDownstreamHealthy.WithLabelValues("downstream_health")))
+	report, ok := tracker.Report("downstream_health")
+	require.True(t, ok)
+	assert.Equal(t, 1, report.CredentialsAvailable)
+	cred, err := bal.NextForModel("gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "downstream_health", cred.Name)
+}
+
+func TestApplyHealthLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rl := ratelimit.New()
+	rl.AddCredential("downstream", -1)
+	modelManager := models.New(logger, 50, []config.ModelRPMConfig{})
+
+	applyHealthLimits(&config.CredentialConfig{Name: "downstream"}, &httputil.ProxyHealthResponse{
+		Models: map[string]httputil.ModelHealthStats{
+			"a:gpt-4o":   {Credential: "a", Model: "gpt-4o", LimitRPM: 10, LimitTPM: 1000, CurrentRPM: 4, CurrentTPM: 100},
+			"b:gpt-4o":   {Credential: "b", Model: "gpt-4o", LimitRPM: 5, LimitTPM: 500, CurrentRPM: 5},
+			"c:gpt-4o":   {Credential: "c", Model: "gpt-4o", LimitRPM: 50, IsBanned: true},
+			"a:embed-v1": {Credential: "a", Model: "embed-v1", LimitRPM: 10, IsBanned: true},
+		},
+	}, rl, modelManager)
+
+	assert.Equal(t, 15, rl.GetModelLimitRPM("downstream", "gpt-4o"), "banned credentials add no headroom")
+	assert.Equal(t, 1500, rl.GetModelLimitTPM("downstream", "gpt-4o"))
+	assert.Equal(t, 9, rl.GetCurrentModelRPM("downstream", "gpt-4o"))
+	assert.True(t, rl.TryAllowAll("downstream", "gpt-4o"))
+
+	assert.False(t, rl.TryAllowAll("downstream", "embed-v1"), "model banned on every downstream credential")
+	assert.True(t, modelManager.HasModel("downstream", "embed-v1"))
+}
+
+func TestPruneStaleModels(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	credentials := []config.CredentialConfig{
+		{Name: "prune_synced", Type: config.ProviderTypeProxy, BaseURL: "http://synced.local", RPM: 100},
+		{Name: "prune_unreachable", Type: config.ProviderTypeProxy, BaseURL: "http://unreachable.local", RPM: 100},
+		{Name: "prune_openai", Type: config.ProviderTypeOpenAI, BaseURL: "http://openai.local", RPM: 100},
+	}
+	rl := ratelimit.New()
+	bal := balancer.New(credentials, fail2ban.New(3, 0, []int{500}), rl)
+	modelManager := models.New(logger, 50, []config.ModelRPMConfig{})
+
+	for _, cred := range credentials {
+		rl.AddModel(cred.Name, "gone-model", 10)
+		modelManager.AddModel(cred.Name, "gone-model")
+	}
+	time.Sleep(100 * time.Millisecond)
+	rl.AddModel("prune_synced", "gpt-4o", 10)
+	modelManager.AddModel("prune_synced", "gpt-4o")
+	recordSyncResult("prune_synced", true, time.Now())
+	before := testutil.ToFloat64(monitoring.StaleModelsEvictedTotal.WithLabelValues("prune_synced"))
+
+	evicted := PruneStaleModels(bal, rl, modelManager, &sync.Mutex{}, 50*time.Millisecond, logger)
+
+	assert.Equal(t, 1, evicted)
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.StaleModelsEvictedTotal.WithLabelValues("prune_synced"))-before)
+	assert.ElementsMatch(t, []string{"prune_unreachable:gone-model", "prune_openai:gone-model", "prune_synced:gpt-4o"}, rl.GetAllModels())
+	assert.ElementsMatch(t, []string{"prune_unreachable", "prune_openai"}, modelManager.GetCredentialsForModel("gone-model"),
+		"proxies without a recent sync and other providers keep their models")
+}
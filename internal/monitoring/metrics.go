@@ -50,6 +50,14 @@ var (
 		[]string{"credential"},
 	)
 
+	CredentialAdaptiveLimitFactor = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_credential_adaptive_limit_factor",
+			Help: "Fraction of configured RPM/TPM currently allowed by adaptive limits (1 = full limits)",
+		},
+		[]string{"credential"},
+	)
+
 	CredentialErrorsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "auto_ai_router_credential_errors_total",
@@ -97,6 +105,410 @@ var (
 		},
 		[]string{"credential", "model"},
 	)
+
+	SpendUSDTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aar_spend_usd_total",
+			Help: "Total calculated request cost in USD per credential and model",
+		},
+		[]string{"credential", "model"},
+	)
+
+	TenantRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_tenant_requests_total",
+			Help: "Total number of requests per tenant by status (success, failure)",
+		},
+		[]string{"tenant", "status"},
+	)
+
+	TenantSpendUSDTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_tenant_spend_usd_total",
+			Help: "Total calculated request cost in USD per tenant",
+		},
+		[]string{"tenant"},
+	)
+
+	TokensTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aar_tokens_total",
+			Help: "Total tokens per credential and model by kind (prompt, completion)",
+		},
+		[]string{"credential", "model", "kind"},
+	)
+
+	PanicsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_panics_total",
+			Help: "Total number of panics recovered while serving requests",
+		},
+		[]string{"endpoint"},
+	)
+
+	InterRouterAuthFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_inter_router_auth_failures_total",
+			Help: "Total number of rejected HMAC-signed inter-router requests by reason",
+		},
+		[]string{"reason"},
+	)
+
+	MasterKeyRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_master_key_requests_total",
+			Help: "Total number of requests authenticated with each master key (by key fingerprint)",
+		},
+		[]string{"key_id"},
+	)
+
+	ProxyDownstreamHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_proxy_downstream_healthy",
+			Help: "Whether the downstream router of a proxy credential reports itself healthy on /health (1) or not (0)",
+		},
+		[]string{"credential"},
+	)
+
+	ReadOnlyMode = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_read_only",
+			Help: "Whether the router is in read-only mode (1) or not (0)",
+		},
+	)
+
+	ReadOnlySkippedSpendLogs = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_read_only_skipped_spend_logs_total",
+			Help: "Total number of spend log entries not written to LiteLLM DB in read-only mode",
+		},
+	)
+
+	FairSchedulerInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_fair_scheduler_in_flight",
+			Help: "Requests currently admitted by the fair scheduler",
+		},
+	)
+
+	FairSchedulerQueued = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_fair_scheduler_queued",
+			Help: "Requests waiting for admission in the fair scheduler",
+		},
+	)
+
+	FairSchedulerRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_fair_scheduler_rejected_total",
+			Help: "Total number of requests rejected by the fair scheduler by reason",
+		},
+		[]string{"reason"},
+	)
+
+	ImageInliningTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_image_inlining_total",
+			Help: "Total number of remote image URLs processed for inlining by result (fetched, cached, error)",
+		},
+		[]string{"result"},
+	)
+
+	FaultInjectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_fault_injections_total",
+			Help: "Total number of faults injected into upstream requests by credential and fault (rate_limit, server_error, latency)",
+		},
+		[]string{"credential", "fault"},
+	)
+
+	VertexTokenRefreshesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_vertex_token_refreshes_total",
+			Help: "Total number of Vertex AI OAuth2 token fetches and refreshes by credential and result (success, failure)",
+		},
+		[]string{"credential", "result"},
+	)
+
+	ContentFilterEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_content_filter_events_total",
+			Help: "Total number of responses stopped by a provider content filter (finish_reason content_filter) by model and credential",
+		},
+		[]string{"model", "credential"},
+	)
+
+	StreamFirstByteTimeoutsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_stream_first_byte_timeouts_total",
+			Help: "Total number of streaming upstream calls cancelled for sending no byte within stream_first_byte_timeout by credential and model",
+		},
+		[]string{"credential", "model"},
+	)
+
+	MalformedConversionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_malformed_conversions_total",
+			Help: "Total number of converted requests failing conversion_validation by provider and action (forwarded, rejected)",
+		},
+		[]string{"provider", "action"},
+	)
+
+	UnresolvedModelPricesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_unresolved_model_prices_total",
+			Help: "Total number of requests logged with cost 0 because no model price matched, by model",
+		},
+		[]string{"model"},
+	)
+
+	ModelPricesSyncAge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_model_prices_sync_age_seconds",
+			Help: "Seconds since the model prices in use were synced from model_prices_link",
+		},
+	)
+
+	ZeroCostLogsSkippedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_zero_cost_logs_skipped_total",
+			Help: "Total number of spend logs not written because their model lost its price (skip_zero_cost_logs), by model",
+		},
+		[]string{"model"},
+	)
+
+	ContextTruncationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_context_truncations_total",
+			Help: "Total number of requests whose oldest messages were removed to fit the prompt budget by model and strategy (truncate, summarize)",
+		},
+		[]string{"model", "strategy"},
+	)
+
+	ModelPinStale = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_model_pin_stale",
+			Help: "1 if a newer snapshot than the pinned one is available for a rolling model alias",
+		},
+		[]string{"alias"},
+	)
+
+	MaxCostRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_max_cost_rejected_total",
+			Help: "Total number of requests rejected because their worst-case cost exceeds the key's max_cost_per_request by model",
+		},
+		[]string{"model"},
+	)
+
+	UnsupportedParamsLearnedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_unsupported_params_learned_total",
+			Help: "Total number of request parameters learned as unsupported by param_negotiation from 400 responses by credential, model and param",
+		},
+		[]string{"credential", "model", "param"},
+	)
+
+	NegotiatedParamsDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_negotiated_params_dropped_total",
+			Help: "Total number of learned unsupported parameters dropped from requests by credential and param",
+		},
+		[]string{"credential", "param"},
+	)
+
+	StaleModelsEvictedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_stale_models_evicted_total",
+			Help: "Total number of proxy models evicted from the rate limiter and model manager after stale_model_ttl without being reported, by credential",
+		},
+		[]string{"credential"},
+	)
+
+	SystemPromptsInjectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_system_prompts_injected_total",
+			Help: "Total number of requests that received a system_prompts prompt by source (key, team, rule)",
+		},
+		[]string{"source"},
+	)
+
+	ReasoningRoutedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_reasoning_routed_total",
+			Help: "Total number of model group requests routed by reasoning_effort by group, effort and model",
+		},
+		[]string{"group", "effort", "model"},
+	)
+
+	ResponseHeadersDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_response_headers_dropped_total",
+			Help: "Total number of upstream response headers not returned to clients by credential and reason (stripped, invalid, limit)",
+		},
+		[]string{"credential", "reason"},
+	)
+
+	SLOBurnRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_slo_burn_rate",
+			Help: "Error budget burn rate of each SLO over each alert window (1 = budget spent exactly at the objective)",
+		},
+		[]string{"slo", "window"},
+	)
+
+	SLOAlertFiring = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_slo_alert_firing",
+			Help: "Whether a burn rate alert of each SLO is firing (1) or not (0)",
+		},
+		[]string{"slo", "alert"},
+	)
+
+	SLOObjective = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_slo_objective_ratio",
+			Help: "Target ratio of good requests of each SLO",
+		},
+		[]string{"slo"},
+	)
+
+	SpendReportsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_spend_reports_total",
+			Help: "Total number of generated daily spend reports by delivery status (success, error)",
+		},
+		[]string{"status"},
+	)
+
+	PriorityClassRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_priority_class_requests_total",
+			Help: "Total number of credential selections per priority class by result (allowed, rejected)",
+		},
+		[]string{"class", "result"},
+	)
+
+	QuotaExhaustionSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_quota_exhaustion_seconds",
+			Help: "Forecast seconds until a credential quota is exhausted at the current rate (+Inf if not before it resets)",
+		},
+		[]string{"credential", "period", "kind"},
+	)
+
+	QuotaUsedRatio = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_quota_used_ratio",
+			Help: "Fraction of a credential quota used in the current period",
+		},
+		[]string{"credential", "period", "kind"},
+	)
+
+	QuotaWarningsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_quota_warnings_total",
+			Help: "Total number of warnings that a credential quota will be exhausted within the warning threshold",
+		},
+		[]string{"credential", "period", "kind"},
+	)
+
+	LiteLLMDBPoolConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_litellm_db_pool_connections",
+			Help: "LiteLLM DB pool connections by pool (primary, replica) and state (total, idle, acquired, constructing, max)",
+		},
+		[]string{"pool", "state"},
+	)
+
+	LiteLLMDBPoolAcquireWaitSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_litellm_db_pool_acquire_wait_seconds",
+			Help: "Cumulative time spent waiting for a LiteLLM DB connection, per pool",
+		},
+		[]string{"pool"},
+	)
+
+	LiteLLMDBPoolEmptyAcquire = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_litellm_db_pool_empty_acquire",
+			Help: "Cumulative number of acquires that had to wait because the LiteLLM DB pool was empty, per pool",
+		},
+		[]string{"pool"},
+	)
+
+	LiteLLMDBHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_litellm_db_healthy",
+			Help: "LiteLLM DB health per pool (1 = healthy, 0 = unhealthy)",
+		},
+		[]string{"pool"},
+	)
+
+	LiteLLMDBReconnects = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_litellm_db_reconnects_total",
+			Help: "LiteLLM DB reconnect attempts per pool and result (success, failure)",
+		},
+		[]string{"pool", "result"},
+	)
+
+	LiteLLMDBSpendFlushDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "auto_ai_router_litellm_db_spend_flush_duration_seconds",
+			Help:    "Duration of a spend log batch flush (insert + spend updates) by method (copy, insert) and status",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		[]string{"method", "status"},
+	)
+
+	LiteLLMDBSpendCopyFallbacks = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_litellm_db_spend_copy_fallbacks_total",
+			Help: "Spend log batches that fell back from COPY to INSERT (e.g. duplicate request_id)",
+		},
+	)
+
+	UpstreamConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_upstream_connections",
+			Help: "Open upstream connections per host by state (active, idle)",
+		},
+		[]string{"host", "state"},
+	)
+
+	UpstreamDialsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_upstream_dials_total",
+			Help: "Total number of new upstream connections dialed per host by result (success, error)",
+		},
+		[]string{"host", "result"},
+	)
+
+	UpstreamConnectionsAcquiredTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_ai_router_upstream_connections_acquired_total",
+			Help: "Total number of upstream requests per host by whether their connection was reused (true, false)",
+		},
+		[]string{"host", "reused"},
+	)
+
+	UpstreamPhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "auto_ai_router_upstream_phase_duration_seconds",
+			Help:    "Duration of upstream request phases per host (dns, connect, tls, time_to_first_byte)",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"host", "phase"},
+	)
+
+	ProxyModelsSyncStaleness = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "auto_ai_router_proxy_models_sync_staleness_seconds",
+			Help: "Seconds since the last successful model sync from each proxy credential",
+		},
+		[]string{"credential"},
+	)
 )
 
 type Metrics struct {
@@ -110,7 +522,12 @@ func New(enabled bool) *Metrics {
 }
 
 func (m *Metrics) isEnabled() bool {
-	return m.enabled
+	return m != nil && m.enabled
+}
+
+// IsEnabled returns true if Prometheus metrics are recorded
+func (m *Metrics) IsEnabled() bool {
+	return m.isEnabled()
 }
 
 // updateCredentialMetric updates a credential-level gauge metric
@@ -143,6 +560,35 @@ func (m *Metrics) RecordRequest(credential, endpoint string, statusCode int, dur
 	}
 }
 
+// RecordSpend adds the cost and token usage of a request
+func (m *Metrics) RecordSpend(credential, model string, cost float64, promptTokens, completionTokens int) {
+	if !m.isEnabled() {
+		return
+	}
+
+	if cost > 0 {
+		SpendUSDTotal.WithLabelValues(credential, model).Add(cost)
+	}
+	if promptTokens > 0 {
+		TokensTotal.WithLabelValues(credential, model, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		TokensTotal.WithLabelValues(credential, model, "completion").Add(float64(completionTokens))
+	}
+}
+
+// RecordTenantRequest counts a request of a tenant and adds its cost
+func (m *Metrics) RecordTenantRequest(tenant, status string, cost float64) {
+	if !m.isEnabled() || tenant == "" {
+		return
+	}
+
+	TenantRequestsTotal.WithLabelValues(tenant, status).Inc()
+	if cost > 0 {
+		TenantSpendUSDTotal.WithLabelValues(tenant).Add(cost)
+	}
+}
+
 func (m *Metrics) UpdateCredentialRPM(credential string, rpm int) {
 	m.updateCredentialMetric(CredentialRPMCurrent, credential, rpm)
 }
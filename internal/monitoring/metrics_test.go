@@ -429,3 +429,23 @@ func TestMetrics_AllPrometheusRegistration(t *testing.T) {
 		assert.NotNil(t, metric)
 	}
 }
+
+func TestRecordSpend(t *testing.T) {
+	SpendUSDTotal.Reset()
+	TokensTotal.Reset()
+
+	m := New(true)
+	m.RecordSpend("cred1", "gpt-4o", 0.25, 100, 50)
+	m.RecordSpend("cred1", "gpt-4o", 0.25, 100, 50)
+
+	assert.InDelta(t, 0.5, testutil.ToFloat64(SpendUSDTotal.WithLabelValues("cred1", "gpt-4o")), 1e-9)
+	assert.Equal(t, 200.0, testutil.ToFloat64(TokensTotal.WithLabelValues("cred1", "gpt-4o", "prompt")))
+	assert.Equal(t, 100.0, testutil.ToFloat64(TokensTotal.WithLabelValues("cred1", "gpt-4o", "completion")))
+
+	// Disabled and nil metrics record nothing
+	New(false).RecordSpend("cred2", "gpt-4o", 1, 1, 1)
+	var nilMetrics *Metrics
+	nilMetrics.RecordSpend("cred2", "gpt-4o", 1, 1, 1)
+	assert.False(t, nilMetrics.IsEnabled())
+	assert.Equal(t, 1, testutil.CollectAndCount(SpendUSDTotal), "only the enabled series is recorded")
+}
@@ -0,0 +1,281 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+	"github.com/mixaill76/auto_ai_router/internal/webhook"
+)
+
+// SLO names used in metric labels and alerts
+const (
+	SLOAvailability = "availability"
+	SLOLatency      = "latency"
+)
+
+// SLO alert states
+const (
+	SLOAlertStatusFiring   = "firing"
+	SLOAlertStatusResolved = "resolved"
+)
+
+// SLOAlert is a burn rate alert that started or stopped firing; it is the JSON webhook payload
+type SLOAlert struct {
+	Status        string    `json:"status"` // "firing" or "resolved"
+	SLO           string    `json:"slo"`    // "availability" or "latency"
+	Alert         string    `json:"alert"`  // Alert name, e.g. "page"
+	Objective     float64   `json:"objective"`
+	BurnRate      float64   `json:"burn_rate"`       // Over the long window
+	ShortBurnRate float64   `json:"short_burn_rate"` // Over the short window
+	Threshold     float64   `json:"threshold"`
+	LongWindow    string    `json:"long_window"`
+	ShortWindow   string    `json:"short_window"`
+	Time          time.Time `json:"time"`
+}
+
+// Text returns a one-line description of the alert (Slack webhook format and logs)
+func (a SLOAlert) Text() string {
+	return fmt.Sprintf("[%s] SLO %s (%g%%) alert %s: burn rate %.1f over %s, %.1f over %s (threshold %g)",
+		map[string]string{SLOAlertStatusFiring: "FIRING", SLOAlertStatusResolved: "RESOLVED"}[a.Status],
+		a.SLO, a.Objective, a.Alert, a.BurnRate, a.LongWindow, a.ShortBurnRate, a.ShortWindow, a.Threshold)
+}
+
+// sloBucket counts the requests of one minute
+type sloBucket struct {
+	minute int64 // Unix minute the counts belong to
+	total  int64 // All requests
+	errors int64 // Requests answered with a 5xx status
+	timed  int64 // Requests without a 5xx status (the latency SLI population)
+	slow   int64 // Requests without a 5xx status slower than latency_threshold
+}
+
+// SLOTracker computes multi-window error budget burn rates of the availability and latency
+// objectives (monitoring.slo) from per-minute request counts, exports them as metrics and
+// posts alerts to a webhook. A nil *SLOTracker is valid and records nothing.
+type SLOTracker struct {
+	cfg     config.SLOConfig
+	logger  *slog.Logger
+	webhook *webhook.Poster
+	now     func() time.Time
+
+	mu      sync.Mutex
+	buckets []sloBucket
+	firing  map[string]bool // "<slo>/<alert>" -> firing
+}
+
+// NewSLOTracker creates an SLOTracker from config.
+// Returns nil if SLO tracking is disabled.
+func NewSLOTracker(cfg config.SLOConfig, log *slog.Logger) *SLOTracker {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var longest time.Duration
+	for _, alert := range cfg.Alerts {
+		longest = max(longest, alert.LongWindow)
+	}
+
+	t := &SLOTracker{
+		cfg:     cfg,
+		logger:  log,
+		webhook: webhook.New(cfg.WebhookURL, cfg.WebhookFormat),
+		now:     utils.NowUTC,
+		buckets: make([]sloBucket, int(longest/time.Minute)+1),
+		firing:  make(map[string]bool),
+	}
+	for _, slo := range t.objectives() {
+		SLOObjective.WithLabelValues(slo.name).Set(slo.target)
+	}
+	return t
+}
+
+// Record counts a finished request. latency is the time to the first response byte, so
+// streaming responses are not penalized for their length.
+func (t *SLOTracker) Record(statusCode int, latency time.Duration) {
+	if t == nil {
+		return
+	}
+	minute := t.now().Unix() / 60
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := &t.buckets[minute%int64(len(t.buckets))]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.total++
+	if statusCode >= 500 {
+		b.errors++
+		return
+	}
+	b.timed++
+	if t.cfg.LatencyThreshold > 0 && latency > t.cfg.LatencyThreshold {
+		b.slow++
+	}
+}
+
+// sloObjective is one tracked objective
+type sloObjective struct {
+	name   string
+	target float64 // Ratio of good requests, e.g. 0.999
+	bad    func(sum sloBucket) (bad, total int64)
+}
+
+func (t *SLOTracker) objectives() []sloObjective {
+	var objectives []sloObjective
+	if t.cfg.Availability > 0 {
+		objectives = append(objectives, sloObjective{
+			name:   SLOAvailability,
+			target: t.cfg.Availability / 100,
+			bad:    func(sum sloBucket) (int64, int64) { return sum.errors, sum.total },
+		})
+	}
+	if t.cfg.LatencyThreshold > 0 {
+		objectives = append(objectives, sloObjective{
+			name:   SLOLatency,
+			target: t.cfg.LatencyTarget / 100,
+			bad:    func(sum sloBucket) (int64, int64) { return sum.slow, sum.timed },
+		})
+	}
+	return objectives
+}
+
+// windowLocked sums the buckets of the last window (including the current minute);
+// the caller must hold t.mu
+func (t *SLOTracker) windowLocked(now time.Time, window time.Duration) sloBucket {
+	var sum sloBucket
+	current := now.Unix() / 60
+	for minute := current - int64(window/time.Minute) + 1; minute <= current; minute++ {
+		b := t.buckets[minute%int64(len(t.buckets))]
+		if b.minute != minute {
+			continue
+		}
+		sum.total += b.total
+		sum.errors += b.errors
+		sum.timed += b.timed
+		sum.slow += b.slow
+	}
+	return sum
+}
+
+// Evaluate computes the burn rates, updates the metrics and returns the alerts that
+// started or stopped firing since the previous evaluation
+func (t *SLOTracker) Evaluate() []SLOAlert {
+	if t == nil {
+		return nil
+	}
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changed []SLOAlert
+	for _, slo := range t.objectives() {
+		burnRate := func(window time.Duration) float64 {
+			bad, total := slo.bad(t.windowLocked(now, window))
+			if total == 0 {
+				return 0
+			}
+			return float64(bad) / float64(total) / (1 - slo.target)
+		}
+
+		for _, alert := range t.cfg.Alerts {
+			long, short := burnRate(alert.LongWindow), burnRate(alert.ShortWindow)
+			SLOBurnRate.WithLabelValues(slo.name, formatWindow(alert.LongWindow)).Set(long)
+			SLOBurnRate.WithLabelValues(slo.name, formatWindow(alert.ShortWindow)).Set(short)
+
+			firing := long > alert.BurnRate && short > alert.BurnRate
+			key := slo.name + "/" + alert.Name
+			SLOAlertFiring.WithLabelValues(slo.name, alert.Name).Set(boolToFloat(firing))
+			if firing == t.firing[key] {
+				continue
+			}
+			t.firing[key] = firing
+
+			status := SLOAlertStatusResolved
+			if firing {
+				status = SLOAlertStatusFiring
+			}
+			changed = append(changed, SLOAlert{
+				Status:        status,
+				SLO:           slo.name,
+				Alert:         alert.Name,
+				Objective:     slo.target * 100,
+				BurnRate:      long,
+				ShortBurnRate: short,
+				Threshold:     alert.BurnRate,
+				LongWindow:    formatWindow(alert.LongWindow),
+				ShortWindow:   formatWindow(alert.ShortWindow),
+				Time:          now,
+			})
+		}
+	}
+	return changed
+}
+
+// Run evaluates the burn rates every evaluation_interval until ctx is cancelled, logging
+// alert changes and posting them to the webhook
+func (t *SLOTracker) Run(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	interval := t.cfg.EvaluationInterval
+	if interval <= 0 {
+		interval = config.DefaultSLOEvaluationInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.Debug("SLO evaluation loop stopped")
+			return
+		case <-ticker.C:
+			for _, alert := range t.Evaluate() {
+				if alert.Status == SLOAlertStatusFiring {
+					t.logger.Warn("SLO burn rate alert firing", "alert", alert.Text())
+				} else {
+					t.logger.Info("SLO burn rate alert resolved", "alert", alert.Text())
+				}
+				if err := t.Notify(ctx, alert); err != nil {
+					t.logger.Warn("Failed to post SLO alert to webhook", "slo", alert.SLO, "alert", alert.Alert, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// Notify posts an alert to the webhook (no-op without webhook_url)
+func (t *SLOTracker) Notify(ctx context.Context, alert SLOAlert) error {
+	if t == nil {
+		return nil
+	}
+	return t.webhook.Post(ctx, alert, alert.Text())
+}
+
+// formatWindow formats a window for metric labels: "5m", "1h", "6h", ...
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	default:
+		return window.String()
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSLOTracker(cfg config.SLOConfig) (*SLOTracker, *time.Time) {
+	cfg.Enabled = true
+	if len(cfg.Alerts) == 0 {
+		cfg.Alerts = config.DefaultSLOAlerts
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker(cfg, spendTestLogger())
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func TestNewSLOTracker_Disabled(t *testing.T) {
+	tracker := NewSLOTracker(config.SLOConfig{}, spendTestLogger())
+	assert.Nil(t, tracker)
+
+	// Nil tracker is safe to use
+	tracker.Record(http.StatusInternalServerError, time.Second)
+	assert.Nil(t, tracker.Evaluate())
+	assert.NoError(t, tracker.Notify(context.Background(), SLOAlert{}))
+	tracker.Run(context.Background())
+}
+
+func TestSLOTracker_AvailabilityBurnRate(t *testing.T) {
+	tracker, now := newTestSLOTracker(config.SLOConfig{Availability: 99})
+
+	// 1 error in 100 requests spends the budget exactly at the objective
+	for i := 0; i < 99; i++ {
+		tracker.Record(http.StatusOK, time.Millisecond)
+	}
+	tracker.Record(http.StatusBadGateway, time.Millisecond)
+	assert.Empty(t, tracker.Evaluate())
+	assert.InDelta(t, 1, testutil.ToFloat64(SLOBurnRate.WithLabelValues(SLOAvailability, "1h")), 0.001)
+	assert.Equal(t, 0.99, testutil.ToFloat64(SLOObjective.WithLabelValues(SLOAvailability)))
+
+	// 20% errors burn the budget 20 times faster than allowed: page (14.4) and ticket (6) fire
+	for i := 0; i < 25; i++ {
+		tracker.Record(http.StatusServiceUnavailable, time.Millisecond)
+	}
+	alerts := tracker.Evaluate()
+	require.Len(t, alerts, 2)
+	assert.Equal(t, SLOAlertStatusFiring, alerts[0].Status)
+	assert.Equal(t, "page", alerts[0].Alert)
+	assert.InDelta(t, 20.8, alerts[0].BurnRate, 0.1)
+	assert.Equal(t, "5m", alerts[0].ShortWindow)
+	assert.Equal(t, float64(1), testutil.ToFloat64(SLOAlertFiring.WithLabelValues(SLOAvailability, "page")))
+	assert.Empty(t, tracker.Evaluate(), "only state changes are returned")
+
+	// Once the errors leave the short window the page resolves, the ticket keeps firing
+	*now = now.Add(10 * time.Minute)
+	for i := 0; i < 100; i++ {
+		tracker.Record(http.StatusOK, time.Millisecond)
+	}
+	alerts = tracker.Evaluate()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, SLOAlertStatusResolved, alerts[0].Status)
+	assert.Equal(t, "page", alerts[0].Alert)
+	assert.Equal(t, float64(1), testutil.ToFloat64(SLOAlertFiring.WithLabelValues(SLOAvailability, "ticket")))
+}
+
+func TestSLOTracker_LatencyBurnRate(t *testing.T) {
+	tracker, now := newTestSLOTracker(config.SLOConfig{LatencyThreshold: time.Second, LatencyTarget: 90})
+
+	for i := 0; i < 8; i++ {
+		tracker.Record(http.StatusOK, 2*time.Second)
+	}
+	for i := 0; i < 2; i++ {
+		tracker.Record(http.StatusOK, 100*time.Millisecond)
+	}
+	tracker.Record(http.StatusInternalServerError, time.Minute) // errors belong to the availability SLO
+
+	alerts := tracker.Evaluate()
+	require.Len(t, alerts, 1, "80% slow requests burn 8x: only ticket (6) fires")
+	assert.Equal(t, SLOLatency, alerts[0].SLO)
+	assert.Equal(t, "ticket", alerts[0].Alert)
+	assert.InDelta(t, 8, alerts[0].BurnRate, 0.001)
+
+	// Requests older than the longest window are forgotten
+	*now = now.Add(7 * time.Hour)
+	alerts = tracker.Evaluate()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, SLOAlertStatusResolved, alerts[0].Status)
+	assert.Zero(t, testutil.ToFloat64(SLOBurnRate.WithLabelValues(SLOLatency, "6h")))
+}
+
+func TestSLOTracker_Notify(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	alert := SLOAlert{Status: SLOAlertStatusFiring, SLO: SLOAvailability, Alert: "page", Objective: 99.9, BurnRate: 20, ShortBurnRate: 18, Threshold: 14.4, LongWindow: "1h", ShortWindow: "5m"}
+
+	tracker, _ := newTestSLOTracker(config.SLOConfig{Availability: 99.9, WebhookURL: server.URL, WebhookFormat: config.WebhookFormatJSON})
+	require.NoError(t, tracker.Notify(context.Background(), alert))
+	tracker, _ = newTestSLOTracker(config.SLOConfig{Availability: 99.9, WebhookURL: server.URL, WebhookFormat: config.WebhookFormatSlack})
+	require.NoError(t, tracker.Notify(context.Background(), alert))
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "firing", received[0]["status"])
+	assert.Equal(t, "page", received[0]["alert"])
+	assert.Equal(t, "[FIRING] SLO availability (99.9%) alert page: burn rate 20.0 over 1h, 18.0 over 5m (threshold 14.4)", received[1]["text"])
+}
@@ -0,0 +1,214 @@
+package monitoring
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// spendPushTimeout bounds a single push to the Pushgateway
+const spendPushTimeout = 10 * time.Second
+
+// otherLabelValue replaces team_id and user_id values beyond the max_label_values cap
+const otherLabelValue = "other"
+
+// SpendEvent describes the cost and token usage of a single proxied request
+type SpendEvent struct {
+	Credential       string
+	Provider         string
+	Model            string
+	TeamID           string
+	UserID           string
+	Status           string // "success" or "failure"
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // USD
+}
+
+// SpendPusher aggregates spend events into counters kept in a dedicated registry
+// and periodically pushes them to a Prometheus Pushgateway.
+// A nil *SpendPusher is valid and records nothing.
+type SpendPusher struct {
+	registry *prometheus.Registry
+	pusher   *push.Pusher
+	interval time.Duration
+	logger   *slog.Logger
+
+	spendTotal    *prometheus.CounterVec
+	tokensTotal   *prometheus.CounterVec
+	requestsTotal *prometheus.CounterVec
+
+	// Bound the series pushed per team and user: values seen after the first
+	// maxLabelValues distinct ones are aggregated as otherLabelValue
+	labelsMu       sync.Mutex
+	maxLabelValues int
+	teams          map[string]struct{}
+	users          map[string]struct{}
+}
+
+// NewSpendPusher creates a SpendPusher from config.
+// Returns nil if spend push is disabled.
+func NewSpendPusher(cfg config.SpendPushConfig, log *slog.Logger) *SpendPusher {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	labels := []string{"credential", "provider", "model", "team_id", "user_id", "status"}
+	sp := &SpendPusher{
+		registry: prometheus.NewRegistry(),
+		interval: cfg.Interval,
+		logger:   log,
+
+		maxLabelValues: cfg.MaxLabelValues,
+		teams:          make(map[string]struct{}),
+		users:          make(map[string]struct{}),
+		spendTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auto_ai_router_spend_push_cost_usd_total",
+				Help: "Total calculated request cost in USD",
+			},
+			labels,
+		),
+		tokensTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auto_ai_router_spend_push_tokens_total",
+				Help: "Total tokens by type (prompt, completion)",
+			},
+			append(labels, "type"),
+		),
+		requestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auto_ai_router_spend_push_requests_total",
+				Help: "Total number of requests with recorded spend",
+			},
+			labels,
+		),
+	}
+	sp.registry.MustRegister(sp.spendTotal, sp.tokensTotal, sp.requestsTotal)
+
+	job := cfg.Job
+	if job == "" {
+		job = config.DefaultSpendPushJob
+	}
+	instance := cfg.Instance
+	if instance == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			instance = hostname
+		}
+	}
+
+	sp.pusher = push.New(cfg.PushgatewayURL, job).Gatherer(sp.registry)
+	if instance != "" {
+		sp.pusher = sp.pusher.Grouping("instance", instance)
+	}
+	if cfg.Username != "" {
+		sp.pusher = sp.pusher.BasicAuth(cfg.Username, cfg.Password)
+	}
+
+	if sp.interval <= 0 {
+		sp.interval = config.DefaultSpendPushInterval
+	}
+	if sp.maxLabelValues <= 0 {
+		sp.maxLabelValues = config.DefaultSpendPushMaxLabelValues
+	}
+
+	return sp
+}
+
+// IsEnabled returns true if spend events are being recorded
+func (s *SpendPusher) IsEnabled() bool {
+	return s != nil
+}
+
+// Record adds a spend event to the pushed counters
+func (s *SpendPusher) Record(event SpendEvent) {
+	if s == nil {
+		return
+	}
+
+	s.labelsMu.Lock()
+	teamID := boundLabelValue(s.teams, event.TeamID, s.maxLabelValues)
+	userID := boundLabelValue(s.users, event.UserID, s.maxLabelValues)
+	s.labelsMu.Unlock()
+
+	labels := prometheus.Labels{
+		"credential": event.Credential,
+		"provider":   event.Provider,
+		"model":      event.Model,
+		"team_id":    teamID,
+		"user_id":    userID,
+		"status":     event.Status,
+	}
+
+	s.requestsTotal.With(labels).Inc()
+	// Add(0) still creates the series, so dashboards show zero cost instead of no data
+	s.spendTotal.With(labels).Add(math.Max(event.Cost, 0))
+
+	tokenLabels := prometheus.Labels{"type": "prompt"}
+	for k, v := range labels {
+		tokenLabels[k] = v
+	}
+	s.tokensTotal.With(tokenLabels).Add(float64(event.PromptTokens))
+	tokenLabels["type"] = "completion"
+	s.tokensTotal.With(tokenLabels).Add(float64(event.CompletionTokens))
+}
+
+// boundLabelValue returns value if it is empty, already seen or fits in the max distinct values
+// (recording it as seen), and otherLabelValue otherwise. Callers hold s.labelsMu.
+func boundLabelValue(seen map[string]struct{}, value string, max int) string {
+	if value == "" {
+		return value
+	}
+	if _, ok := seen[value]; ok {
+		return value
+	}
+	if len(seen) >= max {
+		return otherLabelValue
+	}
+	seen[value] = struct{}{}
+	return value
+}
+
+// Push sends the current counters to the Pushgateway
+func (s *SpendPusher) Push(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, spendPushTimeout)
+	defer cancel()
+	return s.pusher.PushContext(ctx)
+}
+
+// Run pushes counters every interval until ctx is cancelled,
+// then performs a final push so the last events before shutdown are not lost.
+func (s *SpendPusher) Run(ctx context.Context) {
+	if s == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Push(context.Background()); err != nil {
+				s.logger.Warn("Final spend push to Pushgateway failed", "error", err)
+			}
+			s.logger.Debug("Spend push loop stopped")
+			return
+		case <-ticker.C:
+			if err := s.Push(ctx); err != nil {
+				s.logger.Warn("Spend push to Pushgateway failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package monitoring
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spendTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+type fakePushgateway struct {
+	mu       sync.Mutex
+	paths    []string
+	methods  []string
+	auth     []string
+	received int
+}
+
+func (f *fakePushgateway) handler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.Path)
+	f.methods = append(f.methods, r.Method)
+	user, _, _ := r.BasicAuth()
+	f.auth = append(f.auth, user)
+	f.received++
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *fakePushgateway) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.received
+}
+
+func TestNewSpendPusher_Disabled(t *testing.T) {
+	sp := NewSpendPusher(config.SpendPushConfig{Enabled: false}, spendTestLogger())
+	assert.Nil(t, sp)
+	assert.False(t, sp.IsEnabled())
+
+	// Nil pusher is safe to use
+	sp.Record(SpendEvent{Credential: "a", Cost: 1})
+	assert.NoError(t, sp.Push(context.Background()))
+	sp.Run(context.Background())
+}
+
+func TestSpendPusher_RecordAndPush(t *testing.T) {
+	gateway := &fakePushgateway{}
+	server := httptest.NewServer(http.HandlerFunc(gateway.handler))
+	defer server.Close()
+
+	sp := NewSpendPusher(config.SpendPushConfig{
+		Enabled:        true,
+		PushgatewayURL: server.URL,
+		Job:            "router",
+		Instance:       "pod-1",
+		Interval:       time.Minute,
+		Username:       "user",
+		Password:       "pass",
+	}, spendTestLogger())
+	require.NotNil(t, sp)
+	assert.True(t, sp.IsEnabled())
+
+	event := SpendEvent{
+		Credential:       "openai_main",
+		Provider:         "openai",
+		Model:            "gpt-4o",
+		TeamID:           "team-1",
+		UserID:           "user-1",
+		Status:           "success",
+		PromptTokens:     100,
+		CompletionTokens: 50,
+		Cost:             0.25,
+	}
+	sp.Record(event)
+	sp.Record(event)
+
+	labels := []string{"openai_main", "openai", "gpt-4o", "team-1", "user-1", "success"}
+	assert.InDelta(t, 0.5, testutil.ToFloat64(sp.spendTotal.WithLabelValues(labels...)), 1e-9)
+	assert.Equal(t, 2.0, testutil.ToFloat64(sp.requestsTotal.WithLabelValues(labels...)))
+	assert.Equal(t, 200.0, testutil.ToFloat64(sp.tokensTotal.WithLabelValues(append(labels, "prompt")...)))
+	assert.Equal(t, 100.0, testutil.ToFloat64(sp.tokensTotal.WithLabelValues(append(labels, "completion")...)))
+
+	require.NoError(t, sp.Push(context.Background()))
+
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	require.Len(t, gateway.paths, 1)
+	assert.Equal(t, "/metrics/job/router/instance/pod-1", gateway.paths[0])
+	assert.Equal(t, http.MethodPut, gateway.methods[0])
+	assert.Equal(t, "user", gateway.auth[0])
+}
+
+func TestSpendPusher_NegativeCostIgnored(t *testing.T) {
+	sp := NewSpendPusher(config.SpendPushConfig{Enabled: true, PushgatewayURL: "http://localhost:9091"}, spendTestLogger())
+	require.NotNil(t, sp)
+
+	sp.Record(SpendEvent{Credential: "c", Status: "failure", Cost: -1})
+	assert.Equal(t, 0.0, testutil.ToFloat64(sp.spendTotal.WithLabelValues("c", "", "", "", "", "failure")))
+	assert.Equal(t, config.DefaultSpendPushInterval, sp.interval)
+}
+
+func TestSpendPusher_MaxLabelValues(t *testing.T) {
+	sp := NewSpendPusher(config.SpendPushConfig{Enabled: true, PushgatewayURL: "http://localhost:9091", MaxLabelValues: 2}, spendTestLogger())
+	require.NotNil(t, sp)
+
+	for _, id := range []string{"a", "b", "c", "d", "a"} {
+		sp.Record(SpendEvent{Credential: "c", TeamID: "team-" + id, UserID: "user-" + id, Status: "success", Cost: 1})
+	}
+	sp.Record(SpendEvent{Credential: "c", Status: "success", Cost: 1})
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(sp.requestsTotal.WithLabelValues("c", "", "", "team-a", "user-a", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(sp.requestsTotal.WithLabelValues("c", "", "", "team-b", "user-b", "success")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(sp.requestsTotal.WithLabelValues("c", "", "", "other", "other", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(sp.requestsTotal.WithLabelValues("c", "", "", "", "", "success")), "empty values are not capped")
+	assert.Equal(t, 4, testutil.CollectAndCount(sp.requestsTotal))
+}
+
+func TestSpendPusher_RunPushesOnShutdown(t *testing.T) {
+	gateway := &fakePushgateway{}
+	server := httptest.NewServer(http.HandlerFunc(gateway.handler))
+	defer server.Close()
+
+	sp := NewSpendPusher(config.SpendPushConfig{
+		Enabled:        true,
+		PushgatewayURL: server.URL,
+		Interval:       20 * time.Millisecond,
+	}, spendTestLogger())
+	require.NotNil(t, sp)
+	sp.Record(SpendEvent{Credential: "c", Status: "success", Cost: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sp.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return gateway.count() >= 1 }, time.Second, 10*time.Millisecond)
+	before := gateway.count()
+	cancel()
+	<-done
+	assert.Greater(t, gateway.count(), before, "final push expected on shutdown")
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// attemptDeadline ends one upstream attempt at its deadline (server.fallback_timeout,
+// server.request_deadline). Like firstByteDeadline, the attempt's context lives until the
+// response body is closed.
+type attemptDeadline struct {
+	cancel context.CancelFunc
+}
+
+// withAttemptDeadline derives the context of an upstream attempt ending at deadline. Returns
+// ctx and a nil deadline if deadline is zero.
+func withAttemptDeadline(ctx context.Context, deadline time.Time) (context.Context, *attemptDeadline) {
+	if deadline.IsZero() {
+		return ctx, nil
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, &attemptDeadline{cancel: cancel}
+}
+
+// finish ties the attempt's context to the outcome of an upstream call: it is released when
+// resp.Body is closed, or right away if the call failed
+func (d *attemptDeadline) finish(resp *http.Response, err error) (*http.Response, error) {
+	if d == nil {
+		return resp, err
+	}
+	if err != nil || resp == nil {
+		d.release()
+		return resp, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: d.release}
+	return resp, nil
+}
+
+// release ends the attempt's context
+func (d *attemptDeadline) release() {
+	if d != nil {
+		d.cancel()
+	}
+}
+
+// releasingBody is a response body whose Close also releases the attempt's context
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// isRetryAttempt reports whether the next upstream attempt of r is a same-type retry or a
+// fallback attempt rather than the first one
+func isRetryAttempt(r *http.Request) bool {
+	logCtx, ok := r.Context().Value(requestLogKey{}).(*RequestLogContext)
+	return ok && logCtx.RetryCount > 0
+}
+
+// requestDeadlineAt returns when all upstream attempts of r must have ended (zero = none)
+func (p *Proxy) requestDeadlineAt(r *http.Request) time.Time {
+	logCtx, ok := r.Context().Value(requestLogKey{}).(*RequestLogContext)
+	if p.requestDeadline <= 0 || !ok {
+		return time.Time{}
+	}
+	return logCtx.StartTime.Add(p.requestDeadline)
+}
+
+// requestDeadlineExceeded reports whether request_deadline of r has passed, so it gets no
+// further attempts
+func (p *Proxy) requestDeadlineExceeded(r *http.Request) bool {
+	deadline := p.requestDeadlineAt(r)
+	return !deadline.IsZero() && !utils.NowUTC().Before(deadline)
+}
+
+// nextAttemptDeadline returns when the next upstream attempt of r must end (zero = only
+// request_timeout applies): fallback_timeout from now for retries and fallback attempts,
+// bounded by request_deadline
+func (p *Proxy) nextAttemptDeadline(r *http.Request) time.Time {
+	deadline := p.requestDeadlineAt(r)
+	if p.fallbackTimeout > 0 && isRetryAttempt(r) {
+		if timeout := utils.NowUTC().Add(p.fallbackTimeout); deadline.IsZero() || timeout.Before(deadline) {
+			deadline = timeout
+		}
+	}
+	return deadline
+}
+
+// responseBodyLimit returns the response body size limit of the next upstream attempt of r:
+// smaller for retries and fallback attempts with fallback_body_multiplier
+func (p *Proxy) responseBodyLimit(r *http.Request) int64 {
+	if isRetryAttempt(r) {
+		return p.maxFallbackBodySize
+	}
+	return p.maxResponseBodySize
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyRequest_FallbackBudgets(t *testing.T) {
+	var calls atomic.Int32
+	var retryDelay atomic.Int64
+	var retryBody atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"message":"overloaded"}}`))
+			return
+		}
+		select {
+		case <-time.After(time.Duration(retryDelay.Load())):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(retryBody.Load().(string)))
+	}))
+	t.Cleanup(upstream.Close)
+
+	newProxy := func() *Proxy {
+		prx := NewTestProxyBuilder().
+			WithCredentials(
+				config.CredentialConfig{Name: "a", Type: config.ProviderTypeOpenAI, APIKey: "sk-a", BaseURL: upstream.URL, RPM: 100},
+				config.CredentialConfig{Name: "b", Type: config.ProviderTypeOpenAI, APIKey: "sk-b", BaseURL: upstream.URL, RPM: 100},
+			).
+			WithMasterKey("master-key").
+			Build()
+		prx.maxProviderRetries = 1
+		return prx
+	}
+	send := func(prx *Proxy) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		return w
+	}
+	reset := func(delay time.Duration, body string) {
+		calls.Store(0)
+		retryDelay.Store(int64(delay))
+		retryBody.Store(body)
+	}
+	const completion = `{"id":"c","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`
+
+	t.Run("retry within the default timeout", func(t *testing.T) {
+		reset(100*time.Millisecond, completion)
+		w := send(newProxy())
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("fallback timeout", func(t *testing.T) {
+		reset(400*time.Millisecond, completion)
+		prx := newProxy()
+		prx.fallbackTimeout = 50 * time.Millisecond
+
+		started := time.Now()
+		w := send(prx)
+		assert.Equal(t, http.StatusRequestTimeout, w.Code, w.Body.String())
+		assert.Less(t, time.Since(started), 300*time.Millisecond)
+	})
+
+	t.Run("request deadline", func(t *testing.T) {
+		reset(400*time.Millisecond, completion)
+		prx := newProxy()
+		prx.requestDeadline = 80 * time.Millisecond
+
+		started := time.Now()
+		w := send(prx)
+		assert.Equal(t, http.StatusRequestTimeout, w.Code, w.Body.String())
+		assert.Less(t, time.Since(started), 300*time.Millisecond)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("request deadline stops retries", func(t *testing.T) {
+		reset(0, completion)
+		prx := newProxy()
+		prx.requestDeadline = time.Nanosecond
+
+		w := send(prx)
+		assert.Equal(t, http.StatusRequestTimeout, w.Code, w.Body.String())
+		assert.Zero(t, calls.Load(), "the first attempt already has no time left")
+	})
+
+	t.Run("fallback response body limit", func(t *testing.T) {
+		reset(0, completion)
+		prx := newProxy()
+		prx.maxFallbackBodySize = 16
+
+		w := send(prx)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.Contains(t, w.Body.String(), "upstream response too large")
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestNextAttemptDeadline(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	start := time.Now()
+	logCtx := &RequestLogContext{StartTime: start}
+	r := withRequestLog(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), logCtx)
+
+	assert.True(t, prx.nextAttemptDeadline(r).IsZero(), "only request_timeout applies")
+
+	prx.fallbackTimeout = time.Minute
+	assert.True(t, prx.nextAttemptDeadline(r).IsZero(), "the first attempt gets request_timeout")
+	logCtx.RetryCount = 1
+	assert.WithinDuration(t, time.Now().Add(time.Minute), prx.nextAttemptDeadline(r), time.Second)
+
+	prx.requestDeadline = 10 * time.Second
+	assert.Equal(t, start.Add(10*time.Second), prx.nextAttemptDeadline(r), "bounded by request_deadline")
+	assert.False(t, prx.requestDeadlineExceeded(r))
+
+	logCtx.StartTime = start.Add(-time.Minute)
+	assert.True(t, prx.requestDeadlineExceeded(r))
+
+	prx.maxResponseBodySize, prx.maxFallbackBodySize = 100, 10
+	assert.Equal(t, int64(10), prx.responseBodyLimit(r))
+	logCtx.RetryCount = 0
+	assert.Equal(t, int64(100), prx.responseBodyLimit(r))
+}
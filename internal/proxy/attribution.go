@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+)
+
+// Attribution headers name the backend that served a response. They are opt-in
+// (attribution_headers) and limited to selected keys so the routing topology is
+// not exposed to external users.
+const (
+	AttributionCredentialHeader    = "X-Router-Credential"     // Credential name
+	AttributionProviderHeader      = "X-Router-Provider"       // Credential type (openai, vertex-ai, anthropic, ...)
+	AttributionModelResolvedHeader = "X-Router-Model-Resolved" // Model name sent to the provider
+	AttributionFallbackUsedHeader  = "X-Router-Fallback-Used"  // "true" when a fallback credential served the request
+)
+
+// AllKeys in a key list (attribution, non_streaming, ...) selects every API key
+const AllKeys = "*"
+
+// keyMatcher selects requests by API key: the master key, key aliases and team IDs,
+// or every key (AllKeys)
+type keyMatcher struct {
+	masterKeys *masterkey.Ring // nil = master key requests only match AllKeys
+	all        bool
+	keys       map[string]bool // Key aliases and team IDs
+}
+
+// newKeyMatcher creates a matcher for the given key aliases or team IDs ("*" = all keys).
+// With masterKeys, requests authenticated with a master key always match.
+func newKeyMatcher(masterKeys *masterkey.Ring, keys []string) keyMatcher {
+	m := keyMatcher{masterKeys: masterKeys, keys: make(map[string]bool, len(keys))}
+	for _, key := range keys {
+		if key == AllKeys {
+			m.all = true
+			continue
+		}
+		m.keys[key] = true
+	}
+	return m
+}
+
+// allows reports whether the request's API key matches
+func (m keyMatcher) allows(logCtx *RequestLogContext) bool {
+	if m.all {
+		return true
+	}
+	if _, ok := m.masterKeys.Match(logCtx.Token); ok {
+		return true
+	}
+	return m.matchesKey(logCtx.TokenInfo)
+}
+
+// matchesKey reports whether a LiteLLM key matches by its alias or team (info may be nil)
+func (m keyMatcher) matchesKey(info *litellmdb.TokenInfo) bool {
+	if m.all {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+	return (info.KeyAlias != "" && m.keys[info.KeyAlias]) || (info.TeamID != "" && m.keys[info.TeamID])
+}
+
+// attributionPolicy decides which requests receive attribution headers
+type attributionPolicy struct {
+	keyMatcher
+}
+
+// newAttributionPolicy creates a policy for the given key aliases or team IDs ("*" = all keys).
+// Requests authenticated with the master key always receive the headers.
+func newAttributionPolicy(masterKeys *masterkey.Ring, keys []string) *attributionPolicy {
+	return &attributionPolicy{keyMatcher: newKeyMatcher(masterKeys, keys)}
+}
+
+// setHeaders sets the attribution headers from the request's final credential
+func (a *attributionPolicy) setHeaders(h http.Header, logCtx *RequestLogContext) {
+	if logCtx.Credential == nil || !a.allows(logCtx) {
+		return
+	}
+	h.Set(AttributionCredentialHeader, logCtx.Credential.Name)
+	h.Set(AttributionProviderHeader, string(logCtx.Credential.Type))
+	model := logCtx.RealModelID
+	if model == "" {
+		model = logCtx.ModelID
+	}
+	if model != "" {
+		h.Set(AttributionModelResolvedHeader, model)
+	}
+	h.Set(AttributionFallbackUsedHeader, strconv.FormatBool(logCtx.FallbackUsed || logCtx.Credential.IsFallback))
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BatchState holds the Anthropic batch records persisted across restarts
+// (server.batch_state_file) and handed off between instances
+type BatchState struct {
+	SavedAt time.Time              `json:"saved_at"`
+	Batches map[string]BatchRecord `json:"batches"` // Batch ID -> record
+}
+
+// SaveBatchState writes state to path as JSON. The file is replaced atomically, so a crash
+// while saving leaves the previous state intact.
+func SaveBatchState(path string, state BatchState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create batch state file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write batch state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write batch state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace batch state file: %w", err)
+	}
+	return nil
+}
+
+// LoadBatchState reads a state written by SaveBatchState. A missing file is an empty state.
+func LoadBatchState(path string) (BatchState, error) {
+	var state BatchState
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read batch state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse batch state file: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,764 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+const (
+	// AnthropicBatchesPath is the Anthropic Message Batches API endpoint prefix
+	AnthropicBatchesPath = "/v1/messages/batches"
+
+	// anthropicBatchCostMultiplier reflects the Message Batches API discount (50% of standard price)
+	anthropicBatchCostMultiplier = 0.5
+
+	// batchAffinityTTL is how long batch->credential affinity is kept.
+	// Anthropic keeps batch results available for 29 days.
+	batchAffinityTTL = 30 * 24 * time.Hour
+
+	// maxBatchResultLineBytes limits a single JSONL line buffered for usage extraction
+	maxBatchResultLineBytes = 16 * 1024 * 1024
+)
+
+// BatchRecord stores the credential a batch was created on and who created it, so that
+// follow-up requests hit the same upstream account, only the creator (or a master key)
+// reaches the batch and spend is attributed correctly
+type BatchRecord struct {
+	Credential     string    `json:"credential"`
+	Owner          string    `json:"owner,omitempty"` // SHA-256 of the creator's token ("" = unknown, master keys only)
+	KeyAlias       string    `json:"key_alias,omitempty"`
+	UserID         string    `json:"user_id,omitempty"`
+	UserEmail      string    `json:"user_email,omitempty"`
+	TeamID         string    `json:"team_id,omitempty"`
+	OrganizationID string    `json:"organization_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	SpendLogged    bool      `json:"spend_logged,omitempty"` // Spend of the results was logged
+}
+
+// newBatchRecord returns the record of a batch created on credential by the caller of logCtx
+func newBatchRecord(credential string, logCtx *RequestLogContext) *BatchRecord {
+	rec := &BatchRecord{Credential: credential, Owner: batchOwner(logCtx.Token), CreatedAt: utils.NowUTC()}
+	if info := logCtx.TokenInfo; info != nil {
+		rec.KeyAlias = info.KeyAlias
+		rec.UserID = info.UserID
+		rec.UserEmail = info.UserEmail
+		rec.TeamID = info.TeamID
+		rec.OrganizationID = info.OrganizationID
+	}
+	return rec
+}
+
+// batchOwner returns the owner of batches created with token ("" for no token). For LiteLLM
+// virtual keys (sk-...) this is the LiteLLM token hash, so it doubles as the spend log api_key.
+func batchOwner(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenInfo returns the spend attribution of the creator (nil if unknown)
+func (rec BatchRecord) tokenInfo() *litellmdb.TokenInfo {
+	if rec.KeyAlias == "" && rec.UserID == "" && rec.UserEmail == "" && rec.TeamID == "" && rec.OrganizationID == "" {
+		return nil
+	}
+	return &litellmdb.TokenInfo{
+		Token:          rec.Owner,
+		KeyAlias:       rec.KeyAlias,
+		UserID:         rec.UserID,
+		UserEmail:      rec.UserEmail,
+		TeamID:         rec.TeamID,
+		OrganizationID: rec.OrganizationID,
+	}
+}
+
+// batchAffinityStore is a batch ID -> record mapping. With a state file (server.batch_state_file)
+// it is saved on every change and survives restarts; otherwise unknown batches are located by
+// probing all anthropic credentials, and only master keys reach them.
+type batchAffinityStore struct {
+	mu      sync.Mutex
+	records map[string]*BatchRecord
+	path    string // State file ("" = not persisted)
+	logger  *slog.Logger
+}
+
+func newBatchAffinityStore(path string, logger *slog.Logger) *batchAffinityStore {
+	s := &batchAffinityStore{records: make(map[string]*BatchRecord), path: path, logger: logger}
+	if path == "" {
+		return s
+	}
+	state, err := LoadBatchState(path)
+	if err != nil {
+		logger.Warn("Failed to load batch state, starting with no batch records", "batch_state_file", path, "error", err)
+		return s
+	}
+	s.restore(state, func(string) bool { return true })
+	return s
+}
+
+// set stores a record and prunes expired entries
+func (s *batchAffinityStore) set(batchID string, rec *BatchRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := utils.NowUTC()
+	for id, existing := range s.records {
+		if now.Sub(existing.CreatedAt) > batchAffinityTTL {
+			delete(s.records, id)
+		}
+	}
+	s.records[batchID] = rec
+	s.save()
+}
+
+// get returns a copy of the record for batchID
+func (s *batchAffinityStore) get(batchID string) (BatchRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[batchID]
+	if !ok {
+		return BatchRecord{}, false
+	}
+	return *rec, true
+}
+
+// markSpendLogged flips the SpendLogged flag. Returns true only for the first caller,
+// so spend for a batch is logged once even if results are downloaded repeatedly.
+func (s *batchAffinityStore) markSpendLogged(batchID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[batchID]
+	if !ok || rec.SpendLogged {
+		return false
+	}
+	rec.SpendLogged = true
+	s.save()
+	return true
+}
+
+// len returns the number of tracked batches
+func (s *batchAffinityStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// snapshot returns a copy of all records
+func (s *batchAffinityStore) snapshot() BatchState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+func (s *batchAffinityStore) snapshotLocked() BatchState {
+	state := BatchState{SavedAt: utils.NowUTC(), Batches: make(map[string]BatchRecord, len(s.records))}
+	for id, rec := range s.records {
+		state.Batches[id] = *rec
+	}
+	return state
+}
+
+// restore adds the records of state, replacing those of the same batches, and saves them to
+// the state file. Expired records and records of credentials for which known returns false
+// are skipped. Returns the number of restored records.
+func (s *batchAffinityStore) restore(state BatchState, known func(credential string) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := utils.NowUTC()
+	restored := 0
+	for id, rec := range state.Batches {
+		if !known(rec.Credential) || now.Sub(rec.CreatedAt) > batchAffinityTTL {
+			continue
+		}
+		s.records[id] = &rec
+		restored++
+	}
+	if restored > 0 {
+		s.save()
+	}
+	return restored
+}
+
+// save writes the records to the state file; must be called with s.mu held
+func (s *batchAffinityStore) save() {
+	if s.path == "" {
+		return
+	}
+	if err := SaveBatchState(s.path, s.snapshotLocked()); err != nil {
+		s.logger.Warn("Failed to save batch state", "batch_state_file", s.path, "error", err)
+	}
+}
+
+// IsAnthropicBatchPath reports whether path belongs to the Anthropic Message Batches API
+func IsAnthropicBatchPath(path string) bool {
+	return path == AnthropicBatchesPath || strings.HasPrefix(path, AnthropicBatchesPath+"/")
+}
+
+// ProxyAnthropicBatches handles the Anthropic Message Batches API:
+//
+//	POST   /v1/messages/batches                  - create (credential selected by first request's model)
+//	GET    /v1/messages/batches                  - list (merged across anthropic credentials)
+//	GET    /v1/messages/batches/{id}             - retrieve
+//	DELETE /v1/messages/batches/{id}             - delete
+//	POST   /v1/messages/batches/{id}/cancel      - cancel
+//	GET    /v1/messages/batches/{id}/results     - download JSONL results (spend is logged here)
+//
+// Requests and responses use the native Anthropic format (no OpenAI conversion). Batches are
+// only visible to the key that created them and to master keys.
+func (p *Proxy) ProxyAnthropicBatches(w http.ResponseWriter, r *http.Request) {
+	logCtx := &RequestLogContext{
+		RequestID:  uuid.New().String(),
+		StartTime:  utils.NowUTC(),
+		Request:    r,
+		Status:     "unknown",
+		baseLogger: p.logger,
+	}
+
+	// Anthropic SDK clients authenticate with x-api-key instead of a Bearer token
+	if r.Header.Get("Authorization") == "" {
+		if apiKey := r.Header.Get("X-Api-Key"); apiKey != "" {
+			r.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+
+	r = initializeRetryTrackingContext(r)
+	if !p.authenticateRequest(w, r, logCtx, p.isLiteLLMHealthy()) {
+		return
+	}
+	if !p.applyTenant(w, r, logCtx) {
+		return
+	}
+	r = withRequestLog(r, logCtx)
+	caller := p.newBatchCaller(logCtx)
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, AnthropicBatchesPath), "/")
+	parts := []string{}
+	if rest != "" {
+		parts = strings.Split(rest, "/")
+	}
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		p.createAnthropicBatch(w, r, logCtx)
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		p.listAnthropicBatches(w, r, caller)
+	case len(parts) == 1 && (r.Method == http.MethodGet || r.Method == http.MethodDelete):
+		p.forwardAnthropicBatchOperation(w, r, caller, parts[0], false)
+	case len(parts) == 2 && parts[1] == "cancel" && r.Method == http.MethodPost:
+		p.forwardAnthropicBatchOperation(w, r, caller, parts[0], false)
+	case len(parts) == 2 && parts[1] == "results" && r.Method == http.MethodGet:
+		p.forwardAnthropicBatchOperation(w, r, caller, parts[0], true)
+	case len(parts) > 2 || (len(parts) == 2 && parts[1] != "cancel" && parts[1] != "results"):
+		WriteErrorNotFound(w, "Not Found")
+	default:
+		WriteJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed", errorTypeForStatus(http.StatusMethodNotAllowed), nil, nil)
+	}
+}
+
+// batchCaller is the client operating on batches
+type batchCaller struct {
+	owner string // batchOwner of the client's token
+	admin bool   // Authenticated with a master key: reaches all batches
+}
+
+// newBatchCaller returns the caller of an authenticated batch request
+func (p *Proxy) newBatchCaller(logCtx *RequestLogContext) batchCaller {
+	_, admin := p.masterKeys.Match(logCtx.Token)
+	if !admin {
+		_, admin = p.tenants.matchMasterKey(logCtx.Token)
+	}
+	return batchCaller{owner: batchOwner(logCtx.Token), admin: admin}
+}
+
+// canAccess reports whether the caller may operate on the batch of rec
+func (c batchCaller) canAccess(rec BatchRecord) bool {
+	return c.admin || (c.owner != "" && rec.Owner == c.owner)
+}
+
+// anthropicCredentials returns the anthropic-type credentials available to r: those of its
+// tenant (see applyTenant)
+func (p *Proxy) anthropicCredentials(r *http.Request) []config.CredentialConfig {
+	triedCreds := GetTried(r.Context())
+	var creds []config.CredentialConfig
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if cred.Type == config.ProviderTypeAnthropic && !triedCreds[cred.Name] {
+			creds = append(creds, cred)
+		}
+	}
+	return creds
+}
+
+// createAnthropicBatch resolves model aliases in every batch request, selects an anthropic
+// credential for the first request's model and records batch affinity on success.
+func (p *Proxy) createAnthropicBatch(w http.ResponseWriter, r *http.Request, logCtx *RequestLogContext) {
+	maxBodyBytes := int64(p.maxBodySizeMB) * 1024 * 1024
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		WriteErrorBadRequest(w, "Failed to read request body")
+		return
+	}
+	if int64(len(body)) > maxBodyBytes {
+		WriteErrorTooLarge(w, "Request Entity Too Large")
+		return
+	}
+
+	var batchReq map[string]interface{}
+	if err := json.Unmarshal(body, &batchReq); err != nil {
+		WriteErrorBadRequest(w, "Invalid JSON body")
+		return
+	}
+	requests, _ := batchReq["requests"].([]interface{})
+	if len(requests) == 0 {
+		WriteErrorBadRequest(w, "requests field is required")
+		return
+	}
+
+	// Resolve aliases in each request; the first model drives credential selection
+	modelID := ""
+	for _, item := range requests {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		params, ok := itemMap["params"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		model, _ := params["model"].(string)
+		if model == "" {
+			continue
+		}
+		if resolved, isAlias := p.modelManager.ResolveAlias(model); isAlias {
+			model = resolved
+		}
+		if modelID == "" {
+			modelID = model
+		}
+		if realName, hasReal := p.modelManager.GetRealModelName(model); hasReal {
+			model = realName
+		}
+		params["model"] = model
+	}
+	if modelID == "" {
+		WriteErrorBadRequest(w, "model field is required in requests[].params")
+		return
+	}
+
+	body, err = json.Marshal(batchReq)
+	if err != nil {
+		WriteErrorInternal(w, "Internal Server Error")
+		return
+	}
+
+	// Only anthropic credentials of the request's tenant support Message Batches
+	allowed := make(map[string]bool)
+	for _, cred := range p.anthropicCredentials(r) {
+		allowed[cred.Name] = true
+	}
+	exclude := make(map[string]bool)
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if !allowed[cred.Name] {
+			exclude[cred.Name] = true
+		}
+	}
+	cred, err := p.balancer.NextForModelExcluding(modelID, exclude)
+	if err != nil {
+		logCtx.Logger().Error("No anthropic credentials available for batch", "model", modelID, "error", err)
+		WriteErrorRateLimit(w, fmt.Sprintf("No anthropic credentials available for batch: %v", err))
+		return
+	}
+
+	start := utils.NowUTC()
+	resp, err := p.doAnthropicBatchRequest(r, cred, r.Method, r.URL.Path, r.URL.RawQuery, body)
+	if err != nil {
+		logCtx.CredentialLogger(cred.Name).Error("Anthropic batch create failed", "error", err)
+		p.balancer.RecordResponse(cred.Name, modelID, http.StatusBadGateway)
+		p.metrics.RecordRequest(cred.Name, AnthropicBatchesPath, http.StatusBadGateway, time.Since(start))
+		WriteErrorBadGateway(w, "Bad Gateway")
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	p.balancer.RecordResponse(cred.Name, modelID, resp.StatusCode)
+	p.metrics.RecordRequest(cred.Name, AnthropicBatchesPath, resp.StatusCode, time.Since(start))
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, p.maxResponseBodySize))
+	if err != nil {
+		WriteErrorBadGateway(w, "Failed to read upstream response")
+		return
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		var created struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(respBody, &created) == nil && created.ID != "" {
+			p.batches.set(created.ID, newBatchRecord(cred.Name, logCtx))
+			logCtx.CredentialLogger(cred.Name).Info("Anthropic batch created",
+				"batch_id", created.ID,
+				"model", modelID,
+				"requests", len(requests),
+			)
+		}
+	}
+
+	p.copyResponseHeaders(w, resp.Header, cred.Name)
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(respBody)
+}
+
+// listAnthropicBatches merges batch lists from all anthropic credentials, newest first. Only
+// the caller's batches are listed, unless it is a master key.
+func (p *Proxy) listAnthropicBatches(w http.ResponseWriter, r *http.Request, caller batchCaller) {
+	creds := p.anthropicCredentials(r)
+	if len(creds) == 0 {
+		WriteErrorNotFound(w, "No anthropic credentials configured")
+		return
+	}
+
+	type listResponse struct {
+		Data    []json.RawMessage `json:"data"`
+		HasMore bool              `json:"has_more"`
+		FirstID *string           `json:"first_id"`
+		LastID  *string           `json:"last_id"`
+	}
+
+	merged := listResponse{Data: []json.RawMessage{}}
+	succeeded := 0
+	var lastStatus int
+	var lastBody []byte
+	for i := range creds {
+		cred := &creds[i]
+		resp, err := p.doAnthropicBatchRequest(r, cred, http.MethodGet, AnthropicBatchesPath, r.URL.RawQuery, nil)
+		if err != nil {
+			p.credentialLogger(r, cred.Name).Warn("Failed to list anthropic batches", "error", err)
+			continue
+		}
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, p.maxResponseBodySize))
+		_ = resp.Body.Close()
+		if readErr != nil {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastStatus, lastBody = resp.StatusCode, body
+			continue
+		}
+
+		var page listResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			continue
+		}
+		succeeded++
+		merged.HasMore = merged.HasMore || page.HasMore
+		for _, item := range page.Data {
+			if caller.admin || p.ownsBatch(caller, item) {
+				merged.Data = append(merged.Data, item)
+			}
+		}
+	}
+
+	if succeeded == 0 {
+		if lastStatus != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(lastStatus)
+			_, _ = w.Write(lastBody)
+			return
+		}
+		WriteErrorBadGateway(w, "Bad Gateway")
+		return
+	}
+
+	// Order by created_at descending like the upstream API
+	createdAt := func(raw json.RawMessage) string {
+		var item struct {
+			CreatedAt string `json:"created_at"`
+		}
+		_ = json.Unmarshal(raw, &item)
+		return item.CreatedAt
+	}
+	sort.SliceStable(merged.Data, func(i, j int) bool {
+		return createdAt(merged.Data[i]) > createdAt(merged.Data[j])
+	})
+
+	batchID := func(raw json.RawMessage) *string {
+		var item struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(raw, &item)
+		return &item.ID
+	}
+	if len(merged.Data) > 0 {
+		merged.FirstID = batchID(merged.Data[0])
+		merged.LastID = batchID(merged.Data[len(merged.Data)-1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(merged)
+}
+
+// ownsBatch reports whether the batch of a list item was created by the caller
+func (p *Proxy) ownsBatch(caller batchCaller, item json.RawMessage) bool {
+	var batch struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(item, &batch) != nil {
+		return false
+	}
+	rec, ok := p.batches.get(batch.ID)
+	return ok && caller.canAccess(rec)
+}
+
+// resolveBatchCredential returns the credential owning batchID, if the caller may access the
+// batch and the credential belongs to its tenant. If affinity is unknown (e.g. after restart without batch_state_file), anthropic
+// credentials are probed for master keys until one knows the batch; the owner of such a
+// batch is unknown, so other keys do not reach it.
+func (p *Proxy) resolveBatchCredential(r *http.Request, caller batchCaller, batchID string) (*config.CredentialConfig, bool) {
+	creds := p.anthropicCredentials(r)
+
+	if rec, ok := p.batches.get(batchID); ok {
+		if !caller.canAccess(rec) {
+			return nil, false
+		}
+		for i := range creds {
+			if creds[i].Name == rec.Credential {
+				return &creds[i], true
+			}
+		}
+		// A batch never moves to another credential
+		return nil, false
+	}
+	if !caller.admin {
+		return nil, false
+	}
+
+	// Probes only ever retrieve the batch, whatever the client's method
+	for i := range creds {
+		cred := &creds[i]
+		resp, err := p.doAnthropicBatchRequest(r, cred, http.MethodGet, AnthropicBatchesPath+"/"+batchID, "", nil)
+		if err != nil {
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			p.batches.set(batchID, &BatchRecord{Credential: cred.Name, CreatedAt: utils.NowUTC()})
+			return cred, true
+		}
+	}
+	return nil, false
+}
+
+// forwardAnthropicBatchOperation forwards a per-batch request to the credential that owns the batch.
+// Batches of other keys are answered like unknown batches.
+// For results downloads, usage is extracted while streaming and spend is logged once per batch.
+func (p *Proxy) forwardAnthropicBatchOperation(w http.ResponseWriter, r *http.Request, caller batchCaller, batchID string, isResults bool) {
+	cred, ok := p.resolveBatchCredential(r, caller, batchID)
+	if !ok {
+		WriteErrorNotFound(w, "Batch not found: "+batchID)
+		return
+	}
+
+	var body []byte
+	if r.Body != nil && r.Method == http.MethodPost {
+		body, _ = io.ReadAll(io.LimitReader(r.Body, int64(p.maxBodySizeMB)*1024*1024))
+	}
+
+	start := utils.NowUTC()
+	resp, err := p.doAnthropicBatchRequest(r, cred, r.Method, r.URL.Path, r.URL.RawQuery, body)
+	if err != nil {
+		p.credentialLogger(r, cred.Name).Error("Anthropic batch request failed", "batch_id", batchID, "error", err)
+		p.metrics.RecordRequest(cred.Name, AnthropicBatchesPath, http.StatusBadGateway, time.Since(start))
+		WriteErrorBadGateway(w, "Bad Gateway")
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	p.metrics.RecordRequest(cred.Name, AnthropicBatchesPath, resp.StatusCode, time.Since(start))
+
+	p.copyResponseHeaders(w, resp.Header, cred.Name)
+	w.WriteHeader(resp.StatusCode)
+
+	if !isResults || resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(w, io.LimitReader(resp.Body, p.maxResponseBodySize))
+		return
+	}
+
+	collector := newBatchUsageCollector()
+	if _, err := io.Copy(w, io.TeeReader(resp.Body, collector)); err != nil {
+		p.credentialLogger(r, cred.Name).Warn("Anthropic batch results stream interrupted", "batch_id", batchID, "error", err)
+		return
+	}
+	collector.flush()
+
+	p.logBatchSpend(r, cred, batchID, collector.usageByModel())
+}
+
+// logBatchSpend logs one spend entry per model for a completed batch (only on the first results
+// download), attributed to the batch's creator
+func (p *Proxy) logBatchSpend(r *http.Request, cred *config.CredentialConfig, batchID string, usage map[string]*converter.TokenUsage) {
+	if len(usage) == 0 || !p.batches.markSpendLogged(batchID) {
+		return
+	}
+	rec, _ := p.batches.get(batchID)
+
+	for model, modelUsage := range usage {
+		logCtx := &RequestLogContext{
+			RequestID:      batchID + ":" + model,
+			StartTime:      rec.CreatedAt,
+			Request:        r,
+			Token:          rec.Owner,
+			TokenInfo:      rec.tokenInfo(),
+			ModelID:        model,
+			RealModelID:    model,
+			Status:         "success",
+			HTTPStatus:     http.StatusOK,
+			TokenUsage:     modelUsage,
+			Credential:     cred,
+			SessionID:      batchID,
+			TargetURL:      cred.BaseURL,
+			CostMultiplier: anthropicBatchCostMultiplier,
+			baseLogger:     p.logger,
+		}
+		if err := p.logSpendToLiteLLMDB(logCtx); err != nil {
+			logCtx.Logger().Warn("Failed to queue batch spend log", "error", err, "batch_id", batchID)
+		}
+	}
+}
+
+// doAnthropicBatchRequest sends a request for the client request r to the anthropic credential
+func (p *Proxy) doAnthropicBatchRequest(r *http.Request, cred *config.CredentialConfig, method, path, rawQuery string, body []byte) (*http.Response, error) {
+	targetURL := strings.TrimSuffix(cred.BaseURL, "/") + path
+	if rawQuery != "" {
+		targetURL += "?" + rawQuery
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), method, targetURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	copyHeadersSkipAuth(req, r)
+	req.Header.Set("X-Api-Key", cred.APIKey)
+	if req.Header.Get(anthropicVersionHeader) == "" {
+		req.Header.Set(anthropicVersionHeader, cred.AnthropicAPIVersion())
+	}
+	setAnthropicBetaHeader(req, cred)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Del("Content-Length")
+	}
+
+	return p.doUpstream(req, cred)
+}
+
+// batchUsageCollector is an io.Writer that parses JSONL batch results line by line
+// and accumulates token usage of succeeded results per model.
+type batchUsageCollector struct {
+	buf     []byte
+	usage   map[string]*converter.TokenUsage
+	skipped bool // current line exceeded maxBatchResultLineBytes
+}
+
+func newBatchUsageCollector() *batchUsageCollector {
+	return &batchUsageCollector{usage: make(map[string]*converter.TokenUsage)}
+}
+
+func (c *batchUsageCollector) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			c.appendPartial(p)
+			break
+		}
+		c.appendPartial(p[:idx])
+		c.processLine()
+		p = p[idx+1:]
+	}
+	return n, nil
+}
+
+func (c *batchUsageCollector) appendPartial(p []byte) {
+	if c.skipped {
+		return
+	}
+	if len(c.buf)+len(p) > maxBatchResultLineBytes {
+		c.buf = c.buf[:0]
+		c.skipped = true
+		return
+	}
+	c.buf = append(c.buf, p...)
+}
+
+// flush processes a trailing line without newline
+func (c *batchUsageCollector) flush() {
+	c.processLine()
+}
+
+func (c *batchUsageCollector) processLine() {
+	defer func() {
+		c.buf = c.buf[:0]
+		c.skipped = false
+	}()
+	if c.skipped || len(bytes.TrimSpace(c.buf)) == 0 {
+		return
+	}
+
+	var line struct {
+		Result struct {
+			Type    string `json:"type"`
+			Message struct {
+				Model string `json:"model"`
+				Usage struct {
+					InputTokens              int `json:"input_tokens"`
+					OutputTokens             int `json:"output_tokens"`
+					CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+					CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(c.buf, &line); err != nil || line.Result.Type != "succeeded" {
+		return
+	}
+
+	model := line.Result.Message.Model
+	usage, ok := c.usage[model]
+	if !ok {
+		usage = &converter.TokenUsage{}
+		c.usage[model] = usage
+	}
+	u := line.Result.Message.Usage
+	usage.PromptTokens += u.InputTokens
+	usage.CompletionTokens += u.OutputTokens
+	usage.CachedInputTokens += u.CacheReadInputTokens
+	usage.CacheCreationTokens += u.CacheCreationInputTokens
+}
+
+func (c *batchUsageCollector) usageByModel() map[string]*converter.TokenUsage {
+	return c.usage
+}
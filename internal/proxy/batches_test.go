@@ -0,0 +1,408 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBatchResults = `{"custom_id":"a","result":{"type":"succeeded","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":2}}}}
+{"custom_id":"b","result":{"type":"errored","error":{"type":"invalid_request"}}}
+{"custom_id":"c","result":{"type":"succeeded","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":20,"output_tokens":7}}}}`
+
+// fakeAnthropicBatchServer serves a single batch owned by apiKey
+type fakeAnthropicBatchServer struct {
+	mu       sync.Mutex
+	apiKey   string
+	batchID  string
+	requests []string
+	bodies   []string
+}
+
+func (f *fakeAnthropicBatchServer) handler(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	f.bodies = append(f.bodies, string(body))
+	f.mu.Unlock()
+
+	if r.Header.Get("X-Api-Key") != f.apiKey || r.Header.Get("anthropic-version") == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == AnthropicBatchesPath:
+		_, _ = w.Write([]byte(`{"id":"` + f.batchID + `","type":"message_batch","processing_status":"in_progress"}`))
+	case r.Method == http.MethodGet && r.URL.Path == AnthropicBatchesPath:
+		_, _ = w.Write([]byte(`{"data":[{"id":"` + f.batchID + `","created_at":"2026-01-01T00:00:00Z"}],"has_more":false}`))
+	case r.URL.Path == AnthropicBatchesPath+"/"+f.batchID:
+		_, _ = w.Write([]byte(`{"id":"` + f.batchID + `","processing_status":"ended"}`))
+	case r.URL.Path == AnthropicBatchesPath+"/"+f.batchID+"/results":
+		w.Header().Set("Content-Type", "application/binary")
+		_, _ = w.Write([]byte(testBatchResults))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"not_found_error"}}`))
+	}
+}
+
+func (f *fakeAnthropicBatchServer) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func newBatchTestProxy(t *testing.T) (*Proxy, *fakeAnthropicBatchServer, *fakeAnthropicBatchServer) {
+	t.Helper()
+	first := &fakeAnthropicBatchServer{apiKey: "sk-ant-1", batchID: "msgbatch_1"}
+	second := &fakeAnthropicBatchServer{apiKey: "sk-ant-2", batchID: "msgbatch_2"}
+	firstServer := httptest.NewServer(http.HandlerFunc(first.handler))
+	secondServer := httptest.NewServer(http.HandlerFunc(second.handler))
+	t.Cleanup(firstServer.Close)
+	t.Cleanup(secondServer.Close)
+
+	prx := NewTestProxyBuilder().WithCredentials(
+		config.CredentialConfig{Name: "ant1", Type: config.ProviderTypeAnthropic, BaseURL: firstServer.URL, APIKey: "sk-ant-1", RPM: 100, TPM: 10000},
+		config.CredentialConfig{Name: "ant2", Type: config.ProviderTypeAnthropic, BaseURL: secondServer.URL, APIKey: "sk-ant-2", RPM: 100, TPM: 10000},
+	).Build()
+	return prx, first, second
+}
+
+func TestIsAnthropicBatchPath(t *testing.T) {
+	assert.True(t, IsAnthropicBatchPath("/v1/messages/batches"))
+	assert.True(t, IsAnthropicBatchPath("/v1/messages/batches/msgbatch_1/results"))
+	assert.False(t, IsAnthropicBatchPath("/v1/messages"))
+	assert.False(t, IsAnthropicBatchPath("/v1/messages/batchesx"))
+}
+
+func TestProxyAnthropicBatches_Unauthorized(t *testing.T) {
+	prx, _, _ := newBatchTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodGet, AnthropicBatchesPath, nil)
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestProxyAnthropicBatches_CreateRecordsAffinity(t *testing.T) {
+	prx, first, second := newBatchTestProxy(t)
+
+	body := `{"requests":[{"custom_id":"a","params":{"model":"claude-sonnet-4-5","max_tokens":10,"messages":[]}}]}`
+	req := httptest.NewRequest(http.MethodPost, AnthropicBatchesPath, strings.NewReader(body))
+	req.Header.Set("X-Api-Key", "sk-master")
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	batchID := created["id"].(string)
+
+	rec, ok := prx.batches.get(batchID)
+	require.True(t, ok)
+	assert.Equal(t, batchOwner("sk-master"), rec.Owner)
+
+	// Follow-up requests go only to the owning credential
+	owner, other := first, second
+	if rec.Credential == "ant2" {
+		owner, other = second, first
+	}
+	otherCalls := len(other.calls())
+
+	req = httptest.NewRequest(http.MethodGet, AnthropicBatchesPath+"/"+batchID, nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w = httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, owner.calls(), http.MethodGet+" "+AnthropicBatchesPath+"/"+batchID)
+	assert.Len(t, other.calls(), otherCalls)
+}
+
+func TestProxyAnthropicBatches_UnknownBatchProbesCredentials(t *testing.T) {
+	prx, _, second := newBatchTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodGet, AnthropicBatchesPath+"/msgbatch_2", nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	rec, ok := prx.batches.get("msgbatch_2")
+	require.True(t, ok)
+	assert.Equal(t, "ant2", rec.Credential)
+	assert.Empty(t, rec.Owner, "owner of a probed batch is unknown")
+	assert.NotEmpty(t, second.calls())
+
+	req = httptest.NewRequest(http.MethodGet, AnthropicBatchesPath+"/msgbatch_missing", nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w = httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestProxyAnthropicBatches_ResultsLogsSpendOnce(t *testing.T) {
+	prx, _, _ := newBatchTestProxy(t)
+	prx.batches.set("msgbatch_1", &BatchRecord{Credential: "ant1", CreatedAt: time.Now()})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, AnthropicBatchesPath+"/msgbatch_1/results", nil)
+		req.Header.Set("Authorization", "Bearer sk-master")
+		w := httptest.NewRecorder()
+		prx.ProxyAnthropicBatches(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, testBatchResults, w.Body.String(), "results must be passed through unchanged")
+	}
+
+	rec, ok := prx.batches.get("msgbatch_1")
+	require.True(t, ok)
+	assert.True(t, rec.SpendLogged)
+	assert.False(t, prx.batches.markSpendLogged("msgbatch_1"))
+}
+
+func TestProxyAnthropicBatches_ProbeOnlyRetrieves(t *testing.T) {
+	prx, first, second := newBatchTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodDelete, AnthropicBatchesPath+"/msgbatch_2?beta=true", nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, []string{"GET " + AnthropicBatchesPath + "/msgbatch_2"}, first.calls(), "the other credential is only probed")
+	assert.Equal(t, []string{
+		"GET " + AnthropicBatchesPath + "/msgbatch_2",
+		"DELETE " + AnthropicBatchesPath + "/msgbatch_2?beta=true",
+	}, second.calls())
+}
+
+// batchKeysDB authenticates sk-key-* tokens as LiteLLM virtual keys and records spend
+type batchKeysDB struct {
+	recordingSpendDB
+}
+
+func (m *batchKeysDB) IsHealthy() bool { return true }
+
+func (m *batchKeysDB) ValidateToken(ctx context.Context, rawToken string) (*litellmdb.TokenInfo, error) {
+	if !strings.HasPrefix(rawToken, "sk-key-") {
+		return nil, litellmdb.ErrTokenNotFound
+	}
+	return &litellmdb.TokenInfo{Token: litellmdb.HashToken(rawToken), KeyAlias: strings.TrimPrefix(rawToken, "sk-"), TeamID: "team-a"}, nil
+}
+
+func sendBatchRequest(prx *Proxy, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	return w
+}
+
+func TestProxyAnthropicBatches_Ownership(t *testing.T) {
+	prx, _, _ := newBatchTestProxy(t)
+	db := &batchKeysDB{recordingSpendDB{NoopManager: litellmdb.NewNoopManager()}}
+	prx.LiteLLMDB = db
+	prx.spendSinksEnabled = true
+	prx.batches.set("msgbatch_1", newBatchRecord("ant1", &RequestLogContext{
+		Token:     "sk-key-a",
+		TokenInfo: &litellmdb.TokenInfo{KeyAlias: "key-a", TeamID: "team-a"},
+	}))
+
+	for _, path := range []string{"/msgbatch_1", "/msgbatch_1/results"} {
+		w := sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath+path, "sk-key-b", "")
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+	w := sendBatchRequest(prx, http.MethodPost, AnthropicBatchesPath+"/msgbatch_1/cancel", "sk-key-b", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendBatchRequest(prx, http.MethodDelete, AnthropicBatchesPath+"/msgbatch_1", "sk-key-b", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath+"/msgbatch_2", "sk-key-b", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "unknown batches are not probed for virtual keys")
+
+	listIDs := func(token string) []string {
+		w := sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath, token, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var list struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		ids := []string{}
+		for _, item := range list.Data {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	assert.Empty(t, listIDs("sk-key-b"))
+	assert.Equal(t, []string{"msgbatch_1"}, listIDs("sk-key-a"))
+	assert.ElementsMatch(t, []string{"msgbatch_1", "msgbatch_2"}, listIDs("sk-master"))
+
+	w = sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath+"/msgbatch_1/results", "sk-key-a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	w = sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath+"/msgbatch_1", "sk-master", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	require.Len(t, db.entries, 1)
+	assert.Equal(t, litellmdb.HashToken("sk-key-a"), db.entries[0].APIKey)
+	assert.Equal(t, "team-a", db.entries[0].TeamID)
+}
+
+func TestProxyAnthropicBatches_TenantCredentials(t *testing.T) {
+	prx, first, second := newBatchTestProxy(t)
+	prx.tenants = newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", MasterKeys: []string{"alpha-key"}, Credentials: []string{"ant1"}},
+		{Name: "beta", MasterKeys: []string{"beta-key"}, Credentials: []string{"ant2"}},
+	})
+
+	body := `{"requests":[{"custom_id":"a","params":{"model":"claude-sonnet-4-5","max_tokens":10,"messages":[]}}]}`
+	for i := 0; i < 3; i++ {
+		w := sendBatchRequest(prx, http.MethodPost, AnthropicBatchesPath, "alpha-key", body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	assert.Len(t, first.calls(), 3)
+	assert.Empty(t, second.calls(), "batches are created on the tenant's credentials only")
+
+	// Batches of another tenant's credentials are neither found nor probed
+	w := sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath+"/msgbatch_2", "alpha-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath+"/msgbatch_1/results", "beta-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, second.calls())
+
+	w = sendBatchRequest(prx, http.MethodGet, AnthropicBatchesPath, "alpha-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "msgbatch_1")
+	assert.NotContains(t, w.Body.String(), "msgbatch_2")
+	assert.Empty(t, second.calls())
+
+	// The server master key uses the credentials of no tenant
+	w = sendBatchRequest(prx, http.MethodPost, AnthropicBatchesPath, "sk-master", body)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Len(t, first.calls(), 5)
+	assert.Empty(t, second.calls())
+}
+
+func TestBatchAffinityStore_StateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batches.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	store := newBatchAffinityStore(path, logger)
+	store.set("msgbatch_1", newBatchRecord("ant1", &RequestLogContext{
+		Token:     "sk-key-a",
+		TokenInfo: &litellmdb.TokenInfo{KeyAlias: "key-a", TeamID: "team-a"},
+	}))
+	store.set("msgbatch_old", &BatchRecord{Credential: "ant1", CreatedAt: time.Now().Add(-2 * batchAffinityTTL)})
+	require.True(t, store.markSpendLogged("msgbatch_1"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-key-a", "tokens are stored hashed")
+
+	restarted := newBatchAffinityStore(path, logger)
+	assert.Equal(t, 1, restarted.len(), "expired records are dropped")
+	rec, ok := restarted.get("msgbatch_1")
+	require.True(t, ok)
+	assert.Equal(t, "ant1", rec.Credential)
+	assert.Equal(t, batchOwner("sk-key-a"), rec.Owner)
+	assert.True(t, rec.SpendLogged)
+	assert.False(t, restarted.markSpendLogged("msgbatch_1"), "spend is not logged again after a restart")
+	require.NotNil(t, rec.tokenInfo())
+	assert.Equal(t, "key-a", rec.tokenInfo().KeyAlias)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+	assert.Zero(t, newBatchAffinityStore(path, logger).len())
+}
+
+func TestProxyAnthropicBatches_ListMergesCredentials(t *testing.T) {
+	prx, _, _ := newBatchTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodGet, AnthropicBatchesPath, nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 2)
+}
+
+func TestProxyAnthropicBatches_MethodAndPathErrors(t *testing.T) {
+	prx, _, _ := newBatchTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodPut, AnthropicBatchesPath+"/msgbatch_1", nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w := httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, AnthropicBatchesPath+"/msgbatch_1/unknown", nil)
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w = httptest.NewRecorder()
+	prx.ProxyAnthropicBatches(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBatchUsageCollector(t *testing.T) {
+	collector := newBatchUsageCollector()
+
+	// Write in small chunks to exercise line reassembly
+	data := []byte(testBatchResults)
+	for len(data) > 0 {
+		n := 7
+		if n > len(data) {
+			n = len(data)
+		}
+		_, err := collector.Write(data[:n])
+		require.NoError(t, err)
+		data = data[n:]
+	}
+	collector.flush()
+
+	usage := collector.usageByModel()
+	require.Len(t, usage, 1)
+	assert.Equal(t, 30, usage["claude-sonnet-4-5"].PromptTokens)
+	assert.Equal(t, 12, usage["claude-sonnet-4-5"].CompletionTokens)
+	assert.Equal(t, 2, usage["claude-sonnet-4-5"].CachedInputTokens)
+}
+
+func TestCalculateRequestCost_Multiplier(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	prx.priceRegistry = models.NewModelPriceRegistry()
+	prx.priceRegistry.Update(map[string]*models.ModelPrice{
+		"claude-sonnet-4-5": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+
+	logCtx := &RequestLogContext{
+		ModelID:    "claude-sonnet-4-5",
+		TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}
+	assert.InDelta(t, 0.02, prx.calculateRequestCost(logCtx), 1e-9)
+
+	logCtx.CostMultiplier = anthropicBatchCostMultiplier
+	assert.InDelta(t, 0.01, prx.calculateRequestCost(logCtx), 1e-9)
+}
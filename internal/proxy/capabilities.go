@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// DroppedParamsHeader lists request parameters removed because the credential cannot honour them
+const DroppedParamsHeader = "X-Router-Dropped-Params"
+
+// selectCapableCredential picks a credential that supports every parameter the request uses
+// (server.unsupported_params: reroute). Returns nil when rerouting is disabled or not needed,
+// or when no capable credential is available; the caller then selects as usual and the
+// unsupported parameters are dropped. share limits the credential RPM/TPM the request may use.
+// Parameters learned by param_negotiation count as unsupported.
+func (p *Proxy) selectCapableCredential(r *http.Request, modelID string, body []byte, share float64) *config.CredentialConfig {
+	if p.unsupportedParams != config.UnsupportedParamsReroute {
+		return nil
+	}
+	requested := converter.RequestedParams(body)
+
+	incapable := make(map[string]bool)
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if len(converter.UnsupportedParams(&cred, requested)) > 0 || len(p.paramNegotiator.unsupported(cred.Name, modelID, requested)) > 0 {
+			incapable[cred.Name] = true
+		}
+	}
+	if len(incapable) == 0 {
+		return nil
+	}
+
+	triedCreds := GetTried(r.Context())
+	exclude := make(map[string]bool, len(incapable)+len(triedCreds))
+	for name := range triedCreds {
+		exclude[name] = true
+	}
+	for name := range incapable {
+		exclude[name] = true
+	}
+	cred, err := p.balancer.Select(modelID, balancer.SelectOptions{Exclude: exclude, Share: share})
+	if err != nil {
+		p.requestLogger(r).Debug("No capable credential for requested params, params will be dropped",
+			"error", err)
+		return nil
+	}
+
+	// Keep same-type retries on capable credentials as well
+	for name := range incapable {
+		triedCreds[name] = true
+	}
+	p.credentialLogger(r, cred.Name).Debug("Rerouted request to credential supporting requested params")
+	return cred
+}
+
+// dropUnsupportedParams removes parameters cred cannot honour, configured or learned for the
+// model by param_negotiation, and reports them in DroppedParamsHeader (cleared when nothing
+// is dropped, e.g. after a retry)
+func (p *Proxy) dropUnsupportedParams(w http.ResponseWriter, body []byte, requested map[string]bool, cred *config.CredentialConfig, modelID string, logCtx *RequestLogContext) []byte {
+	dropped := converter.UnsupportedParams(cred, requested)
+	for _, param := range p.paramNegotiator.unsupported(cred.Name, modelID, requested) {
+		if !slices.Contains(dropped, param) {
+			dropped = append(dropped, param)
+			monitoring.NegotiatedParamsDroppedTotal.WithLabelValues(cred.Name, param).Inc()
+		}
+	}
+	if len(dropped) == 0 {
+		w.Header().Del(DroppedParamsHeader)
+		return body
+	}
+
+	logCtx.CredentialLogger(cred.Name).Debug("Dropping params unsupported by credential",
+		"type", cred.Type, "params", dropped)
+	w.Header().Set(DroppedParamsHeader, strings.Join(dropped, ", "))
+	return converter.DropParams(body, dropped)
+}
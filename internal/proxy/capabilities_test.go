@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const logprobsRequest = `{"model":"claude-sonnet-4-5","logprobs":true,"top_logprobs":2,"messages":[{"role":"user","content":"Hi"}]}`
+
+func newAnthropicUpstream(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Hello"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":1}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProxyRequest_DropsUnsupportedParams(t *testing.T) {
+	var calls int32
+	upstream := newAnthropicUpstream(t, &calls)
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("ant", config.ProviderTypeAnthropic, upstream.URL, "sk-ant").
+		WithMasterKey("master-key").
+		Build()
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(logprobsRequest))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "logprobs, top_logprobs", w.Header().Get(DroppedParamsHeader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestProxyRequest_ReroutesUnsupportedParams(t *testing.T) {
+	var anthropicCalls, openaiCalls int32
+	anthropicUpstream := newAnthropicUpstream(t, &anthropicCalls)
+
+	var openaiBody string
+	openaiUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&openaiCalls, 1)
+		data, _ := io.ReadAll(r.Body)
+		openaiBody = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	t.Cleanup(openaiUpstream.Close)
+
+	prx := NewTestProxyBuilder().
+		WithCredentials(
+			config.CredentialConfig{Name: "ant", Type: config.ProviderTypeAnthropic, BaseURL: anthropicUpstream.URL, APIKey: "sk-ant", RPM: 100, TPM: 10000},
+			config.CredentialConfig{Name: "oai", Type: config.ProviderTypeOpenAI, BaseURL: openaiUpstream.URL, APIKey: "sk-oai", RPM: 100, TPM: 10000},
+		).
+		WithMasterKey("master-key").
+		Build()
+	prx.unsupportedParams = config.UnsupportedParamsReroute
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(logprobsRequest))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get(DroppedParamsHeader))
+	}
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&anthropicCalls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&openaiCalls))
+	assert.Contains(t, openaiBody, `"logprobs":true`)
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+)
+
+// chargebackPolicy selects the chargeback rate of a request (chargeback)
+type chargebackPolicy struct {
+	rules []chargebackRule
+}
+
+type chargebackRule struct {
+	keys          keyMatcher      // Key aliases and team IDs ("*" = all keys)
+	credentials   map[string]bool // nil = all credentials
+	markupPercent float64
+	feePerRequest float64
+}
+
+// chargebackCharge is the charged cost of a request, logged next to its provider cost
+type chargebackCharge struct {
+	Spend         float64 // Provider cost plus markup and fee (USD)
+	MarkupPercent float64
+	Fee           float64 // USD
+}
+
+func newChargebackPolicy(cfg config.ChargebackConfig) *chargebackPolicy {
+	policy := &chargebackPolicy{rules: make([]chargebackRule, 0, len(cfg.Rules))}
+	for _, rule := range cfg.Rules {
+		compiled := chargebackRule{
+			keys:          newKeyMatcher(nil, rule.Keys),
+			markupPercent: rule.MarkupPercent,
+			feePerRequest: rule.FeePerRequest,
+		}
+		if len(rule.Credentials) > 0 {
+			compiled.credentials = make(map[string]bool, len(rule.Credentials))
+			for _, cred := range rule.Credentials {
+				compiled.credentials[cred] = true
+			}
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy
+}
+
+// charge returns the charged cost of a request with the provider cost cost: the first rule
+// matching the key and credential applies. The fee is charged for successful requests only.
+// Returns nil if no rule applies or c is nil.
+func (c *chargebackPolicy) charge(info *litellmdb.TokenInfo, credential string, cost float64, success bool) *chargebackCharge {
+	if c == nil {
+		return nil
+	}
+	for _, rule := range c.rules {
+		credentialMatch := rule.credentials == nil || rule.credentials[credential]
+		if !rule.keys.matchesKey(info) || !credentialMatch {
+			continue
+		}
+		charge := &chargebackCharge{
+			Spend:         cost * (1 + rule.markupPercent/100),
+			MarkupPercent: rule.markupPercent,
+		}
+		if success {
+			charge.Fee = rule.feePerRequest
+			charge.Spend += rule.feePerRequest
+		}
+		return charge
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargebackPolicy_Charge(t *testing.T) {
+	policy := newChargebackPolicy(config.ChargebackConfig{Enabled: true, Rules: []config.ChargebackRuleConfig{
+		{Keys: []string{"team-research"}, Credentials: []string{"azure_ptu"}, FeePerRequest: 0.5},
+		{Keys: []string{"team-research", "ci-bot"}, MarkupPercent: 20, FeePerRequest: 0.001},
+		{Keys: []string{AllKeys}, MarkupPercent: 10},
+	}})
+	research := &litellmdb.TokenInfo{TeamID: "team-research"}
+
+	assert.Equal(t, &chargebackCharge{Spend: 0.5, Fee: 0.5}, policy.charge(research, "azure_ptu", 0, true), "credential rules come first")
+
+	charge := policy.charge(research, "openai_main", 1, true)
+	require.NotNil(t, charge)
+	assert.InDelta(t, 1.201, charge.Spend, 1e-9)
+	assert.Equal(t, 20.0, charge.MarkupPercent)
+	assert.Equal(t, 0.001, charge.Fee)
+
+	charge = policy.charge(&litellmdb.TokenInfo{KeyAlias: "ci-bot"}, "openai_main", 1, false)
+	require.NotNil(t, charge)
+	assert.InDelta(t, 1.2, charge.Spend, 1e-9, "failed requests pay no fee")
+	assert.Zero(t, charge.Fee)
+
+	charge = policy.charge(nil, "openai_main", 2, true)
+	require.NotNil(t, charge)
+	assert.InDelta(t, 2.2, charge.Spend, 1e-9)
+
+	assert.Nil(t, newChargebackPolicy(config.ChargebackConfig{}).charge(research, "openai_main", 1, true))
+	var disabled *chargebackPolicy
+	assert.Nil(t, disabled.charge(research, "openai_main", 1, true))
+}
+
+func TestLogSpend_Chargeback(t *testing.T) {
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	db := &recordingSpendDB{NoopManager: litellmdb.NewNoopManager()}
+
+	prx := New(&Config{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(false),
+		LiteLLMDB:     db,
+		PriceRegistry: registry,
+		Chargeback: config.ChargebackConfig{Enabled: true, Rules: []config.ChargebackRuleConfig{
+			{Keys: []string{"team-1"}, MarkupPercent: 50, FeePerRequest: 0.01},
+		}},
+	})
+
+	logCtx := func(requestID, teamID string) *RequestLogContext {
+		return &RequestLogContext{
+			RequestID:  requestID,
+			StartTime:  time.Now(),
+			Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+			Token:      "sk-test",
+			ModelID:    "gpt-4o",
+			HTTPStatus: http.StatusOK,
+			TokenInfo:  &litellmdb.TokenInfo{TeamID: teamID},
+			Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+			TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+		}
+	}
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-charged", "team-1")))
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-other", "team-2")))
+	require.Len(t, db.entries, 2)
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(db.entries[0].Metadata), &metadata))
+	assert.InDelta(t, 0.02, db.entries[0].Spend, 1e-9, "spend keeps the provider cost")
+	assert.InDelta(t, 0.04, metadata["chargeback_spend"], 1e-9)
+	assert.Equal(t, 50.0, metadata["chargeback_markup_percent"])
+	assert.Equal(t, 0.01, metadata["chargeback_fee"])
+
+	metadata = nil
+	require.NoError(t, json.Unmarshal([]byte(db.entries[1].Metadata), &metadata))
+	assert.NotContains(t, metadata, "chargeback_spend", "keys without a rule are not charged back")
+}
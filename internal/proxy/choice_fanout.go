@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+)
+
+// errChoiceFanOutLimit is returned when a credential has no RPM/TPM headroom left for the extra
+// requests of a fanned out request
+var errChoiceFanOutLimit = errors.New("no rate limit headroom for the fanned out requests")
+
+// choiceFanOut returns the number of upstream requests a chat request with n choices is sent
+// as on cred (n_fanout): n for providers returning one choice per request, 1 otherwise
+func (p *Proxy) choiceFanOut(cred *config.CredentialConfig, n int) int {
+	if n < 2 || n > p.maxFanOutChoices || !converter.ReturnsSingleChoice(cred.Type) {
+		return 1
+	}
+	return n
+}
+
+// reserveChoiceFanOut records the extra requests of a request fanned out to n requests against
+// the RPM/TPM limits of cred, model and pool; the first one was recorded when cred was selected.
+// Reports false, recording nothing, if the limits have no room left for all of them.
+func (p *Proxy) reserveChoiceFanOut(cred *config.CredentialConfig, modelID string, n int) bool {
+	return p.rateLimiter.TryAllowAllN(cred.Name, modelID, n-1)
+}
+
+// doChoiceFanOut sends n copies of an upstream request in parallel. If all of them succeed, the
+// returned response carries the JSON array of their decoded bodies, which ResponseTo merges into
+// one response; otherwise the first failed response or error is returned. Each body is read up
+// to maxBodySize.
+func (p *Proxy) doChoiceFanOut(req *http.Request, body []byte, cred *config.CredentialConfig, n int, maxBodySize int64) (*http.Response, error) {
+	log := p.credentialLogger(req, cred.Name)
+	type result struct {
+		resp *http.Response
+		body []byte
+		err  error
+	}
+	results := make([]result, n)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			upstreamReq := req.Clone(req.Context())
+			upstreamReq.Body = io.NopCloser(bytes.NewReader(body))
+			upstreamReq.ContentLength = int64(len(body))
+
+			resp, err := p.doUpstream(upstreamReq, cred)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			defer func() {
+				if closeErr := resp.Body.Close(); closeErr != nil {
+					log.Error("Failed to close fanned out response body", "error", closeErr)
+				}
+			}()
+			results[i].resp = resp
+			results[i].body, results[i].err = p.readLimitedResponseBody(resp.Body, maxBodySize, log)
+		}()
+	}
+	wg.Wait()
+
+	bodies := make([]string, 0, n)
+	for _, res := range results {
+		if res.err != nil {
+			return nil, res.err
+		}
+		if res.resp.StatusCode < 200 || res.resp.StatusCode >= 300 {
+			res.resp.Body = io.NopCloser(bytes.NewReader(res.body))
+			return res.resp, nil
+		}
+		bodies = append(bodies, decodeResponseBody(res.body, res.resp.Header.Get("Content-Encoding"), p.maxResponseBodySize))
+	}
+
+	first := results[0].resp
+	header := first.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	log.Debug("Fanned out request for n choices", "requests", n)
+	return &http.Response{
+		StatusCode: first.StatusCode,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("[" + strings.Join(bodies, ",") + "]")),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_ChoiceFanOut(t *testing.T) {
+	var calls atomic.Int32
+	var streamed atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			streamed.Store(true)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"id":"msg_%d","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"answer"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`, call)
+	}))
+	t.Cleanup(upstream.Close)
+
+	newProxy := func(rpm, maxN int) *Proxy {
+		prx := NewTestProxyBuilder().
+			WithCredentials(config.CredentialConfig{Name: "ant", Type: config.ProviderTypeAnthropic, APIKey: "sk-ant", BaseURL: upstream.URL, RPM: rpm}).
+			WithMasterKey("master-key").
+			Build()
+		prx.maxFanOutChoices = maxN
+		return prx
+	}
+	send := func(prx *Proxy, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		return w
+	}
+
+	t.Run("merges choices", func(t *testing.T) {
+		calls.Store(0)
+		w := send(newProxy(100, 4), `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"n":3}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, int32(3), calls.Load())
+
+		var resp openai.OpenAIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Choices, 3)
+		for i, choice := range resp.Choices {
+			assert.Equal(t, i, choice.Index)
+			assert.Equal(t, "answer", choice.Message.Content)
+		}
+		require.NotNil(t, resp.Usage)
+		assert.Equal(t, 30, resp.Usage.PromptTokens)
+		assert.Equal(t, 6, resp.Usage.CompletionTokens)
+	})
+
+	t.Run("emulates streams", func(t *testing.T) {
+		calls.Store(0)
+		streamed.Store(false)
+		w := send(newProxy(100, 4), `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"n":2,"stream":true}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, int32(2), calls.Load())
+		assert.False(t, streamed.Load(), "fanned out requests are sent without stream")
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"index":1`)
+		assert.Contains(t, w.Body.String(), "data: [DONE]")
+	})
+
+	t.Run("respects rate limits", func(t *testing.T) {
+		calls.Store(0)
+		w := send(newProxy(2, 4), `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"n":3}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+		assert.Zero(t, calls.Load())
+	})
+
+	t.Run("rejects n without fan-out", func(t *testing.T) {
+		calls.Store(0)
+		w := send(newProxy(100, 0), `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"n":2}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "one choice per request")
+		assert.Zero(t, calls.Load())
+
+		w = send(newProxy(100, 4), `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"n":5}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "must be at most 4")
+	})
+}
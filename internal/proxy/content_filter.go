@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+var contentFilterMarker = []byte(`"content_filter"`)
+
+// contentFilterCounter records a content filter event at most once per response.
+// Streamed responses are observed chunk by chunk.
+type contentFilterCounter struct {
+	model    string
+	credName string
+	counted  bool
+}
+
+// observe inspects an OpenAI-format response body or stream chunk for finish_reason content_filter
+func (c *contentFilterCounter) observe(chunk []byte) {
+	if c.counted || !bytes.Contains(chunk, contentFilterMarker) {
+		return
+	}
+	for _, payload := range extractJSONPayloadsFromStreamChunk(chunk) {
+		if isContentFiltered(payload) {
+			c.counted = true
+			monitoring.ContentFilterEventsTotal.WithLabelValues(c.model, c.credName).Inc()
+			return
+		}
+	}
+}
+
+// isContentFiltered reports whether any choice of a Chat Completions payload was content filtered
+func isContentFiltered(payload []byte) bool {
+	var data struct {
+		Choices []struct {
+			FinishReason *string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return false
+	}
+	for _, choice := range data.Choices {
+		if choice.FinishReason != nil && *choice.FinishReason == "content_filter" {
+			return true
+		}
+	}
+	return false
+}
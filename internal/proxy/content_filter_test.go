@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentFilterCounter(t *testing.T) {
+	metric := monitoring.ContentFilterEventsTotal.WithLabelValues("gemini-filter-test", "vertex")
+	before := testutil.ToFloat64(metric)
+
+	counter := &contentFilterCounter{model: "gemini-filter-test", credName: "vertex"}
+	counter.observe([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":null}]}` + "\n\n"))
+	counter.observe([]byte(`data: {"choices":[{"index":0,"delta":{"content":"content_filter"},"finish_reason":null}]}` + "\n\n"))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metric)-before)
+
+	counter.observe([]byte(`data: {"choices":[{"index":0,"delta":{},"finish_reason":"content_filter"}]}` + "\n\n"))
+	counter.observe([]byte(`data: {"choices":[{"index":0,"delta":{},"finish_reason":"content_filter"}]}` + "\n\ndata: [DONE]\n\n"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metric)-before, "counted once per response")
+
+	// Non-streaming bodies
+	(&contentFilterCounter{model: "gemini-filter-test", credName: "vertex"}).observe(
+		[]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason": "content_filter"}]}`))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metric)-before)
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/contextwindow"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// ContextTruncatedHeader reports how many messages context management removed from the
+// request, e.g. "3" or "3; summarized" when they were replaced with a summary
+const ContextTruncatedHeader = "X-Router-Context-Truncated"
+
+// internalRequestKey marks requests the router sends to itself (conversation summaries).
+// They skip the fair scheduler (the caller already holds a slot) and context management.
+type internalRequestKey struct{}
+
+func isInternalRequest(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalRequestKey{}).(bool)
+	return internal
+}
+
+// modelContextLimit returns the max input tokens of a model from the price registry (0 = unknown)
+func (p *Proxy) modelContextLimit(modelID, realModelID string) int {
+	if p.priceRegistry == nil {
+		return 0
+	}
+	price := p.priceRegistry.GetPrice(realModelID)
+	if price == nil && realModelID != modelID {
+		price = p.priceRegistry.GetPrice(modelID)
+	}
+	if price == nil {
+		return 0
+	}
+	return int(price.MaxInputTokens)
+}
+
+// fitContextWindow removes or summarizes the oldest messages of a Chat Completions request
+// that exceeds the model context or the configured prompt budget.
+// On summary errors the messages are only removed and the request proceeds.
+func (p *Proxy) fitContextWindow(w http.ResponseWriter, r *http.Request, body []byte, modelID, realModelID string, logCtx *RequestLogContext) []byte {
+	if p.contextManager == nil || isInternalRequest(r.Context()) || !strings.Contains(r.URL.Path, "/chat/completions") {
+		return body
+	}
+	budget := p.contextManager.Budget(p.modelContextLimit(modelID, realModelID))
+	if budget <= 0 {
+		return body
+	}
+
+	newBody, result, err := p.contextManager.Fit(r.Context(), body, budget, p.summarizer(r))
+	if err != nil {
+		logCtx.Logger().Warn("Context management failed to summarize, removed messages without summary",
+			"error", err,
+		)
+	}
+	if result.Dropped == 0 {
+		return body
+	}
+
+	strategy := contextwindow.StrategyTruncate
+	header := strconv.Itoa(result.Dropped)
+	if result.Summarized {
+		strategy = contextwindow.StrategySummarize
+		header += "; summarized"
+	}
+	w.Header().Set(ContextTruncatedHeader, header)
+	monitoring.ContextTruncationsTotal.WithLabelValues(modelID, strategy).Inc()
+	logCtx.Logger().Info("Fitted conversation into prompt budget",
+		"strategy", strategy,
+		"dropped_messages", result.Dropped,
+		"budget", budget,
+		"tokens_before", result.TokensBefore,
+		"tokens_after", result.TokensAfter,
+	)
+	return newBody
+}
+
+// summarizer sends summary requests through the router itself with the caller's credentials,
+// so they are routed, rate limited and billed like any other request.
+// Signed inter-router requests carry no API key to send them with: their messages are only
+// removed (nil summarizer).
+func (p *Proxy) summarizer(r *http.Request) contextwindow.Summarizer {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil
+	}
+	return func(ctx context.Context, body []byte) ([]byte, error) {
+		req, err := http.NewRequestWithContext(context.WithValue(ctx, internalRequestKey{}, true),
+			http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", auth)
+
+		rec := newBufferedResponseWriter()
+		p.ProxyRequest(rec, req)
+		if rec.status != http.StatusOK {
+			msg := rec.body.String()
+			if len(msg) > 200 {
+				msg = msg[:200]
+			}
+			return nil, fmt.Errorf("summary request failed with status %d: %s", rec.status, msg)
+		}
+		return rec.body.Bytes(), nil
+	}
+}
+
+// bufferedResponseWriter collects an internal request's response in memory
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) WriteHeader(statusCode int) {
+	b.status = statusCode
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
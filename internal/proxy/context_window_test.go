@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/contextwindow"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func longConversationRequest(t *testing.T) string {
+	t.Helper()
+	long := strings.Repeat("abc ", 100)
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "gpt-4o",
+		"messages": []map[string]string{
+			{"role": "system", "content": "You are helpful."},
+			{"role": "user", "content": long},
+			{"role": "assistant", "content": long},
+			{"role": "user", "content": "Latest question"},
+		},
+	})
+	require.NoError(t, err)
+	return string(body)
+}
+
+// newContextUpstream answers summary requests (model gpt-4o-mini) with a summary and
+// records the messages of every other request
+func newContextUpstream(t *testing.T, summaryStatus int) (*httptest.Server, *[][]map[string]interface{}) {
+	t.Helper()
+	var mu sync.Mutex
+	var received [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var req struct {
+			Model    string                   `json:"model"`
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		_ = json.Unmarshal(data, &req)
+
+		w.Header().Set("Content-Type", "application/json")
+		content := "Hello"
+		if req.Model == "gpt-4o-mini" {
+			if summaryStatus != http.StatusOK {
+				w.WriteHeader(summaryStatus)
+				_, _ = w.Write([]byte(`{"error":{"message":"bad request"}}`))
+				return
+			}
+			content = "Earlier the user sent abc."
+		} else {
+			mu.Lock()
+			received = append(received, req.Messages)
+			mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"` + content + `"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func TestProxyRequest_ContextManagementTruncates(t *testing.T) {
+	upstream, received := newContextUpstream(t, http.StatusOK)
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("oai", config.ProviderTypeOpenAI, upstream.URL, "sk-oai").
+		WithMasterKey("master-key").
+		Build()
+	prx.contextManager = contextwindow.New(contextwindow.Config{MaxPromptTokens: 150})
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(longConversationRequest(t)))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "1", w.Header().Get(ContextTruncatedHeader))
+	require.Len(t, *received, 1)
+	messages := (*received)[0]
+	require.Len(t, messages, 3)
+	assert.Equal(t, "system", messages[0]["role"])
+	assert.Equal(t, "assistant", messages[1]["role"])
+}
+
+func TestProxyRequest_ContextManagementSummarizes(t *testing.T) {
+	upstream, received := newContextUpstream(t, http.StatusOK)
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("oai", config.ProviderTypeOpenAI, upstream.URL, "sk-oai").
+		WithMasterKey("master-key").
+		Build()
+	prx.contextManager = contextwindow.New(contextwindow.Config{
+		Strategy:        contextwindow.StrategySummarize,
+		MaxPromptTokens: 50,
+		SummaryModel:    "gpt-4o-mini",
+	})
+	// The summary request must not wait for the slot held by the request it summarizes
+	prx.scheduler = scheduler.NewFairScheduler(scheduler.Config{MaxInFlight: 1, MaxQueue: 0})
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(longConversationRequest(t)))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "2; summarized", w.Header().Get(ContextTruncatedHeader))
+	require.Len(t, *received, 1)
+	messages := (*received)[0]
+	require.Len(t, messages, 3)
+	assert.Equal(t, "Summary of the earlier conversation:\nEarlier the user sent abc.", messages[1]["content"])
+	assert.Equal(t, "Latest question", messages[2]["content"])
+}
+
+func TestProxyRequest_ContextManagementSignedRequestTruncates(t *testing.T) {
+	upstream, received := newContextUpstream(t, http.StatusOK)
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("oai", config.ProviderTypeOpenAI, upstream.URL, "sk-oai").
+		WithMasterKey("master-key").
+		Build()
+	prx.routerVerifier = httputil.NewRequestVerifier(testInterRouterSecret, 0)
+	prx.contextManager = contextwindow.New(contextwindow.Config{
+		Strategy:        contextwindow.StrategySummarize,
+		MaxPromptTokens: 50,
+		SummaryModel:    "gpt-4o-mini",
+	})
+
+	req := newSignedRequest(t, testInterRouterSecret, longConversationRequest(t))
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "2", w.Header().Get(ContextTruncatedHeader), "no summary is requested without the caller's API key")
+	require.Len(t, *received, 1)
+	assert.Len(t, (*received)[0], 2)
+}
+
+func TestProxyRequest_ContextManagementSummaryFailure(t *testing.T) {
+	upstream, received := newContextUpstream(t, http.StatusBadRequest)
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("oai", config.ProviderTypeOpenAI, upstream.URL, "sk-oai").
+		WithMasterKey("master-key").
+		Build()
+	prx.contextManager = contextwindow.New(contextwindow.Config{
+		Strategy:        contextwindow.StrategySummarize,
+		MaxPromptTokens: 50,
+		SummaryModel:    "gpt-4o-mini",
+	})
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(longConversationRequest(t)))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "2", w.Header().Get(ContextTruncatedHeader))
+	require.Len(t, *received, 1)
+	assert.Len(t, (*received)[0], 2)
+}
+
+func TestProxyRequest_ContextManagementWithinBudget(t *testing.T) {
+	upstream, received := newContextUpstream(t, http.StatusOK)
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("oai", config.ProviderTypeOpenAI, upstream.URL, "sk-oai").
+		WithMasterKey("master-key").
+		Build()
+	prx.contextManager = contextwindow.New(contextwindow.Config{MaxPromptTokens: 10000})
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(longConversationRequest(t)))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get(ContextTruncatedHeader))
+	require.Len(t, *received, 1)
+	assert.Len(t, (*received)[0], 4)
+}
@@ -0,0 +1,32 @@
+package proxy
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// checkConversion schema-checks a converted request body (conversion_validation). Malformed
+// conversions are logged and forwarded, or with reject returned as an error: the
+// conversion is deterministic, so another credential of the same type would not help.
+func (p *Proxy) checkConversion(conv *converter.ProviderConverter, body []byte, cred *config.CredentialConfig, logCtx *RequestLogContext) error {
+	if !p.conversionCheck.Enabled {
+		return nil
+	}
+	err := conv.ValidateRequest(body)
+	if err == nil {
+		return nil
+	}
+
+	action := "forwarded"
+	if p.conversionCheck.Reject {
+		action = "rejected"
+	}
+	monitoring.MalformedConversionsTotal.WithLabelValues(string(cred.Type), action).Inc()
+	logCtx.CredentialLogger(cred.Name).Warn("Converted request failed validation",
+		"type", cred.Type, "error", err, "action", action)
+	if p.conversionCheck.Reject {
+		return err
+	}
+	return nil
+}
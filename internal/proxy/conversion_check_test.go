@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyContentRequest converts to an Anthropic message with empty content, which the API rejects
+const emptyContentRequest = `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":""}]}`
+
+func TestProxyRequest_ConversionValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		check     config.ConversionCheckConfig
+		body      string
+		wantCode  int
+		wantCalls int32
+		action    string // Label of the malformed conversion counted, "" = none
+	}{
+		{"disabled", config.ConversionCheckConfig{}, emptyContentRequest, http.StatusOK, 1, ""},
+		{"log only", config.ConversionCheckConfig{Enabled: true}, emptyContentRequest, http.StatusOK, 1, "forwarded"},
+		{"reject", config.ConversionCheckConfig{Enabled: true, Reject: true}, emptyContentRequest, http.StatusInternalServerError, 0, "rejected"},
+		{"valid conversion", config.ConversionCheckConfig{Enabled: true, Reject: true},
+			`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Hi"}]}`, http.StatusOK, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			upstream := newAnthropicUpstream(t, &calls)
+			prx := NewTestProxyBuilder().
+				WithSingleCredential("ant", config.ProviderTypeAnthropic, upstream.URL, "sk-ant").
+				WithMasterKey("master-key").
+				Build()
+			prx.conversionCheck = tt.check
+
+			forwarded := testutil.ToFloat64(monitoring.MalformedConversionsTotal.WithLabelValues("anthropic", "forwarded"))
+			rejected := testutil.ToFloat64(monitoring.MalformedConversionsTotal.WithLabelValues("anthropic", "rejected"))
+
+			req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer master-key")
+			w := httptest.NewRecorder()
+			prx.ProxyRequest(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+
+			wantForwarded, wantRejected := forwarded, rejected
+			switch tt.action {
+			case "forwarded":
+				wantForwarded++
+			case "rejected":
+				wantRejected++
+			}
+			assert.Equal(t, wantForwarded, testutil.ToFloat64(monitoring.MalformedConversionsTotal.WithLabelValues("anthropic", "forwarded")))
+			assert.Equal(t, wantRejected, testutil.ToFloat64(monitoring.MalformedConversionsTotal.WithLabelValues("anthropic", "rejected")))
+		})
+	}
+}
+
+func TestProxyRequest_ConversionRequestErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantError string // Error code of the response
+		wantParam string
+	}{
+		{"unsupported content type",
+			`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":[{"type":"input_file","file_id":"f1"}]}]}`,
+			http.StatusUnprocessableEntity, "unsupported_content_type", "messages[0].content[0].type"},
+		{"invalid tool schema",
+			`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Hi"}],"tools":[{"type":"function","function":{"name":"f","parameters":{"type":"array"}}}]}`,
+			http.StatusUnprocessableEntity, "invalid_tool_schema", "tools[0].function.parameters.type"},
+		{"unsupported param",
+			`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Hi"}],"tool_choice":"sometimes"}`,
+			http.StatusUnprocessableEntity, "unsupported_param", "tool_choice"},
+		{"invalid request",
+			`{"model":"claude-sonnet-4-5","messages":{"role":"user"}}`,
+			http.StatusBadRequest, "invalid_request", "messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			upstream := newAnthropicUpstream(t, &calls)
+			prx := NewTestProxyBuilder().
+				WithSingleCredential("ant", config.ProviderTypeAnthropic, upstream.URL, "sk-ant").
+				WithMasterKey("master-key").
+				Build()
+
+			req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer master-key")
+			w := httptest.NewRecorder()
+			prx.ProxyRequest(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Zero(t, atomic.LoadInt32(&calls), "the request is not sent upstream")
+
+			var resp APIErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.NotNil(t, resp.Error.Code)
+			require.NotNil(t, resp.Error.Param)
+			assert.Equal(t, tt.wantError, *resp.Error.Code)
+			assert.Equal(t, tt.wantParam, *resp.Error.Param)
+			assert.Equal(t, "invalid_request_error", resp.Error.Type)
+			assert.Contains(t, resp.Error.Message, tt.wantParam)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/converter/responses"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// costCeilingPolicy maps API keys to the highest cost a single request may incur
+// (max_cost_per_request)
+type costCeilingPolicy struct {
+	defaultCeiling float64            // Ceiling of keys without an entry (0 = none)
+	keys           map[string]float64 // Key alias or team ID -> ceiling in USD
+}
+
+func newCostCeilingPolicy(cfg config.MaxCostPerRequestConfig) *costCeilingPolicy {
+	return &costCeilingPolicy{defaultCeiling: cfg.Default, keys: cfg.Keys}
+}
+
+// ceilingFor returns the ceiling of the request's API key: its key alias entry, then its team
+// entry, then the default. Requests without a LiteLLM key (master key, signed inter-router
+// requests) have no ceiling (0).
+func (c *costCeilingPolicy) ceilingFor(logCtx *RequestLogContext) float64 {
+	info := logCtx.TokenInfo
+	if c == nil || info == nil {
+		return 0
+	}
+	for _, name := range []string{info.KeyAlias, info.TeamID} {
+		if ceiling, ok := c.keys[name]; ok && name != "" {
+			return ceiling
+		}
+	}
+	return c.defaultCeiling
+}
+
+// enforceCostCeiling rejects a request whose worst-case cost exceeds the ceiling of its key
+// with 400 Bad Request. Requests for models without a price are not limited.
+func (p *Proxy) enforceCostCeiling(w http.ResponseWriter, body []byte, modelID, realModelID string, logCtx *RequestLogContext) bool {
+	ceiling := p.costCeiling.ceilingFor(logCtx)
+	if ceiling <= 0 || p.priceRegistry == nil {
+		return true
+	}
+	price := p.priceRegistry.GetPrice(realModelID)
+	if price == nil && realModelID != modelID {
+		price = p.priceRegistry.GetPrice(modelID)
+	}
+	if price == nil {
+		logCtx.Logger().Debug("Model price not found, max_cost_per_request not enforced")
+		return true
+	}
+
+	cost, promptTokens, maxOutputTokens := worstCaseCost(price, body)
+	if cost <= ceiling {
+		return true
+	}
+
+	monitoring.MaxCostRejectedTotal.WithLabelValues(modelID).Inc()
+	logCtx.Logger().Info("Request rejected by max_cost_per_request",
+		"worst_case_cost", cost,
+		"max_cost_per_request", ceiling,
+		"prompt_tokens_estimate", promptTokens,
+		"max_output_tokens", maxOutputTokens,
+	)
+	msg := fmt.Sprintf("Request may cost up to $%.4f (about %d prompt tokens and up to %d output tokens of %s), "+
+		"exceeding the max_cost_per_request of $%.4f for this key; lower max_tokens or shorten the prompt",
+		cost, promptTokens, maxOutputTokens, modelID, ceiling)
+	logCtx.Status = "failure"
+	logCtx.HTTPStatus = http.StatusBadRequest
+	logCtx.ErrorMsg = msg
+	WriteErrorBadRequest(w, msg)
+	return false
+}
+
+// worstCaseCost estimates the highest cost of a request: its estimated prompt tokens (text, and
+// images counted as OpenAI tiles) plus max_completion_tokens (or max_tokens, or the model's max
+// output tokens) for each of the n requested choices at the model's prices. Without any output
+// limit only the prompt is priced. Responses API requests are estimated from their Chat Completions form.
+func worstCaseCost(price *models.ModelPrice, body []byte) (float64, int, int) {
+	if responses.IsResponsesAPI(body) {
+		if chatBody, err := responses.RequestToChat(body); err == nil {
+			body = chatBody
+		}
+	}
+
+	var req struct {
+		MaxTokens           int `json:"max_tokens"`
+		MaxCompletionTokens int `json:"max_completion_tokens"`
+	}
+	_ = json.Unmarshal(body, &req)
+	maxOutputTokens := req.MaxCompletionTokens
+	if maxOutputTokens <= 0 {
+		maxOutputTokens = req.MaxTokens
+	}
+	if maxOutputTokens <= 0 {
+		maxOutputTokens = int(price.MaxOutputTokens)
+	}
+	maxOutputTokens *= converter.RequestedChoices(body)
+
+	imageTokens := estimateImageTokens(body, "")
+	promptTokens := estimatePromptTokens(body) + imageTokens
+	cost := price.CalculateCost(&converter.TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: maxOutputTokens,
+		ImageTokens:      imageTokens,
+	})
+	return cost, promptTokens, maxOutputTokens
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostCeilingPolicy_CeilingFor(t *testing.T) {
+	policy := newCostCeilingPolicy(config.MaxCostPerRequestConfig{
+		Default: 0.5,
+		Keys:    map[string]float64{"batch-key": 5, "team-research": 2, "team-free": 0},
+	})
+
+	assert.Zero(t, policy.ceilingFor(&RequestLogContext{}), "master key has no ceiling")
+	assert.Equal(t, 5.0, policy.ceilingFor(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "batch-key", TeamID: "team-research"}}), "key alias wins over team")
+	assert.Equal(t, 2.0, policy.ceilingFor(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "other", TeamID: "team-research"}}))
+	assert.Zero(t, policy.ceilingFor(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{TeamID: "team-free"}}))
+	assert.Equal(t, 0.5, policy.ceilingFor(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "other"}}))
+
+	var disabled *costCeilingPolicy
+	assert.Zero(t, disabled.ceilingFor(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "batch-key"}}))
+}
+
+func TestWorstCaseCost(t *testing.T) {
+	price := &models.ModelPrice{InputCostPerToken: 0.000001, OutputCostPerToken: 0.00001, MaxOutputTokens: 16000}
+	prompt := `"messages":[{"role":"user","content":"` + strings.Repeat("abcd", 1000) + `"}]`
+
+	tests := []struct {
+		name       string
+		body       string
+		wantOutput int
+	}{
+		{name: "max_completion_tokens", body: `{"model":"m","max_completion_tokens":2000,"max_tokens":10,` + prompt + `}`, wantOutput: 2000},
+		{name: "max_tokens", body: `{"model":"m","max_tokens":500,` + prompt + `}`, wantOutput: 500},
+		{name: "model max output tokens", body: `{"model":"m",` + prompt + `}`, wantOutput: 16000},
+		{name: "several choices", body: `{"model":"m","max_tokens":500,"n":8,` + prompt + `}`, wantOutput: 4000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, promptTokens, maxOutput := worstCaseCost(price, []byte(tt.body))
+			assert.Equal(t, 1000, promptTokens)
+			assert.Equal(t, tt.wantOutput, maxOutput)
+			assert.InDelta(t, 0.001+float64(tt.wantOutput)*0.00001, cost, 1e-9)
+		})
+	}
+
+	_, promptTokens, maxOutput := worstCaseCost(price, []byte(`{"model":"m","input":"`+strings.Repeat("abcd", 100)+`","max_output_tokens":300}`))
+	assert.Equal(t, 100, promptTokens, "Responses API input is estimated")
+	assert.Equal(t, 300, maxOutput)
+}
+
+func TestEnforceCostCeiling(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	prx.priceRegistry = models.NewModelPriceRegistry()
+	prx.priceRegistry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.0000025, OutputCostPerToken: 0.00001},
+	})
+	prx.costCeiling = newCostCeilingPolicy(config.MaxCostPerRequestConfig{Default: 0.05})
+	logCtx := func() *RequestLogContext {
+		return &RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "app"}}
+	}
+	rejected := monitoring.MaxCostRejectedTotal.WithLabelValues("gpt-4o")
+	before := testutil.ToFloat64(rejected)
+
+	w := httptest.NewRecorder()
+	assert.True(t, prx.enforceCostCeiling(w, []byte(`{"model":"gpt-4o","max_tokens":1000,"messages":[]}`), "gpt-4o", "gpt-4o", logCtx()))
+
+	w = httptest.NewRecorder()
+	ctx := logCtx()
+	require.False(t, prx.enforceCostCeiling(w, []byte(`{"model":"gpt-4o","max_tokens":10000,"messages":[]}`), "gpt-4o", "gpt-4o", ctx))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "max_cost_per_request of $0.0500")
+	assert.Equal(t, http.StatusBadRequest, ctx.HTTPStatus)
+	assert.Equal(t, 1.0, testutil.ToFloat64(rejected)-before)
+
+	// Models without a price and requests without a LiteLLM key are not limited
+	assert.True(t, prx.enforceCostCeiling(httptest.NewRecorder(), []byte(`{"model":"unpriced","max_tokens":100000}`), "unpriced", "unpriced", logCtx()))
+	assert.True(t, prx.enforceCostCeiling(httptest.NewRecorder(), []byte(`{"model":"gpt-4o","max_tokens":100000}`), "gpt-4o", "gpt-4o", &RequestLogContext{}))
+}
+
+func TestWorstCaseCost_Images(t *testing.T) {
+	price := &models.ModelPrice{InputCostPerToken: 0.000001, InputCostPerImageToken: 0.000002, OutputCostPerToken: 0.00001}
+	body := `{"model":"m","max_tokens":100,"messages":[{"role":"user","content":[` +
+		`{"type":"image_url","image_url":{"url":"https://example.com/a.png"}},` +
+		`{"type":"image_url","image_url":{"url":"https://example.com/b.png","detail":"low"}}]}]}`
+
+	cost, promptTokens, _ := worstCaseCost(price, []byte(body))
+	assert.Equal(t, 1+765+85, promptTokens, "images are counted as OpenAI tiles")
+	assert.InDelta(t, 0.000001+850*0.000002+100*0.00001, cost, 1e-9)
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// CredentialHeader names the credential that served the request. It is the attribution
+// credential header, sent to requests authenticated with the master key even without
+// attribution_headers; credential names are not exposed to regular API keys (used by
+// cmd/loadgen for per-credential reports).
+const CredentialHeader = AttributionCredentialHeader
+
+// credentialHeaderWriter sets CredentialHeader and the attribution headers from the request's
+// final credential (after retries and fallbacks) right before the response headers are sent.
+// It also records when the first response body bytes are written (time to first token).
+type credentialHeaderWriter struct {
+	http.ResponseWriter
+	logCtx      *RequestLogContext
+	masterKeys  *masterkey.Ring
+	attribution *attributionPolicy // nil = attribution headers disabled
+	litellm     *litellmHeaders    // nil = x-litellm-* headers disabled
+	wroteHeader bool
+	statusCode  int  // Status sent to the client (0 = nothing written yet)
+	costPending bool // The x-litellm-* cost headers are sent as trailers by finish
+}
+
+func newCredentialHeaderWriter(w http.ResponseWriter, logCtx *RequestLogContext, masterKeys *masterkey.Ring, attribution *attributionPolicy, litellm *litellmHeaders) *credentialHeaderWriter {
+	return &credentialHeaderWriter{ResponseWriter: w, logCtx: logCtx, masterKeys: masterKeys, attribution: attribution, litellm: litellm}
+}
+
+func (cw *credentialHeaderWriter) setHeader() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if cw.logCtx.Credential != nil {
+		if _, ok := cw.masterKeys.Match(cw.logCtx.Token); ok {
+			cw.Header().Set(CredentialHeader, cw.logCtx.Credential.Name)
+		}
+	}
+	if cw.attribution != nil {
+		cw.attribution.setHeaders(cw.Header(), cw.logCtx)
+	}
+	if cw.litellm != nil {
+		cw.costPending = !cw.litellm.setHeaders(cw.Header(), cw.logCtx)
+	}
+}
+
+// finish sends the x-litellm-* cost of a streamed response as trailers; it must run before
+// the handler returns
+func (cw *credentialHeaderWriter) finish() {
+	if cw.costPending {
+		cw.costPending = false
+		cw.litellm.setTrailers(cw.Header(), cw.logCtx)
+	}
+}
+
+func (cw *credentialHeaderWriter) WriteHeader(statusCode int) {
+	cw.setHeader()
+	if cw.statusCode == 0 {
+		cw.statusCode = statusCode
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *credentialHeaderWriter) Write(p []byte) (int, error) {
+	cw.setHeader()
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	if len(p) > 0 && cw.logCtx.FirstByteTime.IsZero() {
+		cw.logCtx.FirstByteTime = utils.NowUTC()
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *credentialHeaderWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		cw.setHeader()
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *credentialHeaderWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_SetsCredentialHeaderForMasterKey(t *testing.T) {
+	prx := newChatProxy(t, []*int32{new(int32)}, nil)
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "oai1", w.Header().Get(CredentialHeader))
+}
+
+func TestCredentialHeaderWriter(t *testing.T) {
+	logCtx := &RequestLogContext{Token: "sk-user", Credential: &config.CredentialConfig{Name: "oai1"}}
+
+	w := httptest.NewRecorder()
+	cw := newCredentialHeaderWriter(w, logCtx, masterkey.NewRing("master-key", nil, 0), nil, nil)
+	_, _ = cw.Write([]byte("ok"))
+	assert.Empty(t, w.Header().Get(CredentialHeader), "not exposed to regular API keys")
+
+	logCtx.Token = "master-key"
+	w = httptest.NewRecorder()
+	cw = newCredentialHeaderWriter(w, logCtx, masterkey.NewRing("master-key", nil, 0), nil, nil)
+	cw.Flush()
+	assert.Equal(t, "oai1", w.Header().Get(CredentialHeader))
+	assert.Equal(t, w, cw.Unwrap())
+}
+
+func TestCredentialHeaderWriter_RecordsFirstByteTime(t *testing.T) {
+	logCtx := &RequestLogContext{}
+	cw := newCredentialHeaderWriter(httptest.NewRecorder(), logCtx, nil, nil, nil)
+
+	cw.WriteHeader(http.StatusOK)
+	_, _ = cw.Write(nil)
+	assert.True(t, logCtx.FirstByteTime.IsZero(), "headers and empty writes carry no content")
+
+	_, _ = cw.Write([]byte("data: {}\n\n"))
+	first := logCtx.FirstByteTime
+	require.False(t, first.IsZero())
+
+	_, _ = cw.Write([]byte("data: [DONE]\n\n"))
+	assert.Equal(t, first, logCtx.FirstByteTime, "only the first write is recorded")
+}
+
+func TestAttributionPolicy(t *testing.T) {
+	cred := &config.CredentialConfig{Name: "vertex1", Type: config.ProviderTypeVertexAI}
+	policy := newAttributionPolicy(masterkey.NewRing("master-key", nil, 0), []string{"team-qa", "debug-key"})
+
+	tests := []struct {
+		name   string
+		logCtx *RequestLogContext
+		want   bool
+	}{
+		{"master key", &RequestLogContext{Token: "master-key"}, true},
+		{"key alias", &RequestLogContext{Token: "sk-1", TokenInfo: &litellmdb.TokenInfo{KeyAlias: "debug-key"}}, true},
+		{"team", &RequestLogContext{Token: "sk-2", TokenInfo: &litellmdb.TokenInfo{KeyAlias: "other", TeamID: "team-qa"}}, true},
+		{"other key", &RequestLogContext{Token: "sk-3", TokenInfo: &litellmdb.TokenInfo{KeyAlias: "external"}}, false},
+		{"no token info", &RequestLogContext{Token: "sk-4"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.logCtx.Credential = cred
+			tt.logCtx.ModelID = "gemini-flash"
+			tt.logCtx.RealModelID = "gemini-2.5-flash"
+			h := http.Header{}
+			policy.setHeaders(h, tt.logCtx)
+			if !tt.want {
+				assert.Empty(t, h)
+				return
+			}
+			assert.Equal(t, "vertex1", h.Get(AttributionCredentialHeader))
+			assert.Equal(t, "vertex-ai", h.Get(AttributionProviderHeader))
+			assert.Equal(t, "gemini-2.5-flash", h.Get(AttributionModelResolvedHeader))
+			assert.Equal(t, "false", h.Get(AttributionFallbackUsedHeader))
+		})
+	}
+
+	h := http.Header{}
+	newAttributionPolicy(nil, []string{AllKeys}).setHeaders(h, &RequestLogContext{Token: "sk-5", Credential: cred, FallbackUsed: true})
+	assert.Equal(t, "true", h.Get(AttributionFallbackUsedHeader))
+}
+
+func TestProxyRequest_AttributionHeaders(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"message":"upstream error"}}`))
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	defer fallback.Close()
+
+	prx := NewTestProxyBuilder().
+		WithPrimaryAndFallback(primary.URL, fallback.URL).
+		WithMasterKey("master-key").
+		Build()
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+
+	w := send()
+	assert.Equal(t, "fallback", w.Header().Get(CredentialHeader), "always sent to the master key")
+	assert.Empty(t, w.Header().Get(AttributionProviderHeader), "disabled by default")
+
+	prx.attribution = newAttributionPolicy(masterkey.NewRing("master-key", nil, 0), nil)
+	w = send()
+	assert.Equal(t, "fallback", w.Header().Get(AttributionCredentialHeader))
+	assert.Equal(t, "proxy", w.Header().Get(AttributionProviderHeader))
+	assert.Equal(t, "gpt-4o", w.Header().Get(AttributionModelResolvedHeader))
+	assert.Equal(t, "true", w.Header().Get(AttributionFallbackUsedHeader))
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/auth"
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// DebugState is a point-in-time snapshot of proxy internals for the admin /debug/state endpoint
+type DebugState struct {
+	Credentials      int                         `json:"credentials"`
+	Available        int                         `json:"available_credentials"`
+	BannedPairs      int                         `json:"banned_pairs"`
+	Limiter          *ratelimit.LimiterStats     `json:"limiter,omitempty"`
+	ModelManager     *models.ManagerStats        `json:"model_manager,omitempty"`
+	VertexTokens     *auth.TokenManagerStats     `json:"vertex_tokens,omitempty"`
+	PriceRegistry    int                         `json:"price_registry_models"`
+	AnthropicBatches int                         `json:"anthropic_batches"`
+	AuthCache        *litellmdb.AuthCacheStats   `json:"auth_cache,omitempty"`
+	SpendLogger      *litellmdb.SpendLoggerStats `json:"spend_logger,omitempty"`
+}
+
+// DebugState collects limiter contents, queue depths and cache sizes.
+// Components that are not configured are omitted.
+func (p *Proxy) DebugState() DebugState {
+	state := DebugState{}
+
+	if p.balancer != nil {
+		state.Credentials = len(p.balancer.GetCredentialsSnapshot())
+		state.Available = p.balancer.GetAvailableCount()
+		state.BannedPairs = p.balancer.GetBannedCount()
+	}
+	if p.rateLimiter != nil {
+		stats := p.rateLimiter.Stats()
+		state.Limiter = &stats
+	}
+	if p.modelManager != nil {
+		stats := p.modelManager.Stats()
+		state.ModelManager = &stats
+	}
+	if p.tokenManager != nil {
+		stats := p.tokenManager.Stats()
+		state.VertexTokens = &stats
+	}
+	if p.priceRegistry != nil {
+		state.PriceRegistry = p.priceRegistry.Count()
+	}
+	if p.batches != nil {
+		state.AnthropicBatches = p.batches.len()
+	}
+	if p.LiteLLMDB != nil && p.LiteLLMDB.IsEnabled() {
+		authStats := p.LiteLLMDB.AuthCacheStats()
+		spendStats := p.LiteLLMDB.SpendLoggerStats()
+		state.AuthCache = &authStats
+		state.SpendLogger = &spendStats
+	}
+
+	return state
+}
+
+// DebugLimits is a point-in-time copy of the limiter, balancer and fail2ban state for the
+// admin /debug/limits endpoint. Every component is read under its own lock, so tests and
+// operators can inspect it while requests are served.
+type DebugLimits struct {
+	Timestamp   time.Time                         `json:"timestamp"`
+	Credentials map[string]ratelimit.LimiterState `json:"credentials"`
+	Models      map[string]ratelimit.LimiterState `json:"models"` // "credential:model" -> state
+	Balancer    balancer.CursorState              `json:"balancer"`
+	Fail2Ban    fail2ban.State                    `json:"fail2ban"`
+}
+
+// DebugLimits collects the credential and model limiter states, the round-robin cursor
+// and the fail2ban bans and failure counters
+func (p *Proxy) DebugLimits() DebugLimits {
+	limits := DebugLimits{Timestamp: utils.NowUTC()}
+
+	if p.rateLimiter != nil {
+		snapshot := p.rateLimiter.Limits()
+		limits.Credentials = snapshot.Credentials
+		limits.Models = snapshot.Models
+	}
+	if p.balancer != nil {
+		limits.Balancer = p.balancer.Cursor()
+		limits.Fail2Ban = p.balancer.Fail2Ban().Snapshot()
+	}
+
+	return limits
+}
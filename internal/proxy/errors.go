@@ -3,6 +3,9 @@ package proxy
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter"
 )
 
 // APIErrorResponse represents an OpenAI-compatible error response.
@@ -16,6 +19,7 @@ type APIError struct {
 	Type    string  `json:"type"`
 	Param   *string `json:"param"`
 	Code    *string `json:"code"`
+	Detail  string  `json:"detail,omitempty"` // Sanitized upstream error message (upstream_errors)
 }
 
 // errorTypeForStatus maps HTTP status codes to OpenAI error type strings.
@@ -103,6 +107,18 @@ func WriteErrorInternal(w http.ResponseWriter, message string) {
 	WriteJSONError(w, http.StatusInternalServerError, message, errorTypeForStatus(http.StatusInternalServerError), nil, nil)
 }
 
+// writeRequestError writes a request conversion error with its code and the offending field
+// as param, so clients can fix the payload
+func writeRequestError(w http.ResponseWriter, reqErr *converter.RequestError) {
+	code := reqErr.Code
+	var param *string
+	if reqErr.Param != "" {
+		param = &reqErr.Param
+	}
+	status := reqErr.StatusCode()
+	WriteJSONError(w, status, reqErr.Error(), errorTypeForStatus(status), param, &code)
+}
+
 // WriteErrorBadGateway writes a 502 Bad Gateway JSON error.
 func WriteErrorBadGateway(w http.ResponseWriter, message string) {
 	WriteJSONError(w, http.StatusBadGateway, message, errorTypeForStatus(http.StatusBadGateway), nil, nil)
@@ -112,3 +128,41 @@ func WriteErrorBadGateway(w http.ResponseWriter, message string) {
 func WriteErrorTimeout(w http.ResponseWriter, message string) {
 	WriteJSONError(w, http.StatusRequestTimeout, message, errorTypeForStatus(http.StatusRequestTimeout), nil, nil)
 }
+
+// CapacityErrorResponse is the error response returned when no credential can serve a request.
+type CapacityErrorResponse struct {
+	Error CapacityError `json:"error"`
+}
+
+// CapacityError extends APIError with why the model's credentials are unavailable.
+type CapacityError struct {
+	APIError
+	Constraint  string              `json:"constraint"`            // rate_limit, banned, proxy_unhealthy or no_credentials
+	RetryAfter  int                 `json:"retry_after,omitempty"` // Earliest estimated retry, in seconds (0 = unknown)
+	Credentials CapacityCredentials `json:"credentials"`
+	Queue       *CapacityQueue      `json:"queue,omitempty"` // Fair scheduler load, when enabled
+}
+
+// CapacityCredentials counts the model's candidate credentials by the constraint blocking them.
+type CapacityCredentials struct {
+	Eligible       int `json:"eligible"`
+	Banned         int `json:"banned"`
+	RateLimited    int `json:"rate_limited"`
+	ProxyUnhealthy int `json:"proxy_unhealthy"`
+}
+
+// CapacityQueue reports the fair scheduler's load.
+type CapacityQueue struct {
+	InFlight int `json:"in_flight"`
+	Queued   int `json:"queued"`
+}
+
+// WriteCapacityError writes a capacity error, with a Retry-After header when an estimate exists.
+func WriteCapacityError(w http.ResponseWriter, statusCode int, capErr CapacityError) {
+	if capErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(capErr.RetryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(CapacityErrorResponse{Error: capErr})
+}
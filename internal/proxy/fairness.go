@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/scheduler"
+)
+
+// admitRequest waits for a fair scheduler slot for the request's API key.
+// Returns a release func that must be called when the request is done (no-op when disabled).
+// Internal requests are admitted immediately: the request that issued them already holds a slot.
+func (p *Proxy) admitRequest(w http.ResponseWriter, r *http.Request, logCtx *RequestLogContext) (func(), bool) {
+	if p.scheduler == nil || isInternalRequest(r.Context()) {
+		return func() {}, true
+	}
+
+	var keyAlias, teamID string
+	if logCtx.TokenInfo != nil {
+		keyAlias = logCtx.TokenInfo.KeyAlias
+		teamID = logCtx.TokenInfo.TeamID
+	}
+
+	release, err := p.scheduler.Acquire(r.Context(), litellmdb.HashToken(logCtx.Token), p.scheduler.WeightFor(keyAlias, teamID))
+	if err == nil {
+		return release, true
+	}
+
+	logCtx.Status = "failure"
+	logCtx.HTTPStatus = http.StatusTooManyRequests
+	logCtx.ErrorMsg = "Fair scheduler: " + err.Error()
+	logCtx.Credential = &config.CredentialConfig{
+		Name: "system",
+		Type: config.ProviderTypeProxy,
+	}
+
+	if !errors.Is(err, scheduler.ErrQueueFull) && !errors.Is(err, scheduler.ErrQueueTimeout) {
+		// Client went away while queued
+		logCtx.Logger().Debug("Request cancelled while waiting for admission", "error", err)
+		return nil, false
+	}
+
+	logCtx.Logger().Warn("Request rejected by fair scheduler",
+		"error", err,
+	)
+	WriteErrorRateLimit(w, "Router is at capacity, please retry later")
+	return nil, false
+}
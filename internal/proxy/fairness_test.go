@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrchestrateRequest_FairScheduler(t *testing.T) {
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("test", config.ProviderTypeOpenAI, "http://test.local", "upstream-key").
+		WithMasterKey("master-key").
+		Build()
+	prx.scheduler = scheduler.NewFairScheduler(scheduler.Config{MaxInFlight: 1, MaxQueue: 0})
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		return req
+	}
+
+	prepared, ok := prx.orchestrateRequest(httptest.NewRecorder(), newRequest(), &RequestLogContext{})
+	require.True(t, ok)
+
+	// Router is at capacity and the queue is full
+	w := httptest.NewRecorder()
+	logCtx := &RequestLogContext{}
+	_, ok = prx.orchestrateRequest(w, newRequest(), logCtx)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "failure", logCtx.Status)
+
+	prepared.release()
+	inFlight, _ := prx.scheduler.Stats()
+	assert.Equal(t, 0, inFlight)
+}
+
+func TestOrchestrateRequest_FairSchedulerReleasesOnFailure(t *testing.T) {
+	prx := NewTestProxyBuilder().WithMasterKey("master-key").Build()
+	prx.scheduler = scheduler.NewFairScheduler(scheduler.Config{MaxInFlight: 1, MaxQueue: 1})
+
+	// No credentials: request fails after admission and must return its slot
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	_, ok := prx.orchestrateRequest(httptest.NewRecorder(), req, &RequestLogContext{})
+	require.False(t, ok)
+
+	inFlight, _ := prx.scheduler.Stats()
+	assert.Equal(t, 0, inFlight)
+
+	release, err := prx.scheduler.Acquire(context.Background(), "other", 1)
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,34 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/faultinject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_InjectedFaultRetriesNextCredential(t *testing.T) {
+	calls := []*int32{new(int32), new(int32)}
+	prx := newChatProxy(t, calls, nil)
+	prx.maxProviderRetries = 1
+	prx.client.Transport = faultinject.NewTransport(prx.client.Transport, map[string]faultinject.Rule{
+		"oai1": {RateLimitProbability: 1},
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "oai2", w.Header().Get(CredentialHeader))
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(calls[0]), "faulty credential never reaches its upstream")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls[1]))
+}
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// federationLimit accumulates a summed limit where any unlimited member makes the total unlimited
+type federationLimit struct {
+	total     int
+	unlimited bool
+}
+
+func (l *federationLimit) add(value int) {
+	if value < 0 {
+		l.unlimited = true
+		return
+	}
+	l.total += value
+}
+
+func (l *federationLimit) value() int {
+	if l.unlimited {
+		return -1
+	}
+	return l.total
+}
+
+// normalizeLimit maps non-positive limits to -1 (unlimited), matching rate limiter semantics
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+// effectiveLimit returns the stricter of a model limit and its credential limit (-1 = unlimited)
+func effectiveLimit(modelLimit, credLimit int) int {
+	modelLimit = normalizeLimit(modelLimit)
+	credLimit = normalizeLimit(credLimit)
+	switch {
+	case modelLimit == -1:
+		return credLimit
+	case credLimit == -1:
+		return modelLimit
+	case modelLimit < credLimit:
+		return modelLimit
+	default:
+		return credLimit
+	}
+}
+
+// headroom returns limit-current clamped to zero, or -1 if unlimited
+func headroom(limit, current int) int {
+	if limit < 0 {
+		return -1
+	}
+	if current >= limit {
+		return 0
+	}
+	return limit - current
+}
+
+// stricterHeadroom returns the smaller of two headroom values (-1 = unlimited)
+func stricterHeadroom(a, b int) int {
+	if a < 0 {
+		return b
+	}
+	if b < 0 || a < b {
+		return a
+	}
+	return b
+}
+
+// FederationLimits aggregates per-model capacity over all credentials for parent routers.
+// Remaining headroom of a credential:model pair is bounded by both the model limit and the
+// credential limit; banned pairs count towards credentials but contribute no capacity.
+func (p *Proxy) FederationLimits() *httputil.FederationResponse {
+	type modelAggregation struct {
+		stats        httputil.FederatedModelStats
+		limitRPM     federationLimit
+		limitTPM     federationLimit
+		remainingRPM federationLimit
+		remainingTPM federationLimit
+	}
+	aggregations := make(map[string]*modelAggregation)
+
+	for _, pair := range p.rateLimiter.GetAllModelPairs() {
+		agg, ok := aggregations[pair.Model]
+		if !ok {
+			agg = &modelAggregation{}
+			aggregations[pair.Model] = agg
+		}
+		agg.stats.Credentials++
+
+		if p.balancer.IsBanned(pair.Credential, pair.Model) {
+			continue
+		}
+		agg.stats.Available++
+
+		curRPM := p.rateLimiter.GetCurrentModelRPM(pair.Credential, pair.Model)
+		curTPM := p.rateLimiter.GetCurrentModelTPM(pair.Credential, pair.Model)
+		modelRPM := p.rateLimiter.GetModelLimitRPM(pair.Credential, pair.Model)
+		modelTPM := p.rateLimiter.GetModelLimitTPM(pair.Credential, pair.Model)
+		credRPM := p.rateLimiter.GetLimitRPM(pair.Credential)
+		credTPM := p.rateLimiter.GetLimitTPM(pair.Credential)
+
+		limitRPM := effectiveLimit(modelRPM, credRPM)
+		limitTPM := effectiveLimit(modelTPM, credTPM)
+
+		// Credential-level usage is shared by all models of the credential
+		remainingRPM := stricterHeadroom(
+			headroom(normalizeLimit(modelRPM), curRPM),
+			headroom(normalizeLimit(credRPM), p.rateLimiter.GetCurrentRPM(pair.Credential)),
+		)
+		remainingTPM := stricterHeadroom(
+			headroom(normalizeLimit(modelTPM), curTPM),
+			headroom(normalizeLimit(credTPM), p.rateLimiter.GetCurrentTPM(pair.Credential)),
+		)
+
+		agg.stats.CurrentRPM += curRPM
+		agg.stats.CurrentTPM += curTPM
+		agg.limitRPM.add(limitRPM)
+		agg.limitTPM.add(limitTPM)
+		agg.remainingRPM.add(remainingRPM)
+		agg.remainingTPM.add(remainingTPM)
+	}
+
+	models := make(map[string]httputil.FederatedModelStats, len(aggregations))
+	for model, agg := range aggregations {
+		stats := agg.stats
+		stats.LimitRPM = agg.limitRPM.value()
+		stats.LimitTPM = agg.limitTPM.value()
+		stats.RemainingRPM = agg.remainingRPM.value()
+		stats.RemainingTPM = agg.remainingTPM.value()
+		models[model] = stats
+	}
+
+	status := "healthy"
+	if p.balancer.GetAvailableCount() == 0 {
+		status = "unhealthy"
+	}
+
+	return &httputil.FederationResponse{
+		Status:  status,
+		Version: Version,
+		Models:  models,
+	}
+}
+
+// RateLimitHeadroom returns the remaining RPM/TPM and reset times of every credential and
+// credential:model pair, for clients that plan their submission rate
+func (p *Proxy) RateLimitHeadroom() ratelimit.HeadroomSnapshot {
+	if p.rateLimiter == nil {
+		return ratelimit.HeadroomSnapshot{
+			Timestamp:   utils.NowUTC(),
+			Credentials: map[string]ratelimit.Headroom{},
+			Models:      map[string]ratelimit.Headroom{},
+		}
+	}
+	return p.rateLimiter.Headroom()
+}
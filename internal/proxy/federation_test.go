@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/auth"
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveLimitAndHeadroom(t *testing.T) {
+	assert.Equal(t, 10, effectiveLimit(10, 100))
+	assert.Equal(t, 50, effectiveLimit(-1, 50))
+	assert.Equal(t, 10, effectiveLimit(10, 0))
+	assert.Equal(t, -1, effectiveLimit(-1, -1))
+
+	assert.Equal(t, 7, headroom(10, 3))
+	assert.Equal(t, 0, headroom(10, 15))
+	assert.Equal(t, -1, headroom(-1, 15))
+
+	assert.Equal(t, 3, stricterHeadroom(3, 7))
+	assert.Equal(t, 7, stricterHeadroom(-1, 7))
+	assert.Equal(t, -1, stricterHeadroom(-1, -1))
+}
+
+func TestFederationLimits(t *testing.T) {
+	logger := createHealthTestLogger()
+	f2b := fail2ban.New(1, 0, []int{500})
+	rl := ratelimit.New()
+
+	credentials := []config.CredentialConfig{
+		{Name: "a", APIKey: "k", BaseURL: "http://a", RPM: 100, TPM: 1000},
+		{Name: "b", APIKey: "k", BaseURL: "http://b", RPM: 5, TPM: -1},
+		{Name: "c", APIKey: "k", BaseURL: "http://c", RPM: 100, TPM: 1000},
+	}
+	bal := balancer.New(credentials, f2b, rl)
+
+	rl.AddModelWithTPM("a", "gpt-4o", 10, 500)
+	rl.AddModelWithTPM("b", "gpt-4o", 20, -1)
+	rl.AddModelWithTPM("c", "gpt-4o", 10, 500)
+	rl.AddModelWithTPM("a", "gpt-4o-mini", 10, 500)
+
+	// Usage on "a": 3 requests for gpt-4o
+	for i := 0; i < 3; i++ {
+		require.True(t, rl.TryAllowAll("a", "gpt-4o"))
+	}
+	// "c" is banned for gpt-4o
+	bal.RecordResponse("c", "gpt-4o", 500)
+
+	prx := createProxyWithParams(bal, logger, 10, 30*time.Second, monitoring.New(false), "test-key", rl,
+		auth.NewVertexTokenManager(logger), models.New(logger, 50, []config.ModelRPMConfig{}), "v", "c")
+
+	resp := prx.FederationLimits()
+	assert.Equal(t, "healthy", resp.Status)
+
+	gpt4o := resp.Models["gpt-4o"]
+	assert.Equal(t, 3, gpt4o.Credentials)
+	assert.Equal(t, 2, gpt4o.Available)
+	// a: min(10, 100) + b: min(20, 5)
+	assert.Equal(t, 15, gpt4o.LimitRPM)
+	// b has unlimited TPM
+	assert.Equal(t, -1, gpt4o.LimitTPM)
+	assert.Equal(t, 3, gpt4o.CurrentRPM)
+	// a: 10-3 = 7, b: 5
+	assert.Equal(t, 12, gpt4o.RemainingRPM)
+
+	mini := resp.Models["gpt-4o-mini"]
+	assert.Equal(t, 1, mini.Available)
+	assert.Equal(t, 10, mini.LimitRPM)
+	// Credential "a" has 3 requests already counted against its RPM (100), model headroom is 10
+	assert.Equal(t, 10, mini.RemainingRPM)
+	assert.Equal(t, 500, mini.LimitTPM)
+}
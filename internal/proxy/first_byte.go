@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// ErrFirstByteTimeout cancels a streaming upstream call that sent no response byte within
+// server.stream_first_byte_timeout. It wraps context.DeadlineExceeded, so the call counts as
+// a timeout.
+var ErrFirstByteTimeout = fmt.Errorf("no response byte within stream_first_byte_timeout: %w", context.DeadlineExceeded)
+
+// firstByteDeadline cancels one upstream attempt unless its first response byte arrives in
+// time. Nothing has been written to the client by then, so the attempt can be retried.
+type firstByteDeadline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	timer  *time.Timer
+}
+
+// withFirstByteDeadline derives the context of an upstream attempt. Returns ctx and a nil
+// deadline if timeout is not positive.
+func withFirstByteDeadline(ctx context.Context, timeout time.Duration) (context.Context, *firstByteDeadline) {
+	if timeout <= 0 {
+		return ctx, nil
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	d := &firstByteDeadline{ctx: ctx, cancel: cancel}
+	d.timer = time.AfterFunc(timeout, func() { cancel(ErrFirstByteTimeout) })
+	return ctx, d
+}
+
+// expired reports whether the attempt was cancelled by the deadline
+func (d *firstByteDeadline) expired() bool {
+	return errors.Is(context.Cause(d.ctx), ErrFirstByteTimeout)
+}
+
+// finish applies the deadline to the outcome of an upstream call: the first byte of the
+// response is awaited and kept in resp.Body, whose Close releases the attempt's context. A
+// call the deadline cancelled returns ErrFirstByteTimeout. Read errors of the first byte are
+// left to the reader of the body.
+func (d *firstByteDeadline) finish(resp *http.Response, err error) (*http.Response, error) {
+	if d == nil {
+		return resp, err
+	}
+	if err == nil {
+		reader := bufio.NewReader(resp.Body)
+		_, _ = reader.Peek(1)
+		if d.timer.Stop() {
+			resp.Body = &peekedBody{Reader: reader, body: resp.Body, release: d.release}
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+		err = ErrFirstByteTimeout
+	} else if d.expired() {
+		err = ErrFirstByteTimeout
+	}
+	d.release()
+	return nil, err
+}
+
+// release ends the attempt's context
+func (d *firstByteDeadline) release() {
+	if d != nil {
+		d.timer.Stop()
+		d.cancel(nil)
+	}
+}
+
+// peekedBody is a response body whose first bytes were buffered by finish
+type peekedBody struct {
+	io.Reader
+	body    io.Closer
+	release func()
+}
+
+func (b *peekedBody) Close() error {
+	err := b.body.Close()
+	b.release()
+	return err
+}
+
+// streamFirstByteTimeout returns the first byte deadline of r's upstream calls to proxy
+// credentials: stream_first_byte_timeout for streaming requests, 0 otherwise
+func (p *Proxy) streamFirstByteTimeout(r *http.Request) time.Duration {
+	if req, ok := r.Context().Value(inFlightKey{}).(*inFlightRequest); ok && req.info.Streaming {
+		return p.firstByteTimeout
+	}
+	return 0
+}
+
+// recordFirstByteTimeout counts a cancelled attempt as a timeout of the credential
+func (p *Proxy) recordFirstByteTimeout(r *http.Request, cred *config.CredentialConfig, modelID string, start time.Time) {
+	p.credentialLogger(r, cred.Name).Warn("Upstream sent no response byte in time, retrying",
+		"stream_first_byte_timeout", p.firstByteTimeout)
+	monitoring.StreamFirstByteTimeoutsTotal.WithLabelValues(cred.Name, modelID).Inc()
+	p.balancer.RecordResponse(cred.Name, modelID, http.StatusRequestTimeout)
+	p.metrics.RecordRequest(cred.Name, r.URL.Path, http.StatusRequestTimeout, time.Since(start))
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firstByteStream = "data: {\"choices\":[{\"delta\":{\"content\":\"fast\"}}]}\n\ndata: [DONE]\n\n"
+
+// newStallingUpstream answers after the request is cancelled or 5s; headersFirst sends the
+// stream headers before stalling
+func newStallingUpstream(t *testing.T, calls *int32, headersFirst bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		_, _ = io.Copy(io.Discard, r.Body) // The server notices the cancellation once the body is read
+		if headersFirst {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newStreamingUpstream(t *testing.T, calls *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, firstByteStream)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProxyRequest_StreamFirstByteTimeoutRetries(t *testing.T) {
+	for _, credType := range []config.ProviderType{config.ProviderTypeOpenAI, config.ProviderTypeProxy} {
+		for _, headersFirst := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/headers_first=%v", credType, headersFirst), func(t *testing.T) {
+				var slowCalls, fastCalls int32
+				slow := newStallingUpstream(t, &slowCalls, headersFirst)
+				fast := newStreamingUpstream(t, &fastCalls)
+				prx := NewTestProxyBuilder().
+					WithCredentials(
+						config.CredentialConfig{Name: "slow", Type: credType, BaseURL: slow.URL, APIKey: "k1", RPM: 100},
+						config.CredentialConfig{Name: "fast", Type: credType, BaseURL: fast.URL, APIKey: "k2", RPM: 100},
+					).
+					WithMasterKey("master-key").
+					Build()
+				prx.firstByteTimeout = 100 * time.Millisecond
+				prx.maxProviderRetries = 1
+				before := testutil.ToFloat64(monitoring.StreamFirstByteTimeoutsTotal.WithLabelValues("slow", "gpt-4o"))
+
+				req := httptest.NewRequest("POST", "/v1/chat/completions",
+					strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}`))
+				req.Header.Set("Authorization", "Bearer master-key")
+				w := httptest.NewRecorder()
+				started := time.Now()
+				prx.ProxyRequest(w, req)
+
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				assert.Contains(t, w.Body.String(), "fast")
+				assert.Less(t, time.Since(started), 2*time.Second, "the stalled credential is abandoned at the deadline")
+				assert.Equal(t, int32(1), atomic.LoadInt32(&slowCalls))
+				assert.Equal(t, int32(1), atomic.LoadInt32(&fastCalls))
+				assert.Equal(t, before+1, testutil.ToFloat64(monitoring.StreamFirstByteTimeoutsTotal.WithLabelValues("slow", "gpt-4o")))
+			})
+		}
+	}
+}
+
+func TestProxyRequest_StreamFirstByteTimeoutExhausted(t *testing.T) {
+	var calls int32
+	slow := newStallingUpstream(t, &calls, false)
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("slow", config.ProviderTypeOpenAI, slow.URL, "k1").
+		WithMasterKey("master-key").
+		Build()
+	prx.firstByteTimeout = 100 * time.Millisecond
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusRequestTimeout, w.Code, w.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestProxyRequest_FirstByteTimeoutSkipsNonStreaming(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(300 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"slow"}}]}`)
+	}))
+	defer upstream.Close()
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("slow", config.ProviderTypeOpenAI, upstream.URL, "k1").
+		WithMasterKey("master-key").
+		Build()
+	prx.firstByteTimeout = 100 * time.Millisecond
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "slow")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
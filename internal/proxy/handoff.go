@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+)
+
+// HandoffState is the runtime state handed from a router instance to its replacement
+// during a blue/green deployment, so limits are not overshot, bans are not forgotten and
+// Anthropic batches keep reaching their credential and owner
+type HandoffState struct {
+	Limits   ratelimit.UsageState `json:"limits"`
+	Fail2Ban fail2ban.State       `json:"fail2ban"`
+	Batches  BatchState           `json:"batches"`
+}
+
+// HandoffImportResult counts the state taken over by ImportHandoffState
+type HandoffImportResult struct {
+	Limiters        int `json:"limiters"`         // Limiter windows with imported usage
+	Bans            int `json:"bans"`             // Restored bans
+	FailureCounters int `json:"failure_counters"` // Restored failure counters
+	Batches         int `json:"batches"`          // Restored Anthropic batch records
+}
+
+// ExportHandoffState returns the current limiter usage, fail2ban state and batch records
+func (p *Proxy) ExportHandoffState() HandoffState {
+	return HandoffState{
+		Limits:   p.rateLimiter.ExportUsage(),
+		Fail2Ban: p.Fail2Ban().Snapshot(),
+		Batches:  p.batches.snapshot(),
+	}
+}
+
+// ImportHandoffState takes over the state exported by another instance. Limiter usage is
+// added to the usage of this instance; bans and failure counters replace those of the same
+// credential+model pairs, batch records those of the same batches. State of credentials not
+// configured here is skipped.
+func (p *Proxy) ImportHandoffState(state HandoffState) HandoffImportResult {
+	result := HandoffImportResult{Limiters: p.rateLimiter.ImportUsage(state.Limits)}
+	result.Bans, result.FailureCounters = p.Fail2Ban().Restore(state.Fail2Ban, p.HasCredential)
+	result.Batches = p.batches.restore(state.Batches, p.HasCredential)
+	return result
+}
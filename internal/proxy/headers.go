@@ -2,8 +2,11 @@ package proxy
 
 import (
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 )
 
 // hopByHopHeaders are headers that should not be proxied.
@@ -27,6 +30,25 @@ func isHopByHopHeader(key string) bool {
 	return hopByHopHeaders[key]
 }
 
+// isRouterSignatureHeader checks if a header is an inter-router signature header.
+// Signatures are valid for a single hop and must never be forwarded upstream.
+func isRouterSignatureHeader(key string) bool {
+	return key == httputil.RouterTimestampHeader || key == httputil.RouterNonceHeader || key == httputil.RouterSignatureHeader
+}
+
+// isRoutingOverrideHeader checks if a header is a routing override or routing seed header.
+// Both steer this router's credential selection only and are not forwarded upstream.
+func isRoutingOverrideHeader(key string) bool {
+	switch http.CanonicalHeaderKey(key) {
+	case http.CanonicalHeaderKey(PreferCredentialHeader),
+		http.CanonicalHeaderKey(ExcludeProvidersHeader),
+		http.CanonicalHeaderKey(RequireRegionHeader),
+		http.CanonicalHeaderKey(RoutingSeedHeader):
+		return true
+	}
+	return false
+}
+
 // GetHopByHopHeaders returns a copy of the hop-by-hop headers map for reference.
 // Use isHopByHopHeader() to check if a specific header should be filtered.
 func GetHopByHopHeaders() map[string]bool {
@@ -43,7 +65,7 @@ func GetHopByHopHeaders() map[string]bool {
 // Accept-Encoding is also skipped (see copyHeadersSkipAuth for rationale).
 func copyRequestHeaders(dst *http.Request, src *http.Request, apiKey string) {
 	for key, values := range src.Header {
-		if isHopByHopHeader(key) {
+		if isHopByHopHeader(key) || isRouterSignatureHeader(key) || isRoutingOverrideHeader(key) {
 			continue
 		}
 		// Don't forward Accept-Encoding to upstream (proxy handles per-segment).
@@ -76,7 +98,7 @@ func copyRequestHeaders(dst *http.Request, src *http.Request, apiKey string) {
 // bytes to flow through instead of decoded content.
 func copyHeadersSkipAuth(dst *http.Request, src *http.Request) {
 	for key, values := range src.Header {
-		if isHopByHopHeader(key) || key == "Authorization" {
+		if isHopByHopHeader(key) || isRouterSignatureHeader(key) || isRoutingOverrideHeader(key) || key == "Authorization" {
 			continue
 		}
 		// Don't forward Accept-Encoding: proxy manages compression per connection segment.
@@ -91,25 +113,49 @@ func copyHeadersSkipAuth(dst *http.Request, src *http.Request) {
 	}
 }
 
-// copyResponseHeaders copies response headers to the response writer,
-// skipping hop-by-hop headers and transformation-related headers.
-// Note: Content-Encoding is always skipped because Go's http.Client automatically
-// decompresses gzip/deflate responses, so the body is already decompressed.
-// The caller should compress the body if needed and set Content-Encoding appropriately.
-func copyResponseHeaders(w http.ResponseWriter, src http.Header, credType config.ProviderType) {
-	for key, values := range src {
-		if isHopByHopHeader(key) {
-			continue
-		}
-		// Skip Content-Length and Content-Encoding for all response types
-		// - Content-Length will be set based on actual body size
-		// - Content-Encoding: Go's http.Client already decompressed the body,
-		//   so we skip upstream's Content-Encoding header and let caller set it if recompressing
-		if key == "Content-Length" || key == "Content-Encoding" {
-			continue
+// Vertex AI capacity headers
+const (
+	vertexRequestTypeHeader = "X-Vertex-AI-LLM-Request-Type"
+	vertexRequestTypePT     = "dedicated"
+	googUserProjectHeader   = "X-Goog-User-Project"
+)
+
+// setVertexCapacityHeaders sets Provisioned Throughput and quota project headers for a Vertex AI request.
+// With "dedicated" Vertex returns 429 when PT capacity is exhausted instead of silently spilling
+// over to pay-as-you-go, so the router can retry on an on-demand credential.
+func setVertexCapacityHeaders(req *http.Request, cred *config.CredentialConfig) {
+	if cred.ProvisionedThroughput {
+		req.Header.Set(vertexRequestTypeHeader, vertexRequestTypePT)
+	}
+	if cred.QuotaProject != "" {
+		req.Header.Set(googUserProjectHeader, cred.QuotaProject)
+	}
+}
+
+// Anthropic API headers
+const (
+	anthropicVersionHeader = "anthropic-version"
+	anthropicBetaHeader    = "anthropic-beta"
+)
+
+// setAnthropicBetaHeader merges the credential's anthropic_beta features into the
+// anthropic-beta header, keeping the betas requested by the client.
+func setAnthropicBetaHeader(req *http.Request, cred *config.CredentialConfig) {
+	if len(cred.AnthropicBeta) == 0 {
+		return
+	}
+	var betas []string
+	for _, value := range req.Header.Values(anthropicBetaHeader) {
+		for _, beta := range strings.Split(value, ",") {
+			if beta = strings.TrimSpace(beta); beta != "" && !slices.Contains(betas, beta) {
+				betas = append(betas, beta)
+			}
 		}
-		for _, value := range values {
-			w.Header().Add(key, value)
+	}
+	for _, beta := range cred.AnthropicBeta {
+		if !slices.Contains(betas, beta) {
+			betas = append(betas, beta)
 		}
 	}
+	req.Header.Set(anthropicBetaHeader, strings.Join(betas, ","))
 }
@@ -1,8 +1,11 @@
 package proxy
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,3 +35,34 @@ func TestGetHopByHopHeaders(t *testing.T) {
 	_, hasCustom := original["X-Custom"]
 	assert.False(t, hasCustom, "modifying returned map should not affect the original")
 }
+
+func TestSetVertexCapacityHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	setVertexCapacityHeaders(req, &config.CredentialConfig{Type: config.ProviderTypeVertexAI})
+	assert.Empty(t, req.Header.Get("X-Vertex-AI-LLM-Request-Type"))
+	assert.Empty(t, req.Header.Get("X-Goog-User-Project"))
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	setVertexCapacityHeaders(req, &config.CredentialConfig{
+		Type:                  config.ProviderTypeVertexAI,
+		ProvisionedThroughput: true,
+		QuotaProject:          "billing-project",
+	})
+	assert.Equal(t, "dedicated", req.Header.Get("X-Vertex-AI-LLM-Request-Type"))
+	assert.Equal(t, "billing-project", req.Header.Get("X-Goog-User-Project"))
+}
+
+func TestAnthropicHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	setAnthropicBetaHeader(req, &config.CredentialConfig{Type: config.ProviderTypeAnthropic})
+	assert.Empty(t, req.Header.Get("anthropic-beta"))
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Add("anthropic-beta", "files-api-2025-04-14, prompt-caching-2024-07-31")
+	setAnthropicBetaHeader(req, &config.CredentialConfig{
+		Type:          config.ProviderTypeAnthropic,
+		AnthropicBeta: []string{"prompt-caching-2024-07-31", "context-1m-2025-08-07"},
+	})
+	assert.Equal(t, "files-api-2025-04-14,prompt-caching-2024-07-31,context-1m-2025-08-07", req.Header.Get("anthropic-beta"))
+	assert.Len(t, req.Header.Values("anthropic-beta"), 1)
+}
@@ -3,9 +3,20 @@ package proxy
 import (
 	_ "embed"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
 	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Size of the sparklines of the health dashboard (SVG viewBox units, as in health.html)
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
 )
 
 func (p *Proxy) HealthCheck() (bool, *httputil.ProxyHealthResponse) {
@@ -40,7 +51,7 @@ func (p *Proxy) HealthCheck() (bool, *httputil.ProxyHealthResponse) {
 		// Check if credential has any banned models
 		isBanned := p.balancer.HasAnyBan(cred.Name)
 
-		credentialsInfo[cred.Name] = httputil.CredentialHealthStats{
+		stats := httputil.CredentialHealthStats{
 			Type:       string(cred.Type),
 			IsFallback: cred.IsFallback,
 			IsBanned:   isBanned,
@@ -48,7 +59,14 @@ func (p *Proxy) HealthCheck() (bool, *httputil.ProxyHealthResponse) {
 			CurrentTPM: p.rateLimiter.GetCurrentTPM(cred.Name),
 			LimitRPM:   limitRPM,
 			LimitTPM:   limitTPM,
+			Quotas:     p.quotaForecasts(cred.Name),
+		}
+		if cred.Type == config.ProviderTypeProxy {
+			if report, ok := p.proxyHealth.Report(cred.Name); ok {
+				stats.Downstream = &report
+			}
 		}
+		credentialsInfo[cred.Name] = stats
 	}
 
 	// Collect models info from rateLimiter (which tracks all credential:model pairs)
@@ -113,6 +131,9 @@ func (p *Proxy) HealthCheck() (bool, *httputil.ProxyHealthResponse) {
 		Models:               modelsInfo,
 	}
 
+	status.Prices = p.priceSyncHealth()
+	status.Workers = p.workerHealth()
+
 	if !healthy {
 		status.Status = "unhealthy"
 	}
@@ -120,9 +141,95 @@ func (p *Proxy) HealthCheck() (bool, *httputil.ProxyHealthResponse) {
 	return healthy, status
 }
 
-// VisualHealthCheck renders an HTML dashboard with health check information
+// priceSyncHealth reports the age and source of the model prices (nil if prices were never
+// loaded or synced). Stale prices do not make the router unhealthy.
+func (p *Proxy) priceSyncHealth() *httputil.PriceSyncHealth {
+	if p.priceRegistry == nil {
+		return nil
+	}
+	state := p.priceRegistry.SyncStatus()
+	if state.LastSync.IsZero() && state.LastError == "" {
+		return nil
+	}
+
+	health := &httputil.PriceSyncHealth{
+		Source:     httputil.PriceSourceSync,
+		Models:     state.Models,
+		AgeSeconds: state.Age(utils.NowUTC()).Seconds(),
+		LastError:  state.LastError,
+	}
+	switch {
+	case state.LastSync.IsZero():
+		health.Source = httputil.PriceSourceNone
+	case state.Restored:
+		health.Source = httputil.PriceSourceSnapshot
+	}
+	if !state.LastSync.IsZero() {
+		lastSync := state.LastSync
+		health.LastSync = &lastSync
+	}
+	return health
+}
+
+// workerHealth reports the supervised background workers (nil without a supervisor)
+func (p *Proxy) workerHealth() map[string]httputil.WorkerHealth {
+	statuses := p.workers.Status()
+	if len(statuses) == 0 {
+		return nil
+	}
+	workers := make(map[string]httputil.WorkerHealth, len(statuses))
+	for name, status := range statuses {
+		workers[name] = httputil.WorkerHealth{
+			State:     status.State,
+			Restarts:  status.Restarts,
+			LastRun:   status.LastRun,
+			LastError: status.LastError,
+			LastPanic: status.LastPanic,
+		}
+	}
+	return workers
+}
+
+// quotaForecasts returns the quota exhaustion forecasts of a credential (nil without usage_forecast)
+func (p *Proxy) quotaForecasts(credential string) []httputil.QuotaForecast {
+	forecasts := p.usageEstimator.Forecasts(credential)
+	if len(forecasts) == 0 {
+		return nil
+	}
+	quotas := make([]httputil.QuotaForecast, 0, len(forecasts))
+	for _, f := range forecasts {
+		quota := httputil.QuotaForecast{
+			Period:      f.Period,
+			Kind:        f.Kind,
+			Used:        f.Used,
+			Limit:       f.Limit,
+			RatePerHour: f.RatePerHour,
+			Warning:     f.Warning,
+		}
+		if f.TimeToExhaustion != nil {
+			seconds := f.TimeToExhaustion.Seconds()
+			quota.SecondsToExhaustion = &seconds
+		}
+		quotas = append(quotas, quota)
+	}
+	return quotas
+}
+
+// HealthHistory returns the per-credential RPM, TPM, error rate and spend histories over
+// the last window, one point per minute
+func (p *Proxy) HealthHistory(window time.Duration) *healthhistory.Snapshot {
+	return p.history.Snapshot(window)
+}
+
+// VisualHealthCheck renders an HTML dashboard with health check information.
+// The history query parameter selects the sparkline window (default 1h, at most 24h).
 func (p *Proxy) VisualHealthCheck(w http.ResponseWriter, r *http.Request) {
 	_, status := p.HealthCheck()
+	historyWindow := r.URL.Query().Get("history")
+	window, err := healthhistory.ParseWindow(historyWindow)
+	if err != nil || historyWindow == "" {
+		window, historyWindow = healthhistory.DefaultWindow, "1h"
+	}
 
 	if p.healthTemplate == nil {
 		p.logger.Error("Health template not available")
@@ -141,9 +248,56 @@ func (p *Proxy) VisualHealthCheck(w http.ResponseWriter, r *http.Request) {
 		"total_credentials":     status.TotalCredentials,
 		"credentials":           status.Credentials,
 		"models":                status.Models,
+		"history":               p.HealthHistory(window).Credentials,
+		"history_window":        historyWindow,
 	}
 
 	if err := p.healthTemplate.Execute(w, statusMap); err != nil {
 		p.logger.Error("Failed to execute health template", "error", err)
 	}
 }
+
+// sparklinePoints returns the points of an SVG polyline drawing values scaled to the
+// sparkline size, the largest value at the top
+func sparklinePoints(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	top := peakValue(values)
+	step := 0.0
+	if len(values) > 1 {
+		step = float64(sparklineWidth) / float64(len(values)-1)
+	}
+	var b strings.Builder
+	for i, value := range values {
+		y := float64(sparklineHeight)
+		if top > 0 {
+			y -= value / top * sparklineHeight
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.FormatFloat(float64(i)*step, 'f', 1, 64))
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatFloat(y, 'f', 1, 64))
+	}
+	return b.String()
+}
+
+// peakValue returns the largest of values (0 for none)
+func peakValue(values []float64) float64 {
+	peak := 0.0
+	for _, value := range values {
+		peak = max(peak, value)
+	}
+	return peak
+}
+
+// totalValue returns the sum of values
+func totalValue(values []float64) float64 {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
@@ -1,10 +1,13 @@
 package proxy
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,10 +15,16 @@ import (
 	"github.com/mixaill76/auto_ai_router/internal/balancer"
 	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/forecast"
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/proxyhealth"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/worker"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func createHealthTestLogger() *slog.Logger {
@@ -127,6 +136,26 @@ func TestHealthCheck_CredentialsInfo(t *testing.T) {
 	assert.Equal(t, true, fallbackStats.IsFallback)
 }
 
+func TestHealthCheck_QuotaForecasts(t *testing.T) {
+	prx := createHealthTestProxy(2)
+	prx.usageEstimator = forecast.New(forecast.Config{Interval: time.Minute, Window: 10 * time.Minute, WarnThreshold: time.Hour},
+		map[string]forecast.Quota{"cred_0": {DailyTokens: 1000}}, createHealthTestLogger())
+	prx.usageEstimator.Record("cred_0", 1000, 0)
+
+	_, status := prx.HealthCheck()
+
+	quotas := status.Credentials["cred_0"].Quotas
+	require.Len(t, quotas, 1)
+	assert.Equal(t, forecast.PeriodDaily, quotas[0].Period)
+	assert.Equal(t, forecast.KindTokens, quotas[0].Kind)
+	assert.Equal(t, 1000.0, quotas[0].Used)
+	require.NotNil(t, quotas[0].SecondsToExhaustion)
+	assert.Zero(t, *quotas[0].SecondsToExhaustion)
+	assert.True(t, quotas[0].Warning)
+
+	assert.Empty(t, status.Credentials["cred_1"].Quotas, "credentials without a quota have no forecast")
+}
+
 func TestHealthCheck_CredentialRateLimit(t *testing.T) {
 	logger := createHealthTestLogger()
 	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
@@ -255,6 +284,47 @@ func TestVisualHealthCheck_HealthyStatus(t *testing.T) {
 	assert.Contains(t, body, "System Health Dashboard")
 }
 
+func TestVisualHealthCheck_History(t *testing.T) {
+	prx := createHealthTestProxy(2)
+	prx.history = healthhistory.New()
+	prx.history.Record("cred_0", 120, 0.25, false)
+	prx.history.Record("cred_0", 30, 0.5, true)
+
+	snapshot := prx.HealthHistory(24 * time.Hour)
+	require.Contains(t, snapshot.Credentials, "cred_0")
+	assert.NotContains(t, snapshot.Credentials, "cred_1")
+	series := snapshot.Credentials["cred_0"]
+	assert.Len(t, series.RPM, 1440)
+	assert.Equal(t, 2.0, series.RPM[len(series.RPM)-1])
+	assert.Equal(t, 0.5, series.ErrorRate[len(series.ErrorRate)-1])
+
+	req := httptest.NewRequest("GET", "/vhealth?history=24h", nil)
+	w := httptest.NewRecorder()
+	prx.VisualHealthCheck(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Last 24h")
+	assert.Contains(t, body, "<polyline points=")
+	assert.Contains(t, body, "$0.75")
+	assert.Contains(t, body, "50%")
+	assert.Equal(t, 4, strings.Count(body, "<polyline"), "only the credential with history gets sparklines")
+
+	// An invalid window falls back to the default
+	req = httptest.NewRequest("GET", "/vhealth?history=forever", nil)
+	w = httptest.NewRecorder()
+	prx.VisualHealthCheck(w, req)
+	assert.Contains(t, w.Body.String(), "Last 1h")
+}
+
+func TestSparklinePoints(t *testing.T) {
+	assert.Equal(t, "", sparklinePoints(nil))
+	assert.Equal(t, "0.0,24.0 60.0,0.0 120.0,12.0", sparklinePoints([]float64{0, 4, 2}))
+	assert.Equal(t, "0.0,24.0 120.0,24.0", sparklinePoints([]float64{0, 0}))
+	assert.Equal(t, 4.0, peakValue([]float64{1, 4, 2}))
+	assert.Equal(t, 7.0, totalValue([]float64{1, 4, 2}))
+}
+
 func TestHealthCheck_MultipleModelsPerCredential(t *testing.T) {
 	logger := createHealthTestLogger()
 	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
@@ -479,3 +549,97 @@ func TestHealthCheck_ProxyCredentialLimitsFromRateLimiter(t *testing.T) {
 	assert.GreaterOrEqual(t, proxyStat.CurrentRPM, 0)
 	assert.GreaterOrEqual(t, proxyStat.CurrentTPM, 36800) // Allow small variance due to time calculations
 }
+
+func TestHealthCheck_ProxyDownstreamHealth(t *testing.T) {
+	logger := createHealthTestLogger()
+	rl := ratelimit.New()
+	proxyCred := config.CredentialConfig{Name: "gateway_proxy", Type: config.ProviderTypeProxy, BaseURL: "http://remote-proxy.com", RPM: 100}
+	bal := balancer.New([]config.CredentialConfig{proxyCred}, fail2ban.New(3, 0, []int{500}), rl)
+	tracker := proxyhealth.NewTracker()
+	bal.SetProxyHealthChecker(tracker)
+
+	prx := createProxyWithParams(bal, logger, 10, 30*time.Second, monitoring.New(false), "test-key", rl,
+		auth.NewVertexTokenManager(logger), models.New(logger, 50, []config.ModelRPMConfig{}), "test-version", "test-commit")
+	prx.proxyHealth = tracker
+
+	healthy, status := prx.HealthCheck()
+	assert.True(t, healthy)
+	assert.Nil(t, status.Credentials["gateway_proxy"].Downstream, "not polled yet")
+
+	tracker.RecordHealth("gateway_proxy", httputil.DownstreamHealth{
+		Status: httputil.DownstreamUnreachable, Error: "connection refused", CheckedAt: time.Now(),
+	})
+	healthy, status = prx.HealthCheck()
+	assert.False(t, healthy, "the only credential is a proxy with an unreachable downstream")
+	assert.Equal(t, 0, status.CredentialsAvailable)
+	downstream := status.Credentials["gateway_proxy"].Downstream
+	require.NotNil(t, downstream)
+	assert.Equal(t, httputil.DownstreamUnreachable, downstream.Status)
+	assert.Equal(t, 1, downstream.ConsecutiveFailures)
+
+	w := httptest.NewRecorder()
+	prx.VisualHealthCheck(w, httptest.NewRequest("GET", "/health/visual", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "DOWNSTREAM unreachable")
+}
+
+func TestHealthCheck_PriceSync(t *testing.T) {
+	prx := createHealthTestProxy(1)
+	_, status := prx.HealthCheck()
+	assert.Nil(t, status.Prices, "no price registry")
+
+	prx.priceRegistry = models.NewModelPriceRegistry()
+	_, status = prx.HealthCheck()
+	assert.Nil(t, status.Prices, "prices never loaded")
+
+	prx.priceRegistry.RecordSyncError(assert.AnError)
+	_, status = prx.HealthCheck()
+	require.NotNil(t, status.Prices)
+	assert.Equal(t, httputil.PriceSourceNone, status.Prices.Source)
+	assert.Nil(t, status.Prices.LastSync)
+	assert.Equal(t, assert.AnError.Error(), status.Prices.LastError)
+
+	syncedAt := time.Now().Add(-time.Hour)
+	prx.priceRegistry.Restore(models.PriceSnapshot{SyncedAt: syncedAt, Prices: map[string]*models.ModelPrice{"gpt-4o": {}}})
+	healthy, status := prx.HealthCheck()
+	assert.True(t, healthy, "stale prices do not make the router unhealthy")
+	require.NotNil(t, status.Prices)
+	assert.Equal(t, httputil.PriceSourceSnapshot, status.Prices.Source)
+	assert.Equal(t, 1, status.Prices.Models)
+	require.NotNil(t, status.Prices.LastSync)
+	assert.InDelta(t, time.Hour.Seconds(), status.Prices.AgeSeconds, 60)
+
+	prx.priceRegistry.Update(map[string]*models.ModelPrice{"gpt-4o": {}})
+	_, status = prx.HealthCheck()
+	assert.Equal(t, httputil.PriceSourceSync, status.Prices.Source)
+	assert.Empty(t, status.Prices.LastError)
+	assert.Less(t, status.Prices.AgeSeconds, 60.0)
+}
+
+func TestHealthCheck_Workers(t *testing.T) {
+	prx := createHealthTestProxy(1)
+	_, status := prx.HealthCheck()
+	assert.Nil(t, status.Workers, "no supervisor")
+
+	prx.workers = worker.NewSupervisor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	reported := make(chan struct{})
+	prx.workers.Go(ctx, "price_sync", func(ctx context.Context, report func(error)) {
+		report(assert.AnError)
+		close(reported)
+		<-ctx.Done()
+	})
+	<-reported
+
+	healthy, status := prx.HealthCheck()
+	assert.True(t, healthy)
+	require.Contains(t, status.Workers, "price_sync")
+	assert.Equal(t, worker.StateRunning, status.Workers["price_sync"].State)
+	assert.Equal(t, assert.AnError.Error(), status.Workers["price_sync"].LastError)
+	assert.NotNil(t, status.Workers["price_sync"].LastRun)
+
+	cancel()
+	prx.workers.Wait()
+	_, status = prx.HealthCheck()
+	assert.Equal(t, worker.StateStopped, status.Workers["price_sync"].State)
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+)
+
+// maxMultipartFieldSize bounds the form fields read from a multipart body; file parts are
+// skipped without being buffered
+const maxMultipartFieldSize = 64 << 10
+
+// isImageEditPath reports whether path is an image edit or variation endpoint. Their requests
+// are multipart forms carrying the source images, forwarded to OpenAI-compatible credentials
+// as they are.
+func isImageEditPath(path string) bool {
+	return strings.HasSuffix(path, "/images/edits") || strings.HasSuffix(path, "/images/variations")
+}
+
+// multipartBoundary returns the boundary of a multipart/form-data Content-Type ("" otherwise)
+func multipartBoundary(contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		return ""
+	}
+	return params["boundary"]
+}
+
+// multipartFields returns the form fields of a multipart body by name. Parsing stops at the
+// first malformed part.
+func multipartFields(body []byte, boundary string) map[string]string {
+	fields := make(map[string]string)
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			return fields
+		}
+		if name := part.FormName(); name != "" && part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxMultipartFieldSize))
+			if err == nil {
+				if _, exists := fields[name]; !exists {
+					fields[name] = string(value)
+				}
+			}
+		}
+		_ = part.Close()
+	}
+}
+
+// setMultipartField replaces the value of a form field of a multipart body, keeping the other
+// parts and the boundary. The body is returned unchanged if it cannot be parsed.
+func setMultipartField(body []byte, boundary, name, value string) []byte {
+	var out bytes.Buffer
+	writer := multipart.NewWriter(&out)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return body
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return body
+		}
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return body
+		}
+		if part.FormName() == name && part.FileName() == "" {
+			_, err = io.WriteString(dst, value)
+		} else {
+			_, err = io.Copy(dst, part)
+		}
+		if err != nil {
+			return body
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return body
+	}
+	return out.Bytes()
+}
+
+// replaceRequestModel rewrites the model of a JSON or multipart request body
+func replaceRequestModel(r *http.Request, body []byte, oldModel, newModel string) []byte {
+	if boundary := multipartBoundary(r.Header.Get("Content-Type")); boundary != "" {
+		return setMultipartField(body, boundary, "model", newModel)
+	}
+	return openai.ReplaceModelInBody(body, oldModel, newModel)
+}
+
+// requestedImageCount returns the number of images an image request asks for (n, default 1)
+func requestedImageCount(r *http.Request, body []byte) int {
+	if boundary := multipartBoundary(r.Header.Get("Content-Type")); boundary != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(multipartFields(body, boundary)["n"])); err == nil && n > 0 {
+			return n
+		}
+		return 1
+	}
+	var imgReq struct {
+		N *int `json:"n"`
+	}
+	if err := json.Unmarshal(body, &imgReq); err == nil && imgReq.N != nil {
+		return *imgReq.N
+	}
+	return 1
+}
+
+// routeImageEdit keeps an image edit or variation request on OpenAI-compatible credentials:
+// other providers have no conversion for them. Fails with 422 if none serves the model.
+func (p *Proxy) routeImageEdit(w http.ResponseWriter, r *http.Request, modelID string, logCtx *RequestLogContext) bool {
+	triedCreds := GetTried(r.Context())
+	capable := false
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if triedCreds[cred.Name] {
+			continue
+		}
+		if !converter.SupportsImageEdits(cred.Type) {
+			triedCreds[cred.Name] = true
+			continue
+		}
+		if !p.modelManager.IsEnabled() || p.modelManager.HasModel(cred.Name, modelID) {
+			capable = true
+		}
+	}
+	if capable {
+		return true
+	}
+
+	reqErr := &converter.RequestError{
+		Code:    converter.ErrCodeUnsupportedEndpoint,
+		Message: fmt.Sprintf("model %s has no credential supporting %s", modelID, r.URL.Path),
+	}
+	logCtx.Logger().Warn("No credential supports the image endpoint", "path", r.URL.Path)
+	logCtx.Status = "failure"
+	logCtx.HTTPStatus = reqErr.StatusCode()
+	logCtx.ErrorMsg = reqErr.Message
+	logCtx.Credential = &config.CredentialConfig{
+		Name: "system",
+		Type: config.ProviderTypeProxy,
+	}
+	writeRequestError(w, reqErr)
+	return false
+}
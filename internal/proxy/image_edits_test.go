@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// imageEditForm builds a multipart image edit request body with the given fields and a PNG file
+func imageEditForm(t *testing.T, fields map[string]string) ([]byte, string) {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	file, err := writer.CreateFormFile("image", "cat.png")
+	require.NoError(t, err)
+	_, err = file.Write([]byte("\x89PNG\r\n\x1a\n\"model\":\"dall-e-2\""))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return form.Bytes(), writer.FormDataContentType()
+}
+
+func TestMultipartFields(t *testing.T) {
+	body, contentType := imageEditForm(t, map[string]string{"model": "dall-e-2", "n": "2"})
+	boundary := multipartBoundary(contentType)
+	require.NotEmpty(t, boundary)
+	assert.Empty(t, multipartBoundary("application/json"))
+
+	fields := multipartFields(body, boundary)
+	assert.Equal(t, "dall-e-2", fields["model"])
+	assert.Equal(t, "2", fields["n"])
+	assert.NotContains(t, fields, "image", "file parts are not form fields")
+
+	rewritten := setMultipartField(body, boundary, "model", "gpt-image-1")
+	assert.Equal(t, "gpt-image-1", multipartFields(rewritten, boundary)["model"])
+	assert.Contains(t, string(rewritten), "\x89PNG\r\n\x1a\n\"model\":\"dall-e-2\"", "file parts are kept byte for byte")
+	assert.Equal(t, []byte("not multipart"), setMultipartField([]byte("not multipart"), boundary, "model", "x"))
+}
+
+func TestRequestedImageCount(t *testing.T) {
+	body, contentType := imageEditForm(t, map[string]string{"model": "dall-e-2", "n": "3"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/edits", nil)
+	req.Header.Set("Content-Type", contentType)
+	assert.Equal(t, 3, requestedImageCount(req, body))
+
+	body, contentType = imageEditForm(t, map[string]string{"model": "dall-e-2"})
+	req.Header.Set("Content-Type", contentType)
+	assert.Equal(t, 1, requestedImageCount(req, body))
+
+	req.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, 2, requestedImageCount(req, []byte(`{"model":"dall-e-3","n":2}`)))
+}
+
+func TestProxyRequest_ImageEdit(t *testing.T) {
+	var received struct {
+		path, model, prompt string
+		file                []byte
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.path = r.URL.Path
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			received.model = r.FormValue("model")
+			received.prompt = r.FormValue("prompt")
+			if file, _, err := r.FormFile("image"); err == nil {
+				received.file, _ = io.ReadAll(file)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"created":1,"data":[{"b64_json":"a"},{"b64_json":"b"},{"b64_json":"c"}]}`))
+	}))
+	defer upstream.Close()
+
+	prx := NewTestProxyBuilder().
+		WithCredentials(
+			config.CredentialConfig{Name: "vertex", Type: config.ProviderTypeVertexAI, BaseURL: "http://127.0.0.1:0", RPM: 100},
+			config.CredentialConfig{Name: "openai", Type: config.ProviderTypeOpenAI, BaseURL: upstream.URL, APIKey: "key", RPM: 100},
+		).
+		WithMasterKey("master-key").
+		Build()
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{"dall-e-2": {OutputCostPerImage: 0.02}})
+	prx.priceRegistry = registry
+	prx.rateLimiter.AddPool("org", ratelimit.PoolLimits{}, "openai")
+	prx.spendSinksEnabled = true
+
+	for _, path := range []string{"/v1/images/edits", "/v1/images/variations"} {
+		body, contentType := imageEditForm(t, map[string]string{"model": "dall-e-2", "prompt": "add a hat", "n": "3"})
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer master-key")
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, "only OpenAI-compatible credentials take image edits: %s", w.Body.String())
+		assert.Equal(t, path, received.path)
+		assert.Equal(t, "dall-e-2", received.model)
+		assert.Equal(t, "add a hat", received.prompt)
+		assert.Equal(t, "\x89PNG\r\n\x1a\n\"model\":\"dall-e-2\"", string(received.file))
+	}
+
+	assert.InDelta(t, 0.12, prx.rateLimiter.Headroom().Pools["org"].DailySpend, 1e-9, "each image is billed")
+}
+
+func TestProxyRequest_ImageEditNoCapableCredential(t *testing.T) {
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("anthropic", config.ProviderTypeAnthropic, "http://127.0.0.1:0", "key").
+		WithMasterKey("master-key").
+		Build()
+
+	body, contentType := imageEditForm(t, map[string]string{"model": "dall-e-2", "prompt": "add a hat"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/edits", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer master-key")
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "unsupported_endpoint", resp.Error.Code)
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	_ "image/gif"  // Register GIF for image.DecodeConfig
+	_ "image/jpeg" // Register JPEG for image.DecodeConfig
+	_ "image/png"  // Register PNG for image.DecodeConfig
+	"math"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+// defaultImageSize is assumed for images whose dimensions are unknown (remote URLs,
+// undecodable data URLs)
+const defaultImageSize = 1024
+
+// estimatePromptTokensFor estimates the prompt tokens of a Chat Completions request sent to
+// a provider: its text (see estimatePromptTokens) plus its images
+func estimatePromptTokensFor(body []byte, providerType config.ProviderType) int {
+	return estimatePromptTokens(body) + estimateImageTokens(body, providerType)
+}
+
+// estimateImageTokens estimates the tokens of the image_url parts of a Chat Completions request
+// the way the provider counts them: 512px tiles for OpenAI, 768px tiles for Gemini, pixel
+// area for Anthropic. Image dimensions are read from data URLs; other images are assumed to
+// be defaultImageSize square.
+func estimateImageTokens(body []byte, providerType config.ProviderType) int {
+	var reqBody struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &reqBody); err != nil {
+		return 0
+	}
+
+	tokens := 0
+	for _, msg := range reqBody.Messages {
+		var parts []struct {
+			Type     string `json:"type"`
+			ImageURL *struct {
+				URL    string `json:"url"`
+				Detail string `json:"detail"`
+			} `json:"image_url"`
+		}
+		if json.Unmarshal(msg.Content, &parts) != nil {
+			continue // String content
+		}
+		for _, part := range parts {
+			if part.Type != "image_url" || part.ImageURL == nil {
+				continue
+			}
+			width, height := imageDimensions(part.ImageURL.URL)
+			tokens += imageTokens(providerType, width, height, part.ImageURL.Detail)
+		}
+	}
+	return tokens
+}
+
+// imageTokens returns the tokens a provider counts for an image of the given size
+func imageTokens(providerType config.ProviderType, width, height int, detail string) int {
+	switch providerType {
+	case config.ProviderTypeVertexAI, config.ProviderTypeGemini:
+		// Images up to 384px are 258 tokens, larger ones 258 tokens per 768x768 tile
+		if width <= 384 && height <= 384 {
+			return 258
+		}
+		return ceilDiv(width, 768) * ceilDiv(height, 768) * 258
+	case config.ProviderTypeAnthropic, config.ProviderTypeBedrock:
+		// Scaled to a long edge of at most 1568px, then width*height/750 tokens
+		w, h := scaleToFit(float64(width), float64(height), 1568)
+		return int(math.Ceil(w * h / 750))
+	default:
+		// OpenAI: 85 base tokens (all of a detail=low image), plus 170 per 512px tile after
+		// fitting into 2048x2048 and scaling the shortest side down to 768px
+		if detail == "low" {
+			return 85
+		}
+		w, h := scaleToFit(float64(width), float64(height), 2048)
+		if shortest := math.Min(w, h); shortest > 768 {
+			w, h = w*768/shortest, h*768/shortest
+		}
+		return 85 + 170*ceilDiv(int(math.Ceil(w)), 512)*ceilDiv(int(math.Ceil(h)), 512)
+	}
+}
+
+// imageDimensions returns the size of a base64 data URL image, or defaultImageSize square
+func imageDimensions(url string) (int, int) {
+	if data, ok := strings.CutPrefix(url, "data:"); ok {
+		if _, encoded, found := strings.Cut(data, ";base64,"); found {
+			cfg, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(encoded)))
+			if err == nil && cfg.Width > 0 && cfg.Height > 0 {
+				return cfg.Width, cfg.Height
+			}
+		}
+	}
+	return defaultImageSize, defaultImageSize
+}
+
+// scaleToFit scales width and height down so that the longer side is at most maxSide
+func scaleToFit(width, height, maxSide float64) (float64, float64) {
+	if longest := math.Max(width, height); longest > maxSide {
+		return width * maxSide / longest, height * maxSide / longest
+	}
+	return width, height
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
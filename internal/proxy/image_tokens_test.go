@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestImageTokens(t *testing.T) {
+	tests := []struct {
+		name          string
+		provider      config.ProviderType
+		width, height int
+		detail        string
+		want          int
+	}{
+		{name: "openai low detail", provider: config.ProviderTypeOpenAI, width: 4096, height: 4096, detail: "low", want: 85},
+		{name: "openai 1024 square", provider: config.ProviderTypeOpenAI, width: 1024, height: 1024, want: 765},
+		{name: "openai 2048x4096", provider: config.ProviderTypeOpenAI, width: 2048, height: 4096, detail: "high", want: 1105},
+		{name: "openai small", provider: config.ProviderTypeOpenAI, width: 256, height: 256, want: 255},
+		{name: "proxy counts as openai", provider: config.ProviderTypeProxy, width: 1024, height: 1024, want: 765},
+		{name: "gemini small", provider: config.ProviderTypeGemini, width: 300, height: 384, want: 258},
+		{name: "vertex tiles", provider: config.ProviderTypeVertexAI, width: 1024, height: 1024, want: 1032},
+		{name: "anthropic area", provider: config.ProviderTypeAnthropic, width: 1000, height: 1000, want: 1334},
+		{name: "anthropic scaled", provider: config.ProviderTypeAnthropic, width: 3136, height: 1568, want: 1640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, imageTokens(tt.provider, tt.width, tt.height, tt.detail))
+		})
+	}
+}
+
+func TestImageDimensions(t *testing.T) {
+	width, height := imageDimensions(pngDataURL(t, 640, 480))
+	assert.Equal(t, 640, width)
+	assert.Equal(t, 480, height)
+
+	width, height = imageDimensions("https://example.com/cat.png")
+	assert.Equal(t, defaultImageSize, width)
+	assert.Equal(t, defaultImageSize, height)
+
+	width, _ = imageDimensions("data:image/png;base64,not-an-image")
+	assert.Equal(t, defaultImageSize, width)
+}
+
+func TestEstimatePromptTokensFor(t *testing.T) {
+	body := []byte(`{"messages":[
+		{"role":"system","content":"Describe images"},
+		{"role":"user","content":[
+			{"type":"text","text":"What is this?"},
+			{"type":"image_url","image_url":{"url":"` + pngDataURL(t, 256, 256) + `"}},
+			{"type":"image_url","image_url":{"url":"https://example.com/cat.png","detail":"low"}}
+		]}]}`)
+
+	text := estimatePromptTokens(body)
+	assert.Equal(t, 255+85, estimateImageTokens(body, config.ProviderTypeOpenAI))
+	assert.Equal(t, text+255+85, estimatePromptTokensFor(body, config.ProviderTypeOpenAI))
+	assert.Equal(t, text+258+1032, estimatePromptTokensFor(body, config.ProviderTypeVertexAI))
+
+	assert.Zero(t, estimateImageTokens([]byte(`{"messages":[{"role":"user","content":"Hi"}]}`), config.ProviderTypeOpenAI))
+	assert.Zero(t, estimateImageTokens([]byte(`invalid json`), config.ProviderTypeOpenAI))
+}
+
+func TestStreamTPMTokens(t *testing.T) {
+	assert.Equal(t, 120, streamTPMTokens(120, &RequestLogContext{PromptTokensEstimate: 900}), "reported usage wins")
+	assert.Equal(t, 900, streamTPMTokens(0, &RequestLogContext{PromptTokensEstimate: 900}))
+	assert.Zero(t, streamTPMTokens(0, nil))
+}
@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+// needsImageInlining reports whether requests for credType get remote image URLs inlined.
+// Only providers whose requests are converted from OpenAI format qualify: OpenAI-compatible
+// credentials fetch image URLs themselves.
+func (p *Proxy) needsImageInlining(credType config.ProviderType) bool {
+	if p.imageFetcher == nil {
+		return false
+	}
+	switch credType {
+	case config.ProviderTypeVertexAI, config.ProviderTypeGemini, config.ProviderTypeAnthropic, config.ProviderTypeBedrock:
+		return true
+	}
+	return false
+}
+
+// inlineImageURLs fetches remote image URLs and embeds them as data URLs.
+// On fetch errors the affected images keep their URL and the request proceeds.
+func (p *Proxy) inlineImageURLs(ctx context.Context, body []byte, logCtx *RequestLogContext) []byte {
+	inlinedBody, inlined, err := p.imageFetcher.InlineImageURLs(ctx, body)
+	if err != nil {
+		logCtx.Logger().Warn("Failed to inline some image URLs, sending them unchanged",
+			"error", err,
+		)
+	}
+	if inlined > 0 {
+		logCtx.Logger().Debug("Inlined remote image URLs", "count", inlined)
+	}
+	return inlinedBody
+}
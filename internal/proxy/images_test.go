@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/imagefetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_InlinesImageURLs(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png-bytes"))
+	}))
+	t.Cleanup(imageServer.Close)
+
+	var upstreamBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		upstreamBody = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"A cat"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("ant", config.ProviderTypeAnthropic, upstream.URL, "sk-ant").
+		WithMasterKey("master-key").
+		Build()
+	prx.imageFetcher = imagefetch.New(imagefetch.Config{
+		MaxBytes:             1024,
+		AllowedMIMETypes:     []string{"image/png"},
+		Timeout:              5 * time.Second,
+		AllowPrivateNetworks: true, // httptest listens on loopback
+	})
+
+	reqBody := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"` + imageServer.URL + `/cat.png"}}]}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(reqBody))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, upstreamBody, `"type":"base64"`)
+	assert.Contains(t, upstreamBody, `"media_type":"image/png"`)
+	assert.NotContains(t, upstreamBody, imageServer.URL)
+}
+
+func TestNeedsImageInlining(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	assert.False(t, prx.needsImageInlining(config.ProviderTypeVertexAI), "disabled without a fetcher")
+
+	prx.imageFetcher = imagefetch.New(imagefetch.Config{})
+	assert.True(t, prx.needsImageInlining(config.ProviderTypeVertexAI))
+	assert.True(t, prx.needsImageInlining(config.ProviderTypeGemini))
+	assert.True(t, prx.needsImageInlining(config.ProviderTypeAnthropic))
+	assert.True(t, prx.needsImageInlining(config.ProviderTypeBedrock))
+	assert.False(t, prx.needsImageInlining(config.ProviderTypeOpenAI))
+	assert.False(t, prx.needsImageInlining(config.ProviderTypeProxy))
+}
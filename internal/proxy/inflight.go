@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/security"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// ErrRequestCancelled is returned for upstream calls of a request cancelled via CancelRequest
+// or Shutdown
+var ErrRequestCancelled = errors.New("request cancelled by administrator")
+
+// ErrShuttingDown is the cancellation cause of the requests aborted by Shutdown
+var ErrShuttingDown = errors.New("router shutting down")
+
+// shutdownPollInterval is how often Shutdown checks for requests still being served
+const shutdownPollInterval = 10 * time.Millisecond
+
+// InFlightRequest is a request currently being served, as listed by GET /admin/requests
+type InFlightRequest struct {
+	ID         string    `json:"id"`
+	Key        string    `json:"key"`                  // Key alias, or the masked API key
+	Model      string    `json:"model"`                // Requested model
+	Credential string    `json:"credential,omitempty"` // Credential of the current upstream call
+	Path       string    `json:"path"`
+	Streaming  bool      `json:"streaming"`
+	StartedAt  time.Time `json:"started_at"`
+	Age        string    `json:"age"`
+}
+
+// inFlightRequest tracks one request; its context is the parent of every upstream call
+type inFlightRequest struct {
+	info   InFlightRequest
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// inFlightRegistry holds the requests between credential selection and the end of the response
+type inFlightRegistry struct {
+	mu       sync.Mutex
+	requests map[string]*inFlightRequest
+
+	ctx      context.Context         // Parent of every request's context, cancelled by Shutdown
+	cancel   context.CancelCauseFunc // Cancels ctx
+	handlers atomic.Int64            // ProxyRequest calls not returned yet, spend logging included
+}
+
+type inFlightKey struct{}
+
+func newInFlightRegistry() *inFlightRegistry {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &inFlightRegistry{requests: make(map[string]*inFlightRequest), ctx: ctx, cancel: cancel}
+}
+
+// trackRequest registers the request for GET /admin/requests and returns it with a context
+// carrying the tracker, plus a func removing it when the request is done
+func (p *Proxy) trackRequest(r *http.Request, logCtx *RequestLogContext, modelID string, streaming bool) (*http.Request, func()) {
+	key := security.MaskToken(logCtx.Token)
+	if logCtx.TokenInfo != nil && logCtx.TokenInfo.KeyAlias != "" {
+		key = logCtx.TokenInfo.KeyAlias
+	}
+	// Upstream calls are not tied to the client connection, only to admin cancellation and Shutdown
+	ctx, cancel := context.WithCancelCause(p.inFlight.ctx)
+	req := &inFlightRequest{
+		info: InFlightRequest{
+			ID:        logCtx.RequestID,
+			Key:       key,
+			Model:     modelID,
+			Path:      r.URL.Path,
+			Streaming: streaming,
+			StartedAt: logCtx.StartTime,
+		},
+		cancel: cancel,
+	}
+	req.ctx = context.WithValue(ctx, inFlightKey{}, req)
+
+	reg := p.inFlight
+	reg.mu.Lock()
+	reg.requests[req.info.ID] = req
+	reg.mu.Unlock()
+
+	return r.WithContext(context.WithValue(r.Context(), inFlightKey{}, req)), func() {
+		reg.mu.Lock()
+		delete(reg.requests, req.info.ID)
+		reg.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// InFlightRequests returns the requests being served, oldest first
+func (p *Proxy) InFlightRequests() []InFlightRequest {
+	now := utils.NowUTC()
+	p.inFlight.mu.Lock()
+	list := make([]InFlightRequest, 0, len(p.inFlight.requests))
+	for _, req := range p.inFlight.requests {
+		info := req.info
+		info.Age = now.Sub(info.StartedAt).Truncate(time.Millisecond).String()
+		list = append(list, info)
+	}
+	p.inFlight.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+// CancelRequest aborts the upstream call of an in-flight request. The client receives a 503
+// (or a truncated stream), and the request is neither retried nor counted against the credential.
+// Returns false if no such request is in flight.
+func (p *Proxy) CancelRequest(id string) bool {
+	p.inFlight.mu.Lock()
+	req, ok := p.inFlight.requests[id]
+	p.inFlight.mu.Unlock()
+	if ok {
+		req.cancel(ErrRequestCancelled)
+	}
+	return ok
+}
+
+// Shutdown waits for the requests being served to finish, spend logging included. If ctx ends
+// first, their upstream calls are cancelled: clients receive a 503 (or a truncated stream) and
+// the requests get spendLogGrace to log their spend; ctx.Err() is returned. Requests arriving
+// afterwards fail immediately, so the listener must be closed first.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	if p.inFlight.wait(ctx) {
+		return nil
+	}
+	p.logger.Warn("Cancelling requests still in flight at shutdown", "requests", p.inFlight.handlers.Load())
+	p.inFlight.cancel(ErrShuttingDown)
+
+	grace, cancel := context.WithTimeout(context.Background(), spendLogGrace)
+	defer cancel()
+	if !p.inFlight.wait(grace) {
+		p.logger.Error("Requests did not finish after cancellation", "requests", p.inFlight.handlers.Load())
+	}
+	return ctx.Err()
+}
+
+// wait reports whether every ProxyRequest call returned before ctx ended
+func (reg *inFlightRegistry) wait(ctx context.Context) bool {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for reg.handlers.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// upstreamContext returns the context for upstream calls of the incoming request r
+func upstreamContext(r *http.Request) context.Context {
+	if req, ok := r.Context().Value(inFlightKey{}).(*inFlightRequest); ok {
+		return req.ctx
+	}
+	return context.Background()
+}
+
+// setUpstreamCredential records the credential of the tracked request's current upstream call
+func (p *Proxy) setUpstreamCredential(ctx context.Context, credentialName string) {
+	if req, ok := ctx.Value(inFlightKey{}).(*inFlightRequest); ok {
+		p.inFlight.mu.Lock()
+		req.info.Credential = credentialName
+		p.inFlight.mu.Unlock()
+	}
+}
+
+// requestCancelled reports whether the tracked request of ctx was cancelled via CancelRequest
+// or Shutdown
+func requestCancelled(ctx context.Context) bool {
+	req, ok := ctx.Value(inFlightKey{}).(*inFlightRequest)
+	return ok && req.ctx.Err() != nil
+}
+
+// cancelCause returns why the tracked request of ctx was cancelled (nil if it was not)
+func cancelCause(ctx context.Context) error {
+	if req, ok := ctx.Value(inFlightKey{}).(*inFlightRequest); ok {
+		return context.Cause(req.ctx)
+	}
+	return nil
+}
+
+// writeRequestCancelled answers a request cancelled via CancelRequest or Shutdown
+func (p *Proxy) writeRequestCancelled(w http.ResponseWriter, logCtx *RequestLogContext) {
+	if errors.Is(cancelCause(logCtx.Request.Context()), ErrShuttingDown) {
+		logCtx.Logger().Warn("Request cancelled by shutdown")
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusServiceUnavailable
+		logCtx.ErrorMsg = ErrShuttingDown.Error()
+		WriteJSONError(w, http.StatusServiceUnavailable, "Router is shutting down",
+			errorTypeForStatus(http.StatusServiceUnavailable), nil, nil)
+		return
+	}
+	logCtx.Logger().Warn("Request cancelled by administrator")
+	logCtx.Status = "failure"
+	logCtx.HTTPStatus = http.StatusServiceUnavailable
+	logCtx.ErrorMsg = ErrRequestCancelled.Error()
+	WriteJSONError(w, http.StatusServiceUnavailable, "Request cancelled by administrator",
+		errorTypeForStatus(http.StatusServiceUnavailable), nil, nil)
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_CancelInFlight(t *testing.T) {
+	for _, credType := range []config.ProviderType{config.ProviderTypeOpenAI, config.ProviderTypeProxy} {
+		t.Run(string(credType), func(t *testing.T) {
+			var calls atomic.Int32
+			release := make(chan struct{})
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				<-release // Stuck until the test ends
+			}))
+			defer upstream.Close()
+			defer close(release)
+
+			logger := testhelpers.NewTestLogger()
+			rl := ratelimit.New()
+			credentials := []config.CredentialConfig{
+				{Name: "stuck", Type: credType, APIKey: "key1", BaseURL: upstream.URL, RPM: 100},
+				{Name: "other", Type: credType, APIKey: "key2", BaseURL: upstream.URL, RPM: 100},
+			}
+			for _, cred := range credentials {
+				rl.AddCredential(cred.Name, cred.RPM)
+			}
+			f2b := fail2ban.New(1, 0, []int{502})
+			bal := balancer.New(credentials, f2b, rl)
+			prx := createProxyWithParams(bal, logger, 10, time.Minute, createTestProxyMetrics(), "master-key", rl,
+				createTestTokenManager(logger), createTestModelManager(logger), "test-version", "test-commit")
+
+			assert.Empty(t, prx.InFlightRequests())
+			assert.False(t, prx.CancelRequest("unknown"))
+
+			w := httptest.NewRecorder()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model": "gpt-4o"}`))
+				req.Header.Set("Authorization", "Bearer master-key")
+				prx.ProxyRequest(w, req)
+			}()
+
+			var inFlight InFlightRequest
+			require.Eventually(t, func() bool {
+				list := prx.InFlightRequests()
+				if len(list) != 1 || list[0].Credential == "" || calls.Load() == 0 {
+					return false
+				}
+				inFlight = list[0]
+				return true
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, "gpt-4o", inFlight.Model)
+			assert.Equal(t, "/v1/chat/completions", inFlight.Path)
+			assert.False(t, inFlight.Streaming)
+			assert.NotEmpty(t, inFlight.ID)
+			assert.NotEmpty(t, inFlight.Age)
+
+			require.True(t, prx.CancelRequest(inFlight.ID))
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("cancelled request did not finish")
+			}
+
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+			var resp APIErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "Request cancelled by administrator", resp.Error.Message)
+			assert.Equal(t, int32(1), calls.Load(), "a cancelled request is not retried")
+			assert.False(t, f2b.IsBanned(inFlight.Credential, "gpt-4o"), "cancellation does not count against the credential")
+			assert.Empty(t, prx.InFlightRequests())
+		})
+	}
+}
+
+// startShutdownTestProxy returns a proxy whose only credential is served by upstream, and
+// the recorder of its spend logs
+func startShutdownTestProxy(upstream *httptest.Server) (*Proxy, *recordingSpendDB) {
+	logger := testhelpers.NewTestLogger()
+	rl := ratelimit.New()
+	credentials := []config.CredentialConfig{
+		{Name: "main", Type: config.ProviderTypeOpenAI, APIKey: "key1", BaseURL: upstream.URL, RPM: 100},
+	}
+	rl.AddCredential("main", 100)
+	bal := balancer.New(credentials, fail2ban.New(1, 0, []int{502}), rl)
+	prx := createProxyWithParams(bal, logger, 10, time.Minute, createTestProxyMetrics(), "master-key", rl,
+		createTestTokenManager(logger), createTestModelManager(logger), "test-version", "test-commit")
+	db := &recordingSpendDB{NoopManager: litellmdb.NewNoopManager()}
+	prx.LiteLLMDB = db
+	prx.spendSinksEnabled = true
+	return prx, db
+}
+
+// serveInBackground runs a chat completion request; done is closed when ProxyRequest returns
+func serveInBackground(prx *Proxy) (w *httptest.ResponseRecorder, done chan struct{}) {
+	w = httptest.NewRecorder()
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model": "gpt-4o"}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		prx.ProxyRequest(w, req)
+	}()
+	return w, done
+}
+
+func TestProxy_ShutdownWaitsForInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","choices":[],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	prx, db := startShutdownTestProxy(upstream)
+	w, done := serveInBackground(prx)
+	require.Eventually(t, func() bool { return len(prx.InFlightRequests()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- prx.Shutdown(ctx)
+	}()
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned while a request was in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdownErr)
+	<-done
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	require.Len(t, db.entries, 1, "spend is logged before Shutdown returns")
+	assert.Equal(t, "success", db.entries[0].Status)
+}
+
+func TestProxy_ShutdownCancelsRequestsPastDeadline(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release // Stuck until the test ends
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	prx, db := startShutdownTestProxy(upstream)
+	w, done := serveInBackground(prx)
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, prx.Shutdown(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("Shutdown returned before the cancelled request finished")
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp APIErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Router is shutting down", resp.Error.Message)
+	assert.Equal(t, int32(1), calls.Load(), "a request cancelled by shutdown is not retried")
+
+	db.mu.Lock()
+	require.Len(t, db.entries, 1)
+	assert.Equal(t, "failure", db.entries[0].Status)
+	assert.NoError(t, db.ctxErrs[0], "spend is queued with a context detached from the cancelled request")
+	db.mu.Unlock()
+
+	// Requests arriving after the cancellation fail without reaching the upstream
+	w, done = serveInBackground(prx)
+	<-done
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mixaill76/auto_ai_router/internal/models"
+)
+
+// LiteLLM-compatible response headers (litellm_headers), for tooling that parses the
+// headers of the LiteLLM proxy. Values set by a downstream router are passed through.
+const (
+	LiteLLMModelIDHeader      = "X-Litellm-Model-Id"      // credential.name:model_name (model_id of the spend logs)
+	LiteLLMResponseCostHeader = "X-Litellm-Response-Cost" // Cost of the request in USD
+	LiteLLMKeySpendHeader     = "X-Litellm-Key-Spend"     // Spend of the API key including the request (USD)
+)
+
+// litellmHeaders computes the x-litellm-* headers from the price registry and token usage
+type litellmHeaders struct {
+	prices *models.ModelPriceRegistry // nil = no cost headers
+}
+
+func newLiteLLMHeaders(prices *models.ModelPriceRegistry) *litellmHeaders {
+	return &litellmHeaders{prices: prices}
+}
+
+// setHeaders sets the headers known when the response headers are sent. Returns false if the
+// token usage is not known yet (streaming): the cost is then sent by setTrailers.
+func (l *litellmHeaders) setHeaders(h http.Header, logCtx *RequestLogContext) bool {
+	if logCtx.Credential != nil && h.Get(LiteLLMModelIDHeader) == "" {
+		h.Set(LiteLLMModelIDHeader, logCtx.Credential.Name+":"+logCtx.ModelID)
+	}
+	if logCtx.TokenUsage == nil {
+		return false
+	}
+	l.setCost(h, "", logCtx)
+	return true
+}
+
+// setTrailers sends the cost of a streamed response as HTTP trailers
+func (l *litellmHeaders) setTrailers(h http.Header, logCtx *RequestLogContext) {
+	if logCtx.TokenUsage != nil {
+		l.setCost(h, http.TrailerPrefix, logCtx)
+	}
+}
+
+func (l *litellmHeaders) setCost(h http.Header, prefix string, logCtx *RequestLogContext) {
+	if l.prices == nil || h.Get(LiteLLMResponseCostHeader) != "" {
+		return
+	}
+	price, _ := lookupRequestPrice(l.prices, logCtx)
+	if price == nil {
+		return
+	}
+	cost := price.CalculateCost(logCtx.TokenUsage)
+	if logCtx.CostMultiplier > 0 {
+		cost *= logCtx.CostMultiplier
+	}
+
+	h.Set(prefix+LiteLLMResponseCostHeader, formatUSD(cost))
+	if logCtx.TokenInfo != nil {
+		h.Set(prefix+LiteLLMKeySpendHeader, formatUSD(logCtx.TokenInfo.Spend+cost))
+	}
+}
+
+func formatUSD(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLiteLLMHeaders() *litellmHeaders {
+	prices := models.NewModelPriceRegistry()
+	prices.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	return newLiteLLMHeaders(prices)
+}
+
+func TestLiteLLMHeaders(t *testing.T) {
+	logCtx := &RequestLogContext{
+		ModelID:    "gpt-4o",
+		Credential: &config.CredentialConfig{Name: "oai1"},
+		TokenInfo:  &litellmdb.TokenInfo{Spend: 1.5},
+		TokenUsage: &converter.TokenUsage{PromptTokens: 100, CompletionTokens: 50},
+	}
+
+	w := httptest.NewRecorder()
+	cw := newCredentialHeaderWriter(w, logCtx, nil, nil, newTestLiteLLMHeaders())
+	_, _ = cw.Write([]byte("{}"))
+	cw.finish()
+	assert.Equal(t, "oai1:gpt-4o", w.Header().Get(LiteLLMModelIDHeader))
+	assert.Equal(t, "0.2", w.Header().Get(LiteLLMResponseCostHeader))
+	assert.Equal(t, "1.7", w.Header().Get(LiteLLMKeySpendHeader))
+
+	// Streaming: the usage is only known at the end, so the cost is sent as trailers
+	logCtx.TokenUsage = nil
+	logCtx.CostMultiplier = 0.5
+	w = httptest.NewRecorder()
+	cw = newCredentialHeaderWriter(w, logCtx, nil, nil, newTestLiteLLMHeaders())
+	_, _ = cw.Write([]byte("data: {}\n\n"))
+	logCtx.TokenUsage = &converter.TokenUsage{PromptTokens: 100, CompletionTokens: 50}
+	cw.finish()
+	res := w.Result()
+	assert.Equal(t, "oai1:gpt-4o", res.Header.Get(LiteLLMModelIDHeader))
+	assert.Empty(t, res.Header.Get(LiteLLMResponseCostHeader))
+	assert.Equal(t, "0.1", res.Trailer.Get(LiteLLMResponseCostHeader))
+	assert.Equal(t, "1.6", res.Trailer.Get(LiteLLMKeySpendHeader))
+
+	// Headers of a downstream router are passed through; unknown models have no cost
+	logCtx.ModelID = "unknown-model"
+	w = httptest.NewRecorder()
+	w.Header().Set(LiteLLMModelIDHeader, "downstream-id")
+	cw = newCredentialHeaderWriter(w, logCtx, nil, nil, newTestLiteLLMHeaders())
+	cw.WriteHeader(http.StatusOK)
+	assert.Equal(t, "downstream-id", w.Header().Get(LiteLLMModelIDHeader))
+	assert.Empty(t, w.Header().Get(LiteLLMResponseCostHeader))
+}
+
+func TestProxyRequest_LiteLLMHeaders(t *testing.T) {
+	prx := newChatProxy(t, []*int32{new(int32)}, nil)
+	prx.litellmHeaders = newTestLiteLLMHeaders()
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "oai1:gpt-4o", w.Header().Get(LiteLLMModelIDHeader))
+	assert.Equal(t, "0.007", w.Header().Get(LiteLLMResponseCostHeader))
+	assert.Empty(t, w.Header().Get(LiteLLMKeySpendHeader), "the master key has no key spend")
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/users"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// MatchMasterKey returns the valid master key equal to token and audits its use
+func (p *Proxy) MatchMasterKey(token string, r *http.Request) (masterkey.Key, bool) {
+	key, ok := p.masterKeys.Match(token)
+	if ok {
+		p.auditMasterKeyUse(key, r)
+	}
+	return key, ok
+}
+
+// auditMasterKeyUse records which master key authenticated a request. Requests with a key
+// that is being rotated out are logged as warnings, so remaining clients can be found
+// before the grace period ends.
+func (p *Proxy) auditMasterKeyUse(key masterkey.Key, r *http.Request) {
+	monitoring.MasterKeyRequests.WithLabelValues(key.ID).Inc()
+	if key.ExpiresAt != nil {
+		p.requestLogger(r).Warn("Request authenticated with a rotated master key",
+			"key_id", key.ID,
+			"expires_at", *key.ExpiresAt,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+		)
+		return
+	}
+	p.requestLogger(r).Debug("Request authenticated with master key", "key_id", key.ID, "path", r.URL.Path)
+}
+
+// validateSessionJWT returns the claims of a session token (from /v2/login) signed with any
+// valid master key, or nil
+func (p *Proxy) validateSessionJWT(token string) *users.SessionClaims {
+	for _, secret := range p.masterKeys.Secrets() {
+		if claims, err := users.ValidateSessionJWT(token, secret); err == nil && claims != nil {
+			return claims
+		}
+	}
+	return nil
+}
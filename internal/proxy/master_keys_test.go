@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/users"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_RotatedMasterKey(t *testing.T) {
+	prx := newChatProxy(t, []*int32{new(int32)}, nil)
+	_, err := prx.MasterKeys().Add("sk-new-master-key", time.Hour)
+	require.NoError(t, err)
+
+	oldKeyRequests := monitoring.MasterKeyRequests.WithLabelValues(masterkey.Fingerprint("master-key"))
+	before := testutil.ToFloat64(oldKeyRequests)
+
+	for _, key := range []string{"sk-new-master-key", "master-key"} {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "oai1", w.Header().Get(CredentialHeader), "both keys are master keys")
+	}
+	assert.Equal(t, before+1, testutil.ToFloat64(oldKeyRequests))
+	assert.Equal(t, "sk-new-master-key", prx.GetMasterKey())
+}
+
+func TestValidateSessionJWT_RotatedMasterKey(t *testing.T) {
+	prx := newChatProxy(t, []*int32{new(int32)}, nil)
+	claims := &users.SessionClaims{UserID: "admin", Exp: time.Now().Add(time.Hour).Unix()}
+	token, err := users.GenerateSessionJWT(claims, "master-key")
+	require.NoError(t, err)
+
+	_, err = prx.MasterKeys().Add("sk-new-master-key", time.Hour)
+	require.NoError(t, err)
+	got := prx.validateSessionJWT(token)
+	require.NotNil(t, got, "sessions signed with the previous key stay valid during the grace period")
+	assert.Equal(t, "admin", got.UserID)
+
+	require.NoError(t, prx.MasterKeys().Revoke(masterkey.Fingerprint("master-key")))
+	assert.Nil(t, prx.validateSessionJWT(token))
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/spendreport"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// MetricsExport is the in-memory accounting of this instance, written at shutdown
+// (metrics_export) so the usage not yet in LiteLLM DB or a delivered report is kept
+type MetricsExport struct {
+	Timestamp   time.Time                       `json:"timestamp"`
+	Version     string                          `json:"version"`
+	Usage       map[string]healthhistory.Totals `json:"usage"`                  // Per credential over the last 24h; idle credentials are pruned
+	SpendReport *spendreport.Report             `json:"spend_report,omitempty"` // Current, undelivered spend report period
+	Limits      ratelimit.HeadroomSnapshot      `json:"limits"`                 // Limiters with requests or tokens in their window; idle ones are pruned
+	SpendLog    *MetricsExportSpendLog          `json:"spend_log,omitempty"`    // LiteLLM DB spend logger counters
+}
+
+// MetricsExportSpendLog counts the spend log entries of this instance by outcome
+type MetricsExportSpendLog struct {
+	Queued    uint64 `json:"queued"`
+	Written   uint64 `json:"written"`
+	Dropped   uint64 `json:"dropped"`
+	Errors    uint64 `json:"errors"`
+	Unwritten int    `json:"unwritten"` // Still queued when the export was taken
+}
+
+// MetricsExport collects the usage histories, the spend report aggregates, the limiter
+// headroom and the spend logger counters. Components that are not configured are omitted.
+func (p *Proxy) MetricsExport() MetricsExport {
+	export := MetricsExport{
+		Timestamp:   utils.NowUTC(),
+		Version:     Version,
+		Usage:       p.history.Totals(healthhistory.MaxWindow),
+		SpendReport: p.spendReporter.Current(),
+	}
+	if p.rateLimiter != nil {
+		export.Limits = pruneIdleLimiters(p.rateLimiter.Headroom())
+	}
+	if p.LiteLLMDB != nil && p.LiteLLMDB.IsEnabled() {
+		stats := p.LiteLLMDB.SpendLoggerStats()
+		export.SpendLog = &MetricsExportSpendLog{
+			Queued:    stats.Queued,
+			Written:   stats.Written,
+			Dropped:   stats.Dropped,
+			Errors:    stats.Errors,
+			Unwritten: stats.QueueLen,
+		}
+	}
+	return export
+}
+
+// pruneIdleLimiters drops the credential and model limiters whose full limits are available,
+// i.e. that have no request or token in their window. Pools are kept for their spend.
+func pruneIdleLimiters(snapshot ratelimit.HeadroomSnapshot) ratelimit.HeadroomSnapshot {
+	for _, limiters := range []map[string]ratelimit.Headroom{snapshot.Credentials, snapshot.Models} {
+		for name, headroom := range limiters {
+			if headroom.ResetRPM == nil && headroom.ResetTPM == nil {
+				delete(limiters, name)
+			}
+		}
+	}
+	return snapshot
+}
+
+// WriteMetricsExport writes MetricsExport to path as JSON, replacing the file atomically
+func (p *Proxy) WriteMetricsExport(path string) error {
+	data, err := json.MarshalIndent(p.MetricsExport(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics export: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics export file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics export file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics export file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace metrics export file: %w", err)
+	}
+	return nil
+}
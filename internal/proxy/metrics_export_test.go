@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
+	"github.com/mixaill76/auto_ai_router/internal/spendreport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetricsExport(t *testing.T) {
+	prx := NewTestProxyBuilder().
+		WithCredentials(
+			config.CredentialConfig{Name: "busy", Type: config.ProviderTypeOpenAI, APIKey: "sk-1", BaseURL: "http://localhost", RPM: 10},
+			config.CredentialConfig{Name: "idle", Type: config.ProviderTypeOpenAI, APIKey: "sk-2", BaseURL: "http://localhost", RPM: 10},
+		).
+		Build()
+	prx.history = healthhistory.New()
+	prx.spendReporter = spendreport.New(config.SpendReportConfig{Enabled: true, TopN: 5}, nil)
+
+	require.True(t, prx.rateLimiter.Allow("busy"))
+	prx.history.Record("busy", 120, 0.25, false)
+	prx.history.Record("busy", 30, 0.5, true)
+	prx.spendReporter.Record(spendreport.Event{Key: "team-key", Model: "gpt-4o", Tokens: 150, Cost: 0.75})
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, prx.WriteMetricsExport(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var export MetricsExport
+	require.NoError(t, json.Unmarshal(data, &export))
+
+	assert.Equal(t, map[string]healthhistory.Totals{
+		"busy": {Requests: 2, Errors: 1, Tokens: 150, Spend: 0.75},
+	}, export.Usage)
+
+	require.NotNil(t, export.SpendReport)
+	assert.Equal(t, int64(1), export.SpendReport.Total.Requests)
+	assert.InDelta(t, 0.75, export.SpendReport.Total.Spend, 1e-9)
+	require.Len(t, export.SpendReport.TopKeys, 1)
+	assert.Equal(t, "team-key", export.SpendReport.TopKeys[0].Name)
+
+	assert.Contains(t, export.Limits.Credentials, "busy")
+	assert.NotContains(t, export.Limits.Credentials, "idle", "idle limiters are pruned")
+	assert.Nil(t, export.SpendLog, "LiteLLM DB is disabled")
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp file is left behind")
+
+	assert.Error(t, prx.WriteMetricsExport(filepath.Join(t.TempDir(), "missing", "metrics.json")))
+}
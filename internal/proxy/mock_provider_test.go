@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_MockCredential(t *testing.T) {
+	prx := NewTestProxyBuilder().
+		WithCredentials(config.CredentialConfig{
+			Name: "mock", Type: config.ProviderTypeMock, BaseURL: config.DefaultMockBaseURL, RPM: 100, TPM: 100000,
+			Mock: &config.MockConfig{Response: "Echo: {{.Prompt}}", PromptTokens: 9, CompletionTokens: 2},
+		}).
+		WithMasterKey("master-key").
+		Build()
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "mock", w.Header().Get(CredentialHeader))
+		return w
+	}
+
+	w := send(seedRequest)
+	assert.Contains(t, w.Body.String(), `"content":"Echo: Hi"`)
+	assert.Contains(t, w.Body.String(), `"total_tokens":11`)
+
+	w = send(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}`)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"content":"Echo: "`)
+	assert.Contains(t, w.Body.String(), "data: [DONE]")
+}
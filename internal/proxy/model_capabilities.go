@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+)
+
+// ModelCapabilitiesPathSuffix ends the per-model capabilities path: /v1/models/{model}/capabilities
+const ModelCapabilitiesPathSuffix = "/capabilities"
+
+// ModelCapabilities is the GET /v1/models/{model}/capabilities response body: what the router
+// knows about a model, so clients can pick parameters that will not be dropped or rejected
+type ModelCapabilities struct {
+	ID                string                 `json:"id"`
+	Object            string                 `json:"object"`                   // "model.capabilities"
+	ResolvedModel     string                 `json:"resolved_model,omitempty"` // Model name sent to providers when it differs from id
+	Mode              string                 `json:"mode,omitempty"`           // chat, embedding, image_generation, ... (from model prices)
+	Streaming         bool                   `json:"streaming"`
+	Tools             *bool                  `json:"tools"`             // Function calling (null = unknown)
+	StructuredOutput  *bool                  `json:"structured_output"` // response_format json_schema (null = unknown)
+	Reasoning         *bool                  `json:"reasoning"`         // Reasoning/thinking tokens (null = unknown)
+	Modalities        ModelModalities        `json:"modalities"`
+	UnsupportedParams []string               `json:"unsupported_params"` // Dropped on at least one credential serving the model
+	Headroom          CapacityHeadroom       `json:"headroom"`           // Sum over available credentials
+	Credentials       []CredentialCapability `json:"credentials"`
+	Pricing           *ModelPricing          `json:"pricing,omitempty"`
+}
+
+// ModelModalities lists the input and output content types of a model
+type ModelModalities struct {
+	Input  []string `json:"input"`
+	Output []string `json:"output"`
+}
+
+// CapacityHeadroom is the RPM/TPM left in the current minute (-1 = unlimited)
+type CapacityHeadroom struct {
+	RPM int `json:"rpm"`
+	TPM int `json:"tpm"`
+}
+
+// CredentialCapability describes one credential serving the model
+type CredentialCapability struct {
+	Name              string              `json:"name,omitempty"` // Only reported to the master key
+	Type              config.ProviderType `json:"type"`
+	Fallback          bool                `json:"fallback"`
+	Available         bool                `json:"available"` // Not banned for the model and below its limits
+	Headroom          CapacityHeadroom    `json:"headroom"`  // Lowest of the credential and model limits
+	UnsupportedParams []string            `json:"unsupported_params,omitempty"`
+}
+
+// ModelPricing is the model's entry in the model prices registry
+type ModelPricing struct {
+	InputCostPerToken  float64 `json:"input_cost_per_token"`
+	OutputCostPerToken float64 `json:"output_cost_per_token"`
+	MaxInputTokens     int     `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens    int     `json:"max_output_tokens,omitempty"`
+}
+
+// ServeModelCapabilities handles GET /v1/models/{model}/capabilities.
+// Any valid API key may query it; credential names are only reported to the master key.
+// Tenant keys only see the credentials of their tenant, like in /v1/models.
+func (p *Proxy) ServeModelCapabilities(w http.ResponseWriter, r *http.Request, modelID string) {
+	logCtx := &RequestLogContext{baseLogger: p.logger}
+	if !p.authenticateRequest(w, r, logCtx, p.isLiteLLMHealthy()) {
+		return
+	}
+
+	tenant := ""
+	if p.tenants != nil {
+		tenant = p.tenants.tenantFor(logCtx)
+	}
+	_, isMasterKey := p.masterKeys.Match(logCtx.Token)
+	caps, ok := p.ModelCapabilities(modelID, tenant, isMasterKey)
+	if !ok {
+		WriteErrorNotFound(w, "Model not found: "+modelID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(caps); err != nil {
+		logCtx.Logger().Error("Failed to encode model capabilities", "error", err)
+	}
+}
+
+// ModelCapabilities collects the credentials of tenant ("" = the credentials not assigned to
+// any tenant), headroom, parameters and price of a model (aliases are resolved like in
+// requests). Returns false if no such credential serves it.
+func (p *Proxy) ModelCapabilities(modelID, tenant string, withNames bool) (ModelCapabilities, bool) {
+	caps := ModelCapabilities{ID: modelID, Object: "model.capabilities", Credentials: []CredentialCapability{}}
+
+	if resolved, isAlias := p.modelManager.ResolveAlias(modelID); isAlias {
+		modelID = resolved
+	}
+	realModelID, _ := p.modelManager.GetRealModelName(modelID)
+	if realModelID != caps.ID {
+		caps.ResolvedModel = realModelID
+	}
+
+	unsupported := make(map[string]bool)
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if p.tenants != nil && p.tenants.owners[cred.Name] != tenant {
+			continue
+		}
+		if p.modelManager.IsEnabled() && !p.modelManager.HasModel(cred.Name, modelID) {
+			continue
+		}
+
+		c := CredentialCapability{
+			Type:              cred.Type,
+			Fallback:          cred.IsFallback,
+			Headroom:          p.credentialHeadroom(cred.Name, modelID),
+			UnsupportedParams: converter.CredentialUnsupportedParams(&cred),
+		}
+		for _, param := range p.paramNegotiator.unsupported(cred.Name, modelID, nil) {
+			if !slices.Contains(c.UnsupportedParams, param) {
+				c.UnsupportedParams = append(c.UnsupportedParams, param)
+			}
+		}
+		if withNames {
+			c.Name = cred.Name
+		}
+		c.Available = !p.balancer.IsBanned(cred.Name, modelID) && c.Headroom.RPM != 0 && c.Headroom.TPM != 0
+		if c.Available {
+			caps.Headroom.RPM = addHeadroom(caps.Headroom.RPM, c.Headroom.RPM)
+			caps.Headroom.TPM = addHeadroom(caps.Headroom.TPM, c.Headroom.TPM)
+		}
+		for _, param := range c.UnsupportedParams {
+			if !unsupported[param] {
+				unsupported[param] = true
+				caps.UnsupportedParams = append(caps.UnsupportedParams, param)
+			}
+		}
+		caps.Credentials = append(caps.Credentials, c)
+	}
+	if len(caps.Credentials) == 0 {
+		return ModelCapabilities{}, false
+	}
+	if caps.UnsupportedParams == nil {
+		caps.UnsupportedParams = []string{}
+	}
+
+	var price *models.ModelPrice
+	if p.priceRegistry != nil {
+		if price = p.priceRegistry.GetPrice(realModelID); price == nil {
+			price = p.priceRegistry.GetPrice(modelID)
+		}
+	}
+	applyPriceCapabilities(&caps, price)
+	return caps, true
+}
+
+// credentialHeadroom returns the requests and tokens left in the current minute for the
+// credential and its model limits (-1 = unlimited)
+func (p *Proxy) credentialHeadroom(credName, modelID string) CapacityHeadroom {
+	rl := p.rateLimiter
+	return CapacityHeadroom{
+		RPM: minHeadroom(
+			limitHeadroom(rl.GetLimitRPM(credName), rl.GetCurrentRPM(credName)),
+			limitHeadroom(rl.GetModelLimitRPM(credName, modelID), rl.GetCurrentModelRPM(credName, modelID)),
+		),
+		TPM: minHeadroom(
+			limitHeadroom(rl.GetLimitTPM(credName), rl.GetCurrentTPM(credName)),
+			limitHeadroom(rl.GetModelLimitTPM(credName, modelID), rl.GetCurrentModelTPM(credName, modelID)),
+		),
+	}
+}
+
+// limitHeadroom returns limit - current, or -1 if the limit is not set
+func limitHeadroom(limit, current int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return max(0, limit-current)
+}
+
+// minHeadroom returns the lower headroom, treating -1 as unlimited
+func minHeadroom(a, b int) int {
+	if a < 0 {
+		return b
+	}
+	if b < 0 {
+		return a
+	}
+	return min(a, b)
+}
+
+// addHeadroom sums headrooms; any unlimited (-1) makes the sum unlimited
+func addHeadroom(total, h int) int {
+	if total < 0 || h < 0 {
+		return -1
+	}
+	return total + h
+}
+
+// applyPriceCapabilities fills mode, features, modalities and pricing from the model prices entry
+func applyPriceCapabilities(caps *ModelCapabilities, price *models.ModelPrice) {
+	caps.Streaming = true
+	caps.Modalities = ModelModalities{Input: []string{"text"}, Output: []string{"text"}}
+	if price == nil {
+		return
+	}
+
+	caps.Mode = price.Mode
+	switch strings.ToLower(price.Mode) {
+	case "embedding", "rerank", "moderation", "image_generation", "audio_transcription":
+		caps.Streaming = false
+	}
+	if price.Mode == "image_generation" {
+		caps.Modalities.Output = []string{"image"}
+	}
+	caps.Tools = flagValue(price.SupportsFunctionCalling)
+	caps.StructuredOutput = flagValue(price.SupportsResponseSchema)
+	caps.Reasoning = flagValue(price.SupportsReasoning)
+
+	for _, m := range []struct {
+		flag     *models.FeatureFlag
+		modality string
+		output   bool
+	}{
+		{price.SupportsVision, "image", false},
+		{price.SupportsAudioInput, "audio", false},
+		{price.SupportsPDFInput, "file", false},
+		{price.SupportsAudioOutput, "audio", true},
+	} {
+		if m.flag == nil || !*m.flag {
+			continue
+		}
+		if m.output {
+			caps.Modalities.Output = append(caps.Modalities.Output, m.modality)
+		} else {
+			caps.Modalities.Input = append(caps.Modalities.Input, m.modality)
+		}
+	}
+
+	caps.Pricing = &ModelPricing{
+		InputCostPerToken:  price.InputCostPerToken,
+		OutputCostPerToken: price.OutputCostPerToken,
+		MaxInputTokens:     int(price.MaxInputTokens),
+		MaxOutputTokens:    int(price.MaxOutputTokens),
+	}
+}
+
+func flagValue(f *models.FeatureFlag) *bool {
+	if f == nil {
+		return nil
+	}
+	v := bool(*f)
+	return &v
+}
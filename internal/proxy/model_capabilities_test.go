@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCapabilitiesTestProxy() *Proxy {
+	prx := NewTestProxyBuilder().
+		WithCredentials(
+			config.CredentialConfig{Name: "ant", Type: config.ProviderTypeAnthropic, BaseURL: "http://ant.local", APIKey: "k1", RPM: 10, TPM: 1000, UnsupportedParams: []string{"seed"}},
+			config.CredentialConfig{Name: "oai", Type: config.ProviderTypeOpenAI, BaseURL: "http://oai.local", APIKey: "k2", RPM: 5, IsFallback: true},
+		).
+		WithMasterKey("master-key").
+		Build()
+
+	yes, no := models.FeatureFlag(true), models.FeatureFlag(false)
+	prx.priceRegistry = models.NewModelPriceRegistry()
+	prx.priceRegistry.Update(map[string]*models.ModelPrice{
+		"claude-sonnet-4-5": {
+			InputCostPerToken:       0.000003,
+			OutputCostPerToken:      0.000015,
+			MaxInputTokens:          200000,
+			MaxOutputTokens:         64000,
+			Mode:                    "chat",
+			SupportsFunctionCalling: &yes,
+			SupportsVision:          &yes,
+			SupportsPDFInput:        &yes,
+			SupportsAudioInput:      &no,
+		},
+		"text-embedding-3-small": {InputCostPerToken: 0.00000002, Mode: "embedding"},
+	})
+	return prx
+}
+
+func TestModelCapabilities(t *testing.T) {
+	prx := newCapabilitiesTestProxy()
+	require.True(t, prx.rateLimiter.TryAllowAll("ant", "claude-sonnet-4-5"))
+	prx.rateLimiter.ConsumeTokens("ant", 400)
+
+	caps, ok := prx.ModelCapabilities("claude-sonnet-4-5", "", true)
+	require.True(t, ok)
+	assert.Equal(t, "model.capabilities", caps.Object)
+	assert.Equal(t, "chat", caps.Mode)
+	assert.True(t, caps.Streaming)
+	require.NotNil(t, caps.Tools)
+	assert.True(t, *caps.Tools)
+	assert.Nil(t, caps.StructuredOutput, "not listed in the model prices")
+	assert.Equal(t, ModelModalities{Input: []string{"text", "image", "file"}, Output: []string{"text"}}, caps.Modalities)
+	assert.Equal(t, []string{"logprobs", "top_logprobs", "seed"}, caps.UnsupportedParams)
+	assert.Equal(t, &ModelPricing{InputCostPerToken: 0.000003, OutputCostPerToken: 0.000015, MaxInputTokens: 200000, MaxOutputTokens: 64000}, caps.Pricing)
+
+	require.Len(t, caps.Credentials, 2)
+	assert.Equal(t, CredentialCapability{
+		Name:              "ant",
+		Type:              config.ProviderTypeAnthropic,
+		Available:         true,
+		Headroom:          CapacityHeadroom{RPM: 9, TPM: 600},
+		UnsupportedParams: []string{"logprobs", "top_logprobs", "seed"},
+	}, caps.Credentials[0])
+	assert.Equal(t, CredentialCapability{
+		Name:      "oai",
+		Type:      config.ProviderTypeOpenAI,
+		Fallback:  true,
+		Available: true,
+		Headroom:  CapacityHeadroom{RPM: 5, TPM: -1},
+	}, caps.Credentials[1])
+	assert.Equal(t, CapacityHeadroom{RPM: 14, TPM: -1}, caps.Headroom)
+
+	// Exhausted and banned credentials are not available
+	for i := 0; i < 5; i++ {
+		require.True(t, prx.rateLimiter.TryAllowAll("oai", ""))
+	}
+	caps, _ = prx.ModelCapabilities("claude-sonnet-4-5", "", false)
+	assert.Empty(t, caps.Credentials[0].Name, "names are only reported to the master key")
+	assert.False(t, caps.Credentials[1].Available)
+	assert.Equal(t, CapacityHeadroom{RPM: 9, TPM: 600}, caps.Headroom)
+}
+
+func TestModelCapabilities_UnknownPrice(t *testing.T) {
+	prx := newCapabilitiesTestProxy()
+
+	caps, ok := prx.ModelCapabilities("text-embedding-3-small", "", true)
+	require.True(t, ok)
+	assert.False(t, caps.Streaming, "embeddings cannot stream")
+
+	caps, ok = prx.ModelCapabilities("unpriced-model", "", true)
+	require.True(t, ok, "model manager disabled: every credential serves every model")
+	assert.Nil(t, caps.Pricing)
+	assert.Nil(t, caps.Tools)
+	assert.True(t, caps.Streaming)
+	assert.Equal(t, ModelModalities{Input: []string{"text"}, Output: []string{"text"}}, caps.Modalities)
+}
+
+func TestServeModelCapabilities(t *testing.T) {
+	prx := newCapabilitiesTestProxy()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models/claude-sonnet-4-5/capabilities", nil)
+	w := httptest.NewRecorder()
+	prx.ServeModelCapabilities(w, req, "claude-sonnet-4-5")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Authorization", "Bearer master-key")
+	w = httptest.NewRecorder()
+	prx.ServeModelCapabilities(w, req, "claude-sonnet-4-5")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var caps ModelCapabilities
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+	assert.Equal(t, "claude-sonnet-4-5", caps.ID)
+	assert.Equal(t, "ant", caps.Credentials[0].Name)
+}
+
+func TestServeModelCapabilities_Tenants(t *testing.T) {
+	prx := newCapabilitiesTestProxy()
+	prx.tenants = newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", MasterKeys: []string{"alpha-key"}, Credentials: []string{"ant"}},
+	})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models/claude-sonnet-4-5/capabilities", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		prx.ServeModelCapabilities(w, req, "claude-sonnet-4-5")
+		return w
+	}
+
+	w := serve("alpha-key")
+	require.Equal(t, http.StatusOK, w.Code)
+	var caps ModelCapabilities
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+	require.Len(t, caps.Credentials, 1, "only the tenant's credentials")
+	assert.Equal(t, config.ProviderTypeAnthropic, caps.Credentials[0].Type)
+	assert.Empty(t, caps.Credentials[0].Name)
+
+	w = serve("master-key")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+	require.Len(t, caps.Credentials, 1, "untenanted keys do not see tenant credentials")
+	assert.Equal(t, "oai", caps.Credentials[0].Name)
+
+	// Models only other tenants serve are not found
+	prx.tenants = newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", MasterKeys: []string{"alpha-key"}, Credentials: []string{"ant"}},
+		{Name: "beta", MasterKeys: []string{"beta-key"}, Credentials: []string{"oai"}},
+	})
+	assert.Equal(t, http.StatusNotFound, serve("master-key").Code)
+}
+
+func TestHeadroomHelpers(t *testing.T) {
+	assert.Equal(t, -1, limitHeadroom(-1, 5))
+	assert.Equal(t, 0, limitHeadroom(3, 5))
+	assert.Equal(t, 3, minHeadroom(-1, 3))
+	assert.Equal(t, 2, minHeadroom(2, 3))
+	assert.Equal(t, -1, addHeadroom(4, -1))
+	assert.Equal(t, 7, addHeadroom(4, 3))
+}
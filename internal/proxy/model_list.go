@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/models"
+)
+
+// ServeModels handles GET /v1/models. The list is narrowed to the models the caller may
+// call: the allowed models of its LiteLLM key and team, the credentials of its tenant and,
+// with hide_unavailable_models, the credentials not banned for the model.
+func (p *Proxy) ServeModels(w http.ResponseWriter, r *http.Request, list models.ModelsResponse) {
+	logCtx := &RequestLogContext{baseLogger: p.logger}
+	if !p.authenticateRequest(w, r, logCtx, p.isLiteLLMHealthy()) {
+		return
+	}
+	list.Data = p.visibleModels(logCtx, list.Data)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		logCtx.Logger().Error("Failed to encode models response", "endpoint", "/v1/models", "error", err)
+	}
+}
+
+// ModelObject is the GET /v1/models/{model} response body: the model object of the list, plus
+// what the router knows about the model from model_alias, the models config and the model prices
+type ModelObject struct {
+	models.Model
+	ResolvedModel   string `json:"resolved_model,omitempty"` // Model name sent to providers when it differs from id
+	Mode            string `json:"mode,omitempty"`           // chat, embedding, image_generation, ... (from model prices)
+	MaxInputTokens  int    `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens int    `json:"max_output_tokens,omitempty"`
+}
+
+// ServeModel handles GET /v1/models/{model}. The model must be in list, or be a model_alias of
+// a model in it, and be visible to the caller like in ServeModels; otherwise 404 model_not_found.
+func (p *Proxy) ServeModel(w http.ResponseWriter, r *http.Request, list models.ModelsResponse, modelID string) {
+	logCtx := &RequestLogContext{baseLogger: p.logger}
+	if !p.authenticateRequest(w, r, logCtx, p.isLiteLLMHealthy()) {
+		return
+	}
+
+	target, isAlias := p.modelManager.ResolveAlias(modelID)
+	if !isAlias {
+		target = modelID
+	}
+	var found []models.Model
+	for _, model := range list.Data {
+		if model.ID == target {
+			model.ID = modelID
+			found = p.visibleModels(logCtx, []models.Model{model})
+			break
+		}
+	}
+	if len(found) == 0 {
+		param, code := "model", "model_not_found"
+		WriteJSONError(w, http.StatusNotFound, "The model '"+modelID+"' does not exist",
+			errorTypeForStatus(http.StatusNotFound), &param, &code)
+		return
+	}
+
+	object := ModelObject{Model: found[0]}
+	realModelID, _ := p.modelManager.GetRealModelName(target)
+	if realModelID != modelID {
+		object.ResolvedModel = realModelID
+	}
+	if p.priceRegistry != nil {
+		price := p.priceRegistry.GetPrice(realModelID)
+		if price == nil {
+			price = p.priceRegistry.GetPrice(target)
+		}
+		if price != nil {
+			object.Mode = price.Mode
+			object.MaxInputTokens = int(price.MaxInputTokens)
+			object.MaxOutputTokens = int(price.MaxOutputTokens)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(object); err != nil {
+		logCtx.Logger().Error("Failed to encode model response", "endpoint", "/v1/models/{model}", "error", err)
+	}
+}
+
+// visibleModels returns the models of list the authenticated request may call
+func (p *Proxy) visibleModels(logCtx *RequestLogContext, list []models.Model) []models.Model {
+	tenant := ""
+	if p.tenants != nil {
+		tenant = p.tenants.tenantFor(logCtx)
+	}
+	checkCredentials := p.tenants != nil || p.hideUnavailable
+
+	visible := make([]models.Model, 0, len(list))
+	for _, model := range list {
+		if logCtx.TokenInfo != nil && !logCtx.TokenInfo.IsModelAllowed(model.ID) {
+			continue
+		}
+		if checkCredentials && !p.modelServed(model.ID, tenant) {
+			continue
+		}
+		visible = append(visible, model)
+	}
+	return visible
+}
+
+// modelServed reports whether a credential of tenant ("" = the credentials not assigned to
+// any tenant) serves modelID and, with hide_unavailable_models, is not banned for it
+func (p *Proxy) modelServed(modelID, tenant string) bool {
+	if resolved, isAlias := p.modelManager.ResolveAlias(modelID); isAlias {
+		modelID = resolved
+	}
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if p.tenants != nil && p.tenants.owners[cred.Name] != tenant {
+			continue
+		}
+		if p.modelManager.IsEnabled() && !p.modelManager.HasModel(cred.Name, modelID) {
+			continue
+		}
+		if p.hideUnavailable && p.balancer.IsBanned(cred.Name, modelID) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var modelListTestModels = []models.Model{
+	{ID: "gpt-4o", Object: "model"},
+	{ID: "gpt-4o-mini", Object: "model"},
+	{ID: "claude-sonnet-4-5", Object: "model"},
+}
+
+func newModelListProxy() *Proxy {
+	return NewTestProxyBuilder().
+		WithCredentials(
+			config.CredentialConfig{Name: "a", Type: config.ProviderTypeOpenAI, BaseURL: "http://a.local", APIKey: "k1"},
+			config.CredentialConfig{Name: "b", Type: config.ProviderTypeOpenAI, BaseURL: "http://b.local", APIKey: "k2"},
+		).
+		WithMasterKey("master-key").
+		Build()
+}
+
+func modelIDs(list []models.Model) []string {
+	ids := make([]string, 0, len(list))
+	for _, model := range list {
+		ids = append(ids, model.ID)
+	}
+	return ids
+}
+
+func TestVisibleModels_KeyAndTeamModels(t *testing.T) {
+	prx := newModelListProxy()
+
+	all := prx.visibleModels(&RequestLogContext{}, modelListTestModels)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini", "claude-sonnet-4-5"}, modelIDs(all), "master key sees every model")
+
+	keyOnly := &RequestLogContext{TokenInfo: &litellmdb.TokenInfo{Models: []string{"gpt-4o", "claude-sonnet-4-5"}}}
+	assert.Equal(t, []string{"gpt-4o", "claude-sonnet-4-5"}, modelIDs(prx.visibleModels(keyOnly, modelListTestModels)))
+
+	withTeam := &RequestLogContext{TokenInfo: &litellmdb.TokenInfo{Models: []string{"gpt-4o", "claude-sonnet-4-5"}, TeamModels: []string{"gpt-4o", "gpt-4o-mini"}}}
+	assert.Equal(t, []string{"gpt-4o"}, modelIDs(prx.visibleModels(withTeam, modelListTestModels)))
+}
+
+func TestVisibleModels_HideUnavailable(t *testing.T) {
+	prx := newModelListProxy()
+	for i := 0; i < 3; i++ {
+		prx.balancer.RecordResponse("a", "gpt-4o", 500)
+		prx.balancer.RecordResponse("a", "gpt-4o-mini", 500)
+		prx.balancer.RecordResponse("b", "gpt-4o-mini", 500)
+	}
+
+	ids := modelIDs(prx.visibleModels(&RequestLogContext{}, modelListTestModels))
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini", "claude-sonnet-4-5"}, ids, "banned models are listed by default")
+
+	prx.hideUnavailable = true
+	ids = modelIDs(prx.visibleModels(&RequestLogContext{}, modelListTestModels))
+	assert.Equal(t, []string{"gpt-4o", "claude-sonnet-4-5"}, ids, "gpt-4o is still served by b")
+}
+
+func TestVisibleModels_Tenants(t *testing.T) {
+	prx := newModelListProxy()
+	prx.tenants = newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", MasterKeys: []string{"alpha-key"}, Credentials: []string{"a"}},
+	})
+	prx.modelManager = models.New(createHealthTestLogger(), 50, []config.ModelRPMConfig{{Name: "gpt-4o"}, {Name: "gpt-4o-mini"}})
+	prx.modelManager.AddModel("a", "gpt-4o")
+	prx.modelManager.AddModel("b", "gpt-4o-mini")
+
+	alpha := modelIDs(prx.visibleModels(&RequestLogContext{Tenant: "alpha"}, modelListTestModels))
+	assert.Equal(t, []string{"gpt-4o"}, alpha)
+
+	untenanted := modelIDs(prx.visibleModels(&RequestLogContext{}, modelListTestModels))
+	assert.Equal(t, []string{"gpt-4o-mini"}, untenanted)
+}
+
+func TestServeModels(t *testing.T) {
+	prx := newModelListProxy()
+	list := models.ModelsResponse{Object: "list", Data: modelListTestModels}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	w := httptest.NewRecorder()
+	prx.ServeModels(w, req, list)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Authorization", "Bearer master-key")
+	w = httptest.NewRecorder()
+	prx.ServeModels(w, req, list)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.ModelsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "list", resp.Object)
+	assert.Len(t, resp.Data, 3)
+}
+
+func TestServeModel(t *testing.T) {
+	prx := newModelListProxy()
+	prx.modelManager.SetModelAliases(map[string]string{"smart": "gpt-4o"})
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{"gpt-4o": {Mode: "chat", MaxInputTokens: 128000, MaxOutputTokens: 16384}})
+	prx.priceRegistry = registry
+	list := models.ModelsResponse{Object: "list", Data: []models.Model{
+		{ID: "gpt-4o", Object: "model", Created: 1715367049, OwnedBy: "openai"},
+		{ID: "claude-sonnet-4-5", Object: "model", Created: 1, OwnedBy: "anthropic"},
+	}}
+
+	retrieve := func(token, modelID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models/"+modelID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		prx.ServeModel(w, req, list, modelID)
+		return w
+	}
+	assert.Equal(t, http.StatusUnauthorized, retrieve("wrong-key", "gpt-4o").Code)
+
+	w := retrieve("master-key", "gpt-4o")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"gpt-4o","object":"model","created":1715367049,"owned_by":"openai","mode":"chat","max_input_tokens":128000,"max_output_tokens":16384}`, w.Body.String())
+
+	w = retrieve("master-key", "smart")
+	require.Equal(t, http.StatusOK, w.Code)
+	var object ModelObject
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &object))
+	assert.Equal(t, "smart", object.ID, "aliases are retrieved under their own name")
+	assert.Equal(t, "gpt-4o", object.ResolvedModel)
+	assert.Equal(t, "openai", object.OwnedBy)
+
+	w = retrieve("master-key", "gpt-5")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Param   string `json:"param"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "The model 'gpt-5' does not exist", resp.Error.Message)
+	assert.Equal(t, "model", resp.Error.Param)
+	assert.Equal(t, "model_not_found", resp.Error.Code)
+}
+
+func TestServeModel_HiddenModel(t *testing.T) {
+	prx := newModelListProxy()
+	prx.tenants = newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", MasterKeys: []string{"alpha-key"}, Credentials: []string{"a"}},
+	})
+	prx.modelManager = models.New(createHealthTestLogger(), 50, []config.ModelRPMConfig{{Name: "gpt-4o"}, {Name: "gpt-4o-mini"}})
+	prx.modelManager.AddModel("b", "gpt-4o-mini")
+	list := models.ModelsResponse{Object: "list", Data: modelListTestModels}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models/gpt-4o-mini", nil)
+	req.Header.Set("Authorization", "Bearer alpha-key")
+	w := httptest.NewRecorder()
+	prx.ServeModel(w, req, list, "gpt-4o-mini")
+	assert.Equal(t, http.StatusNotFound, w.Code, "models of other tenants do not exist for the caller")
+}
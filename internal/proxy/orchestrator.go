@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strings"
 
@@ -12,7 +13,7 @@ import (
 	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"github.com/mixaill76/auto_ai_router/internal/converter/responses"
-	"github.com/mixaill76/auto_ai_router/internal/litellmdb/users"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/mixaill76/auto_ai_router/internal/security"
 )
 
@@ -25,6 +26,7 @@ type orchestratedRequest struct {
 	cred           *config.CredentialConfig
 	isResponsesAPI bool
 	convertedResp  bool
+	release        func() // Returns the fair scheduler slot; call when the request is done
 }
 
 // orchestrateRequest performs auth and credential selection for an incoming request.
@@ -41,23 +43,62 @@ func (p *Proxy) orchestrateRequest(
 		return nil, false
 	}
 
+	if !p.enforceKeyRateLimit(w, r, logCtx) {
+		return nil, false
+	}
+	if !p.applyTenant(w, r, logCtx) {
+		return nil, false
+	}
+	if p.priority != nil {
+		logCtx.PriorityClass = p.priority.classFor(logCtx)
+	}
+
 	body, modelID, realModelID, streaming, ok := p.readRequestBodyAndSelectModel(w, r, logCtx)
 	if !ok {
 		return nil, false
 	}
 
-	// Detect Responses API requests and select credential before conversion.
-	isResponsesAPI := responses.IsResponsesAPI(body) && strings.Contains(r.URL.Path, "/responses")
+	if isImageEditPath(r.URL.Path) && !p.routeImageEdit(w, r, modelID, logCtx) {
+		return nil, false
+	}
 
-	cred, ok := p.selectCredentialForModel(w, modelID, logCtx)
+	overrides, ok := p.applyRoutingOverrides(w, r, logCtx)
 	if !ok {
 		return nil, false
 	}
+	if !p.enforceCostCeiling(w, body, modelID, realModelID, logCtx) {
+		return nil, false
+	}
 
-	p.logger.Debug("Responses API detection",
+	release, ok := p.admitRequest(w, r, logCtx)
+	if !ok {
+		return nil, false
+	}
+	admitted := false
+	defer func() {
+		if !admitted {
+			release()
+		}
+	}()
+
+	// Detect Responses API requests and select credential before conversion.
+	isResponsesAPI := responses.IsResponsesAPI(body) && strings.Contains(r.URL.Path, "/responses")
+
+	share := p.classShare(logCtx)
+	cred := p.selectPreferredCredential(overrides, modelID, share, logCtx)
+	if cred == nil {
+		cred = p.selectCapableCredential(r, modelID, body, share)
+	}
+	if cred == nil {
+		if cred, ok = p.selectCredentialForModel(w, r, modelID, p.routingSeed(r, body), logCtx); !ok {
+			return nil, false
+		}
+	}
+	recordPriorityResult(logCtx, "allowed")
+
+	logCtx.Logger().Debug("Responses API detection",
 		"is_responses_api", isResponsesAPI,
 		"provider", cred.Type,
-		"model", modelID,
 		"streaming", streaming,
 		"url_path", r.URL.Path)
 
@@ -70,7 +111,7 @@ func (p *Proxy) orchestrateRequest(
 	if isResponsesAPI {
 		chatBody, convErr := responses.RequestToChat(body)
 		if convErr != nil {
-			p.logger.Error("Failed to convert Responses API request", "error", convErr)
+			logCtx.Logger().Error("Failed to convert Responses API request", "error", convErr)
 			logCtx.Status = "failure"
 			logCtx.HTTPStatus = http.StatusBadRequest
 			logCtx.ErrorMsg = "Failed to convert Responses API request: " + convErr.Error()
@@ -88,13 +129,17 @@ func (p *Proxy) orchestrateRequest(
 		// Rewrite URL path from /v1/responses to /v1/chat/completions
 		// so passthrough providers (OpenAI, Proxy) send to the correct endpoint.
 		r.URL.Path = strings.Replace(r.URL.Path, "/responses", "/chat/completions", 1)
-		p.logger.Debug("Converted Responses API request to Chat Completions format",
-			"model", modelID, "streaming", streaming)
+		logCtx.Logger().Debug("Converted Responses API request to Chat Completions format",
+			"streaming", streaming)
 	}
 
+	body = p.applySystemPrompt(r, body, modelID, realModelID, logCtx)
+	body = p.fitContextWindow(w, r, body, modelID, realModelID, logCtx)
+
 	logCtx.Credential = cred
 	r = markCredentialAsTried(r, cred.Name)
 
+	admitted = true
 	return &orchestratedRequest{
 		request:        r,
 		body:           body,
@@ -104,6 +149,7 @@ func (p *Proxy) orchestrateRequest(
 		cred:           cred,
 		isResponsesAPI: isResponsesAPI,
 		convertedResp:  convertedResp,
+		release:        release,
 	}, true
 }
 
@@ -138,9 +184,13 @@ func (p *Proxy) authenticateRequest(
 	logCtx *RequestLogContext,
 	isLiteLLMHealthy bool,
 ) bool {
+	if p.routerVerifier != nil && httputil.IsSignedRequest(r) {
+		return p.authenticateSignedRequest(w, r, logCtx)
+	}
+
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		p.logger.Error("Missing Authorization header")
+		logCtx.Logger().Error("Missing Authorization header")
 		logCtx.Status = "failure"
 		logCtx.HTTPStatus = http.StatusUnauthorized
 		logCtx.ErrorMsg = "Missing Authorization header"
@@ -151,7 +201,7 @@ func (p *Proxy) authenticateRequest(
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	logCtx.Token = token
 	if token == authHeader {
-		p.logger.Error("Invalid Authorization header format")
+		logCtx.Logger().Error("Invalid Authorization header format")
 		logCtx.Status = "failure"
 		logCtx.HTTPStatus = http.StatusUnauthorized
 		logCtx.ErrorMsg = "Invalid Authorization header format"
@@ -159,15 +209,20 @@ func (p *Proxy) authenticateRequest(
 		return false
 	}
 
-	if token == p.masterKey {
+	if _, ok := p.MatchMasterKey(token, r); ok {
+		return true
+	}
+	if tenant, ok := p.tenants.matchMasterKey(token); ok {
+		logCtx.Logger().Debug("Request authenticated with tenant master key", "tenant", tenant, "path", r.URL.Path)
+		logCtx.Tenant = tenant
 		return true
 	}
 
 	// JWT session token validation (tokens from /v2/login)
 	if strings.HasPrefix(token, "eyJ") {
-		claims, jwtErr := users.ValidateSessionJWT(token, p.masterKey)
-		if jwtErr == nil && claims != nil {
-			p.logger.Debug("Authenticated via session JWT",
+		claims := p.validateSessionJWT(token)
+		if claims != nil {
+			logCtx.Logger().Debug("Authenticated via session JWT",
 				"user_id", claims.UserID,
 				"user_role", claims.UserRole,
 			)
@@ -183,21 +238,21 @@ func (p *Proxy) authenticateRequest(
 			logCtx.Status = "failure"
 			logCtx.HTTPStatus = http.StatusUnauthorized
 
-			if p.handleLiteLLMAuthError(w, err, token) {
+			if p.handleLiteLLMAuthError(w, err, token, logCtx) {
 				logCtx.ErrorMsg = "LiteLLM auth validation failed"
 			} else {
 				logCtx.ErrorMsg = "LiteLLM DB unavailable"
 			}
 			return false
 		} else if tokenInfo != nil {
-			p.logger.Debug("Token validated via LiteLLM DB",
+			logCtx.Logger().Debug("Token validated via LiteLLM DB",
 				"user_id", tokenInfo.UserID,
 				"team_id", tokenInfo.TeamID,
 			)
 		}
 		return true
 	} else {
-		p.logger.Error("Invalid master key", "provided_key_prefix", security.MaskAPIKey(token))
+		logCtx.Logger().Error("Invalid master key", "provided_key_prefix", security.MaskAPIKey(token))
 		WriteErrorUnauthorized(w, "Invalid master key")
 	}
 
@@ -212,7 +267,7 @@ func (p *Proxy) readRequestBodyAndSelectModel(
 	maxBodyBytes := int64(p.maxBodySizeMB) * 1024 * 1024
 	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
 	if err != nil {
-		p.logger.Error("Failed to read request body", "error", err)
+		logCtx.Logger().Error("Failed to read request body", "error", err)
 		logCtx.Status = "failure"
 		logCtx.HTTPStatus = http.StatusBadRequest
 		logCtx.ErrorMsg = "Failed to read request body: " + err.Error()
@@ -220,10 +275,10 @@ func (p *Proxy) readRequestBodyAndSelectModel(
 		return nil, "", "", false, false
 	}
 	if closeErr := r.Body.Close(); closeErr != nil {
-		p.logger.Error("Failed to close request body", "error", closeErr)
+		logCtx.Logger().Error("Failed to close request body", "error", closeErr)
 	}
 	if int64(len(body)) > maxBodyBytes {
-		p.logger.Error("Request body exceeds max size",
+		logCtx.Logger().Error("Request body exceeds max size",
 			"max_body_size_mb", p.maxBodySizeMB,
 			"actual_size_bytes", len(body),
 		)
@@ -234,12 +289,24 @@ func (p *Proxy) readRequestBodyAndSelectModel(
 		return nil, "", "", false, false
 	}
 
-	modelID, streaming, sessionID, body := extractMetadataFromBody(body)
+	var (
+		modelID, sessionID string
+		streaming          bool
+	)
+	boundary := multipartBoundary(r.Header.Get("Content-Type"))
+	if boundary != "" {
+		// Image edits and variations: the model is a form field
+		fields := multipartFields(body, boundary)
+		modelID, sessionID = fields["model"], fields["user"]
+	} else {
+		modelID, streaming, sessionID, body = extractMetadataFromBody(body)
+	}
+	requestedModel := modelID
 	logCtx.ModelID = modelID
 	logCtx.SessionID = sessionID
 
 	if modelID == "" {
-		p.logger.Error("Model not specified in request body")
+		logCtx.Logger().Error("Model not specified in request body")
 		logCtx.Status = "failure"
 		logCtx.HTTPStatus = http.StatusBadRequest
 		logCtx.ErrorMsg = "Model not specified in request body"
@@ -247,52 +314,89 @@ func (p *Proxy) readRequestBodyAndSelectModel(
 		return nil, "", "", false, false
 	}
 
+	// Route model groups by the request's reasoning_effort (reasoning_routing)
+	body, modelID, errMsg := p.resolveReasoningGroup(body, modelID, logCtx)
+	if errMsg != "" {
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusBadRequest
+		logCtx.ErrorMsg = errMsg
+		WriteErrorBadRequest(w, errMsg)
+		return nil, "", "", false, false
+	}
+	logCtx.ModelID = modelID
+
 	// Resolve model_alias entries (changes modelID to real name; credential lookup uses real name)
 	if resolved, isAlias := p.modelManager.ResolveAlias(modelID); isAlias {
-		p.logger.Debug("Resolved model alias", "alias", modelID, "resolved", resolved)
+		logCtx.Logger().Debug("Resolved model alias", "alias", modelID, "resolved", resolved)
 		body = openai.ReplaceModelInBody(body, modelID, resolved)
 		modelID = resolved
 		logCtx.ModelID = modelID
 	}
 
+	// Resolve rolling aliases to their pinned snapshot (model_pins)
+	if snapshot, pinned := p.modelPins.Resolve(modelID); pinned {
+		logCtx.Logger().Debug("Resolved pinned model snapshot", "alias", modelID, "snapshot", snapshot)
+		body = openai.ReplaceModelInBody(body, modelID, snapshot)
+		modelID = snapshot
+		logCtx.ModelID = modelID
+	}
+
 	// Resolve models[].model field: replace model in body for provider but keep alias as modelID
 	// for rate limiting and credential lookup.
 	realModelID := modelID
 	if realName, hasReal := p.modelManager.GetRealModelName(modelID); hasReal {
-		p.logger.Debug("Resolved model real name", "alias", modelID, "real", realName)
+		logCtx.Logger().Debug("Resolved model real name", "alias", modelID, "real", realName)
 		body = openai.ReplaceModelInBody(body, modelID, realName)
 		realModelID = realName
 	}
 
-	body = openai.ReplaceBodyParam(modelID, body)
+	if boundary != "" {
+		if realModelID != requestedModel {
+			body = setMultipartField(body, boundary, "model", realModelID)
+		}
+	} else {
+		body = p.reasoningParams.replaceBodyParams(body, modelID, realModelID)
+	}
 
 	return body, modelID, realModelID, streaming, true
 }
 
+// selectCredentialForModel selects a regular, then a fallback credential for the model,
+// skipping the request's tried credentials (those ruled out by routing overrides)
 func (p *Proxy) selectCredentialForModel(
 	w http.ResponseWriter,
+	r *http.Request,
 	modelID string,
+	seed string,
 	logCtx *RequestLogContext,
 ) (*config.CredentialConfig, bool) {
-	cred, err := p.balancer.NextForModel(modelID)
+	share := p.classShare(logCtx)
+	exclude := GetTried(r.Context())
+	cred, err := p.balancer.Select(modelID, balancer.SelectOptions{Exclude: exclude, Seed: seed, Share: share})
 	if err == nil {
 		return cred, true
 	}
 
 	fallbackErr := error(nil)
-	cred, fallbackErr = p.balancer.NextFallbackForModel(modelID)
+	cred, fallbackErr = p.balancer.Select(modelID, balancer.SelectOptions{Fallback: true, Exclude: exclude, Seed: seed, Share: share})
 	if fallbackErr == nil {
 		return cred, true
 	}
 
-	errCode := http.StatusTooManyRequests
-	errorMsg := fmt.Sprintf("No credentials available: %v", err)
-	if errors.Is(err, balancer.ErrRateLimitExceeded) || errors.Is(fallbackErr, balancer.ErrRateLimitExceeded) {
-		errorMsg = "Rate limit exceeded"
+	exhaustion := p.balancer.Explain(modelID, balancer.SelectOptions{Exclude: exclude, Share: share}).
+		Merge(p.balancer.Explain(modelID, balancer.SelectOptions{Fallback: true, Exclude: exclude, Share: share}))
+	errCode, capErr := capacityError(modelID, err, fallbackErr, exhaustion)
+	if share < 1 && capErr.Constraint == "rate_limit" {
+		capErr.Message = "Rate limit exceeded for priority class " + logCtx.PriorityClass
+	}
+	if p.scheduler != nil {
+		inFlight, queued := p.scheduler.Stats()
+		capErr.Queue = &CapacityQueue{InFlight: inFlight, Queued: queued}
 	}
+	errorMsg := capErr.Message
+	recordPriorityResult(logCtx, "rejected")
 
-	p.logger.Error("No credentials available (regular and fallback)",
-		"model", modelID,
+	logCtx.Logger().Error("No credentials available (regular and fallback)",
 		"primary_error", err,
 		"fallback_error", fallbackErr,
 	)
@@ -306,13 +410,53 @@ func (p *Proxy) selectCredentialForModel(
 	}
 
 	if err := p.logSpendToLiteLLMDB(logCtx); err != nil {
-		p.logger.Warn("Failed to queue error log for no credentials",
+		logCtx.Logger().Warn("Failed to queue error log for no credentials",
 			"error", err,
-			"request_id", logCtx.RequestID,
 		)
 	}
 	logCtx.Logged = true
 
-	WriteErrorRateLimit(w, errorMsg)
+	WriteCapacityError(w, errCode, capErr)
 	return nil, false
 }
+
+// capacityError builds the error for a request no credential can serve. Rate limits take
+// precedence (429): they clear on their own soonest. Candidates that are all banned or behind
+// unhealthy proxies give 503; a model without candidates keeps the generic 429.
+func capacityError(modelID string, err, fallbackErr error, e balancer.Exhaustion) (int, CapacityError) {
+	statusCode := http.StatusTooManyRequests
+	constraint, code := "no_credentials", "no_credentials"
+	message := fmt.Sprintf("No credentials available: %v", err)
+	switch {
+	case e.RateLimited > 0 || errors.Is(err, balancer.ErrRateLimitExceeded) || errors.Is(fallbackErr, balancer.ErrRateLimitExceeded):
+		constraint, code = "rate_limit", "rate_limit_exceeded"
+		message = "Rate limit exceeded"
+	case e.Banned > 0 || e.ProxyUnhealthy > 0:
+		statusCode = http.StatusServiceUnavailable
+		constraint, code = "banned", "credentials_unavailable"
+		if e.Banned == 0 {
+			constraint = "proxy_unhealthy"
+		}
+		message = fmt.Sprintf("All credentials for model %s are temporarily unavailable", modelID)
+	}
+
+	retryAfter := int(math.Ceil(e.RetryAfter.Seconds()))
+	if constraint == "rate_limit" && retryAfter < 1 {
+		retryAfter = 1
+	}
+	return statusCode, CapacityError{
+		APIError: APIError{
+			Message: message,
+			Type:    errorTypeForStatus(statusCode),
+			Code:    &code,
+		},
+		Constraint: constraint,
+		RetryAfter: retryAfter,
+		Credentials: CapacityCredentials{
+			Eligible:       e.Credentials,
+			Banned:         e.Banned,
+			RateLimited:    e.RateLimited,
+			ProxyUnhealthy: e.ProxyUnhealthy,
+		},
+	}
+}
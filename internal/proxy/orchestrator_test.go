@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
 	"github.com/stretchr/testify/require"
 )
@@ -80,3 +81,29 @@ func TestOrchestrateRequest_ResponsesAPI_ConvertedForOpenAI(t *testing.T) {
 	_, hasMessages := raw["messages"]
 	require.True(t, hasMessages, "messages should be present after conversion")
 }
+
+func TestOrchestrateRequest_ModelPinResolved(t *testing.T) {
+	logger := testhelpers.NewTestLogger()
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("test", config.ProviderTypeOpenAI, "http://test.local", "upstream-key").
+		WithMasterKey("master-key").
+		Build()
+	prx.logger = logger
+	prx.modelPins = models.NewPinRegistry(config.ModelPinsConfig{
+		Enabled: true,
+		Aliases: map[string]config.ModelPinConfig{"gpt-4o": {Policy: config.ModelPinPolicyPinned, Family: "gpt-4o"}},
+	}, func() []string { return []string{"gpt-4o", "gpt-4o-2024-05-13", "gpt-4o-2024-08-06"} }, logger)
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	logCtx := &RequestLogContext{}
+
+	prepared, ok := prx.orchestrateRequest(w, req, logCtx)
+	require.True(t, ok, w.Body.String())
+	require.Equal(t, "gpt-4o-2024-08-06", logCtx.ModelID)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(prepared.body, &raw))
+	require.Equal(t, "gpt-4o-2024-08-06", raw["model"], "the pinned snapshot is sent upstream")
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError wraps a panic recovered inside the proxy together with the request context
+// known at that point. It is re-panicked so the router's recovery middleware can write
+// the 500 response and log the stack with request_id/credential/model.
+type PanicError struct {
+	Value      interface{}
+	Stack      []byte
+	RequestID  string
+	Credential string
+	Model      string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// newPanicError captures the stack of a recovered panic and marks logCtx as failed,
+// so the deferred spend log entry is written with status=failure
+func newPanicError(rec interface{}, logCtx *RequestLogContext) *PanicError {
+	pe := &PanicError{
+		Value:     rec,
+		Stack:     debug.Stack(),
+		RequestID: logCtx.RequestID,
+		Model:     logCtx.ModelID,
+	}
+	if logCtx.Credential != nil {
+		pe.Credential = logCtx.Credential.Name
+	}
+
+	logCtx.Status = "failure"
+	logCtx.HTTPStatus = http.StatusInternalServerError
+	logCtx.ErrorMsg = pe.Error()
+	return pe
+}
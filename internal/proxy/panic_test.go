@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSpendDB captures spend log entries
+type recordingSpendDB struct {
+	*litellmdb.NoopManager
+	mu      sync.Mutex
+	entries []*litellmdb.SpendLogEntry
+	ctxErrs []error // Error of the context of each LogSpend call
+}
+
+func (m *recordingSpendDB) IsEnabled() bool { return true }
+
+func (m *recordingSpendDB) LogSpend(ctx context.Context, entry *litellmdb.SpendLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry)
+	m.ctxErrs = append(m.ctxErrs, ctx.Err())
+	return nil
+}
+
+func TestProxyRequest_PanicLogsSpendAndRepanicsWithContext(t *testing.T) {
+	prx := NewTestProxyBuilder().WithCredentials(
+		config.CredentialConfig{Name: "vertex_panic", Type: config.ProviderTypeVertexAI, ProjectID: "p", Location: "us-central1", RPM: 100, TPM: 10000},
+	).Build()
+	db := &recordingSpendDB{NoopManager: litellmdb.NewNoopManager()}
+	prx.LiteLLMDB = db
+	prx.spendSinksEnabled = true
+	prx.tokenManager = nil // GetToken on a nil manager panics after credential selection
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"gemini-2.5-flash","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer sk-master")
+	w := httptest.NewRecorder()
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		prx.ProxyRequest(w, req)
+	}()
+
+	pe, ok := recovered.(*PanicError)
+	require.True(t, ok, "expected *PanicError, got %T", recovered)
+	assert.NotEmpty(t, pe.RequestID)
+	assert.Equal(t, "vertex_panic", pe.Credential)
+	assert.Equal(t, "gemini-2.5-flash", pe.Model)
+	assert.NotEmpty(t, pe.Stack)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	require.Len(t, db.entries, 1)
+	assert.Equal(t, "failure", db.entries[0].Status)
+	assert.Equal(t, pe.RequestID, db.entries[0].RequestID)
+}
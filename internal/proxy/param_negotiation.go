@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// unsupportedParamMessage matches provider error messages rejecting a request parameter as
+// unsupported. A merely invalid value (e.g. "Invalid parameter: max_tokens is too large")
+// does not match: dropping the parameter would change the request instead of fixing it.
+var unsupportedParamMessage = regexp.MustCompile(`(?i)unsupported|not supported|does not support|unrecognized|unknown (?:name|field|parameter)|extra inputs|not permitted`)
+
+// messageWords splits an error message into the words parameter names are matched against
+var messageWords = regexp.MustCompile(`\w+`)
+
+// nonNegotiableParams are never learned as unsupported: without them the request is
+// meaningless, or streaming is emulated instead (see streamUnsupported)
+var nonNegotiableParams = map[string]bool{
+	"model": true, "messages": true, "input": true, "prompt": true, "stream": true, "stream_options": true,
+}
+
+// paramNegotiator remembers request parameters credentials rejected for a model
+// (param_negotiation). A nil *paramNegotiator is valid and learns nothing.
+type paramNegotiator struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	learned map[paramNegotiationKey]map[string]time.Time // Param -> expiry
+}
+
+type paramNegotiationKey struct {
+	credential string
+	model      string
+}
+
+func newParamNegotiator(ttl time.Duration) *paramNegotiator {
+	return &paramNegotiator{ttl: ttl, now: utils.NowUTC, learned: make(map[paramNegotiationKey]map[string]time.Time)}
+}
+
+// unsupported returns the learned, unexpired parameters of credential and model, sorted; with
+// requested, only those set in the request
+func (n *paramNegotiator) unsupported(credential, model string, requested map[string]bool) []string {
+	if n == nil {
+		return nil
+	}
+	key := paramNegotiationKey{credential: credential, model: model}
+	now := n.now()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var params []string
+	for param, expiry := range n.learned[key] {
+		if !now.Before(expiry) {
+			delete(n.learned[key], param)
+			continue
+		}
+		if requested == nil || requested[param] {
+			params = append(params, param)
+		}
+	}
+	if len(n.learned[key]) == 0 {
+		delete(n.learned, key)
+	}
+	slices.Sort(params)
+	return params
+}
+
+// learn records params as unsupported by credential for model and returns the ones not
+// already known; known ones keep their expiry
+func (n *paramNegotiator) learn(credential, model string, params []string) []string {
+	if n == nil || len(params) == 0 {
+		return nil
+	}
+	key := paramNegotiationKey{credential: credential, model: model}
+	now := n.now()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	learned, ok := n.learned[key]
+	if !ok {
+		learned = make(map[string]time.Time, len(params))
+		n.learned[key] = learned
+	}
+	var added []string
+	for _, param := range params {
+		if expiry, known := learned[param]; known && now.Before(expiry) {
+			continue
+		}
+		learned[param] = now.Add(n.ttl)
+		added = append(added, param)
+	}
+	return added
+}
+
+// rejectedParams returns the requested parameters a 400 response body rejects, sorted: the
+// error's param, or else the requested parameters named in its message, when the message
+// says a parameter is unsupported
+func rejectedParams(body []byte, requested map[string]bool) []string {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+			Param   string `json:"param"`
+			Code    string `json:"code"`
+		} `json:"error"`
+		Message string `json:"message"`
+	}
+	message := string(body)
+	param := ""
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if parsed.Error.Message != "" {
+			message = parsed.Error.Message + " " + parsed.Error.Code
+			param = parsed.Error.Param
+		} else if parsed.Message != "" {
+			message = parsed.Message
+		}
+	}
+	if !unsupportedParamMessage.MatchString(message) {
+		return nil
+	}
+	if requested[param] && !nonNegotiableParams[param] {
+		return []string{param}
+	}
+
+	var params []string
+	for _, word := range messageWords.FindAllString(message, -1) {
+		if requested[word] && !nonNegotiableParams[word] && !slices.Contains(params, word) {
+			params = append(params, word)
+		}
+	}
+	slices.Sort(params)
+	return params
+}
+
+// learnRejectedParams records the parameters a 400 response of credential rejected for model
+func (p *Proxy) learnRejectedParams(credential, model string, body []byte, requested map[string]bool, logCtx *RequestLogContext) {
+	learned := p.paramNegotiator.learn(credential, model, rejectedParams(body, requested))
+	for _, param := range learned {
+		monitoring.UnsupportedParamsLearnedTotal.WithLabelValues(credential, model, param).Inc()
+	}
+	if len(learned) > 0 {
+		logCtx.CredentialLogger(credential).Warn("Learned params unsupported by credential, dropping them from later requests",
+			"model", model, "params", learned, "ttl", p.paramNegotiator.ttl)
+	}
+}
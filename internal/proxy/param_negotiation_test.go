@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectedParams(t *testing.T) {
+	requested := map[string]bool{"model": true, "messages": true, "temperature": true, "top_k": true, "max_tokens": true, "stream": true}
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			"openai param field",
+			`{"error":{"message":"Unsupported value: 'temperature' does not support 0.2 with this model.","type":"invalid_request_error","param":"temperature","code":"unsupported_value"}}`,
+			[]string{"temperature"},
+		},
+		{"message names the param", `{"error":{"message":"top_k: Extra inputs are not permitted","type":"invalid_request_error"}}`, []string{"top_k"}},
+		{"top-level message", `{"message":"Unrecognized request argument supplied: top_k"}`, []string{"top_k"}},
+		{"plain text", `unsupported parameter "temperature"`, []string{"temperature"}},
+		{"other 400", `{"error":{"message":"max_tokens is too large: 100000","param":"max_tokens"}}`, nil},
+		{"invalid value", `{"error":{"message":"Invalid parameter: temperature must be at most 2","param":"temperature"}}`, nil},
+		{"param named twice", `Unsupported parameter top_k: top_k is not supported by this model`, []string{"top_k"}},
+		{"param not requested", `{"error":{"message":"Unsupported parameter: 'seed'","param":"seed"}}`, nil},
+		{"non-negotiable param", `{"error":{"message":"stream is not supported with this model","param":"stream"}}`, nil},
+		{"not json nor param", `Bad Request`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rejectedParams([]byte(tt.body), requested))
+		})
+	}
+}
+
+func TestParamNegotiator(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	n := newParamNegotiator(time.Hour)
+	n.now = func() time.Time { return now }
+
+	assert.Equal(t, []string{"top_k", "temperature"}, n.learn("a", "gpt-4o", []string{"top_k", "temperature"}))
+	assert.Empty(t, n.learn("a", "gpt-4o", []string{"temperature"}), "already known")
+
+	assert.Equal(t, []string{"temperature", "top_k"}, n.unsupported("a", "gpt-4o", nil))
+	assert.Equal(t, []string{"temperature"}, n.unsupported("a", "gpt-4o", map[string]bool{"temperature": true}))
+	assert.Empty(t, n.unsupported("a", "gpt-4o-mini", nil), "learned per model")
+	assert.Empty(t, n.unsupported("b", "gpt-4o", nil), "learned per credential")
+
+	now = now.Add(time.Hour)
+	assert.Empty(t, n.unsupported("a", "gpt-4o", nil), "expired params are sent again")
+	assert.Equal(t, []string{"temperature"}, n.learn("a", "gpt-4o", []string{"temperature"}))
+
+	var disabled *paramNegotiator
+	assert.Nil(t, disabled.learn("a", "gpt-4o", []string{"temperature"}))
+	assert.Nil(t, disabled.unsupported("a", "gpt-4o", nil))
+}
+
+func TestProxyRequest_ParamNegotiation(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "temperature") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Unsupported parameter: 'temperature' is not supported with this model.","type":"invalid_request_error","param":"temperature","code":"unsupported_parameter"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("o1", config.ProviderTypeOpenAI, upstream.URL, "sk-test").
+		WithMasterKey("master-key").
+		Build()
+	prx.paramNegotiator = newParamNegotiator(time.Hour)
+	learned := testutil.ToFloat64(monitoring.UnsupportedParamsLearnedTotal.WithLabelValues("o1", "llama-3-70b", "temperature"))
+	dropped := testutil.ToFloat64(monitoring.NegotiatedParamsDroppedTotal.WithLabelValues("o1", "temperature"))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"llama-3-70b","temperature":0.2,"messages":[{"role":"user","content":"Hi"}]}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		return w
+	}
+
+	first := send()
+	assert.Equal(t, http.StatusBadRequest, first.Code)
+	assert.Equal(t, learned+1, testutil.ToFloat64(monitoring.UnsupportedParamsLearnedTotal.WithLabelValues("o1", "llama-3-70b", "temperature")))
+
+	second := send()
+	require.Equal(t, http.StatusOK, second.Code, second.Body.String())
+	assert.Equal(t, "temperature", second.Header().Get(DroppedParamsHeader))
+	assert.Equal(t, dropped+1, testutil.ToFloat64(monitoring.NegotiatedParamsDroppedTotal.WithLabelValues("o1", "temperature")))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	caps, ok := prx.ModelCapabilities("llama-3-70b", "", true)
+	require.True(t, ok)
+	assert.Contains(t, caps.UnsupportedParams, "temperature")
+}
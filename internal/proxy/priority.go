@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// priorityPolicy maps API keys to priority classes and classes to their share of
+// credential RPM/TPM (priority_classes)
+type priorityPolicy struct {
+	shares       map[string]float64 // Class -> share (0..1]
+	defaultClass string             // Class of keys without an assignment ("" = none)
+	assignments  map[string]string  // Key alias or team ID -> class
+}
+
+func newPriorityPolicy(cfg config.PriorityClassesConfig) *priorityPolicy {
+	return &priorityPolicy{
+		shares:       cfg.Classes,
+		defaultClass: cfg.DefaultClass,
+		assignments:  cfg.Assignments,
+	}
+}
+
+// classFor returns the class of the request's API key: its key alias assignment,
+// then its team assignment, then the default class
+func (pp *priorityPolicy) classFor(logCtx *RequestLogContext) string {
+	if info := logCtx.TokenInfo; info != nil {
+		for _, name := range []string{info.KeyAlias, info.TeamID} {
+			if class, ok := pp.assignments[name]; ok && name != "" {
+				return class
+			}
+		}
+	}
+	return pp.defaultClass
+}
+
+// classShare returns the share of credential RPM/TPM the request may use (1 = full limits)
+func (p *Proxy) classShare(logCtx *RequestLogContext) float64 {
+	if p.priority == nil || logCtx == nil || logCtx.PriorityClass == "" {
+		return 1
+	}
+	if share, ok := p.priority.shares[logCtx.PriorityClass]; ok {
+		return share
+	}
+	return 1
+}
+
+// recordPriorityResult counts a credential selection outcome of a classified request
+func recordPriorityResult(logCtx *RequestLogContext, result string) {
+	if logCtx.PriorityClass != "" {
+		monitoring.PriorityClassRequestsTotal.WithLabelValues(logCtx.PriorityClass, result).Inc()
+	}
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityPolicy_ClassFor(t *testing.T) {
+	pp := newPriorityPolicy(config.PriorityClassesConfig{
+		Classes:      map[string]float64{"gold": 1, "bronze": 0.5},
+		DefaultClass: "bronze",
+		Assignments:  map[string]string{"vip-key": "gold", "team-prod": "gold", "team-batch": "bronze"},
+	})
+
+	assert.Equal(t, "bronze", pp.classFor(&RequestLogContext{}), "no token info uses the default class")
+	assert.Equal(t, "gold", pp.classFor(&RequestLogContext{TokenInfo: &models.TokenInfo{KeyAlias: "vip-key", TeamID: "team-batch"}}), "key alias wins over team")
+	assert.Equal(t, "gold", pp.classFor(&RequestLogContext{TokenInfo: &models.TokenInfo{KeyAlias: "other", TeamID: "team-prod"}}))
+	assert.Equal(t, "bronze", pp.classFor(&RequestLogContext{TokenInfo: &models.TokenInfo{KeyAlias: "other"}}))
+}
+
+func TestOrchestrateRequest_PriorityClassShare(t *testing.T) {
+	prx := NewTestProxyBuilder().
+		WithCredentials(config.CredentialConfig{Name: "test", Type: config.ProviderTypeOpenAI, BaseURL: "http://test.local", APIKey: "upstream-key", RPM: 4}).
+		WithMasterKey("master-key").
+		Build()
+	prx.priority = newPriorityPolicy(config.PriorityClassesConfig{
+		Classes:      map[string]float64{"gold": 1, "bronze": 0.5},
+		DefaultClass: "bronze",
+	})
+	allowed := monitoring.PriorityClassRequestsTotal.WithLabelValues("bronze", "allowed")
+	rejected := monitoring.PriorityClassRequestsTotal.WithLabelValues("bronze", "rejected")
+	allowedBefore, rejectedBefore := testutil.ToFloat64(allowed), testutil.ToFloat64(rejected)
+
+	orchestrate := func() (*httptest.ResponseRecorder, *RequestLogContext, bool) {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		logCtx := &RequestLogContext{}
+		_, ok := prx.orchestrateRequest(w, req, logCtx)
+		return w, logCtx, ok
+	}
+
+	// Bronze may use half of the credential's 4 RPM
+	for i := 0; i < 2; i++ {
+		_, logCtx, ok := orchestrate()
+		require.True(t, ok)
+		assert.Equal(t, "bronze", logCtx.PriorityClass)
+	}
+	w, logCtx, ok := orchestrate()
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, logCtx.ErrorMsg, "priority class bronze")
+	assert.Equal(t, 2.0, testutil.ToFloat64(allowed)-allowedBefore)
+	assert.Equal(t, 1.0, testutil.ToFloat64(rejected)-rejectedBefore)
+
+	// The reserved capacity is still available to gold requests
+	prx.priority.defaultClass = "gold"
+	_, logCtx, ok = orchestrate()
+	assert.True(t, ok)
+	assert.Equal(t, "gold", logCtx.PriorityClass)
+}
+
+func TestClassShare(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	assert.Equal(t, 1.0, prx.classShare(&RequestLogContext{PriorityClass: "bronze"}), "disabled")
+
+	prx.priority = newPriorityPolicy(config.PriorityClassesConfig{Classes: map[string]float64{"bronze": 0.5}})
+	assert.Equal(t, 0.5, prx.classShare(&RequestLogContext{PriorityClass: "bronze"}))
+	assert.Equal(t, 1.0, prx.classShare(&RequestLogContext{}))
+	assert.Equal(t, 1.0, prx.classShare(nil))
+}
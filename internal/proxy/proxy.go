@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
@@ -14,22 +13,38 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mixaill76/auto_ai_router/internal/auth"
 	"github.com/mixaill76/auto_ai_router/internal/balancer"
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/contextwindow"
 	"github.com/mixaill76/auto_ai_router/internal/converter"
 	"github.com/mixaill76/auto_ai_router/internal/converter/responses"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/faultinject"
+	"github.com/mixaill76/auto_ai_router/internal/forecast"
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
 	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/imagefetch"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
 	"github.com/mixaill76/auto_ai_router/internal/logger"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/mixaill76/auto_ai_router/internal/mockprovider"
 	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/proxyhealth"
+	"github.com/mixaill76/auto_ai_router/internal/quota"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/sampling"
+	"github.com/mixaill76/auto_ai_router/internal/scheduler"
 	"github.com/mixaill76/auto_ai_router/internal/security"
+	"github.com/mixaill76/auto_ai_router/internal/spendreport"
+	"github.com/mixaill76/auto_ai_router/internal/spendstore"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
+	"github.com/mixaill76/auto_ai_router/internal/worker"
 )
 
 // DefaultResponseBodyMultiplier is the default multiplier for response body size limit
@@ -58,10 +73,19 @@ type RequestLogContext struct {
 	TargetURL            string                   // Target URL (for APIBase extraction)
 	TokenInfo            *litellmdb.TokenInfo     // User/team/org info
 	IsImageGeneration    bool                     // True if this is an image generation request
+	IsImageEdit          bool                     // True if this is an image edit or variation request
 	ImageCount           int                      // Number of images to generate (from 'n' param)
 	Logged               bool                     // True if already logged (prevents duplicate logging)
 	PromptTokensEstimate int                      // Estimated prompt tokens for streaming responses (since streaming doesn't provide prompt tokens in headers)
 	IsResponsesAPI       bool                     // True if this is a Responses API request (converted to Chat Completions)
+	CostMultiplier       float64                  // Cost multiplier applied to calculated cost (0 = 1, e.g. 0.5 for batch discount)
+	FallbackUsed         bool                     // True if a fallback proxy served the request (TryFallbackProxy)
+	FirstByteTime        time.Time                // Time the first response body bytes were written (time to first token for streaming)
+	RetryCount           int                      // Number of retries with other credentials (same-type retries and fallback attempts)
+	PriorityClass        string                   // Priority class of the API key ("" = full credential limits)
+	Tenant               string                   // Tenant of the request ("" = none, the credentials of no tenant)
+
+	baseLogger *slog.Logger // Logger the correlation fields of Logger are added to (nil = slog.Default())
 }
 
 // HealthChecker provides cached database health status
@@ -81,15 +105,66 @@ type Config struct {
 	IdleConnTimeout        time.Duration
 	Metrics                *monitoring.Metrics
 	MasterKey              string
+	MasterKeys             *masterkey.Ring // Optional: valid master keys for key rotation (default: MasterKey only)
 	RateLimiter            *ratelimit.RPMLimiter
 	TokenManager           *auth.VertexTokenManager
 	ModelManager           *models.Manager
 	Version                string
 	Commit                 string
-	LiteLLMDB              litellmdb.Manager          // LiteLLM database integration (optional)
-	HealthChecker          HealthChecker              // Optional: cached DB health status (updated by health monitor)
-	PriceRegistry          *models.ModelPriceRegistry // Model pricing information (optional)
-	MaxProviderRetries     int                        // Max same-type credential retries (default: 2)
+	LiteLLMDB              litellmdb.Manager                         // LiteLLM database integration (optional)
+	HealthChecker          HealthChecker                             // Optional: cached DB health status (updated by health monitor)
+	ProxyHealth            *proxyhealth.Tracker                      // Optional: /health reports of downstream routers (shown on the health page)
+	PriceRegistry          *models.ModelPriceRegistry                // Model pricing information (optional)
+	MaxProviderRetries     int                                       // Max same-type credential retries (default: 2)
+	SpendPusher            *monitoring.SpendPusher                   // Optional: mirrors spend events to a Pushgateway
+	SLOTracker             *monitoring.SLOTracker                    // Optional: availability/latency SLO burn rates (monitoring.slo)
+	UsageEstimator         *forecast.Estimator                       // Optional: forecasts credential quota exhaustion
+	Workers                *worker.Supervisor                        // Optional: background workers reported in /health
+	History                *healthhistory.Recorder                   // Optional: per-credential RPM/TPM/error rate/spend histories (/vhealth, /health/history)
+	SpendReporter          *spendreport.Reporter                     // Optional: aggregates spend for the daily spend report
+	SpendStore             *spendstore.Store                         // Optional: local spend log used while LiteLLM DB is disabled
+	QuotaBoosts            *quota.Store                              // Optional: temporary key/team boosts; enables key/team rpm_limit enforcement
+	InterRouterSecret      string                                    // Optional: accept HMAC-signed requests from parent routers
+	Scheduler              *scheduler.FairScheduler                  // Optional: per-key fair admission when at capacity
+	ImageFetcher           *imagefetch.Fetcher                       // Optional: inline remote image URLs for non-OpenAI providers
+	ContextManager         *contextwindow.Manager                    // Optional: truncate or summarize conversations exceeding the prompt budget
+	PostProcess            converter.PostProcessOptions              // Normalizations of converted responses (response_postprocessing)
+	ConversionCheck        config.ConversionCheckConfig              // Schema checks of converted requests (conversion_validation)
+	AttributionHeaders     bool                                      // Send X-Router-* attribution headers to the master key and AttributionKeys
+	AttributionKeys        []string                                  // Key aliases or team IDs receiving attribution headers ("*" = all keys)
+	PriorityClasses        config.PriorityClassesConfig              // Per-class shares of credential RPM/TPM (priority_classes)
+	Tenants                []config.TenantConfig                     // Isolated credential pools, keys and rate limits (tenants)
+	StreamCoalescing       config.StreamCoalescingConfig             // Merge streamed content deltas within a short window (stream_coalescing)
+	NonStreaming           bool                                      // Answer streaming requests of NonStreamingKeys with a single JSON response
+	NonStreamingKeys       []string                                  // Key aliases or team IDs whose streams are assembled ("*" = all keys)
+	LiteLLMHeaders         bool                                      // Send x-litellm-* model id, response cost and key spend headers
+	RoutingOverrides       bool                                      // Accept X-Router-* routing override headers from the master key and RoutingOverrideKeys
+	RoutingOverrideKeys    []string                                  // Key aliases or team IDs allowed to override routing ("*" = all keys)
+	MaxCostPerRequest      config.MaxCostPerRequestConfig            // Per-key ceilings of the worst-case request cost (max_cost_per_request)
+	ModelPins              *models.PinRegistry                       // Optional: resolve rolling model aliases to pinned snapshots
+	RequestSampler         *sampling.Sampler                         // Optional: requests logged in full (monitoring.request_sampling)
+	ReasoningRouting       config.ReasoningRoutingConfig             // Model groups routed by reasoning_effort (reasoning_routing)
+	ReasoningParams        config.ReasoningParamsConfig              // Per-family handling of reasoning model params (reasoning_params)
+	ResponseHeaders        config.ResponseHeadersConfig              // Limits and stripped names of upstream response headers returned to clients
+	UpstreamErrors         config.UpstreamErrorsConfig               // Wrap upstream error bodies into the error envelope (upstream_errors)
+	SystemPrompts          config.SystemPromptsConfig                // Policy system prompts per key, team and model (system_prompts)
+	Chargeback             config.ChargebackConfig                   // Chargeback markup and fees per key, team and credential (chargeback)
+	ParamNegotiation       config.ParamNegotiationConfig             // Learn and drop params credentials reject with 400 (param_negotiation)
+	ChoiceFanOut           config.ChoiceFanOutConfig                 // Fan out n > 1 to providers returning one choice per request (n_fanout)
+	UnsupportedParams      string                                    // Handling of params a credential cannot honour: "drop" (default) or "reroute"
+	DeterministicRouting   bool                                      // Seed credential selection with the request body hash
+	HideUnavailableModels  bool                                      // Leave models whose credentials are all banned out of GET /v1/models
+	SkipZeroCostLogs       bool                                      // Do not write spend logs with cost 0 for models that lost their price
+	StreamFirstByteTimeout time.Duration                             // Retry streaming requests whose upstream sends no byte in time (0 = disabled)
+	FallbackTimeout        time.Duration                             // Timeout of each retry and fallback attempt (0 = RequestTimeout)
+	FallbackBodyMultiplier int                                       // Response body size limit of retry and fallback attempts (0 = ResponseBodyMultiplier)
+	RequestDeadline        time.Duration                             // Total time of all attempts of a request, retries and fallbacks included (0 = none)
+	UpstreamCompression    bool                                      // Ask upstreams for gzip, deflate, br and zstd responses
+	BatchStateFile         string                                    // File keeping Anthropic batch records across restarts ("" = not persisted)
+	ReadOnly               bool                                      // Start in read-only mode: no LiteLLM DB spend writes, no admin mutations
+	DNSCache               *httputil.DNSCache                        // Optional: cache upstream DNS results (dns_cache)
+	FaultRules             map[string]faultinject.Rule               // Optional (dev mode): simulated upstream failures by credential
+	WrapTransport          func(http.RoundTripper) http.RoundTripper // Optional (dev mode): wraps the upstream transport (cassette record/replay)
 }
 
 type Proxy struct {
@@ -98,17 +173,63 @@ type Proxy struct {
 	logger              *slog.Logger
 	maxBodySizeMB       int
 	maxResponseBodySize int64 // Pre-computed max response body size in bytes
+	maxFallbackBodySize int64 // Max response body size of retry and fallback attempts in bytes
 	requestTimeout      time.Duration
 	metrics             *monitoring.Metrics
-	masterKey           string
+	masterKeys          *masterkey.Ring
 	rateLimiter         *ratelimit.RPMLimiter
 	tokenManager        *auth.VertexTokenManager
-	healthTemplate      *template.Template         // Cached template
-	modelManager        *models.Manager            // Model manager for getting configured models
-	LiteLLMDB           litellmdb.Manager          // LiteLLM database integration
-	healthChecker       HealthChecker              // Cached DB health status (optional)
-	priceRegistry       *models.ModelPriceRegistry // Model pricing information (optional)
-	maxProviderRetries  int                        // Max same-type credential retries on provider errors
+	healthTemplate      *template.Template            // Cached template
+	modelManager        *models.Manager               // Model manager for getting configured models
+	LiteLLMDB           litellmdb.Manager             // LiteLLM database integration
+	healthChecker       HealthChecker                 // Cached DB health status (optional)
+	proxyHealth         *proxyhealth.Tracker          // Downstream router health reports (optional)
+	priceRegistry       *models.ModelPriceRegistry    // Model pricing information (optional)
+	maxProviderRetries  int                           // Max same-type credential retries on provider errors
+	spendPusher         *monitoring.SpendPusher       // Spend events mirror (nil if disabled)
+	sloTracker          *monitoring.SLOTracker        // SLO burn rates (nil if disabled)
+	usageEstimator      *forecast.Estimator           // Quota exhaustion forecasts (nil if disabled)
+	workers             *worker.Supervisor            // Supervised background workers (nil = not reported)
+	history             *healthhistory.Recorder       // Per-credential usage histories (nil if disabled)
+	spendReporter       *spendreport.Reporter         // Daily spend report aggregates (nil if disabled)
+	spendStore          *spendstore.Store             // Local spend log (nil if disabled)
+	spendSinksEnabled   bool                          // Any of the above (or LiteLLM DB, metrics, pools) records spend
+	quotaBoosts         *quota.Store                  // Temporary key/team boosts (nil if disabled)
+	batches             *batchAffinityStore           // Anthropic batch ID -> credential affinity
+	routerVerifier      *httputil.RequestVerifier     // Inter-router signature verifier (nil if disabled)
+	scheduler           *scheduler.FairScheduler      // Per-key fair admission (nil if disabled)
+	imageFetcher        *imagefetch.Fetcher           // Remote image inlining (nil if disabled)
+	contextManager      *contextwindow.Manager        // Conversation truncation/summarization (nil if disabled)
+	postProcess         converter.PostProcessOptions  // Normalizations of converted responses
+	conversionCheck     config.ConversionCheckConfig  // Schema checks of converted requests
+	attribution         *attributionPolicy            // X-Router-* attribution headers (nil if disabled)
+	priority            *priorityPolicy               // Priority classes (nil if disabled)
+	tenants             *tenantPolicy                 // Tenants (nil if none)
+	streamCoalescing    config.StreamCoalescingConfig // Stream chunk coalescing window
+	nonStreaming        *nonStreamingPolicy           // Keys whose streams are assembled into JSON (nil if disabled)
+	litellmHeaders      *litellmHeaders               // x-litellm-* response headers (nil if disabled)
+	routingOverrides    *routingOverridePolicy        // Keys allowed to send routing override headers (nil if disabled)
+	costCeiling         *costCeilingPolicy            // Per-key worst-case request cost ceilings (nil if disabled)
+	modelPins           *models.PinRegistry           // Rolling alias -> snapshot pins (nil if disabled)
+	requestSampler      *sampling.Sampler             // Requests logged in full (nil = none)
+	reasoningRouter     *reasoningRouter              // Model group -> reasoning_effort tiers (nil if disabled)
+	reasoningParams     *reasoningParams              // Configured reasoning model param handling (nil = built-in rules)
+	responseHeaders     *responseHeaderPolicy         // Upstream response headers returned to clients
+	upstreamErrors      config.UpstreamErrorsConfig   // Wrapping of upstream error bodies
+	systemPrompts       *systemPromptPolicy           // Policy system prompts (nil if disabled)
+	chargeback          *chargebackPolicy             // Chargeback rates of spend logs (nil if disabled)
+	paramNegotiator     *paramNegotiator              // Learned unsupported params (nil if disabled)
+	maxFanOutChoices    int                           // Largest n fanned out (0 = n_fanout disabled)
+	unsupportedParams   string                        // "drop" or "reroute" (server.unsupported_params)
+	deterministicRoute  bool                          // Seed credential selection with the request body hash
+	hideUnavailable     bool                          // Leave models whose credentials are all banned out of GET /v1/models
+	skipZeroCostLogs    bool                          // Do not write spend logs with cost 0 for models that lost their price
+	firstByteTimeout    time.Duration                 // Deadline for the first byte of streaming upstream calls (0 = none)
+	fallbackTimeout     time.Duration                 // Timeout of each retry and fallback attempt (0 = request timeout)
+	requestDeadline     time.Duration                 // Total time of all attempts of a request (0 = none)
+	upstreamCompression bool                          // Advertise br and zstd besides gzip and deflate to upstreams
+	readOnly            atomic.Bool                   // No LiteLLM DB spend writes and admin mutations
+	inFlight            *inFlightRegistry             // Requests being served, for /admin/requests
 }
 
 var (
@@ -134,6 +255,12 @@ func New(cfg *Config) *Proxy {
 		"commit": func() string {
 			return cfg.Commit
 		},
+		"sparkline": sparklinePoints,
+		"peak":      peakValue,
+		"total":     totalValue,
+		"percent": func(v float64) float64 {
+			return v * 100
+		},
 	}).Parse(healthHTML)
 	if err != nil {
 		cfg.Logger.Error("Failed to parse health template at startup", "error", err)
@@ -153,30 +280,237 @@ func New(cfg *Config) *Proxy {
 		multiplier = DefaultResponseBodyMultiplier
 	}
 	maxResponseBodySize := int64(cfg.MaxBodySizeMB) * int64(multiplier) * 1024 * 1024
+	fallbackMultiplier := cfg.FallbackBodyMultiplier
+	if fallbackMultiplier <= 0 {
+		fallbackMultiplier = multiplier
+	}
+	maxFallbackBodySize := int64(cfg.MaxBodySizeMB) * int64(fallbackMultiplier) * 1024 * 1024
+
+	var routerVerifier *httputil.RequestVerifier
+	if cfg.InterRouterSecret != "" {
+		routerVerifier = httputil.NewRequestVerifier(cfg.InterRouterSecret, httputil.DefaultRouterSignatureMaxAge)
+	}
+
+	masterKeys := cfg.MasterKeys
+	if masterKeys == nil {
+		masterKeys = masterkey.NewRing(cfg.MasterKey, nil, config.DefaultMasterKeyGracePeriod)
+	}
+
+	var attribution *attributionPolicy
+	if cfg.AttributionHeaders {
+		attribution = newAttributionPolicy(masterKeys, cfg.AttributionKeys)
+	}
+
+	var nonStreaming *nonStreamingPolicy
+	if cfg.NonStreaming {
+		nonStreaming = newNonStreamingPolicy(cfg.NonStreamingKeys)
+	}
+
+	var routingOverrides *routingOverridePolicy
+	if cfg.RoutingOverrides {
+		routingOverrides = newRoutingOverridePolicy(masterKeys, cfg.RoutingOverrideKeys)
+	}
+
+	var costCeiling *costCeilingPolicy
+	if cfg.MaxCostPerRequest.Enabled {
+		costCeiling = newCostCeilingPolicy(cfg.MaxCostPerRequest)
+	}
+	var systemPrompts *systemPromptPolicy
+	if cfg.SystemPrompts.Enabled {
+		systemPrompts = newSystemPromptPolicy(cfg.SystemPrompts)
+	}
+	var chargeback *chargebackPolicy
+	if cfg.Chargeback.Enabled {
+		chargeback = newChargebackPolicy(cfg.Chargeback)
+	}
+	var negotiator *paramNegotiator
+	if cfg.ParamNegotiation.Enabled {
+		negotiator = newParamNegotiator(cfg.ParamNegotiation.TTL)
+	}
+	maxFanOutChoices := 0
+	if cfg.ChoiceFanOut.Enabled {
+		maxFanOutChoices = cfg.ChoiceFanOut.MaxN
+	}
+	var reasoning *reasoningRouter
+	if cfg.ReasoningRouting.Enabled {
+		reasoning = newReasoningRouter(cfg.ReasoningRouting)
+	}
 
-	return &Proxy{
+	var litellm *litellmHeaders
+	if cfg.LiteLLMHeaders {
+		litellm = newLiteLLMHeaders(cfg.PriceRegistry)
+	}
+
+	var tenants *tenantPolicy
+	if len(cfg.Tenants) > 0 {
+		tenants = newTenantPolicy(cfg.Tenants)
+	}
+	var priority *priorityPolicy
+	if cfg.PriorityClasses.Enabled {
+		priority = newPriorityPolicy(cfg.PriorityClasses)
+	}
+
+	// Spend of finished requests is only computed when something consumes it
+	spendSinksEnabled := (cfg.LiteLLMDB != nil && cfg.LiteLLMDB.IsEnabled()) || cfg.SpendPusher.IsEnabled() ||
+		cfg.Metrics.IsEnabled() || cfg.SpendReporter.IsEnabled() || cfg.SpendStore != nil || cfg.History != nil ||
+		cfg.UsageEstimator != nil || cfg.RateLimiter.HasPools()
+
+	client := httputil.NewHTTPClient(httpClientCfg)
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		client.Transport = httputil.NewUpstreamTransport(transport, httputil.UpstreamTransportOptions{
+			Metrics:  cfg.Metrics.IsEnabled(),
+			DNSCache: cfg.DNSCache,
+		})
+	}
+	if cfg.WrapTransport != nil {
+		client.Transport = cfg.WrapTransport(client.Transport)
+	}
+	// Mock credentials are answered in-process; faults wrap them so they can be simulated offline
+	client.Transport = mockprovider.NewTransport(client.Transport)
+	if len(cfg.FaultRules) > 0 {
+		client.Transport = faultinject.NewTransport(client.Transport, cfg.FaultRules)
+	}
+
+	p := &Proxy{
 		balancer:            cfg.Balancer,
 		logger:              cfg.Logger,
 		maxBodySizeMB:       cfg.MaxBodySizeMB,
 		maxResponseBodySize: maxResponseBodySize,
+		maxFallbackBodySize: maxFallbackBodySize,
 		requestTimeout:      cfg.RequestTimeout,
 		metrics:             cfg.Metrics,
-		masterKey:           cfg.MasterKey,
+		masterKeys:          masterKeys,
 		rateLimiter:         cfg.RateLimiter,
 		tokenManager:        cfg.TokenManager,
 		healthTemplate:      tmpl,
 		modelManager:        cfg.ModelManager,
 		LiteLLMDB:           cfg.LiteLLMDB,
 		healthChecker:       cfg.HealthChecker,
+		proxyHealth:         cfg.ProxyHealth,
 		priceRegistry:       cfg.PriceRegistry,
 		maxProviderRetries:  cfg.MaxProviderRetries,
-		client:              httputil.NewHTTPClient(httpClientCfg),
+		spendPusher:         cfg.SpendPusher,
+		sloTracker:          cfg.SLOTracker,
+		usageEstimator:      cfg.UsageEstimator,
+		workers:             cfg.Workers,
+		history:             cfg.History,
+		spendReporter:       cfg.SpendReporter,
+		spendStore:          cfg.SpendStore,
+		spendSinksEnabled:   spendSinksEnabled,
+		quotaBoosts:         cfg.QuotaBoosts,
+		batches:             newBatchAffinityStore(cfg.BatchStateFile, cfg.Logger),
+		routerVerifier:      routerVerifier,
+		scheduler:           cfg.Scheduler,
+		inFlight:            newInFlightRegistry(),
+		imageFetcher:        cfg.ImageFetcher,
+		contextManager:      cfg.ContextManager,
+		postProcess:         cfg.PostProcess,
+		conversionCheck:     cfg.ConversionCheck,
+		attribution:         attribution,
+		priority:            priority,
+		tenants:             tenants,
+		streamCoalescing:    cfg.StreamCoalescing,
+		nonStreaming:        nonStreaming,
+		litellmHeaders:      litellm,
+		routingOverrides:    routingOverrides,
+		costCeiling:         costCeiling,
+		modelPins:           cfg.ModelPins,
+		requestSampler:      cfg.RequestSampler,
+		reasoningRouter:     reasoning,
+		reasoningParams:     newReasoningParams(cfg.ReasoningParams),
+		responseHeaders:     newResponseHeaderPolicy(cfg.ResponseHeaders),
+		upstreamErrors:      cfg.UpstreamErrors,
+		systemPrompts:       systemPrompts,
+		chargeback:          chargeback,
+		paramNegotiator:     negotiator,
+		maxFanOutChoices:    maxFanOutChoices,
+		unsupportedParams:   cfg.UnsupportedParams,
+		deterministicRoute:  cfg.DeterministicRouting,
+		hideUnavailable:     cfg.HideUnavailableModels,
+		skipZeroCostLogs:    cfg.SkipZeroCostLogs,
+		firstByteTimeout:    cfg.StreamFirstByteTimeout,
+		fallbackTimeout:     cfg.FallbackTimeout,
+		requestDeadline:     cfg.RequestDeadline,
+		upstreamCompression: cfg.UpstreamCompression,
+		client:              client,
 	}
+	p.SetReadOnly(cfg.ReadOnly)
+	return p
 }
 
-// GetMasterKey returns the proxy master key.
+// GetMasterKey returns the primary master key.
 func (p *Proxy) GetMasterKey() string {
-	return p.masterKey
+	return p.masterKeys.Primary()
+}
+
+// ModelPins returns the model snapshot pins (nil if disabled)
+func (p *Proxy) ModelPins() *models.PinRegistry {
+	return p.modelPins
+}
+
+// RequestSampler returns the request log sampler (nil if not configured)
+func (p *Proxy) RequestSampler() *sampling.Sampler {
+	return p.requestSampler
+}
+
+// MasterKeys returns the valid master keys
+func (p *Proxy) MasterKeys() *masterkey.Ring {
+	return p.masterKeys
+}
+
+// Fail2Ban returns the credential+model ban tracker
+func (p *Proxy) Fail2Ban() *fail2ban.Fail2Ban {
+	return p.balancer.Fail2Ban()
+}
+
+// HasCredential reports whether a credential with the given name is configured
+func (p *Proxy) HasCredential(name string) bool {
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if cred.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetReadOnly switches read-only mode: traffic is still served and API keys are still
+// validated against LiteLLM DB, but spend logs are not written (queued entries are held, no DLQ batches recovered or
+// spend aggregated) and admin mutations are refused
+func (p *Proxy) SetReadOnly(readOnly bool) {
+	p.readOnly.Store(readOnly)
+	if p.LiteLLMDB != nil {
+		p.LiteLLMDB.SetReadOnly(readOnly)
+	}
+	if readOnly {
+		monitoring.ReadOnlyMode.Set(1)
+	} else {
+		monitoring.ReadOnlyMode.Set(0)
+	}
+}
+
+// ReadOnly reports whether the router is in read-only mode
+func (p *Proxy) ReadOnly() bool {
+	return p.readOnly.Load()
+}
+
+// SpendStore returns the local spend log (nil if disabled)
+func (p *Proxy) SpendStore() *spendstore.Store {
+	return p.spendStore
+}
+
+// doUpstream sends a request to cred's upstream, tagging it with the credential
+// so fault injection rules and mock credentials can match it
+func (p *Proxy) doUpstream(req *http.Request, cred *config.CredentialConfig) (*http.Response, error) {
+	p.setUpstreamCredential(req.Context(), cred.Name)
+	if p.upstreamCompression && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", upstreamAcceptEncoding)
+	}
+	resp, err := p.client.Do(req.WithContext(httputil.WithCredential(req.Context(), cred)))
+	if err != nil && requestCancelled(req.Context()) {
+		return nil, ErrRequestCancelled
+	}
+	decodeUpstreamResponse(resp)
+	return resp, err
 }
 
 // ProxyResponse holds response details from a proxy credential
@@ -197,6 +531,13 @@ func (p *Proxy) executeProxyRequest(
 	body []byte,
 	start time.Time,
 ) (*ProxyResponse, error) {
+	// Send the downstream router its own name of a renamed model (proxy_models.rename)
+	if upstream := cred.ProxyModels.Upstream(modelID); upstream != modelID {
+		body = replaceRequestModel(r, body, modelID, upstream)
+	}
+
+	log := p.credentialLogger(r, cred.Name)
+
 	// Build target URL
 	proxyBaseURL := strings.TrimSuffix(cred.BaseURL, "/")
 	targetURL := proxyBaseURL + r.URL.Path
@@ -205,29 +546,50 @@ func (p *Proxy) executeProxyRequest(
 	}
 
 	// Create proxy request
-	proxyReq, err := http.NewRequest(r.Method, targetURL, bytes.NewReader(body))
+	bodyLimit := p.responseBodyLimit(r)
+	attemptCtx, attemptEnd := withAttemptDeadline(upstreamContext(r), p.nextAttemptDeadline(r))
+	attemptCtx, firstByte := withFirstByteDeadline(attemptCtx, p.streamFirstByteTimeout(r))
+	proxyReq, err := http.NewRequestWithContext(attemptCtx, r.Method, targetURL, bytes.NewReader(body))
 	if err != nil {
-		p.logger.Error("Failed to create proxy request", "error", err, "url", targetURL)
+		firstByte.release()
+		attemptEnd.release()
+		log.Error("Failed to create proxy request", "error", err, "url", targetURL)
 		return nil, err
 	}
 
-	// Copy headers (skip hop-by-hop headers)
-	copyRequestHeaders(proxyReq, r, cred.APIKey)
+	// Copy headers (skip hop-by-hop headers).
+	// With hmac_secret no key is forwarded: the request is signed for the downstream router instead.
+	if cred.HMACSecret != "" {
+		copyHeadersSkipAuth(proxyReq, r)
+		if err := httputil.SignRequest(proxyReq, cred.HMACSecret, body, utils.NowUTC()); err != nil {
+			firstByte.release()
+			attemptEnd.release()
+			log.Error("Failed to sign proxy request", "error", err)
+			return nil, err
+		}
+	} else {
+		copyRequestHeaders(proxyReq, r, cred.APIKey)
+	}
 
 	// Send request
-	resp, err := p.client.Do(proxyReq)
+	resp, err := attemptEnd.finish(firstByte.finish(p.doUpstream(proxyReq, cred)))
+	if errors.Is(err, ErrRequestCancelled) {
+		return nil, err
+	}
+	if errors.Is(err, ErrFirstByteTimeout) {
+		p.recordFirstByteTimeout(r, cred, modelID, start)
+		return nil, err
+	}
 	if err != nil {
 		statusCode := http.StatusBadGateway
 		if isTimeoutError(err) {
 			statusCode = http.StatusRequestTimeout
-			p.logger.Error("Proxy request timeout",
-				"credential", cred.Name,
+			log.Error("Proxy request timeout",
 				"error", err,
 				"url", targetURL,
 			)
 		} else {
-			p.logger.Error("Failed to proxy request",
-				"credential", cred.Name,
+			log.Error("Failed to proxy request",
 				"error", err,
 				"url", targetURL,
 			)
@@ -240,8 +602,7 @@ func (p *Proxy) executeProxyRequest(
 	p.balancer.RecordResponse(cred.Name, modelID, resp.StatusCode)
 	p.metrics.RecordRequest(cred.Name, r.URL.Path, resp.StatusCode, time.Since(start))
 
-	p.logger.Debug("Proxy request forwarded",
-		"credential", cred.Name,
+	log.Debug("Proxy request forwarded",
 		"target_url", targetURL,
 		"status_code", resp.StatusCode,
 		"duration", time.Since(start),
@@ -249,24 +610,31 @@ func (p *Proxy) executeProxyRequest(
 
 	// For streaming responses, return body reader directly to avoid buffering entire stream.
 	if IsStreamingResponse(resp) {
+		streamBody := resp.Body
+		if cred.NormalizeStream && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			streamBody = newStreamNormalizer(resp.Body, body)
+		}
 		return &ProxyResponse{
 			StatusCode:  resp.StatusCode,
 			Headers:     resp.Header,
-			StreamBody:  resp.Body,
+			StreamBody:  streamBody,
 			IsStreaming: true,
 		}, nil
 	}
 
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
-			p.logger.Error("Failed to close proxy response body", "error", closeErr)
+			log.Error("Failed to close proxy response body", "error", closeErr)
 		}
 	}()
 
 	// Read response body with size limit protection
-	respBody, err := p.readLimitedResponseBody(resp.Body)
+	respBody, err := p.readLimitedResponseBody(resp.Body, bodyLimit, log)
 	if err != nil {
-		p.logger.Error("Failed to read proxy response body", "error", err)
+		if requestCancelled(r.Context()) {
+			return nil, ErrRequestCancelled
+		}
+		log.Error("Failed to read proxy response body", "error", err)
 		return nil, err
 	}
 
@@ -299,41 +667,70 @@ func (p *Proxy) forwardToProxy(
 }
 
 func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
+	p.inFlight.handlers.Add(1)
+	defer p.inFlight.handlers.Add(-1)
+
 	start := utils.NowUTC()
 	requestID := uuid.New().String()
 
 	// Create logging context that will be filled throughout request processing
 	// and logged at the end via defer to ensure all requests are logged
 	logCtx := &RequestLogContext{
-		RequestID: requestID,
-		StartTime: start,
-		Request:   r,
-		Status:    "unknown",
+		RequestID:  requestID,
+		StartTime:  start,
+		Request:    r,
+		Status:     "unknown",
+		baseLogger: p.logger,
 	}
+	r = withRequestLog(r, logCtx)
+	cw := newCredentialHeaderWriter(w, logCtx, p.masterKeys, p.attribution, p.litellmHeaders)
+	w = cw
+	defer cw.finish()
 
 	// Ensure request is logged at the end regardless of which path is taken
 	defer func() {
+		// A panic still produces a spend log entry (status=failure); the panic is then
+		// re-raised with request context for the router's recovery middleware
+		rec := recover()
+		if rec != nil && rec != http.ErrAbortHandler {
+			rec = newPanicError(rec, logCtx)
+		}
+
 		if !logCtx.Logged && logCtx.Token != "" {
 			// Log request only if we have a credential (successful auth path)
 			// For auth/credential selection errors, log directly at the error point instead
 			if logCtx.Credential != nil {
 				if err := p.logSpendToLiteLLMDB(logCtx); err != nil {
-					p.logger.Warn("Failed to queue spend log",
+					logCtx.Logger().Warn("Failed to queue spend log",
 						"error", err,
-						"request_id", requestID,
 					)
 				}
 			}
 			logCtx.Logged = true
 		}
+
+		p.recordSLO(cw, logCtx)
+
+		if rec != nil {
+			panic(rec)
+		}
 	}()
 
 	prepared, ok := p.orchestrateRequest(w, r, logCtx)
 	if !ok {
 		return
 	}
+	defer prepared.release()
+
+	// Keys that cannot consume SSE get the assembled stream as a single JSON response
+	if prepared.streaming && p.nonStreaming.applies(logCtx) {
+		sw := newStreamAssemblyWriter(w, p.maxResponseBodySize, p.logger)
+		w = sw
+		defer sw.finish()
+	}
 
-	r = prepared.request
+	r, untrack := p.trackRequest(prepared.request, logCtx, prepared.modelID, prepared.streaming)
+	defer untrack()
 	logCtx.Request = r
 	body := prepared.body
 	modelID := prepared.modelID
@@ -344,11 +741,9 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 	logCtx.RealModelID = realModelID
 
 	// Log request details at DEBUG level
-	p.logger.Debug("Processing request",
-		"credential", cred.Name,
+	logCtx.CredentialLogger(cred.Name).Debug("Processing request",
 		"method", r.Method,
 		"path", r.URL.Path,
-		"model", modelID,
 		"type", cred.Type,
 	)
 
@@ -362,17 +757,22 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 		for attempt := 0; attempt <= p.maxProviderRetries; attempt++ {
 			if attempt > 0 {
-				nextCred, err := p.balancer.NextForModelExcluding(modelID, triedCreds)
+				if p.requestDeadlineExceeded(r) {
+					logCtx.Logger().Info("Request deadline reached, not retrying",
+						"attempt", attempt, "request_deadline", p.requestDeadline)
+					break
+				}
+				nextCred, err := p.balancer.Select(modelID, balancer.SelectOptions{Exclude: triedCreds, Share: p.classShare(logCtx)})
 				if err != nil {
-					p.logger.Debug("No more same-type proxy credentials for retry",
-						"model", modelID, "attempt", attempt, "error", err)
+					logCtx.Logger().Debug("No more same-type proxy credentials for retry",
+						"attempt", attempt, "error", err)
 					break
 				}
 				cred = nextCred
 				triedCreds[cred.Name] = true
 				logCtx.Credential = cred
-				p.logger.Info("Retrying with next same-type proxy credential",
-					"credential", cred.Name, "model", modelID,
+				logCtx.RetryCount++
+				logCtx.CredentialLogger(cred.Name).Info("Retrying with next same-type proxy credential",
 					"attempt", attempt+1, "max_attempts", p.maxProviderRetries+1,
 					"retry_reason", retryReason)
 				time.Sleep(time.Duration(rand.Intn(50)) * time.Millisecond)
@@ -381,9 +781,16 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			shouldRetry = false
 
 			proxyResp, lastProxyErr = p.forwardToProxy(w, r, modelID, cred, body, start)
+			if errors.Is(lastProxyErr, ErrRequestCancelled) {
+				p.writeRequestCancelled(w, logCtx)
+				return
+			}
 			if lastProxyErr != nil {
 				shouldRetry = true
 				retryReason = RetryReasonNetErr
+				if errors.Is(lastProxyErr, ErrFirstByteTimeout) {
+					retryReason = RetryReasonFirstByte
+				}
 				continue
 			}
 
@@ -399,10 +806,9 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 
-			p.logger.Info("Proxy credential returned retryable error",
-				"credential", cred.Name, "status", proxyResp.StatusCode,
-				"reason", retryReason, "model", modelID,
-				"attempt", attempt+1, "max_attempts", p.maxProviderRetries+1)
+			logCtx.CredentialLogger(cred.Name).Info("Proxy credential returned retryable error",
+				"status", proxyResp.StatusCode,
+				"reason", retryReason, "attempt", attempt+1, "max_attempts", p.maxProviderRetries+1)
 		}
 
 		// After retry loop: try fallback proxy as last resort
@@ -417,15 +823,14 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 				fallbackStatus = proxyResp.StatusCode
 			}
 
-			p.logger.Info("All same-type proxy credentials exhausted, attempting fallback",
-				"credential", cred.Name, "model", modelID,
+			logCtx.CredentialLogger(cred.Name).Info("All same-type proxy credentials exhausted, attempting fallback",
 				"last_status", fallbackStatus, "reason", retryReason)
 			success, fallbackReason := p.TryFallbackProxy(w, r, modelID, cred.Name, fallbackStatus, retryReason, body, start, logCtx)
 			if success {
 				return
 			}
-			p.logger.Debug("Fallback retry failed, using original response",
-				"credential", cred.Name, "fallback_reason", fallbackReason)
+			logCtx.CredentialLogger(cred.Name).Debug("Fallback retry failed, using original response",
+				"fallback_reason", fallbackReason)
 		}
 
 		// Handle transport error (no successful response)
@@ -455,20 +860,20 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 		// Write response (streaming or non-streaming)
 		if proxyResp.IsStreaming {
-			p.logger.Debug("Response is streaming (no retry for streaming)",
-				"credential", cred.Name, "status", proxyResp.StatusCode)
+			logCtx.CredentialLogger(cred.Name).Debug("Response is streaming (no retry for streaming)",
+				"status", proxyResp.StatusCode)
 
 			if prepared.convertedResp {
 				// Proxy streaming + Responses API: need to convert Chat Completions SSE
 				// to Responses API SSE. Wrap StreamBody in http.Response for handleResponsesAPIStreaming.
 				defer func() {
 					if closeErr := proxyResp.StreamBody.Close(); closeErr != nil {
-						p.logger.Error("Failed to close proxy streaming response body", "error", closeErr)
+						logCtx.Logger().Error("Failed to close proxy streaming response body", "error", closeErr)
 					}
 				}()
-				copyResponseHeaders(w, proxyResp.Headers, cred.Type)
+				p.copyResponseHeaders(w, proxyResp.Headers, cred.Name)
 				w.WriteHeader(proxyResp.StatusCode)
-				logCtx.PromptTokensEstimate = estimatePromptTokens(body)
+				logCtx.PromptTokensEstimate = estimatePromptTokensFor(body, cred.Type)
 				fakeResp := &http.Response{
 					StatusCode: proxyResp.StatusCode,
 					Header:     proxyResp.Headers,
@@ -476,43 +881,48 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 				}
 				err := p.handleResponsesAPIStreaming(w, fakeResp, cred, realModelID, logCtx)
 				if err != nil {
-					p.logger.Error("Failed to handle proxy Responses API streaming", "error", err)
+					logCtx.Logger().Error("Failed to handle proxy Responses API streaming", "error", err)
 				}
 			} else {
 				totalTokens, err := p.writeProxyStreamingResponseWithTokens(w, proxyResp, r, cred.Name)
 				if err != nil {
-					p.logger.Error("Failed to write streaming proxy response",
-						"credential", cred.Name, "error", err)
+					logCtx.CredentialLogger(cred.Name).Error("Failed to write streaming proxy response",
+						"error", err)
 				}
 				if totalTokens > 0 {
 					p.rateLimiter.ConsumeTokens(cred.Name, totalTokens)
 					if modelID != "" {
 						p.rateLimiter.ConsumeModelTokens(cred.Name, modelID, totalTokens)
 					}
-					p.logger.Debug("Proxy streaming token usage recorded",
-						"credential", cred.Name, "model", modelID, "tokens", totalTokens)
+					logCtx.CredentialLogger(cred.Name).Debug("Proxy streaming token usage recorded",
+						"tokens", totalTokens)
 				}
 			}
 		} else {
-			// Convert proxy response back to Responses API format if needed
-			if prepared.convertedResp && proxyResp.StatusCode >= 200 && proxyResp.StatusCode < 300 {
-				responsesBody, convErr := responses.ChatToResponse(proxyResp.Body)
-				if convErr != nil {
-					p.logger.Error("Failed to convert proxy response to Responses API format", "error", convErr)
-				} else {
-					proxyResp.Body = responsesBody
+			// The downstream router may answer a streaming request with a complete response
+			emulated := streaming && proxyResp.StatusCode >= 200 && proxyResp.StatusCode < 300 &&
+				p.writeEmulatedStream(w, proxyResp.Body, proxyResp.StatusCode, logCtx.CredentialLogger(cred.Name), realModelID, prepared.convertedResp)
+			if !emulated {
+				// Convert proxy response back to Responses API format if needed
+				if prepared.convertedResp && proxyResp.StatusCode >= 200 && proxyResp.StatusCode < 300 {
+					responsesBody, convErr := responses.ChatToResponse(proxyResp.Body)
+					if convErr != nil {
+						logCtx.Logger().Error("Failed to convert proxy response to Responses API format", "error", convErr)
+					} else {
+						proxyResp.Body = responsesBody
+					}
 				}
-			}
 
-			p.writeProxyResponse(w, proxyResp, r)
+				p.writeProxyResponse(w, proxyResp, r, cred.Name)
+			}
 			tokens := extractTokensFromResponse(string(proxyResp.Body), config.ProviderTypeOpenAI)
 			if tokens > 0 {
 				p.rateLimiter.ConsumeTokens(cred.Name, tokens)
 				if modelID != "" {
 					p.rateLimiter.ConsumeModelTokens(cred.Name, modelID, tokens)
 				}
-				p.logger.Debug("Proxy token usage recorded",
-					"credential", cred.Name, "model", modelID, "tokens", tokens)
+				logCtx.CredentialLogger(cred.Name).Debug("Proxy token usage recorded",
+					"tokens", tokens)
 			}
 		}
 
@@ -531,15 +941,21 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 	// Track embeddings and image generation requests (once, before retry loop)
 	isEmbeddings := strings.Contains(r.URL.Path, "/embeddings")
 	logCtx.IsImageGeneration = strings.Contains(r.URL.Path, "/images/generations")
-	if logCtx.IsImageGeneration {
-		var imgReq struct {
-			N *int `json:"n"`
-		}
-		if err := json.Unmarshal(body, &imgReq); err == nil && imgReq.N != nil {
-			logCtx.ImageCount = *imgReq.N
-		} else {
-			logCtx.ImageCount = 1
-		}
+	logCtx.IsImageEdit = isImageEditPath(r.URL.Path)
+	if logCtx.IsImageGeneration || logCtx.IsImageEdit {
+		logCtx.ImageCount = requestedImageCount(r, body)
+	}
+
+	// Parameters set in the request, checked against each credential's capabilities
+	requestedParams := converter.RequestedParams(body)
+	requestedChoices := 1 // n of chat requests, fanned out on providers without it (n_fanout)
+	if requestedParams["n"] && !isEmbeddings && !logCtx.IsImageGeneration && !logCtx.IsImageEdit {
+		requestedChoices = converter.RequestedChoices(body)
+	}
+
+	var stopSequences []string // Stripped from converted responses (response_postprocessing)
+	if p.postProcess.StripStopSequences {
+		stopSequences = converter.StopSequences(body)
 	}
 
 	// Retry loop: try same-type credentials on provider errors (429/5xx/auth)
@@ -551,6 +967,7 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		conv            *converter.ProviderConverter
 		closeBody       func()
 		isStreamingResp bool
+		inlinedBody     []byte // body with remote images inlined, fetched once and reused on retries
 		shouldRetry     bool
 		retryReason     RetryReason
 		transportErr    error
@@ -558,6 +975,12 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 	for attempt := 0; attempt <= p.maxProviderRetries; attempt++ {
 		if attempt > 0 {
+			if p.requestDeadlineExceeded(r) {
+				logCtx.Logger().Info("Request deadline reached, not retrying",
+					"attempt", attempt, "request_deadline", p.requestDeadline)
+				break
+			}
+
 			// Close previous response body before retrying
 			if closeBody != nil {
 				closeBody()
@@ -566,18 +989,18 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			resp = nil
 			responseBody = nil
 
-			nextCred, err := p.balancer.NextForModelExcluding(modelID, triedCreds)
+			nextCred, err := p.balancer.Select(modelID, balancer.SelectOptions{Exclude: triedCreds, Share: p.classShare(logCtx)})
 			if err != nil {
-				p.logger.Debug("No more same-type credentials for retry",
-					"model", modelID, "attempt", attempt, "error", err)
+				logCtx.Logger().Debug("No more same-type credentials for retry",
+					"attempt", attempt, "error", err)
 				break
 			}
 			cred = nextCred
 			triedCreds[cred.Name] = true
 			logCtx.Credential = cred
+			logCtx.RetryCount++
 
-			p.logger.Info("Retrying with next same-type credential",
-				"credential", cred.Name, "model", modelID,
+			logCtx.CredentialLogger(cred.Name).Info("Retrying with next same-type credential",
 				"attempt", attempt+1, "max_attempts", p.maxProviderRetries+1,
 				"retry_reason", retryReason)
 
@@ -590,21 +1013,68 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		transportErr = nil
 
 		// Create provider converter for this request
-		// Use realModelID for URL construction and body conversion (provider-facing name).
-		// modelID (alias) is used for credential selection and rate limiting.
+		// Use realModelID, or the credential's model_map entry, for URL construction and body
+		// conversion (provider-facing name). modelID (alias) is used for credential selection and rate limiting.
+		providerModelID := cred.ProviderModel(modelID, realModelID)
+		fanOut := p.choiceFanOut(cred, requestedChoices)
+		upstreamStreaming := streaming && !streamUnsupported(cred, requestedParams) && fanOut == 1
 		conv = converter.New(cred.Type, converter.RequestMode{
 			IsImageGeneration: logCtx.IsImageGeneration,
+			IsImageEdit:       logCtx.IsImageEdit,
 			IsEmbeddings:      isEmbeddings,
-			IsStreaming:       streaming,
-			ModelID:           realModelID,
+			IsStreaming:       upstreamStreaming,
+			ModelID:           providerModelID,
+			ThinkingBudgets:   p.reasoningParams.thinkingBudgets(modelID, providerModelID),
+			ChoiceFanOut:      p.maxFanOutChoices,
+			PostProcess:       p.postProcess,
+			StopSequences:     stopSequences,
+			Logger:            logCtx.CredentialLogger(cred.Name),
 		})
 
 		// Convert request body to provider format
-		requestBody, convErr := conv.RequestFrom(body)
+		providerBody := body
+		if !isEmbeddings && !logCtx.IsImageGeneration && !logCtx.IsImageEdit && p.needsImageInlining(cred.Type) {
+			if inlinedBody == nil {
+				inlinedBody = p.inlineImageURLs(upstreamContext(r), body, logCtx)
+			}
+			providerBody = inlinedBody
+		}
+		if providerModelID != realModelID {
+			providerBody = replaceRequestModel(r, providerBody, realModelID, providerModelID)
+		}
+		providerBody = p.dropUnsupportedParams(w, providerBody, requestedParams, cred, modelID, logCtx)
+		if fanOut > 1 && streaming {
+			// Fanned out streams are emulated from the merged response
+			providerBody = converter.DropParams(providerBody, []string{"stream", "stream_options"})
+		}
+		requestBody, convErr := conv.RequestFrom(upstreamContext(r), providerBody)
+		if convErr != nil && requestCancelled(r.Context()) {
+			p.writeRequestCancelled(w, logCtx)
+			return
+		}
+		if reqErr := converter.AsRequestError(convErr); reqErr != nil {
+			// The client's request: another credential of the same type fails the same way
+			logCtx.CredentialLogger(cred.Name).Warn("Request cannot be converted to provider format",
+				"type", cred.Type, "code", reqErr.Code, "param", reqErr.Param, "error", reqErr.Message)
+			logCtx.Status = "failure"
+			logCtx.HTTPStatus = reqErr.StatusCode()
+			logCtx.ErrorMsg = fmt.Sprintf("Request conversion failed: %v", reqErr)
+			logCtx.TargetURL = cred.BaseURL
+			writeRequestError(w, reqErr)
+			return
+		}
 		if convErr != nil {
 			// Fatal: conversion error won't be fixed by another credential
-			p.logger.Error("Failed to convert request to provider format",
-				"credential", cred.Name, "type", cred.Type, "error", convErr)
+			logCtx.CredentialLogger(cred.Name).Error("Failed to convert request to provider format",
+				"type", cred.Type, "error", convErr)
+			logCtx.Status = "failure"
+			logCtx.HTTPStatus = http.StatusInternalServerError
+			logCtx.ErrorMsg = fmt.Sprintf("Request conversion failed: %v", convErr)
+			logCtx.TargetURL = cred.BaseURL
+			WriteErrorInternal(w, "Failed to convert request")
+			return
+		}
+		if convErr = p.checkConversion(conv, requestBody, cred, logCtx); convErr != nil {
 			logCtx.Status = "failure"
 			logCtx.HTTPStatus = http.StatusInternalServerError
 			logCtx.ErrorMsg = fmt.Sprintf("Request conversion failed: %v", convErr)
@@ -638,10 +1108,10 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		var vertexToken string
 		if cred.Type == config.ProviderTypeVertexAI {
 			var tokenErr error
-			vertexToken, tokenErr = p.tokenManager.GetToken(cred.Name, cred.CredentialsFile, cred.CredentialsJSON)
+			vertexToken, tokenErr = p.tokenManager.GetToken(upstreamContext(r), cred.Name, cred.CredentialsFile, cred.CredentialsJSON)
 			if tokenErr != nil {
-				p.logger.Error("Failed to get Vertex AI token",
-					"credential", cred.Name, "error", tokenErr)
+				logCtx.CredentialLogger(cred.Name).Error("Failed to get Vertex AI token",
+					"error", tokenErr)
 				// Token error is retryable (different credential may have valid token)
 				shouldRetry = true
 				retryReason = RetryReasonAuthErr
@@ -651,10 +1121,29 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		proxyReq, reqErr := http.NewRequest(r.Method, targetURL, bytes.NewReader(requestBody))
+		if fanOut > 1 && !p.reserveChoiceFanOut(cred, modelID, fanOut) {
+			logCtx.CredentialLogger(cred.Name).Info("No rate limit headroom to fan out request", "n", fanOut)
+			shouldRetry = true
+			retryReason = RetryReasonRateLimit
+			transportErr = errChoiceFanOutLimit
+			continue
+		}
+
+		// Emulated streams are answered in one piece once generated: no first byte deadline
+		firstByteTimeout := time.Duration(0)
+		if upstreamStreaming {
+			firstByteTimeout = p.firstByteTimeout
+		}
+		// Retries and fallback attempts get fallback_timeout, and all of them request_deadline
+		bodyLimit := p.responseBodyLimit(r)
+		attemptCtx, attemptEnd := withAttemptDeadline(upstreamContext(r), p.nextAttemptDeadline(r))
+		attemptCtx, firstByte := withFirstByteDeadline(attemptCtx, firstByteTimeout)
+		proxyReq, reqErr := http.NewRequestWithContext(attemptCtx, r.Method, targetURL, bytes.NewReader(requestBody))
 		if reqErr != nil {
+			firstByte.release()
+			attemptEnd.release()
 			// Fatal: request creation error
-			p.logger.Error("Failed to create proxy request", "error", reqErr, "url", targetURL)
+			logCtx.Logger().Error("Failed to create proxy request", "error", reqErr, "url", targetURL)
 			logCtx.Status = "failure"
 			logCtx.HTTPStatus = http.StatusInternalServerError
 			logCtx.ErrorMsg = fmt.Sprintf("Failed to create request: %v", reqErr)
@@ -668,11 +1157,13 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		switch cred.Type {
 		case config.ProviderTypeVertexAI:
 			proxyReq.Header.Set("Authorization", "Bearer "+vertexToken)
+			setVertexCapacityHeaders(proxyReq, cred)
 		case config.ProviderTypeGemini:
 			proxyReq.Header.Set("x-goog-api-key", cred.APIKey)
 		case config.ProviderTypeAnthropic:
 			proxyReq.Header.Set("X-Api-Key", cred.APIKey)
-			proxyReq.Header.Set("anthropic-version", "2023-06-01")
+			proxyReq.Header.Set(anthropicVersionHeader, cred.AnthropicAPIVersion())
+			setAnthropicBetaHeader(proxyReq, cred)
 		case config.ProviderTypeBedrock:
 			proxyReq.Header.Set("Authorization", "Bearer "+cred.APIKey)
 		default:
@@ -680,9 +1171,8 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if p.logger.Enabled(context.Background(), slog.LevelDebug) {
-			p.logger.Debug("Proxy request details",
-				"target_url", targetURL, "credential", cred.Name,
-				"request_body", logger.TruncateLongFields(string(requestBody), 500))
+			logCtx.CredentialLogger(cred.Name).Debug("Proxy request details",
+				"target_url", targetURL, "request_body", logger.TruncateLongFields(string(requestBody), 500))
 		}
 
 		debugHeaders := make(map[string]string)
@@ -692,20 +1182,35 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			}
 			debugHeaders[key] = strings.Join(values, ", ")
 		}
-		p.logger.Debug("Proxy request headers", "headers", debugHeaders)
+		logCtx.Logger().Debug("Proxy request headers", "headers", debugHeaders)
 
 		// Execute HTTP request
 		var doErr error
-		resp, doErr = p.client.Do(proxyReq)
+		if fanOut > 1 {
+			resp, doErr = attemptEnd.finish(firstByte.finish(p.doChoiceFanOut(proxyReq, requestBody, cred, fanOut, bodyLimit)))
+		} else {
+			resp, doErr = attemptEnd.finish(firstByte.finish(p.doUpstream(proxyReq, cred)))
+		}
+		if errors.Is(doErr, ErrRequestCancelled) {
+			p.writeRequestCancelled(w, logCtx)
+			return
+		}
+		if errors.Is(doErr, ErrFirstByteTimeout) {
+			p.recordFirstByteTimeout(r, cred, modelID, start)
+			shouldRetry = true
+			retryReason = RetryReasonFirstByte
+			transportErr = doErr
+			continue
+		}
 		if doErr != nil {
 			statusCode := http.StatusBadGateway
 			if isTimeoutError(doErr) {
 				statusCode = http.StatusRequestTimeout
-				p.logger.Error("Upstream request timeout",
-					"credential", cred.Name, "error", doErr, "url", targetURL)
+				logCtx.CredentialLogger(cred.Name).Error("Upstream request timeout",
+					"error", doErr, "url", targetURL)
 			} else {
-				p.logger.Error("Upstream request failed",
-					"credential", cred.Name, "error", doErr, "url", targetURL)
+				logCtx.CredentialLogger(cred.Name).Error("Upstream request failed",
+					"error", doErr, "url", targetURL)
 			}
 			p.balancer.RecordResponse(cred.Name, modelID, statusCode)
 			p.metrics.RecordRequest(cred.Name, r.URL.Path, statusCode, time.Since(start))
@@ -720,7 +1225,7 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		closeBody = func() {
 			closeOnce.Do(func() {
 				if closeErr := resp.Body.Close(); closeErr != nil {
-					p.logger.Error("Failed to close response body", "error", closeErr)
+					logCtx.Logger().Error("Failed to close response body", "error", closeErr)
 				}
 			})
 		}
@@ -734,9 +1239,8 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		for key, values := range maskedRespHeaders {
 			debugRespHeaders[key] = strings.Join(values, ", ")
 		}
-		p.logger.Debug("Proxy response received",
-			"status_code", resp.StatusCode, "credential", cred.Name,
-			"headers", debugRespHeaders)
+		logCtx.CredentialLogger(cred.Name).Debug("Proxy response received",
+			"status_code", resp.StatusCode, "headers", debugRespHeaders)
 
 		isStreamingResp = IsStreamingResponse(resp)
 		if isStreamingResp {
@@ -749,13 +1253,17 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		currentCloseBody := closeBody // capture for timer closure
 		bodyReadTimer := time.AfterFunc(p.requestTimeout, func() { currentCloseBody() })
 		var readErr error
-		responseBody, readErr = p.readLimitedResponseBody(resp.Body)
+		responseBody, readErr = p.readLimitedResponseBody(resp.Body, bodyLimit, logCtx.CredentialLogger(cred.Name))
 		bodyReadTimer.Stop()
 		if readErr != nil {
 			closeBody()
+			if requestCancelled(r.Context()) {
+				p.writeRequestCancelled(w, logCtx)
+				return
+			}
 			if errors.Is(readErr, ErrResponseBodyTooLarge) {
 				// Response too large — fatal, another credential won't help
-				p.logger.Error("Failed to read response body", "error", readErr)
+				logCtx.Logger().Error("Failed to read response body", "error", readErr)
 				logCtx.Status = "failure"
 				logCtx.HTTPStatus = http.StatusBadGateway
 				logCtx.ErrorMsg = fmt.Sprintf("Failed to read response body: %v", readErr)
@@ -764,30 +1272,35 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			// Transport error reading body — retryable with another credential
-			p.logger.Warn("Failed to read response body, will retry", "error", readErr,
-				"credential", cred.Name, "attempt", attempt+1)
+			logCtx.CredentialLogger(cred.Name).Warn("Failed to read response body, will retry", "error", readErr,
+				"attempt", attempt+1)
 			shouldRetry = true
 			retryReason = RetryReasonNetErr
 			transportErr = readErr
 			continue
 		}
 
+		if resp.StatusCode == http.StatusBadRequest && p.paramNegotiator != nil {
+			p.learnRejectedParams(cred.Name, modelID, []byte(decodeResponseBody(responseBody, resp.Header.Get("Content-Encoding"), p.maxResponseBodySize)), requestedParams, logCtx)
+		}
+
 		// Check if we should retry with another same-type credential
 		shouldRetry, retryReason = ShouldRetryWithFallback(resp.StatusCode, responseBody)
 		if !shouldRetry {
 			break
 		}
 
-		p.logger.Info("Provider returned retryable error",
-			"credential", cred.Name, "status", resp.StatusCode,
-			"reason", retryReason, "model", modelID,
-			"attempt", attempt+1, "max_attempts", p.maxProviderRetries+1)
+		logCtx.CredentialLogger(cred.Name).Info("Provider returned retryable error",
+			"status", resp.StatusCode,
+			"reason", retryReason, "attempt", attempt+1, "max_attempts", p.maxProviderRetries+1)
 	}
 
 	// After retry loop: try proxy fallback as last resort
 	if shouldRetry && !isStreamingResp {
 		fallbackStatus := 0
-		if transportErr != nil {
+		if errors.Is(transportErr, errChoiceFanOutLimit) {
+			fallbackStatus = http.StatusTooManyRequests
+		} else if transportErr != nil {
 			fallbackStatus = http.StatusBadGateway
 			if isTimeoutError(transportErr) {
 				fallbackStatus = http.StatusRequestTimeout
@@ -796,8 +1309,7 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			fallbackStatus = resp.StatusCode
 		}
 
-		p.logger.Info("All same-type credentials exhausted, attempting fallback proxy",
-			"credential", cred.Name, "model", modelID,
+		logCtx.CredentialLogger(cred.Name).Info("All same-type credentials exhausted, attempting fallback proxy",
 			"last_status", fallbackStatus, "reason", retryReason)
 		success, fallbackReason := p.TryFallbackProxy(w, r, modelID, cred.Name, fallbackStatus, retryReason, body, start, logCtx)
 		if success {
@@ -806,8 +1318,8 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		p.logger.Debug("Fallback retry failed, using original response",
-			"credential", cred.Name, "fallback_reason", fallbackReason)
+		logCtx.CredentialLogger(cred.Name).Debug("Fallback retry failed, using original response",
+			"fallback_reason", fallbackReason)
 	}
 
 	// Handle case where all attempts were transport errors (no response at all)
@@ -825,7 +1337,11 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		logCtx.HTTPStatus = statusCode
 		logCtx.ErrorMsg = "All provider attempts failed"
 		logCtx.TargetURL = targetURL
-		if statusCode == http.StatusRequestTimeout {
+		if errors.Is(transportErr, errChoiceFanOutLimit) {
+			logCtx.HTTPStatus = http.StatusTooManyRequests
+			logCtx.ErrorMsg = "Rate limit exceeded: " + errChoiceFanOutLimit.Error()
+			WriteErrorRateLimit(w, "Rate limit exceeded: "+errChoiceFanOutLimit.Error())
+		} else if statusCode == http.StatusRequestTimeout {
 			WriteErrorTimeout(w, statusMessage)
 		} else {
 			WriteErrorBadGateway(w, statusMessage)
@@ -840,25 +1356,26 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 	// === Process final response ===
 	var finalResponseBody []byte
+	var chatResponseBody []byte // Chat Completions body (before Responses API conversion)
 
 	if isStreamingResp {
-		p.logger.Debug("Response is streaming", "credential", cred.Name)
+		logCtx.CredentialLogger(cred.Name).Debug("Response is streaming")
 	} else {
 		// Decode the response body for logging (handles gzip, etc.)
 		contentEncoding := resp.Header.Get("Content-Encoding")
-		decodedBody := decodeResponseBody(responseBody, contentEncoding)
+		decodedBody := decodeResponseBody(responseBody, contentEncoding, p.maxResponseBodySize)
 
 		// Transform response to OpenAI format (only for successful responses).
 		// For error responses (4xx/5xx) pass the provider body through unchanged.
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 && !conv.IsPassthrough() {
 			convertedBody, convErr := conv.ResponseTo([]byte(decodedBody))
 			if convErr != nil {
-				p.logger.Error("Failed to transform provider response to OpenAI format",
-					"credential", cred.Name, "type", cred.Type, "error", convErr)
+				logCtx.CredentialLogger(cred.Name).Error("Failed to transform provider response to OpenAI format",
+					"type", cred.Type, "error", convErr)
 				finalResponseBody = []byte(decodedBody)
 			} else {
 				finalResponseBody = convertedBody
-				p.logTransformedResponse(cred.Name, string(cred.Type), finalResponseBody)
+				p.logTransformedResponse(logCtx.CredentialLogger(cred.Name), string(cred.Type), finalResponseBody)
 			}
 		} else {
 			finalResponseBody = []byte(decodedBody)
@@ -869,31 +1386,36 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		if len(bodyForTokenExtraction) == 0 {
 			bodyForTokenExtraction = []byte(decodedBody)
 		}
+		chatResponseBody = bodyForTokenExtraction
 
 		// Convert to Responses API format only if the request was converted to Chat Completions
 		if prepared.convertedResp && resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			responsesBody, convErr := responses.ChatToResponse(finalResponseBody)
 			if convErr != nil {
-				p.logger.Error("Failed to convert to Responses API format", "error", convErr)
+				logCtx.Logger().Error("Failed to convert to Responses API format", "error", convErr)
 				// fallback: use Chat Completions body
 			} else {
 				finalResponseBody = responsesBody
 			}
 		}
 
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			(&contentFilterCounter{model: modelID, credName: cred.Name}).observe(bodyForTokenExtraction)
+		}
+
 		tokens := extractTokensFromResponse(string(bodyForTokenExtraction), config.ProviderTypeOpenAI)
 		if tokens > 0 {
 			p.rateLimiter.ConsumeTokens(cred.Name, tokens)
 			if modelID != "" {
 				p.rateLimiter.ConsumeModelTokens(cred.Name, modelID, tokens)
 			}
-			p.logger.Debug("Token usage recorded",
-				"credential", cred.Name, "model", modelID, "tokens", tokens)
+			logCtx.CredentialLogger(cred.Name).Debug("Token usage recorded",
+				"tokens", tokens)
 		}
 
 		if p.logger.Enabled(context.Background(), slog.LevelDebug) {
-			p.logger.Debug("Proxy response body",
-				"credential", cred.Name, "content_encoding", contentEncoding,
+			logCtx.CredentialLogger(cred.Name).Debug("Proxy response body",
+				"content_encoding", contentEncoding,
 				"body", logger.TruncateLongFields(decodedBody, 500))
 		}
 
@@ -905,7 +1427,11 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		logCtx.HTTPStatus = resp.StatusCode
 		logCtx.TargetURL = targetURL
 
-		if logCtx.IsImageGeneration && logCtx.TokenUsage != nil {
+		// Images are billed per image; edits and some generation models report no token usage
+		if (logCtx.IsImageGeneration || logCtx.IsImageEdit) && resp.StatusCode < 300 {
+			if logCtx.TokenUsage == nil {
+				logCtx.TokenUsage = &converter.TokenUsage{}
+			}
 			logCtx.TokenUsage.ImageCount = logCtx.ImageCount
 		}
 
@@ -916,15 +1442,23 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 		if logCtx.Token != "" && logCtx.Credential != nil {
 			if err := p.logSpendToLiteLLMDB(logCtx); err != nil {
-				p.logger.Warn("Failed to queue spend log",
-					"error", err, "request_id", logCtx.RequestID)
+				logCtx.Logger().Warn("Failed to queue spend log",
+					"error", err)
 			}
 		}
 		logCtx.Logged = true
 	}
 
+	wrapError := !isStreamingResp && resp.StatusCode >= 400 && p.upstreamErrors.Wrap
+	if wrapError {
+		finalResponseBody = p.wrapUpstreamError(resp.StatusCode, finalResponseBody, logCtx.CredentialLogger(cred.Name))
+	}
+
 	// Copy response headers (skip hop-by-hop headers and transformation-related headers)
-	copyResponseHeaders(w, resp.Header, cred.Type)
+	p.copyResponseHeaders(w, resp.Header, cred.Name)
+	if wrapError {
+		w.Header().Set("Content-Type", "application/json")
+	}
 
 	rc := http.NewResponseController(w)
 
@@ -932,17 +1466,15 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(resp.StatusCode)
 
 		if logCtx != nil {
-			logCtx.PromptTokensEstimate = estimatePromptTokens(body)
-			p.logger.Debug("Estimated prompt tokens for streaming response",
-				"estimate", logCtx.PromptTokensEstimate,
-				"request_id", logCtx.RequestID)
+			logCtx.PromptTokensEstimate = estimatePromptTokensFor(body, cred.Type)
+			logCtx.Logger().Debug("Estimated prompt tokens for streaming response",
+				"estimate", logCtx.PromptTokensEstimate)
 		}
 
-		p.logger.Debug("Streaming handler selection",
+		logCtx.Logger().Debug("Streaming handler selection",
 			"is_responses_api", prepared.isResponsesAPI,
 			"converted_resp", prepared.convertedResp,
 			"provider", cred.Type,
-			"model", modelID,
 			"resp_content_type", resp.Header.Get("Content-Type"),
 			"resp_status", resp.StatusCode)
 
@@ -953,7 +1485,7 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 				// Transform to Responses API SSE format
 				err := p.handleResponsesAPIStreaming(w, resp, cred, realModelID, logCtx)
 				if err != nil {
-					p.logger.Error("Failed to handle Responses API streaming", "error", err)
+					logCtx.Logger().Error("Failed to handle Responses API streaming", "error", err)
 					// Note: finalizeStreamingLog inside handleTransformedStreaming already
 					// logged the spend. We only update error metadata here for the defer
 					// safety net, but don't reset Logged to avoid double logging.
@@ -964,23 +1496,23 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 				case config.ProviderTypeVertexAI, config.ProviderTypeGemini:
 					err := p.handleVertexStreaming(w, resp, cred.Name, realModelID, logCtx)
 					if err != nil {
-						p.logger.Error("Failed to handle vertex streaming response", "error", err)
+						logCtx.Logger().Error("Failed to handle vertex streaming response", "error", err)
 					}
 				case config.ProviderTypeAnthropic:
 					err := p.handleAnthropicStreaming(w, resp, cred.Name, realModelID, logCtx)
 					if err != nil {
-						p.logger.Error("Failed to handle anthropic streaming response", "error", err)
+						logCtx.Logger().Error("Failed to handle anthropic streaming response", "error", err)
 					}
 				case config.ProviderTypeBedrock:
 					err := p.handleBedrockStreaming(w, resp, cred.Name, realModelID, logCtx)
 					if err != nil {
-						p.logger.Error("Failed to handle bedrock streaming response", "error", err)
+						logCtx.Logger().Error("Failed to handle bedrock streaming response", "error", err)
 					}
 				default:
 					// For passthrough providers, stream error as-is
 					err := p.handleStreamingWithTokens(w, resp, cred.Name, modelID, logCtx)
 					if err != nil {
-						p.logger.Error("Failed to handle streaming response", "error", err)
+						logCtx.Logger().Error("Failed to handle streaming response", "error", err)
 					}
 				}
 			}
@@ -989,26 +1521,29 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 			case config.ProviderTypeVertexAI, config.ProviderTypeGemini:
 				err := p.handleVertexStreaming(w, resp, cred.Name, realModelID, logCtx)
 				if err != nil {
-					p.logger.Error("Failed to handle vertex streaming response", "error", err)
+					logCtx.Logger().Error("Failed to handle vertex streaming response", "error", err)
 				}
 			case config.ProviderTypeAnthropic:
 				err := p.handleAnthropicStreaming(w, resp, cred.Name, realModelID, logCtx)
 				if err != nil {
-					p.logger.Error("Failed to handle anthropic streaming response", "error", err)
+					logCtx.Logger().Error("Failed to handle anthropic streaming response", "error", err)
 				}
 			case config.ProviderTypeBedrock:
 				err := p.handleBedrockStreaming(w, resp, cred.Name, realModelID, logCtx)
 				if err != nil {
-					p.logger.Error("Failed to handle bedrock streaming response", "error", err)
+					logCtx.Logger().Error("Failed to handle bedrock streaming response", "error", err)
 				}
 			default:
 				err := p.handleStreamingWithTokens(w, resp, cred.Name, modelID, logCtx)
 				if err != nil {
-					p.logger.Error("Failed to handle streaming response", "error", err)
+					logCtx.Logger().Error("Failed to handle streaming response", "error", err)
 				}
 			}
 		}
 
+	} else if streaming && resp.StatusCode >= 200 && resp.StatusCode < 300 &&
+		p.writeEmulatedStream(w, chatResponseBody, resp.StatusCode, logCtx.CredentialLogger(cred.Name), realModelID, prepared.convertedResp) {
+		// The provider answered the streaming request with a complete response
 	} else {
 		acceptEncoding := r.Header.Get("Accept-Encoding")
 		acceptedEncodings := ParseAcceptEncoding(acceptEncoding)
@@ -1018,11 +1553,11 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		if targetEncoding != "identity" && len(finalResponseBody) > 0 {
 			compressedBody, usedEncoding, compErr := CompressBody(finalResponseBody, targetEncoding)
 			if compErr != nil {
-				p.logger.Warn("Failed to compress response body",
-					"credential", cred.Name, "encoding", targetEncoding, "error", compErr)
+				logCtx.CredentialLogger(cred.Name).Warn("Failed to compress response body",
+					"encoding", targetEncoding, "error", compErr)
 			} else {
-				p.logger.Debug("Response body compressed for client",
-					"credential", cred.Name, "encoding", usedEncoding,
+				logCtx.CredentialLogger(cred.Name).Debug("Response body compressed for client",
+					"encoding", usedEncoding,
 					"original_size", len(finalResponseBody), "compressed_size", len(compressedBody))
 				outputBody = compressedBody
 				w.Header().Set("Content-Encoding", usedEncoding)
@@ -1037,19 +1572,18 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) {
 		_ = rc.SetWriteDeadline(time.Now().Add(30 * time.Second))
 		if _, err := p.streamResponseBody(w, bytes.NewReader(outputBody)); err != nil {
 			if isClientDisconnectError(err) {
-				p.logger.Debug("Client disconnected during response body copy", "error", err)
+				logCtx.Logger().Debug("Client disconnected during response body copy", "error", err)
 			} else {
-				p.logger.Error("Failed to copy response body", "error", err)
+				logCtx.Logger().Error("Failed to copy response body", "error", err)
 			}
 		}
 	}
 }
 
 // readLimitedResponseBody reads a response body with size limit protection.
-// Returns ErrResponseBodyTooLarge if the response exceeds maxResponseBodySize.
+// Returns ErrResponseBodyTooLarge if the response exceeds maxSize (responseBodyLimit).
 // Logs a warning when response size exceeds 50% of the limit for observability.
-func (p *Proxy) readLimitedResponseBody(body io.Reader) ([]byte, error) {
-	maxSize := p.maxResponseBodySize
+func (p *Proxy) readLimitedResponseBody(body io.Reader, maxSize int64, log *slog.Logger) ([]byte, error) {
 	// Read one extra byte to detect overflow without allocating the full oversized buffer
 	limitedReader := io.LimitReader(body, maxSize+1)
 	data, err := io.ReadAll(limitedReader)
@@ -1057,14 +1591,14 @@ func (p *Proxy) readLimitedResponseBody(body io.Reader) ([]byte, error) {
 		return nil, err
 	}
 	if int64(len(data)) > maxSize {
-		p.logger.Error("Response body exceeds size limit",
+		log.Error("Response body exceeds size limit",
 			"limit_mb", maxSize/(1024*1024),
 		)
 		return nil, ErrResponseBodyTooLarge
 	}
 	// Warn when response is large (>50% of limit) for observability
 	if int64(len(data)) > maxSize/2 {
-		p.logger.Warn("Large response body detected",
+		log.Warn("Large response body detected",
 			"size_bytes", len(data),
 			"limit_bytes", maxSize,
 			"usage_pct", int(float64(len(data))/float64(maxSize)*100),
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"bytes"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -433,3 +434,37 @@ func TestForwardToProxy_UpstreamError(t *testing.T) {
 		"Статус код ошибки должен быть проксирован",
 	)
 }
+
+// TestForwardToProxy_RenamedModel проверяет что переименованная модель (proxy_models.rename)
+// отправляется в downstream роутер под своим исходным именем
+func TestForwardToProxy_RenamedModel(t *testing.T) {
+	var receivedBody string
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstreamServer.Close()
+
+	logger := testhelpers.NewTestLogger()
+	bal, rl := createTestBalancer(upstreamServer.URL)
+	prx := createProxyWithParams(
+		bal, logger, 10, 5*time.Second, createTestProxyMetrics(),
+		"master-key", rl, createTestTokenManager(logger), createTestModelManager(logger),
+		"test-version", "test-commit",
+	)
+
+	cred := &config.CredentialConfig{
+		Name:        "gateway",
+		Type:        config.ProviderTypeProxy,
+		BaseURL:     upstreamServer.URL,
+		ProxyModels: &config.ProxyModelsConfig{Rename: map[string]string{"gpt-4o": "east/gpt-4o"}},
+	}
+	body := []byte(`{"model":"east/gpt-4o","messages":[]}`)
+	upstreamReq := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+
+	_, err := prx.forwardToProxy(httptest.NewRecorder(), upstreamReq, "east/gpt-4o", cred, body, time.Now().UTC())
+	require.NoError(t, err)
+	assert.Equal(t, `{"model":"gpt-4o","messages":[]}`, receivedBody)
+}
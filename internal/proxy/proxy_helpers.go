@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
 )
@@ -104,8 +105,36 @@ func mapHTTPStatusToErrorClass(statusCode int) string {
 	}
 }
 
-// buildMetadata builds metadata JSON with user/team alias and optional error info
-func buildMetadata(hashedToken string, tokenInfo *litellmdb.TokenInfo, errorMsg string, httpStatus int) string {
+// requestPerformance holds latency and retry details added to spend log metadata,
+// so spend can be correlated with performance in the LiteLLM UI
+type requestPerformance struct {
+	LatencyMs          int64  // Total request latency
+	TimeToFirstTokenMs int64  // Time until the first response bytes were written (0 = no response body)
+	RetryCount         int    // Retries with other credentials
+	FallbackCredential string // Fallback credential that served the request ("" = none)
+	CacheHit           bool   // Provider served part of the prompt from its prompt cache
+}
+
+// newRequestPerformance collects the performance details of a finished request
+func newRequestPerformance(logCtx *RequestLogContext, endTime time.Time) requestPerformance {
+	perf := requestPerformance{
+		LatencyMs:  endTime.Sub(logCtx.StartTime).Milliseconds(),
+		RetryCount: logCtx.RetryCount,
+		CacheHit:   logCtx.TokenUsage != nil && logCtx.TokenUsage.CachedInputTokens > 0,
+	}
+	if !logCtx.FirstByteTime.IsZero() {
+		// At least 1ms so a recorded first byte is distinguishable from none
+		perf.TimeToFirstTokenMs = max(logCtx.FirstByteTime.Sub(logCtx.StartTime).Milliseconds(), 1)
+	}
+	if logCtx.Credential != nil && (logCtx.FallbackUsed || logCtx.Credential.IsFallback) {
+		perf.FallbackCredential = logCtx.Credential.Name
+	}
+	return perf
+}
+
+// buildMetadata builds metadata JSON with user/team alias, request performance, optional
+// chargeback cost and error info
+func buildMetadata(hashedToken string, tokenInfo *litellmdb.TokenInfo, errorMsg string, httpStatus int, perf requestPerformance, charge *chargebackCharge) string {
 	// Extract user info from tokenInfo (or use empty strings as fallback)
 	var userID, teamID, organizationID string
 	if tokenInfo != nil {
@@ -125,6 +154,21 @@ func buildMetadata(hashedToken string, tokenInfo *litellmdb.TokenInfo, errorMsg
 		"user_api_key_team_id": teamID,
 		"user_api_key_user_id": userID,
 		"status":               "success",
+		"latency_ms":           perf.LatencyMs,
+		"retry_count":          perf.RetryCount,
+		"fallback_used":        perf.FallbackCredential != "",
+		"cache_hit":            perf.CacheHit,
+	}
+	if perf.TimeToFirstTokenMs > 0 {
+		metadata["time_to_first_token_ms"] = perf.TimeToFirstTokenMs
+	}
+	if perf.FallbackCredential != "" {
+		metadata["fallback_credential"] = perf.FallbackCredential
+	}
+	if charge != nil {
+		metadata["chargeback_spend"] = charge.Spend
+		metadata["chargeback_markup_percent"] = charge.MarkupPercent
+		metadata["chargeback_fee"] = charge.Fee
 	}
 
 	// Add aliases from tokenInfo if available
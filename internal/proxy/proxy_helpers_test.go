@@ -8,10 +8,13 @@ import (
 	"net/http"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
 )
 
@@ -191,7 +194,7 @@ func TestGetClientIP(t *testing.T) {
 
 func TestBuildMetadata(t *testing.T) {
 	t.Run("nil_tokenInfo", func(t *testing.T) {
-		result := buildMetadata("hashed123", nil, "", 0)
+		result := buildMetadata("hashed123", nil, "", 0, requestPerformance{}, nil)
 		var m map[string]interface{}
 		err := json.Unmarshal([]byte(result), &m)
 		require.NoError(t, err)
@@ -209,7 +212,7 @@ func TestBuildMetadata(t *testing.T) {
 			UserAlias:      "my-user",
 			TeamAlias:      "my-team",
 		}
-		result := buildMetadata("hashed456", tokenInfo, "", 0)
+		result := buildMetadata("hashed456", tokenInfo, "", 0, requestPerformance{}, nil)
 		var m map[string]interface{}
 		err := json.Unmarshal([]byte(result), &m)
 		require.NoError(t, err)
@@ -223,7 +226,7 @@ func TestBuildMetadata(t *testing.T) {
 	})
 
 	t.Run("with_error_info", func(t *testing.T) {
-		result := buildMetadata("hashed789", nil, "rate limit exceeded", http.StatusTooManyRequests)
+		result := buildMetadata("hashed789", nil, "rate limit exceeded", http.StatusTooManyRequests, requestPerformance{}, nil)
 		var m map[string]interface{}
 		err := json.Unmarshal([]byte(result), &m)
 		require.NoError(t, err)
@@ -237,6 +240,79 @@ func TestBuildMetadata(t *testing.T) {
 	})
 }
 
+func TestBuildMetadata_Performance(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(buildMetadata("hashed", nil, "", 0, requestPerformance{}, nil)), &m))
+		assert.Equal(t, float64(0), m["latency_ms"])
+		assert.Equal(t, float64(0), m["retry_count"])
+		assert.Equal(t, false, m["fallback_used"])
+		assert.Equal(t, false, m["cache_hit"])
+		assert.NotContains(t, m, "time_to_first_token_ms")
+		assert.NotContains(t, m, "fallback_credential")
+	})
+
+	t.Run("with_performance", func(t *testing.T) {
+		perf := requestPerformance{
+			LatencyMs:          1500,
+			TimeToFirstTokenMs: 320,
+			RetryCount:         2,
+			FallbackCredential: "fallback-proxy",
+			CacheHit:           true,
+		}
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(buildMetadata("hashed", nil, "", 0, perf, nil)), &m))
+		assert.Equal(t, float64(1500), m["latency_ms"])
+		assert.Equal(t, float64(320), m["time_to_first_token_ms"])
+		assert.Equal(t, float64(2), m["retry_count"])
+		assert.Equal(t, true, m["fallback_used"])
+		assert.Equal(t, "fallback-proxy", m["fallback_credential"])
+		assert.Equal(t, true, m["cache_hit"])
+	})
+}
+
+func TestNewRequestPerformance(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Second)
+
+	t.Run("streaming_with_fallback_and_cache", func(t *testing.T) {
+		logCtx := &RequestLogContext{
+			StartTime:     start,
+			FirstByteTime: start.Add(250 * time.Millisecond),
+			RetryCount:    1,
+			FallbackUsed:  true,
+			Credential:    &config.CredentialConfig{Name: "fallback-proxy"},
+			TokenUsage:    &converter.TokenUsage{PromptTokens: 100, CachedInputTokens: 80},
+		}
+		assert.Equal(t, requestPerformance{
+			LatencyMs:          2000,
+			TimeToFirstTokenMs: 250,
+			RetryCount:         1,
+			FallbackCredential: "fallback-proxy",
+			CacheHit:           true,
+		}, newRequestPerformance(logCtx, end))
+	})
+
+	t.Run("fallback_credential_selected_directly", func(t *testing.T) {
+		logCtx := &RequestLogContext{
+			StartTime:  start,
+			Credential: &config.CredentialConfig{Name: "backup", IsFallback: true},
+		}
+		assert.Equal(t, "backup", newRequestPerformance(logCtx, end).FallbackCredential)
+	})
+
+	t.Run("no_response_body", func(t *testing.T) {
+		logCtx := &RequestLogContext{
+			StartTime:  start,
+			Credential: &config.CredentialConfig{Name: "primary"},
+		}
+		perf := newRequestPerformance(logCtx, end)
+		assert.Equal(t, int64(0), perf.TimeToFirstTokenMs)
+		assert.Empty(t, perf.FallbackCredential)
+		assert.False(t, perf.CacheHit)
+	})
+}
+
 func TestExtractEndUser(t *testing.T) {
 	tests := []struct {
 		name    string
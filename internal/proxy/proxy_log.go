@@ -7,19 +7,26 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/converter"
 	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
 	"github.com/mixaill76/auto_ai_router/internal/logger"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/security"
+	"github.com/mixaill76/auto_ai_router/internal/spendreport"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
+// spendLogGrace bounds the wait for spend log queue space once the response is sent; Shutdown
+// gives cancelled requests the same time to log their spend
+const spendLogGrace = 5 * time.Second
+
 // logTransformedResponse logs a transformed response at debug level
-func (p *Proxy) logTransformedResponse(credName, providerName string, body []byte) {
-	if p.logger.Enabled(context.Background(), slog.LevelDebug) {
-		p.logger.Debug("Transformed response to OpenAI format",
-			"credential", credName,
+func (p *Proxy) logTransformedResponse(log *slog.Logger, providerName string, body []byte) {
+	if log.Enabled(context.Background(), slog.LevelDebug) {
+		log.Debug("Transformed response to OpenAI format",
 			"provider", providerName,
 			"body", logger.TruncateLongFields(string(body), 500),
 		)
@@ -29,7 +36,7 @@ func (p *Proxy) logTransformedResponse(credName, providerName string, body []byt
 // ==================== LiteLLM DB Integration ====================
 // handleLiteLLMAuthError handles LiteLLM authentication errors
 // Returns true if error was handled and response was written
-func (p *Proxy) handleLiteLLMAuthError(w http.ResponseWriter, err error, token string) bool {
+func (p *Proxy) handleLiteLLMAuthError(w http.ResponseWriter, err error, token string, logCtx *RequestLogContext) bool {
 	// Map error types to HTTP status and message
 	errorMap := map[error]struct {
 		status  int
@@ -50,7 +57,7 @@ func (p *Proxy) handleLiteLLMAuthError(w http.ResponseWriter, err error, token s
 	// Check for known auth errors
 	for errType, info := range errorMap {
 		if errors.Is(err, errType) {
-			p.logger.Error(info.logMsg, "token_prefix", security.MaskAPIKey(token))
+			logCtx.Logger().Error(info.logMsg, "token_prefix", security.MaskAPIKey(token))
 			switch info.status {
 			case http.StatusForbidden:
 				WriteErrorForbidden(w, info.message)
@@ -64,17 +71,22 @@ func (p *Proxy) handleLiteLLMAuthError(w http.ResponseWriter, err error, token s
 	}
 
 	// Unknown error
-	p.logger.Error("Auth error", "error", err, "token_prefix", security.MaskAPIKey(token))
+	logCtx.Logger().Error("Auth error", "error", err, "token_prefix", security.MaskAPIKey(token))
 	WriteErrorInternal(w, "Internal Server Error")
 	return true
 }
 
 // logSpendToLiteLLMDB logs request to LiteLLM_SpendLogs table
 // Returns error if the log entry cannot be queued (e.g., queue full)
+// Spend is also recorded in Prometheus counters and mirrored to the Pushgateway spend pusher
+// when configured, even if LiteLLM DB is disabled; the entry is then written to the local
+// spend log (local_spend_log) instead. The request is also added to the credential histories of /vhealth,
+// the quota forecasts (usage_forecast) and its cost to the budget of the credential's pool.
 func (p *Proxy) logSpendToLiteLLMDB(logCtx *RequestLogContext) error {
-	if p.LiteLLMDB == nil || !p.LiteLLMDB.IsEnabled() {
+	if !p.spendSinksEnabled {
 		return nil
 	}
+	dbEnabled := p.LiteLLMDB != nil && p.LiteLLMDB.IsEnabled()
 
 	if logCtx == nil || logCtx.Credential == nil || logCtx.Request == nil {
 		return nil
@@ -97,10 +109,6 @@ func (p *Proxy) logSpendToLiteLLMDB(logCtx *RequestLogContext) error {
 		organizationID = logCtx.TokenInfo.OrganizationID
 	}
 
-	// Build metadata with optional alias fields from tokenInfo
-	// Add error field if request failed
-	metadata := buildMetadata(hashedToken, logCtx.TokenInfo, logCtx.ErrorMsg, logCtx.HTTPStatus)
-
 	// Determine end user - prefer user email from tokenInfo
 	endUser := extractEndUser(logCtx.Request)
 	if logCtx.TokenInfo != nil && logCtx.TokenInfo.UserEmail != "" {
@@ -130,46 +138,70 @@ func (p *Proxy) logSpendToLiteLLMDB(logCtx *RequestLogContext) error {
 		logCtx.TokenUsage = &converter.TokenUsage{}
 	}
 
-	// Calculate cost based on model pricing and token usage.
-	// Try real model name first (from models[].model), then alias name.
-	var cost float64
-	if p.priceRegistry == nil {
-		p.logger.Warn("Price registry not available, using 0 cost for spend log")
-		cost = 0.0
-	} else {
-		priceModelID := logCtx.ModelID
-		if logCtx.RealModelID != "" && logCtx.RealModelID != logCtx.ModelID {
-			priceModelID = logCtx.RealModelID
-		}
-		modelPrice := p.priceRegistry.GetPrice(priceModelID)
-		if modelPrice == nil && priceModelID != logCtx.ModelID {
-			// Fallback: try alias name
-			modelPrice = p.priceRegistry.GetPrice(logCtx.ModelID)
-		}
-		if modelPrice == nil {
-			p.logger.Warn("Model price not found in registry, using 0 cost",
-				"model_name", priceModelID)
-			cost = 0.0
-		} else {
-			cost = modelPrice.CalculateCost(logCtx.TokenUsage)
-			p.logger.Debug("Calculated cost for model",
-				"model_name", priceModelID,
-				"cost", cost,
-				"prompt_tokens", logCtx.TokenUsage.PromptTokens,
-				"completion_tokens", logCtx.TokenUsage.CompletionTokens)
-		}
+	// Build metadata with optional alias fields from tokenInfo, request performance and the
+	// chargeback cost. Add error field if request failed
+	endTime := utils.NowUTC()
+	cost := p.calculateRequestCost(logCtx)
+	charge := p.chargeback.charge(logCtx.TokenInfo, logCtx.Credential.Name, cost, status == "success")
+	metadata := buildMetadata(hashedToken, logCtx.TokenInfo, logCtx.ErrorMsg, logCtx.HTTPStatus,
+		newRequestPerformance(logCtx, endTime), charge)
+
+	provider := strings.Replace(string(logCtx.Credential.Type), "-", "_", 1)
+
+	p.metrics.RecordSpend(logCtx.Credential.Name, logCtx.ModelID, cost,
+		logCtx.TokenUsage.PromptTokens, logCtx.TokenUsage.CompletionTokens)
+	p.metrics.RecordTenantRequest(logCtx.Tenant, status, cost)
+	p.usageEstimator.Record(logCtx.Credential.Name,
+		logCtx.TokenUsage.PromptTokens+logCtx.TokenUsage.CompletionTokens, cost)
+	p.history.Record(logCtx.Credential.Name,
+		logCtx.TokenUsage.PromptTokens+logCtx.TokenUsage.CompletionTokens, cost, status == "failure")
+	p.rateLimiter.RecordSpend(logCtx.Credential.Name, cost)
+
+	p.spendPusher.Record(monitoring.SpendEvent{
+		Credential:       logCtx.Credential.Name,
+		Provider:         provider,
+		Model:            logCtx.ModelID,
+		TeamID:           teamID,
+		UserID:           userID,
+		Status:           status,
+		PromptTokens:     logCtx.TokenUsage.PromptTokens,
+		CompletionTokens: logCtx.TokenUsage.CompletionTokens,
+		Cost:             cost,
+	})
+
+	var keyAlias string
+	if logCtx.TokenInfo != nil {
+		keyAlias = logCtx.TokenInfo.KeyAlias
 	}
+	p.spendReporter.Record(spendreport.Event{
+		Key:      hashedToken,
+		KeyAlias: keyAlias,
+		Team:     teamID,
+		Model:    logCtx.ModelID,
+		Tokens:   logCtx.TokenUsage.Total(),
+		Cost:     cost,
+		Failed:   status == "failure",
+	})
 
-	return p.LiteLLMDB.LogSpend(&litellmdb.SpendLogEntry{
+	if !dbEnabled && p.spendStore == nil {
+		return nil
+	}
+	if cost == 0 && p.skipZeroCostLogs && p.lostPrice(logCtx) {
+		monitoring.ZeroCostLogsSkippedTotal.WithLabelValues(logCtx.ModelID).Inc()
+		logCtx.Logger().Warn("Model lost its price, spend log not written", "model", logCtx.ModelID)
+		return nil
+	}
+
+	entry := &litellmdb.SpendLogEntry{
 		RequestID:         logCtx.RequestID,
 		StartTime:         logCtx.StartTime,
-		EndTime:           utils.NowUTC(),
+		EndTime:           endTime,
 		CallType:          logCtx.Request.URL.Path,
 		APIBase:           apiBase,
-		Model:             logCtx.ModelID,                                               // Model name
-		ModelID:           modelIDFormatted,                                             // credential.name:model_name
-		ModelGroup:        logCtx.ModelID,                                               // Model name
-		CustomLLMProvider: strings.Replace(string(logCtx.Credential.Type), "-", "_", 1), // Provider type as string
+		Model:             logCtx.ModelID,   // Model name
+		ModelID:           modelIDFormatted, // credential.name:model_name
+		ModelGroup:        logCtx.ModelID,   // Model name
+		CustomLLMProvider: provider,         // Provider type as string
 		PromptTokens:      logCtx.TokenUsage.PromptTokens,
 		CompletionTokens:  logCtx.TokenUsage.CompletionTokens,
 		TotalTokens:       logCtx.TokenUsage.Total(),
@@ -183,5 +215,84 @@ func (p *Proxy) logSpendToLiteLLMDB(logCtx *RequestLogContext) error {
 		RequesterIP:       getClientIP(logCtx.Request),
 		Status:            status,
 		SessionID:         logCtx.SessionID,
-	})
+	}
+	if !dbEnabled {
+		return p.spendStore.Log(entry)
+	}
+	if p.ReadOnly() {
+		monitoring.ReadOnlySkippedSpendLogs.Inc()
+		logCtx.Logger().Debug("Read-only mode, spend log not written to LiteLLM DB")
+		return nil
+	}
+
+	ctx, cancel := spendLogContext(logCtx.Request)
+	defer cancel()
+	return p.LiteLLMDB.LogSpend(ctx, entry)
+}
+
+// spendLogContext returns the context for queuing the spend log of request r: r's values
+// without its cancellation, so a client disconnecting at the end of the response does not drop
+// its spend, bounded by spendLogGrace
+func spendLogContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(r.Context()), spendLogGrace)
+}
+
+// calculateRequestCost calculates cost based on model pricing and token usage.
+// Tries real model name first (from models[].model), then alias name.
+// Returns 0 if pricing is not available.
+func (p *Proxy) calculateRequestCost(logCtx *RequestLogContext) float64 {
+	var cost float64
+	if p.priceRegistry == nil {
+		logCtx.Logger().Warn("Price registry not available, using 0 cost for spend log")
+		cost = 0.0
+	} else {
+		modelPrice, priceModelID := lookupRequestPrice(p.priceRegistry, logCtx)
+		if modelPrice == nil {
+			monitoring.UnresolvedModelPricesTotal.WithLabelValues(priceModelID).Inc()
+			logCtx.Logger().Warn("Model price not found in registry, using 0 cost",
+				"model_name", priceModelID)
+			cost = 0.0
+		} else {
+			cost = modelPrice.CalculateCost(logCtx.TokenUsage)
+			logCtx.Logger().Debug("Calculated cost for model",
+				"model_name", priceModelID,
+				"cost", cost,
+				"prompt_tokens", logCtx.TokenUsage.PromptTokens,
+				"completion_tokens", logCtx.TokenUsage.CompletionTokens)
+		}
+	}
+
+	if logCtx.CostMultiplier > 0 {
+		cost *= logCtx.CostMultiplier
+	}
+
+	return cost
+}
+
+// lostPrice reports whether a request with tokens is unpriced because its model was dropped
+// by the current model prices, so a cost of 0 would be wrong rather than free
+func (p *Proxy) lostPrice(logCtx *RequestLogContext) bool {
+	if p.priceRegistry == nil || logCtx.TokenUsage.Total() == 0 {
+		return false
+	}
+	if price, _ := lookupRequestPrice(p.priceRegistry, logCtx); price != nil {
+		return false
+	}
+	return p.priceRegistry.HadPrice(logCtx.ModelID) ||
+		(logCtx.RealModelID != "" && p.priceRegistry.HadPrice(logCtx.RealModelID))
+}
+
+// lookupRequestPrice returns the price of the request's model and the model name it was found
+// under: the real model name first (from models[].model), then the alias name
+func lookupRequestPrice(registry *models.ModelPriceRegistry, logCtx *RequestLogContext) (*models.ModelPrice, string) {
+	priceModelID := logCtx.ModelID
+	if logCtx.RealModelID != "" && logCtx.RealModelID != logCtx.ModelID {
+		priceModelID = logCtx.RealModelID
+	}
+	modelPrice := registry.GetPrice(priceModelID)
+	if modelPrice == nil && priceModelID != logCtx.ModelID {
+		// Fallback: try alias name
+		modelPrice = registry.GetPrice(logCtx.ModelID)
+	}
+	return modelPrice, priceModelID
 }
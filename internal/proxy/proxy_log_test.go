@@ -0,0 +1,364 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/forecast"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/spendreport"
+	"github.com/mixaill76/auto_ai_router/internal/spendstore"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSpend_MirrorsToSpendPusherWithoutDB(t *testing.T) {
+	var mu sync.Mutex
+	var pushed []byte
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		pushed = body
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	spendPusher := monitoring.NewSpendPusher(config.SpendPushConfig{
+		Enabled:        true,
+		PushgatewayURL: gateway.URL,
+		Interval:       time.Minute,
+	}, logger)
+	require.NotNil(t, spendPusher)
+
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+
+	prx := New(&Config{
+		Logger:        logger,
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(false),
+		LiteLLMDB:     litellmdb.NewNoopManager(),
+		PriceRegistry: registry,
+		SpendPusher:   spendPusher,
+	})
+
+	logCtx := &RequestLogContext{
+		RequestID:  "req-1",
+		StartTime:  time.Now(),
+		Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+		Token:      "sk-test",
+		ModelID:    "gpt-4o",
+		HTTPStatus: http.StatusOK,
+		Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+		TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}
+
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx))
+	assert.InDelta(t, 0.02, prx.calculateRequestCost(logCtx), 1e-9)
+
+	require.NoError(t, spendPusher.Push(context.Background()))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, string(pushed), "auto_ai_router_spend_push_cost_usd_total")
+	assert.Contains(t, string(pushed), "openai_main")
+	assert.Contains(t, string(pushed), "gpt-4o")
+}
+
+func TestLogSpend_NoDBNoPusher(t *testing.T) {
+	prx := New(&Config{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(false),
+		LiteLLMDB:     litellmdb.NewNoopManager(),
+	})
+
+	// Must be a no-op without touching the request context
+	assert.NoError(t, prx.logSpendToLiteLLMDB(&RequestLogContext{}))
+}
+
+func TestLogSpend_RecordsUsageForecastOnly(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	estimator := forecast.New(forecast.Config{Interval: time.Minute, Window: 10 * time.Minute, WarnThreshold: time.Hour},
+		map[string]forecast.Quota{"openai_main": {DailyTokens: 1000}}, logger)
+
+	prx := New(&Config{
+		Logger:         logger,
+		MaxBodySizeMB:  10,
+		Metrics:        monitoring.New(false),
+		LiteLLMDB:      litellmdb.NewNoopManager(),
+		UsageEstimator: estimator,
+	})
+	require.NoError(t, prx.logSpendToLiteLLMDB(&RequestLogContext{
+		RequestID:  "req-1",
+		StartTime:  time.Now(),
+		Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+		Token:      "sk-test",
+		ModelID:    "gpt-4o",
+		HTTPStatus: http.StatusOK,
+		Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+		TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}))
+
+	forecasts := estimator.Forecasts("openai_main")
+	require.Len(t, forecasts, 1)
+	assert.Equal(t, 15.0, forecasts[0].Used)
+}
+
+func TestLogSpend_RecordsPrometheusSpendWithoutDB(t *testing.T) {
+	monitoring.SpendUSDTotal.Reset()
+	monitoring.TokensTotal.Reset()
+
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+
+	prx := New(&Config{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(true),
+		LiteLLMDB:     litellmdb.NewNoopManager(),
+		PriceRegistry: registry,
+	})
+
+	require.NoError(t, prx.logSpendToLiteLLMDB(&RequestLogContext{
+		RequestID:  "req-1",
+		StartTime:  time.Now(),
+		Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+		Token:      "sk-test",
+		ModelID:    "gpt-4o",
+		HTTPStatus: http.StatusOK,
+		Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+		TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}))
+
+	assert.InDelta(t, 0.02, testutil.ToFloat64(monitoring.SpendUSDTotal.WithLabelValues("openai_main", "gpt-4o")), 1e-9)
+	assert.Equal(t, 10.0, testutil.ToFloat64(monitoring.TokensTotal.WithLabelValues("openai_main", "gpt-4o", "prompt")))
+	assert.Equal(t, 5.0, testutil.ToFloat64(monitoring.TokensTotal.WithLabelValues("openai_main", "gpt-4o", "completion")))
+}
+
+func TestLogSpend_RecordsSpendReportWithoutDB(t *testing.T) {
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	reporter := spendreport.New(config.SpendReportConfig{Enabled: true, TopN: 10, OutputDir: t.TempDir()}, nil)
+
+	prx := New(&Config{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(false),
+		LiteLLMDB:     litellmdb.NewNoopManager(),
+		PriceRegistry: registry,
+		SpendReporter: reporter,
+	})
+
+	logCtx := func(tokenInfo *litellmdb.TokenInfo, status int) *RequestLogContext {
+		return &RequestLogContext{
+			RequestID:  "req-1",
+			StartTime:  time.Now(),
+			Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+			Token:      "sk-test",
+			ModelID:    "gpt-4o",
+			HTTPStatus: status,
+			TokenInfo:  tokenInfo,
+			Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+			TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+		}
+	}
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx(&litellmdb.TokenInfo{KeyAlias: "frontend", TeamID: "team-1"}, http.StatusOK)))
+	unaliased := logCtx(nil, http.StatusTooManyRequests)
+	unaliased.Token = "sk-other"
+	require.NoError(t, prx.logSpendToLiteLLMDB(unaliased))
+
+	report := reporter.Generate()
+	assert.Equal(t, int64(2), report.Total.Requests)
+	assert.Equal(t, int64(1), report.Total.Failures)
+	require.Len(t, report.TopKeys, 2)
+	assert.ElementsMatch(t, []string{"frontend", litellmdb.HashToken("sk-other")},
+		[]string{report.TopKeys[0].Name, report.TopKeys[1].Name}, "keys without alias use the hashed key")
+	require.Len(t, report.TopTeams, 1)
+	assert.Equal(t, spendreport.Entry{Name: "team-1", Spend: 0.02, Requests: 1, Tokens: 15}, report.TopTeams[0])
+	assert.Equal(t, "gpt-4o", report.TopModels[0].Name)
+}
+
+func TestLogSpend_WritesLocalSpendLogWithoutDB(t *testing.T) {
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	storeCfg := config.LocalSpendLogConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "spend.db")}
+	store, err := spendstore.Open(storeCfg, logger)
+	require.NoError(t, err)
+
+	prx := New(&Config{
+		Logger:        logger,
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(false),
+		LiteLLMDB:     litellmdb.NewNoopManager(),
+		PriceRegistry: registry,
+		SpendStore:    store,
+	})
+	require.NoError(t, prx.logSpendToLiteLLMDB(&RequestLogContext{
+		RequestID:  "req-1",
+		StartTime:  time.Now(),
+		Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+		Token:      "sk-test",
+		ModelID:    "gpt-4o",
+		HTTPStatus: http.StatusOK,
+		TokenInfo:  &litellmdb.TokenInfo{TeamID: "team-1"},
+		Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+		TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}))
+	require.NoError(t, store.Close()) // Writes the queued entry
+
+	store, err = spendstore.Open(storeCfg, logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	result, err := store.Query(context.Background(), spendstore.Filter{})
+	require.NoError(t, err)
+	require.Len(t, result.Logs, 1)
+	entry := result.Logs[0]
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, litellmdb.HashToken("sk-test"), entry.APIKey)
+	assert.Equal(t, "team-1", entry.TeamID)
+	assert.Equal(t, "openai_main:gpt-4o", entry.ModelID)
+	assert.Equal(t, "success", entry.Status)
+	assert.Equal(t, 15, entry.TotalTokens)
+	assert.InDelta(t, 0.02, entry.Spend, 1e-9)
+}
+
+func TestLogSpend_ReadOnlySkipsDBWrite(t *testing.T) {
+	monitoring.SpendUSDTotal.Reset()
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	db := &recordingSpendDB{NoopManager: litellmdb.NewNoopManager()}
+
+	prx := New(&Config{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(true),
+		LiteLLMDB:     db,
+		PriceRegistry: registry,
+		ReadOnly:      true,
+	})
+	t.Cleanup(func() { prx.SetReadOnly(false) })
+	assert.True(t, prx.ReadOnly())
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.ReadOnlyMode))
+
+	logCtx := func(requestID string) *RequestLogContext {
+		return &RequestLogContext{
+			RequestID:  requestID,
+			StartTime:  time.Now(),
+			Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+			Token:      "sk-test",
+			ModelID:    "gpt-4o",
+			HTTPStatus: http.StatusOK,
+			Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+			TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+		}
+	}
+
+	skipped := testutil.ToFloat64(monitoring.ReadOnlySkippedSpendLogs)
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-1")))
+	assert.Empty(t, db.entries)
+	assert.Equal(t, skipped+1, testutil.ToFloat64(monitoring.ReadOnlySkippedSpendLogs))
+	// Prometheus spend is still recorded
+	assert.InDelta(t, 0.02, testutil.ToFloat64(monitoring.SpendUSDTotal.WithLabelValues("openai_main", "gpt-4o")), 1e-9)
+
+	prx.SetReadOnly(false)
+	assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.ReadOnlyMode))
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-2")))
+	require.Len(t, db.entries, 1)
+	assert.Equal(t, "req-2", db.entries[0].RequestID)
+}
+
+func TestCalculateRequestCost_ResolvesDatedModels(t *testing.T) {
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	prx := New(&Config{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB: 10,
+		Metrics:       monitoring.New(false),
+		LiteLLMDB:     litellmdb.NewNoopManager(),
+		PriceRegistry: registry,
+	})
+
+	logCtx := &RequestLogContext{
+		ModelID:    "gpt-4o-2024-11-20",
+		TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}
+	assert.InDelta(t, 0.02, prx.calculateRequestCost(logCtx), 1e-9)
+
+	unresolved := testutil.ToFloat64(monitoring.UnresolvedModelPricesTotal.WithLabelValues("unknown-model"))
+	logCtx.ModelID = "unknown-model"
+	assert.Zero(t, prx.calculateRequestCost(logCtx))
+	assert.Equal(t, unresolved+1, testutil.ToFloat64(monitoring.UnresolvedModelPricesTotal.WithLabelValues("unknown-model")))
+}
+
+func TestLogSpend_SkipZeroCostLogsForLostPrices(t *testing.T) {
+	registry := models.NewModelPriceRegistry()
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o":      {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+		"gpt-4o-mini": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	registry.Update(map[string]*models.ModelPrice{
+		"gpt-4o-mini": {InputCostPerToken: 0.001, OutputCostPerToken: 0.002},
+	})
+	db := &recordingSpendDB{NoopManager: litellmdb.NewNoopManager()}
+
+	prx := New(&Config{
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxBodySizeMB:    10,
+		Metrics:          monitoring.New(false),
+		LiteLLMDB:        db,
+		PriceRegistry:    registry,
+		SkipZeroCostLogs: true,
+	})
+
+	logCtx := func(requestID, model string) *RequestLogContext {
+		return &RequestLogContext{
+			RequestID:  requestID,
+			StartTime:  time.Now(),
+			Request:    httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil),
+			Token:      "sk-test",
+			ModelID:    model,
+			HTTPStatus: http.StatusOK,
+			Credential: &config.CredentialConfig{Name: "openai_main", Type: config.ProviderTypeOpenAI},
+			TokenUsage: &converter.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+		}
+	}
+
+	skipped := testutil.ToFloat64(monitoring.ZeroCostLogsSkippedTotal.WithLabelValues("gpt-4o"))
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-lost", "gpt-4o")))
+	assert.Empty(t, db.entries, "a model that lost its price is not logged at zero cost")
+	assert.Equal(t, skipped+1, testutil.ToFloat64(monitoring.ZeroCostLogsSkippedTotal.WithLabelValues("gpt-4o")))
+
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-priced", "gpt-4o-mini")))
+	require.NoError(t, prx.logSpendToLiteLLMDB(logCtx("req-unknown", "never-priced")))
+	require.Len(t, db.entries, 2)
+	assert.Equal(t, "req-priced", db.entries[0].RequestID)
+	assert.Equal(t, "req-unknown", db.entries[1].RequestID)
+	assert.Zero(t, db.entries[1].Spend)
+}
@@ -8,12 +8,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/mixaill76/auto_ai_router/internal/balancer"
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
 	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
 	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
 	"github.com/mixaill76/auto_ai_router/internal/models"
@@ -21,6 +23,7 @@ import (
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
 	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -33,7 +36,7 @@ func TestNew(t *testing.T) {
 	prx := createProxyWithParams(bal, logger, 10, 30*time.Second, metrics, "test-master-key", rl, tm, mm, "test-version", "test-commit")
 
 	assert.NotNil(t, prx)
-	assert.Equal(t, "test-master-key", prx.masterKey)
+	assert.Equal(t, "test-master-key", prx.GetMasterKey())
 	assert.Equal(t, 10, prx.maxBodySizeMB)
 	assert.Equal(t, 30*time.Second, prx.requestTimeout)
 	assert.NotNil(t, prx.client)
@@ -172,7 +175,72 @@ func TestProxyRequest_NoCredentialsAvailable(t *testing.T) {
 
 	prx.ProxyRequest(w, req)
 
+	// Banned (not rate-limited) credentials: the service is unavailable, with no retry estimate for a permanent ban
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	var resp CapacityErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "banned", resp.Error.Constraint)
+	assert.Equal(t, "server_error", resp.Error.Type)
+	require.NotNil(t, resp.Error.Code)
+	assert.Equal(t, "credentials_unavailable", *resp.Error.Code)
+	assert.Equal(t, CapacityCredentials{Eligible: 1, Banned: 1}, resp.Error.Credentials)
+	assert.Zero(t, resp.Error.RetryAfter)
+}
+
+func TestProxyRequest_CapacityErrorRetryAfter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	f2b := fail2ban.New(1, time.Minute, []int{500})
+	rl := ratelimit.New()
+
+	credentials := []config.CredentialConfig{
+		{Name: "banned", APIKey: "key1", BaseURL: "http://test.com", RPM: 100},
+		{Name: "limited", APIKey: "key2", BaseURL: "http://test.com", RPM: 1},
+		{Name: "backup", APIKey: "key3", BaseURL: "http://test.com", RPM: 100, IsFallback: true},
+	}
+	for _, cred := range credentials {
+		rl.AddCredential(cred.Name, cred.RPM)
+	}
+	bal := balancer.New(credentials, f2b, rl)
+	prx := createProxyWithParams(bal, logger, 10, 30*time.Second, monitoring.New(false), "master-key", rl, createTestTokenManager(logger), createTestModelManager(logger), "test-version", "test-commit")
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model": "gpt-4"}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		return w
+	}
+
+	f2b.RecordResponse("banned", "gpt-4", 500)
+	f2b.RecordResponse("backup", "gpt-4", 500)
+
+	// Temporary bans only: 503 with the remaining ban time
+	f2b.RecordResponse("limited", "gpt-4", 500)
+	w := send()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp CapacityErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "banned", resp.Error.Constraint)
+	assert.Equal(t, CapacityCredentials{Eligible: 3, Banned: 3}, resp.Error.Credentials)
+	assert.InDelta(t, 60, resp.Error.RetryAfter, 1)
+	assert.Equal(t, strconv.Itoa(resp.Error.RetryAfter), w.Header().Get("Retry-After"))
+
+	// A rate-limited credential takes precedence: 429 with the time until its window frees up
+	f2b.Unban("limited", "gpt-4")
+	rl.Allow("limited")
+	w = send()
 	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	resp = CapacityErrorResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "rate_limit", resp.Error.Constraint)
+	assert.Equal(t, "Rate limit exceeded", resp.Error.Message)
+	require.NotNil(t, resp.Error.Code)
+	assert.Equal(t, "rate_limit_exceeded", *resp.Error.Code)
+	assert.Equal(t, CapacityCredentials{Eligible: 3, Banned: 2, RateLimited: 1}, resp.Error.Credentials)
+	assert.InDelta(t, 60, resp.Error.RetryAfter, 1)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Nil(t, resp.Error.Queue)
 }
 
 func TestProxyRequest_RateLimitExceeded(t *testing.T) {
@@ -616,7 +684,7 @@ func TestDecodeResponseBody(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := decodeResponseBody(tt.body, tt.encoding)
+			result := decodeResponseBody(tt.body, tt.encoding, 1<<20)
 			if tt.shouldMatch {
 				assert.Equal(t, tt.expected, result)
 			}
@@ -890,3 +958,80 @@ func TestExtractTokensFromStreamingChunk(t *testing.T) {
 		})
 	}
 }
+
+func TestProxyRequest_PostProcessesConvertedResponses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"\n\nHello END"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("ant", config.ProviderTypeAnthropic, upstream.URL, "sk-ant").
+		WithMasterKey("master-key").
+		Build()
+	prx.postProcess = converter.PostProcessOptions{TrimLeadingWhitespace: true, StripStopSequences: true}
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"claude-sonnet-4-5","stop":["END"],"messages":[{"role":"user","content":"Hi"}]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp openai.OpenAIResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Choices, 1) {
+		assert.Equal(t, "Hello ", resp.Choices[0].Message.Content)
+	}
+}
+
+func TestProxyRequest_CredentialModelMap(t *testing.T) {
+	var upstreamModel, upstreamPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		upstreamModel, upstreamPath = body.Model, r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4o-2024-11-20","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	send := func(prx *Proxy, model string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model": "`+model+`", "messages": [{"role": "user", "content": "Hi"}]}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		return w
+	}
+
+	openAI := NewTestProxyBuilder().
+		WithCredentials(config.CredentialConfig{Name: "oai", Type: config.ProviderTypeOpenAI, APIKey: "sk-oai", BaseURL: upstream.URL, RPM: 100, TPM: 10000,
+			ModelMap: map[string]string{"gpt-4o": "gpt-4o-2024-11-20"}}).
+		WithMasterKey("master-key").
+		Build()
+	w := send(openAI, "gpt-4o")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "gpt-4o-2024-11-20", upstreamModel)
+
+	// Unmapped models are sent as requested
+	w = send(openAI, "gpt-4o-mini")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "gpt-4o-mini", upstreamModel)
+
+	// Converted requests use the mapped model too
+	anthropic := NewTestProxyBuilder().
+		WithCredentials(config.CredentialConfig{Name: "ant", Type: config.ProviderTypeAnthropic, APIKey: "sk-ant", BaseURL: upstream.URL, RPM: 100, TPM: 10000,
+			ModelMap: map[string]string{"claude-sonnet": "claude-sonnet-4-5-20250929"}}).
+		WithMasterKey("master-key").
+		Build()
+	w = send(anthropic, "claude-sonnet")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "/v1/messages", upstreamPath)
+	assert.Equal(t, "claude-sonnet-4-5-20250929", upstreamModel)
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/quota"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+)
+
+// QuotaBoosts returns the store of temporary key/team boosts (nil if disabled)
+func (p *Proxy) QuotaBoosts() *quota.Store {
+	return p.quotaBoosts
+}
+
+// enforceKeyRateLimit checks the LiteLLM rpm_limit of the request's key and team, raised by
+// their active quota boosts. Only enforced when quota boosts are enabled.
+func (p *Proxy) enforceKeyRateLimit(w http.ResponseWriter, r *http.Request, logCtx *RequestLogContext) bool {
+	info := logCtx.TokenInfo
+	if p.quotaBoosts == nil || info == nil || isInternalRequest(r.Context()) {
+		return true
+	}
+
+	factors := p.quotaBoosts.Factors(info.KeyAlias, info.Token, info.TeamID)
+	limits := []ratelimit.KeyLimit{{Name: "key:" + info.Token, RPM: boostedRPM(info.RPMLimit, factors.KeyRPM)}}
+	if info.TeamID != "" {
+		limits = append(limits, ratelimit.KeyLimit{Name: "team:" + info.TeamID, RPM: boostedRPM(info.TeamRPMLimit, factors.TeamRPM)})
+	}
+	if p.rateLimiter.AllowKeys(limits...) {
+		return true
+	}
+
+	logCtx.Status = "failure"
+	logCtx.HTTPStatus = http.StatusTooManyRequests
+	logCtx.ErrorMsg = "Key rate limit exceeded"
+	logCtx.Credential = &config.CredentialConfig{
+		Name: "system",
+		Type: config.ProviderTypeProxy,
+	}
+
+	logCtx.Logger().Warn("Request rejected by key rate limit",
+		"team_id", info.TeamID,
+	)
+	WriteErrorRateLimit(w, "Rate limit exceeded for this API key, please retry later")
+	return false
+}
+
+// boostedRPM scales an rpm_limit by a boost factor (0 = unlimited)
+func boostedRPM(limit *int64, factor float64) int {
+	if limit == nil || *limit <= 0 {
+		return 0
+	}
+	return int(math.Floor(float64(*limit) * factor))
+}
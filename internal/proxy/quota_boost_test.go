@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
+	"github.com/mixaill76/auto_ai_router/internal/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforceKeyRateLimit(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	rpm := int64(2)
+	info := &models.TokenInfo{Token: "hashed", KeyAlias: "campaign", RPMLimit: &rpm}
+
+	allow := func() (bool, *httptest.ResponseRecorder, *RequestLogContext) {
+		w := httptest.NewRecorder()
+		logCtx := &RequestLogContext{TokenInfo: info}
+		return prx.enforceKeyRateLimit(w, req, logCtx), w, logCtx
+	}
+
+	// Disabled: rpm_limit is not enforced
+	for i := 0; i < 3; i++ {
+		ok, _, _ := allow()
+		assert.True(t, ok)
+	}
+
+	prx.quotaBoosts = quota.NewStore(0)
+	for i := 0; i < 2; i++ {
+		ok, _, _ := allow()
+		assert.True(t, ok)
+	}
+	ok, w, logCtx := allow()
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "failure", logCtx.Status)
+	assert.Equal(t, http.StatusTooManyRequests, logCtx.HTTPStatus)
+
+	// +50% RPM raises the limit to 3 within the same window
+	_, err := prx.quotaBoosts.Grant(quota.Boost{Key: "campaign", RPMPercent: 50}, time.Hour)
+	require.NoError(t, err)
+	ok, _, _ = allow()
+	assert.True(t, ok)
+	ok, _, _ = allow()
+	assert.False(t, ok)
+}
+
+func TestEnforceKeyRateLimit_Team(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	prx.quotaBoosts = quota.NewStore(0)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	teamRPM := int64(1)
+
+	first := &RequestLogContext{TokenInfo: &models.TokenInfo{Token: "a", TeamID: "team-1", TeamRPMLimit: &teamRPM}}
+	second := &RequestLogContext{TokenInfo: &models.TokenInfo{Token: "b", TeamID: "team-1", TeamRPMLimit: &teamRPM}}
+	assert.True(t, prx.enforceKeyRateLimit(httptest.NewRecorder(), req, first))
+	assert.False(t, prx.enforceKeyRateLimit(httptest.NewRecorder(), req, second), "keys of a team share its rpm_limit")
+
+	_, err := prx.quotaBoosts.Grant(quota.Boost{TeamID: "team-1", RPMPercent: 100}, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, prx.enforceKeyRateLimit(httptest.NewRecorder(), req, second))
+
+	// Requests without LiteLLM token info (master key) are not limited
+	assert.True(t, prx.enforceKeyRateLimit(httptest.NewRecorder(), req, &RequestLogContext{}))
+}
+
+func TestBoostedRPM(t *testing.T) {
+	limit := int64(10)
+	assert.Equal(t, 0, boostedRPM(nil, 2))
+	assert.Equal(t, 10, boostedRPM(&limit, 1))
+	assert.Equal(t, 15, boostedRPM(&limit, 1.5))
+}
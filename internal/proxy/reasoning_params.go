@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+)
+
+// reasoningParams applies the configured parameter handling of reasoning model families
+// (reasoning_params) in place of the built-in rules
+type reasoningParams struct {
+	models map[string]config.ReasoningModelConfig // Lower-cased family -> handling
+}
+
+func newReasoningParams(cfg config.ReasoningParamsConfig) *reasoningParams {
+	if len(cfg.Models) == 0 {
+		return nil
+	}
+	return &reasoningParams{models: cfg.Models}
+}
+
+// lookup returns the handling of the longest family matching the first of models that
+// matches any (false if none does or r is nil)
+func (r *reasoningParams) lookup(models ...string) (config.ReasoningModelConfig, bool) {
+	if r == nil {
+		return config.ReasoningModelConfig{}, false
+	}
+	for _, model := range models {
+		best := ""
+		for family := range r.models {
+			if len(family) > len(best) && openai.MatchModelFamily(model, family) {
+				best = family
+			}
+		}
+		if best != "" {
+			return r.models[best], true
+		}
+	}
+	return config.ReasoningModelConfig{}, false
+}
+
+// replaceBodyParams renames and removes the params of reasoning models: with the configured
+// rename_params and drop_params of the model's family, else with the built-in rules
+func (r *reasoningParams) replaceBodyParams(body []byte, modelID, realModelID string) []byte {
+	if model, ok := r.lookup(modelID, realModelID); ok && (len(model.RenameParams) > 0 || len(model.DropParams) > 0) {
+		return openai.UpdateJSONField(body, openai.ModelParamsMapping{
+			KeysToReplace: model.RenameParams,
+			KeysToRemove:  model.DropParams,
+		})
+	}
+	return openai.ReplaceBodyParam(modelID, body)
+}
+
+// thinkingBudgets returns the configured thinking budgets of the first of models with any
+// (nil = built-in budgets)
+func (r *reasoningParams) thinkingBudgets(models ...string) map[string]int {
+	for _, model := range models {
+		if handling, ok := r.lookup(model); ok && len(handling.ThinkingBudgets) > 0 {
+			return handling.ThinkingBudgets
+		}
+	}
+	return nil
+}
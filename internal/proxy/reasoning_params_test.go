@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReasoningParams_ReplaceBodyParams(t *testing.T) {
+	r := newReasoningParams(config.ReasoningParamsConfig{Models: map[string]config.ReasoningModelConfig{
+		"o4":      {DropParams: []string{"temperature"}},
+		"o4-mini": {RenameParams: map[string]string{"max_tokens": "max_completion_tokens"}, DropParams: []string{"top_p"}},
+		"o3":      {ThinkingBudgets: map[string]int{"high": 8000}},
+	}})
+	const body = `{"model":"m","max_tokens":100,"temperature":0.2,"top_p":0.9}`
+	params := func(out []byte) map[string]interface{} {
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(out, &req))
+		return req
+	}
+
+	mini := params(r.replaceBodyParams([]byte(body), "o4-mini-2025-04-16", "o4-mini-2025-04-16"))
+	assert.Equal(t, map[string]interface{}{"model": "m", "max_completion_tokens": float64(100), "temperature": 0.2}, mini,
+		"the longest matching family applies")
+
+	aliased := params(r.replaceBodyParams([]byte(body), "fast", "o4"))
+	assert.NotContains(t, aliased, "temperature", "the real model name is matched too")
+
+	builtin := params(r.replaceBodyParams([]byte(body), "o3-mini", "o3-mini"))
+	assert.Equal(t, map[string]interface{}{"model": "m", "max_completion_tokens": float64(100)}, builtin,
+		"a family with only thinking budgets keeps the built-in rules")
+
+	var disabled *reasoningParams
+	assert.Equal(t, builtin, params(disabled.replaceBodyParams([]byte(body), "o3-mini", "o3-mini")))
+	assert.Nil(t, newReasoningParams(config.ReasoningParamsConfig{}))
+}
+
+func TestReasoningParams_ThinkingBudgets(t *testing.T) {
+	r := newReasoningParams(config.ReasoningParamsConfig{Models: map[string]config.ReasoningModelConfig{
+		"claude-sonnet-4": {ThinkingBudgets: map[string]int{"high": 16000}},
+		"smart":           {DropParams: []string{"temperature"}},
+	}})
+
+	assert.Equal(t, map[string]int{"high": 16000}, r.thinkingBudgets("smart", "claude-sonnet-4-5"),
+		"families without budgets are skipped")
+	assert.Nil(t, r.thinkingBudgets("gemini-2.5-pro"))
+
+	var disabled *reasoningParams
+	assert.Nil(t, disabled.thinkingBudgets("claude-sonnet-4-5"))
+}
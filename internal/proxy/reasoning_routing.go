@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// defaultReasoningTier is the effort label of requests routed to a group's default model
+const defaultReasoningTier = "default"
+
+// reasoningRouter routes requests for model groups to a model by their reasoning_effort
+// (reasoning_routing)
+type reasoningRouter struct {
+	groups map[string]config.ReasoningGroupConfig
+}
+
+func newReasoningRouter(cfg config.ReasoningRoutingConfig) *reasoningRouter {
+	return &reasoningRouter{groups: cfg.Groups}
+}
+
+// route returns the model a request of model group modelID is sent to and the tier that
+// selected it. ok is false if modelID is not a group; errMsg is set if the group has neither
+// a tier for the request's reasoning_effort nor a default model. Safe on a nil router.
+func (r *reasoningRouter) route(modelID string, body []byte) (model, tier string, ok bool, errMsg string) {
+	if r == nil {
+		return "", "", false, ""
+	}
+	group, ok := r.groups[modelID]
+	if !ok {
+		return "", "", false, ""
+	}
+
+	effort := requestReasoningEffort(body)
+	if model := group.Tiers[effort]; effort != "" && model != "" {
+		return model, effort, true, ""
+	}
+	if group.Default != "" {
+		return group.Default, defaultReasoningTier, true, ""
+	}
+
+	tiers := make([]string, 0, len(group.Tiers))
+	for name := range group.Tiers {
+		tiers = append(tiers, name)
+	}
+	sort.Strings(tiers)
+	if effort == "" {
+		return "", "", true, fmt.Sprintf("model %s requires reasoning_effort (one of %s)", modelID, strings.Join(tiers, ", "))
+	}
+	return "", "", true, fmt.Sprintf("model %s does not support reasoning_effort %q (one of %s)", modelID, effort, strings.Join(tiers, ", "))
+}
+
+// resolveReasoningGroup rewrites a request for a model group to the model selected by its
+// reasoning_effort. reasoning_effort is removed for the group's non_reasoning models.
+func (p *Proxy) resolveReasoningGroup(body []byte, modelID string, logCtx *RequestLogContext) ([]byte, string, string) {
+	model, tier, ok, errMsg := p.reasoningRouter.route(modelID, body)
+	if !ok || errMsg != "" {
+		return body, modelID, errMsg
+	}
+
+	logCtx.Logger().Debug("Routed model group by reasoning effort", "tier", tier, "routed_model", model)
+	monitoring.ReasoningRoutedTotal.WithLabelValues(modelID, tier, model).Inc()
+	body = openai.ReplaceModelInBody(body, modelID, model)
+	if slices.Contains(p.reasoningRouter.groups[modelID].NonReasoning, model) {
+		body = openai.UpdateJSONField(body, openai.ModelParamsMapping{
+			KeysToRemove: []string{"reasoning_effort", "reasoning"},
+		})
+	}
+	return body, model, ""
+}
+
+// requestReasoningEffort returns the lower-cased reasoning_effort of a Chat Completions
+// request, or reasoning.effort of a Responses API request ("" = not set)
+func requestReasoningEffort(body []byte) string {
+	var req struct {
+		ReasoningEffort string `json:"reasoning_effort"`
+		Reasoning       *struct {
+			Effort string `json:"effort"`
+		} `json:"reasoning"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	effort := req.ReasoningEffort
+	if effort == "" && req.Reasoning != nil {
+		effort = req.Reasoning.Effort
+	}
+	return strings.ToLower(strings.TrimSpace(effort))
+}
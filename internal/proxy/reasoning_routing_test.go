@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testReasoningRouting = config.ReasoningRoutingConfig{
+	Enabled: true,
+	Groups: map[string]config.ReasoningGroupConfig{
+		"smart": {
+			Tiers:        map[string]string{"high": "o3", "medium": "o4-mini", "low": "gpt-4o-mini"},
+			Default:      "gpt-4o",
+			NonReasoning: []string{"gpt-4o-mini", "gpt-4o"},
+		},
+		"deep": {Tiers: map[string]string{"high": "gemini-2.5-pro", "medium": "gemini-2.5-flash"}},
+	},
+}
+
+func TestReasoningRouter_Route(t *testing.T) {
+	router := newReasoningRouter(testReasoningRouting)
+
+	tests := []struct {
+		name      string
+		model     string
+		body      string
+		wantModel string
+		wantTier  string
+		wantOK    bool
+		wantErr   string
+	}{
+		{name: "high", model: "smart", body: `{"model":"smart","reasoning_effort":"high"}`, wantModel: "o3", wantTier: "high", wantOK: true},
+		{name: "case insensitive", model: "smart", body: `{"model":"smart","reasoning_effort":"Low"}`, wantModel: "gpt-4o-mini", wantTier: "low", wantOK: true},
+		{name: "responses api", model: "smart", body: `{"model":"smart","reasoning":{"effort":"medium"}}`, wantModel: "o4-mini", wantTier: "medium", wantOK: true},
+		{name: "no effort", model: "smart", body: `{"model":"smart"}`, wantModel: "gpt-4o", wantTier: "default", wantOK: true},
+		{name: "effort without tier", model: "smart", body: `{"model":"smart","reasoning_effort":"minimal"}`, wantModel: "gpt-4o", wantTier: "default", wantOK: true},
+		{name: "no default", model: "deep", body: `{"model":"deep","reasoning_effort":"low"}`, wantOK: true, wantErr: `does not support reasoning_effort "low" (one of high, medium)`},
+		{name: "no default no effort", model: "deep", body: `{"model":"deep"}`, wantOK: true, wantErr: "requires reasoning_effort (one of high, medium)"},
+		{name: "not a group", model: "gpt-4o", body: `{"model":"gpt-4o","reasoning_effort":"high"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, tier, ok, errMsg := router.route(tt.model, []byte(tt.body))
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantModel, model)
+			assert.Equal(t, tt.wantTier, tier)
+			if tt.wantErr == "" {
+				assert.Empty(t, errMsg)
+			} else {
+				assert.Contains(t, errMsg, tt.wantErr)
+			}
+		})
+	}
+
+	var disabled *reasoningRouter
+	_, _, ok, _ := disabled.route("smart", []byte(`{"model":"smart"}`))
+	assert.False(t, ok)
+}
+
+func TestOrchestrateRequest_ReasoningRouting(t *testing.T) {
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("test", config.ProviderTypeOpenAI, "http://test.local", "upstream-key").
+		WithMasterKey("master-key").
+		Build()
+	prx.reasoningRouter = newReasoningRouter(testReasoningRouting)
+
+	orchestrate := func(body string) (*httptest.ResponseRecorder, *RequestLogContext, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		logCtx := &RequestLogContext{}
+		prepared, ok := prx.orchestrateRequest(w, req, logCtx)
+		if !ok {
+			return w, logCtx, nil
+		}
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(prepared.body, &raw))
+		return w, logCtx, raw
+	}
+
+	routed := monitoring.ReasoningRoutedTotal.WithLabelValues("smart", "high", "o3")
+	before := testutil.ToFloat64(routed)
+	_, logCtx, raw := orchestrate(`{"model":"smart","reasoning_effort":"high","messages":[{"role":"user","content":"Hi"}]}`)
+	require.NotNil(t, raw)
+	assert.Equal(t, "o3", logCtx.ModelID)
+	assert.Equal(t, "o3", raw["model"])
+	assert.Equal(t, "high", raw["reasoning_effort"])
+	assert.Equal(t, 1.0, testutil.ToFloat64(routed)-before)
+
+	_, logCtx, raw = orchestrate(`{"model":"smart","reasoning_effort":"low","messages":[{"role":"user","content":"Hi"}]}`)
+	require.NotNil(t, raw)
+	assert.Equal(t, "gpt-4o-mini", logCtx.ModelID)
+	assert.Equal(t, "gpt-4o-mini", raw["model"])
+	assert.NotContains(t, raw, "reasoning_effort", "removed for non_reasoning models")
+
+	w, logCtx, raw := orchestrate(`{"model":"deep","reasoning_effort":"low","messages":[]}`)
+	assert.Nil(t, raw)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusBadRequest, logCtx.HTTPStatus)
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+type requestLogKey struct{}
+
+// Logger returns a logger adding the request's correlation fields to every line:
+// request_id, key_alias, model and, once one is selected, credential. A nil l logs
+// to slog.Default() without them.
+func (l *RequestLogContext) Logger() *slog.Logger {
+	credName := ""
+	if l != nil && l.Credential != nil {
+		credName = l.Credential.Name
+	}
+	return l.CredentialLogger(credName)
+}
+
+// CredentialLogger is Logger for a call to the credential credName, which on retries and
+// fallbacks is not the selected credential yet
+func (l *RequestLogContext) CredentialLogger(credName string) *slog.Logger {
+	if l == nil {
+		l = &RequestLogContext{}
+	}
+	logger := l.baseLogger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	args := make([]interface{}, 0, 8)
+	if l.RequestID != "" {
+		args = append(args, "request_id", l.RequestID)
+	}
+	if l.TokenInfo != nil && l.TokenInfo.KeyAlias != "" {
+		args = append(args, "key_alias", l.TokenInfo.KeyAlias)
+	}
+	if l.ModelID != "" {
+		args = append(args, "model", l.ModelID)
+	}
+	if credName != "" {
+		args = append(args, "credential", credName)
+	}
+	if len(args) == 0 {
+		return logger
+	}
+	return logger.With(args...)
+}
+
+// withRequestLog returns r with logCtx attached, for requestLogger in functions without logCtx
+func withRequestLog(r *http.Request, logCtx *RequestLogContext) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestLogKey{}, logCtx))
+}
+
+// requestLogger returns the logger of the request r belongs to, or the proxy logger for
+// requests not served by ProxyRequest
+func (p *Proxy) requestLogger(r *http.Request) *slog.Logger {
+	if logCtx, ok := r.Context().Value(requestLogKey{}).(*RequestLogContext); ok {
+		return logCtx.Logger()
+	}
+	return p.logger
+}
+
+// credentialLogger is requestLogger for a call to the credential credName
+func (p *Proxy) credentialLogger(r *http.Request, credName string) *slog.Logger {
+	if logCtx, ok := r.Context().Value(requestLogKey{}).(*RequestLogContext); ok {
+		return logCtx.CredentialLogger(credName)
+	}
+	return p.logger.With("credential", credName)
+}
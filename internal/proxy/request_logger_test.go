@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeLogLines parses the JSON log lines written to buf
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestRequestLogContext_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logCtx := &RequestLogContext{
+		RequestID:  "req-1",
+		TokenInfo:  &litellmdb.TokenInfo{KeyAlias: "ci-key"},
+		ModelID:    "gpt-4o",
+		baseLogger: slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+
+	logCtx.Logger().Info("before selection")
+	logCtx.Credential = &config.CredentialConfig{Name: "primary"}
+	logCtx.Logger().Info("after selection")
+	logCtx.CredentialLogger("fallback").Info("fallback attempt")
+
+	lines := decodeLogLines(t, &buf)
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.Equal(t, "req-1", line["request_id"])
+		assert.Equal(t, "ci-key", line["key_alias"])
+		assert.Equal(t, "gpt-4o", line["model"])
+	}
+	assert.NotContains(t, lines[0], "credential")
+	assert.Equal(t, "primary", lines[1]["credential"])
+	assert.Equal(t, "fallback", lines[2]["credential"])
+
+	// Unset fields are left out
+	buf.Reset()
+	(&RequestLogContext{baseLogger: slog.New(slog.NewJSONHandler(&buf, nil))}).Logger().Info("bare")
+	lines = decodeLogLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.NotContains(t, lines[0], "request_id")
+	assert.NotContains(t, lines[0], "key_alias")
+	assert.NotContains(t, lines[0], "model")
+}
+
+func TestProxyRequest_LogLinesShareRequestID(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	rl := ratelimit.New()
+	credentials := []config.CredentialConfig{
+		{Name: "downstream", Type: config.ProviderTypeProxy, APIKey: "key1", BaseURL: upstream.URL, RPM: 100},
+	}
+	rl.AddCredential("downstream", 100)
+	bal := balancer.New(credentials, fail2ban.New(3, 0, []int{500}), rl)
+	prx := createProxyWithParams(bal, logger, 10, time.Minute, createTestProxyMetrics(), "master-key", rl,
+		createTestTokenManager(logger), createTestModelManager(logger), "test-version", "test-commit")
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model": "gpt-4o"}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	requestIDs := map[interface{}]bool{}
+	forwarded := 0
+	for _, line := range decodeLogLines(t, &buf) {
+		if line["msg"] == "Proxy request forwarded" {
+			forwarded++
+			assert.Equal(t, "downstream", line["credential"])
+			assert.Equal(t, "gpt-4o", line["model"])
+			require.NotEmpty(t, line["request_id"])
+			requestIDs[line["request_id"]] = true
+		}
+	}
+	assert.Equal(t, 2, forwarded)
+	assert.Len(t, requestIDs, 2, "each request logs its own request_id")
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+// responseHeaderPolicy decides which upstream response headers are returned to clients
+// (response_headers)
+type responseHeaderPolicy struct {
+	maxCount int             // Most headers returned (<= 0 = no limit)
+	maxBytes int             // Largest total size of the returned headers (<= 0 = no limit)
+	strip    map[string]bool // Canonical names of headers never returned
+}
+
+func newResponseHeaderPolicy(cfg config.ResponseHeadersConfig) *responseHeaderPolicy {
+	strip := make(map[string]bool, len(config.DefaultStrippedResponseHeaders)+len(cfg.Strip))
+	for _, name := range config.DefaultStrippedResponseHeaders {
+		strip[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range cfg.Strip {
+		strip[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range cfg.Allow {
+		delete(strip, http.CanonicalHeaderKey(name))
+	}
+	return &responseHeaderPolicy{maxCount: cfg.MaxCount, maxBytes: cfg.MaxBytes, strip: strip}
+}
+
+// filter returns the headers of src to return to the client, in name order, and the reason
+// each other header was dropped: "stripped", "invalid" (control characters in the value)
+// or "limit" (max_count or max_bytes exceeded). Hop-by-hop, Content-Length and
+// Content-Encoding headers are left out without a reason (see copyResponseHeaders).
+func (rp *responseHeaderPolicy) filter(src http.Header) (http.Header, map[string]string) {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kept := make(http.Header, len(src))
+	dropped := make(map[string]string)
+	count, size := 0, 0
+	for _, key := range keys {
+		if isHopByHopHeader(key) || key == "Content-Length" || key == "Content-Encoding" {
+			continue
+		}
+		values := src[key]
+		switch {
+		case rp.strip[http.CanonicalHeaderKey(key)]:
+			dropped[key] = "stripped"
+			continue
+		case slices.ContainsFunc(values, hasControlChars):
+			dropped[key] = "invalid"
+			continue
+		}
+
+		headerSize := 0
+		for _, value := range values {
+			headerSize += len(key) + len(value) + 4 // "key: value\r\n"
+		}
+		if (rp.maxCount > 0 && count+len(values) > rp.maxCount) || (rp.maxBytes > 0 && size+headerSize > rp.maxBytes) {
+			dropped[key] = "limit"
+			continue
+		}
+		count += len(values)
+		size += headerSize
+		kept[key] = values
+	}
+	return kept, dropped
+}
+
+// hasControlChars reports whether a header value contains control characters other than
+// horizontal tab (CR/LF would split the header)
+func hasControlChars(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; (c < ' ' && c != '\t') || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// copyResponseHeaders copies upstream response headers to the response writer, skipping
+// hop-by-hop headers, transformation-related headers and the headers response_headers
+// strips or limits.
+// Note: Content-Encoding is always skipped because Go's http.Client automatically
+// decompresses gzip/deflate responses, so the body is already decompressed.
+// The caller should compress the body if needed and set Content-Encoding appropriately.
+func (p *Proxy) copyResponseHeaders(w http.ResponseWriter, src http.Header, credName string) {
+	kept, dropped := p.responseHeaders.filter(src)
+	for key, values := range kept {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if len(dropped) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(dropped))
+	misbehaving := false
+	for key, reason := range dropped {
+		names = append(names, key)
+		misbehaving = misbehaving || reason != "stripped"
+		monitoring.ResponseHeadersDroppedTotal.WithLabelValues(credName, reason).Inc()
+	}
+	sort.Strings(names)
+	if misbehaving {
+		// Oversized or malformed headers point at a misbehaving backend
+		p.logger.Warn("Dropped upstream response headers exceeding response_headers limits or with invalid values",
+			"credential", credName, "headers", names)
+		return
+	}
+	p.logger.Debug("Stripped upstream response headers", "credential", credName, "headers", names)
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseHeaderPolicy_Filter(t *testing.T) {
+	src := http.Header{}
+	src.Set("Content-Type", "application/json")
+	src.Set("Content-Length", "42")
+	src.Set("Connection", "keep-alive")
+	src.Add("Set-Cookie", "session=abc")
+	src.Set("Alt-Svc", `h3=":443"`)
+	src.Set("X-Debug-Token", "abc")
+	src.Set("X-Request-Id", "req-1")
+	src["X-Injected"] = []string{"ok\r\nSet-Cookie: evil=1"}
+
+	policy := newResponseHeaderPolicy(config.ResponseHeadersConfig{Strip: []string{"x-debug-token"}, Allow: []string{"alt-svc"}})
+	kept, dropped := policy.filter(src)
+
+	assert.Equal(t, http.Header{
+		"Content-Type": {"application/json"},
+		"Alt-Svc":      {`h3=":443"`},
+		"X-Request-Id": {"req-1"},
+	}, kept)
+	assert.Equal(t, map[string]string{
+		"Set-Cookie":    "stripped",
+		"X-Debug-Token": "stripped",
+		"X-Injected":    "invalid",
+	}, dropped)
+}
+
+func TestResponseHeaderPolicy_Limits(t *testing.T) {
+	src := http.Header{}
+	for i := 0; i < 5; i++ {
+		src.Set(fmt.Sprintf("X-Header-%d", i), "value")
+	}
+
+	kept, dropped := newResponseHeaderPolicy(config.ResponseHeadersConfig{MaxCount: 3}).filter(src)
+	assert.Equal(t, []string{"X-Header-0", "X-Header-1", "X-Header-2"}, sortedKeys(kept), "headers are kept in name order")
+	assert.Equal(t, map[string]string{"X-Header-3": "limit", "X-Header-4": "limit"}, dropped)
+
+	// "X-Header-N: value\r\n" is 21 bytes
+	kept, _ = newResponseHeaderPolicy(config.ResponseHeadersConfig{MaxBytes: 50}).filter(src)
+	assert.Len(t, kept, 2)
+
+	src.Set("X-Huge", strings.Repeat("x", 100))
+	kept, dropped = newResponseHeaderPolicy(config.ResponseHeadersConfig{MaxBytes: 110}).filter(src)
+	assert.Equal(t, "limit", dropped["X-Huge"])
+	assert.Len(t, kept, 5, "smaller headers after an oversized one are still kept")
+
+	kept, _ = newResponseHeaderPolicy(config.ResponseHeadersConfig{MaxCount: -1, MaxBytes: -1}).filter(src)
+	assert.Len(t, kept, 6)
+}
+
+func TestProxyRequest_ResponseHeadersSanitized(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Set-Cookie", "upstream_session=secret")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		for i := 0; i < 20; i++ {
+			w.Header().Set(fmt.Sprintf("X-Padding-%02d", i), "x")
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	defer upstream.Close()
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("flaky", config.ProviderTypeOpenAI, upstream.URL, "sk").
+		WithMasterKey("master-key").
+		Build()
+	prx.responseHeaders = newResponseHeaderPolicy(config.ResponseHeadersConfig{MaxCount: 15})
+	limited := monitoring.ResponseHeadersDroppedTotal.WithLabelValues("flaky", "limit")
+	before := testutil.ToFloat64(limited)
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Values("Set-Cookie"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("X-Ratelimit-Remaining-Requests"), "dropped beyond max_count in name order")
+	assert.Positive(t, testutil.ToFloat64(limited)-before)
+}
+
+func sortedKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,12 +1,7 @@
 package proxy
 
 import (
-	"bytes"
-	"compress/flate"
-	"compress/gzip"
 	"encoding/json"
-	"io"
-	"strings"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
 )
@@ -36,23 +31,12 @@ func extractOpenAITotalTokens(payload []byte) int {
 	return openAIResp.Response.Usage.TotalTokens
 }
 
+// extractTokensFromStreamingChunk returns the usage.total_tokens reported by the SSE events of chunk
 func extractTokensFromStreamingChunk(chunk string) int {
-	// Look for usage information in streaming chunks
-	lines := strings.Split(chunk, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "data: ") {
-			jsonData := strings.TrimPrefix(line, "data: ")
-			if jsonData == "[DONE]" {
-				continue
-			}
-
-			tokens := extractOpenAITotalTokens([]byte(jsonData))
-			if tokens > 0 {
-				return tokens
-			}
-		}
-	}
-	return 0
+	var capture streamCapture
+	capture.observe([]byte(chunk))
+	capture.finish()
+	return capture.tokens
 }
 
 // extractMetadataFromBody extracts the model ID and session ID from the request body
@@ -139,45 +123,6 @@ func extractMetadataFromBody(body []byte) (string, bool, string, []byte) {
 	return model, stream, sessionID, modifiedBody
 }
 
-// decodeResponseBody decodes the response body based on Content-Encoding
-func decodeResponseBody(body []byte, encoding string) string {
-	lowerEncoding := strings.ToLower(encoding)
-
-	// Check if response is gzip-encoded
-	if strings.Contains(lowerEncoding, "gzip") {
-		reader, err := gzip.NewReader(bytes.NewReader(body))
-		if err != nil {
-			return string(body) // Return as-is if can't decode
-		}
-		defer func() {
-			_ = reader.Close()
-		}()
-
-		decoded, err := io.ReadAll(reader)
-		if err != nil {
-			return string(body) // Return as-is if can't read
-		}
-		return string(decoded)
-	}
-
-	// Check if response is deflate-encoded
-	if strings.Contains(lowerEncoding, "deflate") {
-		reader := flate.NewReader(bytes.NewReader(body))
-		defer func() {
-			_ = reader.Close()
-		}()
-
-		decoded, err := io.ReadAll(reader)
-		if err != nil {
-			return string(body) // Return as-is if can't read
-		}
-		return string(decoded)
-	}
-
-	// Return as plain text
-	return string(body)
-}
-
 // extractTokensFromResponse extracts total_tokens from the response body
 // Supports both OpenAI format (usage.total_tokens) and Vertex AI format (usageMetadata.totalTokenCount)
 func extractTokensFromResponse(body string, credType config.ProviderType) int {
@@ -8,7 +8,7 @@ import (
 // writeProxyResponse writes raw upstream proxy response to client.
 // Respects the client's Accept-Encoding header to compress the response appropriately.
 // Used by both primary proxy path and fallback retry path to avoid duplication.
-func (p *Proxy) writeProxyResponse(w http.ResponseWriter, resp *ProxyResponse, clientReq *http.Request) {
+func (p *Proxy) writeProxyResponse(w http.ResponseWriter, resp *ProxyResponse, clientReq *http.Request, credName string) {
 	if resp == nil {
 		return
 	}
@@ -17,8 +17,9 @@ func (p *Proxy) writeProxyResponse(w http.ResponseWriter, resp *ProxyResponse, c
 	acceptEncoding := clientReq.Header.Get("Accept-Encoding")
 	acceptedEncodings := ParseAcceptEncoding(acceptEncoding)
 	targetEncoding := SelectBestEncoding(acceptedEncodings)
+	log := p.credentialLogger(clientReq, credName)
 
-	p.logger.Debug("Proxy response encoding decision",
+	log.Debug("Proxy response encoding decision",
 		"accept_encoding_header", acceptEncoding,
 		"target_encoding", targetEncoding,
 		"body_size", len(resp.Body),
@@ -26,20 +27,24 @@ func (p *Proxy) writeProxyResponse(w http.ResponseWriter, resp *ProxyResponse, c
 
 	// Compress body if needed (Go's http.Client already decompressed upstream response)
 	responseBody := resp.Body
+	wrapError := resp.StatusCode >= 400 && p.upstreamErrors.Wrap
+	if wrapError {
+		responseBody = p.wrapUpstreamError(resp.StatusCode, resp.Body, log)
+	}
 	contentEncoding := ""
 
-	if targetEncoding != "identity" && len(resp.Body) > 0 {
-		compressedBody, usedEncoding, err := CompressBody(resp.Body, targetEncoding)
+	if targetEncoding != "identity" && len(responseBody) > 0 {
+		compressedBody, usedEncoding, err := CompressBody(responseBody, targetEncoding)
 		if err != nil {
-			p.logger.Warn("Failed to compress response body",
+			log.Warn("Failed to compress response body",
 				"encoding", targetEncoding,
 				"error", err,
 			)
 			// Continue with uncompressed body on error
 		} else {
-			p.logger.Debug("Response body compressed",
+			log.Debug("Response body compressed",
 				"encoding", usedEncoding,
-				"original_size", len(resp.Body),
+				"original_size", len(responseBody),
 				"compressed_size", len(compressedBody),
 			)
 			responseBody = compressedBody
@@ -47,19 +52,11 @@ func (p *Proxy) writeProxyResponse(w http.ResponseWriter, resp *ProxyResponse, c
 		}
 	}
 
-	// Copy response headers
-	for key, values := range resp.Headers {
-		if isHopByHopHeader(key) {
-			continue
-		}
-		// Skip Content-Length, Transfer-Encoding, and Content-Encoding
-		// We'll set Content-Encoding based on our compression, and Content-Length based on actual body size
-		if key == "Content-Length" || key == "Transfer-Encoding" || key == "Content-Encoding" {
-			continue
-		}
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
+	// Copy response headers (Content-Encoding is set based on our compression, and
+	// Content-Length based on actual body size)
+	p.copyResponseHeaders(w, resp.Headers, credName)
+	if wrapError {
+		w.Header().Set("Content-Type", "application/json")
 	}
 
 	// Set Content-Encoding if we compressed the response
@@ -71,9 +68,9 @@ func (p *Proxy) writeProxyResponse(w http.ResponseWriter, resp *ProxyResponse, c
 	w.WriteHeader(resp.StatusCode)
 	if _, err := w.Write(responseBody); err != nil {
 		if isClientDisconnectError(err) {
-			p.logger.Debug("Client disconnected during proxy response write", "error", err)
+			log.Debug("Client disconnected during proxy response write", "error", err)
 		} else {
-			p.logger.Error("Failed to write proxy response body", "error", err)
+			log.Error("Failed to write proxy response body", "error", err)
 		}
 	}
 }
@@ -90,49 +87,31 @@ func (p *Proxy) writeProxyStreamingResponseWithTokens(
 	if resp == nil || resp.StreamBody == nil {
 		return 0, nil
 	}
+	log := p.credentialLogger(clientReq, credName)
 	defer func() {
 		if closeErr := resp.StreamBody.Close(); closeErr != nil {
-			p.logger.Error("Failed to close proxy streaming response body", "error", closeErr)
+			log.Error("Failed to close proxy streaming response body", "error", closeErr)
 		}
 	}()
 
-	for key, values := range resp.Headers {
-		if isHopByHopHeader(key) {
-			continue
-		}
-		// Skip Content-Length, Transfer-Encoding, and Content-Encoding
-		// For streaming responses, we don't re-compress since it would break the stream protocol.
-		// We remove Content-Encoding from upstream since Go's http.Client already decompressed it.
-		if key == "Content-Length" || key == "Transfer-Encoding" || key == "Content-Encoding" {
-			continue
-		}
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
-	}
+	// For streaming responses, we don't re-compress since it would break the stream protocol.
+	// Content-Encoding is removed since Go's http.Client already decompressed the stream.
+	p.copyResponseHeaders(w, resp.Headers, credName)
 
 	w.WriteHeader(resp.StatusCode)
 
-	totalTokens := 0
-	onChunk := func(chunk []byte) {
-		tokens := extractTokensFromStreamingChunk(string(chunk))
-		if tokens > 0 {
-			totalTokens += tokens
-		}
-	}
-
+	var capture streamCapture
 	if _, ok := w.(http.Flusher); ok {
-		if err := p.streamToClient(w, resp.StreamBody, credName, onChunk, nil); err != nil {
-			return totalTokens, err
-		}
-		return totalTokens, nil
+		err := p.streamToClient(w, resp.StreamBody, log, capture.observe, nil)
+		capture.finish()
+		return capture.tokens, err
 	}
 
 	// Non-flushing fallback: copy as-is (token usage cannot be parsed reliably here).
 	if _, err := io.Copy(w, resp.StreamBody); err != nil {
-		return totalTokens, err
+		return 0, err
 	}
-	return totalTokens, nil
+	return 0, nil
 }
 
 // itoa avoids fmt.Sprintf for a hot path.
@@ -19,6 +19,7 @@ const (
 	RetryReasonServerErr RetryReason = "server_error"
 	RetryReasonAuthErr   RetryReason = "auth_error"
 	RetryReasonNetErr    RetryReason = "network_error"
+	RetryReasonFirstByte RetryReason = "first_byte_timeout" // No response byte within stream_first_byte_timeout
 )
 
 // TriedCredentialsKey is the context key for tracking attempted credentials
@@ -135,24 +136,31 @@ func (p *Proxy) TryFallbackProxy(
 	// Check attempt count - max 2 total attempts (primary + 1 fallback)
 	attemptCount, ctx := incrementAttempts(ctx)
 	if attemptCount >= MaxRetryAttempts {
-		p.logger.Warn("Max retry attempts reached, not attempting additional fallback",
+		logCtx.Logger().Warn("Max retry attempts reached, not attempting additional fallback",
 			"original_credential", originalCredName,
-			"model", modelID,
 			"attempt_count", attemptCount,
 			"max_attempts", MaxRetryAttempts,
 		)
 		return false, "max_retry_attempts_exceeded"
 	}
 
+	// The whole chain is bounded by request_deadline
+	if p.requestDeadlineExceeded(r) {
+		logCtx.Logger().Info("Request deadline reached, not attempting fallback",
+			"original_credential", originalCredName,
+			"request_deadline", p.requestDeadline,
+		)
+		return false, "request_deadline_exceeded"
+	}
+
 	// Get set of already-tried credentials from context
 	triedCreds := GetTried(ctx)
 
 	// Try to find a fallback proxy credential
 	fallbackCred, err := p.balancer.NextFallbackProxyForModel(modelID)
 	if err != nil {
-		p.logger.Debug("No fallback proxy available for retry",
+		logCtx.Logger().Debug("No fallback proxy available for retry",
 			"original_credential", originalCredName,
-			"model", modelID,
 			"original_status", originalStatus,
 			"reason", originalReason,
 		)
@@ -161,8 +169,7 @@ func (p *Proxy) TryFallbackProxy(
 
 	// Guard against nil credential (balancer returned no error but also no credential)
 	if fallbackCred == nil {
-		p.logger.Warn("Balancer returned nil credential without error",
-			"model", modelID,
+		logCtx.Logger().Warn("Balancer returned nil credential without error",
 			"original_credential", originalCredName,
 		)
 		return false, "no_fallback_available"
@@ -170,27 +177,22 @@ func (p *Proxy) TryFallbackProxy(
 
 	// Safety check: don't retry with the same credential
 	if fallbackCred.Name == originalCredName {
-		p.logger.Warn("Fallback credential is the same as original, skipping retry",
-			"credential", fallbackCred.Name,
-			"model", modelID,
-		)
+		logCtx.CredentialLogger(fallbackCred.Name).Warn("Fallback credential is the same as original, skipping retry")
 		return false, "fallback_is_same_credential"
 	}
 
 	// Check if fallback credential has already been tried in this request chain
 	if triedCreds[fallbackCred.Name] {
-		p.logger.Warn("Fallback credential already attempted, skipping to prevent circular retry",
+		logCtx.Logger().Warn("Fallback credential already attempted, skipping to prevent circular retry",
 			"fallback_credential", fallbackCred.Name,
 			"tried_credentials", formatTriedCreds(triedCreds),
-			"model", modelID,
 		)
 		return false, "credential_already_tried"
 	}
 
-	p.logger.Info("Retrying request on fallback proxy",
+	logCtx.Logger().Info("Retrying request on fallback proxy",
 		"original_credential", originalCredName,
 		"fallback_credential", fallbackCred.Name,
-		"model", modelID,
 		"original_status", originalStatus,
 		"retry_reason", originalReason,
 		"attempt_number", attemptCount+1,
@@ -208,20 +210,30 @@ func (p *Proxy) TryFallbackProxy(
 	jitter := time.Duration(rand.Intn(50)) * time.Millisecond
 	time.Sleep(jitter)
 
+	if logCtx != nil {
+		logCtx.RetryCount++
+	}
+
 	// Forward request to fallback proxy
 	proxyResp, err := p.forwardToProxy(w, r, modelID, fallbackCred, body, start)
 	if err != nil {
-		p.logger.Error("Fallback proxy request failed",
+		logCtx.Logger().Error("Fallback proxy request failed",
 			"fallback_credential", fallbackCred.Name,
 			"error", err,
 		)
 		return false, "fallback_request_failed"
 	}
 
+	// The fallback serves the response: headers written below name it
+	if logCtx != nil {
+		logCtx.Credential = fallbackCred
+		logCtx.FallbackUsed = true
+	}
+
 	if proxyResp.IsStreaming {
 		totalTokens, err := p.writeProxyStreamingResponseWithTokens(w, proxyResp, r, fallbackCred.Name)
 		if err != nil {
-			p.logger.Error("Failed to write fallback streaming proxy response",
+			logCtx.Logger().Error("Failed to write fallback streaming proxy response",
 				"fallback_credential", fallbackCred.Name,
 				"error", err,
 			)
@@ -232,30 +244,28 @@ func (p *Proxy) TryFallbackProxy(
 			if modelID != "" {
 				p.rateLimiter.ConsumeModelTokens(fallbackCred.Name, modelID, totalTokens)
 			}
-			p.logger.Debug("Fallback proxy streaming token usage recorded",
+			logCtx.Logger().Debug("Fallback proxy streaming token usage recorded",
 				"fallback_credential", fallbackCred.Name,
-				"model", modelID,
 				"tokens", totalTokens,
 			)
 		}
 	} else {
-		p.writeProxyResponse(w, proxyResp, r)
+		p.writeProxyResponse(w, proxyResp, r, fallbackCred.Name)
 		tokens := extractTokensFromResponse(string(proxyResp.Body), config.ProviderTypeOpenAI)
 		if tokens > 0 {
 			p.rateLimiter.ConsumeTokens(fallbackCred.Name, tokens)
 			if modelID != "" {
 				p.rateLimiter.ConsumeModelTokens(fallbackCred.Name, modelID, tokens)
 			}
-			p.logger.Debug("Fallback proxy token usage recorded",
+			logCtx.Logger().Debug("Fallback proxy token usage recorded",
 				"fallback_credential", fallbackCred.Name,
-				"model", modelID,
 				"tokens", tokens,
 			)
 		}
 	}
 
 	// Log that retry was completed
-	p.logger.Debug("Fallback proxy retry completed",
+	logCtx.Logger().Debug("Fallback proxy retry completed",
 		"fallback_credential", fallbackCred.Name,
 		"duration", time.Since(start),
 	)
@@ -273,9 +283,8 @@ func (p *Proxy) TryFallbackProxy(
 		logCtx.Logged = true
 
 		if err := p.logSpendToLiteLLMDB(logCtx); err != nil {
-			p.logger.Warn("Failed to queue fallback spend log",
+			logCtx.Logger().Warn("Failed to queue fallback spend log",
 				"error", err,
-				"request_id", logCtx.RequestID,
 				"fallback_credential", fallbackCred.Name,
 			)
 		}
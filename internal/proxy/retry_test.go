@@ -324,6 +324,34 @@ func TestTryFallbackProxy_Success(t *testing.T) {
 	assert.Equal(t, "gpt-4", respData["model"])
 }
 
+func TestTryFallbackProxy_RecordsRetryAndFallback(t *testing.T) {
+	fallbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = NewResponseBuilder().
+			WithStatus(http.StatusOK).
+			WithJSONBody(createMockChatCompletionResponse("chatcmpl-test-fallback", "gpt-4", "fallback ok")).
+			Write(w)
+	}))
+	defer fallbackServer.Close()
+
+	prx := NewTestProxyBuilder().
+		WithPrimaryAndFallback("http://primary.local", fallbackServer.URL).
+		Build()
+
+	body := `{"model":"gpt-4","messages":[{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer sk-master")
+	logCtx := &RequestLogContext{Request: req, StartTime: time.Now().UTC(), RetryCount: 1}
+
+	success, _ := prx.TryFallbackProxy(httptest.NewRecorder(), req, "gpt-4", "primary",
+		http.StatusTooManyRequests, RetryReasonRateLimit, []byte(body), logCtx.StartTime, logCtx)
+
+	require.True(t, success)
+	assert.Equal(t, 2, logCtx.RetryCount, "fallback attempt counts as a retry")
+	assert.True(t, logCtx.FallbackUsed)
+	require.NotNil(t, logCtx.Credential)
+	assert.Equal(t, "fallback", logCtx.Credential.Name)
+}
+
 func TestTryFallbackProxy_NoFallbackAvailable(t *testing.T) {
 	// Build proxy with only primary credential (no fallback)
 	prx := NewTestProxyBuilder().
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// authenticateSignedRequest authenticates a request signed by a parent router (inter_router_secret).
+// The body is read to verify its digest and restored for the rest of the pipeline.
+// A verified request is treated like a master key request, including spend logging.
+func (p *Proxy) authenticateSignedRequest(w http.ResponseWriter, r *http.Request, logCtx *RequestLogContext) bool {
+	maxBodyBytes := int64(p.maxBodySizeMB) * 1024 * 1024
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		logCtx.Logger().Error("Failed to read signed request body", "error", err)
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusBadRequest
+		logCtx.ErrorMsg = "Failed to read request body: " + err.Error()
+		WriteErrorBadRequest(w, "Failed to read request body")
+		return false
+	}
+	if int64(len(body)) > maxBodyBytes {
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusRequestEntityTooLarge
+		logCtx.ErrorMsg = "Request body too large"
+		WriteErrorTooLarge(w, "Request Entity Too Large")
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := p.routerVerifier.Verify(r, body, utils.NowUTC()); err != nil {
+		monitoring.InterRouterAuthFailures.WithLabelValues(signatureFailureReason(err)).Inc()
+		logCtx.Logger().Error("Inter-router signature rejected",
+			"error", err,
+			"remote_addr", r.RemoteAddr,
+			"path", r.URL.Path,
+		)
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusUnauthorized
+		logCtx.ErrorMsg = err.Error()
+		WriteErrorUnauthorized(w, "Invalid inter-router signature")
+		return false
+	}
+
+	logCtx.Token = p.masterKeys.Primary()
+	return true
+}
+
+// AuthorizeRouterRequest reports whether a bodiless request (GET /federation/limits) carries
+// a valid inter-router signature or a master key
+func (p *Proxy) AuthorizeRouterRequest(r *http.Request) bool {
+	if p.routerVerifier != nil && httputil.IsSignedRequest(r) {
+		if err := p.routerVerifier.Verify(r, nil, utils.NowUTC()); err != nil {
+			monitoring.InterRouterAuthFailures.WithLabelValues(signatureFailureReason(err)).Inc()
+			p.logger.Error("Inter-router signature rejected",
+				"error", err,
+				"remote_addr", r.RemoteAddr,
+				"path", r.URL.Path,
+			)
+			return false
+		}
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	_, ok := p.MatchMasterKey(token, r)
+	return ok
+}
+
+// signatureFailureReason maps a verification error to the inter-router auth failure metric label
+func signatureFailureReason(err error) string {
+	switch {
+	case errors.Is(err, httputil.ErrSignatureMissing):
+		return "missing"
+	case errors.Is(err, httputil.ErrSignatureExpired):
+		return "expired"
+	case errors.Is(err, httputil.ErrSignatureReplayed):
+		return "replayed"
+	default:
+		return "invalid"
+	}
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInterRouterSecret = "test-inter-router-secret-0123456789"
+
+func newSignedRequest(t *testing.T, secret, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	require.NoError(t, httputil.SignRequest(req, secret, []byte(body), time.Now()))
+	return req
+}
+
+func TestAuthenticateRequest_SignedRequest(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	prx.routerVerifier = httputil.NewRequestVerifier(testInterRouterSecret, 0)
+
+	body := `{"model":"gpt-4o","messages":[]}`
+	req := newSignedRequest(t, testInterRouterSecret, body)
+	signed := req.Clone(req.Context())
+
+	logCtx := &RequestLogContext{}
+	w := httptest.NewRecorder()
+	require.True(t, prx.authenticateRequest(w, req, logCtx, false))
+	assert.Equal(t, "sk-master", logCtx.Token, "signed requests are logged like master key requests")
+
+	// Body is restored for the rest of the pipeline
+	restored, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(restored))
+
+	// Same signed request again is a replay
+	before := testutil.ToFloat64(monitoring.InterRouterAuthFailures.WithLabelValues("replayed"))
+	signed.Body = io.NopCloser(strings.NewReader(body))
+	w = httptest.NewRecorder()
+	assert.False(t, prx.authenticateRequest(w, signed, &RequestLogContext{}, false))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(monitoring.InterRouterAuthFailures.WithLabelValues("replayed")))
+}
+
+func TestAuthenticateRequest_SignedRequestRejected(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	prx.routerVerifier = httputil.NewRequestVerifier(testInterRouterSecret, 0)
+
+	t.Run("wrong secret", func(t *testing.T) {
+		req := newSignedRequest(t, "another-secret-another-secret-000", `{"model":"gpt-4o"}`)
+		w := httptest.NewRecorder()
+		assert.False(t, prx.authenticateRequest(w, req, &RequestLogContext{}, false))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := newSignedRequest(t, testInterRouterSecret, `{"model":"gpt-4o"}`)
+		req.Body = io.NopCloser(strings.NewReader(`{"model":"gpt-4o","n":100}`))
+		w := httptest.NewRecorder()
+		assert.False(t, prx.authenticateRequest(w, req, &RequestLogContext{}, false))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("valid signature does not bypass when disabled", func(t *testing.T) {
+		disabled := NewTestProxyBuilder().Build()
+		req := newSignedRequest(t, testInterRouterSecret, `{"model":"gpt-4o"}`)
+		w := httptest.NewRecorder()
+		assert.False(t, disabled.authenticateRequest(w, req, &RequestLogContext{}, false))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestExecuteProxyRequest_HMACSigned(t *testing.T) {
+	verifier := httputil.NewRequestVerifier(testInterRouterSecret, 0)
+	var verifyErr error
+	var receivedAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedAuth = r.Header.Get("Authorization")
+		verifyErr = verifier.Verify(r, body, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	cred := config.CredentialConfig{
+		Name:       "downstream",
+		Type:       config.ProviderTypeProxy,
+		BaseURL:    upstream.URL,
+		APIKey:     "downstream-master-key",
+		HMACSecret: testInterRouterSecret,
+		RPM:        100,
+	}
+	prx := NewTestProxyBuilder().WithCredentials(cred).Build()
+
+	body := []byte(`{"model":"gpt-4o","messages":[]}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer sk-client")
+	// Signature headers from a previous hop must not be forwarded
+	req.Header.Set(httputil.RouterSignatureHeader, "stale")
+
+	resp, err := prx.executeProxyRequest(req, &cred, "gpt-4o", body, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, verifyErr)
+	assert.Empty(t, receivedAuth, "no bare key is forwarded to a signed downstream router")
+}
@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+)
+
+// Routing override headers steer credential selection of a single request (evals, debugging).
+// They are opt-in (routing_overrides) and limited to selected keys; other keys sending them
+// are rejected with 403 Forbidden.
+const (
+	PreferCredentialHeader = "X-Router-Prefer-Credential" // Credential tried first
+	ExcludeProvidersHeader = "X-Router-Exclude-Providers" // Comma-separated credential types never selected
+	RequireRegionHeader    = "X-Router-Require-Region"    // Comma-separated regions the credential must be in
+)
+
+// routingOverridePolicy decides which requests may send routing override headers
+type routingOverridePolicy struct {
+	keyMatcher
+}
+
+// newRoutingOverridePolicy creates a policy for the given key aliases or team IDs ("*" = all keys).
+// Requests authenticated with the master key may always send the headers.
+func newRoutingOverridePolicy(masterKeys *masterkey.Ring, keys []string) *routingOverridePolicy {
+	return &routingOverridePolicy{keyMatcher: newKeyMatcher(masterKeys, keys)}
+}
+
+// allows reports whether the request's API key may override routing; false on a nil policy
+func (o *routingOverridePolicy) allows(logCtx *RequestLogContext) bool {
+	return o != nil && o.keyMatcher.allows(logCtx)
+}
+
+// routingOverrides are the validated override headers of a request
+type routingOverrides struct {
+	prefer  string          // Preferred credential name ("" = none)
+	exclude map[string]bool // Credentials ruled out by X-Router-Exclude-Providers and X-Router-Require-Region
+}
+
+// hasRoutingOverrides reports whether the request sends any routing override header
+func hasRoutingOverrides(h http.Header) bool {
+	return h.Get(PreferCredentialHeader) != "" || h.Get(ExcludeProvidersHeader) != "" || h.Get(RequireRegionHeader) != ""
+}
+
+// parseRoutingOverrides validates the override headers against the configured credentials
+// and returns the credentials they rule out. The returned message explains a validation error.
+func parseRoutingOverrides(h http.Header, creds []config.CredentialConfig) (*routingOverrides, string) {
+	overrides := &routingOverrides{
+		prefer:  strings.TrimSpace(h.Get(PreferCredentialHeader)),
+		exclude: make(map[string]bool),
+	}
+
+	excludedTypes := make(map[config.ProviderType]bool)
+	for _, name := range splitHeaderList(h.Get(ExcludeProvidersHeader)) {
+		providerType := config.ProviderType(strings.ToLower(name))
+		if !providerType.IsValid() {
+			return nil, "Unknown provider type in " + ExcludeProvidersHeader + ": " + name
+		}
+		excludedTypes[providerType] = true
+	}
+	regions := make(map[string]bool)
+	for _, region := range splitHeaderList(h.Get(RequireRegionHeader)) {
+		regions[strings.ToLower(region)] = true
+	}
+
+	preferFound := overrides.prefer == ""
+	for i := range creds {
+		cred := &creds[i]
+		if cred.Name == overrides.prefer {
+			preferFound = true
+		}
+		if excludedTypes[cred.Type] || (len(regions) > 0 && !regions[strings.ToLower(credentialRegion(cred))]) {
+			overrides.exclude[cred.Name] = true
+		}
+	}
+	if !preferFound {
+		return nil, "Unknown credential in " + PreferCredentialHeader + ": " + overrides.prefer
+	}
+	if overrides.exclude[overrides.prefer] {
+		return nil, "Credential in " + PreferCredentialHeader + " is excluded by " +
+			ExcludeProvidersHeader + " or " + RequireRegionHeader + ": " + overrides.prefer
+	}
+	return overrides, ""
+}
+
+// applyRoutingOverrides validates the request's routing override headers and marks the
+// credentials they rule out as tried, so the initial selection and all retries skip them.
+// Returns false after writing 403 (key not allowed) or 400 (invalid header).
+func (p *Proxy) applyRoutingOverrides(w http.ResponseWriter, r *http.Request, logCtx *RequestLogContext) (*routingOverrides, bool) {
+	if !hasRoutingOverrides(r.Header) {
+		return nil, true
+	}
+	if !p.routingOverrides.allows(logCtx) {
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusForbidden
+		logCtx.ErrorMsg = "Routing override headers are not allowed for this key"
+		WriteErrorForbidden(w, logCtx.ErrorMsg)
+		return nil, false
+	}
+
+	// Credentials already ruled out (outside the request's tenant) are unknown to the headers
+	triedCreds := GetTried(r.Context())
+	var creds []config.CredentialConfig
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if !triedCreds[cred.Name] {
+			creds = append(creds, cred)
+		}
+	}
+
+	overrides, errMsg := parseRoutingOverrides(r.Header, creds)
+	if overrides == nil {
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusBadRequest
+		logCtx.ErrorMsg = errMsg
+		WriteErrorBadRequest(w, errMsg)
+		return nil, false
+	}
+
+	for name := range overrides.exclude {
+		triedCreds[name] = true
+	}
+	logCtx.Logger().Debug("Applying routing overrides",
+		"prefer_credential", overrides.prefer,
+		"excluded_credentials", formatTriedCreds(overrides.exclude),
+	)
+	return overrides, true
+}
+
+// selectPreferredCredential returns the preferred credential of the overrides if it serves the
+// model and is within its limits. Returns nil otherwise; the request is then routed as usual.
+func (p *Proxy) selectPreferredCredential(overrides *routingOverrides, modelID string, share float64, logCtx *RequestLogContext) *config.CredentialConfig {
+	if overrides == nil || overrides.prefer == "" {
+		return nil
+	}
+	others := make(map[string]bool)
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if cred.Name != overrides.prefer {
+			others[cred.Name] = true
+		}
+	}
+	for _, fallback := range []bool{false, true} {
+		cred, err := p.balancer.Select(modelID, balancer.SelectOptions{Fallback: fallback, Exclude: others, Share: share})
+		if err == nil {
+			return cred
+		}
+	}
+	logCtx.CredentialLogger(overrides.prefer).Debug("Preferred credential unavailable, routing as usual")
+	return nil
+}
+
+// credentialRegion returns the region a credential serves requests from
+// (region, or location for Vertex AI credentials)
+func credentialRegion(cred *config.CredentialConfig) string {
+	if cred.Region != "" {
+		return cred.Region
+	}
+	return cred.Location
+}
+
+// splitHeaderList splits a comma-separated header value, dropping empty entries
+func splitHeaderList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
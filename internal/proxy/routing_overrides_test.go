@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_RoutingOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		wantCode int
+		wantHits []int32
+	}{
+		{name: "prefer credential", headers: map[string]string{PreferCredentialHeader: "oai3"}, wantCode: http.StatusOK, wantHits: []int32{0, 0, 4}},
+		{name: "require region", headers: map[string]string{RequireRegionHeader: "EU-West-1"}, wantCode: http.StatusOK, wantHits: []int32{4, 0, 0}},
+		{name: "exclude all providers", headers: map[string]string{ExcludeProvidersHeader: "anthropic, openai"}, wantCode: http.StatusTooManyRequests, wantHits: []int32{0, 0, 0}},
+		{name: "unknown credential", headers: map[string]string{PreferCredentialHeader: "missing"}, wantCode: http.StatusBadRequest, wantHits: []int32{0, 0, 0}},
+		{name: "unknown provider", headers: map[string]string{ExcludeProvidersHeader: "azure"}, wantCode: http.StatusBadRequest, wantHits: []int32{0, 0, 0}},
+		{name: "preferred credential excluded", headers: map[string]string{PreferCredentialHeader: "oai2", RequireRegionHeader: "eu-west-1"}, wantCode: http.StatusBadRequest, wantHits: []int32{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []*int32{new(int32), new(int32), new(int32)}
+			regions := []string{"eu-west-1", "us-east-1", "us-east-1"}
+			prx := newChatProxy(t, calls, func(i int, cred *config.CredentialConfig) { cred.Region = regions[i] })
+			prx.routingOverrides = newRoutingOverridePolicy(prx.masterKeys, nil)
+
+			for i := 0; i < 4; i++ {
+				w := sendChatRequest(prx, "master-key", tt.headers)
+				require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			}
+			for i, want := range tt.wantHits {
+				assert.Equal(t, want, atomic.LoadInt32(calls[i]), "credential oai%d", i+1)
+			}
+		})
+	}
+}
+
+func TestProxyRequest_RoutingOverridesNotAllowed(t *testing.T) {
+	calls := []*int32{new(int32), new(int32)}
+	prx := newChatProxy(t, calls, nil)
+
+	w := sendChatRequest(prx, "master-key", map[string]string{PreferCredentialHeader: "oai1"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 0, hitCredentials(calls))
+
+	// Requests without override headers are unaffected
+	w = sendChatRequest(prx, "master-key", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRoutingOverridePolicy_Allows(t *testing.T) {
+	ring := masterkey.NewRing("master-key", nil, 0)
+	policy := newRoutingOverridePolicy(ring, []string{"evals"})
+
+	assert.True(t, policy.allows(&RequestLogContext{Token: "master-key"}))
+	assert.True(t, policy.allows(&RequestLogContext{Token: "sk-1", TokenInfo: &models.TokenInfo{KeyAlias: "evals"}}))
+	assert.True(t, policy.allows(&RequestLogContext{Token: "sk-2", TokenInfo: &models.TokenInfo{TeamID: "evals"}}))
+	assert.False(t, policy.allows(&RequestLogContext{Token: "sk-3", TokenInfo: &models.TokenInfo{KeyAlias: "app"}}))
+	assert.False(t, policy.allows(&RequestLogContext{Token: "sk-4"}))
+
+	all := newRoutingOverridePolicy(ring, []string{AllKeys})
+	assert.True(t, all.allows(&RequestLogContext{Token: "sk-4"}))
+
+	var disabled *routingOverridePolicy
+	assert.False(t, disabled.allows(&RequestLogContext{Token: "master-key"}))
+}
+
+func TestParseRoutingOverrides_VertexLocation(t *testing.T) {
+	creds := []config.CredentialConfig{
+		{Name: "vertex-eu", Type: config.ProviderTypeVertexAI, Location: "europe-west4"},
+		{Name: "vertex-us", Type: config.ProviderTypeVertexAI, Location: "us-central1"},
+		{Name: "oai", Type: config.ProviderTypeOpenAI, Region: "europe-west4"},
+	}
+	h := http.Header{}
+	h.Set(RequireRegionHeader, "europe-west4")
+	h.Set(ExcludeProvidersHeader, "openai")
+
+	overrides, errMsg := parseRoutingOverrides(h, creds)
+	require.Empty(t, errMsg)
+	assert.Equal(t, map[string]bool{"vertex-us": true, "oai": true}, overrides.exclude)
+}
+
+func TestIsRoutingOverrideHeader(t *testing.T) {
+	assert.True(t, isRoutingOverrideHeader("X-Router-Prefer-Credential"))
+	assert.True(t, isRoutingOverrideHeader(ExcludeProvidersHeader))
+	assert.True(t, isRoutingOverrideHeader("x-router-require-region"))
+	assert.True(t, isRoutingOverrideHeader(RoutingSeedHeader))
+	assert.False(t, isRoutingOverrideHeader(AttributionCredentialHeader))
+}
@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// RoutingSeedHeader makes credential selection deterministic: requests with the same seed
+// are routed to the same credential while it stays available
+const RoutingSeedHeader = "X-Router-Seed"
+
+// routingSeed returns the seed for deterministic credential selection: the X-Router-Seed
+// header, or a hash of the request body when server.deterministic_routing is enabled.
+// Returns "" for regular round-robin selection.
+func (p *Proxy) routingSeed(r *http.Request, body []byte) string {
+	if seed := strings.TrimSpace(r.Header.Get(RoutingSeedHeader)); seed != "" {
+		return seed
+	}
+	if !p.deterministicRoute {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
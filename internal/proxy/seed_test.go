@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const seedRequest = `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`
+
+// newChatProxy creates a proxy with one OpenAI credential per call counter, named oai1, oai2, ...
+// configure, if set, adjusts credential i before the proxy is built.
+func newChatProxy(t *testing.T, calls []*int32, configure func(i int, cred *config.CredentialConfig)) *Proxy {
+	t.Helper()
+	var credentials []config.CredentialConfig
+	for i, c := range calls {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(c, 1)
+			for key := range r.Header {
+				assert.False(t, isRoutingOverrideHeader(key), "%s is not forwarded", key)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+		}))
+		t.Cleanup(upstream.Close)
+		cred := config.CredentialConfig{
+			Name: fmt.Sprintf("oai%d", i+1), Type: config.ProviderTypeOpenAI, BaseURL: upstream.URL, APIKey: "sk", RPM: 100, TPM: 100000,
+		}
+		if configure != nil {
+			configure(i, &cred)
+		}
+		credentials = append(credentials, cred)
+	}
+	return NewTestProxyBuilder().
+		WithCredentials(credentials...).
+		WithMasterKey("master-key").
+		Build()
+}
+
+// sendChatRequest sends seedRequest authenticated with token and the extra headers
+func sendChatRequest(prx *Proxy, token string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+	req.Header.Set("Authorization", "Bearer "+token)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+	return w
+}
+
+func sendSeedRequest(t *testing.T, prx *Proxy, seed string) {
+	t.Helper()
+	var headers map[string]string
+	if seed != "" {
+		headers = map[string]string{RoutingSeedHeader: seed}
+	}
+	w := sendChatRequest(prx, "master-key", headers)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func hitCredentials(calls []*int32) int {
+	hit := 0
+	for _, c := range calls {
+		if atomic.LoadInt32(c) > 0 {
+			hit++
+		}
+	}
+	return hit
+}
+
+func TestProxyRequest_SeedHeaderIsDeterministic(t *testing.T) {
+	calls := []*int32{new(int32), new(int32), new(int32)}
+	prx := newChatProxy(t, calls, nil)
+
+	for i := 0; i < 6; i++ {
+		sendSeedRequest(t, prx, "eval-run-7")
+	}
+	assert.Equal(t, 1, hitCredentials(calls), "all seeded requests go to one credential")
+
+	// Without a seed, round-robin spreads the requests
+	for i := 0; i < 3; i++ {
+		sendSeedRequest(t, prx, "")
+	}
+	assert.Equal(t, 3, hitCredentials(calls))
+}
+
+func TestProxyRequest_DeterministicRoutingUsesBodyHash(t *testing.T) {
+	calls := []*int32{new(int32), new(int32), new(int32)}
+	prx := newChatProxy(t, calls, nil)
+	prx.deterministicRoute = true
+
+	for i := 0; i < 6; i++ {
+		sendSeedRequest(t, prx, "")
+	}
+	assert.Equal(t, 1, hitCredentials(calls), "identical bodies go to one credential")
+}
+
+func TestRoutingSeed(t *testing.T) {
+	prx := &Proxy{}
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	assert.Empty(t, prx.routingSeed(req, []byte(seedRequest)))
+
+	req.Header.Set(RoutingSeedHeader, " abc ")
+	assert.Equal(t, "abc", prx.routingSeed(req, []byte(seedRequest)))
+
+	prx.deterministicRoute = true
+	req.Header.Del(RoutingSeedHeader)
+	seed := prx.routingSeed(req, []byte(seedRequest))
+	assert.Len(t, seed, 64)
+	assert.Equal(t, seed, prx.routingSeed(req, []byte(seedRequest)))
+	assert.NotEqual(t, seed, prx.routingSeed(req, []byte(`{"model":"gpt-4o"}`)))
+}
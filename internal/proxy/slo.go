@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// recordSLO counts a finished request towards the availability and latency SLOs
+// (monitoring.slo). Requests that wrote nothing (e.g. panics) count as 500; latency is
+// the time to the first response byte, or the total duration without a body.
+func (p *Proxy) recordSLO(cw *credentialHeaderWriter, logCtx *RequestLogContext) {
+	if p.sloTracker == nil {
+		return
+	}
+	statusCode := cw.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	end := logCtx.FirstByteTime
+	if end.IsZero() {
+		end = utils.NowUTC()
+	}
+	p.sloTracker.Record(statusCode, end.Sub(logCtx.StartTime))
+}
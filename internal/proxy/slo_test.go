@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_RecordsSLO(t *testing.T) {
+	prx := newChatProxy(t, []*int32{new(int32)}, nil)
+	prx.sloTracker = monitoring.NewSLOTracker(config.SLOConfig{
+		Enabled:          true,
+		Availability:     99,
+		LatencyThreshold: time.Nanosecond,
+		LatencyTarget:    50,
+		Alerts:           []config.SLOAlertConfig{{Name: "proxy-test", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 1}},
+	}, testhelpers.NewTestLogger())
+
+	send := func(token string) int {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(seedRequest))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, send("master-key"))
+	require.Equal(t, http.StatusUnauthorized, send("wrong-key"))
+
+	// Both requests are slower than 1ns: the latency budget burns at 1 / (1 - 0.5) = 2
+	alerts := prx.sloTracker.Evaluate()
+	require.Len(t, alerts, 1, "client errors do not spend the availability budget")
+	assert.Equal(t, monitoring.SLOLatency, alerts[0].SLO)
+	assert.InDelta(t, 2, alerts[0].BurnRate, 0.001)
+	assert.Zero(t, testutil.ToFloat64(monitoring.SLOBurnRate.WithLabelValues(monitoring.SLOAvailability, "1h")))
+}
+
+func TestCredentialHeaderWriter_RecordsStatusCode(t *testing.T) {
+	cw := newCredentialHeaderWriter(httptest.NewRecorder(), &RequestLogContext{}, nil, nil, nil)
+	_, _ = cw.Write([]byte("ok"))
+	cw.WriteHeader(http.StatusBadGateway)
+	assert.Equal(t, http.StatusOK, cw.statusCode, "the first status sent to the client is kept")
+}
@@ -40,6 +40,7 @@ type StreamUsageInfo struct {
 	ReasoningTokens     int // Reasoning/thoughts tokens (output)
 	CacheCreationTokens int // Anthropic: tokens created for cache (billed at different rate)
 	CacheReadTokens     int // Anthropic: tokens read from cache (billed at cheaper rate)
+	WebSearchRequests   int // Anthropic: server-side web searches (billed per query)
 }
 
 // StreamUsageExtractor provides a provider-agnostic interface for extracting
@@ -95,6 +96,9 @@ func (o *openAIStreamUsageExtractor) extractChatCompletionUsage(payload []byte)
 				AudioTokens     int `json:"audio_tokens,omitempty"`
 				ReasoningTokens int `json:"reasoning_tokens,omitempty"`
 			} `json:"completion_tokens_details,omitempty"`
+			ServerToolUse struct {
+				WebSearchRequests int `json:"web_search_requests,omitempty"`
+			} `json:"server_tool_use,omitempty"`
 		} `json:"usage"`
 	}
 
@@ -113,6 +117,7 @@ func (o *openAIStreamUsageExtractor) extractChatCompletionUsage(payload []byte)
 		AudioInputTokens:  data.Usage.PromptTokensDetails.AudioTokens,
 		AudioOutputTokens: data.Usage.CompletionTokensDetails.AudioTokens,
 		ReasoningTokens:   data.Usage.CompletionTokensDetails.ReasoningTokens,
+		WebSearchRequests: data.Usage.ServerToolUse.WebSearchRequests,
 	}
 }
 
@@ -265,16 +270,15 @@ func getStreamUsageExtractor(providerName string) StreamUsageExtractor {
 }
 
 func IsStreamingResponse(resp *http.Response) bool {
-	contentType := resp.Header.Get("Content-Type")
-	return strings.Contains(contentType, "text/event-stream") ||
-		strings.Contains(contentType, "application/stream+json") ||
-		strings.Contains(contentType, "application/vnd.amazon.eventstream")
+	return isStreamingContentType(resp.Header.Get("Content-Type"))
 }
 
 type streamTransformer func(io.Reader, string, io.Writer) error
 
 func (p *Proxy) handleVertexStreaming(w http.ResponseWriter, resp *http.Response, credName, modelID string, logCtx *RequestLogContext) error {
-	conv := converter.New(config.ProviderTypeVertexAI, converter.RequestMode{ModelID: modelID, IsStreaming: true})
+	conv := converter.New(config.ProviderTypeVertexAI, converter.RequestMode{
+		ModelID: modelID, IsStreaming: true, PostProcess: p.postProcess, Logger: logCtx.CredentialLogger(credName),
+	})
 	transformer := func(r io.Reader, id string, w io.Writer) error {
 		return conv.StreamTo(r, w)
 	}
@@ -282,7 +286,9 @@ func (p *Proxy) handleVertexStreaming(w http.ResponseWriter, resp *http.Response
 }
 
 func (p *Proxy) handleAnthropicStreaming(w http.ResponseWriter, resp *http.Response, credName, modelID string, logCtx *RequestLogContext) error {
-	conv := converter.New(config.ProviderTypeAnthropic, converter.RequestMode{ModelID: modelID, IsStreaming: true})
+	conv := converter.New(config.ProviderTypeAnthropic, converter.RequestMode{
+		ModelID: modelID, IsStreaming: true, PostProcess: p.postProcess, Logger: logCtx.CredentialLogger(credName),
+	})
 	transformer := func(r io.Reader, id string, w io.Writer) error {
 		return conv.StreamTo(r, w)
 	}
@@ -290,32 +296,23 @@ func (p *Proxy) handleAnthropicStreaming(w http.ResponseWriter, resp *http.Respo
 }
 
 func (p *Proxy) handleBedrockStreaming(w http.ResponseWriter, resp *http.Response, credName, modelID string, logCtx *RequestLogContext) error {
-	conv := converter.New(config.ProviderTypeBedrock, converter.RequestMode{ModelID: modelID, IsStreaming: true})
+	conv := converter.New(config.ProviderTypeBedrock, converter.RequestMode{
+		ModelID: modelID, IsStreaming: true, PostProcess: p.postProcess, Logger: logCtx.CredentialLogger(credName),
+	})
 	transformer := func(r io.Reader, id string, w io.Writer) error {
 		return conv.StreamTo(r, w)
 	}
 	return p.handleTransformedStreaming(w, resp, credName, modelID, "Bedrock", transformer, logCtx)
 }
 
+// tokenCapturingWriter feeds the transformed stream written to writer into a streamCapture
 type tokenCapturingWriter struct {
 	writer  io.Writer
-	tokens  *int
-	logger  *slog.Logger
-	onChunk func([]byte) // Callback invoked for each chunk (optional, for capturing last chunk)
+	capture *streamCapture
 }
 
 func (tcw *tokenCapturingWriter) Write(p []byte) (n int, err error) {
-	// Extract tokens from the data being written
-	tokens := extractTokensFromStreamingChunk(string(p))
-	if tokens > 0 {
-		*tcw.tokens += tokens
-	}
-
-	// Invoke callback if provided (used to capture last chunk for usage extraction)
-	if tcw.onChunk != nil {
-		tcw.onChunk(p)
-	}
-
+	tcw.capture.observe(p)
 	return tcw.writer.Write(p)
 }
 
@@ -328,124 +325,104 @@ func (p *Proxy) handleTransformedStreaming(
 	transformFunc streamTransformer,
 	logCtx *RequestLogContext,
 ) error {
-	p.logger.Debug("Starting streaming response", "provider", providerName, "credential", credName)
+	logCtx.CredentialLogger(credName).Debug("Starting streaming response", "provider", providerName)
 
 	pr, pw := io.Pipe()
 	defer func() {
 		_ = pr.Close()
 	}()
-	var totalTokens int
 
-	// Capture last chunk for usage extraction (Solution 3: Hybrid approach)
-	var lastChunk []byte
+	// Capture tokens and the last usage event (hybrid approach) with constant memory
+	capture := &streamCapture{filter: &contentFilterCounter{model: modelID, credName: credName}}
 
 	// WaitGroup ensures the transform goroutine completes before we read
-	// lastChunk and totalTokens, preventing a data race.
+	// the capture, preventing a data race.
 	var wg sync.WaitGroup
 	wg.Add(1)
-	chunkCount := 0
 	go func() {
 		defer wg.Done()
-		err := transformFunc(resp.Body, modelID, &tokenCapturingWriter{
-			writer: pw,
-			tokens: &totalTokens,
-			logger: p.logger,
-			onChunk: func(chunk []byte) {
-				chunkCount++
-				// Store each chunk, keeping only the last one
-				// This allows us to extract usage info that typically appears in final chunks
-				lastChunk = make([]byte, len(chunk))
-				copy(lastChunk, chunk)
-			},
-		})
+		err := transformFunc(resp.Body, modelID, &tokenCapturingWriter{writer: pw, capture: capture})
+		capture.finish()
 		if err != nil {
-			p.logger.Error("Transform goroutine error",
-				"provider", providerName, "error", err, "chunks_written", chunkCount)
+			logCtx.Logger().Error("Transform goroutine error",
+				"provider", providerName, "error", err, "chunks_written", capture.chunks)
 			_ = pw.CloseWithError(fmt.Errorf("%s transform: %w", providerName, err))
 		} else {
-			p.logger.Debug("Transform goroutine completed OK",
-				"provider", providerName, "chunks_written", chunkCount, "total_tokens", totalTokens)
+			logCtx.Logger().Debug("Transform goroutine completed OK",
+				"provider", providerName, "chunks_written", capture.chunks, "total_tokens", capture.tokens)
 			_ = pw.Close()
 		}
 	}()
 
-	if err := p.streamToClient(w, pr, credName, nil, func() { _ = pr.Close() }); err != nil {
-		p.logger.Error("streamToClient error in handleTransformedStreaming",
+	if err := p.streamToClient(w, pr, logCtx.CredentialLogger(credName), nil, func() { _ = pr.Close() }); err != nil {
+		logCtx.Logger().Error("streamToClient error in handleTransformedStreaming",
 			"provider", providerName, "error", err)
 		wg.Wait()
 		return err
 	}
 	wg.Wait()
 
-	p.logger.Debug("handleTransformedStreaming completed",
-		"provider", providerName, "total_tokens", totalTokens,
-		"chunks_written", chunkCount, "last_chunk_len", len(lastChunk))
+	logCtx.Logger().Debug("handleTransformedStreaming completed",
+		"provider", providerName, "total_tokens", capture.tokens,
+		"chunks_written", capture.chunks, "last_usage_len", len(capture.lastUsage))
 
-	if totalTokens > 0 {
-		p.rateLimiter.ConsumeTokens(credName, totalTokens)
+	if tpmTokens := streamTPMTokens(capture.tokens, logCtx); tpmTokens > 0 {
+		p.rateLimiter.ConsumeTokens(credName, tpmTokens)
 		if modelID != "" {
-			p.rateLimiter.ConsumeModelTokens(credName, modelID, totalTokens)
+			p.rateLimiter.ConsumeModelTokens(credName, modelID, tpmTokens)
 		}
-		p.logger.Debug("Streaming token usage recorded", "credential", credName, "model", modelID, "tokens", totalTokens)
+		logCtx.CredentialLogger(credName).Debug("Streaming token usage recorded", "tokens", tpmTokens)
 	}
 
-	p.finalizeStreamingLog(logCtx, totalTokens, lastChunk, providerName, resp.StatusCode)
+	p.finalizeStreamingLog(logCtx, capture.tokens, capture.lastUsage, providerName, resp.StatusCode)
 
-	p.logger.Debug("Streaming response completed", "provider", providerName, "credential", credName)
+	logCtx.CredentialLogger(credName).Debug("Streaming response completed", "provider", providerName)
 	return nil
 }
 
 func (p *Proxy) handleStreamingWithTokens(w http.ResponseWriter, resp *http.Response, credName, modelID string, logCtx *RequestLogContext) error {
-	p.logger.Debug("Starting streaming response with token tracking (passthrough)",
-		"credential", credName, "model", modelID,
+	logCtx.CredentialLogger(credName).Debug("Starting streaming response with token tracking (passthrough)",
 		"content_type", resp.Header.Get("Content-Type"))
 
-	var totalTokens int
-	chunkCount := 0
-
-	// Capture last chunk for usage extraction (Solution 3: Hybrid approach)
-	var lastChunk []byte
-
-	onChunk := func(chunk []byte) {
-		chunkCount++
-		tokens := extractTokensFromStreamingChunk(string(chunk))
-		if tokens > 0 {
-			totalTokens += tokens
-		}
-
-		// Store each chunk, keeping only the last one
-		// This allows us to extract usage info that typically appears in final chunks
-		lastChunk = make([]byte, len(chunk))
-		copy(lastChunk, chunk)
-	}
+	// Capture tokens and the last usage event (hybrid approach) with constant memory
+	capture := &streamCapture{filter: &contentFilterCounter{model: modelID, credName: credName}}
 
-	if err := p.streamToClient(w, resp.Body, credName, onChunk, nil); err != nil {
-		p.logger.Error("streamToClient error in handleStreamingWithTokens",
-			"credential", credName, "error", err, "chunks_received", chunkCount)
+	if err := p.streamToClient(w, resp.Body, logCtx.CredentialLogger(credName), capture.observe, nil); err != nil {
+		logCtx.CredentialLogger(credName).Error("streamToClient error in handleStreamingWithTokens",
+			"error", err, "chunks_received", capture.chunks)
 		return err
 	}
+	capture.finish()
 
-	p.logger.Debug("handleStreamingWithTokens completed",
-		"credential", credName, "model", modelID,
-		"chunks_received", chunkCount, "total_tokens", totalTokens,
-		"last_chunk_len", len(lastChunk))
+	logCtx.CredentialLogger(credName).Debug("handleStreamingWithTokens completed",
+		"chunks_received", capture.chunks, "total_tokens", capture.tokens,
+		"last_usage_len", len(capture.lastUsage))
 
-	if totalTokens > 0 {
-		p.rateLimiter.ConsumeTokens(credName, totalTokens)
+	if tpmTokens := streamTPMTokens(capture.tokens, logCtx); tpmTokens > 0 {
+		p.rateLimiter.ConsumeTokens(credName, tpmTokens)
 		if modelID != "" {
-			p.rateLimiter.ConsumeModelTokens(credName, modelID, totalTokens)
+			p.rateLimiter.ConsumeModelTokens(credName, modelID, tpmTokens)
 		}
-		p.logger.Debug("Streaming token usage recorded", "credential", credName, "model", modelID, "tokens", totalTokens)
+		logCtx.CredentialLogger(credName).Debug("Streaming token usage recorded", "tokens", tpmTokens)
 	}
 
-	p.finalizeStreamingLog(logCtx, totalTokens, lastChunk, "openai", resp.StatusCode)
+	p.finalizeStreamingLog(logCtx, capture.tokens, capture.lastUsage, "openai", resp.StatusCode)
 
-	p.logger.Debug("Streaming response completed", "credential", credName)
+	logCtx.CredentialLogger(credName).Debug("Streaming response completed")
 	return nil
 }
 
-// finalizeStreamingLog extracts usage info from the last streaming chunk and logs spend to LiteLLM DB.
-func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int, lastChunk []byte, providerName string, statusCode int) {
+// streamTPMTokens returns the tokens a stream consumes from TPM: the usage it reported, or the
+// prompt estimate (text and images) for streams without usage
+func streamTPMTokens(totalTokens int, logCtx *RequestLogContext) int {
+	if totalTokens > 0 || logCtx == nil {
+		return totalTokens
+	}
+	return logCtx.PromptTokensEstimate
+}
+
+// finalizeStreamingLog extracts usage info from the last usage event of the stream and logs spend to LiteLLM DB.
+func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int, lastUsage []byte, providerName string, statusCode int) {
 	if logCtx == nil || logCtx.Logged {
 		return
 	}
@@ -457,9 +434,9 @@ func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int,
 	logCtx.TokenUsage.PromptTokens = logCtx.PromptTokensEstimate
 	logCtx.TokenUsage.CompletionTokens = totalTokens
 
-	if len(lastChunk) > 0 {
+	if len(lastUsage) > 0 {
 		extractor := getStreamUsageExtractor(providerName)
-		if usageInfo := extractor.ExtractUsage(lastChunk); usageInfo != nil {
+		if usageInfo := extractor.ExtractUsage(lastUsage); usageInfo != nil {
 			if usageInfo.PromptTokens > 0 {
 				logCtx.TokenUsage.PromptTokens = usageInfo.PromptTokens
 			}
@@ -472,12 +449,13 @@ func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int,
 			logCtx.TokenUsage.AudioOutputTokens = usageInfo.AudioOutputTokens
 			logCtx.TokenUsage.ImageTokens = usageInfo.ImageTokens
 			logCtx.TokenUsage.ReasoningTokens = usageInfo.ReasoningTokens
+			logCtx.TokenUsage.WebSearchRequests = usageInfo.WebSearchRequests
 
 			if usageInfo.CacheCreationTokens > 0 {
 				logCtx.TokenUsage.CacheCreationTokens = usageInfo.CacheCreationTokens
 			}
 
-			p.logger.Debug("Extracted usage from streaming response",
+			logCtx.Logger().Debug("Extracted usage from streaming response",
 				"provider", providerName,
 				"prompt_tokens", usageInfo.PromptTokens,
 				"completion_tokens", usageInfo.CompletionTokens,
@@ -486,6 +464,7 @@ func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int,
 				"audio_output_tokens", usageInfo.AudioOutputTokens,
 				"image_tokens", usageInfo.ImageTokens,
 				"reasoning_tokens", usageInfo.ReasoningTokens,
+				"web_search_requests", usageInfo.WebSearchRequests,
 			)
 		}
 	}
@@ -498,9 +477,8 @@ func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int,
 	}
 	logCtx.Logged = true
 	if err := p.logSpendToLiteLLMDB(logCtx); err != nil {
-		p.logger.Warn("Failed to queue streaming spend log",
+		logCtx.Logger().Warn("Failed to queue streaming spend log",
 			"error", err,
-			"request_id", logCtx.RequestID,
 		)
 	}
 }
@@ -508,18 +486,39 @@ func (p *Proxy) finalizeStreamingLog(logCtx *RequestLogContext, totalTokens int,
 func (p *Proxy) streamToClient(
 	w http.ResponseWriter,
 	reader io.Reader,
-	credName string,
+	log *slog.Logger,
 	onChunk func([]byte),
 	onWriteErr func(),
 ) error {
 	_, ok := w.(http.Flusher)
 	if !ok {
-		p.logger.Error("Streaming not supported", "credential", credName)
+		log.Error("Streaming not supported")
 		WriteErrorInternal(w, "Streaming Not Supported")
 		return fmt.Errorf("streaming not supported")
 	}
 	controller := http.NewResponseController(w)
 
+	// With stream_coalescing, writes are buffered and flushed by the coalescer
+	var out io.Writer = w
+	var coalescer *streamCoalescer
+	if p.streamCoalescing.Enabled {
+		coalescer = newStreamCoalescer(w, func() { p.flushStreaming(controller, log) },
+			p.streamCoalescing.Interval, p.streamCoalescing.MaxBytes)
+		out = coalescer
+	}
+	writeFailed := func(writeErr error) error {
+		_ = coalescer.Close()
+		if isClientDisconnectError(writeErr) {
+			log.Warn("Client disconnected during streaming", "error", writeErr)
+		} else {
+			log.Error("Failed to write streaming chunk", "error", writeErr)
+		}
+		if onWriteErr != nil {
+			onWriteErr()
+		}
+		return writeErr
+	}
+
 	buf := streamBufPool.Get().(*[]byte)
 	defer streamBufPool.Put(buf)
 	for {
@@ -531,40 +530,37 @@ func (p *Proxy) streamToClient(
 			// Set write deadline before each write — keeps active streams alive,
 			// terminates if client stops reading for streamChunkWriteTimeout.
 			_ = controller.SetWriteDeadline(time.Now().Add(streamChunkWriteTimeout))
-			if _, writeErr := w.Write((*buf)[:n]); writeErr != nil {
-				if isClientDisconnectError(writeErr) {
-					p.logger.Warn("Client disconnected during streaming", "error", writeErr, "credential", credName)
-				} else {
-					p.logger.Error("Failed to write streaming chunk", "error", writeErr, "credential", credName)
-				}
-				if onWriteErr != nil {
-					onWriteErr()
-				}
-				return writeErr
+			if _, writeErr := out.Write((*buf)[:n]); writeErr != nil {
+				return writeFailed(writeErr)
+			}
+			if coalescer == nil {
+				p.flushStreaming(controller, log)
 			}
-			p.flushStreaming(controller, credName)
 		}
 		if err != nil {
 			if err != io.EOF {
-				p.logger.Error("Streaming read error", "error", err, "credential", credName)
+				log.Error("Streaming read error", "error", err)
 			}
 			break
 		}
 	}
+	if err := coalescer.Close(); err != nil {
+		return writeFailed(err)
+	}
 	return nil
 }
 
-func (p *Proxy) flushStreaming(controller *http.ResponseController, credName string) {
+func (p *Proxy) flushStreaming(controller *http.ResponseController, log *slog.Logger) {
 	defer func() {
 		if r := recover(); r != nil {
-			p.logger.Error("Flusher panic", "panic", r, "credential", credName)
+			log.Error("Flusher panic", "panic", r)
 		}
 	}()
 	if err := controller.Flush(); err != nil {
 		if errors.Is(err, http.ErrNotSupported) {
-			p.logger.Error("Streaming not supported", "credential", credName)
+			log.Error("Streaming not supported")
 		} else {
-			p.logger.Error("Flusher error", "error", err, "credential", credName)
+			log.Error("Flusher error", "error", err)
 		}
 	}
 }
@@ -579,28 +575,31 @@ func (p *Proxy) handleResponsesAPIStreaming(
 	modelID string,
 	logCtx *RequestLogContext,
 ) error {
-	p.logger.Debug("Starting Responses API streaming", "credential", cred.Name, "provider", cred.Type)
+	logCtx.CredentialLogger(cred.Name).Debug("Starting Responses API streaming", "provider", cred.Type)
 
 	// For providers that need transformation (Vertex, Anthropic, Bedrock),
 	// first transform to OpenAI Chat Completions SSE, then to Responses API SSE.
 	// For OpenAI (passthrough), the stream is already in Chat Completions SSE format.
 
+	log := logCtx.CredentialLogger(cred.Name)
 	conv := converter.New(cred.Type, converter.RequestMode{
 		ModelID:     modelID,
 		IsStreaming: true,
+		PostProcess: p.postProcess,
+		Logger:      log,
 	})
 
 	// Create a wrapper transformer that chains:
 	// Provider SSE -> Chat Completions SSE -> Responses API SSE
 	transformer := func(r io.Reader, id string, w io.Writer) error {
 		if conv.IsPassthrough() {
-			p.logger.Debug("Responses API streaming: passthrough mode (Chat Completions SSE → Responses SSE)",
-				"model", modelID, "provider", cred.Type)
-			return responses.TransformChatStreamToResponses(r, w, modelID)
+			logCtx.Logger().Debug("Responses API streaming: passthrough mode (Chat Completions SSE → Responses SSE)",
+				"real_model", modelID, "provider", cred.Type)
+			return responses.TransformChatStreamToResponses(r, w, modelID, log)
 		}
 
-		p.logger.Debug("Responses API streaming: converted mode (Provider SSE → Chat Completions SSE → Responses SSE)",
-			"model", modelID, "provider", cred.Type)
+		logCtx.Logger().Debug("Responses API streaming: converted mode (Provider SSE → Chat Completions SSE → Responses SSE)",
+			"real_model", modelID, "provider", cred.Type)
 
 		// Non-passthrough providers: first convert to Chat Completions SSE via pipe
 		pr, pw := io.Pipe()
@@ -611,27 +610,27 @@ func (p *Proxy) handleResponsesAPIStreaming(
 			defer wg.Done()
 			transformErr = conv.StreamTo(r, pw)
 			if transformErr != nil {
-				p.logger.Error("Responses API streaming: provider→ChatCompletions transform failed",
+				logCtx.Logger().Error("Responses API streaming: provider→ChatCompletions transform failed",
 					"error", transformErr, "provider", cred.Type)
 				_ = pw.CloseWithError(transformErr)
 			} else {
-				p.logger.Debug("Responses API streaming: provider→ChatCompletions transform completed OK",
+				logCtx.Logger().Debug("Responses API streaming: provider→ChatCompletions transform completed OK",
 					"provider", cred.Type)
 				_ = pw.Close()
 			}
 		}()
 
 		// Then convert Chat Completions SSE to Responses API SSE
-		err := responses.TransformChatStreamToResponses(pr, w, modelID)
+		err := responses.TransformChatStreamToResponses(pr, w, modelID, log)
 		_ = pr.Close()
 		wg.Wait() // ensure goroutine completes before reading transformErr
 		if err != nil {
-			p.logger.Error("Responses API streaming: ChatCompletions→Responses transform failed",
+			logCtx.Logger().Error("Responses API streaming: ChatCompletions→Responses transform failed",
 				"error", err, "provider", cred.Type)
 			return err
 		}
 		if transformErr != nil {
-			p.logger.Error("Responses API streaming: provider transform error after Responses transform",
+			logCtx.Logger().Error("Responses API streaming: provider transform error after Responses transform",
 				"error", transformErr, "provider", cred.Type)
 		}
 		return transformErr
@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+)
+
+// errAssembledStreamTooLarge aborts a stream that does not fit into the response body limit
+var errAssembledStreamTooLarge = errors.New("streamed response exceeds the response body size limit")
+
+// nonStreamingPolicy selects the API keys whose streaming requests are answered with a single
+// JSON response (non_streaming), for clients that cannot consume SSE
+type nonStreamingPolicy struct {
+	keyMatcher
+}
+
+// newNonStreamingPolicy creates a policy for the given key aliases or team IDs ("*" = all keys)
+func newNonStreamingPolicy(keys []string) *nonStreamingPolicy {
+	return &nonStreamingPolicy{keyMatcher: newKeyMatcher(nil, keys)}
+}
+
+// applies reports whether streaming responses to the request's API key are assembled
+func (n *nonStreamingPolicy) applies(logCtx *RequestLogContext) bool {
+	if n == nil {
+		return false
+	}
+	return n.matchesKey(logCtx.TokenInfo)
+}
+
+// streamAssemblyWriter buffers a successful streaming response and, in finish, writes it to the
+// client as the equivalent non-streaming JSON response. Error responses and non-SSE bodies are
+// passed through unchanged. Token accounting is unaffected: it runs on the upstream stream.
+type streamAssemblyWriter struct {
+	http.ResponseWriter
+	logger     *slog.Logger
+	maxBody    int64
+	status     int
+	assembling bool
+	tooLarge   bool
+	body       bytes.Buffer
+}
+
+func newStreamAssemblyWriter(w http.ResponseWriter, maxBody int64, logger *slog.Logger) *streamAssemblyWriter {
+	return &streamAssemblyWriter{ResponseWriter: w, maxBody: maxBody, logger: logger}
+}
+
+func (sw *streamAssemblyWriter) WriteHeader(statusCode int) {
+	if sw.status != 0 {
+		return
+	}
+	sw.status = statusCode
+	contentType := sw.Header().Get("Content-Type")
+	sw.assembling = statusCode >= 200 && statusCode < 300 && isStreamingContentType(contentType)
+	if !sw.assembling {
+		sw.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+func (sw *streamAssemblyWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if !sw.assembling {
+		return sw.ResponseWriter.Write(p)
+	}
+	if sw.maxBody > 0 && int64(sw.body.Len()+len(p)) > sw.maxBody {
+		sw.tooLarge = true
+		return 0, errAssembledStreamTooLarge
+	}
+	return sw.body.Write(p)
+}
+
+// Flush is a no-op while the stream is buffered
+func (sw *streamAssemblyWriter) Flush() {
+	if sw.assembling {
+		return
+	}
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (write deadlines)
+func (sw *streamAssemblyWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// finish writes the assembled response; it must run before the handler returns
+func (sw *streamAssemblyWriter) finish() {
+	if !sw.assembling {
+		return
+	}
+	sw.assembling = false
+
+	h := sw.Header()
+	h.Del("Content-Length")
+	h.Del("Cache-Control")
+	h.Del("X-Accel-Buffering")
+	if sw.tooLarge {
+		h.Del("Content-Type")
+		WriteErrorBadGateway(sw.ResponseWriter, errAssembledStreamTooLarge.Error())
+		return
+	}
+
+	payload, status, err := assembleStream(sw.body.Bytes())
+	if err != nil {
+		sw.logger.Warn("Failed to assemble streaming response, returning it as received", "error", err)
+		sw.ResponseWriter.WriteHeader(sw.status)
+		_, _ = sw.ResponseWriter.Write(sw.body.Bytes())
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	h.Set("Content-Length", strconv.Itoa(len(payload)))
+	sw.ResponseWriter.WriteHeader(status)
+	if _, err := sw.ResponseWriter.Write(payload); err != nil {
+		sw.logger.Warn("Failed to write assembled streaming response", "error", err)
+	}
+}
+
+// isStreamingContentType reports whether a Content-Type is one of the streaming formats
+func isStreamingContentType(contentType string) bool {
+	return strings.Contains(contentType, "text/event-stream") ||
+		strings.Contains(contentType, "application/stream+json") ||
+		strings.Contains(contentType, "application/vnd.amazon.eventstream")
+}
+
+// assembleStream converts a Chat Completions or Responses API SSE body into the JSON body and
+// status of the equivalent non-streaming response. An error event in the stream is returned
+// as-is with 502 Bad Gateway.
+func assembleStream(stream []byte) ([]byte, int, error) {
+	var assembler chatStreamAssembler
+	for _, payload := range extractJSONPayloadsFromStreamChunk(stream) {
+		var event struct {
+			Object   string          `json:"object"`
+			Type     string          `json:"type"`
+			Response json.RawMessage `json:"response"`
+			Error    json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			continue
+		}
+		switch {
+		case len(event.Error) > 0 && string(event.Error) != "null":
+			return payload, http.StatusBadGateway, nil
+		case event.Type == "response.completed" && len(event.Response) > 0:
+			// Responses API: the completed event carries the full response object
+			return event.Response, http.StatusOK, nil
+		case event.Type == "response.failed" && len(event.Response) > 0:
+			return event.Response, http.StatusBadGateway, nil
+		case event.Object == "chat.completion.chunk":
+			var chunk openai.OpenAIStreamingChunk
+			if err := json.Unmarshal(payload, &chunk); err != nil {
+				return nil, 0, err
+			}
+			assembler.add(&chunk)
+		}
+	}
+	if assembler.chunks == 0 {
+		return nil, 0, errors.New("no chat.completion.chunk or response.completed event in stream")
+	}
+
+	payload, err := json.Marshal(assembler.response())
+	if err != nil {
+		return nil, 0, err
+	}
+	return payload, http.StatusOK, nil
+}
+
+// chatStreamAssembler merges Chat Completions chunks into a chat.completion response
+type chatStreamAssembler struct {
+	chunks  int
+	resp    openai.OpenAIResponse
+	choices map[int]*openai.OpenAIChoice
+	order   []int
+}
+
+func (a *chatStreamAssembler) add(chunk *openai.OpenAIStreamingChunk) {
+	a.chunks++
+	if a.choices == nil {
+		a.choices = make(map[int]*openai.OpenAIChoice)
+	}
+	if a.resp.ID == "" {
+		a.resp.ID = chunk.ID
+	}
+	if a.resp.Created == 0 {
+		a.resp.Created = chunk.Created
+	}
+	if a.resp.Model == "" {
+		a.resp.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.resp.Usage = chunk.Usage
+	}
+
+	for _, c := range chunk.Choices {
+		choice, ok := a.choices[c.Index]
+		if !ok {
+			choice = &openai.OpenAIChoice{Index: c.Index, Message: openai.OpenAIResponseMessage{Role: "assistant"}}
+			a.choices[c.Index] = choice
+			a.order = append(a.order, c.Index)
+		}
+		msg := &choice.Message
+		if c.Delta.Role != "" {
+			msg.Role = c.Delta.Role
+		}
+		msg.Content += c.Delta.Content
+		msg.Refusal += c.Delta.Refusal
+		msg.ReasoningContent += c.Delta.ReasoningContent
+		if c.Delta.Audio != nil {
+			if msg.Audio == nil {
+				msg.Audio = &openai.AudioOutput{}
+			}
+			if c.Delta.Audio.ID != "" {
+				msg.Audio.ID = c.Delta.Audio.ID
+			}
+			if c.Delta.Audio.Format != "" {
+				msg.Audio.Format = c.Delta.Audio.Format
+			}
+			msg.Audio.Data += c.Delta.Audio.Data
+		}
+		for _, tc := range c.Delta.ToolCalls {
+			for len(msg.ToolCalls) <= tc.Index {
+				msg.ToolCalls = append(msg.ToolCalls, openai.OpenAIToolCall{Type: "function"})
+			}
+			call := &msg.ToolCalls[tc.Index]
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			if tc.Type != "" {
+				call.Type = tc.Type
+			}
+			if tc.ProviderSpecificFields != nil {
+				call.ProviderSpecificFields = tc.ProviderSpecificFields
+			}
+			if tc.Function != nil {
+				call.Function.Name += tc.Function.Name
+				call.Function.Arguments += tc.Function.Arguments
+			}
+		}
+		if c.FinishReason != nil {
+			choice.FinishReason = *c.FinishReason
+		}
+		if c.ContentFilterResults != nil {
+			choice.ContentFilterResults = c.ContentFilterResults
+		}
+	}
+}
+
+func (a *chatStreamAssembler) response() *openai.OpenAIResponse {
+	resp := a.resp
+	resp.Object = "chat.completion"
+	resp.Choices = make([]openai.OpenAIChoice, 0, len(a.order))
+	sort.Ints(a.order)
+	for _, index := range a.order {
+		resp.Choices = append(resp.Choices, *a.choices[index])
+	}
+	return &resp
+}
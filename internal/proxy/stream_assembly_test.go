@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChatStream = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":"}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":7,"total_tokens":19}}
+
+data: [DONE]
+
+`
+
+func TestAssembleStream_ChatCompletions(t *testing.T) {
+	payload, status, err := assembleStream([]byte(testChatStream))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	var resp openai.OpenAIResponse
+	require.NoError(t, json.Unmarshal(payload, &resp))
+	assert.Equal(t, "chatcmpl-1", resp.ID)
+	assert.Equal(t, "chat.completion", resp.Object)
+	assert.Equal(t, int64(1700000000), resp.Created)
+	assert.Equal(t, "gpt-4o", resp.Model)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	assert.Equal(t, "assistant", resp.Choices[0].Message.Role)
+	assert.Equal(t, "Hello world", resp.Choices[0].Message.Content)
+	assert.Equal(t, []openai.OpenAIToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: openai.OpenAIToolFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}, resp.Choices[0].Message.ToolCalls)
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, 19, resp.Usage.TotalTokens)
+}
+
+func TestAssembleStream_Other(t *testing.T) {
+	// Responses API: the completed event holds the response object
+	stream := "event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_1\",\"status\":\"in_progress\"}}\n\n" +
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"status\":\"completed\"}}\n\n"
+	payload, status, err := assembleStream([]byte(stream))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"id":"resp_1","status":"completed"}`, string(payload))
+
+	// Errors reported inside the stream
+	payload, status, err = assembleStream([]byte("data: {\"error\":{\"message\":\"overloaded\"}}\n\n"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.JSONEq(t, `{"error":{"message":"overloaded"}}`, string(payload))
+
+	_, _, err = assembleStream([]byte("data: [DONE]\n\n"))
+	assert.Error(t, err)
+}
+
+func TestNonStreamingPolicy(t *testing.T) {
+	var disabled *nonStreamingPolicy
+	assert.False(t, disabled.applies(&RequestLogContext{}))
+
+	policy := newNonStreamingPolicy([]string{"legacy", "team-batch"})
+	assert.False(t, policy.applies(&RequestLogContext{Token: "master-key"}), "master key streams as requested")
+	assert.True(t, policy.applies(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "legacy"}}))
+	assert.True(t, policy.applies(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{TeamID: "team-batch"}}))
+	assert.False(t, policy.applies(&RequestLogContext{TokenInfo: &litellmdb.TokenInfo{KeyAlias: "other"}}))
+	assert.True(t, newNonStreamingPolicy([]string{AllKeys}).applies(&RequestLogContext{}))
+}
+
+func TestProxyRequest_NonStreaming(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(testChatStream))
+	}))
+	defer mockServer.Close()
+
+	logger := testhelpers.NewTestLogger()
+	bal, rl := createTestBalancer(mockServer.URL)
+	prx := createProxyWithParams(bal, logger, 10, 30*time.Second, createTestProxyMetrics(), "master-key", rl,
+		createTestTokenManager(logger), createTestModelManager(logger), "test-version", "test-commit")
+	prx.nonStreaming = newNonStreamingPolicy([]string{AllKeys})
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model": "gpt-4o", "stream": true}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+
+	prx.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var resp openai.OpenAIResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Hello world", resp.Choices[0].Message.Content)
+	assert.Greater(t, rl.GetCurrentTPM("test"), 0, "upstream stream is still accounted")
+}
+
+func TestStreamAssemblyWriter_PassThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := newStreamAssemblyWriter(rec, 0, testhelpers.NewTestLogger())
+	sw.Header().Set("Content-Type", "application/json")
+	sw.WriteHeader(http.StatusTooManyRequests)
+	_, err := sw.Write([]byte(`{"error":{"message":"slow down"}}`))
+	require.NoError(t, err)
+	sw.finish()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, `{"error":{"message":"slow down"}}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	sw = newStreamAssemblyWriter(rec, 10, testhelpers.NewTestLogger())
+	sw.Header().Set("Content-Type", "text/event-stream")
+	_, err = sw.Write([]byte(testChatStream))
+	assert.ErrorIs(t, err, errAssembledStreamTooLarge)
+	sw.finish()
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
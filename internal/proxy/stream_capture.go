@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+)
+
+// maxStreamLineBytes bounds the partial SSE line carried over between chunks. Longer lines
+// (large content deltas) are skipped: usage and finish_reason events are far smaller.
+const maxStreamLineBytes = 64 << 10
+
+var (
+	sseDataPrefix = []byte("data:")
+	usageMarker   = []byte(`"usage"`)
+)
+
+// streamCapture extracts token counts, the last usage payload and content filter events from
+// a streamed response with constant memory. Chunks are split into SSE lines as they arrive,
+// so events split across chunks are still parsed; only one partial line is kept.
+type streamCapture struct {
+	filter *contentFilterCounter // nil = content filter events not recorded
+
+	tokens    int    // Sum of usage.total_tokens of the stream's events
+	chunks    int    // Chunks observed
+	lastUsage []byte // Data payload of the last event reporting usage
+
+	line     []byte // Partial line of the previous chunk
+	skipping bool   // The current line exceeded maxStreamLineBytes and is skipped
+}
+
+// observe processes a chunk of the stream. The chunk is not retained.
+func (c *streamCapture) observe(chunk []byte) {
+	c.chunks++
+	for len(chunk) > 0 {
+		end := bytes.IndexByte(chunk, '\n')
+		if end < 0 {
+			c.carry(chunk)
+			return
+		}
+		part := chunk[:end]
+		chunk = chunk[end+1:]
+
+		switch {
+		case c.skipping:
+			c.skipping = false
+		case len(c.line) > 0:
+			if c.carry(part) {
+				c.processLine(c.line)
+			}
+			c.line = c.line[:0]
+			c.skipping = false
+		default:
+			c.processLine(part)
+		}
+	}
+}
+
+// finish processes a last line not terminated by a newline
+func (c *streamCapture) finish() {
+	if len(c.line) > 0 && !c.skipping {
+		c.processLine(c.line)
+	}
+	c.line = c.line[:0]
+	c.skipping = false
+}
+
+// carry appends part to the partial line; reports false once the line is skipped
+func (c *streamCapture) carry(part []byte) bool {
+	if c.skipping {
+		return false
+	}
+	if len(c.line)+len(part) > maxStreamLineBytes {
+		c.line = c.line[:0]
+		c.skipping = true
+		return false
+	}
+	c.line = append(c.line, part...)
+	return true
+}
+
+// processLine inspects one complete line: an SSE data line or a line of a JSON stream
+func (c *streamCapture) processLine(line []byte) {
+	if len(line) > maxStreamLineBytes {
+		return
+	}
+	line = bytes.TrimSpace(line)
+	payload, isData := bytes.CutPrefix(line, sseDataPrefix)
+	if !isData && !bytes.HasPrefix(line, []byte("{")) {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+
+	if c.filter != nil {
+		c.filter.observe(payload)
+	}
+	if !bytes.Contains(payload, usageMarker) {
+		return
+	}
+	if isData {
+		c.tokens += extractOpenAITotalTokens(payload)
+	}
+	c.lastUsage = append(c.lastUsage[:0], payload...)
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const captureUsageEvent = `data: {"choices":[],"usage":{"prompt_tokens":100,"completion_tokens":50,"total_tokens":150}}` + "\n\n"
+
+// captureStream observes stream split into chunks of size bytes
+func captureStream(stream []byte, size int, capture *streamCapture) {
+	for len(stream) > 0 {
+		n := min(size, len(stream))
+		capture.observe(stream[:n])
+		stream = stream[n:]
+	}
+	capture.finish()
+}
+
+func TestStreamCapture_EventsSplitAcrossChunks(t *testing.T) {
+	stream := `data: {"choices":[{"delta":{"content":"hello"}}]}` + "\n\n" + captureUsageEvent + "data: [DONE]\n\n"
+
+	for _, size := range []int{1, 7, 64, len(stream)} {
+		capture := &streamCapture{}
+		captureStream([]byte(stream), size, capture)
+
+		assert.Equal(t, 150, capture.tokens, "chunk size %d", size)
+		info := (&openAIStreamUsageExtractor{}).ExtractUsage(capture.lastUsage)
+		require.NotNil(t, info, "chunk size %d", size)
+		assert.Equal(t, 100, info.PromptTokens)
+		assert.Equal(t, 50, info.CompletionTokens)
+	}
+}
+
+func TestStreamCapture_LastUsageWithoutTrailingNewline(t *testing.T) {
+	capture := &streamCapture{}
+	captureStream([]byte(strings.TrimSpace(captureUsageEvent)), 10, capture)
+	assert.Equal(t, 150, capture.tokens)
+}
+
+func TestStreamCapture_JSONLines(t *testing.T) {
+	capture := &streamCapture{}
+	captureStream([]byte(`{"text":"hi"}`+"\n"+`{"usage":{"prompt_tokens":3,"completion_tokens":4}}`+"\n"), 5, capture)
+
+	assert.Zero(t, capture.tokens, "token counting only reads SSE data lines")
+	info := (&openAIStreamUsageExtractor{}).ExtractUsage(capture.lastUsage)
+	require.NotNil(t, info)
+	assert.Equal(t, 4, info.CompletionTokens)
+}
+
+func TestStreamCapture_BoundsLongLines(t *testing.T) {
+	huge := `data: {"choices":[{"delta":{"content":"` + strings.Repeat("x", 4*maxStreamLineBytes) + `"}}],"usage":{"total_tokens":9}}` + "\n\n"
+	stream := huge + captureUsageEvent
+
+	capture := &streamCapture{}
+	captureStream([]byte(stream), 8192, capture)
+
+	assert.Equal(t, 150, capture.tokens, "lines over the bound are skipped, the next line is parsed")
+	assert.LessOrEqual(t, cap(capture.line), 2*maxStreamLineBytes)
+	assert.LessOrEqual(t, cap(capture.lastUsage), 2*maxStreamLineBytes)
+}
+
+func TestStreamCapture_ContentFilter(t *testing.T) {
+	counter := &contentFilterCounter{model: "capture-model", credName: "capture-cred"}
+	capture := &streamCapture{filter: counter}
+	captureStream([]byte(`data: {"choices":[{"finish_reason":"content_filter","delta":{}}]}`+"\n\n"), 9, capture)
+
+	assert.True(t, counter.counted, "marker split across chunks is still found")
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.ContentFilterEventsTotal.WithLabelValues("capture-model", "capture-cred")))
+}
+
+// BenchmarkStreamCapture measures a multi-megabyte stream; allocations must not grow with it
+func BenchmarkStreamCapture(b *testing.B) {
+	var stream bytes.Buffer
+	for stream.Len() < 4<<20 {
+		stream.WriteString(`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"` + strings.Repeat("token ", 20) + `"}}]}` + "\n\n")
+	}
+	stream.WriteString(captureUsageEvent + "data: [DONE]\n\n")
+	data := stream.Bytes()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		capture := &streamCapture{}
+		captureStream(data, 8192, capture)
+		if capture.tokens != 150 {
+			b.Fatalf("tokens = %d", capture.tokens)
+		}
+	}
+}
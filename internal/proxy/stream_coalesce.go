@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+var sseEventSeparator = []byte("\n\n")
+
+// streamCoalescer buffers an SSE stream for up to interval (or maxBytes) and merges consecutive
+// content-only Chat Completions chunks of the same completion into one chunk.
+// Other events (role, tool calls, finish_reason, usage, [DONE], Responses API events) are
+// written unchanged and in order. The timer flushes from its own goroutine, so all methods lock mu.
+type streamCoalescer struct {
+	w        io.Writer
+	flush    func()
+	interval time.Duration
+	maxBytes int
+
+	mu      sync.Mutex
+	partial []byte          // Received bytes of an event that is not terminated yet
+	rawTail bool            // The start of the current event was already written: pass it through
+	out     bytes.Buffer    // Events ready to be written
+	merge   *coalescedChunk // Content chunk that following deltas are merged into
+	timer   *time.Timer
+	closed  bool
+	err     error // First write error; returned by all later calls
+}
+
+func newStreamCoalescer(w io.Writer, flush func(), interval time.Duration, maxBytes int) *streamCoalescer {
+	return &streamCoalescer{w: w, flush: flush, interval: interval, maxBytes: maxBytes}
+}
+
+// Write buffers p; complete events are merged or queued, and the buffer is written to the
+// client once maxBytes is reached or interval has passed since the first buffered byte
+func (c *streamCoalescer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	c.partial = append(c.partial, p...)
+	if c.rawTail {
+		end := bytes.Index(c.partial, sseEventSeparator)
+		if end < 0 {
+			c.out.Write(c.partial)
+			c.partial = c.partial[:0]
+		} else {
+			c.out.Write(c.partial[:end+len(sseEventSeparator)])
+			c.partial = append(c.partial[:0], c.partial[end+len(sseEventSeparator):]...)
+			c.rawTail = false
+		}
+	}
+	for !c.rawTail {
+		end := bytes.Index(c.partial, sseEventSeparator)
+		if end < 0 {
+			break
+		}
+		c.addEventLocked(c.partial[:end+len(sseEventSeparator)])
+		c.partial = append(c.partial[:0], c.partial[end+len(sseEventSeparator):]...)
+	}
+
+	if c.bufferedLocked() >= c.maxBytes {
+		c.flushLocked(false)
+	} else if c.timer == nil && c.bufferedLocked() > 0 {
+		c.timer = time.AfterFunc(c.interval, c.onTimer)
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return len(p), nil
+}
+
+// Close writes everything still buffered and stops the timer; safe on a nil coalescer
+func (c *streamCoalescer) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked(true)
+	c.closed = true
+	return c.err
+}
+
+func (c *streamCoalescer) onTimer() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer = nil
+	if !c.closed {
+		c.flushLocked(true)
+	}
+}
+
+// bufferedLocked returns the size of the data held back from the client
+func (c *streamCoalescer) bufferedLocked() int {
+	n := c.out.Len() + len(c.partial)
+	if c.merge != nil {
+		n += len(c.merge.raw) + c.merge.content.Len()
+	}
+	return n
+}
+
+// addEventLocked merges a content chunk into the pending one or queues the event
+func (c *streamCoalescer) addEventLocked(event []byte) {
+	chunk := parseContentChunk(event)
+	if chunk != nil && c.merge != nil && c.merge.id == chunk.id && c.merge.index == chunk.index {
+		c.merge.content.WriteString(chunk.content.String())
+		c.merge.merged++
+		return
+	}
+
+	c.emitMergeLocked()
+	if chunk != nil {
+		c.merge = chunk
+		return
+	}
+	c.out.Write(event)
+}
+
+func (c *streamCoalescer) emitMergeLocked() {
+	if c.merge != nil {
+		c.out.Write(c.merge.encode())
+		c.merge = nil
+	}
+}
+
+// flushLocked writes the buffered events to the client. withPartial also writes the start of an
+// unterminated event, so a stream without event separators is never held back.
+func (c *streamCoalescer) flushLocked(withPartial bool) {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.err != nil {
+		return
+	}
+
+	c.emitMergeLocked()
+	if withPartial && len(c.partial) > 0 {
+		c.out.Write(c.partial)
+		c.partial = c.partial[:0]
+		c.rawTail = true
+	}
+	if c.out.Len() == 0 {
+		return
+	}
+
+	_, err := c.w.Write(c.out.Bytes())
+	c.out.Reset()
+	if err != nil {
+		c.err = err
+		return
+	}
+	c.flush()
+}
+
+// coalescedChunk is a content-only chat.completion.chunk and the deltas merged into it
+type coalescedChunk struct {
+	raw     []byte // Original event, written as-is when nothing was merged
+	fields  map[string]json.RawMessage
+	choice  map[string]json.RawMessage
+	delta   map[string]json.RawMessage
+	id      string
+	index   string
+	content strings.Builder // Content of the original delta and the merged ones
+	merged  int
+}
+
+// parseContentChunk returns the event as a mergeable chunk: a single "data:" line holding a
+// chat.completion.chunk with one choice whose delta only has content, and no finish_reason,
+// logprobs or usage. Returns nil for any other event.
+func parseContentChunk(event []byte) *coalescedChunk {
+	line := bytes.TrimSpace(event)
+	if bytes.IndexByte(line, '\n') >= 0 || !bytes.HasPrefix(line, []byte("data:")) {
+		return nil
+	}
+	payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil
+	}
+	var object, id string
+	if json.Unmarshal(fields["object"], &object) != nil || object != "chat.completion.chunk" {
+		return nil
+	}
+	if json.Unmarshal(fields["id"], &id) != nil {
+		return nil
+	}
+	if usage, ok := fields["usage"]; ok && string(usage) != "null" {
+		return nil
+	}
+
+	var choices []map[string]json.RawMessage
+	if json.Unmarshal(fields["choices"], &choices) != nil || len(choices) != 1 {
+		return nil
+	}
+	choice := choices[0]
+	for key, value := range choice {
+		switch key {
+		case "index", "delta":
+		case "finish_reason", "logprobs":
+			if string(value) != "null" {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+
+	var delta map[string]json.RawMessage
+	if json.Unmarshal(choice["delta"], &delta) != nil || len(delta) != 1 {
+		return nil
+	}
+	rawContent, ok := delta["content"]
+	var content string
+	if !ok || string(rawContent) == "null" || json.Unmarshal(rawContent, &content) != nil {
+		return nil
+	}
+
+	chunk := &coalescedChunk{
+		raw:    bytes.Clone(event),
+		fields: fields,
+		choice: choice,
+		delta:  delta,
+		id:     id,
+		index:  string(choice["index"]),
+	}
+	chunk.content.WriteString(content)
+	return chunk
+}
+
+// encode returns the SSE event of the chunk with the merged content
+func (m *coalescedChunk) encode() []byte {
+	if m.merged == 0 {
+		return m.raw
+	}
+	// Marshaling cannot fail: every value is valid JSON from json.Unmarshal or json.Marshal
+	m.delta["content"], _ = json.Marshal(m.content.String())
+	m.choice["delta"], _ = json.Marshal(m.delta)
+	m.fields["choices"], _ = json.Marshal([]map[string]json.RawMessage{m.choice})
+	payload, _ := json.Marshal(m.fields)
+
+	event := make([]byte, 0, len(payload)+len("data: \n\n"))
+	event = append(event, "data: "...)
+	event = append(event, payload...)
+	return append(event, sseEventSeparator...)
+}
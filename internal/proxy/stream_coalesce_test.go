@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is written by the coalescer timer goroutine while the test reads it
+type lockedBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	flushes int
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushes++
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func contentChunk(id, content string) string {
+	return `data: {"id":"` + id + `","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"` + content + `"},"finish_reason":null}]}` + "\n\n"
+}
+
+func TestStreamCoalescer_MergesContentDeltas(t *testing.T) {
+	out := &lockedBuffer{}
+	c := newStreamCoalescer(out, out.flush, time.Hour, 1<<20)
+
+	role := `data: {"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}` + "\n\n"
+	finish := `data: {"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\n"
+	usage := `data: {"id":"c1","object":"chat.completion.chunk","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":3}}` + "\n\n"
+	stream := role + contentChunk("c1", "Hel") + contentChunk("c1", "lo") + contentChunk("c1", ` \"world\"`) +
+		finish + contentChunk("c2", "single") + usage + "data: [DONE]\n\n"
+
+	// Events split across writes are reassembled
+	for i := 0; i < len(stream); i += 7 {
+		_, err := c.Write([]byte(stream[min(i, len(stream)):min(i+7, len(stream))]))
+		require.NoError(t, err)
+	}
+	assert.Empty(t, out.String(), "held back until the window ends")
+	require.NoError(t, c.Close())
+
+	assert.Equal(t, role+
+		`data: {"choices":[{"delta":{"content":"Hello \"world\""},"finish_reason":null,"index":0}],"id":"c1","model":"gpt-4o","object":"chat.completion.chunk"}`+"\n\n"+
+		finish+contentChunk("c2", "single")+usage+"data: [DONE]\n\n", out.String())
+	assert.Equal(t, 1, out.flushes)
+}
+
+func TestStreamCoalescer_FlushTriggers(t *testing.T) {
+	t.Run("max_bytes", func(t *testing.T) {
+		out := &lockedBuffer{}
+		// The pending chunk counts its original event and the merged content
+		c := newStreamCoalescer(out, out.flush, time.Hour, len(contentChunk("c1", "abc"))+6)
+		for i := 0; i < 3; i++ {
+			_, err := c.Write([]byte(contentChunk("c1", "abc")))
+			require.NoError(t, err)
+		}
+		assert.Contains(t, out.String(), `"content":"abcabc"`)
+		assert.Equal(t, 1, out.flushes)
+		require.NoError(t, c.Close())
+		assert.Equal(t, 2, out.flushes)
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		out := &lockedBuffer{}
+		c := newStreamCoalescer(out, out.flush, 10*time.Millisecond, 1<<20)
+		_, err := c.Write([]byte(contentChunk("c1", "a") + contentChunk("c1", "b")))
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return strings.Contains(out.String(), `"content":"ab"`) },
+			time.Second, 5*time.Millisecond)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("unterminated event", func(t *testing.T) {
+		out := &lockedBuffer{}
+		c := newStreamCoalescer(out, out.flush, 10*time.Millisecond, 1<<20)
+		_, err := c.Write([]byte(`{"partial":`))
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return out.String() == `{"partial":` }, time.Second, 5*time.Millisecond)
+
+		// The rest of the event is passed through, then merging resumes
+		_, err = c.Write([]byte("true}\n\n" + contentChunk("c1", "x") + contentChunk("c1", "y")))
+		require.NoError(t, err)
+		require.NoError(t, c.Close())
+		assert.True(t, strings.HasPrefix(out.String(), "{\"partial\":true}\n\ndata: "))
+		assert.Contains(t, out.String(), `"content":"xy"`)
+	})
+}
+
+func TestStreamToClient_Coalescing(t *testing.T) {
+	prx := NewTestProxyBuilder().Build()
+	prx.streamCoalescing = config.StreamCoalescingConfig{Enabled: true, Interval: time.Hour, MaxBytes: 1 << 20}
+
+	var chunks int
+	w := httptest.NewRecorder()
+	stream := contentChunk("c1", "a") + contentChunk("c1", "b") + "data: [DONE]\n\n"
+	require.NoError(t, prx.streamToClient(w, strings.NewReader(stream), prx.logger, func([]byte) { chunks++ }, nil))
+
+	assert.Equal(t, 1, chunks, "callbacks receive the upstream bytes")
+	assert.Equal(t, strings.Count(w.Body.String(), "data: "), 2)
+	assert.Contains(t, w.Body.String(), `"content":"ab"`)
+	assert.True(t, w.Flushed)
+}
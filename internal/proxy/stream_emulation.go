@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter"
+	"github.com/mixaill76/auto_ai_router/internal/converter/responses"
+)
+
+// emulatedStreamChunkRunes is the content size of each emulated content delta
+const emulatedStreamChunkRunes = 64
+
+// streamUnsupported reports whether cred cannot stream the request (stream in its
+// unsupported_params): the stream parameter is dropped and the response is emulated as SSE
+func streamUnsupported(cred *config.CredentialConfig, requested map[string]bool) bool {
+	return requested["stream"] && slices.Contains(converter.UnsupportedParams(cred, requested), "stream")
+}
+
+// writeEmulatedStream answers a streaming request that the provider served with a complete
+// Chat Completions response: the response is split into OpenAI-format SSE deltas (converted to
+// Responses API events for Responses API requests). Returns false, writing nothing, if chatBody
+// is not a chat.completion.
+func (p *Proxy) writeEmulatedStream(w http.ResponseWriter, chatBody []byte, statusCode int, log *slog.Logger, modelID string, responsesAPI bool) bool {
+	stream, ok := emulateChatStream(chatBody)
+	if !ok {
+		return false
+	}
+	if responsesAPI {
+		var buf bytes.Buffer
+		if err := responses.TransformChatStreamToResponses(bytes.NewReader(stream), &buf, modelID, log); err != nil {
+			log.Error("Failed to convert emulated stream to Responses API events", "error", err)
+			return false
+		}
+		stream = buf.Bytes()
+	}
+
+	log.Debug("Emulating streaming response from a complete response", "size", len(stream))
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(statusCode)
+	if err := p.streamToClient(w, bytes.NewReader(stream), log, nil, nil); err != nil {
+		log.Debug("Failed to write emulated stream", "error", err)
+	}
+	return true
+}
+
+// emulateChatStream splits a chat.completion response into the chat.completion.chunk SSE events
+// a streaming request would have received: role, remaining message fields (reasoning, ...),
+// content deltas, tool calls, finish_reason, usage and [DONE].
+func emulateChatStream(body []byte) ([]byte, bool) {
+	var resp struct {
+		ID                string          `json:"id"`
+		Object            string          `json:"object"`
+		Created           int64           `json:"created"`
+		Model             string          `json:"model"`
+		SystemFingerprint string          `json:"system_fingerprint,omitempty"`
+		Usage             json.RawMessage `json:"usage,omitempty"`
+		Choices           []struct {
+			Index        int                        `json:"index"`
+			Message      map[string]json.RawMessage `json:"message"`
+			FinishReason *string                    `json:"finish_reason"`
+			Logprobs     json.RawMessage            `json:"logprobs,omitempty"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Object != "chat.completion" {
+		return nil, false
+	}
+
+	type streamChoice struct {
+		Index        int             `json:"index"`
+		Delta        interface{}     `json:"delta"`
+		FinishReason *string         `json:"finish_reason"`
+		Logprobs     json.RawMessage `json:"logprobs,omitempty"`
+	}
+	type streamChunk struct {
+		ID                string          `json:"id"`
+		Object            string          `json:"object"`
+		Created           int64           `json:"created"`
+		Model             string          `json:"model"`
+		SystemFingerprint string          `json:"system_fingerprint,omitempty"`
+		Choices           []streamChoice  `json:"choices"`
+		Usage             json.RawMessage `json:"usage,omitempty"`
+	}
+
+	var buf bytes.Buffer
+	write := func(choices []streamChoice, usage json.RawMessage) {
+		data, _ := json.Marshal(streamChunk{
+			ID:                resp.ID,
+			Object:            "chat.completion.chunk",
+			Created:           resp.Created,
+			Model:             resp.Model,
+			SystemFingerprint: resp.SystemFingerprint,
+			Choices:           choices,
+			Usage:             usage,
+		})
+		buf.WriteString("data: ")
+		buf.Write(data)
+		buf.WriteString("\n\n")
+	}
+	delta := func(index int, d interface{}) {
+		write([]streamChoice{{Index: index, Delta: d}}, nil)
+	}
+
+	for _, choice := range resp.Choices {
+		msg := choice.Message
+		role := "assistant"
+		_ = json.Unmarshal(msg["role"], &role)
+		delta(choice.Index, map[string]string{"role": role, "content": ""})
+
+		// Reasoning, refusal, audio, images, annotations, ... in a single delta before the content
+		rest := make(map[string]json.RawMessage)
+		for key, value := range msg {
+			switch key {
+			case "role", "content", "tool_calls":
+			default:
+				if string(value) != "null" {
+					rest[key] = value
+				}
+			}
+		}
+		if len(rest) > 0 {
+			delta(choice.Index, rest)
+		}
+
+		var content string
+		if json.Unmarshal(msg["content"], &content) == nil {
+			runes := []rune(content)
+			for start := 0; start < len(runes); start += emulatedStreamChunkRunes {
+				end := min(start+emulatedStreamChunkRunes, len(runes))
+				delta(choice.Index, map[string]string{"content": string(runes[start:end])})
+			}
+		}
+
+		var toolCalls []map[string]json.RawMessage
+		if json.Unmarshal(msg["tool_calls"], &toolCalls) == nil {
+			for i, call := range toolCalls {
+				call["index"], _ = json.Marshal(i)
+				delta(choice.Index, map[string]interface{}{"tool_calls": []map[string]json.RawMessage{call}})
+			}
+		}
+
+		write([]streamChoice{{
+			Index:        choice.Index,
+			Delta:        map[string]string{},
+			FinishReason: choice.FinishReason,
+			Logprobs:     choice.Logprobs,
+		}}, nil)
+	}
+
+	if len(resp.Usage) > 0 && string(resp.Usage) != "null" {
+		write([]streamChoice{}, resp.Usage)
+	}
+	buf.WriteString("data: [DONE]\n\n")
+	return buf.Bytes(), true
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/converter/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChatCompletion = `{"id":"chatcmpl-1","object":"chat.completion","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hello world","reasoning_content":"Greeting","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":12,"completion_tokens":7,"total_tokens":19}}`
+
+func TestEmulateChatStream(t *testing.T) {
+	stream, ok := emulateChatStream([]byte(testChatCompletion))
+	require.True(t, ok)
+	assert.True(t, strings.HasSuffix(string(stream), "data: [DONE]\n\n"))
+
+	payload, status, err := assembleStream(stream)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	var resp openai.OpenAIResponse
+	require.NoError(t, json.Unmarshal(payload, &resp))
+	assert.Equal(t, "chatcmpl-1", resp.ID)
+	require.Len(t, resp.Choices, 1)
+	msg := resp.Choices[0].Message
+	assert.Equal(t, "assistant", msg.Role)
+	assert.Equal(t, "Hello world", msg.Content)
+	assert.Equal(t, "Greeting", msg.ReasoningContent)
+	require.Len(t, msg.ToolCalls, 1)
+	assert.Equal(t, "call_1", msg.ToolCalls[0].ID)
+	assert.Equal(t, `{"city":"Paris"}`, msg.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, 19, resp.Usage.TotalTokens)
+
+	long := strings.Repeat("ж", emulatedStreamChunkRunes*2+1)
+	stream, ok = emulateChatStream([]byte(`{"object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"` + long + `"},"finish_reason":"stop"}]}`))
+	require.True(t, ok)
+	// role, three content deltas and finish
+	assert.Len(t, extractJSONPayloadsFromStreamChunk(stream), 5)
+
+	_, ok = emulateChatStream([]byte(`{"object":"list","data":[]}`))
+	assert.False(t, ok)
+	_, ok = emulateChatStream([]byte(`not json`))
+	assert.False(t, ok)
+}
+
+func TestProxyRequest_StreamEmulation(t *testing.T) {
+	var upstreamBody map[string]interface{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		upstreamBody = nil
+		_ = json.Unmarshal(data, &upstreamBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testChatCompletion))
+	}))
+	t.Cleanup(upstream.Close)
+
+	tests := []struct {
+		name        string
+		unsupported []string
+		wantStream  bool
+	}{
+		{name: "stream not supported", unsupported: []string{"stream"}, wantStream: false},
+		{name: "complete response to streaming request", wantStream: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prx := NewTestProxyBuilder().
+				WithCredentials(config.CredentialConfig{
+					Name: "oai", Type: config.ProviderTypeOpenAI, BaseURL: upstream.URL, APIKey: "sk-oai",
+					RPM: 100, TPM: 10000, UnsupportedParams: tt.unsupported,
+				}).
+				WithMasterKey("master-key").
+				Build()
+
+			req := httptest.NewRequest("POST", "/v1/chat/completions",
+				strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}`))
+			req.Header.Set("Authorization", "Bearer master-key")
+			w := httptest.NewRecorder()
+			prx.ProxyRequest(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Body.String(), `"object":"chat.completion.chunk"`)
+			assert.True(t, strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n"))
+
+			_, hasStream := upstreamBody["stream"]
+			assert.Equal(t, tt.wantStream, hasStream)
+			if !tt.wantStream {
+				assert.NotContains(t, upstreamBody, "stream_options")
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// normalizedStreamReadSize is the read size of the downstream stream of a normalized stream
+const normalizedStreamReadSize = 32 * 1024
+
+// streamNormalizer re-emits the Chat Completions SSE stream of a proxy credential with
+// normalize_stream as uniform OpenAI SSE, whatever the downstream router version:
+//   - chunk and error events are sent as plain data events, without event names or ids
+//   - a usage chunk is synthesized if the client asked for usage (stream_options.include_usage)
+//     and none arrived: prompt tokens are estimated from the request, completion tokens from
+//     the accumulated deltas
+//   - data: [DONE] is appended to a stream ending without it
+//
+// Other events (comments, Responses API events) are passed through unchanged, and streams
+// without chunks are not completed. A stream failing with a read error is not completed either.
+type streamNormalizer struct {
+	src io.ReadCloser
+	buf []byte
+	in  []byte       // Received bytes of the incomplete event
+	out bytes.Buffer // Normalized bytes not read yet
+	err error        // Read error of src, returned once out is drained
+
+	includeUsage bool // The client asked for a usage chunk
+	promptTokens int  // Estimated prompt tokens of the request
+
+	chunks          int    // Chat chunks seen
+	sawUsage        bool   // A chunk carried usage
+	done            bool   // data: [DONE] was seen
+	completionChars int    // Characters of content, reasoning and tool call deltas
+	lastChunk       []byte // Last chat chunk, whose id, created and model the usage chunk reuses
+}
+
+// newStreamNormalizer wraps the stream src answering the Chat Completions request body
+func newStreamNormalizer(src io.ReadCloser, body []byte) *streamNormalizer {
+	var req struct {
+		StreamOptions struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+	}
+	_ = json.Unmarshal(body, &req)
+	n := &streamNormalizer{src: src, buf: make([]byte, normalizedStreamReadSize), includeUsage: req.StreamOptions.IncludeUsage}
+	if n.includeUsage {
+		n.promptTokens = estimatePromptTokens(body)
+	}
+	return n
+}
+
+// Read implements io.Reader
+func (n *streamNormalizer) Read(p []byte) (int, error) {
+	for n.out.Len() == 0 && n.err == nil {
+		read, err := n.src.Read(n.buf)
+		n.in = append(n.in, n.buf[:read]...)
+		n.processEvents()
+		if err == io.EOF {
+			n.finish()
+		} else if err != nil {
+			n.out.Write(n.in)
+			n.in = nil
+		}
+		n.err = err
+	}
+	if n.out.Len() > 0 {
+		return n.out.Read(p)
+	}
+	return 0, n.err
+}
+
+// Close implements io.Closer
+func (n *streamNormalizer) Close() error {
+	return n.src.Close()
+}
+
+// processEvents normalizes the complete events received so far. An incomplete event longer
+// than maxStreamLineBytes is passed through as is.
+func (n *streamNormalizer) processEvents() {
+	for {
+		end, sepLen := eventEnd(n.in)
+		if end < 0 {
+			break
+		}
+		n.processEvent(n.in[:end], n.in[:end+sepLen])
+		n.in = n.in[end+sepLen:]
+	}
+	if len(n.in) > maxStreamLineBytes {
+		n.out.Write(n.in)
+		n.in = nil
+	}
+}
+
+// eventEnd returns the end of the first event of data and the length of the blank line
+// ending it, or -1 if no event is complete
+func eventEnd(data []byte) (int, int) {
+	lf := bytes.Index(data, []byte("\n\n"))
+	crlf := bytes.Index(data, []byte("\r\n\r\n"))
+	switch {
+	case lf < 0 && crlf < 0:
+		return -1, 0
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return crlf, 4
+	default:
+		return lf, 2
+	}
+}
+
+// processEvent writes the normalized form of one event (raw includes its blank line)
+func (n *streamNormalizer) processEvent(event, raw []byte) {
+	var data [][]byte
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if payload, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			data = append(data, bytes.TrimPrefix(payload, []byte(" ")))
+		}
+	}
+	payload := bytes.Join(data, []byte("\n"))
+
+	if string(bytes.TrimSpace(payload)) == "[DONE]" {
+		if n.done {
+			return
+		}
+		n.writeUsageChunk()
+		n.done = true
+		n.out.WriteString("data: [DONE]\n\n")
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if len(data) == 0 || json.Unmarshal(payload, &fields) != nil {
+		n.out.Write(raw)
+		return
+	}
+	if _, isChunk := fields["choices"]; isChunk {
+		n.observeChunk(payload, fields)
+	} else if _, isError := fields["error"]; !isError {
+		n.out.Write(raw)
+		return
+	}
+	n.out.WriteString("data: ")
+	n.out.Write(payload)
+	n.out.WriteString("\n\n")
+}
+
+// observeChunk accumulates the usage and delta sizes of a chat chunk
+func (n *streamNormalizer) observeChunk(payload []byte, fields map[string]json.RawMessage) {
+	n.chunks++
+	n.lastChunk = append(n.lastChunk[:0], payload...)
+	if usage, ok := fields["usage"]; ok && string(usage) != "null" {
+		n.sawUsage = true
+	}
+
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+				ToolCalls        []struct {
+					Function struct {
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+		} `json:"choices"`
+	}
+	if json.Unmarshal(payload, &chunk) != nil {
+		return
+	}
+	for _, choice := range chunk.Choices {
+		n.completionChars += len(choice.Delta.Content) + len(choice.Delta.ReasoningContent)
+		for _, call := range choice.Delta.ToolCalls {
+			n.completionChars += len(call.Function.Arguments)
+		}
+	}
+}
+
+// writeUsageChunk writes the synthesized usage chunk, if the client asked for usage and the
+// stream carried none
+func (n *streamNormalizer) writeUsageChunk() {
+	if !n.includeUsage || n.sawUsage || n.chunks == 0 {
+		return
+	}
+	var last struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		Model   string `json:"model"`
+	}
+	_ = json.Unmarshal(n.lastChunk, &last)
+	if last.Created == 0 {
+		last.Created = utils.NowUTC().Unix()
+	}
+
+	completionTokens := (n.completionChars + 3) / 4
+	data, err := json.Marshal(map[string]interface{}{
+		"id":      last.ID,
+		"object":  "chat.completion.chunk",
+		"created": last.Created,
+		"model":   last.Model,
+		"choices": []interface{}{},
+		"usage": map[string]int{
+			"prompt_tokens":     n.promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      n.promptTokens + completionTokens,
+		},
+	})
+	if err != nil {
+		return
+	}
+	n.sawUsage = true
+	n.out.WriteString("data: ")
+	n.out.Write(data)
+	n.out.WriteString("\n\n")
+}
+
+// finish completes a stream that ended cleanly: a trailing event without blank line is
+// normalized, and the usage chunk and [DONE] are added to chat streams missing them
+func (n *streamNormalizer) finish() {
+	if len(bytes.TrimSpace(n.in)) > 0 {
+		n.processEvent(bytes.TrimRight(n.in, "\r\n"), append(n.in, "\n\n"...))
+	}
+	n.in = nil
+	if n.chunks > 0 && !n.done {
+		n.writeUsageChunk()
+		n.done = true
+		n.out.WriteString("data: [DONE]\n\n")
+	}
+}
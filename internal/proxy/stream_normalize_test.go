@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func normalizeStream(t *testing.T, stream string, body string) string {
+	t.Helper()
+	n := newStreamNormalizer(io.NopCloser(iotest.OneByteReader(strings.NewReader(stream))), []byte(body))
+	out, err := io.ReadAll(n)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestStreamNormalizer(t *testing.T) {
+	const chunk = `{"id":"c1","object":"chat.completion.chunk","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hello world!"}}]}`
+
+	t.Run("standard stream unchanged", func(t *testing.T) {
+		stream := "data: " + chunk + "\n\ndata: [DONE]\n\n"
+		assert.Equal(t, stream, normalizeStream(t, stream, `{}`))
+	})
+
+	t.Run("event names and missing done", func(t *testing.T) {
+		stream := "event: chunk\nid: 1\ndata: " + chunk + "\r\n\r\n" +
+			": keep-alive\n\n" +
+			"event: error\ndata: {\"error\":{\"message\":\"boom\"}}\n\n"
+		assert.Equal(t, "data: "+chunk+"\n\n"+
+			": keep-alive\n\n"+
+			"data: {\"error\":{\"message\":\"boom\"}}\n\n"+
+			"data: [DONE]\n\n", normalizeStream(t, stream, `{}`))
+	})
+
+	t.Run("trailing event without blank line", func(t *testing.T) {
+		assert.Equal(t, "data: "+chunk+"\n\ndata: [DONE]\n\n", normalizeStream(t, "data: "+chunk, `{}`))
+	})
+
+	t.Run("synthesized usage", func(t *testing.T) {
+		body := `{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"12345678"}]}`
+		events := strings.Split(strings.TrimSuffix(normalizeStream(t, "data: "+chunk+"\n\ndata: [DONE]\n\n", body), "\n\n"), "\n\n")
+		require.Len(t, events, 3)
+		assert.Equal(t, "data: [DONE]", events[2])
+
+		var usageChunk struct {
+			ID      string        `json:"id"`
+			Object  string        `json:"object"`
+			Model   string        `json:"model"`
+			Choices []interface{} `json:"choices"`
+			Usage   struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+				TotalTokens      int `json:"total_tokens"`
+			} `json:"usage"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[1], "data: ")), &usageChunk))
+		assert.Equal(t, "c1", usageChunk.ID)
+		assert.Equal(t, "chat.completion.chunk", usageChunk.Object)
+		assert.Equal(t, "gpt-4o", usageChunk.Model)
+		assert.Empty(t, usageChunk.Choices)
+		assert.Equal(t, estimatePromptTokens([]byte(body)), usageChunk.Usage.PromptTokens)
+		assert.Equal(t, 3, usageChunk.Usage.CompletionTokens, "12 characters of content")
+		assert.Equal(t, usageChunk.Usage.PromptTokens+3, usageChunk.Usage.TotalTokens)
+	})
+
+	t.Run("reported usage kept", func(t *testing.T) {
+		body := `{"stream_options":{"include_usage":true}}`
+		usage := `{"id":"c1","object":"chat.completion.chunk","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`
+		stream := "data: " + chunk + "\n\ndata: " + usage + "\n\n"
+		assert.Equal(t, stream+"data: [DONE]\n\n", normalizeStream(t, stream, body))
+	})
+
+	t.Run("responses api events unchanged", func(t *testing.T) {
+		stream := "event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"hi\"}\n\n"
+		assert.Equal(t, stream, normalizeStream(t, stream, `{"stream_options":{"include_usage":true}}`))
+	})
+
+	t.Run("read error not completed", func(t *testing.T) {
+		readErr := errors.New("connection reset")
+		n := newStreamNormalizer(io.NopCloser(io.MultiReader(strings.NewReader("data: "+chunk+"\n\ndata: {\"id\""), iotest.ErrReader(readErr))), nil)
+		out, err := io.ReadAll(n)
+		assert.ErrorIs(t, err, readErr)
+		assert.Equal(t, "data: "+chunk+"\n\ndata: {\"id\"", string(out))
+	})
+}
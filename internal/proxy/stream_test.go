@@ -735,6 +735,16 @@ func TestAnthropicStreamUsageExtractor_CacheCreationTokens(t *testing.T) {
 	assert.Equal(t, 30, result.CachedTokens, "CachedTokens should equal CacheReadTokens (30)")
 }
 
+func TestOpenAIStreamUsageExtractor_WebSearchRequests(t *testing.T) {
+	extractor := &openAIStreamUsageExtractor{}
+
+	chunk := []byte(`data: {"choices":[{"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":100,"completion_tokens":20,"server_tool_use":{"web_search_requests":2}}}`)
+
+	result := extractor.ExtractUsage(chunk)
+	require.NotNil(t, result)
+	assert.Equal(t, 2, result.WebSearchRequests)
+}
+
 // TestOpenAIStreamUsageExtractor_MultiPayloadNoStaleData verifies that when a single
 // stream chunk contains multiple SSE data payloads (e.g. "data: {...}\ndata: {...}\n"),
 // the extractor does not carry over stale fields from the first payload into the result
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Metadata fields of LiteLLM keys and teams read by system_prompts
+const (
+	SystemPromptMetadataKey         = "system_prompt"
+	SystemPromptPositionMetadataKey = "system_prompt_position"
+)
+
+// systemPromptPolicy selects the system prompt added to a request (system_prompts)
+type systemPromptPolicy struct {
+	rules []systemPromptRule
+}
+
+type systemPromptRule struct {
+	keys     keyMatcher      // Key aliases and team IDs ("*" = all keys)
+	models   map[string]bool // nil = all models
+	prompt   string
+	position string
+}
+
+func newSystemPromptPolicy(cfg config.SystemPromptsConfig) *systemPromptPolicy {
+	policy := &systemPromptPolicy{rules: make([]systemPromptRule, 0, len(cfg.Rules))}
+	for _, rule := range cfg.Rules {
+		compiled := systemPromptRule{keys: newKeyMatcher(nil, rule.Keys), prompt: rule.Prompt, position: rule.Position}
+		if len(rule.Models) > 0 {
+			compiled.models = make(map[string]bool, len(rule.Models))
+			for _, model := range rule.Models {
+				compiled.models[model] = true
+			}
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy
+}
+
+// promptFor returns the prompt template, position and source ("key", "team" or "rule") of a
+// request: the system_prompt of the key metadata, then of the team metadata, then the first
+// rule matching the key and model. An empty prompt means none applies.
+func (s *systemPromptPolicy) promptFor(info *litellmdb.TokenInfo, modelID, realModelID string) (string, string, string) {
+	if info != nil {
+		if prompt, position := metadataSystemPrompt(info.Metadata); prompt != "" {
+			return prompt, position, "key"
+		}
+		if prompt, position := metadataSystemPrompt(info.TeamMetadata); prompt != "" {
+			return prompt, position, "team"
+		}
+	}
+	for _, rule := range s.rules {
+		modelMatch := rule.models == nil || rule.models[modelID] || rule.models[realModelID]
+		if rule.keys.matchesKey(info) && modelMatch {
+			return rule.prompt, rule.position, "rule"
+		}
+	}
+	return "", "", ""
+}
+
+// metadataSystemPrompt returns the system_prompt and system_prompt_position of a metadata dict
+// (position: prepend unless "append")
+func metadataSystemPrompt(metadata map[string]interface{}) (string, string) {
+	prompt, _ := metadata[SystemPromptMetadataKey].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return "", ""
+	}
+	position, _ := metadata[SystemPromptPositionMetadataKey].(string)
+	if position != config.SystemPromptAppend {
+		position = config.SystemPromptPrepend
+	}
+	return prompt, position
+}
+
+// renderSystemPrompt fills the placeholders of a prompt template
+func renderSystemPrompt(prompt string, info *litellmdb.TokenInfo, modelID string) string {
+	var keyAlias, teamID, teamAlias, userID string
+	if info != nil {
+		keyAlias, teamID, teamAlias, userID = info.KeyAlias, info.TeamID, info.TeamAlias, info.UserID
+	}
+	return strings.NewReplacer(
+		"{{key_alias}}", keyAlias,
+		"{{team_id}}", teamID,
+		"{{team_alias}}", teamAlias,
+		"{{user_id}}", userID,
+		"{{model}}", modelID,
+		"{{date}}", utils.NowUTC().Format("2006-01-02"),
+	).Replace(prompt)
+}
+
+// insertSystemMessage adds a system message before the first or after the last message of a
+// Chat Completions body. Bodies without a messages array are returned unchanged (false).
+func insertSystemMessage(body []byte, prompt, position string) ([]byte, bool) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return body, false
+	}
+	var messages []json.RawMessage
+	if err := json.Unmarshal(raw["messages"], &messages); err != nil || messages == nil {
+		return body, false
+	}
+
+	system, err := json.Marshal(map[string]string{"role": "system", "content": prompt})
+	if err != nil {
+		return body, false
+	}
+	if position == config.SystemPromptAppend {
+		messages = append(messages, system)
+	} else {
+		messages = append([]json.RawMessage{system}, messages...)
+	}
+
+	if raw["messages"], err = json.Marshal(messages); err != nil {
+		return body, false
+	}
+	modified, err := json.Marshal(raw)
+	if err != nil {
+		return body, false
+	}
+	return modified, true
+}
+
+// applySystemPrompt adds the system prompt of the request's key, team or system_prompts rule
+// to a Chat Completions request (after Responses API conversion, before context management)
+func (p *Proxy) applySystemPrompt(r *http.Request, body []byte, modelID, realModelID string, logCtx *RequestLogContext) []byte {
+	if p.systemPrompts == nil || isInternalRequest(r.Context()) || !strings.Contains(r.URL.Path, "/chat/completions") {
+		return body
+	}
+	prompt, position, source := p.systemPrompts.promptFor(logCtx.TokenInfo, modelID, realModelID)
+	if prompt == "" {
+		return body
+	}
+
+	newBody, ok := insertSystemMessage(body, renderSystemPrompt(prompt, logCtx.TokenInfo, modelID), position)
+	if !ok {
+		logCtx.Logger().Debug("Request has no messages, system prompt not added", "source", source)
+		return body
+	}
+	monitoring.SystemPromptsInjectedTotal.WithLabelValues(source).Inc()
+	logCtx.Logger().Debug("Added system prompt", "source", source, "position", position)
+	return newBody
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemPromptPolicy_PromptFor(t *testing.T) {
+	policy := newSystemPromptPolicy(config.SystemPromptsConfig{Enabled: true, Rules: []config.SystemPromptConfig{
+		{Keys: []string{"team-support"}, Models: []string{"gpt-4o"}, Prompt: "support gpt-4o", Position: config.SystemPromptPrepend},
+		{Keys: []string{"team-support"}, Prompt: "support", Position: config.SystemPromptAppend},
+		{Keys: []string{"*"}, Models: []string{"claude-sonnet-4-5"}, Prompt: "everyone", Position: config.SystemPromptPrepend},
+	}})
+
+	tests := []struct {
+		name         string
+		info         *litellmdb.TokenInfo
+		model        string
+		wantPrompt   string
+		wantPosition string
+		wantSource   string
+	}{
+		{"team rule for model", &litellmdb.TokenInfo{TeamID: "team-support"}, "gpt-4o", "support gpt-4o", "prepend", "rule"},
+		{"team rule for other models", &litellmdb.TokenInfo{TeamID: "team-support"}, "gpt-4o-mini", "support", "append", "rule"},
+		{"key alias matches", &litellmdb.TokenInfo{KeyAlias: "team-support"}, "gpt-4o-mini", "support", "append", "rule"},
+		{"all keys rule", nil, "claude-sonnet-4-5", "everyone", "prepend", "rule"},
+		{"no rule", &litellmdb.TokenInfo{KeyAlias: "other"}, "gpt-4o", "", "", ""},
+		{
+			"key metadata wins",
+			&litellmdb.TokenInfo{TeamID: "team-support", Metadata: map[string]interface{}{"system_prompt": "from key", "system_prompt_position": "append"},
+				TeamMetadata: map[string]interface{}{"system_prompt": "from team"}},
+			"gpt-4o", "from key", "append", "key",
+		},
+		{
+			"team metadata before rules",
+			&litellmdb.TokenInfo{TeamID: "team-support", Metadata: map[string]interface{}{"system_prompt": 42}, TeamMetadata: map[string]interface{}{"system_prompt": "from team"}},
+			"gpt-4o", "from team", "prepend", "team",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, position, source := policy.promptFor(tt.info, tt.model, tt.model)
+			assert.Equal(t, tt.wantPrompt, prompt)
+			assert.Equal(t, tt.wantPosition, position)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+}
+
+func TestRenderSystemPrompt(t *testing.T) {
+	info := &litellmdb.TokenInfo{KeyAlias: "ci-bot", TeamID: "t-1", TeamAlias: "Platform", UserID: "u-7"}
+	got := renderSystemPrompt("{{key_alias}}/{{team_id}}/{{team_alias}}/{{user_id}} on {{model}} at {{date}}", info, "gpt-4o")
+	assert.Equal(t, "ci-bot/t-1/Platform/u-7 on gpt-4o at "+utils.NowUTC().Format("2006-01-02"), got)
+	assert.Equal(t, "key  on gpt-4o", renderSystemPrompt("key {{key_alias}} on {{model}}", nil, "gpt-4o"))
+}
+
+func TestInsertSystemMessage(t *testing.T) {
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"system","content":"client"},{"role":"user","content":"Hi"}],"temperature":0.2}`)
+
+	prepended, ok := insertSystemMessage(body, "policy <rules>", config.SystemPromptPrepend)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"model":"gpt-4o","messages":[{"role":"system","content":"policy <rules>"},{"role":"system","content":"client"},{"role":"user","content":"Hi"}],"temperature":0.2}`, string(prepended))
+
+	appended, ok := insertSystemMessage(body, "policy", config.SystemPromptAppend)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"model":"gpt-4o","messages":[{"role":"system","content":"client"},{"role":"user","content":"Hi"},{"role":"system","content":"policy"}],"temperature":0.2}`, string(appended))
+
+	for _, unchanged := range []string{`{"model":"text-embedding-3-small","input":"Hi"}`, `not json`} {
+		out, ok := insertSystemMessage([]byte(unchanged), "policy", config.SystemPromptPrepend)
+		assert.False(t, ok)
+		assert.Equal(t, unchanged, string(out))
+	}
+}
+
+func TestProxyRequest_SystemPrompts(t *testing.T) {
+	var received []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("openai", config.ProviderTypeOpenAI, upstream.URL, "sk-test").
+		WithMasterKey("master-key").
+		Build()
+	prx.systemPrompts = newSystemPromptPolicy(config.SystemPromptsConfig{Enabled: true, Rules: []config.SystemPromptConfig{
+		{Keys: []string{"*"}, Prompt: "You are an internal assistant. Model: {{model}}", Position: config.SystemPromptPrepend},
+	}})
+	injected := testutil.ToFloat64(monitoring.SystemPromptsInjectedTotal.WithLabelValues("rule"))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var sent struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(received, &sent))
+	require.Len(t, sent.Messages, 2)
+	assert.Equal(t, "system", sent.Messages[0].Role)
+	assert.Equal(t, "You are an internal assistant. Model: gpt-4o", sent.Messages[0].Content)
+	assert.Equal(t, "Hi", sent.Messages[1].Content)
+	assert.Equal(t, injected+1, testutil.ToFloat64(monitoring.SystemPromptsInjectedTotal.WithLabelValues("rule")))
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+)
+
+// tenantPolicy maps requests to tenants and tenants to their credential pools (tenants)
+type tenantPolicy struct {
+	keys       map[string]string // LiteLLM key alias -> tenant
+	teams      map[string]string // LiteLLM team ID -> tenant
+	owners     map[string]string // Credential -> tenant
+	rpm        map[string]int    // Tenant -> requests per minute (0 = unlimited)
+	masterKeys []tenantMasterKey
+}
+
+// tenantMasterKey is a master key authenticating as a tenant
+type tenantMasterKey struct {
+	secret []byte
+	tenant string
+}
+
+func newTenantPolicy(tenants []config.TenantConfig) *tenantPolicy {
+	tp := &tenantPolicy{
+		keys:   make(map[string]string),
+		teams:  make(map[string]string),
+		owners: make(map[string]string),
+		rpm:    make(map[string]int, len(tenants)),
+	}
+	for _, tenant := range tenants {
+		for _, key := range tenant.MasterKeys {
+			tp.masterKeys = append(tp.masterKeys, tenantMasterKey{secret: []byte(key), tenant: tenant.Name})
+		}
+		for _, alias := range tenant.Keys {
+			tp.keys[alias] = tenant.Name
+		}
+		for _, team := range tenant.Teams {
+			tp.teams[team] = tenant.Name
+		}
+		for _, cred := range tenant.Credentials {
+			tp.owners[cred] = tenant.Name
+		}
+		tp.rpm[tenant.Name] = tenant.RPM
+	}
+	return tp
+}
+
+// matchMasterKey returns the tenant whose master key equals token
+func (tp *tenantPolicy) matchMasterKey(token string) (string, bool) {
+	if tp == nil || token == "" {
+		return "", false
+	}
+	var tenant string
+	var found bool
+	for _, key := range tp.masterKeys {
+		if subtle.ConstantTimeCompare(key.secret, []byte(token)) == 1 {
+			tenant, found = key.tenant, true
+		}
+	}
+	return tenant, found
+}
+
+// tenantFor returns the tenant of the request: the tenant of its master key, then of its
+// key alias, then of its team ("" = no tenant)
+func (tp *tenantPolicy) tenantFor(logCtx *RequestLogContext) string {
+	if logCtx.Tenant != "" {
+		return logCtx.Tenant
+	}
+	if info := logCtx.TokenInfo; info != nil {
+		if tenant, ok := tp.keys[info.KeyAlias]; ok && info.KeyAlias != "" {
+			return tenant
+		}
+		if tenant, ok := tp.teams[info.TeamID]; ok && info.TeamID != "" {
+			return tenant
+		}
+	}
+	return ""
+}
+
+// applyTenant resolves the request's tenant, enforces the tenant rpm and marks the
+// credentials outside the tenant's pool as tried, so the initial selection, retries and
+// fallbacks never leave the pool. Requests without a tenant use the credentials not
+// assigned to any tenant. Returns false after writing 429.
+func (p *Proxy) applyTenant(w http.ResponseWriter, r *http.Request, logCtx *RequestLogContext) bool {
+	if p.tenants == nil {
+		return true
+	}
+	logCtx.Tenant = p.tenants.tenantFor(logCtx)
+
+	// Internal requests (conversation summaries) are counted with the request they serve
+	if rpm := p.tenants.rpm[logCtx.Tenant]; logCtx.Tenant != "" && rpm > 0 && !isInternalRequest(r.Context()) &&
+		!p.rateLimiter.AllowKeys(ratelimit.KeyLimit{Name: "tenant:" + logCtx.Tenant, RPM: rpm}) {
+		logCtx.Status = "failure"
+		logCtx.HTTPStatus = http.StatusTooManyRequests
+		logCtx.ErrorMsg = "Tenant rate limit exceeded"
+		logCtx.Credential = &config.CredentialConfig{
+			Name: "system",
+			Type: config.ProviderTypeProxy,
+		}
+
+		logCtx.Logger().Warn("Request rejected by tenant rate limit",
+			"tenant", logCtx.Tenant,
+		)
+		WriteErrorRateLimit(w, "Rate limit exceeded for this tenant, please retry later")
+		return false
+	}
+
+	triedCreds := GetTried(r.Context())
+	for _, cred := range p.balancer.GetCredentialsSnapshot() {
+		if p.tenants.owners[cred.Name] != logCtx.Tenant {
+			triedCreds[cred.Name] = true
+		}
+	}
+	return true
+}
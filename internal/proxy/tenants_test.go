@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTenantProxy creates a proxy with credentials oai1 (tenant alpha), oai2 (tenant beta) and
+// oai3 (no tenant)
+func newTenantProxy(t *testing.T, calls []*int32, alphaRPM int) *Proxy {
+	t.Helper()
+	prx := newChatProxy(t, calls, nil)
+	prx.tenants = newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", MasterKeys: []string{"alpha-key"}, Credentials: []string{"oai1"}, RPM: alphaRPM},
+		{Name: "beta", MasterKeys: []string{"beta-key"}, Credentials: []string{"oai2"}},
+	})
+	return prx
+}
+
+func TestProxyRequest_TenantCredentialPools(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		wantHits []int32
+	}{
+		{name: "tenant alpha", token: "alpha-key", wantHits: []int32{3, 0, 0}},
+		{name: "tenant beta", token: "beta-key", wantHits: []int32{0, 3, 0}},
+		{name: "server master key", token: "master-key", wantHits: []int32{0, 0, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []*int32{new(int32), new(int32), new(int32)}
+			prx := newTenantProxy(t, calls, 0)
+
+			for i := 0; i < 3; i++ {
+				w := sendChatRequest(prx, tt.token, nil)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			}
+			for i, want := range tt.wantHits {
+				assert.Equal(t, want, atomic.LoadInt32(calls[i]), "credential oai%d", i+1)
+			}
+		})
+	}
+}
+
+func TestProxyRequest_TenantUnknownKey(t *testing.T) {
+	calls := []*int32{new(int32), new(int32), new(int32)}
+	prx := newTenantProxy(t, calls, 0)
+
+	w := sendChatRequest(prx, "gamma-key", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, 0, hitCredentials(calls))
+}
+
+func TestProxyRequest_TenantRateLimit(t *testing.T) {
+	calls := []*int32{new(int32), new(int32), new(int32)}
+	prx := newTenantProxy(t, calls, 2)
+
+	for i := 0; i < 2; i++ {
+		w := sendChatRequest(prx, "alpha-key", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	w := sendChatRequest(prx, "alpha-key", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "tenant")
+
+	// Other tenants have their own limit
+	w = sendChatRequest(prx, "beta-key", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls[0]))
+}
+
+func TestProxyRequest_TenantPreferCredentialOfOtherTenant(t *testing.T) {
+	calls := []*int32{new(int32), new(int32), new(int32)}
+	prx := newTenantProxy(t, calls, 0)
+	prx.routingOverrides = newRoutingOverridePolicy(prx.masterKeys, []string{AllKeys})
+
+	w := sendChatRequest(prx, "alpha-key", map[string]string{PreferCredentialHeader: "oai2"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, hitCredentials(calls))
+
+	w = sendChatRequest(prx, "alpha-key", map[string]string{PreferCredentialHeader: "oai1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls[0]))
+}
+
+func TestTenantPolicy_TenantFor(t *testing.T) {
+	policy := newTenantPolicy([]config.TenantConfig{
+		{Name: "alpha", Keys: []string{"alpha-app"}, Teams: []string{"team-a"}, Credentials: []string{"oai1"}},
+		{Name: "beta", Keys: []string{"beta-app"}, Teams: []string{"team-b"}, Credentials: []string{"oai2"}},
+	})
+
+	assert.Equal(t, "alpha", policy.tenantFor(&RequestLogContext{TokenInfo: &models.TokenInfo{KeyAlias: "alpha-app"}}))
+	assert.Equal(t, "beta", policy.tenantFor(&RequestLogContext{TokenInfo: &models.TokenInfo{TeamID: "team-b"}}))
+	// The key alias wins over the team
+	assert.Equal(t, "beta", policy.tenantFor(&RequestLogContext{TokenInfo: &models.TokenInfo{KeyAlias: "beta-app", TeamID: "team-a"}}))
+	assert.Equal(t, "alpha", policy.tenantFor(&RequestLogContext{Tenant: "alpha"}))
+	assert.Empty(t, policy.tenantFor(&RequestLogContext{TokenInfo: &models.TokenInfo{KeyAlias: "other"}}))
+	assert.Empty(t, policy.tenantFor(&RequestLogContext{}))
+
+	_, ok := policy.matchMasterKey("alpha-key")
+	assert.False(t, ok)
+	var none *tenantPolicy
+	_, ok = none.matchMasterKey("alpha-key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// upstreamAcceptEncoding is sent to upstreams with server.upstream_compression. Without it Go's
+// Transport asks for gzip only and decodes it itself.
+const upstreamAcceptEncoding = "gzip, deflate, br, zstd"
+
+// maxZstdWindow bounds the memory of a zstd decoder. RFC 9659 limits the window of the zstd
+// Content-Encoding to 8 MB.
+const maxZstdWindow = 8 << 20
+
+// contentDecoder returns the decoder of a Content-Encoding (nil for identity and unknown encodings)
+func contentDecoder(encoding string) func(io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		return func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case "deflate":
+		return func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil }
+	case "br":
+		return func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(brotli.NewReader(r)), nil }
+	case "zstd":
+		return func(r io.Reader) (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(maxZstdWindow))
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		}
+	}
+	return nil
+}
+
+// decodeUpstreamResponse replaces the body of a response the Transport did not decode with its
+// decoded content, so converters, token extraction and streaming see plain bytes. Unknown
+// encodings are left as they are.
+func decodeUpstreamResponse(resp *http.Response) {
+	if resp == nil || resp.Uncompressed {
+		return
+	}
+	newDecoder := contentDecoder(resp.Header.Get("Content-Encoding"))
+	if newDecoder == nil {
+		return
+	}
+	resp.Body = &decodedBody{body: resp.Body, newDecoder: newDecoder}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decodedBody decodes an upstream response body as it is read. The decoder is created by the
+// first Read, so a stream is not read before its consumer asks for it.
+type decodedBody struct {
+	body       io.ReadCloser
+	newDecoder func(io.Reader) (io.ReadCloser, error)
+	decoder    io.ReadCloser
+	err        error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.decoder == nil && b.err == nil {
+		b.decoder, b.err = b.newDecoder(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.decoder.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if b.decoder != nil {
+		_ = b.decoder.Close()
+	}
+	return b.body.Close()
+}
+
+// decodeResponseBody decodes a buffered response body based on Content-Encoding. At most
+// maxSize decoded bytes are produced; a body that cannot be decoded within the limit is
+// returned as-is.
+func decodeResponseBody(body []byte, encoding string, maxSize int64) string {
+	newDecoder := contentDecoder(encoding)
+	if newDecoder == nil {
+		return string(body)
+	}
+	reader, err := newDecoder(bytes.NewReader(body))
+	if err != nil {
+		return string(body) // Return as-is if can't decode
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil || int64(len(decoded)) > maxSize {
+		return string(body) // Return as-is if can't read or too large
+	}
+	return string(decoded)
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createBrotliBody(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	writer := brotli.NewWriter(&buf)
+	_, err := writer.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func createZstdBody(t *testing.T, data string) []byte {
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer func() { _ = encoder.Close() }()
+	return encoder.EncodeAll([]byte(data), nil)
+}
+
+func TestDecodeResponseBody_BrotliAndZstd(t *testing.T) {
+	assert.Equal(t, "brotli text", decodeResponseBody(createBrotliBody(t, "brotli text"), "br", 1<<20))
+	assert.Equal(t, "zstd text", decodeResponseBody(createZstdBody(t, "zstd text"), "ZSTD", 1<<20))
+	assert.Equal(t, "not zstd", decodeResponseBody([]byte("not zstd"), "zstd", 1<<20), "undecodable bodies are returned as-is")
+}
+
+func TestDecodeResponseBody_SizeLimit(t *testing.T) {
+	plain := strings.Repeat("a", 4096)
+	for encoding, body := range map[string][]byte{
+		"gzip": createGzipBody(plain),
+		"br":   createBrotliBody(t, plain),
+		"zstd": createZstdBody(t, plain),
+	} {
+		assert.Equal(t, plain, decodeResponseBody(body, encoding, 4096), encoding)
+		assert.Equal(t, string(body), decodeResponseBody(body, encoding, 1024), "%s: decoding stops at the limit", encoding)
+	}
+}
+
+func TestProxyRequest_DecodesUpstreamEncodings(t *testing.T) {
+	const response = `{"id":"1","choices":[{"message":{"content":"decoded"}}],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`
+	const stream = "data: {\"choices\":[{\"delta\":{\"content\":\"decoded\"}}]}\n\ndata: [DONE]\n\n"
+
+	for _, encoding := range []string{"br", "zstd"} {
+		for _, streaming := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%v", encoding, streaming), func(t *testing.T) {
+				var acceptEncoding string
+				upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					acceptEncoding = r.Header.Get("Accept-Encoding")
+					body := response
+					if streaming {
+						body = stream
+						w.Header().Set("Content-Type", "text/event-stream")
+					} else {
+						w.Header().Set("Content-Type", "application/json")
+					}
+					encoded := createZstdBody(t, body)
+					if encoding == "br" {
+						encoded = createBrotliBody(t, body)
+					}
+					w.Header().Set("Content-Encoding", encoding)
+					_, _ = w.Write(encoded)
+				}))
+				defer upstream.Close()
+
+				prx := NewTestProxyBuilder().
+					WithSingleCredential("test", config.ProviderTypeOpenAI, upstream.URL, "key").
+					WithMasterKey("master-key").
+					Build()
+				prx.upstreamCompression = true
+
+				req := httptest.NewRequest("POST", "/v1/chat/completions",
+					strings.NewReader(fmt.Sprintf(`{"model":"gpt-4o","stream":%v,"messages":[{"role":"user","content":"Hi"}]}`, streaming)))
+				req.Header.Set("Authorization", "Bearer master-key")
+				w := httptest.NewRecorder()
+				prx.ProxyRequest(w, req)
+
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				assert.Equal(t, upstreamAcceptEncoding, acceptEncoding)
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+				assert.Contains(t, w.Body.String(), "decoded")
+			})
+		}
+	}
+}
+
+func TestProxyRequest_UpstreamCompressionDisabled(t *testing.T) {
+	var acceptEncoding string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	prx := NewTestProxyBuilder().
+		WithSingleCredential("test", config.ProviderTypeOpenAI, upstream.URL, "key").
+		WithMasterKey("master-key").
+		Build()
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+	req.Header.Set("Authorization", "Bearer master-key")
+	req.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+	prx.ProxyRequest(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", acceptEncoding, "the Transport's own gzip negotiation is kept")
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+var (
+	// stackTraceLine matches the frames and headers of Python, Java/JS, Go and .NET stack traces
+	stackTraceLine = regexp.MustCompile(`(?i)^\s*(?:traceback \(most recent call last\)|file "[^"]*", line \d+|at [\w$.<>\[\]/-]+[(:]|goroutine \d+ \[|(?:[\w.-]*/)*[\w.-]+\.(?:go|py|java|js|ts|rb|cs|kt|scala):\d+|\.\.\. \d+ more)`)
+	urlPattern     = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^\s"'<>]+`)
+	ipPattern      = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`)
+	hostPattern    = regexp.MustCompile(`\b(?:localhost|(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+(?:internal|local|lan|svc|corp|intranet|localdomain)|(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.){2,}[a-z]{2,})(?::\d+)?\b`)
+	htmlTag        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// wrapUpstreamError replaces an upstream 4xx/5xx body by the standard error envelope
+// (upstream_errors). The detail carries the upstream error message without hostnames, IP
+// addresses, URLs and stack traces, truncated to max_detail_length; the full upstream body
+// is logged.
+func (p *Proxy) wrapUpstreamError(statusCode int, body []byte, log *slog.Logger) []byte {
+	log.Warn("Wrapped upstream error response",
+		"status", statusCode, "body", string(body))
+
+	resp := APIErrorResponse{
+		Error: APIError{
+			Message: fmt.Sprintf("Upstream provider returned HTTP %d", statusCode),
+			Type:    errorTypeForStatus(statusCode),
+			Detail:  sanitizeErrorDetail(upstreamErrorMessage(body), p.upstreamErrors.MaxDetailLength),
+		},
+	}
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+// upstreamErrorMessage returns the message of an upstream error body: error.message,
+// a string error, message or detail of a JSON body, or else the body text without markup
+func upstreamErrorMessage(body []byte) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "":
+			return nested.Message
+		case json.Unmarshal(parsed.Error, &text) == nil && text != "":
+			return text
+		case parsed.Message != "":
+			return parsed.Message
+		case json.Unmarshal(parsed.Detail, &text) == nil && text != "":
+			return text
+		}
+	}
+	return htmlTag.ReplaceAllString(string(body), " ")
+}
+
+// sanitizeErrorDetail drops stack trace lines and the indented lines following them,
+// redacts URLs, IP addresses and hostnames, collapses whitespace and truncates the
+// result to maxLength characters
+func sanitizeErrorDetail(message string, maxLength int) string {
+	kept := make([]string, 0, 4)
+	inTrace := false
+	for _, line := range strings.Split(message, "\n") {
+		if stackTraceLine.MatchString(line) {
+			inTrace = true
+			continue
+		}
+		if inTrace && strings.TrimLeft(line, " \t") != line {
+			continue
+		}
+		inTrace = false
+		kept = append(kept, line)
+	}
+
+	detail := strings.Join(kept, " ")
+	detail = urlPattern.ReplaceAllString(detail, "[url]")
+	detail = ipPattern.ReplaceAllString(detail, "[ip]")
+	detail = hostPattern.ReplaceAllString(detail, "[host]")
+	detail = strings.Join(strings.Fields(detail), " ")
+
+	if runes := []rune(detail); maxLength > 0 && len(runes) > maxLength {
+		detail = strings.TrimRight(string(runes[:maxLength]), " ") + "..."
+	}
+	return detail
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"openai", `{"error":{"message":"Invalid model","type":"invalid_request_error"}}`, "Invalid model"},
+		{"string error", `{"error":"model not loaded"}`, "model not loaded"},
+		{"message", `{"message":"Too many tokens"}`, "Too many tokens"},
+		{"fastapi detail", `{"detail":"Not Found"}`, "Not Found"},
+		{"html", `<html><body><h1>502 Bad Gateway</h1></body></html>`, "502 Bad Gateway"},
+		{"plain text", "upstream connect error", "upstream connect error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, strings.TrimSpace(sanitizeErrorDetail(upstreamErrorMessage([]byte(tt.body)), 0)))
+		})
+	}
+}
+
+func TestSanitizeErrorDetail(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"hostname and port", "connection refused: vllm-0.gpu.svc.cluster.local:8000", "connection refused: [host]"},
+		{"internal hostname", "backend inference.internal unavailable", "backend [host] unavailable"},
+		{"ip address", "dial tcp 10.0.3.17:8080: i/o timeout", "dial tcp [ip]: i/o timeout"},
+		{"url", "GET http://tgi.corp.example.com/generate failed", "GET [url] failed"},
+		{"model names kept", "gpt-4.1 and claude-3.5-sonnet are not available", "gpt-4.1 and claude-3.5-sonnet are not available"},
+		{
+			"python traceback",
+			"Traceback (most recent call last):\n  File \"/srv/app/server.py\", line 12, in handle\n    raise ValueError(msg)\nValueError: prompt too long",
+			"ValueError: prompt too long",
+		},
+		{
+			"java stack trace",
+			"java.lang.IllegalStateException: model busy\n\tat com.acme.Inference.run(Inference.java:42)\n\tat com.acme.Server.handle(Server.java:10)\n\t... 3 more",
+			"java.lang.IllegalStateException: model busy",
+		},
+		{"go panic", "runtime error: index out of range\ngoroutine 1 [running]:\n\t/app/main.go:15 +0x1d", "runtime error: index out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeErrorDetail(tt.message, 0))
+		})
+	}
+
+	assert.Equal(t, "абв...", sanitizeErrorDetail("абвгд", 3), "truncated by characters")
+	assert.Equal(t, "model not...", sanitizeErrorDetail("model not loaded", 10))
+	assert.Equal(t, "short", sanitizeErrorDetail("short", 10))
+}
+
+func TestProxyRequest_WrapsUpstreamErrors(t *testing.T) {
+	upstreamBody := `{"error":{"message":"model llama-70b not loaded on http://10.1.2.3:8000/v1","type":"invalid_request_error"}}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(upstreamBody))
+	}))
+	t.Cleanup(upstream.Close)
+
+	for _, wrap := range []bool{false, true} {
+		prx := NewTestProxyBuilder().
+			WithSingleCredential("vllm", config.ProviderTypeOpenAI, upstream.URL, "sk-test").
+			WithMasterKey("master-key").
+			Build()
+		prx.upstreamErrors = config.UpstreamErrorsConfig{Wrap: wrap, MaxDetailLength: config.DefaultUpstreamErrorsMaxDetailLength}
+
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"llama-70b","messages":[{"role":"user","content":"Hi"}]}`))
+		req.Header.Set("Authorization", "Bearer master-key")
+		w := httptest.NewRecorder()
+		prx.ProxyRequest(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		if !wrap {
+			assert.JSONEq(t, upstreamBody, w.Body.String(), "passed through verbatim by default")
+			continue
+		}
+
+		var resp APIErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		assert.Equal(t, "Upstream provider returned HTTP 400", resp.Error.Message)
+		assert.Equal(t, "invalid_request_error", resp.Error.Type)
+		assert.Equal(t, "model llama-70b not loaded on [url]", resp.Error.Detail)
+		assert.NotContains(t, w.Body.String(), "10.1.2.3")
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestWriteProxyResponse_WrapsUpstreamErrors(t *testing.T) {
+	prx := NewTestProxyBuilder().WithMasterKey("master-key").Build()
+	prx.upstreamErrors = config.UpstreamErrorsConfig{Wrap: true, MaxDetailLength: 100}
+
+	resp := &ProxyResponse{
+		StatusCode: http.StatusBadGateway,
+		Headers:    http.Header{"Content-Type": []string{"text/html"}},
+		Body:       []byte("<html><body>502 Bad Gateway from nginx at lb-1.prod.example.net</body></html>"),
+	}
+	w := httptest.NewRecorder()
+	prx.writeProxyResponse(w, resp, httptest.NewRequest("POST", "/v1/chat/completions", nil), "router")
+
+	require.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var body APIErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "api_error", body.Error.Type)
+	assert.Equal(t, "502 Bad Gateway from nginx at [host]", body.Error.Detail)
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
@@ -12,10 +13,11 @@ import (
 // Uses mutex for thread-safe access to health state.
 type Tracker struct {
 	mu                   sync.RWMutex
-	healthStatus         map[string]bool      // true = healthy, false = unhealthy
-	failureCount         map[string]int       // consecutive failures per proxy
-	lastStatusChangeTime map[string]time.Time // track when status changed
-	recovered            map[string]bool      // one-shot recovered markers
+	healthStatus         map[string]bool                      // true = healthy, false = unhealthy
+	failureCount         map[string]int                       // consecutive failures per proxy
+	lastStatusChangeTime map[string]time.Time                 // track when status changed
+	recovered            map[string]bool                      // one-shot recovered markers
+	reports              map[string]httputil.DownstreamHealth // last /health report per proxy
 }
 
 // NewTracker creates a new proxy health tracker with empty state.
@@ -25,6 +27,7 @@ func NewTracker() *Tracker {
 		failureCount:         make(map[string]int),
 		lastStatusChangeTime: make(map[string]time.Time),
 		recovered:            make(map[string]bool),
+		reports:              make(map[string]httputil.DownstreamHealth),
 	}
 }
 
@@ -33,7 +36,10 @@ func NewTracker() *Tracker {
 func (t *Tracker) RecordSuccess(proxyName string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.recordSuccessLocked(proxyName)
+}
 
+func (t *Tracker) recordSuccessLocked(proxyName string) {
 	wasHealthy := t.healthStatus[proxyName]
 	t.healthStatus[proxyName] = true
 	t.failureCount[proxyName] = 0
@@ -50,7 +56,10 @@ func (t *Tracker) RecordSuccess(proxyName string) {
 func (t *Tracker) RecordFailure(proxyName string, _ error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.recordFailureLocked(proxyName)
+}
 
+func (t *Tracker) recordFailureLocked(proxyName string) {
 	wasHealthy := t.healthStatus[proxyName]
 	t.failureCount[proxyName]++
 
@@ -67,6 +76,33 @@ func (t *Tracker) RecordFailure(proxyName string, _ error) {
 	}
 }
 
+// RecordHealth stores the /health report of a downstream router and records it as a
+// success (status healthy) or a failure (unhealthy or unreachable).
+func (t *Tracker) RecordHealth(proxyName string, report httputil.DownstreamHealth) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if report.Status == httputil.DownstreamHealthy {
+		t.recordSuccessLocked(proxyName)
+	} else {
+		t.recordFailureLocked(proxyName)
+	}
+	report.ConsecutiveFailures = t.failureCount[proxyName]
+	t.reports[proxyName] = report
+}
+
+// Report returns the last /health report of a downstream router; safe on a nil tracker.
+func (t *Tracker) Report(proxyName string) (httputil.DownstreamHealth, bool) {
+	if t == nil {
+		return httputil.DownstreamHealth{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	report, ok := t.reports[proxyName]
+	return report, ok
+}
+
 // GetFailureCount returns the number of consecutive failures for a proxy.
 func (t *Tracker) GetFailureCount(proxyName string) int {
 	t.mu.RLock()
@@ -75,8 +111,11 @@ func (t *Tracker) GetFailureCount(proxyName string) int {
 	return t.failureCount[proxyName]
 }
 
-// IsUnhealthy returns true if the proxy is marked as unhealthy.
+// IsUnhealthy returns true if the proxy is marked as unhealthy; safe on a nil tracker.
 func (t *Tracker) IsUnhealthy(proxyName string) bool {
+	if t == nil {
+		return false
+	}
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
@@ -3,7 +3,9 @@ package proxyhealth
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -129,3 +131,31 @@ func TestGetStatus_And_GetAllStatuses(t *testing.T) {
 	tracker.ResetFailureCount("proxy-failed")
 	assert.Equal(t, 0, tracker.GetFailureCount("proxy-failed"))
 }
+
+func TestRecordHealth(t *testing.T) {
+	tracker := NewTracker()
+	checkedAt := time.Now()
+
+	_, ok := tracker.Report("downstream")
+	assert.False(t, ok)
+
+	tracker.RecordHealth("downstream", httputil.DownstreamHealth{Status: httputil.DownstreamUnreachable, Error: "connection refused", CheckedAt: checkedAt})
+	tracker.RecordHealth("downstream", httputil.DownstreamHealth{Status: httputil.DownstreamUnhealthy, CheckedAt: checkedAt})
+	assert.True(t, tracker.IsUnhealthy("downstream"))
+	report, ok := tracker.Report("downstream")
+	assert.True(t, ok)
+	assert.Equal(t, httputil.DownstreamUnhealthy, report.Status)
+	assert.Equal(t, 2, report.ConsecutiveFailures)
+
+	tracker.RecordHealth("downstream", httputil.DownstreamHealth{Status: httputil.DownstreamHealthy, CredentialsAvailable: 3, TotalCredentials: 4})
+	assert.False(t, tracker.IsUnhealthy("downstream"))
+	assert.Equal(t, []string{"downstream"}, tracker.GetRecoveredNames())
+	report, _ = tracker.Report("downstream")
+	assert.Equal(t, 0, report.ConsecutiveFailures)
+	assert.Equal(t, 3, report.CredentialsAvailable)
+
+	var nilTracker *Tracker
+	assert.False(t, nilTracker.IsUnhealthy("downstream"))
+	_, ok = nilTracker.Report("downstream")
+	assert.False(t, ok)
+}
@@ -0,0 +1,160 @@
+// Package quota grants temporary rate limit and budget boosts to API keys and teams,
+// so short-lived business needs do not require config or LiteLLM DB edits.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Boost raises the RPM limit and/or max budget of a key or team by a percentage until it expires
+type Boost struct {
+	ID            string    `json:"id"`
+	Key           string    `json:"key,omitempty"`     // Key alias or hashed API key
+	TeamID        string    `json:"team_id,omitempty"` // Team ID
+	RPMPercent    float64   `json:"rpm_percent"`       // e.g. 50 = +50% RPM
+	BudgetPercent float64   `json:"budget_percent"`    // e.g. 50 = +50% max budget
+	Reason        string    `json:"reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Factors are the limit multipliers of the boosts active for a request (1 = no boost)
+type Factors struct {
+	KeyRPM     float64
+	TeamRPM    float64
+	KeyBudget  float64
+	TeamBudget float64
+}
+
+// Store keeps boosts in memory; they are lost when the router restarts.
+// A nil *Store is valid and grants nothing.
+type Store struct {
+	maxDuration time.Duration
+	now         func() time.Time
+
+	mu     sync.Mutex
+	boosts map[string]Boost
+}
+
+// NewStore creates a Store accepting boosts of up to maxDuration (0 = unlimited)
+func NewStore(maxDuration time.Duration) *Store {
+	return &Store{
+		maxDuration: maxDuration,
+		now:         utils.NowUTC,
+		boosts:      make(map[string]Boost),
+	}
+}
+
+// Grant validates a boost, sets its ID and expiry and stores it
+func (s *Store) Grant(b Boost, duration time.Duration) (Boost, error) {
+	if s == nil {
+		return Boost{}, errors.New("quota boosts are disabled")
+	}
+	if (b.Key == "") == (b.TeamID == "") {
+		return Boost{}, errors.New("exactly one of key and team_id is required")
+	}
+	if b.RPMPercent < 0 || b.BudgetPercent < 0 {
+		return Boost{}, errors.New("rpm_percent and budget_percent must not be negative")
+	}
+	if b.RPMPercent == 0 && b.BudgetPercent == 0 {
+		return Boost{}, errors.New("rpm_percent or budget_percent is required")
+	}
+	if duration <= 0 {
+		return Boost{}, errors.New("duration must be positive")
+	}
+	if s.maxDuration > 0 && duration > s.maxDuration {
+		return Boost{}, fmt.Errorf("duration %v exceeds max_duration %v", duration, s.maxDuration)
+	}
+
+	now := s.now()
+	b.ID = uuid.New().String()
+	b.CreatedAt = now
+	b.ExpiresAt = now.Add(duration)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(now)
+	s.boosts[b.ID] = b
+	return b, nil
+}
+
+// Revoke removes a boost before it expires; returns false if it does not exist
+func (s *Store) Revoke(id string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(s.now())
+	if _, ok := s.boosts[id]; !ok {
+		return false
+	}
+	delete(s.boosts, id)
+	return true
+}
+
+// List returns the active boosts ordered by expiry
+func (s *Store) List() []Boost {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(s.now())
+
+	boosts := make([]Boost, 0, len(s.boosts))
+	for _, b := range s.boosts {
+		boosts = append(boosts, b)
+	}
+	sort.Slice(boosts, func(i, j int) bool {
+		if !boosts[i].ExpiresAt.Equal(boosts[j].ExpiresAt) {
+			return boosts[i].ExpiresAt.Before(boosts[j].ExpiresAt)
+		}
+		return boosts[i].ID < boosts[j].ID
+	})
+	return boosts
+}
+
+// Factors returns the multipliers of the active boosts of a key (matched by alias or
+// hashed key) and its team. Percentages of several active boosts add up.
+func (s *Store) Factors(keyAlias, hashedKey, teamID string) Factors {
+	f := Factors{KeyRPM: 1, TeamRPM: 1, KeyBudget: 1, TeamBudget: 1}
+	if s == nil {
+		return f
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.boosts {
+		if !b.ExpiresAt.After(now) {
+			continue
+		}
+		switch {
+		case b.Key != "" && (b.Key == keyAlias || b.Key == hashedKey):
+			f.KeyRPM += b.RPMPercent / 100
+			f.KeyBudget += b.BudgetPercent / 100
+		case b.TeamID != "" && b.TeamID == teamID:
+			f.TeamRPM += b.RPMPercent / 100
+			f.TeamBudget += b.BudgetPercent / 100
+		}
+	}
+	return f
+}
+
+// pruneLocked removes expired boosts; callers hold s.mu
+func (s *Store) pruneLocked(now time.Time) {
+	for id, b := range s.boosts {
+		if !b.ExpiresAt.After(now) {
+			delete(s.boosts, id)
+		}
+	}
+}
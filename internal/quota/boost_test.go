@@ -0,0 +1,77 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(maxDuration time.Duration, now *time.Time) *Store {
+	s := NewStore(maxDuration)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestStore_Grant(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(48*time.Hour, &now)
+
+	b, err := s.Grant(Boost{Key: "campaign", RPMPercent: 50, Reason: "launch"}, 24*time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, b.ID)
+	assert.Equal(t, now, b.CreatedAt)
+	assert.Equal(t, now.Add(24*time.Hour), b.ExpiresAt)
+	assert.Equal(t, []Boost{b}, s.List())
+
+	tests := []struct {
+		boost    Boost
+		duration time.Duration
+		want     string
+	}{
+		{Boost{RPMPercent: 50}, time.Hour, "exactly one of key and team_id"},
+		{Boost{Key: "k", TeamID: "t", RPMPercent: 50}, time.Hour, "exactly one of key and team_id"},
+		{Boost{Key: "k"}, time.Hour, "rpm_percent or budget_percent is required"},
+		{Boost{Key: "k", RPMPercent: -10}, time.Hour, "must not be negative"},
+		{Boost{Key: "k", RPMPercent: 10}, 0, "duration must be positive"},
+		{Boost{Key: "k", RPMPercent: 10}, 72 * time.Hour, "exceeds max_duration"},
+	}
+	for _, tt := range tests {
+		_, err := s.Grant(tt.boost, tt.duration)
+		assert.ErrorContains(t, err, tt.want)
+	}
+}
+
+func TestStore_FactorsAndExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	s := newTestStore(0, &now)
+
+	_, err := s.Grant(Boost{Key: "campaign", RPMPercent: 50}, 24*time.Hour)
+	require.NoError(t, err)
+	_, err = s.Grant(Boost{Key: "hashed-key", RPMPercent: 25, BudgetPercent: 10}, time.Hour)
+	require.NoError(t, err)
+	teamBoost, err := s.Grant(Boost{TeamID: "team-1", BudgetPercent: 100}, 2*time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, Factors{KeyRPM: 1.75, TeamRPM: 1, KeyBudget: 1.1, TeamBudget: 2},
+		s.Factors("campaign", "hashed-key", "team-1"), "boosts of the alias and hashed key add up")
+	assert.Equal(t, Factors{KeyRPM: 1, TeamRPM: 1, KeyBudget: 1, TeamBudget: 1}, s.Factors("other", "", "team-2"))
+
+	now = now.Add(90 * time.Minute)
+	assert.Equal(t, Factors{KeyRPM: 1.5, TeamRPM: 1, KeyBudget: 1, TeamBudget: 2}, s.Factors("campaign", "hashed-key", "team-1"))
+	assert.Len(t, s.List(), 2, "expired boosts are removed")
+
+	assert.True(t, s.Revoke(teamBoost.ID))
+	assert.False(t, s.Revoke(teamBoost.ID))
+	assert.Equal(t, 1.0, s.Factors("", "", "team-1").TeamBudget)
+}
+
+func TestStore_Nil(t *testing.T) {
+	var s *Store
+	_, err := s.Grant(Boost{Key: "k", RPMPercent: 10}, time.Hour)
+	assert.Error(t, err)
+	assert.False(t, s.Revoke("id"))
+	assert.Nil(t, s.List())
+	assert.Equal(t, Factors{KeyRPM: 1, TeamRPM: 1, KeyBudget: 1, TeamBudget: 1}, s.Factors("k", "", ""))
+}
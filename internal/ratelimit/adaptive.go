@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// AdaptiveConfig tunes AIMD (additive increase, multiplicative decrease) adjustment
+// of effective RPM/TPM limits based on upstream 429 responses.
+//
+// On a 429 the limit factor is multiplied by DecreaseFactor (at most once per Cooldown).
+// After IncreaseInterval without a 429, each successful response raises the factor
+// by IncreaseStep until the configured limits are restored.
+type AdaptiveConfig struct {
+	DecreaseFactor   float64       // Multiplier applied on 429, in (0, 1)
+	IncreaseStep     float64       // Fraction of the configured limit restored per IncreaseInterval, in (0, 1]
+	IncreaseInterval time.Duration // Minimum time between increases (and after the last decrease)
+	MinFactor        float64       // Lower bound for the factor, in (0, 1]
+	Cooldown         time.Duration // Minimum time between decreases, so one burst of 429s counts once
+}
+
+// EnableAdaptive turns on adaptive limits for all credential and model limiters
+func (r *RPMLimiter) EnableAdaptive(cfg AdaptiveConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adaptive = &cfg
+}
+
+func (r *RPMLimiter) getAdaptiveConfig() *AdaptiveConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.adaptive
+}
+
+// RecordRateLimited lowers the effective limits of the credential and its model after a 429.
+// Returns the credential factor and whether it changed. No-op when adaptive limits are disabled.
+func (r *RPMLimiter) RecordRateLimited(credentialName, modelName string) (float64, bool) {
+	cfg := r.getAdaptiveConfig()
+	if cfg == nil {
+		return 1, false
+	}
+	if modelLimiter := r.getModelLimiter(credentialName, modelName); modelLimiter != nil {
+		decreaseFactor(modelLimiter, cfg)
+	}
+	credLimiter := r.getCredentialLimiter(credentialName)
+	if credLimiter == nil {
+		return 1, false
+	}
+	return decreaseFactor(credLimiter, cfg)
+}
+
+// RecordSuccess gradually restores the effective limits of the credential and its model.
+// Returns the credential factor and whether it changed. No-op when adaptive limits are disabled.
+func (r *RPMLimiter) RecordSuccess(credentialName, modelName string) (float64, bool) {
+	cfg := r.getAdaptiveConfig()
+	if cfg == nil {
+		return 1, false
+	}
+	if modelLimiter := r.getModelLimiter(credentialName, modelName); modelLimiter != nil {
+		increaseFactor(modelLimiter, cfg)
+	}
+	credLimiter := r.getCredentialLimiter(credentialName)
+	if credLimiter == nil {
+		return 1, false
+	}
+	return increaseFactor(credLimiter, cfg)
+}
+
+// GetAdaptiveFactor returns the current limit factor for a credential (1 = configured limits)
+func (r *RPMLimiter) GetAdaptiveFactor(credentialName string) float64 {
+	limiter := r.getCredentialLimiter(credentialName)
+	if limiter == nil {
+		return 1
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return limiter.factor
+}
+
+func decreaseFactor(l *limiter, cfg *AdaptiveConfig) (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := utils.NowUTC()
+	if !l.lastDecrease.IsZero() && now.Sub(l.lastDecrease) < cfg.Cooldown {
+		return l.factor, false
+	}
+	l.lastDecrease = now
+
+	factor := math.Max(cfg.MinFactor, l.factor*cfg.DecreaseFactor)
+	if factor == l.factor {
+		return l.factor, false
+	}
+	l.factor = factor
+	return l.factor, true
+}
+
+func increaseFactor(l *limiter, cfg *AdaptiveConfig) (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.factor >= 1 {
+		return l.factor, false
+	}
+	now := utils.NowUTC()
+	if now.Sub(l.lastDecrease) < cfg.IncreaseInterval || now.Sub(l.lastIncrease) < cfg.IncreaseInterval {
+		return l.factor, false
+	}
+	l.lastIncrease = now
+	l.factor = math.Min(1, l.factor+cfg.IncreaseStep)
+	return l.factor, true
+}
+
+// effectiveRPM returns the RPM limit scaled by the adaptive factor (at least 1 request)
+// Must be called with limiter.mu locked
+func effectiveRPM(l *limiter) int {
+	if l.rpm <= 0 || l.factor >= 1 {
+		return l.rpm
+	}
+	return max(1, int(float64(l.rpm)*l.factor))
+}
+
+// effectiveTPM returns the TPM limit scaled by the adaptive factor
+// Must be called with limiter.mu locked
+func effectiveTPM(l *limiter) int {
+	if l.tpm <= 0 || l.factor >= 1 {
+		return l.tpm
+	}
+	return max(1, int(float64(l.tpm)*l.factor))
+}
+
+// inheritAdaptiveState keeps the adaptive factor when a limiter is replaced
+// (model list refreshes and proxy limit syncs re-add limiters)
+func inheritAdaptiveState(dst, src *limiter) {
+	if src == nil {
+		return
+	}
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	dst.factor = src.factor
+	dst.lastDecrease = src.lastDecrease
+	dst.lastIncrease = src.lastIncrease
+}
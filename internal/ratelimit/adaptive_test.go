@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAdaptiveConfig() AdaptiveConfig {
+	return AdaptiveConfig{
+		DecreaseFactor:   0.5,
+		IncreaseStep:     0.25,
+		IncreaseInterval: time.Minute,
+		MinFactor:        0.2,
+		Cooldown:         10 * time.Second,
+	}
+}
+
+// shiftAdaptiveClock moves the limiter's adaptive timestamps into the past
+func shiftAdaptiveClock(l *limiter, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastDecrease = l.lastDecrease.Add(-d)
+	l.lastIncrease = l.lastIncrease.Add(-d)
+}
+
+func TestAdaptive_DisabledByDefault(t *testing.T) {
+	rl := New()
+	rl.AddCredential("cred1", 10)
+
+	factor, changed := rl.RecordRateLimited("cred1", "gpt-4o")
+	assert.False(t, changed)
+	assert.Equal(t, 1.0, factor)
+	assert.Equal(t, 1.0, rl.GetAdaptiveFactor("cred1"))
+}
+
+func TestAdaptive_DecreaseLowersEffectiveRPM(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredentialWithTPM("cred1", 10, 1000)
+
+	factor, changed := rl.RecordRateLimited("cred1", "")
+	assert.True(t, changed)
+	assert.Equal(t, 0.5, factor)
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, rl.Allow("cred1"))
+	}
+	assert.False(t, rl.Allow("cred1"), "effective rpm is 5")
+	assert.Equal(t, 10, rl.GetLimitRPM("cred1"), "configured limit is reported unchanged")
+
+	rl.ConsumeTokens("cred1", 500)
+	assert.False(t, rl.AllowTokens("cred1"), "effective tpm is 500")
+}
+
+func TestAdaptive_CooldownAndMinFactor(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredential("cred1", 100)
+
+	rl.RecordRateLimited("cred1", "")
+	_, changed := rl.RecordRateLimited("cred1", "")
+	assert.False(t, changed, "second 429 within cooldown is ignored")
+	assert.Equal(t, 0.5, rl.GetAdaptiveFactor("cred1"))
+
+	l := rl.getCredentialLimiter("cred1")
+	for i := 0; i < 5; i++ {
+		shiftAdaptiveClock(l, time.Minute)
+		rl.RecordRateLimited("cred1", "")
+	}
+	assert.Equal(t, 0.2, rl.GetAdaptiveFactor("cred1"), "factor never drops below min_factor")
+}
+
+func TestAdaptive_AdditiveIncrease(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredential("cred1", 100)
+	rl.RecordRateLimited("cred1", "")
+
+	_, changed := rl.RecordSuccess("cred1", "")
+	assert.False(t, changed, "no increase right after a 429")
+
+	l := rl.getCredentialLimiter("cred1")
+	shiftAdaptiveClock(l, time.Minute)
+	factor, changed := rl.RecordSuccess("cred1", "")
+	assert.True(t, changed)
+	assert.Equal(t, 0.75, factor)
+
+	_, changed = rl.RecordSuccess("cred1", "")
+	assert.False(t, changed, "one increase per interval")
+
+	shiftAdaptiveClock(l, time.Minute)
+	factor, _ = rl.RecordSuccess("cred1", "")
+	assert.Equal(t, 1.0, factor, "factor is capped at configured limits")
+}
+
+func TestAdaptive_ModelLimiter(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredential("cred1", 100)
+	rl.AddModel("cred1", "gpt-4o", 4)
+
+	rl.RecordRateLimited("cred1", "gpt-4o")
+	assert.True(t, rl.AllowModel("cred1", "gpt-4o"))
+	assert.True(t, rl.AllowModel("cred1", "gpt-4o"))
+	assert.False(t, rl.AllowModel("cred1", "gpt-4o"), "model effective rpm is 2")
+}
+
+func TestAdaptive_StateSurvivesReAdd(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredential("cred1", 100)
+	rl.AddModel("cred1", "gpt-4o", 10)
+	rl.RecordRateLimited("cred1", "gpt-4o")
+
+	// Model refreshes and proxy limit syncs replace limiters
+	rl.AddCredentialWithTPM("cred1", 200, -1)
+	rl.AddModelWithTPM("cred1", "gpt-4o", 10, -1)
+
+	assert.Equal(t, 0.5, rl.GetAdaptiveFactor("cred1"))
+	assert.Equal(t, 200, rl.GetLimitRPM("cred1"))
+	for i := 0; i < 5; i++ {
+		assert.True(t, rl.AllowModel("cred1", "gpt-4o"))
+	}
+	assert.False(t, rl.AllowModel("cred1", "gpt-4o"))
+}
+
+func TestAdaptive_UnlimitedRPM(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredential("cred1", -1)
+	rl.RecordRateLimited("cred1", "")
+
+	for i := 0; i < 20; i++ {
+		assert.True(t, rl.Allow("cred1"))
+	}
+}
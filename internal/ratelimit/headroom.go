@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Headroom is the capacity a limiter has left, for clients planning their request rate
+type Headroom struct {
+	LimitRPM     int        `json:"limit_rpm"`           // Effective limit (-1 = unlimited)
+	LimitTPM     int        `json:"limit_tpm"`           // Effective limit (-1 = unlimited)
+	RemainingRPM int        `json:"remaining_rpm"`       // Requests that can start now (-1 = unlimited)
+	RemainingTPM int        `json:"remaining_tpm"`       // Tokens left in the window (-1 = unlimited)
+	ResetRPM     *time.Time `json:"reset_rpm,omitempty"` // When the full RPM limit is available again (nil = now)
+	ResetTPM     *time.Time `json:"reset_tpm,omitempty"` // When the full TPM limit is available again (nil = now)
+}
+
+// HeadroomSnapshot is the headroom of every credential and model limiter at one point in time
+type HeadroomSnapshot struct {
+	Timestamp   time.Time               `json:"timestamp"`
+	Credentials map[string]Headroom     `json:"credentials"`
+	Models      map[string]Headroom     `json:"models"` // "credential:model" -> headroom, bounded by the credential's
+	Pools       map[string]PoolHeadroom `json:"pools,omitempty"`
+}
+
+// Headroom returns the remaining RPM/TPM and reset times of all credential, model and pool
+// limiters. The headroom of a model is bounded by its credential's, whose usage all models
+// share, and the headroom of a credential by its pool's.
+func (r *RPMLimiter) Headroom() HeadroomSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := utils.NowUTC()
+	snapshot := HeadroomSnapshot{
+		Timestamp:   now,
+		Credentials: make(map[string]Headroom, len(r.limiters)),
+		Models:      make(map[string]Headroom, len(r.modelLimiters)),
+	}
+	if len(r.pools) > 0 {
+		snapshot.Pools = make(map[string]PoolHeadroom, len(r.pools))
+		for name, p := range r.pools {
+			snapshot.Pools[name] = poolHeadroom(p, now)
+		}
+	}
+	for name, l := range r.limiters {
+		headroom := limiterHeadroom(l, now)
+		if p := r.credentialPools[name]; p != nil {
+			headroom = stricterHeadroom(headroom, snapshot.Pools[p.name].Headroom)
+		}
+		snapshot.Credentials[name] = headroom
+	}
+	for key, l := range r.modelLimiters {
+		credentialName, _, _ := strings.Cut(key, ":")
+		headroom := limiterHeadroom(l, now)
+		if credential, ok := snapshot.Credentials[credentialName]; ok {
+			headroom = stricterHeadroom(headroom, credential)
+		}
+		snapshot.Models[key] = headroom
+	}
+	return snapshot
+}
+
+// ModelHeadroom returns the headroom of a model of a credential, bounded by the credential's
+// and its pool's. Reports false if the credential is not configured; untracked models get the
+// credential's.
+func (r *RPMLimiter) ModelHeadroom(credentialName, modelName string) (Headroom, bool) {
+	credLimiter := r.getCredentialLimiter(credentialName)
+	if credLimiter == nil {
+		return Headroom{}, false
+	}
+	now := utils.NowUTC()
+	headroom := limiterHeadroom(credLimiter, now)
+	if p := r.getPool(credentialName); p != nil {
+		headroom = stricterHeadroom(headroom, limiterHeadroom(p.limiter, now))
+	}
+	if modelLimiter := r.getModelLimiter(credentialName, modelName); modelLimiter != nil {
+		headroom = stricterHeadroom(limiterHeadroom(modelLimiter, now), headroom)
+	}
+	return headroom, true
+}
+
+// limiterHeadroom computes the headroom of l at now
+func limiterHeadroom(l *limiter, now time.Time) Headroom {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	headroom := Headroom{LimitRPM: -1, LimitTPM: -1, RemainingRPM: -1, RemainingTPM: -1}
+
+	current := cleanOldRequests(l)
+	switch {
+	case l.burst > 0:
+		// Token bucket: whole tokens can start now, the bucket refills at the effective RPM
+		refillBucket(l)
+		headroom.LimitRPM = effectiveRPM(l)
+		headroom.RemainingRPM = max(0, int(l.bucketTokens))
+		if missing := float64(l.burst) - l.bucketTokens; missing > 0 {
+			reset := now.Add(time.Duration(missing / float64(headroom.LimitRPM) * float64(time.Minute)))
+			headroom.ResetRPM = &reset
+		}
+	case l.rpm != -1:
+		headroom.LimitRPM = effectiveRPM(l)
+		headroom.RemainingRPM = max(0, headroom.LimitRPM-current)
+		headroom.ResetRPM = windowReset(l.requests, func(t time.Time) time.Time { return t })
+	}
+
+	if l.tpm != -1 {
+		headroom.LimitTPM = effectiveTPM(l)
+		headroom.RemainingTPM = max(0, headroom.LimitTPM-cleanOldTokens(l))
+		headroom.ResetTPM = windowReset(l.tokens, func(tu tokenUsage) time.Time { return tu.timestamp })
+	}
+	return headroom
+}
+
+// windowReset returns when the newest entry of a one minute sliding window expires (nil if
+// the window is empty). Imported entries are not ordered, so every entry is checked.
+func windowReset[T any](entries []T, timestamp func(T) time.Time) *time.Time {
+	var newest time.Time
+	for _, entry := range entries {
+		if t := timestamp(entry); t.After(newest) {
+			newest = t
+		}
+	}
+	if newest.IsZero() {
+		return nil
+	}
+	reset := newest.Add(time.Minute)
+	return &reset
+}
+
+// stricterHeadroom combines the headroom of a model with its credential's: the smaller
+// limits and remaining capacity, and the later reset
+func stricterHeadroom(model, credential Headroom) Headroom {
+	return Headroom{
+		LimitRPM:     stricterLimit(model.LimitRPM, credential.LimitRPM),
+		LimitTPM:     stricterLimit(model.LimitTPM, credential.LimitTPM),
+		RemainingRPM: stricterLimit(model.RemainingRPM, credential.RemainingRPM),
+		RemainingTPM: stricterLimit(model.RemainingTPM, credential.RemainingTPM),
+		ResetRPM:     laterReset(model.ResetRPM, credential.ResetRPM),
+		ResetTPM:     laterReset(model.ResetTPM, credential.ResetTPM),
+	}
+}
+
+// stricterLimit returns the smaller of two values where -1 means unlimited
+func stricterLimit(a, b int) int {
+	if a < 0 {
+		return b
+	}
+	if b < 0 || a < b {
+		return a
+	}
+	return b
+}
+
+func laterReset(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
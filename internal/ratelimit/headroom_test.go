@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadroom_SlidingWindow(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithTPM("cred1", 10, 1000)
+	rl.AddModelWithTPM("cred1", "gpt-4o", 3, -1)
+
+	before := time.Now()
+	require.True(t, rl.TryAllowAll("cred1", "gpt-4o"))
+	require.True(t, rl.TryAllowAll("cred1", "gpt-4o"))
+	rl.ConsumeTokens("cred1", 400)
+
+	snapshot := rl.Headroom()
+	cred := snapshot.Credentials["cred1"]
+	assert.Equal(t, 10, cred.LimitRPM)
+	assert.Equal(t, 8, cred.RemainingRPM)
+	assert.Equal(t, 600, cred.RemainingTPM)
+	require.NotNil(t, cred.ResetRPM)
+	assert.WithinDuration(t, before.Add(time.Minute), *cred.ResetRPM, 5*time.Second)
+	require.NotNil(t, cred.ResetTPM)
+
+	// The model's RPM limit is stricter; its TPM is bounded by the credential's
+	model := snapshot.Models["cred1:gpt-4o"]
+	assert.Equal(t, 3, model.LimitRPM)
+	assert.Equal(t, 1, model.RemainingRPM)
+	assert.Equal(t, 1000, model.LimitTPM)
+	assert.Equal(t, 600, model.RemainingTPM)
+	assert.NotNil(t, model.ResetTPM)
+}
+
+func TestHeadroom_Unlimited(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithTPM("cred1", -1, -1)
+	require.True(t, rl.Allow("cred1"))
+
+	headroom := rl.Headroom().Credentials["cred1"]
+	assert.Equal(t, Headroom{LimitRPM: -1, LimitTPM: -1, RemainingRPM: -1, RemainingTPM: -1}, headroom)
+}
+
+func TestHeadroom_TokenBucket(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithBurst("cred1", 60, -1, 5)
+
+	headroom := rl.Headroom().Credentials["cred1"]
+	assert.Equal(t, 5, headroom.RemainingRPM)
+	assert.Nil(t, headroom.ResetRPM, "a full bucket has nothing to reset")
+
+	for i := 0; i < 5; i++ {
+		require.True(t, rl.Allow("cred1"))
+	}
+	headroom = rl.Headroom().Credentials["cred1"]
+	assert.Equal(t, 0, headroom.RemainingRPM)
+	require.NotNil(t, headroom.ResetRPM)
+	// 5 requests refill at 60 RPM in about 5 seconds
+	assert.WithinDuration(t, time.Now().Add(5*time.Second), *headroom.ResetRPM, time.Second)
+}
+
+func TestHeadroom_AdaptiveFactor(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(testAdaptiveConfig())
+	rl.AddCredential("cred1", 100)
+	rl.RecordRateLimited("cred1", "")
+
+	headroom := rl.Headroom().Credentials["cred1"]
+	assert.Less(t, headroom.LimitRPM, 100, "remaining capacity follows the lowered limit")
+	assert.Equal(t, headroom.LimitRPM, headroom.RemainingRPM)
+}
+
+func TestModelHeadroom(t *testing.T) {
+	rl := New()
+	rl.AddCredential("cred1", 2)
+	rl.AddModel("cred1", "gpt-4o", 5)
+	require.True(t, rl.TryAllowAll("cred1", "gpt-4o"))
+
+	headroom, ok := rl.ModelHeadroom("cred1", "gpt-4o")
+	require.True(t, ok)
+	assert.Equal(t, 2, headroom.LimitRPM)
+	assert.Equal(t, 1, headroom.RemainingRPM)
+
+	headroom, ok = rl.ModelHeadroom("cred1", "untracked")
+	require.True(t, ok)
+	assert.Equal(t, 1, headroom.RemainingRPM, "untracked models share the credential's headroom")
+
+	_, ok = rl.ModelHeadroom("missing", "gpt-4o")
+	assert.False(t, ok)
+}
@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// PoolLimits are the limits a group of credentials shares, e.g. the quota of an OpenAI
+// organization all its keys draw from (0 = unlimited)
+type PoolLimits struct {
+	RPM           int
+	TPM           int
+	DailyBudget   float64 // USD per UTC day
+	MonthlyBudget float64 // USD per UTC month
+}
+
+// pool enforces the limits of a credential pool on the requests of all its credentials
+type pool struct {
+	name        string
+	credentials []string
+	limiter     *limiter // Shared RPM/TPM window; its mu also guards the spend fields
+	limits      PoolLimits
+
+	day, month   time.Time // Start of the UTC periods of dailySpend and monthlySpend
+	dailySpend   float64
+	monthlySpend float64
+}
+
+// AddPool groups credentials under shared limits, enforced in addition to their own. A
+// credential belongs to one pool; adding it to another moves it. Re-adding a pool keeps its
+// usage and spend.
+func (r *RPMLimiter) AddPool(name string, limits PoolLimits, credentials ...string) {
+	rpm, tpm := limits.RPM, limits.TPM
+	if rpm <= 0 {
+		rpm = -1
+	}
+	if tpm <= 0 {
+		tpm = -1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := &pool{name: name, credentials: slices.Clone(credentials), limiter: newLimiter(rpm, tpm, 0), limits: limits}
+	if old := r.pools[name]; old != nil {
+		old.limiter.mu.Lock()
+		p.limiter.requests = old.limiter.requests
+		p.limiter.tokens = old.limiter.tokens
+		p.day, p.month = old.day, old.month
+		p.dailySpend, p.monthlySpend = old.dailySpend, old.monthlySpend
+		old.limiter.mu.Unlock()
+		for _, credential := range old.credentials {
+			delete(r.credentialPools, credential)
+		}
+	}
+	r.pools[name] = p
+	for _, credential := range credentials {
+		if other := r.credentialPools[credential]; other != nil && other != p {
+			other.credentials = slices.DeleteFunc(other.credentials, func(c string) bool { return c == credential })
+		}
+		r.credentialPools[credential] = p
+	}
+}
+
+// getPool returns the pool of a credential (nil if it is not pooled or r is nil)
+func (r *RPMLimiter) getPool(credentialName string) *pool {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	p := r.credentialPools[credentialName]
+	r.mu.RUnlock()
+	return p
+}
+
+// HasPools reports whether any credential pool is configured
+func (r *RPMLimiter) HasPools() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.pools) > 0
+}
+
+// RecordSpend adds the cost (USD) of a request to the budget of the credential's pool
+func (r *RPMLimiter) RecordSpend(credentialName string, cost float64) {
+	p := r.getPool(credentialName)
+	if p == nil || cost <= 0 {
+		return
+	}
+	p.limiter.mu.Lock()
+	defer p.limiter.mu.Unlock()
+
+	rollBudgetPeriods(p, utils.NowUTC())
+	p.dailySpend += cost
+	p.monthlySpend += cost
+}
+
+// allowPool reports whether the pool's RPM/TPM (scaled by share) and budgets allow n requests.
+// Must be called with p.limiter.mu locked.
+func allowPool(p *pool, share float64, n int) bool {
+	return hasRoom(p.limiter, n) && withinShare(p.limiter, share) && withinBudget(p, utils.NowUTC())
+}
+
+// withinBudget reports whether the pool has budget left in the current day and month.
+// Must be called with p.limiter.mu locked.
+func withinBudget(p *pool, now time.Time) bool {
+	rollBudgetPeriods(p, now)
+	if p.limits.DailyBudget > 0 && p.dailySpend >= p.limits.DailyBudget {
+		return false
+	}
+	return p.limits.MonthlyBudget <= 0 || p.monthlySpend < p.limits.MonthlyBudget
+}
+
+// budgetRetryAfter returns how long until the exhausted budgets of the pool reset (0 if none
+// is exhausted). Must be called with p.limiter.mu locked.
+func budgetRetryAfter(p *pool, now time.Time) time.Duration {
+	rollBudgetPeriods(p, now)
+	var wait time.Duration
+	if p.limits.DailyBudget > 0 && p.dailySpend >= p.limits.DailyBudget {
+		wait = p.day.AddDate(0, 0, 1).Sub(now)
+	}
+	if p.limits.MonthlyBudget > 0 && p.monthlySpend >= p.limits.MonthlyBudget {
+		wait = max(wait, p.month.AddDate(0, 1, 0).Sub(now))
+	}
+	return wait
+}
+
+// rollBudgetPeriods resets the spend of a day or month that has ended.
+// Must be called with p.limiter.mu locked.
+func rollBudgetPeriods(p *pool, now time.Time) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !p.day.Equal(day) {
+		p.day = day
+		p.dailySpend = 0
+	}
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if !p.month.Equal(month) {
+		p.month = month
+		p.monthlySpend = 0
+	}
+}
+
+// PoolHeadroom is the capacity and spend of a credential pool
+type PoolHeadroom struct {
+	Headroom
+	Credentials   []string   `json:"credentials"`
+	DailyBudget   float64    `json:"daily_budget,omitempty"`   // USD (omitted = unlimited)
+	MonthlyBudget float64    `json:"monthly_budget,omitempty"` // USD (omitted = unlimited)
+	DailySpend    float64    `json:"daily_spend"`              // USD spent in the current UTC day
+	MonthlySpend  float64    `json:"monthly_spend"`            // USD spent in the current UTC month
+	BudgetReset   *time.Time `json:"budget_reset,omitempty"`   // When an exhausted budget resets (nil = none exhausted)
+}
+
+// poolHeadroom computes the headroom of p at now
+func poolHeadroom(p *pool, now time.Time) PoolHeadroom {
+	headroom := PoolHeadroom{
+		Headroom:      limiterHeadroom(p.limiter, now),
+		Credentials:   slices.Clone(p.credentials),
+		DailyBudget:   p.limits.DailyBudget,
+		MonthlyBudget: p.limits.MonthlyBudget,
+	}
+
+	p.limiter.mu.Lock()
+	defer p.limiter.mu.Unlock()
+	if wait := budgetRetryAfter(p, now); wait > 0 {
+		reset := now.Add(wait)
+		headroom.BudgetReset = &reset
+	}
+	headroom.DailySpend = p.dailySpend
+	headroom.MonthlySpend = p.monthlySpend
+	return headroom
+}
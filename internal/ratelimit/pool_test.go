@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPooledLimiter(limits PoolLimits) *RPMLimiter {
+	rl := New()
+	rl.AddCredentialWithTPM("key1", 10, -1)
+	rl.AddCredentialWithTPM("key2", 10, -1)
+	rl.AddCredentialWithTPM("other", 10, -1)
+	rl.AddPool("org", limits, "key1", "key2")
+	return rl
+}
+
+func TestPool_SharedRPM(t *testing.T) {
+	rl := newPooledLimiter(PoolLimits{RPM: 3})
+
+	require.True(t, rl.TryAllowAll("key1", ""))
+	require.True(t, rl.TryAllowAll("key2", ""))
+	require.True(t, rl.TryAllowAll("key1", ""))
+	assert.False(t, rl.TryAllowAll("key2", ""), "the pool RPM is shared by its credentials")
+	assert.True(t, rl.TryAllowAll("other", ""), "credentials outside the pool are not affected")
+
+	assert.Equal(t, 2, rl.GetCurrentRPM("key1"), "the rejected request is not recorded")
+	assert.Greater(t, rl.RetryAfter("key2", ""), time.Duration(0))
+}
+
+func TestPool_SharedTPM(t *testing.T) {
+	rl := newPooledLimiter(PoolLimits{TPM: 1000})
+
+	require.True(t, rl.TryAllowAll("key1", ""))
+	rl.ConsumeTokens("key1", 1000)
+	assert.False(t, rl.TryAllowAll("key2", ""), "tokens of one credential count against the pool")
+	assert.Equal(t, 0, rl.GetCurrentTPM("key2"), "the credential's own window is untouched")
+}
+
+func TestPool_Share(t *testing.T) {
+	rl := newPooledLimiter(PoolLimits{RPM: 4})
+
+	require.True(t, rl.TryAllowAllShare("key1", "", 0.5))
+	require.True(t, rl.TryAllowAllShare("key2", "", 0.5))
+	assert.False(t, rl.TryAllowAllShare("key1", "", 0.5), "a priority class share applies to the pool")
+	assert.True(t, rl.TryAllowAllShare("key1", "", 1))
+}
+
+func TestPool_Budget(t *testing.T) {
+	rl := newPooledLimiter(PoolLimits{DailyBudget: 1.5, MonthlyBudget: 10})
+
+	rl.RecordSpend("key1", 1)
+	require.True(t, rl.TryAllowAll("key2", ""))
+	rl.RecordSpend("key2", 0.5)
+	assert.False(t, rl.TryAllowAll("key1", ""), "the daily budget is spent")
+	assert.True(t, rl.TryAllowAll("other", ""))
+
+	now := time.Now().UTC()
+	nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	assert.WithinDuration(t, nextDay, now.Add(rl.RetryAfter("key1", "")), 5*time.Second)
+
+	pool := rl.Headroom().Pools["org"]
+	assert.Equal(t, []string{"key1", "key2"}, pool.Credentials)
+	assert.InDelta(t, 1.5, pool.DailySpend, 1e-9)
+	assert.InDelta(t, 1.5, pool.MonthlySpend, 1e-9)
+	require.NotNil(t, pool.BudgetReset)
+	assert.WithinDuration(t, nextDay, *pool.BudgetReset, 5*time.Second)
+}
+
+func TestPool_BudgetPeriodsRoll(t *testing.T) {
+	p := &pool{limits: PoolLimits{DailyBudget: 1, MonthlyBudget: 2}}
+	day1 := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+
+	rollBudgetPeriods(p, day1)
+	p.dailySpend, p.monthlySpend = 1, 2
+	assert.False(t, withinBudget(p, day1))
+	assert.Equal(t, time.Hour, budgetRetryAfter(p, day1), "both budgets reset at midnight of the month's last day")
+
+	feb := day1.Add(2 * time.Hour)
+	assert.True(t, withinBudget(p, feb))
+	assert.Zero(t, p.dailySpend)
+	assert.Zero(t, p.monthlySpend)
+
+	p.monthlySpend = 2
+	feb2 := feb.Add(24 * time.Hour)
+	assert.False(t, withinBudget(p, feb2), "a new day keeps the month's spend")
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Sub(feb2), budgetRetryAfter(p, feb2))
+}
+
+func TestPool_HeadroomBoundsCredentials(t *testing.T) {
+	rl := newPooledLimiter(PoolLimits{RPM: 5})
+	rl.AddModelWithTPM("key1", "gpt-4o", 100, -1)
+	for i := 0; i < 3; i++ {
+		require.True(t, rl.TryAllowAll("key2", ""))
+	}
+
+	snapshot := rl.Headroom()
+	assert.Equal(t, 2, snapshot.Pools["org"].RemainingRPM)
+	assert.Equal(t, 5, snapshot.Credentials["key1"].LimitRPM)
+	assert.Equal(t, 2, snapshot.Credentials["key1"].RemainingRPM)
+	assert.Equal(t, 2, snapshot.Models["key1:gpt-4o"].RemainingRPM)
+	assert.Equal(t, 10, snapshot.Credentials["other"].RemainingRPM)
+
+	headroom, ok := rl.ModelHeadroom("key1", "gpt-4o")
+	require.True(t, ok)
+	assert.Equal(t, 2, headroom.RemainingRPM)
+}
+
+func TestPool_ReAddKeepsUsageAndMovesCredentials(t *testing.T) {
+	rl := newPooledLimiter(PoolLimits{RPM: 2, DailyBudget: 1})
+	require.True(t, rl.TryAllowAll("key1", ""))
+	rl.RecordSpend("key1", 0.4)
+
+	rl.AddPool("org", PoolLimits{RPM: 1, DailyBudget: 1}, "key1")
+	assert.False(t, rl.TryAllowAll("key1", ""), "the request window survives re-adding the pool")
+	assert.True(t, rl.TryAllowAll("key2", ""), "credentials left out are no longer pooled")
+	assert.InDelta(t, 0.4, rl.Headroom().Pools["org"].DailySpend, 1e-9)
+
+	rl.AddPool("team", PoolLimits{RPM: 1}, "key1")
+	assert.Empty(t, rl.Headroom().Pools["org"].Credentials)
+	assert.Equal(t, []string{"key1"}, rl.Headroom().Pools["team"].Credentials)
+	assert.True(t, rl.HasPools())
+}
+
+func TestPool_UsageHandoff(t *testing.T) {
+	old := newPooledLimiter(PoolLimits{RPM: 2})
+	require.True(t, old.TryAllowAll("key1", ""))
+	require.True(t, old.TryAllowAll("key2", ""))
+
+	rl := newPooledLimiter(PoolLimits{RPM: 2})
+	rl.ImportUsage(old.ExportUsage())
+	assert.False(t, rl.TryAllowAll("key1", ""), "the pool window is handed off")
+}
+
+func TestPool_SpendHandoff(t *testing.T) {
+	old := newPooledLimiter(PoolLimits{DailyBudget: 1, MonthlyBudget: 10})
+	old.RecordSpend("key1", 0.75)
+	state := old.ExportUsage()
+	require.Contains(t, state.Pools, "org")
+	assert.Empty(t, state.Pools["org"].Requests, "spend alone is exported")
+
+	rl := newPooledLimiter(PoolLimits{DailyBudget: 1, MonthlyBudget: 10})
+	rl.RecordSpend("key2", 0.25)
+	rl.ImportUsage(state)
+	pool := rl.Headroom().Pools["org"]
+	assert.InDelta(t, 1.0, pool.DailySpend, 1e-9)
+	assert.InDelta(t, 1.0, pool.MonthlySpend, 1e-9)
+	assert.False(t, rl.TryAllowAll("key1", ""), "the handed off spend counts against the budget")
+
+	// Spend of an earlier period is not imported
+	stale := state.Pools["org"]
+	stale.Day = stale.Day.AddDate(0, 0, -1)
+	stale.Month = stale.Month.AddDate(0, -1, 0)
+	state.Pools["org"] = stale
+	fresh := newPooledLimiter(PoolLimits{DailyBudget: 1})
+	fresh.ImportUsage(state)
+	assert.Zero(t, fresh.Headroom().Pools["org"].DailySpend)
+	assert.Zero(t, fresh.Headroom().Pools["org"].MonthlySpend)
+}
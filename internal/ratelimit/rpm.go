@@ -2,6 +2,7 @@ package ratelimit
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +25,14 @@ type RPMLimiter struct {
 	mu            sync.RWMutex
 	limiters      map[string]*limiter // credential limiters
 	modelLimiters map[string]*limiter // (credential:model) limiters
+	adaptive      *AdaptiveConfig     // nil = adaptive limits disabled
+
+	pools           map[string]*pool // pool name -> pool
+	credentialPools map[string]*pool // credential -> its pool
+
+	keyMu       sync.Mutex          // Guards keyLimiters and their request windows
+	keyLimiters map[string]*limiter // API key / team limiters (created on first request)
+	keysPruned  time.Time           // Last sweep of idle key limiters
 }
 
 type tokenUsage struct {
@@ -37,6 +46,44 @@ type limiter struct {
 	requests []time.Time
 	tokens   []tokenUsage
 	mu       sync.Mutex
+
+	// Token bucket (burst > 0): refills at rpm per minute up to burst requests.
+	// Replaces the sliding-window RPM cap; requests are still tracked for current RPM reporting.
+	burst         int
+	bucketTokens  float64
+	bucketUpdated time.Time
+
+	// Adaptive limits (AIMD): rpm/tpm are scaled by factor in (0, 1] after upstream 429s
+	factor       float64
+	lastDecrease time.Time
+	lastIncrease time.Time
+}
+
+// newLimiter creates a limiter; burst > 0 with a finite rpm enables token bucket semantics
+func newLimiter(rpm, tpm, burst int) *limiter {
+	l := &limiter{
+		rpm:      rpm,
+		tpm:      tpm,
+		requests: make([]time.Time, 0),
+		tokens:   make([]tokenUsage, 0),
+		factor:   1,
+	}
+	if burst > 0 && rpm > 0 {
+		l.burst = burst
+		l.bucketTokens = float64(burst)
+		l.bucketUpdated = utils.NowUTC()
+	}
+	return l
+}
+
+// refillBucket adds tokens accrued since the last refill, capped at burst
+// Must be called with limiter.mu locked
+func refillBucket(l *limiter) {
+	now := utils.NowUTC()
+	if elapsed := now.Sub(l.bucketUpdated); elapsed > 0 {
+		l.bucketTokens = math.Min(float64(l.burst), l.bucketTokens+elapsed.Minutes()*float64(effectiveRPM(l)))
+		l.bucketUpdated = now
+	}
 }
 
 // MaxRequestsBufferSize limits the maximum number of request timestamps stored
@@ -49,8 +96,11 @@ const MaxTokensBufferSize = 10_000_000
 
 func New() *RPMLimiter {
 	return &RPMLimiter{
-		limiters:      make(map[string]*limiter),
-		modelLimiters: make(map[string]*limiter),
+		limiters:        make(map[string]*limiter),
+		modelLimiters:   make(map[string]*limiter),
+		keyLimiters:     make(map[string]*limiter),
+		pools:           make(map[string]*pool),
+		credentialPools: make(map[string]*pool),
 	}
 }
 
@@ -83,15 +133,18 @@ func (r *RPMLimiter) AddCredential(name string, rpm int) {
 }
 
 func (r *RPMLimiter) AddCredentialWithTPM(name string, rpm int, tpm int) {
+	r.AddCredentialWithBurst(name, rpm, tpm, 0)
+}
+
+// AddCredentialWithBurst adds a credential whose RPM limit is a token bucket of size burst
+// (burst <= 0 keeps the sliding-window RPM limit)
+func (r *RPMLimiter) AddCredentialWithBurst(name string, rpm, tpm, burst int) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.limiters[name] = &limiter{
-		rpm:      rpm,
-		tpm:      tpm,
-		requests: make([]time.Time, 0),
-		tokens:   make([]tokenUsage, 0),
-	}
+	l := newLimiter(rpm, tpm, burst)
+	inheritAdaptiveState(l, r.limiters[name])
+	r.limiters[name] = l
 }
 
 // AddModel adds a model with RPM limit for a specific credential
@@ -101,16 +154,32 @@ func (r *RPMLimiter) AddModel(credentialName, modelName string, rpm int) {
 
 // AddModelWithTPM adds a model with both RPM and TPM limits for a specific credential
 func (r *RPMLimiter) AddModelWithTPM(credentialName, modelName string, rpm int, tpm int) {
+	r.AddModelWithBurst(credentialName, modelName, rpm, tpm, 0)
+}
+
+// AddModelWithBurst adds a model whose RPM limit is a token bucket of size burst
+// (burst <= 0 keeps the sliding-window RPM limit)
+func (r *RPMLimiter) AddModelWithBurst(credentialName, modelName string, rpm, tpm, burst int) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	key := makeModelKey(credentialName, modelName)
-	r.modelLimiters[key] = &limiter{
-		rpm:      rpm,
-		tpm:      tpm,
-		requests: make([]time.Time, 0),
-		tokens:   make([]tokenUsage, 0),
+	l := newLimiter(rpm, tpm, burst)
+	inheritAdaptiveState(l, r.modelLimiters[key])
+	r.modelLimiters[key] = l
+}
+
+// RemoveModel drops the limiter of a model of a credential; reports whether it existed
+func (r *RPMLimiter) RemoveModel(credentialName, modelName string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := makeModelKey(credentialName, modelName)
+	if _, ok := r.modelLimiters[key]; !ok {
+		return false
 	}
+	delete(r.modelLimiters, key)
+	return true
 }
 
 // setCurrentUsage fills request and token arrays to simulate current usage
@@ -161,6 +230,13 @@ func setCurrentUsage(limiter *limiter, currentRPM, currentTPM int) {
 	} else {
 		limiter.tokens = make([]tokenUsage, 0)
 	}
+
+	// Token bucket: remaining capacity is what the remote side has left this minute
+	if limiter.burst > 0 {
+		remaining := limiter.rpm - currentRPM
+		limiter.bucketTokens = math.Max(0, math.Min(float64(limiter.burst), float64(remaining)))
+		limiter.bucketUpdated = now
+	}
 }
 
 // SetCredentialCurrentUsage sets the current RPM/TPM usage for a credential
@@ -196,21 +272,20 @@ func (r *RPMLimiter) SetModelCurrentUsage(credentialName, modelName string, curr
 func checkRPMLimit(l *limiter, record bool) bool {
 	cleanOldRequests(l)
 
-	// Check limit only if RPM is not unlimited (-1)
-	if l.rpm != -1 && len(l.requests) >= l.rpm {
+	if l.burst > 0 {
+		// Token bucket: allow while at least one whole token is available
+		refillBucket(l)
+		if l.bucketTokens < 1 {
+			return false
+		}
+	} else if l.rpm != -1 && len(l.requests) >= effectiveRPM(l) {
+		// Check limit only if RPM is not unlimited (-1)
 		return false
 	}
 
 	// Record the request if requested
 	if record {
-		// Always record - but clean old requests first if buffer is full
-		if len(l.requests) >= MaxRequestsBufferSize {
-			cleanOldRequests(l)
-		}
-		// Only skip if still at capacity after cleaning (extremely rare edge case)
-		if len(l.requests) < MaxRequestsBufferSize {
-			l.requests = append(l.requests, utils.NowUTC())
-		}
+		recordRequest(l)
 	}
 
 	return true
@@ -241,6 +316,57 @@ func (r *RPMLimiter) CanAllow(credentialName string) bool {
 	return checkRPMLimit(limiter, false)
 }
 
+// KeyLimit is the RPM limit of an API key or team (RPM <= 0 = unlimited)
+type KeyLimit struct {
+	Name string // Unique limiter name, e.g. "key:<hash>" or "team:<id>"
+	RPM  int
+}
+
+// AllowKeys records a request against every limit if all of them allow it.
+// Limits may change between calls (e.g. temporary boosts); the request window is kept.
+func (r *RPMLimiter) AllowKeys(limits ...KeyLimit) bool {
+	r.keyMu.Lock()
+	defer r.keyMu.Unlock()
+
+	r.pruneIdleKeys(utils.NowUTC())
+
+	tracked := make([]*limiter, 0, len(limits))
+	for _, limit := range limits {
+		if limit.RPM <= 0 {
+			continue
+		}
+		l := r.keyLimiters[limit.Name]
+		if l == nil {
+			l = newLimiter(limit.RPM, -1, 0)
+			r.keyLimiters[limit.Name] = l
+		}
+		l.rpm = limit.RPM
+		if !checkRPMLimit(l, false) {
+			return false
+		}
+		tracked = append(tracked, l)
+	}
+	for _, l := range tracked {
+		recordRequest(l)
+	}
+	return true
+}
+
+// pruneIdleKeys drops the limiters of keys and teams without a request in the last minute, at
+// most once a minute, so limiters of keys that stopped sending requests do not pile up.
+// Must be called with r.keyMu locked.
+func (r *RPMLimiter) pruneIdleKeys(now time.Time) {
+	if now.Sub(r.keysPruned) < time.Minute {
+		return
+	}
+	r.keysPruned = now
+	for name, l := range r.keyLimiters {
+		if cleanOldRequests(l) == 0 {
+			delete(r.keyLimiters, name)
+		}
+	}
+}
+
 // cleanOldRequests removes requests older than 1 minute and returns count of valid ones
 // Must be called with limiter.mu locked
 func cleanOldRequests(l *limiter) int {
@@ -366,6 +492,97 @@ func (r *RPMLimiter) GetAllModelPairs() []ModelPair {
 	return pairs
 }
 
+// LimiterStats summarizes limiter contents for diagnostics
+type LimiterStats struct {
+	Credentials     int `json:"credentials"`
+	Models          int `json:"models"`
+	TrackedRequests int `json:"tracked_requests"` // Request timestamps held in sliding windows
+	TrackedTokens   int `json:"tracked_tokens"`   // Token usage records held in sliding windows
+}
+
+// Stats returns the number of tracked limiters and sliding window entries
+func (r *RPMLimiter) Stats() LimiterStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := LimiterStats{
+		Credentials: len(r.limiters),
+		Models:      len(r.modelLimiters),
+	}
+	count := func(l *limiter) {
+		l.mu.Lock()
+		stats.TrackedRequests += len(l.requests)
+		stats.TrackedTokens += len(l.tokens)
+		l.mu.Unlock()
+	}
+	for _, l := range r.limiters {
+		count(l)
+	}
+	for _, l := range r.modelLimiters {
+		count(l)
+	}
+	return stats
+}
+
+// LimiterState is the point-in-time state of one limiter
+type LimiterState struct {
+	RPM          int      `json:"rpm"`                     // Configured limit (-1 = unlimited)
+	TPM          int      `json:"tpm"`                     // Configured limit (-1 = unlimited)
+	EffectiveRPM int      `json:"effective_rpm"`           // Limit scaled by the adaptive factor
+	EffectiveTPM int      `json:"effective_tpm"`           // Limit scaled by the adaptive factor
+	CurrentRPM   int      `json:"current_rpm"`             // Requests within the last minute
+	CurrentTPM   int      `json:"current_tpm"`             // Tokens within the last minute
+	Factor       float64  `json:"factor"`                  // Adaptive limit factor (1 = not lowered)
+	BucketTokens *float64 `json:"bucket_tokens,omitempty"` // Requests left in the token bucket (rpm_burst only)
+}
+
+// LimitsSnapshot is the state of every credential and model limiter
+type LimitsSnapshot struct {
+	Credentials map[string]LimiterState `json:"credentials"`
+	Models      map[string]LimiterState `json:"models"` // "credential:model" -> state
+}
+
+// Limits returns a copy of the state of all credential and model limiters, each read under
+// its own lock, so it is safe to call while requests are being served
+func (r *RPMLimiter) Limits() LimitsSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := LimitsSnapshot{
+		Credentials: make(map[string]LimiterState, len(r.limiters)),
+		Models:      make(map[string]LimiterState, len(r.modelLimiters)),
+	}
+	for name, l := range r.limiters {
+		snapshot.Credentials[name] = limiterState(l)
+	}
+	for key, l := range r.modelLimiters {
+		snapshot.Models[key] = limiterState(l)
+	}
+	return snapshot
+}
+
+// limiterState copies the state of l
+func limiterState(l *limiter) LimiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := LimiterState{
+		RPM:          l.rpm,
+		TPM:          l.tpm,
+		EffectiveRPM: effectiveRPM(l),
+		EffectiveTPM: effectiveTPM(l),
+		CurrentRPM:   cleanOldRequests(l),
+		CurrentTPM:   cleanOldTokens(l),
+		Factor:       l.factor,
+	}
+	if l.burst > 0 {
+		refillBucket(l)
+		tokens := l.bucketTokens
+		state.BucketTokens = &tokens
+	}
+	return state
+}
+
 // ConsumeTokens records token usage for a credential
 func (r *RPMLimiter) ConsumeTokens(credentialName string, tokenCount int) {
 	limiter := r.getCredentialLimiter(credentialName)
@@ -374,15 +591,27 @@ func (r *RPMLimiter) ConsumeTokens(credentialName string, tokenCount int) {
 	}
 
 	limiter.mu.Lock()
-	defer limiter.mu.Unlock()
+	recordTokens(limiter, tokenCount)
+	limiter.mu.Unlock()
 
+	// The tokens also count against the pool of the credential
+	if p := r.getPool(credentialName); p != nil {
+		p.limiter.mu.Lock()
+		recordTokens(p.limiter, tokenCount)
+		p.limiter.mu.Unlock()
+	}
+}
+
+// recordTokens appends a token usage record to the limiter.
+// Must be called with limiter.mu locked.
+func recordTokens(l *limiter, tokenCount int) {
 	// Always record - but clean old tokens first if buffer is full
-	if len(limiter.tokens) >= MaxTokensBufferSize {
-		cleanOldTokens(limiter)
+	if len(l.tokens) >= MaxTokensBufferSize {
+		cleanOldTokens(l)
 	}
 	// Only skip if still at capacity after cleaning (extremely rare edge case)
-	if len(limiter.tokens) < MaxTokensBufferSize {
-		limiter.tokens = append(limiter.tokens, tokenUsage{
+	if len(l.tokens) < MaxTokensBufferSize {
+		l.tokens = append(l.tokens, tokenUsage{
 			timestamp: utils.NowUTC(),
 			count:     tokenCount,
 		})
@@ -400,7 +629,7 @@ func checkTPMLimit(l *limiter) bool {
 	currentTPM := cleanOldTokens(l)
 
 	// Check if we're at or over the limit
-	return currentTPM < l.tpm
+	return currentTPM < effectiveTPM(l)
 }
 
 // AllowTokens checks if the given number of tokens can be consumed without exceeding TPM limit
@@ -440,17 +669,7 @@ func (r *RPMLimiter) ConsumeModelTokens(credentialName, modelName string, tokenC
 	modelLimiter.mu.Lock()
 	defer modelLimiter.mu.Unlock()
 
-	// Always record - but clean old tokens first if buffer is full
-	if len(modelLimiter.tokens) >= MaxTokensBufferSize {
-		cleanOldTokens(modelLimiter)
-	}
-	// Only skip if still at capacity after cleaning (extremely rare edge case)
-	if len(modelLimiter.tokens) < MaxTokensBufferSize {
-		modelLimiter.tokens = append(modelLimiter.tokens, tokenUsage{
-			timestamp: utils.NowUTC(),
-			count:     tokenCount,
-		})
-	}
+	recordTokens(modelLimiter, tokenCount)
 }
 
 // AllowModelTokens checks if request to a specific model for a credential is allowed based on TPM limit
@@ -519,11 +738,34 @@ func (r *RPMLimiter) GetModelLimitRPM(credentialName, modelName string) int {
 	return modelLimiter.rpm
 }
 
-// TryAllowAll atomically checks credential RPM, credential TPM, model RPM, and model TPM limits.
-// If all checks pass, it records the credential and model RPM usage. Returns true if allowed.
+// TryAllowAll atomically checks credential RPM, credential TPM, model RPM, and model TPM limits,
+// and the limits of the credential's pool. If all checks pass, it records the credential, model
+// and pool RPM usage. Returns true if allowed.
 // This prevents TOCTOU races where separate CanAllow+Allow calls could exceed limits.
 // modelName can be empty if no model-level limiting is needed.
 func (r *RPMLimiter) TryAllowAll(credentialName, modelName string) bool {
+	return r.TryAllowAllShare(credentialName, modelName, 1)
+}
+
+// TryAllowAllShare is TryAllowAll for a request that may only use a share (0..1] of the
+// credential and model RPM/TPM limits, e.g. a lower priority class. Shares >= 1 (or <= 0)
+// use the full limits.
+func (r *RPMLimiter) TryAllowAllShare(credentialName, modelName string, share float64) bool {
+	return r.tryAllowAll(credentialName, modelName, share, 1)
+}
+
+// TryAllowAllN is TryAllowAll for n requests at once, e.g. the extra upstream requests of a
+// fanned out request: either all n are recorded, or none is and false is returned.
+func (r *RPMLimiter) TryAllowAllN(credentialName, modelName string, n int) bool {
+	if n <= 0 {
+		return true
+	}
+	return r.tryAllowAll(credentialName, modelName, 1, n)
+}
+
+// tryAllowAll checks that the credential, model and pool limits allow n more requests and, if
+// they do, records them all
+func (r *RPMLimiter) tryAllowAll(credentialName, modelName string, share float64, n int) bool {
 	credLimiter := r.getCredentialLimiter(credentialName)
 	if credLimiter == nil {
 		return false
@@ -534,18 +776,18 @@ func (r *RPMLimiter) TryAllowAll(credentialName, modelName string) bool {
 		modLimiter = r.getModelLimiter(credentialName, modelName)
 		// nil modLimiter means model not tracked — no model-level limit to enforce
 	}
+	credPool := r.getPool(credentialName)
 
-	// Lock credential limiter first, then model limiter (consistent ordering)
+	// Lock credential limiter first, then model limiter, then pool limiter (consistent ordering)
 	credLimiter.mu.Lock()
 	defer credLimiter.mu.Unlock()
 
-	// Check credential RPM
-	if !checkRPMLimit(credLimiter, false) {
+	// Check credential RPM and TPM
+	if !hasRoom(credLimiter, n) {
 		return false
 	}
 
-	// Check credential TPM
-	if !checkTPMLimit(credLimiter) {
+	if !withinShare(credLimiter, share) {
 		return false
 	}
 
@@ -554,29 +796,132 @@ func (r *RPMLimiter) TryAllowAll(credentialName, modelName string) bool {
 		modLimiter.mu.Lock()
 		defer modLimiter.mu.Unlock()
 
-		if !checkRPMLimit(modLimiter, false) {
+		if !hasRoom(modLimiter, n) {
 			return false
 		}
-		if !checkTPMLimit(modLimiter) {
+		if !withinShare(modLimiter, share) {
 			return false
 		}
 	}
 
-	// All checks passed — now record RPM for both credential and model
-	recordRequest(credLimiter)
-	if modLimiter != nil {
-		recordRequest(modLimiter)
+	// Check the limits the credential shares with its pool
+	if credPool != nil {
+		credPool.limiter.mu.Lock()
+		defer credPool.limiter.mu.Unlock()
+
+		if !allowPool(credPool, share, n) {
+			return false
+		}
 	}
 
+	// All checks passed — now record RPM for the credential, model and pool
+	for i := 0; i < n; i++ {
+		recordRequest(credLimiter)
+		if modLimiter != nil {
+			recordRequest(modLimiter)
+		}
+		if credPool != nil {
+			recordRequest(credPool.limiter)
+		}
+	}
+
+	return true
+}
+
+// hasRoom reports whether the limiter's RPM allows n more requests and its TPM is not used up.
+// Must be called with l.mu locked.
+func hasRoom(l *limiter, n int) bool {
+	cleanOldRequests(l)
+
+	if l.burst > 0 {
+		refillBucket(l)
+		if l.bucketTokens < float64(n) {
+			return false
+		}
+	} else if l.rpm != -1 && len(l.requests)+n > effectiveRPM(l) {
+		return false
+	}
+
+	return checkTPMLimit(l)
+}
+
+// RetryAfter estimates how long until the credential, model and pool RPM/TPM limits and the
+// pool budgets allow another request: 0 if they allow one now or the wait cannot be estimated.
+// Priority class shares are not taken into account.
+func (r *RPMLimiter) RetryAfter(credentialName, modelName string) time.Duration {
+	var wait time.Duration
+	for _, l := range []*limiter{r.getCredentialLimiter(credentialName), r.getModelLimiter(credentialName, modelName)} {
+		if l == nil {
+			continue
+		}
+		l.mu.Lock()
+		wait = max(wait, limiterRetryAfter(l, utils.NowUTC()))
+		l.mu.Unlock()
+	}
+	if p := r.getPool(credentialName); p != nil {
+		p.limiter.mu.Lock()
+		now := utils.NowUTC()
+		wait = max(wait, limiterRetryAfter(p.limiter, now), budgetRetryAfter(p, now))
+		p.limiter.mu.Unlock()
+	}
+	return wait
+}
+
+// limiterRetryAfter returns how long until the limiter allows a request: the next bucket
+// token, or the expiry of the requests and tokens over the limit.
+// Must be called with limiter.mu locked
+func limiterRetryAfter(l *limiter, now time.Time) time.Duration {
+	var wait time.Duration
+	requests := cleanOldRequests(l)
+	if l.burst > 0 {
+		refillBucket(l)
+		if rpm := effectiveRPM(l); l.bucketTokens < 1 && rpm > 0 {
+			wait = time.Duration((1 - l.bucketTokens) / float64(rpm) * float64(time.Minute))
+		}
+	} else if rpm := effectiveRPM(l); l.rpm != -1 && rpm > 0 && requests >= rpm {
+		// The oldest requests over the limit have to leave the window
+		wait = l.requests[requests-rpm].Add(time.Minute).Sub(now)
+	}
+
+	if tpm := effectiveTPM(l); l.tpm != -1 && tpm > 0 {
+		used := cleanOldTokens(l)
+		for _, tu := range l.tokens {
+			if used < tpm {
+				break
+			}
+			used -= tu.count
+			wait = max(wait, tu.timestamp.Add(time.Minute).Sub(now))
+		}
+	}
+	return max(wait, 0)
+}
+
+// withinShare reports whether the limiter's RPM and TPM usage is below share of its limits.
+// Must be called with limiter.mu locked, after checkRPMLimit cleaned old requests.
+func withinShare(l *limiter, share float64) bool {
+	if share <= 0 || share >= 1 {
+		return true
+	}
+	if l.rpm > 0 && float64(len(l.requests)) >= math.Max(1, float64(effectiveRPM(l))*share) {
+		return false
+	}
+	if l.tpm > 0 && float64(cleanOldTokens(l)) >= float64(effectiveTPM(l))*share {
+		return false
+	}
 	return true
 }
 
-// recordRequest appends a request timestamp to the limiter.
+// recordRequest appends a request timestamp to the limiter and takes a token from its bucket.
 // Must be called with limiter.mu locked.
 func recordRequest(l *limiter) {
+	if l.burst > 0 {
+		l.bucketTokens--
+	}
+	// Always record - but clean old requests first if buffer is full
 	if len(l.requests) >= MaxRequestsBufferSize {
 		cleanOldRequests(l)
 	}
+	// Only skip if still at capacity after cleaning (extremely rare edge case)
 	if len(l.requests) < MaxRequestsBufferSize {
 		l.requests = append(l.requests, utils.NowUTC())
 	}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -38,6 +39,16 @@ func TestAddModel(t *testing.T) {
 	assert.True(t, rl.AllowModel("cred1", "gpt-4o-mini"))
 }
 
+func TestRemoveModel(t *testing.T) {
+	rl := New()
+	rl.AddModel("cred1", "gpt-4", 10)
+
+	assert.True(t, rl.RemoveModel("cred1", "gpt-4"))
+	assert.Empty(t, rl.GetAllModels())
+	assert.False(t, rl.RemoveModel("cred1", "gpt-4"))
+	assert.True(t, rl.AllowModel("cred1", "gpt-4"), "untracked models are not limited")
+}
+
 func TestAllow_UnderLimit(t *testing.T) {
 	rl := New()
 	rl.AddCredential("cred1", 5)
@@ -1140,6 +1151,57 @@ func TestConcurrentMultipleModels(t *testing.T) {
 	}
 }
 
+func TestTryAllowAllShare(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithTPM("cred1", 10, 1000)
+	rl.AddModelWithTPM("cred1", "gpt-4o", 4, -1)
+
+	// Half of the model RPM (4) is 2 requests
+	assert.True(t, rl.TryAllowAllShare("cred1", "gpt-4o", 0.5))
+	assert.True(t, rl.TryAllowAllShare("cred1", "gpt-4o", 0.5))
+	assert.False(t, rl.TryAllowAllShare("cred1", "gpt-4o", 0.5))
+	assert.Equal(t, 2, rl.GetCurrentModelRPM("cred1", "gpt-4o"), "rejected requests are not recorded")
+
+	// Full share still uses the remaining capacity
+	assert.True(t, rl.TryAllowAllShare("cred1", "gpt-4o", 1))
+	assert.True(t, rl.TryAllowAll("cred1", "gpt-4o"))
+	assert.False(t, rl.TryAllowAll("cred1", "gpt-4o"))
+
+	// TPM share: 600 of 1000 tokens used is above a 0.5 share
+	rl.AddCredentialWithTPM("cred2", 100, 1000)
+	rl.ConsumeTokens("cred2", 600)
+	assert.False(t, rl.TryAllowAllShare("cred2", "", 0.5))
+	assert.True(t, rl.TryAllowAllShare("cred2", "", 0.8))
+
+	// A tiny share still allows one request per window
+	rl.AddCredential("cred3", 10)
+	assert.True(t, rl.TryAllowAllShare("cred3", "", 0.01))
+	assert.False(t, rl.TryAllowAllShare("cred3", "", 0.01))
+}
+
+func TestTryAllowAllN(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithTPM("cred1", 10, 1000)
+	rl.AddModelWithTPM("cred1", "gpt-4o", 4, -1)
+
+	// 5 requests do not fit in the model RPM (4): nothing is recorded
+	assert.False(t, rl.TryAllowAllN("cred1", "gpt-4o", 5))
+	assert.Equal(t, 0, rl.GetCurrentRPM("cred1"))
+	assert.Equal(t, 0, rl.GetCurrentModelRPM("cred1", "gpt-4o"))
+
+	assert.True(t, rl.TryAllowAllN("cred1", "gpt-4o", 3))
+	assert.Equal(t, 3, rl.GetCurrentRPM("cred1"))
+	assert.Equal(t, 3, rl.GetCurrentModelRPM("cred1", "gpt-4o"))
+	assert.False(t, rl.TryAllowAllN("cred1", "gpt-4o", 2))
+	assert.Equal(t, 3, rl.GetCurrentModelRPM("cred1", "gpt-4o"))
+	assert.True(t, rl.TryAllowAllN("cred1", "gpt-4o", 0))
+
+	// A used up TPM refuses the requests even with RPM headroom left
+	rl.ConsumeTokens("cred1", 1000)
+	assert.False(t, rl.TryAllowAllN("cred1", "gpt-4o", 1))
+	assert.Equal(t, 3, rl.GetCurrentRPM("cred1"))
+}
+
 func TestTryAllowAll(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -1307,3 +1369,193 @@ func TestTryAllowAll(t *testing.T) {
 		})
 	}
 }
+
+func TestBurst_AllowsSpikeUpToBurst(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithBurst("cred1", 60, -1, 5)
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, rl.Allow("cred1"), "request %d within burst", i+1)
+	}
+	assert.False(t, rl.Allow("cred1"), "bucket is empty after burst")
+	assert.Equal(t, 5, rl.GetCurrentRPM("cred1"), "requests are still tracked")
+}
+
+func TestBurst_AboveRPM(t *testing.T) {
+	rl := New()
+	rl.AddModelWithBurst("cred1", "gpt-4o", 2, -1, 4)
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, rl.AllowModel("cred1", "gpt-4o"))
+	}
+	assert.False(t, rl.AllowModel("cred1", "gpt-4o"))
+}
+
+func TestBurst_Refill(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithBurst("cred1", 60, -1, 2)
+
+	assert.True(t, rl.Allow("cred1"))
+	assert.True(t, rl.Allow("cred1"))
+	assert.False(t, rl.Allow("cred1"))
+
+	// 60 rpm refills one token per second; capacity stays capped at burst
+	l := rl.getCredentialLimiter("cred1")
+	l.mu.Lock()
+	l.bucketUpdated = l.bucketUpdated.Add(-time.Minute)
+	l.mu.Unlock()
+
+	assert.True(t, rl.CanAllow("cred1"))
+	assert.True(t, rl.Allow("cred1"))
+	assert.True(t, rl.Allow("cred1"))
+	assert.False(t, rl.Allow("cred1"))
+}
+
+func TestBurst_DisabledForUnlimitedRPM(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithBurst("cred1", -1, -1, 2)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, rl.Allow("cred1"))
+	}
+}
+
+func TestBurst_SetCurrentUsage(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithBurst("cred1", 10, -1, 5)
+
+	// 8 of 10 requests already used remotely leaves 2 tokens
+	rl.SetCredentialCurrentUsage("cred1", 8, 0)
+	assert.True(t, rl.Allow("cred1"))
+	assert.True(t, rl.Allow("cred1"))
+	assert.False(t, rl.Allow("cred1"))
+}
+
+func TestAllowKeys(t *testing.T) {
+	rl := New()
+	key := KeyLimit{Name: "key:abc", RPM: 2}
+	team := KeyLimit{Name: "team:t1", RPM: 3}
+
+	assert.True(t, rl.AllowKeys(key, team))
+	assert.True(t, rl.AllowKeys(key, team))
+	assert.False(t, rl.AllowKeys(key, team), "key limit reached")
+
+	// A rejected request is not recorded against the other limits
+	assert.True(t, rl.AllowKeys(KeyLimit{Name: "key:other", RPM: 5}, team))
+	assert.False(t, rl.AllowKeys(KeyLimit{Name: "key:other", RPM: 5}, team), "team limit reached")
+
+	// Raised limits apply to the existing window
+	key.RPM = 3
+	assert.True(t, rl.AllowKeys(key))
+	assert.False(t, rl.AllowKeys(key))
+
+	assert.True(t, rl.AllowKeys(KeyLimit{Name: "key:unlimited"}), "no limit")
+	assert.True(t, rl.AllowKeys())
+}
+
+func TestAllowKeys_PrunesIdleKeys(t *testing.T) {
+	rl := New()
+	require.True(t, rl.AllowKeys(KeyLimit{Name: "key:idle", RPM: 5}))
+	require.True(t, rl.AllowKeys(KeyLimit{Name: "key:active", RPM: 5}))
+
+	rl.keyLimiters["key:idle"].requests[0] = time.Now().Add(-2 * time.Minute)
+	require.True(t, rl.AllowKeys(KeyLimit{Name: "key:active", RPM: 5}))
+	assert.Contains(t, rl.keyLimiters, "key:idle", "swept at most once a minute")
+
+	rl.keysPruned = time.Now().Add(-2 * time.Minute)
+	require.True(t, rl.AllowKeys(KeyLimit{Name: "key:active", RPM: 5}))
+	assert.NotContains(t, rl.keyLimiters, "key:idle")
+	assert.Equal(t, 3, len(rl.keyLimiters["key:active"].requests))
+}
+
+func TestRetryAfter(t *testing.T) {
+	rl := New()
+	rl.AddCredential("cred1", 2)
+	rl.AddModelWithBurst("cred1", "gpt-4o", 60, -1, 1)
+	rl.AddCredentialWithTPM("cred2", -1, 100)
+
+	assert.Zero(t, rl.RetryAfter("cred1", "gpt-4o"), "limits allow a request")
+	assert.Zero(t, rl.RetryAfter("unknown", "gpt-4o"))
+
+	// Sliding window: the oldest request has to leave the minute window
+	assert.True(t, rl.Allow("cred1"))
+	assert.True(t, rl.Allow("cred1"))
+	wait := rl.RetryAfter("cred1", "")
+	assert.Greater(t, wait, 59*time.Second)
+	assert.LessOrEqual(t, wait, time.Minute)
+
+	// Token bucket: 60 rpm refills one token per second
+	rl.AddCredential("cred1", 100)
+	assert.True(t, rl.AllowModel("cred1", "gpt-4o"))
+	wait = rl.RetryAfter("cred1", "gpt-4o")
+	assert.Greater(t, wait, 900*time.Millisecond)
+	assert.LessOrEqual(t, wait, time.Second)
+
+	// TPM: tokens over the limit have to leave the window
+	rl.ConsumeTokens("cred2", 150)
+	wait = rl.RetryAfter("cred2", "")
+	assert.Greater(t, wait, 59*time.Second)
+	assert.LessOrEqual(t, wait, time.Minute)
+}
+
+func TestLimits(t *testing.T) {
+	rl := New()
+	rl.EnableAdaptive(AdaptiveConfig{DecreaseFactor: 0.5, IncreaseStep: 0.1, IncreaseInterval: time.Minute, MinFactor: 0.1, Cooldown: time.Minute})
+	rl.AddCredentialWithTPM("openai", 10, 1000)
+	rl.AddCredentialWithBurst("bursty", 60, -1, 5)
+	rl.AddModelWithTPM("openai", "gpt-4o", 5, -1)
+
+	assert.True(t, rl.TryAllowAll("openai", "gpt-4o"))
+	rl.ConsumeTokens("openai", 150)
+	assert.True(t, rl.Allow("bursty"))
+	rl.RecordRateLimited("openai", "gpt-4o")
+
+	limits := rl.Limits()
+	require.Len(t, limits.Credentials, 2)
+	openai := limits.Credentials["openai"]
+	assert.Equal(t, 10, openai.RPM)
+	assert.Equal(t, 1000, openai.TPM)
+	assert.Equal(t, 1, openai.CurrentRPM)
+	assert.Equal(t, 150, openai.CurrentTPM)
+	assert.Equal(t, 0.5, openai.Factor)
+	assert.Equal(t, 5, openai.EffectiveRPM)
+	assert.Equal(t, 500, openai.EffectiveTPM)
+	assert.Nil(t, openai.BucketTokens)
+
+	bursty := limits.Credentials["bursty"]
+	require.NotNil(t, bursty.BucketTokens)
+	assert.InDelta(t, 4, *bursty.BucketTokens, 0.1)
+
+	model := limits.Models["openai:gpt-4o"]
+	assert.Equal(t, 5, model.RPM)
+	assert.Equal(t, 1, model.CurrentRPM)
+}
+
+func TestLimits_Concurrent(t *testing.T) {
+	rl := New()
+	rl.AddCredential("openai", 1000)
+	rl.AddModel("openai", "gpt-4o", 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				rl.TryAllowAll("openai", "gpt-4o")
+				rl.ConsumeModelTokens("openai", "gpt-4o", 10)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = rl.Limits()
+			}
+		}()
+	}
+	wg.Wait()
+
+	limits := rl.Limits()
+	assert.Equal(t, 200, limits.Credentials["openai"].CurrentRPM)
+	assert.Equal(t, 2000, limits.Models["openai:gpt-4o"].CurrentTPM)
+}
@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// UsageState is the sliding window usage of all limiters, exported by one router instance
+// and imported by its replacement during a deployment handoff
+type UsageState struct {
+	SavedAt     time.Time              `json:"saved_at"`
+	Credentials map[string]WindowState `json:"credentials"`
+	Models      map[string]WindowState `json:"models"` // "credential:model" -> usage
+	Pools       map[string]PoolState   `json:"pools,omitempty"`
+	Keys        map[string]WindowState `json:"keys"` // "key:<hash>", "team:<id>", "tenant:<name>" -> usage
+}
+
+// WindowState is the usage of one limiter within the last minute
+type WindowState struct {
+	Requests []time.Time  `json:"requests"`
+	Tokens   []TokenState `json:"tokens,omitempty"`
+	Factor   float64      `json:"factor,omitempty"` // Adaptive limit factor (omitted at 1)
+}
+
+// PoolState is the usage and the budget spend of a credential pool
+type PoolState struct {
+	WindowState
+	Day          time.Time `json:"day"`                     // Start of the UTC day of DailySpend
+	Month        time.Time `json:"month"`                   // Start of the UTC month of MonthlySpend
+	DailySpend   float64   `json:"daily_spend,omitempty"`   // USD
+	MonthlySpend float64   `json:"monthly_spend,omitempty"` // USD
+}
+
+// TokenState is a token usage record of a sliding window
+type TokenState struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+}
+
+// ExportUsage returns the requests and tokens recorded within the last minute
+func (r *RPMLimiter) ExportUsage() UsageState {
+	state := UsageState{SavedAt: utils.NowUTC()}
+
+	r.mu.RLock()
+	state.Credentials = exportWindows(r.limiters)
+	state.Models = exportWindows(r.modelLimiters)
+	state.Pools = exportPools(r.pools)
+	r.mu.RUnlock()
+
+	r.keyMu.Lock()
+	state.Keys = exportWindows(r.keyLimiters)
+	r.keyMu.Unlock()
+
+	return state
+}
+
+// ImportUsage adds the usage exported by another instance to the limiters, so the combined
+// traffic of both instances counts against the limits until the old window expires. Pool spend
+// of the current UTC day and month is added to the pool budgets. Usage of credentials, models
+// and pools that are not configured here is skipped; adaptive limit factors are lowered to the
+// imported ones. Returns the number of imported limiter windows.
+func (r *RPMLimiter) ImportUsage(state UsageState) int {
+	imported := 0
+
+	r.mu.RLock()
+	for name, window := range state.Credentials {
+		if l := r.limiters[name]; l != nil {
+			importWindow(l, window)
+			imported++
+		}
+	}
+	for key, window := range state.Models {
+		if l := r.modelLimiters[key]; l != nil {
+			importWindow(l, window)
+			imported++
+		}
+	}
+	for name, poolState := range state.Pools {
+		if p := r.pools[name]; p != nil {
+			importWindow(p.limiter, poolState.WindowState)
+			importPoolSpend(p, poolState)
+			imported++
+		}
+	}
+	r.mu.RUnlock()
+
+	r.keyMu.Lock()
+	for name, window := range state.Keys {
+		l := r.keyLimiters[name]
+		if l == nil {
+			// The limit is set by AllowKeys on the key's next request
+			l = newLimiter(0, -1, 0)
+			r.keyLimiters[name] = l
+		}
+		importWindow(l, window)
+		imported++
+	}
+	r.keyMu.Unlock()
+
+	return imported
+}
+
+// exportWindows copies the current windows of limiters; the caller must hold their map's lock
+func exportWindows(limiters map[string]*limiter) map[string]WindowState {
+	windows := make(map[string]WindowState, len(limiters))
+	for name, l := range limiters {
+		l.mu.Lock()
+		cleanOldRequests(l)
+		cleanOldTokens(l)
+		window := WindowState{Requests: append([]time.Time(nil), l.requests...)}
+		for _, tu := range l.tokens {
+			window.Tokens = append(window.Tokens, TokenState{Time: tu.timestamp, Count: tu.count})
+		}
+		if l.factor != 1 {
+			window.Factor = l.factor
+		}
+		l.mu.Unlock()
+
+		if len(window.Requests) > 0 || len(window.Tokens) > 0 || window.Factor != 0 {
+			windows[name] = window
+		}
+	}
+	return windows
+}
+
+// exportPools copies the windows and budget spend of pools; the caller must hold r.mu
+func exportPools(pools map[string]*pool) map[string]PoolState {
+	limiters := make(map[string]*limiter, len(pools))
+	for name, p := range pools {
+		limiters[name] = p.limiter
+	}
+	windows := exportWindows(limiters)
+
+	states := make(map[string]PoolState, len(pools))
+	now := utils.NowUTC()
+	for name, p := range pools {
+		p.limiter.mu.Lock()
+		rollBudgetPeriods(p, now)
+		state := PoolState{WindowState: windows[name], Day: p.day, Month: p.month, DailySpend: p.dailySpend, MonthlySpend: p.monthlySpend}
+		p.limiter.mu.Unlock()
+
+		if _, ok := windows[name]; ok || state.MonthlySpend > 0 {
+			states[name] = state
+		}
+	}
+	return states
+}
+
+// importPoolSpend adds the exported spend of the current budget periods to p
+func importPoolSpend(p *pool, state PoolState) {
+	p.limiter.mu.Lock()
+	defer p.limiter.mu.Unlock()
+
+	rollBudgetPeriods(p, utils.NowUTC())
+	if state.Day.Equal(p.day) && state.DailySpend > 0 {
+		p.dailySpend += state.DailySpend
+	}
+	if state.Month.Equal(p.month) && state.MonthlySpend > 0 {
+		p.monthlySpend += state.MonthlySpend
+	}
+}
+
+// importWindow adds an exported window to l
+func importWindow(l *limiter, window WindowState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	oneMinuteAgo := utils.NowUTC().Add(-time.Minute)
+	added := 0
+	for _, t := range window.Requests {
+		if t.After(oneMinuteAgo) && len(l.requests) < MaxRequestsBufferSize {
+			l.requests = append(l.requests, t)
+			added++
+		}
+	}
+	for _, tu := range window.Tokens {
+		if tu.Time.After(oneMinuteAgo) && tu.Count > 0 && len(l.tokens) < MaxTokensBufferSize {
+			l.tokens = append(l.tokens, tokenUsage{timestamp: tu.Time, count: tu.Count})
+		}
+	}
+	// Token bucket: the imported requests consumed capacity on the other instance
+	if l.burst > 0 {
+		refillBucket(l)
+		l.bucketTokens = math.Max(0, l.bucketTokens-float64(added))
+	}
+	if window.Factor > 0 && window.Factor < l.factor {
+		l.factor = window.Factor
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportUsage(t *testing.T) {
+	old := New()
+	old.AddCredentialWithTPM("cred1", 3, 1000)
+	old.AddModel("cred1", "gpt-4o", 10)
+	old.AddCredential("removed", 10)
+	for i := 0; i < 2; i++ {
+		require.True(t, old.Allow("cred1"))
+		require.True(t, old.AllowModel("cred1", "gpt-4o"))
+		require.True(t, old.Allow("removed"))
+	}
+	old.ConsumeTokens("cred1", 400)
+	require.True(t, old.AllowKeys(KeyLimit{Name: "team:a", RPM: 2}))
+	old.limiters["cred1"].factor = 0.5
+
+	// The state survives a JSON round trip (admin API)
+	data, err := json.Marshal(old.ExportUsage())
+	require.NoError(t, err)
+	var state UsageState
+	require.NoError(t, json.Unmarshal(data, &state))
+	assert.Len(t, state.Credentials["cred1"].Requests, 2)
+	assert.Equal(t, 0.5, state.Credentials["cred1"].Factor)
+
+	next := New()
+	next.AddCredentialWithTPM("cred1", 3, 1000)
+	next.AddModel("cred1", "gpt-4o", 10)
+	require.True(t, next.Allow("cred1"))
+
+	assert.Equal(t, 3, next.ImportUsage(state), "cred1, cred1:gpt-4o and team:a; removed is skipped")
+	assert.Equal(t, 3, next.GetCurrentRPM("cred1"), "usage of both instances counts")
+	assert.False(t, next.Allow("cred1"))
+	assert.Equal(t, 2, next.GetCurrentModelRPM("cred1", "gpt-4o"))
+	assert.Equal(t, 400, next.GetCurrentTPM("cred1"))
+	assert.Equal(t, 0.5, next.GetAdaptiveFactor("cred1"))
+	assert.True(t, next.AllowKeys(KeyLimit{Name: "team:a", RPM: 2}))
+	assert.False(t, next.AllowKeys(KeyLimit{Name: "team:a", RPM: 2}))
+	assert.Nil(t, next.getCredentialLimiter("removed"))
+}
+
+func TestImportUsage_SkipsExpiredRequests(t *testing.T) {
+	rl := New()
+	rl.AddCredentialWithBurst("cred1", 60, -1, 5)
+
+	now := utils.NowUTC()
+	rl.ImportUsage(UsageState{Credentials: map[string]WindowState{
+		"cred1": {Requests: []time.Time{now.Add(-2 * time.Minute), now.Add(-10 * time.Second), now.Add(-time.Second)}},
+	}})
+
+	assert.Equal(t, 2, rl.GetCurrentRPM("cred1"))
+	l := rl.getCredentialLimiter("cred1")
+	assert.InDelta(t, 3, l.bucketTokens, 0.1, "imported requests consume token bucket capacity")
+}
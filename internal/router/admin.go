@@ -0,0 +1,668 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/quota"
+	"github.com/mixaill76/auto_ai_router/internal/sampling"
+	"github.com/mixaill76/auto_ai_router/internal/security"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// RuntimeState holds Go runtime counters for the /debug/state snapshot
+type RuntimeState struct {
+	Goroutines    int    `json:"goroutines"`
+	HeapAllocMB   uint64 `json:"heap_alloc_mb"`
+	HeapObjects   uint64 `json:"heap_objects"`
+	SysMB         uint64 `json:"sys_mb"`
+	NumGC         uint32 `json:"num_gc"`
+	LastGCPauseUs uint64 `json:"last_gc_pause_us"`
+}
+
+// AdminStateResponse is the /debug/state response body
+type AdminStateResponse struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Version   string           `json:"version"`
+	Uptime    string           `json:"uptime"`
+	Runtime   RuntimeState     `json:"runtime"`
+	Proxy     proxy.DebugState `json:"proxy"`
+}
+
+var processStart = utils.NowUTC()
+
+// NewAdminHandler returns the handler for the admin listener:
+//
+//	/debug/pprof/*                                  - net/http/pprof profiles (cpu, heap, trace, ...)
+//	/debug/goroutines                               - full goroutine stack dump (text)
+//	/debug/state                                    - JSON snapshot of limiter, queue and cache sizes
+//	/debug/limits                                   - JSON dump of credential and model limiter states, balancer cursor and fail2ban counters
+//	/admin/boosts                                   - list (GET) and grant (POST) temporary key/team quota boosts
+//	/admin/boosts/{id}                              - revoke (DELETE) a quota boost
+//	/admin/master-keys                              - list (GET) and add (POST) master keys; the previous keys stay valid for a grace period
+//	/admin/master-keys/{id}                         - revoke (DELETE) a previous master key
+//	/admin/read-only                                - show (GET) and switch (PUT) read-only mode
+//	/admin/model-pins                               - list (GET) rolling model aliases with their pinned and newest snapshots
+//	/admin/model-pins/{alias}                       - bump (PUT) the pinned snapshot of an alias
+//	/admin/fail2ban                                 - list (GET) current bans with reasons and remaining duration
+//	/admin/fail2ban/bans                            - manually ban (POST) a credential or credential+model pair
+//	/admin/fail2ban/bans/{credential}[/{model}]     - unban (DELETE) a credential's models or one pair
+//	/admin/fail2ban/failures/{credential}[/{model}] - reset (DELETE) failure counters without unbanning
+//	/admin/log-sampling                             - show (GET) and change (PUT) the sampling percentage and body limit
+//	/admin/log-sampling/keys                        - log all requests (POST) of an API key in full
+//	/admin/log-sampling/keys/{key}                  - stop logging (DELETE) a key's requests
+//	/admin/handoff                                  - export (GET) limiter usage, pool spend, fail2ban state and batch records, import (POST) them into a new instance
+//	/admin/requests                                 - list (GET) in-flight requests with age, key, credential and model
+//	/admin/requests/{id}                            - cancel (DELETE) the upstream call of an in-flight request
+//
+// The /admin/boosts endpoints are registered only when quota_boosts is enabled,
+// the /admin/model-pins endpoints only when model_pins is enabled.
+// Every endpoint requires a valid master key as a Bearer token. In read-only mode
+// all /admin/ mutations except switching read-only mode are refused with 403.
+func NewAdminHandler(p *proxy.Proxy, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", handleGoroutineDump)
+	mux.HandleFunc("/debug/state", func(w http.ResponseWriter, req *http.Request) {
+		handleDebugState(w, p, logger)
+	})
+	mux.HandleFunc("/debug/limits", func(w http.ResponseWriter, req *http.Request) {
+		writeAdminJSON(w, http.StatusOK, p.DebugLimits(), logger)
+	})
+	if boosts := p.QuotaBoosts(); boosts != nil {
+		mux.HandleFunc("GET /admin/boosts", func(w http.ResponseWriter, req *http.Request) {
+			writeAdminJSON(w, http.StatusOK, map[string][]quota.Boost{"boosts": boosts.List()}, logger)
+		})
+		mux.HandleFunc("POST /admin/boosts", func(w http.ResponseWriter, req *http.Request) {
+			handleGrantBoost(w, req, boosts, logger)
+		})
+		mux.HandleFunc("DELETE /admin/boosts/{id}", func(w http.ResponseWriter, req *http.Request) {
+			handleRevokeBoost(w, req, boosts, logger)
+		})
+	}
+
+	masterKeys := p.MasterKeys()
+	mux.HandleFunc("GET /admin/master-keys", func(w http.ResponseWriter, req *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string][]masterkey.Key{"keys": masterKeys.List()}, logger)
+	})
+	mux.HandleFunc("POST /admin/master-keys", func(w http.ResponseWriter, req *http.Request) {
+		handleAddMasterKey(w, req, masterKeys, logger)
+	})
+	mux.HandleFunc("DELETE /admin/master-keys/{id}", func(w http.ResponseWriter, req *http.Request) {
+		handleRevokeMasterKey(w, req, masterKeys, logger)
+	})
+
+	if pins := p.ModelPins(); pins != nil {
+		mux.HandleFunc("GET /admin/model-pins", func(w http.ResponseWriter, req *http.Request) {
+			writeAdminJSON(w, http.StatusOK, map[string][]models.Pin{"pins": pins.List()}, logger)
+		})
+		mux.HandleFunc("PUT /admin/model-pins/{alias}", func(w http.ResponseWriter, req *http.Request) {
+			handleBumpModelPin(w, req, pins, logger)
+		})
+	}
+
+	f2b := p.Fail2Ban()
+	mux.HandleFunc("GET /admin/fail2ban", func(w http.ResponseWriter, req *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string][]BanEntry{"bans": listBans(f2b)}, logger)
+	})
+	mux.HandleFunc("POST /admin/fail2ban/bans", func(w http.ResponseWriter, req *http.Request) {
+		handleBan(w, req, p, f2b, logger)
+	})
+	mux.HandleFunc("DELETE /admin/fail2ban/bans/{credential}", func(w http.ResponseWriter, req *http.Request) {
+		handleUnban(w, req, f2b, logger)
+	})
+	mux.HandleFunc("DELETE /admin/fail2ban/bans/{credential}/{model...}", func(w http.ResponseWriter, req *http.Request) {
+		handleUnban(w, req, f2b, logger)
+	})
+	mux.HandleFunc("DELETE /admin/fail2ban/failures/{credential}", func(w http.ResponseWriter, req *http.Request) {
+		handleResetFailures(w, req, f2b, logger)
+	})
+	mux.HandleFunc("DELETE /admin/fail2ban/failures/{credential}/{model...}", func(w http.ResponseWriter, req *http.Request) {
+		handleResetFailures(w, req, f2b, logger)
+	})
+
+	if sampler := p.RequestSampler(); sampler != nil {
+		mux.HandleFunc("GET /admin/log-sampling", func(w http.ResponseWriter, req *http.Request) {
+			writeAdminJSON(w, http.StatusOK, sampler.Settings(), logger)
+		})
+		mux.HandleFunc("PUT /admin/log-sampling", func(w http.ResponseWriter, req *http.Request) {
+			handleSetLogSampling(w, req, sampler, logger)
+		})
+		mux.HandleFunc("POST /admin/log-sampling/keys", func(w http.ResponseWriter, req *http.Request) {
+			handleAddSampledKey(w, req, sampler, logger)
+		})
+		mux.HandleFunc("DELETE /admin/log-sampling/keys/{key}", func(w http.ResponseWriter, req *http.Request) {
+			handleRemoveSampledKey(w, req, sampler, logger)
+		})
+	}
+
+	mux.HandleFunc("GET /admin/handoff", func(w http.ResponseWriter, req *http.Request) {
+		writeAdminJSON(w, http.StatusOK, p.ExportHandoffState(), logger)
+	})
+	mux.HandleFunc("POST /admin/handoff", func(w http.ResponseWriter, req *http.Request) {
+		handleImportHandoff(w, req, p, logger)
+	})
+
+	mux.HandleFunc("GET /admin/requests", func(w http.ResponseWriter, req *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string][]proxy.InFlightRequest{"requests": p.InFlightRequests()}, logger)
+	})
+	mux.HandleFunc("DELETE /admin/requests/{id}", func(w http.ResponseWriter, req *http.Request) {
+		handleCancelRequest(w, req, p, logger)
+	})
+
+	mux.HandleFunc("GET /admin/read-only", func(w http.ResponseWriter, req *http.Request) {
+		writeAdminJSON(w, http.StatusOK, ReadOnlyState{ReadOnly: p.ReadOnly()}, logger)
+	})
+	mux.HandleFunc("PUT /admin/read-only", func(w http.ResponseWriter, req *http.Request) {
+		handleSetReadOnly(w, req, p, logger)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if _, ok := p.MatchMasterKey(token, req); !ok {
+			if logger != nil {
+				logger.Warn("Unauthorized admin request", "path", req.URL.Path, "remote_addr", req.RemoteAddr)
+			}
+			proxy.WriteErrorUnauthorized(w, "Invalid master key")
+			return
+		}
+		if p.ReadOnly() && isAdminMutation(req) {
+			proxy.WriteErrorForbidden(w, "Router is in read-only mode")
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+func handleGoroutineDump(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func handleDebugState(w http.ResponseWriter, p *proxy.Proxy, logger *slog.Logger) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := AdminStateResponse{
+		Timestamp: utils.NowUTC(),
+		Version:   proxy.Version,
+		Uptime:    utils.NowUTC().Sub(processStart).Truncate(time.Second).String(),
+		Runtime: RuntimeState{
+			Goroutines:    runtime.NumGoroutine(),
+			HeapAllocMB:   mem.HeapAlloc / 1024 / 1024,
+			HeapObjects:   mem.HeapObjects,
+			SysMB:         mem.Sys / 1024 / 1024,
+			NumGC:         mem.NumGC,
+			LastGCPauseUs: mem.PauseNs[(mem.NumGC+255)%256] / 1000,
+		},
+		Proxy: p.DebugState(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil && logger != nil {
+		logger.Error("Failed to encode debug state", "error", err)
+	}
+}
+
+// GrantBoostRequest is the POST /admin/boosts request body
+type GrantBoostRequest struct {
+	Key           string  `json:"key,omitempty"`     // Key alias or hashed API key
+	TeamID        string  `json:"team_id,omitempty"` // Team ID
+	RPMPercent    float64 `json:"rpm_percent"`       // e.g. 50 = +50% rpm_limit
+	BudgetPercent float64 `json:"budget_percent"`    // e.g. 50 = +50% max_budget
+	Duration      string  `json:"duration"`          // Go duration, e.g. "24h"
+	Reason        string  `json:"reason,omitempty"`
+}
+
+func handleGrantBoost(w http.ResponseWriter, req *http.Request, boosts *quota.Store, logger *slog.Logger) {
+	var body GrantBoostRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+	duration, err := time.ParseDuration(body.Duration)
+	if err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid duration: "+body.Duration)
+		return
+	}
+
+	boost, err := boosts.Grant(quota.Boost{
+		Key:           body.Key,
+		TeamID:        body.TeamID,
+		RPMPercent:    body.RPMPercent,
+		BudgetPercent: body.BudgetPercent,
+		Reason:        body.Reason,
+	}, duration)
+	if err != nil {
+		proxy.WriteErrorBadRequest(w, err.Error())
+		return
+	}
+
+	if logger != nil {
+		logger.Info("Quota boost granted",
+			"id", boost.ID,
+			"key", boost.Key,
+			"team_id", boost.TeamID,
+			"rpm_percent", boost.RPMPercent,
+			"budget_percent", boost.BudgetPercent,
+			"expires_at", boost.ExpiresAt,
+			"reason", boost.Reason,
+		)
+	}
+	writeAdminJSON(w, http.StatusCreated, boost, logger)
+}
+
+func handleRevokeBoost(w http.ResponseWriter, req *http.Request, boosts *quota.Store, logger *slog.Logger) {
+	id := req.PathValue("id")
+	if !boosts.Revoke(id) {
+		proxy.WriteErrorNotFound(w, "Quota boost not found: "+id)
+		return
+	}
+	if logger != nil {
+		logger.Info("Quota boost revoked", "id", id)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMasterKeyRequest is the POST /admin/master-keys request body
+type AddMasterKeyRequest struct {
+	Key         string `json:"key"`                    // New primary master key
+	GracePeriod string `json:"grace_period,omitempty"` // Go duration the previous keys stay valid (default: server.master_key_grace_period)
+}
+
+func handleAddMasterKey(w http.ResponseWriter, req *http.Request, masterKeys *masterkey.Ring, logger *slog.Logger) {
+	var body AddMasterKeyRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+	var grace time.Duration
+	if body.GracePeriod != "" {
+		var err error
+		if grace, err = time.ParseDuration(body.GracePeriod); err != nil || grace <= 0 {
+			proxy.WriteErrorBadRequest(w, "Invalid grace_period: "+body.GracePeriod)
+			return
+		}
+	}
+
+	key, err := masterKeys.Add(body.Key, grace)
+	if err != nil {
+		proxy.WriteErrorBadRequest(w, err.Error())
+		return
+	}
+
+	if logger != nil {
+		logger.Info("Master key rotated",
+			"key_id", key.ID,
+			"previous_keys", len(masterKeys.List())-1,
+			"remote_addr", req.RemoteAddr,
+		)
+	}
+	writeAdminJSON(w, http.StatusCreated, key, logger)
+}
+
+func handleRevokeMasterKey(w http.ResponseWriter, req *http.Request, masterKeys *masterkey.Ring, logger *slog.Logger) {
+	id := req.PathValue("id")
+	if err := masterKeys.Revoke(id); err != nil {
+		if errors.Is(err, masterkey.ErrNotFound) {
+			proxy.WriteErrorNotFound(w, "Master key not found: "+id)
+		} else {
+			proxy.WriteErrorBadRequest(w, err.Error())
+		}
+		return
+	}
+	if logger != nil {
+		logger.Info("Master key revoked", "key_id", id, "remote_addr", req.RemoteAddr)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BumpModelPinRequest is the PUT /admin/model-pins/{alias} request body
+type BumpModelPinRequest struct {
+	Snapshot string `json:"snapshot,omitempty"` // Snapshot to pin ("" = newest available)
+}
+
+func handleBumpModelPin(w http.ResponseWriter, req *http.Request, pins *models.PinRegistry, logger *slog.Logger) {
+	var body BumpModelPinRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	alias := req.PathValue("alias")
+	pin, err := pins.Bump(alias, body.Snapshot)
+	if err != nil {
+		if errors.Is(err, models.ErrUnknownPin) {
+			proxy.WriteErrorNotFound(w, "Model pin not found: "+alias)
+		} else {
+			proxy.WriteErrorBadRequest(w, err.Error())
+		}
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, pin, logger)
+}
+
+// BanEntry is a ban listed by GET /admin/fail2ban
+type BanEntry struct {
+	Credential      string      `json:"credential"`
+	Model           string      `json:"model,omitempty"` // "" = every model of the credential
+	Reason          string      `json:"reason"`          // "manual: ..." or "status 429"
+	ErrorCode       int         `json:"error_code,omitempty"`
+	ErrorCodeCounts map[int]int `json:"error_code_counts,omitempty"`
+	BannedAt        time.Time   `json:"banned_at"`
+	Permanent       bool        `json:"permanent"`
+	ExpiresAt       *time.Time  `json:"expires_at,omitempty"`
+	Remaining       string      `json:"remaining,omitempty"` // Time left of a temporary ban
+}
+
+// listBans returns the active bans sorted by credential and model
+func listBans(f2b *fail2ban.Fail2Ban) []BanEntry {
+	now := utils.NowUTC()
+	pairs := f2b.GetBannedPairs()
+	entries := make([]BanEntry, 0, len(pairs))
+	for _, pair := range pairs {
+		entry := BanEntry{
+			Credential:      pair.Credential,
+			Model:           pair.Model,
+			ErrorCode:       pair.ErrorCode,
+			ErrorCodeCounts: pair.ErrorCodeCounts,
+			BannedAt:        pair.BanTime,
+			Permanent:       pair.BanDuration == 0,
+		}
+		if pair.Reason != "" {
+			entry.Reason = "manual: " + pair.Reason
+		} else {
+			entry.Reason = "status " + strconv.Itoa(pair.ErrorCode)
+		}
+		if !entry.Permanent {
+			expiresAt := pair.BanTime.Add(pair.BanDuration)
+			if !expiresAt.After(now) {
+				continue // Expired, lifted on the next IsBanned check
+			}
+			entry.ExpiresAt = &expiresAt
+			entry.Remaining = expiresAt.Sub(now).Truncate(time.Second).String()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Credential != entries[j].Credential {
+			return entries[i].Credential < entries[j].Credential
+		}
+		return entries[i].Model < entries[j].Model
+	})
+	return entries
+}
+
+// BanRequest is the POST /admin/fail2ban/bans request body
+type BanRequest struct {
+	Credential string `json:"credential"`
+	Model      string `json:"model,omitempty"`    // "" = every model of the credential
+	Duration   string `json:"duration,omitempty"` // Go duration ("" or "permanent" = permanent)
+	Reason     string `json:"reason,omitempty"`
+}
+
+func handleBan(w http.ResponseWriter, req *http.Request, p *proxy.Proxy, f2b *fail2ban.Fail2Ban, logger *slog.Logger) {
+	var body BanRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+	if body.Credential == "" {
+		proxy.WriteErrorBadRequest(w, "credential is required")
+		return
+	}
+	if !p.HasCredential(body.Credential) {
+		proxy.WriteErrorNotFound(w, "Credential not found: "+body.Credential)
+		return
+	}
+	var duration time.Duration
+	if body.Duration != "" && body.Duration != "permanent" {
+		var err error
+		if duration, err = time.ParseDuration(body.Duration); err != nil || duration <= 0 {
+			proxy.WriteErrorBadRequest(w, "Invalid duration: "+body.Duration)
+			return
+		}
+	}
+
+	f2b.Ban(body.Credential, body.Model, duration, body.Reason)
+	if logger != nil {
+		logger.Warn("Credential banned manually",
+			"credential", body.Credential,
+			"model", body.Model,
+			"duration", banDurationString(duration),
+			"reason", body.Reason,
+			"remote_addr", req.RemoteAddr,
+		)
+	}
+	for _, entry := range listBans(f2b) {
+		if entry.Credential == body.Credential && entry.Model == body.Model {
+			writeAdminJSON(w, http.StatusCreated, entry, logger)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func handleUnban(w http.ResponseWriter, req *http.Request, f2b *fail2ban.Fail2Ban, logger *slog.Logger) {
+	credential, model := req.PathValue("credential"), req.PathValue("model")
+	unbanned := 0
+	if model != "" {
+		if f2b.Unban(credential, model) {
+			unbanned = 1
+		}
+	} else {
+		unbanned = f2b.UnbanCredential(credential)
+	}
+	if unbanned == 0 {
+		proxy.WriteErrorNotFound(w, "No ban found for "+banTarget(credential, model))
+		return
+	}
+	if logger != nil {
+		logger.Info("Credential unbanned manually",
+			"credential", credential, "model", model, "bans", unbanned, "remote_addr", req.RemoteAddr)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleResetFailures(w http.ResponseWriter, req *http.Request, f2b *fail2ban.Fail2Ban, logger *slog.Logger) {
+	credential, model := req.PathValue("credential"), req.PathValue("model")
+	reset := f2b.ResetFailures(credential, model)
+	if logger != nil && reset > 0 {
+		logger.Info("Fail2ban failure counters reset",
+			"credential", credential, "model", model, "pairs", reset, "remote_addr", req.RemoteAddr)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func banTarget(credential, model string) string {
+	if model == "" {
+		return "credential " + credential
+	}
+	return credential + "/" + model
+}
+
+func banDurationString(duration time.Duration) string {
+	if duration == 0 {
+		return "permanent"
+	}
+	return duration.String()
+}
+
+// LogSamplingRequest is the PUT /admin/log-sampling request body; omitted fields are unchanged
+type LogSamplingRequest struct {
+	Percent      *float64 `json:"percent,omitempty"`        // Share of all requests logged, 0-100
+	MaxBodyBytes *int     `json:"max_body_bytes,omitempty"` // Logged body size limit per request and response
+}
+
+func handleSetLogSampling(w http.ResponseWriter, req *http.Request, sampler *sampling.Sampler, logger *slog.Logger) {
+	var body LogSamplingRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+	if body.Percent != nil && (*body.Percent < 0 || *body.Percent > 100) {
+		proxy.WriteErrorBadRequest(w, fmt.Sprintf("percent must be between 0 and 100, got %v", *body.Percent))
+		return
+	}
+	if body.MaxBodyBytes != nil && *body.MaxBodyBytes <= 0 {
+		proxy.WriteErrorBadRequest(w, fmt.Sprintf("max_body_bytes must be positive, got %d", *body.MaxBodyBytes))
+		return
+	}
+	if body.Percent != nil {
+		_ = sampler.SetPercent(*body.Percent)
+	}
+	if body.MaxBodyBytes != nil {
+		_ = sampler.SetMaxBodyBytes(*body.MaxBodyBytes)
+	}
+
+	settings := sampler.Settings()
+	if logger != nil {
+		logger.Info("Request log sampling changed",
+			"percent", settings.Percent,
+			"max_body_bytes", settings.MaxBodyBytes,
+			"remote_addr", req.RemoteAddr,
+		)
+	}
+	writeAdminJSON(w, http.StatusOK, settings, logger)
+}
+
+// AddSampledKeyRequest is the POST /admin/log-sampling/keys request body
+type AddSampledKeyRequest struct {
+	Key      string `json:"key"`                // API key (sk-...) or its hash
+	Duration string `json:"duration,omitempty"` // Go duration, e.g. "1h" (default: until removed)
+	Reason   string `json:"reason,omitempty"`
+}
+
+func handleAddSampledKey(w http.ResponseWriter, req *http.Request, sampler *sampling.Sampler, logger *slog.Logger) {
+	var body AddSampledKeyRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+	var duration time.Duration
+	if body.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(body.Duration); err != nil || duration <= 0 {
+			proxy.WriteErrorBadRequest(w, "Invalid duration: "+body.Duration)
+			return
+		}
+	}
+
+	key, err := sampler.AddKey(body.Key, body.Reason, duration)
+	if err != nil {
+		proxy.WriteErrorBadRequest(w, err.Error())
+		return
+	}
+
+	if logger != nil {
+		logger.Info("Request log sampling enabled for key",
+			"key", security.MaskToken(key.Key),
+			"duration", body.Duration,
+			"reason", key.Reason,
+		)
+	}
+	writeAdminJSON(w, http.StatusCreated, key, logger)
+}
+
+func handleRemoveSampledKey(w http.ResponseWriter, req *http.Request, sampler *sampling.Sampler, logger *slog.Logger) {
+	key := req.PathValue("key")
+	if !sampler.RemoveKey(key) {
+		proxy.WriteErrorNotFound(w, "Sampled key not found: "+security.MaskToken(key))
+		return
+	}
+	if logger != nil {
+		logger.Info("Request log sampling disabled for key", "key", security.MaskToken(key))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleCancelRequest(w http.ResponseWriter, req *http.Request, p *proxy.Proxy, logger *slog.Logger) {
+	id := req.PathValue("id")
+	if !p.CancelRequest(id) {
+		proxy.WriteErrorNotFound(w, "Request not in flight: "+id)
+		return
+	}
+	if logger != nil {
+		logger.Warn("In-flight request cancelled", "request_id", id, "remote_addr", req.RemoteAddr)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReadOnlyState is the /admin/read-only request and response body
+type ReadOnlyState struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+func handleSetReadOnly(w http.ResponseWriter, req *http.Request, p *proxy.Proxy, logger *slog.Logger) {
+	var body ReadOnlyState
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+	if previous := p.ReadOnly(); previous != body.ReadOnly {
+		p.SetReadOnly(body.ReadOnly)
+		if logger != nil {
+			logger.Warn("Read-only mode switched", "read_only", body.ReadOnly, "remote_addr", req.RemoteAddr)
+		}
+	}
+	writeAdminJSON(w, http.StatusOK, body, logger)
+}
+
+// isAdminMutation reports whether req changes admin state; switching read-only mode
+// itself is not a mutation, so it can always be turned off again
+func isAdminMutation(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return false
+	}
+	return strings.HasPrefix(req.URL.Path, "/admin/") && req.URL.Path != "/admin/read-only"
+}
+
+func handleImportHandoff(w http.ResponseWriter, req *http.Request, p *proxy.Proxy, logger *slog.Logger) {
+	var state proxy.HandoffState
+	if err := json.NewDecoder(req.Body).Decode(&state); err != nil {
+		proxy.WriteErrorBadRequest(w, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result := p.ImportHandoffState(state)
+	if logger != nil {
+		logger.Info("Runtime state imported",
+			"limiters", result.Limiters,
+			"batches", result.Batches,
+			"bans", result.Bans,
+			"failure_counters", result.FailureCounters,
+			"saved_at", state.Limits.SavedAt,
+			"remote_addr", req.RemoteAddr,
+		)
+	}
+	writeAdminJSON(w, http.StatusOK, result, logger)
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil && logger != nil {
+		logger.Error("Failed to encode admin response", "error", err)
+	}
+}
@@ -0,0 +1,455 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/masterkey"
+	"github.com/mixaill76/auto_ai_router/internal/models"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/quota"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/sampling"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_RequiresMasterKey(t *testing.T) {
+	handler := NewAdminHandler(createTestProxy(), testhelpers.NewTestLogger())
+
+	for _, path := range []string{"/debug/state", "/debug/limits", "/debug/goroutines", "/debug/pprof/"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer wrong-key")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+	}
+}
+
+func TestAdminHandler_DebugState(t *testing.T) {
+	handler := NewAdminHandler(createTestProxy(), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/state", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AdminStateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Greater(t, resp.Runtime.Goroutines, 0)
+	assert.Equal(t, 2, resp.Proxy.Credentials)
+	require.NotNil(t, resp.Proxy.Limiter)
+	assert.Equal(t, 2, resp.Proxy.Limiter.Credentials)
+	assert.NotNil(t, resp.Proxy.ModelManager)
+	assert.NotNil(t, resp.Proxy.VertexTokens)
+	assert.Nil(t, resp.Proxy.SpendLogger, "spend logger is omitted when LiteLLM DB is disabled")
+}
+
+func TestAdminHandler_DebugLimits(t *testing.T) {
+	var bal *balancer.RoundRobin
+	p := createTestProxyWith(func(cfg *proxy.Config) { bal = cfg.Balancer })
+	handler := NewAdminHandler(p, testhelpers.NewTestLogger())
+	_, err := bal.NextForModel("")
+	require.NoError(t, err)
+	p.Fail2Ban().RecordResponse("test2", "gpt-4o", 500)
+	p.Fail2Ban().Ban("test1", "gpt-4o", 0, "maintenance")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/limits", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var resp proxy.DebugLimits
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Credentials, 2)
+	assert.Equal(t, 100, resp.Credentials["test1"].RPM)
+	assert.Equal(t, 1, resp.Credentials["test1"].CurrentRPM)
+	assert.Equal(t, 0, resp.Credentials["test2"].CurrentRPM)
+	assert.Equal(t, map[string]int{"": 1}, resp.Balancer.Types)
+	require.Len(t, resp.Fail2Ban.Bans, 1)
+	assert.Equal(t, "test1", resp.Fail2Ban.Bans[0].Credential)
+	assert.Equal(t, "maintenance", resp.Fail2Ban.Bans[0].Reason)
+	require.Len(t, resp.Fail2Ban.Failures, 1)
+	assert.Equal(t, map[int]int{500: 1}, resp.Fail2Ban.Failures[0].Counts)
+}
+
+func TestAdminHandler_GoroutinesAndPprof(t *testing.T) {
+	handler := NewAdminHandler(createTestProxy(), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/goroutines", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine ")
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/pprof/heap?debug=1", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "heap profile")
+}
+
+func TestAdminHandler_QuotaBoosts(t *testing.T) {
+	boosts := quota.NewStore(48 * time.Hour)
+	handler := NewAdminHandler(createTestProxyWith(func(cfg *proxy.Config) {
+		cfg.QuotaBoosts = boosts
+	}), testhelpers.NewTestLogger())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/boosts", `{"key":"campaign","rpm_percent":50,"duration":"24h","reason":"launch"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var granted quota.Boost
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &granted))
+	assert.NotEmpty(t, granted.ID)
+	assert.Equal(t, "campaign", granted.Key)
+	assert.Equal(t, 24*time.Hour, granted.ExpiresAt.Sub(granted.CreatedAt))
+	assert.Equal(t, 1.5, boosts.Factors("campaign", "", "").KeyRPM)
+
+	for _, body := range []string{
+		`not json`,
+		`{"key":"campaign","rpm_percent":50,"duration":"tomorrow"}`,
+		`{"key":"campaign","rpm_percent":50,"duration":"72h"}`,
+		`{"rpm_percent":50,"duration":"1h"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/boosts", body).Code, body)
+	}
+
+	w = do(http.MethodGet, "/admin/boosts", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list map[string][]quota.Boost
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list["boosts"], 1)
+	assert.Equal(t, granted.ID, list["boosts"][0].ID)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/boosts/"+granted.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/boosts/"+granted.ID, "").Code)
+	assert.Empty(t, boosts.List())
+}
+
+func TestAdminHandler_QuotaBoostsDisabled(t *testing.T) {
+	handler := NewAdminHandler(createTestProxy(), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/boosts", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminHandler_ModelPins(t *testing.T) {
+	available := []string{"gpt-4o-2024-08-06"}
+	pins := models.NewPinRegistry(config.ModelPinsConfig{
+		Enabled: true,
+		Aliases: map[string]config.ModelPinConfig{"gpt-4o": {Policy: config.ModelPinPolicyPinned, Family: "gpt-4o"}},
+	}, func() []string { return available }, testhelpers.NewTestLogger())
+	handler := NewAdminHandler(createTestProxyWith(func(cfg *proxy.Config) {
+		cfg.ModelPins = pins
+	}), testhelpers.NewTestLogger())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	snapshot, _ := pins.Resolve("gpt-4o")
+	require.Equal(t, "gpt-4o-2024-08-06", snapshot)
+	available = append(available, "gpt-4o-2024-11-20")
+
+	w := do(http.MethodPut, "/admin/model-pins/gpt-4o", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pin models.Pin
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pin))
+	assert.Equal(t, "gpt-4o-2024-11-20", pin.Snapshot)
+
+	w = do(http.MethodPut, "/admin/model-pins/gpt-4o", `{"snapshot":"gpt-4o-2024-08-06"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(http.MethodGet, "/admin/model-pins", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list map[string][]models.Pin
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list["pins"], 1)
+	assert.Equal(t, "gpt-4o-2024-08-06", list["pins"][0].Snapshot)
+	assert.True(t, list["pins"][0].Stale)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/model-pins/gpt-4o", `{"snapshot":"gpt-4o-mini"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/model-pins/gpt-4o", `not json`).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/admin/model-pins/gpt-4", "").Code)
+}
+
+func TestAdminHandler_MasterKeyRotation(t *testing.T) {
+	p := createTestProxy()
+	handler := NewAdminHandler(p, testhelpers.NewTestLogger())
+
+	do := func(key, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("test-master-key", http.MethodPost, "/admin/master-keys", `{"key":"sk-rotated-master-key","grace_period":"30m"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var added masterkey.Key
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	assert.Equal(t, masterkey.Fingerprint("sk-rotated-master-key"), added.ID)
+	assert.True(t, added.Primary)
+	assert.NotContains(t, w.Body.String(), "sk-rotated-master-key", "the secret is never returned")
+	assert.Equal(t, "sk-rotated-master-key", p.GetMasterKey())
+
+	for _, body := range []string{
+		`not json`,
+		`{"key":""}`,
+		`{"key":"sk-rotated-master-key"}`,
+		`{"key":"sk-other","grace_period":"soon"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do("sk-rotated-master-key", http.MethodPost, "/admin/master-keys", body).Code, body)
+	}
+
+	// The previous key stays valid during the grace period
+	w = do("test-master-key", http.MethodGet, "/admin/master-keys", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list map[string][]masterkey.Key
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list["keys"], 2)
+	previous := list["keys"][1]
+	assert.Equal(t, masterkey.Fingerprint("test-master-key"), previous.ID)
+	require.NotNil(t, previous.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), *previous.ExpiresAt, time.Minute)
+
+	assert.Equal(t, http.StatusBadRequest, do("sk-rotated-master-key", http.MethodDelete, "/admin/master-keys/"+added.ID, "").Code,
+		"the primary key cannot be revoked")
+	assert.Equal(t, http.StatusNoContent, do("sk-rotated-master-key", http.MethodDelete, "/admin/master-keys/"+previous.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("sk-rotated-master-key", http.MethodDelete, "/admin/master-keys/"+previous.ID, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("test-master-key", http.MethodGet, "/admin/master-keys", "").Code)
+}
+
+func TestAdminHandler_Fail2Ban(t *testing.T) {
+	p := createTestProxy()
+	handler := NewAdminHandler(p, testhelpers.NewTestLogger())
+	f2b := p.Fail2Ban()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	listBans := func() []BanEntry {
+		w := do(http.MethodGet, "/admin/fail2ban", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var list map[string][]BanEntry
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		return list["bans"]
+	}
+
+	for i := 0; i < 3; i++ {
+		f2b.RecordResponse("test1", "gpt-4o", http.StatusUnauthorized)
+	}
+	w := do(http.MethodPost, "/admin/fail2ban/bans", `{"credential":"test2","duration":"1h","reason":"provider incident"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created BanEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "manual: provider incident", created.Reason)
+	require.NotNil(t, created.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *created.ExpiresAt, time.Minute)
+	assert.True(t, f2b.IsBanned("test2", "any-model"), "a ban without model covers every model")
+
+	bans := listBans()
+	require.Len(t, bans, 2)
+	assert.Equal(t, "test1", bans[0].Credential)
+	assert.Equal(t, "status 401", bans[0].Reason)
+	assert.True(t, bans[0].Permanent)
+	assert.Equal(t, map[int]int{401: 3}, bans[0].ErrorCodeCounts)
+	assert.Equal(t, "test2", bans[1].Credential)
+	assert.NotEmpty(t, bans[1].Remaining)
+
+	for _, body := range []string{`not json`, `{"model":"gpt-4o"}`, `{"credential":"test1","duration":"soon"}`} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/fail2ban/bans", body).Code, body)
+	}
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/fail2ban/bans", `{"credential":"unknown"}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/fail2ban/bans/test1/gpt-4o", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/fail2ban/bans/test1/gpt-4o", "").Code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/fail2ban/bans/test2", "").Code)
+	assert.Empty(t, listBans())
+
+	f2b.RecordResponse("test1", "org/model", http.StatusInternalServerError)
+	require.Equal(t, 1, f2b.GetFailureCount("test1", "org/model"))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/fail2ban/failures/test1/org/model", "").Code)
+	assert.Zero(t, f2b.GetFailureCount("test1", "org/model"), "model names may contain slashes")
+}
+
+func TestAdminHandler_LogSampling(t *testing.T) {
+	sampler := sampling.New(config.RequestSamplingConfig{})
+	p := createTestProxyWith(func(cfg *proxy.Config) { cfg.RequestSampler = sampler })
+	handler := NewAdminHandler(p, testhelpers.NewTestLogger())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPut, "/admin/log-sampling", `{"percent":2.5}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var settings sampling.Settings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, 2.5, settings.Percent)
+	assert.Equal(t, config.DefaultRequestSamplingMaxBodyBytes, settings.MaxBodyBytes, "omitted fields are unchanged")
+
+	for _, body := range []string{`not json`, `{"percent":150}`, `{"max_body_bytes":0}`} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/log-sampling", body).Code, body)
+	}
+
+	w = do(http.MethodPost, "/admin/log-sampling/keys", `{"key":"sk-customer","duration":"30m","reason":"ticket-42"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var key sampling.Key
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &key))
+	assert.NotContains(t, key.Key, "sk-customer", "keys are stored hashed")
+	require.NotNil(t, key.ExpiresAt)
+	_, sampled := sampler.Sample("sk-customer")
+	assert.True(t, sampled)
+
+	for _, body := range []string{`{"key":""}`, `{"key":"sk-x","duration":"soon"}`} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/log-sampling/keys", body).Code, body)
+	}
+
+	w = do(http.MethodGet, "/admin/log-sampling", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	require.Len(t, settings.Keys, 1)
+	assert.Equal(t, "ticket-42", settings.Keys[0].Reason)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/log-sampling/keys/"+key.Key, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/log-sampling/keys/"+key.Key, "").Code)
+}
+
+func TestAdminHandler_Handoff(t *testing.T) {
+	var oldLimiter *ratelimit.RPMLimiter
+	old := createTestProxyWith(func(cfg *proxy.Config) { oldLimiter = cfg.RateLimiter })
+	for i := 0; i < 5; i++ {
+		require.True(t, oldLimiter.Allow("test1"))
+	}
+	old.Fail2Ban().Ban("test2", "gpt-4o", 0, "provider outage")
+
+	do := func(p *proxy.Proxy, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/handoff", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		NewAdminHandler(p, testhelpers.NewTestLogger()).ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(old, http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	exported := w.Body.String()
+
+	var newLimiter *ratelimit.RPMLimiter
+	next := createTestProxyWith(func(cfg *proxy.Config) { newLimiter = cfg.RateLimiter })
+	w = do(next, http.MethodPost, exported)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result proxy.HandoffImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, proxy.HandoffImportResult{Limiters: 1, Bans: 1}, result)
+	assert.Equal(t, 5, newLimiter.GetCurrentRPM("test1"))
+	assert.True(t, next.Fail2Ban().IsBanned("test2", "gpt-4o"))
+
+	// Batch records of configured credentials are taken over
+	result = next.ImportHandoffState(proxy.HandoffState{Batches: proxy.BatchState{Batches: map[string]proxy.BatchRecord{
+		"msgbatch_1":       {Credential: "test1", Owner: "owner-hash", CreatedAt: time.Now()},
+		"msgbatch_removed": {Credential: "removed", CreatedAt: time.Now()},
+	}}})
+	assert.Equal(t, 1, result.Batches)
+	assert.Equal(t, "owner-hash", next.ExportHandoffState().Batches.Batches["msgbatch_1"].Owner)
+	assert.NotContains(t, next.ExportHandoffState().Batches.Batches, "msgbatch_removed")
+
+	assert.Equal(t, http.StatusBadRequest, do(next, http.MethodPost, "not json").Code)
+}
+
+func TestAdminHandler_InFlightRequests(t *testing.T) {
+	handler := NewAdminHandler(createTestProxy(), testhelpers.NewTestLogger())
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/admin/requests")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"requests":[]}`, w.Body.String())
+
+	w = do(http.MethodDelete, "/admin/requests/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Request not in flight: unknown")
+}
+
+func TestAdminHandler_ReadOnly(t *testing.T) {
+	boosts := quota.NewStore(48 * time.Hour)
+	p := createTestProxyWith(func(cfg *proxy.Config) {
+		cfg.QuotaBoosts = boosts
+		cfg.ReadOnly = true
+	})
+	handler := NewAdminHandler(p, testhelpers.NewTestLogger())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-master-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/admin/read-only", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"read_only":true}`, w.Body.String())
+
+	// Reads are served, mutations are refused
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/boosts", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/master-keys", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/debug/state", "").Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/admin/boosts", `{"key":"campaign","rpm_percent":50,"duration":"1h"}`).Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/admin/master-keys", `{"key":"sk-rotated-master-key"}`).Code)
+	assert.Empty(t, boosts.List())
+	assert.Equal(t, "test-master-key", p.GetMasterKey())
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/read-only", `not json`).Code)
+	w = do(http.MethodPut, "/admin/read-only", `{"read_only":false}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"read_only":false}`, w.Body.String())
+	assert.False(t, p.ReadOnly())
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/admin/boosts", `{"key":"campaign","rpm_percent":50,"duration":"1h"}`).Code)
+}
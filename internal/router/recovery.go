@@ -0,0 +1,95 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+)
+
+// recoveryWriter tracks whether a response has started, so a recovered panic
+// only writes the 500 error envelope when headers have not been sent yet
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func newRecoveryWriter(w http.ResponseWriter) *recoveryWriter {
+	return &recoveryWriter{ResponseWriter: w}
+}
+
+func (rw *recoveryWriter) WriteHeader(statusCode int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *recoveryWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(p)
+}
+
+func (rw *recoveryWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// recoverPanic converts a panic into a 500 OpenAI-style error response.
+// Must be called via defer. http.ErrAbortHandler is re-panicked so net/http
+// aborts the connection as intended.
+func (r *Router) recoverPanic(rw *recoveryWriter, req *http.Request) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	if rec == http.ErrAbortHandler {
+		panic(rec)
+	}
+
+	// Panics from ProxyRequest carry request context; others are captured here
+	var requestID, credential, model string
+	value, stack := rec, []byte(nil)
+	if pe, ok := rec.(*proxy.PanicError); ok {
+		value, stack = pe.Value, pe.Stack
+		requestID, credential, model = pe.RequestID, pe.Credential, pe.Model
+	} else {
+		stack = debug.Stack()
+	}
+
+	monitoring.PanicsTotal.WithLabelValues(panicEndpointLabel(req.URL.Path)).Inc()
+
+	if r.logger != nil {
+		r.logger.Error("Panic recovered",
+			"error", fmt.Sprint(value),
+			"method", req.Method,
+			"path", req.URL.Path,
+			"request_id", requestID,
+			"credential", credential,
+			"model", model,
+			"stack", string(stack),
+		)
+	}
+
+	if !rw.wroteHeader {
+		proxy.WriteErrorInternal(rw, "Internal Server Error")
+	}
+}
+
+// panicEndpointLabel bounds metric cardinality to known endpoints
+func panicEndpointLabel(path string) string {
+	if proxiedPaths[path] || path == "/v1/models" {
+		return path
+	}
+	if proxy.IsAnthropicBatchPath(path) {
+		return proxy.AnthropicBatchesPath
+	}
+	return "other"
+}
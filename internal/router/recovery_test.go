@@ -0,0 +1,109 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servePanicking runs fn behind the router's recovery middleware
+func servePanicking(r *Router, w http.ResponseWriter, req *http.Request, fn func(w http.ResponseWriter)) {
+	rw := newRecoveryWriter(w)
+	defer r.recoverPanic(rw, req)
+	fn(rw)
+}
+
+func TestRecoverPanic_WritesOpenAIError(t *testing.T) {
+	r := New(createTestProxy(), nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+	before := testutil.ToFloat64(monitoring.PanicsTotal.WithLabelValues("/v1/chat/completions"))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	w := httptest.NewRecorder()
+	servePanicking(r, w, req, func(http.ResponseWriter) {
+		panic(&proxy.PanicError{Value: "boom", RequestID: "req-1", Credential: "cred", Model: "gpt-4o"})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var resp proxy.APIErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Internal Server Error", resp.Error.Message)
+	assert.Equal(t, before+1, testutil.ToFloat64(monitoring.PanicsTotal.WithLabelValues("/v1/chat/completions")))
+}
+
+func TestRecoverPanic_AfterHeadersSent(t *testing.T) {
+	r := New(createTestProxy(), nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/unknown/path", nil)
+	w := httptest.NewRecorder()
+	servePanicking(r, w, req, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("data: partial\n\n"))
+		panic("mid-stream")
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "data: partial\n\n", w.Body.String(), "no error body appended after the response started")
+	assert.Equal(t, "other", panicEndpointLabel("/unknown/path"))
+}
+
+func TestRecoverPanic_ErrAbortHandlerPropagates(t *testing.T) {
+	r := New(createTestProxy(), nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		servePanicking(r, httptest.NewRecorder(), req, func(http.ResponseWriter) {
+			panic(http.ErrAbortHandler)
+		})
+	})
+}
+
+func TestServeHTTP_StreamOutlivesWriteTimeout(t *testing.T) {
+	t.Run("plain", func(t *testing.T) { testStreamOutlivesWriteTimeout(t, false) })
+	t.Run("error logging", func(t *testing.T) { testStreamOutlivesWriteTimeout(t, true) })
+}
+
+// testStreamOutlivesWriteTimeout streams through the router for longer than the server
+// write timeout, optionally behind the error logging response capture
+func testStreamOutlivesWriteTimeout(t *testing.T, logErrors bool) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 4; i++ {
+			_, _ = w.Write([]byte(`data: {"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"x"}}]}` + "\n\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	router := New(createProxyWithMockServer(upstream.URL), nil, createTestMonitoringConfig("/health", logErrors, ""), testhelpers.NewTestLogger())
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 150 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-key")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "the per-chunk write deadline extends the server write timeout")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 4, strings.Count(string(body), "chat.completion.chunk"))
+	assert.Contains(t, string(body), "data: [DONE]")
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/auth"
+	"github.com/mixaill76/auto_ai_router/internal/security"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// sensitiveBodyFields are JSON object keys whose values are replaced in sampled bodies
+var sensitiveBodyFields = map[string]bool{
+	"api_key":       true,
+	"apikey":        true,
+	"password":      true,
+	"secret":        true,
+	"client_secret": true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"private_key":   true,
+}
+
+// SampledLogEntry is a request logged in full by monitoring.request_sampling
+type SampledLogEntry struct {
+	Timestamp  string       `json:"timestamp"`
+	Reason     string       `json:"reason"` // "key" or "percent"
+	Key        string       `json:"key"`    // Hashed API key
+	Path       string       `json:"path"`
+	Method     string       `json:"method"`
+	Status     int          `json:"status"`
+	DurationMs int64        `json:"duration_ms"`
+	Request    RequestInfo  `json:"request"`
+	Response   ResponseInfo `json:"response"`
+}
+
+// logSampledRequest writes a sampled request with masked headers and redacted, size-limited
+// bodies to monitoring.request_sampling.log_path, or to the application log if it is unset
+func (r *Router) logSampledRequest(req *http.Request, rc *responseCapture, requestBody []byte, reason string, maxBodyBytes int, start time.Time) {
+	// Keys that LiteLLM does not hash (no "sk-" prefix) are masked instead
+	key := auth.HashToken(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer sk-") {
+		key = security.MaskToken(key)
+	}
+
+	entry := SampledLogEntry{
+		Timestamp:  utils.NowUTC().Format(time.RFC3339),
+		Reason:     reason,
+		Key:        key,
+		Path:       req.URL.Path,
+		Method:     req.Method,
+		Status:     rc.statusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+		Request: RequestInfo{
+			Headers: firstHeaderValues(security.MaskSensitiveHeaders(req.Header)),
+			Body:    redactBody(requestBody, maxBodyBytes, 0),
+		},
+		Response: ResponseInfo{
+			Headers: firstHeaderValues(security.MaskSensitiveHeaders(rc.Header())),
+			Body:    redactBody(rc.body.Bytes(), maxBodyBytes, rc.dropped),
+		},
+	}
+
+	logPath := r.monitoringConfig.RequestSampling.LogPath
+	if logPath == "" {
+		if r.logger != nil {
+			r.logger.Info("Sampled request", "entry", entry)
+		}
+		return
+	}
+
+	if err := appendLogEntry(logPath, entry); err != nil && r.logger != nil {
+		r.logger.Warn("Failed to write sampled request", "path", logPath, "error", err)
+	}
+}
+
+// firstHeaderValues flattens headers to their first values
+func firstHeaderValues(headers http.Header) map[string]string {
+	flat := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if len(values) > 0 {
+			flat[key] = values[0]
+		}
+	}
+	return flat
+}
+
+// redactBody returns body with the values of sensitive JSON fields replaced by "***",
+// cut to maxBytes. dropped is the number of bytes already cut off before capture.
+// Non-JSON bodies (e.g. SSE streams) are only cut.
+func redactBody(body []byte, maxBytes, dropped int) string {
+	var payload interface{}
+	if json.Unmarshal(body, &payload) == nil {
+		if redacted, err := json.Marshal(redactJSON(payload)); err == nil {
+			body = redacted
+		}
+	}
+	if maxBytes > 0 && len(body) > maxBytes {
+		dropped += len(body) - maxBytes
+		body = body[:maxBytes]
+	}
+	if dropped > 0 {
+		return string(body) + fmt.Sprintf("...[truncated %d bytes]", dropped)
+	}
+	return string(body)
+}
+
+// redactJSON replaces the values of sensitiveBodyFields in decoded JSON
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveBodyFields[strings.ToLower(key)] {
+				v[key] = "***"
+				continue
+			}
+			v[key] = redactJSON(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/balancer"
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/auth"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
+	"github.com/mixaill76/auto_ai_router/internal/sampling"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeHTTP_RequestSampling(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"` + strings.Repeat("a", 200) + `"},"finish_reason":"stop"}]}`))
+	}))
+	defer mockServer.Close()
+
+	sampler := sampling.New(config.RequestSamplingConfig{Keys: []string{"sk-test-master-key"}, MaxBodyBytes: 100})
+	prx := createTestProxyWith(func(cfg *proxy.Config) {
+		rl := ratelimit.New()
+		rl.AddCredential("test1", 100)
+		cfg.RateLimiter = rl
+		cfg.Balancer = balancer.New([]config.CredentialConfig{
+			{Name: "test1", APIKey: "key1", BaseURL: mockServer.URL, RPM: 100},
+		}, fail2ban.New(3, 0, []int{500}), rl)
+		cfg.MasterKey = "sk-test-master-key"
+		cfg.RequestSampler = sampler
+	})
+	logFile := filepath.Join(t.TempDir(), "sampled.jsonl")
+	monitoringCfg := createTestMonitoringConfig("/health", false, "")
+	monitoringCfg.RequestSampling = config.RequestSamplingConfig{LogPath: logFile}
+	router := New(prx, nil, monitoringCfg, testhelpers.NewTestLogger())
+
+	send := func(token string) {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"api_key":"sk-leaked"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), strings.Repeat("a", 200), "the client receives the full response")
+	}
+
+	send("sk-test-master-key")
+	require.True(t, sampler.RemoveKey("sk-test-master-key"))
+	send("sk-test-master-key")
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 1, "requests of keys no longer sampled are not logged")
+
+	var entry SampledLogEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, sampling.ReasonKey, entry.Reason)
+	assert.Equal(t, auth.HashToken("sk-test-master-key"), entry.Key)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.Equal(t, "Bearer sk-t...", entry.Request.Headers["Authorization"])
+	assert.Contains(t, entry.Request.Body, `"api_key":"***"`)
+	assert.NotContains(t, entry.Request.Body, "sk-leaked")
+	assert.True(t, strings.HasPrefix(entry.Response.Body, `{"id":"chatcmpl-1"`))
+	assert.Contains(t, entry.Response.Body, "...[truncated ")
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, `{"messages":[{"Password":"***","content":"hi"}],"model":"gpt-4o"}`,
+		redactBody([]byte(`{"model":"gpt-4o","messages":[{"content":"hi","Password":"hunter2"}]}`), 0, 0))
+	assert.Equal(t, "data: {...[truncated 10 bytes]", redactBody([]byte("data: {"), 100, 10))
+	assert.Equal(t, "abc...[truncated 3 bytes]", redactBody([]byte("abcdef"), 3, 0))
+}
@@ -1,13 +1,15 @@
 package router
 
 import (
-	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
 
 type Router struct {
@@ -26,7 +28,24 @@ func New(p *proxy.Proxy, modelManager *models.Manager, monitoringConfig *config.
 	}
 }
 
+// proxiedPaths are the OpenAI-compatible endpoints forwarded to providers
+var proxiedPaths = map[string]bool{
+	"/v1/chat/completions":   true,
+	"/v1/completions":        true,
+	"/v1/embeddings":         true,
+	"/v1/images/generations": true,
+	"/v1/images/edits":       true,
+	"/v1/images/variations":  true,
+	"/v1/responses":          true,
+}
+
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rw := newRecoveryWriter(w)
+	defer r.recoverPanic(rw, req)
+	r.route(rw, req)
+}
+
+func (r *Router) route(w http.ResponseWriter, req *http.Request) {
 	if req.URL.Path == r.monitoringConfig.HealthCheckPath {
 		r.handleHealth(w, req)
 		return
@@ -38,11 +57,37 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if req.URL.Path == "/health/readiness" {
+	// /readyz and /livez are the Kubernetes probe paths (generate k8s)
+	if req.URL.Path == "/health/readiness" || req.URL.Path == "/readyz" {
 		r.handleReadiness(w, req)
 		return
 	}
 
+	if req.URL.Path == "/livez" {
+		r.handleLiveness(w, req)
+		return
+	}
+
+	if req.URL.Path == "/health/history" {
+		r.handleHealthHistory(w, req)
+		return
+	}
+
+	if req.URL.Path == httputil.FederationPath {
+		r.handleFederation(w, req)
+		return
+	}
+
+	if req.URL.Path == "/rate-limits" {
+		r.handleRateLimits(w, req)
+		return
+	}
+
+	if req.URL.Path == "/spend" {
+		r.handleSpend(w, req)
+		return
+	}
+
 	if r.handleLitellm(w, req) {
 		return
 	}
@@ -53,19 +98,33 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	allowedPaths := map[string]bool{
-		"/v1/chat/completions":   true,
-		"/v1/completions":        true,
-		"/v1/embeddings":         true,
-		"/v1/images/generations": true,
-		"/v1/responses":          true,
+	// Handle GET /v1/models/{model}/capabilities (model IDs may contain "/")
+	if modelID, ok := capabilitiesModelID(req); ok {
+		r.proxy.ServeModelCapabilities(w, req, modelID)
+		return
+	}
+
+	// Handle GET /v1/models/{model}
+	if modelID, ok := retrieveModelID(req); ok {
+		r.handleModel(w, req, modelID)
+		return
 	}
-	if !allowedPaths[req.URL.Path] {
+
+	// Anthropic Message Batches API (native passthrough with credential affinity)
+	if proxy.IsAnthropicBatchPath(req.URL.Path) {
+		r.proxy.ProxyAnthropicBatches(w, req)
+		return
+	}
+
+	if !proxiedPaths[req.URL.Path] {
 		proxy.WriteErrorNotFound(w, "Not Found")
 		return
 	}
 
-	if r.monitoringConfig.LogErrors {
+	sampler := r.proxy.RequestSampler()
+	sampleReason, sampled := sampler.Sample(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+
+	if r.monitoringConfig.LogErrors || sampled {
 		// Capture request body for logging (detects streaming requests)
 		reqBody, isStreaming, err := captureRequestBody(req)
 		if err != nil {
@@ -73,43 +132,73 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// Create response capture wrapper
+		// Create response capture wrapper; sampled responses (including streams) are
+		// captured up to the sampling body limit only
 		rc := newResponseCapture(w)
+		if sampled && !r.monitoringConfig.LogErrors {
+			rc.maxBody = sampler.MaxBodyBytes()
+		}
 
 		// Proxy the request through captured response
+		start := utils.NowUTC()
 		r.proxy.ProxyRequest(rc, req)
 
 		// Log error responses if enabled and status is error (4xx or 5xx).
 		// Skip logging for streaming requests to avoid memory overhead with large responses.
-		if r.monitoringConfig.ErrorsLogPath != "" && isErrorStatus(rc.statusCode) && !isStreaming {
+		if r.monitoringConfig.LogErrors && r.monitoringConfig.ErrorsLogPath != "" && isErrorStatus(rc.statusCode) && !isStreaming {
 			_ = logErrorResponse(r.monitoringConfig.ErrorsLogPath, req, rc, reqBody)
 			// Log error internally but don't fail the response
 			// (error logging shouldn't break the API response)
 		}
+		if sampled {
+			r.logSampledRequest(req, rc, reqBody, sampleReason, sampler.MaxBodyBytes(), start)
+		}
 	} else {
 		r.proxy.ProxyRequest(w, req)
 	}
 }
 
-func (r *Router) handleModels(w http.ResponseWriter, req *http.Request) {
-	var modelsResp models.ModelsResponse
-	if r.modelManager != nil {
-		modelsResp = r.modelManager.GetAllModels()
-	} else {
-		modelsResp = models.ModelsResponse{Object: "list", Data: []models.Model{}}
+// capabilitiesModelID extracts the model of a GET /v1/models/{model}/capabilities request
+func capabilitiesModelID(req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet {
+		return "", false
 	}
+	rest, ok := strings.CutPrefix(req.URL.Path, "/v1/models/")
+	if !ok {
+		return "", false
+	}
+	modelID, ok := strings.CutSuffix(rest, proxy.ModelCapabilitiesPathSuffix)
+	if !ok || modelID == "" {
+		return "", false
+	}
+	return modelID, true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+// retrieveModelID extracts the model of a GET /v1/models/{model} request (model IDs may
+// contain "/")
+func retrieveModelID(req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet {
+		return "", false
+	}
+	modelID, ok := strings.CutPrefix(req.URL.Path, "/v1/models/")
+	if !ok || modelID == "" {
+		return "", false
+	}
+	return modelID, true
+}
 
-	if err := json.NewEncoder(w).Encode(modelsResp); err != nil {
-		if r.logger != nil {
-			r.logger.Error("Failed to encode models response",
-				"endpoint", "/v1/models",
-				"error", err.Error(),
-			)
-		}
-		// Headers already sent, cannot send http.Error
-		return
+func (r *Router) handleModels(w http.ResponseWriter, req *http.Request) {
+	r.proxy.ServeModels(w, req, r.allModels())
+}
+
+func (r *Router) handleModel(w http.ResponseWriter, req *http.Request, modelID string) {
+	r.proxy.ServeModel(w, req, r.allModels(), modelID)
+}
+
+// allModels returns the models of all credentials (none without a model manager)
+func (r *Router) allModels() models.ModelsResponse {
+	if r.modelManager != nil {
+		return r.modelManager.GetAllModels()
 	}
+	return models.ModelsResponse{Object: "list", Data: []models.Model{}}
 }
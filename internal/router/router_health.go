@@ -3,8 +3,12 @@ package router
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/mixaill76/auto_ai_router/internal/healthhistory"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
 	"github.com/mixaill76/auto_ai_router/internal/proxy"
 	"github.com/mixaill76/auto_ai_router/internal/utils"
 )
@@ -31,6 +35,105 @@ func (r *Router) handleHealth(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// handleFederation reports aggregated per-model capacity for parent routers
+// that use this router as a proxy credential. Requires an inter-router signature or a master key.
+func (r *Router) handleFederation(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		proxy.WriteJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed", "invalid_request_error", nil, nil)
+		return
+	}
+	if !r.proxy.AuthorizeRouterRequest(req) {
+		proxy.WriteErrorUnauthorized(w, "Invalid master key or inter-router signature")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(r.proxy.FederationLimits()); err != nil {
+		if r.logger != nil {
+			r.logger.Error("Failed to encode federation response",
+				"endpoint", httputil.FederationPath,
+				"error", err.Error(),
+			)
+		}
+		return
+	}
+}
+
+// handleRateLimits serves GET /rate-limits: the remaining RPM/TPM and reset times of every
+// credential and credential:model pair. Requires a master key. The optional credential and
+// model query parameters narrow the snapshot to one credential or model.
+func (r *Router) handleRateLimits(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		proxy.WriteJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed", "invalid_request_error", nil, nil)
+		return
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if _, ok := r.proxy.MatchMasterKey(token, req); !ok {
+		proxy.WriteErrorUnauthorized(w, "Invalid master key")
+		return
+	}
+
+	snapshot := r.proxy.RateLimitHeadroom()
+	credential, model := req.URL.Query().Get("credential"), req.URL.Query().Get("model")
+	if credential != "" || model != "" {
+		for name := range snapshot.Credentials {
+			if credential != "" && name != credential {
+				delete(snapshot.Credentials, name)
+			}
+		}
+		for key := range snapshot.Models {
+			keyCredential, keyModel, _ := strings.Cut(key, ":")
+			if (credential != "" && keyCredential != credential) || (model != "" && keyModel != model) {
+				delete(snapshot.Models, key)
+			}
+		}
+		for name, pool := range snapshot.Pools {
+			if credential != "" && !slices.Contains(pool.Credentials, credential) {
+				delete(snapshot.Pools, name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		if r.logger != nil {
+			r.logger.Error("Failed to encode rate limits response",
+				"endpoint", "/rate-limits",
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// handleHealthHistory reports the per-credential RPM, TPM, error rate and spend histories
+// over the window query parameter (default 1h, at most 24h)
+func (r *Router) handleHealthHistory(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		proxy.WriteJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed", "invalid_request_error", nil, nil)
+		return
+	}
+	window, err := healthhistory.ParseWindow(req.URL.Query().Get("window"))
+	if err != nil {
+		proxy.WriteJSONError(w, http.StatusBadRequest, err.Error(), "invalid_request_error", nil, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(r.proxy.HealthHistory(window)); err != nil {
+		if r.logger != nil {
+			r.logger.Error("Failed to encode health history response",
+				"endpoint", "/health/history",
+				"error", err.Error(),
+			)
+		}
+		return
+	}
+}
+
 type Readiness struct {
 	Status              string   `json:"status"`
 	DB                  string   `json:"db"`
@@ -69,6 +172,14 @@ func (r *Router) handleReadiness(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// handleLiveness answers as long as the process serves HTTP; it checks nothing else, so a
+// failing upstream or database never restarts the pod
+func (r *Router) handleLiveness(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
 func (r *Router) handleVisualHealth(w http.ResponseWriter, req *http.Request) {
 	r.proxy.VisualHealthCheck(w, req)
 }
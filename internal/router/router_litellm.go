@@ -49,7 +49,12 @@ func (r *Router) handleLogin(w http.ResponseWriter, req *http.Request) {
 	// Get DB pool (may be nil if LiteLLM DB is disabled)
 	var pool = r.proxy.LiteLLMDB.GetPool()
 
-	result, err := users.AuthenticateUser(req.Context(), loginReq, masterKey, pool)
+	// Keys being rotated out log in too, until their grace period ends
+	isMasterKey := func(password string) bool {
+		_, ok := r.proxy.MatchMasterKey(password, req)
+		return ok
+	}
+	result, err := users.AuthenticateUser(req.Context(), loginReq, masterKey, isMasterKey, pool)
 	if err != nil {
 		if err == users.ErrInvalidCredentials {
 			r.logger.Warn("Login failed: invalid credentials", "username", loginReq.Username)
@@ -69,6 +69,8 @@ type responseCapture struct {
 	http.ResponseWriter
 	statusCode int
 	body       *bytes.Buffer
+	maxBody    int // Captured body size limit (0 = no limit); the client still receives everything
+	dropped    int // Body bytes not captured because of maxBody
 }
 
 func newResponseCapture(w http.ResponseWriter) *responseCapture {
@@ -85,7 +87,14 @@ func (rc *responseCapture) WriteHeader(statusCode int) {
 }
 
 func (rc *responseCapture) Write(p []byte) (int, error) {
-	rc.body.Write(p)
+	switch {
+	case rc.maxBody <= 0:
+		rc.body.Write(p)
+	default:
+		n := min(len(p), max(rc.maxBody-rc.body.Len(), 0))
+		rc.body.Write(p[:n])
+		rc.dropped += len(p) - n
+	}
 	return rc.ResponseWriter.Write(p)
 }
 
@@ -95,6 +104,11 @@ func (rc *responseCapture) Flush() {
 	}
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rc *responseCapture) Unwrap() http.ResponseWriter {
+	return rc.ResponseWriter
+}
+
 // ErrorLogEntry represents a single error log entry
 type ErrorLogEntry struct {
 	Timestamp string       `json:"timestamp"`
@@ -161,14 +175,17 @@ func logErrorResponse(errorsLogPath string, req *http.Request, rc *responseCaptu
 		},
 	}
 
-	// Marshal to JSON
+	return appendLogEntry(errorsLogPath, entry)
+}
+
+// appendLogEntry writes entry as a JSON line to the cached file handle of path
+func appendLogEntry(path string, entry interface{}) error {
 	entryJSON, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
 
-	// Get or create cached file handle
-	file, err := logFileCache.getOrCreate(errorsLogPath)
+	file, err := logFileCache.getOrCreate(path)
 	if err != nil {
 		return err
 	}
@@ -3,9 +3,11 @@ package router
 import (
 	"encoding/json"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -14,16 +16,24 @@ import (
 	"github.com/mixaill76/auto_ai_router/internal/balancer"
 	"github.com/mixaill76/auto_ai_router/internal/config"
 	"github.com/mixaill76/auto_ai_router/internal/fail2ban"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
 	"github.com/mixaill76/auto_ai_router/internal/models"
 	"github.com/mixaill76/auto_ai_router/internal/monitoring"
 	"github.com/mixaill76/auto_ai_router/internal/proxy"
 	"github.com/mixaill76/auto_ai_router/internal/ratelimit"
 	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // createTestProxy creates a test proxy instance
 func createTestProxy() *proxy.Proxy {
+	return createTestProxyWith(func(*proxy.Config) {})
+}
+
+// createTestProxyWith creates a test proxy after applying configure to its config
+func createTestProxyWith(configure func(*proxy.Config)) *proxy.Proxy {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	f2b := fail2ban.New(3, 0, []int{401, 403, 500})
 	rl := ratelimit.New()
@@ -41,7 +51,7 @@ func createTestProxy() *proxy.Proxy {
 	metrics := monitoring.New(false)
 	tokenManager := auth.NewVertexTokenManager(logger)
 
-	return proxy.New(&proxy.Config{
+	cfg := &proxy.Config{
 		Balancer:            bal,
 		Logger:              logger,
 		MaxBodySizeMB:       10,
@@ -56,7 +66,9 @@ func createTestProxy() *proxy.Proxy {
 		ModelManager:        createTestModelManager(),
 		Version:             "test-version",
 		Commit:              "test-commit",
-	})
+	}
+	configure(cfg)
+	return proxy.New(cfg)
 }
 
 // createTestModelManager creates a test model manager instance (disabled - no static models)
@@ -218,6 +230,7 @@ func TestServeHTTP_V1Models_Enabled(t *testing.T) {
 	router := New(prx, modelManager, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
 
 	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
@@ -275,6 +288,88 @@ func TestServeHTTP_V1Models_NilManager(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestServeHTTP_ModelCapabilities(t *testing.T) {
+	prx := createTestProxy()
+	router := New(prx, nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest("GET", "/v1/models/gpt-4o/capabilities", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var caps proxy.ModelCapabilities
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+	assert.Equal(t, "gpt-4o", caps.ID)
+	assert.Equal(t, "model.capabilities", caps.Object)
+	assert.Len(t, caps.Credentials, 2)
+	assert.Equal(t, "test1", caps.Credentials[0].Name)
+}
+
+func TestCapabilitiesModelID(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{"GET", "/v1/models/gpt-4o/capabilities", "gpt-4o", true},
+		{"GET", "/v1/models/vertex_ai/gemini-2.5-pro/capabilities", "vertex_ai/gemini-2.5-pro", true},
+		{"GET", "/v1/models//capabilities", "", false},
+		{"GET", "/v1/models/gpt-4o", "", false},
+		{"POST", "/v1/models/gpt-4o/capabilities", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := capabilitiesModelID(httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+}
+
+func TestRetrieveModelID(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{"GET", "/v1/models/gpt-4o", "gpt-4o", true},
+		{"GET", "/v1/models/vertex_ai/gemini-2.5-pro", "vertex_ai/gemini-2.5-pro", true},
+		{"GET", "/v1/models/", "", false},
+		{"GET", "/v1/models", "", false},
+		{"DELETE", "/v1/models/gpt-4o", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := retrieveModelID(httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+}
+
+func TestServeHTTP_RetrieveModel(t *testing.T) {
+	prx := createTestProxy()
+	manager := models.New(testhelpers.NewTestLogger(), 50, []config.ModelRPMConfig{{Name: "gpt-4o"}})
+	router := New(prx, manager, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest("GET", "/v1/models/gpt-4o", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var object proxy.ModelObject
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &object))
+	assert.Equal(t, "gpt-4o", object.ID)
+	assert.Equal(t, "model", object.Object)
+
+	req = httptest.NewRequest("GET", "/v1/models/unknown-model", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestServeHTTP_ProxyRequest(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -294,6 +389,8 @@ func TestServeHTTP_ProxyRequest(t *testing.T) {
 		{"completions", "/v1/completions"},
 		{"embeddings", "/v1/embeddings"},
 		{"images", "/v1/images/generations"},
+		{"image edits", "/v1/images/edits"},
+		{"image variations", "/v1/images/variations"},
 	}
 
 	for _, tt := range tests {
@@ -397,6 +494,7 @@ func TestHandleModels(t *testing.T) {
 	router := New(prx, modelManager, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
 
 	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
 	w := httptest.NewRecorder()
 
 	router.handleModels(w, req)
@@ -411,6 +509,16 @@ func TestHandleModels(t *testing.T) {
 	// Models list might be empty if not fetched, which is OK
 }
 
+func TestHandleModels_Unauthenticated(t *testing.T) {
+	router := New(createTestProxy(), createEnabledTestModelManager(), createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
 func TestHandleVisualHealth(t *testing.T) {
 	prx := createTestProxy()
 	router := New(prx, nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
@@ -512,3 +620,132 @@ func TestServeHTTP_NonStreamingErrorIsLogged(t *testing.T) {
 	assert.NoError(t, err, "Log file should contain valid JSON")
 	assert.Equal(t, http.StatusBadRequest, entry.Status)
 }
+
+func TestServeHTTP_Federation(t *testing.T) {
+	const secret = "inter-router-secret-of-32-characters"
+	prx := createTestProxyWith(func(c *proxy.Config) {
+		c.InterRouterSecret = secret
+	})
+	router := New(prx, nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest("GET", "/federation/limits", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "limits are not public")
+
+	req = httptest.NewRequest("GET", "/federation/limits", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "healthy", response["status"])
+	assert.Contains(t, response, "models")
+
+	req = httptest.NewRequest("GET", "/federation/limits", nil)
+	require.NoError(t, httputil.SignRequest(req, secret, nil, time.Now()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "signed by a parent router")
+
+	req = httptest.NewRequest("GET", "/federation/limits", nil)
+	require.NoError(t, httputil.SignRequest(req, "another-secret-of-at-least-32-chars", nil, time.Now()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest("POST", "/federation/limits", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestServeHTTP_ProbePaths(t *testing.T) {
+	prx := createTestProxyWith(func(c *proxy.Config) {
+		c.LiteLLMDB = litellmdb.NewNoopManager()
+	})
+	router := New(prx, nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var readiness Readiness
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	assert.Equal(t, "healthy", readiness.Status)
+
+	req = httptest.NewRequest("GET", "/livez", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}
+
+func TestServeHTTP_HealthHistory(t *testing.T) {
+	prx := createTestProxy()
+	router := New(prx, nil, createTestMonitoringConfig("/health", false, ""), testhelpers.NewTestLogger())
+
+	req := httptest.NewRequest("GET", "/health/history?window=24h", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "24h0m0s", response["window"])
+	assert.Contains(t, response, "credentials")
+
+	req = httptest.NewRequest("GET", "/health/history?window=48h", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("POST", "/health/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestRouter_RateLimits(t *testing.T) {
+	prx := createTestProxyWith(func(c *proxy.Config) {
+		c.RateLimiter.AddModel("test1", "gpt-4o", 10)
+		c.RateLimiter.AddModel("test2", "gpt-4o-mini", 10)
+		c.RateLimiter.AddPool("org", ratelimit.PoolLimits{RPM: 50}, "test2")
+	})
+	r := New(prx, createTestModelManager(), &config.MonitoringConfig{HealthCheckPath: "/health"}, testhelpers.NewTestLogger())
+	get := func(target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("/rate-limits", "wrong-key").Code)
+
+	w := get("/rate-limits", "test-master-key")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var all ratelimit.HeadroomSnapshot
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	assert.Len(t, all.Credentials, 2)
+	assert.Equal(t, 100, all.Credentials["test1"].RemainingRPM)
+	assert.Equal(t, -1, all.Credentials["test1"].RemainingTPM)
+	assert.Equal(t, 10, all.Models["test1:gpt-4o"].RemainingRPM)
+	assert.Equal(t, 50, all.Pools["org"].RemainingRPM)
+	assert.Equal(t, 50, all.Credentials["test2"].LimitRPM, "the pool bounds its credentials")
+
+	w = get("/rate-limits?model=gpt-4o-mini", "test-master-key")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var filtered ratelimit.HeadroomSnapshot
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &filtered))
+	assert.Len(t, filtered.Credentials, 2, "the model filter keeps every credential")
+	assert.Equal(t, []string{"test2:gpt-4o-mini"}, slices.Sorted(maps.Keys(filtered.Models)))
+
+	w = get("/rate-limits?credential=test1", "test-master-key")
+	var byCredential ratelimit.HeadroomSnapshot
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &byCredential))
+	assert.Equal(t, []string{"test1"}, slices.Sorted(maps.Keys(byCredential.Credentials)))
+	assert.Equal(t, []string{"test1:gpt-4o"}, slices.Sorted(maps.Keys(byCredential.Models)))
+	assert.Empty(t, byCredential.Pools, "pools without the credential are left out")
+}
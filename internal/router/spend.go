@@ -0,0 +1,100 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/spendstore"
+)
+
+// handleSpend serves GET /spend: totals and newest entries of the local spend log.
+// Requires a master key. Query parameters (all optional):
+//
+//	start_date  YYYY-MM-DD or RFC 3339, inclusive
+//	end_date    YYYY-MM-DD (inclusive day) or RFC 3339 (exclusive)
+//	api_key     raw key (sk-...) or its hash
+//	team_id     LiteLLM team ID
+//	user_id     LiteLLM user ID
+//	model       model name or group
+//	limit       logs to return, newest first (default 100, max 1000)
+func (r *Router) handleSpend(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		proxy.WriteJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed", "invalid_request_error", nil, nil)
+		return
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if _, ok := r.proxy.MatchMasterKey(token, req); !ok {
+		proxy.WriteErrorUnauthorized(w, "Invalid master key")
+		return
+	}
+	store := r.proxy.SpendStore()
+	if store == nil {
+		proxy.WriteErrorNotFound(w, "Local spend log is disabled (local_spend_log.enabled)")
+		return
+	}
+
+	filter, err := parseSpendFilter(req)
+	if err != nil {
+		proxy.WriteErrorBadRequest(w, err.Error())
+		return
+	}
+	result, err := store.Query(req.Context(), filter)
+	if err != nil {
+		r.logger.Error("Failed to query local spend log", "error", err)
+		proxy.WriteErrorInternal(w, "Failed to query local spend log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		r.logger.Error("Failed to encode spend response", "error", err)
+	}
+}
+
+// parseSpendFilter reads the /spend query parameters
+func parseSpendFilter(req *http.Request) (spendstore.Filter, error) {
+	query := req.URL.Query()
+	filter := spendstore.Filter{
+		APIKey: query.Get("api_key"),
+		TeamID: query.Get("team_id"),
+		UserID: query.Get("user_id"),
+		Model:  query.Get("model"),
+	}
+	if strings.HasPrefix(filter.APIKey, "sk-") {
+		filter.APIKey = litellmdb.HashToken(filter.APIKey)
+	}
+
+	var err error
+	if filter.Start, err = parseSpendTime(query.Get("start_date"), false); err != nil {
+		return filter, fmt.Errorf("invalid start_date: %q", query.Get("start_date"))
+	}
+	if filter.End, err = parseSpendTime(query.Get("end_date"), true); err != nil {
+		return filter, fmt.Errorf("invalid end_date: %q", query.Get("end_date"))
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+			return filter, fmt.Errorf("invalid limit: %q", value)
+		}
+	}
+	return filter, nil
+}
+
+// parseSpendTime parses a YYYY-MM-DD date (UTC) or an RFC 3339 time; an end date covers its whole day
+func parseSpendTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		if end {
+			return day.AddDate(0, 0, 1), nil
+		}
+		return day, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
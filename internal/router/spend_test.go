@@ -0,0 +1,81 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb"
+	"github.com/mixaill76/auto_ai_router/internal/proxy"
+	"github.com/mixaill76/auto_ai_router/internal/spendstore"
+	"github.com/mixaill76/auto_ai_router/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Spend(t *testing.T) {
+	cfg := config.LocalSpendLogConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "spend.db")}
+	store, err := spendstore.Open(cfg, testhelpers.NewTestLogger())
+	require.NoError(t, err)
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, key := range []string{"sk-alpha", "sk-alpha", "sk-beta"} {
+		require.NoError(t, store.Log(&litellmdb.SpendLogEntry{
+			RequestID:   "r" + string(rune('1'+i)),
+			StartTime:   day.Add(time.Duration(i) * 24 * time.Hour),
+			EndTime:     day.Add(time.Duration(i) * 24 * time.Hour),
+			Model:       "gpt-4o",
+			TotalTokens: 10,
+			Spend:       0.5,
+			APIKey:      litellmdb.HashToken(key),
+		}))
+	}
+	require.NoError(t, store.Close()) // Writes the queued entries
+	store, err = spendstore.Open(cfg, testhelpers.NewTestLogger())
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	r := New(createTestProxyWith(func(c *proxy.Config) { c.SpendStore = store }), createTestModelManager(),
+		&config.MonitoringConfig{HealthCheckPath: "/health"}, testhelpers.NewTestLogger())
+	get := func(target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("/spend", "wrong-key").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/spend?start_date=yesterday", "test-master-key").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/spend?limit=0", "test-master-key").Code)
+
+	w := get("/spend", "test-master-key")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var all spendstore.Result
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	assert.Equal(t, 3, all.Requests)
+	assert.InDelta(t, 1.5, all.Spend, 1e-9)
+	assert.Len(t, all.Logs, 3)
+
+	// Raw keys are hashed; end_date covers its whole day
+	w = get("/spend?api_key=sk-alpha&start_date=2026-03-01&end_date=2026-03-01", "test-master-key")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var filtered spendstore.Result
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &filtered))
+	assert.Equal(t, 1, filtered.Requests)
+	require.Len(t, filtered.Logs, 1)
+	assert.Equal(t, "r1", filtered.Logs[0].RequestID)
+}
+
+func TestRouter_SpendDisabled(t *testing.T) {
+	r := New(createTestProxy(), createTestModelManager(), &config.MonitoringConfig{HealthCheckPath: "/health"},
+		testhelpers.NewTestLogger())
+	req := httptest.NewRequest(http.MethodGet, "/spend", nil)
+	req.Header.Set("Authorization", "Bearer test-master-key")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,208 @@
+// Package sampling decides which requests get their full (redacted) request and response
+// logged, so a single customer's issue can be investigated without enabling debug logging
+// for all traffic.
+package sampling
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/auth"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Sample reasons returned by Sampler.Sample
+const (
+	ReasonKey     = "key"
+	ReasonPercent = "percent"
+)
+
+// Key is an API key whose requests are always logged
+type Key struct {
+	Key       string     `json:"key"`                  // Hashed API key
+	Reason    string     `json:"reason,omitempty"`     // Why the key is sampled (e.g. a ticket)
+	CreatedAt time.Time  `json:"created_at"`           // When the key was added
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil = until removed
+}
+
+// Settings is a snapshot of the sampling configuration
+type Settings struct {
+	Percent      float64 `json:"percent"`        // Share of all requests logged, 0-100
+	MaxBodyBytes int     `json:"max_body_bytes"` // Logged body size limit per request and response
+	Keys         []Key   `json:"keys"`
+}
+
+// Sampler holds the runtime sampling settings; changes made via the admin API are lost
+// when the router restarts. A nil *Sampler is valid and samples nothing.
+type Sampler struct {
+	now   func() time.Time
+	float func() float64
+
+	mu           sync.RWMutex
+	percent      float64
+	maxBodyBytes int
+	keys         map[string]Key
+}
+
+// New creates a Sampler with the startup values of cfg (monitoring.request_sampling)
+func New(cfg config.RequestSamplingConfig) *Sampler {
+	s := &Sampler{
+		now:          utils.NowUTC,
+		float:        rand.Float64,
+		percent:      cfg.Percent,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		keys:         make(map[string]Key, len(cfg.Keys)),
+	}
+	if s.maxBodyBytes <= 0 {
+		s.maxBodyBytes = config.DefaultRequestSamplingMaxBodyBytes
+	}
+	for _, key := range cfg.Keys {
+		if key == "" {
+			continue
+		}
+		hashed := auth.HashToken(key)
+		s.keys[hashed] = Key{Key: hashed, Reason: "config", CreatedAt: s.now()}
+	}
+	return s
+}
+
+// Sample reports whether the request authenticated with token is logged and why
+// (ReasonKey or ReasonPercent)
+func (s *Sampler) Sample(token string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.keys) > 0 && token != "" {
+		if key, ok := s.keys[auth.HashToken(token)]; ok && (key.ExpiresAt == nil || s.now().Before(*key.ExpiresAt)) {
+			return ReasonKey, true
+		}
+	}
+	if s.percent > 0 && s.float()*100 < s.percent {
+		return ReasonPercent, true
+	}
+	return "", false
+}
+
+// Enabled reports whether any request can currently be sampled
+func (s *Sampler) Enabled() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.percent > 0 || len(s.keys) > 0
+}
+
+// MaxBodyBytes returns the logged body size limit
+func (s *Sampler) MaxBodyBytes() int {
+	if s == nil {
+		return config.DefaultRequestSamplingMaxBodyBytes
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxBodyBytes
+}
+
+// Settings returns the current settings; keys are sorted and expired keys left out
+func (s *Sampler) Settings() Settings {
+	if s == nil {
+		return Settings{MaxBodyBytes: config.DefaultRequestSamplingMaxBodyBytes, Keys: []Key{}}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+
+	settings := Settings{Percent: s.percent, MaxBodyBytes: s.maxBodyBytes, Keys: make([]Key, 0, len(s.keys))}
+	for _, key := range s.keys {
+		settings.Keys = append(settings.Keys, key)
+	}
+	sort.Slice(settings.Keys, func(i, j int) bool { return settings.Keys[i].Key < settings.Keys[j].Key })
+	return settings
+}
+
+// SetPercent changes the share of all requests logged
+func (s *Sampler) SetPercent(percent float64) error {
+	if s == nil {
+		return errors.New("request sampling is disabled")
+	}
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100, got %v", percent)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.percent = percent
+	return nil
+}
+
+// SetMaxBodyBytes changes the logged body size limit
+func (s *Sampler) SetMaxBodyBytes(maxBodyBytes int) error {
+	if s == nil {
+		return errors.New("request sampling is disabled")
+	}
+	if maxBodyBytes <= 0 {
+		return fmt.Errorf("max_body_bytes must be positive, got %d", maxBodyBytes)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxBodyBytes = maxBodyBytes
+	return nil
+}
+
+// AddKey samples all requests of an API key (raw sk-... key or its hash) for duration
+// (0 = until removed). Adding a key again replaces its reason and expiry.
+func (s *Sampler) AddKey(key, reason string, duration time.Duration) (Key, error) {
+	if s == nil {
+		return Key{}, errors.New("request sampling is disabled")
+	}
+	if key == "" {
+		return Key{}, errors.New("key is required")
+	}
+	if duration < 0 {
+		return Key{}, errors.New("duration must not be negative")
+	}
+
+	now := s.now()
+	entry := Key{Key: auth.HashToken(key), Reason: reason, CreatedAt: now}
+	if duration > 0 {
+		expiresAt := now.Add(duration)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[entry.Key] = entry
+	return entry, nil
+}
+
+// RemoveKey stops sampling an API key (raw sk-... key or its hash).
+// Returns false if the key was not sampled.
+func (s *Sampler) RemoveKey(key string) bool {
+	if s == nil {
+		return false
+	}
+	hashed := auth.HashToken(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[hashed]
+	delete(s.keys, hashed)
+	return ok
+}
+
+// pruneLocked removes expired keys; the caller must hold s.mu
+func (s *Sampler) pruneLocked() {
+	now := s.now()
+	for hashed, key := range s.keys {
+		if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			delete(s.keys, hashed)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler_Keys(t *testing.T) {
+	s := New(config.RequestSamplingConfig{Keys: []string{"sk-config"}})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	reason, ok := s.Sample("sk-config")
+	assert.True(t, ok)
+	assert.Equal(t, ReasonKey, reason)
+	_, ok = s.Sample("sk-other")
+	assert.False(t, ok)
+
+	// Keys are matched by hash, so the hash works as well as the raw key
+	_, err := s.AddKey(auth.HashToken("sk-customer"), "ticket-42", time.Hour)
+	require.NoError(t, err)
+	_, ok = s.Sample("sk-customer")
+	assert.True(t, ok)
+
+	settings := s.Settings()
+	require.Len(t, settings.Keys, 2)
+	assert.Equal(t, config.DefaultRequestSamplingMaxBodyBytes, settings.MaxBodyBytes)
+
+	now = now.Add(time.Hour)
+	_, ok = s.Sample("sk-customer")
+	assert.False(t, ok, "expired keys are not sampled")
+	assert.Len(t, s.Settings().Keys, 1)
+
+	assert.True(t, s.RemoveKey("sk-config"))
+	assert.False(t, s.RemoveKey("sk-config"))
+	assert.False(t, s.Enabled())
+
+	_, err = s.AddKey("", "", 0)
+	assert.Error(t, err)
+}
+
+func TestSampler_Percent(t *testing.T) {
+	s := New(config.RequestSamplingConfig{Percent: 10})
+	s.float = func() float64 { return 0.05 }
+	reason, ok := s.Sample("")
+	assert.True(t, ok)
+	assert.Equal(t, ReasonPercent, reason)
+
+	s.float = func() float64 { return 0.5 }
+	_, ok = s.Sample("")
+	assert.False(t, ok)
+
+	require.NoError(t, s.SetPercent(100))
+	_, ok = s.Sample("")
+	assert.True(t, ok)
+	assert.Error(t, s.SetPercent(101))
+	assert.Error(t, s.SetMaxBodyBytes(0))
+}
+
+func TestSampler_Nil(t *testing.T) {
+	var s *Sampler
+	_, ok := s.Sample("sk-test")
+	assert.False(t, ok)
+	assert.False(t, s.Enabled())
+	assert.Empty(t, s.Settings().Keys)
+	assert.Error(t, s.SetPercent(1))
+}
@@ -0,0 +1,236 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+)
+
+var (
+	ErrQueueFull    = errors.New("admission queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting for admission")
+)
+
+// Config configures a FairScheduler
+type Config struct {
+	MaxInFlight   int            // Requests processed concurrently across all keys
+	MaxQueue      int            // Requests waiting for admission across all keys
+	QueueTimeout  time.Duration  // Maximum wait for admission (0 = until the request context ends)
+	DefaultWeight int            // Share of keys without an explicit weight (default: 1)
+	Weights       map[string]int // Key name -> share of admissions per round
+}
+
+// FairScheduler bounds concurrent requests and admits queued requests with per-key
+// weighted round robin, so one key flooding the router cannot starve the others.
+//
+// While there is free capacity and nothing is queued, requests are admitted immediately.
+// Otherwise each key waits in its own FIFO queue; every round a key may admit up to
+// its weight before the next key with waiting requests gets its turn.
+//
+// Thread-safe via internal mutex.
+type FairScheduler struct {
+	mu            sync.Mutex
+	maxInFlight   int
+	maxQueue      int
+	queueTimeout  time.Duration
+	defaultWeight int
+	weights       map[string]int
+
+	inFlight int
+	queued   int
+	queues   map[string]*keyQueue
+	ring     []string // keys with waiting requests, in round-robin order
+	next     int      // ring index of the key served next
+}
+
+type keyQueue struct {
+	waiters []*waiter
+	weight  int
+	credit  int // admissions left for this key in the current round
+}
+
+type waiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// NewFairScheduler creates a scheduler. MaxInFlight and MaxQueue must be positive.
+func NewFairScheduler(cfg Config) *FairScheduler {
+	defaultWeight := cfg.DefaultWeight
+	if defaultWeight <= 0 {
+		defaultWeight = 1
+	}
+	return &FairScheduler{
+		maxInFlight:   cfg.MaxInFlight,
+		maxQueue:      cfg.MaxQueue,
+		queueTimeout:  cfg.QueueTimeout,
+		defaultWeight: defaultWeight,
+		weights:       cfg.Weights,
+		queues:        make(map[string]*keyQueue),
+	}
+}
+
+// WeightFor returns the configured weight of the first name found in Weights,
+// or the default weight
+func (s *FairScheduler) WeightFor(names ...string) int {
+	for _, name := range names {
+		if weight, ok := s.weights[name]; ok && name != "" && weight > 0 {
+			return weight
+		}
+	}
+	return s.defaultWeight
+}
+
+// Acquire waits until a request for key may proceed and returns a release func
+// that must be called once the request is done.
+// Returns ErrQueueFull, ErrQueueTimeout or the context error if not admitted.
+func (s *FairScheduler) Acquire(ctx context.Context, key string, weight int) (func(), error) {
+	s.mu.Lock()
+	if s.inFlight < s.maxInFlight && s.queued == 0 {
+		s.inFlight++
+		s.updateMetricsLocked()
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+	if s.queued >= s.maxQueue {
+		s.mu.Unlock()
+		monitoring.FairSchedulerRejected.WithLabelValues("queue_full").Inc()
+		return nil, ErrQueueFull
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	q, ok := s.queues[key]
+	if !ok {
+		q = &keyQueue{}
+		s.queues[key] = q
+		s.ring = append(s.ring, key)
+	}
+	q.weight = max(1, weight)
+	q.waiters = append(q.waiters, w)
+	s.queued++
+	s.updateMetricsLocked()
+	s.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if s.queueTimeout > 0 {
+		timer := time.NewTimer(s.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+
+	s.mu.Lock()
+	if w.admitted {
+		// Admitted while giving up: hand the slot to the next waiter
+		s.inFlight--
+		s.dispatchLocked()
+	} else {
+		s.removeWaiterLocked(key, w)
+	}
+	s.updateMetricsLocked()
+	s.mu.Unlock()
+
+	if errors.Is(err, ErrQueueTimeout) {
+		monitoring.FairSchedulerRejected.WithLabelValues("timeout").Inc()
+	}
+	return nil, err
+}
+
+// Stats returns the number of in-flight and queued requests
+func (s *FairScheduler) Stats() (inFlight, queued int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight, s.queued
+}
+
+func (s *FairScheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.inFlight--
+			s.dispatchLocked()
+			s.updateMetricsLocked()
+		})
+	}
+}
+
+// dispatchLocked admits queued requests while there is capacity. Caller must hold s.mu.
+func (s *FairScheduler) dispatchLocked() {
+	for s.inFlight < s.maxInFlight && s.queued > 0 {
+		key := s.ring[s.next]
+		q := s.queues[key]
+		if q.credit <= 0 {
+			q.credit = q.weight
+		}
+
+		w := q.waiters[0]
+		q.waiters = q.waiters[1:]
+		w.admitted = true
+		close(w.ready)
+		s.inFlight++
+		s.queued--
+		q.credit--
+
+		if len(q.waiters) == 0 {
+			s.removeKeyLocked(s.next)
+		} else if q.credit == 0 {
+			s.next = (s.next + 1) % len(s.ring)
+		}
+	}
+}
+
+// removeWaiterLocked drops a waiter that gave up. Caller must hold s.mu.
+func (s *FairScheduler) removeWaiterLocked(key string, w *waiter) {
+	q, ok := s.queues[key]
+	if !ok {
+		return
+	}
+	for i, candidate := range q.waiters {
+		if candidate == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			s.queued--
+			break
+		}
+	}
+	if len(q.waiters) > 0 {
+		return
+	}
+	for i, k := range s.ring {
+		if k == key {
+			s.removeKeyLocked(i)
+			return
+		}
+	}
+}
+
+// removeKeyLocked removes the key at ring index i. Caller must hold s.mu.
+func (s *FairScheduler) removeKeyLocked(i int) {
+	delete(s.queues, s.ring[i])
+	s.ring = append(s.ring[:i], s.ring[i+1:]...)
+	if s.next > i {
+		s.next--
+	}
+	if s.next >= len(s.ring) {
+		s.next = 0
+	}
+}
+
+// updateMetricsLocked publishes scheduler gauges. Caller must hold s.mu.
+func (s *FairScheduler) updateMetricsLocked() {
+	monitoring.FairSchedulerInFlight.Set(float64(s.inFlight))
+	monitoring.FairSchedulerQueued.Set(float64(s.queued))
+}
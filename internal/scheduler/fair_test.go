@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueRequest starts a goroutine waiting for admission and records the admission order
+func queueRequest(t *testing.T, s *FairScheduler, key string, weight int, order chan<- string, releases chan<- func()) {
+	t.Helper()
+	_, queuedBefore := s.Stats()
+	go func() {
+		release, err := s.Acquire(context.Background(), key, weight)
+		if err != nil {
+			return
+		}
+		order <- key
+		releases <- release
+	}()
+	require.Eventually(t, func() bool {
+		_, queued := s.Stats()
+		return queued == queuedBefore+1
+	}, time.Second, time.Millisecond)
+}
+
+func TestFairScheduler_AdmitsImmediatelyUnderCapacity(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 2, MaxQueue: 10})
+
+	release1, err := s.Acquire(context.Background(), "a", 1)
+	require.NoError(t, err)
+	release2, err := s.Acquire(context.Background(), "a", 1)
+	require.NoError(t, err)
+
+	inFlight, queued := s.Stats()
+	assert.Equal(t, 2, inFlight)
+	assert.Equal(t, 0, queued)
+
+	release1()
+	release1() // idempotent
+	release2()
+	inFlight, _ = s.Stats()
+	assert.Equal(t, 0, inFlight)
+}
+
+func TestFairScheduler_RoundRobinAcrossKeys(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 1, MaxQueue: 10})
+	hold, err := s.Acquire(context.Background(), "noisy", 1)
+	require.NoError(t, err)
+
+	order := make(chan string, 10)
+	releases := make(chan func(), 10)
+	// The noisy key queues first, the quiet key must not wait behind all of it
+	queueRequest(t, s, "noisy", 1, order, releases)
+	queueRequest(t, s, "noisy", 1, order, releases)
+	queueRequest(t, s, "noisy", 1, order, releases)
+	queueRequest(t, s, "quiet", 1, order, releases)
+
+	hold()
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, <-order)
+		(<-releases)()
+	}
+	assert.Equal(t, []string{"noisy", "quiet", "noisy", "noisy"}, got)
+}
+
+func TestFairScheduler_Weights(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 1, MaxQueue: 10})
+	hold, err := s.Acquire(context.Background(), "x", 1)
+	require.NoError(t, err)
+
+	order := make(chan string, 10)
+	releases := make(chan func(), 10)
+	for i := 0; i < 3; i++ {
+		queueRequest(t, s, "heavy", 2, order, releases)
+	}
+	for i := 0; i < 2; i++ {
+		queueRequest(t, s, "light", 1, order, releases)
+	}
+
+	hold()
+	var got []string
+	for i := 0; i < 5; i++ {
+		got = append(got, <-order)
+		(<-releases)()
+	}
+	assert.Equal(t, []string{"heavy", "heavy", "light", "heavy", "light"}, got)
+}
+
+func TestFairScheduler_QueueFull(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 1, MaxQueue: 1})
+	hold, err := s.Acquire(context.Background(), "a", 1)
+	require.NoError(t, err)
+	defer hold()
+
+	order := make(chan string, 1)
+	releases := make(chan func(), 1)
+	queueRequest(t, s, "a", 1, order, releases)
+
+	_, err = s.Acquire(context.Background(), "b", 1)
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestFairScheduler_QueueTimeoutAndCancel(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 1, MaxQueue: 10, QueueTimeout: 20 * time.Millisecond})
+	hold, err := s.Acquire(context.Background(), "a", 1)
+	require.NoError(t, err)
+
+	_, err = s.Acquire(context.Background(), "b", 1)
+	assert.ErrorIs(t, err, ErrQueueTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Acquire(ctx, "b", 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, queued := s.Stats()
+	assert.Equal(t, 0, queued, "abandoned waiters are removed")
+
+	hold()
+	release, err := s.Acquire(context.Background(), "b", 1)
+	require.NoError(t, err)
+	release()
+}
+
+func TestFairScheduler_WeightFor(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 1, MaxQueue: 1, DefaultWeight: 2, Weights: map[string]int{"team-a": 5}})
+
+	assert.Equal(t, 5, s.WeightFor("", "team-a"))
+	assert.Equal(t, 5, s.WeightFor("unknown", "team-a"))
+	assert.Equal(t, 2, s.WeightFor("unknown"))
+}
+
+func TestFairScheduler_Concurrency(t *testing.T) {
+	s := NewFairScheduler(Config{MaxInFlight: 4, MaxQueue: 1000})
+
+	var (
+		mu      sync.Mutex
+		current int
+		peak    int
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), []string{"a", "b", "c"}[i%3], 1)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			current++
+			peak = max(peak, current)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			current--
+			mu.Unlock()
+			release()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak, 4)
+	inFlight, queued := s.Stats()
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+}
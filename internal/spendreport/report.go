@@ -0,0 +1,427 @@
+// Package spendreport aggregates the spend of proxied requests and delivers a daily report
+// (top keys, teams, models, total spend and error rates) to a webhook or a directory.
+// There is no built-in email delivery; a webhook of a mail gateway can be used instead.
+package spendreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/monitoring"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+	"github.com/mixaill76/auto_ai_router/internal/webhook"
+)
+
+// deliveryRetryInterval is the wait before a failed report delivery is retried
+const deliveryRetryInterval = 5 * time.Minute
+
+// Event describes the cost and outcome of a single proxied request
+type Event struct {
+	Key      string // Hashed API key
+	KeyAlias string // Key alias ("" = none); keys are listed by alias, but counted by key
+	Team     string // Team ID ("" = no team)
+	Model    string
+	Tokens   int
+	Cost     float64 // USD
+	Failed   bool
+}
+
+// Entry aggregates the requests of one key, team or model (or all requests)
+type Entry struct {
+	Name      string  `json:"name,omitempty"`
+	Key       string  `json:"key,omitempty"` // Hashed API key of a key listed by its alias
+	Spend     float64 `json:"spend"`         // USD
+	Requests  int64   `json:"requests"`
+	Failures  int64   `json:"failures"`
+	ErrorRate float64 `json:"error_rate"` // Failures / requests
+	Tokens    int64   `json:"tokens"`
+}
+
+func (e *Entry) add(event Event) {
+	e.Spend += event.Cost
+	e.Requests++
+	e.Tokens += int64(event.Tokens)
+	if event.Failed {
+		e.Failures++
+	}
+	e.ErrorRate = float64(e.Failures) / float64(e.Requests)
+}
+
+func (e *Entry) merge(other Entry) {
+	e.Spend += other.Spend
+	e.Requests += other.Requests
+	e.Tokens += other.Tokens
+	e.Failures += other.Failures
+	if e.Requests > 0 {
+		e.ErrorRate = float64(e.Failures) / float64(e.Requests)
+	}
+}
+
+// Report is the spend of the requests served between Start and End
+type Report struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Total     Entry     `json:"total"`
+	TopKeys   []Entry   `json:"top_keys"`
+	TopTeams  []Entry   `json:"top_teams"`
+	TopModels []Entry   `json:"top_models"`
+}
+
+// Reporter aggregates request spend in memory and delivers a report once a day.
+// Aggregates cover the requests served by this instance and restart from zero after
+// every delivered report and when the router restarts; the requests of a report that
+// failed to deliver are kept and reported again on the next attempt.
+// A nil *Reporter is valid and records nothing.
+type Reporter struct {
+	cfg           config.SpendReportConfig
+	at            time.Duration // Offset of the report time from UTC midnight
+	logger        *slog.Logger
+	webhook       *webhook.Poster
+	now           func() time.Time
+	retryInterval time.Duration
+
+	mu     sync.Mutex
+	period *period
+}
+
+// period holds the aggregates of the requests served since start
+type period struct {
+	start  time.Time
+	total  Entry
+	keys   map[string]*Entry // Hashed API key -> entry
+	teams  map[string]*Entry
+	models map[string]*Entry
+}
+
+func newPeriod(start time.Time) *period {
+	return &period{
+		start:  start,
+		keys:   make(map[string]*Entry),
+		teams:  make(map[string]*Entry),
+		models: make(map[string]*Entry),
+	}
+}
+
+// New creates a Reporter from config.
+// Returns nil if the spend report is disabled.
+func New(cfg config.SpendReportConfig, logger *slog.Logger) *Reporter {
+	if !cfg.Enabled {
+		return nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := &Reporter{
+		cfg:     cfg,
+		logger:  logger,
+		webhook: webhook.New(cfg.WebhookURL, cfg.WebhookFormat),
+		now:     utils.NowUTC,
+
+		retryInterval: deliveryRetryInterval,
+	}
+	if at, err := time.Parse("15:04", cfg.Time); err == nil {
+		r.at = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	r.reset(r.now())
+	return r
+}
+
+// IsEnabled returns true if request spend is being recorded
+func (r *Reporter) IsEnabled() bool {
+	return r != nil
+}
+
+// reset starts a new report period; callers hold r.mu (or own r exclusively)
+func (r *Reporter) reset(start time.Time) {
+	r.period = newPeriod(start)
+}
+
+// Record adds a completed request to the current report period
+func (r *Reporter) Record(event Event) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.period.total.add(event)
+	keyEntry := entryOf(r.period.keys, event.Key, event.Key)
+	if event.KeyAlias != "" {
+		keyEntry.Name = event.KeyAlias
+		keyEntry.Key = event.Key
+	}
+	keyEntry.add(event)
+	if event.Team != "" {
+		entryOf(r.period.teams, event.Team, event.Team).add(event)
+	}
+	entryOf(r.period.models, event.Model, event.Model).add(event)
+}
+
+// entryOf returns the entry with the given map key, adding one named name if there is none
+func entryOf(entries map[string]*Entry, key, name string) *Entry {
+	entry, ok := entries[key]
+	if !ok {
+		entry = &Entry{Name: name}
+		entries[key] = entry
+	}
+	return entry
+}
+
+// Generate returns the report of the current period and starts a new one
+func (r *Reporter) Generate() *Report {
+	if r == nil {
+		return nil
+	}
+	report, _ := r.generate()
+	return report
+}
+
+// generate returns the report of the current period and the period itself, and starts a new one
+func (r *Reporter) generate() (*Report, *period) {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := r.reportLocked(now)
+	done := r.period
+	r.reset(now)
+	return report, done
+}
+
+// requeue merges a period that failed to deliver back into the current one
+func (r *Reporter) requeue(done *period) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.period
+	current.start = done.start
+	current.total.merge(done.total)
+	for _, merged := range []struct{ into, from map[string]*Entry }{
+		{current.keys, done.keys},
+		{current.teams, done.teams},
+		{current.models, done.models},
+	} {
+		for key, entry := range merged.from {
+			if into, ok := merged.into[key]; ok {
+				into.merge(*entry)
+			} else {
+				merged.into[key] = entry
+			}
+		}
+	}
+}
+
+// Current returns the report of the current period so far, without starting a new one
+// (nil if r is nil)
+func (r *Reporter) Current() *Report {
+	if r == nil {
+		return nil
+	}
+
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reportLocked(now)
+}
+
+// reportLocked returns the report of the current period ending at end; callers hold r.mu
+func (r *Reporter) reportLocked(end time.Time) *Report {
+	return &Report{
+		Start:     r.period.start,
+		End:       end,
+		Total:     r.period.total,
+		TopKeys:   topEntries(r.period.keys, r.cfg.TopN),
+		TopTeams:  topEntries(r.period.teams, r.cfg.TopN),
+		TopModels: topEntries(r.period.models, r.cfg.TopN),
+	}
+}
+
+// topEntries returns the n entries with the highest spend (then requests, then name)
+func topEntries(entries map[string]*Entry, n int) []Entry {
+	top := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		top = append(top, *entry)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Spend != top[j].Spend {
+			return top[i].Spend > top[j].Spend
+		}
+		if top[i].Requests != top[j].Requests {
+			return top[i].Requests > top[j].Requests
+		}
+		return top[i].Name < top[j].Name
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// Run generates and delivers a report every day at the configured UTC time until ctx is cancelled.
+// A failed delivery is retried every retryInterval with a report that also covers the requests
+// served since the failed attempt.
+func (r *Reporter) Run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	retry := false
+	for {
+		now := r.now()
+		wait := nextRun(now, r.at).Sub(now)
+		if retry {
+			wait = r.retryInterval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.logger.Debug("Spend report loop stopped")
+			return
+		case <-timer.C:
+		}
+
+		report, done := r.generate()
+		if err := r.Deliver(ctx, report); err != nil {
+			r.requeue(done)
+			retry = true
+			monitoring.SpendReportsTotal.WithLabelValues("error").Inc()
+			r.logger.Warn("Failed to deliver spend report", "error", err, "retry_in", r.retryInterval)
+			continue
+		}
+		retry = false
+		monitoring.SpendReportsTotal.WithLabelValues("success").Inc()
+		r.logger.Info("Spend report delivered",
+			"start", report.Start,
+			"end", report.End,
+			"spend", report.Total.Spend,
+			"requests", report.Total.Requests,
+		)
+	}
+}
+
+// nextRun returns the first time after now that is at the given offset from UTC midnight
+func nextRun(now time.Time, at time.Duration) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Deliver posts the report to the webhook and writes it to the output directory (whichever are configured)
+func (r *Reporter) Deliver(ctx context.Context, report *Report) error {
+	if r == nil || report == nil {
+		return nil
+	}
+
+	var errs []error
+	if r.cfg.WebhookURL != "" {
+		if err := r.webhook.Post(ctx, report, FormatText(report)); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if r.cfg.OutputDir != "" {
+		if err := r.writeFile(report); err != nil {
+			errs = append(errs, fmt.Errorf("output_dir: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Reporter) writeFile(report *Report) error {
+	var data []byte
+	var err error
+	if r.cfg.OutputFormat == config.SpendReportFormatCSV {
+		data, err = MarshalCSV(report)
+	} else {
+		data, err = json.MarshalIndent(report, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.cfg.OutputDir, 0o755); err != nil {
+		return err
+	}
+	name := "spend-report-" + report.End.Format("2006-01-02") + "." + r.cfg.OutputFormat
+	return os.WriteFile(filepath.Join(r.cfg.OutputDir, name), data, 0o644)
+}
+
+// MarshalCSV encodes a report with one row for the total and each top key, team and model
+func MarshalCSV(report *Report) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"section", "name", "spend_usd", "requests", "failures", "error_rate", "tokens"})
+
+	row := func(section string, e Entry) {
+		_ = w.Write([]string{
+			section,
+			e.Name,
+			strconv.FormatFloat(e.Spend, 'f', 6, 64),
+			strconv.FormatInt(e.Requests, 10),
+			strconv.FormatInt(e.Failures, 10),
+			strconv.FormatFloat(e.ErrorRate, 'f', 4, 64),
+			strconv.FormatInt(e.Tokens, 10),
+		})
+	}
+	row("total", report.Total)
+	for _, e := range report.TopKeys {
+		row("key", e)
+	}
+	for _, e := range report.TopTeams {
+		row("team", e)
+	}
+	for _, e := range report.TopModels {
+		row("model", e)
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// FormatText renders a report as a human-readable message (Slack mrkdwn)
+func FormatText(report *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Spend report* %s - %s UTC\n",
+		report.Start.UTC().Format("2006-01-02 15:04"), report.End.UTC().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Total: %s\n", formatEntry(report.Total))
+
+	sections := []struct {
+		title   string
+		entries []Entry
+	}{
+		{"Top keys", report.TopKeys},
+		{"Top teams", report.TopTeams},
+		{"Top models", report.TopModels},
+	}
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n*%s*\n", section.title)
+		for i, e := range section.entries {
+			fmt.Fprintf(&b, "%d. %s: %s\n", i+1, e.Name, formatEntry(e))
+		}
+	}
+	return b.String()
+}
+
+func formatEntry(e Entry) string {
+	return fmt.Sprintf("$%.4f, %d requests, %.1f%% errors, %d tokens", e.Spend, e.Requests, e.ErrorRate*100, e.Tokens)
+}
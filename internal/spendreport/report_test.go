@@ -0,0 +1,228 @@
+package spendreport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReporter(t *testing.T, cfg config.SpendReportConfig, now *time.Time) *Reporter {
+	t.Helper()
+	cfg.Enabled = true
+	r := New(cfg, nil)
+	require.NotNil(t, r)
+	r.now = func() time.Time { return *now }
+	r.reset(*now)
+	return r
+}
+
+func recordSample(r *Reporter) {
+	r.Record(Event{Key: "key-a", Team: "team-1", Model: "gpt-4o", Tokens: 100, Cost: 1.5})
+	r.Record(Event{Key: "key-a", Team: "team-1", Model: "gpt-4o", Tokens: 50, Cost: 0.5, Failed: true})
+	r.Record(Event{Key: "key-b", Model: "claude", Tokens: 10, Cost: 3})
+	r.Record(Event{Key: "key-c", Team: "team-2", Model: "gemini", Tokens: 5, Cost: 0.1})
+}
+
+func TestNew_Disabled(t *testing.T) {
+	r := New(config.SpendReportConfig{}, nil)
+	assert.Nil(t, r)
+	assert.False(t, r.IsEnabled())
+	r.Record(Event{Key: "k", Cost: 1})
+	assert.Nil(t, r.Generate())
+	assert.Nil(t, r.Current())
+}
+
+func TestReporter_Generate(t *testing.T) {
+	now := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	r := newTestReporter(t, config.SpendReportConfig{TopN: 2}, &now)
+	recordSample(r)
+
+	now = now.Add(24 * time.Hour)
+	current := r.Current()
+	require.NotNil(t, current)
+	assert.Equal(t, int64(4), current.Total.Requests, "the current period is kept")
+
+	report := r.Generate()
+	require.NotNil(t, report)
+	assert.Equal(t, current, report)
+
+	assert.Equal(t, time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), report.Start)
+	assert.Equal(t, now, report.End)
+	assert.InDelta(t, 5.1, report.Total.Spend, 1e-9)
+	assert.Equal(t, int64(4), report.Total.Requests)
+	assert.Equal(t, int64(1), report.Total.Failures)
+	assert.InDelta(t, 0.25, report.Total.ErrorRate, 1e-9)
+	assert.Equal(t, int64(165), report.Total.Tokens)
+
+	require.Len(t, report.TopKeys, 2, "limited to top_n")
+	assert.Equal(t, "key-b", report.TopKeys[0].Name)
+	assert.Equal(t, Entry{Name: "key-a", Spend: 2, Requests: 2, Failures: 1, ErrorRate: 0.5, Tokens: 150}, report.TopKeys[1])
+
+	require.Len(t, report.TopTeams, 2, "requests without team are not listed")
+	assert.Equal(t, "team-1", report.TopTeams[0].Name)
+	assert.Equal(t, "team-2", report.TopTeams[1].Name)
+	assert.Equal(t, []string{"claude", "gpt-4o"}, []string{report.TopModels[0].Name, report.TopModels[1].Name})
+
+	// Aggregates restart after a report
+	next := r.Generate()
+	assert.Equal(t, now, next.Start)
+	assert.Equal(t, int64(0), next.Total.Requests)
+	assert.Empty(t, next.TopKeys)
+}
+
+func TestReporter_KeyAliases(t *testing.T) {
+	now := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	r := newTestReporter(t, config.SpendReportConfig{}, &now)
+	r.Record(Event{Key: "hash-1", KeyAlias: "frontend", Cost: 1})
+	r.Record(Event{Key: "hash-2", KeyAlias: "frontend", Cost: 2})
+	r.Record(Event{Key: "hash-1", KeyAlias: "frontend", Cost: 0.5})
+
+	report := r.Generate()
+	assert.Equal(t, []Entry{
+		{Name: "frontend", Key: "hash-2", Spend: 2, Requests: 1},
+		{Name: "frontend", Key: "hash-1", Spend: 1.5, Requests: 2},
+	}, report.TopKeys, "keys sharing an alias are counted separately")
+}
+
+func TestReporter_RunRetriesFailedDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	var delivered Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&delivered))
+	}))
+	defer server.Close()
+
+	r := New(config.SpendReportConfig{Enabled: true, WebhookURL: server.URL}, nil)
+	require.NotNil(t, r)
+	start := r.Current().Start
+	now := time.Now().UTC()
+	r.at = now.Sub(now.Truncate(24*time.Hour)) + 20*time.Millisecond
+	r.retryInterval = 20 * time.Millisecond
+	recordSample(r)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go r.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts >= 2
+	}, 5*time.Second, 5*time.Millisecond)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, start, delivered.Start, "the failed period is kept")
+	assert.Equal(t, int64(4), delivered.Total.Requests)
+	assert.Len(t, delivered.TopKeys, 3)
+	assert.Equal(t, int64(0), r.Current().Total.Requests)
+}
+
+func TestNextRun(t *testing.T) {
+	at := 6*time.Hour + 30*time.Minute
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 10, 13, 5, 0, 0, 0, time.UTC), time.Date(2026, 10, 13, 6, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 13, 6, 30, 0, 0, time.UTC), time.Date(2026, 10, 14, 6, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 13, 23, 0, 0, 0, time.UTC), time.Date(2026, 10, 14, 6, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 13, 2, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), time.Date(2026, 10, 13, 6, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, nextRun(tt.now, at), "now=%v", tt.now)
+	}
+}
+
+func TestReporter_DeliverWebhook(t *testing.T) {
+	for _, format := range []string{config.WebhookFormatJSON, config.WebhookFormatSlack} {
+		t.Run(format, func(t *testing.T) {
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, http.MethodPost, req.Method)
+				assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+				body, _ = io.ReadAll(req.Body)
+			}))
+			defer server.Close()
+
+			now := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+			r := newTestReporter(t, config.SpendReportConfig{WebhookURL: server.URL, WebhookFormat: format}, &now)
+			recordSample(r)
+			now = now.Add(24 * time.Hour)
+			require.NoError(t, r.Deliver(t.Context(), r.Generate()))
+
+			if format == config.WebhookFormatSlack {
+				var msg map[string]string
+				require.NoError(t, json.Unmarshal(body, &msg))
+				assert.Contains(t, msg["text"], "*Spend report* 2026-10-13 00:00 - 2026-10-14 00:00 UTC")
+				assert.Contains(t, msg["text"], "Total: $5.1000, 4 requests, 25.0% errors, 165 tokens")
+				assert.Contains(t, msg["text"], "*Top keys*\n1. key-b: $3.0000")
+				return
+			}
+			var report Report
+			require.NoError(t, json.Unmarshal(body, &report))
+			assert.Equal(t, int64(4), report.Total.Requests)
+			assert.Len(t, report.TopKeys, 3)
+		})
+	}
+}
+
+func TestReporter_DeliverWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	now := time.Now().UTC()
+	r := newTestReporter(t, config.SpendReportConfig{WebhookURL: server.URL}, &now)
+	assert.ErrorContains(t, r.Deliver(t.Context(), r.Generate()), "unexpected status 500")
+}
+
+func TestReporter_DeliverFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	now := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+
+	r := newTestReporter(t, config.SpendReportConfig{OutputDir: dir, OutputFormat: config.SpendReportFormatCSV}, &now)
+	recordSample(r)
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, r.Deliver(t.Context(), r.Generate()))
+
+	data, err := os.ReadFile(filepath.Join(dir, "spend-report-2026-10-14.csv"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, "section,name,spend_usd,requests,failures,error_rate,tokens", lines[0])
+	assert.Equal(t, "total,,5.100000,4,1,0.2500,165", lines[1])
+	assert.Equal(t, "key,key-a,2.000000,2,1,0.5000,150", lines[3])
+	assert.Equal(t, "team,team-1,2.000000,2,1,0.5000,150", lines[5])
+	assert.Len(t, lines, 1+1+3+2+3)
+
+	r.cfg.OutputFormat = config.SpendReportFormatJSON
+	require.NoError(t, r.Deliver(t.Context(), r.Generate()))
+	data, err = os.ReadFile(filepath.Join(dir, "spend-report-2026-10-14.json"))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, now, report.Start)
+}
@@ -0,0 +1,137 @@
+package spendstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Filter selects spend log entries; zero fields match everything
+type Filter struct {
+	Start  time.Time // Inclusive
+	End    time.Time // Exclusive
+	APIKey string    // Hashed API key
+	TeamID string
+	UserID string
+	Model  string // Model name or model group
+	Limit  int    // Logs to return, newest first (0 = DefaultLimit, capped at MaxLimit)
+}
+
+// Result is the answer to a Query: totals over all matching entries and the newest of them
+type Result struct {
+	Requests         int     `json:"requests"`
+	Spend            float64 `json:"spend"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Logs             []Log   `json:"logs"`
+}
+
+// Log is a spend log entry in the JSON form of LiteLLM /spend/logs
+type Log struct {
+	RequestID         string          `json:"request_id"`
+	CallType          string          `json:"call_type"`
+	APIKey            string          `json:"api_key"`
+	Spend             float64         `json:"spend"`
+	TotalTokens       int             `json:"total_tokens"`
+	PromptTokens      int             `json:"prompt_tokens"`
+	CompletionTokens  int             `json:"completion_tokens"`
+	StartTime         time.Time       `json:"startTime"`
+	EndTime           time.Time       `json:"endTime"`
+	Model             string          `json:"model"`
+	ModelID           string          `json:"model_id"`
+	ModelGroup        string          `json:"model_group"`
+	CustomLLMProvider string          `json:"custom_llm_provider"`
+	APIBase           string          `json:"api_base"`
+	User              string          `json:"user"`
+	Metadata          json.RawMessage `json:"metadata"`
+	TeamID            string          `json:"team_id"`
+	OrganizationID    string          `json:"organization_id"`
+	EndUser           string          `json:"end_user"`
+	RequesterIP       string          `json:"requester_ip_address"`
+	Status            string          `json:"status"`
+	SessionID         string          `json:"session_id"`
+	RequestTags       json.RawMessage `json:"request_tags"`
+	Pushed            bool            `json:"pushed"` // Backfilled into LiteLLM DB
+}
+
+// Query returns the totals and newest logs matching filter
+func (s *Store) Query(ctx context.Context, filter Filter) (*Result, error) {
+	var conditions []string
+	var args []interface{}
+	if !filter.Start.IsZero() {
+		conditions = append(conditions, `"startTime" >= ?`)
+		args = append(args, filter.Start.UnixMicro())
+	}
+	if !filter.End.IsZero() {
+		conditions = append(conditions, `"startTime" < ?`)
+		args = append(args, filter.End.UnixMicro())
+	}
+	for column, value := range map[string]string{"api_key": filter.APIKey, "team_id": filter.TeamID, `"user"`: filter.UserID} {
+		if value != "" {
+			conditions = append(conditions, column+` = ?`)
+			args = append(args, value)
+		}
+	}
+	if filter.Model != "" {
+		conditions = append(conditions, `(model = ? OR model_group = ?)`)
+		args = append(args, filter.Model, filter.Model)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	result := &Result{Logs: []Log{}}
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(spend), 0), COALESCE(SUM(prompt_tokens), 0),
+		COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(total_tokens), 0) FROM spend_logs`+where, args...).
+		Scan(&result.Requests, &result.Spend, &result.PromptTokens, &result.CompletionTokens, &result.TotalTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query local spend log: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+selectColumns()+`, pushed_at IS NOT NULL FROM spend_logs`+where+
+		` ORDER BY "startTime" DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query local spend log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var l Log
+		var start, end int64
+		var metadata, tags, agentID, toolName string
+		if err := rows.Scan(
+			&l.RequestID, &l.CallType, &l.APIKey, &l.Spend, &l.TotalTokens, &l.PromptTokens, &l.CompletionTokens,
+			&start, &end, &l.Model, &l.ModelID, &l.ModelGroup, &l.CustomLLMProvider, &l.APIBase,
+			&l.User, &metadata, &l.TeamID, &l.OrganizationID, &l.EndUser, &l.RequesterIP, &l.Status,
+			&l.SessionID, &agentID, &toolName, &tags, &l.Pushed,
+		); err != nil {
+			return nil, fmt.Errorf("failed to query local spend log: %w", err)
+		}
+		l.StartTime = time.UnixMicro(start).UTC()
+		l.EndTime = time.UnixMicro(end).UTC()
+		l.Metadata = rawJSON(metadata, "{}")
+		l.RequestTags = rawJSON(tags, "[]")
+		result.Logs = append(result.Logs, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query local spend log: %w", err)
+	}
+	return result, nil
+}
+
+// rawJSON returns value as raw JSON, or fallback if value is not valid JSON
+func rawJSON(value, fallback string) json.RawMessage {
+	if !json.Valid([]byte(value)) {
+		value = fallback
+	}
+	return json.RawMessage(value)
+}
@@ -0,0 +1,342 @@
+// Package spendstore keeps spend log entries in an embedded SQLite database while LiteLLM DB
+// is disabled (local_spend_log), serves them to the /spend endpoint and backfills them into
+// LiteLLM DB once it is available.
+package spendstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver (builds with CGO_ENABLED=0)
+)
+
+// Writer settings
+const (
+	queueSize      = 10000
+	batchSize      = 500
+	flushInterval  = time.Second
+	pruneInterval  = time.Hour
+	backfillChunk  = 500
+	DefaultLimit   = 100  // Logs returned by Query without a limit
+	MaxLimit       = 1000 // Most logs returned by Query
+	sqliteDSNFlags = "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+)
+
+// ErrQueueFull is returned by Log when the write queue is full and the entry is dropped
+var ErrQueueFull = errors.New("local spend log queue full")
+
+// spendLogColumns are the columns of spend_logs in insert order; the names follow LiteLLM_SpendLogs
+var spendLogColumns = []string{
+	"request_id", "call_type", "api_key", "spend", "total_tokens", "prompt_tokens", "completion_tokens",
+	"startTime", "endTime", "model", "model_id", "model_group", "custom_llm_provider", "api_base",
+	"user", "metadata", "team_id", "organization_id", "end_user", "requester_ip_address", "status",
+	"session_id", "agent_id", "mcp_namespaced_tool_name", "request_tags",
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS spend_logs (
+	request_id               TEXT PRIMARY KEY,
+	call_type                TEXT NOT NULL DEFAULT '',
+	api_key                  TEXT NOT NULL DEFAULT '',
+	spend                    REAL NOT NULL DEFAULT 0,
+	total_tokens             INTEGER NOT NULL DEFAULT 0,
+	prompt_tokens            INTEGER NOT NULL DEFAULT 0,
+	completion_tokens        INTEGER NOT NULL DEFAULT 0,
+	"startTime"              INTEGER NOT NULL, -- Unix microseconds (UTC)
+	"endTime"                INTEGER NOT NULL, -- Unix microseconds (UTC)
+	model                    TEXT NOT NULL DEFAULT '',
+	model_id                 TEXT NOT NULL DEFAULT '',
+	model_group              TEXT NOT NULL DEFAULT '',
+	custom_llm_provider      TEXT NOT NULL DEFAULT '',
+	api_base                 TEXT NOT NULL DEFAULT '',
+	"user"                   TEXT NOT NULL DEFAULT '',
+	metadata                 TEXT NOT NULL DEFAULT '{}',
+	team_id                  TEXT NOT NULL DEFAULT '',
+	organization_id          TEXT NOT NULL DEFAULT '',
+	end_user                 TEXT NOT NULL DEFAULT '',
+	requester_ip_address     TEXT NOT NULL DEFAULT '',
+	status                   TEXT NOT NULL DEFAULT '',
+	session_id               TEXT NOT NULL DEFAULT '',
+	agent_id                 TEXT NOT NULL DEFAULT '',
+	mcp_namespaced_tool_name TEXT NOT NULL DEFAULT '',
+	request_tags             TEXT NOT NULL DEFAULT '[]',
+	pushed_at                INTEGER -- Unix microseconds of the backfill into LiteLLM DB (NULL = not pushed)
+);
+CREATE INDEX IF NOT EXISTS spend_logs_start_time ON spend_logs ("startTime");
+CREATE INDEX IF NOT EXISTS spend_logs_api_key ON spend_logs (api_key, "startTime");
+CREATE INDEX IF NOT EXISTS spend_logs_team_id ON spend_logs (team_id, "startTime");
+CREATE INDEX IF NOT EXISTS spend_logs_unpushed ON spend_logs ("startTime") WHERE pushed_at IS NULL;
+`
+
+// Store is a SQLite spend log. Entries are written asynchronously in batches.
+// A nil *Store is valid and stores nothing.
+type Store struct {
+	db        *sql.DB
+	logger    *slog.Logger
+	retention time.Duration
+	now       func() time.Time
+
+	queue     chan *models.SpendLogEntry
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// Open opens (creating if needed) the database of cfg and starts the writer
+func Open(cfg config.LocalSpendLogConfig, log *slog.Logger) (*Store, error) {
+	path := cfg.Path
+	if path == "" {
+		path = config.DefaultLocalSpendLogPath
+	}
+	db, err := sql.Open("sqlite", "file:"+path+sqliteDSNFlags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local spend log: %w", err)
+	}
+	// A single connection serializes writes; SQLite allows one writer at a time anyway
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create local spend log schema: %w", err)
+	}
+
+	s := &Store{
+		db:        db,
+		logger:    log,
+		retention: cfg.Retention,
+		now:       utils.NowUTC,
+		queue:     make(chan *models.SpendLogEntry, queueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.writer()
+	return s, nil
+}
+
+// Log queues an entry for writing without blocking.
+// Returns ErrQueueFull if the writer is behind and the entry is dropped.
+func (s *Store) Log(entry *models.SpendLogEntry) error {
+	if s == nil || entry == nil {
+		return nil
+	}
+	select {
+	case s.queue <- entry:
+		return nil
+	default:
+		s.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Close writes the queued entries and closes the database
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.db.Close()
+		s.logger.Info("Local spend log closed",
+			"written", s.written.Load(),
+			"dropped", s.dropped.Load(),
+			"errors", s.errors.Load(),
+		)
+	})
+	return err
+}
+
+// writer batches queued entries into transactions and prunes expired entries
+func (s *Store) writer() {
+	defer close(s.done)
+
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+	s.prune()
+
+	batch := make([]*models.SpendLogEntry, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.insert(batch); err != nil {
+			s.errors.Add(uint64(len(batch)))
+			s.logger.Error("Failed to write local spend log entries", "entries", len(batch), "error", err)
+		} else {
+			s.written.Add(uint64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-flushTicker.C:
+			flush()
+		case <-pruneTicker.C:
+			s.prune()
+		case <-s.stop:
+			for {
+				select {
+				case entry := <-s.queue:
+					batch = append(batch, entry)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// insert writes entries in one transaction; entries with a known request_id are skipped
+func (s *Store) insert(entries []*models.SpendLogEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	quoted := make([]string, len(spendLogColumns))
+	for i, column := range spendLogColumns {
+		quoted[i] = `"` + column + `"`
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO spend_logs (` + strings.Join(quoted, ", ") + `) VALUES (?` +
+		strings.Repeat(", ?", len(spendLogColumns)-1) + `)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, e := range entries {
+		metadata, tags := e.Metadata, e.RequestTags
+		if metadata == "" {
+			metadata = "{}"
+		}
+		if tags == "" {
+			tags = "[]"
+		}
+		if _, err := stmt.Exec(
+			e.RequestID, e.CallType, e.APIKey, e.Spend, e.TotalTokens, e.PromptTokens, e.CompletionTokens,
+			e.StartTime.UnixMicro(), e.EndTime.UnixMicro(), e.Model, e.ModelID, e.ModelGroup, e.CustomLLMProvider, e.APIBase,
+			e.UserID, metadata, e.TeamID, e.OrganizationID, e.EndUser, e.RequesterIP, e.Status,
+			e.SessionID, e.AgentID, e.MCPNamespacedToolName, tags,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// prune deletes entries older than the retention; entries not yet backfilled are kept
+func (s *Store) prune() {
+	if s.retention <= 0 {
+		return
+	}
+	cutoff := s.now().Add(-s.retention).UnixMicro()
+	result, err := s.db.Exec(`DELETE FROM spend_logs WHERE "startTime" < ? AND pushed_at IS NOT NULL`, cutoff)
+	if err != nil {
+		s.logger.Warn("Failed to prune local spend log", "error", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.logger.Info("Pruned local spend log", "deleted", n, "retention", s.retention)
+	}
+}
+
+// scanEntry reads a spend_logs row selected with spendLogColumns
+func scanEntry(rows *sql.Rows) (*models.SpendLogEntry, error) {
+	var e models.SpendLogEntry
+	var start, end int64
+	if err := rows.Scan(
+		&e.RequestID, &e.CallType, &e.APIKey, &e.Spend, &e.TotalTokens, &e.PromptTokens, &e.CompletionTokens,
+		&start, &end, &e.Model, &e.ModelID, &e.ModelGroup, &e.CustomLLMProvider, &e.APIBase,
+		&e.UserID, &e.Metadata, &e.TeamID, &e.OrganizationID, &e.EndUser, &e.RequesterIP, &e.Status,
+		&e.SessionID, &e.AgentID, &e.MCPNamespacedToolName, &e.RequestTags,
+	); err != nil {
+		return nil, err
+	}
+	e.StartTime = time.UnixMicro(start).UTC()
+	e.EndTime = time.UnixMicro(end).UTC()
+	return &e, nil
+}
+
+// selectColumns returns the quoted spendLogColumns for SELECT
+func selectColumns() string {
+	quoted := make([]string, len(spendLogColumns))
+	for i, column := range spendLogColumns {
+		quoted[i] = `"` + column + `"`
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Backfill hands every entry not yet pushed to log (the LiteLLM DB spend logger), oldest
+// first, then calls flush, which must return once the handed entries are written. Only after
+// a successful flush are the entries marked as pushed, so a failed backfill can be repeated.
+// Returns the number of pushed entries.
+func (s *Store) Backfill(ctx context.Context, log func(context.Context, *models.SpendLogEntry) error, flush func(context.Context) error) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+selectColumns()+` FROM spend_logs WHERE pushed_at IS NULL ORDER BY "startTime"`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read local spend log: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to read local spend log: %w", err)
+		}
+		if err := log(ctx, entry); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to push spend log entry %s: %w", entry.RequestID, err)
+		}
+		ids = append(ids, entry.RequestID)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, fmt.Errorf("failed to read local spend log: %w", err)
+	}
+	_ = rows.Close()
+
+	if err := flush(ctx); err != nil {
+		return 0, fmt.Errorf("failed to write spend logs to LiteLLM DB: %w", err)
+	}
+
+	pushedAt := s.now().UnixMicro()
+	for start := 0; start < len(ids); start += backfillChunk {
+		chunk := ids[start:min(start+backfillChunk, len(ids))]
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, pushedAt)
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE spend_logs SET pushed_at = ? WHERE request_id IN (?`+
+			strings.Repeat(", ?", len(chunk)-1)+`)`, args...); err != nil {
+			return start, fmt.Errorf("failed to mark local spend log entries as pushed: %w", err)
+		}
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,173 @@
+package spendstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/litellmdb/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(config.LocalSpendLogConfig{Enabled: true, Path: path}, testLogger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func testEntry(id string, start time.Time, key, team, model string, spend float64) *models.SpendLogEntry {
+	return &models.SpendLogEntry{
+		RequestID:        id,
+		StartTime:        start,
+		EndTime:          start.Add(time.Second),
+		CallType:         "/v1/chat/completions",
+		Model:            model,
+		ModelGroup:       model,
+		ModelID:          "openai:" + model,
+		PromptTokens:     10,
+		CompletionTokens: 5,
+		TotalTokens:      15,
+		Metadata:         `{"user_api_key":"` + key + `"}`,
+		Spend:            spend,
+		APIKey:           key,
+		TeamID:           team,
+		Status:           "success",
+	}
+}
+
+// writeEntries logs entries and reopens the store once they are written
+func writeEntries(t *testing.T, entries ...*models.SpendLogEntry) *Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spend.db")
+	s := openTestStore(t, path)
+	for _, entry := range entries {
+		require.NoError(t, s.Log(entry))
+	}
+	require.NoError(t, s.Close())
+	return openTestStore(t, path)
+}
+
+func TestStore_Query(t *testing.T) {
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := writeEntries(t,
+		testEntry("r1", day, "key-a", "team-1", "gpt-4o", 0.5),
+		testEntry("r2", day.Add(time.Hour), "key-a", "team-1", "gpt-4o-mini", 0.25),
+		testEntry("r3", day.Add(24*time.Hour), "key-b", "team-2", "gpt-4o", 1),
+		testEntry("r1", day, "key-a", "team-1", "gpt-4o", 0.5), // Duplicate request ID
+	)
+	ctx := context.Background()
+
+	all, err := s.Query(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, all.Requests)
+	assert.InDelta(t, 1.75, all.Spend, 1e-9)
+	assert.Equal(t, 45, all.TotalTokens)
+	require.Len(t, all.Logs, 3)
+	assert.Equal(t, "r3", all.Logs[0].RequestID, "newest first")
+	assert.Equal(t, day.Add(24*time.Hour), all.Logs[0].StartTime)
+	assert.JSONEq(t, `{"user_api_key":"key-b"}`, string(all.Logs[0].Metadata))
+	assert.JSONEq(t, `[]`, string(all.Logs[0].RequestTags))
+	assert.False(t, all.Logs[0].Pushed)
+
+	byKey, err := s.Query(ctx, Filter{APIKey: "key-a", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, byKey.Requests)
+	assert.InDelta(t, 0.75, byKey.Spend, 1e-9)
+	require.Len(t, byKey.Logs, 1)
+	assert.Equal(t, "r2", byKey.Logs[0].RequestID)
+
+	byDay, err := s.Query(ctx, Filter{Start: day, End: day.Add(24 * time.Hour), Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, byDay.Requests)
+
+	byTeam, err := s.Query(ctx, Filter{TeamID: "team-2"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, byTeam.Requests)
+
+	none, err := s.Query(ctx, Filter{UserID: "nobody"})
+	require.NoError(t, err)
+	assert.Zero(t, none.Requests)
+	assert.NotNil(t, none.Logs)
+}
+
+func TestStore_Backfill(t *testing.T) {
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := writeEntries(t,
+		testEntry("r1", day.Add(time.Hour), "key-a", "team-1", "gpt-4o", 0.5),
+		testEntry("r2", day, "key-a", "team-1", "gpt-4o", 0.25),
+	)
+	ctx := context.Background()
+
+	// A failed flush leaves the entries unpushed
+	var pushed []*models.SpendLogEntry
+	logEntry := func(_ context.Context, e *models.SpendLogEntry) error {
+		pushed = append(pushed, e)
+		return nil
+	}
+	_, err := s.Backfill(ctx, logEntry, func(context.Context) error { return errors.New("db down") })
+	require.Error(t, err)
+	require.Len(t, pushed, 2)
+
+	pushed = nil
+	n, err := s.Backfill(ctx, logEntry, func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, pushed, 2)
+	assert.Equal(t, "r2", pushed[0].RequestID, "oldest first")
+	assert.Equal(t, day, pushed[0].StartTime)
+	assert.Equal(t, "openai:gpt-4o", pushed[0].ModelID)
+	assert.Equal(t, `{"user_api_key":"key-a"}`, pushed[0].Metadata)
+
+	result, err := s.Query(ctx, Filter{})
+	require.NoError(t, err)
+	for _, l := range result.Logs {
+		assert.True(t, l.Pushed, l.RequestID)
+	}
+
+	// Pushed entries are not pushed again
+	pushed = nil
+	n, err = s.Backfill(ctx, logEntry, func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, pushed)
+}
+
+func TestStore_PruneKeepsUnpushed(t *testing.T) {
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	s := writeEntries(t,
+		testEntry("old-pushed", now.Add(-10*24*time.Hour), "key-a", "", "gpt-4o", 1),
+		testEntry("old-unpushed", now.Add(-9*24*time.Hour), "key-a", "", "gpt-4o", 1),
+		testEntry("recent", now.Add(-time.Hour), "key-a", "", "gpt-4o", 1),
+	)
+	ctx := context.Background()
+	_, err := s.db.Exec(`UPDATE spend_logs SET pushed_at = 1 WHERE request_id = 'old-pushed'`)
+	require.NoError(t, err)
+
+	s.retention = 7 * 24 * time.Hour
+	s.now = func() time.Time { return now }
+	s.prune()
+
+	result, err := s.Query(ctx, Filter{})
+	require.NoError(t, err)
+	var ids []string
+	for _, l := range result.Logs {
+		ids = append(ids, l.RequestID)
+	}
+	assert.Equal(t, []string{"recent", "old-unpushed"}, ids)
+}
+
+func TestStore_Nil(t *testing.T) {
+	var s *Store
+	assert.NoError(t, s.Log(&models.SpendLogEntry{RequestID: "r1"}))
+	assert.NoError(t, s.Close())
+}
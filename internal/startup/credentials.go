@@ -0,0 +1,359 @@
+package startup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/mixaill76/auto_ai_router/internal/httputil"
+)
+
+// maxProbeBodyBytes limits the size of model listing responses read during probing
+const maxProbeBodyBytes = 10 * 1024 * 1024
+
+// ProbeStatus is the outcome of a single credential probe
+type ProbeStatus string
+
+const (
+	ProbeStatusOK            ProbeStatus = "ok"
+	ProbeStatusMissingModels ProbeStatus = "missing_models" // Reachable and authenticated, but configured models are not listed
+	ProbeStatusFailed        ProbeStatus = "failed"
+	ProbeStatusSkipped       ProbeStatus = "skipped" // Provider type cannot be probed without sending a billable request
+)
+
+// TokenProvider issues OAuth2 tokens for Vertex AI service account credentials.
+// Implemented by auth.VertexTokenManager.
+type TokenProvider interface {
+	GetToken(ctx context.Context, credentialName, credentialsFile, credentialsJSON string) (string, error)
+}
+
+// CredentialProbeResult holds the result of probing one credential
+type CredentialProbeResult struct {
+	Name          string
+	Type          config.ProviderType
+	Required      bool
+	Status        ProbeStatus
+	Latency       time.Duration
+	ModelsChecked int
+	MissingModels []string
+	Error         string
+}
+
+// CredentialProber checks credential connectivity, authentication and model availability
+type CredentialProber struct {
+	client  *http.Client
+	tokens  TokenProvider
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewCredentialProber creates a prober with the given per-credential timeout.
+// tokens may be nil, in which case Vertex AI service account credentials are skipped.
+func NewCredentialProber(timeout time.Duration, tokens TokenProvider, log *slog.Logger) *CredentialProber {
+	return &CredentialProber{
+		client:  httputil.NewHTTPClient(&httputil.HTTPClientConfig{Timeout: timeout}),
+		tokens:  tokens,
+		timeout: timeout,
+		logger:  log,
+	}
+}
+
+// ProbeAll probes all credentials in parallel (bounded by concurrency).
+// Results are returned in the same order as credentials.
+func (p *CredentialProber) ProbeAll(ctx context.Context, credentials []config.CredentialConfig, models []config.ModelRPMConfig, concurrency int) []CredentialProbeResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]CredentialProbeResult, len(credentials))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range credentials {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cred := credentials[i]
+			results[i] = p.Probe(ctx, &cred, expectedModelsForCredential(cred.Name, models))
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Probe checks a single credential. expectedModels are the real model names
+// that must appear in the credential's model listing.
+func (p *CredentialProber) Probe(ctx context.Context, cred *config.CredentialConfig, expectedModels []string) CredentialProbeResult {
+	result := CredentialProbeResult{
+		Name:     cred.Name,
+		Type:     cred.Type,
+		Required: cred.Required,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	available, skipReason, err := p.listModels(ctx, cred)
+	result.Latency = time.Since(start)
+
+	switch {
+	case err != nil:
+		result.Status = ProbeStatusFailed
+		result.Error = err.Error()
+		return result
+	case skipReason != "":
+		result.Status = ProbeStatusSkipped
+		result.Error = skipReason
+		return result
+	}
+
+	result.Status = ProbeStatusOK
+	if available == nil {
+		// Authentication succeeded but the provider does not expose a model listing
+		return result
+	}
+
+	result.ModelsChecked = len(expectedModels)
+	for _, model := range expectedModels {
+		if !available[model] {
+			result.MissingModels = append(result.MissingModels, model)
+		}
+	}
+	if len(result.MissingModels) > 0 {
+		result.Status = ProbeStatusMissingModels
+	}
+	return result
+}
+
+// listModels performs the provider-specific probe request.
+// Returns the set of available model IDs (nil if the provider has no listing endpoint),
+// a non-empty skip reason if the credential cannot be probed, or an error.
+func (p *CredentialProber) listModels(ctx context.Context, cred *config.CredentialConfig) (map[string]bool, string, error) {
+	baseURL := strings.TrimSuffix(cred.BaseURL, "/")
+
+	switch cred.Type {
+	case config.ProviderTypeOpenAI, config.ProviderTypeProxy:
+		headers := map[string]string{}
+		if cred.APIKey != "" {
+			headers["Authorization"] = "Bearer " + cred.APIKey
+		}
+		var resp struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if err := p.getJSON(ctx, baseURL+"/v1/models", headers, &resp); err != nil {
+			return nil, "", err
+		}
+		available := make(map[string]bool, len(resp.Data))
+		for _, m := range resp.Data {
+			available[m.ID] = true
+		}
+		return available, "", nil
+
+	case config.ProviderTypeAnthropic:
+		headers := map[string]string{
+			"X-Api-Key":         cred.APIKey,
+			"anthropic-version": cred.AnthropicAPIVersion(),
+		}
+		var resp struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if err := p.getJSON(ctx, baseURL+"/v1/models?limit=1000", headers, &resp); err != nil {
+			return nil, "", err
+		}
+		available := make(map[string]bool, len(resp.Data))
+		for _, m := range resp.Data {
+			available[m.ID] = true
+		}
+		return available, "", nil
+
+	case config.ProviderTypeGemini:
+		headers := map[string]string{"x-goog-api-key": cred.APIKey}
+		var resp struct {
+			Models []struct {
+				Name string `json:"name"`
+			} `json:"models"`
+		}
+		if err := p.getJSON(ctx, baseURL+"/v1beta/models?pageSize=1000", headers, &resp); err != nil {
+			return nil, "", err
+		}
+		available := make(map[string]bool, len(resp.Models))
+		for _, m := range resp.Models {
+			available[strings.TrimPrefix(m.Name, "models/")] = true
+		}
+		return available, "", nil
+
+	case config.ProviderTypeVertexAI:
+		if cred.CredentialsFile == "" && cred.CredentialsJSON == "" {
+			return nil, "api_key (express mode) is not probed", nil
+		}
+		if p.tokens == nil {
+			return nil, "token provider not configured", nil
+		}
+		// Obtaining an OAuth2 token validates the service account; Vertex AI has no cheap model listing
+		if _, err := p.tokens.GetToken(ctx, cred.Name, cred.CredentialsFile, cred.CredentialsJSON); err != nil {
+			return nil, "", fmt.Errorf("failed to obtain token: %w", err)
+		}
+		return nil, "", nil
+
+	case config.ProviderTypeMock:
+		return nil, "mock credentials are served in-process", nil
+
+	default:
+		return nil, fmt.Sprintf("probing is not supported for type %s", cred.Type), nil
+	}
+}
+
+// getJSON performs a GET request and decodes a JSON response.
+// 401/403 responses are reported as authentication failures.
+func (p *CredentialProber) getJSON(ctx context.Context, url string, headers map[string]string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			p.logger.Debug("Failed to close response body", "error", closeErr)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("authentication failed (status %d)", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse model list: %w", err)
+	}
+	return nil
+}
+
+// expectedModelsForCredential returns the real model names explicitly bound to a credential
+func expectedModelsForCredential(credentialName string, models []config.ModelRPMConfig) []string {
+	seen := make(map[string]bool)
+	var expected []string
+	for _, m := range models {
+		if m.Credential != credentialName {
+			continue
+		}
+		name := m.Model
+		if name == "" {
+			name = m.Name
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			expected = append(expected, name)
+		}
+	}
+	sort.Strings(expected)
+	return expected
+}
+
+// FormatProbeReport renders probe results as an aligned text table
+func FormatProbeReport(results []CredentialProbeResult) string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tTYPE\tREQUIRED\tSTATUS\tLATENCY\tMODELS\tDETAILS")
+	for _, r := range results {
+		models := "-"
+		if r.ModelsChecked > 0 {
+			models = fmt.Sprintf("%d/%d", r.ModelsChecked-len(r.MissingModels), r.ModelsChecked)
+		}
+		details := r.Error
+		if len(r.MissingModels) > 0 {
+			details = "missing: " + strings.Join(r.MissingModels, ", ")
+		}
+		if details == "" {
+			details = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\t%s\t%s\n",
+			r.Name, r.Type, r.Required, r.Status, r.Latency.Round(time.Millisecond), models, details)
+	}
+	_ = tw.Flush()
+	return sb.String()
+}
+
+// ValidateCredentialsAtStartup probes every configured credential in parallel and logs a summary table.
+// In strict mode it returns an error if any credential marked as required failed its probe
+// (missing models are reported but do not fail startup). Otherwise failures are only logged.
+func ValidateCredentialsAtStartup(cfg *config.Config, tokens TokenProvider, log *slog.Logger) error {
+	checkCfg := cfg.StartupCheck
+	log.Info("Probing credentials at startup",
+		"total_credentials", len(cfg.Credentials),
+		"timeout", checkCfg.Timeout.String(),
+		"concurrency", checkCfg.Concurrency,
+		"strict", checkCfg.Strict,
+	)
+
+	prober := NewCredentialProber(checkCfg.Timeout, tokens, log)
+	results := prober.ProbeAll(context.Background(), cfg.Credentials, cfg.Models, checkCfg.Concurrency)
+
+	counts := make(map[ProbeStatus]int)
+	var failedRequired []string
+	for _, r := range results {
+		counts[r.Status]++
+		switch r.Status {
+		case ProbeStatusFailed:
+			log.Warn("Credential probe failed at startup",
+				"name", r.Name, "type", r.Type, "required", r.Required, "error", r.Error)
+			if r.Required {
+				failedRequired = append(failedRequired, r.Name)
+			}
+		case ProbeStatusMissingModels:
+			log.Warn("Credential is missing configured models",
+				"name", r.Name, "type", r.Type, "missing_models", r.MissingModels)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(FormatProbeReport(results), "\n"), "\n") {
+		log.Info("  " + line)
+	}
+
+	log.Info("Credential startup check completed",
+		"total", len(results),
+		"ok", counts[ProbeStatusOK],
+		"missing_models", counts[ProbeStatusMissingModels],
+		"failed", counts[ProbeStatusFailed],
+		"skipped", counts[ProbeStatusSkipped],
+	)
+
+	if len(failedRequired) > 0 {
+		if checkCfg.Strict {
+			return fmt.Errorf("required credentials failed startup check: %s", strings.Join(failedRequired, ", "))
+		}
+		log.Error("Required credentials failed startup check (strict mode disabled, continuing)",
+			"credentials", failedRequired)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+type fakeTokenProvider struct {
+	err error
+}
+
+func (f *fakeTokenProvider) GetToken(_ context.Context, _, _, _ string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return "token", nil
+}
+
+func newModelsServer(t *testing.T, wantAuthHeader, wantAuthValue, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(wantAuthHeader) != wantAuthValue {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCredentialProber_OpenAI(t *testing.T) {
+	server := newModelsServer(t, "Authorization", "Bearer sk-good", `{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`)
+	prober := NewCredentialProber(2*time.Second, nil, testLogger())
+
+	t.Run("ok", func(t *testing.T) {
+		cred := &config.CredentialConfig{Name: "openai", Type: config.ProviderTypeOpenAI, APIKey: "sk-good", BaseURL: server.URL}
+		result := prober.Probe(context.Background(), cred, []string{"gpt-4o"})
+		assert.Equal(t, ProbeStatusOK, result.Status)
+		assert.Equal(t, 1, result.ModelsChecked)
+		assert.Empty(t, result.MissingModels)
+	})
+
+	t.Run("missing models", func(t *testing.T) {
+		cred := &config.CredentialConfig{Name: "openai", Type: config.ProviderTypeOpenAI, APIKey: "sk-good", BaseURL: server.URL}
+		result := prober.Probe(context.Background(), cred, []string{"gpt-4o", "o3"})
+		assert.Equal(t, ProbeStatusMissingModels, result.Status)
+		assert.Equal(t, []string{"o3"}, result.MissingModels)
+	})
+
+	t.Run("auth failure", func(t *testing.T) {
+		cred := &config.CredentialConfig{Name: "openai", Type: config.ProviderTypeOpenAI, APIKey: "sk-bad", BaseURL: server.URL}
+		result := prober.Probe(context.Background(), cred, nil)
+		assert.Equal(t, ProbeStatusFailed, result.Status)
+		assert.Contains(t, result.Error, "authentication failed (status 401)")
+	})
+}
+
+func TestCredentialProber_Anthropic(t *testing.T) {
+	server := newModelsServer(t, "X-Api-Key", "sk-ant", `{"data":[{"id":"claude-sonnet-4-5"}]}`)
+	prober := NewCredentialProber(2*time.Second, nil, testLogger())
+
+	cred := &config.CredentialConfig{Name: "anthropic", Type: config.ProviderTypeAnthropic, APIKey: "sk-ant", BaseURL: server.URL}
+	result := prober.Probe(context.Background(), cred, []string{"claude-sonnet-4-5"})
+	assert.Equal(t, ProbeStatusOK, result.Status)
+}
+
+func TestCredentialProber_Gemini(t *testing.T) {
+	server := newModelsServer(t, "x-goog-api-key", "g-key", `{"models":[{"name":"models/gemini-2.5-flash"}]}`)
+	prober := NewCredentialProber(2*time.Second, nil, testLogger())
+
+	cred := &config.CredentialConfig{Name: "gemini", Type: config.ProviderTypeGemini, APIKey: "g-key", BaseURL: server.URL}
+	result := prober.Probe(context.Background(), cred, []string{"gemini-2.5-flash"})
+	assert.Equal(t, ProbeStatusOK, result.Status)
+}
+
+func TestCredentialProber_VertexAI(t *testing.T) {
+	cred := &config.CredentialConfig{Name: "vertex", Type: config.ProviderTypeVertexAI, CredentialsJSON: "{}"}
+
+	result := NewCredentialProber(time.Second, &fakeTokenProvider{}, testLogger()).Probe(context.Background(), cred, nil)
+	assert.Equal(t, ProbeStatusOK, result.Status)
+
+	result = NewCredentialProber(time.Second, &fakeTokenProvider{err: errors.New("bad key")}, testLogger()).Probe(context.Background(), cred, nil)
+	assert.Equal(t, ProbeStatusFailed, result.Status)
+	assert.Contains(t, result.Error, "bad key")
+
+	apiKeyCred := &config.CredentialConfig{Name: "vertex", Type: config.ProviderTypeVertexAI, APIKey: "key"}
+	result = NewCredentialProber(time.Second, &fakeTokenProvider{}, testLogger()).Probe(context.Background(), apiKeyCred, nil)
+	assert.Equal(t, ProbeStatusSkipped, result.Status)
+}
+
+func TestCredentialProber_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	prober := NewCredentialProber(100*time.Millisecond, nil, testLogger())
+	cred := &config.CredentialConfig{Name: "slow", Type: config.ProviderTypeOpenAI, APIKey: "sk", BaseURL: server.URL}
+	result := prober.Probe(context.Background(), cred, nil)
+	assert.Equal(t, ProbeStatusFailed, result.Status)
+	assert.Less(t, result.Latency, time.Second)
+}
+
+func TestCredentialProber_ProbeAll_PreservesOrder(t *testing.T) {
+	server := newModelsServer(t, "Authorization", "Bearer sk-good", `{"data":[{"id":"gpt-4o"}]}`)
+	prober := NewCredentialProber(2*time.Second, nil, testLogger())
+
+	credentials := []config.CredentialConfig{
+		{Name: "a", Type: config.ProviderTypeOpenAI, APIKey: "sk-good", BaseURL: server.URL},
+		{Name: "b", Type: config.ProviderTypeOpenAI, APIKey: "sk-bad", BaseURL: server.URL},
+		{Name: "c", Type: config.ProviderTypeBedrock, APIKey: "x", BaseURL: server.URL},
+	}
+	models := []config.ModelRPMConfig{
+		{Name: "alias", Model: "gpt-4o", Credential: "a"},
+		{Name: "gpt-5", Credential: "a"},
+	}
+
+	results := prober.ProbeAll(context.Background(), credentials, models, 2)
+	require.Len(t, results, 3)
+	assert.Equal(t, "a", results[0].Name)
+	assert.Equal(t, ProbeStatusMissingModels, results[0].Status)
+	assert.Equal(t, []string{"gpt-5"}, results[0].MissingModels)
+	assert.Equal(t, ProbeStatusFailed, results[1].Status)
+	assert.Equal(t, ProbeStatusSkipped, results[2].Status)
+}
+
+func TestValidateCredentialsAtStartup_Strict(t *testing.T) {
+	server := newModelsServer(t, "Authorization", "Bearer sk-good", `{"data":[]}`)
+
+	cfg := &config.Config{
+		Credentials: []config.CredentialConfig{
+			{Name: "good", Type: config.ProviderTypeOpenAI, APIKey: "sk-good", BaseURL: server.URL, Required: true},
+			{Name: "optional", Type: config.ProviderTypeOpenAI, APIKey: "sk-bad", BaseURL: server.URL},
+		},
+		StartupCheck: config.StartupCheckConfig{Enabled: true, Strict: true, Timeout: 2 * time.Second, Concurrency: 4},
+	}
+
+	// Failing optional credential does not block startup
+	require.NoError(t, ValidateCredentialsAtStartup(cfg, nil, testLogger()))
+
+	// Failing required credential blocks startup in strict mode
+	cfg.Credentials[1].Required = true
+	err := ValidateCredentialsAtStartup(cfg, nil, testLogger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "optional")
+
+	// Non-strict mode only logs
+	cfg.StartupCheck.Strict = false
+	assert.NoError(t, ValidateCredentialsAtStartup(cfg, nil, testLogger()))
+}
+
+func TestFormatProbeReport(t *testing.T) {
+	report := FormatProbeReport([]CredentialProbeResult{
+		{Name: "a", Type: config.ProviderTypeOpenAI, Status: ProbeStatusOK, ModelsChecked: 2},
+		{Name: "b", Type: config.ProviderTypeAnthropic, Status: ProbeStatusMissingModels, ModelsChecked: 2, MissingModels: []string{"x"}},
+	})
+	assert.Contains(t, report, "NAME")
+	assert.Contains(t, report, "2/2")
+	assert.Contains(t, report, "1/2")
+	assert.Contains(t, report, "missing: x")
+}
@@ -0,0 +1,68 @@
+// Package webhook posts notifications (spend reports, SLO alerts) to HTTP webhooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+)
+
+// postTimeout bounds a single delivery to the webhook
+const postTimeout = 10 * time.Second
+
+// Poster posts payloads to a webhook URL as JSON, or as Slack messages
+// (config.WebhookFormatSlack). A nil *Poster posts nothing.
+type Poster struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+// New creates a Poster for url in format ("json" or "slack").
+// Returns nil if url is empty.
+func New(url, format string) *Poster {
+	if url == "" {
+		return nil
+	}
+	return &Poster{url: url, format: format, client: &http.Client{Timeout: postTimeout}}
+}
+
+// Post sends payload as JSON, or text as {"text": ...} in the Slack format.
+// Responses other than 2xx are errors.
+func (p *Poster) Post(ctx context.Context, payload interface{}, text string) error {
+	if p == nil {
+		return nil
+	}
+
+	var body []byte
+	var err error
+	if p.format == config.WebhookFormatSlack {
+		body, err = json.Marshal(map[string]string{"text": text})
+	} else {
+		body, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
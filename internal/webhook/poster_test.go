@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mixaill76/auto_ai_router/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoster_Post(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	payload := map[string]string{"status": "ok"}
+	require.NoError(t, New(server.URL, config.WebhookFormatJSON).Post(context.Background(), payload, "All good"))
+	require.NoError(t, New(server.URL, config.WebhookFormatSlack).Post(context.Background(), payload, "All good"))
+
+	require.Len(t, received, 2)
+	assert.Equal(t, map[string]interface{}{"status": "ok"}, received[0])
+	assert.Equal(t, map[string]interface{}{"text": "All good"}, received[1])
+}
+
+func TestPoster_PostError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	assert.ErrorContains(t, New(server.URL, config.WebhookFormatJSON).Post(context.Background(), nil, ""), "unexpected status 502")
+
+	var none *Poster
+	assert.Nil(t, New("", config.WebhookFormatJSON))
+	assert.NoError(t, none.Post(context.Background(), nil, ""))
+}
@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/mixaill76/auto_ai_router/internal/utils"
+)
+
+// Restart backoff of supervised workers: doubled after every panic up to DefaultMaxBackoff,
+// and reset once a worker ran for DefaultMaxBackoff without panicking
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// States of a supervised worker
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting" // Panicked, waiting for the restart backoff
+	StateStopped    = "stopped"    // Returned, e.g. because its context was cancelled
+)
+
+// Status is the state and last run of a supervised worker
+type Status struct {
+	State     string     `json:"state"`
+	Restarts  int        `json:"restarts"`
+	LastRun   *time.Time `json:"last_run,omitempty"`   // When the worker last reported a completed iteration
+	LastError string     `json:"last_error,omitempty"` // Error of the last iteration or panic (omitted after a successful one)
+	LastPanic *time.Time `json:"last_panic,omitempty"`
+}
+
+// RunFunc is the body of a supervised worker. It runs until ctx is cancelled and calls report
+// after every iteration with its error (nil on success).
+type RunFunc func(ctx context.Context, report func(err error))
+
+// Supervisor runs long-lived background workers. A worker that panics is logged and restarted
+// with backoff instead of silently stopping or crashing the process.
+type Supervisor struct {
+	logger     *slog.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	workers map[string]*Status
+}
+
+// NewSupervisor creates a supervisor with the default restart backoff
+func NewSupervisor(logger *slog.Logger) *Supervisor {
+	return &Supervisor{
+		logger:     logger,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		workers:    make(map[string]*Status),
+	}
+}
+
+// Go starts a supervised worker. Names are unique; starting a worker under the name of
+// another one resets its status.
+func (s *Supervisor) Go(ctx context.Context, name string, run RunFunc) {
+	s.mu.Lock()
+	s.workers[name] = &Status{State: StateRunning}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.update(name, func(status *Status) { status.State = StateStopped })
+
+		backoff := s.minBackoff
+		for {
+			started := time.Now()
+			if !s.runOnce(ctx, name, run) || ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) >= s.maxBackoff {
+				backoff = s.minBackoff
+			}
+
+			s.logger.Warn("Restarting background worker", "worker", name, "backoff", backoff.String())
+			s.update(name, func(status *Status) { status.State = StateRestarting })
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, s.maxBackoff)
+			s.update(name, func(status *Status) {
+				status.State = StateRunning
+				status.Restarts++
+			})
+		}
+	}()
+}
+
+// runOnce runs a worker until it returns or panics. Reports whether it panicked.
+func (s *Supervisor) runOnce(ctx context.Context, name string, run RunFunc) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			now := utils.NowUTC()
+			s.logger.Error("Background worker panicked",
+				"worker", name,
+				"panic", fmt.Sprintf("%v", r),
+				"stack", string(debug.Stack()),
+			)
+			s.update(name, func(status *Status) {
+				status.LastPanic = &now
+				status.LastError = fmt.Sprintf("panic: %v", r)
+			})
+			panicked = true
+		}
+	}()
+
+	run(ctx, func(err error) {
+		now := utils.NowUTC()
+		s.update(name, func(status *Status) {
+			status.LastRun = &now
+			status.LastError = ""
+			if err != nil {
+				status.LastError = err.Error()
+			}
+		})
+	})
+	return false
+}
+
+// update applies fn to the status of a worker
+func (s *Supervisor) update(name string, fn func(status *Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status := s.workers[name]; status != nil {
+		fn(status)
+	}
+}
+
+// Status returns the status of every worker by name (nil if s is nil)
+func (s *Supervisor) Status() map[string]Status {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	statuses := make(map[string]Status, len(s.workers))
+	for name, status := range s.workers {
+		statuses[name] = *status
+	}
+	return statuses
+}
+
+// Wait blocks until all workers have returned
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSupervisor() *Supervisor {
+	s := NewSupervisor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.minBackoff = time.Millisecond
+	s.maxBackoff = 4 * time.Millisecond
+	return s
+}
+
+func TestSupervisor_RestartsPanickedWorker(t *testing.T) {
+	s := newTestSupervisor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	s.Go(ctx, "updater", func(ctx context.Context, report func(error)) {
+		if runs.Add(1) <= 2 {
+			panic("boom")
+		}
+		report(nil)
+		<-ctx.Done()
+	})
+
+	require.Eventually(t, func() bool {
+		status := s.Status()["updater"]
+		return status.State == StateRunning && status.LastRun != nil
+	}, time.Second, time.Millisecond)
+
+	status := s.Status()["updater"]
+	assert.Equal(t, 2, status.Restarts)
+	assert.Empty(t, status.LastError, "a successful run clears the panic")
+	require.NotNil(t, status.LastPanic)
+
+	cancel()
+	s.Wait()
+	assert.Equal(t, StateStopped, s.Status()["updater"].State)
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestSupervisor_ReportsErrors(t *testing.T) {
+	s := newTestSupervisor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reported := make(chan struct{})
+	s.Go(ctx, "price_sync", func(ctx context.Context, report func(error)) {
+		report(errors.New("fetch failed"))
+		close(reported)
+		<-ctx.Done()
+	})
+
+	<-reported
+	status := s.Status()["price_sync"]
+	assert.Equal(t, StateRunning, status.State)
+	assert.Equal(t, "fetch failed", status.LastError)
+	assert.NotNil(t, status.LastRun)
+	assert.Nil(t, status.LastPanic)
+}
+
+func TestSupervisor_StopsDuringBackoff(t *testing.T) {
+	s := newTestSupervisor()
+	s.minBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.Go(ctx, "monitor", func(context.Context, func(error)) {
+		panic("boom")
+	})
+	require.Eventually(t, func() bool {
+		return s.Status()["monitor"].State == StateRestarting
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "panic: boom", s.Status()["monitor"].LastError)
+
+	cancel()
+	s.Wait()
+	assert.Equal(t, StateStopped, s.Status()["monitor"].State)
+	assert.Zero(t, s.Status()["monitor"].Restarts)
+}
+
+func TestSupervisor_WorkerReturns(t *testing.T) {
+	s := newTestSupervisor()
+	s.Go(context.Background(), "prewarm", func(context.Context, func(error)) {})
+	s.Wait()
+	assert.Equal(t, StateStopped, s.Status()["prewarm"].State, "returning workers are not restarted")
+
+	var nilSupervisor *Supervisor
+	assert.Nil(t, nilSupervisor.Status())
+}